@@ -0,0 +1,242 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/pinger"
+	"github.com/networkables/mason/internal/ratelimit"
+	"github.com/networkables/mason/nettools"
+)
+
+var (
+	ErrServerRequired   = errors.New("agent server url is required")
+	ErrTokenRequired    = errors.New("agent token is required")
+	ErrNetworksRequired = errors.New("agent needs at least one network to scan")
+)
+
+type (
+	scanFunc func(context.Context, model.Addr) (model.EventDeviceDiscovered, error)
+	pingFunc func(context.Context, model.Device) (pinger.PerformancePingResponseEvent, error)
+)
+
+// Agent scans networks the central server cannot reach and reports the devices it finds
+// to the server.  Only discovery and the performance ping run on the agent, the server
+// does the enrichment and keeps the history.
+type Agent struct {
+	cfg        *Config
+	name       string
+	networks   []model.Network
+	maxWorkers int
+	scan       scanFunc
+	ping       pingFunc
+	limits     *ratelimit.Group
+	client     *http.Client
+	reportURL  string
+}
+
+// New checks the agent configuration and builds the scanners from the discovery and pinger
+// configuration, ping may be nil to only report discovered devices
+func New(
+	cfg *Config,
+	disco *discovery.Config,
+	ping *pinger.Config,
+	limits *ratelimit.Group,
+) (*Agent, error) {
+	if cfg.Server == "" {
+		return nil, ErrServerRequired
+	}
+	if cfg.Token == "" {
+		return nil, ErrTokenRequired
+	}
+	if len(cfg.Networks) == 0 {
+		return nil, ErrNetworksRequired
+	}
+	u, err := url.Parse(cfg.Server)
+	if err != nil {
+		return nil, tre.New(err, "parse agent server url", "server", cfg.Server)
+	}
+	if u.Scheme != "https" {
+		log.Warn("agent server is not https, the token is sent in the clear", "server", cfg.Server)
+	}
+	a := &Agent{
+		cfg:        cfg,
+		name:       cfg.Name,
+		maxWorkers: max(disco.MaxWorkers, 1),
+		scan:       discovery.BuildAddrScannerFunc(discovery.BuildAddrScanners(disco)),
+		limits:     limits,
+		reportURL:  u.JoinPath(ReportPath).String(),
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify},
+			},
+		},
+	}
+	if a.name == "" {
+		a.name, err = os.Hostname()
+		if err != nil {
+			return nil, tre.New(err, "agent name from hostname")
+		}
+	}
+	if ping != nil && ping.Enabled {
+		a.ping = pinger.BuildPingDevice(ping, nil)
+	}
+	for _, s := range cfg.Networks {
+		n, err := model.New("", strings.TrimSpace(s))
+		if err != nil {
+			return nil, tre.New(err, "parse agent network", "network", s)
+		}
+		if n.Prefix.Is6() {
+			log.Warn("ipv6 networks are not scanned by the agent", "network", n.Prefix)
+			continue
+		}
+		a.networks = append(a.networks, n)
+	}
+	if len(a.networks) == 0 {
+		return nil, ErrNetworksRequired
+	}
+	return a, nil
+}
+
+// Run scans and reports straight away and then on every scan interval until the context
+// is done.  A failed report is logged and retried with the next scan.
+func (a *Agent) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.cfg.ScanInterval)
+	defer ticker.Stop()
+	for {
+		err := a.ScanAndReport(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error("agent report", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ScanAndReport scans every network once and sends the devices found to the server
+func (a *Agent) ScanAndReport(ctx context.Context) error {
+	start := time.Now()
+	report := Report{Agent: a.name, Site: a.cfg.Site, Devices: make([]ReportDevice, 0)}
+	for _, n := range a.networks {
+		report.Networks = append(report.Networks, n.Prefix.String())
+		report.Devices = append(report.Devices, a.scanNetwork(ctx, n)...)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	log.Info(
+		"agent scan complete",
+		"networks", len(a.networks),
+		"devices", len(report.Devices),
+		"elapsed", time.Since(start).Round(time.Second),
+	)
+	report.Sent = time.Now()
+	return a.send(ctx, report)
+}
+
+func (a *Agent) scanNetwork(ctx context.Context, n model.Network) []ReportDevice {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		found   = make([]ReportDevice, 0)
+		sem     = make(chan struct{}, a.maxWorkers)
+		limiter = a.limits.Network(n.Prefix.P)
+	)
+	ni := model.NewNetworkIterator(n)
+	for addr, done := ni.Next(); !done; addr, done = ni.Next() {
+		if limiter.Wait(ctx) != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			rd, ok := a.probe(ctx, addr)
+			if !ok {
+				return
+			}
+			mu.Lock()
+			found = append(found, rd)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return found
+}
+
+func (a *Agent) probe(ctx context.Context, addr model.Addr) (ReportDevice, bool) {
+	event, err := a.scan(ctx, addr)
+	if err != nil {
+		if !errors.Is(err, discovery.ErrNoDeviceDiscovered) {
+			log.Debug("agent probe", "addr", addr, "error", err)
+		}
+		return ReportDevice{}, false
+	}
+	d := model.Device(event)
+	var stats *nettools.Icmp4EchoResponseStatistics
+	if a.ping != nil {
+		pre, err := a.ping(ctx, d)
+		if err == nil {
+			stats = &pre.Stats
+		}
+	}
+	return NewReportDevice(d, stats), true
+}
+
+func (a *Agent) send(ctx context.Context, report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return tre.New(err, "encode agent report")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.reportURL, bytes.NewReader(body))
+	if err != nil {
+		return tre.New(err, "agent report request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.cfg.Token)
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return tre.New(err, "send agent report", "server", a.cfg.Server)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return tre.New(
+			fmt.Errorf("unexpected status %s", resp.Status),
+			"send agent report",
+			"server", a.cfg.Server,
+		)
+	}
+	return nil
+}
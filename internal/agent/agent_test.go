@@ -0,0 +1,167 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+func TestReportDevice_RoundTrip(t *testing.T) {
+	ts := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	stats := &nettools.Icmp4EchoResponseStatistics{
+		Start:        ts,
+		TotalPackets: 3,
+		SuccessCount: 3,
+		Mean:         2 * time.Millisecond,
+		Maximum:      3 * time.Millisecond,
+	}
+	d := model.Device{
+		Name:         "printer",
+		Addr:         model.MustParseAddr("10.1.0.5"),
+		MAC:          model.MustParseMAC("00:11:22:33:44:55"),
+		DiscoveredBy: discovery.ArpDiscoverySource,
+		DiscoveredAt: ts,
+	}
+
+	data, err := json.Marshal(NewReportDevice(d, stats))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rd ReportDevice
+	err = json.Unmarshal(data, &rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := rd.Device("site-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := d
+	want.DiscoveredBy = "AGENT:site-a"
+	want.UpdateFromPingStats(*stats, ts)
+	opts := cmp.Comparer(func(a, b model.Device) bool {
+		return a.Name == b.Name &&
+			a.Addr.Compare(b.Addr) == 0 &&
+			a.MAC.Compare(b.MAC) == 0 &&
+			a.DiscoveredBy == b.DiscoveredBy &&
+			a.DiscoveredAt.Equal(b.DiscoveredAt) &&
+			cmp.Equal(a.PerformancePing, b.PerformancePing)
+	})
+	if diff := cmp.Diff(want, got, opts); diff != "" {
+		t.Errorf("Device() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReportDevice_InvalidAddr(t *testing.T) {
+	_, err := ReportDevice{Addr: "not-an-addr"}.Device("site-a")
+	if err == nil {
+		t.Error("want error for invalid addr")
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	disco := &discovery.Config{
+		Arp:  &discovery.ArpConfig{},
+		Icmp: &discovery.ICMPConfig{},
+		Snmp: &discovery.SNMPConfig{},
+	}
+	tests := map[string]struct {
+		cfg  Config
+		want error
+	}{
+		"NoServer": {
+			cfg:  Config{Token: "t", Networks: []string{"10.0.0.0/24"}},
+			want: ErrServerRequired,
+		},
+		"NoToken": {
+			cfg:  Config{Server: "https://m", Networks: []string{"10.0.0.0/24"}},
+			want: ErrTokenRequired,
+		},
+		"NoNetworks": {
+			cfg:  Config{Server: "https://m", Token: "t"},
+			want: ErrNetworksRequired,
+		},
+		"OnlyIPv6": {
+			cfg:  Config{Server: "https://m", Token: "t", Networks: []string{"fd00::/64"}},
+			want: ErrNetworksRequired,
+		},
+		"Valid": {
+			cfg: Config{Server: "https://m", Token: "t", Name: "a", Networks: []string{"10.0.0.0/24"}},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(&tc.cfg, disco, nil, nil)
+			if !errors.Is(err, tc.want) {
+				t.Errorf("error want: %v, got: %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestAgent_Send(t *testing.T) {
+	var (
+		gotAuth   string
+		gotReport Report
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != ReportPath {
+			http.NotFound(w, r)
+			return
+		}
+		gotAuth = r.Header.Get("Authorization")
+		err := json.NewDecoder(r.Body).Decode(&gotReport)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	a := &Agent{
+		cfg:       &Config{Server: srv.URL, Token: "secret"},
+		client:    srv.Client(),
+		reportURL: srv.URL + ReportPath,
+	}
+	report := Report{
+		Agent:    "site-a",
+		Site:     "branch",
+		Networks: []string{"10.1.0.0/24"},
+		Devices:  []ReportDevice{{Addr: "10.1.0.5", DiscoveredBy: "PING"}},
+	}
+	err := a.send(context.Background(), report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("authorization want: Bearer secret, got: %s", gotAuth)
+	}
+	if diff := cmp.Diff(report, gotReport); diff != "" {
+		t.Errorf("report mismatch (-want +got):\n%s", diff)
+	}
+
+	a.cfg.Token = "wrong"
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+	err = a.send(context.Background(), report)
+	if err == nil {
+		t.Error("want error when the server rejects the report")
+	}
+}
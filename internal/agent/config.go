@@ -0,0 +1,93 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/networkables/mason/internal/flagset"
+)
+
+type Config struct {
+	Name               string
+	Site               string
+	Server             string
+	Token              string
+	Networks           []string
+	ScanInterval       time.Duration
+	Timeout            time.Duration
+	InsecureSkipVerify bool
+}
+
+func SetFlags(fs *pflag.FlagSet, cfg *Config) {
+	configMajorKey := "agent"
+
+	flagset.String(
+		fs,
+		&cfg.Name,
+		configMajorKey,
+		"name",
+		"",
+		"name the agent reports under, blank for the hostname",
+	)
+	flagset.String(
+		fs,
+		&cfg.Site,
+		configMajorKey,
+		"site",
+		"",
+		"site the agent's devices belong to, blank to place them by the server's networks",
+	)
+	flagset.String(
+		fs,
+		&cfg.Server,
+		configMajorKey,
+		"server",
+		"",
+		"url of the central mason server the agent reports to (https://mason.example.com)",
+	)
+	flagset.String(
+		fs,
+		&cfg.Token,
+		configMajorKey,
+		"token",
+		"",
+		"shared token between agents and the server, the server ignores agents when blank",
+	)
+	flagset.StringSlice(
+		fs,
+		&cfg.Networks,
+		configMajorKey,
+		"networks",
+		nil,
+		"networks (cidr) scanned by the agent",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.ScanInterval,
+		configMajorKey,
+		"scaninterval",
+		15*time.Minute,
+		"time between agent scans of its networks",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Timeout,
+		configMajorKey,
+		"timeout",
+		30*time.Second,
+		"max time to wait for the server to accept a report",
+	)
+	flagset.Bool(
+		fs,
+		&cfg.InsecureSkipVerify,
+		configMajorKey,
+		"insecureskipverify",
+		false,
+		"accept any server certificate, only for testing with self signed certificates",
+	)
+}
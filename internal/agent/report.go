@@ -0,0 +1,92 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"errors"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+// ReportPath is where the server accepts agent reports
+const ReportPath = "/api/agent/report"
+
+var (
+	ErrAgentReportsDisabled = errors.New("agent reports are disabled, no agent token is set")
+	ErrAgentUnauthorized    = errors.New("agent token does not match")
+	ErrAgentNameRequired    = errors.New("agent name is required")
+)
+
+// Report is what an agent sends to the server after scanning its networks
+type Report struct {
+	Agent    string         `json:"agent"`
+	Site     string         `json:"site,omitempty"`
+	Sent     time.Time      `json:"sent"`
+	Networks []string       `json:"networks"`
+	Devices  []ReportDevice `json:"devices"`
+}
+
+// ReportDevice is a device found by an agent, Ping holds the performance ping taken after
+// the device was found when the agent has the pinger enabled
+type ReportDevice struct {
+	Addr         string                                `json:"addr"`
+	MAC          string                                `json:"mac,omitempty"`
+	Name         string                                `json:"name,omitempty"`
+	DiscoveredBy string                                `json:"discoveredby"`
+	DiscoveredAt time.Time                             `json:"discoveredat"`
+	Ping         *nettools.Icmp4EchoResponseStatistics `json:"ping,omitempty"`
+}
+
+// DiscoverySource marks the devices reported by the named agent
+func DiscoverySource(name string) model.DiscoverySource {
+	return model.DiscoverySource("AGENT:" + name)
+}
+
+// NewReportDevice converts a discovered device for sending, stats may be nil
+func NewReportDevice(
+	d model.Device,
+	stats *nettools.Icmp4EchoResponseStatistics,
+) ReportDevice {
+	rd := ReportDevice{
+		Addr:         d.Addr.String(),
+		Name:         d.Name,
+		DiscoveredBy: d.DiscoveredBy.String(),
+		DiscoveredAt: d.DiscoveredAt,
+		Ping:         stats,
+	}
+	if !d.MAC.IsEmpty() {
+		rd.MAC = d.MAC.String()
+	}
+	return rd
+}
+
+// Device converts the reported device back into a device discovered by the named agent
+func (rd ReportDevice) Device(agent string) (model.Device, error) {
+	addr, err := model.ParseAddr(rd.Addr)
+	if err != nil {
+		return model.Device{}, err
+	}
+	d := model.Device{
+		Name:         rd.Name,
+		Addr:         addr,
+		DiscoveredBy: DiscoverySource(agent),
+		DiscoveredAt: rd.DiscoveredAt,
+	}
+	if rd.MAC != "" {
+		d.MAC, err = model.ParseMAC(rd.MAC)
+		if err != nil {
+			return model.Device{}, err
+		}
+	}
+	if d.DiscoveredAt.IsZero() {
+		d.DiscoveredAt = time.Now()
+	}
+	if rd.Ping != nil {
+		d.UpdateFromPingStats(*rd.Ping, rd.Ping.Start)
+	}
+	return d, nil
+}
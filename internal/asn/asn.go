@@ -5,6 +5,7 @@
 package asn
 
 import (
+	"context"
 	"errors"
 	"log"
 	"net/netip"
@@ -12,16 +13,47 @@ import (
 	"path/filepath"
 	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/networkables/mason/internal/cachedb"
 )
 
 type store struct {
-	initialized   bool
 	cachefilename string
 	asnurl        string
 	countryurl    string
-	db            []CacheEntry
+	asnstore      asnstorer
+	offline       bool
+
+	// data is swapped as a whole by a refresh so lookups never see a partial listing
+	data atomic.Pointer[dataset]
+
+	// refreshMu allows one refresh at a time, statusMu guards the result of the last one
+	refreshMu   sync.Mutex
+	refreshing  atomic.Bool
+	statusMu    sync.Mutex
+	lastAttempt time.Time
+	lastError   error
+}
+
+type dataset struct {
+	entries []CacheEntry
+	builtAt time.Time
 }
 
+// Status describes the loaded asn listing and the last refresh
+type Status struct {
+	Available   bool
+	Entries     int
+	BuiltAt     time.Time
+	Refreshing  bool
+	LastAttempt time.Time
+	LastError   error
+}
+
+var ErrRefreshOffline = errors.New("offline mode, asn listings are not downloaded")
+
 var (
 	once      sync.Once
 	singleton *store
@@ -29,7 +61,7 @@ var (
 
 func getstore() *store {
 	once.Do(func() {
-		singleton = &store{cachefilename: defaultCacheFilename, db: make([]CacheEntry, 0)}
+		singleton = &store{cachefilename: defaultCacheFilename}
 		// load(singleton)
 	})
 	return singleton
@@ -47,14 +79,97 @@ func load(s *store, opts ...Option) {
 	s.cachefilename = datafile
 	s.asnurl = popts.asnurl
 	s.countryurl = popts.countryurl
+	s.asnstore = popts.store
+	s.offline = popts.offline
 
-	s.initialized, s.db = getdb(s.asnurl, s.countryurl, s.cachefilename, popts.store)
+	initialized, memdb, builtAt := getdb(
+		s.asnurl,
+		s.countryurl,
+		s.cachefilename,
+		s.asnstore,
+		s.offline,
+	)
+	if initialized {
+		s.data.Store(&dataset{entries: memdb, builtAt: builtAt})
+	}
 }
 
-func FindAsn(addr netip.Addr) (asn string) {
+// Refresh downloads the asn and country listings again, stores them and replaces the
+// listing used for lookups.  Lookups keep using the previous listing until the new one is
+// complete, and keep it when the refresh fails.
+func Refresh(ctx context.Context) error {
+	return getstore().refresh(ctx)
+}
+
+func (s *store) refresh(ctx context.Context) (err error) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+	s.refreshing.Store(true)
+	defer func() {
+		s.refreshing.Store(false)
+		s.statusMu.Lock()
+		s.lastAttempt = time.Now()
+		s.lastError = err
+		s.statusMu.Unlock()
+	}()
+
+	if s.offline && (isRemote(s.asnurl) || isRemote(s.countryurl)) {
+		return ErrRefreshOffline
+	}
+	memdb, fulldb, err := builddb(s.asnurl, s.countryurl)
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	err = savefulldb(ctx, s.asnstore, fulldb)
+	if err != nil {
+		return err
+	}
+	err = cachedb.Write(s.cachefilename, memdb)
+	if err != nil {
+		return err
+	}
+	s.data.Store(&dataset{entries: memdb, builtAt: time.Now()})
+	return nil
+}
+
+// Available reports if the asn database has been loaded and lookups will return data
+func Available() bool {
+	return getstore().data.Load() != nil
+}
+
+// Stale reports if the loaded listing was built longer than maxAge ago, a listing which is
+// not loaded is always stale
+func Stale(maxAge time.Duration) bool {
+	data := getstore().data.Load()
+	return data == nil || time.Since(data.builtAt) > maxAge
+}
+
+// GetStatus returns the state of the loaded listing and the last refresh
+func GetStatus() Status {
 	s := getstore()
+	st := Status{Refreshing: s.refreshing.Load()}
+	if data := s.data.Load(); data != nil {
+		st.Available = true
+		st.Entries = len(data.entries)
+		st.BuiltAt = data.builtAt
+	}
+	s.statusMu.Lock()
+	st.LastAttempt = s.lastAttempt
+	st.LastError = s.lastError
+	s.statusMu.Unlock()
+	return st
+}
+
+func FindAsn(addr netip.Addr) (asn string) {
+	data := getstore().data.Load()
+	if data == nil {
+		return asn
+	}
 	idx, found := slices.BinarySearchFunc(
-		s.db,
+		data.entries,
 		addr,
 		func(e CacheEntry, ip netip.Addr) int {
 			if e.Range.Contains(ip) {
@@ -64,7 +179,7 @@ func FindAsn(addr netip.Addr) (asn string) {
 		},
 	)
 	if found {
-		return s.db[idx].Asn
+		return data.entries[idx].Asn
 	}
 	return asn
 }
@@ -0,0 +1,123 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package asn
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"go4.org/netipx"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+type fakeAsnStore struct {
+	loads   int
+	entries map[string]model.Asn
+}
+
+func (f *fakeAsnStore) StartAsnLoad() func(*error) {
+	f.loads++
+	return func(*error) {}
+}
+
+func (f *fakeAsnStore) UpsertAsn(ctx context.Context, a model.Asn) error {
+	f.entries[a.Asn] = a
+	return nil
+}
+
+func writeListing(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	fn := filepath.Join(dir, name)
+	err := os.WriteFile(fn, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fn
+}
+
+func TestStore_refresh(t *testing.T) {
+	dir := t.TempDir()
+	asnurl := writeListing(t, dir, "asn.csv",
+		"1.0.0.0,1.0.0.255,13335,CLOUDFLARENET\n8.8.8.0,8.8.8.255,15169,GOOGLE\n")
+	countryurl := writeListing(t, dir, "country.csv",
+		"1.0.0.0,1.0.0.255,AU\n8.8.8.0,8.8.8.255,US\n")
+	fake := &fakeAsnStore{entries: make(map[string]model.Asn)}
+	s := &store{
+		asnurl:        asnurl,
+		countryurl:    countryurl,
+		cachefilename: filepath.Join(dir, "cache"),
+		asnstore:      fake,
+	}
+	old := &dataset{
+		entries: []CacheEntry{{Asn: "1", Range: netipx.MustParseIPRange("8.8.8.0-8.8.8.255")}},
+		builtAt: time.Now().Add(-30 * 24 * time.Hour),
+	}
+	s.data.Store(old)
+
+	err := s.refresh(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := s.data.Load()
+	if data == old || len(data.entries) != 2 {
+		t.Fatalf("listing not replaced: %+v", data)
+	}
+	if time.Since(data.builtAt) > time.Minute {
+		t.Errorf("built at not updated: %s", data.builtAt)
+	}
+	if fake.entries["15169"].Country != "US" {
+		t.Errorf("stored asn mismatch: %+v", fake.entries["15169"])
+	}
+	if s.lastAttempt.IsZero() || s.lastError != nil {
+		t.Errorf("refresh status want success, got: %s %v", s.lastAttempt, s.lastError)
+	}
+
+	// a broken listing keeps the loaded data
+	writeListing(t, dir, "asn.csv", "1.0.0.0,1.0.0.255\n")
+	err = s.refresh(context.Background())
+	if !errors.Is(err, ErrInvalidListing) {
+		t.Errorf("broken listing want: %v, got: %v", ErrInvalidListing, err)
+	}
+	if s.data.Load() != data {
+		t.Error("broken listing replaced the loaded data")
+	}
+	if !errors.Is(s.lastError, ErrInvalidListing) {
+		t.Errorf("refresh status error want: %v, got: %v", ErrInvalidListing, s.lastError)
+	}
+}
+
+func TestStore_refreshOffline(t *testing.T) {
+	s := &store{
+		asnurl:     defaultAsnUrl,
+		countryurl: defaultCountryUrl,
+		offline:    true,
+	}
+	err := s.refresh(context.Background())
+	if !errors.Is(err, ErrRefreshOffline) {
+		t.Errorf("offline refresh want: %v, got: %v", ErrRefreshOffline, err)
+	}
+}
+
+func TestSavefulldb_batches(t *testing.T) {
+	fake := &fakeAsnStore{entries: make(map[string]model.Asn)}
+	db := make([]asnCountryEntry, saveBatchSize+1)
+	for idx := range db {
+		db[idx] = asnCountryEntry{Asn: strconv.Itoa(idx)}
+	}
+	err := savefulldb(context.Background(), fake, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.loads != 2 || len(fake.entries) != len(db) {
+		t.Errorf("batches want: 2 loads %d entries, got: %d loads %d entries",
+			len(db), fake.loads, len(fake.entries))
+	}
+}
@@ -5,17 +5,20 @@
 package asn
 
 import (
+	"time"
+
 	"github.com/spf13/pflag"
 
 	"github.com/networkables/mason/internal/flagset"
 )
 
 type Config struct {
-	Enabled       bool
-	AsnUrl        string
-	CountryUrl    string
-	Directory     string
-	CacheFilename string
+	Enabled         bool
+	AsnUrl          string
+	CountryUrl      string
+	Directory       string
+	CacheFilename   string
+	RefreshInterval time.Duration
 }
 
 const (
@@ -41,7 +44,7 @@ func SetFlags(pflags *pflag.FlagSet, cfg *Config) {
 		configMajorKey,
 		"asnurl",
 		defaultAsnUrl,
-		"Github url of the asn-ipv4.csv file",
+		"Github url (or local file path) of the asn-ipv4.csv file",
 	)
 	flagset.String(
 		pflags,
@@ -49,7 +52,7 @@ func SetFlags(pflags *pflag.FlagSet, cfg *Config) {
 		configMajorKey,
 		"countryurl",
 		defaultCountryUrl,
-		"Github url of the geo-whois-asn-country-ipv4.csv file",
+		"Github url (or local file path) of the geo-whois-asn-country-ipv4.csv file",
 	)
 	flagset.String(
 		pflags,
@@ -67,4 +70,12 @@ func SetFlags(pflags *pflag.FlagSet, cfg *Config) {
 		defaultCacheFilename,
 		"filename of the asn cache db",
 	)
+	flagset.Duration(
+		pflags,
+		&cfg.RefreshInterval,
+		configMajorKey,
+		"refreshinterval",
+		7*24*time.Hour,
+		"download the asn and country listings again once they are older than this, 0 disables",
+	)
 }
@@ -12,7 +12,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"slices"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"go4.org/netipx"
@@ -21,6 +24,9 @@ import (
 	"github.com/networkables/mason/internal/model"
 )
 
+// saveBatchSize is the number of asn entries written to the store in one transaction
+const saveBatchSize = 5000
+
 type CacheEntry struct {
 	Asn   string
 	Range netipx.IPRange
@@ -44,6 +50,8 @@ type ctentry struct {
 	Country string
 }
 
+var ErrInvalidListing = errors.New("invalid listing, too few columns")
+
 type asnstorer interface {
 	StartAsnLoad() func(*error)
 	UpsertAsn(context.Context, model.Asn) error
@@ -63,9 +71,18 @@ func getdb(
 	countryurl string,
 	cachefilename string,
 	store asnstorer,
-) (initialized bool, memdb []CacheEntry) {
+	offline bool,
+) (initialized bool, memdb []CacheEntry, builtAt time.Time) {
 	var err error
 	if !cachedb.Exists(cachefilename) {
+		if offline && (isRemote(asnurl) || isRemote(countryurl)) {
+			log.Warn(
+				"offline mode, asn local cache not found, lookups disabled",
+				"filename",
+				cachefilename,
+			)
+			return false, memdb, builtAt
+		}
 		log.Info("building asn local cache (roughly 60s)")
 		ctx := context.Background()
 		var fulldb []asnCountryEntry
@@ -79,26 +96,42 @@ func getdb(
 		}
 		err = cachedb.Write(cachefilename, memdb)
 		log.Info("finished building asn local cache", "count", len(memdb))
-		return true, memdb
+		return true, memdb, time.Now()
 	}
 	memdb, err = cachedb.Read[CacheEntry](cachefilename)
 	if err != nil {
 		log.Fatal(err)
 	}
-	log.Info("loaded asn from local cache", "count", len(memdb))
-	return true, memdb
+	// the cache file is rewritten by every build, so its age is the age of the listing
+	stat, err := os.Stat(cachedb.Filename(cachefilename))
+	if err == nil {
+		builtAt = stat.ModTime()
+	}
+	log.Info("loaded asn from local cache", "count", len(memdb), "built", builtAt)
+	return true, memdb, builtAt
 }
 
-func savefulldb(ctx context.Context, store asnstorer, db []asnCountryEntry) (err error) {
+// savefulldb writes the listing to the store in batches, so a refresh while the server is
+// running does not hold the store's write lock for the whole listing
+func savefulldb(ctx context.Context, store asnstorer, db []asnCountryEntry) error {
 	if store == nil {
 		return errors.New("asnstorer is nil")
 	}
+	for start := 0; start < len(db); start += saveBatchSize {
+		err := savebatch(ctx, store, db[start:min(start+saveBatchSize, len(db))])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+func savebatch(ctx context.Context, store asnstorer, batch []asnCountryEntry) (err error) {
 	fn := store.StartAsnLoad()
 	defer func() {
 		fn(&err)
 	}()
-	for _, entry := range db {
+	for _, entry := range batch {
 		err = store.UpsertAsn(ctx, fullToModelAsn(entry))
 		if err != nil {
 			return err
@@ -115,39 +148,61 @@ func builddb(
 	if err != nil {
 		return memdb, fulldb, err
 	}
-	var asndb []asnEntry
-	memdb, asndb = buildAsnDBs(asndat)
+	memdb, asndb, err := buildAsnDBs(asndat)
+	if err != nil {
+		return memdb, fulldb, err
+	}
 
 	countrydat, err := download(countryurl)
 	if err != nil {
 		return memdb, fulldb, err
 	}
-	ctdb := buildCtDB(countrydat)
+	ctdb, err := buildCtDB(countrydat)
+	if err != nil {
+		return memdb, fulldb, err
+	}
 
 	fulldb = buildFullDB(asndb, ctdb)
 
 	return memdb, fulldb, err
 }
 
+func isRemote(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+// download fetches the listing from the url, non http urls are read from the local filesystem
 func download(url string) (dat []byte, err error) {
+	if !isRemote(url) {
+		return os.ReadFile(strings.TrimPrefix(url, "file://"))
+	}
 	resp, err := http.Get(url)
 	if err != nil {
 		return dat, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return dat, fmt.Errorf("download %s: %s", url, resp.Status)
+	}
 	return io.ReadAll(resp.Body)
 }
 
-func buildCtDB(raw []byte) (db []ctentry) {
+func buildCtDB(raw []byte) (db []ctentry, err error) {
 	ctbuff := bytes.NewBuffer(raw)
 	ctcsvr := csv.NewReader(ctbuff)
 	ctrecs, err := ctcsvr.ReadAll()
 	if err != nil {
-		log.Fatal(err)
+		return db, err
 	}
 	db = make([]ctentry, len(ctrecs))
 	for idx, rec := range ctrecs {
-		rng := netipx.MustParseIPRange(rec[0] + "-" + rec[1])
+		if len(rec) < 3 {
+			return db, fmt.Errorf("country listing line %d: %w", idx+1, ErrInvalidListing)
+		}
+		rng, err := netipx.ParseIPRange(rec[0] + "-" + rec[1])
+		if err != nil {
+			return db, fmt.Errorf("country listing line %d: %w", idx+1, err)
+		}
 		db[idx] = ctentry{
 			Range:   rng,
 			Country: rec[2],
@@ -156,22 +211,28 @@ func buildCtDB(raw []byte) (db []ctentry) {
 	slices.SortFunc(db, func(a, b ctentry) int {
 		return a.Range.From().Compare(b.Range.From())
 	})
-	return db
+	return db, nil
 }
 
-func buildAsnDBs(raw []byte) (cachedb []CacheEntry, asndb []asnEntry) {
+func buildAsnDBs(raw []byte) (cachedb []CacheEntry, asndb []asnEntry, err error) {
 	asnbuff := bytes.NewBuffer(raw)
 	asncsvr := csv.NewReader(asnbuff)
 	asnrecs, err := asncsvr.ReadAll()
 	if err != nil {
-		log.Fatal(err)
+		return cachedb, asndb, err
 	}
 	asndb = make([]asnEntry, len(asnrecs))
 	cachedb = make([]CacheEntry, len(asnrecs))
 	for idx, rec := range asnrecs {
+		if len(rec) < 4 {
+			return cachedb, asndb, fmt.Errorf("asn listing line %d: %w", idx+1, ErrInvalidListing)
+		}
 		asn := rec[2]
 		name := rec[3]
-		rng := netipx.MustParseIPRange(rec[0] + "-" + rec[1])
+		rng, err := netipx.ParseIPRange(rec[0] + "-" + rec[1])
+		if err != nil {
+			return cachedb, asndb, fmt.Errorf("asn listing line %d: %w", idx+1, err)
+		}
 		asndb[idx] = asnEntry{
 			Asn:   asn,
 			Name:  name,
@@ -189,7 +250,7 @@ func buildAsnDBs(raw []byte) (cachedb []CacheEntry, asndb []asnEntry) {
 		return a.Range.From().Compare(b.Range.From())
 	})
 
-	return cachedb, asndb
+	return cachedb, asndb, nil
 }
 
 func buildFullDB(asndb []asnEntry, ctdb []ctentry) (full []asnCountryEntry) {
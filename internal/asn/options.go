@@ -10,6 +10,7 @@ type Options struct {
 	directory     string
 	cachefilename string
 	store         asnstorer
+	offline       bool
 }
 
 type Option func(*Options)
@@ -63,3 +64,10 @@ func WithStorer(x asnstorer) Option {
 		o.store = x
 	}
 }
+
+// WithOffline prevents any download of the asn/country listings, only local files are used
+func WithOffline(x bool) Option {
+	return func(o *Options) {
+		o.offline = x
+	}
+}
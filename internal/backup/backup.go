@@ -0,0 +1,276 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package backup writes and reads the backup archives of the datastore.  An archive is a
+// gzipped tar holding a manifest and a directory per store, each store snapshots its own files
+// into its directory and restores them from it.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// FormatVersion is the layout of the archive, raised when older versions can not read it
+	FormatVersion = 1
+
+	ManifestName = "manifest.json"
+
+	filePrefix = "mason-"
+	fileSuffix = ".tar.gz"
+)
+
+var (
+	ErrNoManifest     = errors.New("backup has no manifest")
+	ErrFormatTooNew   = errors.New("backup was written by a newer version of mason")
+	ErrSchemaTooNew   = errors.New("backup holds a store schema newer than this version of mason")
+	ErrInvalidArchive = errors.New("backup holds a file outside of the archive")
+)
+
+type (
+	// Manifest describes the archive and the stores in it
+	Manifest struct {
+		Format  int
+		Version string
+		Created time.Time
+		Stores  []Store
+	}
+
+	// Store is a store in the archive, its files are in the directory named after it
+	Store struct {
+		Name   string
+		Schema int
+		Files  int
+	}
+
+	// Snapshotter writes a consistent copy of the files of a store into a directory and
+	// returns the schema version of the copy, 0 for a store without a schema
+	Snapshotter interface {
+		Snapshot(ctx context.Context, dir string) (int, error)
+	}
+)
+
+// NewManifest returns the manifest of an archive written now by this version of mason
+func NewManifest(now time.Time) Manifest {
+	version := "dev_unknown"
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		version = bi.Main.Version
+	}
+	return Manifest{Format: FormatVersion, Version: version, Created: now.UTC()}
+}
+
+// Store returns the store of the manifest with the name
+func (m Manifest) Store(name string) (Store, bool) {
+	idx := slices.IndexFunc(m.Stores, func(s Store) bool { return s.Name == name })
+	if idx < 0 {
+		return Store{}, false
+	}
+	return m.Stores[idx], true
+}
+
+// Write archives the manifest and the files below dir
+func Write(w io.Writer, m Manifest, dir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	x, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    ManifestName,
+		Mode:    0o644,
+		Size:    int64(len(x)),
+		ModTime: m.Created,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(x)
+	if err != nil {
+		return err
+	}
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		err = tw.WriteHeader(hdr)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Read extracts the archive into dir and returns its manifest.  Archives of a newer format
+// are refused.
+func Read(r io.Reader, dir string) (Manifest, error) {
+	var m Manifest
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return m, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	found := false
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return m, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if hdr.Name == ManifestName {
+			err = json.NewDecoder(tr).Decode(&m)
+			if err != nil {
+				return m, fmt.Errorf("read manifest: %w", err)
+			}
+			found = true
+			continue
+		}
+		name := filepath.FromSlash(hdr.Name)
+		if !filepath.IsLocal(name) {
+			return m, fmt.Errorf("%w: %s", ErrInvalidArchive, hdr.Name)
+		}
+		err = writeFile(filepath.Join(dir, name), tr)
+		if err != nil {
+			return m, err
+		}
+	}
+	if !found {
+		return m, ErrNoManifest
+	}
+	if m.Format > FormatVersion {
+		return m, fmt.Errorf("%w: format %d", ErrFormatTooNew, m.Format)
+	}
+	return m, nil
+}
+
+// CopyFiles copies the files of src whose name matches into dst, a missing src has no files
+func CopyFiles(src string, dst string, match func(name string) bool) (int, error) {
+	entries, err := os.ReadDir(src)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, e := range entries {
+		if e.IsDir() || !match(e.Name()) {
+			continue
+		}
+		err := CopyFile(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name()))
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// CopyFile copies src to dst, creating the directory of dst
+func CopyFile(src string, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeFile(dst, f)
+}
+
+func writeFile(path string, r io.Reader) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Filename returns the name of an archive written at the time
+func Filename(t time.Time) string {
+	return filePrefix + t.UTC().Format("20060102-150405") + fileSuffix
+}
+
+// Rotate removes the oldest archives in dir, keeping the newest keep of them
+func Rotate(dir string, keep int) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var archives []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() && strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileSuffix) {
+			archives = append(archives, name)
+		}
+	}
+	if len(archives) <= keep {
+		return nil, nil
+	}
+	// the names sort by the time they were written
+	slices.Sort(archives)
+	removed := archives[:len(archives)-keep]
+	for _, name := range removed {
+		err := os.Remove(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return removed, nil
+}
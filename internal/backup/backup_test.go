@@ -0,0 +1,131 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteRead(t *testing.T) {
+	src := t.TempDir()
+	files := map[string]string{
+		"sqlite/mason.db":           "db",
+		"sqlite/archive/pings.gz":   "pings",
+		"whisper/10-0-0-1_ping.wsp": "wsp",
+	}
+	for name, content := range files {
+		path := filepath.Join(src, name)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := NewManifest(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	want.Stores = []Store{{Name: "sqlite", Schema: 30}, {Name: "whisper"}}
+
+	var buf bytes.Buffer
+	err := Write(&buf, want, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := t.TempDir()
+	got, err := Read(&buf, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("manifest (-want +got):\n%s", diff)
+	}
+	for name, content := range files {
+		x, err := os.ReadFile(filepath.Join(dst, name))
+		if err != nil || string(x) != content {
+			t.Errorf("%s want: %q, got: %q %v", name, content, x, err)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := map[string]struct {
+		files map[string]string
+		want  error
+	}{
+		"NoManifest": {
+			files: map[string]string{"sqlite/mason.db": "db"},
+			want:  ErrNoManifest,
+		},
+		"NewerFormat": {
+			files: map[string]string{ManifestName: `{"Format": 99}`},
+			want:  ErrFormatTooNew,
+		},
+		"OutsideArchive": {
+			files: map[string]string{ManifestName: `{"Format": 1}`, "../escape": "x"},
+			want:  ErrInvalidArchive,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			tw := tar.NewWriter(gz)
+			for name, content := range tc.files {
+				tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))})
+				tw.Write([]byte(content))
+			}
+			tw.Close()
+			gz.Close()
+
+			_, err := Read(&buf, t.TempDir())
+			if !errors.Is(err, tc.want) {
+				t.Errorf("want: %v, got: %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		err := os.WriteFile(filepath.Join(dir, Filename(start.AddDate(0, 0, i))), nil, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := Rotate(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{Filename(start), Filename(start.AddDate(0, 0, 1))}
+	if diff := cmp.Diff(want, removed); diff != "" {
+		t.Errorf("removed (-want +got):\n%s", diff)
+	}
+	entries, _ := os.ReadDir(dir)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if len(names) != 4 || !slices.Contains(names, "notes.txt") {
+		t.Errorf("want 3 backups and the other file, got %v", names)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package backup
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/networkables/mason/internal/flagset"
+)
+
+type Config struct {
+	Enabled   bool
+	Directory string
+	Interval  time.Duration
+	Keep      int
+}
+
+func SetFlags(fs *pflag.FlagSet, cfg *Config) {
+	configMajorKey := "backup"
+
+	flagset.Bool(
+		fs,
+		&cfg.Enabled,
+		configMajorKey,
+		"enabled",
+		false,
+		"regularly write a backup archive of the datastore",
+	)
+	flagset.String(
+		fs,
+		&cfg.Directory,
+		configMajorKey,
+		"directory",
+		"data/backup",
+		"directory the scheduled backups are written to",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Interval,
+		configMajorKey,
+		"interval",
+		24*time.Hour,
+		"time between scheduled backups",
+	)
+	flagset.Int(
+		fs,
+		&cfg.Keep,
+		configMajorKey,
+		"keep",
+		7,
+		"number of scheduled backups kept, older ones are removed",
+	)
+}
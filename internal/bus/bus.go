@@ -13,11 +13,14 @@ import (
 	"time"
 
 	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/networkables/mason/internal/discovery"
 	"github.com/networkables/mason/internal/enrichment"
 	"github.com/networkables/mason/internal/model"
 	"github.com/networkables/mason/internal/pinger"
+	"github.com/networkables/mason/internal/tracing"
 )
 
 type (
@@ -38,15 +41,18 @@ var _ Bus = (*memoryBus)(nil)
 
 type Bus interface {
 	AddListener() chan Event
+	Subscribe(size int) (chan Event, func())
 	Publish(Event)
 	Run(context.Context)
 	History() []HistoricalEvent
 	Errors() []HistoricalError
+	Stats() Stats
 }
 
 type memoryBus struct {
-	inbound          chan Event
+	queue            *queue
 	outbound         []chan Event
+	subscribers      map[chan Event]struct{}
 	lock             sync.Mutex
 	historicalEvents []HistoricalEvent
 	historicalErrors []HistoricalError
@@ -70,8 +76,9 @@ func New(cfg *Config) *memoryBus {
 		enableerrorlog:  cfg.EnableErrorLog,
 		minimumLogLevel: cfg.MinimumPriorityLevel,
 	}
-	bus.inbound = make(chan Event, cfg.InboundSize)
+	bus.queue = newQueue(cfg)
 	bus.outbound = make([]chan Event, 0)
+	bus.subscribers = make(map[chan Event]struct{})
 	bus.historicalEvents = make([]HistoricalEvent, 0, bus.maxhistory)
 	bus.historicalErrors = make([]HistoricalError, 0, bus.maxerrors)
 	return bus
@@ -85,14 +92,38 @@ func (b *memoryBus) AddListener() chan Event {
 	return ch
 }
 
+// Subscribe returns a channel receiving the events published after the call.  Unlike a
+// listener a subscriber never blocks the bus, events are dropped when its buffer is full.
+// The returned func ends the subscription and closes the channel.
+func (b *memoryBus) Subscribe(size int) (chan Event, func()) {
+	ch := make(chan Event, size)
+	b.lock.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.lock.Unlock()
+	return ch, func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish queues the event, it only waits with the block overflow policy
 func (b *memoryBus) Publish(e Event) {
 	if b.enableddebuglog {
 		log.Debugf("buseevent %T : %s", e, e)
 	}
-	b.inbound <- e
+	b.queue.push(e)
+}
+
+func (b *memoryBus) Stats() Stats {
+	return b.queue.Stats()
 }
 
-func classifyEvent(e Event) int {
+// Priority ranks the event, events below the minimum priority level are not kept in the history
+func Priority(e Event) int {
 	switch e.(type) {
 	case model.EventDeviceUpdated:
 		return 1
@@ -104,7 +135,13 @@ func classifyEvent(e Event) int {
 		return 10
 	case model.DiscoveredNetwork, discovery.DiscoverNetworksFromSNMPDevice:
 		return 11
-	case model.EventDeviceAdded, model.NetworkAddedEvent:
+	case model.EventDeviceAdded, model.NetworkAddedEvent, model.EventDevicePortsChanged,
+		model.EventDeviceNeedsReview, model.EventDeviceEdited, model.EventFlowAnomaly,
+		model.EventDeviceAddrChanged, model.EventHTTPCheckChanged, model.EventQuotaAlert,
+		model.EventServiceCheckChanged, model.EventDevicePingChanged, model.EventMACConflict,
+		model.EventRogueDHCP,
+		model.EventCaptureFinished, model.EventHookAlert, model.EventSyslogAlert,
+		discovery.EventNetworkScanStarted, discovery.EventNetworkScanFinished:
 		return 50
 	}
 	return 99
@@ -124,7 +161,7 @@ func (b *memoryBus) recordEvent(e Event) {
 		b.historicalErrors = append(b.historicalErrors, HistoricalError{E: err, Ts: ts})
 	} else {
 		if b.minimumLogLevel != 0 {
-			if classifyEvent(e) < b.minimumLogLevel {
+			if Priority(e) < b.minimumLogLevel {
 				return
 			}
 		}
@@ -139,20 +176,42 @@ func (b *memoryBus) recordEvent(e Event) {
 
 func (b *memoryBus) Run(ctx context.Context) {
 	for {
-		select {
-		case <-ctx.Done():
-			for _, ch := range b.outbound {
-				close(ch)
-			}
+		if ctx.Err() != nil {
+			b.stop()
 			return
-		case e := <-b.inbound:
-			b.recordEvent(e)
-			b.sendEvent(e)
-			// log.Debugf("sent event %T", e)
 		}
+		e, ok := b.queue.pop()
+		if !ok {
+			select {
+			case <-ctx.Done():
+			case <-b.queue.ready:
+			}
+			continue
+		}
+		_, span := tracing.Start(ctx, "bus.dispatch")
+		if span.IsRecording() {
+			span.SetAttributes(attribute.String("mason.event", fmt.Sprintf("%T", e)))
+		}
+		b.recordEvent(e)
+		b.sendEvent(e)
+		span.End()
+		// log.Debugf("sent event %T", e)
 	}
 }
 
+func (b *memoryBus) stop() {
+	for _, ch := range b.outbound {
+		close(ch)
+	}
+	b.lock.Lock()
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+	b.lock.Unlock()
+	b.queue.close()
+}
+
 func (b *memoryBus) sendEvent(e Event) {
 	// TODO: Need a watchdog on this incase a receive blocks up
 	//   - Or maybe a way to filter what is sent?
@@ -160,6 +219,14 @@ func (b *memoryBus) sendEvent(e Event) {
 	for _, outch := range b.outbound {
 		outch <- e
 	}
+	b.lock.Lock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	b.lock.Unlock()
 	// log.Debug("\tbus sendout end")
 }
 
@@ -185,6 +252,16 @@ func (he HistoricalEvent) Type() string {
 	return strings.Replace(fmt.Sprintf("%T", he.E), "mason.", "", 1)
 }
 
+// Record is the event as kept in the persistent event history
+func (he HistoricalEvent) Record() model.EventRecord {
+	return model.EventRecord{
+		Time:    he.Ts,
+		Kind:    model.EventRecordEvent,
+		Type:    he.Type(),
+		Message: fmt.Sprint(he.E),
+	}
+}
+
 func (he HistoricalError) FmtTime() string {
 	return he.Ts.Format(time.TimeOnly)
 }
@@ -192,3 +269,41 @@ func (he HistoricalError) FmtTime() string {
 func (he HistoricalError) Type() string {
 	return fmt.Sprintf("%T", he.E)
 }
+
+// Record is the error as kept in the persistent event history, a tracing error is kept as its
+// message, cause and context on one line without the stack
+func (he HistoricalError) Record() model.EventRecord {
+	rec := model.EventRecord{
+		Time:    he.Ts,
+		Kind:    model.EventRecordError,
+		Type:    he.Type(),
+		Message: he.E.Error(),
+	}
+	var te tre.TracingError
+	switch e := he.E.(type) {
+	case tre.TracingError:
+		te = e
+	case *tre.TracingError:
+		te = *e
+	default:
+		return rec
+	}
+	rec.Type = fmt.Sprintf("%T", te.Cause())
+	ctx := te.LoggingContext()
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v: %v", ctx["msg"], ctx["err"])
+	keys := make([]string, 0, len(ctx))
+	for k := range ctx {
+		switch k {
+		case "msg", "err", "err.type", "stack":
+		default:
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, ctx[k])
+	}
+	rec.Message = b.String()
+	return rec
+}
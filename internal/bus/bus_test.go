@@ -5,10 +5,16 @@
 package bus
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/emicklei/tre"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
 )
 
 func isMemoryBusEqual(a, b *memoryBus) bool {
@@ -47,13 +53,12 @@ func TestBus_New(t *testing.T) {
 			input: &Config{
 				MaxEvents:            1,
 				MaxErrors:            1,
-				InboundSize:          1,
+				QueueSize:            1,
 				EnableErrorLog:       true,
 				EnableDebugLog:       true,
 				MinimumPriorityLevel: 0,
 			},
 			want: &memoryBus{
-				inbound:          make(chan Event, 1),
 				outbound:         make([]chan Event, 0),
 				historicalEvents: make([]HistoricalEvent, 1),
 				historicalErrors: make([]HistoricalError, 1),
@@ -66,7 +71,6 @@ func TestBus_New(t *testing.T) {
 		"nil config new": {
 			input: nil,
 			want: &memoryBus{
-				inbound:          make(chan Event, 0),
 				outbound:         make([]chan Event, 0),
 				historicalEvents: make([]HistoricalEvent, 0),
 				historicalErrors: make([]HistoricalError, 0),
@@ -94,3 +98,75 @@ func TestBus_New(t *testing.T) {
 		}
 	}
 }
+
+func TestBus_Subscribe(t *testing.T) {
+	b := New(&Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	ch, unsubscribe := b.Subscribe(1)
+	slow, unsubscribeSlow := b.Subscribe(0)
+	defer unsubscribeSlow()
+
+	// a full subscriber must not hold up the bus
+	b.Publish("first")
+	b.Publish("second")
+
+	got := <-ch
+	if got != "first" {
+		t.Fatalf("want first event, got %v", got)
+	}
+	select {
+	case e := <-slow:
+		t.Fatalf("unbuffered subscriber received %v", e)
+	default:
+	}
+
+	unsubscribe()
+	unsubscribe()
+	// publish no longer waits for the dispatch, the second event may still be buffered
+	_, ok := <-ch
+	if ok {
+		_, ok = <-ch
+	}
+	if ok {
+		t.Fatal("channel still open after unsubscribe")
+	}
+	b.Publish("third")
+}
+
+func TestHistoricalError_Record(t *testing.T) {
+	ts := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		err  error
+		want model.EventRecord
+	}{
+		"Plain": {
+			err: errors.New("database is locked"),
+			want: model.EventRecord{
+				Time:    ts,
+				Kind:    model.EventRecordError,
+				Type:    "*errors.errorString",
+				Message: "database is locked",
+			},
+		},
+		"Tracing": {
+			err: tre.New(errors.New("invalid connection"), "icmp4 echo", "addr", "10.0.0.1"),
+			want: model.EventRecord{
+				Time:    ts,
+				Kind:    model.EventRecordError,
+				Type:    "*errors.errorString",
+				Message: "icmp4 echo: invalid connection addr=10.0.0.1",
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := HistoricalError{E: tc.err, Ts: ts}.Record()
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
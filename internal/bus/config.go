@@ -5,6 +5,8 @@
 package bus
 
 import (
+	"time"
+
 	"github.com/spf13/pflag"
 
 	"github.com/networkables/mason/internal/flagset"
@@ -13,7 +15,11 @@ import (
 type Config struct {
 	MaxEvents            int
 	MaxErrors            int
-	InboundSize          int
+	QueueSize            int
+	OverflowPolicy       string
+	BlockTimeout         time.Duration
+	SpillDirectory       string
+	SpillMaxEvents       int
 	MinimumPriorityLevel int
 	EnableDebugLog       bool
 	EnableErrorLog       bool
@@ -38,7 +44,46 @@ func SetFlags(fs *pflag.FlagSet, cfg *Config) {
 		100,
 		"max number of errors to retain",
 	)
-	flagset.Int(fs, &cfg.InboundSize, configMajorKey, "inboundsize", 0, "inbound channel size")
+	flagset.Int(
+		fs,
+		&cfg.QueueSize,
+		configMajorKey,
+		"queuesize",
+		10_000,
+		"events waiting to be dispatched before the overflow policy applies",
+	)
+	flagset.String(
+		fs,
+		&cfg.OverflowPolicy,
+		configMajorKey,
+		"overflowpolicy",
+		string(OverflowDropOldest),
+		"what to do with events once the queue is full: dropoldest, block or spill",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.BlockTimeout,
+		configMajorKey,
+		"blocktimeout",
+		time.Second,
+		"longest a publish waits for room with the block policy before the event is dropped",
+	)
+	flagset.String(
+		fs,
+		&cfg.SpillDirectory,
+		configMajorKey,
+		"spilldirectory",
+		"data/bus",
+		"directory of the overflow file of the spill policy",
+	)
+	flagset.Int(
+		fs,
+		&cfg.SpillMaxEvents,
+		configMajorKey,
+		"spillmaxevents",
+		1_000_000,
+		"events written to the overflow file before new events are dropped",
+	)
 	flagset.Int(
 		fs,
 		&cfg.MinimumPriorityLevel,
@@ -0,0 +1,182 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package bus
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// OverflowPolicy decides what happens to a published event when the queue is full
+	OverflowPolicy string
+
+	// Stats shows how well the bus keeps up with the published events
+	Stats struct {
+		Policy     OverflowPolicy
+		Capacity   int
+		Queued     int
+		Spilled    int
+		HighWater  int
+		Published  uint64
+		Dispatched uint64
+		Dropped    uint64
+		Blocked    uint64
+		SpillErr   string
+	}
+)
+
+const (
+	// OverflowDropOldest makes room by dropping the oldest queued event
+	OverflowDropOldest OverflowPolicy = "dropoldest"
+	// OverflowBlock makes the publisher wait for room, up to the block timeout
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowSpill writes the events past the queue size to a file, read back in order
+	OverflowSpill OverflowPolicy = "spill"
+
+	defaultQueueSize = 10_000
+)
+
+// queue holds the published events until the bus dispatches them
+type queue struct {
+	lock         sync.Mutex
+	events       []Event
+	capacity     int
+	policy       OverflowPolicy
+	blockTimeout time.Duration
+	spill        *spill
+	ready        chan struct{}
+	space        chan struct{}
+	stats        Stats
+}
+
+func newQueue(cfg *Config) *queue {
+	q := &queue{
+		capacity:     cfg.QueueSize,
+		policy:       OverflowPolicy(cfg.OverflowPolicy),
+		blockTimeout: cfg.BlockTimeout,
+		ready:        make(chan struct{}, 1),
+		space:        make(chan struct{}, 1),
+	}
+	if q.capacity <= 0 {
+		q.capacity = defaultQueueSize
+	}
+	switch q.policy {
+	case OverflowBlock, OverflowSpill:
+	default:
+		q.policy = OverflowDropOldest
+	}
+	if q.policy == OverflowSpill {
+		q.spill = newSpill(cfg.SpillDirectory, cfg.SpillMaxEvents)
+	}
+	q.events = make([]Event, 0, min(q.capacity, 1024))
+	q.stats.Policy = q.policy
+	q.stats.Capacity = q.capacity
+	return q
+}
+
+// push adds the event, applying the overflow policy when the queue is full
+func (q *queue) push(e Event) {
+	q.lock.Lock()
+	defer signal(q.ready)
+	defer q.lock.Unlock()
+	q.stats.Published++
+
+	switch q.policy {
+	case OverflowSpill:
+		if q.spill.count == 0 && len(q.events) < q.capacity {
+			break
+		}
+		// once spilling, events go to the file until it is read back to keep them in order
+		spilled := q.spill.count
+		err := q.spill.write(e)
+		if err != nil {
+			// a failed encode starts the file over, the events in it are lost as well
+			q.stats.Dropped += uint64(1 + spilled - q.spill.count)
+			q.stats.SpillErr = err.Error()
+		}
+		return
+	case OverflowBlock:
+		if len(q.events) < q.capacity {
+			break
+		}
+		q.stats.Blocked++
+		timer := time.NewTimer(q.blockTimeout)
+		defer timer.Stop()
+		for len(q.events) >= q.capacity {
+			q.lock.Unlock()
+			select {
+			case <-q.space:
+				q.lock.Lock()
+			case <-timer.C:
+				q.lock.Lock()
+				if len(q.events) >= q.capacity {
+					q.stats.Dropped++
+					return
+				}
+			}
+		}
+	default:
+		if len(q.events) >= q.capacity {
+			q.events[0] = nil
+			q.events = q.events[1:]
+			q.stats.Dropped++
+		}
+	}
+	q.events = append(q.events, e)
+	q.stats.HighWater = max(q.stats.HighWater, len(q.events))
+}
+
+// pop returns the oldest event, refilling from the spill file once the memory queue is empty
+func (q *queue) pop() (Event, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if len(q.events) == 0 && q.spill != nil {
+		for q.spill.count > 0 && len(q.events) < q.capacity {
+			e, err := q.spill.read()
+			if err != nil {
+				q.stats.Dropped++
+				q.stats.SpillErr = err.Error()
+				continue
+			}
+			q.events = append(q.events, e)
+		}
+	}
+	if len(q.events) == 0 {
+		return nil, false
+	}
+	e := q.events[0]
+	q.events[0] = nil
+	q.events = q.events[1:]
+	q.stats.Dispatched++
+	signal(q.space)
+	return e, true
+}
+
+func (q *queue) Stats() Stats {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	stats := q.stats
+	stats.Queued = len(q.events)
+	if q.spill != nil {
+		stats.Spilled = q.spill.count
+	}
+	return stats
+}
+
+func (q *queue) close() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.spill != nil {
+		q.spill.reset()
+	}
+}
+
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
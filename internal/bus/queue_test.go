@@ -0,0 +1,192 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package bus
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestQueue_Overflow(t *testing.T) {
+	tests := map[string]struct {
+		cfg   Config
+		input []Event
+		want  []Event
+		stats Stats
+	}{
+		"DropOldest": {
+			cfg:   Config{QueueSize: 2, OverflowPolicy: "dropoldest"},
+			input: []Event{"a", "b", "c"},
+			want:  []Event{"b", "c"},
+			stats: Stats{Published: 3, Dispatched: 2, Dropped: 1, HighWater: 2},
+		},
+		"UnknownPolicyDropsOldest": {
+			cfg:   Config{QueueSize: 1, OverflowPolicy: "bogus"},
+			input: []Event{"a", "b"},
+			want:  []Event{"b"},
+			stats: Stats{Published: 2, Dispatched: 1, Dropped: 1, HighWater: 1},
+		},
+		"BlockTimesOut": {
+			cfg:   Config{QueueSize: 2, OverflowPolicy: "block", BlockTimeout: time.Millisecond},
+			input: []Event{"a", "b", "c"},
+			want:  []Event{"a", "b"},
+			stats: Stats{Published: 3, Dispatched: 2, Dropped: 1, Blocked: 1, HighWater: 2},
+		},
+		"SpillKeepsOrder": {
+			cfg: Config{QueueSize: 2, OverflowPolicy: "spill", SpillMaxEvents: 10},
+			input: []Event{
+				"a",
+				"b",
+				"c",
+				errors.New("failed"),
+				model.EventDeviceDiscovered{Addr: model.MustParseAddr("192.168.1.1")},
+				"d",
+			},
+			want: []Event{
+				"a",
+				"b",
+				"c",
+				spilledError{Msg: "failed"},
+				model.EventDeviceDiscovered{Addr: model.MustParseAddr("192.168.1.1")},
+				"d",
+			},
+			stats: Stats{Published: 6, Dispatched: 6, HighWater: 2},
+		},
+		"SpillFull": {
+			cfg:   Config{QueueSize: 1, OverflowPolicy: "spill", SpillMaxEvents: 1},
+			input: []Event{"a", "b", "c"},
+			want:  []Event{"a", "b"},
+			stats: Stats{
+				Published:  3,
+				Dispatched: 2,
+				Dropped:    1,
+				HighWater:  1,
+				SpillErr:   ErrSpillFull.Error(),
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tc.cfg.SpillDirectory = t.TempDir()
+			q := newQueue(&tc.cfg)
+			for _, e := range tc.input {
+				q.push(e)
+			}
+			got := make([]Event, 0)
+			for {
+				e, ok := q.pop()
+				if !ok {
+					break
+				}
+				got = append(got, e)
+			}
+			opts := cmp.Options{
+				cmpopts.EquateComparable(model.Addr{}),
+				cmpopts.IgnoreUnexported(model.EventDeviceDiscovered{}),
+			}
+			if diff := cmp.Diff(tc.want, got, opts); diff != "" {
+				t.Errorf("events (-want +got):\n%s", diff)
+			}
+			stats := q.Stats()
+			if diff := cmp.Diff(
+				tc.stats,
+				stats,
+				cmpopts.IgnoreFields(Stats{}, "Policy", "Capacity"),
+			); diff != "" {
+				t.Errorf("stats (-want +got):\n%s", diff)
+			}
+			_, err := os.Stat(filepath.Join(tc.cfg.SpillDirectory, spillFilename))
+			if !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("spill file remains after the queue drained: %v", err)
+			}
+		})
+	}
+}
+
+func TestSpill_KeepsDeviceUpdated(t *testing.T) {
+	s := newSpill(t.TempDir(), 0)
+	dev := model.Device{Name: "router", Addr: model.MustParseAddr("192.168.1.1")}
+	dev.SetUpdated()
+	err := s.write(model.EventDeviceUpdated(dev))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := s.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := e.(model.EventDeviceUpdated)
+	if !ok {
+		t.Fatalf("want model.EventDeviceUpdated, got %T", e)
+	}
+	if got.Name != dev.Name || !model.Device(got).IsUpdated() {
+		t.Errorf("want updated device %s, got %+v", dev.Name, got)
+	}
+}
+
+// unencodable has no exported fields, gob refuses to encode it
+type unencodable struct {
+	ch chan int
+}
+
+func TestSpill_EncodeErrorStartsOver(t *testing.T) {
+	dir := t.TempDir()
+	s := newSpill(dir, 0)
+	if err := s.write("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.write(unencodable{}); err == nil {
+		t.Fatal("want an error for an unencodable event, got nil")
+	}
+	if s.count != 0 {
+		t.Errorf("count want: 0, got: %d", s.count)
+	}
+	if _, err := os.Stat(filepath.Join(dir, spillFilename)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("spill file remains after the failed encode: %v", err)
+	}
+	if err := s.write("b"); err != nil {
+		t.Fatal(err)
+	}
+	e, err := s.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e != "b" {
+		t.Errorf("want: b, got: %v", e)
+	}
+}
+
+func TestQueue_SpillEncodeErrorDropsSpilled(t *testing.T) {
+	q := newQueue(&Config{
+		QueueSize:      1,
+		OverflowPolicy: "spill",
+		SpillDirectory: t.TempDir(),
+	})
+	for _, e := range []Event{"a", "b", unencodable{}, "c"} {
+		q.push(e)
+	}
+	got := make([]Event, 0)
+	for {
+		e, ok := q.pop()
+		if !ok {
+			break
+		}
+		got = append(got, e)
+	}
+	if diff := cmp.Diff([]Event{"a", "c"}, got); diff != "" {
+		t.Errorf("events (-want +got):\n%s", diff)
+	}
+	if stats := q.Stats(); stats.Dropped != 2 || stats.SpillErr == "" {
+		t.Errorf("want 2 dropped with a spill error, got %d %q", stats.Dropped, stats.SpillErr)
+	}
+}
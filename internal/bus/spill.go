@@ -0,0 +1,134 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package bus
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const spillFilename = "events.spill"
+
+var ErrSpillFull = errors.New("bus spill file is full")
+
+// spill is the overflow file of the spill policy, events are gob encoded and only live as
+// long as the process: the file is removed once it has been read back
+type spill struct {
+	directory string
+	max       int
+	count     int
+	w         *os.File
+	r         *os.File
+	buf       bytes.Buffer
+	enc       *gob.Encoder
+	dec       *gob.Decoder
+}
+
+// spilledError replaces errors in the file, most error types have no exported fields
+type spilledError struct {
+	Msg string
+}
+
+func (se spilledError) Error() string {
+	return se.Msg
+}
+
+func newSpill(directory string, max int) *spill {
+	return &spill{directory: directory, max: max}
+}
+
+func (s *spill) write(e Event) (err error) {
+	if s.max > 0 && s.count >= s.max {
+		return ErrSpillFull
+	}
+	if s.w == nil {
+		err = s.open()
+		if err != nil {
+			return err
+		}
+	}
+	if err, ok := e.(error); ok {
+		e = spilledError{Msg: err.Error()}
+	}
+	defer func() {
+		// gob.Register panics on a type name registered for another type
+		if r := recover(); r != nil {
+			err = fmt.Errorf("spill %T: %v", e, r)
+		}
+	}()
+	defer s.buf.Reset()
+	gob.Register(e)
+	// the event is encoded whole before it is written, so the file only holds complete
+	// records.  A failed encode may leave types marked sent the reader never sees, the
+	// encoder and file start over, dropping the events spilled before.
+	err = s.enc.Encode(&e)
+	if err == nil {
+		_, err = s.w.Write(s.buf.Bytes())
+	}
+	if err != nil {
+		s.reset()
+		return err
+	}
+	s.count++
+	return nil
+}
+
+func (s *spill) read() (Event, error) {
+	var e Event
+	err := s.dec.Decode(&e)
+	s.count--
+	if s.count == 0 {
+		s.reset()
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the updated mark is unexported so it does not survive the file
+	if d, ok := e.(model.EventDeviceUpdated); ok {
+		dev := model.Device(d)
+		dev.SetUpdated()
+		e = model.EventDeviceUpdated(dev)
+	}
+	return e, nil
+}
+
+func (s *spill) open() error {
+	err := os.MkdirAll(s.directory, 0o755)
+	if err != nil {
+		return err
+	}
+	filename := filepath.Join(s.directory, spillFilename)
+	s.w, err = os.Create(filename)
+	if err != nil {
+		return err
+	}
+	s.r, err = os.Open(filename)
+	if err != nil {
+		s.w.Close()
+		s.w = nil
+		return err
+	}
+	s.enc = gob.NewEncoder(&s.buf)
+	s.dec = gob.NewDecoder(s.r)
+	return nil
+}
+
+// reset drops whatever is left in the file and removes it
+func (s *spill) reset() {
+	if s.w == nil {
+		return
+	}
+	s.w.Close()
+	s.r.Close()
+	os.Remove(s.w.Name())
+	s.w, s.r, s.enc, s.dec = nil, nil, nil, nil
+	s.count = 0
+}
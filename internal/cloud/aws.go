@@ -0,0 +1,346 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package cloud
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emicklei/tre"
+)
+
+const (
+	awsEc2Version   = "2016-11-15"
+	awsSigAlgorithm = "AWS4-HMAC-SHA256"
+	awsAmzDate      = "20060102T150405Z"
+)
+
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+type awsTag struct {
+	Key   string `xml:"key"`
+	Value string `xml:"value"`
+}
+
+type awsTags []awsTag
+
+func (tags awsTags) name() string {
+	for _, t := range tags {
+		if t.Key == "Name" {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+type awsSubnetsResponse struct {
+	Subnets []struct {
+		ID               string  `xml:"subnetId"`
+		VPC              string  `xml:"vpcId"`
+		CIDR             string  `xml:"cidrBlock"`
+		AvailabilityZone string  `xml:"availabilityZone"`
+		Tags             awsTags `xml:"tagSet>item"`
+	} `xml:"subnetSet>item"`
+	NextToken string `xml:"nextToken"`
+}
+
+type awsInterfacesResponse struct {
+	Interfaces []struct {
+		ID          string  `xml:"networkInterfaceId"`
+		VPC         string  `xml:"vpcId"`
+		Description string  `xml:"description"`
+		MAC         string  `xml:"macAddress"`
+		PrivateIP   string  `xml:"privateIpAddress"`
+		Instance    string  `xml:"attachment>instanceId"`
+		Tags        awsTags `xml:"tagSet>item"`
+	} `xml:"networkInterfaceSet>item"`
+	NextToken string `xml:"nextToken"`
+}
+
+func fetchAws(ctx context.Context, cfg *Config) (Inventory, error) {
+	creds, err := loadAwsCredentials(cfg.Aws.Profile)
+	if err != nil {
+		return Inventory{}, err
+	}
+	client := &http.Client{Timeout: cfg.Timeout}
+	inv := Inventory{Provider: ProviderAws}
+	for _, region := range cfg.Aws.Regions {
+		err = awsPages(ctx, client, creds, region, "DescribeSubnets", func(body []byte) (string, error) {
+			var resp awsSubnetsResponse
+			err := xml.Unmarshal(body, &resp)
+			if err != nil {
+				return "", err
+			}
+			for _, s := range resp.Subnets {
+				prefix, err := netip.ParsePrefix(s.CIDR)
+				if err != nil {
+					continue
+				}
+				inv.Subnets = append(inv.Subnets, Subnet{
+					ID:     s.ID,
+					Name:   s.Tags.name(),
+					VPC:    s.VPC,
+					Region: region,
+					Prefix: prefix,
+				})
+			}
+			return resp.NextToken, nil
+		})
+		if err != nil {
+			return inv, tre.New(err, "describe subnets", "region", region)
+		}
+		err = awsPages(ctx, client, creds, region, "DescribeNetworkInterfaces", func(body []byte) (string, error) {
+			var resp awsInterfacesResponse
+			err := xml.Unmarshal(body, &resp)
+			if err != nil {
+				return "", err
+			}
+			for _, i := range resp.Interfaces {
+				addr, err := netip.ParseAddr(i.PrivateIP)
+				if err != nil {
+					continue
+				}
+				mac, _ := net.ParseMAC(i.MAC)
+				inv.Interfaces = append(inv.Interfaces, Interface{
+					ID:       i.ID,
+					Name:     i.Tags.name(),
+					Instance: i.Instance,
+					VPC:      i.VPC,
+					Addr:     addr,
+					MAC:      mac,
+				})
+			}
+			return resp.NextToken, nil
+		})
+		if err != nil {
+			return inv, tre.New(err, "describe network interfaces", "region", region)
+		}
+	}
+	return inv, nil
+}
+
+// awsPages calls the ec2 action until the response has no next token
+func awsPages(
+	ctx context.Context,
+	client *http.Client,
+	creds awsCredentials,
+	region string,
+	action string,
+	page func([]byte) (string, error),
+) error {
+	token := ""
+	for {
+		form := url.Values{}
+		form.Set("Action", action)
+		form.Set("Version", awsEc2Version)
+		if token != "" {
+			form.Set("NextToken", token)
+		}
+		body := []byte(form.Encode())
+		endpoint := "https://ec2." + region + ".amazonaws.com/"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+		awsSign(req, body, creds, region, "ec2", time.Now())
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("ec2 %s: %s: %s", action, resp.Status, awsErrorMessage(data))
+		}
+		token, err = page(data)
+		if err != nil || token == "" {
+			return err
+		}
+	}
+}
+
+func awsErrorMessage(data []byte) string {
+	var resp struct {
+		Errors []struct {
+			Code    string
+			Message string
+		} `xml:"Errors>Error"`
+	}
+	if xml.Unmarshal(data, &resp) != nil || len(resp.Errors) == 0 {
+		return ""
+	}
+	return resp.Errors[0].Code + " " + resp.Errors[0].Message
+}
+
+// awsSign adds the signature version 4 authorization to the request, the host and every
+// x-amz header are signed
+func awsSign(
+	req *http.Request,
+	body []byte,
+	creds awsCredentials,
+	region string,
+	service string,
+	now time.Time,
+) {
+	amzdate := now.UTC().Format(awsAmzDate)
+	date := amzdate[:8]
+	req.Header.Set("X-Amz-Date", amzdate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-") {
+			headers[lk] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	bodyHash := sha256.Sum256(body)
+	canonical := strings.Join([]string{
+		req.Method,
+		path,
+		awsCanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	toSign := awsSigAlgorithm + "\n" + amzdate + "\n" + scope + "\n" +
+		hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsSigAlgorithm, creds.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func awsCanonicalQuery(q url.Values) string {
+	// url.Values.Encode sorts by key, aws wants spaces as %20
+	return strings.ReplaceAll(q.Encode(), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// loadAwsCredentials reads the keys from the environment, otherwise from the profile of the
+// shared credentials file
+func loadAwsCredentials(profile string) (awsCredentials, error) {
+	if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		return awsCredentials{
+			AccessKeyID:     id,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return awsCredentials{}, ErrNoCredentials
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return awsCredentials{}, tre.New(ErrNoCredentials, "open aws credentials", "path", path)
+	}
+	defer f.Close()
+	creds, ok := parseAwsCredentials(f, profile)
+	if !ok {
+		return awsCredentials{}, tre.New(ErrNoCredentials, "aws profile", "profile", profile)
+	}
+	return creds, nil
+}
+
+// parseAwsCredentials reads the section of the profile from an ini credentials file
+func parseAwsCredentials(r io.Reader, profile string) (awsCredentials, bool) {
+	var (
+		creds   awsCredentials
+		section string
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != profile {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = value
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = value
+		case "aws_session_token":
+			creds.SessionToken = value
+		}
+	}
+	return creds, creds.AccessKeyID != "" && creds.SecretAccessKey != ""
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package cloud imports the subnets and network interfaces of cloud vpcs, so the machines of
+// a hybrid environment are part of the same inventory as the local ones.  The provider apis
+// are called directly with the credentials their own tools use.
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	ProviderAws = "aws"
+	ProviderGcp = "gcp"
+
+	// DiscoverySource marks the devices imported from a cloud provider
+	DiscoverySource model.DiscoverySource = "CLOUD"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown cloud provider")
+	ErrNoCredentials   = errors.New("no cloud credentials found")
+
+	// CloudTag marks every network and device imported from a cloud provider
+	CloudTag = model.Tag{Val: "cloud"}
+)
+
+// Subnet is a vpc subnet as the provider reports it
+type Subnet struct {
+	ID     string
+	Name   string
+	VPC    string
+	Region string
+	Prefix netip.Prefix
+}
+
+// Interface is a network interface in a vpc, the instance is empty for interfaces of managed
+// services
+type Interface struct {
+	ID       string
+	Name     string
+	Instance string
+	VPC      string
+	Addr     netip.Addr
+	MAC      net.HardwareAddr
+}
+
+// Inventory is what a provider reports, turned into networks and devices by Networks and
+// Devices
+type Inventory struct {
+	Provider   string
+	Subnets    []Subnet
+	Interfaces []Interface
+}
+
+// Fetch reads the inventory of the provider
+func Fetch(ctx context.Context, cfg *Config, provider string) (Inventory, error) {
+	switch provider {
+	case ProviderAws:
+		return fetchAws(ctx, cfg)
+	case ProviderGcp:
+		return fetchGcp(ctx, cfg)
+	}
+	return Inventory{}, fmt.Errorf("%w: %s", ErrUnknownProvider, provider)
+}
+
+// Tags returns the tags of anything found in the vpc
+func (inv Inventory) Tags(vpc string) model.Tags {
+	tags := model.Tags{CloudTag, {Val: inv.Provider}}
+	if vpc != "" {
+		tags = append(tags, model.Tag{Val: inv.Provider + "-" + vpc})
+	}
+	return tags
+}
+
+// Networks returns a network for each IPv4 subnet
+func (inv Inventory) Networks() []model.Network {
+	networks := make([]model.Network, 0, len(inv.Subnets))
+	for _, s := range inv.Subnets {
+		if !s.Prefix.Addr().Is4() {
+			continue
+		}
+		n, err := model.New(inv.Provider+" "+s.ID, s.Prefix.String())
+		if err != nil {
+			continue
+		}
+		n.Tags = inv.Tags(s.VPC)
+		networks = append(networks, n)
+	}
+	return networks
+}
+
+// Devices returns a device for each network interface with an IPv4 address, named after the
+// interface, its instance or its id
+func (inv Inventory) Devices(now time.Time) []model.Device {
+	devices := make([]model.Device, 0, len(inv.Interfaces))
+	for _, i := range inv.Interfaces {
+		if !i.Addr.Is4() {
+			continue
+		}
+		name := i.Name
+		if name == "" {
+			name = i.Instance
+		}
+		if name == "" {
+			name = i.ID
+		}
+		devices = append(devices, model.Device{
+			Name:         name,
+			Addr:         model.AddrToModelAddr(i.Addr),
+			MAC:          model.HardwareAddrToMAC(i.MAC),
+			DiscoveredBy: DiscoverySource,
+			DiscoveredAt: now,
+			Meta:         model.Meta{Tags: slices.Clone(inv.Tags(i.VPC))},
+		})
+	}
+	return devices
+}
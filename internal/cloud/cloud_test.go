@@ -0,0 +1,236 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package cloud
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestAwsSign(t *testing.T) {
+	// get-vanilla of the aws signature version 4 test suite
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := awsCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	awsSign(req, nil, creds, "us-east-1", "service", now)
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, " +
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("authorization mismatch\nwant: %s\ngot:  %s", want, got)
+	}
+}
+
+func TestParseAwsCredentials(t *testing.T) {
+	file := `
+[default]
+aws_access_key_id = AKIDDEFAULT
+aws_secret_access_key = defaultsecret
+
+# a comment
+[work]
+aws_access_key_id=AKIDWORK
+aws_secret_access_key=worksecret
+aws_session_token=worktoken
+`
+	tests := map[string]struct {
+		profile string
+		want    awsCredentials
+		wantOk  bool
+	}{
+		"Default": {
+			profile: "default",
+			want:    awsCredentials{AccessKeyID: "AKIDDEFAULT", SecretAccessKey: "defaultsecret"},
+			wantOk:  true,
+		},
+		"SessionToken": {
+			profile: "work",
+			want: awsCredentials{
+				AccessKeyID:     "AKIDWORK",
+				SecretAccessKey: "worksecret",
+				SessionToken:    "worktoken",
+			},
+			wantOk: true,
+		},
+		"Missing": {
+			profile: "nope",
+			wantOk:  false,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := parseAwsCredentials(strings.NewReader(file), tc.profile)
+			if ok != tc.wantOk {
+				t.Fatalf("ok: want %v, got %v", tc.wantOk, ok)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAwsResponses(t *testing.T) {
+	subnets := `<DescribeSubnetsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <subnetSet>
+    <item>
+      <subnetId>subnet-1</subnetId>
+      <vpcId>vpc-a</vpcId>
+      <cidrBlock>10.0.1.0/24</cidrBlock>
+      <tagSet><item><key>Name</key><value>private</value></item></tagSet>
+    </item>
+  </subnetSet>
+  <nextToken>more</nextToken>
+</DescribeSubnetsResponse>`
+	var sresp awsSubnetsResponse
+	err := xml.Unmarshal([]byte(subnets), &sresp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sresp.Subnets) != 1 || sresp.Subnets[0].CIDR != "10.0.1.0/24" ||
+		sresp.Subnets[0].Tags.name() != "private" || sresp.NextToken != "more" {
+		t.Errorf("unexpected subnets response %+v", sresp)
+	}
+
+	interfaces := `<DescribeNetworkInterfacesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <networkInterfaceSet>
+    <item>
+      <networkInterfaceId>eni-1</networkInterfaceId>
+      <vpcId>vpc-a</vpcId>
+      <macAddress>02:00:00:00:00:01</macAddress>
+      <privateIpAddress>10.0.1.5</privateIpAddress>
+      <attachment><instanceId>i-1</instanceId></attachment>
+      <tagSet/>
+    </item>
+  </networkInterfaceSet>
+</DescribeNetworkInterfacesResponse>`
+	var iresp awsInterfacesResponse
+	err = xml.Unmarshal([]byte(interfaces), &iresp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(iresp.Interfaces) != 1 || iresp.Interfaces[0].Instance != "i-1" ||
+		iresp.Interfaces[0].PrivateIP != "10.0.1.5" || iresp.NextToken != "" {
+		t.Errorf("unexpected interfaces response %+v", iresp)
+	}
+}
+
+func TestGcpResponses(t *testing.T) {
+	subnetworks := `{"items":{
+  "regions/us-central1":{"subnetworks":[{
+    "name":"default",
+    "network":"https://www.googleapis.com/compute/v1/projects/p/global/networks/default",
+    "region":"https://www.googleapis.com/compute/v1/projects/p/regions/us-central1",
+    "ipCidrRange":"10.128.0.0/20"}]},
+  "regions/europe-west1":{"warning":{"code":"NO_RESULTS_ON_PAGE"}}}}`
+	var sresp gcpSubnetworksResponse
+	err := json.Unmarshal([]byte(subnetworks), &sresp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSubnets := []Subnet{{
+		ID:     "default",
+		Name:   "default",
+		VPC:    "default",
+		Region: "us-central1",
+		Prefix: netip.MustParsePrefix("10.128.0.0/20"),
+	}}
+	if diff := cmp.Diff(wantSubnets, sresp.subnets(), cmpopts.EquateComparable(netip.Prefix{})); diff != "" {
+		t.Errorf("subnets (-want +got):\n%s", diff)
+	}
+
+	instances := `{"items":{"zones/us-central1-a":{"instances":[{
+  "id":"123","name":"web",
+  "networkInterfaces":[{"name":"nic0",
+    "network":"https://www.googleapis.com/compute/v1/projects/p/global/networks/default",
+    "networkIP":"10.128.0.2"}]}]}},
+  "nextPageToken":"next"}`
+	var iresp gcpInstancesResponse
+	err = json.Unmarshal([]byte(instances), &iresp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantInterfaces := []Interface{{
+		ID:       "123/nic0",
+		Name:     "web",
+		Instance: "123",
+		VPC:      "default",
+		Addr:     netip.MustParseAddr("10.128.0.2"),
+	}}
+	if diff := cmp.Diff(wantInterfaces, iresp.interfaces(), cmpopts.EquateComparable(netip.Addr{})); diff != "" {
+		t.Errorf("interfaces (-want +got):\n%s", diff)
+	}
+	if iresp.NextPageToken != "next" {
+		t.Errorf("next page token: want next, got %q", iresp.NextPageToken)
+	}
+}
+
+func TestInventory(t *testing.T) {
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	mac, _ := net.ParseMAC("02:00:00:00:00:01")
+	inv := Inventory{
+		Provider: ProviderAws,
+		Subnets: []Subnet{
+			{ID: "subnet-1", VPC: "vpc-a", Prefix: netip.MustParsePrefix("10.0.1.0/24")},
+			{ID: "subnet-6", VPC: "vpc-a", Prefix: netip.MustParsePrefix("2600:1f18::/64")},
+		},
+		Interfaces: []Interface{
+			{ID: "eni-1", Name: "web", Instance: "i-1", VPC: "vpc-a", Addr: netip.MustParseAddr("10.0.1.5"), MAC: mac},
+			{ID: "eni-2", Instance: "i-2", VPC: "vpc-a", Addr: netip.MustParseAddr("10.0.1.6")},
+			{ID: "eni-3", VPC: "vpc-a", Addr: netip.MustParseAddr("10.0.1.7")},
+		},
+	}
+	tags := model.Tags{CloudTag, {Val: "aws"}, {Val: "aws-vpc-a"}}
+
+	network, err := model.New("aws subnet-1", "10.0.1.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	network.Tags = tags
+	opts := cmp.Options{
+		cmpopts.EquateComparable(netip.Addr{}, netip.Prefix{}),
+		cmpopts.IgnoreUnexported(model.Device{}),
+	}
+	if diff := cmp.Diff([]model.Network{network}, inv.Networks(), opts); diff != "" {
+		t.Errorf("networks (-want +got):\n%s", diff)
+	}
+
+	device := func(name string, addr string, mac net.HardwareAddr) model.Device {
+		return model.Device{
+			Name:         name,
+			Addr:         model.AddrToModelAddr(netip.MustParseAddr(addr)),
+			MAC:          model.HardwareAddrToMAC(mac),
+			DiscoveredBy: DiscoverySource,
+			DiscoveredAt: now,
+			Meta:         model.Meta{Tags: tags},
+		}
+	}
+	want := []model.Device{
+		device("web", "10.0.1.5", mac),
+		device("i-2", "10.0.1.6", nil),
+		device("eni-3", "10.0.1.7", nil),
+	}
+	if diff := cmp.Diff(want, inv.Devices(now), opts); diff != "" {
+		t.Errorf("devices (-want +got):\n%s", diff)
+	}
+}
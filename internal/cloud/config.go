@@ -0,0 +1,110 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package cloud
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/networkables/mason/internal/flagset"
+)
+
+type (
+	Config struct {
+		Enabled   bool
+		Interval  time.Duration
+		Timeout   time.Duration
+		Providers []string
+		Aws       *AwsConfig
+		Gcp       *GcpConfig
+	}
+
+	AwsConfig struct {
+		Regions []string
+		Profile string
+	}
+
+	GcpConfig struct {
+		Project         string
+		CredentialsFile string
+	}
+)
+
+func SetFlags(fs *pflag.FlagSet, cfg *Config) {
+	cfg.Aws = &AwsConfig{}
+	cfg.Gcp = &GcpConfig{}
+
+	configMajorKey := "cloud"
+
+	flagset.Bool(
+		fs,
+		&cfg.Enabled,
+		configMajorKey,
+		"enabled",
+		false,
+		"regularly import the subnets and network interfaces of the cloud providers",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Interval,
+		configMajorKey,
+		"interval",
+		6*time.Hour,
+		"time between scheduled cloud imports",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Timeout,
+		configMajorKey,
+		"timeout",
+		30*time.Second,
+		"timeout of a cloud api request",
+	)
+	flagset.StringSlice(
+		fs,
+		&cfg.Providers,
+		configMajorKey,
+		"providers",
+		[]string{ProviderAws},
+		"cloud providers of the scheduled import [aws,gcp]",
+	)
+
+	awsConfigMajorKey := flagset.Key(configMajorKey, "aws")
+	flagset.StringSlice(
+		fs,
+		&cfg.Aws.Regions,
+		awsConfigMajorKey,
+		"regions",
+		[]string{"us-east-1"},
+		"aws regions to import the vpcs of",
+	)
+	flagset.String(
+		fs,
+		&cfg.Aws.Profile,
+		awsConfigMajorKey,
+		"profile",
+		"",
+		"profile of ~/.aws/credentials, empty uses $AWS_PROFILE or default, the AWS_ACCESS_KEY_ID environment takes precedence",
+	)
+
+	gcpConfigMajorKey := flagset.Key(configMajorKey, "gcp")
+	flagset.String(
+		fs,
+		&cfg.Gcp.Project,
+		gcpConfigMajorKey,
+		"project",
+		"",
+		"gcp project to import the vpcs of",
+	)
+	flagset.String(
+		fs,
+		&cfg.Gcp.CredentialsFile,
+		gcpConfigMajorKey,
+		"credentialsfile",
+		"",
+		"service account key file, empty uses $GOOGLE_APPLICATION_CREDENTIALS or the metadata server",
+	)
+}
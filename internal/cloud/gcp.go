@@ -0,0 +1,363 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package cloud
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/emicklei/tre"
+)
+
+const (
+	gcpComputeURL   = "https://compute.googleapis.com/compute/v1/projects/"
+	gcpMetadataURL  = "http://metadata.google.internal/computeMetadata/v1/"
+	gcpComputeScope = "https://www.googleapis.com/auth/compute.readonly"
+)
+
+var ErrInvalidServiceAccount = errors.New("invalid gcp service account key")
+
+type gcpServiceAccount struct {
+	ProjectID   string `json:"project_id"`
+	PrivateKey  string `json:"private_key"`
+	ClientEmail string `json:"client_email"`
+	TokenURI    string `json:"token_uri"`
+}
+
+type gcpSubnetworksResponse struct {
+	Items map[string]struct {
+		Subnetworks []struct {
+			Name        string
+			Network     string
+			Region      string
+			IPCidrRange string `json:"ipCidrRange"`
+		}
+	}
+	NextPageToken string
+}
+
+type gcpInstancesResponse struct {
+	Items map[string]struct {
+		Instances []struct {
+			ID                string `json:"id"`
+			Name              string
+			NetworkInterfaces []struct {
+				Name      string
+				Network   string
+				NetworkIP string `json:"networkIP"`
+			}
+		}
+	}
+	NextPageToken string
+}
+
+type gcpClient struct {
+	client  *http.Client
+	token   string
+	project string
+}
+
+func fetchGcp(ctx context.Context, cfg *Config) (Inventory, error) {
+	client := &http.Client{Timeout: cfg.Timeout}
+	gc, err := newGcpClient(ctx, client, cfg.Gcp)
+	if err != nil {
+		return Inventory{}, err
+	}
+	inv := Inventory{Provider: ProviderGcp}
+
+	err = gc.pages(ctx, "aggregated/subnetworks", func(data []byte) (string, error) {
+		var resp gcpSubnetworksResponse
+		err := json.Unmarshal(data, &resp)
+		if err != nil {
+			return "", err
+		}
+		inv.Subnets = append(inv.Subnets, resp.subnets()...)
+		return resp.NextPageToken, nil
+	})
+	if err != nil {
+		return inv, tre.New(err, "list subnetworks", "project", gc.project)
+	}
+	err = gc.pages(ctx, "aggregated/instances", func(data []byte) (string, error) {
+		var resp gcpInstancesResponse
+		err := json.Unmarshal(data, &resp)
+		if err != nil {
+			return "", err
+		}
+		inv.Interfaces = append(inv.Interfaces, resp.interfaces()...)
+		return resp.NextPageToken, nil
+	})
+	if err != nil {
+		return inv, tre.New(err, "list instances", "project", gc.project)
+	}
+	return inv, nil
+}
+
+func (resp gcpSubnetworksResponse) subnets() []Subnet {
+	subnets := make([]Subnet, 0)
+	for _, scope := range resp.Items {
+		for _, s := range scope.Subnetworks {
+			prefix, err := netip.ParsePrefix(s.IPCidrRange)
+			if err != nil {
+				continue
+			}
+			subnets = append(subnets, Subnet{
+				ID:     s.Name,
+				Name:   s.Name,
+				VPC:    path.Base(s.Network),
+				Region: path.Base(s.Region),
+				Prefix: prefix,
+			})
+		}
+	}
+	return subnets
+}
+
+// interfaces returns the network interfaces of the instances, gcp does not report mac addresses
+func (resp gcpInstancesResponse) interfaces() []Interface {
+	interfaces := make([]Interface, 0)
+	for _, scope := range resp.Items {
+		for _, instance := range scope.Instances {
+			for _, nic := range instance.NetworkInterfaces {
+				addr, err := netip.ParseAddr(nic.NetworkIP)
+				if err != nil {
+					continue
+				}
+				interfaces = append(interfaces, Interface{
+					ID:       instance.ID + "/" + nic.Name,
+					Name:     instance.Name,
+					Instance: instance.ID,
+					VPC:      path.Base(nic.Network),
+					Addr:     addr,
+				})
+			}
+		}
+	}
+	return interfaces
+}
+
+// newGcpClient gets an access token from the service account key given or named by
+// GOOGLE_APPLICATION_CREDENTIALS, otherwise from the metadata server of the instance mason
+// runs on
+func newGcpClient(ctx context.Context, client *http.Client, cfg *GcpConfig) (*gcpClient, error) {
+	gc := &gcpClient{client: client, project: cfg.Project}
+	file := cfg.CredentialsFile
+	if file == "" {
+		file = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	var err error
+	if file != "" {
+		var sa gcpServiceAccount
+		sa, err = readGcpServiceAccount(file)
+		if err != nil {
+			return nil, err
+		}
+		if gc.project == "" {
+			gc.project = sa.ProjectID
+		}
+		gc.token, err = gc.serviceAccountToken(ctx, sa, time.Now())
+	} else {
+		gc.token, err = gc.metadataToken(ctx)
+		if err == nil && gc.project == "" {
+			var project []byte
+			project, err = gc.metadata(ctx, "project/project-id")
+			gc.project = string(project)
+		}
+	}
+	if err != nil {
+		return nil, tre.New(err, "gcp access token")
+	}
+	if gc.project == "" {
+		return nil, tre.New(ErrNoCredentials, "gcp project not set")
+	}
+	return gc, nil
+}
+
+func readGcpServiceAccount(file string) (gcpServiceAccount, error) {
+	var sa gcpServiceAccount
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return sa, tre.New(ErrNoCredentials, "open gcp credentials", "path", file)
+	}
+	err = json.Unmarshal(data, &sa)
+	if err != nil {
+		return sa, tre.New(err, "parse gcp credentials", "path", file)
+	}
+	if sa.PrivateKey == "" || sa.ClientEmail == "" {
+		return sa, tre.New(ErrInvalidServiceAccount, "missing key or email", "path", file)
+	}
+	if sa.TokenURI == "" {
+		sa.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	return sa, nil
+}
+
+// serviceAccountToken exchanges a jwt signed with the key of the service account for an
+// access token
+func (gc *gcpClient) serviceAccountToken(
+	ctx context.Context,
+	sa gcpServiceAccount,
+	now time.Time,
+) (string, error) {
+	assertion, err := gcpJWT(sa, now)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		sa.TokenURI,
+		strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return gc.tokenResponse(req)
+}
+
+func (gc *gcpClient) metadataToken(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		gcpMetadataURL+"instance/service-accounts/default/token",
+		nil,
+	)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	token, err := gc.tokenResponse(req)
+	if err != nil {
+		return "", tre.New(ErrNoCredentials, "metadata server", "err", err)
+	}
+	return token, nil
+}
+
+func (gc *gcpClient) tokenResponse(req *http.Request) (string, error) {
+	resp, err := gc.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token %s: %s", req.URL, resp.Status)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+func (gc *gcpClient) metadata(ctx context.Context, key string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataURL+key, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := gc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata %s: %s", key, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// pages calls the compute api until the response has no next page token
+func (gc *gcpClient) pages(
+	ctx context.Context,
+	resource string,
+	page func([]byte) (string, error),
+) error {
+	token := ""
+	for {
+		u := gcpComputeURL + url.PathEscape(gc.project) + "/" + resource
+		if token != "" {
+			u += "?pageToken=" + url.QueryEscape(token)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+gc.token)
+		resp, err := gc.client.Do(req)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("compute %s: %s", resource, resp.Status)
+		}
+		token, err = page(data)
+		if err != nil || token == "" {
+			return err
+		}
+	}
+}
+
+// gcpJWT returns the RS256 signed assertion for the compute read only scope
+func gcpJWT(sa gcpServiceAccount, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return "", ErrInvalidServiceAccount
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return "", tre.New(ErrInvalidServiceAccount, "parse private key", "err", err)
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", ErrInvalidServiceAccount
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iss":   sa.ClientEmail,
+		"scope": gcpComputeScope,
+		"aud":   sa.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build linux || freebsd || openbsd || darwin
+
+package combostore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/networkables/mason/internal/backup"
+)
+
+// Snapshot dumps the records as msgpack files into dir, with the whisper files of the
+// performance pings unless another engine keeps them.  The store has no schema.
+func (cs *Store) Snapshot(ctx context.Context, dir string) (int, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return 0, err
+	}
+	for filename, records := range map[string]any{
+		cs.networkfilename: cs.networks,
+		cs.devicefilename:  cs.devices,
+		cs.annotationfile:  cs.annotations,
+		cs.reservationfile: cs.reservations,
+		cs.tagfile:         cs.tags,
+		cs.sitefile:        cs.sites,
+		cs.healthfile:      cs.health,
+		cs.speedtestfile:   cs.speedtests,
+		cs.eventfile:       cs.events,
+		cs.tombstonefile:   cs.tombstones,
+		cs.maintenancefile: cs.maintenance,
+		cs.changefile:      cs.changes,
+		cs.httpcheckfile:   cs.httpchecks,
+		cs.httpresultfile:  cs.httpresults,
+		cs.serviceresfile:  cs.serviceresults,
+		cs.macbindingfile:  cs.macbindings,
+		cs.dhcpfile:        cs.dhcpsightings,
+		cs.quotafile:       cs.quotas,
+		cs.exclusionfile:   cs.exclusions,
+		cs.dependencyfile:  cs.dependencies,
+		cs.portprofilefile: cs.portprofiles,
+		cs.fullscanfile:    cs.fullscans,
+	} {
+		bytes, err := msgpack.Marshal(records)
+		if err != nil {
+			return 0, err
+		}
+		err = os.WriteFile(filepath.Join(dir, filename), bytes, 0644)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if cs.externalts {
+		return 0, nil
+	}
+	return cs.wsp.Snapshot(ctx, dir)
+}
+
+// Restore replaces the msgpack and whisper files of the configuration with the snapshot in dir,
+// the device journal is dropped as its changes belong to the replaced devices
+func Restore(cfg *Config, dir string) error {
+	_, err := backup.CopyFiles(dir, cfg.Directory, func(string) bool { return true })
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filepath.Join(cfg.Directory, journalFilename))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -9,29 +9,75 @@ package combostore
 import (
 	"context"
 	"errors"
-	"fmt"
-	"math"
 	"os"
+	"path/filepath"
 	"slices"
 	"strings"
 	"time"
 
-	"github.com/charmbracelet/log"
-	whisper "github.com/go-graphite/go-whisper"
 	"github.com/vmihailenco/msgpack/v5"
 
+	"github.com/networkables/mason/internal/logging"
 	"github.com/networkables/mason/internal/model"
 	"github.com/networkables/mason/internal/pinger"
+	"github.com/networkables/mason/internal/tsstore"
 	"github.com/networkables/mason/nettools"
 )
 
+var logger = logging.For(logging.Store)
+
 type Store struct {
 	directory       string
-	retentions      whisper.Retentions
+	wsp             *tsstore.Whisper
+	externalts      bool
 	networkfilename string
 	devicefilename  string
+	annotationfile  string
+	reservationfile string
+	tagfile         string
+	sitefile        string
+	healthfile      string
+	speedtestfile   string
+	eventfile       string
+	tombstonefile   string
+	maintenancefile string
+	changefile      string
+	httpcheckfile   string
+	httpresultfile  string
+	serviceresfile  string
+	macbindingfile  string
+	dhcpfile        string
+	quotafile       string
+	exclusionfile   string
+	dependencyfile  string
+	portprofilefile string
+	fullscanfile    string
+	journalfile     string
+	journal         *os.File
+	journalEntries  int
+	journalCompact  int
 	networks        []model.Network
 	devices         []model.Device
+	annotations     []model.Annotation
+	reservations    []model.Reservation
+	tags            []model.TagDefinition
+	sites           []model.Site
+	health          []model.HealthProbe
+	speedtests      []model.SpeedTest
+	events          []model.EventRecord
+	tombstones      []model.Tombstone
+	maintenance     []model.MaintenanceWindow
+	changes         []model.DeviceChange
+	httpchecks      []model.HTTPCheck
+	httpresults     []model.HTTPCheckResult
+	serviceresults  []model.ServiceCheckResult
+	macbindings     []model.MACBinding
+	dhcpsightings   []model.DHCPSighting
+	quotas          []model.BandwidthQuota
+	exclusions      []model.Exclusion
+	dependencies    []model.Dependency
+	portprofiles    []model.PortProfile
+	fullscans       []model.FullPortScan
 }
 
 // var _ model.Storer = (*Store)(nil)
@@ -39,13 +85,40 @@ type Store struct {
 func New(cfg *Config) (*Store, error) {
 	cs := &Store{
 		directory:       cfg.Directory,
-		retentions:      whisper.MustParseRetentionDefs(cfg.WSPRetention),
 		networkfilename: "networks.mb",
 		devicefilename:  "devices.mb",
+		annotationfile:  "annotations.mb",
+		reservationfile: "reservations.mb",
+		tagfile:         "tags.mb",
+		sitefile:        "sites.mb",
+		healthfile:      "health.mb",
+		speedtestfile:   "speedtests.mb",
+		eventfile:       "events.mb",
+		tombstonefile:   "tombstones.mb",
+		maintenancefile: "maintenance.mb",
+		changefile:      "changes.mb",
+		httpcheckfile:   "httpchecks.mb",
+		httpresultfile:  "httpcheckresults.mb",
+		serviceresfile:  "servicecheckresults.mb",
+		macbindingfile:  "macbindings.mb",
+		dhcpfile:        "dhcpsightings.mb",
+		quotafile:       "quotas.mb",
+		exclusionfile:   "exclusions.mb",
+		dependencyfile:  "dependencies.mb",
+		portprofilefile: "portprofiles.mb",
+		fullscanfile:    "fullportscans.mb",
+		journalfile:     journalFilename,
+		journalCompact:  cfg.JournalCompact,
+		externalts:      cfg.ExternalTimeseries,
 	}
 
 	cs.ensureDirectory(cfg.Directory)
-	err := cs.readNetworks()
+	wsp, err := tsstore.NewWhisper(cfg.Directory, cfg.WSPRetention)
+	if err != nil {
+		return nil, err
+	}
+	cs.wsp = wsp
+	err = cs.readNetworks()
 	if err != nil {
 		return nil, err
 	}
@@ -53,12 +126,116 @@ func New(cfg *Config) (*Store, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = cs.replayJournal()
+	if err != nil {
+		return nil, err
+	}
+	if cs.journalEntries > 0 {
+		err = cs.saveDevices()
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = cs.readAnnotations()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readReservations()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readTags()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readSites()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readHealthProbes()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readSpeedTests()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readEvents()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readTombstones()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readMaintenance()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readChanges()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readHTTPChecks()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readHTTPCheckResults()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readServiceCheckResults()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readMACBindings()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readDHCPSightings()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readQuotas()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readExclusions()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readDependencies()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readPortProfiles()
+	if err != nil {
+		return nil, err
+	}
+	err = cs.readFullPortScans()
+	if err != nil {
+		return nil, err
+	}
 
 	return cs, nil
 }
 
+// Ping checks the data directory can still be written
+func (cs *Store) Ping(ctx context.Context) error {
+	f, err := os.CreateTemp(cs.directory, ".ping*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// Close writes the journaled device changes to the devices file
 func (cs *Store) Close() error {
-	return nil
+	if cs.journalEntries > 0 {
+		return cs.saveDevices()
+	}
+	return cs.truncateJournal()
 }
 
 //
@@ -155,7 +332,7 @@ func (cs *Store) saveNetworks() error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(cs.directory+"/"+cs.networkfilename, bytes, 0644)
+	return writeFileAtomic(filepath.Join(cs.directory, cs.networkfilename), bytes)
 }
 
 func (cs *Store) readNetworks() error {
@@ -182,7 +359,7 @@ func (cs *Store) AddDevice(ctx context.Context, newdevice model.Device) error {
 		}
 	}
 	cs.devices = append(cs.devices, newdevice)
-	return cs.saveDevices()
+	return cs.journalDevice(newdevice)
 }
 
 // RemoveDeviceByAddr will remove the device with the given Addr from the store
@@ -190,7 +367,7 @@ func (cs *Store) RemoveDeviceByAddr(ctx context.Context, addr model.Addr) error
 	for idx, device := range cs.devices {
 		if device.Addr.Compare(addr) == 0 {
 			cs.devices = slices.Delete(cs.devices, idx, idx+1)
-			return cs.saveDevices()
+			return cs.journalRemove(addr)
 		}
 	}
 	return model.ErrDeviceDoesNotExist
@@ -208,12 +385,79 @@ func (cs *Store) UpdateDevice(
 		if device.Addr.Compare(newdevice.Addr) == 0 {
 			enrich = device.MAC.Compare(newdevice.MAC) != 0
 			cs.devices[idx] = cs.devices[idx].Merge(newdevice)
-			return enrich, cs.saveDevices()
+			return enrich, cs.journalDevice(cs.devices[idx])
 		}
 	}
 	return enrich, model.ErrDeviceDoesNotExist
 }
 
+// SetDeviceTags replaces the tags of the device
+func (cs *Store) SetDeviceTags(ctx context.Context, addr model.Addr, tags model.Tags) error {
+	for idx, device := range cs.devices {
+		if device.Addr.Compare(addr) == 0 {
+			cs.devices[idx].Meta.Tags = slices.Clone(tags)
+			return cs.journalDevice(cs.devices[idx])
+		}
+	}
+	return model.ErrDeviceDoesNotExist
+}
+
+// SetDeviceParent sets the host the device runs on as a guest, an empty addr clears it
+func (cs *Store) SetDeviceParent(ctx context.Context, addr model.Addr, parent model.Addr) error {
+	for idx, device := range cs.devices {
+		if device.Addr.Compare(addr) == 0 {
+			cs.devices[idx].Virtual.Parent = parent
+			return cs.journalDevice(cs.devices[idx])
+		}
+	}
+	return model.ErrDeviceDoesNotExist
+}
+
+// SetDevicePolicy replaces the monitoring policy of the device
+func (cs *Store) SetDevicePolicy(
+	ctx context.Context,
+	addr model.Addr,
+	policy model.MonitoringPolicy,
+) error {
+	for idx, device := range cs.devices {
+		if device.Addr.Compare(addr) == 0 {
+			cs.devices[idx].Meta.Policy = policy
+			return cs.journalDevice(cs.devices[idx])
+		}
+	}
+	return model.ErrDeviceDoesNotExist
+}
+
+// SetDeviceApproval replaces the approval state of the device
+func (cs *Store) SetDeviceApproval(
+	ctx context.Context,
+	addr model.Addr,
+	state model.ApprovalState,
+) error {
+	for idx, device := range cs.devices {
+		if device.Addr.Compare(addr) == 0 {
+			cs.devices[idx].Meta.Approval = state
+			return cs.journalDevice(cs.devices[idx])
+		}
+	}
+	return model.ErrDeviceDoesNotExist
+}
+
+// SetDeviceDetails replaces the name, owner, notes and asset fields of the device
+func (cs *Store) SetDeviceDetails(
+	ctx context.Context,
+	addr model.Addr,
+	details model.DeviceDetails,
+) error {
+	for idx, device := range cs.devices {
+		if device.Addr.Compare(addr) == 0 {
+			cs.devices[idx] = device.WithDetails(details)
+			return cs.journalDevice(cs.devices[idx])
+		}
+	}
+	return model.ErrDeviceDoesNotExist
+}
+
 // GetDeviceByAddr returns the device with the matching Addr
 func (cs *Store) GetDeviceByAddr(
 	ctx context.Context,
@@ -227,6 +471,33 @@ func (cs *Store) GetDeviceByAddr(
 	return model.Device{}, model.ErrDeviceDoesNotExist
 }
 
+// GetDeviceByMAC returns the first stored device seen with the MAC
+func (cs *Store) GetDeviceByMAC(ctx context.Context, mac model.MAC) (model.Device, error) {
+	if mac.IsEmpty() {
+		return model.Device{}, model.ErrDeviceDoesNotExist
+	}
+	for _, device := range cs.devices {
+		if device.HasMAC(mac) {
+			return device, nil
+		}
+	}
+	return model.Device{}, model.ErrDeviceDoesNotExist
+}
+
+// FindDevicesByName returns the devices named name, ignoring case
+func (cs *Store) FindDevicesByName(ctx context.Context, name string) []model.Device {
+	devices := make([]model.Device, 0)
+	if name == "" {
+		return devices
+	}
+	for _, device := range cs.devices {
+		if strings.EqualFold(device.Name, name) {
+			devices = append(devices, device)
+		}
+	}
+	return devices
+}
+
 // GetFilteredDevices returns the devices which match the given GetFilteredDevices
 func (cs *Store) GetFilteredDevices(
 	ctx context.Context,
@@ -241,6 +512,11 @@ func (cs *Store) GetFilteredDevices(
 	return devices
 }
 
+// QueryDevices returns one page of the devices matching the query
+func (cs *Store) QueryDevices(ctx context.Context, q model.DeviceQuery) model.DevicePage {
+	return model.QueryDevices(cs.devices, q)
+}
+
 // ListDevices returns all the stored devices
 func (cs *Store) ListDevices(ctx context.Context) []model.Device {
 	return slices.Clone(cs.devices)
@@ -251,12 +527,17 @@ func (cs *Store) CountDevices(ctx context.Context) int {
 	return len(cs.devices)
 }
 
+// saveDevices rewrites the devices file, the journal is emptied once the file is in place
 func (cs *Store) saveDevices() error {
 	bytes, err := msgpack.Marshal(cs.devices)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(cs.directory+"/"+cs.devicefilename, bytes, 0644)
+	err = writeFileAtomic(filepath.Join(cs.directory, cs.devicefilename), bytes)
+	if err != nil {
+		return err
+	}
+	return cs.truncateJournal()
 }
 
 func (cs *Store) readDevices() error {
@@ -272,186 +553,1206 @@ func (cs *Store) readDevices() error {
 }
 
 //
-// Timeseries data
+// Annotation data
 //
 
-// WritePoint stores a given point for a device
-func (cs *Store) WritePerformancePing(
+// AddAnnotation stores an annotation
+func (cs *Store) AddAnnotation(ctx context.Context, a model.Annotation) error {
+	cs.annotations = append(cs.annotations, a)
+	return cs.saveAnnotations()
+}
+
+// ReadAnnotations returns the annotations for the addr, including global annotations,
+// from Now() minus the duration
+func (cs *Store) ReadAnnotations(
 	ctx context.Context,
-	timestamp time.Time,
-	device model.Device,
-	point nettools.Icmp4EchoResponseStatistics,
-) error {
-	avgfilename := cs.wspfilename(cs.directory, device.Addr, "pingavg")
-	avgwsp, err := cs.openwsp(avgfilename)
-	if err != nil {
-		return err
-	}
-	err = avgwsp.Update(float64(point.Mean/time.Millisecond), cs.timeToWspTime(timestamp))
-	if err != nil {
-		return err
-	}
-	err = avgwsp.Close()
-	if err != nil {
-		return err
+	addr model.Addr,
+	duration time.Duration,
+) ([]model.Annotation, error) {
+	from := time.Now().Add(-1 * duration)
+	ret := make([]model.Annotation, 0)
+	for _, a := range cs.annotations {
+		if a.Time.Before(from) {
+			continue
+		}
+		if a.IsGlobal() || a.Addr.Compare(addr) == 0 {
+			ret = append(ret, a)
+		}
 	}
+	return ret, nil
+}
 
-	maxfilename := cs.wspfilename(cs.directory, device.Addr, "pingmax")
-	maxwsp, err := cs.openwsp(maxfilename)
-	if err != nil {
-		return err
-	}
-	err = maxwsp.Update(float64(point.Maximum/time.Millisecond), cs.timeToWspTime(timestamp))
-	if err != nil {
-		return err
-	}
-	err = maxwsp.Close()
+func (cs *Store) saveAnnotations() error {
+	bytes, err := msgpack.Marshal(cs.annotations)
 	if err != nil {
 		return err
 	}
+	return writeFileAtomic(filepath.Join(cs.directory, cs.annotationfile), bytes)
+}
 
-	lossfilename := cs.wspfilename(cs.directory, device.Addr, "pingloss")
-	losswsp, err := cs.openwsp(lossfilename)
-	if err != nil {
-		return err
-	}
-	err = losswsp.Update(point.PacketLoss, cs.timeToWspTime(timestamp))
-	if err != nil {
-		return err
-	}
-	err = maxwsp.Close()
+func (cs *Store) readAnnotations() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.annotationfile)
 	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
 		return err
 	}
+	err = msgpack.Unmarshal(bytes, &cs.annotations)
+	return err
+}
 
-	return nil
+//
+// Change data
+//
+
+// AddDeviceChanges stores the device changes
+func (cs *Store) AddDeviceChanges(ctx context.Context, changes []model.DeviceChange) error {
+	cs.changes = append(cs.changes, changes...)
+	return cs.saveChanges()
 }
 
-// ReadPoints returns the points from Now() minus the duration for the given type
-func (cs *Store) ReadPerformancePings(
+// ReadDeviceChanges returns the change history of the addr, newest first
+func (cs *Store) ReadDeviceChanges(
 	ctx context.Context,
-	device model.Device,
-	duration time.Duration,
-) (points []pinger.Point, err error) {
-	avgname := cs.wspfilename(cs.directory, device.Addr, "pingavg")
-	avgwsp, err := cs.openwsp(avgname)
-	if err != nil {
-		return nil, err
-	}
-	defer avgwsp.Close()
-	avgts, err := avgwsp.Fetch(cs.fetchlast(duration))
-	if err != nil {
-		return nil, err
-	}
-	avgpoints := avgts.Points()
-	numOfPoints := len(avgpoints)
-	if numOfPoints == 0 {
-		return nil, errors.New("no points")
-	}
-	points = make([]pinger.Point, numOfPoints)
-	for idx, point := range avgpoints {
-		if !math.IsNaN(point.Value) {
-			points[idx] = pinger.Point{
-				Device:  device,
-				Start:   time.Unix(int64(point.Time), 0),
-				Average: time.Duration(point.Value) * time.Millisecond,
-			}
+	addr model.Addr,
+) ([]model.DeviceChange, error) {
+	ret := make([]model.DeviceChange, 0)
+	for i := len(cs.changes) - 1; i >= 0; i-- {
+		if cs.changes[i].Addr.Compare(addr) == 0 {
+			ret = append(ret, cs.changes[i])
 		}
 	}
+	return ret, nil
+}
 
-	maxname := cs.wspfilename(cs.directory, device.Addr, "pingmax")
-	maxwsp, err := cs.openwsp(maxname)
+func (cs *Store) saveChanges() error {
+	bytes, err := msgpack.Marshal(cs.changes)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer maxwsp.Close()
-	maxts, err := maxwsp.Fetch(cs.fetchlast(duration))
+	return writeFileAtomic(filepath.Join(cs.directory, cs.changefile), bytes)
+}
+
+func (cs *Store) readChanges() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.changefile)
 	if err != nil {
-		return nil, err
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
 	}
-	if numOfPoints == len(avgts.Points()) {
-		return nil, errors.New("max point count does not equal avg count")
+	err = msgpack.Unmarshal(bytes, &cs.changes)
+	return err
+}
+
+//
+// Reservation data
+//
+
+// UpsertReservation adds the reservation or replaces the existing one for the same addr
+func (cs *Store) UpsertReservation(ctx context.Context, r model.Reservation) error {
+	for idx, x := range cs.reservations {
+		if x.Addr.Compare(r.Addr) == 0 {
+			cs.reservations[idx] = r
+			return cs.saveReservations()
+		}
 	}
-	for idx, point := range maxts.Points() {
-		if !math.IsNaN(point.Value) {
-			points[idx].Maximum = time.Duration(point.Value) * time.Millisecond
+	cs.reservations = append(cs.reservations, r)
+	return cs.saveReservations()
+}
+
+// RemoveReservation deletes the reservation for the addr
+func (cs *Store) RemoveReservation(ctx context.Context, addr model.Addr) error {
+	for idx, r := range cs.reservations {
+		if r.Addr.Compare(addr) == 0 {
+			cs.reservations = slices.Delete(cs.reservations, idx, idx+1)
+			return cs.saveReservations()
 		}
 	}
+	return model.ErrReservationDoesNotExist
+}
+
+// ListReservations returns all stored reservations
+func (cs *Store) ListReservations(ctx context.Context) ([]model.Reservation, error) {
+	return slices.Clone(cs.reservations), nil
+}
 
-	lossname := cs.wspfilename(cs.directory, device.Addr, "pingloss")
-	losswsp, err := cs.openwsp(lossname)
+func (cs *Store) saveReservations() error {
+	bytes, err := msgpack.Marshal(cs.reservations)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer losswsp.Close()
-	lossts, err := losswsp.Fetch(cs.fetchlast(duration))
+	return writeFileAtomic(filepath.Join(cs.directory, cs.reservationfile), bytes)
+}
+
+func (cs *Store) readReservations() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.reservationfile)
 	if err != nil {
-		return nil, err
-	}
-	if numOfPoints == len(lossts.Points()) {
-		return nil, errors.New("loss point count does not equal avg count")
-	}
-	for idx, point := range lossts.Points() {
-		if !math.IsNaN(point.Value) {
-			points[idx].Loss = point.Value * 100
+		if os.IsNotExist(err) {
+			return nil
 		}
+		return err
 	}
-
-	return points, nil
+	err = msgpack.Unmarshal(bytes, &cs.reservations)
+	return err
 }
 
-func convertPingDuration(t time.Duration) float64 {
-	return float64(t) / float64(time.Millisecond)
-}
+//
+// Tag data
+//
 
-func (cs *Store) ensureDirectory(dir string) {
-	stat, err := os.Stat(dir)
-	if err != nil && errors.Is(err, os.ErrNotExist) {
-		err = os.MkdirAll(dir, 0755)
-		if err != nil {
-			log.Fatal(err)
+// UpsertTagDefinition adds the tag definition or replaces the existing one with the same name
+func (cs *Store) UpsertTagDefinition(ctx context.Context, def model.TagDefinition) error {
+	for idx, x := range cs.tags {
+		if x.Name == def.Name {
+			cs.tags[idx] = def
+			return cs.saveTags()
 		}
-		return
-	}
-	if err != nil {
-		log.Fatal(err)
-	}
-	if stat.IsDir() {
-		return
 	}
-	log.Fatal("not a directory", "dir", dir)
+	cs.tags = append(cs.tags, def)
+	return cs.saveTags()
 }
 
-// func (cs *Store) sanitizeAddrString(addr netip.Addr) string {
-// 	return strings.Replace(addr.String(), ".", "-", -1)
-// }
-
-func (cs *Store) wspfilename(dir string, addr model.Addr, name string) string {
-	addrString := sanitizeAddrString(addr)
-	return fmt.Sprintf("%s/%s_%s.wsp", dir, addrString, name)
+// RemoveTagDefinition deletes the named tag definition
+func (cs *Store) RemoveTagDefinition(ctx context.Context, name string) error {
+	for idx, def := range cs.tags {
+		if def.Name == name {
+			cs.tags = slices.Delete(cs.tags, idx, idx+1)
+			return cs.saveTags()
+		}
+	}
+	return model.ErrTagDoesNotExist
 }
 
-func sanitizeAddrString(addr model.Addr) string {
-	return strings.Replace(addr.String(), ".", "-", -1)
+// ListTagDefinitions returns all tag definitions
+func (cs *Store) ListTagDefinitions(ctx context.Context) ([]model.TagDefinition, error) {
+	return slices.Clone(cs.tags), nil
 }
 
-func (cs *Store) openwsp(filename string) (*whisper.Whisper, error) {
-	wsp, err := whisper.Open(filename)
-	if err == nil {
-		return wsp, nil
-	}
-	if errors.Is(err, os.ErrNotExist) {
-		return whisper.Create(filename, cs.retentions, whisper.Average, 0.5)
+func (cs *Store) saveTags() error {
+	bytes, err := msgpack.Marshal(cs.tags)
+	if err != nil {
+		return err
 	}
-	return nil, err
+	return writeFileAtomic(filepath.Join(cs.directory, cs.tagfile), bytes)
 }
 
-func (cs *Store) fetchlast(dur time.Duration) (int, int) {
-	t2 := time.Now()
-	t1 := t2.Add(-1 * dur)
-	return cs.timeToWspTime(t1), cs.timeToWspTime(t2)
+func (cs *Store) readTags() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.tagfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = msgpack.Unmarshal(bytes, &cs.tags)
+	return err
 }
 
-func (cs *Store) timeToWspTime(timestamp time.Time) int {
-	return int(timestamp.Unix())
+//
+// Site data
+//
+
+// UpsertSite adds the site or replaces the existing one with the same name
+func (cs *Store) UpsertSite(ctx context.Context, site model.Site) error {
+	for idx, x := range cs.sites {
+		if x.Name == site.Name {
+			cs.sites[idx] = site
+			return cs.saveSites()
+		}
+	}
+	cs.sites = append(cs.sites, site)
+	return cs.saveSites()
+}
+
+// RemoveSite deletes the named site
+func (cs *Store) RemoveSite(ctx context.Context, name string) error {
+	for idx, site := range cs.sites {
+		if site.Name == name {
+			cs.sites = slices.Delete(cs.sites, idx, idx+1)
+			return cs.saveSites()
+		}
+	}
+	return model.ErrSiteDoesNotExist
+}
+
+// ListSites returns all sites
+func (cs *Store) ListSites(ctx context.Context) ([]model.Site, error) {
+	return slices.Clone(cs.sites), nil
+}
+
+func (cs *Store) saveSites() error {
+	bytes, err := msgpack.Marshal(cs.sites)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(cs.directory, cs.sitefile), bytes)
+}
+
+func (cs *Store) readSites() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.sitefile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = msgpack.Unmarshal(bytes, &cs.sites)
+	return err
+}
+
+//
+// Internet health data
+//
+
+// WriteHealthProbes stores the results of an internet health run
+func (cs *Store) WriteHealthProbes(ctx context.Context, probes []model.HealthProbe) error {
+	cs.health = append(cs.health, probes...)
+	return cs.saveHealthProbes()
+}
+
+// ReadHealthProbes returns the probes from Now() minus the duration
+func (cs *Store) ReadHealthProbes(
+	ctx context.Context,
+	duration time.Duration,
+) ([]model.HealthProbe, error) {
+	from := time.Now().Add(-1 * duration)
+	probes := make([]model.HealthProbe, 0)
+	for _, p := range cs.health {
+		if p.Start.After(from) {
+			probes = append(probes, p)
+		}
+	}
+	return probes, nil
+}
+
+// PurgeHealthProbes removes the probes started before the cutoff, returns the number removed
+func (cs *Store) PurgeHealthProbes(ctx context.Context, cutoff time.Time) (int, error) {
+	count := len(cs.health)
+	cs.health = slices.DeleteFunc(cs.health, func(p model.HealthProbe) bool {
+		return p.Start.Before(cutoff)
+	})
+	removed := count - len(cs.health)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, cs.saveHealthProbes()
+}
+
+func (cs *Store) saveHealthProbes() error {
+	bytes, err := msgpack.Marshal(cs.health)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(cs.directory, cs.healthfile), bytes)
+}
+
+func (cs *Store) readHealthProbes() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.healthfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = msgpack.Unmarshal(bytes, &cs.health)
+	return err
+}
+
+//
+// Speed test data
+//
+
+// WriteSpeedTest stores the result of a bandwidth test
+func (cs *Store) WriteSpeedTest(ctx context.Context, st model.SpeedTest) error {
+	cs.speedtests = append(cs.speedtests, st)
+	return cs.saveSpeedTests()
+}
+
+// ReadSpeedTests returns the bandwidth tests from Now() minus the duration
+func (cs *Store) ReadSpeedTests(
+	ctx context.Context,
+	duration time.Duration,
+) ([]model.SpeedTest, error) {
+	from := time.Now().Add(-1 * duration)
+	tests := make([]model.SpeedTest, 0)
+	for _, st := range cs.speedtests {
+		if st.Start.After(from) {
+			tests = append(tests, st)
+		}
+	}
+	return tests, nil
+}
+
+func (cs *Store) saveSpeedTests() error {
+	bytes, err := msgpack.Marshal(cs.speedtests)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(cs.directory, cs.speedtestfile), bytes)
+}
+
+func (cs *Store) readSpeedTests() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.speedtestfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = msgpack.Unmarshal(bytes, &cs.speedtests)
+	return err
+}
+
+//
+// Event history
+//
+
+// WriteEvents stores bus events and errors in the event history
+func (cs *Store) WriteEvents(ctx context.Context, records []model.EventRecord) error {
+	cs.events = append(cs.events, records...)
+	return cs.saveEvents()
+}
+
+// ReadEvents returns the records matching the query, newest first
+func (cs *Store) ReadEvents(
+	ctx context.Context,
+	q model.EventQuery,
+) ([]model.EventRecord, error) {
+	return model.FilterEventRecords(cs.events, q), nil
+}
+
+// PurgeEvents removes the records from before the cutoff and all but the newest keep records
+// (zero keeps them all), returns the number removed
+func (cs *Store) PurgeEvents(ctx context.Context, cutoff time.Time, keep int) (int, error) {
+	count := len(cs.events)
+	cs.events = slices.DeleteFunc(cs.events, func(rec model.EventRecord) bool {
+		return rec.Time.Before(cutoff)
+	})
+	if keep > 0 && len(cs.events) > keep {
+		cs.events = slices.Delete(cs.events, 0, len(cs.events)-keep)
+	}
+	removed := count - len(cs.events)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, cs.saveEvents()
+}
+
+func (cs *Store) saveEvents() error {
+	bytes, err := msgpack.Marshal(cs.events)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(cs.directory, cs.eventfile), bytes)
+}
+
+func (cs *Store) readEvents() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.eventfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = msgpack.Unmarshal(bytes, &cs.events)
+	return err
+}
+
+//
+// Tombstone data
+//
+
+// UpsertTombstone stores the tombstone, replacing an existing one for the same item
+func (cs *Store) UpsertTombstone(ctx context.Context, t model.Tombstone) error {
+	for idx, x := range cs.tombstones {
+		if x.Kind == t.Kind && x.Key == t.Key {
+			cs.tombstones[idx] = t
+			return cs.saveTombstones()
+		}
+	}
+	cs.tombstones = append(cs.tombstones, t)
+	return cs.saveTombstones()
+}
+
+// RemoveTombstone deletes the tombstone of the item
+func (cs *Store) RemoveTombstone(ctx context.Context, kind model.TombstoneKind, key string) error {
+	for idx, t := range cs.tombstones {
+		if t.Kind == kind && t.Key == key {
+			cs.tombstones = slices.Delete(cs.tombstones, idx, idx+1)
+			return cs.saveTombstones()
+		}
+	}
+	return model.ErrTombstoneDoesNotExist
+}
+
+// ListTombstones returns all tombstones
+func (cs *Store) ListTombstones(ctx context.Context) ([]model.Tombstone, error) {
+	return slices.Clone(cs.tombstones), nil
+}
+
+func (cs *Store) saveTombstones() error {
+	bytes, err := msgpack.Marshal(cs.tombstones)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(cs.directory, cs.tombstonefile), bytes)
+}
+
+func (cs *Store) readTombstones() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.tombstonefile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = msgpack.Unmarshal(bytes, &cs.tombstones)
+	return err
+}
+
+//
+// Maintenance data
+//
+
+// UpsertMaintenanceWindow adds the window or replaces the existing one with the same name
+func (cs *Store) UpsertMaintenanceWindow(ctx context.Context, w model.MaintenanceWindow) error {
+	for idx, x := range cs.maintenance {
+		if x.Name == w.Name {
+			cs.maintenance[idx] = w
+			return cs.saveMaintenance()
+		}
+	}
+	cs.maintenance = append(cs.maintenance, w)
+	return cs.saveMaintenance()
+}
+
+// RemoveMaintenanceWindow deletes the named maintenance window
+func (cs *Store) RemoveMaintenanceWindow(ctx context.Context, name string) error {
+	for idx, w := range cs.maintenance {
+		if w.Name == name {
+			cs.maintenance = slices.Delete(cs.maintenance, idx, idx+1)
+			return cs.saveMaintenance()
+		}
+	}
+	return model.ErrMaintenanceWindowDoesNotExist
+}
+
+// ListMaintenanceWindows returns all maintenance windows
+func (cs *Store) ListMaintenanceWindows(ctx context.Context) ([]model.MaintenanceWindow, error) {
+	return slices.Clone(cs.maintenance), nil
+}
+
+func (cs *Store) saveMaintenance() error {
+	bytes, err := msgpack.Marshal(cs.maintenance)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(cs.directory, cs.maintenancefile), bytes)
+}
+
+func (cs *Store) readMaintenance() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.maintenancefile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = msgpack.Unmarshal(bytes, &cs.maintenance)
+	return err
+}
+
+//
+// HTTP check data
+//
+
+// UpsertHTTPCheck adds the check or replaces the existing one with the same name
+func (cs *Store) UpsertHTTPCheck(ctx context.Context, c model.HTTPCheck) error {
+	for idx, x := range cs.httpchecks {
+		if x.Name == c.Name {
+			cs.httpchecks[idx] = c
+			return cs.saveHTTPChecks()
+		}
+	}
+	cs.httpchecks = append(cs.httpchecks, c)
+	return cs.saveHTTPChecks()
+}
+
+// RemoveHTTPCheck deletes the named check and its results
+func (cs *Store) RemoveHTTPCheck(ctx context.Context, name string) error {
+	idx := slices.IndexFunc(cs.httpchecks, func(c model.HTTPCheck) bool { return c.Name == name })
+	if idx < 0 {
+		return model.ErrHTTPCheckDoesNotExist
+	}
+	cs.httpchecks = slices.Delete(cs.httpchecks, idx, idx+1)
+	err := cs.saveHTTPChecks()
+	if err != nil {
+		return err
+	}
+	cs.httpresults = slices.DeleteFunc(cs.httpresults, func(r model.HTTPCheckResult) bool {
+		return r.Name == name
+	})
+	return cs.saveHTTPCheckResults()
+}
+
+// ListHTTPChecks returns all http checks ordered by name
+func (cs *Store) ListHTTPChecks(ctx context.Context) ([]model.HTTPCheck, error) {
+	checks := slices.Clone(cs.httpchecks)
+	slices.SortFunc(checks, func(a, b model.HTTPCheck) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return checks, nil
+}
+
+// WriteHTTPCheckResults stores the results of a run of the http checks
+func (cs *Store) WriteHTTPCheckResults(ctx context.Context, results []model.HTTPCheckResult) error {
+	cs.httpresults = append(cs.httpresults, results...)
+	return cs.saveHTTPCheckResults()
+}
+
+// ReadHTTPCheckResults returns the results from Now() minus the duration
+func (cs *Store) ReadHTTPCheckResults(
+	ctx context.Context,
+	duration time.Duration,
+) ([]model.HTTPCheckResult, error) {
+	from := time.Now().Add(-1 * duration)
+	results := make([]model.HTTPCheckResult, 0)
+	for _, r := range cs.httpresults {
+		if r.Start.After(from) {
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+// PurgeHTTPCheckResults removes the results started before the cutoff, returns the number
+// removed
+func (cs *Store) PurgeHTTPCheckResults(ctx context.Context, cutoff time.Time) (int, error) {
+	count := len(cs.httpresults)
+	cs.httpresults = slices.DeleteFunc(cs.httpresults, func(r model.HTTPCheckResult) bool {
+		return r.Start.Before(cutoff)
+	})
+	removed := count - len(cs.httpresults)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, cs.saveHTTPCheckResults()
+}
+
+func (cs *Store) saveHTTPChecks() error {
+	bytes, err := msgpack.Marshal(cs.httpchecks)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(cs.directory, cs.httpcheckfile), bytes)
+}
+
+func (cs *Store) readHTTPChecks() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.httpcheckfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = msgpack.Unmarshal(bytes, &cs.httpchecks)
+	return err
+}
+
+func (cs *Store) saveHTTPCheckResults() error {
+	bytes, err := msgpack.Marshal(cs.httpresults)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(cs.directory, cs.httpresultfile), bytes)
+}
+
+func (cs *Store) readHTTPCheckResults() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.httpresultfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = msgpack.Unmarshal(bytes, &cs.httpresults)
+	return err
+}
+
+//
+// Service check data
+//
+
+// WriteServiceCheckResults stores the results of a run of the service checks
+func (cs *Store) WriteServiceCheckResults(
+	ctx context.Context,
+	results []model.ServiceCheckResult,
+) error {
+	cs.serviceresults = append(cs.serviceresults, results...)
+	return cs.saveServiceCheckResults()
+}
+
+// ReadServiceCheckResults returns the results from Now() minus the duration
+func (cs *Store) ReadServiceCheckResults(
+	ctx context.Context,
+	duration time.Duration,
+) ([]model.ServiceCheckResult, error) {
+	from := time.Now().Add(-1 * duration)
+	results := make([]model.ServiceCheckResult, 0)
+	for _, r := range cs.serviceresults {
+		if r.Start.After(from) {
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+// PurgeServiceCheckResults removes the results started before the cutoff, returns the
+// number removed
+func (cs *Store) PurgeServiceCheckResults(ctx context.Context, cutoff time.Time) (int, error) {
+	count := len(cs.serviceresults)
+	cs.serviceresults = slices.DeleteFunc(cs.serviceresults, func(r model.ServiceCheckResult) bool {
+		return r.Start.Before(cutoff)
+	})
+	removed := count - len(cs.serviceresults)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, cs.saveServiceCheckResults()
+}
+
+func (cs *Store) saveServiceCheckResults() error {
+	bytes, err := msgpack.Marshal(cs.serviceresults)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(cs.directory, cs.serviceresfile), bytes)
+}
+
+func (cs *Store) readServiceCheckResults() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.serviceresfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = msgpack.Unmarshal(bytes, &cs.serviceresults)
+	return err
+}
+
+//
+// MAC binding data
+//
+
+// UpsertMACBinding adds the binding or moves the last seen time and source of the existing
+// binding of the MAC to the address
+func (cs *Store) UpsertMACBinding(ctx context.Context, b model.MACBinding) error {
+	for idx, x := range cs.macbindings {
+		if x.Addr == b.Addr && x.MAC.String() == b.MAC.String() {
+			cs.macbindings[idx].LastSeen = b.LastSeen
+			cs.macbindings[idx].Source = b.Source
+			return cs.saveMACBindings()
+		}
+	}
+	cs.macbindings = append(cs.macbindings, b)
+	return cs.saveMACBindings()
+}
+
+// ListMACBindings returns all bindings ordered by address and first seen
+func (cs *Store) ListMACBindings(ctx context.Context) ([]model.MACBinding, error) {
+	bindings := slices.Clone(cs.macbindings)
+	slices.SortFunc(bindings, func(a, b model.MACBinding) int {
+		if c := a.Addr.Compare(b.Addr); c != 0 {
+			return c
+		}
+		return a.FirstSeen.Compare(b.FirstSeen)
+	})
+	return bindings, nil
+}
+
+// PurgeMACBindings removes the bindings last seen before the cutoff, returns the number
+// removed
+func (cs *Store) PurgeMACBindings(ctx context.Context, cutoff time.Time) (int, error) {
+	count := len(cs.macbindings)
+	cs.macbindings = slices.DeleteFunc(cs.macbindings, func(b model.MACBinding) bool {
+		return b.LastSeen.Before(cutoff)
+	})
+	removed := count - len(cs.macbindings)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, cs.saveMACBindings()
+}
+
+func (cs *Store) saveMACBindings() error {
+	bytes, err := msgpack.Marshal(cs.macbindings)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(cs.directory, cs.macbindingfile), bytes)
+}
+
+func (cs *Store) readMACBindings() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.macbindingfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = msgpack.Unmarshal(bytes, &cs.macbindings)
+	return err
+}
+
+//
+// DHCP sighting data
+//
+
+// UpsertDHCPSighting adds the sighting or updates the existing sighting of the same kind and
+// address, the first seen time is kept
+func (cs *Store) UpsertDHCPSighting(ctx context.Context, s model.DHCPSighting) error {
+	for idx, x := range cs.dhcpsightings {
+		if x.Key() == s.Key() {
+			s.FirstSeen = x.FirstSeen
+			cs.dhcpsightings[idx] = s
+			return cs.saveDHCPSightings()
+		}
+	}
+	cs.dhcpsightings = append(cs.dhcpsightings, s)
+	return cs.saveDHCPSightings()
+}
+
+// ListDHCPSightings returns all sightings ordered by kind and address
+func (cs *Store) ListDHCPSightings(ctx context.Context) ([]model.DHCPSighting, error) {
+	sightings := slices.Clone(cs.dhcpsightings)
+	slices.SortFunc(sightings, func(a, b model.DHCPSighting) int {
+		if c := strings.Compare(string(b.Kind), string(a.Kind)); c != 0 {
+			return c
+		}
+		return a.Addr.Compare(b.Addr)
+	})
+	return sightings, nil
+}
+
+// RemoveDHCPSighting removes the sighting of the kind at the address
+func (cs *Store) RemoveDHCPSighting(ctx context.Context, kind model.DHCPSightingKind, addr model.Addr) error {
+	cs.dhcpsightings = slices.DeleteFunc(cs.dhcpsightings, func(s model.DHCPSighting) bool {
+		return s.Kind == kind && s.Addr == addr
+	})
+	return cs.saveDHCPSightings()
+}
+
+func (cs *Store) saveDHCPSightings() error {
+	bytes, err := msgpack.Marshal(cs.dhcpsightings)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(cs.directory, cs.dhcpfile), bytes)
+}
+
+func (cs *Store) readDHCPSightings() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.dhcpfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = msgpack.Unmarshal(bytes, &cs.dhcpsightings)
+	return err
+}
+
+//
+// Bandwidth quota data
+//
+
+// UpsertBandwidthQuota adds the quota or replaces the existing one with the same name
+func (cs *Store) UpsertBandwidthQuota(ctx context.Context, q model.BandwidthQuota) error {
+	for idx, x := range cs.quotas {
+		if x.Name == q.Name {
+			cs.quotas[idx] = q
+			return cs.saveQuotas()
+		}
+	}
+	cs.quotas = append(cs.quotas, q)
+	return cs.saveQuotas()
+}
+
+// RemoveBandwidthQuota deletes the named quota
+func (cs *Store) RemoveBandwidthQuota(ctx context.Context, name string) error {
+	for idx, q := range cs.quotas {
+		if q.Name == name {
+			cs.quotas = slices.Delete(cs.quotas, idx, idx+1)
+			return cs.saveQuotas()
+		}
+	}
+	return model.ErrBandwidthQuotaDoesNotExist
+}
+
+// ListBandwidthQuotas returns all quotas
+func (cs *Store) ListBandwidthQuotas(ctx context.Context) ([]model.BandwidthQuota, error) {
+	return slices.Clone(cs.quotas), nil
+}
+
+func (cs *Store) saveQuotas() error {
+	bytes, err := msgpack.Marshal(cs.quotas)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(cs.directory, cs.quotafile), bytes)
+}
+
+func (cs *Store) readQuotas() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.quotafile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = msgpack.Unmarshal(bytes, &cs.quotas)
+	return err
+}
+
+//
+// Exclusion data
+//
+
+// UpsertExclusion adds the exclusion or replaces the existing one with the same name
+func (cs *Store) UpsertExclusion(ctx context.Context, x model.Exclusion) error {
+	for idx, e := range cs.exclusions {
+		if e.Name == x.Name {
+			cs.exclusions[idx] = x
+			return cs.saveExclusions()
+		}
+	}
+	cs.exclusions = append(cs.exclusions, x)
+	return cs.saveExclusions()
+}
+
+// RemoveExclusion deletes the named exclusion
+func (cs *Store) RemoveExclusion(ctx context.Context, name string) error {
+	for idx, x := range cs.exclusions {
+		if x.Name == name {
+			cs.exclusions = slices.Delete(cs.exclusions, idx, idx+1)
+			return cs.saveExclusions()
+		}
+	}
+	return model.ErrExclusionDoesNotExist
+}
+
+// ListExclusions returns all exclusions
+func (cs *Store) ListExclusions(ctx context.Context) ([]model.Exclusion, error) {
+	return slices.Clone(cs.exclusions), nil
+}
+
+func (cs *Store) saveExclusions() error {
+	bytes, err := msgpack.Marshal(cs.exclusions)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(cs.directory, cs.exclusionfile), bytes)
+}
+
+func (cs *Store) readExclusions() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.exclusionfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = msgpack.Unmarshal(bytes, &cs.exclusions)
+	return err
+}
+
+//
+// Dependency data
+//
+
+// UpsertDependency adds the dependency or replaces the existing one with the same name
+func (cs *Store) UpsertDependency(ctx context.Context, dep model.Dependency) error {
+	for idx, x := range cs.dependencies {
+		if x.Name == dep.Name {
+			cs.dependencies[idx] = dep
+			return cs.saveDependencies()
+		}
+	}
+	cs.dependencies = append(cs.dependencies, dep)
+	return cs.saveDependencies()
+}
+
+// RemoveDependency deletes the named dependency
+func (cs *Store) RemoveDependency(ctx context.Context, name string) error {
+	for idx, dep := range cs.dependencies {
+		if dep.Name == name {
+			cs.dependencies = slices.Delete(cs.dependencies, idx, idx+1)
+			return cs.saveDependencies()
+		}
+	}
+	return model.ErrDependencyDoesNotExist
+}
+
+// ListDependencies returns all dependencies
+func (cs *Store) ListDependencies(ctx context.Context) ([]model.Dependency, error) {
+	return slices.Clone(cs.dependencies), nil
+}
+
+func (cs *Store) saveDependencies() error {
+	bytes, err := msgpack.Marshal(cs.dependencies)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(cs.directory, cs.dependencyfile), bytes)
+}
+
+func (cs *Store) readDependencies() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.dependencyfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = msgpack.Unmarshal(bytes, &cs.dependencies)
+	return err
+}
+
+//
+// Port profile data
+//
+
+// UpsertPortProfile adds the port profile or replaces the existing one with the same name
+func (cs *Store) UpsertPortProfile(ctx context.Context, pp model.PortProfile) error {
+	for idx, x := range cs.portprofiles {
+		if x.Name == pp.Name {
+			cs.portprofiles[idx] = pp
+			return cs.savePortProfiles()
+		}
+	}
+	cs.portprofiles = append(cs.portprofiles, pp)
+	return cs.savePortProfiles()
+}
+
+// RemovePortProfile deletes the named port profile
+func (cs *Store) RemovePortProfile(ctx context.Context, name string) error {
+	for idx, pp := range cs.portprofiles {
+		if pp.Name == name {
+			cs.portprofiles = slices.Delete(cs.portprofiles, idx, idx+1)
+			return cs.savePortProfiles()
+		}
+	}
+	return model.ErrPortProfileDoesNotExist
+}
+
+// ListPortProfiles returns all port profiles
+func (cs *Store) ListPortProfiles(ctx context.Context) ([]model.PortProfile, error) {
+	return slices.Clone(cs.portprofiles), nil
+}
+
+func (cs *Store) savePortProfiles() error {
+	bytes, err := msgpack.Marshal(cs.portprofiles)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(cs.directory, cs.portprofilefile), bytes)
+}
+
+func (cs *Store) readPortProfiles() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.portprofilefile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = msgpack.Unmarshal(bytes, &cs.portprofiles)
+	return err
+}
+
+//
+// Full port scan data
+//
+
+// UpsertFullPortScan stores the progress of the sweep of the device's ports
+func (cs *Store) UpsertFullPortScan(ctx context.Context, fs model.FullPortScan) error {
+	for idx, x := range cs.fullscans {
+		if x.Addr == fs.Addr {
+			cs.fullscans[idx] = fs
+			return cs.saveFullPortScans()
+		}
+	}
+	cs.fullscans = append(cs.fullscans, fs)
+	return cs.saveFullPortScans()
+}
+
+// RemoveFullPortScan deletes the progress of the sweep of the device's ports
+func (cs *Store) RemoveFullPortScan(ctx context.Context, addr model.Addr) error {
+	for idx, fs := range cs.fullscans {
+		if fs.Addr == addr {
+			cs.fullscans = slices.Delete(cs.fullscans, idx, idx+1)
+			return cs.saveFullPortScans()
+		}
+	}
+	return model.ErrFullPortScanDoesNotExist
+}
+
+// ListFullPortScans returns the progress of every sweep
+func (cs *Store) ListFullPortScans(ctx context.Context) ([]model.FullPortScan, error) {
+	return slices.Clone(cs.fullscans), nil
+}
+
+func (cs *Store) saveFullPortScans() error {
+	bytes, err := msgpack.Marshal(cs.fullscans)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(cs.directory, cs.fullscanfile), bytes)
+}
+
+func (cs *Store) readFullPortScans() error {
+	bytes, err := os.ReadFile(cs.directory + "/" + cs.fullscanfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = msgpack.Unmarshal(bytes, &cs.fullscans)
+	return err
+}
+
+//
+// Timeseries data
+//
+
+// WritePerformancePing stores a given point for a device in its whisper files
+func (cs *Store) WritePerformancePing(
+	ctx context.Context,
+	timestamp time.Time,
+	device model.Device,
+	point nettools.Icmp4EchoResponseStatistics,
+) error {
+	return cs.wsp.WritePerformancePing(ctx, timestamp, device, point)
+}
+
+// ReadPerformancePings returns the points from Now() minus the duration
+func (cs *Store) ReadPerformancePings(
+	ctx context.Context,
+	device model.Device,
+	duration time.Duration,
+) ([]pinger.Point, error) {
+	return cs.wsp.ReadPerformancePings(ctx, device, duration)
+}
+
+// MergePerformancePings moves the ping history of the from addr onto the to addr
+func (cs *Store) MergePerformancePings(ctx context.Context, from, to model.Addr) error {
+	return cs.wsp.MergePerformancePings(ctx, from, to)
+}
+
+func (cs *Store) ensureDirectory(dir string) {
+	stat, err := os.Stat(dir)
+	if err != nil && errors.Is(err, os.ErrNotExist) {
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if stat.IsDir() {
+		return
+	}
+	logger.Fatal("not a directory", "dir", dir)
+}
+
+//
+// Consistency
+//
+
+// CheckConsistency looks for pinged devices whose whisper files are missing and whisper files
+// of addrs which are not a device (or a deleted device which can still be restored).  With
+// repair the ping state of the device is reset so the files are recreated on the next ping and
+// orphaned files are moved into the quarantine directory.
+func (cs *Store) CheckConsistency(ctx context.Context, repair bool) ([]model.ConsistencyIssue, error) {
+	issues := make([]model.ConsistencyIssue, 0)
+	known := make(map[string]bool)
+
+	reset := false
+	for idx, d := range cs.devices {
+		known[tsstore.SanitizeAddrString(d.Addr)] = true
+		if d.PerformancePing.LastSeen.IsZero() || cs.externalts {
+			continue
+		}
+		missing := make([]string, 0)
+		for _, series := range tsstore.WhisperSeries {
+			_, err := os.Stat(cs.wsp.Filename(d.Addr, series))
+			if errors.Is(err, os.ErrNotExist) {
+				missing = append(missing, series)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		issue := model.ConsistencyIssue{
+			Check:  model.ConsistencyMissingTimeseries,
+			Ref:    d.Addr.String(),
+			Detail: "missing whisper files " + strings.Join(missing, ","),
+		}
+		if repair {
+			cs.devices[idx].PerformancePing = model.Pinger{}
+			issue.Repaired = true
+			issue.Action = "ping history reset"
+			reset = true
+		}
+		issues = append(issues, issue)
+	}
+	if reset {
+		err := cs.saveDevices()
+		if err != nil {
+			return issues, err
+		}
+	}
+	for _, t := range cs.tombstones {
+		if t.Kind == model.TombstoneDevice {
+			known[tsstore.SanitizeAddrString(t.Device.Addr)] = true
+		}
+	}
+
+	entries, err := os.ReadDir(cs.directory)
+	if err != nil {
+		return issues, err
+	}
+	quarantine := filepath.Join(cs.directory, "quarantine")
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".wsp" {
+			continue
+		}
+		addr, _, _ := strings.Cut(strings.TrimSuffix(name, ".wsp"), "_")
+		if known[addr] {
+			continue
+		}
+		issue := model.ConsistencyIssue{
+			Check:  model.ConsistencyOrphanedTimeseries,
+			Ref:    name,
+			Detail: "whisper file for an unknown device",
+		}
+		if repair {
+			cs.ensureDirectory(quarantine)
+			err = os.Rename(filepath.Join(cs.directory, name), filepath.Join(quarantine, name))
+			if err != nil {
+				return issues, err
+			}
+			issue.Repaired = true
+			issue.Action = "moved to " + quarantine
+		}
+		issues = append(issues, issue)
+	}
+	return issues, nil
 }
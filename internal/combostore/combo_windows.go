@@ -30,6 +30,18 @@ func (cs *Store) Close() error {
 	return unsupported
 }
 
+func (cs *Store) Ping(ctx context.Context) error {
+	return unsupported
+}
+
+func (cs *Store) Snapshot(ctx context.Context, dir string) (int, error) {
+	return 0, unsupported
+}
+
+func Restore(cfg *Config, dir string) error {
+	return unsupported
+}
+
 //
 // Network data
 //
@@ -99,6 +111,43 @@ func (cs *Store) UpdateDevice(
 	return false, unsupported
 }
 
+// SetDeviceTags replaces the tags of the device
+func (cs *Store) SetDeviceTags(ctx context.Context, addr model.Addr, tags model.Tags) error {
+	return unsupported
+}
+
+// SetDeviceParent sets the host the device runs on as a guest, an empty addr clears it
+func (cs *Store) SetDeviceParent(ctx context.Context, addr model.Addr, parent model.Addr) error {
+	return unsupported
+}
+
+// SetDevicePolicy replaces the monitoring policy of the device
+func (cs *Store) SetDevicePolicy(
+	ctx context.Context,
+	addr model.Addr,
+	policy model.MonitoringPolicy,
+) error {
+	return unsupported
+}
+
+// SetDeviceApproval replaces the approval state of the device
+func (cs *Store) SetDeviceApproval(
+	ctx context.Context,
+	addr model.Addr,
+	state model.ApprovalState,
+) error {
+	return unsupported
+}
+
+// SetDeviceDetails replaces the name, owner, notes and asset fields of the device
+func (cs *Store) SetDeviceDetails(
+	ctx context.Context,
+	addr model.Addr,
+	details model.DeviceDetails,
+) error {
+	return unsupported
+}
+
 // GetDeviceByAddr returns the device with the matching Addr
 func (cs *Store) GetDeviceByAddr(
 	ctx context.Context,
@@ -107,6 +156,16 @@ func (cs *Store) GetDeviceByAddr(
 	return model.Device{}, unsupported
 }
 
+// GetDeviceByMAC returns the first stored device seen with the MAC
+func (cs *Store) GetDeviceByMAC(ctx context.Context, mac model.MAC) (model.Device, error) {
+	return model.Device{}, unsupported
+}
+
+// FindDevicesByName returns the devices named name, ignoring case
+func (cs *Store) FindDevicesByName(ctx context.Context, name string) []model.Device {
+	return nil
+}
+
 // GetFilteredDevices returns the devices which match the given GetFilteredDevices
 func (cs *Store) GetFilteredDevices(
 	ctx context.Context,
@@ -115,6 +174,11 @@ func (cs *Store) GetFilteredDevices(
 	return nil
 }
 
+// QueryDevices returns one page of the devices matching the query
+func (cs *Store) QueryDevices(ctx context.Context, q model.DeviceQuery) model.DevicePage {
+	return model.DevicePage{}
+}
+
 // ListDevices returns all the stored devices
 func (cs *Store) ListDevices(ctx context.Context) []model.Device {
 	return nil
@@ -125,6 +189,398 @@ func (cs *Store) CountDevices(ctx context.Context) int {
 	return 0
 }
 
+//
+// Annotation data
+//
+
+// AddAnnotation stores an annotation
+func (cs *Store) AddAnnotation(ctx context.Context, a model.Annotation) error {
+	return unsupported
+}
+
+// ReadAnnotations returns the annotations for the addr from Now() minus the duration
+func (cs *Store) ReadAnnotations(
+	ctx context.Context,
+	addr model.Addr,
+	duration time.Duration,
+) ([]model.Annotation, error) {
+	return nil, unsupported
+}
+
+//
+// Change data
+//
+
+// AddDeviceChanges stores the device changes
+func (cs *Store) AddDeviceChanges(ctx context.Context, changes []model.DeviceChange) error {
+	return unsupported
+}
+
+// ReadDeviceChanges returns the change history of the addr, newest first
+func (cs *Store) ReadDeviceChanges(
+	ctx context.Context,
+	addr model.Addr,
+) ([]model.DeviceChange, error) {
+	return nil, unsupported
+}
+
+//
+// Reservation data
+//
+
+// UpsertReservation adds the reservation or replaces the existing one for the same addr
+func (cs *Store) UpsertReservation(ctx context.Context, r model.Reservation) error {
+	return unsupported
+}
+
+// RemoveReservation deletes the reservation for the addr
+func (cs *Store) RemoveReservation(ctx context.Context, addr model.Addr) error {
+	return unsupported
+}
+
+// ListReservations returns all stored reservations
+func (cs *Store) ListReservations(ctx context.Context) ([]model.Reservation, error) {
+	return nil, unsupported
+}
+
+//
+// Tag data
+//
+
+// UpsertTagDefinition adds the tag definition or replaces the existing one with the same name
+func (cs *Store) UpsertTagDefinition(ctx context.Context, def model.TagDefinition) error {
+	return unsupported
+}
+
+// RemoveTagDefinition deletes the named tag definition
+func (cs *Store) RemoveTagDefinition(ctx context.Context, name string) error {
+	return unsupported
+}
+
+// ListTagDefinitions returns all tag definitions
+func (cs *Store) ListTagDefinitions(ctx context.Context) ([]model.TagDefinition, error) {
+	return nil, unsupported
+}
+
+//
+// Site data
+//
+
+// UpsertSite adds the site or replaces the existing one with the same name
+func (cs *Store) UpsertSite(ctx context.Context, site model.Site) error {
+	return unsupported
+}
+
+// RemoveSite deletes the named site
+func (cs *Store) RemoveSite(ctx context.Context, name string) error {
+	return unsupported
+}
+
+// ListSites returns all sites
+func (cs *Store) ListSites(ctx context.Context) ([]model.Site, error) {
+	return nil, unsupported
+}
+
+//
+// Internet health data
+//
+
+// WriteHealthProbes stores the results of an internet health run
+func (cs *Store) WriteHealthProbes(ctx context.Context, probes []model.HealthProbe) error {
+	return unsupported
+}
+
+// ReadHealthProbes returns the probes from Now() minus the duration
+func (cs *Store) ReadHealthProbes(
+	ctx context.Context,
+	duration time.Duration,
+) ([]model.HealthProbe, error) {
+	return nil, unsupported
+}
+
+// PurgeHealthProbes removes the probes started before the cutoff, returns the number removed
+func (cs *Store) PurgeHealthProbes(ctx context.Context, cutoff time.Time) (int, error) {
+	return 0, unsupported
+}
+
+//
+// Speed test data
+//
+
+// WriteSpeedTest stores the result of a bandwidth test
+func (cs *Store) WriteSpeedTest(ctx context.Context, st model.SpeedTest) error {
+	return unsupported
+}
+
+// ReadSpeedTests returns the bandwidth tests from Now() minus the duration
+func (cs *Store) ReadSpeedTests(
+	ctx context.Context,
+	duration time.Duration,
+) ([]model.SpeedTest, error) {
+	return nil, unsupported
+}
+
+//
+// Event history
+//
+
+// WriteEvents stores bus events and errors in the event history
+func (cs *Store) WriteEvents(ctx context.Context, records []model.EventRecord) error {
+	return unsupported
+}
+
+// ReadEvents returns the records matching the query, newest first
+func (cs *Store) ReadEvents(
+	ctx context.Context,
+	q model.EventQuery,
+) ([]model.EventRecord, error) {
+	return nil, unsupported
+}
+
+// PurgeEvents removes the records from before the cutoff and all but the newest keep records
+// (zero keeps them all), returns the number removed
+func (cs *Store) PurgeEvents(ctx context.Context, cutoff time.Time, keep int) (int, error) {
+	return 0, unsupported
+}
+
+//
+// Tombstone data
+//
+
+// UpsertTombstone stores the tombstone, replacing an existing one for the same item
+func (cs *Store) UpsertTombstone(ctx context.Context, t model.Tombstone) error {
+	return unsupported
+}
+
+// RemoveTombstone deletes the tombstone of the item
+func (cs *Store) RemoveTombstone(ctx context.Context, kind model.TombstoneKind, key string) error {
+	return unsupported
+}
+
+// ListTombstones returns all tombstones
+func (cs *Store) ListTombstones(ctx context.Context) ([]model.Tombstone, error) {
+	return nil, unsupported
+}
+
+//
+// Maintenance data
+//
+
+// UpsertMaintenanceWindow adds the window or replaces the existing one with the same name
+func (cs *Store) UpsertMaintenanceWindow(ctx context.Context, w model.MaintenanceWindow) error {
+	return unsupported
+}
+
+// RemoveMaintenanceWindow deletes the named maintenance window
+func (cs *Store) RemoveMaintenanceWindow(ctx context.Context, name string) error {
+	return unsupported
+}
+
+// ListMaintenanceWindows returns all maintenance windows
+func (cs *Store) ListMaintenanceWindows(ctx context.Context) ([]model.MaintenanceWindow, error) {
+	return nil, unsupported
+}
+
+//
+// HTTP check data
+//
+
+// UpsertHTTPCheck adds the check or replaces the existing one with the same name
+func (cs *Store) UpsertHTTPCheck(ctx context.Context, c model.HTTPCheck) error {
+	return unsupported
+}
+
+// RemoveHTTPCheck deletes the named check and its results
+func (cs *Store) RemoveHTTPCheck(ctx context.Context, name string) error {
+	return unsupported
+}
+
+// ListHTTPChecks returns all http checks ordered by name
+func (cs *Store) ListHTTPChecks(ctx context.Context) ([]model.HTTPCheck, error) {
+	return nil, unsupported
+}
+
+// WriteHTTPCheckResults stores the results of a run of the http checks
+func (cs *Store) WriteHTTPCheckResults(ctx context.Context, results []model.HTTPCheckResult) error {
+	return unsupported
+}
+
+// ReadHTTPCheckResults returns the results from Now() minus the duration
+func (cs *Store) ReadHTTPCheckResults(
+	ctx context.Context,
+	duration time.Duration,
+) ([]model.HTTPCheckResult, error) {
+	return nil, unsupported
+}
+
+// PurgeHTTPCheckResults removes the results started before the cutoff, returns the number
+// removed
+func (cs *Store) PurgeHTTPCheckResults(ctx context.Context, cutoff time.Time) (int, error) {
+	return 0, unsupported
+}
+
+//
+// Service check data
+//
+
+// WriteServiceCheckResults stores the results of a run of the service checks
+func (cs *Store) WriteServiceCheckResults(
+	ctx context.Context,
+	results []model.ServiceCheckResult,
+) error {
+	return unsupported
+}
+
+// ReadServiceCheckResults returns the results from Now() minus the duration
+func (cs *Store) ReadServiceCheckResults(
+	ctx context.Context,
+	duration time.Duration,
+) ([]model.ServiceCheckResult, error) {
+	return nil, unsupported
+}
+
+// PurgeServiceCheckResults removes the results started before the cutoff, returns the
+// number removed
+func (cs *Store) PurgeServiceCheckResults(ctx context.Context, cutoff time.Time) (int, error) {
+	return 0, unsupported
+}
+
+//
+// MAC binding data
+//
+
+// UpsertMACBinding adds the binding or moves the last seen time and source of the existing
+// binding of the MAC to the address
+func (cs *Store) UpsertMACBinding(ctx context.Context, b model.MACBinding) error {
+	return unsupported
+}
+
+// ListMACBindings returns all bindings ordered by address and first seen
+func (cs *Store) ListMACBindings(ctx context.Context) ([]model.MACBinding, error) {
+	return nil, unsupported
+}
+
+// PurgeMACBindings removes the bindings last seen before the cutoff, returns the number
+// removed
+func (cs *Store) PurgeMACBindings(ctx context.Context, cutoff time.Time) (int, error) {
+	return 0, unsupported
+}
+
+//
+// DHCP sighting data
+//
+
+// UpsertDHCPSighting adds the sighting or updates the existing sighting of the same kind and
+// address, the first seen time is kept
+func (cs *Store) UpsertDHCPSighting(ctx context.Context, s model.DHCPSighting) error {
+	return unsupported
+}
+
+// ListDHCPSightings returns all sightings ordered by kind and address
+func (cs *Store) ListDHCPSightings(ctx context.Context) ([]model.DHCPSighting, error) {
+	return nil, unsupported
+}
+
+// RemoveDHCPSighting removes the sighting of the kind at the address
+func (cs *Store) RemoveDHCPSighting(ctx context.Context, kind model.DHCPSightingKind, addr model.Addr) error {
+	return unsupported
+}
+
+//
+// Bandwidth quota data
+//
+
+// UpsertBandwidthQuota adds the quota or replaces the existing one with the same name
+func (cs *Store) UpsertBandwidthQuota(ctx context.Context, q model.BandwidthQuota) error {
+	return unsupported
+}
+
+// RemoveBandwidthQuota deletes the named quota
+func (cs *Store) RemoveBandwidthQuota(ctx context.Context, name string) error {
+	return unsupported
+}
+
+// ListBandwidthQuotas returns all quotas
+func (cs *Store) ListBandwidthQuotas(ctx context.Context) ([]model.BandwidthQuota, error) {
+	return nil, unsupported
+}
+
+//
+// Exclusion data
+//
+
+// UpsertExclusion adds the exclusion or replaces the existing one with the same name
+func (cs *Store) UpsertExclusion(ctx context.Context, x model.Exclusion) error {
+	return unsupported
+}
+
+// RemoveExclusion deletes the named exclusion
+func (cs *Store) RemoveExclusion(ctx context.Context, name string) error {
+	return unsupported
+}
+
+// ListExclusions returns all exclusions
+func (cs *Store) ListExclusions(ctx context.Context) ([]model.Exclusion, error) {
+	return nil, unsupported
+}
+
+//
+// Dependency data
+//
+
+// UpsertDependency adds the dependency or replaces the existing one with the same name
+func (cs *Store) UpsertDependency(ctx context.Context, dep model.Dependency) error {
+	return unsupported
+}
+
+// RemoveDependency deletes the named dependency
+func (cs *Store) RemoveDependency(ctx context.Context, name string) error {
+	return unsupported
+}
+
+// ListDependencies returns all dependencies
+func (cs *Store) ListDependencies(ctx context.Context) ([]model.Dependency, error) {
+	return nil, unsupported
+}
+
+//
+// Port profile data
+//
+
+// UpsertPortProfile adds the port profile or replaces the existing one with the same name
+func (cs *Store) UpsertPortProfile(ctx context.Context, pp model.PortProfile) error {
+	return unsupported
+}
+
+// RemovePortProfile deletes the named port profile
+func (cs *Store) RemovePortProfile(ctx context.Context, name string) error {
+	return unsupported
+}
+
+// ListPortProfiles returns all port profiles
+func (cs *Store) ListPortProfiles(ctx context.Context) ([]model.PortProfile, error) {
+	return nil, unsupported
+}
+
+//
+// Full port scan data
+//
+
+// UpsertFullPortScan stores the progress of the sweep of the device's ports
+func (cs *Store) UpsertFullPortScan(ctx context.Context, fs model.FullPortScan) error {
+	return unsupported
+}
+
+// RemoveFullPortScan deletes the progress of the sweep of the device's ports
+func (cs *Store) RemoveFullPortScan(ctx context.Context, addr model.Addr) error {
+	return unsupported
+}
+
+// ListFullPortScans returns the progress of every sweep
+func (cs *Store) ListFullPortScans(ctx context.Context) ([]model.FullPortScan, error) {
+	return nil, unsupported
+}
+
 //
 // Timeseries data
 //
@@ -147,3 +603,17 @@ func (cs *Store) ReadPerformancePings(
 ) (points []pinger.Point, err error) {
 	return nil, unsupported
 }
+
+// MergePerformancePings moves the ping history of the from addr onto the to addr
+func (cs *Store) MergePerformancePings(ctx context.Context, from, to model.Addr) error {
+	return unsupported
+}
+
+//
+// Consistency
+//
+
+// CheckConsistency looks for inconsistent records in the store
+func (cs *Store) CheckConsistency(ctx context.Context, repair bool) ([]model.ConsistencyIssue, error) {
+	return nil, unsupported
+}
@@ -14,6 +14,13 @@ type Config struct {
 	Enabled      bool
 	Directory    string
 	WSPRetention string
+	// JournalCompact is the number of journaled device changes after which the devices file is
+	// rewritten and the journal emptied
+	JournalCompact int
+
+	// ExternalTimeseries is set when another engine keeps the performance pings, the missing
+	// whisper files of pinged devices are then not reported
+	ExternalTimeseries bool
 }
 
 func SetFlags(fs *pflag.FlagSet, cfg *Config) {
@@ -43,4 +50,12 @@ func SetFlags(fs *pflag.FlagSet, cfg *Config) {
 		"10m:3d,1h:3w",
 		"whisper retention settings",
 	)
+	flagset.Int(
+		fs,
+		&cfg.JournalCompact,
+		configMajorKey,
+		"journalcompact",
+		1000,
+		"device changes journaled before the devices file is rewritten and the journal emptied",
+	)
 }
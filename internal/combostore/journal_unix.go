@@ -0,0 +1,247 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build linux || freebsd || openbsd || darwin
+
+package combostore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// The device changes are appended to a journal instead of rewriting the devices file on every
+// change.  Each record is the full device after the change, or its removal, so replaying a
+// record twice leaves the same devices.  The journal is replayed over the devices file when
+// the store is opened and emptied once the devices file is rewritten.
+//
+// A record is its length and crc32 as big endian uint32s followed by the msgpack encoded
+// entry.  A record cut short or damaged by a power loss ends the replay, the journal is
+// truncated to the records before it.
+
+const (
+	journalFilename = "devices.journal"
+
+	journalPut    = "put"
+	journalRemove = "remove"
+
+	journalHeaderSize = 8
+)
+
+var (
+	ErrJournalRecordTooLarge = errors.New("combostore journal record too large")
+	ErrJournalChecksum       = errors.New("combostore journal record checksum mismatch")
+)
+
+type journalEntry struct {
+	Op     string
+	Device model.Device
+}
+
+// writeFileAtomic replaces the file with the data, readers see either the old or the new file
+// and never a partial write.  The data is written to a temporary file of the same directory
+// which is synced and renamed over the file.
+func writeFileAtomic(filename string, data []byte) (err error) {
+	dir := filepath.Dir(filename)
+	tmp, err := os.CreateTemp(dir, filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	_, err = tmp.Write(data)
+	if err != nil {
+		return err
+	}
+	err = tmp.Chmod(0o644)
+	if err != nil {
+		return err
+	}
+	err = tmp.Sync()
+	if err != nil {
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp.Name(), filename)
+	if err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir makes a rename within the directory durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// appendJournal writes the entry to the end of the journal and syncs it
+func (cs *Store) appendJournal(entry journalEntry) error {
+	payload, err := msgpack.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if cs.journal == nil {
+		cs.journal, err = os.OpenFile(
+			cs.journalPath(),
+			os.O_WRONLY|os.O_CREATE|os.O_APPEND,
+			0o644,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	record := make([]byte, journalHeaderSize, journalHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	record = append(record, payload...)
+	_, err = cs.journal.Write(record)
+	if err != nil {
+		return err
+	}
+	err = cs.journal.Sync()
+	if err != nil {
+		return err
+	}
+	cs.journalEntries++
+	if cs.journalCompact > 0 && cs.journalEntries >= cs.journalCompact {
+		return cs.saveDevices()
+	}
+	return nil
+}
+
+// journalDevice records the device as stored
+func (cs *Store) journalDevice(device model.Device) error {
+	return cs.appendJournal(journalEntry{Op: journalPut, Device: device})
+}
+
+// journalRemove records the removal of the device
+func (cs *Store) journalRemove(addr model.Addr) error {
+	return cs.appendJournal(journalEntry{Op: journalRemove, Device: model.Device{Addr: addr}})
+}
+
+// replayJournal applies the journal to the devices read from the devices file, a damaged
+// tail is cut off
+func (cs *Store) replayJournal() error {
+	f, err := os.OpenFile(cs.journalPath(), os.O_RDWR, 0o644)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	entries, good, err := readJournal(bufio.NewReader(f))
+	if err != nil {
+		logger.Warn(
+			"combostore journal damaged, dropping its tail",
+			"file", cs.journalPath(),
+			"records", len(entries),
+			"error", err,
+		)
+		err = f.Truncate(good)
+		if err != nil {
+			return err
+		}
+	}
+	for _, e := range entries {
+		cs.devices = applyJournalEntry(cs.devices, e)
+	}
+	cs.journalEntries = len(entries)
+	return nil
+}
+
+// readJournal returns the entries of the journal up to the first damaged record, along with
+// the offset of the end of the last good record and the error of the damaged one
+func readJournal(r io.Reader) ([]journalEntry, int64, error) {
+	entries := make([]journalEntry, 0)
+	var offset int64
+	header := make([]byte, journalHeaderSize)
+	for {
+		_, err := io.ReadFull(r, header)
+		if errors.Is(err, io.EOF) {
+			return entries, offset, nil
+		}
+		if err != nil {
+			return entries, offset, err
+		}
+		size := binary.BigEndian.Uint32(header[0:4])
+		if size > 64<<20 {
+			return entries, offset, ErrJournalRecordTooLarge
+		}
+		payload := make([]byte, size)
+		_, err = io.ReadFull(r, payload)
+		if err != nil {
+			return entries, offset, err
+		}
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+			return entries, offset, ErrJournalChecksum
+		}
+		var e journalEntry
+		err = msgpack.Unmarshal(payload, &e)
+		if err != nil {
+			return entries, offset, err
+		}
+		entries = append(entries, e)
+		offset += int64(journalHeaderSize) + int64(size)
+	}
+}
+
+func applyJournalEntry(devices []model.Device, e journalEntry) []model.Device {
+	idx := slices.IndexFunc(devices, func(d model.Device) bool {
+		return d.Addr.Compare(e.Device.Addr) == 0
+	})
+	switch {
+	case e.Op == journalRemove && idx >= 0:
+		return slices.Delete(devices, idx, idx+1)
+	case e.Op == journalPut && idx >= 0:
+		devices[idx] = e.Device
+	case e.Op == journalPut:
+		devices = append(devices, e.Device)
+	}
+	return devices
+}
+
+// truncateJournal empties the journal once its changes are in the devices file
+func (cs *Store) truncateJournal() error {
+	cs.journalEntries = 0
+	if cs.journal != nil {
+		err := cs.journal.Close()
+		cs.journal = nil
+		if err != nil {
+			return err
+		}
+	}
+	err := os.Remove(cs.journalPath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (cs *Store) journalPath() string {
+	return filepath.Join(cs.directory, cs.journalfile)
+}
@@ -0,0 +1,111 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build linux || freebsd || openbsd || darwin
+
+package combostore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func openTestStore(t *testing.T, dir string, compact int) *Store {
+	t.Helper()
+	cs, err := New(&Config{
+		Directory:          dir,
+		WSPRetention:       "10m:3d",
+		JournalCompact:     compact,
+		ExternalTimeseries: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cs
+}
+
+func deviceNames(cs *Store) []string {
+	names := make([]string, 0)
+	for _, d := range cs.ListDevices(context.Background()) {
+		names = append(names, d.Addr.String()+"="+d.Name)
+	}
+	return names
+}
+
+func TestJournal_Replay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	cs := openTestStore(t, dir, 100)
+	for _, addr := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		err := cs.AddDevice(ctx, model.Device{Addr: model.MustParseAddr(addr), Name: addr})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := cs.SetDeviceDetails(ctx, model.MustParseAddr("10.0.0.2"), model.DeviceDetails{Name: "nas"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cs.RemoveDeviceByAddr(ctx, model.MustParseAddr("10.0.0.3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, cs.devicefilename)); !os.IsNotExist(err) {
+		t.Fatalf("devices file written before compaction: %v", err)
+	}
+
+	// a power loss mid append leaves a partial record after the good ones
+	f, err := os.OpenFile(filepath.Join(dir, journalFilename), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.Write([]byte{0, 0, 1, 0, 1, 2})
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := openTestStore(t, dir, 100)
+	got := deviceNames(reopened)
+	want := []string{"10.0.0.1=10.0.0.1", "10.0.0.2=nas"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("devices want: %v, got: %v", want, got)
+	}
+	// the replayed changes are written to the devices file and the journal emptied
+	if _, err := os.Stat(filepath.Join(dir, journalFilename)); !os.IsNotExist(err) {
+		t.Errorf("journal left after replay: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, cs.devicefilename)); err != nil {
+		t.Errorf("devices file missing after replay: %v", err)
+	}
+}
+
+func TestJournal_Compact(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	cs := openTestStore(t, dir, 2)
+	for _, addr := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		err := cs.AddDevice(ctx, model.Device{Addr: model.MustParseAddr(addr), Name: addr})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cs.journalEntries != 1 {
+		t.Errorf("journal entries want: 1, got: %d", cs.journalEntries)
+	}
+	err := cs.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, journalFilename)); !os.IsNotExist(err) {
+		t.Errorf("journal left after close: %v", err)
+	}
+	if got := len(openTestStore(t, dir, 2).devices); got != 3 {
+		t.Errorf("devices want: 3, got: %d", got)
+	}
+}
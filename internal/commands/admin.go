@@ -0,0 +1,162 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+
+	"github.com/networkables/mason/internal/backup"
+	"github.com/networkables/mason/internal/server"
+	"github.com/networkables/mason/internal/sqlitestore"
+)
+
+var (
+	cmdAdmin = &cobra.Command{
+		Use:   "admin",
+		Short: "datastore administration",
+	}
+
+	cmdAdminBackup = &cobra.Command{
+		Use:   "backup [file]",
+		Short: "write an archive of the datastore, to stdout with -",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdAdminBackup(args)
+		},
+	}
+
+	cmdAdminRestore = &cobra.Command{
+		Use:   "restore [file]",
+		Short: "replace the datastore with the archive, the server must be stopped",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdAdminRestore(args)
+		},
+	}
+
+	cmdAdminMigrate = &cobra.Command{
+		Use:   "migrate [version]",
+		Short: "migrate the sqlite schema to the version, the newest by default, the server must be stopped",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdAdminMigrate(args)
+		},
+	}
+
+	cmdAdminMigrations = &cobra.Command{
+		Use:   "migrations",
+		Short: "list the sqlite schema migrations and when they were applied",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdAdminMigrations()
+		},
+	}
+)
+
+func init() {
+	cmdAdmin.AddCommand(cmdAdminBackup)
+	cmdAdmin.AddCommand(cmdAdminRestore)
+	cmdAdmin.AddCommand(cmdAdminMigrate)
+	cmdAdmin.AddCommand(cmdAdminMigrations)
+}
+
+func runCmdAdminBackup(args []string) error {
+	m, closefn, err := openStoreMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	filename := backup.Filename(time.Now())
+	if len(args) > 0 {
+		filename = args[0]
+	}
+	var w io.Writer = os.Stdout
+	if filename != "-" {
+		f, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	manifest, err := m.Backup(context.Background(), w)
+	if err != nil {
+		if filename != "-" {
+			os.Remove(filename)
+		}
+		return err
+	}
+	for _, s := range manifest.Stores {
+		log.Info("store backed up", "store", s.Name, "schema", s.Schema)
+	}
+	if filename != "-" {
+		log.Info("backup written", "file", filename)
+	}
+	return nil
+}
+
+func runCmdAdminRestore(args []string) error {
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	manifest, err := server.Restore(server.GetConfig(), f)
+	if err != nil {
+		return err
+	}
+	for _, s := range manifest.Stores {
+		log.Info("store restored", "store", s.Name, "schema", s.Schema)
+	}
+	log.Info("backup restored", "version", manifest.Version, "created", manifest.Created)
+	return nil
+}
+
+func runCmdAdminMigrate(args []string) error {
+	target := -1
+	if len(args) > 0 {
+		var err error
+		target, err = strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+	}
+	ran, err := sqlitestore.Migrate(server.GetConfig().Store.Sqlite, target)
+	for _, m := range ran {
+		log.Info("migration", "version", m.Version, "name", m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(ran) == 0 {
+		log.Info("schema is up to date")
+	}
+	return nil
+}
+
+func runCmdAdminMigrations() error {
+	status, err := sqlitestore.Migrations(server.GetConfig().Store.Sqlite)
+	if err != nil {
+		return err
+	}
+	for _, m := range status {
+		applied := "pending"
+		if !m.Applied.IsZero() {
+			applied = m.Applied.Local().Format(time.DateTime)
+		}
+		fmt.Printf("%4d %-24s %s\n", m.Version, m.Name, applied)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+
+	"github.com/networkables/mason/internal/agent"
+	"github.com/networkables/mason/internal/ratelimit"
+	"github.com/networkables/mason/internal/server"
+)
+
+var cmdAgent = &cobra.Command{
+	Use:   "agent",
+	Short: "scan remote networks and report to a mason server",
+	Long: `scan the networks given by --agent.networks and report the devices found to the
+mason server at --agent.server, the server must be started with the same --agent.token`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCmdAgent()
+	},
+}
+
+func runCmdAgent() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := server.GetConfig()
+	usePrivilegedHelper(cfg)
+	a, err := agent.New(cfg.Agent, cfg.Discovery, cfg.Pinger, ratelimit.NewGroup(cfg.RateLimit))
+	if err != nil {
+		return err
+	}
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-done
+		log.Info("caught interrupt signal, stopping agent")
+		cancel()
+	}()
+
+	log.Info("starting agent", "server", cfg.Agent.Server, "networks", cfg.Agent.Networks)
+	a.Run(ctx)
+	return nil
+}
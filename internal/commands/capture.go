@@ -0,0 +1,183 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+	"github.com/networkables/mason/pkg/client"
+)
+
+// capturePollInterval is how often the state of a capture on a server is checked
+const capturePollInterval = time.Second
+
+var (
+	flagCaptureFilter   string
+	flagCaptureDuration time.Duration
+	flagCaptureMaxSize  string
+	flagCaptureOutput   string
+	cmdToolCapture      = &cobra.Command{
+		Use:   "capture [interface]",
+		Short: "capture the packets on an interface to a pcap file, on the server with --remote",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdToolCapture(args)
+		},
+	}
+)
+
+func init() {
+	cmdToolCapture.Flags().
+		StringVarP(&flagCaptureFilter, "filter", "f", "", "packets kept, ex: host 192.168.1.20 and port 53")
+	cmdToolCapture.Flags().
+		DurationVarP(&flagCaptureDuration, "duration", "d", 30*time.Second, "how long to capture, 0 for the server limit")
+	cmdToolCapture.Flags().
+		StringVar(&flagCaptureMaxSize, "maxsize", "10MB", "largest pcap file written")
+	cmdToolCapture.Flags().
+		StringVarP(&flagCaptureOutput, "output", "w", "", "pcap file written, defaults to the interface and time")
+}
+
+func runCmdToolCapture(args []string) error {
+	req := model.PacketCaptureRequest{
+		Interface: args[0],
+		Filter:    flagCaptureFilter,
+		Duration:  flagCaptureDuration,
+	}
+	var err error
+	if flagCaptureMaxSize != "" {
+		req.MaxBytes, err = model.ParseQuotaBytes(flagCaptureMaxSize)
+		if err != nil {
+			return err
+		}
+	}
+	output := flagCaptureOutput
+	if output == "" {
+		output = fmt.Sprintf("%s-%s.pcap", req.Interface, time.Now().Format("20060102-150405"))
+	}
+
+	// an interrupt ends the capture early, the packets so far are kept
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	var c model.PacketCapture
+	if remote := remoteServer(); remote != "" {
+		c, err = runRemoteCapture(ctx, remote, req, output)
+	} else {
+		c, err = runLocalCapture(ctx, req, output)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("captured %d packets (%s) to %s\n", c.Packets, c.Size(), output)
+	return nil
+}
+
+func runLocalCapture(
+	ctx context.Context,
+	req model.PacketCaptureRequest,
+	output string,
+) (model.PacketCapture, error) {
+	c := model.PacketCapture{Interface: req.Interface}
+	filter, err := nettools.ParseCaptureFilter(req.Filter)
+	if err != nil {
+		return c, err
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return c, err
+	}
+	fmt.Printf("capturing on %s for %s, ctrl-c to stop\n", req.Interface, req.Duration)
+	stats, err := nettools.Capture(ctx, req.Interface, nettools.CaptureOptions{
+		Filter:   filter,
+		Duration: req.Duration,
+		MaxBytes: req.MaxBytes,
+	}, f)
+	c.Packets, c.Bytes = stats.Packets, stats.Bytes
+	if errors.Is(err, context.Canceled) {
+		err = nil
+	}
+	return c, errors.Join(err, f.Close())
+}
+
+// runRemoteCapture starts the capture on the server, waits for it to finish and downloads
+// the file.  An interrupt stops the capture on the server before the download.
+func runRemoteCapture(
+	ctx context.Context,
+	remote string,
+	req model.PacketCaptureRequest,
+	output string,
+) (model.PacketCapture, error) {
+	cl, err := client.New(remote)
+	if err != nil {
+		return model.PacketCapture{}, err
+	}
+	c, err := cl.StartCapture(ctx, req)
+	if err != nil {
+		return c, err
+	}
+	fmt.Printf("capture %s started on %s for %s, ctrl-c to stop\n", c.ID, remote, c.Duration)
+
+	// the requests after an interrupt use their own context
+	bg := context.Background()
+	ticker := time.NewTicker(capturePollInterval)
+	defer ticker.Stop()
+	for c.State == model.CaptureRunning {
+		select {
+		case <-ctx.Done():
+			err = cl.StopCapture(bg, c.ID)
+			if err != nil && !errors.Is(err, client.ErrUnexpectedStatus) {
+				return c, err
+			}
+			ctx = bg
+		case <-ticker.C:
+		}
+		c, err = findCapture(bg, cl, c.ID)
+		if err != nil {
+			return c, err
+		}
+		fmt.Printf("\r%d packets, %s", c.Packets, humanize.Bytes(uint64(c.Bytes)))
+	}
+	fmt.Println()
+	if c.State == model.CaptureFailed {
+		return c, fmt.Errorf("capture %s failed: %s", c.ID, c.Err)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return c, err
+	}
+	err = cl.DownloadCapture(bg, c.ID, f)
+	return c, errors.Join(err, f.Close())
+}
+
+func findCapture(ctx context.Context, cl *client.Client, id string) (model.PacketCapture, error) {
+	captures, err := cl.Captures(ctx)
+	if err != nil {
+		return model.PacketCapture{}, err
+	}
+	for _, c := range captures {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return model.PacketCapture{}, model.ErrCaptureDoesNotExist
+}
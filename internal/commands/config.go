@@ -0,0 +1,183 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/networkables/mason/internal/server"
+)
+
+// configSource is where the value of a setting comes from, in the order viper prefers them
+type configSource string
+
+const (
+	configSourceFlag    configSource = "flag"
+	configSourceEnv     configSource = "env"
+	configSourceFile    configSource = "file"
+	configSourceDefault configSource = "default"
+)
+
+// configSecrets end the keys of the settings holding credentials, their values are not printed
+var configSecrets = []string{"password", "token", "key", "secret", "webhookurl", "community"}
+
+// configNotSecrets are the settings named like a credential which are not one
+var configNotSecrets = []string{"identity.key"}
+
+var (
+	cmdConfig = &cobra.Command{
+		Use:   "config",
+		Short: "check and show the settings mason runs with",
+	}
+
+	cmdConfigCheck = &cobra.Command{
+		Use:   "check",
+		Short: "check the merged settings of the defaults, config file, environment and flags",
+		// the problems are the output, the usage would bury them
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdConfigCheck(args)
+		},
+	}
+
+	flagConfigEnvTemplateChanged bool
+	cmdConfigEnvTemplate         = &cobra.Command{
+		Use:   "env-template",
+		Short: "write an env file with a MASON_ variable for every setting, such as for docker --env-file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdConfigEnvTemplate(args)
+		},
+	}
+
+	flagConfigPrintEffective bool
+	cmdConfigPrint           = &cobra.Command{
+		Use:   "print",
+		Short: "print the settings changed from the defaults and where each is set",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdConfigPrint(args)
+		},
+	}
+)
+
+func init() {
+	cmdConfig.AddCommand(cmdConfigCheck)
+	cmdConfig.AddCommand(cmdConfigPrint)
+	cmdConfig.AddCommand(cmdConfigEnvTemplate)
+
+	cmdConfigPrint.Flags().
+		BoolVar(&flagConfigPrintEffective, "effective", false, "print every setting, the defaults included")
+	cmdConfigEnvTemplate.Flags().
+		BoolVar(&flagConfigEnvTemplateChanged, "changed", false, "only write the settings changed from the defaults, with their current values")
+}
+
+func runCmdConfigCheck([]string) error {
+	file := viper.ConfigFileUsed()
+	if file == "" {
+		file = "none"
+	}
+	problems := server.GetConfig().Problems()
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("config has %d problems", len(problems))
+	}
+	log.Info("config ok", "file", file)
+	return nil
+}
+
+func runCmdConfigPrint([]string) error {
+	if file := viper.ConfigFileUsed(); file != "" {
+		fmt.Println("# config file:", file)
+	}
+	keys := viper.AllKeys()
+	slices.Sort(keys)
+	printed := 0
+	for _, key := range keys {
+		source := settingSource(key)
+		if source == configSourceDefault && !flagConfigPrintEffective {
+			continue
+		}
+		fmt.Printf("%-48s %-8s %s\n", key, source, settingValue(key))
+		printed++
+	}
+	if printed == 0 {
+		fmt.Println("# no settings changed from the defaults, use --effective to print them all")
+	}
+	return nil
+}
+
+// runCmdConfigEnvTemplate writes each setting as a commented out variable set to its default,
+// with --changed the settings of the config file, environment and flags are written set
+func runCmdConfigEnvTemplate([]string) error {
+	fmt.Println("# mason settings, flags take precedence over these and these over the config file")
+	cmdRoot.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		// --debug and --remote are options of the cli, not settings
+		if !strings.Contains(f.Name, ".") {
+			return
+		}
+		changed := settingSource(f.Name) != configSourceDefault
+		if flagConfigEnvTemplateChanged && !changed {
+			return
+		}
+		fmt.Printf("\n# %s\n", f.Usage)
+		if changed {
+			fmt.Printf("%s=%s\n", configEnvName(f.Name), envValue(f.Value))
+			return
+		}
+		fmt.Printf("#%s=%s\n", configEnvName(f.Name), envValue(f.Value))
+	})
+	return nil
+}
+
+// configEnvName is the environment variable viper reads the setting from
+func configEnvName(key string) string {
+	return "MASON_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// envValue formats the flag as its variable is parsed, lists are comma separated
+func envValue(v pflag.Value) string {
+	if strings.HasSuffix(v.Type(), "Slice") {
+		return strings.TrimSuffix(strings.TrimPrefix(v.String(), "["), "]")
+	}
+	return v.String()
+}
+
+// settingSource finds where viper takes the value of the setting from
+func settingSource(key string) configSource {
+	if f := cmdRoot.PersistentFlags().Lookup(key); f != nil && f.Changed {
+		return configSourceFlag
+	}
+	if _, ok := os.LookupEnv(configEnvName(key)); ok {
+		return configSourceEnv
+	}
+	if viper.InConfig(key) {
+		return configSourceFile
+	}
+	return configSourceDefault
+}
+
+// settingValue formats the value of the setting, credentials are masked
+func settingValue(key string) string {
+	val := fmt.Sprint(viper.Get(key))
+	if slices.Contains(configNotSecrets, key) {
+		return val
+	}
+	name := key[strings.LastIndex(key, ".")+1:]
+	for _, secret := range configSecrets {
+		if strings.HasSuffix(name, secret) && val != "" && val != "[]" {
+			return "********"
+		}
+	}
+	return val
+}
@@ -5,7 +5,19 @@
 package commands
 
 import (
+	"context"
+	"errors"
+	"fmt"
+
 	"github.com/spf13/cobra"
+
+	"github.com/networkables/mason/pkg/client"
+)
+
+// errLogNeedsServer is returned for log levels without --remote, they belong to the running
+// server
+var errLogNeedsServer = errors.New(
+	"log levels are changed on a running server, use --remote or $" + remoteServerEnv,
 )
 
 var (
@@ -24,10 +36,68 @@ var (
 			return runCmdDebugDumpWSP(args)
 		},
 	}
+
+	flagDebugLogProbes bool
+	cmdDebugLog        = &cobra.Command{
+		Use:   "log [subsystem|all] [level]",
+		Short: "list or change the log levels and probe traces of the subsystems of a running server",
+		Args:  cobra.MaximumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdDebugLog(args, cmd.Flags().Changed("probes"))
+		},
+	}
 )
 
 func init() {
 	cmdDebug.AddCommand(cmdDebugDumpWSP)
+	cmdDebug.AddCommand(cmdDebugLog)
+
+	cmdDebugLog.Flags().
+		BoolVar(&flagDebugLogProbes, "probes", false, "trace the probes of the subsystem, --probes=false stops")
+}
+
+func runCmdDebugLog(args []string, probesChanged bool) error {
+	remote := remoteServer()
+	if remote == "" {
+		return errLogNeedsServer
+	}
+	c, err := client.New(remote)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	var levels []client.LogLevel
+	if len(args) == 0 {
+		levels, err = c.LogLevels(ctx)
+	} else {
+		change := client.LogLevelChange{Subsystem: args[0]}
+		if change.Subsystem == "all" {
+			change.Subsystem = ""
+		}
+		if len(args) > 1 {
+			change.Level = args[1]
+		}
+		if probesChanged {
+			change.Probes = &flagDebugLogProbes
+		}
+		levels, err = c.SetLogLevel(ctx, change)
+	}
+	if err != nil {
+		return err
+	}
+	for _, l := range levels {
+		name := l.Subsystem
+		if name == "" {
+			name = "all"
+		}
+		probes := ""
+		if l.Probes {
+			probes = "probes traced"
+		}
+		fmt.Printf("%-10s %-6s %s\n", name, l.Level, probes)
+	}
+	return nil
 }
 
 func runCmdDebugDumpWSP([]string) error {
@@ -0,0 +1,152 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/server"
+)
+
+var (
+	cmdDelete = &cobra.Command{
+		Use:   "delete",
+		Short: "delete devices and networks, deletes can be undone until the grace period ends",
+	}
+
+	cmdDeleteDevice = &cobra.Command{
+		Use:   "device [addr]",
+		Short: "delete a device",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdDeleteDevice(args)
+		},
+	}
+
+	cmdDeleteNetwork = &cobra.Command{
+		Use:   "network [name]",
+		Short: "delete a network",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdDeleteNetwork(args)
+		},
+	}
+
+	cmdDeleted = &cobra.Command{
+		Use:   "deleted",
+		Short: "manage deleted devices and networks",
+	}
+
+	cmdDeletedList = &cobra.Command{
+		Use:   "list",
+		Short: "list deleted devices and networks which can be restored",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdDeletedList(args)
+		},
+	}
+
+	cmdDeletedRestore = &cobra.Command{
+		Use:   "restore [device|network] [key]",
+		Short: "undo the delete of a device (by addr) or network (by name)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdDeletedRestore(args)
+		},
+	}
+
+	cmdDeletedPurge = &cobra.Command{
+		Use:   "purge",
+		Short: "permanently remove deleted items whose grace period has passed",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdDeletedPurge(args)
+		},
+	}
+)
+
+func init() {
+	cmdDelete.AddCommand(cmdDeleteDevice, cmdDeleteNetwork)
+	cmdDeleted.AddCommand(cmdDeletedList, cmdDeletedRestore, cmdDeletedPurge)
+}
+
+func runCmdDeleteDevice(args []string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	addr, err := model.ParseAddr(args[0])
+	if err != nil {
+		return err
+	}
+	return m.RemoveDevice(context.Background(), addr)
+}
+
+func runCmdDeleteNetwork(args []string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	return m.RemoveNetwork(context.Background(), args[0])
+}
+
+func runCmdDeletedList([]string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	ts, err := m.ListDeleted(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, t := range ts {
+		fmt.Printf(
+			"%-8s %-40s deleted:%s purge:%s\n",
+			t.Kind,
+			t.Key,
+			t.DeletedAt.Format(time.DateTime),
+			t.PurgeAt.Format(time.DateTime),
+		)
+	}
+	return nil
+}
+
+func runCmdDeletedRestore(args []string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	kind, err := model.ParseTombstoneKind(args[0])
+	if err != nil {
+		return err
+	}
+	return m.RestoreDeleted(context.Background(), kind, args[1])
+}
+
+func runCmdDeletedPurge([]string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	count, err := m.PurgeDeleted(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("purged %d deleted items\n", count)
+	return nil
+}
@@ -0,0 +1,454 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/server"
+)
+
+var (
+	flagDeviceJSON bool
+	flagDeviceType string
+	cmdDevice      = &cobra.Command{
+		Use:   "device",
+		Short: "list, inspect and manage devices",
+	}
+
+	cmdDeviceList = &cobra.Command{
+		Use:   "list",
+		Short: "list all devices",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdDeviceList(args)
+		},
+	}
+
+	cmdDeviceShow = &cobra.Command{
+		Use:   "show [addr]",
+		Short: "show the details of a device",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdDeviceShow(args)
+		},
+	}
+
+	cmdDeviceRemove = &cobra.Command{
+		Use:   "rm [addr]",
+		Short: "delete a device, it can be restored until the grace period ends",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdDeleteDevice(args)
+		},
+	}
+
+	cmdDeviceTag = &cobra.Command{
+		Use:   "tag [addr] [tag]",
+		Short: "add (or with --remove remove) a tag on a device",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdTagDevice(args)
+		},
+	}
+
+	cmdDeviceEnrich = &cobra.Command{
+		Use:   "enrich [addr]",
+		Short: "look up the dns name, manufacturer, open ports and snmp details of a device again",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdDeviceEnrich(args)
+		},
+	}
+
+	cmdDevicePingNow = &cobra.Command{
+		Use:   "pingnow [addr]",
+		Short: "performance ping a device now instead of waiting for its interval",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdDevicePingNow(args)
+		},
+	}
+
+	flagDevicePingInterval     time.Duration
+	flagDevicePortScanInterval time.Duration
+	cmdDevicePolicy            = &cobra.Command{
+		Use:   "policy [addr]",
+		Short: "set the monitoring intervals of a device, 0 uses the tag or global interval",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdDevicePolicy(args)
+		},
+	}
+
+	flagDeviceDetails model.DeviceDetails
+	cmdDeviceDetails  = &cobra.Command{
+		Use:   "details [addr]",
+		Short: "set the name, owner, notes, site and asset fields of a device, unset flags keep their value",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdDeviceDetails(cmd, args)
+		},
+	}
+
+	cmdDeviceHistory = &cobra.Command{
+		Use:   "history [addr]",
+		Short: "list the recorded field changes of a device, newest first",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdDeviceHistory(args)
+		},
+	}
+
+	cmdDeviceApproval = &cobra.Command{
+		Use:   "approval [addr] [unknown|approved|blocked]",
+		Short: "set the approval state of a device",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdDeviceApproval(args)
+		},
+	}
+
+	cmdDeviceReview = &cobra.Command{
+		Use:   "review",
+		Short: "list the devices waiting for approval",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdDeviceReview(args)
+		},
+	}
+)
+
+func init() {
+	cmdDevice.AddCommand(cmdDeviceList)
+	cmdDevice.AddCommand(cmdDeviceShow)
+	cmdDevice.AddCommand(cmdDeviceRemove)
+	cmdDevice.AddCommand(cmdDeviceTag)
+	cmdDevice.AddCommand(cmdDeviceEnrich)
+	cmdDevice.AddCommand(cmdDevicePingNow)
+	cmdDevice.AddCommand(cmdDevicePolicy)
+	cmdDevice.AddCommand(cmdDeviceDetails)
+	cmdDevice.AddCommand(cmdDeviceHistory)
+	cmdDevice.AddCommand(cmdDeviceApproval)
+	cmdDevice.AddCommand(cmdDeviceReview)
+
+	cmdDevice.PersistentFlags().
+		BoolVar(&flagDeviceJSON, "json", false, "write json instead of a table")
+	cmdDeviceList.Flags().
+		StringVar(&flagDeviceType, "type", "", "only list the devices of the type (phone, printer, camera, server, iot, ...)")
+	cmdDeviceTag.Flags().BoolVar(&flagTagRemove, "remove", false, "remove the tag")
+	cmdDevicePolicy.Flags().
+		DurationVar(&flagDevicePingInterval, "ping", 0, "ping interval for the device, 0 for the default")
+	cmdDevicePolicy.Flags().
+		DurationVar(&flagDevicePortScanInterval, "portscan", 0, "port scan interval for the device, 0 for the default")
+	detailFlags := cmdDeviceDetails.Flags()
+	detailFlags.StringVar(&flagDeviceDetails.Name, "name", "", "name of the device")
+	detailFlags.StringVar(&flagDeviceDetails.Owner, "owner", "", "who to ask about the device")
+	detailFlags.StringVar(&flagDeviceDetails.Notes, "notes", "", "free form notes")
+	detailFlags.StringVar(&flagDeviceDetails.Site, "site", "", "site of the device, blank places it by its network")
+	detailFlags.StringVar(&flagDeviceDetails.Location, "location", "", "room, rack or desk of the device")
+	detailFlags.StringVar(&flagDeviceDetails.PurchaseDate, "purchased", "", "purchase date as yyyy-mm-dd")
+	detailFlags.StringVar(&flagDeviceDetails.Serial, "serial", "", "serial number")
+	detailFlags.StringVar(&flagDeviceDetails.AssetTag, "assettag", "", "asset tag")
+}
+
+func runCmdDeviceList([]string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	devs, err := m.ListDevices(context.Background())
+	if err != nil {
+		return err
+	}
+	if flagDeviceType != "" {
+		filter := model.DeviceTypeFilter(model.DeviceType(strings.ToLower(flagDeviceType)))
+		devs = slices.DeleteFunc(devs, func(d model.Device) bool { return !filter(d) })
+	}
+	if flagDeviceJSON {
+		return writeJSON(devs)
+	}
+	for _, d := range devs {
+		printDeviceRow(d)
+	}
+	return nil
+}
+
+func printDeviceRow(d model.Device) {
+	fmt.Printf(
+		"%-16s %-18s %-30s %-8s %-8s %-4s %s\n",
+		d.Addr,
+		d.MAC,
+		d.Name,
+		d.Meta.Approval,
+		d.Meta.DeviceType,
+		pingState(d),
+		tagNames(d.Meta.Tags),
+	)
+}
+
+func runCmdDeviceShow(args []string) error {
+	return deviceAction(args[0], func(m masonAPI, addr model.Addr) (model.Device, error) {
+		return m.GetDeviceByAddr(context.Background(), addr)
+	})
+}
+
+func runCmdDeviceEnrich(args []string) error {
+	return deviceAction(args[0], func(m masonAPI, addr model.Addr) (model.Device, error) {
+		return m.EnrichDeviceNow(context.Background(), addr)
+	})
+}
+
+func runCmdDevicePingNow(args []string) error {
+	return deviceAction(args[0], func(m masonAPI, addr model.Addr) (model.Device, error) {
+		return m.PingDeviceNow(context.Background(), addr)
+	})
+}
+
+// deviceAction runs fn on the device at the address and prints the device it returns
+func deviceAction(arg string, fn func(masonAPI, model.Addr) (model.Device, error)) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	addr, err := model.ParseAddr(arg)
+	if err != nil {
+		return err
+	}
+	d, err := fn(m, addr)
+	if err != nil {
+		return err
+	}
+	if flagDeviceJSON {
+		return writeJSON(d)
+	}
+	printDevice(d)
+	return nil
+}
+
+func printDevice(d model.Device) {
+	field := func(name string, value string) {
+		if value != "" {
+			fmt.Printf("%-14s %s\n", name, value)
+		}
+	}
+	field("name", d.Name)
+	field("addr", d.Addr.String())
+	field("addresses", strings.ReplaceAll(d.Addresses.AddrsString(), " ", ", "))
+	field("mac", d.MAC.String())
+	if len(d.ObservedMACs) > 0 {
+		macs := make([]string, len(d.ObservedMACs))
+		for i, mac := range d.ObservedMACs {
+			macs[i] = mac.String()
+		}
+		field("observed macs", strings.Join(macs, " "))
+	}
+	if !d.VLAN.IsEmpty() {
+		field("vlan", d.VLAN.String())
+	}
+	field("discovered", d.DiscoveredAt.Local().Format(time.DateTime)+" by "+d.DiscoveredBy.String())
+	field("approval", string(d.Meta.Approval))
+	field("type", string(d.Meta.DeviceType))
+	field("site", d.Meta.Site)
+	field("owner", d.Meta.Owner)
+	field("location", d.Meta.Location)
+	field("purchased", d.Meta.PurchaseDate)
+	field("serial", d.Meta.Serial)
+	field("asset tag", d.Meta.AssetTag)
+	field("tags", tagNames(d.Meta.Tags))
+	if !d.Meta.Policy.IsEmpty() {
+		field("policy", fmt.Sprintf(
+			"ping:%s portscan:%s",
+			intervalString(d.Meta.Policy.PingInterval),
+			intervalString(d.Meta.Policy.PortScanInterval),
+		))
+	}
+	field("dns", d.Meta.DnsName)
+	field("mdns", d.Meta.MDNSName)
+	field("dhcp", d.Meta.DHCPHostname)
+	field("manufacturer", d.Meta.Manufacturer)
+	if !d.PerformancePing.LastSeen.IsZero() {
+		field("ping", fmt.Sprintf(
+			"%s mean:%s max:%s last:%s",
+			pingState(d),
+			d.PerformancePing.Mean,
+			d.PerformancePing.Maximum,
+			d.PerformancePing.LastSeen.Local().Format(time.DateTime),
+		))
+	}
+	if !d.Server.LastScan.IsZero() {
+		field("ports", d.Server.Ports.String())
+	}
+	if d.SNMP.Community != "" {
+		field("snmp", strings.TrimSpace(d.SNMP.Name+" "+d.SNMP.Description))
+	}
+	if d.Virtual.IsHost() {
+		field("virtual", fmt.Sprintf("%s host of %d guests", d.Virtual.Platform, len(d.Virtual.Guests)))
+	}
+	if d.Virtual.IsGuest() {
+		field("parent", d.Virtual.Parent.String())
+	}
+	field("notes", d.Meta.Notes)
+}
+
+func pingState(d model.Device) string {
+	switch {
+	case d.PerformancePing.LastSeen.IsZero():
+		return "-"
+	case d.PerformancePing.LastFailed:
+		return "down"
+	}
+	return "up"
+}
+
+func tagNames(tags model.Tags) string {
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.Val
+	}
+	return strings.Join(names, ",")
+}
+
+func writeJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func runCmdDevicePolicy(args []string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	addr, err := model.ParseAddr(args[0])
+	if err != nil {
+		return err
+	}
+	return m.SetDevicePolicy(context.Background(), addr, model.MonitoringPolicy{
+		PingInterval:     flagDevicePingInterval,
+		PortScanInterval: flagDevicePortScanInterval,
+	})
+}
+
+// runCmdDeviceDetails replaces the details given by flags, the others keep their value
+func runCmdDeviceDetails(cmd *cobra.Command, args []string) error {
+	return deviceAction(args[0], func(m masonAPI, addr model.Addr) (model.Device, error) {
+		ctx := context.Background()
+		d, err := m.GetDeviceByAddr(ctx, addr)
+		if err != nil {
+			return d, err
+		}
+		details := d.Details()
+		set := func(flag string, field *string, value string) {
+			if cmd.Flags().Changed(flag) {
+				*field = value
+			}
+		}
+		set("name", &details.Name, flagDeviceDetails.Name)
+		set("owner", &details.Owner, flagDeviceDetails.Owner)
+		set("notes", &details.Notes, flagDeviceDetails.Notes)
+		set("site", &details.Site, flagDeviceDetails.Site)
+		set("location", &details.Location, flagDeviceDetails.Location)
+		set("purchased", &details.PurchaseDate, flagDeviceDetails.PurchaseDate)
+		set("serial", &details.Serial, flagDeviceDetails.Serial)
+		set("assettag", &details.AssetTag, flagDeviceDetails.AssetTag)
+		err = m.SetDeviceDetails(ctx, addr, details)
+		if err != nil {
+			return d, err
+		}
+		return m.GetDeviceByAddr(ctx, addr)
+	})
+}
+
+func runCmdDeviceHistory(args []string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	addr, err := model.ParseAddr(args[0])
+	if err != nil {
+		return err
+	}
+	changes, err := m.DeviceHistory(context.Background(), addr)
+	if err != nil {
+		return err
+	}
+	if flagDeviceJSON {
+		return writeJSON(changes)
+	}
+	for _, c := range changes {
+		fmt.Printf(
+			"%s %-10s %-16s %q -> %q\n",
+			c.Time.Local().Format(time.DateTime),
+			c.Source,
+			c.Field,
+			c.Old,
+			c.New,
+		)
+	}
+	return nil
+}
+
+func runCmdDeviceApproval(args []string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	addr, err := model.ParseAddr(args[0])
+	if err != nil {
+		return err
+	}
+	state, err := model.ParseApprovalState(args[1])
+	if err != nil {
+		return err
+	}
+	return m.SetDeviceApproval(context.Background(), addr, state)
+}
+
+func runCmdDeviceReview([]string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	devs, err := m.ReviewQueue(context.Background())
+	if err != nil {
+		return err
+	}
+	if flagDeviceJSON {
+		return writeJSON(devs)
+	}
+	for _, d := range devs {
+		fmt.Printf(
+			"%-16s %-18s %-30s %s %s\n",
+			d.Addr,
+			d.MAC,
+			d.Name,
+			d.DiscoveredAt.Local().Format(time.DateTime),
+			d.DiscoveredBy,
+		)
+	}
+	return nil
+}
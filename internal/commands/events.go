@@ -0,0 +1,120 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/server"
+)
+
+const eventsTimeLayout = "2006-01-02 15:04:05"
+
+var (
+	cmdEvents = &cobra.Command{
+		Use:   "events",
+		Short: "read the event history kept with --eventhistory.enabled",
+	}
+
+	flagEventsLines    int
+	flagEventsFollow   bool
+	flagEventsKind     string
+	flagEventsSearch   string
+	flagEventsInterval time.Duration
+	cmdEventsTail      = &cobra.Command{
+		Use:   "tail",
+		Short: "print the latest events, with --follow keep printing new events as they are stored",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdEventsTail(args)
+		},
+	}
+)
+
+func init() {
+	cmdEvents.AddCommand(cmdEventsTail)
+
+	cmdEventsTail.Flags().IntVarP(&flagEventsLines, "lines", "n", 20, "number of events to print")
+	cmdEventsTail.Flags().
+		BoolVarP(&flagEventsFollow, "follow", "f", false, "keep printing new events until interrupted")
+	cmdEventsTail.Flags().StringVar(&flagEventsKind, "kind", "", "only print [event,error]")
+	cmdEventsTail.Flags().
+		StringVar(&flagEventsSearch, "search", "", "only print events with the text in the type or message")
+	cmdEventsTail.Flags().
+		DurationVar(&flagEventsInterval, "interval", time.Second, "how often --follow checks for new events")
+}
+
+func runCmdEventsTail([]string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	q := model.EventQuery{
+		Kind:   model.EventRecordKind(flagEventsKind),
+		Search: flagEventsSearch,
+		Limit:  flagEventsLines,
+	}
+	records, err := m.TailEvents(ctx, q)
+	if err != nil {
+		return err
+	}
+	printEventRecords(records)
+	if !flagEventsFollow {
+		return nil
+	}
+
+	// new events are polled from the store, the server writes them every flush interval
+	q.Limit = 0
+	if len(records) > 0 {
+		q.After = records[len(records)-1].Time
+	}
+	ticker := time.NewTicker(flagEventsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			records, err = m.TailEvents(ctx, q)
+			if err != nil {
+				return err
+			}
+			printEventRecords(records)
+			if len(records) > 0 {
+				q.After = records[len(records)-1].Time
+			}
+		}
+	}
+}
+
+func printEventRecords(records []model.EventRecord) {
+	for _, rec := range records {
+		fmt.Printf(
+			"%s %-5s %-40s %s\n",
+			rec.Time.Local().Format(eventsTimeLayout),
+			rec.Kind,
+			rec.Type,
+			rec.Message,
+		)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+
+	"github.com/networkables/mason/internal/server"
+	"github.com/networkables/mason/nettools"
+)
+
+// systemd passes the sockets of a socket unit starting from this descriptor
+const listenFdsStart = 3
+
+var cmdHelper = &cobra.Command{
+	Use:   "helper",
+	Short: "send raw icmp and arp packets for an unprivileged mason server",
+	Long: `run with net admin privileges and listen on the unix socket --helper.socket, a mason
+server or tool given the same --helper.socket then runs without privileges.  Under systemd the
+socket of a socket unit is used instead.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCmdHelper()
+	},
+}
+
+func runCmdHelper() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := server.GetConfig()
+	ln, err := helperListener(cfg.Helper.Socket)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-done
+		log.Info("caught interrupt signal, stopping helper")
+		cancel()
+	}()
+
+	log.Info("starting privileged helper", "socket", ln.Addr())
+	return nettools.ServePrivilegedHelper(ctx, ln)
+}
+
+// helperListener uses the socket passed by systemd socket activation, otherwise it listens on
+// the socket, which only the owner and group of the helper may connect to
+func helperListener(socket string) (net.Listener, error) {
+	if os.Getenv("LISTEN_PID") == strconv.Itoa(os.Getpid()) && os.Getenv("LISTEN_FDS") == "1" {
+		return net.FileListener(os.NewFile(listenFdsStart, "helper"))
+	}
+	if socket == "" {
+		return nil, errors.New("no socket given, set --helper.socket")
+	}
+	// a socket left behind by a stopped helper blocks the listen, anything else at the path
+	// is left alone
+	fi, err := os.Lstat(socket)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, err
+	case fi.Mode().Type() != os.ModeSocket:
+		return nil, fmt.Errorf("%s exists and is not a socket", socket)
+	default:
+		err = os.Remove(socket)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return listenPrivateSocket(socket)
+}
+
+// usePrivilegedHelper sends the raw packets through the helper when one is configured
+func usePrivilegedHelper(cfg *server.Config) {
+	if cfg.Helper.Socket != "" {
+		nettools.UsePrivilegedHelper(cfg.Helper.Socket)
+	}
+}
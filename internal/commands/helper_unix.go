@@ -0,0 +1,20 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build linux || freebsd || openbsd || darwin
+
+package commands
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenPrivateSocket creates the socket readable and writable by the owner and group only,
+// the umask is set for the listen so the socket is never open to others
+func listenPrivateSocket(socket string) (net.Listener, error) {
+	old := syscall.Umask(0o117)
+	defer syscall.Umask(old)
+	return net.Listen("unix", socket)
+}
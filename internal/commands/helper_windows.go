@@ -0,0 +1,14 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build windows
+
+package commands
+
+import "net"
+
+// listenPrivateSocket listens on the socket, access is given by the acl of its directory
+func listenPrivateSocket(socket string) (net.Listener, error) {
+	return net.Listen("unix", socket)
+}
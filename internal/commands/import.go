@@ -0,0 +1,90 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/networkables/mason/internal/cloud"
+	"github.com/networkables/mason/internal/server"
+)
+
+var (
+	cmdImport = &cobra.Command{
+		Use:   "import",
+		Short: "import networks and devices from other inventories",
+	}
+
+	flagImportCloudProvider string
+	cmdImportCloud          = &cobra.Command{
+		Use:   "cloud",
+		Short: "import the vpc subnets and network interfaces of a cloud provider",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdImportCloud(args)
+		},
+	}
+
+	cmdImportNetbox = &cobra.Command{
+		Use:   "netbox",
+		Short: "sync the prefixes and ip addresses with netbox and push back the devices it does not know",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdImportNetbox(args)
+		},
+	}
+)
+
+func init() {
+	cmdImport.AddCommand(cmdImportCloud)
+	cmdImport.AddCommand(cmdImportNetbox)
+
+	cmdImportCloud.Flags().
+		StringVar(&flagImportCloudProvider, "provider", cloud.ProviderAws, "cloud provider [aws,gcp]")
+}
+
+func runCmdImportCloud([]string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	result, err := m.ImportCloud(context.Background(), flagImportCloudProvider)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(
+		"%s: networks added:%d updated:%d devices:%d\n",
+		result.Provider,
+		result.NetworksAdded,
+		result.NetworksUpdated,
+		result.Devices,
+	)
+	return nil
+}
+
+func runCmdImportNetbox([]string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	result, err := m.SyncNetbox(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Printf(
+		"netbox: networks added:%d updated:%d devices added:%d updated:%d pushed:%d\n",
+		result.NetworksAdded,
+		result.NetworksUpdated,
+		result.DevicesAdded,
+		result.DevicesUpdated,
+		result.DevicesPushed,
+	)
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/server"
+)
+
+const maintenanceStartLayout = "2006-01-02 15:04"
+
+var (
+	cmdMaintenance = &cobra.Command{
+		Use:   "maintenance",
+		Short: "manage maintenance windows which suppress ping failures and change events",
+	}
+
+	cmdMaintenanceList = &cobra.Command{
+		Use:   "list",
+		Short: "list maintenance windows",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdMaintenanceList(args)
+		},
+	}
+
+	flagMaintenanceScope    string
+	flagMaintenanceTarget   string
+	flagMaintenanceStart    string
+	flagMaintenanceDuration time.Duration
+	flagMaintenanceRepeat   string
+	flagMaintenanceNote     string
+	cmdMaintenanceSet       = &cobra.Command{
+		Use:   "set [name]",
+		Short: "create or update a maintenance window",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdMaintenanceSet(args)
+		},
+	}
+
+	cmdMaintenanceDelete = &cobra.Command{
+		Use:   "delete [name]",
+		Short: "delete a maintenance window",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdMaintenanceDelete(args)
+		},
+	}
+)
+
+func init() {
+	cmdMaintenance.AddCommand(cmdMaintenanceList, cmdMaintenanceSet, cmdMaintenanceDelete)
+
+	cmdMaintenanceSet.Flags().
+		StringVar(&flagMaintenanceScope, "scope", "device", "what the window applies to [device,tag,network]")
+	cmdMaintenanceSet.Flags().
+		StringVar(&flagMaintenanceTarget, "target", "", "device addr, tag name or network name")
+	cmdMaintenanceSet.Flags().
+		StringVar(&flagMaintenanceStart, "start", "", "local start time as \"YYYY-MM-DD HH:MM\", now if not set")
+	cmdMaintenanceSet.Flags().
+		DurationVar(&flagMaintenanceDuration, "duration", time.Hour, "length of the window")
+	cmdMaintenanceSet.Flags().
+		StringVar(&flagMaintenanceRepeat, "repeat", "once", "repeat the window [once,daily,weekly]")
+	cmdMaintenanceSet.Flags().StringVar(&flagMaintenanceNote, "note", "", "note")
+}
+
+func runCmdMaintenanceList([]string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	windows, err := m.ListMaintenanceWindows(context.Background())
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, w := range windows {
+		active := ""
+		if w.ActiveAt(now) {
+			active = "active"
+		}
+		fmt.Printf(
+			"%-20s %-8s %-20s %s %-10s %-7s %-7s %s\n",
+			w.Name,
+			w.Scope,
+			w.Target,
+			w.Start.Local().Format(maintenanceStartLayout),
+			w.Duration,
+			w.Repeat,
+			active,
+			w.Note,
+		)
+	}
+	return nil
+}
+
+func runCmdMaintenanceSet(args []string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	w := model.MaintenanceWindow{
+		Name:     args[0],
+		Target:   flagMaintenanceTarget,
+		Start:    time.Now(),
+		Duration: flagMaintenanceDuration,
+		Note:     flagMaintenanceNote,
+	}
+	w.Scope, err = model.ParseMaintenanceScope(flagMaintenanceScope)
+	if err != nil {
+		return err
+	}
+	w.Repeat, err = model.ParseMaintenanceRepeat(flagMaintenanceRepeat)
+	if err != nil {
+		return err
+	}
+	if flagMaintenanceStart != "" {
+		w.Start, err = time.ParseInLocation(maintenanceStartLayout, flagMaintenanceStart, time.Local)
+		if err != nil {
+			return err
+		}
+	}
+	return m.SaveMaintenanceWindow(context.Background(), w)
+}
+
+func runCmdMaintenanceDelete(args []string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	return m.RemoveMaintenanceWindow(context.Background(), args[0])
+}
@@ -0,0 +1,228 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/spf13/cobra"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/server"
+	"github.com/networkables/mason/nettools"
+)
+
+// mtrReportCount is the number of rounds of --report when no --count is given
+const mtrReportCount = 10
+
+var (
+	flagMtrCount    int
+	flagMtrInterval time.Duration
+	flagMtrMaxHops  int
+	flagMtrTimeout  time.Duration
+	flagMtrReport   bool
+	cmdToolMtr      = &cobra.Command{
+		Use:   "mtr [target]",
+		Short: "repeatedly trace the route to the target and show the loss and latency of each hop",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdToolMtr(args)
+		},
+	}
+)
+
+func init() {
+	cmdToolMtr.Flags().
+		IntVarP(&flagMtrCount, "count", "c", 0, "number of rounds, 0 to run until q is pressed")
+	cmdToolMtr.Flags().
+		DurationVarP(&flagMtrInterval, "interval", "i", time.Second, "time between rounds")
+	cmdToolMtr.Flags().IntVar(&flagMtrMaxHops, "maxhops", 30, "most hops probed")
+	cmdToolMtr.Flags().
+		DurationVar(&flagMtrTimeout, "timeout", 500*time.Millisecond, "how long to wait for each hop to answer")
+	cmdToolMtr.Flags().
+		BoolVar(&flagMtrReport, "report", false, "print the table once the rounds are done instead of updating it")
+}
+
+func runCmdToolMtr(args []string) error {
+	cfg := server.GetConfig()
+	if !cfg.Discovery.Icmp.Privileged {
+		return errors.New("mtr needs privileged icmp, use --discovery.icmp.privileged")
+	}
+	addr, err := model.ParseAddr(args[0])
+	if err != nil {
+		return err
+	}
+	mtr := nettools.NewMtr(
+		addr.Addr(),
+		flagMtrMaxHops,
+		nettools.I4EWithReadTimeout(flagMtrTimeout),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if flagMtrReport {
+		return runMtrReport(ctx, cancel, mtr)
+	}
+
+	final, err := tea.NewProgram(mtrModel{
+		ctx:    ctx,
+		target: addr.String(),
+		mtr:    mtr,
+	}).Run()
+	if err != nil {
+		return err
+	}
+	return final.(mtrModel).err
+}
+
+// runMtrReport runs the rounds without a live view, an interrupt prints the rounds so far
+func runMtrReport(ctx context.Context, cancel context.CancelFunc, mtr *nettools.Mtr) error {
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	count := flagMtrCount
+	if count <= 0 {
+		count = mtrReportCount
+	}
+	var hops []nettools.MtrHop
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(flagMtrInterval):
+			}
+		}
+		var err error
+		hops, err = mtr.Round(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Println(mtrTable(hops))
+	return nil
+}
+
+type (
+	mtrModel struct {
+		ctx    context.Context
+		target string
+		mtr    *nettools.Mtr
+		hops   []nettools.MtrHop
+		rounds int
+		err    error
+	}
+
+	mtrRoundMsg struct {
+		hops []nettools.MtrHop
+		err  error
+	}
+
+	mtrTickMsg struct{}
+)
+
+func (m mtrModel) Init() tea.Cmd {
+	return m.round
+}
+
+// round runs off the update loop, the Mtr is only used by one round at a time
+func (m mtrModel) round() tea.Msg {
+	hops, err := m.mtr.Round(m.ctx)
+	return mtrRoundMsg{hops: hops, err: err}
+}
+
+func (m mtrModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q", "esc":
+			return m, tea.Quit
+		}
+
+	case mtrRoundMsg:
+		m.hops = msg.hops
+		m.rounds++
+		if msg.err != nil {
+			m.err = msg.err
+			return m, tea.Quit
+		}
+		if flagMtrCount > 0 && m.rounds >= flagMtrCount {
+			return m, tea.Quit
+		}
+		return m, tea.Tick(flagMtrInterval, func(time.Time) tea.Msg {
+			return mtrTickMsg{}
+		})
+
+	case mtrTickMsg:
+		return m, m.round
+	}
+	return m, nil
+}
+
+func (m mtrModel) View() string {
+	return fmt.Sprintf("mtr to %s, round %d, q to quit\n%s\n", m.target, m.rounds, mtrTable(m.hops))
+}
+
+func mtrTable(hops []nettools.MtrHop) *table.Table {
+	right := func(width int) lipgloss.Style {
+		return lipgloss.NewStyle().Width(width).Align(lipgloss.Right)
+	}
+	colstyles := []lipgloss.Style{
+		// Hop
+		lipgloss.NewStyle().Width(5).Align(lipgloss.Center),
+		// Address
+		right(19),
+		// Loss, Sent
+		right(8),
+		right(6),
+		// Last, Avg, Best, Worst, StdDev
+		right(10),
+		right(10),
+		right(10),
+		right(10),
+		right(10),
+	}
+	t := newHopTable(
+		colstyles,
+		"Hop", "Address", "Loss", "Sent", "Last", "Avg", "Best", "Worst", "StdDev",
+	)
+	round := func(d time.Duration) string {
+		return d.Round(10 * time.Microsecond).String()
+	}
+	for i, hop := range hops {
+		peer := "???"
+		if hop.Peer.IsValid() {
+			peer = hop.Peer.String()
+		}
+		t.Row(
+			strconv.Itoa(i+1),
+			peer,
+			fmt.Sprintf("%.1f%%", hop.Loss()),
+			strconv.Itoa(hop.Sent),
+			round(hop.Last),
+			round(hop.Mean),
+			round(hop.Best),
+			round(hop.Worst),
+			round(hop.StdDev()),
+		)
+	}
+	return t
+}
@@ -0,0 +1,265 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/server"
+)
+
+// networkScanPollInterval is how often scan --wait asks for the progress of the scan
+const networkScanPollInterval = time.Second
+
+var (
+	flagNetworkJSON bool
+	cmdNetwork      = &cobra.Command{
+		Use:   "network",
+		Short: "list, add, delete and scan networks",
+	}
+
+	cmdNetworkList = &cobra.Command{
+		Use:   "list",
+		Short: "list all networks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdNetworkList(args)
+		},
+	}
+
+	flagNetworkName string
+	flagNetworkScan bool
+	cmdNetworkAdd   = &cobra.Command{
+		Use:   "add [prefix]",
+		Short: "add a network, without --scan it is scanned on the next rescan check",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdNetworkAdd(args)
+		},
+	}
+
+	cmdNetworkRemove = &cobra.Command{
+		Use:   "rm [name]",
+		Short: "delete a network, it can be restored until the grace period ends",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdDeleteNetwork(args)
+		},
+	}
+
+	flagNetworkScanWait bool
+	cmdNetworkScan      = &cobra.Command{
+		Use:   "scan [prefix]",
+		Short: "scan a network (by prefix or name) now, requires a running server",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdNetworkScan(args)
+		},
+	}
+)
+
+func init() {
+	cmdNetwork.AddCommand(cmdNetworkList, cmdNetworkAdd, cmdNetworkRemove, cmdNetworkScan)
+
+	cmdNetwork.PersistentFlags().
+		BoolVar(&flagNetworkJSON, "json", false, "write json instead of a table")
+	cmdNetworkAdd.Flags().
+		StringVar(&flagNetworkName, "name", "", "name of the network, the prefix when blank")
+	cmdNetworkAdd.Flags().
+		BoolVar(&flagNetworkScan, "scan", false, "scan the network once added, requires a running server")
+	cmdNetworkScan.Flags().
+		BoolVar(&flagNetworkScanWait, "wait", false, "print the progress until the scan is done, then the new devices")
+}
+
+func runCmdNetworkList([]string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	networks, err := m.ListNetworks(context.Background())
+	if err != nil {
+		return err
+	}
+	if flagNetworkJSON {
+		return writeJSON(networks)
+	}
+	for _, n := range networks {
+		lastScan := "never"
+		if !n.LastScan.IsZero() {
+			lastScan = n.LastScan.Local().Format(time.DateTime)
+		}
+		fmt.Printf(
+			"%-24s %-18s %-12s %-19s %s\n",
+			n.Name,
+			n.Prefix,
+			n.Site,
+			lastScan,
+			tagNames(n.Tags),
+		)
+	}
+	return nil
+}
+
+func runCmdNetworkAdd(args []string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	n, err := m.CreateNetwork(context.Background(), flagNetworkName, args[0], flagNetworkScan)
+	if err != nil {
+		return err
+	}
+	if flagNetworkJSON {
+		return writeJSON(n)
+	}
+	fmt.Printf("added %s %s\n", n.Name, n.Prefix)
+	return nil
+}
+
+func runCmdNetworkScan(args []string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	// the devices known before the scan tell the new devices apart once it is done
+	var before []model.Device
+	if flagNetworkScanWait {
+		before, err = m.ListDevices(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	n, err := m.ScanNetwork(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if !flagNetworkScanWait {
+		fmt.Printf("scan of %s %s requested\n", n.Name, n.Prefix)
+		return nil
+	}
+	p, err := waitForNetworkScan(ctx, m, n)
+	if err != nil || ctx.Err() != nil {
+		return err
+	}
+	after, err := m.ListDevices(ctx)
+	if err != nil {
+		return err
+	}
+	added := newDevicesIn(n.Prefix, before, after)
+	if flagNetworkJSON {
+		return writeJSON(added)
+	}
+	fmt.Printf(
+		"scan of %s %s finished in %s, %d responded, %d new devices\n",
+		n.Name,
+		n.Prefix,
+		p.Elapsed().Round(time.Second),
+		p.Found,
+		len(added),
+	)
+	for _, d := range added {
+		printDeviceRow(d)
+	}
+	return nil
+}
+
+// waitForNetworkScan prints the progress of the scan of the network until it is done or the
+// context is cancelled
+func waitForNetworkScan(
+	ctx context.Context,
+	m masonAPI,
+	n model.Network,
+) (discovery.NetworkScanProgress, error) {
+	ticker := time.NewTicker(networkScanPollInterval)
+	defer ticker.Stop()
+	var last string
+	for {
+		select {
+		case <-ctx.Done():
+			return discovery.NetworkScanProgress{}, nil
+		case <-ticker.C:
+		}
+		scans, err := m.NetworkScans(ctx)
+		if err != nil {
+			return discovery.NetworkScanProgress{}, err
+		}
+		p, ok := networkScanOf(scans, n)
+		if !ok {
+			continue
+		}
+		line := fmt.Sprintf(
+			"%3d%% probed %d/%d, %d responded, eta %s",
+			p.Percent(),
+			p.Probed,
+			p.Total,
+			p.Found,
+			p.ETA.Round(time.Second),
+		)
+		if !flagNetworkJSON && line != last {
+			fmt.Println(line)
+			last = line
+		}
+		if p.Done() {
+			return p, nil
+		}
+	}
+}
+
+// networkScanOf returns the scan of the network requested at its LastScan, an earlier
+// finished scan of the network is skipped while a running one is taken over
+func networkScanOf(
+	scans []discovery.NetworkScanProgress,
+	n model.Network,
+) (discovery.NetworkScanProgress, bool) {
+	for _, p := range scans {
+		if p.Prefix != n.Prefix.String() {
+			continue
+		}
+		if p.Done() && p.Started.Before(n.LastScan) {
+			continue
+		}
+		return p, true
+	}
+	return discovery.NetworkScanProgress{}, false
+}
+
+// newDevicesIn returns the devices in the prefix which were not known before
+func newDevicesIn(prefix model.Prefix, before []model.Device, after []model.Device) []model.Device {
+	known := make(map[model.Addr]bool, len(before))
+	for _, d := range before {
+		known[d.Addr] = true
+	}
+	added := make([]model.Device, 0)
+	for _, d := range after {
+		if prefix.Contains(d.Addr) && !known[d.Addr] {
+			added = append(added, d)
+		}
+	}
+	return added
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/server"
+)
+
+var (
+	cmdPortList = &cobra.Command{
+		Use:   "portlist",
+		Short: "manage custom port profiles scanned on the devices of a tag or network",
+	}
+
+	cmdPortListList = &cobra.Command{
+		Use:   "list",
+		Short: "list port profiles",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdPortListList(args)
+		},
+	}
+
+	flagPortListPorts       string
+	flagPortListScope       string
+	flagPortListTarget      string
+	flagPortListDescription string
+	cmdPortListSet          = &cobra.Command{
+		Use:   "set [name]",
+		Short: "create or update a port profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdPortListSet(args)
+		},
+	}
+
+	cmdPortListDelete = &cobra.Command{
+		Use:   "delete [name]",
+		Short: "delete a port profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdPortListDelete(args)
+		},
+	}
+)
+
+func init() {
+	cmdPortList.AddCommand(cmdPortListList, cmdPortListSet, cmdPortListDelete)
+
+	cmdPortListSet.Flags().
+		StringVar(&flagPortListPorts, "ports", "", "ports and ranges to scan, e.g. 22,80,8000-8100")
+	cmdPortListSet.Flags().
+		StringVar(&flagPortListScope, "scope", "none", "what the profile is scanned on [none,tag,network]")
+	cmdPortListSet.Flags().
+		StringVar(&flagPortListTarget, "target", "", "tag name or network name")
+	cmdPortListSet.Flags().StringVar(&flagPortListDescription, "description", "", "description")
+}
+
+func runCmdPortListList([]string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	pps, err := m.ListPortProfiles(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, pp := range pps {
+		scope := string(pp.Scope)
+		if pp.Scope == model.PortProfileScopeNone {
+			scope = "none"
+		}
+		fmt.Printf(
+			"%-20s %-8s %-20s %-30s %s\n",
+			pp.Name,
+			scope,
+			pp.Target,
+			pp.Ports,
+			pp.Description,
+		)
+	}
+	return nil
+}
+
+func runCmdPortListSet(args []string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	pp := model.PortProfile{
+		Name:        args[0],
+		Target:      flagPortListTarget,
+		Description: flagPortListDescription,
+	}
+	pp.Scope, err = model.ParsePortProfileScope(flagPortListScope)
+	if err != nil {
+		return err
+	}
+	pp.Ports, err = model.ParsePortRanges(flagPortListPorts)
+	if err != nil {
+		return err
+	}
+	return m.SavePortProfile(context.Background(), pp)
+}
+
+func runCmdPortListDelete(args []string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	return m.RemovePortProfile(context.Background(), args[0])
+}
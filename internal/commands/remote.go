@@ -0,0 +1,204 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/server"
+	"github.com/networkables/mason/pkg/client"
+)
+
+// remoteServerEnv names the server the cli talks to when --remote is not given
+const remoteServerEnv = "MASON_SERVER"
+
+// errScanNeedsServer is returned for scans without --remote, only a running server has the
+// discovery workers
+var errScanNeedsServer = errors.New(
+	"scanning needs a running server, use --remote or $" + remoteServerEnv,
+)
+
+var flagRemote string
+
+// masonAPI is what the inventory commands need from mason, either a mason instance over the
+// local stores or a running server reached through its api
+type masonAPI interface {
+	ListDevices(context.Context) ([]model.Device, error)
+	GetDeviceByAddr(context.Context, model.Addr) (model.Device, error)
+	EnrichDeviceNow(context.Context, model.Addr) (model.Device, error)
+	PingDeviceNow(context.Context, model.Addr) (model.Device, error)
+	ReviewQueue(context.Context) ([]model.Device, error)
+	DeviceHistory(context.Context, model.Addr) ([]model.DeviceChange, error)
+	SetDevicePolicy(context.Context, model.Addr, model.MonitoringPolicy) error
+	SetDeviceDetails(context.Context, model.Addr, model.DeviceDetails) error
+	SetDeviceApproval(context.Context, model.Addr, model.ApprovalState) error
+	RemoveDevice(context.Context, model.Addr) error
+	TagDevice(context.Context, model.Addr, string) error
+	UntagDevice(context.Context, model.Addr, string) error
+	ListNetworks(context.Context) ([]model.Network, error)
+	CreateNetwork(context.Context, string, string, bool) (model.Network, error)
+	ScanNetwork(context.Context, string) (model.Network, error)
+	NetworkScans(context.Context) ([]discovery.NetworkScanProgress, error)
+	RemoveNetwork(context.Context, string) error
+	TagNetwork(context.Context, string, string) error
+	UntagNetwork(context.Context, string, string) error
+	ListDeleted(context.Context) ([]model.Tombstone, error)
+	RestoreDeleted(context.Context, model.TombstoneKind, string) error
+	PurgeDeleted(context.Context) (int, error)
+	ListMaintenanceWindows(context.Context) ([]model.MaintenanceWindow, error)
+	SaveMaintenanceWindow(context.Context, model.MaintenanceWindow) error
+	RemoveMaintenanceWindow(context.Context, string) error
+	ListTagDefinitions(context.Context) ([]model.TagDefinition, error)
+	SaveTagDefinition(context.Context, model.TagDefinition) error
+	RemoveTagDefinition(context.Context, string) error
+	ListPortProfiles(context.Context) ([]model.PortProfile, error)
+	SavePortProfile(context.Context, model.PortProfile) error
+	RemovePortProfile(context.Context, string) error
+	TailEvents(context.Context, model.EventQuery) ([]model.EventRecord, error)
+	ExportInventory(context.Context, bool, string) (server.InventoryExport, error)
+	ArchiveTimeseries(context.Context) (int, error)
+	CheckConsistency(context.Context, bool) ([]model.ConsistencyIssue, error)
+	ImportCloud(context.Context, string) (server.CloudImportResult, error)
+	SyncNetbox(context.Context) (server.NetboxSyncResult, error)
+}
+
+var (
+	_ masonAPI = localMason{}
+	_ masonAPI = remoteMason{}
+)
+
+// openMason connects to the server given by --remote or $MASON_SERVER, otherwise the
+// configured stores are opened, which fails while a server holds them
+func openMason(cfg *server.Config) (masonAPI, func() error, error) {
+	remote := remoteServer()
+	if remote != "" {
+		c, err := client.New(remote)
+		if err != nil {
+			return nil, nil, err
+		}
+		return remoteMason{c}, func() error { return nil }, nil
+	}
+	m, closefn, err := openStoreMason(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return localMason{m}, closefn, nil
+}
+
+// remoteServer returns the url of the server given by --remote or $MASON_SERVER, blank when
+// the cli works on its own
+func remoteServer() string {
+	if flagRemote != "" {
+		return flagRemote
+	}
+	return os.Getenv(remoteServerEnv)
+}
+
+type localMason struct {
+	*server.Mason
+}
+
+func (l localMason) ListDevices(ctx context.Context) ([]model.Device, error) {
+	return l.Mason.ListDevices(ctx), nil
+}
+
+func (l localMason) ReviewQueue(ctx context.Context) ([]model.Device, error) {
+	return l.Mason.ReviewQueue(ctx), nil
+}
+
+func (l localMason) ListNetworks(ctx context.Context) ([]model.Network, error) {
+	return l.Mason.ListNetworks(ctx), nil
+}
+
+// CreateNetwork only stores the network, the server scans it on its next rescan check
+func (l localMason) CreateNetwork(
+	ctx context.Context,
+	name string,
+	prefix string,
+	scan bool,
+) (model.Network, error) {
+	if scan {
+		return model.Network{}, errScanNeedsServer
+	}
+	return l.Mason.CreateNetwork(ctx, name, prefix, false)
+}
+
+func (l localMason) ScanNetwork(context.Context, string) (model.Network, error) {
+	return model.Network{}, errScanNeedsServer
+}
+
+func (l localMason) NetworkScans(ctx context.Context) ([]discovery.NetworkScanProgress, error) {
+	return l.Mason.NetworkScans(ctx), nil
+}
+
+func (l localMason) ExportInventory(
+	ctx context.Context,
+	anonymize bool,
+	key string,
+) (server.InventoryExport, error) {
+	return l.Mason.ExportInventory(ctx, anonymize, key), nil
+}
+
+type remoteMason struct {
+	*client.Client
+}
+
+func (r remoteMason) ListDevices(ctx context.Context) ([]model.Device, error) {
+	return r.Devices(ctx)
+}
+
+func (r remoteMason) GetDeviceByAddr(ctx context.Context, addr model.Addr) (model.Device, error) {
+	return r.Device(ctx, addr)
+}
+
+func (r remoteMason) EnrichDeviceNow(ctx context.Context, addr model.Addr) (model.Device, error) {
+	return r.EnrichDevice(ctx, addr)
+}
+
+func (r remoteMason) PingDeviceNow(ctx context.Context, addr model.Addr) (model.Device, error) {
+	return r.PingDevice(ctx, addr)
+}
+
+func (r remoteMason) ListNetworks(ctx context.Context) ([]model.Network, error) {
+	return r.Networks(ctx)
+}
+
+func (r remoteMason) ListTagDefinitions(ctx context.Context) ([]model.TagDefinition, error) {
+	return r.Tags(ctx)
+}
+
+func (r remoteMason) ExportInventory(
+	ctx context.Context,
+	anonymize bool,
+	key string,
+) (server.InventoryExport, error) {
+	var (
+		inv client.Inventory
+		err error
+	)
+	if anonymize {
+		inv, err = r.AnonymizedInventory(ctx, key)
+	} else {
+		inv, err = r.Inventory(ctx)
+	}
+	return server.InventoryExport(inv), err
+}
+
+func (r remoteMason) ImportCloud(
+	ctx context.Context,
+	provider string,
+) (server.CloudImportResult, error) {
+	result, err := r.Client.ImportCloud(ctx, provider)
+	return server.CloudImportResult(result), err
+}
+
+func (r remoteMason) SyncNetbox(ctx context.Context) (server.NetboxSyncResult, error) {
+	result, err := r.Client.SyncNetbox(ctx)
+	return server.NetboxSyncResult(result), err
+}
@@ -14,16 +14,31 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
+	"github.com/networkables/mason/internal/agent"
 	"github.com/networkables/mason/internal/asn"
+	"github.com/networkables/mason/internal/backup"
 	"github.com/networkables/mason/internal/bus"
+	"github.com/networkables/mason/internal/cloud"
 	"github.com/networkables/mason/internal/combostore"
 	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/dnslog"
 	"github.com/networkables/mason/internal/enrichment"
+	"github.com/networkables/mason/internal/exporter"
+	"github.com/networkables/mason/internal/hooks"
+	"github.com/networkables/mason/internal/kubernetes"
+	"github.com/networkables/mason/internal/logging"
+	"github.com/networkables/mason/internal/netbox"
 	"github.com/networkables/mason/internal/netflows"
+	"github.com/networkables/mason/internal/notify"
 	"github.com/networkables/mason/internal/oui"
 	"github.com/networkables/mason/internal/pinger"
+	"github.com/networkables/mason/internal/ratelimit"
 	"github.com/networkables/mason/internal/server"
 	"github.com/networkables/mason/internal/sqlitestore"
+	"github.com/networkables/mason/internal/syslog"
+	"github.com/networkables/mason/internal/tracing"
+	"github.com/networkables/mason/internal/tsstore"
+	"github.com/networkables/mason/internal/unifi"
 )
 
 var (
@@ -33,8 +48,15 @@ var (
 		Short: "cli for mason",
 		// Long:  `cli for mason`,
 		PersistentPreRunE: func(*cobra.Command, []string) error {
+			err := logging.Apply(server.GetConfig().Logging)
+			if err != nil {
+				return err
+			}
 			if flagDebug {
-				log.SetLevel(log.DebugLevel)
+				err = logging.SetLevel("", log.DebugLevel.String())
+				if err != nil {
+					return err
+				}
 				log.Debug("debug log activated")
 			}
 			return nil
@@ -65,9 +87,30 @@ func RootExecute() {
 }
 
 func init() {
-	cmdRoot.AddCommand(cmdVersion, cmdServer, cmdTool, cmdSys, cmdDebug)
+	cmdRoot.AddCommand(
+		cmdVersion,
+		cmdServer,
+		cmdAgent,
+		cmdHelper,
+		cmdTool,
+		cmdSys,
+		cmdTag,
+		cmdDevice,
+		cmdNetwork,
+		cmdMaintenance,
+		cmdPortList,
+		cmdEvents,
+		cmdDelete,
+		cmdDeleted,
+		cmdImport,
+		cmdAdmin,
+		cmdDebug,
+		cmdConfig,
+	)
 
 	cmdRoot.PersistentFlags().BoolVar(&flagDebug, "debug", false, "Activate debug logging")
+	cmdRoot.PersistentFlags().
+		StringVar(&flagRemote, "remote", "", "url of a running server for the inventory commands to use instead of opening the stores, defaults to $"+remoteServerEnv)
 
 	// TODO: set all flags is probably only useful for server commands, should get rid of this and only apply flags needed at the command level (or sub command)
 	setAllFlags(cmdRoot.PersistentFlags(), server.GetConfig())
@@ -94,12 +137,27 @@ func setAllFlags(f *pflag.FlagSet, c *server.Config) {
 	discovery.SetFlags(f, c.Discovery)
 	combostore.SetFlags(f, c.Store.Combo)
 	sqlitestore.SetFlags(f, c.Store.Sqlite)
+	tsstore.SetFlags(f, c.Store.Timeseries)
 	bus.SetFlags(f, c.Bus)
 	pinger.SetFlags(f, c.Pinger)
 	enrichment.SetFlags(f, c.Enrichment)
 	netflows.SetFlags(f, c.NetFlows)
+	dnslog.SetFlags(f, c.DNSLog)
+	syslog.SetFlags(f, c.Syslog)
+	hooks.SetFlags(f, c.Hooks)
+	notify.SetFlags(f, c.Notify)
 	asn.SetFlags(f, c.Asn)
 	oui.SetFlags(f, c.Oui)
+	ratelimit.SetFlags(f, c.RateLimit)
+	agent.SetFlags(f, c.Agent)
+	exporter.SetFlags(f, c.Exporter)
+	kubernetes.SetFlags(f, c.Kubernetes)
+	unifi.SetFlags(f, c.Unifi)
+	netbox.SetFlags(f, c.Netbox)
+	backup.SetFlags(f, c.Backup)
+	cloud.SetFlags(f, c.Cloud)
+	logging.SetFlags(f, c.Logging)
+	tracing.SetFlags(f, c.Tracing)
 
 	// Env
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
@@ -108,12 +166,15 @@ func setAllFlags(f *pflag.FlagSet, c *server.Config) {
 
 	// Config file
 	viper.AddConfigPath(".")
-	viper.AddConfigPath(c.ConfigDirectory)
+	// read through viper, the directory may be set by the environment
+	viper.AddConfigPath(viper.GetString("config.directory"))
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
+	// without a config file the settings come from the environment and flags, as in a container
 	err := viper.ReadInConfig()
-	if err != nil && !errors.Is(err, viper.ConfigFileNotFoundError{}) {
-		log.Warn("did not find a config file to read")
+	var notFound viper.ConfigFileNotFoundError
+	if err != nil && !errors.As(err, &notFound) {
+		log.Warn("could not read the config file", "error", err)
 	}
 
 	err = viper.Unmarshal(c)
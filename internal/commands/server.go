@@ -7,6 +7,7 @@ package commands
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -25,6 +26,9 @@ import (
 	"github.com/networkables/mason/internal/combostore"
 	"github.com/networkables/mason/internal/server"
 	"github.com/networkables/mason/internal/sqlitestore"
+	"github.com/networkables/mason/internal/systemd"
+	"github.com/networkables/mason/internal/tracing"
+	"github.com/networkables/mason/internal/tsstore"
 	"github.com/networkables/mason/internal/tui"
 	"github.com/networkables/mason/internal/wui"
 )
@@ -43,6 +47,14 @@ func runCmdServer() error {
 	defer normalcancel()
 
 	cfg := server.GetConfig()
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config, see mason config check:\n%w", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		return err
+	}
 
 	masonServer, err := startMason(ctx, cfg)
 	if err != nil {
@@ -59,10 +71,14 @@ func runCmdServer() error {
 	httpServer := wui.New(masonServer, cfg.Wui.ListenAddress)
 	go httpServer.Start()
 
+	notifySystemd(systemd.Ready)
+	go runWatchdog(ctx, masonServer)
+
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	<-done
 	log.Info("caught interrupt signal, starting normalcancel")
+	notifySystemd(systemd.Stopping)
 	normalcancel()
 	// time.Sleep(5 * time.Second)
 
@@ -83,45 +99,126 @@ func runCmdServer() error {
 	}
 	log.Info("http shutdown")
 
+	// Flush the pending spans
+	if err := shutdownTracing(shutdownctx); err != nil {
+		log.Error("tracing shutdown", "error", err)
+	}
+
 	return nil
 }
 
+// notifySystemd sends the state to systemd when mason runs as a notify unit
+func notifySystemd(state string) {
+	_, err := systemd.Notify(state)
+	if err != nil {
+		log.Warn("systemd notify", "state", state, "error", err)
+	}
+}
+
+// runWatchdog pings the systemd watchdog at half its interval while the self check passes, a
+// wedged server stops pinging and is restarted by systemd
+func runWatchdog(ctx context.Context, m *server.Mason) {
+	interval, ok := systemd.WatchdogInterval()
+	if !ok {
+		return
+	}
+	log.Info("systemd watchdog enabled", "interval", interval)
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	failing := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkctx, cancel := context.WithTimeout(ctx, interval/2)
+			err := m.SelfCheck(checkctx)
+			cancel()
+			if err != nil {
+				log.Error("self check failed, skipping the watchdog ping", "error", err)
+				notifySystemd(systemd.Status("self check failed: " + err.Error()))
+				failing = true
+				continue
+			}
+			if failing {
+				notifySystemd(systemd.Status("self check passed"))
+				failing = false
+			}
+			notifySystemd(systemd.Watchdog)
+		}
+	}
+}
+
 func startMason(ctx context.Context, cfg *server.Config) (*server.Mason, error) {
 	// if !cfg.IgnoreCap && !server.HasCapabilities(cfg) {
 	// 	return nil, errors.New("not all capabilities are present, run sudo ./mason sys setcap")
 	// }
+	usePrivilegedHelper(cfg)
 	if !server.HasCapabilities(cfg) {
 		return nil, errors.New("not all capabilities are present, run sudo ./mason sys setcap")
 	}
 
-	var (
-		store     server.Storer
-		flowstore server.NetflowStorer
-		err       error
+	store, flowstore, err := openStores(cfg)
+	if err != nil {
+		return nil, err
+	}
+	timeseries, err := openTimeseries(cfg, store)
+	if err != nil {
+		return nil, err
+	}
+
+	m := server.New(
+		server.WithConfig(cfg),
+		server.WithBus(bus.New(cfg.Bus)),
+		server.WithStore(store),
+		server.WithNetflowStorer(flowstore),
+		server.WithTimeseriesStorer(timeseries),
 	)
+	go m.Run(ctx)
+	return m, nil
+}
 
+func openStores(cfg *server.Config) (
+	store server.Storer,
+	flowstore server.NetflowStorer,
+	err error,
+) {
 	if cfg.Store.Combo.Enabled {
+		engine := cfg.Store.Timeseries.Engine
+		cfg.Store.Combo.ExternalTimeseries = engine != tsstore.EngineStore &&
+			engine != tsstore.EngineWhisper
 		store, err = combostore.New(cfg.Store.Combo)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	} else if cfg.Store.Sqlite.Enabled {
 		sqls, err := sqlitestore.New(cfg.Store.Sqlite)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		store = sqls
 		flowstore = sqls
 	}
+	return store, flowstore, nil
+}
 
-	m := server.New(
-		server.WithConfig(cfg),
-		server.WithBus(bus.New(cfg.Bus)),
-		server.WithStore(store),
-		server.WithNetflowStorer(flowstore),
-	)
-	go m.Run(ctx)
-	return m, nil
+// openTimeseries returns the engine selected for the performance pings, nil when the store
+// keeps them
+func openTimeseries(cfg *server.Config, store server.Storer) (server.TimeseriesStorer, error) {
+	switch cfg.Store.Timeseries.Engine {
+	case tsstore.EngineStore:
+		return nil, nil
+	case tsstore.EngineWhisper:
+		return tsstore.NewWhisper(cfg.Store.Combo.Directory, cfg.Store.Combo.WSPRetention)
+	case tsstore.EngineSqlite:
+		if sqls, ok := store.(*sqlitestore.Store); ok {
+			return sqls, nil
+		}
+		return sqlitestore.New(cfg.Store.Sqlite)
+	case tsstore.EngineMemory:
+		return tsstore.NewMemory(cfg.Store.Timeseries.MemoryCapacity), nil
+	}
+	return nil, fmt.Errorf("unknown timeseries engine: %s", cfg.Store.Timeseries.Engine)
 }
 
 func startSSHServer(
@@ -5,10 +5,17 @@
 package commands
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
 	"github.com/charmbracelet/log"
 	"github.com/spf13/cobra"
 
 	"github.com/networkables/mason/internal/server"
+	"github.com/networkables/mason/pkg/provider"
 )
 
 var (
@@ -33,11 +40,60 @@ var (
 			return runCmdSysSetCap(args)
 		},
 	}
+
+	flagSysExportAnonymize bool
+	flagSysExportKey       string
+	flagSysExportFormat    string
+	cmdSysExport           = &cobra.Command{
+		Use:   "export",
+		Short: "write the network and device inventory as json, or the asset register as csv, to stdout",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdSysExport(args)
+		},
+	}
+
+	flagSysCheckRepair bool
+	cmdSysCheck        = &cobra.Command{
+		Use:   "check",
+		Short: "check the stores for records referencing missing data",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdSysCheck(args)
+		},
+	}
+
+	cmdSysProviders = &cobra.Command{
+		Use:   "providers",
+		Short: "list the discovery and enrichment providers compiled into the binary",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdSysProviders(args)
+		},
+	}
+
+	cmdSysArchive = &cobra.Command{
+		Use:   "archive",
+		Short: "move old ping and flow data from the database into archive files now",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdSysArchive(args)
+		},
+	}
 )
 
 func init() {
 	cmdSys.AddCommand(cmdSysHasCap)
 	cmdSys.AddCommand(cmdSysSetCap)
+	cmdSys.AddCommand(cmdSysExport)
+	cmdSys.AddCommand(cmdSysArchive)
+	cmdSys.AddCommand(cmdSysCheck)
+	cmdSys.AddCommand(cmdSysProviders)
+
+	cmdSysExport.Flags().
+		BoolVar(&flagSysExportAnonymize, "anonymize", false, "replace macs, names and public ips with hashed values")
+	cmdSysExport.Flags().
+		StringVar(&flagSysExportKey, "key", "", "key used to hash values, reuse it to correlate exports")
+	cmdSysExport.Flags().
+		StringVar(&flagSysExportFormat, "format", "json", "json inventory or csv asset register of the devices")
+	cmdSysCheck.Flags().
+		BoolVar(&flagSysCheckRepair, "repair", false, "fix or quarantine the inconsistent records")
 }
 
 func runCmdSysHasCap([]string) error {
@@ -58,3 +114,107 @@ func runCmdSysSetCap([]string) error {
 	}
 	return nil
 }
+
+// openStoreMason creates a mason instance backed by the configured stores without starting
+// any of the background workers, the returned func closes the stores
+func openStoreMason(cfg *server.Config) (*server.Mason, func() error, error) {
+	store, flowstore, err := openStores(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if store == nil {
+		return nil, nil, errors.New("no store enabled")
+	}
+	timeseries, err := openTimeseries(cfg, store)
+	if err != nil {
+		store.Close()
+		return nil, nil, err
+	}
+	m := server.New(
+		server.WithConfig(cfg),
+		server.WithStore(store),
+		server.WithNetflowStorer(flowstore),
+		server.WithTimeseriesStorer(timeseries),
+	)
+	return m, store.Close, nil
+}
+
+func runCmdSysExport([]string) error {
+	cfg := server.GetConfig()
+	if flagSysExportAnonymize && flagSysExportKey == "" {
+		return errors.New("anonymize requires a key")
+	}
+	if flagSysExportFormat != "json" && flagSysExportFormat != "csv" {
+		return errors.New("export format must be json or csv")
+	}
+	m, closefn, err := openMason(cfg)
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	exp, err := m.ExportInventory(context.Background(), flagSysExportAnonymize, flagSysExportKey)
+	if err != nil {
+		return err
+	}
+	if flagSysExportFormat == "csv" {
+		return exp.WriteAssetCSV(os.Stdout)
+	}
+	return writeJSON(exp)
+}
+
+func runCmdSysArchive([]string) error {
+	cfg := server.GetConfig()
+	m, closefn, err := openMason(cfg)
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	count, err := m.ArchiveTimeseries(context.Background())
+	if err != nil {
+		return err
+	}
+	log.Info("archive complete", "records", count)
+	return nil
+}
+
+func runCmdSysCheck([]string) error {
+	cfg := server.GetConfig()
+	m, closefn, err := openMason(cfg)
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	issues, err := m.CheckConsistency(context.Background(), flagSysCheckRepair)
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if err != nil {
+		return err
+	}
+	log.Info("consistency check complete", "issues", len(issues), "repair", flagSysCheckRepair)
+	return nil
+}
+
+func runCmdSysProviders([]string) error {
+	cfg := server.GetConfig()
+	enabled := func(name string, names []string) string {
+		if slices.Contains(names, name) {
+			return "enabled"
+		}
+		return ""
+	}
+	if len(provider.Discoverers())+len(provider.Enrichers()) == 0 {
+		fmt.Println("no providers are compiled into this binary")
+		return nil
+	}
+	for _, name := range provider.Discoverers() {
+		fmt.Printf("discovery   %-20s %s\n", name, enabled(name, cfg.Providers.Discovery))
+	}
+	for _, name := range provider.Enrichers() {
+		fmt.Printf("enrichment  %-20s %s\n", name, enabled(name, cfg.Providers.Enrichment))
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/server"
+)
+
+var (
+	cmdTag = &cobra.Command{
+		Use:   "tag",
+		Short: "manage tags used to group devices and networks",
+	}
+
+	cmdTagList = &cobra.Command{
+		Use:   "list",
+		Short: "list tag definitions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdTagList(args)
+		},
+	}
+
+	flagTagDescription      string
+	flagTagPingInterval     time.Duration
+	flagTagPortScanInterval time.Duration
+	cmdTagSet               = &cobra.Command{
+		Use:   "set [name]",
+		Short: "create or update a tag definition",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdTagSet(args)
+		},
+	}
+
+	cmdTagDelete = &cobra.Command{
+		Use:   "delete [name]",
+		Short: "delete a tag definition and remove it from all devices and networks",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdTagDelete(args)
+		},
+	}
+
+	flagTagRemove bool
+	cmdTagDevice  = &cobra.Command{
+		Use:   "device [addr] [tag]",
+		Short: "add (or with --remove remove) a tag on a device",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdTagDevice(args)
+		},
+	}
+
+	cmdTagNetwork = &cobra.Command{
+		Use:   "network [name] [tag]",
+		Short: "add (or with --remove remove) a tag on a network",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdTagNetwork(args)
+		},
+	}
+)
+
+func init() {
+	cmdTag.AddCommand(cmdTagList, cmdTagSet, cmdTagDelete, cmdTagDevice, cmdTagNetwork)
+
+	cmdTagSet.Flags().StringVar(&flagTagDescription, "description", "", "tag description")
+	cmdTagSet.Flags().
+		DurationVar(&flagTagPingInterval, "ping", 0, "ping interval for tagged devices, 0 for the default")
+	cmdTagSet.Flags().
+		DurationVar(&flagTagPortScanInterval, "portscan", 0, "port scan interval for tagged devices, 0 for the default")
+	cmdTagDevice.Flags().BoolVar(&flagTagRemove, "remove", false, "remove the tag")
+	cmdTagNetwork.Flags().BoolVar(&flagTagRemove, "remove", false, "remove the tag")
+}
+
+func runCmdTagList([]string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	ctx := context.Background()
+	defs, err := m.ListTagDefinitions(ctx)
+	if err != nil {
+		return err
+	}
+	devices, err := m.ListDevices(ctx)
+	if err != nil {
+		return err
+	}
+	for _, def := range defs {
+		devs := len(filterTagged(devices, def.Name))
+		fmt.Printf(
+			"%-20s ping:%-8s portscan:%-8s devices:%-5d %s\n",
+			def.Name,
+			intervalString(def.Policy.PingInterval),
+			intervalString(def.Policy.PortScanInterval),
+			devs,
+			def.Description,
+		)
+	}
+	return nil
+}
+
+func runCmdTagSet(args []string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	return m.SaveTagDefinition(context.Background(), model.TagDefinition{
+		Name:        args[0],
+		Description: flagTagDescription,
+		Policy: model.MonitoringPolicy{
+			PingInterval:     flagTagPingInterval,
+			PortScanInterval: flagTagPortScanInterval,
+		},
+	})
+}
+
+func runCmdTagDelete(args []string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	return m.RemoveTagDefinition(context.Background(), args[0])
+}
+
+func runCmdTagDevice(args []string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	addr, err := model.ParseAddr(args[0])
+	if err != nil {
+		return err
+	}
+	if flagTagRemove {
+		return m.UntagDevice(context.Background(), addr, args[1])
+	}
+	return m.TagDevice(context.Background(), addr, args[1])
+}
+
+func runCmdTagNetwork(args []string) error {
+	m, closefn, err := openMason(server.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer closefn()
+
+	if flagTagRemove {
+		return m.UntagNetwork(context.Background(), args[0], args[1])
+	}
+	return m.TagNetwork(context.Background(), args[0], args[1])
+}
+
+func filterTagged(devs []model.Device, name string) []model.Device {
+	ret := make([]model.Device, 0)
+	for _, d := range devs {
+		if model.TagDeviceFilter(name)(d) {
+			ret = append(ret, d)
+		}
+	}
+	return ret
+}
+
+func intervalString(d time.Duration) string {
+	if d == 0 {
+		return "default"
+	}
+	return d.String()
+}
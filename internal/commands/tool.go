@@ -8,6 +8,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/netip"
 	"os"
 	"strconv"
 	"time"
@@ -20,6 +21,7 @@ import (
 	"github.com/networkables/mason/internal/model"
 	"github.com/networkables/mason/internal/server"
 	"github.com/networkables/mason/internal/sqlitestore"
+	"github.com/networkables/mason/nettools"
 )
 
 var (
@@ -28,6 +30,7 @@ var (
 		Short: "network tools",
 		PersistentPreRunE: func(*cobra.Command, []string) error {
 			cfg := server.GetConfig()
+			usePrivilegedHelper(cfg)
 			if !server.HasCapabilities(cfg) {
 				return errors.New(
 					"capabilities required based on config, but not granted to the executable",
@@ -72,7 +75,20 @@ var (
 		},
 	}
 
-	cmdToolTraceroute = &cobra.Command{
+	flagTcpingCount   int
+	flagTcpingTimeout time.Duration
+	cmdToolTcping     = &cobra.Command{
+		Use:   "tcping [host:port]",
+		Short: "time tcp connects to a port of the target",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdToolTcping(args)
+		},
+	}
+
+	flagTracerouteProbes []string
+	flagTraceroutePort   int
+	cmdToolTraceroute    = &cobra.Command{
 		Use:   "traceroute [target]",
 		Short: "discover hops between mason and the target",
 		Args:  cobra.MinimumNArgs(1),
@@ -90,6 +106,16 @@ var (
 		},
 	}
 
+	flagCidrSplit int
+	cmdToolCidr   = &cobra.Command{
+		Use:   "cidr [prefix]",
+		Short: "show the address range of a prefix and how it splits into subnets",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdToolCidr(args)
+		},
+	}
+
 	cmdToolSNMP = &cobra.Command{
 		Use:   "snmp [target]",
 		Short: "show snmp for a target",
@@ -107,19 +133,46 @@ var (
 			return runCmdToolCheckDNS(args)
 		},
 	}
+
+	cmdToolCompareDNS = &cobra.Command{
+		Use:   "dnscompare [target]",
+		Short: "compare plain, DoT and DoH answers to find blocked or intercepted resolvers",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCmdToolCompareDNS(args)
+		},
+	}
 )
 
 func init() {
 	cmdTool.AddCommand(
 		cmdToolPing,
+		cmdToolTcping,
 		cmdToolArpPing,
 		cmdToolPortScan,
 		cmdToolExternalIP,
 		cmdToolTraceroute,
+		cmdToolMtr,
 		cmdToolTLS,
+		cmdToolCidr,
 		cmdToolSNMP,
 		cmdToolCheckDNS,
+		cmdToolCompareDNS,
+		cmdToolCapture,
 	)
+	cmdToolTcping.Flags().IntVarP(&flagTcpingCount, "count", "c", 4, "number of connects")
+	cmdToolTcping.Flags().
+		DurationVar(&flagTcpingTimeout, "timeout", time.Second, "max time to wait for each connect")
+	cmdToolTraceroute.Flags().StringSliceVar(
+		&flagTracerouteProbes,
+		"probe",
+		[]string{"icmp"},
+		"probes tried in order at each hop until one is answered: icmp, udp, tcp",
+	)
+	cmdToolTraceroute.Flags().
+		IntVar(&flagTraceroutePort, "port", 0, "destination port of udp and tcp probes, 0 for the default")
+	cmdToolCidr.Flags().
+		IntVar(&flagCidrSplit, "split", 0, "list the subnets of this prefix length")
 }
 
 func runCmdArpPing(args []string) error {
@@ -173,6 +226,36 @@ func runCmdPing(args []string) error {
 	return nil
 }
 
+func runCmdToolTcping(args []string) error {
+	target := args[0]
+
+	cfg := server.GetConfig()
+	m := server.New(server.WithConfig(cfg))
+
+	stats, err := m.TcpPing(context.Background(), target, flagTcpingCount, flagTcpingTimeout)
+	if err != nil {
+		return err
+	}
+	log.Info(
+		"tcping",
+		"target",
+		target,
+		"count",
+		stats.TotalPackets,
+		"packetloss",
+		stats.PacketLoss,
+		"min",
+		stats.Minimum,
+		"mean",
+		stats.Mean,
+		"max",
+		stats.Maximum,
+		"stddev",
+		stats.StdDev,
+	)
+	return nil
+}
+
 func runCmdToolPortScan(args []string) error {
 	cfg := server.GetConfig()
 	target := args[0]
@@ -206,7 +289,21 @@ func runCmdToolTraceroute(args []string) error {
 	svropts := []server.Option{
 		server.WithConfig(cfg),
 	}
+	probes := make([]nettools.TraceProbe, 0, len(flagTracerouteProbes))
+	for _, name := range flagTracerouteProbes {
+		probe, err := nettools.StringToTraceProbe(name)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, name)
+		}
+		probes = append(probes, probe)
+	}
+	// the probe each hop answered is only of interest when there is a fallback
+	showProbe := len(probes) > 1
+
 	headers := []string{"Hop", "Address", "Loss", "Min", "Max"}
+	if showProbe {
+		headers = append(headers, "Probe")
+	}
 	if cfg.Asn.Enabled {
 		headers = append(headers, "Asn", "Org")
 		sqls, err := sqlitestore.New(cfg.Store.Sqlite)
@@ -220,61 +317,40 @@ func runCmdToolTraceroute(args []string) error {
 	}
 	m := server.New(svropts...)
 
-	hops, err := m.Traceroute(context.Background(), target)
+	hops, err := m.Traceroute(
+		context.Background(),
+		target,
+		nettools.I4EWithTraceProbes(probes...),
+		nettools.I4EWithTracePort(flagTraceroutePort),
+	)
 	if err != nil {
 		return err
 	}
 	// log.Info("traceroute", "target", target)
 
-	re := lipgloss.NewRenderer(os.Stdout)
-
-	var (
-		purple    = lipgloss.Color("99")
-		gray      = lipgloss.Color("245")
-		lightGray = lipgloss.Color("241")
-		// HeaderStyle is the lipgloss style used for the table headers.
-		HeaderStyle = re.NewStyle().Foreground(purple).Bold(true).Align(lipgloss.Center)
-		// CellStyle is the base lipgloss style used for the table rows.
-		// CellStyle = re.NewStyle().Padding(0, 1).Width(14)
-		CellStyle = re.NewStyle().Padding(0, 1)
-		// OddRowStyle is the lipgloss style used for odd-numbered table rows.
-		OddRowStyle = CellStyle.Foreground(gray)
-		// EvenRowStyle is the lipgloss style used for even-numbered table rows.
-		EvenRowStyle = CellStyle.Foreground(lightGray)
-		// BorderStyle is the lipgloss style used for the table border.
-		BorderStyle = lipgloss.NewStyle().Foreground(purple)
-		colstyles   = []lipgloss.Style{
-			// Hop
-			lipgloss.NewStyle().Width(5).Align(lipgloss.Center),
-			// Address
-			lipgloss.NewStyle().Width(19).Align(lipgloss.Right),
-			// Loss
-			lipgloss.NewStyle().Width(7).Align(lipgloss.Right),
-			// Min
-			lipgloss.NewStyle().Width(9).Align(lipgloss.Right),
-			// Max
-			lipgloss.NewStyle().Width(9).Align(lipgloss.Right),
-			// Asn
-			lipgloss.NewStyle().Width(7).Align(lipgloss.Center),
-			// Org
-			lipgloss.NewStyle().Width(50).Align(lipgloss.Left),
-		}
+	colstyles := []lipgloss.Style{
+		// Hop
+		lipgloss.NewStyle().Width(5).Align(lipgloss.Center),
+		// Address
+		lipgloss.NewStyle().Width(19).Align(lipgloss.Right),
+		// Loss
+		lipgloss.NewStyle().Width(7).Align(lipgloss.Right),
+		// Min
+		lipgloss.NewStyle().Width(9).Align(lipgloss.Right),
+		// Max
+		lipgloss.NewStyle().Width(9).Align(lipgloss.Right),
+	}
+	if showProbe {
+		// Probe
+		colstyles = append(colstyles, lipgloss.NewStyle().Width(7).Align(lipgloss.Center))
+	}
+	colstyles = append(colstyles,
+		// Asn
+		lipgloss.NewStyle().Width(7).Align(lipgloss.Center),
+		// Org
+		lipgloss.NewStyle().Width(50).Align(lipgloss.Left),
 	)
-
-	t := table.New().
-		Border(lipgloss.NormalBorder()).
-		BorderStyle(BorderStyle).
-		StyleFunc(func(row, col int) lipgloss.Style {
-			switch {
-			case row == 0:
-				return HeaderStyle
-			case row%2 == 0:
-				return EvenRowStyle.Inherit(colstyles[col])
-			default:
-				return OddRowStyle.Inherit(colstyles[col])
-			}
-		}).
-		Headers(headers...)
+	t := newHopTable(colstyles, headers...)
 
 	for i, hop := range hops {
 		row := []string{
@@ -284,6 +360,13 @@ func runCmdToolTraceroute(args []string) error {
 			hop.Maximum.Round(50 * time.Microsecond).String(),
 			hop.Maximum.Round(50 * time.Microsecond).String(),
 		}
+		if showProbe {
+			probe := ""
+			if hop.SuccessCount > 0 {
+				probe = hop.Probe.String()
+			}
+			row = append(row, probe)
+		}
 		if cfg.Asn.Enabled {
 			asn := m.LookupIP(model.AddrToModelAddr(hop.Peer))
 			asninfo, err := m.GetAsn(context.Background(), asn)
@@ -328,6 +411,43 @@ func runCmdToolTraceroute(args []string) error {
 	return nil
 }
 
+// newHopTable returns the table used to print the hops of a route, the column styles set
+// the width and alignment of each column
+func newHopTable(colstyles []lipgloss.Style, headers ...string) *table.Table {
+	re := lipgloss.NewRenderer(os.Stdout)
+
+	var (
+		purple    = lipgloss.Color("99")
+		gray      = lipgloss.Color("245")
+		lightGray = lipgloss.Color("241")
+		// HeaderStyle is the lipgloss style used for the table headers.
+		HeaderStyle = re.NewStyle().Foreground(purple).Bold(true).Align(lipgloss.Center)
+		// CellStyle is the base lipgloss style used for the table rows.
+		CellStyle = re.NewStyle().Padding(0, 1)
+		// OddRowStyle is the lipgloss style used for odd-numbered table rows.
+		OddRowStyle = CellStyle.Foreground(gray)
+		// EvenRowStyle is the lipgloss style used for even-numbered table rows.
+		EvenRowStyle = CellStyle.Foreground(lightGray)
+		// BorderStyle is the lipgloss style used for the table border.
+		BorderStyle = lipgloss.NewStyle().Foreground(purple)
+	)
+
+	return table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(BorderStyle).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			switch {
+			case row == 0:
+				return HeaderStyle
+			case row%2 == 0:
+				return EvenRowStyle.Inherit(colstyles[col])
+			default:
+				return OddRowStyle.Inherit(colstyles[col])
+			}
+		}).
+		Headers(headers...)
+}
+
 func runCmdToolTLS(args []string) error {
 	target := args[0]
 
@@ -342,6 +462,47 @@ func runCmdToolTLS(args []string) error {
 	return nil
 }
 
+func runCmdToolCidr(args []string) error {
+	c, err := nettools.ParseCIDR(args[0])
+	if err != nil {
+		return err
+	}
+	field := func(name string, value fmt.Stringer) {
+		// ipv6 prefixes have no broadcast or netmask
+		if addr, ok := value.(netip.Addr); ok && !addr.IsValid() {
+			return
+		}
+		fmt.Printf("%-14s %s\n", name, value)
+	}
+	field("prefix", c.Prefix)
+	field("network", c.Network)
+	field("broadcast", c.Broadcast)
+	field("netmask", c.Netmask)
+	field("wildcard", c.Wildcard)
+	fmt.Printf("%-14s %s - %s\n", "usable range", c.FirstUsable, c.LastUsable)
+	field("addresses", c.Addresses)
+	field("usable", c.Usable)
+	if len(c.Splits) > 0 {
+		fmt.Println()
+		fmt.Printf("%-8s %-22s %s\n", "split", "subnets", "usable each")
+		for _, split := range c.Splits {
+			fmt.Printf("/%-7d %-22s %s\n", split.Bits, split.Subnets, split.Usable)
+		}
+	}
+	if flagCidrSplit == 0 {
+		return nil
+	}
+	subnets, err := nettools.SplitPrefix(c.Prefix, flagCidrSplit)
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	for _, subnet := range subnets {
+		fmt.Println(subnet)
+	}
+	return nil
+}
+
 func runCmdToolSNMP(args []string) error {
 	target := args[0]
 
@@ -394,3 +555,36 @@ func runCmdToolCheckDNS(args []string) error {
 
 	return nil
 }
+
+func runCmdToolCompareDNS(args []string) error {
+	target := args[0]
+
+	cfg := server.GetConfig()
+	m := server.New(server.WithConfig(cfg))
+
+	results, err := m.CompareDNSResolvers(context.Background(), target)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		kv := []any{
+			"target", target,
+			"provider", r.Provider,
+			"transport", r.Transport,
+			"server", r.Server,
+			"status", r.Status,
+			"records", r.Addrs,
+		}
+		if r.Err != nil {
+			kv = append(kv, "error", r.Err)
+		}
+		if r.Status == nettools.DNSResolverOK {
+			log.Info("dns", kv...)
+		} else {
+			log.Warn("dns", kv...)
+		}
+	}
+
+	return nil
+}
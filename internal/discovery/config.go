@@ -20,9 +20,15 @@ type (
 		CheckInterval           time.Duration
 		NetworkScanInterval     time.Duration
 		MaxWorkers              int
+		MaxNetworkScanners      int
+		MaxManualScanSize       int
 		Arp                     *ArpConfig
+		HostArp                 *HostArpConfig
 		Icmp                    *ICMPConfig
 		Snmp                    *SNMPConfig
+		Dhcp                    *DHCPConfig
+		Ndp                     *NDPConfig
+		DryRun                  *DryRunConfig
 	}
 
 	ArpConfig struct {
@@ -30,6 +36,11 @@ type (
 		Timeout time.Duration
 	}
 
+	HostArpConfig struct {
+		Enabled  bool
+		Interval time.Duration
+	}
+
 	ICMPConfig struct {
 		Enabled      bool
 		Privileged   bool
@@ -38,6 +49,23 @@ type (
 		SleepBetween time.Duration
 	}
 
+	DryRunConfig struct {
+		Enabled bool
+		Fixture string
+	}
+
+	DHCPConfig struct {
+		Enabled       bool
+		ListenAddress string
+	}
+
+	// NDPConfig sets the listener for the IPv6 neighbor discovery messages, every interval
+	// the routers and nodes are solicited and the host's IPv6 neighbor table is read
+	NDPConfig struct {
+		Enabled  bool
+		Interval time.Duration
+	}
+
 	SNMPConfig struct {
 		Enabled                 bool
 		Timeout                 time.Duration
@@ -50,8 +78,12 @@ type (
 
 func SetFlags(fs *pflag.FlagSet, cfg *Config) {
 	cfg.Arp = &ArpConfig{}
+	cfg.HostArp = &HostArpConfig{}
 	cfg.Icmp = &ICMPConfig{}
 	cfg.Snmp = &SNMPConfig{}
+	cfg.Dhcp = &DHCPConfig{}
+	cfg.Ndp = &NDPConfig{}
+	cfg.DryRun = &DryRunConfig{}
 	configMajorKey := "discovery"
 
 	// Base
@@ -103,6 +135,22 @@ func SetFlags(fs *pflag.FlagSet, cfg *Config) {
 		2,
 		"number of workers to use for device discovery",
 	)
+	flagset.Int(
+		fs,
+		&cfg.MaxNetworkScanners,
+		configMajorKey,
+		"maxnetworkscanners",
+		2,
+		"number of networks enumerated at once, all share the discovery workers",
+	)
+	flagset.Int(
+		fs,
+		&cfg.MaxManualScanSize,
+		configMajorKey,
+		"maxmanualscansize",
+		65536,
+		"largest number of addresses allowed in a manually requested scan (0 for no limit)",
+	)
 
 	// Arp
 	arpMajorKey := flagset.Key(configMajorKey, "arp")
@@ -124,6 +172,25 @@ func SetFlags(fs *pflag.FlagSet, cfg *Config) {
 		"how long to wait for an arp ping reply",
 	)
 
+	// Host Arp
+	hostArpMajorKey := flagset.Key(configMajorKey, "hostarp")
+	flagset.Bool(
+		fs,
+		&cfg.HostArp.Enabled,
+		hostArpMajorKey,
+		"enabled",
+		true,
+		"read the arp table of the host to find devices without sending probes",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.HostArp.Interval,
+		hostArpMajorKey,
+		"interval",
+		5*time.Minute,
+		"time between reads of the host arp table",
+	)
+
 	// Icmp
 	icmpMajorKey := flagset.Key(configMajorKey, "icmp")
 	flagset.Bool(
@@ -217,4 +284,61 @@ func SetFlags(fs *pflag.FlagSet, cfg *Config) {
 		24*time.Hour,
 		"time between interface table scans",
 	)
+
+	// Dhcp
+	dhcpMajorKey := flagset.Key(configMajorKey, "dhcp")
+	flagset.Bool(
+		fs,
+		&cfg.Dhcp.Enabled,
+		dhcpMajorKey,
+		"enabled",
+		false,
+		"listen for dhcp client requests (requires binding the dhcp server port)",
+	)
+	flagset.String(
+		fs,
+		&cfg.Dhcp.ListenAddress,
+		dhcpMajorKey,
+		"listenaddress",
+		":67",
+		"address to listen on for dhcp client requests",
+	)
+
+	// Ndp
+	ndpMajorKey := flagset.Key(configMajorKey, "ndp")
+	flagset.Bool(
+		fs,
+		&cfg.Ndp.Enabled,
+		ndpMajorKey,
+		"enabled",
+		false,
+		"listen for ipv6 neighbor discovery messages (requires raw socket access)",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Ndp.Interval,
+		ndpMajorKey,
+		"interval",
+		10*time.Minute,
+		"time between ipv6 router and neighbor solicitations",
+	)
+
+	// Dry Run
+	dryRunMajorKey := flagset.Key(configMajorKey, "dryrun")
+	flagset.Bool(
+		fs,
+		&cfg.DryRun.Enabled,
+		dryRunMajorKey,
+		"enabled",
+		false,
+		"log the probes network scans would send without sending them",
+	)
+	flagset.String(
+		fs,
+		&cfg.DryRun.Fixture,
+		dryRunMajorKey,
+		"fixture",
+		"",
+		"yaml file of synthetic networks and devices injected in dry run mode",
+	)
 }
@@ -12,15 +12,25 @@ import (
 
 	"github.com/emicklei/tre"
 
+	"github.com/networkables/mason/internal/logging"
 	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/ratelimit"
+	"github.com/networkables/mason/internal/tracing"
 	"github.com/networkables/mason/nettools"
 )
 
+var logger = logging.For(logging.Discovery)
+
 const (
-	ArpDiscoverySource     model.DiscoverySource = "ARP"
-	PingDiscoverySource    model.DiscoverySource = "PING"
-	SNMPDiscoverySource    model.DiscoverySource = "SNMP"
-	SNMPArpDiscoverySource model.DiscoverySource = "SNMP_ARP"
+	ArpDiscoverySource        model.DiscoverySource = "ARP"
+	PingDiscoverySource       model.DiscoverySource = "PING"
+	SNMPDiscoverySource       model.DiscoverySource = "SNMP"
+	SNMPArpDiscoverySource    model.DiscoverySource = "SNMP_ARP"
+	DHCPDiscoverySource       model.DiscoverySource = "DHCP"
+	NDPDiscoverySource        model.DiscoverySource = "NDP"
+	HostArpDiscoverySource    model.DiscoverySource = "HOST_ARP"
+	KubernetesDiscoverySource model.DiscoverySource = "KUBERNETES"
+	UnifiDiscoverySource      model.DiscoverySource = "UNIFI"
 )
 
 type (
@@ -81,6 +91,9 @@ func NoDeviceDiscovered(addr model.Addr) NoDeviceDiscoveredError {
 type scanfunc func(context.Context, model.Addr) (model.EventDeviceDiscovered, error)
 
 func BuildAddrScanners(cfg *Config) []scanfunc {
+	if cfg.DryRun != nil && cfg.DryRun.Enabled {
+		return []scanfunc{dryRunScanner(cfg)}
+	}
 	ret := make([]scanfunc, 0)
 	if cfg.Arp.Enabled {
 		ret = append(ret,
@@ -125,28 +138,51 @@ func BuildAddrScannerFunc(
 	}
 }
 
+// BuildNetworkScanFunc returns a func which feeds every address of a network to the
+// discovery workers.  Several networks can be enumerated at once, they all feed the same
+// discovery workers so the probe budget is shared between them.  The addresses excluded from
+// scans, looked up once per network, are skipped.
 func BuildNetworkScanFunc(
 	q chan model.Addr,
-	status *string,
+	progress *ScanProgress,
+	limits *ratelimit.Group,
+	exclusions func(context.Context) model.Exclusions,
 ) func(context.Context, model.Network) (string, error) {
 	return func(ctx context.Context, n model.Network) (string, error) {
 		if n.Prefix.Is6() {
 			return "", nil
 		}
+		if !progress.start(n) {
+			logger.Debug("network scan already running", "network", n.Prefix)
+			return "", nil
+		}
+		defer progress.finish(n)
 
-		*status = n.String()
+		var excluded model.Exclusions
+		if exclusions != nil {
+			excluded = exclusions(ctx)
+		}
+		limiter := limits.Network(n.Prefix.P)
 		ni := model.NewNetworkIteratorAsChannel(n)
 		for addr := range ni.C {
 			if ctx.Err() != nil {
 				return "", nil
 			}
+			if excluded.Excludes(addr, model.ExcludeScan) {
+				progress.exclude(n)
+				continue
+			}
+			if limiter.Wait(ctx) != nil {
+				return "", nil
+			}
+			tracing.Carry(ctx, addr.String())
 			select {
 			case <-ctx.Done():
-				break
+				return "", nil
 			case q <- addr:
+				progress.advance(n)
 			}
 		}
-		*status = ""
 		return "", nil
 	}
 }
@@ -201,11 +237,13 @@ func discoverDeviceWithArp(
 	addr model.Addr,
 	cfg *ArpConfig,
 ) (model.EventDeviceDiscovered, error) {
+	start := time.Now()
 	entry, err := nettools.FindHardwareAddrOf(
 		ctx,
 		addr.Addr(),
 		nettools.WithArpReplyTimeout(cfg.Timeout),
 	)
+	logging.Probe(logging.Discovery, addr, "arp who-has", time.Since(start), err)
 	if err != nil {
 		if errors.Is(err, nettools.ErrNoResponseFromRemote) {
 			return model.EmptyDiscoveredDevice, NoDeviceDiscovered(addr)
@@ -241,6 +279,12 @@ func discoverDeviceWithICMP(
 		nettools.I4EWithPrivileged(cfg.Privileged),
 		nettools.I4EWithBetweenDuration(cfg.SleepBetween),
 	)
+	if len(responses) == 0 {
+		logging.Probe(logging.Discovery, addr, "icmp echo", 0, err)
+	}
+	for _, r := range responses {
+		logging.Probe(logging.Discovery, addr, "icmp echo", r.Elapsed, r.Err)
+	}
 	if err != nil {
 		if errors.Is(err, nettools.ErrNoResponseFromRemote) {
 			return event, NoDeviceDiscovered(addr)
@@ -269,10 +313,18 @@ func discoverDeviceWithSNMP(
 ) (model.EventDeviceDiscovered, error) {
 	for _, port := range cfg.Ports {
 		for _, community := range cfg.Community {
+			start := time.Now()
 			ssi, err := nettools.SnmpGetSystemInfo(ctx, addr.Addr(),
 				nettools.WithSnmpCommunity(community),
 				nettools.WithSnmpPort(port),
 				nettools.WithSnmpReplyTimeout(cfg.Timeout))
+			logging.Probe(
+				logging.Discovery,
+				fmt.Sprintf("%s:%d", addr, port),
+				"snmp get community "+community,
+				time.Since(start),
+				err,
+			)
 			if err != nil {
 				if errors.Is(err, nettools.ErrConnectionRefused) ||
 					errors.Is(err, nettools.ErrNoResponseFromRemote) {
@@ -0,0 +1,154 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// FixtureDiscoverySource is the source recorded on the synthetic devices of a fixture
+const FixtureDiscoverySource model.DiscoverySource = "FIXTURE"
+
+type (
+	// Fixture is the layout of a file of synthetic networks and devices used in dry run mode
+	Fixture struct {
+		Networks []FixtureNetwork `yaml:"networks"`
+		Devices  []FixtureDevice  `yaml:"devices"`
+	}
+
+	FixtureNetwork struct {
+		Name   string   `yaml:"name"`
+		Prefix string   `yaml:"prefix"`
+		Site   string   `yaml:"site"`
+		Tags   []string `yaml:"tags"`
+	}
+
+	FixtureDevice struct {
+		Addr         string   `yaml:"addr"`
+		MAC          string   `yaml:"mac"`
+		Name         string   `yaml:"name"`
+		Manufacturer string   `yaml:"manufacturer"`
+		Type         string   `yaml:"type"`
+		Site         string   `yaml:"site"`
+		Tags         []string `yaml:"tags"`
+	}
+)
+
+// LoadFixture reads the fixture file and returns its networks and devices
+func LoadFixture(path string) ([]model.Network, []model.Device, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var f Fixture
+	err = yaml.Unmarshal(b, &f)
+	if err != nil {
+		return nil, nil, err
+	}
+	networks := make([]model.Network, 0, len(f.Networks))
+	for _, fn := range f.Networks {
+		n, err := model.New(fn.Name, fn.Prefix)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fixture network %s: %w", fn.Name, err)
+		}
+		n.Site = fn.Site
+		n.Tags = fixtureTags(fn.Tags)
+		networks = append(networks, n)
+	}
+	devices := make([]model.Device, 0, len(f.Devices))
+	for _, fd := range f.Devices {
+		d, err := fd.device()
+		if err != nil {
+			return nil, nil, fmt.Errorf("fixture device %s: %w", fd.Addr, err)
+		}
+		devices = append(devices, d)
+	}
+	return networks, devices, nil
+}
+
+func (fd FixtureDevice) device() (model.Device, error) {
+	addr, err := model.ParseAddr(fd.Addr)
+	if err != nil {
+		return model.Device{}, err
+	}
+	d := model.Device{
+		Name:         fd.Name,
+		Addr:         addr,
+		DiscoveredBy: FixtureDiscoverySource,
+	}
+	if d.Name == "" {
+		d.Name = addr.String()
+	}
+	if fd.MAC != "" {
+		d.MAC, err = model.ParseMAC(fd.MAC)
+		if err != nil {
+			return model.Device{}, err
+		}
+	}
+	d.Meta.Manufacturer = fd.Manufacturer
+	d.Meta.DeviceType = model.DeviceType(fd.Type)
+	d.Meta.Site = fd.Site
+	d.Meta.Tags = fixtureTags(fd.Tags)
+	return d, nil
+}
+
+func fixtureTags(names []string) model.Tags {
+	tags := make(model.Tags, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, model.Tag{Val: name})
+	}
+	return tags
+}
+
+// Probes describes the probes a scan of the address sends with the configuration, in the
+// order they are tried
+func Probes(cfg *Config, addr model.Addr) []string {
+	var probes []string
+	if cfg.Arp.Enabled {
+		probes = append(probes, fmt.Sprintf("arp who-has %s", addr))
+	}
+	if cfg.Icmp.Enabled {
+		probes = append(probes, fmt.Sprintf("icmp echo %s x%d", addr, cfg.Icmp.PingCount))
+	}
+	if cfg.Snmp.Enabled {
+		for _, port := range cfg.Snmp.Ports {
+			for _, community := range cfg.Snmp.Community {
+				probes = append(probes, fmt.Sprintf("snmp get %s:%d community %s", addr, port, community))
+			}
+		}
+	}
+	return probes
+}
+
+// dryRunScanner logs the probes a scan would send in place of sending them, the devices of
+// the fixture are discovered when their address is scanned
+func dryRunScanner(cfg *Config) scanfunc {
+	fixture := make(map[model.Addr]model.Device)
+	if cfg.DryRun.Fixture != "" {
+		_, devices, err := LoadFixture(cfg.DryRun.Fixture)
+		if err != nil {
+			logger.Error("dry run fixture", "file", cfg.DryRun.Fixture, "error", err)
+		}
+		for _, d := range devices {
+			fixture[d.Addr] = d
+		}
+	}
+	return func(ctx context.Context, addr model.Addr) (model.EventDeviceDiscovered, error) {
+		logger.Info("dry run scan", "addr", addr, "probes", Probes(cfg, addr))
+		d, ok := fixture[addr]
+		if !ok {
+			return model.EmptyDiscoveredDevice, NoDeviceDiscovered(addr)
+		}
+		d.DiscoveredAt = time.Now()
+		return model.EventDeviceDiscovered(d), nil
+	}
+}
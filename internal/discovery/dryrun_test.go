@@ -0,0 +1,127 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package discovery
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const testFixture = `
+networks:
+  - name: lab
+    prefix: 10.10.0.0/24
+    site: home
+devices:
+  - addr: 10.10.0.1
+    mac: 00:11:22:33:44:55
+    name: router
+    type: network
+    tags: [core]
+  - addr: 10.10.0.20
+`
+
+func writeFixture(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fixture.yaml")
+	err := os.WriteFile(path, []byte(content), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFixture(t *testing.T) {
+	networks, devices, err := LoadFixture(writeFixture(t, testFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(networks) != 1 || networks[0].Name != "lab" || networks[0].Site != "home" ||
+		networks[0].Prefix.String() != "10.10.0.0/24" {
+		t.Errorf("unexpected networks: %v", networks)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("devices want: 2, got: %d", len(devices))
+	}
+	router := devices[0]
+	if router.Name != "router" || router.MAC.String() != "00:11:22:33:44:55" ||
+		router.Meta.DeviceType != model.DeviceTypeNetwork || !router.Meta.Tags.Has("core") ||
+		router.DiscoveredBy != FixtureDiscoverySource {
+		t.Errorf("unexpected router: %+v", router)
+	}
+	if devices[1].Name != "10.10.0.20" {
+		t.Errorf("unnamed device want addr as name, got: %s", devices[1].Name)
+	}
+
+	_, _, err = LoadFixture(writeFixture(t, "devices:\n  - addr: not-an-addr\n"))
+	if err == nil {
+		t.Error("want error for invalid addr")
+	}
+}
+
+func TestProbes(t *testing.T) {
+	addr := model.MustParseAddr("10.10.0.1")
+	tests := map[string]struct {
+		cfg  *Config
+		want []string
+	}{
+		"None": {
+			cfg: &Config{Arp: &ArpConfig{}, Icmp: &ICMPConfig{}, Snmp: &SNMPConfig{}},
+		},
+		"All": {
+			cfg: &Config{
+				Arp:  &ArpConfig{Enabled: true},
+				Icmp: &ICMPConfig{Enabled: true, PingCount: 2},
+				Snmp: &SNMPConfig{
+					Enabled:   true,
+					Community: []string{"public", "private"},
+					Ports:     []int{161},
+				},
+			},
+			want: []string{
+				"arp who-has 10.10.0.1",
+				"icmp echo 10.10.0.1 x2",
+				"snmp get 10.10.0.1:161 community public",
+				"snmp get 10.10.0.1:161 community private",
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, Probes(tc.cfg, addr)); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDryRunScanner(t *testing.T) {
+	cfg := &Config{
+		Arp:    &ArpConfig{},
+		Icmp:   &ICMPConfig{Enabled: true, PingCount: 1},
+		Snmp:   &SNMPConfig{},
+		DryRun: &DryRunConfig{Enabled: true, Fixture: writeFixture(t, testFixture)},
+	}
+	scan := BuildAddrScannerFunc(BuildAddrScanners(cfg))
+
+	d, err := scan(context.Background(), model.MustParseAddr("10.10.0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != "router" || d.DiscoveredAt.IsZero() {
+		t.Errorf("unexpected device: %+v", d)
+	}
+	_, err = scan(context.Background(), model.MustParseAddr("10.10.0.2"))
+	if !errors.Is(err, ErrNoDeviceDiscovered) {
+		t.Errorf("error want: %v, got: %v", ErrNoDeviceDiscovered, err)
+	}
+}
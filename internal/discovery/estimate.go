@@ -0,0 +1,84 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package discovery
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/ratelimit"
+)
+
+// ScanEstimate is the expected cost of scanning a network, assuming no address responds
+type ScanEstimate struct {
+	Addresses int
+	Packets   int
+	Duration  time.Duration
+}
+
+type ScanTooLargeError struct {
+	Addresses int
+	Max       int
+}
+
+var ErrScanTooLarge = ScanTooLargeError{}
+
+func (e ScanTooLargeError) Error() string {
+	return fmt.Sprintf("scan of %d addresses exceeds the limit of %d", e.Addresses, e.Max)
+}
+
+func (e ScanTooLargeError) Is(target error) bool {
+	_, ok := target.(ScanTooLargeError)
+	return ok
+}
+
+// EstimateScan computes the addresses, packets and duration needed to scan the network
+// with the given discovery config.  Non-responding addresses are the worst case since every
+// enabled scanner runs to its timeout, so that is what is assumed.  A scan takes at least as
+// long as the rate limits allow its packets to be sent.
+func EstimateScan(cfg *Config, limits *ratelimit.Config, n model.Network) ScanEstimate {
+	est := ScanEstimate{}
+	if n.Prefix.Is6() {
+		// ipv6 networks are excluded from discovery
+		return est
+	}
+	est.Addresses = 1 << (32 - n.Prefix.P.Bits())
+
+	var (
+		packets int
+		elapsed time.Duration
+	)
+	if cfg.Arp.Enabled {
+		packets++
+		elapsed += cfg.Arp.Timeout
+	}
+	if cfg.Icmp.Enabled {
+		packets += cfg.Icmp.PingCount
+		elapsed += time.Duration(cfg.Icmp.PingCount) * (cfg.Icmp.Timeout + cfg.Icmp.SleepBetween)
+	}
+	if cfg.Snmp.Enabled {
+		attempts := len(cfg.Snmp.Ports) * len(cfg.Snmp.Community)
+		packets += attempts
+		elapsed += time.Duration(attempts) * cfg.Snmp.Timeout
+	}
+
+	workers := max(cfg.MaxWorkers, 1)
+	est.Packets = est.Addresses * packets
+	est.Duration = time.Duration(est.Addresses) * elapsed / time.Duration(workers)
+	if rate := limits.Rate(); rate > 0 {
+		paced := time.Duration(float64(est.Packets) / float64(rate) * float64(time.Second))
+		est.Duration = max(est.Duration, paced)
+	}
+	return est
+}
+
+// CheckScanSize returns an error if the estimate is over the configured manual scan limit
+func CheckScanSize(cfg *Config, est ScanEstimate) error {
+	if cfg.MaxManualScanSize > 0 && est.Addresses > cfg.MaxManualScanSize {
+		return ScanTooLargeError{Addresses: est.Addresses, Max: cfg.MaxManualScanSize}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package discovery
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/ratelimit"
+)
+
+func TestEstimateScan(t *testing.T) {
+	cfg := &Config{
+		MaxWorkers: 2,
+		Arp:        &ArpConfig{Enabled: true, Timeout: 10 * time.Millisecond},
+		Icmp: &ICMPConfig{
+			Enabled:      true,
+			Timeout:      10 * time.Millisecond,
+			PingCount:    2,
+			SleepBetween: time.Millisecond,
+		},
+		Snmp: &SNMPConfig{
+			Enabled:   true,
+			Timeout:   15 * time.Millisecond,
+			Community: []string{"public"},
+			Ports:     []int{161},
+		},
+	}
+	tests := map[string]struct {
+		prefix string
+		limits *ratelimit.Config
+		want   ScanEstimate
+	}{
+		"Slash24": {
+			prefix: "192.168.1.0/24",
+			want: ScanEstimate{
+				Addresses: 256,
+				Packets:   256 * 4,
+				Duration:  256 * 47 * time.Millisecond / 2,
+			},
+		},
+		"RateAboveTimeouts": {
+			prefix: "192.168.1.0/24",
+			limits: &ratelimit.Config{Global: 1000},
+			want: ScanEstimate{
+				Addresses: 256,
+				Packets:   256 * 4,
+				Duration:  256 * 47 * time.Millisecond / 2,
+			},
+		},
+		"RateLimited": {
+			prefix: "192.168.1.0/24",
+			limits: &ratelimit.Config{Global: 100, PerNetwork: 16},
+			want: ScanEstimate{
+				Addresses: 256,
+				Packets:   256 * 4,
+				Duration:  64 * time.Second,
+			},
+		},
+		"PerNetworkOnly": {
+			prefix: "192.168.1.0/24",
+			limits: &ratelimit.Config{PerNetwork: 32},
+			want: ScanEstimate{
+				Addresses: 256,
+				Packets:   256 * 4,
+				Duration:  32 * time.Second,
+			},
+		},
+		"IPv6": {
+			prefix: "fd00::/64",
+			want:   ScanEstimate{},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			n, err := model.New("", tc.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := EstimateScan(cfg, tc.limits, n)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckScanSize(t *testing.T) {
+	cfg := &Config{MaxManualScanSize: 256}
+	if err := CheckScanSize(cfg, ScanEstimate{Addresses: 256}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := CheckScanSize(cfg, ScanEstimate{Addresses: 512})
+	if !errors.Is(err, ErrScanTooLarge) {
+		t.Errorf("want ErrScanTooLarge, got %v", err)
+	}
+	cfg.MaxManualScanSize = 0
+	if err := CheckScanSize(cfg, ScanEstimate{Addresses: 1 << 24}); err != nil {
+		t.Errorf("unexpected error with no limit: %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package discovery
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// NetworkScanProgress is a point in time view of the scan of a network.  Sent counts the
+// addresses handed to the discovery workers, Probed the addresses they have finished with and
+// Excluded the addresses skipped as excluded from scans.
+type NetworkScanProgress struct {
+	Name     string
+	Prefix   string
+	Total    int
+	Sent     int
+	Probed   int
+	Excluded int
+	Found    int
+	Started  time.Time
+	Finished time.Time
+	ETA      time.Duration
+}
+
+func (p NetworkScanProgress) Done() bool {
+	return !p.Finished.IsZero()
+}
+
+// Percent is the share of the network's addresses which have been probed
+func (p NetworkScanProgress) Percent() int {
+	if p.Total == 0 || p.Done() {
+		return 100
+	}
+	return (p.Probed + p.Excluded) * 100 / p.Total
+}
+
+// Elapsed is the running time of the scan, or its total duration once finished
+func (p NetworkScanProgress) Elapsed() time.Duration {
+	if p.Done() {
+		return p.Finished.Sub(p.Started)
+	}
+	return time.Since(p.Started)
+}
+
+// eta extrapolates the time remaining from the probe rate so far
+func (p NetworkScanProgress) eta(now time.Time) time.Duration {
+	if p.Done() || p.Probed == 0 {
+		return 0
+	}
+	per := now.Sub(p.Started) / time.Duration(p.Probed)
+	return per * time.Duration(p.Total-p.Probed-p.Excluded)
+}
+
+// EventNetworkScanStarted is published when the enumeration of a network begins
+type EventNetworkScanStarted NetworkScanProgress
+
+func (e EventNetworkScanStarted) String() string {
+	return fmt.Sprintf("%s %s", e.Name, e.Prefix)
+}
+
+// EventNetworkScanFinished is published once every address of a network has been probed
+type EventNetworkScanFinished NetworkScanProgress
+
+func (e EventNetworkScanFinished) String() string {
+	return fmt.Sprintf(
+		"%s %s found %d in %s",
+		e.Name,
+		e.Prefix,
+		e.Found,
+		e.Finished.Sub(e.Started).Round(time.Second),
+	)
+}
+
+type networkScan struct {
+	NetworkScanProgress
+	prefix     model.Prefix
+	enumerated bool
+}
+
+// complete marks the scan finished once every address has been handed out and probed,
+// true is returned when this call finished the scan
+func (s *networkScan) complete() bool {
+	if s.enumerated && s.Probed >= s.Sent && !s.Done() {
+		s.Finished = time.Now()
+		return true
+	}
+	return false
+}
+
+// ScanProgress tracks the network scans in flight, the last finished scan of each network
+// is kept so its duration can still be shown
+type ScanProgress struct {
+	mu      sync.Mutex
+	scans   map[string]*networkScan
+	publish func(any)
+}
+
+// NewScanProgress creates a tracker which sends scan started and finished events to
+// publish, publish may be nil
+func NewScanProgress(publish func(any)) *ScanProgress {
+	if publish == nil {
+		publish = func(any) {}
+	}
+	return &ScanProgress{
+		scans:   make(map[string]*networkScan),
+		publish: publish,
+	}
+}
+
+// start begins tracking a scan of the network, false is returned when the network is
+// already being scanned
+func (sp *ScanProgress) start(n model.Network) bool {
+	key := n.Prefix.String()
+	sp.mu.Lock()
+	if s, ok := sp.scans[key]; ok && !s.Done() {
+		sp.mu.Unlock()
+		return false
+	}
+	s := &networkScan{
+		NetworkScanProgress: NetworkScanProgress{
+			Name:    n.Name,
+			Prefix:  key,
+			Total:   1 << (32 - n.Prefix.Bits()),
+			Started: time.Now(),
+		},
+		prefix: n.Prefix,
+	}
+	sp.scans[key] = s
+	sp.mu.Unlock()
+	sp.publish(EventNetworkScanStarted(s.NetworkScanProgress))
+	return true
+}
+
+func (sp *ScanProgress) advance(n model.Network) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if s, ok := sp.scans[n.Prefix.String()]; ok {
+		s.Sent++
+	}
+}
+
+// exclude records an address of the network skipped as excluded from scans
+func (sp *ScanProgress) exclude(n model.Network) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if s, ok := sp.scans[n.Prefix.String()]; ok {
+		s.Excluded++
+	}
+}
+
+func (sp *ScanProgress) finish(n model.Network) {
+	sp.mu.Lock()
+	s, ok := sp.scans[n.Prefix.String()]
+	if !ok {
+		sp.mu.Unlock()
+		return
+	}
+	s.enumerated = true
+	done := s.complete()
+	p := s.NetworkScanProgress
+	sp.mu.Unlock()
+	if done {
+		sp.publish(EventNetworkScanFinished(p))
+	}
+}
+
+// probed records the discovery workers finishing with an address, the most specific
+// running scan containing the address is credited
+func (sp *ScanProgress) probed(addr model.Addr, found bool) {
+	sp.mu.Lock()
+	var match *networkScan
+	for _, s := range sp.scans {
+		if s.Done() || !s.prefix.Contains(addr) {
+			continue
+		}
+		if match == nil || s.prefix.Bits() > match.prefix.Bits() {
+			match = s
+		}
+	}
+	if match == nil {
+		sp.mu.Unlock()
+		return
+	}
+	match.Probed++
+	if found {
+		match.Found++
+	}
+	done := match.complete()
+	p := match.NetworkScanProgress
+	sp.mu.Unlock()
+	if done {
+		sp.publish(EventNetworkScanFinished(p))
+	}
+}
+
+// Active returns the number of networks currently being scanned
+func (sp *ScanProgress) Active() int {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	ct := 0
+	for _, s := range sp.scans {
+		if !s.Done() {
+			ct++
+		}
+	}
+	return ct
+}
+
+// List returns the tracked scans, running scans first then by network
+func (sp *ScanProgress) List() []NetworkScanProgress {
+	now := time.Now()
+	sp.mu.Lock()
+	ret := make([]NetworkScanProgress, 0, len(sp.scans))
+	for _, s := range sp.scans {
+		p := s.NetworkScanProgress
+		p.ETA = p.eta(now)
+		ret = append(ret, p)
+	}
+	sp.mu.Unlock()
+	slices.SortFunc(ret, func(a, b NetworkScanProgress) int {
+		if a.Done() != b.Done() {
+			if a.Done() {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(a.Prefix, b.Prefix)
+	})
+	return ret
+}
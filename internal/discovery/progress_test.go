@@ -0,0 +1,182 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package discovery
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func testNetwork(name string, prefix string) model.Network {
+	return model.Network{
+		Name:   name,
+		Prefix: model.PrefixToModelPrefix(netip.MustParsePrefix(prefix)),
+	}
+}
+
+func TestScanProgress_Start(t *testing.T) {
+	sp := NewScanProgress(nil)
+	n := testNetwork("lan", "192.168.1.0/24")
+
+	if !sp.start(n) {
+		t.Fatal("first start refused")
+	}
+	if sp.start(n) {
+		t.Fatal("second start of a running scan accepted")
+	}
+	sp.advance(n)
+	sp.advance(n)
+	sp.probed(model.MustParseAddr("192.168.1.1"), true)
+	sp.finish(n)
+
+	// the scan runs until the workers finish with every address handed out
+	if sp.Active() != 1 {
+		t.Fatalf("active: want 1, got %d", sp.Active())
+	}
+	if eta := sp.List()[0].ETA; eta <= 0 {
+		t.Fatalf("eta: want > 0, got %s", eta)
+	}
+	sp.probed(model.MustParseAddr("192.168.1.2"), false)
+	sp.probed(model.MustParseAddr("10.0.0.1"), true)
+	if sp.Active() != 0 {
+		t.Fatalf("active: want 0, got %d", sp.Active())
+	}
+
+	list := sp.List()
+	if len(list) != 1 {
+		t.Fatalf("list: want 1 scan, got %d", len(list))
+	}
+	want := NetworkScanProgress{
+		Name:   "lan",
+		Prefix: "192.168.1.0/24",
+		Total:  256,
+		Sent:   2,
+		Probed: 2,
+		Found:  1,
+	}
+	got := list[0]
+	if !got.Done() || got.ETA != 0 || got.Percent() != 100 {
+		t.Fatalf("unexpected finished progress %+v", got)
+	}
+	got.Started, got.Finished = time.Time{}, time.Time{}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("progress mismatch (-want +got):\n%s", diff)
+	}
+
+	// a finished network can be scanned again
+	if !sp.start(n) {
+		t.Fatal("restart of a finished scan refused")
+	}
+	if sp.List()[0].Sent != 0 {
+		t.Fatal("restarted scan kept the old progress")
+	}
+}
+
+func TestScanProgress_List(t *testing.T) {
+	sp := NewScanProgress(nil)
+	a := testNetwork("a", "10.0.1.0/24")
+	b := testNetwork("b", "10.0.2.0/24")
+	c := testNetwork("c", "10.0.3.0/24")
+	for _, n := range []model.Network{a, b, c} {
+		sp.start(n)
+	}
+	sp.finish(a)
+	// b has been enumerated but is still waiting on a probe
+	sp.advance(b)
+	sp.finish(b)
+
+	got := make([]string, 0, 3)
+	for _, p := range sp.List() {
+		got = append(got, p.Name)
+	}
+	want := []string{"b", "c", "a"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("list mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBuildNetworkScanFunc_Concurrent(t *testing.T) {
+	q := make(chan model.Addr)
+	sp := NewScanProgress(nil)
+	scan := BuildNetworkScanFunc(q, sp, nil, nil)
+	nets := []model.Network{
+		testNetwork("a", "10.0.1.0/28"),
+		testNetwork("b", "10.0.2.0/28"),
+	}
+
+	var wg sync.WaitGroup
+	for _, n := range nets {
+		wg.Add(1)
+		go func(n model.Network) {
+			defer wg.Done()
+			scan(context.Background(), n)
+		}(n)
+	}
+	go func() {
+		wg.Wait()
+		close(q)
+	}()
+
+	seen := make(map[string]int)
+	for addr := range q {
+		for _, n := range nets {
+			if n.Prefix.Contains(addr) {
+				seen[n.Name]++
+			}
+		}
+		sp.probed(addr, false)
+	}
+	for _, p := range sp.List() {
+		if !p.Done() {
+			t.Fatalf("scan of %s not finished", p.Name)
+		}
+		if seen[p.Name] != p.Sent {
+			t.Fatalf("%s: sent %d, received %d", p.Name, p.Sent, seen[p.Name])
+		}
+		if p.Sent == 0 {
+			t.Fatalf("%s: no addresses sent", p.Name)
+		}
+	}
+}
+
+func TestBuildNetworkScanFunc_Exclusions(t *testing.T) {
+	q := make(chan model.Addr)
+	sp := NewScanProgress(nil)
+	excluded := model.Exclusions{
+		{Name: "plcs", Prefix: model.MustParsePrefix("10.0.1.4/30"), Scan: true},
+		{Name: "pingonly", Prefix: model.MustParsePrefix("10.0.1.8/30"), Ping: true},
+	}
+	scan := BuildNetworkScanFunc(q, sp, nil, func(context.Context) model.Exclusions {
+		return excluded
+	})
+	n := testNetwork("a", "10.0.1.0/28")
+	go func() {
+		scan(context.Background(), n)
+		close(q)
+	}()
+
+	sent := 0
+	for addr := range q {
+		if excluded.Excludes(addr, model.ExcludeScan) {
+			t.Errorf("excluded address %s sent", addr)
+		}
+		sent++
+		sp.probed(addr, false)
+	}
+	p := sp.List()[0]
+	if !p.Done() {
+		t.Fatal("scan not finished")
+	}
+	if p.Excluded != 4 || p.Sent != sent {
+		t.Errorf("excluded want: 4, got: %d, sent want: %d, got: %d", p.Excluded, sent, p.Sent)
+	}
+}
@@ -7,9 +7,8 @@ package discovery
 import (
 	"context"
 
-	"github.com/charmbracelet/log"
-
 	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/ratelimit"
 	"github.com/networkables/mason/internal/workerpool"
 )
 
@@ -18,12 +17,23 @@ type Worker struct {
 	*workerpool.Pool[model.Addr, model.EventDeviceDiscovered]
 }
 
-func NewWorker(cfg *Config) *Worker {
+func NewWorker(cfg *Config, progress *ScanProgress) *Worker {
 	input := make(chan model.Addr)
-	return &Worker{
-		In:   input,
-		Pool: workerpool.New("discovery", input, BuildAddrScannerFunc(BuildAddrScanners(cfg))),
+	scan := BuildAddrScannerFunc(BuildAddrScanners(cfg))
+	w := &Worker{
+		In: input,
+		Pool: workerpool.New(
+			"discovery",
+			input,
+			func(ctx context.Context, addr model.Addr) (model.EventDeviceDiscovered, error) {
+				device, err := scan(ctx, addr)
+				progress.probed(addr, err == nil)
+				return device, err
+			},
+		),
 	}
+	w.Pool.TraceKey = model.Addr.String
+	return w
 }
 
 func (w *Worker) Run(ctx context.Context, max int) {
@@ -31,7 +41,7 @@ func (w *Worker) Run(ctx context.Context, max int) {
 }
 
 func (w *Worker) Close() {
-	log.Info("discovery workerpool shutdown")
+	logger.Info("discovery workerpool shutdown")
 	close(w.In)
 }
 
@@ -40,19 +50,28 @@ type NetworkScannerWorker struct {
 	*workerpool.Pool[model.Network, string]
 }
 
-func NewNetworkScannerWorker(status *string, devin chan model.Addr) *NetworkScannerWorker {
+func NewNetworkScannerWorker(
+	progress *ScanProgress,
+	devin chan model.Addr,
+	limits *ratelimit.Group,
+	exclusions func(context.Context) model.Exclusions,
+) *NetworkScannerWorker {
 	input := make(chan model.Network)
 	return &NetworkScannerWorker{
-		In:   input,
-		Pool: workerpool.New("networkscan", input, BuildNetworkScanFunc(devin, status)),
+		In: input,
+		Pool: workerpool.New(
+			"networkscan",
+			input,
+			BuildNetworkScanFunc(devin, progress, limits, exclusions),
+		),
 	}
 }
 
-func (w *NetworkScannerWorker) Run(ctx context.Context) {
-	w.Pool.Run(ctx, 1)
+func (w *NetworkScannerWorker) Run(ctx context.Context, max int) {
+	w.Pool.Run(ctx, max)
 }
 
 func (w *NetworkScannerWorker) Close() {
-	log.Info("networkscanner workerpool shutdown")
+	logger.Info("networkscanner workerpool shutdown")
 	close(w.In)
 }
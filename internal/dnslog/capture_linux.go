@@ -0,0 +1,96 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package dnslog
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/mdlayher/packet"
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// Capture reads the dns queries sent to port 53 seen on the interface until the context is
+// done.  The interface is put in promiscuous mode so the queries of other devices mirrored to
+// it are seen.
+func Capture(ctx context.Context, ifname string, fn func(model.DNSQuery)) error {
+	ifi, err := net.InterfaceByName(ifname)
+	if err != nil {
+		return err
+	}
+	filter, err := bpf.Assemble(queryFilter)
+	if err != nil {
+		return err
+	}
+	conn, err := packet.Listen(ifi, packet.Raw, unix.ETH_P_ALL, &packet.Config{Filter: filter})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.SetPromiscuous(true)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = conn.SetReadDeadline(time.Now())
+	}()
+
+	buf := make([]byte, ifi.MTU+ethernetHeaderLen)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			var nerr net.Error
+			if errors.As(err, &nerr) && nerr.Timeout() {
+				continue
+			}
+			return err
+		}
+		q, err := ParseFrame(buf[:n], time.Now())
+		if err != nil {
+			continue
+		}
+		fn(q)
+	}
+}
+
+// queryFilter passes the ethernet frames of udp packets to port 53, ipv4 fragments after the
+// first and ipv6 packets with extension headers are dropped
+var queryFilter = []bpf.Instruction{
+	// ethertype
+	bpf.LoadAbsolute{Off: 12, Size: 2},
+	bpf.JumpIf{Cond: bpf.JumpEqual, Val: etherTypeIPv4, SkipFalse: 8},
+	// ipv4 protocol
+	bpf.LoadAbsolute{Off: 23, Size: 1},
+	bpf.JumpIf{Cond: bpf.JumpEqual, Val: protocolUDP, SkipFalse: 12},
+	// fragment offset
+	bpf.LoadAbsolute{Off: 20, Size: 2},
+	bpf.JumpIf{Cond: bpf.JumpBitsSet, Val: 0x1fff, SkipTrue: 10},
+	// udp destination port after the ipv4 header
+	bpf.LoadMemShift{Off: ethernetHeaderLen},
+	bpf.LoadIndirect{Off: ethernetHeaderLen + 2, Size: 2},
+	bpf.JumpIf{Cond: bpf.JumpEqual, Val: dnsPort, SkipFalse: 7},
+	bpf.RetConstant{Val: 0xffff},
+	bpf.JumpIf{Cond: bpf.JumpEqual, Val: etherTypeIPv6, SkipFalse: 5},
+	// ipv6 next header
+	bpf.LoadAbsolute{Off: 20, Size: 1},
+	bpf.JumpIf{Cond: bpf.JumpEqual, Val: protocolUDP, SkipFalse: 3},
+	// udp destination port after the ipv6 header
+	bpf.LoadAbsolute{Off: ethernetHeaderLen + ipv6HeaderLen + 2, Size: 2},
+	bpf.JumpIf{Cond: bpf.JumpEqual, Val: dnsPort, SkipFalse: 1},
+	bpf.RetConstant{Val: 0xffff},
+	bpf.RetConstant{Val: 0},
+}
@@ -0,0 +1,46 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package dnslog
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/bpf"
+)
+
+func TestQueryFilter(t *testing.T) {
+	vm, err := bpf.NewVM(queryFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v4 := netip.MustParseAddr("192.168.1.20")
+	v6 := netip.MustParseAddr("fd00::20")
+	query := dnsMessage(t, "example.com.", dns.TypeA, false)
+	tests := map[string]struct {
+		frame []byte
+		pass  bool
+	}{
+		"IPv4":      {frame: udpFrame(t, v4, 53, query), pass: true},
+		"IPv6":      {frame: udpFrame(t, v6, 53, query), pass: true},
+		"IPv4Other": {frame: udpFrame(t, v4, 123, query)},
+		"IPv6Other": {frame: udpFrame(t, v6, 123, query)},
+		"ARP":       {frame: append(make([]byte, 12), 0x08, 0x06, 0, 1)},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			n, err := vm.Run(tc.frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := n > 0; got != tc.pass {
+				t.Errorf("pass want %v got %v", tc.pass, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package dnslog
+
+import (
+	"context"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// Capture is only supported on linux, use the pihole source elsewhere
+func Capture(ctx context.Context, ifname string, fn func(model.DNSQuery)) error {
+	return ErrCaptureUnsupported
+}
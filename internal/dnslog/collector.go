@@ -0,0 +1,107 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package dnslog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+var (
+	ErrUnknownSource      = errors.New("unknown dns log source")
+	ErrCaptureUnsupported = errors.New("dns capture is only supported on linux")
+)
+
+// Collector reads the queries from the configured source and passes them to write in batches
+// every FlushInterval, queries are dropped while MaxQueued are waiting
+type Collector struct {
+	cfg   *Config
+	write func(context.Context, []model.DNSQuery)
+	in    chan model.DNSQuery
+
+	// whether the source is read and the error which stopped reading it
+	mu      sync.Mutex
+	reading bool
+	readErr error
+}
+
+func NewCollector(cfg *Config, write func(context.Context, []model.DNSQuery)) *Collector {
+	return &Collector{
+		cfg:   cfg,
+		write: write,
+		in:    make(chan model.DNSQuery, max(1, cfg.MaxQueued)),
+	}
+}
+
+// Submit queues the query, it is dropped when the queue is full
+func (c *Collector) Submit(q model.DNSQuery) bool {
+	select {
+	case c.in <- q:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run collects queries until the context is done
+func (c *Collector) Run(ctx context.Context) {
+	c.mu.Lock()
+	c.reading = true
+	c.readErr = nil
+	c.mu.Unlock()
+	go func() {
+		err := c.read(ctx)
+		c.mu.Lock()
+		c.reading = false
+		c.readErr = err
+		c.mu.Unlock()
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Error("dns log collection stopped", "source", c.cfg.Source, "error", err)
+		}
+	}()
+
+	ticker := time.NewTicker(c.cfg.FlushInterval)
+	defer ticker.Stop()
+	pending := make([]model.DNSQuery, 0)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case q := <-c.in:
+			pending = append(pending, q)
+		case <-ticker.C:
+			if len(pending) == 0 {
+				continue
+			}
+			c.write(ctx, pending)
+			pending = make([]model.DNSQuery, 0, len(pending))
+		}
+	}
+}
+
+// Reading reports whether the source is being read, with the error which stopped it otherwise
+func (c *Collector) Reading() (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reading, c.readErr
+}
+
+func (c *Collector) read(ctx context.Context) error {
+	submit := func(q model.DNSQuery) { c.Submit(q) }
+	switch c.cfg.Source {
+	case SourceCapture:
+		return Capture(ctx, c.cfg.Interface, submit)
+	case SourcePihole:
+		return FollowLog(ctx, c.cfg.LogFile, c.cfg.PollInterval, submit)
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownSource, c.cfg.Source)
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package dnslog
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/networkables/mason/internal/flagset"
+)
+
+const (
+	SourceCapture = "capture"
+	SourcePihole  = "pihole"
+)
+
+// Config sets where the dns queries of the devices are collected from: a capture of the
+// queries to port 53 seen on an interface (e.g. a mirror port), or the query log written by
+// Pi-hole or dnsmasq with log-queries enabled
+type Config struct {
+	Enabled       bool
+	Source        string
+	Interface     string
+	LogFile       string
+	PollInterval  time.Duration
+	FlushInterval time.Duration
+	MaxQueued     int
+	Retention     time.Duration
+}
+
+func SetFlags(fs *pflag.FlagSet, cfg *Config) {
+	configMajorKey := "dnslog"
+
+	flagset.Bool(
+		fs,
+		&cfg.Enabled,
+		configMajorKey,
+		"enabled",
+		false,
+		"collect the dns queries of devices",
+	)
+	flagset.String(
+		fs,
+		&cfg.Source,
+		configMajorKey,
+		"source",
+		SourceCapture,
+		"where queries are collected from [capture,pihole]",
+	)
+	flagset.String(
+		fs,
+		&cfg.Interface,
+		configMajorKey,
+		"interface",
+		"",
+		"interface to capture dns queries on, in promiscuous mode (linux only, requires net raw privileges)",
+	)
+	flagset.String(
+		fs,
+		&cfg.LogFile,
+		configMajorKey,
+		"logfile",
+		"/var/log/pihole/pihole.log",
+		"pihole or dnsmasq query log to follow",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.PollInterval,
+		configMajorKey,
+		"pollinterval",
+		time.Second,
+		"interval between reads of new lines of the query log",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.FlushInterval,
+		configMajorKey,
+		"flushinterval",
+		5*time.Second,
+		"longest time collected queries wait before being written",
+	)
+	flagset.Int(
+		fs,
+		&cfg.MaxQueued,
+		configMajorKey,
+		"maxqueued",
+		10_000,
+		"queries waiting to be written before new queries are dropped",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Retention,
+		configMajorKey,
+		"retention",
+		7*24*time.Hour,
+		"how long dns queries are kept",
+	)
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package dnslog
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+var ErrNotQuery = errors.New("not a dns query")
+
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+	protocolUDP   = 17
+	dnsPort       = 53
+
+	ethernetHeaderLen = 14
+	ipv6HeaderLen     = 40
+	udpHeaderLen      = 8
+)
+
+// ParseFrame reads the query of an ethernet frame carrying a udp packet to port 53, the
+// client is the source address of the packet
+func ParseFrame(frame []byte, ts time.Time) (model.DNSQuery, error) {
+	if len(frame) < ethernetHeaderLen {
+		return model.DNSQuery{}, ErrNotQuery
+	}
+	var (
+		src     netip.Addr
+		payload []byte
+	)
+	pkt := frame[ethernetHeaderLen:]
+	switch binary.BigEndian.Uint16(frame[12:14]) {
+	case etherTypeIPv4:
+		if len(pkt) < 20 || pkt[9] != protocolUDP {
+			return model.DNSQuery{}, ErrNotQuery
+		}
+		ihl := int(pkt[0]&0x0f) * 4
+		if len(pkt) < ihl {
+			return model.DNSQuery{}, ErrNotQuery
+		}
+		src = netip.AddrFrom4([4]byte(pkt[12:16]))
+		payload = pkt[ihl:]
+	case etherTypeIPv6:
+		if len(pkt) < ipv6HeaderLen || pkt[6] != protocolUDP {
+			return model.DNSQuery{}, ErrNotQuery
+		}
+		src = netip.AddrFrom16([16]byte(pkt[8:24]))
+		payload = pkt[ipv6HeaderLen:]
+	default:
+		return model.DNSQuery{}, ErrNotQuery
+	}
+	if len(payload) < udpHeaderLen || binary.BigEndian.Uint16(payload[2:4]) != dnsPort {
+		return model.DNSQuery{}, ErrNotQuery
+	}
+
+	var msg dns.Msg
+	err := msg.Unpack(payload[udpHeaderLen:])
+	if err != nil || msg.Response || msg.Opcode != dns.OpcodeQuery || len(msg.Question) == 0 {
+		return model.DNSQuery{}, ErrNotQuery
+	}
+	q := msg.Question[0]
+	return model.DNSQuery{
+		Time:   ts,
+		Addr:   model.AddrToModelAddr(src),
+		Domain: model.NormalizeDomain(q.Name),
+		Type:   dns.TypeToString[q.Qtype],
+	}, nil
+}
+
+// dnsmasq writes syslog style timestamps without a year, newer Pi-hole versions a full date
+var logTimeLayouts = []string{time.Stamp, "2006-01-02 15:04:05.000", time.DateTime}
+
+// ParseDnsmasqLine reads the query of a line of the Pi-hole or dnsmasq query log such as
+// "Jun  3 10:00:00 dnsmasq[412]: query[A] example.com from 192.168.1.20", the other lines
+// (forwarded, reply, cached ...) are not queries
+func ParseDnsmasqLine(line string, now time.Time) (model.DNSQuery, error) {
+	stamp, entry, ok := strings.Cut(line, " dnsmasq[")
+	if !ok {
+		return model.DNSQuery{}, ErrNotQuery
+	}
+	_, entry, ok = strings.Cut(entry, "]: query[")
+	if !ok {
+		return model.DNSQuery{}, ErrNotQuery
+	}
+	qtype, entry, ok := strings.Cut(entry, "] ")
+	if !ok {
+		return model.DNSQuery{}, ErrNotQuery
+	}
+	fields := strings.Fields(entry)
+	if len(fields) != 3 || fields[1] != "from" {
+		return model.DNSQuery{}, ErrNotQuery
+	}
+	addr, err := model.ParseAddr(fields[2])
+	if err != nil {
+		return model.DNSQuery{}, ErrNotQuery
+	}
+	return model.DNSQuery{
+		Time:   parseLogTime(strings.TrimSpace(stamp), now),
+		Addr:   addr,
+		Domain: model.NormalizeDomain(fields[0]),
+		Type:   qtype,
+	}, nil
+}
+
+// parseLogTime falls back to now for a timestamp in an unknown layout, a time without a year
+// is in the last year
+func parseLogTime(stamp string, now time.Time) time.Time {
+	for _, layout := range logTimeLayouts {
+		t, err := time.ParseInLocation(layout, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		if t.Year() == 0 {
+			t = t.AddDate(now.Year(), 0, 0)
+			if t.After(now.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+		}
+		return t
+	}
+	return now
+}
@@ -0,0 +1,182 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package dnslog
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/miekg/dns"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// udpFrame wraps the payload in an ethernet frame of a udp packet from src to the port
+func udpFrame(t *testing.T, src netip.Addr, port uint16, payload []byte) []byte {
+	t.Helper()
+	udp := make([]byte, udpHeaderLen, udpHeaderLen+len(payload))
+	binary.BigEndian.PutUint16(udp[0:2], 40000)
+	binary.BigEndian.PutUint16(udp[2:4], port)
+	binary.BigEndian.PutUint16(udp[4:6], uint16(udpHeaderLen+len(payload)))
+	udp = append(udp, payload...)
+
+	frame := make([]byte, ethernetHeaderLen)
+	if src.Is4() {
+		binary.BigEndian.PutUint16(frame[12:14], etherTypeIPv4)
+		ip := make([]byte, 20)
+		ip[0] = 0x45
+		binary.BigEndian.PutUint16(ip[2:4], uint16(20+len(udp)))
+		ip[8] = 64
+		ip[9] = protocolUDP
+		copy(ip[12:16], src.AsSlice())
+		copy(ip[16:20], []byte{192, 168, 1, 1})
+		frame = append(frame, ip...)
+	} else {
+		binary.BigEndian.PutUint16(frame[12:14], etherTypeIPv6)
+		ip := make([]byte, ipv6HeaderLen)
+		ip[0] = 0x60
+		binary.BigEndian.PutUint16(ip[4:6], uint16(len(udp)))
+		ip[6] = protocolUDP
+		ip[7] = 64
+		copy(ip[8:24], src.AsSlice())
+		frame = append(frame, ip...)
+	}
+	return append(frame, udp...)
+}
+
+func dnsMessage(t *testing.T, name string, qtype uint16, response bool) []byte {
+	t.Helper()
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, qtype)
+	msg.Response = response
+	b, err := msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParseFrame(t *testing.T) {
+	ts := time.Date(2024, 6, 3, 10, 0, 0, 0, time.UTC)
+	v4 := netip.MustParseAddr("192.168.1.20")
+	v6 := netip.MustParseAddr("fd00::20")
+	tests := map[string]struct {
+		frame []byte
+		want  model.DNSQuery
+		err   error
+	}{
+		"IPv4": {
+			frame: udpFrame(t, v4, 53, dnsMessage(t, "Example.COM.", dns.TypeA, false)),
+			want: model.DNSQuery{
+				Time:   ts,
+				Addr:   model.AddrToModelAddr(v4),
+				Domain: "example.com",
+				Type:   "A",
+			},
+		},
+		"IPv6": {
+			frame: udpFrame(t, v6, 53, dnsMessage(t, "example.com.", dns.TypeAAAA, false)),
+			want: model.DNSQuery{
+				Time:   ts,
+				Addr:   model.AddrToModelAddr(v6),
+				Domain: "example.com",
+				Type:   "AAAA",
+			},
+		},
+		"Response": {
+			frame: udpFrame(t, v4, 53, dnsMessage(t, "example.com.", dns.TypeA, true)),
+			err:   ErrNotQuery,
+		},
+		"OtherPort": {
+			frame: udpFrame(t, v4, 5353, dnsMessage(t, "example.com.", dns.TypeA, false)),
+			err:   ErrNotQuery,
+		},
+		"NotDNS": {
+			frame: udpFrame(t, v4, 53, []byte{1, 2, 3}),
+			err:   ErrNotQuery,
+		},
+		"Short": {
+			frame: []byte{0, 1, 2},
+			err:   ErrNotQuery,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseFrame(tc.frame, ts)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("error want %v got %v", tc.err, err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateComparable(netip.Addr{})); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseDnsmasqLine(t *testing.T) {
+	now := time.Date(2024, 6, 3, 12, 0, 0, 0, time.Local)
+	addr := model.MustParseAddr("192.168.1.20")
+	tests := map[string]struct {
+		line string
+		want model.DNSQuery
+		err  error
+	}{
+		"Syslog": {
+			line: "Jun  3 10:00:00 dnsmasq[412]: query[A] Example.com from 192.168.1.20",
+			want: model.DNSQuery{
+				Time:   time.Date(2024, 6, 3, 10, 0, 0, 0, time.Local),
+				Addr:   addr,
+				Domain: "example.com",
+				Type:   "A",
+			},
+		},
+		"LastYear": {
+			line: "Dec 31 23:59:00 dnsmasq[412]: query[AAAA] example.com from 192.168.1.20",
+			want: model.DNSQuery{
+				Time:   time.Date(2023, 12, 31, 23, 59, 0, 0, time.Local),
+				Addr:   addr,
+				Domain: "example.com",
+				Type:   "AAAA",
+			},
+		},
+		"FullDate": {
+			line: "2024-06-03 10:00:00.250 dnsmasq[412]: query[HTTPS] example.com from 192.168.1.20",
+			want: model.DNSQuery{
+				Time:   time.Date(2024, 6, 3, 10, 0, 0, 250_000_000, time.Local),
+				Addr:   addr,
+				Domain: "example.com",
+				Type:   "HTTPS",
+			},
+		},
+		"Forwarded": {
+			line: "Jun  3 10:00:00 dnsmasq[412]: forwarded example.com to 1.1.1.1",
+			err:  ErrNotQuery,
+		},
+		"Reply": {
+			line: "Jun  3 10:00:00 dnsmasq[412]: reply example.com is 93.184.215.14",
+			err:  ErrNotQuery,
+		},
+		"BadAddr": {
+			line: "Jun  3 10:00:00 dnsmasq[412]: query[A] example.com from nowhere",
+			err:  ErrNotQuery,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseDnsmasqLine(tc.line, now)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("error want %v got %v", tc.err, err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateComparable(netip.Addr{})); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package dnslog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// FollowLog reads the queries appended to the Pi-hole or dnsmasq log until the context is
+// done.  Reading starts at the end of the log, a truncated or rotated log is read again from
+// its start.
+func FollowLog(
+	ctx context.Context,
+	path string,
+	interval time.Duration,
+	fn func(model.DNSQuery),
+) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	rd := bufio.NewReader(f)
+	partial := ""
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for {
+			line, err := rd.ReadString('\n')
+			offset += int64(len(line))
+			if errors.Is(err, io.EOF) {
+				partial += line
+				break
+			}
+			if err != nil {
+				return err
+			}
+			q, err := ParseDnsmasqLine(partial+line, time.Now())
+			partial = ""
+			if err == nil {
+				fn(q)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if !logReplaced(f, path, offset) {
+			continue
+		}
+		nf, err := os.Open(path)
+		if err != nil {
+			// the new log is not created yet
+			continue
+		}
+		f.Close()
+		f, offset, partial = nf, 0, ""
+		rd.Reset(f)
+	}
+}
+
+// logReplaced reports if the log at path is no longer the open file or was truncated
+func logReplaced(f *os.File, path string, offset int64) bool {
+	open, err := f.Stat()
+	if err != nil {
+		return true
+	}
+	current, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return !os.SameFile(open, current) || current.Size() < offset
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package enrichment
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/emicklei/tre"
+	"gopkg.in/yaml.v3"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// classifyRulesFile is the layout of the local rules file
+type classifyRulesFile struct {
+	Rules []model.ClassifyRule `yaml:"rules"`
+}
+
+// classifiers caches the classifier of the rules file, it is built again once the file changes
+var classifiers struct {
+	sync.Mutex
+	path       string
+	modified   time.Time
+	classifier *model.Classifier
+}
+
+// LoadClassifyRules reads the custom classification rules from the yaml file
+func LoadClassifyRules(path string) ([]model.ClassifyRule, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f classifyRulesFile
+	err = yaml.Unmarshal(b, &f)
+	if err != nil {
+		return nil, err
+	}
+	return f.Rules, nil
+}
+
+// classifierFor returns the classifier with the custom rules of the file, an empty path only
+// uses the built in rules
+func classifierFor(path string) (*model.Classifier, error) {
+	classifiers.Lock()
+	defer classifiers.Unlock()
+
+	var modified time.Time
+	if path != "" {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, tre.New(err, "classify rules", "file", path)
+		}
+		modified = fi.ModTime()
+	}
+	if classifiers.classifier != nil && classifiers.path == path &&
+		classifiers.modified.Equal(modified) {
+		return classifiers.classifier, nil
+	}
+
+	var rules []model.ClassifyRule
+	if path != "" {
+		var err error
+		rules, err = LoadClassifyRules(path)
+		if err != nil {
+			return nil, tre.New(err, "classify rules", "file", path)
+		}
+	}
+	c, err := model.NewClassifier(rules)
+	if err != nil {
+		return nil, tre.New(err, "classify rules", "file", path)
+	}
+	classifiers.path, classifiers.modified, classifiers.classifier = path, modified, c
+	return c, nil
+}
+
+// classifyDevice sets the device type from the first matching rule, a device no rule matches
+// keeps its type
+func classifyDevice(d *model.Device, cfg *ClassifyConfig) error {
+	c, err := classifierFor(cfg.RulesFile)
+	if err != nil {
+		return err
+	}
+	t := c.Classify(*d)
+	if t != model.DeviceTypeUnknown && t != d.Meta.DeviceType {
+		d.Meta.DeviceType = t
+		d.SetUpdated()
+	}
+	return nil
+}
@@ -16,16 +16,30 @@ type (
 	Config struct {
 		Enabled    bool
 		MaxWorkers int
+		Classify   *ClassifyConfig
 		Dns        *DnsConfig
+		MDNS       *MDNSConfig
 		Oui        *OuiConfig
 		PortScan   *PortScanConfig
 		Snmp       *SnmpConfig
+		Virtual    *VirtualConfig
+		VulnHints  *VulnHintsConfig
+	}
+
+	ClassifyConfig struct {
+		Enabled   bool
+		RulesFile string
 	}
 
 	DnsConfig struct {
 		Enabled bool
 	}
 
+	MDNSConfig struct {
+		Enabled bool
+		Timeout time.Duration
+	}
+
 	OuiConfig struct {
 		Enabled bool
 	}
@@ -37,6 +51,7 @@ type (
 		DefaultScanInterval time.Duration
 		ServerScanInterval  time.Duration
 		PortList            string
+		Mode                string
 	}
 
 	SnmpConfig struct {
@@ -45,13 +60,43 @@ type (
 		Community []string
 		Ports     []int
 	}
+
+	VirtualConfig struct {
+		Enabled        bool
+		Timeout        time.Duration
+		RescanInterval time.Duration
+		Docker         *DockerConfig
+		Proxmox        *ProxmoxConfig
+	}
+
+	VulnHintsConfig struct {
+		Enabled   bool
+		Timeout   time.Duration
+		RulesFile string
+	}
+
+	DockerConfig struct {
+		Port int
+	}
+
+	ProxmoxConfig struct {
+		Port  int
+		Token string
+	}
 )
 
 func SetFlags(fs *pflag.FlagSet, cfg *Config) {
+	cfg.Classify = &ClassifyConfig{}
 	cfg.Dns = &DnsConfig{}
+	cfg.MDNS = &MDNSConfig{}
 	cfg.Oui = &OuiConfig{}
 	cfg.PortScan = &PortScanConfig{}
 	cfg.Snmp = &SnmpConfig{}
+	cfg.Virtual = &VirtualConfig{
+		Docker:  &DockerConfig{},
+		Proxmox: &ProxmoxConfig{},
+	}
+	cfg.VulnHints = &VulnHintsConfig{}
 
 	configMajorKey := "enrichment"
 
@@ -72,6 +117,24 @@ func SetFlags(fs *pflag.FlagSet, cfg *Config) {
 		"max number of devices to simultaneously enrich",
 	)
 
+	classifyConfigMajorKey := flagset.Key(configMajorKey, "classify")
+	flagset.Bool(
+		fs,
+		&cfg.Classify.Enabled,
+		classifyConfigMajorKey,
+		"enabled",
+		true,
+		"classify the device type from its dhcp options, manufacturer, open ports and names",
+	)
+	flagset.String(
+		fs,
+		&cfg.Classify.RulesFile,
+		classifyConfigMajorKey,
+		"rulesfile",
+		"",
+		"yaml file of custom classification rules, tried before the built in rules",
+	)
+
 	dnsConfigMajorKey := flagset.Key(configMajorKey, "dns")
 	flagset.Bool(
 		fs,
@@ -82,6 +145,24 @@ func SetFlags(fs *pflag.FlagSet, cfg *Config) {
 		"use reverse ip dns lookup",
 	)
 
+	mdnsConfigMajorKey := flagset.Key(configMajorKey, "mdns")
+	flagset.Bool(
+		fs,
+		&cfg.MDNS.Enabled,
+		mdnsConfigMajorKey,
+		"enabled",
+		true,
+		"ask the device for its .local hostname with a unicast mdns query",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.MDNS.Timeout,
+		mdnsConfigMajorKey,
+		"timeout",
+		time.Second,
+		"max time to wait for the mdns answer",
+	)
+
 	ouiConfigMajorKey := flagset.Key(configMajorKey, "oui")
 	flagset.Bool(
 		fs,
@@ -139,7 +220,15 @@ func SetFlags(fs *pflag.FlagSet, cfg *Config) {
 		psConfigMajorKey,
 		"portlist",
 		"general",
-		"portlist set to use for scanning [all,general,privileged,common]",
+		"portlist set to use for scanning [all,general,priviledged,common], a port profile name or port ranges such as 22,8000-8100",
+	)
+	flagset.String(
+		fs,
+		&cfg.PortScan.Mode,
+		psConfigMajorKey,
+		"mode",
+		"connect",
+		"port scan mode [connect,syn], syn requires raw socket access and falls back to connect",
 	)
 
 	snmpConfigMajorKey := flagset.Key(configMajorKey, "snmp")
@@ -175,4 +264,84 @@ func SetFlags(fs *pflag.FlagSet, cfg *Config) {
 		[]int{161},
 		"list of ports to test for snmp port",
 	)
+
+	virtualConfigMajorKey := flagset.Key(configMajorKey, "virtual")
+	flagset.Bool(
+		fs,
+		&cfg.Virtual.Enabled,
+		virtualConfigMajorKey,
+		"enabled",
+		true,
+		"detect docker, proxmox and esxi hosts and list their containers and virtual machines",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Virtual.Timeout,
+		virtualConfigMajorKey,
+		"timeout",
+		2*time.Second,
+		"max time to wait for a host to list its guests",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Virtual.RescanInterval,
+		virtualConfigMajorKey,
+		"rescaninterval",
+		time.Hour,
+		"duration between guest listings of known hosts",
+	)
+
+	dockerConfigMajorKey := flagset.Key(virtualConfigMajorKey, "docker")
+	flagset.Int(
+		fs,
+		&cfg.Virtual.Docker.Port,
+		dockerConfigMajorKey,
+		"port",
+		2375,
+		"port of the docker engine api, only the plain tcp api is supported",
+	)
+
+	proxmoxConfigMajorKey := flagset.Key(virtualConfigMajorKey, "proxmox")
+	flagset.Int(
+		fs,
+		&cfg.Virtual.Proxmox.Port,
+		proxmoxConfigMajorKey,
+		"port",
+		8006,
+		"port of the proxmox api",
+	)
+	flagset.String(
+		fs,
+		&cfg.Virtual.Proxmox.Token,
+		proxmoxConfigMajorKey,
+		"token",
+		"",
+		"proxmox api token as USER@REALM!TOKENID=SECRET, needed to list the guests",
+	)
+
+	vulnConfigMajorKey := flagset.Key(configMajorKey, "vulnhints")
+	flagset.Bool(
+		fs,
+		&cfg.VulnHints.Enabled,
+		vulnConfigMajorKey,
+		"enabled",
+		false,
+		"read the banners of open ports and flag services of likely vulnerable versions",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.VulnHints.Timeout,
+		vulnConfigMajorKey,
+		"timeout",
+		2*time.Second,
+		"max time to wait for the banner of a port",
+	)
+	flagset.String(
+		fs,
+		&cfg.VulnHints.RulesFile,
+		vulnConfigMajorKey,
+		"rulesfile",
+		"",
+		"yaml file of custom vulnerability rules, matched before the bundled rules",
+	)
 }
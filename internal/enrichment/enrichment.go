@@ -10,23 +10,31 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/charmbracelet/log"
 	"github.com/emicklei/tre"
 
 	"github.com/networkables/mason/internal/model"
 	"github.com/networkables/mason/internal/oui"
+	"github.com/networkables/mason/internal/ratelimit"
 	"github.com/networkables/mason/nettools"
 )
 
-func PortScannerFilter(cfg *PortScanConfig) model.DeviceFilter {
+// PortScannerFilter selects the devices due for a port scan, the policy lookup may be nil
+// when only the global intervals apply
+func PortScannerFilter(cfg *PortScanConfig, policy model.PolicyLookup) model.DeviceFilter {
 	return func(d model.Device) bool {
+		var override time.Duration
+		if policy != nil {
+			override = policy(d).PortScanInterval
+		}
 		since := time.Since(d.Server.LastScan)
 		if d.IsServer() {
-			if since > cfg.ServerScanInterval {
+			if since > model.IntervalOrDefault(override, cfg.ServerScanInterval) {
 				return true
 			}
 			return false
 		}
-		if since > cfg.DefaultScanInterval {
+		if since > model.IntervalOrDefault(override, cfg.DefaultScanInterval) {
 			return true
 		}
 		return false
@@ -35,11 +43,15 @@ func PortScannerFilter(cfg *PortScanConfig) model.DeviceFilter {
 
 // TODO: This should probably go away and just use the EnrichmentConfig
 type EnrichmentFields struct {
-	PerformDNSLookup bool
-	PerformOUILookup bool
-	PerformPortScan  bool
-	PerformSNMPScan  bool
-	Cfg              *Config
+	PerformClassify    bool
+	PerformDNSLookup   bool
+	PerformMDNSLookup  bool
+	PerformOUILookup   bool
+	PerformPortScan    bool
+	PerformSNMPScan    bool
+	PerformVirtualScan bool
+	PerformProviders   bool
+	Cfg                *Config
 }
 
 func (e EnrichmentFields) String() string {
@@ -47,25 +59,41 @@ func (e EnrichmentFields) String() string {
 	if e.PerformDNSLookup {
 		str += "DNS "
 	}
+	if e.PerformMDNSLookup {
+		str += "MDNS "
+	}
 	if e.PerformOUILookup {
 		str += "OUI "
 	}
 	if e.PerformSNMPScan {
 		str += "SNMP "
 	}
+	if e.PerformVirtualScan {
+		str += "Virtual "
+	}
 	if e.PerformPortScan {
 		str += "PortScan:" + e.Cfg.PortScan.PortList + " "
 	}
+	if e.PerformProviders && hasProviders() {
+		str += "Providers "
+	}
+	if e.PerformClassify {
+		str += "Classify "
+	}
 	return str
 }
 
 func DefaultEnrichmentFields(cfg *Config) EnrichmentFields {
 	return EnrichmentFields{
-		PerformDNSLookup: cfg.Dns.Enabled,
-		PerformOUILookup: cfg.Oui.Enabled,
-		PerformPortScan:  cfg.PortScan.Enabled,
-		PerformSNMPScan:  cfg.Snmp.Enabled,
-		Cfg:              cfg,
+		PerformClassify:    cfg.Classify.Enabled,
+		PerformDNSLookup:   cfg.Dns.Enabled,
+		PerformMDNSLookup:  cfg.MDNS.Enabled,
+		PerformOUILookup:   cfg.Oui.Enabled,
+		PerformPortScan:    cfg.PortScan.Enabled,
+		PerformSNMPScan:    cfg.Snmp.Enabled,
+		PerformVirtualScan: cfg.Virtual.Enabled,
+		PerformProviders:   true,
+		Cfg:                cfg,
 	}
 }
 
@@ -74,6 +102,8 @@ type EnrichAllDevicesEvent EnrichmentFields
 type EnrichDeviceRequest struct {
 	Fields EnrichmentFields
 	Device model.Device
+	// Ports are scanned instead of the configured port list when set
+	Ports []int
 }
 
 func (e EnrichDeviceRequest) String() string {
@@ -89,6 +119,24 @@ func (e EnrichAllDevicesEvent) String() string {
 }
 
 func EnrichDevice(ctx context.Context, d EnrichDeviceRequest) (model.Device, error) {
+	return enrichDevice(ctx, d, nil)
+}
+
+// BuildEnrichDeviceFunc returns an enrichment func which paces its port scan probes with
+// the rate limits of the device's network
+func BuildEnrichDeviceFunc(
+	limits *ratelimit.Group,
+) func(context.Context, EnrichDeviceRequest) (model.Device, error) {
+	return func(ctx context.Context, d EnrichDeviceRequest) (model.Device, error) {
+		return enrichDevice(ctx, d, limits.Addr(d.Device.Addr.Addr()))
+	}
+}
+
+func enrichDevice(
+	ctx context.Context,
+	d EnrichDeviceRequest,
+	limiter nettools.RateLimiter,
+) (model.Device, error) {
 	if d.Fields.PerformDNSLookup && d.Device.Meta.DnsName == "" {
 		name, err := nettools.FindHostnameOf(d.Device.Addr.Addr())
 		if err != nil && !errors.Is(err, nettools.ErrNoDnsNames) {
@@ -99,6 +147,14 @@ func EnrichDevice(ctx context.Context, d EnrichDeviceRequest) (model.Device, err
 			d.Device.SetUpdated()
 		}
 	}
+	if d.Fields.PerformMDNSLookup && d.Device.Meta.MDNSName == "" {
+		// most devices do not run an mdns responder, an unanswered query is not an error
+		name, err := nettools.FindMDNSNameOf(ctx, d.Device.Addr.Addr(), d.Fields.Cfg.MDNS.Timeout)
+		if err == nil && name != "" {
+			d.Device.Meta.MDNSName = name
+			d.Device.SetUpdated()
+		}
+	}
 	if d.Fields.PerformOUILookup && d.Device.Meta.Manufacturer == "" {
 		if nettools.IsRandomMac(d.Device.MAC.Addr()) {
 			d.Device.Meta.Tags = model.Add(model.RandomizedMacAddressTag, d.Device.Meta.Tags)
@@ -114,15 +170,26 @@ func EnrichDevice(ctx context.Context, d EnrichDeviceRequest) (model.Device, err
 		}
 	}
 	if d.Fields.PerformPortScan {
+		ports := nettools.WithPortscanPorts(d.Ports)
+		if len(d.Ports) == 0 {
+			ports = nettools.WithPortscanPortlistName(d.Fields.Cfg.PortScan.PortList)
+		}
 		openports, err := nettools.ScanTcpPorts(ctx, d.Device.Addr.Addr(),
 			nettools.WithPortscanReplyTimeout(d.Fields.Cfg.PortScan.Timeout),
-			nettools.WithPortscanPortlistName(d.Fields.Cfg.PortScan.PortList),
+			ports,
 			nettools.WithPortscanMaxworkers(d.Fields.Cfg.PortScan.MaxWorkers),
+			nettools.WithPortscanModeName(d.Fields.Cfg.PortScan.Mode),
+			nettools.WithPortscanRateLimiter(limiter),
 		)
 		if err != nil {
 			return d.Device, tre.New(err, "port scan", "addr", d.Device.Addr)
 		}
 		d.Device.Server.Ports = model.IntSliceToPortList(openports)
+		d.Device.Server.Banners = nil
+		if d.Fields.Cfg.VulnHints.Enabled {
+			d.Device.Server.Banners = grabBanners(ctx, d.Device.Addr.Addr(), openports,
+				d.Fields.Cfg.VulnHints.Timeout)
+		}
 		d.Device.Server.LastScan = time.Now()
 		d.Device.SetUpdated()
 	}
@@ -173,5 +240,23 @@ func EnrichDevice(ctx context.Context, d EnrichDeviceRequest) (model.Device, err
 			d.Device.SetUpdated()
 		}
 	}
+	if d.Fields.PerformVirtualScan {
+		// esxi is recognized by the snmp description, so the snmp scan comes first
+		scanVirtualHost(ctx, &d.Device, d.Fields.Cfg)
+	}
+	if d.Fields.PerformProviders {
+		// a failing provider is logged, the enrichments of mason itself are still kept
+		err := enrichFromProviders(ctx, &d.Device)
+		if err != nil {
+			log.Warn("enrichment providers", "error", err)
+		}
+	}
+	if d.Fields.PerformClassify {
+		// the classification combines what the other enrichments found, so it comes last
+		err := classifyDevice(&d.Device, d.Fields.Cfg.Classify)
+		if err != nil {
+			return d.Device, err
+		}
+	}
 	return d.Device, nil
 }
@@ -0,0 +1,117 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/pkg/provider"
+)
+
+type namedEnricher struct {
+	name string
+	provider.Enricher
+}
+
+// providerEnrichers are the enrichers of the enabled providers, run by each enrichment
+var providerEnrichers struct {
+	sync.RWMutex
+	list []namedEnricher
+}
+
+// UseProviders builds the enrichers of the named providers with their settings, the ones
+// which fail to build are left out and their errors returned
+func UseProviders(names []string, settings map[string]provider.Settings) error {
+	list := make([]namedEnricher, 0, len(names))
+	var errs []error
+	for _, name := range names {
+		e, err := provider.NewEnricher(name, settings[name])
+		if err != nil {
+			errs = append(errs, tre.New(err, "enrichment provider", "name", name))
+			continue
+		}
+		list = append(list, namedEnricher{name: name, Enricher: e})
+	}
+	providerEnrichers.Lock()
+	providerEnrichers.list = list
+	providerEnrichers.Unlock()
+	return errors.Join(errs...)
+}
+
+func hasProviders() bool {
+	providerEnrichers.RLock()
+	defer providerEnrichers.RUnlock()
+	return len(providerEnrichers.list) > 0
+}
+
+// enrichFromProviders runs the provider enrichers on the device in the order they were
+// enabled, a failing provider does not stop the others
+func enrichFromProviders(ctx context.Context, d *model.Device) error {
+	providerEnrichers.RLock()
+	list := providerEnrichers.list
+	providerEnrichers.RUnlock()
+
+	var errs []error
+	for _, e := range list {
+		pd := ToProviderDevice(*d)
+		changed, err := e.Enrich(ctx, &pd)
+		if err != nil {
+			errs = append(errs, tre.New(err, "enrichment provider", "name", e.name, "addr", d.Addr))
+			continue
+		}
+		if changed {
+			MergeProviderDevice(d, pd)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ToProviderDevice is the device as seen by the providers
+func ToProviderDevice(d model.Device) provider.Device {
+	tags := make([]string, 0, len(d.Meta.Tags))
+	for _, t := range d.Meta.Tags {
+		tags = append(tags, t.Val)
+	}
+	return provider.Device{
+		Addr:         d.Addr.Addr(),
+		MAC:          d.MAC.Addr(),
+		Name:         d.Name,
+		Manufacturer: d.Meta.Manufacturer,
+		Type:         string(d.Meta.DeviceType),
+		Tags:         tags,
+	}
+}
+
+// MergeProviderDevice copies what the provider set into the device.  The name only replaces
+// one which is still the address so names given by users are kept, tags are added.
+func MergeProviderDevice(d *model.Device, pd provider.Device) {
+	if pd.Name != "" && pd.Name != d.Name && (d.Name == "" || d.IsNameAddr()) {
+		d.Name = pd.Name
+		d.SetUpdated()
+	}
+	if len(pd.MAC) > 0 && d.MAC.IsEmpty() {
+		d.MAC = model.HardwareAddrToMAC(pd.MAC)
+		d.SetUpdated()
+	}
+	if pd.Manufacturer != "" && pd.Manufacturer != d.Meta.Manufacturer {
+		d.Meta.Manufacturer = pd.Manufacturer
+		d.SetUpdated()
+	}
+	if pd.Type != "" && model.DeviceType(pd.Type) != d.Meta.DeviceType {
+		d.Meta.DeviceType = model.DeviceType(pd.Type)
+		d.SetUpdated()
+	}
+	for _, tag := range pd.Tags {
+		if tag != "" && !d.Meta.Tags.Has(tag) {
+			d.Meta.Tags = model.Add(model.Tag{Val: tag}, d.Meta.Tags)
+			d.SetUpdated()
+		}
+	}
+}
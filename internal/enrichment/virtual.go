@@ -0,0 +1,94 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+// VirtualHostRescanFilter selects the hosts due for a new listing of their guests
+func VirtualHostRescanFilter(cfg *VirtualConfig) model.DeviceFilter {
+	return func(d model.Device) bool {
+		return d.Virtual.IsHost() && time.Since(d.Virtual.LastScan) > cfg.RescanInterval
+	}
+}
+
+// scanVirtualHost asks the device for its guests on each platform in turn, the first platform
+// to answer makes the device a host.  Most devices answer none, which is not an error, a
+// device which stopped answering is no longer a host.
+func scanVirtualHost(ctx context.Context, d *model.Device, cfg *Config) {
+	addr := d.Addr.Addr()
+	vcfg := cfg.Virtual
+
+	guests, err := nettools.DockerGuests(ctx, addr, vcfg.Docker.Port, vcfg.Timeout)
+	if err == nil {
+		setVirtualHost(d, model.VirtualPlatformDocker, guests)
+		return
+	}
+	guests, err = nettools.ProxmoxGuests(
+		ctx,
+		addr,
+		vcfg.Proxmox.Port,
+		vcfg.Proxmox.Token,
+		vcfg.Timeout,
+	)
+	// a host refusing to list its guests without a token is still known as a host
+	if err == nil || errors.Is(err, nettools.ErrVirtualCredentialsRequired) {
+		setVirtualHost(d, model.VirtualPlatformProxmox, guests)
+		return
+	}
+	if isESXi(*d) {
+		guests, err = nettools.SnmpGetVMwareGuests(ctx, addr,
+			nettools.WithSnmpCommunity(d.SNMP.Community),
+			nettools.WithSnmpPort(d.SNMP.Port),
+			nettools.WithSnmpReplyTimeout(cfg.Snmp.Timeout),
+		)
+		if err == nil {
+			setVirtualHost(d, model.VirtualPlatformESXi, guests)
+			return
+		}
+	}
+	if d.Virtual.IsHost() {
+		setVirtualHost(d, "", nil)
+	}
+}
+
+func isESXi(d model.Device) bool {
+	return d.SNMP.Community != "" &&
+		(strings.Contains(d.SNMP.Description, "ESXi") ||
+			strings.Contains(d.SNMP.Description, "VMware"))
+}
+
+func setVirtualHost(
+	d *model.Device,
+	platform model.VirtualPlatform,
+	guests []nettools.VirtualGuest,
+) {
+	d.Virtual.Platform = platform
+	d.Virtual.Guests = nil
+	for _, g := range guests {
+		guest := model.Guest{
+			ID:    g.ID,
+			Name:  g.Name,
+			Kind:  model.GuestKind(g.Kind),
+			State: g.State,
+		}
+		if g.MAC != nil {
+			guest.MAC = g.MAC.String()
+		}
+		if g.Addr.IsValid() {
+			guest.Addr = g.Addr.String()
+		}
+		d.Virtual.Guests = append(d.Virtual.Guests, guest)
+	}
+	d.Virtual.LastScan = time.Now()
+	d.SetUpdated()
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package enrichment
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/emicklei/tre"
+	"gopkg.in/yaml.v3"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+// vulnRulesFile is the layout of the local vulnerability rules file
+type vulnRulesFile struct {
+	Rules []model.VulnRule `yaml:"rules"`
+}
+
+// vulnMatchers caches the matcher of the rules file, it is built again once the file changes
+var vulnMatchers struct {
+	sync.Mutex
+	path     string
+	modified time.Time
+	matcher  *model.VulnMatcher
+}
+
+// LoadVulnRules reads the custom vulnerability rules from the yaml file
+func LoadVulnRules(path string) ([]model.VulnRule, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f vulnRulesFile
+	err = yaml.Unmarshal(b, &f)
+	if err != nil {
+		return nil, err
+	}
+	return f.Rules, nil
+}
+
+// vulnMatcherFor returns the matcher with the custom rules of the file, an empty path only
+// uses the built in rules
+func vulnMatcherFor(path string) (*model.VulnMatcher, error) {
+	vulnMatchers.Lock()
+	defer vulnMatchers.Unlock()
+
+	var modified time.Time
+	if path != "" {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, tre.New(err, "vulnerability rules", "file", path)
+		}
+		modified = fi.ModTime()
+	}
+	if vulnMatchers.matcher != nil && vulnMatchers.path == path &&
+		vulnMatchers.modified.Equal(modified) {
+		return vulnMatchers.matcher, nil
+	}
+
+	var rules []model.VulnRule
+	if path != "" {
+		var err error
+		rules, err = LoadVulnRules(path)
+		if err != nil {
+			return nil, tre.New(err, "vulnerability rules", "file", path)
+		}
+	}
+	vm, err := model.NewVulnMatcher(rules)
+	if err != nil {
+		return nil, tre.New(err, "vulnerability rules", "file", path)
+	}
+	vulnMatchers.path, vulnMatchers.modified, vulnMatchers.matcher = path, modified, vm
+	return vm, nil
+}
+
+// VulnHints matches the banners of the device against the bundled and custom rules
+func VulnHints(d model.Device, cfg *VulnHintsConfig) ([]model.VulnHint, error) {
+	vm, err := vulnMatcherFor(cfg.RulesFile)
+	if err != nil {
+		return nil, err
+	}
+	return vm.Match(d), nil
+}
+
+// grabBanners reads the banner of each open port, a port which does not answer has none
+func grabBanners(
+	ctx context.Context,
+	addr netip.Addr,
+	ports []int,
+	timeout time.Duration,
+) model.Banners {
+	var banners model.Banners
+	ports = slices.Clone(ports)
+	slices.Sort(ports)
+	for _, port := range ports {
+		if ctx.Err() != nil {
+			break
+		}
+		text, err := nettools.GrabBanner(ctx, netip.AddrPortFrom(addr, uint16(port)), timeout)
+		if err != nil {
+			continue
+		}
+		banners = append(banners, model.Banner{Port: port, Text: text})
+	}
+	return banners
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/charmbracelet/log"
 
 	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/ratelimit"
 	"github.com/networkables/mason/internal/workerpool"
 )
 
@@ -18,12 +19,14 @@ type Worker struct {
 	*workerpool.Pool[EnrichDeviceRequest, model.Device]
 }
 
-func NewWorker() *Worker {
+func NewWorker(limits *ratelimit.Group) *Worker {
 	input := make(chan EnrichDeviceRequest)
-	return &Worker{
+	w := &Worker{
 		In:   input,
-		Pool: workerpool.New("enrichment", input, EnrichDevice),
+		Pool: workerpool.New("enrichment", input, BuildEnrichDeviceFunc(limits)),
 	}
+	w.Pool.TraceKey = func(req EnrichDeviceRequest) string { return req.Device.Addr.String() }
+	return w
 }
 
 func (w *Worker) Run(ctx context.Context, max int) {
@@ -0,0 +1,118 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package exporter
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/networkables/mason/internal/flagset"
+)
+
+const (
+	// FormatInflux posts InfluxDB line protocol, e.g. to /api/v2/write or /write
+	FormatInflux = "influx"
+	// FormatPrometheus posts a Prometheus remote_write request, e.g. to /api/v1/write
+	FormatPrometheus = "prometheus"
+)
+
+type Config struct {
+	Enabled      bool
+	Format       string
+	URL          string
+	Token        string
+	Username     string
+	Password     string
+	Interval     time.Duration
+	Timeout      time.Duration
+	MaxPending   int
+	SnmpCounters bool
+}
+
+func SetFlags(fs *pflag.FlagSet, cfg *Config) {
+	configMajorKey := "exporter"
+
+	flagset.Bool(
+		fs,
+		&cfg.Enabled,
+		configMajorKey,
+		"enabled",
+		false,
+		"remote write ping statistics and snmp counters to a time series database",
+	)
+	flagset.String(
+		fs,
+		&cfg.Format,
+		configMajorKey,
+		"format",
+		FormatInflux,
+		"format of the remote write: influx (line protocol) or prometheus (remote_write)",
+	)
+	flagset.String(
+		fs,
+		&cfg.URL,
+		configMajorKey,
+		"url",
+		"",
+		"write endpoint (http://influxdb:8086/api/v2/write?org=o&bucket=b)",
+	)
+	flagset.String(
+		fs,
+		&cfg.Token,
+		configMajorKey,
+		"token",
+		"",
+		"token sent as the authorization (Token for influx, Bearer for prometheus)",
+	)
+	flagset.String(
+		fs,
+		&cfg.Username,
+		configMajorKey,
+		"username",
+		"",
+		"basic auth username, used when no token is set",
+	)
+	flagset.String(
+		fs,
+		&cfg.Password,
+		configMajorKey,
+		"password",
+		"",
+		"basic auth password",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Interval,
+		configMajorKey,
+		"interval",
+		time.Minute,
+		"time between remote writes (and snmp counter polls)",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Timeout,
+		configMajorKey,
+		"timeout",
+		10*time.Second,
+		"timeout of a remote write",
+	)
+	flagset.Int(
+		fs,
+		&cfg.MaxPending,
+		configMajorKey,
+		"maxpending",
+		100_000,
+		"samples kept while the endpoint is unreachable, the oldest are dropped beyond this",
+	)
+	flagset.Bool(
+		fs,
+		&cfg.SnmpCounters,
+		configMajorKey,
+		"snmpcounters",
+		true,
+		"poll the interface octet counters of snmp devices on each remote write",
+	)
+}
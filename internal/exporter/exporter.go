@@ -0,0 +1,151 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package exporter remote writes ping statistics and snmp counters to a time series database,
+// so long term storage can be left to an existing InfluxDB, VictoriaMetrics or Prometheus
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/emicklei/tre"
+)
+
+var (
+	ErrURLRequired   = errors.New("exporter url is required")
+	ErrUnknownFormat = errors.New("unknown exporter format")
+
+	// ErrRejected is returned when the endpoint refused the samples, they are not retried
+	ErrRejected = errors.New("remote write rejected")
+)
+
+// Exporter buffers samples between remote writes, samples of a failed write are kept for the
+// next one up to the configured maximum unless the endpoint rejected them
+type Exporter struct {
+	cfg     *Config
+	client  *http.Client
+	lock    sync.Mutex
+	pending []Sample
+	dropped int
+}
+
+func New(cfg *Config) (*Exporter, error) {
+	if cfg.URL == "" {
+		return nil, ErrURLRequired
+	}
+	_, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, tre.New(err, "parse exporter url", "url", cfg.URL)
+	}
+	if cfg.Format != FormatInflux && cfg.Format != FormatPrometheus {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, cfg.Format)
+	}
+	return &Exporter{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// Add queues the samples for the next remote write
+func (e *Exporter) Add(samples ...Sample) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.pending = append(e.pending, samples...)
+	e.trim()
+}
+
+// Dropped returns the number of samples dropped because the endpoint rejected them or was
+// unreachable for too long
+func (e *Exporter) Dropped() int {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.dropped
+}
+
+// Flush remote writes the queued samples, returns the number written
+func (e *Exporter) Flush(ctx context.Context) (int, error) {
+	e.lock.Lock()
+	samples := e.pending
+	e.pending = nil
+	e.lock.Unlock()
+	if len(samples) == 0 {
+		return 0, nil
+	}
+
+	err := e.write(ctx, samples)
+	if errors.Is(err, ErrRejected) {
+		e.lock.Lock()
+		e.dropped += len(samples)
+		e.lock.Unlock()
+		return 0, err
+	}
+	if err != nil {
+		e.lock.Lock()
+		e.pending = append(samples, e.pending...)
+		e.trim()
+		e.lock.Unlock()
+		return 0, err
+	}
+	return len(samples), nil
+}
+
+func (e *Exporter) write(ctx context.Context, samples []Sample) error {
+	var body []byte
+	header := make(http.Header)
+	switch e.cfg.Format {
+	case FormatInflux:
+		body = encodeInflux(samples)
+		header.Set("Content-Type", "text/plain; charset=utf-8")
+	case FormatPrometheus:
+		body = snappyEncode(encodeRemoteWrite(samples))
+		header.Set("Content-Type", "application/x-protobuf")
+		header.Set("Content-Encoding", "snappy")
+		header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	switch {
+	case e.cfg.Token != "" && e.cfg.Format == FormatInflux:
+		req.Header.Set("Authorization", "Token "+e.cfg.Token)
+	case e.cfg.Token != "":
+		req.Header.Set("Authorization", "Bearer "+e.cfg.Token)
+	case e.cfg.Username != "":
+		req.SetBasicAuth(e.cfg.Username, e.cfg.Password)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err = fmt.Errorf("remote write %s: %s", resp.Status, bytes.TrimSpace(msg))
+		if resp.StatusCode < http.StatusInternalServerError &&
+			resp.StatusCode != http.StatusTooManyRequests {
+			err = fmt.Errorf("%w: %w", ErrRejected, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// trim drops the oldest samples beyond the maximum, the lock must be held
+func (e *Exporter) trim() {
+	over := len(e.pending) - e.cfg.MaxPending
+	if e.cfg.MaxPending <= 0 || over <= 0 {
+		return
+	}
+	e.pending = e.pending[over:]
+	e.dropped += over
+}
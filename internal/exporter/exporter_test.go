@@ -0,0 +1,151 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+var testTime = time.Unix(1700000000, 0)
+
+func TestEncodeInflux(t *testing.T) {
+	device := model.Device{Addr: model.MustParseAddr("192.168.1.1"), Name: "core switch"}
+	samples := PingSamples(testTime, device, nettools.Icmp4EchoResponseStatistics{
+		Minimum:    time.Millisecond,
+		Mean:       1500 * time.Microsecond,
+		Maximum:    3 * time.Millisecond,
+		PacketLoss: 0.25,
+	})
+	samples = append(samples, InterfaceSamples(testTime, device, []nettools.InterfaceCounters{
+		{Index: 1, Name: "eth0", InOctets: 1000, OutOctets: 2000},
+	})...)
+
+	want := "ping,addr=192.168.1.1,name=core\\ switch " +
+		"minimum_ms=1,average_ms=1.5,maximum_ms=3,loss=0.25 1700000000000000000\n" +
+		"interface,addr=192.168.1.1,ifindex=1,ifname=eth0,name=core\\ switch " +
+		"in_octets=1000,out_octets=2000 1700000000000000000\n"
+	if diff := cmp.Diff(want, string(encodeInflux(samples))); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestEncodeRemoteWrite(t *testing.T) {
+	sample := Sample{
+		Measurement: "m",
+		Field:       "f",
+		Tags:        []Tag{{Key: "a", Value: "b"}, {Key: "empty"}},
+		Value:       1.5,
+		Time:        time.UnixMilli(1000),
+	}
+	want := []byte{
+		0x0a, 45, // timeseries
+		0x0a, 21, 0x0a, 8, '_', '_', 'n', 'a', 'm', 'e', '_', '_', // label name
+		0x12, 9, 'm', 'a', 's', 'o', 'n', '_', 'm', '_', 'f', // label value
+		0x0a, 6, 0x0a, 1, 'a', 0x12, 1, 'b', // label
+		0x12, 12, 0x09, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f, 0x10, 0xe8, 0x07, // sample
+	}
+	if diff := cmp.Diff(want, encodeRemoteWrite([]Sample{sample})); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestSnappyEncode(t *testing.T) {
+	long := bytes.Repeat([]byte{'x'}, 300)
+	tests := map[string]struct {
+		src  []byte
+		want []byte
+	}{
+		"Short": {src: []byte("abc"), want: []byte{3, 2 << 2, 'a', 'b', 'c'}},
+		"Long":  {src: long, want: append([]byte{0xac, 0x02, 61 << 2, 43, 1}, long...)},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, snappyEncode(tc.src)); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExporter_Flush(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	var (
+		body   []byte
+		header http.Header
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	e, err := New(&Config{
+		Format:     FormatPrometheus,
+		URL:        srv.URL,
+		Token:      "secret",
+		Timeout:    time.Second,
+		MaxPending: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sample := Sample{Measurement: "m", Field: "f", Time: testTime}
+	e.Add(sample, sample)
+
+	// unavailable keeps the samples for the next write
+	_, err = e.Flush(ctx)
+	if err == nil || errors.Is(err, ErrRejected) {
+		t.Fatalf("want a retryable error, got: %v", err)
+	}
+	e.Add(sample, sample)
+	if e.Dropped() != 1 {
+		t.Errorf("dropped want: 1, got: %d", e.Dropped())
+	}
+
+	status = http.StatusNoContent
+	count, err := e.Flush(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("count want: 3, got: %d", count)
+	}
+	if got := header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("authorization want: Bearer secret, got: %s", got)
+	}
+	if got := header.Get("Content-Encoding"); got != "snappy" {
+		t.Errorf("content encoding want: snappy, got: %s", got)
+	}
+	want := snappyEncode(encodeRemoteWrite([]Sample{sample, sample, sample}))
+	if diff := cmp.Diff(want, body); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	// rejected samples are dropped
+	status = http.StatusBadRequest
+	e.Add(sample)
+	_, err = e.Flush(ctx)
+	if !errors.Is(err, ErrRejected) {
+		t.Fatalf("want: %v, got: %v", ErrRejected, err)
+	}
+	count, err = e.Flush(ctx)
+	if count != 0 || err != nil {
+		t.Errorf("want nothing pending, got: %d, %v", count, err)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package exporter
+
+import (
+	"strconv"
+	"strings"
+)
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// encodeInflux writes the samples as line protocol with nanosecond timestamps, consecutive
+// samples of the same measurement, tags and time share a line
+func encodeInflux(samples []Sample) []byte {
+	var b strings.Builder
+	for idx, s := range samples {
+		if idx > 0 && sameLine(samples[idx-1], s) {
+			b.WriteByte(',')
+		} else {
+			if idx > 0 {
+				b.WriteString(" " + strconv.FormatInt(samples[idx-1].Time.UnixNano(), 10) + "\n")
+			}
+			b.WriteString(measurementEscaper.Replace(s.Measurement))
+			for _, t := range s.Tags {
+				if t.Value == "" {
+					continue
+				}
+				b.WriteString("," + tagEscaper.Replace(t.Key) + "=" + tagEscaper.Replace(t.Value))
+			}
+			b.WriteByte(' ')
+		}
+		b.WriteString(tagEscaper.Replace(s.Field) + "=")
+		b.WriteString(strconv.FormatFloat(s.Value, 'f', -1, 64))
+	}
+	if len(samples) > 0 {
+		b.WriteString(" " + strconv.FormatInt(samples[len(samples)-1].Time.UnixNano(), 10) + "\n")
+	}
+	return []byte(b.String())
+}
+
+func sameLine(a, b Sample) bool {
+	if a.Measurement != b.Measurement || !a.Time.Equal(b.Time) || len(a.Tags) != len(b.Tags) {
+		return false
+	}
+	for idx := range a.Tags {
+		if a.Tags[idx] != b.Tags[idx] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package exporter
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// encodeRemoteWrite builds the protobuf prometheus.WriteRequest of the samples, each sample is
+// its own time series.  The few messages involved are encoded by hand:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func encodeRemoteWrite(samples []Sample) []byte {
+	var req []byte
+	for _, s := range samples {
+		var ts []byte
+		ts = appendBytes(ts, 1, encodeLabel("__name__", "mason_"+s.Measurement+"_"+s.Field))
+		for _, t := range s.Tags {
+			if t.Value == "" {
+				continue
+			}
+			ts = appendBytes(ts, 1, encodeLabel(t.Key, t.Value))
+		}
+		var sample []byte
+		sample = append(sample, 1<<3|1)
+		sample = binary.LittleEndian.AppendUint64(sample, math.Float64bits(s.Value))
+		sample = append(sample, 2<<3)
+		sample = binary.AppendUvarint(sample, uint64(s.Time.UnixMilli()))
+		ts = appendBytes(ts, 2, sample)
+		req = appendBytes(req, 1, ts)
+	}
+	return req
+}
+
+func encodeLabel(name string, value string) []byte {
+	var label []byte
+	label = appendBytes(label, 1, []byte(name))
+	return appendBytes(label, 2, []byte(value))
+}
+
+// appendBytes appends a length delimited field
+func appendBytes(dst []byte, field int, value []byte) []byte {
+	dst = binary.AppendUvarint(dst, uint64(field<<3|2))
+	dst = binary.AppendUvarint(dst, uint64(len(value)))
+	return append(dst, value...)
+}
+
+// snappyEncode writes src as a snappy block made only of literals, which every snappy decoder
+// accepts.  The payload is not compressed but no snappy library is needed.
+func snappyEncode(src []byte) []byte {
+	dst := binary.AppendUvarint(nil, uint64(len(src)))
+	for len(src) > 0 {
+		n := min(len(src), 1<<16)
+		dst = appendSnappyLiteral(dst, src[:n])
+		src = src[n:]
+	}
+	return dst
+}
+
+func appendSnappyLiteral(dst []byte, literal []byte) []byte {
+	n := len(literal) - 1
+	switch {
+	case n < 60:
+		dst = append(dst, byte(n)<<2)
+	case n < 1<<8:
+		dst = append(dst, 60<<2, byte(n))
+	default:
+		dst = append(dst, 61<<2, byte(n), byte(n>>8))
+	}
+	return append(dst, literal...)
+}
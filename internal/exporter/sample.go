@@ -0,0 +1,83 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package exporter
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+type (
+	// Sample is one field of a measurement.  Influx lines group the fields of a measurement
+	// with the same tags and time, Prometheus names the series mason_<measurement>_<field>.
+	Sample struct {
+		Measurement string
+		Field       string
+		Tags        []Tag
+		Value       float64
+		Time        time.Time
+	}
+
+	// Tag is a label of the sample, tags are kept ordered by key
+	Tag struct {
+		Key   string
+		Value string
+	}
+)
+
+// PingSamples are the statistics of one performance ping of a device, durations in ms
+func PingSamples(
+	start time.Time,
+	device model.Device,
+	stats nettools.Icmp4EchoResponseStatistics,
+) []Sample {
+	tags := deviceTags(device)
+	return []Sample{
+		{"ping", "minimum_ms", tags, milliseconds(stats.Minimum), start},
+		{"ping", "average_ms", tags, milliseconds(stats.Mean), start},
+		{"ping", "maximum_ms", tags, milliseconds(stats.Maximum), start},
+		{"ping", "loss", tags, stats.PacketLoss, start},
+	}
+}
+
+// InterfaceSamples are the octet counters of the interfaces of a device.  The counters are
+// exported as is, the database computes the rate.
+func InterfaceSamples(
+	start time.Time,
+	device model.Device,
+	counters []nettools.InterfaceCounters,
+) []Sample {
+	ret := make([]Sample, 0, 2*len(counters))
+	for _, ic := range counters {
+		tags := []Tag{
+			{Key: "addr", Value: device.Addr.String()},
+			{Key: "ifindex", Value: strconv.Itoa(ic.Index)},
+			{Key: "ifname", Value: ic.Name},
+		}
+		if device.Name != "" {
+			tags = append(tags, Tag{Key: "name", Value: device.Name})
+		}
+		ret = append(ret,
+			Sample{"interface", "in_octets", tags, float64(ic.InOctets), start},
+			Sample{"interface", "out_octets", tags, float64(ic.OutOctets), start},
+		)
+	}
+	return ret
+}
+
+func deviceTags(device model.Device) []Tag {
+	tags := []Tag{{Key: "addr", Value: device.Addr.String()}}
+	if device.Name != "" {
+		tags = append(tags, Tag{Key: "name", Value: device.Name})
+	}
+	return tags
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
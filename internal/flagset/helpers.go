@@ -6,18 +6,40 @@ package flagset
 
 import (
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
+// registered holds the flag key of every setting registered by the helpers, by its pointer
+var registered = struct {
+	sync.Mutex
+	m map[any]string
+}{m: make(map[any]string)}
+
 func Key(keys ...string) string {
 	return strings.Join(keys, ".")
 }
 
+// KeyOf returns the flag key the setting v points to was registered under
+func KeyOf(v any) (string, bool) {
+	registered.Lock()
+	defer registered.Unlock()
+	key, ok := registered.m[v]
+	return key, ok
+}
+
+func register(v any, key string) {
+	registered.Lock()
+	defer registered.Unlock()
+	registered.m[v] = key
+}
+
 func Bool(f *pflag.FlagSet, v *bool, keyMajor string, keyMinor string, def bool, desc string) {
 	key := Key(keyMajor, keyMinor)
+	register(v, key)
 	// viper.SetDefault(key, def)
 	f.BoolVar(v, key, def, desc)
 	viper.BindPFlag(key, f.Lookup(key))
@@ -25,6 +47,7 @@ func Bool(f *pflag.FlagSet, v *bool, keyMajor string, keyMinor string, def bool,
 
 func Int(f *pflag.FlagSet, v *int, keyMajor string, keyMinor string, def int, desc string) {
 	key := Key(keyMajor, keyMinor)
+	register(v, key)
 	// viper.SetDefault(key, def)
 	f.IntVar(v, key, def, desc)
 	viper.BindPFlag(key, f.Lookup(key))
@@ -39,6 +62,7 @@ func IntSlice(
 	desc string,
 ) {
 	key := Key(keyMajor, keyMinor)
+	register(v, key)
 	// viper.SetDefault(key, def)
 	f.IntSliceVar(v, key, def, desc)
 	viper.BindPFlag(key, f.Lookup(key))
@@ -53,6 +77,7 @@ func String(
 	desc string,
 ) {
 	key := Key(keyMajor, keyMinor)
+	register(v, key)
 	// viper.SetDefault(key, def)
 	f.StringVar(v, key, def, desc)
 	viper.BindPFlag(key, f.Lookup(key))
@@ -67,6 +92,7 @@ func StringSlice(
 	desc string,
 ) {
 	key := Key(keyMajor, keyMinor)
+	register(v, key)
 	// viper.SetDefault(key, def)
 	f.StringSliceVar(v, key, def, desc)
 	viper.BindPFlag(key, f.Lookup(key))
@@ -81,6 +107,7 @@ func Duration(
 	desc string,
 ) {
 	key := Key(keyMajor, keyMinor)
+	register(v, key)
 	// viper.SetDefault(key, def)
 	f.DurationVar(v, key, def, desc)
 	viper.BindPFlag(key, f.Lookup(key))
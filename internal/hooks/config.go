@@ -0,0 +1,50 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package hooks
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/networkables/mason/internal/flagset"
+)
+
+// Config sets the yaml file of the scripting hooks evaluated on discovered and updated
+// devices and on ingested flows
+type Config struct {
+	Enabled       bool
+	File          string
+	AlertCooldown time.Duration
+}
+
+func SetFlags(fs *pflag.FlagSet, cfg *Config) {
+	configMajorKey := "hooks"
+
+	flagset.Bool(
+		fs,
+		&cfg.Enabled,
+		configMajorKey,
+		"enabled",
+		false,
+		"evaluate the scripting hooks of the hooks file on devices and flows",
+	)
+	flagset.String(
+		fs,
+		&cfg.File,
+		configMajorKey,
+		"file",
+		"hooks.yaml",
+		"yaml file of hooks, read again once it changes",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.AlertCooldown,
+		configMajorKey,
+		"alertcooldown",
+		time.Hour,
+		"shortest time between alerts of the same hook for the same address",
+	)
+}
@@ -0,0 +1,422 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package hooks evaluates small user supplied expressions on discovered and updated devices
+// and on ingested flows, to tag, rename, drop or raise alerts on them without changing mason.
+// The expressions use the expr language (https://expr-lang.org).
+package hooks
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"gopkg.in/yaml.v3"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// Trigger is the event a hook is evaluated on
+type Trigger string
+
+const (
+	// OnDiscovered runs on each device found by discovery, known devices included, before it
+	// is stored
+	OnDiscovered Trigger = "discovered"
+	// OnUpdated runs on each device update, e.g. after enrichment
+	OnUpdated Trigger = "updated"
+	// OnFlow runs on each ingested flow, before it is stored
+	OnFlow Trigger = "flow"
+)
+
+var (
+	ErrHookNameRequired  = errors.New("hook name is required")
+	ErrUnknownTrigger    = errors.New("unknown hook trigger, use discovered, updated or flow")
+	ErrHookNoAction      = errors.New("hook needs one of drop, tag, rename or alert")
+	ErrHookCannotDrop    = errors.New("updated devices can not be dropped")
+	ErrHookDeviceActions = errors.New("tag and rename only apply to devices")
+)
+
+type (
+	// Hook is one entry of the hooks file.  When is a boolean expression selecting the devices
+	// or flows the hook acts on, an empty When selects all of them.  Rename and Alert are
+	// string expressions, Tag is added as is.
+	Hook struct {
+		Name   string  `yaml:"name"`
+		On     Trigger `yaml:"on"`
+		When   string  `yaml:"when"`
+		Drop   bool    `yaml:"drop"`
+		Tag    string  `yaml:"tag"`
+		Rename string  `yaml:"rename"`
+		Alert  string  `yaml:"alert"`
+	}
+
+	// DeviceEnv is the device seen by the expressions as device.<field>
+	DeviceEnv struct {
+		Addr         string   `expr:"addr"`
+		MAC          string   `expr:"mac"`
+		Name         string   `expr:"name"`
+		DnsName      string   `expr:"dnsname"`
+		Manufacturer string   `expr:"manufacturer"`
+		Type         string   `expr:"type"`
+		DiscoveredBy string   `expr:"discoveredby"`
+		Tags         []string `expr:"tags"`
+		Ports        []int    `expr:"ports"`
+	}
+
+	// FlowEnv is the flow seen by the expressions as flow.<field>
+	FlowEnv struct {
+		Src      string `expr:"src"`
+		SrcPort  int    `expr:"srcport"`
+		SrcASN   string `expr:"srcasn"`
+		Dst      string `expr:"dst"`
+		DstPort  int    `expr:"dstport"`
+		DstASN   string `expr:"dstasn"`
+		Protocol string `expr:"protocol"`
+		ProtoNum int    `expr:"protonum"`
+		Bytes    int    `expr:"bytes"`
+		Packets  int    `expr:"packets"`
+		Vlan     int    `expr:"vlan"`
+	}
+
+	deviceEnv struct {
+		Device DeviceEnv `expr:"device"`
+	}
+
+	flowEnv struct {
+		Flow FlowEnv `expr:"flow"`
+	}
+
+	hooksFile struct {
+		Hooks []Hook `yaml:"hooks"`
+	}
+
+	compiled struct {
+		Hook
+		when   *vm.Program
+		rename *vm.Program
+		alert  *vm.Program
+	}
+
+	// Set is the compiled hooks of a file, a nil Set has no hooks
+	Set struct {
+		hooks []compiled
+	}
+)
+
+// inNet is available to the expressions as inNet(addr, "192.168.1.0/24")
+var inNet = expr.Function(
+	"inNet",
+	func(params ...any) (any, error) {
+		addr, err := netip.ParseAddr(params[0].(string))
+		if err != nil {
+			return false, nil
+		}
+		prefix, err := netip.ParsePrefix(params[1].(string))
+		if err != nil {
+			return false, err
+		}
+		return prefix.Contains(addr), nil
+	},
+	new(func(string, string) bool),
+)
+
+// Load reads and compiles the hooks of the yaml file
+func Load(path string) (*Set, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f hooksFile
+	err = yaml.Unmarshal(b, &f)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(f.Hooks)
+}
+
+// Compile checks the hooks and compiles their expressions
+func Compile(hooks []Hook) (*Set, error) {
+	s := &Set{hooks: make([]compiled, 0, len(hooks))}
+	for _, h := range hooks {
+		c, err := compile(h)
+		if err != nil {
+			if h.Name != "" {
+				err = fmt.Errorf("hook %s: %w", h.Name, err)
+			}
+			return nil, err
+		}
+		s.hooks = append(s.hooks, c)
+	}
+	return s, nil
+}
+
+func compile(h Hook) (c compiled, err error) {
+	c.Hook = h
+	if h.Name == "" {
+		return c, ErrHookNameRequired
+	}
+	var env any
+	switch h.On {
+	case OnDiscovered, OnUpdated:
+		env = deviceEnv{}
+	case OnFlow:
+		env = flowEnv{}
+		if h.Tag != "" || h.Rename != "" {
+			return c, ErrHookDeviceActions
+		}
+	default:
+		return c, fmt.Errorf("%w: %q", ErrUnknownTrigger, h.On)
+	}
+	if h.On == OnUpdated && h.Drop {
+		return c, ErrHookCannotDrop
+	}
+	if !h.Drop && h.Tag == "" && h.Rename == "" && h.Alert == "" {
+		return c, ErrHookNoAction
+	}
+
+	if h.When != "" {
+		c.when, err = expr.Compile(h.When, expr.Env(env), inNet, expr.AsBool())
+		if err != nil {
+			return c, fmt.Errorf("when: %w", err)
+		}
+	}
+	if h.Rename != "" {
+		c.rename, err = expr.Compile(h.Rename, expr.Env(env), inNet, expr.AsKind(reflect.String))
+		if err != nil {
+			return c, fmt.Errorf("rename: %w", err)
+		}
+	}
+	if h.Alert != "" {
+		c.alert, err = expr.Compile(h.Alert, expr.Env(env), inNet, expr.AsKind(reflect.String))
+		if err != nil {
+			return c, fmt.Errorf("alert: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// Len is the number of hooks in the set
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.hooks)
+}
+
+// ApplyDevice runs the hooks of the trigger on the device in file order.  Tags are added and
+// the name is only set on a device still named by its address, so edits by the user are kept.
+// A dropping hook stops the evaluation.  The errors of failing hooks are joined, the other
+// hooks still run.
+func (s *Set) ApplyDevice(
+	on Trigger,
+	d *model.Device,
+) (drop bool, alerts []model.EventHookAlert, err error) {
+	if s == nil {
+		return false, nil, nil
+	}
+	var errs []error
+	for _, h := range s.hooks {
+		if h.On != on {
+			continue
+		}
+		env := deviceEnv{Device: deviceToEnv(*d)}
+		match, herr := h.match(env)
+		if herr != nil {
+			errs = append(errs, herr)
+			continue
+		}
+		if !match {
+			continue
+		}
+		if h.Drop {
+			return true, alerts, errors.Join(errs...)
+		}
+		if h.Tag != "" && !d.Meta.Tags.Has(h.Tag) {
+			d.Meta.Tags = append(d.Meta.Tags, model.Tag{Val: h.Tag})
+			d.SetUpdated()
+		}
+		if h.rename != nil && (d.Name == "" || d.IsNameAddr()) {
+			name, herr := h.run(h.rename, env)
+			if herr != nil {
+				errs = append(errs, herr)
+			} else if name != "" && name != d.Name {
+				d.Name = name
+				d.SetUpdated()
+			}
+		}
+		if h.alert != nil {
+			msg, herr := h.run(h.alert, env)
+			if herr != nil {
+				errs = append(errs, herr)
+			} else {
+				alerts = append(alerts, model.EventHookAlert{
+					Hook:    h.Name,
+					Addr:    d.Addr,
+					Message: msg,
+				})
+			}
+		}
+	}
+	return false, alerts, errors.Join(errs...)
+}
+
+// ApplyFlows runs the flow hooks on each flow and returns the flows which were not dropped,
+// reusing the backing array.  Only the first error of each hook is returned, a broken hook
+// usually fails on every flow.
+func (s *Set) ApplyFlows(
+	flows []model.IpFlow,
+) (kept []model.IpFlow, alerts []model.EventHookAlert, err error) {
+	if s.Len() == 0 {
+		return flows, nil, nil
+	}
+	failed := make(map[string]error)
+	kept = flows[:0]
+	for _, flow := range flows {
+		drop := false
+		env := flowEnv{Flow: flowToEnv(flow)}
+		for _, h := range s.hooks {
+			if h.On != OnFlow {
+				continue
+			}
+			match, herr := h.match(env)
+			if herr == nil && match && h.alert != nil {
+				var msg string
+				msg, herr = h.run(h.alert, env)
+				if herr == nil {
+					alerts = append(alerts, model.EventHookAlert{
+						Hook:    h.Name,
+						Addr:    flow.SrcAddr,
+						Message: msg,
+					})
+				}
+			}
+			if herr != nil {
+				if _, ok := failed[h.Name]; !ok {
+					failed[h.Name] = herr
+				}
+				continue
+			}
+			if match && h.Drop {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, flow)
+		}
+	}
+	errs := make([]error, 0, len(failed))
+	for _, h := range s.hooks {
+		if ferr, ok := failed[h.Name]; ok {
+			errs = append(errs, ferr)
+			delete(failed, h.Name)
+		}
+	}
+	return kept, alerts, errors.Join(errs...)
+}
+
+func (c compiled) match(env any) (bool, error) {
+	if c.when == nil {
+		return true, nil
+	}
+	out, err := expr.Run(c.when, env)
+	if err != nil {
+		return false, fmt.Errorf("hook %s: %w", c.Name, err)
+	}
+	return out.(bool), nil
+}
+
+func (c compiled) run(p *vm.Program, env any) (string, error) {
+	out, err := expr.Run(p, env)
+	if err != nil {
+		return "", fmt.Errorf("hook %s: %w", c.Name, err)
+	}
+	return out.(string), nil
+}
+
+func deviceToEnv(d model.Device) DeviceEnv {
+	tags := make([]string, 0, len(d.Meta.Tags))
+	for _, t := range d.Meta.Tags {
+		tags = append(tags, t.Val)
+	}
+	return DeviceEnv{
+		Addr:         d.Addr.String(),
+		MAC:          d.MAC.String(),
+		Name:         d.Name,
+		DnsName:      d.Meta.DnsName,
+		Manufacturer: d.Meta.Manufacturer,
+		Type:         string(d.Meta.DeviceType),
+		DiscoveredBy: string(d.DiscoveredBy),
+		Tags:         tags,
+		Ports:        d.Server.Ports.Ports,
+	}
+}
+
+func flowToEnv(f model.IpFlow) FlowEnv {
+	return FlowEnv{
+		Src:      f.SrcAddr.String(),
+		SrcPort:  int(f.SrcPort),
+		SrcASN:   f.SrcASN,
+		Dst:      f.DstAddr.String(),
+		DstPort:  int(f.DstPort),
+		DstASN:   f.DstASN,
+		Protocol: strings.ToLower(f.Protocol.String()),
+		ProtoNum: int(f.Protocol),
+		Bytes:    f.Bytes,
+		Packets:  f.Packets,
+		Vlan:     f.VlanID,
+	}
+}
+
+// Loader reads the hooks file again once it changes.  While the file is missing or broken
+// the hooks of the last good read are kept, and the error is only returned once per change.
+type Loader struct {
+	mu       sync.Mutex
+	path     string
+	modified time.Time
+	failed   bool
+	set      *Set
+}
+
+func NewLoader(path string) *Loader {
+	return &Loader{path: path}
+}
+
+// Hooks returns the current hooks of the file
+func (l *Loader) Hooks() (*Set, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fi, err := os.Stat(l.path)
+	if err != nil {
+		return l.fail(err)
+	}
+	if fi.ModTime().Equal(l.modified) {
+		return l.set, nil
+	}
+	l.modified = fi.ModTime()
+	s, err := Load(l.path)
+	if err != nil {
+		l.failed = false
+		return l.fail(err)
+	}
+	l.set, l.failed = s, false
+	return s, nil
+}
+
+func (l *Loader) fail(err error) (*Set, error) {
+	if l.failed {
+		return l.set, nil
+	}
+	l.failed = true
+	return l.set, fmt.Errorf("hooks file %s: %w", l.path, err)
+}
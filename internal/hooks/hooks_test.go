@@ -0,0 +1,231 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package hooks
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestCompile_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		hook Hook
+		want error
+	}{
+		"NoName": {
+			hook: Hook{On: OnFlow, Drop: true},
+			want: ErrHookNameRequired,
+		},
+		"UnknownTrigger": {
+			hook: Hook{Name: "x", On: "added", Drop: true},
+			want: ErrUnknownTrigger,
+		},
+		"NoAction": {
+			hook: Hook{Name: "x", On: OnDiscovered, When: "true"},
+			want: ErrHookNoAction,
+		},
+		"DropUpdated": {
+			hook: Hook{Name: "x", On: OnUpdated, Drop: true},
+			want: ErrHookCannotDrop,
+		},
+		"TagFlow": {
+			hook: Hook{Name: "x", On: OnFlow, Tag: "iot"},
+			want: ErrHookDeviceActions,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Compile([]Hook{tc.hook})
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("want %v, got %v", tc.want, err)
+			}
+		})
+	}
+
+	for name, hook := range map[string]Hook{
+		"UnknownField": {Name: "x", On: OnDiscovered, When: `device.color == "red"`, Drop: true},
+		"FlowOnDevice": {Name: "x", On: OnDiscovered, When: `flow.dstport == 23`, Drop: true},
+		"NotBool":      {Name: "x", On: OnFlow, When: `flow.dstport`, Drop: true},
+		"NotString":    {Name: "x", On: OnFlow, Alert: `flow.bytes`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Compile([]Hook{hook})
+			if err == nil {
+				t.Fatal("want compile error")
+			}
+		})
+	}
+}
+
+func TestSet_ApplyDevice(t *testing.T) {
+	addr := model.MustParseAddr("192.168.1.20")
+	tests := map[string]struct {
+		hooks      []Hook
+		on         Trigger
+		device     model.Device
+		wantDrop   bool
+		wantName   string
+		wantTags   model.Tags
+		wantAlerts []model.EventHookAlert
+	}{
+		"Tag": {
+			hooks: []Hook{
+				{Name: "sonos", On: OnDiscovered, When: `device.manufacturer contains "Sonos"`, Tag: "speaker"},
+			},
+			on:       OnDiscovered,
+			device:   model.Device{Name: "kitchen", Addr: addr, Meta: model.Meta{Manufacturer: "Sonos, Inc."}},
+			wantName: "kitchen",
+			wantTags: model.Tags{{Val: "speaker"}},
+		},
+		"TagKept": {
+			hooks: []Hook{
+				{Name: "sonos", On: OnDiscovered, Tag: "speaker"},
+			},
+			on:       OnDiscovered,
+			device:   model.Device{Name: "kitchen", Addr: addr, Meta: model.Meta{Tags: model.Tags{{Val: "speaker"}}}},
+			wantName: "kitchen",
+			wantTags: model.Tags{{Val: "speaker"}},
+		},
+		"OtherTrigger": {
+			hooks: []Hook{
+				{Name: "sonos", On: OnUpdated, Tag: "speaker"},
+			},
+			on:       OnDiscovered,
+			device:   model.Device{Name: "kitchen", Addr: addr},
+			wantName: "kitchen",
+		},
+		"Drop": {
+			hooks: []Hook{
+				{Name: "guests", On: OnDiscovered, When: `inNet(device.addr, "192.168.1.0/24")`, Drop: true},
+				{Name: "later", On: OnDiscovered, Tag: "never"},
+			},
+			on:       OnDiscovered,
+			device:   model.Device{Name: "kitchen", Addr: addr},
+			wantDrop: true,
+			wantName: "kitchen",
+		},
+		"RenameAddrName": {
+			hooks: []Hook{
+				{Name: "printers", On: OnUpdated, When: `9100 in device.ports`, Rename: `"printer-" + device.addr`},
+			},
+			on: OnUpdated,
+			device: model.Device{
+				Name:   "192.168.1.20",
+				Addr:   addr,
+				Server: model.Server{Ports: model.PortList{Ports: []int{80, 9100}}},
+			},
+			wantName: "printer-192.168.1.20",
+		},
+		"RenameKeepsUserName": {
+			hooks: []Hook{
+				{Name: "printers", On: OnUpdated, Rename: `"printer"`},
+			},
+			on:       OnUpdated,
+			device:   model.Device{Name: "office", Addr: addr},
+			wantName: "office",
+		},
+		"Alert": {
+			hooks: []Hook{
+				{Name: "unknown", On: OnDiscovered, When: `device.manufacturer == ""`, Alert: `"unknown device " + device.addr`},
+			},
+			on:         OnDiscovered,
+			device:     model.Device{Name: "x", Addr: addr},
+			wantName:   "x",
+			wantAlerts: []model.EventHookAlert{{Hook: "unknown", Addr: addr, Message: "unknown device 192.168.1.20"}},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := Compile(tc.hooks)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := tc.device
+			drop, alerts, err := s.ApplyDevice(tc.on, &d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if drop != tc.wantDrop {
+				t.Errorf("drop want: %v, got: %v", tc.wantDrop, drop)
+			}
+			if d.Name != tc.wantName {
+				t.Errorf("name want: %s, got: %s", tc.wantName, d.Name)
+			}
+			if diff := cmp.Diff(tc.wantTags, d.Meta.Tags); diff != "" {
+				t.Errorf("tags (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantAlerts, alerts, cmp.Comparer(func(a, b model.Addr) bool {
+				return a.Compare(b) == 0
+			})); diff != "" {
+				t.Errorf("alerts (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSet_ApplyFlows(t *testing.T) {
+	flow := func(dst string, port uint16) model.IpFlow {
+		return model.IpFlow{
+			SrcAddr:  model.MustParseAddr("192.168.1.20"),
+			DstAddr:  model.MustParseAddr(dst),
+			DstPort:  port,
+			Protocol: 6,
+		}
+	}
+	s, err := Compile([]Hook{
+		{Name: "nodns", On: OnFlow, When: `flow.protocol == "tcp" && flow.dstport == 53`, Drop: true},
+		{Name: "telnet", On: OnFlow, When: `flow.dstport == 23`, Alert: `"telnet to " + flow.dst`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, alerts, err := s.ApplyFlows([]model.IpFlow{
+		flow("1.1.1.1", 53),
+		flow("10.0.0.1", 23),
+		flow("1.1.1.1", 443),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 2 || kept[0].DstPort != 23 || kept[1].DstPort != 443 {
+		t.Errorf("kept want ports 23 and 443, got %v", kept)
+	}
+	if len(alerts) != 1 || alerts[0].Message != "telnet to 10.0.0.1" {
+		t.Errorf("want one telnet alert, got %v", alerts)
+	}
+}
+
+func TestLoader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hooks.yaml")
+	l := NewLoader(path)
+	s, err := l.Hooks()
+	if err == nil || s.Len() != 0 {
+		t.Fatalf("missing file want error and no hooks, got %v, %d", err, s.Len())
+	}
+	if _, err = l.Hooks(); err != nil {
+		t.Fatalf("missing file error want reported once, got %v", err)
+	}
+
+	err = os.WriteFile(path, []byte(`
+hooks:
+  - name: iot
+    on: discovered
+    when: device.manufacturer startsWith "Espressif"
+    tag: iot
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = l.Hooks()
+	if err != nil || s.Len() != 1 {
+		t.Fatalf("want one hook, got %d, %v", s.Len(), err)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/networkables/mason/internal/flagset"
+)
+
+type Config struct {
+	Enabled     bool
+	Kubeconfig  string
+	Context     string
+	Interval    time.Duration
+	Timeout     time.Duration
+	PodNetworks bool
+}
+
+func SetFlags(fs *pflag.FlagSet, cfg *Config) {
+	configMajorKey := "kubernetes"
+
+	flagset.Bool(
+		fs,
+		&cfg.Enabled,
+		configMajorKey,
+		"enabled",
+		false,
+		"add the nodes and the loadbalancer and nodeport services of a kubernetes cluster as devices",
+	)
+	flagset.String(
+		fs,
+		&cfg.Kubeconfig,
+		configMajorKey,
+		"kubeconfig",
+		"",
+		"kubeconfig of the cluster, empty uses $KUBECONFIG, ~/.kube/config or the in-cluster service account",
+	)
+	flagset.String(
+		fs,
+		&cfg.Context,
+		configMajorKey,
+		"context",
+		"",
+		"kubeconfig context to use, empty uses the current context",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Interval,
+		configMajorKey,
+		"interval",
+		time.Minute,
+		"time between listings of the cluster nodes and services",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Timeout,
+		configMajorKey,
+		"timeout",
+		10*time.Second,
+		"timeout of a cluster api request",
+	)
+	flagset.Bool(
+		fs,
+		&cfg.PodNetworks,
+		configMajorKey,
+		"podnetworks",
+		false,
+		"add the pod cidr of each node as a network, only useful when pod addresses are routed",
+	)
+}
@@ -0,0 +1,229 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	inClusterTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	inClusterCAFile    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+var (
+	ErrNoKubeconfig    = errors.New("no kubeconfig found and not running in a cluster")
+	ErrContextNotFound = errors.New("kubeconfig context not found")
+	ErrInvalidCAData   = errors.New("no certificates found in certificate authority")
+	ErrUnsupportedUser = errors.New("kubeconfig user authenticates with an exec or auth provider plugin")
+)
+
+// kubeconfig holds the parts of a kubeconfig file needed to reach the cluster api
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string
+		Cluster struct {
+			Server                   string
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		}
+	}
+	Contexts []struct {
+		Name    string
+		Context struct {
+			Cluster string
+			User    string
+		}
+	}
+	Users []struct {
+		Name string
+		User struct {
+			Token                 string
+			TokenFile             string `yaml:"tokenFile"`
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+			Username              string
+			Password              string
+			Exec                  any
+			AuthProvider          any `yaml:"auth-provider"`
+		}
+	}
+}
+
+// endpoint is how the cluster api is reached, as read from a kubeconfig or the service account
+// of the pod mason runs in
+type endpoint struct {
+	server   string
+	tls      *tls.Config
+	token    string
+	username string
+	password string
+}
+
+// loadEndpoint reads the kubeconfig given, otherwise $KUBECONFIG or ~/.kube/config, and
+// falls back to the service account when running inside a cluster
+func loadEndpoint(path string, context string) (endpoint, error) {
+	if path == "" {
+		path = defaultKubeconfigPath()
+	}
+	if path == "" {
+		return inClusterEndpoint()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return endpoint{}, err
+	}
+	return parseKubeconfig(data, filepath.Dir(path), context)
+}
+
+func defaultKubeconfigPath() string {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		// only the first file of a kubeconfig list is used
+		return filepath.SplitList(env)[0]
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	path := filepath.Join(home, ".kube", "config")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+func inClusterEndpoint() (endpoint, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return endpoint{}, ErrNoKubeconfig
+	}
+	token, err := os.ReadFile(inClusterTokenFile)
+	if err != nil {
+		return endpoint{}, err
+	}
+	ca, err := os.ReadFile(inClusterCAFile)
+	if err != nil {
+		return endpoint{}, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return endpoint{}, ErrInvalidCAData
+	}
+	return endpoint{
+		server: "https://" + net.JoinHostPort(host, port),
+		tls:    &tls.Config{RootCAs: pool},
+		token:  strings.TrimSpace(string(token)),
+	}, nil
+}
+
+// parseKubeconfig finds the cluster and user of the context, relative file names are read
+// from dir
+func parseKubeconfig(data []byte, dir string, context string) (endpoint, error) {
+	var kc kubeconfig
+	err := yaml.Unmarshal(data, &kc)
+	if err != nil {
+		return endpoint{}, err
+	}
+	if context == "" {
+		context = kc.CurrentContext
+	}
+	var clusterName, userName string
+	found := false
+	for _, c := range kc.Contexts {
+		if c.Name == context {
+			clusterName, userName, found = c.Context.Cluster, c.Context.User, true
+		}
+	}
+	if !found {
+		return endpoint{}, fmt.Errorf("%w: %q", ErrContextNotFound, context)
+	}
+
+	ep := endpoint{tls: &tls.Config{}}
+	readFile := func(name string) ([]byte, error) {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		return os.ReadFile(name)
+	}
+	for _, c := range kc.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		ep.server = strings.TrimSuffix(c.Cluster.Server, "/")
+		ep.tls.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify
+		ca, err := dataOrFile(c.Cluster.CertificateAuthorityData, c.Cluster.CertificateAuthority, readFile)
+		if err != nil {
+			return endpoint{}, err
+		}
+		if len(ca) > 0 {
+			ep.tls.RootCAs = x509.NewCertPool()
+			if !ep.tls.RootCAs.AppendCertsFromPEM(ca) {
+				return endpoint{}, ErrInvalidCAData
+			}
+		}
+	}
+	for _, u := range kc.Users {
+		if u.Name != userName {
+			continue
+		}
+		user := u.User
+		if user.Exec != nil || user.AuthProvider != nil {
+			return endpoint{}, ErrUnsupportedUser
+		}
+		ep.token = user.Token
+		if ep.token == "" && user.TokenFile != "" {
+			token, err := readFile(user.TokenFile)
+			if err != nil {
+				return endpoint{}, err
+			}
+			ep.token = strings.TrimSpace(string(token))
+		}
+		ep.username, ep.password = user.Username, user.Password
+		cert, err := dataOrFile(user.ClientCertificateData, user.ClientCertificate, readFile)
+		if err != nil {
+			return endpoint{}, err
+		}
+		key, err := dataOrFile(user.ClientKeyData, user.ClientKey, readFile)
+		if err != nil {
+			return endpoint{}, err
+		}
+		if len(cert) > 0 && len(key) > 0 {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return endpoint{}, err
+			}
+			ep.tls.Certificates = []tls.Certificate{pair}
+		}
+	}
+	if ep.server == "" {
+		return endpoint{}, fmt.Errorf("%w: cluster %q has no server", ErrContextNotFound, clusterName)
+	}
+	return ep, nil
+}
+
+// dataOrFile returns the base64 data of a kubeconfig field, or the content of its file
+func dataOrFile(data string, file string, readFile func(string) ([]byte, error)) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if file != "" {
+		return readFile(file)
+	}
+	return nil, nil
+}
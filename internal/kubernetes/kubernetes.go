@@ -0,0 +1,269 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package kubernetes lists the nodes and the services reachable from outside of a kubernetes
+// cluster, so the infrastructure of the cluster shows up in the inventory
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	ServiceTypeLoadBalancer = "LoadBalancer"
+	ServiceTypeNodePort     = "NodePort"
+)
+
+var (
+	// KubernetesTag marks every device and network found in the cluster
+	KubernetesTag   = model.Tag{Val: "kubernetes"}
+	NodeTag         = model.Tag{Val: "kubernetes-node"}
+	LoadBalancerTag = model.Tag{Val: "kubernetes-loadbalancer"}
+	// NodePortTag marks the nodes when a service is exposed on a port of every node
+	NodePortTag = model.Tag{Val: "kubernetes-nodeport"}
+)
+
+// Client reads the cluster api, the kubeconfig is read once when the client is created
+type Client struct {
+	ep     endpoint
+	client *http.Client
+}
+
+// Node is a cluster node with the addresses it is reached at
+type Node struct {
+	Name       string
+	InternalIP netip.Addr
+	ExternalIP netip.Addr
+	PodCIDRs   []netip.Prefix
+}
+
+// Service is a service of type LoadBalancer or NodePort
+type Service struct {
+	Namespace       string
+	Name            string
+	Type            string
+	LoadBalancerIPs []netip.Addr
+	NodePorts       []int
+}
+
+func New(cfg *Config) (*Client, error) {
+	ep, err := loadEndpoint(cfg.Kubeconfig, cfg.Context)
+	if err != nil {
+		return nil, tre.New(err, "load kubeconfig", "kubeconfig", cfg.Kubeconfig)
+	}
+	return &Client{
+		ep: ep,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: &http.Transport{TLSClientConfig: ep.tls},
+		},
+	}, nil
+}
+
+// Server returns the url of the cluster api
+func (c *Client) Server() string {
+	return c.ep.server
+}
+
+func (c *Client) get(ctx context.Context, path string, data any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.ep.server+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case c.ep.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.ep.token)
+	case c.ep.username != "":
+		req.SetBasicAuth(c.ep.username, c.ep.password)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("kubernetes api %s: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(data)
+}
+
+// Nodes lists the nodes of the cluster
+func (c *Client) Nodes(ctx context.Context) ([]Node, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string
+			}
+			Spec struct {
+				PodCIDRs []string
+			}
+			Status struct {
+				Addresses []struct {
+					Type    string
+					Address string
+				}
+			}
+		}
+	}
+	err := c.get(ctx, "/api/v1/nodes", &list)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]Node, 0, len(list.Items))
+	for _, item := range list.Items {
+		node := Node{Name: item.Metadata.Name}
+		for _, addr := range item.Status.Addresses {
+			ip, err := netip.ParseAddr(addr.Address)
+			if err != nil || !ip.Is4() {
+				continue
+			}
+			switch addr.Type {
+			case "InternalIP":
+				node.InternalIP = ip
+			case "ExternalIP":
+				node.ExternalIP = ip
+			}
+		}
+		for _, cidr := range item.Spec.PodCIDRs {
+			if prefix, err := netip.ParsePrefix(cidr); err == nil && prefix.Addr().Is4() {
+				node.PodCIDRs = append(node.PodCIDRs, prefix)
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// Services lists the services of type LoadBalancer and NodePort in all namespaces
+func (c *Client) Services(ctx context.Context) ([]Service, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name      string
+				Namespace string
+			}
+			Spec struct {
+				Type  string
+				Ports []struct {
+					NodePort int
+				}
+			}
+			Status struct {
+				LoadBalancer struct {
+					Ingress []struct {
+						IP string
+					}
+				}
+			}
+		}
+	}
+	err := c.get(ctx, "/api/v1/services", &list)
+	if err != nil {
+		return nil, err
+	}
+	services := make([]Service, 0)
+	for _, item := range list.Items {
+		if item.Spec.Type != ServiceTypeLoadBalancer && item.Spec.Type != ServiceTypeNodePort {
+			continue
+		}
+		svc := Service{
+			Namespace: item.Metadata.Namespace,
+			Name:      item.Metadata.Name,
+			Type:      item.Spec.Type,
+		}
+		// loadbalancer services get node ports as well unless the cluster turned them off
+		for _, port := range item.Spec.Ports {
+			if port.NodePort != 0 {
+				svc.NodePorts = append(svc.NodePorts, port.NodePort)
+			}
+		}
+		for _, ingress := range item.Status.LoadBalancer.Ingress {
+			if ip, err := netip.ParseAddr(ingress.IP); err == nil && ip.Is4() {
+				svc.LoadBalancerIPs = append(svc.LoadBalancerIPs, ip)
+			}
+		}
+		services = append(services, svc)
+	}
+	return services, nil
+}
+
+// Inventory turns the nodes and services into devices, and with podNetworks the pod cidrs of
+// the nodes into networks.  A loadbalancer ip shared by several services is one device named
+// after the first of them.
+func Inventory(
+	nodes []Node,
+	services []Service,
+	podNetworks bool,
+	now time.Time,
+) ([]model.Device, []model.Network) {
+	devices := make([]model.Device, 0)
+	networks := make([]model.Network, 0)
+
+	hasNodePorts := slices.ContainsFunc(services, func(svc Service) bool {
+		return len(svc.NodePorts) > 0
+	})
+	nodeTags := model.Tags{KubernetesTag, NodeTag}
+	if hasNodePorts {
+		nodeTags = append(nodeTags, NodePortTag)
+	}
+	seen := make(map[netip.Addr]bool)
+	for _, node := range nodes {
+		addr := node.InternalIP
+		if !addr.IsValid() {
+			addr = node.ExternalIP
+		}
+		if addr.IsValid() && !seen[addr] {
+			seen[addr] = true
+			devices = append(devices, kubeDevice(node.Name, addr, now, nodeTags))
+		}
+		if !podNetworks {
+			continue
+		}
+		for _, prefix := range node.PodCIDRs {
+			network, err := model.New("pods "+node.Name, prefix.String())
+			if err != nil {
+				continue
+			}
+			network.Tags = model.Tags{KubernetesTag}
+			networks = append(networks, network)
+		}
+	}
+	for _, svc := range services {
+		for _, addr := range svc.LoadBalancerIPs {
+			if seen[addr] {
+				continue
+			}
+			seen[addr] = true
+			devices = append(devices, kubeDevice(
+				svc.Namespace+"/"+svc.Name,
+				addr,
+				now,
+				model.Tags{KubernetesTag, LoadBalancerTag},
+			))
+		}
+	}
+	return devices, networks
+}
+
+func kubeDevice(name string, addr netip.Addr, now time.Time, tags model.Tags) model.Device {
+	return model.Device{
+		Name:         name,
+		Addr:         model.AddrToModelAddr(addr),
+		DiscoveredBy: discovery.KubernetesDiscoverySource,
+		DiscoveredAt: now,
+		Meta:         model.Meta{Tags: slices.Clone(tags)},
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestParseKubeconfig(t *testing.T) {
+	kc := `
+apiVersion: v1
+current-context: home
+clusters:
+- name: home
+  cluster:
+    server: https://192.168.1.10:6443/
+    insecure-skip-tls-verify: true
+- name: lab
+  cluster:
+    server: https://10.0.0.5:6443
+contexts:
+- name: home
+  context:
+    cluster: home
+    user: admin
+- name: lab
+  context:
+    cluster: lab
+    user: viewer
+users:
+- name: admin
+  user:
+    token: abc123
+- name: viewer
+  user:
+    username: view
+    password: secret
+`
+	tests := map[string]struct {
+		context      string
+		wantServer   string
+		wantToken    string
+		wantUsername string
+		wantErr      error
+	}{
+		"CurrentContext": {
+			wantServer: "https://192.168.1.10:6443",
+			wantToken:  "abc123",
+		},
+		"NamedContext": {
+			context:      "lab",
+			wantServer:   "https://10.0.0.5:6443",
+			wantUsername: "view",
+		},
+		"MissingContext": {
+			context: "prod",
+			wantErr: ErrContextNotFound,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ep, err := parseKubeconfig([]byte(kc), t.TempDir(), tc.context)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("error want %v got %v", tc.wantErr, err)
+			}
+			if ep.server != tc.wantServer || ep.token != tc.wantToken ||
+				ep.username != tc.wantUsername {
+				t.Errorf(
+					"want %s %q %q got %s %q %q",
+					tc.wantServer, tc.wantToken, tc.wantUsername,
+					ep.server, ep.token, ep.username,
+				)
+			}
+		})
+	}
+}
+
+func TestClient_NodesAndServices(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/nodes":
+			w.Write([]byte(`{"items":[{"metadata":{"name":"node1"},"spec":{"podCIDRs":["10.42.0.0/24","fd00::/64"]},
+"status":{"addresses":[{"type":"InternalIP","address":"192.168.1.21"},{"type":"Hostname","address":"node1"}]}}]}`))
+		case "/api/v1/services":
+			w.Write([]byte(`{"items":[
+{"metadata":{"name":"kubernetes","namespace":"default"},"spec":{"type":"ClusterIP","ports":[{"port":443}]}},
+{"metadata":{"name":"ingress","namespace":"web"},"spec":{"type":"LoadBalancer","ports":[{"port":80,"nodePort":30080}]},
+"status":{"loadBalancer":{"ingress":[{"ip":"192.168.1.240"}]}}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	c := &Client{ep: endpoint{server: srv.URL, token: "abc123"}, client: srv.Client()}
+
+	nodes, err := c.Nodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantNodes := []Node{{
+		Name:       "node1",
+		InternalIP: netip.MustParseAddr("192.168.1.21"),
+		PodCIDRs:   []netip.Prefix{netip.MustParsePrefix("10.42.0.0/24")},
+	}}
+	opts := cmpopts.EquateComparable(netip.Addr{}, netip.Prefix{})
+	if diff := cmp.Diff(wantNodes, nodes, opts); diff != "" {
+		t.Errorf("nodes (-want +got):\n%s", diff)
+	}
+
+	services, err := c.Services(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantServices := []Service{{
+		Namespace:       "web",
+		Name:            "ingress",
+		Type:            ServiceTypeLoadBalancer,
+		LoadBalancerIPs: []netip.Addr{netip.MustParseAddr("192.168.1.240")},
+		NodePorts:       []int{30080},
+	}}
+	if diff := cmp.Diff(wantServices, services, opts); diff != "" {
+		t.Errorf("services (-want +got):\n%s", diff)
+	}
+}
+
+func TestInventory(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	nodes := []Node{{
+		Name:       "node1",
+		InternalIP: netip.MustParseAddr("192.168.1.21"),
+		PodCIDRs:   []netip.Prefix{netip.MustParsePrefix("10.42.0.0/24")},
+	}}
+	lb := Service{
+		Namespace:       "web",
+		Name:            "ingress",
+		Type:            ServiceTypeLoadBalancer,
+		LoadBalancerIPs: []netip.Addr{netip.MustParseAddr("192.168.1.240")},
+	}
+	nodeDevice := model.Device{
+		Name:         "node1",
+		Addr:         model.MustParseAddr("192.168.1.21"),
+		DiscoveredBy: discovery.KubernetesDiscoverySource,
+		DiscoveredAt: now,
+		Meta:         model.Meta{Tags: model.Tags{KubernetesTag, NodeTag}},
+	}
+	lbDevice := model.Device{
+		Name:         "web/ingress",
+		Addr:         model.MustParseAddr("192.168.1.240"),
+		DiscoveredBy: discovery.KubernetesDiscoverySource,
+		DiscoveredAt: now,
+		Meta:         model.Meta{Tags: model.Tags{KubernetesTag, LoadBalancerTag}},
+	}
+	nodePortDevice := nodeDevice
+	nodePortDevice.Meta = model.Meta{Tags: model.Tags{KubernetesTag, NodeTag, NodePortTag}}
+	podNetwork, _ := model.New("pods node1", "10.42.0.0/24")
+	podNetwork.Tags = model.Tags{KubernetesTag}
+
+	tests := map[string]struct {
+		services     []Service
+		podNetworks  bool
+		wantDevices  []model.Device
+		wantNetworks []model.Network
+	}{
+		"LoadBalancer": {
+			services:     []Service{lb},
+			wantDevices:  []model.Device{nodeDevice, lbDevice},
+			wantNetworks: []model.Network{},
+		},
+		"NodePort": {
+			services: []Service{{
+				Namespace: "web",
+				Name:      "api",
+				Type:      ServiceTypeNodePort,
+				NodePorts: []int{30443},
+			}},
+			wantDevices:  []model.Device{nodePortDevice},
+			wantNetworks: []model.Network{},
+		},
+		"PodNetworks": {
+			podNetworks:  true,
+			wantDevices:  []model.Device{nodeDevice},
+			wantNetworks: []model.Network{podNetwork},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			devices, networks := Inventory(nodes, tc.services, tc.podNetworks, now)
+			opts := cmp.Options{
+				cmpopts.EquateComparable(netip.Addr{}, netip.Prefix{}),
+				cmpopts.IgnoreUnexported(model.Device{}),
+			}
+			if diff := cmp.Diff(tc.wantDevices, devices, opts); diff != "" {
+				t.Errorf("devices (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantNetworks, networks, opts); diff != "" {
+				t.Errorf("networks (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
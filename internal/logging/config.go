@@ -0,0 +1,82 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/networkables/mason/internal/flagset"
+)
+
+type Config struct {
+	Level     string
+	Discovery string
+	Pinger    string
+	NetFlows  string
+	WUI       string
+	Store     string
+	Probes    []string
+}
+
+func SetFlags(fs *pflag.FlagSet, cfg *Config) {
+	configMajorKey := "logging"
+
+	flagset.String(
+		fs,
+		&cfg.Level,
+		configMajorKey,
+		"level",
+		"info",
+		"global log level (debug, info, warn, error), --debug sets debug",
+	)
+	flagset.String(
+		fs,
+		&cfg.Discovery,
+		configMajorKey,
+		"discovery",
+		"",
+		"log level of discovery, blank follows the global level",
+	)
+	flagset.String(
+		fs,
+		&cfg.Pinger,
+		configMajorKey,
+		"pinger",
+		"",
+		"log level of the pinger, blank follows the global level",
+	)
+	flagset.String(
+		fs,
+		&cfg.NetFlows,
+		configMajorKey,
+		"netflows",
+		"",
+		"log level of the netflow collector, blank follows the global level",
+	)
+	flagset.String(
+		fs,
+		&cfg.WUI,
+		configMajorKey,
+		"wui",
+		"",
+		"log level of the web ui and api, blank follows the global level",
+	)
+	flagset.String(
+		fs,
+		&cfg.Store,
+		configMajorKey,
+		"store",
+		"",
+		"log level of the datastores, blank follows the global level",
+	)
+	flagset.StringSlice(
+		fs,
+		&cfg.Probes,
+		configMajorKey,
+		"probes",
+		[]string{},
+		"subsystems whose probes are traced with their target, type, rtt and error (discovery, pinger)",
+	)
+}
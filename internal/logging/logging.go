@@ -0,0 +1,191 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package logging holds a logger per subsystem so each can be given its own level, along with
+// the probe traces of the subsystems which send probes.  Levels and traces can be changed while
+// the server runs.
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+const (
+	Discovery = "discovery"
+	Pinger    = "pinger"
+	NetFlows  = "netflows"
+	WUI       = "wui"
+	Store     = "store"
+)
+
+// Subsystems lists the subsystems with their own logger
+var Subsystems = []string{Discovery, Pinger, NetFlows, WUI, Store}
+
+var ErrUnknownSubsystem = errors.New("unknown log subsystem")
+
+// SubsystemLevel is the level of a subsystem and whether its probes are traced
+type SubsystemLevel struct {
+	Subsystem string
+	Level     string
+	Probes    bool
+}
+
+// Change sets the level and or the probe traces of a subsystem, a blank subsystem being the
+// global level
+type Change struct {
+	Subsystem string
+	Level     string
+	Probes    *bool
+}
+
+var registry = struct {
+	sync.RWMutex
+	loggers map[string]*log.Logger
+	// own are the subsystems with a level of their own, the others follow the global level
+	own    map[string]bool
+	probes map[string]bool
+}{
+	loggers: make(map[string]*log.Logger),
+	own:     make(map[string]bool),
+	probes:  make(map[string]bool),
+}
+
+// For returns the logger of the subsystem, its records are prefixed with the subsystem name
+func For(subsystem string) *log.Logger {
+	registry.Lock()
+	defer registry.Unlock()
+	l, ok := registry.loggers[subsystem]
+	if !ok {
+		l = log.Default().WithPrefix(subsystem)
+		registry.loggers[subsystem] = l
+	}
+	return l
+}
+
+// Apply sets the global level and the levels and probe traces of the subsystems from the
+// configuration, a subsystem without a level follows the global one
+func Apply(cfg *Config) error {
+	if cfg.Level != "" {
+		err := SetLevel("", cfg.Level)
+		if err != nil {
+			return err
+		}
+	}
+	levels := map[string]string{
+		Discovery: cfg.Discovery,
+		Pinger:    cfg.Pinger,
+		NetFlows:  cfg.NetFlows,
+		WUI:       cfg.WUI,
+		Store:     cfg.Store,
+	}
+	for _, s := range Subsystems {
+		if levels[s] == "" {
+			continue
+		}
+		err := SetLevel(s, levels[s])
+		if err != nil {
+			return err
+		}
+	}
+	for _, s := range cfg.Probes {
+		err := SetProbes(s, true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetLevel sets the level of the subsystem, a blank subsystem sets the global level along with
+// the subsystems following it
+func SetLevel(subsystem string, level string) error {
+	lvl, err := log.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	if subsystem == "" {
+		log.SetLevel(lvl)
+		for _, s := range Subsystems {
+			registry.RLock()
+			own := registry.own[s]
+			registry.RUnlock()
+			if !own {
+				For(s).SetLevel(lvl)
+			}
+		}
+		return nil
+	}
+	if !slices.Contains(Subsystems, subsystem) {
+		return fmt.Errorf("%w: %s", ErrUnknownSubsystem, subsystem)
+	}
+	For(subsystem).SetLevel(lvl)
+	registry.Lock()
+	registry.own[subsystem] = true
+	registry.Unlock()
+	return nil
+}
+
+// SetProbes turns the probe traces of the subsystem on or off
+func SetProbes(subsystem string, enabled bool) error {
+	if !slices.Contains(Subsystems, subsystem) {
+		return fmt.Errorf("%w: %s", ErrUnknownSubsystem, subsystem)
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	registry.probes[subsystem] = enabled
+	return nil
+}
+
+// Apply makes the change, the level is left as it is when blank
+func (c Change) Apply() error {
+	if c.Level != "" {
+		err := SetLevel(c.Subsystem, c.Level)
+		if err != nil {
+			return err
+		}
+	}
+	if c.Probes != nil {
+		return SetProbes(c.Subsystem, *c.Probes)
+	}
+	return nil
+}
+
+// Levels returns the global level as a blank subsystem followed by each subsystem
+func Levels() []SubsystemLevel {
+	levels := []SubsystemLevel{{Level: log.GetLevel().String()}}
+	for _, s := range Subsystems {
+		l := For(s)
+		registry.RLock()
+		probes := registry.probes[s]
+		registry.RUnlock()
+		levels = append(levels, SubsystemLevel{
+			Subsystem: s,
+			Level:     l.GetLevel().String(),
+			Probes:    probes,
+		})
+	}
+	return levels
+}
+
+// Probe traces a probe sent by the subsystem when its traces are on.  The traces are written
+// at info level so turning them on does not also need the subsystem lowered to debug.
+func Probe(subsystem string, target any, probe string, rtt time.Duration, err error) {
+	registry.RLock()
+	enabled := registry.probes[subsystem]
+	registry.RUnlock()
+	if !enabled {
+		return
+	}
+	kv := []any{"target", target, "probe", probe, "rtt", rtt}
+	if err != nil {
+		kv = append(kv, "error", err)
+	}
+	For(subsystem).Info("probe", kv...)
+}
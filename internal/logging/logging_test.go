@@ -0,0 +1,104 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-cmp/cmp"
+)
+
+func resetRegistry(t *testing.T) {
+	t.Helper()
+	level := log.GetLevel()
+	t.Cleanup(func() {
+		log.SetLevel(level)
+		registry.Lock()
+		registry.loggers = make(map[string]*log.Logger)
+		registry.own = make(map[string]bool)
+		registry.probes = make(map[string]bool)
+		registry.Unlock()
+	})
+}
+
+func TestApply(t *testing.T) {
+	resetRegistry(t)
+	err := Apply(&Config{Level: "warn", Pinger: "debug", Probes: []string{Discovery}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SubsystemLevel{
+		{Level: "warn"},
+		{Subsystem: Discovery, Level: "warn", Probes: true},
+		{Subsystem: Pinger, Level: "debug"},
+		{Subsystem: NetFlows, Level: "warn"},
+		{Subsystem: WUI, Level: "warn"},
+		{Subsystem: Store, Level: "warn"},
+	}
+	if diff := cmp.Diff(want, Levels()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// the global level leaves the subsystems with their own level be
+	err = SetLevel("", "error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := For(Pinger).GetLevel(); got != log.DebugLevel {
+		t.Errorf("pinger level want: debug, got: %s", got)
+	}
+	if got := For(WUI).GetLevel(); got != log.ErrorLevel {
+		t.Errorf("wui level want: error, got: %s", got)
+	}
+}
+
+func TestChange_Apply(t *testing.T) {
+	on := true
+	tests := map[string]struct {
+		change Change
+		err    error
+	}{
+		"Level":            {change: Change{Subsystem: Store, Level: "debug"}},
+		"Probes":           {change: Change{Subsystem: Pinger, Probes: &on}},
+		"Global":           {change: Change{Level: "info"}},
+		"UnknownSubsystem": {change: Change{Subsystem: "nope", Level: "debug"}, err: ErrUnknownSubsystem},
+		"UnknownLevel":     {change: Change{Subsystem: Store, Level: "loud"}, err: log.ErrInvalidLevel},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			resetRegistry(t)
+			err := tc.change.Apply()
+			if !errors.Is(err, tc.err) {
+				t.Errorf("error want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestProbe(t *testing.T) {
+	resetRegistry(t)
+	var buf bytes.Buffer
+	For(Discovery).SetOutput(&buf)
+
+	Probe(Discovery, "10.0.0.1", "icmp echo", time.Millisecond, nil)
+	if buf.Len() != 0 {
+		t.Fatalf("trace written while off: %s", buf.String())
+	}
+	err := SetProbes(Discovery, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Probe(Discovery, "10.0.0.1", "icmp echo", time.Millisecond, errors.New("timeout"))
+	for _, want := range []string{"discovery", "target=10.0.0.1", "icmp echo", "rtt=1ms", "error=timeout"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("trace %q missing %q", buf.String(), want)
+		}
+	}
+}
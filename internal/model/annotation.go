@@ -0,0 +1,67 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type AnnotationKind string
+
+const (
+	AnnotationRediscovered AnnotationKind = "rediscovered"
+	AnnotationOffline      AnnotationKind = "offline"
+	AnnotationPortsChanged AnnotationKind = "portschanged"
+	AnnotationMACChanged   AnnotationKind = "macchanged"
+	AnnotationRouteChanged AnnotationKind = "routechanged"
+	AnnotationAddrChanged  AnnotationKind = "addrchanged"
+	AnnotationMaintenance  AnnotationKind = "maintenance"
+	AnnotationFlowAnomaly  AnnotationKind = "flowanomaly"
+	AnnotationQuota        AnnotationKind = "quota"
+)
+
+// Annotation marks a point in time where something happened that may explain a change
+// in the timeseries data of a device.  An annotation with an invalid Addr applies to all devices.
+type Annotation struct {
+	Time time.Time
+	Addr Addr
+	Kind AnnotationKind
+	Text string
+}
+
+// IsGlobal reports if the annotation is not tied to a single device
+func (a Annotation) IsGlobal() bool {
+	return !a.Addr.Addr().IsValid()
+}
+
+// DeviceChangeAnnotations compares the stored device against its update and returns
+// an annotation for each notable change
+func DeviceChangeAnnotations(prev Device, next Device, ts time.Time) []Annotation {
+	ret := make([]Annotation, 0)
+	add := func(kind AnnotationKind, text string) {
+		ret = append(ret, Annotation{Time: ts, Addr: prev.Addr, Kind: kind, Text: text})
+	}
+
+	if !prev.PerformancePing.LastFailed && next.PerformancePing.LastFailed {
+		add(AnnotationOffline, "device stopped responding to ping")
+	}
+	if prev.PerformancePing.LastFailed && !next.PerformancePing.LastFailed {
+		add(AnnotationRediscovered, "device is responding again")
+	}
+	if !prev.MAC.IsEmpty() && !next.MAC.IsEmpty() && prev.MAC.Compare(next.MAC) != 0 {
+		add(AnnotationMACChanged, fmt.Sprintf("mac changed from %s to %s", prev.MAC, next.MAC))
+	}
+	if !prev.Server.LastScan.IsZero() && next.Server.LastScan.After(prev.Server.LastScan) &&
+		!cmp.Equal(prev.Server.Ports, next.Server.Ports) {
+		add(
+			AnnotationPortsChanged,
+			fmt.Sprintf("open ports changed from %s to %s", prev.Server.Ports, next.Server.Ports),
+		)
+	}
+	return ret
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeviceChangeAnnotations(t *testing.T) {
+	now := time.Now()
+	base := Device{
+		Addr:   MustParseAddr("192.168.1.1"),
+		MAC:    MustParseMAC("00:11:22:33:44:55"),
+		Server: Server{Ports: PortList{Ports: []int{22}}, LastScan: now.Add(-time.Hour)},
+	}
+	tests := map[string]struct {
+		next func(Device) Device
+		want []AnnotationKind
+	}{
+		"NoChange": {
+			next: func(d Device) Device { return d },
+			want: []AnnotationKind{},
+		},
+		"Offline": {
+			next: func(d Device) Device {
+				d.PerformancePing.LastFailed = true
+				return d
+			},
+			want: []AnnotationKind{AnnotationOffline},
+		},
+		"MACAndPorts": {
+			next: func(d Device) Device {
+				d.MAC = MustParseMAC("00:11:22:33:44:66")
+				d.Server = Server{Ports: PortList{Ports: []int{22, 80}}, LastScan: now}
+				return d
+			},
+			want: []AnnotationKind{AnnotationMACChanged, AnnotationPortsChanged},
+		},
+		"PortsWithoutNewScan": {
+			next: func(d Device) Device {
+				d.Server = Server{}
+				return d
+			},
+			want: []AnnotationKind{},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := DeviceChangeAnnotations(base, tc.next(base), now)
+			if len(got) != len(tc.want) {
+				t.Fatalf("count want: %d, got %d (%v)", len(tc.want), len(got), got)
+			}
+			for i, a := range got {
+				if a.Kind != tc.want[i] {
+					t.Errorf("kind want: %s, got %s", tc.want[i], a.Kind)
+				}
+				if a.Addr.Compare(base.Addr) != 0 {
+					t.Errorf("addr want: %s, got %s", base.Addr, a.Addr)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+)
+
+// ApprovalState records if an operator has reviewed a device.  Devices stored before
+// approval states existed have an empty state and are treated as approved.
+type ApprovalState string
+
+const (
+	ApprovalUnknown  ApprovalState = "unknown"
+	ApprovalApproved ApprovalState = "approved"
+	ApprovalBlocked  ApprovalState = "blocked"
+)
+
+var ErrInvalidApprovalState = errors.New("invalid approval state")
+
+// ApprovalStates lists the valid approval states
+var ApprovalStates = []ApprovalState{ApprovalUnknown, ApprovalApproved, ApprovalBlocked}
+
+// ParseApprovalState converts the string into an ApprovalState
+func ParseApprovalState(s string) (ApprovalState, error) {
+	switch ApprovalState(s) {
+	case ApprovalUnknown, ApprovalApproved, ApprovalBlocked:
+		return ApprovalState(s), nil
+	}
+	return "", ErrInvalidApprovalState
+}
+
+// Approval returns the approval state of the device
+func (d Device) Approval() ApprovalState {
+	if d.Meta.Approval == "" {
+		return ApprovalApproved
+	}
+	return d.Meta.Approval
+}
+
+// ApprovalDeviceFilter selects devices in the approval state
+func ApprovalDeviceFilter(state ApprovalState) DeviceFilter {
+	return func(d Device) bool {
+		return d.Approval() == state
+	}
+}
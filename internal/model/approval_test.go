@@ -0,0 +1,49 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseApprovalState(t *testing.T) {
+	for _, state := range ApprovalStates {
+		got, err := ParseApprovalState(string(state))
+		if err != nil || got != state {
+			t.Errorf("%s want: %s, got: %s (%v)", state, state, got, err)
+		}
+	}
+	_, err := ParseApprovalState("hostile")
+	if !errors.Is(err, ErrInvalidApprovalState) {
+		t.Errorf("invalid want: %v, got: %v", ErrInvalidApprovalState, err)
+	}
+}
+
+func TestApprovalDeviceFilter(t *testing.T) {
+	legacy := Device{}
+	unknown := Device{Meta: Meta{Approval: ApprovalUnknown}}
+	blocked := Device{Meta: Meta{Approval: ApprovalBlocked}}
+
+	tests := map[string]struct {
+		state ApprovalState
+		d     Device
+		want  bool
+	}{
+		"LegacyIsApproved":  {state: ApprovalApproved, d: legacy, want: true},
+		"LegacyNotUnknown":  {state: ApprovalUnknown, d: legacy, want: false},
+		"Unknown":           {state: ApprovalUnknown, d: unknown, want: true},
+		"BlockedNotUnknown": {state: ApprovalUnknown, d: blocked, want: false},
+		"Blocked":           {state: ApprovalBlocked, d: blocked, want: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := ApprovalDeviceFilter(tc.state)(tc.d)
+			if got != tc.want {
+				t.Errorf("want: %t, got: %t", tc.want, got)
+			}
+		})
+	}
+}
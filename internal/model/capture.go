@@ -0,0 +1,101 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+type (
+	// PacketCaptureRequest asks for a capture of the frames on an interface matching the
+	// filter, it stops after the duration or once the file reaches MaxBytes
+	PacketCaptureRequest struct {
+		Interface string
+		Filter    string
+		Duration  time.Duration
+		MaxBytes  int64
+	}
+
+	// PacketCapture is a requested capture and the pcap file it wrote, Err is why it failed
+	PacketCapture struct {
+		ID        string
+		Interface string
+		Filter    string
+		Duration  time.Duration
+		MaxBytes  int64
+		Started   time.Time
+		Finished  time.Time
+		State     CaptureState
+		Packets   int
+		Bytes     int64
+		Err       string
+	}
+
+	CaptureState string
+)
+
+const (
+	CaptureRunning CaptureState = "running"
+	CaptureDone    CaptureState = "done"
+	CaptureStopped CaptureState = "stopped"
+	CaptureFailed  CaptureState = "failed"
+)
+
+var (
+	ErrCaptureDoesNotExist       = errors.New("packet capture does not exist")
+	ErrCaptureInterfaceRequired  = errors.New("packet capture interface is required")
+	ErrInvalidCaptureDuration    = errors.New("invalid packet capture duration")
+	ErrInvalidCaptureMaxBytes    = errors.New("invalid packet capture size")
+	ErrCaptureNotRunning         = errors.New("packet capture is not running")
+	ErrCaptureStillRunning       = errors.New("packet capture is still running")
+	ErrTooManyCapturesRunning    = errors.New("too many packet captures running")
+	ErrCaptureInterfaceNotExists = errors.New("packet capture interface does not exist")
+)
+
+// Bound validates the request against the largest duration and size allowed, a zero duration
+// or size is raised to the largest allowed
+func (r PacketCaptureRequest) Bound(
+	maxDuration time.Duration,
+	maxBytes int64,
+) (PacketCaptureRequest, error) {
+	if r.Interface == "" {
+		return r, ErrCaptureInterfaceRequired
+	}
+	if r.Duration == 0 {
+		r.Duration = maxDuration
+	}
+	if r.Duration < 0 || r.Duration > maxDuration {
+		return r, fmt.Errorf("%w: must be at most %s", ErrInvalidCaptureDuration, maxDuration)
+	}
+	if r.MaxBytes == 0 {
+		r.MaxBytes = maxBytes
+	}
+	if r.MaxBytes < 0 || r.MaxBytes > maxBytes {
+		return r, fmt.Errorf(
+			"%w: must be at most %s",
+			ErrInvalidCaptureMaxBytes,
+			humanize.Bytes(uint64(maxBytes)),
+		)
+	}
+	return r, nil
+}
+
+// Filename is the name of the pcap file of the capture
+func (c PacketCapture) Filename() string {
+	return c.ID + ".pcap"
+}
+
+// Size returns the size of the file as a readable size
+func (c PacketCapture) Size() string {
+	return humanize.Bytes(uint64(c.Bytes))
+}
+
+func (c PacketCapture) String() string {
+	return fmt.Sprintf("%s on %s %s %d packets", c.ID, c.Interface, c.State, c.Packets)
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPacketCaptureRequest_Bound(t *testing.T) {
+	const (
+		maxDuration = 5 * time.Minute
+		maxBytes    = 1 << 20
+	)
+	tests := map[string]struct {
+		req     PacketCaptureRequest
+		want    PacketCaptureRequest
+		wantErr error
+	}{
+		"Defaults": {
+			req:  PacketCaptureRequest{Interface: "eth0"},
+			want: PacketCaptureRequest{Interface: "eth0", Duration: maxDuration, MaxBytes: maxBytes},
+		},
+		"WithinLimits": {
+			req:  PacketCaptureRequest{Interface: "eth0", Duration: time.Minute, MaxBytes: 1000},
+			want: PacketCaptureRequest{Interface: "eth0", Duration: time.Minute, MaxBytes: 1000},
+		},
+		"NoInterface": {
+			req:     PacketCaptureRequest{Duration: time.Minute},
+			wantErr: ErrCaptureInterfaceRequired,
+		},
+		"LongDuration": {
+			req:     PacketCaptureRequest{Interface: "eth0", Duration: time.Hour},
+			wantErr: ErrInvalidCaptureDuration,
+		},
+		"NegativeDuration": {
+			req:     PacketCaptureRequest{Interface: "eth0", Duration: -time.Second},
+			wantErr: ErrInvalidCaptureDuration,
+		},
+		"LargeSize": {
+			req:     PacketCaptureRequest{Interface: "eth0", MaxBytes: maxBytes + 1},
+			wantErr: ErrInvalidCaptureMaxBytes,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := tc.req.Bound(maxDuration, maxBytes)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("want err: %v, got: %v", tc.wantErr, err)
+			}
+			if tc.wantErr == nil && got != tc.want {
+				t.Errorf("want: %+v, got: %+v", tc.want, got)
+			}
+		})
+	}
+}
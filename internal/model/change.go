@@ -0,0 +1,110 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"strconv"
+	"time"
+)
+
+// ChangeSource identifies what produced a device change
+type ChangeSource string
+
+const (
+	ChangeSourceDiscovery  ChangeSource = "discovery"
+	ChangeSourceEnrichment ChangeSource = "enrichment"
+	ChangeSourceImport     ChangeSource = "import"
+	ChangeSourcePinger     ChangeSource = "pinger"
+	ChangeSourceUpdate     ChangeSource = "update"
+	ChangeSourceUser       ChangeSource = "user"
+)
+
+// DeviceChange records a single field of a device changing value
+type DeviceChange struct {
+	Time   time.Time
+	Addr   Addr
+	Field  string
+	Old    string
+	New    string
+	Source ChangeSource
+}
+
+// changeFields are the device fields tracked in the change history.  Timestamps and
+// ping measurements which change on every check are left out.
+var changeFields = []struct {
+	name  string
+	value func(Device) string
+}{
+	{"Name", func(d Device) string { return d.Name }},
+	{"MAC", func(d Device) string { return d.MAC.String() }},
+	{"Addresses", func(d Device) string { return d.Addresses.AddrsString() }},
+	{"DiscoveredBy", func(d Device) string { return d.DiscoveredBy.String() }},
+	{"VLAN", func(d Device) string { return d.VLAN.String() }},
+	{"DnsName", func(d Device) string { return d.Meta.DnsName }},
+	{"Manufacturer", func(d Device) string { return d.Meta.Manufacturer }},
+	{"MDNSName", func(d Device) string { return d.Meta.MDNSName }},
+	{"DHCPHostname", func(d Device) string { return d.Meta.DHCPHostname }},
+	{"Tags", func(d Device) string { return d.Meta.Tags.String() }},
+	{"Approval", func(d Device) string { return string(d.Approval()) }},
+	{"Owner", func(d Device) string { return d.Meta.Owner }},
+	{"Notes", func(d Device) string { return d.Meta.Notes }},
+	{"Location", func(d Device) string { return d.Meta.Location }},
+	{"PurchaseDate", func(d Device) string { return d.Meta.PurchaseDate }},
+	{"Serial", func(d Device) string { return d.Meta.Serial }},
+	{"AssetTag", func(d Device) string { return d.Meta.AssetTag }},
+	{"Site", func(d Device) string { return d.Meta.Site }},
+	{"PingInterval", func(d Device) string {
+		return durationChangeString(d.Meta.Policy.PingInterval)
+	}},
+	{"PortScanInterval", func(d Device) string {
+		return durationChangeString(d.Meta.Policy.PortScanInterval)
+	}},
+	{"Ports", func(d Device) string { return d.Server.Ports.String() }},
+	{"Offline", func(d Device) string {
+		return strconv.FormatBool(d.PerformancePing.LastFailed)
+	}},
+	{"SNMPName", func(d Device) string { return d.SNMP.Name }},
+	{"SNMPDescription", func(d Device) string { return d.SNMP.Description }},
+	{"SNMPPort", func(d Device) string { return intChangeString(d.SNMP.Port) }},
+	{"SNMPArpTable", func(d Device) string { return strconv.FormatBool(d.SNMP.HasArpTable) }},
+	{"SNMPInterfaces", func(d Device) string {
+		return strconv.FormatBool(d.SNMP.HasInterfaces)
+	}},
+}
+
+// DeviceChanges compares the stored device against its merged update and returns a
+// change for each tracked field which differs
+func DeviceChanges(prev Device, next Device, source ChangeSource, ts time.Time) []DeviceChange {
+	ret := make([]DeviceChange, 0)
+	for _, f := range changeFields {
+		o, n := f.value(prev), f.value(next)
+		if o == n {
+			continue
+		}
+		ret = append(ret, DeviceChange{
+			Time:   ts,
+			Addr:   prev.Addr,
+			Field:  f.name,
+			Old:    o,
+			New:    n,
+			Source: source,
+		})
+	}
+	return ret
+}
+
+func durationChangeString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+func intChangeString(i int) string {
+	if i == 0 {
+		return ""
+	}
+	return strconv.Itoa(i)
+}
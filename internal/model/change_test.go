@@ -0,0 +1,76 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeviceChanges(t *testing.T) {
+	now := time.Now()
+	base := Device{
+		Name:   "router",
+		Addr:   MustParseAddr("192.168.1.1"),
+		MAC:    MustParseMAC("00:11:22:33:44:55"),
+		Meta:   Meta{DnsName: "router"},
+		Server: Server{Ports: PortList{Ports: []int{22}}, LastScan: now.Add(-time.Hour)},
+	}
+	tests := map[string]struct {
+		next func(Device) Device
+		want []DeviceChange
+	}{
+		"NoChange": {
+			next: func(d Device) Device { return d },
+			want: []DeviceChange{},
+		},
+		"Measurements": {
+			next: func(d Device) Device {
+				d.PerformancePing.LastSeen = now
+				d.PerformancePing.Mean = time.Millisecond
+				d.Server.LastScan = now
+				return d
+			},
+			want: []DeviceChange{},
+		},
+		"MACAndDns": {
+			next: func(d Device) Device {
+				d.MAC = MustParseMAC("00:11:22:33:44:66")
+				d.Meta.DnsName = "gateway"
+				return d
+			},
+			want: []DeviceChange{
+				{Field: "MAC", Old: "00:11:22:33:44:55", New: "00:11:22:33:44:66"},
+				{Field: "DnsName", Old: "router", New: "gateway"},
+			},
+		},
+		"Ports": {
+			next: func(d Device) Device {
+				d.Server.Ports = PortList{Ports: []int{22, 80}}
+				return d
+			},
+			want: []DeviceChange{{Field: "Ports", Old: "22", New: "22 80"}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := DeviceChanges(base, tc.next(base), ChangeSourceEnrichment, now)
+			if len(got) != len(tc.want) {
+				t.Fatalf("count want: %d, got %d (%v)", len(tc.want), len(got), got)
+			}
+			for i, c := range got {
+				want := tc.want[i]
+				if c.Field != want.Field || c.Old != want.Old || c.New != want.New {
+					t.Errorf("change want: %v, got %v", want, c)
+				}
+				if c.Source != ChangeSourceEnrichment || !c.Time.Equal(now) ||
+					c.Addr.Compare(base.Addr) != 0 {
+					t.Errorf("change metadata mismatch: %v", c)
+				}
+			}
+		})
+	}
+}
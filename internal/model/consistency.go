@@ -0,0 +1,34 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import "fmt"
+
+type ConsistencyCheck string
+
+const (
+	ConsistencyOrphanedTimeseries ConsistencyCheck = "orphanedtimeseries"
+	ConsistencyMissingTimeseries  ConsistencyCheck = "missingtimeseries"
+	ConsistencyUnknownAsn         ConsistencyCheck = "unknownasn"
+)
+
+// ConsistencyIssue is a record in the store which references data that does not exist.  Ref
+// identifies the record (an addr, a filename or an asn) and Action describes the repair done
+// when Repaired is set.
+type ConsistencyIssue struct {
+	Check    ConsistencyCheck
+	Ref      string
+	Detail   string
+	Repaired bool
+	Action   string
+}
+
+func (i ConsistencyIssue) String() string {
+	s := fmt.Sprintf("%s %s: %s", i.Check, i.Ref, i.Detail)
+	if i.Repaired {
+		s += " (" + i.Action + ")"
+	}
+	return s
+}
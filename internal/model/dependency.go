@@ -0,0 +1,145 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import "errors"
+
+type DependencyScope string
+
+const (
+	DependencyScopeDevice  DependencyScope = "device"
+	DependencyScopeTag     DependencyScope = "tag"
+	DependencyScopeNetwork DependencyScope = "network"
+)
+
+var (
+	ErrDependencyDoesNotExist   = errors.New("dependency does not exist")
+	ErrInvalidDependencyScope   = errors.New("invalid dependency scope")
+	ErrDependencyParentRequired = errors.New("dependency parent is required")
+	ErrDependencyOnItself       = errors.New("device can not depend on itself")
+)
+
+// ParseDependencyScope converts the string into a DependencyScope
+func ParseDependencyScope(s string) (DependencyScope, error) {
+	switch DependencyScope(s) {
+	case DependencyScopeDevice, DependencyScopeTag, DependencyScopeNetwork:
+		return DependencyScope(s), nil
+	}
+	return "", ErrInvalidDependencyScope
+}
+
+// Dependency declares the devices in scope to be reached through the parent device, e.g. the
+// devices behind a switch or the networks behind a router.  Target is the device addr, the
+// tag name or the network name.
+type Dependency struct {
+	Name   string
+	Parent Addr
+	Scope  DependencyScope
+	Target string
+	Note   string
+}
+
+// Applies reports if the device is in the scope of the dependency, nets are used to resolve
+// network scoped dependencies.  The parent never depends on itself.
+func (dep Dependency) Applies(d Device, nets []Network) bool {
+	if d.Addr == dep.Parent {
+		return false
+	}
+	switch dep.Scope {
+	case DependencyScopeDevice:
+		return d.Addr.String() == dep.Target
+	case DependencyScopeTag:
+		return d.Meta.Tags.Has(dep.Target)
+	case DependencyScopeNetwork:
+		for _, n := range nets {
+			if n.Name == dep.Target && n.Contains(d) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Validate checks the dependency has a parent and does not make a device depend on itself
+func (dep Dependency) Validate() error {
+	if !dep.Parent.A.IsValid() {
+		return ErrDependencyParentRequired
+	}
+	if dep.Scope == DependencyScopeDevice && dep.Target == dep.Parent.String() {
+		return ErrDependencyOnItself
+	}
+	return nil
+}
+
+// Dependencies is the set of declared dependencies with what is needed to follow them
+type Dependencies struct {
+	deps    []Dependency
+	devices map[Addr]Device
+	nets    []Network
+}
+
+func NewDependencies(deps []Dependency, devices []Device, nets []Network) Dependencies {
+	byAddr := make(map[Addr]Device, len(devices))
+	for _, d := range devices {
+		byAddr[d.Addr] = d
+	}
+	return Dependencies{deps: deps, devices: byAddr, nets: nets}
+}
+
+// Parents are the known devices the device is declared to depend on
+func (ds Dependencies) Parents(d Device) []Device {
+	parents := make([]Device, 0)
+	for _, dep := range ds.deps {
+		if !dep.Applies(d, ds.nets) {
+			continue
+		}
+		if p, ok := ds.devices[dep.Parent]; ok {
+			parents = append(parents, p)
+		}
+	}
+	return parents
+}
+
+// UpstreamDown returns the device furthest up the chain of down parents of the device, the
+// likely cause of the device failing.  It is false when no parent of the device is down.
+func (ds Dependencies) UpstreamDown(d Device, down func(Device) bool) (Device, bool) {
+	seen := map[Addr]bool{d.Addr: true}
+	var (
+		cause Device
+		found bool
+	)
+	for {
+		p, ok := ds.nearestDown(d, down, seen)
+		if !ok {
+			return cause, found
+		}
+		cause, found, d = p, true, p
+	}
+}
+
+// nearestDown walks the parents of the device breadth first for the closest one which is
+// down, the devices walked are added to seen so a cycle of dependencies ends
+func (ds Dependencies) nearestDown(
+	d Device,
+	down func(Device) bool,
+	seen map[Addr]bool,
+) (Device, bool) {
+	queue := []Device{d}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, p := range ds.Parents(next) {
+			if seen[p.Addr] {
+				continue
+			}
+			seen[p.Addr] = true
+			if down(p) {
+				return p, true
+			}
+			queue = append(queue, p)
+		}
+	}
+	return Device{}, false
+}
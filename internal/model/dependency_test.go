@@ -0,0 +1,142 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDependency_Applies(t *testing.T) {
+	d := Device{
+		Addr: MustParseAddr("192.168.1.20"),
+		Meta: Meta{Tags: Tags{{Val: "printer"}}},
+	}
+	nets := []Network{
+		{Name: "office", Prefix: MustParsePrefix("192.168.1.0/24")},
+	}
+	sw, router := MustParseAddr("192.168.1.2"), MustParseAddr("192.168.1.1")
+	tests := map[string]struct {
+		dep  Dependency
+		want bool
+	}{
+		"Device": {
+			dep:  Dependency{Parent: sw, Scope: DependencyScopeDevice, Target: "192.168.1.20"},
+			want: true,
+		},
+		"Tag": {
+			dep:  Dependency{Parent: sw, Scope: DependencyScopeTag, Target: "printer"},
+			want: true,
+		},
+		"Network": {
+			dep:  Dependency{Parent: router, Scope: DependencyScopeNetwork, Target: "office"},
+			want: true,
+		},
+		"OtherNetwork": {
+			dep: Dependency{Parent: router, Scope: DependencyScopeNetwork, Target: "lab"},
+		},
+		"ParentItself": {
+			dep: Dependency{
+				Parent: MustParseAddr("192.168.1.20"),
+				Scope:  DependencyScopeNetwork,
+				Target: "office",
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.dep.Applies(d, nets); got != tc.want {
+				t.Errorf("applies want: %t, got: %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDependency_Validate(t *testing.T) {
+	err := Dependency{Scope: DependencyScopeTag, Target: "printer"}.Validate()
+	if !errors.Is(err, ErrDependencyParentRequired) {
+		t.Errorf("want %v, got %v", ErrDependencyParentRequired, err)
+	}
+	err = Dependency{
+		Parent: MustParseAddr("192.168.1.2"),
+		Scope:  DependencyScopeDevice,
+		Target: "192.168.1.2",
+	}.Validate()
+	if !errors.Is(err, ErrDependencyOnItself) {
+		t.Errorf("want %v, got %v", ErrDependencyOnItself, err)
+	}
+}
+
+func TestDependencies_UpstreamDown(t *testing.T) {
+	router := Device{Name: "router", Addr: MustParseAddr("192.168.1.1")}
+	sw := Device{Name: "switch", Addr: MustParseAddr("192.168.1.2")}
+	nas := Device{Name: "nas", Addr: MustParseAddr("192.168.1.20")}
+	nets := []Network{{Name: "office", Prefix: MustParsePrefix("192.168.1.0/24")}}
+	deps := []Dependency{
+		{Name: "office", Parent: router.Addr, Scope: DependencyScopeNetwork, Target: "office"},
+		{Name: "rack", Parent: sw.Addr, Scope: DependencyScopeDevice, Target: nas.Addr.String()},
+	}
+	ds := NewDependencies(deps, []Device{router, sw, nas}, nets)
+
+	tests := map[string]struct {
+		down   []Device
+		device Device
+		want   Device
+		found  bool
+	}{
+		"NothingDown": {device: nas},
+		"ParentDown": {
+			down:   []Device{sw},
+			device: nas,
+			want:   sw,
+			found:  true,
+		},
+		"GrandparentDown": {
+			down:   []Device{router},
+			device: nas,
+			want:   router,
+			found:  true,
+		},
+		"ChainDown": {
+			down:   []Device{router, sw},
+			device: nas,
+			want:   router,
+			found:  true,
+		},
+		"RootHasNoParent": {
+			down:   []Device{router},
+			device: router,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			down := func(d Device) bool {
+				for _, x := range tc.down {
+					if x.Addr == d.Addr {
+						return true
+					}
+				}
+				return false
+			}
+			got, found := ds.UpstreamDown(tc.device, down)
+			if found != tc.found || got.Addr != tc.want.Addr {
+				t.Errorf("want %s %t, got %s %t", tc.want.Addr, tc.found, got.Addr, found)
+			}
+		})
+	}
+}
+
+func TestDependencies_UpstreamDownCycle(t *testing.T) {
+	a := Device{Name: "a", Addr: MustParseAddr("10.0.0.1")}
+	b := Device{Name: "b", Addr: MustParseAddr("10.0.0.2")}
+	ds := NewDependencies([]Dependency{
+		{Parent: a.Addr, Scope: DependencyScopeDevice, Target: b.Addr.String()},
+		{Parent: b.Addr, Scope: DependencyScopeDevice, Target: a.Addr.String()},
+	}, []Device{a, b}, nil)
+	got, found := ds.UpstreamDown(a, func(Device) bool { return true })
+	if !found || got.Addr != b.Addr {
+		t.Errorf("want %s, got %s %t", b.Addr, got.Addr, found)
+	}
+}
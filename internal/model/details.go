@@ -0,0 +1,89 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxDeviceNotesLength is the longest notes text kept on a device
+const MaxDeviceNotesLength = 4096
+
+var (
+	ErrInvalidDeviceName   = errors.New("device name must not be empty")
+	ErrDeviceNotesTooLong  = errors.New("device notes are too long")
+	ErrInvalidPurchaseDate = errors.New("purchase date must be a yyyy-mm-dd date")
+)
+
+// DeviceDetails are the operator maintained fields of a device.  Unlike a discovery update
+// the details replace the stored values, so an empty owner or notes clears them.  An empty
+// site places the device by the network containing it.
+type DeviceDetails struct {
+	Name         string
+	Owner        string
+	Notes        string
+	Site         string
+	Location     string
+	PurchaseDate string
+	Serial       string
+	AssetTag     string
+}
+
+// Details returns the operator maintained fields of the device
+func (d Device) Details() DeviceDetails {
+	return DeviceDetails{
+		Name:         d.Name,
+		Owner:        d.Meta.Owner,
+		Notes:        d.Meta.Notes,
+		Site:         d.Meta.Site,
+		Location:     d.Meta.Location,
+		PurchaseDate: d.Meta.PurchaseDate,
+		Serial:       d.Meta.Serial,
+		AssetTag:     d.Meta.AssetTag,
+	}
+}
+
+// Clean trims the details and checks they can be stored
+func (dd DeviceDetails) Clean() (DeviceDetails, error) {
+	dd.Name = strings.TrimSpace(dd.Name)
+	dd.Owner = strings.TrimSpace(dd.Owner)
+	dd.Notes = strings.TrimSpace(dd.Notes)
+	dd.Site = strings.TrimSpace(dd.Site)
+	dd.Location = strings.TrimSpace(dd.Location)
+	dd.PurchaseDate = strings.TrimSpace(dd.PurchaseDate)
+	dd.Serial = strings.TrimSpace(dd.Serial)
+	dd.AssetTag = strings.TrimSpace(dd.AssetTag)
+	if dd.Name == "" {
+		return dd, ErrInvalidDeviceName
+	}
+	if dd.Site != "" && !ValidSiteName(dd.Site) {
+		return dd, ErrInvalidSiteName
+	}
+	if utf8.RuneCountInString(dd.Notes) > MaxDeviceNotesLength {
+		return dd, ErrDeviceNotesTooLong
+	}
+	if dd.PurchaseDate != "" {
+		if _, err := time.Parse(time.DateOnly, dd.PurchaseDate); err != nil {
+			return dd, ErrInvalidPurchaseDate
+		}
+	}
+	return dd, nil
+}
+
+// WithDetails returns the device with the details applied
+func (d Device) WithDetails(dd DeviceDetails) Device {
+	d.Name = dd.Name
+	d.Meta.Owner = dd.Owner
+	d.Meta.Notes = dd.Notes
+	d.Meta.Site = dd.Site
+	d.Meta.Location = dd.Location
+	d.Meta.PurchaseDate = dd.PurchaseDate
+	d.Meta.Serial = dd.Serial
+	d.Meta.AssetTag = dd.AssetTag
+	return d
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDeviceDetails_Clean(t *testing.T) {
+	tests := map[string]struct {
+		in      DeviceDetails
+		want    DeviceDetails
+		wantErr error
+	}{
+		"Trimmed": {
+			in:   DeviceDetails{Name: " nas ", Owner: " ops\n", Notes: "\nrack 2\n"},
+			want: DeviceDetails{Name: "nas", Owner: "ops", Notes: "rack 2"},
+		},
+		"EmptyOwnerAndNotes": {
+			in:   DeviceDetails{Name: "nas"},
+			want: DeviceDetails{Name: "nas"},
+		},
+		"EmptyName": {
+			in:      DeviceDetails{Name: "  ", Owner: "ops"},
+			want:    DeviceDetails{Owner: "ops"},
+			wantErr: ErrInvalidDeviceName,
+		},
+		"InvalidSite": {
+			in:      DeviceDetails{Name: "nas", Site: "head office"},
+			want:    DeviceDetails{Name: "nas", Site: "head office"},
+			wantErr: ErrInvalidSiteName,
+		},
+		"Asset": {
+			in: DeviceDetails{
+				Name:         "nas",
+				Location:     " rack 2 ",
+				PurchaseDate: "2023-04-01 ",
+				Serial:       " SN123",
+				AssetTag:     "IT-0042\n",
+			},
+			want: DeviceDetails{
+				Name:         "nas",
+				Location:     "rack 2",
+				PurchaseDate: "2023-04-01",
+				Serial:       "SN123",
+				AssetTag:     "IT-0042",
+			},
+		},
+		"InvalidPurchaseDate": {
+			in:      DeviceDetails{Name: "nas", PurchaseDate: "04/01/2023"},
+			want:    DeviceDetails{Name: "nas", PurchaseDate: "04/01/2023"},
+			wantErr: ErrInvalidPurchaseDate,
+		},
+		"NotesTooLong": {
+			in:      DeviceDetails{Name: "nas", Notes: strings.Repeat("x", MaxDeviceNotesLength+1)},
+			want:    DeviceDetails{Name: "nas", Notes: strings.Repeat("x", MaxDeviceNotesLength+1)},
+			wantErr: ErrDeviceNotesTooLong,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := tc.in.Clean()
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("error want: %v, got: %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Clean() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDevice_WithDetails(t *testing.T) {
+	d := Device{
+		Name: "old",
+		Addr: MustParseAddr("192.168.1.1"),
+		Meta: Meta{Owner: "ops", Notes: "rack 2", Manufacturer: "acme", Serial: "SN123"},
+	}
+	next := d.WithDetails(DeviceDetails{Name: "new"})
+	if diff := cmp.Diff(DeviceDetails{Name: "new"}, next.Details()); diff != "" {
+		t.Errorf("Details() mismatch (-want +got):\n%s", diff)
+	}
+	if next.Meta.Manufacturer != "acme" {
+		t.Errorf("manufacturer want: acme, got: %s", next.Meta.Manufacturer)
+	}
+	changes := DeviceChanges(d, next, ChangeSourceUser, d.DiscoveredAt)
+	fields := make([]string, 0, len(changes))
+	for _, c := range changes {
+		fields = append(fields, c.Field)
+	}
+	if diff := cmp.Diff([]string{"Name", "Owner", "Notes", "Serial"}, fields); diff != "" {
+		t.Errorf("DeviceChanges() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -18,16 +18,25 @@ import (
 
 type (
 	Device struct {
-		Name         string
-		Addr         Addr
-		MAC          MAC
+		Name string
+		Addr Addr
+		// Addresses holds the other addresses the device answers on, the IPv6 global and
+		// link-local addresses of a device found by its IPv4 address
+		Addresses DeviceAddrs
+		MAC       MAC
+		// ObservedMACs holds every MAC the device has been seen with once it has more than
+		// one, devices with a randomized MAC rotate it
+		ObservedMACs []MAC
 		DiscoveredAt time.Time
 		DiscoveredBy DiscoverySource
+		VLAN         VLAN
 
 		Meta            Meta
 		Server          Server
 		PerformancePing Pinger
 		SNMP            SNMP
+		Virtual         Virtual
+		Link            Link
 
 		updated bool
 	}
@@ -39,10 +48,29 @@ type (
 		DnsName      string
 		Manufacturer string
 		Tags         Tags
+		Policy       MonitoringPolicy
+		Approval     ApprovalState
+		Owner        string
+		Notes        string
+		Site         string
+		DeviceType   DeviceType
+
+		// asset register of the device, kept by the operator
+		Location     string
+		PurchaseDate string
+		Serial       string
+		AssetTag     string
+
+		// identity the device announces itself, kept to recognise it after a MAC change
+		MDNSName        string
+		DHCPHostname    string
+		DHCPFingerprint string
+		DHCPVendorClass string
 	}
 
 	Server struct {
 		Ports    PortList
+		Banners  Banners
 		LastScan time.Time
 	}
 
@@ -52,8 +80,14 @@ type (
 		Mean       time.Duration
 		Maximum    time.Duration
 		LastFailed bool
+		// Probe is how the device last answered, the fallbacks for devices dropping icmp
+		// measure a tcp connect or an arp reply instead of an echo
+		Probe PingProbe
 	}
 
+	// PingProbe is the kind of request a performance ping timed
+	PingProbe string
+
 	SNMP struct {
 		Name               string
 		Description        string
@@ -76,10 +110,22 @@ func (d Device) merge(in Device) (out Device, updated bool) {
 		d.Addr = in.Addr
 		updated = true
 	}
+	if addrs, ok := d.Addresses.merge(in.Addresses, d.Addr); ok {
+		d.Addresses = addrs
+		updated = true
+	}
 	if !in.MAC.IsEmpty() && d.MAC.String() != in.MAC.String() {
+		if !d.MAC.IsEmpty() {
+			d.ObservedMACs = unionMACs(d.MACs(), []MAC{in.MAC})
+		}
 		d.MAC = in.MAC
 		updated = true
 	}
+	if macs := unionMACs(d.MACs(), in.ObservedMACs); len(macs) > 1 &&
+		len(macs) > len(d.ObservedMACs) {
+		d.ObservedMACs = macs
+		updated = true
+	}
 	if !in.DiscoveredAt.IsZero() && !d.DiscoveredAt.Equal(in.DiscoveredAt) {
 		d.DiscoveredAt = in.DiscoveredAt
 		updated = true
@@ -88,6 +134,14 @@ func (d Device) merge(in Device) (out Device, updated bool) {
 		d.DiscoveredBy = in.DiscoveredBy
 		updated = true
 	}
+	if !in.VLAN.IsEmpty() && d.VLAN.ID != in.VLAN.ID {
+		d.VLAN = in.VLAN
+		updated = true
+	}
+	if in.VLAN.Name != "" && d.VLAN.ID == in.VLAN.ID && d.VLAN.Name != in.VLAN.Name {
+		d.VLAN.Name = in.VLAN.Name
+		updated = true
+	}
 	return d, updated
 }
 
@@ -104,14 +158,76 @@ func (m Meta) merge(in Meta) (out Meta, updated bool) {
 		m.Tags = slices.Clone(in.Tags)
 		updated = true
 	}
+	if !in.Policy.IsEmpty() && m.Policy != in.Policy {
+		m.Policy = in.Policy
+		updated = true
+	}
+	if in.Approval != "" && m.Approval != in.Approval {
+		m.Approval = in.Approval
+		updated = true
+	}
+	if in.Owner != "" && m.Owner != in.Owner {
+		m.Owner = in.Owner
+		updated = true
+	}
+	if in.Notes != "" && m.Notes != in.Notes {
+		m.Notes = in.Notes
+		updated = true
+	}
+	if in.Site != "" && m.Site != in.Site {
+		m.Site = in.Site
+		updated = true
+	}
+	if in.Location != "" && m.Location != in.Location {
+		m.Location = in.Location
+		updated = true
+	}
+	if in.PurchaseDate != "" && m.PurchaseDate != in.PurchaseDate {
+		m.PurchaseDate = in.PurchaseDate
+		updated = true
+	}
+	if in.Serial != "" && m.Serial != in.Serial {
+		m.Serial = in.Serial
+		updated = true
+	}
+	if in.AssetTag != "" && m.AssetTag != in.AssetTag {
+		m.AssetTag = in.AssetTag
+		updated = true
+	}
+	if in.MDNSName != "" && m.MDNSName != in.MDNSName {
+		m.MDNSName = in.MDNSName
+		updated = true
+	}
+	if in.DHCPHostname != "" && m.DHCPHostname != in.DHCPHostname {
+		m.DHCPHostname = in.DHCPHostname
+		updated = true
+	}
+	if in.DHCPFingerprint != "" && m.DHCPFingerprint != in.DHCPFingerprint {
+		m.DHCPFingerprint = in.DHCPFingerprint
+		updated = true
+	}
+	if in.DHCPVendorClass != "" && m.DHCPVendorClass != in.DHCPVendorClass {
+		m.DHCPVendorClass = in.DHCPVendorClass
+		updated = true
+	}
+	if in.DeviceType != "" && m.DeviceType != in.DeviceType {
+		m.DeviceType = in.DeviceType
+		updated = true
+	}
 	return m, updated
 }
 
 func (s Server) merge(in Server) (out Server, updated bool) {
-	if s.Ports.IsEmpty() && !cmp.Equal(s.Ports, in.Ports) {
+	// a newer scan replaces the ports, including when ports have closed
+	newScan := in.LastScan.After(s.LastScan)
+	if (s.Ports.IsEmpty() || newScan) && !cmp.Equal(s.Ports, in.Ports) {
 		s.Ports = in.Ports.Clone()
 		updated = true
 	}
+	if (len(s.Banners) == 0 || newScan) && !cmp.Equal(s.Banners, in.Banners) {
+		s.Banners = slices.Clone(in.Banners)
+		updated = true
+	}
 	if !in.LastScan.IsZero() && !s.LastScan.Equal(in.LastScan) {
 		s.LastScan = in.LastScan
 		updated = true
@@ -140,6 +256,10 @@ func (p Pinger) merge(in Pinger) (out Pinger, updated bool) {
 		p.LastFailed = in.LastFailed
 		updated = true
 	}
+	if in.Probe != "" && p.Probe != in.Probe {
+		p.Probe = in.Probe
+		updated = true
+	}
 	return p, updated
 }
 
@@ -183,6 +303,12 @@ func (s SNMP) merge(in SNMP) (out SNMP, updated bool) {
 	return s, updated
 }
 
+const (
+	PingProbeICMP PingProbe = "icmp"
+	PingProbeTCP  PingProbe = "tcp"
+	PingProbeARP  PingProbe = "arp"
+)
+
 var (
 	ErrDeviceExists       = errors.New("device exists")
 	ErrDeviceDoesNotExist = errors.New("device does not exists")
@@ -247,6 +373,7 @@ func (d Device) LastSeenDurString(f func(time.Time) time.Duration) string {
 	return dls
 }
 
+// LastPingMeanString is the mean of the last ping, a fallback probe is named after it
 func (d Device) LastPingMeanString() string {
 	if d.PerformancePing.FirstSeen.IsZero() {
 		return ""
@@ -254,7 +381,11 @@ func (d Device) LastPingMeanString() string {
 	if d.PerformancePing.LastFailed {
 		return "failure"
 	}
-	return d.PerformancePing.Mean.Round(50 * time.Microsecond).String()
+	mean := d.PerformancePing.Mean.Round(50 * time.Microsecond).String()
+	if d.PerformancePing.Probe == "" || d.PerformancePing.Probe == PingProbeICMP {
+		return mean
+	}
+	return mean + " (" + string(d.PerformancePing.Probe) + ")"
 }
 
 func (d Device) LastPingMaximumString() string {
@@ -283,13 +414,16 @@ func (d *Device) UpdateFromPingStats(stats nettools.Icmp4EchoResponseStatistics,
 }
 
 func (d Device) Merge(in Device) Device {
-	var baseUpdated, metaUpdated, serverUpdated, pingerUpdated, snmpUpdated bool
+	var baseUpdated, metaUpdated, serverUpdated, pingerUpdated, snmpUpdated, virtualUpdated, linkUpdated bool
 	d, baseUpdated = d.merge(in)
 	d.Meta, metaUpdated = d.Meta.merge(in.Meta)
 	d.Server, serverUpdated = d.Server.merge(in.Server)
 	d.PerformancePing, pingerUpdated = d.PerformancePing.merge(in.PerformancePing)
 	d.SNMP, snmpUpdated = d.SNMP.merge(in.SNMP)
-	d.updated = baseUpdated || metaUpdated || serverUpdated || pingerUpdated || snmpUpdated
+	d.Virtual, virtualUpdated = d.Virtual.merge(in.Virtual)
+	d.Link, linkUpdated = d.Link.merge(in.Link)
+	d.updated = baseUpdated || metaUpdated || serverUpdated || pingerUpdated || snmpUpdated ||
+		virtualUpdated || linkUpdated
 
 	if d.Name == "" || (d.IsNameAddr() && d.Meta.DnsName != "") {
 		d.Name = d.Addr.String()
@@ -300,6 +434,32 @@ func (d Device) Merge(in Device) Device {
 	return d
 }
 
+// MovedTo returns the device at the address of the newly discovered device, keeping its
+// history and settings.  A name taken from the old address follows the new one.
+func (d Device) MovedTo(next Device) Device {
+	if d.IsNameAddr() {
+		d.Name = next.Addr.String()
+	}
+	d.Addr = next.Addr
+	d.Addresses, _ = d.Addresses.merge(next.Addresses, d.Addr)
+	if !next.MAC.IsEmpty() {
+		if !d.MAC.IsEmpty() && d.MAC.Compare(next.MAC) != 0 {
+			d.ObservedMACs = unionMACs(d.MACs(), []MAC{next.MAC})
+		}
+		d.MAC = next.MAC
+	}
+	if !next.VLAN.IsEmpty() {
+		d.VLAN = next.VLAN
+	}
+	d.Meta, _ = d.Meta.merge(Meta{
+		MDNSName:        next.Meta.MDNSName,
+		DHCPHostname:    next.Meta.DHCPHostname,
+		DHCPFingerprint: next.Meta.DHCPFingerprint,
+		DHCPVendorClass: next.Meta.DHCPVendorClass,
+	})
+	return d
+}
+
 func (d Device) IsNameAddr() bool {
 	return d.Name == d.Addr.String()
 }
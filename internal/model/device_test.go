@@ -139,6 +139,16 @@ func TestDevice_MeanString(t *testing.T) {
 			input: Device{PerformancePing: Pinger{FirstSeen: firstseen, LastFailed: true}},
 			want:  "failure",
 		},
+		"arp": {
+			input: Device{
+				PerformancePing: Pinger{
+					FirstSeen: firstseen,
+					Mean:      2 * time.Millisecond,
+					Probe:     PingProbeARP,
+				},
+			},
+			want: "2ms (arp)",
+		},
 	}
 
 	for name, tc := range tests {
@@ -393,6 +403,21 @@ func TestDevice_ServerMerge(t *testing.T) {
 			},
 			wantUpdated: false,
 		},
+		"NewScan": {
+			starting: Server{
+				Ports:    IntSliceToPortList([]int{1, 2, 3}),
+				LastScan: ts,
+			},
+			in: Server{
+				Ports:    IntSliceToPortList([]int{2, 4}),
+				LastScan: ts.Add(time.Hour),
+			},
+			want: Server{
+				Ports:    IntSliceToPortList([]int{2, 4}),
+				LastScan: ts.Add(time.Hour),
+			},
+			wantUpdated: true,
+		},
 	}
 
 	for name, tc := range tests {
@@ -648,6 +673,24 @@ func TestDevice_BaseMerge(t *testing.T) {
 			},
 			wantUpdated: false,
 		},
+		"VLAN": {
+			starting:    Device{Addr: addr, VLAN: VLAN{ID: 10}},
+			in:          Device{Addr: addr, VLAN: VLAN{ID: 20, Name: "iot"}},
+			want:        Device{Addr: addr, VLAN: VLAN{ID: 20, Name: "iot"}},
+			wantUpdated: true,
+		},
+		"Addresses": {
+			starting: Device{Addr: addr},
+			in: Device{
+				Addr:      addr,
+				Addresses: DeviceAddrs{{Addr: MustParseAddr("fe80::1"), LastSeen: ts}},
+			},
+			want: Device{
+				Addr:      addr,
+				Addresses: DeviceAddrs{{Addr: MustParseAddr("fe80::1"), LastSeen: ts}},
+			},
+			wantUpdated: true,
+		},
 	}
 
 	for name, tc := range tests {
@@ -884,3 +927,64 @@ func TestDevice_Merge(t *testing.T) {
 		})
 	}
 }
+
+func TestDevice_MovedTo(t *testing.T) {
+	mac := MustParseMAC("00:00:5e:00:53:01")
+	rotated := MustParseMAC("da:00:5e:00:53:02")
+	tests := map[string]struct {
+		prev Device
+		next Device
+		want Device
+	}{
+		"KeepsName": {
+			prev: Device{Name: "nas", Addr: MustParseAddr("192.168.1.5"), MAC: mac},
+			next: Device{Addr: MustParseAddr("192.168.1.9"), MAC: mac},
+			want: Device{Name: "nas", Addr: MustParseAddr("192.168.1.9"), MAC: mac},
+		},
+		"AddrName": {
+			prev: Device{
+				Name: "192.168.1.5",
+				Addr: MustParseAddr("192.168.1.5"),
+				Meta: Meta{Approval: ApprovalApproved},
+			},
+			next: Device{Addr: MustParseAddr("192.168.1.9"), Meta: Meta{Approval: ApprovalUnknown}},
+			want: Device{
+				Name: "192.168.1.9",
+				Addr: MustParseAddr("192.168.1.9"),
+				Meta: Meta{Approval: ApprovalApproved},
+			},
+		},
+		"RotatedMAC": {
+			prev: Device{
+				Name: "phone",
+				Addr: MustParseAddr("192.168.1.5"),
+				MAC:  mac,
+				Meta: Meta{MDNSName: "phone.local"},
+			},
+			next: Device{
+				Addr: MustParseAddr("192.168.1.9"),
+				MAC:  rotated,
+				Meta: Meta{MDNSName: "phone.local", DHCPHostname: "phone"},
+			},
+			want: Device{
+				Name:         "phone",
+				Addr:         MustParseAddr("192.168.1.9"),
+				MAC:          rotated,
+				ObservedMACs: []MAC{mac, rotated},
+				Meta:         Meta{MDNSName: "phone.local", DHCPHostname: "phone"},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := tc.prev.MovedTo(tc.next)
+			opts := cmp.Options{
+				cmpopts.EquateComparable(netip.Addr{}),
+				cmpopts.IgnoreUnexported(Device{}),
+			}
+			if diff := cmp.Diff(tc.want, got, opts); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
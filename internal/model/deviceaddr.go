@@ -0,0 +1,126 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+type (
+	// DeviceAddr is an address the device answers on besides its primary addr
+	DeviceAddr struct {
+		Addr     Addr
+		LastSeen time.Time
+	}
+
+	// DeviceAddrs are the additional addresses of a device, most recently seen first
+	DeviceAddrs []DeviceAddr
+)
+
+// Seen returns the addresses with the addr seen at the time, an addr seen before keeps the
+// later of the two times
+func (as DeviceAddrs) Seen(addr Addr, ts time.Time) DeviceAddrs {
+	out, _ := as.merge(DeviceAddrs{{Addr: addr, LastSeen: ts}}, Addr{})
+	return out
+}
+
+// Contains reports if the addr is one of the addresses
+func (as DeviceAddrs) Contains(addr Addr) bool {
+	return slices.ContainsFunc(as, func(a DeviceAddr) bool { return a.Addr == addr })
+}
+
+// merge adds the incoming addresses, keeping the later last seen of an addr known to both.
+// The primary addr of the device is never one of its additional addresses.
+func (as DeviceAddrs) merge(in DeviceAddrs, primary Addr) (out DeviceAddrs, updated bool) {
+	out = slices.Clone(as)
+	for _, a := range in {
+		if !a.Addr.A.IsValid() || a.Addr == primary {
+			continue
+		}
+		idx := slices.IndexFunc(out, func(o DeviceAddr) bool { return o.Addr == a.Addr })
+		switch {
+		case idx < 0:
+			out = append(out, a)
+			updated = true
+		case a.LastSeen.After(out[idx].LastSeen):
+			out[idx].LastSeen = a.LastSeen
+			updated = true
+		}
+	}
+	if primary.A.IsValid() && out.Contains(primary) {
+		out = slices.DeleteFunc(out, func(a DeviceAddr) bool { return a.Addr == primary })
+		updated = true
+	}
+	if !updated {
+		return as, false
+	}
+	slices.SortStableFunc(out, func(a, b DeviceAddr) int {
+		return b.LastSeen.Compare(a.LastSeen)
+	})
+	return out, true
+}
+
+// AddrsString lists the addresses without when they were seen, for the change history
+func (as DeviceAddrs) AddrsString() string {
+	addrs := make([]string, 0, len(as))
+	for _, a := range as {
+		addrs = append(addrs, a.Addr.String())
+	}
+	slices.Sort(addrs)
+	return strings.Join(addrs, " ")
+}
+
+func (as DeviceAddrs) String() string {
+	v, err := as.Value()
+	if err != nil {
+		log.Error("deviceaddrs.String", "error", err)
+		return ""
+	}
+	return v.(string)
+}
+
+func (as DeviceAddrs) Value() (driver.Value, error) {
+	if len(as) == 0 {
+		return "", nil
+	}
+	x, err := json.Marshal(as)
+	if err != nil {
+		return nil, err
+	}
+	return string(x), nil
+}
+
+func (as *DeviceAddrs) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		if len(src) == 0 {
+			*as = nil
+			return nil
+		}
+		return json.Unmarshal([]byte(src), as)
+	}
+	return nil
+}
+
+// AllAddrs returns the primary addr of the device followed by its additional addresses
+func (d Device) AllAddrs() []Addr {
+	addrs := make([]Addr, 0, len(d.Addresses)+1)
+	addrs = append(addrs, d.Addr)
+	for _, a := range d.Addresses {
+		addrs = append(addrs, a.Addr)
+	}
+	return addrs
+}
+
+// HasAddr reports if the device answers on the addr, as its primary or an additional address
+func (d Device) HasAddr(addr Addr) bool {
+	return d.Addr == addr || d.Addresses.Contains(addr)
+}
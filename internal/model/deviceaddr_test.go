@@ -0,0 +1,104 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestDeviceAddrs_Merge(t *testing.T) {
+	primary := MustParseAddr("192.168.1.20")
+	global := MustParseAddr("2001:db8::20")
+	linklocal := MustParseAddr("fe80::20")
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	later := ts.Add(time.Hour)
+	tests := map[string]struct {
+		starting    DeviceAddrs
+		in          DeviceAddrs
+		want        DeviceAddrs
+		wantUpdated bool
+	}{
+		"Empty": {},
+		"Added": {
+			starting:    DeviceAddrs{{Addr: linklocal, LastSeen: ts}},
+			in:          DeviceAddrs{{Addr: global, LastSeen: later}},
+			want:        DeviceAddrs{{Addr: global, LastSeen: later}, {Addr: linklocal, LastSeen: ts}},
+			wantUpdated: true,
+		},
+		"SeenLater": {
+			starting:    DeviceAddrs{{Addr: global, LastSeen: ts}},
+			in:          DeviceAddrs{{Addr: global, LastSeen: later}},
+			want:        DeviceAddrs{{Addr: global, LastSeen: later}},
+			wantUpdated: true,
+		},
+		"SeenEarlier": {
+			starting:    DeviceAddrs{{Addr: global, LastSeen: later}},
+			in:          DeviceAddrs{{Addr: global, LastSeen: ts}},
+			want:        DeviceAddrs{{Addr: global, LastSeen: later}},
+			wantUpdated: false,
+		},
+		"Primary": {
+			starting:    DeviceAddrs{{Addr: global, LastSeen: ts}},
+			in:          DeviceAddrs{{Addr: primary, LastSeen: later}},
+			want:        DeviceAddrs{{Addr: global, LastSeen: ts}},
+			wantUpdated: false,
+		},
+		"PrimaryDropped": {
+			starting:    DeviceAddrs{{Addr: primary, LastSeen: ts}, {Addr: global, LastSeen: ts}},
+			want:        DeviceAddrs{{Addr: global, LastSeen: ts}},
+			wantUpdated: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, gotUpdated := tc.starting.merge(tc.in, primary)
+			diff := cmp.Diff(tc.want, got, cmpopts.EquateComparable(netip.Addr{}))
+			if diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+			if tc.wantUpdated != gotUpdated {
+				t.Errorf("updated mismatch (want:%t got:%t)", tc.wantUpdated, gotUpdated)
+			}
+		})
+	}
+}
+
+func TestDeviceAddrs_Scan(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	want := DeviceAddrs{{Addr: MustParseAddr("2001:db8::20"), LastSeen: ts}}
+	var got DeviceAddrs
+	if err := got.Scan(want.String()); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateComparable(netip.Addr{})); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if err := got.Scan(""); err != nil || got != nil {
+		t.Errorf("want empty addresses, got: %v (%v)", got, err)
+	}
+}
+
+func TestDevice_HasAddr(t *testing.T) {
+	d := Device{
+		Addr:      MustParseAddr("192.168.1.20"),
+		Addresses: DeviceAddrs{{Addr: MustParseAddr("2001:db8::20")}},
+	}
+	for _, addr := range []string{"192.168.1.20", "2001:db8::20"} {
+		if !d.HasAddr(MustParseAddr(addr)) {
+			t.Errorf("want device at %s", addr)
+		}
+	}
+	if d.HasAddr(MustParseAddr("2001:db8::30")) {
+		t.Error("want device not at 2001:db8::30")
+	}
+	if got := len(d.AllAddrs()); got != 2 {
+		t.Errorf("want 2 addrs, got: %d", got)
+	}
+}
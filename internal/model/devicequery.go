@@ -0,0 +1,156 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// DeviceSort is the column a device query is ordered by
+type DeviceSort string
+
+const (
+	DeviceSortAddr     DeviceSort = "addr"
+	DeviceSortName     DeviceSort = "name"
+	DeviceSortLastSeen DeviceSort = "lastseen"
+	DeviceSortPing     DeviceSort = "ping"
+
+	// DefaultDevicePageSize is used when a query does not set a limit
+	DefaultDevicePageSize = 50
+)
+
+var ErrInvalidDeviceSort = errors.New("invalid device sort")
+
+// ParseDeviceSort converts the string into a DeviceSort, an empty string sorts by address
+func ParseDeviceSort(s string) (DeviceSort, error) {
+	switch DeviceSort(strings.ToLower(s)) {
+	case "", DeviceSortAddr:
+		return DeviceSortAddr, nil
+	case DeviceSortName:
+		return DeviceSortName, nil
+	case DeviceSortLastSeen:
+		return DeviceSortLastSeen, nil
+	case DeviceSortPing:
+		return DeviceSortPing, nil
+	}
+	return "", ErrInvalidDeviceSort
+}
+
+// DeviceQuery selects one page of devices.  Search is matched case insensitively against
+// the name, address, dns name, MAC, manufacturer, owner, location, serial, asset tag, device
+// type and tags; Filter may be nil.
+type DeviceQuery struct {
+	Search     string
+	Filter     DeviceFilter
+	Sort       DeviceSort
+	Descending bool
+	Offset     int
+	Limit      int
+}
+
+// DevicePage is the result of a DeviceQuery, Total is the number of devices matching the
+// query before paging
+type DevicePage struct {
+	Devices []Device
+	Total   int
+	Offset  int
+	Limit   int
+}
+
+// HasPrev reports if there are matching devices before this page
+func (p DevicePage) HasPrev() bool {
+	return p.Offset > 0
+}
+
+// HasNext reports if there are matching devices after this page
+func (p DevicePage) HasNext() bool {
+	return p.Offset+len(p.Devices) < p.Total
+}
+
+// Matches reports if the device satisfies the search and filter of the query
+func (q DeviceQuery) Matches(d Device) bool {
+	if q.Filter != nil && !q.Filter(d) {
+		return false
+	}
+	search := strings.ToLower(strings.TrimSpace(q.Search))
+	if search == "" {
+		return true
+	}
+	fields := []string{
+		d.Name,
+		d.Addr.String(),
+		d.Meta.DnsName,
+		d.MAC.String(),
+		d.Meta.Manufacturer,
+		d.Meta.Owner,
+		d.Meta.Location,
+		d.Meta.Serial,
+		d.Meta.AssetTag,
+		string(d.Meta.DeviceType),
+		d.Addresses.AddrsString(),
+	}
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), search) {
+			return true
+		}
+	}
+	return slices.ContainsFunc(d.Meta.Tags, func(t Tag) bool {
+		return strings.Contains(strings.ToLower(t.Val), search)
+	})
+}
+
+// QueryDevices applies the query to the devices, the input is not modified
+func QueryDevices(devices []Device, q DeviceQuery) DevicePage {
+	matched := make([]Device, 0)
+	for _, d := range devices {
+		if q.Matches(d) {
+			matched = append(matched, d)
+		}
+	}
+	slices.SortStableFunc(matched, deviceSortFunc(q.Sort, q.Descending))
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultDevicePageSize
+	}
+	offset := max(q.Offset, 0)
+	if offset > len(matched) {
+		offset = len(matched)
+	}
+	end := min(offset+limit, len(matched))
+	return DevicePage{
+		Devices: matched[offset:end],
+		Total:   len(matched),
+		Offset:  offset,
+		Limit:   limit,
+	}
+}
+
+func deviceSortFunc(sort DeviceSort, desc bool) func(a, b Device) int {
+	var f func(a, b Device) int
+	switch sort {
+	case DeviceSortName:
+		f = func(a, b Device) int {
+			return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		}
+	case DeviceSortLastSeen:
+		f = func(a, b Device) int {
+			return a.PerformancePing.LastSeen.Compare(b.PerformancePing.LastSeen)
+		}
+	case DeviceSortPing:
+		f = func(a, b Device) int {
+			return cmp.Compare(a.PerformancePing.Mean, b.PerformancePing.Mean)
+		}
+	default:
+		f = func(a, b Device) int { return a.Addr.Compare(b.Addr) }
+	}
+	if desc {
+		return func(a, b Device) int { return f(b, a) }
+	}
+	return f
+}
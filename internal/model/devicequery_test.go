@@ -0,0 +1,130 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestQueryDevices(t *testing.T) {
+	now := time.Now()
+	devices := []Device{
+		{
+			Name:            "printer",
+			Addr:            MustParseAddr("192.168.1.3"),
+			MAC:             MustParseMAC("00:11:22:33:44:03"),
+			PerformancePing: Pinger{Mean: 3 * time.Millisecond, LastSeen: now},
+		},
+		{
+			Name:            "Router",
+			Addr:            MustParseAddr("192.168.1.1"),
+			MAC:             MustParseMAC("00:11:22:33:44:01"),
+			Meta:            Meta{Manufacturer: "Ubiquiti", Tags: []Tag{{Val: "infra"}}},
+			PerformancePing: Pinger{Mean: time.Millisecond, LastSeen: now.Add(-time.Hour)},
+		},
+		{
+			Name:            "laptop",
+			Addr:            MustParseAddr("192.168.1.2"),
+			MAC:             MustParseMAC("aa:bb:cc:dd:ee:02"),
+			Meta:            Meta{DeviceType: DeviceTypeComputer},
+			PerformancePing: Pinger{Mean: 2 * time.Millisecond, LastSeen: now.Add(-time.Minute)},
+		},
+	}
+	names := func(p DevicePage) []string {
+		out := make([]string, 0, len(p.Devices))
+		for _, d := range p.Devices {
+			out = append(out, d.Name)
+		}
+		return out
+	}
+	tests := map[string]struct {
+		q         DeviceQuery
+		want      []string
+		wantTotal int
+	}{
+		"DefaultAddr": {
+			q:         DeviceQuery{},
+			want:      []string{"Router", "laptop", "printer"},
+			wantTotal: 3,
+		},
+		"NameDesc": {
+			q:         DeviceQuery{Sort: DeviceSortName, Descending: true},
+			want:      []string{"Router", "printer", "laptop"},
+			wantTotal: 3,
+		},
+		"LastSeen": {
+			q:         DeviceQuery{Sort: DeviceSortLastSeen},
+			want:      []string{"Router", "laptop", "printer"},
+			wantTotal: 3,
+		},
+		"Ping": {
+			q:         DeviceQuery{Sort: DeviceSortPing, Descending: true},
+			want:      []string{"printer", "laptop", "Router"},
+			wantTotal: 3,
+		},
+		"SearchMAC": {
+			q:         DeviceQuery{Search: "AA:BB"},
+			want:      []string{"laptop"},
+			wantTotal: 1,
+		},
+		"SearchManufacturer": {
+			q:         DeviceQuery{Search: "ubiq"},
+			want:      []string{"Router"},
+			wantTotal: 1,
+		},
+		"SearchTag": {
+			q:         DeviceQuery{Search: "infra"},
+			want:      []string{"Router"},
+			wantTotal: 1,
+		},
+		"SearchDeviceType": {
+			q:         DeviceQuery{Search: "computer"},
+			want:      []string{"laptop"},
+			wantTotal: 1,
+		},
+		"Filter": {
+			q: DeviceQuery{Filter: func(d Device) bool {
+				return d.PerformancePing.Mean > time.Millisecond
+			}},
+			want:      []string{"laptop", "printer"},
+			wantTotal: 2,
+		},
+		"Page": {
+			q:         DeviceQuery{Offset: 1, Limit: 1},
+			want:      []string{"laptop"},
+			wantTotal: 3,
+		},
+		"OffsetPastEnd": {
+			q:         DeviceQuery{Offset: 10, Limit: 1},
+			want:      []string{},
+			wantTotal: 3,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			page := QueryDevices(devices, tc.q)
+			if diff := cmp.Diff(tc.want, names(page)); diff != "" {
+				t.Errorf("QueryDevices() mismatch (-want +got):\n%s", diff)
+			}
+			if page.Total != tc.wantTotal {
+				t.Errorf("QueryDevices() total want %d got %d", tc.wantTotal, page.Total)
+			}
+		})
+	}
+}
+
+func TestDevicePage_Paging(t *testing.T) {
+	page := DevicePage{Devices: make([]Device, 10), Total: 25, Offset: 10, Limit: 10}
+	if !page.HasPrev() || !page.HasNext() {
+		t.Errorf("middle page want prev and next, got %v %v", page.HasPrev(), page.HasNext())
+	}
+	page = DevicePage{Devices: make([]Device, 5), Total: 25, Offset: 20, Limit: 10}
+	if page.HasNext() {
+		t.Error("last page want no next")
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DeviceType is the kind of device as classified from what it announces and answers, custom
+// classification rules may use their own types
+type DeviceType string
+
+const (
+	DeviceTypeUnknown  DeviceType = ""
+	DeviceTypePhone    DeviceType = "phone"
+	DeviceTypePrinter  DeviceType = "printer"
+	DeviceTypeCamera   DeviceType = "camera"
+	DeviceTypeServer   DeviceType = "server"
+	DeviceTypeIoT      DeviceType = "iot"
+	DeviceTypeNetwork  DeviceType = "network"
+	DeviceTypeMedia    DeviceType = "media"
+	DeviceTypeComputer DeviceType = "computer"
+)
+
+var ErrInvalidClassifyRule = errors.New("invalid classify rule")
+
+func (t DeviceType) String() string {
+	if t == DeviceTypeUnknown {
+		return "unknown"
+	}
+	return string(t)
+}
+
+// DeviceTypeFilter selects devices classified as the type
+func DeviceTypeFilter(t DeviceType) DeviceFilter {
+	return func(d Device) bool {
+		return d.Meta.DeviceType == t
+	}
+}
+
+// ClassifyRule sets the type of the devices matching all of its non empty criteria.  The
+// text criteria are case insensitive regular expressions: the hostname is matched against
+// the name, dns, mdns and dhcp hostnames, the vendor against the dhcp vendor class (option
+// 60), the fingerprint against the dhcp parameter request list (option 55) and snmp against
+// the snmp name and description.  Ports matches when any of the ports is open.
+type ClassifyRule struct {
+	Type            DeviceType `yaml:"type"`
+	Manufacturer    string     `yaml:"manufacturer"`
+	Hostname        string     `yaml:"hostname"`
+	DHCPVendor      string     `yaml:"dhcpvendor"`
+	DHCPFingerprint string     `yaml:"dhcpfingerprint"`
+	SNMP            string     `yaml:"snmp"`
+	Ports           []int      `yaml:"ports"`
+}
+
+// Classifier assigns a device type with the first matching rule
+type Classifier struct {
+	rules []compiledClassifyRule
+}
+
+type compiledClassifyRule struct {
+	typ          DeviceType
+	manufacturer *regexp.Regexp
+	hostname     *regexp.Regexp
+	vendor       *regexp.Regexp
+	fingerprint  *regexp.Regexp
+	snmp         *regexp.Regexp
+	ports        []int
+}
+
+// NewClassifier returns a classifier trying the custom rules before the built in rules
+func NewClassifier(custom []ClassifyRule) (*Classifier, error) {
+	c := &Classifier{}
+	for _, r := range slices.Concat(custom, defaultClassifyRules) {
+		cr, err := r.compile()
+		if err != nil {
+			return nil, err
+		}
+		c.rules = append(c.rules, cr)
+	}
+	return c, nil
+}
+
+// Classify returns the type of the first rule the device matches
+func (c *Classifier) Classify(d Device) DeviceType {
+	for _, r := range c.rules {
+		if r.matches(d) {
+			return r.typ
+		}
+	}
+	return DeviceTypeUnknown
+}
+
+func (r ClassifyRule) compile() (compiledClassifyRule, error) {
+	cr := compiledClassifyRule{
+		typ:   DeviceType(strings.ToLower(strings.TrimSpace(string(r.Type)))),
+		ports: r.Ports,
+	}
+	if cr.typ == DeviceTypeUnknown {
+		return cr, fmt.Errorf("%w: rule without a type", ErrInvalidClassifyRule)
+	}
+	empty := len(r.Ports) == 0
+	for _, f := range []struct {
+		expr string
+		re   **regexp.Regexp
+	}{
+		{r.Manufacturer, &cr.manufacturer},
+		{r.Hostname, &cr.hostname},
+		{r.DHCPVendor, &cr.vendor},
+		{r.DHCPFingerprint, &cr.fingerprint},
+		{r.SNMP, &cr.snmp},
+	} {
+		if f.expr == "" {
+			continue
+		}
+		re, err := regexp.Compile("(?i)" + f.expr)
+		if err != nil {
+			return cr, fmt.Errorf("%w: %s rule: %w", ErrInvalidClassifyRule, cr.typ, err)
+		}
+		*f.re = re
+		empty = false
+	}
+	if empty {
+		return cr, fmt.Errorf("%w: %s rule without criteria", ErrInvalidClassifyRule, cr.typ)
+	}
+	return cr, nil
+}
+
+func (r compiledClassifyRule) matches(d Device) bool {
+	if r.manufacturer != nil && !r.manufacturer.MatchString(d.Meta.Manufacturer) {
+		return false
+	}
+	if r.hostname != nil && !slices.ContainsFunc(
+		[]string{d.Name, d.Meta.DnsName, d.Meta.MDNSName, d.Meta.DHCPHostname},
+		func(name string) bool { return name != "" && r.hostname.MatchString(name) },
+	) {
+		return false
+	}
+	if r.vendor != nil && !r.vendor.MatchString(d.Meta.DHCPVendorClass) {
+		return false
+	}
+	if r.fingerprint != nil && !r.fingerprint.MatchString(d.Meta.DHCPFingerprint) {
+		return false
+	}
+	if r.snmp != nil && !r.snmp.MatchString(d.SNMP.Name+" "+d.SNMP.Description) {
+		return false
+	}
+	if len(r.ports) > 0 && !slices.ContainsFunc(r.ports, func(p int) bool {
+		return slices.Contains(d.Server.Ports.Ports, p)
+	}) {
+		return false
+	}
+	return true
+}
+
+// defaultClassifyRules are tried after the custom rules, the more specific ones first
+var defaultClassifyRules = []ClassifyRule{
+	// dhcp vendor classes and fingerprints are announced by the operating system
+	{Type: DeviceTypePhone, DHCPVendor: `^android-dhcp`},
+	{Type: DeviceTypePhone, DHCPFingerprint: `^1,121,3,6,15,119,252(,95,44,46)?$`},
+	{Type: DeviceTypePhone, Hostname: `iphone|ipad|android|galaxy|pixel`},
+	{Type: DeviceTypeComputer, DHCPVendor: `^msft`},
+	{Type: DeviceTypeComputer, Hostname: `macbook|imac|^desktop-|^laptop-`},
+
+	{Type: DeviceTypePrinter, Ports: []int{9100, 515, 631}},
+	{Type: DeviceTypePrinter, Manufacturer: `brother|epson|lexmark|xerox|kyocera|ricoh|zebra`},
+	{Type: DeviceTypePrinter, Hostname: `printer|^brw|^npi`},
+
+	{Type: DeviceTypeCamera, Manufacturer: `hikvision|dahua|axis comm|amcrest|reolink|uniview`},
+	{Type: DeviceTypeCamera, Ports: []int{554}, Hostname: `cam|nvr|dvr`},
+
+	{Type: DeviceTypeNetwork, SNMP: `routeros|cisco ios|junos|edgeos|edgeswitch|unifi|openwrt`},
+	{Type: DeviceTypeNetwork, Manufacturer: `ubiquiti|mikrotik|cisco|juniper|aruba|zyxel`},
+	{Type: DeviceTypeNetwork, Manufacturer: `netgear|tp-link`},
+
+	{Type: DeviceTypeMedia, Manufacturer: `roku|sonos`},
+	{Type: DeviceTypeMedia, Hostname: `chromecast|appletv|apple-tv|roku|sonos|firetv|shield`},
+	{Type: DeviceTypeMedia, Ports: []int{8008, 8009}},
+
+	{Type: DeviceTypeIoT, Manufacturer: `espressif|tuya|shelly|sonoff|signify|philips lighting`},
+	{Type: DeviceTypeIoT, Manufacturer: `ecobee|nest labs|\bring\b`},
+	{Type: DeviceTypeIoT, Hostname: `^esp[-_]|tasmota|shelly|philips-hue|wled|homeassistant`},
+	{Type: DeviceTypeIoT, Ports: []int{1883, 8883}},
+
+	{Type: DeviceTypeServer, Manufacturer: `synology|qnap|supermicro|vmware|proxmox`},
+	{Type: DeviceTypeServer, Hostname: `\bnas\b|server|srv|^pve`},
+	{Type: DeviceTypeServer, Ports: []int{1433, 2375, 3306, 5432, 6379, 8006, 27017}},
+	{Type: DeviceTypeServer, SNMP: `linux|freebsd|windows server`},
+	{Type: DeviceTypeComputer, Ports: []int{3389, 5900}},
+}
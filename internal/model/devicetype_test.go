@@ -0,0 +1,99 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifier_Classify(t *testing.T) {
+	custom := []ClassifyRule{
+		{Type: "thermostat", Hostname: `^thermo`},
+		{Type: DeviceTypeCamera, Manufacturer: `acme`, Ports: []int{80}},
+	}
+	c, err := NewClassifier(custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]struct {
+		d    Device
+		want DeviceType
+	}{
+		"Unknown": {
+			d: Device{Name: "192.168.1.9"},
+		},
+		"AndroidVendorClass": {
+			d:    Device{Meta: Meta{DHCPVendorClass: "android-dhcp-13"}},
+			want: DeviceTypePhone,
+		},
+		"WindowsVendorClass": {
+			d:    Device{Meta: Meta{DHCPVendorClass: "MSFT 5.0"}},
+			want: DeviceTypeComputer,
+		},
+		"PrinterPort": {
+			d:    Device{Server: Server{Ports: PortList{Ports: []int{80, 9100}}}},
+			want: DeviceTypePrinter,
+		},
+		"CameraManufacturer": {
+			d:    Device{Meta: Meta{Manufacturer: "Hangzhou Hikvision Digital Technology"}},
+			want: DeviceTypeCamera,
+		},
+		"RtspWithoutCameraName": {
+			d: Device{Name: "mediabox", Server: Server{Ports: PortList{Ports: []int{554}}}},
+		},
+		"MDNSName": {
+			d:    Device{Meta: Meta{MDNSName: "Living-Room-AppleTV.local"}},
+			want: DeviceTypeMedia,
+		},
+		"SNMPRouter": {
+			d:    Device{SNMP: SNMP{Description: "RouterOS RB4011iGS+"}},
+			want: DeviceTypeNetwork,
+		},
+		"ServerPorts": {
+			d:    Device{Server: Server{Ports: PortList{Ports: []int{22, 5432}}}},
+			want: DeviceTypeServer,
+		},
+		"CustomType": {
+			d:    Device{Meta: Meta{DHCPHostname: "thermo-hall"}},
+			want: "thermostat",
+		},
+		"CustomBeforeBuiltIn": {
+			d: Device{
+				Meta:   Meta{Manufacturer: "Acme Printers"},
+				Server: Server{Ports: PortList{Ports: []int{80, 9100}}},
+			},
+			want: DeviceTypeCamera,
+		},
+		"CustomNeedsAllCriteria": {
+			d:    Device{Meta: Meta{Manufacturer: "Acme Printers"}},
+			want: DeviceTypeUnknown,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := c.Classify(tc.d)
+			if got != tc.want {
+				t.Errorf("want: %s, got: %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNewClassifier_InvalidRules(t *testing.T) {
+	tests := map[string]ClassifyRule{
+		"NoType":     {Hostname: "nas"},
+		"NoCriteria": {Type: DeviceTypeServer},
+		"BadRegexp":  {Type: DeviceTypeServer, Hostname: "(nas"},
+	}
+	for name, rule := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewClassifier([]ClassifyRule{rule})
+			if !errors.Is(err, ErrInvalidClassifyRule) {
+				t.Errorf("want: %v, got: %v", ErrInvalidClassifyRule, err)
+			}
+		})
+	}
+}
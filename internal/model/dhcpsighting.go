@@ -0,0 +1,90 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/networkables/mason/nettools"
+)
+
+type (
+	// DHCPSighting is a DHCP server answering a discover, or a default gateway handed out
+	// by one, from the first to the last time it was seen
+	DHCPSighting struct {
+		Kind      DHCPSightingKind
+		Addr      Addr
+		Server    Addr
+		FirstSeen time.Time
+		LastSeen  time.Time
+		Trusted   bool
+	}
+
+	// DHCPSightingKind is what was seen at the address of a sighting
+	DHCPSightingKind string
+)
+
+const (
+	DHCPServerSighting  DHCPSightingKind = "server"
+	DHCPGatewaySighting DHCPSightingKind = "gateway"
+)
+
+var ErrDHCPSightingDoesNotExist = errors.New("dhcp sighting does not exist")
+
+func (s DHCPSighting) Key() string {
+	return string(s.Kind) + " " + s.Addr.String()
+}
+
+func (s DHCPSighting) String() string {
+	if s.Kind == DHCPGatewaySighting {
+		return fmt.Sprintf("gateway %s from %s", s.Addr, s.Server)
+	}
+	return fmt.Sprintf("dhcp server %s", s.Addr)
+}
+
+// DHCPSightingsFromOffers returns the servers of the offers and the gateways they hand out
+func DHCPSightingsFromOffers(offers []nettools.DHCPOffer, ts time.Time) []DHCPSighting {
+	sightings := make([]DHCPSighting, 0, len(offers))
+	for _, offer := range offers {
+		server := AddrToModelAddr(offer.Server)
+		sightings = append(sightings, DHCPSighting{
+			Kind:      DHCPServerSighting,
+			Addr:      server,
+			Server:    server,
+			FirstSeen: ts,
+			LastSeen:  ts,
+		})
+		for _, router := range offer.Routers {
+			gw := DHCPSighting{
+				Kind:      DHCPGatewaySighting,
+				Addr:      AddrToModelAddr(router),
+				Server:    server,
+				FirstSeen: ts,
+				LastSeen:  ts,
+			}
+			if slices.ContainsFunc(sightings, func(s DHCPSighting) bool { return s.Key() == gw.Key() }) {
+				continue
+			}
+			sightings = append(sightings, gw)
+		}
+	}
+	return sightings
+}
+
+// ObserveDHCPSighting merges a seen sighting into the known sighting of the same kind and
+// address, keeping its first seen time and trust.  Reports false when it was not known.
+func ObserveDHCPSighting(known []DHCPSighting, seen DHCPSighting) (DHCPSighting, bool) {
+	idx := slices.IndexFunc(known, func(s DHCPSighting) bool { return s.Key() == seen.Key() })
+	if idx < 0 {
+		return seen, false
+	}
+	merged := seen
+	merged.FirstSeen = known[idx].FirstSeen
+	merged.Trusted = known[idx].Trusted
+	return merged, true
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/nettools"
+)
+
+func TestDHCPSightingsFromOffers(t *testing.T) {
+	ts := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	offers := []nettools.DHCPOffer{
+		{
+			Server: netip.MustParseAddr("192.168.1.1"),
+			Routers: []netip.Addr{
+				netip.MustParseAddr("192.168.1.1"),
+				netip.MustParseAddr("192.168.1.254"),
+			},
+		},
+		{
+			Server:  netip.MustParseAddr("192.168.1.77"),
+			Routers: []netip.Addr{netip.MustParseAddr("192.168.1.254")},
+		},
+	}
+	sighting := func(kind DHCPSightingKind, addr string, server string) DHCPSighting {
+		return DHCPSighting{
+			Kind:      kind,
+			Addr:      MustParseAddr(addr),
+			Server:    MustParseAddr(server),
+			FirstSeen: ts,
+			LastSeen:  ts,
+		}
+	}
+	want := []DHCPSighting{
+		sighting(DHCPServerSighting, "192.168.1.1", "192.168.1.1"),
+		sighting(DHCPGatewaySighting, "192.168.1.1", "192.168.1.1"),
+		sighting(DHCPGatewaySighting, "192.168.1.254", "192.168.1.1"),
+		sighting(DHCPServerSighting, "192.168.1.77", "192.168.1.77"),
+	}
+	got := DHCPSightingsFromOffers(offers, ts)
+	if diff := cmp.Diff(want, got, cmpopts.EquateComparable(netip.Addr{})); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestObserveDHCPSighting(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	known := []DHCPSighting{{
+		Kind:      DHCPServerSighting,
+		Addr:      MustParseAddr("192.168.1.1"),
+		FirstSeen: start,
+		LastSeen:  start,
+		Trusted:   true,
+	}}
+	seen := DHCPSighting{
+		Kind:      DHCPServerSighting,
+		Addr:      MustParseAddr("192.168.1.1"),
+		FirstSeen: start.Add(time.Hour),
+		LastSeen:  start.Add(time.Hour),
+	}
+	got, found := ObserveDHCPSighting(known, seen)
+	if !found {
+		t.Fatal("want the known server found")
+	}
+	if !got.Trusted || !got.FirstSeen.Equal(start) || !got.LastSeen.Equal(seen.LastSeen) {
+		t.Errorf("merge kept the wrong fields: %+v", got)
+	}
+
+	// a gateway at the address of a known server is a separate sighting
+	seen.Kind = DHCPGatewaySighting
+	_, found = ObserveDHCPSighting(known, seen)
+	if found {
+		t.Error("want the gateway not found")
+	}
+}
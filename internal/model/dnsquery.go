@@ -0,0 +1,38 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type (
+	// DNSQuery is a name looked up by a device, as captured on the wire or read from the
+	// log of a resolver
+	DNSQuery struct {
+		Time   time.Time
+		Addr   Addr
+		Domain string
+		Type   string
+	}
+
+	// DomainSummary is how often and when last a device looked up a domain
+	DomainSummary struct {
+		Domain   string
+		Queries  int
+		LastSeen time.Time
+	}
+)
+
+func (q DNSQuery) String() string {
+	return fmt.Sprintf("%s %s %s", q.Addr, q.Type, q.Domain)
+}
+
+// NormalizeDomain lowercases the name and drops the trailing dot of a fully qualified name
+func NormalizeDomain(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
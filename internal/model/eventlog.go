@@ -0,0 +1,65 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+type (
+	EventRecordKind string
+
+	// EventRecord is a bus event or error kept in the event history
+	EventRecord struct {
+		Time    time.Time
+		Kind    EventRecordKind
+		Type    string
+		Message string
+	}
+
+	// EventQuery selects event records, blank fields match everything.  Search matches the
+	// type or message ignoring case, only records after After are returned.
+	EventQuery struct {
+		Kind   EventRecordKind
+		Search string
+		After  time.Time
+		Limit  int
+	}
+)
+
+const (
+	EventRecordEvent EventRecordKind = "event"
+	EventRecordError EventRecordKind = "error"
+)
+
+func (q EventQuery) Match(rec EventRecord) bool {
+	if q.Kind != "" && rec.Kind != q.Kind {
+		return false
+	}
+	if !q.After.IsZero() && !rec.Time.After(q.After) {
+		return false
+	}
+	if q.Search == "" {
+		return true
+	}
+	search := strings.ToLower(q.Search)
+	return strings.Contains(strings.ToLower(rec.Type), search) ||
+		strings.Contains(strings.ToLower(rec.Message), search)
+}
+
+// FilterEventRecords returns the matching records newest first, up to the query limit
+func FilterEventRecords(records []EventRecord, q EventQuery) []EventRecord {
+	ret := make([]EventRecord, 0)
+	for idx := len(records) - 1; idx >= 0; idx-- {
+		if q.Limit > 0 && len(ret) >= q.Limit {
+			break
+		}
+		if q.Match(records[idx]) {
+			ret = append(ret, records[idx])
+		}
+	}
+	return ret
+}
@@ -0,0 +1,57 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFilterEventRecords(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	added := EventRecord{
+		Time:    start,
+		Kind:    EventRecordEvent,
+		Type:    "model.EventDeviceAdded",
+		Message: "device added: 192.168.1.10",
+	}
+	failed := EventRecord{
+		Time:    start.Add(time.Second),
+		Kind:    EventRecordError,
+		Type:    "tre.TracingError",
+		Message: "storing updated device",
+	}
+	scanned := EventRecord{
+		Time:    start.Add(2 * time.Second),
+		Kind:    EventRecordEvent,
+		Type:    "discovery.EventNetworkScanFinished",
+		Message: "scan finished: lan",
+	}
+	records := []EventRecord{added, failed, scanned}
+
+	tests := map[string]struct {
+		query EventQuery
+		want  []EventRecord
+	}{
+		"NewestFirst": {want: []EventRecord{scanned, failed, added}},
+		"Limit":       {query: EventQuery{Limit: 2}, want: []EventRecord{scanned, failed}},
+		"Kind":        {query: EventQuery{Kind: EventRecordError}, want: []EventRecord{failed}},
+		"SearchIgnoresCase": {
+			query: EventQuery{Search: "DEVICE"},
+			want:  []EventRecord{failed, added},
+		},
+		"After": {query: EventQuery{After: failed.Time}, want: []EventRecord{scanned}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := FilterEventRecords(records, tc.query)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -6,12 +6,121 @@ package model
 
 import (
 	"fmt"
+	"strings"
 )
 
 type (
 	EventDeviceDiscovered Device
 	EventDeviceAdded      Device
 	EventDeviceUpdated    Device
+
+	// EventDeviceNeedsReview is raised when a newly discovered device is waiting for an
+	// operator to approve or block it
+	EventDeviceNeedsReview Device
+
+	// EventDevicePortsChanged is raised when a port scan finds ports which were not open in
+	// the previous scan of the device, or finds previously open ports closed
+	EventDevicePortsChanged struct {
+		Device Device
+		Opened []int
+		Closed []int
+	}
+
+	// EventDeviceEdited is raised when an operator changes the name, owner or notes of a
+	// device
+	EventDeviceEdited struct {
+		Device  Device
+		Changes []DeviceChange
+	}
+
+	// EventDeviceAddrChanged is raised when a known device, matched by its MAC or name, is
+	// discovered at a new address and has stopped answering at the old one
+	EventDeviceAddrChanged struct {
+		Device Device
+		From   Addr
+	}
+
+	// EventFlowAnomaly is raised when the netflows of a device deviate from its hourly
+	// baseline
+	EventFlowAnomaly struct {
+		Addr   Addr
+		Kind   FlowAnomalyKind
+		Detail string
+	}
+
+	FlowAnomalyKind string
+
+	// EventHTTPCheckChanged is raised when an http check goes down or comes back up
+	EventHTTPCheckChanged struct {
+		Check  HTTPCheck
+		Result HTTPCheckResult
+	}
+
+	// EventServiceCheckChanged is raised when a service of a device stops or starts accepting
+	// connections
+	EventServiceCheckChanged struct {
+		Check  ServiceCheck
+		Result ServiceCheckResult
+	}
+
+	// EventMACConflict is raised when the MAC of an address changes or two MACs claim it
+	EventMACConflict struct {
+		Kind     MACConflictKind
+		Addr     Addr
+		MAC      MAC
+		Previous MAC
+		Source   DiscoverySource
+	}
+
+	// EventRogueDHCP is raised when an untrusted DHCP server or default gateway is first seen
+	EventRogueDHCP DHCPSighting
+
+	// EventQuotaAlert is raised when the traffic of a device first reaches the warning level
+	// or uses up a bandwidth quota within a period
+	EventQuotaAlert struct {
+		Usage QuotaUsage
+		Level QuotaLevel
+	}
+
+	// EventCaptureFinished is raised when a packet capture stops and its file can be
+	// downloaded
+	EventCaptureFinished PacketCapture
+
+	// EventHookAlert is raised by a scripting hook with an alert on a device or flow
+	EventHookAlert struct {
+		Hook    string
+		Addr    Addr
+		Message string
+	}
+
+	// EventSyslogAlert is raised when a device logs a message at or above the alert severity
+	EventSyslogAlert SyslogMessage
+
+	// EventDevicePingChanged is raised when a device stops answering its performance pings or
+	// answers them again, the device holds the result of the ping
+	EventDevicePingChanged struct {
+		Device Device
+	}
+
+	// EventUpstreamFailure is raised in place of the failures of the devices behind a parent
+	// device which is down
+	EventUpstreamFailure struct {
+		Parent     Device
+		Dependents []Addr
+	}
+
+	// EventUpstreamRecovered is raised when the parent of an upstream failure answers again,
+	// the dependents are the ones of the failure
+	EventUpstreamRecovered struct {
+		Parent     Device
+		Dependents []Addr
+	}
+)
+
+const (
+	FlowAnomalyNewCountry  FlowAnomalyKind = "newcountry"
+	FlowAnomalySpike       FlowAnomalyKind = "trafficspike"
+	FlowAnomalyUnusualPort FlowAnomalyKind = "unusualport"
 )
 
 var EmptyDiscoveredDevice EventDeviceDiscovered
@@ -25,3 +134,118 @@ func (nd EventDeviceAdded) String() string { return nd.Addr.String() }
 func (ude EventDeviceUpdated) String() string {
 	return fmt.Sprintf("%s [%s %s]", ude.Name, ude.Addr, ude.MAC)
 }
+
+func (nr EventDeviceNeedsReview) String() string {
+	return fmt.Sprintf("%s [%s %s]", nr.Name, nr.Addr, nr.MAC)
+}
+
+func (pc EventDevicePortsChanged) String() string {
+	return fmt.Sprintf("%s opened %v closed %v", pc.Device.Addr, pc.Opened, pc.Closed)
+}
+
+func (de EventDeviceEdited) String() string {
+	fields := make([]string, 0, len(de.Changes))
+	for _, c := range de.Changes {
+		fields = append(fields, c.Field)
+	}
+	return fmt.Sprintf("%s edited %s", de.Device.Addr, strings.Join(fields, ","))
+}
+
+func (ac EventDeviceAddrChanged) String() string {
+	return fmt.Sprintf("%s [%s] moved from %s", ac.Device.Name, ac.Device.Addr, ac.From)
+}
+
+func (fa EventFlowAnomaly) String() string {
+	return fmt.Sprintf("%s %s: %s", fa.Addr, fa.Kind, fa.Detail)
+}
+
+func (hc EventHTTPCheckChanged) String() string {
+	if hc.Result.Failed() {
+		return fmt.Sprintf("%s %s down: %s", hc.Check.Name, hc.Check.URL, hc.Result.Err)
+	}
+	return fmt.Sprintf("%s %s up", hc.Check.Name, hc.Check.URL)
+}
+
+func (e EventMACConflict) String() string {
+	switch e.Kind {
+	case DuplicateAddr:
+		return fmt.Sprintf("%s claimed by %s and %s", e.Addr, e.MAC, e.Previous)
+	}
+	return fmt.Sprintf("%s changed from %s to %s", e.Addr, e.Previous, e.MAC)
+}
+
+func (rd EventRogueDHCP) String() string {
+	return "untrusted " + DHCPSighting(rd).String()
+}
+
+func (qa EventQuotaAlert) String() string {
+	return fmt.Sprintf("%s quota %s %s: %s", qa.Usage.Addr, qa.Usage.Quota.Name, qa.Level, qa.Usage)
+}
+
+func (cf EventCaptureFinished) String() string {
+	return "packet capture " + PacketCapture(cf).String()
+}
+
+func (ha EventHookAlert) String() string {
+	return fmt.Sprintf("%s hook %s: %s", ha.Addr, ha.Hook, ha.Message)
+}
+
+func (sa EventSyslogAlert) String() string {
+	return "syslog " + SyslogMessage(sa).String()
+}
+
+func (uf EventUpstreamFailure) String() string {
+	addrs := make([]string, 0, len(uf.Dependents))
+	for _, a := range uf.Dependents {
+		addrs = append(addrs, a.String())
+	}
+	return fmt.Sprintf(
+		"upstream %s [%s] down, %d dependent failures: %s",
+		uf.Parent.Name,
+		uf.Parent.Addr,
+		len(uf.Dependents),
+		strings.Join(addrs, ", "),
+	)
+}
+
+func (ur EventUpstreamRecovered) String() string {
+	return fmt.Sprintf(
+		"upstream %s [%s] up, %d dependents",
+		ur.Parent.Name,
+		ur.Parent.Addr,
+		len(ur.Dependents),
+	)
+}
+
+// Down is true when the device has stopped answering
+func (pc EventDevicePingChanged) Down() bool {
+	return pc.Device.PerformancePing.LastFailed
+}
+
+func (pc EventDevicePingChanged) String() string {
+	if pc.Down() {
+		return fmt.Sprintf("%s [%s] down, no reply to ping", pc.Device.Name, pc.Device.Addr)
+	}
+	return fmt.Sprintf("%s [%s] up", pc.Device.Name, pc.Device.Addr)
+}
+
+func (sc EventServiceCheckChanged) String() string {
+	if sc.Result.Failed() {
+		return fmt.Sprintf("%s %s down: %s", sc.Check.Device, sc.Check, sc.Result.Err)
+	}
+	return fmt.Sprintf("%s %s up", sc.Check.Device, sc.Check)
+}
+
+// DevicePortsChanged compares the stored device against a port scan update and returns the
+// ports changed event when the update is a newer scan with a different set of open ports.
+// The first scan of a device does not raise an event.
+func DevicePortsChanged(prev Device, next Device) (EventDevicePortsChanged, bool) {
+	if prev.Server.LastScan.IsZero() || !next.Server.LastScan.After(prev.Server.LastScan) {
+		return EventDevicePortsChanged{}, false
+	}
+	opened, closed := prev.Server.Ports.Diff(next.Server.Ports)
+	if len(opened) == 0 && len(closed) == 0 {
+		return EventDevicePortsChanged{}, false
+	}
+	return EventDevicePortsChanged{Device: next, Opened: opened, Closed: closed}, true
+}
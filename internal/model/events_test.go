@@ -6,7 +6,9 @@ package model
 
 import (
 	"net"
+	"slices"
 	"testing"
+	"time"
 )
 
 func TestEventDeviceDiscoveredString(t *testing.T) {
@@ -59,3 +61,71 @@ func TestEventDeviceUpdatedString(t *testing.T) {
 		t.Errorf("expected: %s, got: %s", want, got)
 	}
 }
+
+func TestDevicePortsChanged(t *testing.T) {
+	ts, _ := time.Parse(time.DateOnly, "2011-01-01")
+	prev := Device{
+		Addr:   MustParseAddr("192.168.1.1"),
+		Server: Server{Ports: IntSliceToPortList([]int{22, 80}), LastScan: ts},
+	}
+	tests := map[string]struct {
+		next       Server
+		wantOk     bool
+		wantOpened []int
+		wantClosed []int
+	}{
+		"Same": {
+			next: Server{Ports: IntSliceToPortList([]int{22, 80}), LastScan: ts.Add(time.Hour)},
+		},
+		"NotNewer": {
+			next: Server{Ports: IntSliceToPortList([]int{443}), LastScan: ts},
+		},
+		"OpenedAndClosed": {
+			next:       Server{Ports: IntSliceToPortList([]int{22, 443}), LastScan: ts.Add(time.Hour)},
+			wantOk:     true,
+			wantOpened: []int{443},
+			wantClosed: []int{80},
+		},
+		"AllClosed": {
+			next:       Server{LastScan: ts.Add(time.Hour)},
+			wantOk:     true,
+			wantClosed: []int{22, 80},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			next := prev
+			next.Server = tc.next
+			got, ok := DevicePortsChanged(prev, next)
+			if ok != tc.wantOk {
+				t.Fatalf("ok want: %t, got %t", tc.wantOk, ok)
+			}
+			if !slices.Equal(got.Opened, tc.wantOpened) {
+				t.Errorf("opened want: %v, got %v", tc.wantOpened, got.Opened)
+			}
+			if !slices.Equal(got.Closed, tc.wantClosed) {
+				t.Errorf("closed want: %v, got %v", tc.wantClosed, got.Closed)
+			}
+		})
+	}
+
+	_, ok := DevicePortsChanged(Device{}, prev)
+	if ok {
+		t.Error("first scan should not raise an event")
+	}
+}
+
+func TestEventDevicePortsChangedString(t *testing.T) {
+	e := EventDevicePortsChanged{
+		Device: Device{Addr: MustParseAddr("192.168.1.1")},
+		Opened: []int{443},
+		Closed: []int{80},
+	}
+	want := "192.168.1.1 opened [443] closed [80]"
+	got := e.String()
+
+	if got != want {
+		t.Errorf("expected: %s, got: %s", want, got)
+	}
+}
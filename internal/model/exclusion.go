@@ -0,0 +1,123 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+)
+
+// ExclusionAction is a probe which is never sent to the addresses of an exclusion
+type ExclusionAction string
+
+const (
+	// ExcludeScan keeps the addresses out of network scans and the active enrichment probes,
+	// port scans included
+	ExcludeScan ExclusionAction = "scan"
+	// ExcludePing keeps the addresses out of the performance pinger and the ping tools
+	ExcludePing ExclusionAction = "ping"
+	// ExcludePortScan keeps the addresses out of the port scans
+	ExcludePortScan ExclusionAction = "portscan"
+)
+
+var (
+	ErrExclusionDoesNotExist = errors.New("exclusion does not exist")
+	ErrExclusionNoAction     = errors.New("exclusion must exclude scan, ping or portscan")
+	ErrAddrExcluded          = errors.New("address is excluded")
+)
+
+// Exclusion is a range of addresses mason keeps its probes away from, such as fragile OT
+// gear.  Configured exclusions come from the config file and can not be changed at runtime.
+type Exclusion struct {
+	Name       string
+	Prefix     Prefix
+	Scan       bool
+	Ping       bool
+	PortScan   bool
+	Note       string
+	Configured bool
+}
+
+// ParseAddrOrPrefix parses a prefix, or an address as the prefix holding only the address
+func ParseAddrOrPrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return p, err
+		}
+		return p.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Excludes reports if the action is never sent to the address
+func (x Exclusion) Excludes(addr Addr, action ExclusionAction) bool {
+	if !x.Prefix.P.IsValid() || !x.Prefix.Contains(addr) {
+		return false
+	}
+	switch action {
+	case ExcludeScan:
+		return x.Scan
+	case ExcludePing:
+		return x.Ping
+	case ExcludePortScan:
+		return x.PortScan || x.Scan
+	}
+	return false
+}
+
+// Actions returns the actions which are excluded
+func (x Exclusion) Actions() []ExclusionAction {
+	actions := make([]ExclusionAction, 0, 3)
+	if x.Scan {
+		actions = append(actions, ExcludeScan)
+	}
+	if x.Ping {
+		actions = append(actions, ExcludePing)
+	}
+	if x.PortScan {
+		actions = append(actions, ExcludePortScan)
+	}
+	return actions
+}
+
+// Exclusions are all the exclusions which apply
+type Exclusions []Exclusion
+
+// Excludes reports if any of the exclusions keeps the action away from the address
+func (xs Exclusions) Excludes(addr Addr, action ExclusionAction) bool {
+	for _, x := range xs {
+		if x.Excludes(addr, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matching returns the exclusions whose range holds the address
+func (xs Exclusions) Matching(addr Addr) Exclusions {
+	matches := make(Exclusions, 0)
+	for _, x := range xs {
+		if x.Prefix.P.IsValid() && x.Prefix.Contains(addr) {
+			matches = append(matches, x)
+		}
+	}
+	return matches
+}
+
+// Filter returns the filter without the devices the action is excluded from
+func (xs Exclusions) Filter(action ExclusionAction, filter DeviceFilter) DeviceFilter {
+	return func(d Device) bool {
+		if xs.Excludes(d.Addr, action) {
+			return false
+		}
+		return filter == nil || filter(d)
+	}
+}
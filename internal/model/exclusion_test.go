@@ -0,0 +1,84 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestParseAddrOrPrefix(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		want    netip.Prefix
+		wantErr bool
+	}{
+		"Addr":       {input: "10.0.0.5", want: netip.MustParsePrefix("10.0.0.5/32")},
+		"Addr6":      {input: "fd00::1", want: netip.MustParsePrefix("fd00::1/128")},
+		"Prefix":     {input: "10.0.0.0/24", want: netip.MustParsePrefix("10.0.0.0/24")},
+		"HostPrefix": {input: "10.0.0.7/24", want: netip.MustParsePrefix("10.0.0.0/24")},
+		"Invalid":    {input: "plc", wantErr: true},
+		"BadBits":    {input: "10.0.0.0/33", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseAddrOrPrefix(tc.input)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error want: %t, got: %v", tc.wantErr, err)
+			}
+			if !tc.wantErr && got != tc.want {
+				t.Errorf("prefix want: %s, got: %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestExclusions_Excludes(t *testing.T) {
+	xs := Exclusions{
+		{Name: "plcs", Prefix: MustParsePrefix("10.1.0.0/24"), Scan: true, Ping: true},
+		{Name: "hmi", Prefix: MustParsePrefix("10.2.0.9/32"), PortScan: true},
+	}
+	tests := map[string]struct {
+		addr   string
+		action ExclusionAction
+		want   bool
+	}{
+		"ScanInRange":        {addr: "10.1.0.20", action: ExcludeScan, want: true},
+		"PingInRange":        {addr: "10.1.0.20", action: ExcludePing, want: true},
+		"ScanCoversPortScan": {addr: "10.1.0.20", action: ExcludePortScan, want: true},
+		"OutsideRange":       {addr: "10.1.1.20", action: ExcludeScan, want: false},
+		"PortScanOnly":       {addr: "10.2.0.9", action: ExcludePortScan, want: true},
+		"PortScanOnlyPing":   {addr: "10.2.0.9", action: ExcludePing, want: false},
+		"PortScanOnlyScan":   {addr: "10.2.0.9", action: ExcludeScan, want: false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := xs.Excludes(MustParseAddr(tc.addr), tc.action)
+			if got != tc.want {
+				t.Errorf("excludes want: %t, got: %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestExclusions_Filter(t *testing.T) {
+	xs := Exclusions{{Name: "plcs", Prefix: MustParsePrefix("10.1.0.0/24"), Ping: true}}
+	filter := xs.Filter(ExcludePing, func(d Device) bool { return d.Name != "skip" })
+	tests := map[string]struct {
+		d    Device
+		want bool
+	}{
+		"Excluded":    {d: Device{Addr: MustParseAddr("10.1.0.3")}, want: false},
+		"Allowed":     {d: Device{Addr: MustParseAddr("10.1.1.3")}, want: true},
+		"InnerFilter": {d: Device{Addr: MustParseAddr("10.1.1.3"), Name: "skip"}, want: false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := filter(tc.d); got != tc.want {
+				t.Errorf("filter want: %t, got: %t", tc.want, got)
+			}
+		})
+	}
+}
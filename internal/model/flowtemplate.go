@@ -0,0 +1,27 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import "time"
+
+type (
+	// FlowTemplate is an IPFIX template announced by an exporter for one of its observation
+	// domains, kept so the data sets it describes can be read after a restart before the
+	// exporter announces it again
+	FlowTemplate struct {
+		Exporter  Addr
+		DomainID  int
+		ID        int
+		Fields    []FlowTemplateField
+		UpdatedAt time.Time
+	}
+
+	// FlowTemplateField is the information element and length of a field of a template
+	FlowTemplateField struct {
+		ID               uint16
+		Length           int
+		EnterpriseNumber uint32
+	}
+)
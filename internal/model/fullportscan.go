@@ -0,0 +1,81 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// MaxPort is the last tcp port
+const MaxPort = 65535
+
+var ErrFullPortScanDoesNotExist = errors.New("full port scan does not exist")
+
+// FullPortScan is the progress of a sweep of every tcp port of a device.  The sweep is done a
+// chunk of ports at a time and NextPort is stored after each chunk, an interrupted sweep
+// resumes from it.
+type FullPortScan struct {
+	Addr      Addr
+	NextPort  int
+	OpenPorts PortList
+	Started   time.Time
+	Updated   time.Time
+	Completed time.Time
+}
+
+// NewFullPortScan starts a sweep of the device from the first port
+func NewFullPortScan(addr Addr, now time.Time) FullPortScan {
+	return FullPortScan{Addr: addr, NextPort: 1, Started: now}
+}
+
+// Done reports if the sweep reached the last port
+func (fs FullPortScan) Done() bool {
+	return fs.NextPort > MaxPort
+}
+
+// Percent is how far the sweep has come through the ports
+func (fs FullPortScan) Percent() int {
+	if fs.Done() {
+		return 100
+	}
+	return (fs.NextPort - 1) * 100 / MaxPort
+}
+
+// Due reports if a chunk is to be scanned, either the sweep is not done or the last sweep
+// completed longer than the rescan interval ago
+func (fs FullPortScan) Due(now time.Time, rescan time.Duration) bool {
+	return !fs.Done() || now.Sub(fs.Completed) > rescan
+}
+
+// NextChunk returns the next size ports of the sweep
+func (fs FullPortScan) NextChunk(size int) []int {
+	last := min(fs.NextPort+size-1, MaxPort)
+	ports := make([]int, 0, max(last-fs.NextPort+1, 0))
+	for port := fs.NextPort; port <= last; port++ {
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// Record advances the sweep past the scanned ports, adding the open ones
+func (fs FullPortScan) Record(scanned []int, open []int, now time.Time) FullPortScan {
+	if len(scanned) > 0 {
+		fs.NextPort = slices.Max(scanned) + 1
+	}
+	fs.OpenPorts = fs.OpenPorts.Clone()
+	for _, port := range open {
+		if !slices.Contains(fs.OpenPorts.Ports, port) {
+			fs.OpenPorts.Ports = append(fs.OpenPorts.Ports, port)
+		}
+	}
+	slices.Sort(fs.OpenPorts.Ports)
+	fs.Updated = now
+	if fs.Done() {
+		fs.Completed = now
+	}
+	return fs
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFullPortScan_Sweep(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fs := NewFullPortScan(MustParseAddr("192.168.1.20"), ts)
+	chunks := 0
+	for !fs.Done() {
+		chunk := fs.NextChunk(10000)
+		var open []int
+		if chunk[0] == 1 {
+			open = []int{443, 22}
+		}
+		if chunk[0] == 60001 {
+			open = []int{65535}
+		}
+		fs = fs.Record(chunk, open, ts.Add(time.Duration(chunks)*time.Minute))
+		chunks++
+	}
+	if chunks != 7 {
+		t.Errorf("want 7 chunks, got: %d", chunks)
+	}
+	if diff := cmp.Diff([]int{22, 443, 65535}, fs.OpenPorts.Ports); diff != "" {
+		t.Errorf("open ports mismatch (-want +got):\n%s", diff)
+	}
+	if fs.Percent() != 100 || !fs.Completed.Equal(ts.Add(6*time.Minute)) {
+		t.Errorf("want completed sweep, got: %d%% at %s", fs.Percent(), fs.Completed)
+	}
+	if fs.Due(fs.Completed.Add(time.Hour), 24*time.Hour) {
+		t.Error("want completed sweep not due before the rescan interval")
+	}
+	if !fs.Due(fs.Completed.Add(25*time.Hour), 24*time.Hour) {
+		t.Error("want completed sweep due after the rescan interval")
+	}
+}
+
+func TestFullPortScan_NextChunk(t *testing.T) {
+	fs := FullPortScan{NextPort: 65530}
+	if diff := cmp.Diff([]int{65530, 65531, 65532, 65533, 65534, 65535}, fs.NextChunk(100)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if got := (FullPortScan{NextPort: 32768}).Percent(); got != 49 {
+		t.Errorf("want 49%%, got: %d", got)
+	}
+}
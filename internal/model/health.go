@@ -0,0 +1,117 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+type (
+	HealthProbeKind string
+
+	// HealthProbe is one measurement of an internet health anchor or url
+	HealthProbe struct {
+		Start   time.Time
+		Kind    HealthProbeKind
+		Target  string
+		Latency time.Duration
+		Loss    float64
+		Hops    int
+		Err     string
+	}
+
+	// HealthTargetStatus summarizes the probes of one target
+	HealthTargetStatus struct {
+		Kind     HealthProbeKind
+		Target   string
+		Last     HealthProbe
+		Average  time.Duration
+		Probes   int
+		Failures int
+		Hops     int
+	}
+
+	InternetStatus string
+)
+
+const (
+	HealthProbePing HealthProbeKind = "ping"
+	HealthProbeHTTP HealthProbeKind = "http"
+
+	InternetStatusUnknown  InternetStatus = "unknown"
+	InternetStatusUp       InternetStatus = "up"
+	InternetStatusDegraded InternetStatus = "degraded"
+	InternetStatusDown     InternetStatus = "down"
+)
+
+// Failed reports if the target did not answer the probe
+func (hp HealthProbe) Failed() bool {
+	return hp.Err != "" || (hp.Kind == HealthProbePing && hp.Loss >= 1)
+}
+
+// SummarizeHealthProbes groups the probes by target, ordered by kind and target.  The average
+// latency only covers the probes which did not fail, the hops are from the latest probe which
+// ran a traceroute.
+func SummarizeHealthProbes(probes []HealthProbe) []HealthTargetStatus {
+	index := make(map[HealthProbeKind]map[string]int)
+	ret := make([]HealthTargetStatus, 0)
+	totals := make([]time.Duration, 0)
+	for _, p := range probes {
+		if index[p.Kind] == nil {
+			index[p.Kind] = make(map[string]int)
+		}
+		idx, ok := index[p.Kind][p.Target]
+		if !ok {
+			idx = len(ret)
+			index[p.Kind][p.Target] = idx
+			ret = append(ret, HealthTargetStatus{Kind: p.Kind, Target: p.Target})
+			totals = append(totals, 0)
+		}
+		ts := &ret[idx]
+		ts.Probes++
+		if !p.Start.Before(ts.Last.Start) {
+			ts.Last = p
+		}
+		if p.Hops > 0 {
+			ts.Hops = p.Hops
+		}
+		if p.Failed() {
+			ts.Failures++
+			continue
+		}
+		totals[idx] += p.Latency
+	}
+	for idx := range ret {
+		if ok := ret[idx].Probes - ret[idx].Failures; ok > 0 {
+			ret[idx].Average = totals[idx] / time.Duration(ok)
+		}
+	}
+	slices.SortFunc(ret, func(a, b HealthTargetStatus) int {
+		return cmp.Or(cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Target, b.Target))
+	})
+	return ret
+}
+
+// GetInternetStatus is up when the latest probe of every target answered, down when none did
+func GetInternetStatus(targets []HealthTargetStatus) InternetStatus {
+	if len(targets) == 0 {
+		return InternetStatusUnknown
+	}
+	failed := 0
+	for _, ts := range targets {
+		if ts.Last.Failed() {
+			failed++
+		}
+	}
+	switch failed {
+	case 0:
+		return InternetStatusUp
+	case len(targets):
+		return InternetStatusDown
+	}
+	return InternetStatusDegraded
+}
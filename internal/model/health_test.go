@@ -0,0 +1,77 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSummarizeHealthProbes(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	ping, web := HealthProbePing, HealthProbeHTTP
+	later := start.Add(time.Minute)
+	probes := []HealthProbe{
+		{Start: start, Kind: ping, Target: "8.8.8.8", Latency: 10 * time.Millisecond, Hops: 9},
+		{Start: start, Kind: web, Target: "https://example.com", Latency: time.Second},
+		{Start: start, Kind: ping, Target: "1.1.1.1", Latency: 4 * time.Millisecond},
+		{Start: later, Kind: ping, Target: "8.8.8.8", Latency: 20 * time.Millisecond},
+		{Start: later, Kind: ping, Target: "1.1.1.1", Loss: 1},
+		{Start: later, Kind: web, Target: "https://example.com", Err: "timeout"},
+	}
+	want := []HealthTargetStatus{
+		{
+			Kind:     HealthProbeHTTP,
+			Target:   "https://example.com",
+			Last:     probes[5],
+			Average:  time.Second,
+			Probes:   2,
+			Failures: 1,
+		},
+		{
+			Kind:     HealthProbePing,
+			Target:   "1.1.1.1",
+			Last:     probes[4],
+			Average:  4 * time.Millisecond,
+			Probes:   2,
+			Failures: 1,
+		},
+		{
+			Kind:    HealthProbePing,
+			Target:  "8.8.8.8",
+			Last:    probes[3],
+			Average: 15 * time.Millisecond,
+			Probes:  2,
+			Hops:    9,
+		},
+	}
+	got := SummarizeHealthProbes(probes)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestGetInternetStatus(t *testing.T) {
+	up := HealthTargetStatus{Last: HealthProbe{Kind: HealthProbePing}}
+	down := HealthTargetStatus{Last: HealthProbe{Kind: HealthProbePing, Loss: 1}}
+	tests := map[string]struct {
+		targets []HealthTargetStatus
+		want    InternetStatus
+	}{
+		"NoProbes": {want: InternetStatusUnknown},
+		"Up":       {targets: []HealthTargetStatus{up, up}, want: InternetStatusUp},
+		"Degraded": {targets: []HealthTargetStatus{up, down}, want: InternetStatusDegraded},
+		"Down":     {targets: []HealthTargetStatus{down, down}, want: InternetStatusDown},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := GetInternetStatus(tc.targets); got != tc.want {
+				t.Errorf("want: %s, got: %s", tc.want, got)
+			}
+		})
+	}
+}
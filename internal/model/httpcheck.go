@@ -0,0 +1,171 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+type (
+	// HTTPCheck is an http(s) endpoint requested every interval.  It is up when the response
+	// has the expected status (any below 400 when zero) and, when set, contains the expected
+	// body text.
+	HTTPCheck struct {
+		Name         string
+		URL          string
+		Method       string
+		ExpectStatus int
+		ExpectBody   string
+		Interval     time.Duration
+		// Insecure skips the verification of the server certificate
+		Insecure bool
+	}
+
+	// HTTPCheckResult is one run of a check, Err is why the check was down
+	HTTPCheckResult struct {
+		Start   time.Time
+		Name    string
+		Status  int
+		Latency time.Duration
+		Err     string
+	}
+
+	// HTTPCheckStatus summarizes the results of one check
+	HTTPCheckStatus struct {
+		Check    HTTPCheck
+		Last     HTTPCheckResult
+		Average  time.Duration
+		Results  int
+		Failures int
+	}
+
+	CheckState string
+)
+
+const (
+	CheckPending CheckState = "pending"
+	CheckUp      CheckState = "up"
+	CheckDown    CheckState = "down"
+)
+
+var (
+	ErrHTTPCheckDoesNotExist    = errors.New("http check does not exist")
+	ErrHTTPCheckNameRequired    = errors.New("http check name is required")
+	ErrInvalidHTTPCheckURL      = errors.New("http check url must be an http or https url")
+	ErrInvalidHTTPCheckMethod   = errors.New("invalid http check method")
+	ErrInvalidHTTPCheckStatus   = errors.New("invalid http check expected status")
+	ErrInvalidHTTPCheckInterval = errors.New("http check interval must be positive")
+)
+
+// Normalize validates the check and returns it with the method upper cased, GET when empty
+func (c HTTPCheck) Normalize() (HTTPCheck, error) {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return c, ErrHTTPCheckNameRequired
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return c, ErrInvalidHTTPCheckURL
+	}
+	c.Method = strings.ToUpper(strings.TrimSpace(c.Method))
+	switch c.Method {
+	case "":
+		c.Method = http.MethodGet
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodOptions:
+	default:
+		return c, ErrInvalidHTTPCheckMethod
+	}
+	if c.ExpectStatus != 0 && (c.ExpectStatus < 100 || c.ExpectStatus > 599) {
+		return c, ErrInvalidHTTPCheckStatus
+	}
+	if c.Interval <= 0 {
+		return c, ErrInvalidHTTPCheckInterval
+	}
+	return c, nil
+}
+
+// Evaluate returns why the response does not meet the expectations of the check, nil when
+// the check is up
+func (c HTTPCheck) Evaluate(status int, body []byte) error {
+	switch {
+	case c.ExpectStatus == 0 && status >= http.StatusBadRequest:
+		return fmt.Errorf("status %d", status)
+	case c.ExpectStatus != 0 && status != c.ExpectStatus:
+		return fmt.Errorf("status %d, expected %d", status, c.ExpectStatus)
+	case c.ExpectBody != "" && !bytes.Contains(body, []byte(c.ExpectBody)):
+		return fmt.Errorf("body does not contain %q", c.ExpectBody)
+	}
+	return nil
+}
+
+// Failed reports if the check was down
+func (r HTTPCheckResult) Failed() bool {
+	return r.Err != ""
+}
+
+// State is pending until the check has run, then the state of its latest result
+func (s HTTPCheckStatus) State() CheckState {
+	switch {
+	case s.Results == 0:
+		return CheckPending
+	case s.Last.Failed():
+		return CheckDown
+	}
+	return CheckUp
+}
+
+// Uptime is the percentage of the results which were up
+func (s HTTPCheckStatus) Uptime() float64 {
+	if s.Results == 0 {
+		return 0
+	}
+	return float64(s.Results-s.Failures) * 100 / float64(s.Results)
+}
+
+// SummarizeHTTPChecks returns the status of each check ordered by name, results of checks
+// which no longer exist are left out.  The average latency only covers the results which
+// were up.
+func SummarizeHTTPChecks(checks []HTTPCheck, results []HTTPCheckResult) []HTTPCheckStatus {
+	ret := make([]HTTPCheckStatus, 0, len(checks))
+	index := make(map[string]int, len(checks))
+	totals := make([]time.Duration, len(checks))
+	for _, c := range checks {
+		index[c.Name] = len(ret)
+		ret = append(ret, HTTPCheckStatus{Check: c})
+	}
+	for _, r := range results {
+		idx, ok := index[r.Name]
+		if !ok {
+			continue
+		}
+		s := &ret[idx]
+		s.Results++
+		if !r.Start.Before(s.Last.Start) {
+			s.Last = r
+		}
+		if r.Failed() {
+			s.Failures++
+			continue
+		}
+		totals[idx] += r.Latency
+	}
+	for idx := range ret {
+		if ok := ret[idx].Results - ret[idx].Failures; ok > 0 {
+			ret[idx].Average = totals[idx] / time.Duration(ok)
+		}
+	}
+	slices.SortFunc(ret, func(a, b HTTPCheckStatus) int {
+		return cmp.Compare(a.Check.Name, b.Check.Name)
+	})
+	return ret
+}
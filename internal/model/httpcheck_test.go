@@ -0,0 +1,114 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHTTPCheck_Normalize(t *testing.T) {
+	valid := HTTPCheck{Name: "wiki", URL: "https://wiki.lan/health", Interval: time.Minute}
+	tests := map[string]struct {
+		edit    func(c *HTTPCheck)
+		want    string
+		wantErr error
+	}{
+		"DefaultMethod": {edit: func(c *HTTPCheck) {}, want: "GET"},
+		"UpperMethod":   {edit: func(c *HTTPCheck) { c.Method = "head" }, want: "HEAD"},
+		"NoName":        {edit: func(c *HTTPCheck) { c.Name = " " }, wantErr: ErrHTTPCheckNameRequired},
+		"Scheme": {
+			edit:    func(c *HTTPCheck) { c.URL = "ftp://wiki.lan" },
+			wantErr: ErrInvalidHTTPCheckURL,
+		},
+		"Method": {
+			edit:    func(c *HTTPCheck) { c.Method = "DELETE" },
+			wantErr: ErrInvalidHTTPCheckMethod,
+		},
+		"Status": {
+			edit:    func(c *HTTPCheck) { c.ExpectStatus = 42 },
+			wantErr: ErrInvalidHTTPCheckStatus,
+		},
+		"Interval": {
+			edit:    func(c *HTTPCheck) { c.Interval = 0 },
+			wantErr: ErrInvalidHTTPCheckInterval,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := valid
+			tc.edit(&c)
+			got, err := c.Normalize()
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("want: %v, got: %v", tc.wantErr, err)
+			}
+			if err == nil && got.Method != tc.want {
+				t.Errorf("method want: %s, got: %s", tc.want, got.Method)
+			}
+		})
+	}
+}
+
+func TestHTTPCheck_Evaluate(t *testing.T) {
+	tests := map[string]struct {
+		check  HTTPCheck
+		status int
+		body   string
+		up     bool
+	}{
+		"AnyStatus":     {check: HTTPCheck{}, status: 302, up: true},
+		"ServerError":   {check: HTTPCheck{}, status: 503, up: false},
+		"Expected":      {check: HTTPCheck{ExpectStatus: 401}, status: 401, up: true},
+		"Unexpected":    {check: HTTPCheck{ExpectStatus: 200}, status: 204, up: false},
+		"BodyFound":     {check: HTTPCheck{ExpectBody: "ok"}, status: 200, body: `{"ok":1}`, up: true},
+		"BodyMissing":   {check: HTTPCheck{ExpectBody: "ok"}, status: 200, body: "degraded", up: false},
+		"BodyAndStatus": {check: HTTPCheck{ExpectBody: "ok"}, status: 500, body: "ok", up: false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tc.check.Evaluate(tc.status, []byte(tc.body))
+			if (err == nil) != tc.up {
+				t.Errorf("up want: %v, got error: %v", tc.up, err)
+			}
+		})
+	}
+}
+
+func TestSummarizeHTTPChecks(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	later := start.Add(time.Minute)
+	wiki := HTTPCheck{Name: "wiki", URL: "https://wiki.lan"}
+	git := HTTPCheck{Name: "git", URL: "https://git.lan"}
+	idle := HTTPCheck{Name: "idle", URL: "https://idle.lan"}
+	results := []HTTPCheckResult{
+		{Start: start, Name: "wiki", Status: 200, Latency: 10 * time.Millisecond},
+		{Start: start, Name: "git", Status: 200, Latency: 30 * time.Millisecond},
+		{Start: start, Name: "removed", Status: 200},
+		{Start: later, Name: "wiki", Status: 200, Latency: 20 * time.Millisecond},
+		{Start: later, Name: "git", Status: 502, Err: "status 502"},
+	}
+	want := []HTTPCheckStatus{
+		{Check: git, Last: results[4], Average: 30 * time.Millisecond, Results: 2, Failures: 1},
+		{Check: idle},
+		{Check: wiki, Last: results[3], Average: 15 * time.Millisecond, Results: 2},
+	}
+	got := SummarizeHTTPChecks([]HTTPCheck{wiki, git, idle}, results)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	states := []CheckState{CheckDown, CheckPending, CheckUp}
+	for idx, s := range got {
+		if s.State() != states[idx] {
+			t.Errorf("%s state want: %s, got: %s", s.Check.Name, states[idx], s.State())
+		}
+	}
+	if uptime := got[0].Uptime(); uptime != 50 {
+		t.Errorf("uptime want: 50, got: %v", uptime)
+	}
+}
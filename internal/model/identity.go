@@ -0,0 +1,124 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// MergedDeviceTag marks the record a device had at its old address once it has been merged
+// into the record at the new address
+var MergedDeviceTag = Tag{Val: "MergedDevice"}
+
+// MergeIdentity folds the record the device had at an older address into it.  Tags are
+// combined, notes are joined and the remaining settings carry over where the device has none
+// of its own.
+func (d Device) MergeIdentity(old Device) Device {
+	if (d.Name == "" || d.IsNameAddr()) && !old.IsNameAddr() {
+		d.Name = old.Name
+	}
+	if d.MAC.IsEmpty() {
+		d.MAC = old.MAC
+	}
+	if macs := unionMACs(d.MACs(), old.MACs()); len(macs) > 1 {
+		d.ObservedMACs = macs
+	}
+	d.Addresses, _ = d.Addresses.merge(old.Addresses, d.Addr)
+	if !old.DiscoveredAt.IsZero() &&
+		(d.DiscoveredAt.IsZero() || old.DiscoveredAt.Before(d.DiscoveredAt)) {
+		d.DiscoveredAt = old.DiscoveredAt
+	}
+
+	tags := slices.Clone(d.Meta.Tags)
+	for _, tag := range old.Meta.Tags {
+		if !tag.Equal(MergedDeviceTag) {
+			tags = Add(tag, tags)
+		}
+	}
+	d.Meta.Tags = tags
+	switch {
+	case d.Meta.Notes == "":
+		d.Meta.Notes = old.Meta.Notes
+	case old.Meta.Notes != "" && old.Meta.Notes != d.Meta.Notes:
+		d.Meta.Notes = d.Meta.Notes + "\n" + old.Meta.Notes
+	}
+	d.Meta.Owner = cmp.Or(d.Meta.Owner, old.Meta.Owner)
+	d.Meta.Site = cmp.Or(d.Meta.Site, old.Meta.Site)
+	d.Meta.Location = cmp.Or(d.Meta.Location, old.Meta.Location)
+	d.Meta.PurchaseDate = cmp.Or(d.Meta.PurchaseDate, old.Meta.PurchaseDate)
+	d.Meta.Serial = cmp.Or(d.Meta.Serial, old.Meta.Serial)
+	d.Meta.AssetTag = cmp.Or(d.Meta.AssetTag, old.Meta.AssetTag)
+	d.Meta.DnsName = cmp.Or(d.Meta.DnsName, old.Meta.DnsName)
+	d.Meta.Manufacturer = cmp.Or(d.Meta.Manufacturer, old.Meta.Manufacturer)
+	d.Meta.MDNSName = cmp.Or(d.Meta.MDNSName, old.Meta.MDNSName)
+	d.Meta.DHCPHostname = cmp.Or(d.Meta.DHCPHostname, old.Meta.DHCPHostname)
+	d.Meta.DHCPFingerprint = cmp.Or(d.Meta.DHCPFingerprint, old.Meta.DHCPFingerprint)
+	d.Meta.DHCPVendorClass = cmp.Or(d.Meta.DHCPVendorClass, old.Meta.DHCPVendorClass)
+	d.Meta.DeviceType = cmp.Or(d.Meta.DeviceType, old.Meta.DeviceType)
+	if d.Meta.Policy.IsEmpty() {
+		d.Meta.Policy = old.Meta.Policy
+	}
+	if d.Meta.Approval == "" || d.Meta.Approval == ApprovalUnknown {
+		d.Meta.Approval = old.Meta.Approval
+	}
+
+	if !old.PerformancePing.FirstSeen.IsZero() &&
+		(d.PerformancePing.FirstSeen.IsZero() ||
+			old.PerformancePing.FirstSeen.Before(d.PerformancePing.FirstSeen)) {
+		d.PerformancePing.FirstSeen = old.PerformancePing.FirstSeen
+	}
+	return d
+}
+
+// MACs returns every MAC the device has been seen with
+func (d Device) MACs() []MAC {
+	if len(d.ObservedMACs) > 0 {
+		return d.ObservedMACs
+	}
+	if d.MAC.IsEmpty() {
+		return nil
+	}
+	return []MAC{d.MAC}
+}
+
+// HasMAC reports if the device has been seen with the MAC
+func (d Device) HasMAC(mac MAC) bool {
+	return slices.ContainsFunc(d.MACs(), func(m MAC) bool { return m.Compare(mac) == 0 })
+}
+
+// CorrelationKeys are the identities the device announces about itself, used to recognise a
+// device with a randomized MAC once it has rotated its MAC.  The mDNS name is unique on a
+// link while a DHCP hostname only counts together with the DHCP fingerprint.
+func (d Device) CorrelationKeys() []string {
+	keys := make([]string, 0, 3)
+	if d.Meta.MDNSName != "" {
+		keys = append(keys, "mdns:"+strings.ToLower(d.Meta.MDNSName))
+	}
+	if d.Meta.DHCPHostname != "" && d.Meta.DHCPFingerprint != "" {
+		keys = append(
+			keys,
+			"dhcp:"+strings.ToLower(d.Meta.DHCPHostname)+"/"+d.Meta.DHCPFingerprint,
+		)
+	}
+	if d.Meta.DnsName != "" {
+		keys = append(keys, "dns:"+strings.ToLower(d.Meta.DnsName))
+	}
+	return keys
+}
+
+func unionMACs(macs []MAC, more []MAC) []MAC {
+	ret := slices.Clone(macs)
+	for _, mac := range more {
+		if mac.IsEmpty() {
+			continue
+		}
+		if !slices.ContainsFunc(ret, func(m MAC) bool { return m.Compare(mac) == 0 }) {
+			ret = append(ret, mac)
+		}
+	}
+	return ret
+}
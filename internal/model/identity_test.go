@@ -0,0 +1,170 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestDevice_MergeIdentity(t *testing.T) {
+	mac := MustParseMAC("00:00:5e:00:53:01")
+	earlier := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := earlier.Add(24 * time.Hour)
+	tests := map[string]struct {
+		device Device
+		old    Device
+		want   Device
+	}{
+		"CarriesOver": {
+			device: Device{
+				Name:         "192.168.1.9",
+				Addr:         MustParseAddr("192.168.1.9"),
+				MAC:          mac,
+				DiscoveredAt: later,
+				Meta:         Meta{Approval: ApprovalUnknown, Tags: Tags{{Val: "new"}}},
+			},
+			old: Device{
+				Name:         "nas",
+				Addr:         MustParseAddr("192.168.1.5"),
+				MAC:          mac,
+				DiscoveredAt: earlier,
+				Meta: Meta{
+					Approval: ApprovalApproved,
+					Tags:     Tags{{Val: "storage"}, MergedDeviceTag},
+					Notes:    "rack 2",
+					Owner:    "it",
+					Policy:   MonitoringPolicy{PingInterval: time.Minute},
+				},
+			},
+			want: Device{
+				Name:         "nas",
+				Addr:         MustParseAddr("192.168.1.9"),
+				MAC:          mac,
+				DiscoveredAt: earlier,
+				Meta: Meta{
+					Approval: ApprovalApproved,
+					Tags:     Tags{{Val: "new"}, {Val: "storage"}},
+					Notes:    "rack 2",
+					Owner:    "it",
+					Policy:   MonitoringPolicy{PingInterval: time.Minute},
+				},
+			},
+		},
+		"KeepsOwnSettings": {
+			device: Device{
+				Name: "nas-2",
+				Addr: MustParseAddr("192.168.1.9"),
+				Meta: Meta{Approval: ApprovalBlocked, Notes: "moved", Owner: "ops"},
+			},
+			old: Device{
+				Name: "nas",
+				Addr: MustParseAddr("192.168.1.5"),
+				Meta: Meta{Approval: ApprovalApproved, Notes: "rack 2", Owner: "it"},
+			},
+			want: Device{
+				Name: "nas-2",
+				Addr: MustParseAddr("192.168.1.9"),
+				Meta: Meta{
+					Approval: ApprovalBlocked,
+					Tags:     Tags{},
+					Notes:    "moved\nrack 2",
+					Owner:    "ops",
+				},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := tc.device.MergeIdentity(tc.old)
+			opts := cmp.Options{
+				cmpopts.EquateComparable(netip.Addr{}),
+				cmpopts.IgnoreUnexported(Device{}),
+				cmpopts.EquateEmpty(),
+			}
+			if diff := cmp.Diff(tc.want, got, opts); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDevice_CorrelationKeys(t *testing.T) {
+	tests := map[string]struct {
+		device Device
+		want   []string
+	}{
+		"None": {
+			device: Device{Meta: Meta{DHCPHostname: "phone"}},
+			want:   []string{},
+		},
+		"All": {
+			device: Device{Meta: Meta{
+				MDNSName:        "Phone.local",
+				DHCPHostname:    "Phone",
+				DHCPFingerprint: "1,3,6,15",
+				DnsName:         "phone.lan",
+			}},
+			want: []string{"mdns:phone.local", "dhcp:phone/1,3,6,15", "dns:phone.lan"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := tc.device.CorrelationKeys()
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDevice_MACs(t *testing.T) {
+	mac := MustParseMAC("da:00:5e:00:53:01")
+	rotated := MustParseMAC("da:00:5e:00:53:02")
+	tests := map[string]struct {
+		device Device
+		in     Device
+		want   []MAC
+	}{
+		"Empty": {
+			device: Device{},
+			in:     Device{},
+			want:   nil,
+		},
+		"Single": {
+			device: Device{MAC: mac},
+			in:     Device{MAC: mac},
+			want:   []MAC{mac},
+		},
+		"Rotated": {
+			device: Device{MAC: mac},
+			in:     Device{MAC: rotated},
+			want:   []MAC{mac, rotated},
+		},
+		"Observed": {
+			device: Device{MAC: rotated},
+			in:     Device{MAC: rotated, ObservedMACs: []MAC{mac, rotated}},
+			want:   []MAC{rotated, mac},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			merged, _ := tc.device.merge(tc.in)
+			got := merged.MACs()
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+			for _, mac := range tc.want {
+				if !merged.HasMAC(mac) {
+					t.Errorf("HasMAC(%s) want: true, got: false", mac)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"go4.org/netipx"
+)
+
+type ReservationKind string
+
+const (
+	// ReservationReserved holds an address back from use (gateways, future servers)
+	ReservationReserved ReservationKind = "reserved"
+	// ReservationStatic records an address which is manually assigned to a known host
+	ReservationStatic ReservationKind = "static"
+)
+
+var (
+	ErrReservationDoesNotExist = errors.New("reservation does not exist")
+	ErrInvalidReservationKind  = errors.New("invalid reservation kind")
+)
+
+// Reservation marks an address as set aside in the address plan
+type Reservation struct {
+	Addr Addr
+	Kind ReservationKind
+	MAC  MAC
+	Name string
+	Note string
+}
+
+func ParseReservationKind(s string) (ReservationKind, error) {
+	switch ReservationKind(s) {
+	case ReservationReserved, ReservationStatic:
+		return ReservationKind(s), nil
+	}
+	return "", ErrInvalidReservationKind
+}
+
+type AddressState string
+
+const (
+	AddressFree     AddressState = "free"
+	AddressUsed     AddressState = "used"
+	AddressReserved AddressState = "reserved"
+	AddressStatic   AddressState = "static"
+)
+
+// AddressAllocation is a single entry in the address grid of a network
+type AddressAllocation struct {
+	Addr  Addr
+	State AddressState
+	Name  string
+	Note  string
+}
+
+// AddressPlan summarizes how the addresses of a network are being used.  Addresses is only
+// filled in when the network is no larger than the requested grid size.  Warn is left for the
+// caller to set since the threshold is a configuration setting.
+type AddressPlan struct {
+	Network     Network
+	Size        int
+	Used        int
+	Reserved    int
+	Static      int
+	Addresses   []AddressAllocation
+	Utilization float64
+	Warn        bool
+}
+
+// Free returns the number of addresses which are not used or set aside
+func (p AddressPlan) Free() int {
+	return p.Size - p.Used - p.Reserved - p.Static
+}
+
+// OverThreshold reports if the utilization (as a percentage) is at or above the threshold,
+// a threshold of 0 disables the check
+func (p AddressPlan) OverThreshold(threshold int) bool {
+	return threshold > 0 && p.Utilization*100 >= float64(threshold)
+}
+
+// PrefixSize returns the number of addresses in the prefix, capped at math.MaxInt
+func PrefixSize(p Prefix) int {
+	hostbits := p.P.Addr().BitLen() - p.Bits()
+	if hostbits >= 62 {
+		return math.MaxInt
+	}
+	return 1 << hostbits
+}
+
+// BuildAddressPlan lays out the network using the known devices and reservations.  A
+// reservation takes precedence over a discovered device at the same address.
+func BuildAddressPlan(
+	n Network,
+	devices []Device,
+	reservations []Reservation,
+	maxgrid int,
+) AddressPlan {
+	plan := AddressPlan{
+		Network: n,
+		Size:    PrefixSize(n.Prefix),
+	}
+
+	allocs := make(map[Addr]AddressAllocation)
+	for _, d := range devices {
+		if !n.Contains(d) {
+			continue
+		}
+		allocs[d.Addr] = AddressAllocation{Addr: d.Addr, State: AddressUsed, Name: d.Name}
+	}
+	for _, r := range reservations {
+		if !n.Prefix.Contains(r.Addr) {
+			continue
+		}
+		a := AddressAllocation{Addr: r.Addr, Name: r.Name, Note: r.Note}
+		switch r.Kind {
+		case ReservationStatic:
+			a.State = AddressStatic
+		default:
+			a.State = AddressReserved
+		}
+		if prev, ok := allocs[r.Addr]; ok && a.Name == "" {
+			a.Name = prev.Name
+		}
+		allocs[r.Addr] = a
+	}
+	for _, a := range allocs {
+		switch a.State {
+		case AddressUsed:
+			plan.Used++
+		case AddressReserved:
+			plan.Reserved++
+		case AddressStatic:
+			plan.Static++
+		}
+	}
+	if plan.Size > 0 {
+		plan.Utilization = float64(plan.Used+plan.Reserved+plan.Static) / float64(plan.Size)
+	}
+
+	if plan.Size > maxgrid {
+		return plan
+	}
+	rng := netipx.RangeOfPrefix(n.Prefix.P)
+	plan.Addresses = make([]AddressAllocation, 0, plan.Size)
+	for ip := rng.From(); ip.IsValid() && ip.Compare(rng.To()) <= 0; ip = ip.Next() {
+		addr := AddrToModelAddr(ip)
+		a, ok := allocs[addr]
+		if !ok {
+			a = AddressAllocation{Addr: addr, State: AddressFree}
+		}
+		plan.Addresses = append(plan.Addresses, a)
+	}
+	return plan
+}
+
+// NetworkUsage is the number of devices known in a network at a time
+type NetworkUsage struct {
+	Time time.Time
+	Used int
+}
+
+// NetworkUsageHistory counts the devices of the network at each step from start to end.  A
+// device counts from when it was discovered, a deleted device until it was deleted; a device
+// without a discovery time counts throughout.
+func NetworkUsageHistory(
+	n Network,
+	devices []Device,
+	deleted []Tombstone,
+	start time.Time,
+	end time.Time,
+	step time.Duration,
+) []NetworkUsage {
+	if step <= 0 {
+		return nil
+	}
+	type span struct{ from, to time.Time }
+	spans := make([]span, 0, len(devices))
+	for _, d := range devices {
+		if n.Contains(d) {
+			spans = append(spans, span{from: discoveredAt(d)})
+		}
+	}
+	for _, t := range deleted {
+		if t.Kind == TombstoneDevice && n.Contains(t.Device) {
+			spans = append(spans, span{from: discoveredAt(t.Device), to: t.DeletedAt})
+		}
+	}
+	usage := make([]NetworkUsage, 0, int(end.Sub(start)/step)+1)
+	for ts := start; !ts.After(end); ts = ts.Add(step) {
+		u := NetworkUsage{Time: ts}
+		for _, s := range spans {
+			if s.from.After(ts) || !s.to.IsZero() && !s.to.After(ts) {
+				continue
+			}
+			u.Used++
+		}
+		usage = append(usage, u)
+	}
+	return usage
+}
+
+// discoveredAt is when the device was first discovered, or else first answered a ping
+func discoveredAt(d Device) time.Time {
+	if !d.DiscoveredAt.IsZero() {
+		return d.DiscoveredAt
+	}
+	return d.PerformancePing.FirstSeen
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPrefixSize(t *testing.T) {
+	tests := map[string]struct {
+		prefix string
+		want   int
+	}{
+		"Slash24":    {prefix: "192.168.1.0/24", want: 256},
+		"Slash32":    {prefix: "192.168.1.1/32", want: 1},
+		"V6Slash120": {prefix: "2001:db8::/120", want: 256},
+		"V6Slash64":  {prefix: "2001:db8::/64", want: math.MaxInt},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := PrefixSize(MustParsePrefix(tc.prefix))
+			if got != tc.want {
+				t.Errorf("size want: %d, got: %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildAddressPlan(t *testing.T) {
+	n := Network{Name: "test", Prefix: MustParsePrefix("192.168.1.0/29")}
+	devices := []Device{
+		{Name: "router", Addr: MustParseAddr("192.168.1.1")},
+		{Name: "nas", Addr: MustParseAddr("192.168.1.2")},
+		{Name: "elsewhere", Addr: MustParseAddr("10.0.0.1")},
+	}
+	reservations := []Reservation{
+		{Addr: MustParseAddr("192.168.1.2"), Kind: ReservationStatic},
+		{Addr: MustParseAddr("192.168.1.6"), Kind: ReservationReserved, Note: "printer"},
+		{Addr: MustParseAddr("10.0.0.2"), Kind: ReservationReserved},
+	}
+
+	plan := BuildAddressPlan(n, devices, reservations, 256)
+	if plan.Size != 8 {
+		t.Fatalf("size want: 8, got: %d", plan.Size)
+	}
+	if plan.Used != 1 || plan.Static != 1 || plan.Reserved != 1 || plan.Free() != 5 {
+		t.Errorf(
+			"counts mismatch used:%d static:%d reserved:%d free:%d",
+			plan.Used, plan.Static, plan.Reserved, plan.Free(),
+		)
+	}
+	if len(plan.Addresses) != 8 {
+		t.Fatalf("grid want: 8, got: %d", len(plan.Addresses))
+	}
+	if a := plan.Addresses[2]; a.State != AddressStatic || a.Name != "nas" {
+		t.Errorf("static address mismatch: %+v", a)
+	}
+	if a := plan.Addresses[6]; a.State != AddressReserved || a.Note != "printer" {
+		t.Errorf("reserved address mismatch: %+v", a)
+	}
+	if !plan.OverThreshold(30) || plan.OverThreshold(50) || plan.OverThreshold(0) {
+		t.Errorf("threshold mismatch for utilization %f", plan.Utilization)
+	}
+
+	plan = BuildAddressPlan(n, devices, reservations, 4)
+	if plan.Addresses != nil {
+		t.Errorf("grid should be skipped when larger than max: %d", len(plan.Addresses))
+	}
+}
+
+func TestNetworkUsageHistory(t *testing.T) {
+	n := Network{Name: "test", Prefix: MustParsePrefix("192.168.1.0/24")}
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	devices := []Device{
+		{Addr: MustParseAddr("192.168.1.1")},
+		{Addr: MustParseAddr("192.168.1.2"), DiscoveredAt: start.Add(day)},
+		{
+			Addr:            MustParseAddr("192.168.1.3"),
+			PerformancePing: Pinger{FirstSeen: start.Add(2 * day)},
+		},
+		{Addr: MustParseAddr("10.0.0.1"), DiscoveredAt: start},
+	}
+	deleted := []Tombstone{
+		DeviceTombstone(
+			Device{Addr: MustParseAddr("192.168.1.4"), DiscoveredAt: start},
+			start.Add(2*day),
+			day,
+		),
+		NetworkTombstone(n, start, day),
+	}
+
+	got := NetworkUsageHistory(n, devices, deleted, start, start.Add(3*day), day)
+	want := []NetworkUsage{
+		{Time: start, Used: 2},
+		{Time: start.Add(day), Used: 3},
+		{Time: start.Add(2 * day), Used: 3},
+		{Time: start.Add(3 * day), Used: 3},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -24,6 +24,7 @@ type IpFlow struct {
 	Packets  int
 	Protocol Protocol // https://www.iana.org/assignments/protocol-numbers/protocol-numbers.xhtml
 	Flags    TcpFlags
+	VlanID   int
 }
 
 func (ipf IpFlow) String() string {
@@ -0,0 +1,52 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"slices"
+)
+
+// IPv6AddrKind tells how a device came by an IPv6 address
+type IPv6AddrKind string
+
+const (
+	IPv6LinkLocal IPv6AddrKind = "link-local"
+	// IPv6EUI64 is an autoconfigured (SLAAC) address with the interface id derived from the MAC
+	IPv6EUI64 IPv6AddrKind = "slaac eui-64"
+	// IPv6Random is an address with a random interface id, an autoconfigured stable privacy or
+	// temporary address or one handed out by DHCPv6
+	IPv6Random IPv6AddrKind = "random"
+	IPv6ULA    IPv6AddrKind = "unique local"
+)
+
+// ClassifyIPv6 returns the kind of an IPv6 address of the device with the MAC, empty for an
+// IPv4 address
+func ClassifyIPv6(addr Addr, mac MAC) IPv6AddrKind {
+	a := addr.Addr()
+	switch {
+	case !a.Is6() || a.Is4In6():
+		return ""
+	case a.IsLinkLocalUnicast():
+		return IPv6LinkLocal
+	case isEUI64(addr, mac):
+		return IPv6EUI64
+	case a.As16()[0]&0xfe == 0xfc:
+		return IPv6ULA
+	default:
+		return IPv6Random
+	}
+}
+
+// isEUI64 reports if the interface id of the address is the modified EUI-64 of the MAC, the
+// MAC with ff:fe in the middle and the universal/local bit flipped
+func isEUI64(addr Addr, mac MAC) bool {
+	m := mac.Addr()
+	if len(m) != 6 {
+		return false
+	}
+	b := addr.Addr().As16()
+	iid := []byte{m[0] ^ 0x02, m[1], m[2], 0xff, 0xfe, m[3], m[4], m[5]}
+	return slices.Equal(b[8:], iid)
+}
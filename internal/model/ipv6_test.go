@@ -0,0 +1,31 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+)
+
+func TestClassifyIPv6(t *testing.T) {
+	mac := MustParseMAC("00:11:22:33:44:55")
+	tests := map[string]struct {
+		addr string
+		want IPv6AddrKind
+	}{
+		"IPv4":      {addr: "192.168.1.20", want: ""},
+		"LinkLocal": {addr: "fe80::211:22ff:fe33:4455", want: IPv6LinkLocal},
+		"EUI64":     {addr: "2001:db8:1::211:22ff:fe33:4455", want: IPv6EUI64},
+		"Random":    {addr: "2001:db8:1::8d3e:1f2a:9b41:77c0", want: IPv6Random},
+		"ULA":       {addr: "fd12:3456:789a::8d3e:1f2a:9b41:77c0", want: IPv6ULA},
+		"ULAEUI64":  {addr: "fd12:3456:789a::211:22ff:fe33:4455", want: IPv6EUI64},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := ClassifyIPv6(MustParseAddr(tc.addr), mac); got != tc.want {
+				t.Errorf("want: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type (
+	// LinkKind tells wired from wireless attachments
+	LinkKind string
+
+	// Link is how a device attaches to the network as a network controller reports it.  The
+	// uplink is the access point of a wireless device or the switch of a wired one, for an
+	// access point or switch it is the device it uplinks through.
+	Link struct {
+		Source     string
+		Kind       LinkKind
+		SSID       string
+		Radio      string
+		Signal     int
+		Uplink     string
+		UplinkMAC  string
+		UplinkPort int
+		LastSeen   time.Time
+	}
+)
+
+const (
+	LinkWired    LinkKind = "wired"
+	LinkWireless LinkKind = "wireless"
+)
+
+// IsEmpty reports if no controller has reported the link
+func (l Link) IsEmpty() bool {
+	return l.Kind == ""
+}
+
+func (l Link) String() string {
+	switch {
+	case l.IsEmpty():
+		return ""
+	case l.Kind == LinkWireless:
+		return fmt.Sprintf("wireless %s via %s (%d dBm)", l.SSID, l.Uplink, l.Signal)
+	case l.UplinkPort > 0:
+		return fmt.Sprintf("wired to %s port %d", l.Uplink, l.UplinkPort)
+	default:
+		return fmt.Sprintf("wired to %s", l.Uplink)
+	}
+}
+
+// merge takes the newer report, a device moving between access points or ports is
+// replaced as a whole
+func (l Link) merge(in Link) (out Link, updated bool) {
+	if in.IsEmpty() || !in.LastSeen.After(l.LastSeen) {
+		return l, false
+	}
+	return in, l != in
+}
+
+func (l Link) Value() (driver.Value, error) {
+	if l.IsEmpty() {
+		return "", nil
+	}
+	x, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(x), nil
+}
+
+func (l *Link) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		if len(src) == 0 {
+			*l = Link{}
+			return nil
+		}
+		return json.Unmarshal([]byte(src), l)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"bytes"
+	"net/netip"
+	"time"
+)
+
+type (
+	// MACBinding is a MAC seen answering for an address, from the first to the last time
+	MACBinding struct {
+		Addr      Addr
+		MAC       MAC
+		FirstSeen time.Time
+		LastSeen  time.Time
+		Source    DiscoverySource
+	}
+
+	// MACConflictKind is why a binding was raised as a conflict
+	MACConflictKind string
+)
+
+const (
+	// MACChanged is a new MAC answering for an address
+	MACChanged MACConflictKind = "macchanged"
+	// DuplicateAddr is a MAC seen again at an address another MAC recently answered for, the
+	// two devices take turns answering as both claim the address or one spoofs it
+	DuplicateAddr MACConflictKind = "duplicateaddr"
+)
+
+// virtualRouterOUIs prefix the MACs shared by the routers of a VRRP or HSRP group, which
+// move between the routers without the address changing hands
+var virtualRouterOUIs = [][]byte{
+	{0x00, 0x00, 0x5e, 0x00, 0x01}, // VRRP IPv4
+	{0x00, 0x00, 0x5e, 0x00, 0x02}, // VRRP IPv6
+	{0x00, 0x00, 0x0c, 0x07, 0xac}, // HSRP v1
+	{0x00, 0x00, 0x0c, 0x9f, 0xf0}, // HSRP v2
+}
+
+// IsVirtualRouterMAC reports if the MAC is shared by a VRRP or HSRP router group
+func IsVirtualRouterMAC(mac MAC) bool {
+	for _, prefix := range virtualRouterOUIs {
+		if bytes.HasPrefix(mac.M, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMACExclusions parses the addresses and prefixes whose MAC changes are expected
+func ParseMACExclusions(exclusions []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(exclusions))
+	for _, s := range exclusions {
+		p, err := ParseAddrOrPrefix(s)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, p)
+	}
+	return prefixes, nil
+}
+
+// DetectMACConflict compares a binding just seen against the known bindings of its address.
+// The most recently seen binding of another MAC makes it a conflict, a duplicate address
+// when the MAC seen was itself bound to the address within the window and otherwise a
+// change.  Bindings of virtual router MACs are never a conflict.
+func DetectMACConflict(
+	known []MACBinding,
+	seen MACBinding,
+	window time.Duration,
+) (EventMACConflict, bool) {
+	var latest, own MACBinding
+	for _, b := range known {
+		if b.Addr != seen.Addr {
+			continue
+		}
+		if b.MAC.String() == seen.MAC.String() {
+			own = b
+			continue
+		}
+		if b.LastSeen.After(latest.LastSeen) {
+			latest = b
+		}
+	}
+	if latest.LastSeen.IsZero() || !latest.LastSeen.After(own.LastSeen) {
+		return EventMACConflict{}, false
+	}
+	if IsVirtualRouterMAC(seen.MAC) || IsVirtualRouterMAC(latest.MAC) {
+		return EventMACConflict{}, false
+	}
+	e := EventMACConflict{
+		Kind:     MACChanged,
+		Addr:     seen.Addr,
+		MAC:      seen.MAC,
+		Previous: latest.MAC,
+		Source:   seen.Source,
+	}
+	if !own.LastSeen.IsZero() && seen.LastSeen.Sub(own.LastSeen) <= window {
+		e.Kind = DuplicateAddr
+	}
+	return e, true
+}
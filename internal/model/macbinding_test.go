@@ -0,0 +1,90 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestDetectMACConflict(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	addr := MustParseAddr("192.168.1.1")
+	gateway := MustParseMAC("aa:aa:aa:aa:aa:01")
+	other := MustParseMAC("bb:bb:bb:bb:bb:02")
+	vrrp := MustParseMAC("00:00:5e:00:01:07")
+	bind := func(mac MAC, last time.Duration) MACBinding {
+		return MACBinding{Addr: addr, MAC: mac, FirstSeen: start, LastSeen: start.Add(last)}
+	}
+	tests := map[string]struct {
+		known []MACBinding
+		seen  MACBinding
+		want  MACConflictKind
+		found bool
+	}{
+		"FirstBinding": {seen: bind(gateway, 0)},
+		"SameMAC":      {known: []MACBinding{bind(gateway, 0)}, seen: bind(gateway, time.Minute)},
+		"Changed": {
+			known: []MACBinding{bind(gateway, 0)},
+			seen:  bind(other, time.Minute),
+			want:  MACChanged,
+			found: true,
+		},
+		"SettledAfterChange": {
+			known: []MACBinding{bind(gateway, 0), bind(other, time.Minute)},
+			seen:  bind(other, 2*time.Minute),
+		},
+		"Flapping": {
+			known: []MACBinding{bind(gateway, 0), bind(other, time.Minute)},
+			seen:  bind(gateway, 2*time.Minute),
+			want:  DuplicateAddr,
+			found: true,
+		},
+		"ReturnedAfterWindow": {
+			known: []MACBinding{bind(gateway, 0), bind(other, time.Minute)},
+			seen:  bind(gateway, 3*time.Hour),
+			want:  MACChanged,
+			found: true,
+		},
+		"OtherAddr": {
+			known: []MACBinding{{Addr: MustParseAddr("192.168.1.2"), MAC: other, LastSeen: start}},
+			seen:  bind(gateway, time.Minute),
+		},
+		"VirtualRouter": {
+			known: []MACBinding{bind(vrrp, 0)},
+			seen:  bind(gateway, time.Minute),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			e, found := DetectMACConflict(tc.known, tc.seen, time.Hour)
+			if found != tc.found {
+				t.Fatalf("found want: %v, got: %v (%s)", tc.found, found, e)
+			}
+			if e.Kind != tc.want {
+				t.Errorf("kind want: %s, got: %s", tc.want, e.Kind)
+			}
+		})
+	}
+}
+
+func TestParseMACExclusions(t *testing.T) {
+	got, err := ParseMACExclusions([]string{"192.168.1.1", "10.0.0.5/30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []netip.Prefix{
+		netip.MustParsePrefix("192.168.1.1/32"),
+		netip.MustParsePrefix("10.0.0.4/30"),
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("want: %v, got: %v", want, got)
+	}
+	_, err = ParseMACExclusions([]string{"gateway"})
+	if err == nil {
+		t.Error("want an error for an invalid exclusion")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+type (
+	MaintenanceScope  string
+	MaintenanceRepeat string
+)
+
+const (
+	MaintenanceScopeDevice  MaintenanceScope = "device"
+	MaintenanceScopeTag     MaintenanceScope = "tag"
+	MaintenanceScopeNetwork MaintenanceScope = "network"
+
+	MaintenanceOnce   MaintenanceRepeat = ""
+	MaintenanceDaily  MaintenanceRepeat = "daily"
+	MaintenanceWeekly MaintenanceRepeat = "weekly"
+)
+
+var (
+	ErrMaintenanceWindowDoesNotExist = errors.New("maintenance window does not exist")
+	ErrInvalidMaintenanceScope       = errors.New("invalid maintenance scope")
+	ErrInvalidMaintenanceRepeat      = errors.New("invalid maintenance repeat")
+	ErrInvalidMaintenanceDuration    = errors.New("maintenance duration must be positive")
+)
+
+// ParseMaintenanceScope converts the string into a MaintenanceScope
+func ParseMaintenanceScope(s string) (MaintenanceScope, error) {
+	switch MaintenanceScope(s) {
+	case MaintenanceScopeDevice, MaintenanceScopeTag, MaintenanceScopeNetwork:
+		return MaintenanceScope(s), nil
+	}
+	return "", ErrInvalidMaintenanceScope
+}
+
+// ParseMaintenanceRepeat converts the string into a MaintenanceRepeat, "once" and the empty
+// string do not repeat
+func ParseMaintenanceRepeat(s string) (MaintenanceRepeat, error) {
+	switch s {
+	case "", "once":
+		return MaintenanceOnce, nil
+	case string(MaintenanceDaily), string(MaintenanceWeekly):
+		return MaintenanceRepeat(s), nil
+	}
+	return "", ErrInvalidMaintenanceRepeat
+}
+
+// MaintenanceWindow is a period during which ping failures and changes of the devices in
+// scope are expected.  Target is the device addr, the tag name or the network name.
+type MaintenanceWindow struct {
+	Name     string
+	Scope    MaintenanceScope
+	Target   string
+	Start    time.Time
+	Duration time.Duration
+	Repeat   MaintenanceRepeat
+	Note     string
+}
+
+// ActiveAt reports if the window (or its latest repeat) covers t
+func (w MaintenanceWindow) ActiveAt(t time.Time) bool {
+	if w.Duration <= 0 || t.Before(w.Start) {
+		return false
+	}
+	start := w.Start
+	var period time.Duration
+	switch w.Repeat {
+	case MaintenanceDaily:
+		period = 24 * time.Hour
+	case MaintenanceWeekly:
+		period = 7 * 24 * time.Hour
+	}
+	if period > 0 {
+		start = start.Add(t.Sub(w.Start) / period * period)
+	}
+	return t.Before(start.Add(w.Duration))
+}
+
+// Applies reports if the device is in the scope of the window, nets are used to resolve
+// network scoped windows
+func (w MaintenanceWindow) Applies(d Device, nets []Network) bool {
+	switch w.Scope {
+	case MaintenanceScopeDevice:
+		return d.Addr.String() == w.Target
+	case MaintenanceScopeTag:
+		return d.Meta.Tags.Has(w.Target)
+	case MaintenanceScopeNetwork:
+		for _, n := range nets {
+			if n.Name == w.Target && n.Contains(d) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ActiveMaintenance returns the first window which is active at t and applies to the device
+func ActiveMaintenance(
+	windows []MaintenanceWindow,
+	d Device,
+	nets []Network,
+	t time.Time,
+) (MaintenanceWindow, bool) {
+	for _, w := range windows {
+		if w.ActiveAt(t) && w.Applies(d, nets) {
+			return w, true
+		}
+	}
+	return MaintenanceWindow{}, false
+}
+
+// Suppress converts an annotation recorded during the window into a maintenance annotation,
+// the original kind is kept in the text so the history still shows what happened
+func (w MaintenanceWindow) Suppress(a Annotation) Annotation {
+	a.Text = "[" + string(a.Kind) + "] " + a.Text + " (maintenance " + w.Name + ")"
+	a.Kind = AnnotationMaintenance
+	return a
+}
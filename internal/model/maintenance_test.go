@@ -0,0 +1,73 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindow_ActiveAt(t *testing.T) {
+	start := time.Date(2024, 6, 3, 2, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	tests := map[string]struct {
+		repeat MaintenanceRepeat
+		at     time.Time
+		want   bool
+	}{
+		"BeforeStart":    {at: start.Add(-time.Minute), want: false},
+		"During":         {at: start.Add(30 * time.Minute), want: true},
+		"AfterEnd":       {at: start.Add(time.Hour), want: false},
+		"OnceNextDay":    {at: start.Add(day + time.Minute), want: false},
+		"DailyNextDay":   {repeat: MaintenanceDaily, at: start.Add(day + time.Minute), want: true},
+		"DailyAfternoon": {repeat: MaintenanceDaily, at: start.Add(36 * time.Hour), want: false},
+		"WeeklyNextDay":  {repeat: MaintenanceWeekly, at: start.Add(day + time.Minute), want: false},
+		"WeeklyNextWeek": {repeat: MaintenanceWeekly, at: start.Add(7*day + time.Minute), want: true},
+		"WeeklyTwoWeeksOn": {
+			repeat: MaintenanceWeekly,
+			at:     start.Add(14*day + 59*time.Minute),
+			want:   true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := MaintenanceWindow{Start: start, Duration: time.Hour, Repeat: tc.repeat}
+			if got := w.ActiveAt(tc.at); got != tc.want {
+				t.Errorf("active want: %t, got: %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindow_Applies(t *testing.T) {
+	d := Device{
+		Addr: MustParseAddr("192.168.1.20"),
+		Meta: Meta{Tags: Tags{{Val: "printer"}}},
+	}
+	nets := []Network{
+		{Name: "office", Prefix: MustParsePrefix("192.168.1.0/24")},
+		{Name: "lab", Prefix: MustParsePrefix("10.0.0.0/24")},
+	}
+	tests := map[string]struct {
+		scope  MaintenanceScope
+		target string
+		want   bool
+	}{
+		"Device":       {scope: MaintenanceScopeDevice, target: "192.168.1.20", want: true},
+		"OtherDevice":  {scope: MaintenanceScopeDevice, target: "192.168.1.21", want: false},
+		"Tag":          {scope: MaintenanceScopeTag, target: "printer", want: true},
+		"OtherTag":     {scope: MaintenanceScopeTag, target: "critical", want: false},
+		"Network":      {scope: MaintenanceScopeNetwork, target: "office", want: true},
+		"OtherNetwork": {scope: MaintenanceScopeNetwork, target: "lab", want: false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := MaintenanceWindow{Scope: tc.scope, Target: tc.target}
+			if got := w.Applies(d, nets); got != tc.want {
+				t.Errorf("applies want: %t, got: %t", tc.want, got)
+			}
+		})
+	}
+}
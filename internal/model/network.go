@@ -26,6 +26,8 @@ type (
 		Prefix   Prefix
 		LastScan time.Time
 		Tags     Tags
+		VLAN     VLAN
+		Site     string
 	}
 )
 
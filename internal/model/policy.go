@@ -0,0 +1,61 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import "time"
+
+// MonitoringPolicy overrides the global monitoring intervals, a zero interval keeps the
+// global setting
+type MonitoringPolicy struct {
+	PingInterval     time.Duration
+	PortScanInterval time.Duration
+}
+
+// PolicyLookup returns the monitoring policy which applies to the device
+type PolicyLookup func(Device) MonitoringPolicy
+
+func (p MonitoringPolicy) IsEmpty() bool {
+	return p.PingInterval == 0 && p.PortScanInterval == 0
+}
+
+// Combine returns a policy using the shortest non-zero interval from either policy
+func (p MonitoringPolicy) Combine(o MonitoringPolicy) MonitoringPolicy {
+	return MonitoringPolicy{
+		PingInterval:     shortestInterval(p.PingInterval, o.PingInterval),
+		PortScanInterval: shortestInterval(p.PortScanInterval, o.PortScanInterval),
+	}
+}
+
+// Override returns the policy with each non-zero interval of o replacing the interval of p
+func (p MonitoringPolicy) Override(o MonitoringPolicy) MonitoringPolicy {
+	return MonitoringPolicy{
+		PingInterval:     IntervalOrDefault(o.PingInterval, p.PingInterval),
+		PortScanInterval: IntervalOrDefault(o.PortScanInterval, p.PortScanInterval),
+	}
+}
+
+// DevicePolicyLookup combines the policies of the tags on the device, the policy set on the
+// device itself takes precedence over its tags
+func DevicePolicyLookup(defs []TagDefinition) PolicyLookup {
+	tags := TagPolicyLookup(defs)
+	return func(d Device) MonitoringPolicy {
+		return tags(d).Override(d.Meta.Policy)
+	}
+}
+
+func shortestInterval(a time.Duration, b time.Duration) time.Duration {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// IntervalOrDefault returns the override when set, otherwise the default
+func IntervalOrDefault(override time.Duration, def time.Duration) time.Duration {
+	if override > 0 {
+		return override
+	}
+	return def
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMonitoringPolicy_Combine(t *testing.T) {
+	a := MonitoringPolicy{PingInterval: time.Minute}
+	b := MonitoringPolicy{PingInterval: 30 * time.Second, PortScanInterval: time.Hour}
+	want := MonitoringPolicy{PingInterval: 30 * time.Second, PortScanInterval: time.Hour}
+	if got := a.Combine(b); got != want {
+		t.Errorf("combine want: %+v, got: %+v", want, got)
+	}
+	if got := b.Combine(a); got != want {
+		t.Errorf("reverse combine want: %+v, got: %+v", want, got)
+	}
+	if !(MonitoringPolicy{}).IsEmpty() {
+		t.Errorf("zero policy should be empty")
+	}
+}
+
+func TestTagPolicyLookup(t *testing.T) {
+	defs := []TagDefinition{
+		{Name: "critical", Policy: MonitoringPolicy{PingInterval: 30 * time.Second}},
+		{Name: "printer", Policy: MonitoringPolicy{PingInterval: time.Hour}},
+	}
+	lookup := TagPolicyLookup(defs)
+
+	tests := map[string]struct {
+		tags Tags
+		want time.Duration
+	}{
+		"None":     {tags: nil, want: 0},
+		"Critical": {tags: Tags{{Val: "critical"}}, want: 30 * time.Second},
+		"Both":     {tags: Tags{{Val: "printer"}, {Val: "critical"}}, want: 30 * time.Second},
+		"Unknown":  {tags: Tags{{Val: "other"}}, want: 0},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := lookup(Device{Meta: Meta{Tags: tc.tags}}).PingInterval
+			if got != tc.want {
+				t.Errorf("ping interval want: %s, got: %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestValidTagName(t *testing.T) {
+	for name, want := range map[string]bool{"critical": true, "": false, "a b": false, "a,b": false} {
+		if got := ValidTagName(name); got != want {
+			t.Errorf("%q want: %t, got: %t", name, want, got)
+		}
+	}
+}
+
+func TestDevicePolicyLookup(t *testing.T) {
+	defs := []TagDefinition{
+		{
+			Name:   "critical",
+			Policy: MonitoringPolicy{PingInterval: 30 * time.Second, PortScanInterval: time.Hour},
+		},
+	}
+	lookup := DevicePolicyLookup(defs)
+
+	tests := map[string]struct {
+		meta Meta
+		want MonitoringPolicy
+	}{
+		"None": {},
+		"DeviceOnly": {
+			meta: Meta{Policy: MonitoringPolicy{PingInterval: 15 * time.Second}},
+			want: MonitoringPolicy{PingInterval: 15 * time.Second},
+		},
+		"TagOnly": {
+			meta: Meta{Tags: Tags{{Val: "critical"}}},
+			want: MonitoringPolicy{PingInterval: 30 * time.Second, PortScanInterval: time.Hour},
+		},
+		"DeviceOverridesTag": {
+			meta: Meta{
+				Tags:   Tags{{Val: "critical"}},
+				Policy: MonitoringPolicy{PingInterval: time.Hour},
+			},
+			want: MonitoringPolicy{PingInterval: time.Hour, PortScanInterval: time.Hour},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := lookup(Device{Meta: tc.meta})
+			if got != tc.want {
+				t.Errorf("policy want: %+v, got: %+v", tc.want, got)
+			}
+		})
+	}
+}
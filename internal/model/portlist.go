@@ -64,6 +64,22 @@ func (pl PortList) Len() int {
 	return len(pl.Ports)
 }
 
+// Diff returns the ports open in next which are not in the list (opened) and the ports
+// in the list which are not in next (closed)
+func (pl PortList) Diff(next PortList) (opened []int, closed []int) {
+	for _, port := range next.Ports {
+		if !slices.Contains(pl.Ports, port) {
+			opened = append(opened, port)
+		}
+	}
+	for _, port := range pl.Ports {
+		if !slices.Contains(next.Ports, port) {
+			closed = append(closed, port)
+		}
+	}
+	return opened, closed
+}
+
 func ParsePortList(s string) (pl PortList, err error) {
 	// s = strings.TrimSpace(s)
 	intstrs := strings.Split(s, portSeperator)
@@ -0,0 +1,166 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type PortProfileScope string
+
+const (
+	// PortProfileScopeNone profiles are only used when named by the portscan portlist setting
+	PortProfileScopeNone    PortProfileScope = ""
+	PortProfileScopeTag     PortProfileScope = "tag"
+	PortProfileScopeNetwork PortProfileScope = "network"
+)
+
+var (
+	ErrPortProfileDoesNotExist = errors.New("port profile does not exist")
+	ErrInvalidPortProfileScope = errors.New("invalid port profile scope")
+	ErrPortProfileNameReserved = errors.New("port profile name is a builtin port list")
+	ErrPortProfileNoPorts      = errors.New("port profile has no ports")
+	ErrInvalidPortRange        = errors.New("invalid port range")
+)
+
+// builtinPortLists name the port lists of the port scanner, profiles can not take their names
+var builtinPortLists = []string{"all", "general", "priviledged", "common"}
+
+// ParsePortProfileScope converts the string into a PortProfileScope, "none" and blank are
+// an unassigned profile
+func ParsePortProfileScope(s string) (PortProfileScope, error) {
+	switch PortProfileScope(s) {
+	case PortProfileScopeNone, "none":
+		return PortProfileScopeNone, nil
+	case PortProfileScopeTag, PortProfileScopeNetwork:
+		return PortProfileScope(s), nil
+	}
+	return "", ErrInvalidPortProfileScope
+}
+
+type (
+	// PortRange is the ports from first to last, a single port has the same first and last
+	PortRange struct {
+		First int
+		Last  int
+	}
+
+	// PortRanges are the ports and ranges of ports of a profile
+	PortRanges []PortRange
+)
+
+// ParsePortRanges reads the comma or space separated ports and ranges, e.g. "22,80,8000-8100"
+func ParsePortRanges(s string) (PortRanges, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+	out := make(PortRanges, 0, len(fields))
+	for _, field := range fields {
+		first, last, isRange := strings.Cut(field, "-")
+		if !isRange {
+			last = first
+		}
+		pr := PortRange{}
+		var err error
+		pr.First, err = strconv.Atoi(first)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidPortRange, err)
+		}
+		pr.Last, err = strconv.Atoi(last)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidPortRange, err)
+		}
+		if pr.First < 1 || pr.Last > 65535 || pr.First > pr.Last {
+			return nil, ErrInvalidPortRange
+		}
+		out = append(out, pr)
+	}
+	return out, nil
+}
+
+func (pr PortRange) String() string {
+	if pr.First == pr.Last {
+		return strconv.Itoa(pr.First)
+	}
+	return strconv.Itoa(pr.First) + "-" + strconv.Itoa(pr.Last)
+}
+
+func (prs PortRanges) String() string {
+	parts := make([]string, 0, len(prs))
+	for _, pr := range prs {
+		parts = append(parts, pr.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// Ports returns every port of the ranges once, in order
+func (prs PortRanges) Ports() []int {
+	ports := make([]int, 0)
+	for _, pr := range prs {
+		for port := pr.First; port <= pr.Last; port++ {
+			ports = append(ports, port)
+		}
+	}
+	slices.Sort(ports)
+	return slices.Compact(ports)
+}
+
+// PortProfile is a named set of ports to scan instead of the configured port list, on the
+// devices of a tag or a network.  Target is the tag name or the network name.
+type PortProfile struct {
+	Name        string
+	Description string
+	Ports       PortRanges
+	Scope       PortProfileScope
+	Target      string
+}
+
+// Validate checks the profile has ports and does not shadow a builtin port list
+func (pp PortProfile) Validate() error {
+	if slices.Contains(builtinPortLists, strings.ToLower(pp.Name)) {
+		return ErrPortProfileNameReserved
+	}
+	if len(pp.Ports) == 0 {
+		return ErrPortProfileNoPorts
+	}
+	return nil
+}
+
+// Applies reports if the device is in the scope of the profile, nets are used to resolve
+// network scoped profiles.  An unassigned profile applies to no device.
+func (pp PortProfile) Applies(d Device, nets []Network) bool {
+	switch pp.Scope {
+	case PortProfileScopeTag:
+		return d.Meta.Tags.Has(pp.Target)
+	case PortProfileScopeNetwork:
+		for _, n := range nets {
+			if n.Name == pp.Target && n.Contains(d) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DevicePortsLookup returns the ports to scan on a device, nil when no profile applies and
+// the configured port list is used.  Profiles of the tags of the device are preferred over
+// those of its networks, the ports of all the profiles of the preferred scope are combined.
+func DevicePortsLookup(profiles []PortProfile, nets []Network) func(Device) []int {
+	return func(d Device) []int {
+		for _, scope := range []PortProfileScope{PortProfileScopeTag, PortProfileScopeNetwork} {
+			var ranges PortRanges
+			for _, pp := range profiles {
+				if pp.Scope == scope && pp.Applies(d, nets) {
+					ranges = append(ranges, pp.Ports...)
+				}
+			}
+			if len(ranges) > 0 {
+				return ranges.Ports()
+			}
+		}
+		return nil
+	}
+}
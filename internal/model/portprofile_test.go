@@ -0,0 +1,129 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParsePortRanges(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		want    PortRanges
+		wantErr error
+	}{
+		"Empty": {
+			input: "",
+			want:  PortRanges{},
+		},
+		"Ports": {
+			input: "22,80 443",
+			want:  PortRanges{{First: 22, Last: 22}, {First: 80, Last: 80}, {First: 443, Last: 443}},
+		},
+		"Range": {
+			input: "22, 8000-8100",
+			want:  PortRanges{{First: 22, Last: 22}, {First: 8000, Last: 8100}},
+		},
+		"Backwards": {
+			input:   "8100-8000",
+			wantErr: ErrInvalidPortRange,
+		},
+		"TooHigh": {
+			input:   "65536",
+			wantErr: ErrInvalidPortRange,
+		},
+		"Zero": {
+			input:   "0-10",
+			wantErr: ErrInvalidPortRange,
+		},
+		"NotANumber": {
+			input:   "common",
+			wantErr: ErrInvalidPortRange,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParsePortRanges(tc.input)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("error want: %v, got: %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+			if err == nil {
+				again, _ := ParsePortRanges(got.String())
+				if diff := cmp.Diff(tc.want, again); diff != "" {
+					t.Errorf("string round trip mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestPortRanges_Ports(t *testing.T) {
+	prs := PortRanges{{First: 8080, Last: 8082}, {First: 22, Last: 22}, {First: 8081, Last: 8081}}
+	want := []int{22, 8080, 8081, 8082}
+	if diff := cmp.Diff(want, prs.Ports()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPortProfile_Validate(t *testing.T) {
+	ports := PortRanges{{First: 22, Last: 22}}
+	if err := (PortProfile{Name: "Common", Ports: ports}).Validate(); !errors.Is(
+		err,
+		ErrPortProfileNameReserved,
+	) {
+		t.Errorf("want %v, got %v", ErrPortProfileNameReserved, err)
+	}
+	if err := (PortProfile{Name: "web"}).Validate(); !errors.Is(err, ErrPortProfileNoPorts) {
+		t.Errorf("want %v, got %v", ErrPortProfileNoPorts, err)
+	}
+	if err := (PortProfile{Name: "web", Ports: ports}).Validate(); err != nil {
+		t.Errorf("want valid, got %v", err)
+	}
+}
+
+func TestDevicePortsLookup(t *testing.T) {
+	nets := []Network{
+		{Name: "office", Prefix: MustParsePrefix("192.168.1.0/24")},
+	}
+	profiles := []PortProfile{
+		{Name: "office", Ports: PortRanges{{First: 22, Last: 22}}, Scope: PortProfileScopeNetwork, Target: "office"},
+		{Name: "web", Ports: PortRanges{{First: 80, Last: 80}}, Scope: PortProfileScopeTag, Target: "web"},
+		{Name: "alt", Ports: PortRanges{{First: 8080, Last: 8081}}, Scope: PortProfileScopeTag, Target: "web"},
+		{Name: "spare", Ports: PortRanges{{First: 9000, Last: 9000}}},
+	}
+	tests := map[string]struct {
+		d    Device
+		want []int
+	}{
+		"Tag": {
+			d: Device{
+				Addr: MustParseAddr("192.168.1.20"),
+				Meta: Meta{Tags: Tags{{Val: "web"}}},
+			},
+			want: []int{80, 8080, 8081},
+		},
+		"Network": {
+			d:    Device{Addr: MustParseAddr("192.168.1.21")},
+			want: []int{22},
+		},
+		"None": {
+			d: Device{Addr: MustParseAddr("10.0.0.5")},
+		},
+	}
+	lookup := DevicePortsLookup(profiles, nets)
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, lookup(tc.d)); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -4,6 +4,12 @@
 
 package model
 
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
 type Protocol byte
 
 func (p Protocol) String() string {
@@ -22,3 +28,30 @@ func (p Protocol) String() string {
 		return "Unknown: [" + string(p) + "]"
 	}
 }
+
+// ParseProtocol converts a protocol number or the output of String back into a Protocol
+func ParseProtocol(s string) (Protocol, error) {
+	switch s {
+	case "HOPOPT":
+		return 0, nil
+	case "ICMP":
+		return 1, nil
+	case "IGMP":
+		return 2, nil
+	case "TCP":
+		return 6, nil
+	case "UDP":
+		return 17, nil
+	}
+	if x, ok := strings.CutPrefix(s, "Unknown: ["); ok {
+		r, size := utf8.DecodeRuneInString(x)
+		if x[size:] == "]" && r <= 0xff {
+			return Protocol(r), nil
+		}
+	}
+	p, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, err
+	}
+	return Protocol(p), nil
+}
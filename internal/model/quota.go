@@ -0,0 +1,217 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+type (
+	QuotaScope  string
+	QuotaPeriod string
+	QuotaLevel  string
+
+	// BandwidthQuota is the number of bytes the devices in scope may send and receive each
+	// period.  Target is the device addr or the tag name, every device with the tag has the
+	// whole quota to itself.
+	BandwidthQuota struct {
+		Name   string
+		Scope  QuotaScope
+		Target string
+		Period QuotaPeriod
+		Bytes  int64
+		Note   string
+	}
+
+	// FlowUsage is the traffic of a device on a day as recorded by its netflows
+	FlowUsage struct {
+		Day time.Time
+		In  int64
+		Out int64
+	}
+
+	// QuotaUsage is the traffic of a device in the current period of a quota
+	QuotaUsage struct {
+		Quota BandwidthQuota
+		Addr  Addr
+		Start time.Time
+		Used  int64
+		Days  []FlowUsage
+	}
+)
+
+const (
+	QuotaScopeDevice QuotaScope = "device"
+	QuotaScopeTag    QuotaScope = "tag"
+
+	QuotaDaily   QuotaPeriod = "daily"
+	QuotaMonthly QuotaPeriod = "monthly"
+
+	QuotaOK       QuotaLevel = "ok"
+	QuotaWarning  QuotaLevel = "warning"
+	QuotaExceeded QuotaLevel = "exceeded"
+)
+
+var (
+	ErrBandwidthQuotaDoesNotExist = errors.New("bandwidth quota does not exist")
+	ErrInvalidQuotaScope          = errors.New("invalid quota scope")
+	ErrInvalidQuotaPeriod         = errors.New("invalid quota period")
+	ErrInvalidQuotaBytes          = errors.New("quota bytes must be positive")
+)
+
+// ParseQuotaScope converts the string into a QuotaScope
+func ParseQuotaScope(s string) (QuotaScope, error) {
+	switch QuotaScope(s) {
+	case QuotaScopeDevice, QuotaScopeTag:
+		return QuotaScope(s), nil
+	}
+	return "", ErrInvalidQuotaScope
+}
+
+// ParseQuotaPeriod converts the string into a QuotaPeriod
+func ParseQuotaPeriod(s string) (QuotaPeriod, error) {
+	switch QuotaPeriod(s) {
+	case QuotaDaily, QuotaMonthly:
+		return QuotaPeriod(s), nil
+	}
+	return "", ErrInvalidQuotaPeriod
+}
+
+// ParseQuotaBytes converts a size such as "10GB" or "500 MiB" into bytes
+func ParseQuotaBytes(s string) (int64, error) {
+	b, err := humanize.ParseBytes(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidQuotaBytes, err)
+	}
+	if b == 0 {
+		return 0, ErrInvalidQuotaBytes
+	}
+	return int64(b), nil
+}
+
+// Limit returns the quota as a readable size
+func (q BandwidthQuota) Limit() string {
+	return humanize.Bytes(uint64(q.Bytes))
+}
+
+// PeriodStart returns the local midnight or first of the month starting the period covering t
+func (q BandwidthQuota) PeriodStart(t time.Time) time.Time {
+	t = t.Local()
+	if q.Period == QuotaMonthly {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// PeriodEnd returns the start of the period after the one covering t
+func (q BandwidthQuota) PeriodEnd(t time.Time) time.Time {
+	if q.Period == QuotaMonthly {
+		return q.PeriodStart(t).AddDate(0, 1, 0)
+	}
+	return q.PeriodStart(t).AddDate(0, 0, 1)
+}
+
+// Applies reports if the device is in the scope of the quota
+func (q BandwidthQuota) Applies(d Device) bool {
+	switch q.Scope {
+	case QuotaScopeDevice:
+		return d.Addr.String() == q.Target
+	case QuotaScopeTag:
+		return d.Meta.Tags.Has(q.Target)
+	}
+	return false
+}
+
+// QuotasFor returns the quotas applying to the device
+func QuotasFor(quotas []BandwidthQuota, d Device) []BandwidthQuota {
+	return slices.DeleteFunc(slices.Clone(quotas), func(q BandwidthQuota) bool {
+		return !q.Applies(d)
+	})
+}
+
+// Total returns the bytes sent and received
+func (fu FlowUsage) Total() int64 {
+	return fu.In + fu.Out
+}
+
+// NewQuotaUsage sums the daily usage of the device within the period of the quota covering t
+func NewQuotaUsage(q BandwidthQuota, addr Addr, days []FlowUsage, t time.Time) QuotaUsage {
+	u := QuotaUsage{Quota: q, Addr: addr, Start: q.PeriodStart(t)}
+	for _, day := range days {
+		if day.Day.Before(u.Start) {
+			continue
+		}
+		u.Days = append(u.Days, day)
+		u.Used += day.Total()
+	}
+	return u
+}
+
+// Percent returns the share of the quota used
+func (u QuotaUsage) Percent() float64 {
+	if u.Quota.Bytes <= 0 {
+		return 0
+	}
+	return float64(u.Used) * 100 / float64(u.Quota.Bytes)
+}
+
+// Level returns exceeded once the quota is used up and warning from warnPercent on, a
+// warnPercent of zero does not warn
+func (u QuotaUsage) Level(warnPercent int) QuotaLevel {
+	pct := u.Percent()
+	switch {
+	case pct >= 100:
+		return QuotaExceeded
+	case warnPercent > 0 && pct >= float64(warnPercent):
+		return QuotaWarning
+	}
+	return QuotaOK
+}
+
+func (u QuotaUsage) String() string {
+	return fmt.Sprintf(
+		"%s of %s %s (%.0f%%)",
+		humanize.Bytes(uint64(u.Used)),
+		u.Quota.Limit(),
+		u.Quota.Period,
+		u.Percent(),
+	)
+}
+
+// Annotation records the level reached by the usage, the text starts with the quota name
+// and level so QuotaAnnotated finds it again
+func (u QuotaUsage) Annotation(level QuotaLevel, t time.Time) Annotation {
+	return Annotation{
+		Time: t,
+		Addr: u.Addr,
+		Kind: AnnotationQuota,
+		Text: quotaAnnotationPrefix(u.Quota.Name, level) + u.String(),
+	}
+}
+
+// QuotaAnnotated reports if the annotations record the quota reaching the level, including
+// the annotations suppressed by a maintenance window
+func QuotaAnnotated(annotations []Annotation, name string, level QuotaLevel) bool {
+	prefix := quotaAnnotationPrefix(name, level)
+	return slices.ContainsFunc(annotations, func(a Annotation) bool {
+		switch a.Kind {
+		case AnnotationQuota:
+			return strings.HasPrefix(a.Text, prefix)
+		case AnnotationMaintenance:
+			return strings.HasPrefix(a.Text, "["+string(AnnotationQuota)+"] "+prefix)
+		}
+		return false
+	})
+}
+
+func quotaAnnotationPrefix(name string, level QuotaLevel) string {
+	return "quota " + name + " " + string(level) + ": "
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewQuotaUsage(t *testing.T) {
+	addr := MustParseAddr("192.168.1.20")
+	at := time.Date(2024, 6, 3, 15, 0, 0, 0, time.Local)
+	days := []FlowUsage{
+		{Day: time.Date(2024, 5, 31, 0, 0, 0, 0, time.Local), In: 900, Out: 100},
+		{Day: time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local), In: 300, Out: 100},
+		{Day: time.Date(2024, 6, 3, 0, 0, 0, 0, time.Local), In: 350, Out: 50},
+	}
+	tests := map[string]struct {
+		period QuotaPeriod
+		bytes  int64
+		used   int64
+		days   int
+		level  QuotaLevel
+	}{
+		"Daily":           {period: QuotaDaily, bytes: 1000, used: 400, days: 1, level: QuotaOK},
+		"DailyWarning":    {period: QuotaDaily, bytes: 480, used: 400, days: 1, level: QuotaWarning},
+		"DailyExceeded":   {period: QuotaDaily, bytes: 400, used: 400, days: 1, level: QuotaExceeded},
+		"Monthly":         {period: QuotaMonthly, bytes: 2000, used: 800, days: 2, level: QuotaOK},
+		"MonthlyExceeded": {period: QuotaMonthly, bytes: 500, used: 800, days: 2, level: QuotaExceeded},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			q := BandwidthQuota{Name: "q", Period: tc.period, Bytes: tc.bytes}
+			u := NewQuotaUsage(q, addr, days, at)
+			if u.Used != tc.used {
+				t.Errorf("used want: %d, got: %d", tc.used, u.Used)
+			}
+			if len(u.Days) != tc.days {
+				t.Errorf("days want: %d, got: %d", tc.days, len(u.Days))
+			}
+			if got := u.Level(80); got != tc.level {
+				t.Errorf("level want: %s, got: %s", tc.level, got)
+			}
+		})
+	}
+}
+
+func TestBandwidthQuota_Applies(t *testing.T) {
+	d := Device{
+		Addr: MustParseAddr("192.168.1.20"),
+		Meta: Meta{Tags: Tags{{Val: "nas"}}},
+	}
+	tests := map[string]struct {
+		scope  QuotaScope
+		target string
+		want   bool
+	}{
+		"Device":      {scope: QuotaScopeDevice, target: "192.168.1.20", want: true},
+		"OtherDevice": {scope: QuotaScopeDevice, target: "192.168.1.21", want: false},
+		"Tag":         {scope: QuotaScopeTag, target: "nas", want: true},
+		"OtherTag":    {scope: QuotaScopeTag, target: "printer", want: false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			q := BandwidthQuota{Scope: tc.scope, Target: tc.target}
+			if got := q.Applies(d); got != tc.want {
+				t.Errorf("applies want: %t, got: %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestQuotaAnnotated(t *testing.T) {
+	at := time.Date(2024, 6, 3, 15, 0, 0, 0, time.Local)
+	u := QuotaUsage{
+		Quota: BandwidthQuota{Name: "backups", Period: QuotaDaily, Bytes: 100},
+		Addr:  MustParseAddr("192.168.1.20"),
+		Used:  90,
+	}
+	warned := []Annotation{u.Annotation(QuotaWarning, at)}
+	suppressed := []Annotation{MaintenanceWindow{Name: "nightly"}.Suppress(warned[0])}
+	tests := map[string]struct {
+		annotations []Annotation
+		name        string
+		level       QuotaLevel
+		want        bool
+	}{
+		"Warned":        {annotations: warned, name: "backups", level: QuotaWarning, want: true},
+		"NotExceeded":   {annotations: warned, name: "backups", level: QuotaExceeded, want: false},
+		"OtherQuota":    {annotations: warned, name: "backup", level: QuotaWarning, want: false},
+		"Suppressed":    {annotations: suppressed, name: "backups", level: QuotaWarning, want: true},
+		"NoAnnotations": {name: "backups", level: QuotaWarning, want: false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := QuotaAnnotated(tc.annotations, tc.name, tc.level); got != tc.want {
+				t.Errorf("annotated want: %t, got: %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseQuotaBytes(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  int64
+		err   error
+	}{
+		"GB":      {input: "10GB", want: 10_000_000_000},
+		"GiB":     {input: "1 GiB", want: 1 << 30},
+		"Zero":    {input: "0", err: ErrInvalidQuotaBytes},
+		"Invalid": {input: "lots", err: ErrInvalidQuotaBytes},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseQuotaBytes(tc.input)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("error want: %v, got: %v", tc.err, err)
+			}
+			if got != tc.want {
+				t.Errorf("bytes want: %d, got: %d", tc.want, got)
+			}
+		})
+	}
+}
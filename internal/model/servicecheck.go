@@ -0,0 +1,177 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"cmp"
+	"net/netip"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type (
+	// ServiceCheck is a tcp connect to an open port of a device, derived from its port scan
+	ServiceCheck struct {
+		Addr   Addr
+		Port   int
+		Device string
+	}
+
+	// ServiceCheckResult is one connect of a check, Err is why the service was down
+	ServiceCheckResult struct {
+		Start   time.Time
+		Addr    Addr
+		Port    int
+		Latency time.Duration
+		Err     string
+	}
+
+	// ServiceCheckStatus summarizes the results of one service check
+	ServiceCheckStatus struct {
+		Check    ServiceCheck
+		Last     ServiceCheckResult
+		Average  time.Duration
+		Results  int
+		Failures int
+	}
+)
+
+// wellKnownServices names the ports shown for the service checks
+var wellKnownServices = map[int]string{
+	21:   "ftp",
+	22:   "ssh",
+	23:   "telnet",
+	25:   "smtp",
+	53:   "dns",
+	80:   "http",
+	110:  "pop3",
+	143:  "imap",
+	389:  "ldap",
+	443:  "https",
+	445:  "smb",
+	587:  "submission",
+	636:  "ldaps",
+	993:  "imaps",
+	995:  "pop3s",
+	1433: "mssql",
+	1883: "mqtt",
+	3306: "mysql",
+	3389: "rdp",
+	5432: "postgres",
+	5900: "vnc",
+	6379: "redis",
+	8080: "http-alt",
+	8443: "https-alt",
+}
+
+// ServiceName returns the name of a well known port, the port number otherwise
+func ServiceName(port int) string {
+	if name, ok := wellKnownServices[port]; ok {
+		return name
+	}
+	return strconv.Itoa(port)
+}
+
+func (c ServiceCheck) String() string {
+	return ServiceName(c.Port) + " " + c.AddrPort().String()
+}
+
+// AddrPort is the address connected to by the check
+func (c ServiceCheck) AddrPort() netip.AddrPort {
+	return netip.AddrPortFrom(c.Addr.Addr(), uint16(c.Port))
+}
+
+// Key identifies the check of the result
+func (r ServiceCheckResult) Key() ServiceCheck {
+	return ServiceCheck{Addr: r.Addr, Port: r.Port}
+}
+
+// Failed reports if the service was down
+func (r ServiceCheckResult) Failed() bool {
+	return r.Err != ""
+}
+
+// State is pending until the check has run, then the state of its latest result
+func (s ServiceCheckStatus) State() CheckState {
+	switch {
+	case s.Results == 0:
+		return CheckPending
+	case s.Last.Failed():
+		return CheckDown
+	}
+	return CheckUp
+}
+
+// Uptime is the percentage of the results which were up
+func (s ServiceCheckStatus) Uptime() float64 {
+	if s.Results == 0 {
+		return 0
+	}
+	return float64(s.Results-s.Failures) * 100 / float64(s.Results)
+}
+
+// ServiceChecksFor returns a check for each of the ports open on the devices with the tag,
+// ordered by address and port
+func ServiceChecksFor(devices []Device, tag string, ports []int) []ServiceCheck {
+	checks := make([]ServiceCheck, 0)
+	for _, d := range devices {
+		if !d.Meta.Tags.Has(tag) {
+			continue
+		}
+		for _, port := range d.Server.Ports.Ports {
+			if slices.Contains(ports, port) {
+				checks = append(checks, ServiceCheck{Addr: d.Addr, Port: port, Device: d.Name})
+			}
+		}
+	}
+	slices.SortFunc(checks, compareServiceChecks)
+	return checks
+}
+
+// SummarizeServiceChecks returns the status of each check in the order of the checks,
+// results of checks which no longer exist are left out.  The average latency only covers
+// the results which were up.
+func SummarizeServiceChecks(
+	checks []ServiceCheck,
+	results []ServiceCheckResult,
+) []ServiceCheckStatus {
+	ret := make([]ServiceCheckStatus, 0, len(checks))
+	index := make(map[ServiceCheck]int, len(checks))
+	totals := make([]time.Duration, len(checks))
+	for _, c := range checks {
+		index[ServiceCheck{Addr: c.Addr, Port: c.Port}] = len(ret)
+		ret = append(ret, ServiceCheckStatus{Check: c})
+	}
+	for _, r := range results {
+		idx, ok := index[r.Key()]
+		if !ok {
+			continue
+		}
+		s := &ret[idx]
+		s.Results++
+		if !r.Start.Before(s.Last.Start) {
+			s.Last = r
+		}
+		if r.Failed() {
+			s.Failures++
+			continue
+		}
+		totals[idx] += r.Latency
+	}
+	for idx := range ret {
+		if ok := ret[idx].Results - ret[idx].Failures; ok > 0 {
+			ret[idx].Average = totals[idx] / time.Duration(ok)
+		}
+	}
+	return ret
+}
+
+func compareServiceChecks(a, b ServiceCheck) int {
+	if c := a.Addr.Compare(b.Addr); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Port, b.Port)
+}
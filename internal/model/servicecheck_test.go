@@ -0,0 +1,81 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestServiceChecksFor(t *testing.T) {
+	web := Device{
+		Name:   "web",
+		Addr:   MustParseAddr("192.168.1.20"),
+		Meta:   Meta{Tags: Tags{{Val: "server"}}},
+		Server: Server{Ports: PortList{Ports: []int{443, 22, 9999}}},
+	}
+	db := Device{
+		Name:   "db",
+		Addr:   MustParseAddr("192.168.1.10"),
+		Meta:   Meta{Tags: Tags{{Val: "server"}, {Val: "critical"}}},
+		Server: Server{Ports: PortList{Ports: []int{5432}}},
+	}
+	laptop := Device{
+		Name:   "laptop",
+		Addr:   MustParseAddr("192.168.1.5"),
+		Server: Server{Ports: PortList{Ports: []int{22}}},
+	}
+	want := []ServiceCheck{
+		{Addr: db.Addr, Port: 5432, Device: "db"},
+		{Addr: web.Addr, Port: 22, Device: "web"},
+		{Addr: web.Addr, Port: 443, Device: "web"},
+	}
+	got := ServiceChecksFor([]Device{web, laptop, db}, "server", []int{22, 443, 5432})
+	if diff := cmp.Diff(want, got, cmpopts.EquateComparable(netip.Addr{})); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestSummarizeServiceChecks(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	later := start.Add(5 * time.Minute)
+	addr := MustParseAddr("192.168.1.20")
+	ssh := ServiceCheck{Addr: addr, Port: 22, Device: "web"}
+	https := ServiceCheck{Addr: addr, Port: 443, Device: "web"}
+	smtp := ServiceCheck{Addr: addr, Port: 25, Device: "web"}
+	results := []ServiceCheckResult{
+		{Start: start, Addr: addr, Port: 22, Latency: 2 * time.Millisecond},
+		{Start: start, Addr: addr, Port: 443, Latency: 4 * time.Millisecond},
+		{Start: start, Addr: addr, Port: 8080, Latency: time.Millisecond},
+		{Start: later, Addr: addr, Port: 22, Latency: 4 * time.Millisecond},
+		{Start: later, Addr: addr, Port: 443, Err: "connection refused"},
+	}
+	want := []ServiceCheckStatus{
+		{Check: ssh, Last: results[3], Average: 3 * time.Millisecond, Results: 2},
+		{Check: https, Last: results[4], Average: 4 * time.Millisecond, Results: 2, Failures: 1},
+		{Check: smtp},
+	}
+	got := SummarizeServiceChecks([]ServiceCheck{ssh, https, smtp}, results)
+	if diff := cmp.Diff(want, got, cmpopts.EquateComparable(netip.Addr{})); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	states := []CheckState{CheckUp, CheckDown, CheckPending}
+	for idx, s := range got {
+		if s.State() != states[idx] {
+			t.Errorf("%s state want: %s, got: %s", s.Check, states[idx], s.State())
+		}
+	}
+	if uptime := got[1].Uptime(); uptime != 50 {
+		t.Errorf("uptime want: 50, got: %v", uptime)
+	}
+	if name := https.String(); name != "https 192.168.1.20:443" {
+		t.Errorf("name want: https 192.168.1.20:443, got: %s", name)
+	}
+}
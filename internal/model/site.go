@@ -0,0 +1,134 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Site is a location, such as an office or a remote segment watched by an agent, that
+// networks and devices are partitioned by
+type Site struct {
+	Name        string
+	Description string
+}
+
+// SiteSummary holds the statistics of the devices at a site
+type SiteSummary struct {
+	Site      Site
+	Networks  int
+	Devices   int
+	Online    int
+	PingMean  time.Duration
+	RecvBytes int
+	XmitBytes int
+}
+
+var (
+	ErrSiteDoesNotExist = errors.New("site does not exist")
+	ErrSiteInUse        = errors.New("site is in use")
+	ErrInvalidSiteName  = errors.New("site name must not be empty or contain spaces or commas")
+)
+
+// ValidSiteName reports if the name can be used as a site
+func ValidSiteName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t,")
+}
+
+// SiteLookup returns a function giving the site of a device.  A site set on the device wins,
+// then the site of the most specific network containing the device.  Networks without a
+// site, and devices outside every network, belong to the local site.
+func SiteLookup(networks []Network, local string) func(Device) string {
+	nets := slices.Clone(networks)
+	slices.SortStableFunc(nets, func(a, b Network) int {
+		return b.Prefix.Bits() - a.Prefix.Bits()
+	})
+	return func(d Device) string {
+		if d.Meta.Site != "" {
+			return d.Meta.Site
+		}
+		for _, n := range nets {
+			if !n.Contains(d) {
+				continue
+			}
+			if n.Site == "" {
+				return local
+			}
+			return n.Site
+		}
+		return local
+	}
+}
+
+// SiteDeviceFilter selects the devices at the site
+func SiteDeviceFilter(site string, lookup func(Device) string) DeviceFilter {
+	return func(d Device) bool {
+		return lookup(d) == site
+	}
+}
+
+// SiteNetworkFilter selects the networks at the site, networks without a site belong to
+// the local site
+func SiteNetworkFilter(site string, local string) NetworkFilter {
+	return func(n Network) bool {
+		if n.Site == "" {
+			return site == local
+		}
+		return n.Site == site
+	}
+}
+
+// SummarizeSites counts the networks, devices and online devices of each site and averages
+// the ping of the online devices, a device is online when it answered its last ping.  Sites
+// referenced by networks or devices but missing from sites are added after the given ones.
+func SummarizeSites(
+	sites []Site,
+	local string,
+	networks []Network,
+	devices []Device,
+) []SiteSummary {
+	ret := make([]SiteSummary, 0, len(sites))
+	index := make(map[string]int)
+	get := func(name string) *SiteSummary {
+		idx, ok := index[name]
+		if !ok {
+			idx = len(ret)
+			index[name] = idx
+			ret = append(ret, SiteSummary{Site: Site{Name: name}})
+		}
+		return &ret[idx]
+	}
+	for _, s := range sites {
+		get(s.Name).Site = s
+	}
+
+	lookup := SiteLookup(networks, local)
+	for _, n := range networks {
+		site := n.Site
+		if site == "" {
+			site = local
+		}
+		get(site).Networks++
+	}
+	pingTotals := make(map[string]time.Duration)
+	for _, d := range devices {
+		site := lookup(d)
+		ss := get(site)
+		ss.Devices++
+		if !d.PerformancePing.FirstSeen.IsZero() && !d.PerformancePing.LastFailed {
+			ss.Online++
+			pingTotals[site] += d.PerformancePing.Mean
+		}
+	}
+	for idx := range ret {
+		if ret[idx].Online > 0 {
+			ret[idx].PingMean = pingTotals[ret[idx].Site.Name] / time.Duration(ret[idx].Online)
+		}
+	}
+	return ret
+}
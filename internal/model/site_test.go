@@ -0,0 +1,104 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func siteTestNetworks() []Network {
+	return []Network{
+		{Name: "lan", Prefix: PrefixToModelPrefix(netip.MustParsePrefix("10.0.0.0/16"))},
+		{
+			Name:   "branch",
+			Prefix: PrefixToModelPrefix(netip.MustParsePrefix("10.0.0.0/8")),
+			Site:   "branch",
+		},
+		{
+			Name:   "lab",
+			Prefix: PrefixToModelPrefix(netip.MustParsePrefix("10.0.5.0/24")),
+			Site:   "lab",
+		},
+	}
+}
+
+func TestSiteLookup(t *testing.T) {
+	lookup := SiteLookup(siteTestNetworks(), "hq")
+	tests := map[string]struct {
+		dev  Device
+		want string
+	}{
+		"DeviceSite": {
+			dev:  Device{Addr: MustParseAddr("10.0.5.1"), Meta: Meta{Site: "remote"}},
+			want: "remote",
+		},
+		"MostSpecificNetwork": {
+			dev:  Device{Addr: MustParseAddr("10.0.5.1")},
+			want: "lab",
+		},
+		"NetworkWithSite": {
+			dev:  Device{Addr: MustParseAddr("10.9.0.1")},
+			want: "branch",
+		},
+		"NetworkWithoutSite": {
+			dev:  Device{Addr: MustParseAddr("10.0.1.1")},
+			want: "hq",
+		},
+		"Local": {
+			dev:  Device{Addr: MustParseAddr("192.168.1.1")},
+			want: "hq",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := lookup(tc.dev); got != tc.want {
+				t.Fatalf("site mismatch want %q got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSummarizeSites(t *testing.T) {
+	seen := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	online := func(addr string, mean time.Duration) Device {
+		return Device{
+			Addr:            MustParseAddr(addr),
+			PerformancePing: Pinger{FirstSeen: seen, LastSeen: seen, Mean: mean},
+		}
+	}
+	devices := []Device{
+		online("10.0.5.1", 2*time.Millisecond),
+		online("10.0.5.2", 4*time.Millisecond),
+		{
+			Addr:            MustParseAddr("10.0.5.3"),
+			PerformancePing: Pinger{FirstSeen: seen, LastFailed: true},
+		},
+		online("192.168.1.1", time.Millisecond),
+		{Addr: MustParseAddr("172.16.0.1"), Meta: Meta{Site: "remote"}},
+	}
+	sites := []Site{{Name: "hq", Description: "head office"}, {Name: "empty"}}
+
+	got := SummarizeSites(sites, "hq", siteTestNetworks(), devices)
+	want := []SiteSummary{
+		{
+			Site:     Site{Name: "hq", Description: "head office"},
+			Networks: 1,
+			Devices:  1,
+			Online:   1,
+			PingMean: time.Millisecond,
+		},
+		{Site: Site{Name: "empty"}},
+		{Site: Site{Name: "branch"}, Networks: 1},
+		{Site: Site{Name: "lab"}, Networks: 1, Devices: 3, Online: 2, PingMean: 3 * time.Millisecond},
+		{Site: Site{Name: "remote"}, Devices: 1},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("site summary mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import "time"
+
+// SpeedTest is the result of one bandwidth test of the internet connection, the rates are in
+// bits per second
+type SpeedTest struct {
+	Start    time.Time
+	Method   string
+	Server   string
+	Latency  time.Duration
+	Download float64
+	Upload   float64
+	Err      string
+}
+
+// Failed reports if the test did not complete
+func (st SpeedTest) Failed() bool {
+	return st.Err != ""
+}
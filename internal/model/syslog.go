@@ -0,0 +1,85 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SyslogSeverity is the severity of a syslog message, lower is more severe
+type SyslogSeverity int
+
+const (
+	SyslogEmergency SyslogSeverity = iota
+	SyslogAlert
+	SyslogCritical
+	SyslogError
+	SyslogWarning
+	SyslogNotice
+	SyslogInfo
+	SyslogDebug
+)
+
+var ErrInvalidSyslogSeverity = errors.New("invalid syslog severity")
+
+var syslogSeverityNames = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+func (s SyslogSeverity) String() string {
+	if s < SyslogEmergency || s > SyslogDebug {
+		return strconv.Itoa(int(s))
+	}
+	return syslogSeverityNames[s]
+}
+
+// ParseSyslogSeverity parses the keyword or number of a severity
+func ParseSyslogSeverity(s string) (SyslogSeverity, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for i, name := range syslogSeverityNames {
+		if s == name {
+			return SyslogSeverity(i), nil
+		}
+	}
+	switch s {
+	case "emergency", "panic":
+		return SyslogEmergency, nil
+	case "critical":
+		return SyslogCritical, nil
+	case "error":
+		return SyslogError, nil
+	case "warn":
+		return SyslogWarning, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < int(SyslogEmergency) || n > int(SyslogDebug) {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidSyslogSeverity, s)
+	}
+	return SyslogSeverity(n), nil
+}
+
+// SyslogMessage is a log message a device sent to the syslog receiver.  Time is the
+// timestamp of the message, Received when it arrived.
+type SyslogMessage struct {
+	Addr     Addr
+	Received time.Time
+	Time     time.Time
+	Facility int
+	Severity SyslogSeverity
+	Hostname string
+	App      string
+	Message  string
+}
+
+func (m SyslogMessage) String() string {
+	if m.App == "" {
+		return fmt.Sprintf("%s %s: %s", m.Addr, m.Severity, m.Message)
+	}
+	return fmt.Sprintf("%s %s %s: %s", m.Addr, m.Severity, m.App, m.Message)
+}
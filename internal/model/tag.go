@@ -6,13 +6,60 @@ package model
 
 import (
 	"database/sql/driver"
+	"errors"
 	"slices"
+	"strings"
 )
 
 type Tag struct {
 	Val string
 }
 
+// TagDefinition describes a user managed tag, devices and networks carrying the tag use
+// its monitoring policy in place of the global intervals
+type TagDefinition struct {
+	Name        string
+	Description string
+	Policy      MonitoringPolicy
+}
+
+var (
+	ErrTagDoesNotExist = errors.New("tag does not exist")
+	ErrInvalidTagName  = errors.New("tag name must not be empty or contain spaces or commas")
+)
+
+// ValidTagName reports if the name can be used as a tag
+func ValidTagName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t,")
+}
+
+// TagDeviceFilter selects devices carrying the tag
+func TagDeviceFilter(name string) DeviceFilter {
+	return func(d Device) bool {
+		return d.Meta.Tags.Has(name)
+	}
+}
+
+// TagNetworkFilter selects networks carrying the tag
+func TagNetworkFilter(name string) NetworkFilter {
+	return func(n Network) bool {
+		return n.Tags.Has(name)
+	}
+}
+
+// TagPolicyLookup combines the policies of every tag definition the device carries
+func TagPolicyLookup(defs []TagDefinition) PolicyLookup {
+	return func(d Device) MonitoringPolicy {
+		var p MonitoringPolicy
+		for _, def := range defs {
+			if d.Meta.Tags.Has(def.Name) {
+				p = p.Combine(def.Policy)
+			}
+		}
+		return p
+	}
+}
+
 func (t Tag) Equal(intag Tag) bool {
 	if t.Val == intag.Val {
 		return true
@@ -7,6 +7,7 @@ package model
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"slices"
 
 	"github.com/charmbracelet/log"
 )
@@ -22,6 +23,13 @@ func (ts Tags) String() string {
 	return v.(string)
 }
 
+// Has reports if the tag name is in the list
+func (ts Tags) Has(name string) bool {
+	return slices.ContainsFunc(ts, func(t Tag) bool {
+		return t.Val == name
+	})
+}
+
 func (ts Tags) Value() (driver.Value, error) {
 	if len(ts) == 0 {
 		return "{}", nil
@@ -0,0 +1,69 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+type TombstoneKind string
+
+const (
+	TombstoneDevice  TombstoneKind = "device"
+	TombstoneNetwork TombstoneKind = "network"
+)
+
+var (
+	ErrTombstoneDoesNotExist = errors.New("deleted item does not exist")
+	ErrInvalidTombstoneKind  = errors.New("invalid deleted item kind")
+)
+
+// ParseTombstoneKind converts the string into a TombstoneKind
+func ParseTombstoneKind(s string) (TombstoneKind, error) {
+	switch TombstoneKind(s) {
+	case TombstoneDevice, TombstoneNetwork:
+		return TombstoneKind(s), nil
+	}
+	return "", ErrInvalidTombstoneKind
+}
+
+// Tombstone holds a copy of a deleted device or network until its grace period ends, allowing
+// the delete to be undone.  Key is the device addr or the network name.
+type Tombstone struct {
+	Kind      TombstoneKind
+	Key       string
+	DeletedAt time.Time
+	PurgeAt   time.Time
+	Device    Device
+	Network   Network
+}
+
+// DeviceTombstone creates the tombstone for the deleted device
+func DeviceTombstone(d Device, ts time.Time, grace time.Duration) Tombstone {
+	return Tombstone{
+		Kind:      TombstoneDevice,
+		Key:       d.Addr.String(),
+		DeletedAt: ts,
+		PurgeAt:   ts.Add(grace),
+		Device:    d,
+	}
+}
+
+// NetworkTombstone creates the tombstone for the deleted network
+func NetworkTombstone(n Network, ts time.Time, grace time.Duration) Tombstone {
+	return Tombstone{
+		Kind:      TombstoneNetwork,
+		Key:       n.Name,
+		DeletedAt: ts,
+		PurgeAt:   ts.Add(grace),
+		Network:   n,
+	}
+}
+
+// Expired reports if the grace period of the tombstone has passed
+func (t Tombstone) Expired(now time.Time) bool {
+	return !now.Before(t.PurgeAt)
+}
@@ -0,0 +1,225 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"slices"
+	"strconv"
+	"time"
+)
+
+type (
+	// TopologyTrace is the hops of a traceroute to a device of a network.  The hops before the
+	// device are the routers on the way to the network, the last of them routes the network.
+	TopologyTrace struct {
+		Network Prefix
+		Target  Addr
+		Hops    []Addr
+		Traced  time.Time
+	}
+
+	// TopologyNodeKind tells the layers of the topology apart
+	TopologyNodeKind string
+
+	// TopologyNode is mason itself, a router, a network or a device of the topology, Layer
+	// counts the steps down from mason.
+	TopologyNode struct {
+		ID    string
+		Kind  TopologyNodeKind
+		Label string
+		Addr  Addr
+		Layer int
+	}
+
+	// TopologyEdge joins a node to the one the next layer down it leads to
+	TopologyEdge struct {
+		From string
+		To   string
+	}
+
+	// Topology is the layered L3 view of the networks, mason at the top then the routers found
+	// by the traces, the networks behind them and their devices.
+	Topology struct {
+		Nodes  []TopologyNode
+		Edges  []TopologyEdge
+		Traces []TopologyTrace
+	}
+)
+
+const (
+	TopologyMason   TopologyNodeKind = "mason"
+	TopologyRouter  TopologyNodeKind = "router"
+	TopologyNetwork TopologyNodeKind = "network"
+	TopologyDevice  TopologyNodeKind = "device"
+	// TopologyMore stands in for the devices of a network past the shown ones
+	TopologyMore TopologyNodeKind = "more"
+
+	topologyMasonID = "mason"
+)
+
+// Routers returns the hops of the trace which answered, without the traced device
+func (t TopologyTrace) Routers() []Addr {
+	routers := make([]Addr, 0, len(t.Hops))
+	for _, hop := range t.Hops {
+		if !hop.A.IsValid() || hop == t.Target {
+			continue
+		}
+		routers = append(routers, hop)
+	}
+	return routers
+}
+
+// BuildTopology layers the networks under the routers their trace went through, a network
+// without a trace is taken to be directly attached to mason.  Up to maxDevices devices are
+// shown per network and a router known as a device is only shown as the router.
+func BuildTopology(
+	traces []TopologyTrace,
+	networks []Network,
+	devices []Device,
+	maxDevices int,
+) Topology {
+	names := make(map[Addr]string, len(devices))
+	for _, d := range devices {
+		names[d.Addr] = d.Name
+	}
+	byNetwork := make(map[Prefix]TopologyTrace, len(traces))
+	for _, t := range traces {
+		byNetwork[t.Network] = t
+	}
+	networks = slices.Clone(networks)
+	slices.SortFunc(networks, CompareNetwork)
+	devices = slices.Clone(devices)
+	slices.SortFunc(devices, func(a, b Device) int {
+		return a.Addr.Compare(b.Addr)
+	})
+
+	// a router reached by several paths sits at its shortest one
+	depth := make(map[Addr]int)
+	for _, n := range networks {
+		for idx, hop := range byNetwork[n.Prefix].Routers() {
+			if d, ok := depth[hop]; !ok || idx+1 < d {
+				depth[hop] = idx + 1
+			}
+		}
+	}
+
+	b := topologyBuilder{
+		index: make(map[string]int),
+		edges: make(map[TopologyEdge]bool),
+	}
+	b.node(TopologyNode{ID: topologyMasonID, Kind: TopologyMason, Label: "mason"})
+
+	routers := make(map[Addr]bool)
+	for _, n := range networks {
+		parent := topologyMasonID
+		for _, hop := range byNetwork[n.Prefix].Routers() {
+			id := "router/" + hop.String()
+			label := hop.String()
+			if name, ok := names[hop]; ok && name != "" && name != label {
+				label = name + " (" + label + ")"
+			}
+			b.node(TopologyNode{
+				ID:    id,
+				Kind:  TopologyRouter,
+				Label: label,
+				Addr:  hop,
+				Layer: depth[hop],
+			})
+			b.edge(parent, id)
+			routers[hop] = true
+			parent = id
+		}
+		id := "network/" + n.Prefix.String()
+		b.node(TopologyNode{
+			ID:    id,
+			Kind:  TopologyNetwork,
+			Label: n.Name,
+			Layer: b.layer(parent) + 1,
+		})
+		b.edge(parent, id)
+	}
+
+	for _, n := range networks {
+		id := "network/" + n.Prefix.String()
+		shown, hidden := 0, 0
+		for _, d := range devices {
+			if routers[d.Addr] || !n.Contains(d) {
+				continue
+			}
+			if shown >= maxDevices {
+				hidden++
+				continue
+			}
+			shown++
+			devID := "device/" + d.Addr.String()
+			b.node(TopologyNode{
+				ID:    devID,
+				Kind:  TopologyDevice,
+				Label: d.Name,
+				Addr:  d.Addr,
+				Layer: b.layer(id) + 1,
+			})
+			b.edge(id, devID)
+		}
+		if hidden > 0 {
+			moreID := "more/" + n.Prefix.String()
+			b.node(TopologyNode{
+				ID:    moreID,
+				Kind:  TopologyMore,
+				Label: "+" + strconv.Itoa(hidden) + " more",
+				Layer: b.layer(id) + 1,
+			})
+			b.edge(id, moreID)
+		}
+	}
+
+	b.topo.Traces = make([]TopologyTrace, 0, len(networks))
+	for _, n := range networks {
+		if t, ok := byNetwork[n.Prefix]; ok {
+			b.topo.Traces = append(b.topo.Traces, t)
+		}
+	}
+	return b.topo
+}
+
+// Layers returns the nodes grouped by layer, from mason down
+func (t Topology) Layers() [][]TopologyNode {
+	layers := make([][]TopologyNode, 0)
+	for _, n := range t.Nodes {
+		for len(layers) <= n.Layer {
+			layers = append(layers, nil)
+		}
+		layers[n.Layer] = append(layers[n.Layer], n)
+	}
+	return layers
+}
+
+type topologyBuilder struct {
+	topo  Topology
+	index map[string]int
+	edges map[TopologyEdge]bool
+}
+
+// node adds the node the first time it is reached
+func (b *topologyBuilder) node(n TopologyNode) {
+	if _, ok := b.index[n.ID]; ok {
+		return
+	}
+	b.index[n.ID] = len(b.topo.Nodes)
+	b.topo.Nodes = append(b.topo.Nodes, n)
+}
+
+func (b *topologyBuilder) layer(id string) int {
+	return b.topo.Nodes[b.index[id]].Layer
+}
+
+func (b *topologyBuilder) edge(from, to string) {
+	e := TopologyEdge{From: from, To: to}
+	if from == to || b.edges[e] {
+		return
+	}
+	b.edges[e] = true
+	b.topo.Edges = append(b.topo.Edges, e)
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestTopologyTrace_Routers(t *testing.T) {
+	trace := TopologyTrace{
+		Target: MustParseAddr("10.0.2.5"),
+		Hops: []Addr{
+			MustParseAddr("192.168.1.1"),
+			{},
+			MustParseAddr("10.0.0.1"),
+			MustParseAddr("10.0.2.5"),
+		},
+	}
+	want := []Addr{MustParseAddr("192.168.1.1"), MustParseAddr("10.0.0.1")}
+	if diff := cmp.Diff(want, trace.Routers(), cmpopts.EquateComparable(netip.Addr{})); diff != "" {
+		t.Errorf("routers mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBuildTopology(t *testing.T) {
+	lan := Network{Name: "lan", Prefix: MustParsePrefix("192.168.1.0/24")}
+	lab := Network{Name: "lab", Prefix: MustParsePrefix("10.0.2.0/24")}
+	iot := Network{Name: "iot", Prefix: MustParsePrefix("10.0.3.0/24")}
+	router := MustParseAddr("192.168.1.1")
+	core := MustParseAddr("10.0.0.1")
+	devices := []Device{
+		{Name: "gw", Addr: router},
+		{Name: "nas", Addr: MustParseAddr("192.168.1.20")},
+		{Name: "pi", Addr: MustParseAddr("10.0.2.5")},
+		{Name: "bulb1", Addr: MustParseAddr("10.0.3.10")},
+		{Name: "bulb2", Addr: MustParseAddr("10.0.3.11")},
+		{Name: "bulb3", Addr: MustParseAddr("10.0.3.12")},
+	}
+	traces := []TopologyTrace{
+		{Network: lan.Prefix, Target: MustParseAddr("192.168.1.20"), Hops: []Addr{
+			MustParseAddr("192.168.1.20"),
+		}},
+		{Network: lab.Prefix, Target: MustParseAddr("10.0.2.5"), Hops: []Addr{
+			router, core, MustParseAddr("10.0.2.5"),
+		}},
+		{Network: iot.Prefix, Target: MustParseAddr("10.0.3.10"), Hops: []Addr{
+			router, MustParseAddr("10.0.3.10"),
+		}},
+	}
+
+	topo := BuildTopology(traces, []Network{lan, lab, iot}, devices, 2)
+
+	wantLayers := map[string]int{
+		"mason":                  0,
+		"router/192.168.1.1":     1,
+		"router/10.0.0.1":        2,
+		"network/10.0.2.0/24":    3,
+		"network/10.0.3.0/24":    2,
+		"network/192.168.1.0/24": 1,
+		"device/192.168.1.20":    2,
+		"device/10.0.2.5":        4,
+		"device/10.0.3.10":       3,
+		"device/10.0.3.11":       3,
+		"more/10.0.3.0/24":       3,
+	}
+	gotLayers := make(map[string]int)
+	for _, n := range topo.Nodes {
+		gotLayers[n.ID] = n.Layer
+	}
+	if diff := cmp.Diff(wantLayers, gotLayers); diff != "" {
+		t.Errorf("layers mismatch (-want +got):\n%s", diff)
+	}
+
+	wantEdges := []TopologyEdge{
+		{From: "mason", To: "router/192.168.1.1"},
+		{From: "router/192.168.1.1", To: "router/10.0.0.1"},
+		{From: "router/10.0.0.1", To: "network/10.0.2.0/24"},
+		{From: "router/192.168.1.1", To: "network/10.0.3.0/24"},
+		{From: "mason", To: "network/192.168.1.0/24"},
+		{From: "network/10.0.2.0/24", To: "device/10.0.2.5"},
+		{From: "network/10.0.3.0/24", To: "device/10.0.3.10"},
+		{From: "network/10.0.3.0/24", To: "device/10.0.3.11"},
+		{From: "network/10.0.3.0/24", To: "more/10.0.3.0/24"},
+		{From: "network/192.168.1.0/24", To: "device/192.168.1.20"},
+	}
+	if diff := cmp.Diff(wantEdges, topo.Edges); diff != "" {
+		t.Errorf("edges mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, n := range topo.Nodes {
+		if n.ID == "router/192.168.1.1" && n.Label != "gw (192.168.1.1)" {
+			t.Errorf("router label want: gw (192.168.1.1), got: %s", n.Label)
+		}
+		if n.ID == "more/10.0.3.0/24" && n.Label != "+1 more" {
+			t.Errorf("more label want: +1 more, got: %s", n.Label)
+		}
+	}
+	if len(topo.Traces) != 3 {
+		t.Errorf("traces want: 3, got: %d", len(topo.Traces))
+	}
+	if layers := topo.Layers(); len(layers) != 5 || len(layers[3]) != 4 {
+		t.Errorf("layers want 5 with 4 nodes on the fourth, got: %v", layers)
+	}
+}
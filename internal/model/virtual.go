@@ -0,0 +1,128 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+type (
+	// VirtualPlatform names the software running the guests of a host
+	VirtualPlatform string
+
+	// GuestKind tells containers from virtual machines
+	GuestKind string
+
+	// Virtual ties hosts of containers and virtual machines to their guests.  A host has a
+	// platform and lists its guests, a guest known as a device has the addr of its host as
+	// parent.
+	Virtual struct {
+		Platform VirtualPlatform
+		Guests   Guests
+		LastScan time.Time
+		Parent   Addr
+	}
+
+	// Guest is a container or virtual machine as its host reports it, the MAC and addr are
+	// set when the host knows them
+	Guest struct {
+		ID    string
+		Name  string
+		Kind  GuestKind
+		State string
+		MAC   string
+		Addr  string
+	}
+
+	Guests []Guest
+)
+
+const (
+	VirtualPlatformDocker  VirtualPlatform = "docker"
+	VirtualPlatformProxmox VirtualPlatform = "proxmox"
+	VirtualPlatformESXi    VirtualPlatform = "esxi"
+
+	GuestContainer GuestKind = "container"
+	GuestVM        GuestKind = "vm"
+)
+
+// IsHost reports if the device runs guests
+func (v Virtual) IsHost() bool {
+	return v.Platform != ""
+}
+
+// IsGuest reports if the device runs on a known host
+func (v Virtual) IsGuest() bool {
+	return v.Parent.A.IsValid()
+}
+
+func (v Virtual) merge(in Virtual) (out Virtual, updated bool) {
+	// a newer scan replaces the guests, including when guests have been removed
+	if in.LastScan.After(v.LastScan) {
+		v.Platform = in.Platform
+		v.Guests = slices.Clone(in.Guests)
+		v.LastScan = in.LastScan
+		updated = true
+	}
+	if in.Parent.A.IsValid() && v.Parent.Compare(in.Parent) != 0 {
+		v.Parent = in.Parent
+		updated = true
+	}
+	return v, updated
+}
+
+// GuestOf finds the guest of the host which is the device, by its MAC or its addr
+func (v Virtual) GuestOf(d Device) (Guest, bool) {
+	idx := slices.IndexFunc(v.Guests, func(g Guest) bool {
+		if g.MAC != "" {
+			mac, err := ParseMAC(g.MAC)
+			if err == nil && d.HasMAC(mac) {
+				return true
+			}
+		}
+		return g.Addr != "" && g.Addr == d.Addr.String()
+	})
+	if idx < 0 {
+		return Guest{}, false
+	}
+	return v.Guests[idx], true
+}
+
+func (gs Guests) String() string {
+	v, err := gs.Value()
+	if err != nil {
+		log.Error("guests.String", "error", err)
+		return ""
+	}
+	return v.(string)
+}
+
+func (gs Guests) Value() (driver.Value, error) {
+	if len(gs) == 0 {
+		return "[]", nil
+	}
+	x, err := json.Marshal(gs)
+	if err != nil {
+		return nil, err
+	}
+	return string(x), nil
+}
+
+func (gs *Guests) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		if len(src) == 0 || src == "[]" {
+			*gs = nil
+			return nil
+		}
+		return json.Unmarshal([]byte(src), gs)
+	}
+	return nil
+}
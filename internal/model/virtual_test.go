@@ -0,0 +1,116 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestVirtual_merge(t *testing.T) {
+	earlier := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+	host := MustParseAddr("192.168.1.2")
+	web := Guest{ID: "101", Name: "web", Kind: GuestVM}
+	db := Guest{ID: "102", Name: "db", Kind: GuestVM}
+	tests := map[string]struct {
+		current     Virtual
+		in          Virtual
+		want        Virtual
+		wantUpdated bool
+	}{
+		"NewerScanReplacesGuests": {
+			current: Virtual{
+				Platform: VirtualPlatformProxmox,
+				Guests:   Guests{web, db},
+				LastScan: earlier,
+			},
+			in: Virtual{Platform: VirtualPlatformProxmox, Guests: Guests{db}, LastScan: later},
+			want: Virtual{
+				Platform: VirtualPlatformProxmox,
+				Guests:   Guests{db},
+				LastScan: later,
+			},
+			wantUpdated: true,
+		},
+		"OlderScanIgnored": {
+			current: Virtual{Platform: VirtualPlatformDocker, Guests: Guests{web}, LastScan: later},
+			in:      Virtual{Platform: VirtualPlatformDocker, LastScan: earlier},
+			want:    Virtual{Platform: VirtualPlatformDocker, Guests: Guests{web}, LastScan: later},
+		},
+		"NoLongerHost": {
+			current:     Virtual{Platform: VirtualPlatformDocker, Guests: Guests{web}, LastScan: earlier},
+			in:          Virtual{LastScan: later},
+			want:        Virtual{LastScan: later},
+			wantUpdated: true,
+		},
+		"ParentSet": {
+			current:     Virtual{},
+			in:          Virtual{Parent: host},
+			want:        Virtual{Parent: host},
+			wantUpdated: true,
+		},
+		"ParentKept": {
+			current: Virtual{Parent: host},
+			in:      Virtual{},
+			want:    Virtual{Parent: host},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, updated := tc.current.merge(tc.in)
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateComparable(netip.Addr{})); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+			if updated != tc.wantUpdated {
+				t.Errorf("updated want %t got %t", tc.wantUpdated, updated)
+			}
+		})
+	}
+}
+
+func TestVirtual_GuestOf(t *testing.T) {
+	host := Virtual{
+		Platform: VirtualPlatformDocker,
+		Guests: Guests{
+			{ID: "a1", Name: "web", MAC: "02:42:ac:11:00:02"},
+			{ID: "b2", Name: "db", Addr: "192.168.1.30"},
+		},
+	}
+	tests := map[string]struct {
+		device    Device
+		want      Guest
+		wantFound bool
+	}{
+		"ByMAC": {
+			device:    Device{Addr: MustParseAddr("172.17.0.2"), MAC: MustParseMAC("02:42:ac:11:00:02")},
+			want:      host.Guests[0],
+			wantFound: true,
+		},
+		"ByAddr": {
+			device:    Device{Addr: MustParseAddr("192.168.1.30"), MAC: MustParseMAC("00:00:5e:00:53:01")},
+			want:      host.Guests[1],
+			wantFound: true,
+		},
+		"NotAGuest": {
+			device: Device{Addr: MustParseAddr("192.168.1.31"), MAC: MustParseMAC("00:00:5e:00:53:02")},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, found := host.GuestOf(tc.device)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+			if found != tc.wantFound {
+				t.Errorf("found want %t got %t", tc.wantFound, found)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import "strconv"
+
+// VLAN is an 802.1Q virtual lan, an ID of 0 means the vlan is unknown
+type VLAN struct {
+	ID   int
+	Name string
+}
+
+func (v VLAN) IsEmpty() bool {
+	return v.ID == 0
+}
+
+func (v VLAN) String() string {
+	if v.IsEmpty() {
+		return ""
+	}
+	if v.Name == "" {
+		return strconv.Itoa(v.ID)
+	}
+	return strconv.Itoa(v.ID) + " (" + v.Name + ")"
+}
+
+// VLANDeviceFilter selects devices which are members of the vlan id
+func VLANDeviceFilter(id int) DeviceFilter {
+	return func(d Device) bool {
+		return d.VLAN.ID == id
+	}
+}
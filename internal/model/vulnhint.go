@@ -0,0 +1,282 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+type (
+	// Banner is the greeting or http server header an open port answered with
+	Banner struct {
+		Port int
+		Text string
+	}
+
+	// Banners are the banners of the open ports of a device, in port order
+	Banners []Banner
+)
+
+func (bs Banners) String() string {
+	v, err := bs.Value()
+	if err != nil {
+		log.Error("banners.String", "error", err)
+		return ""
+	}
+	return v.(string)
+}
+
+func (bs Banners) Value() (driver.Value, error) {
+	if len(bs) == 0 {
+		return "", nil
+	}
+	x, err := json.Marshal(bs)
+	if err != nil {
+		return nil, err
+	}
+	return string(x), nil
+}
+
+func (bs *Banners) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		if len(src) == 0 {
+			*bs = nil
+			return nil
+		}
+		return json.Unmarshal([]byte(src), bs)
+	}
+	return nil
+}
+
+type VulnSeverity string
+
+const (
+	VulnSeverityLow      VulnSeverity = "low"
+	VulnSeverityMedium   VulnSeverity = "medium"
+	VulnSeverityHigh     VulnSeverity = "high"
+	VulnSeverityCritical VulnSeverity = "critical"
+)
+
+var ErrInvalidVulnRule = errors.New("invalid vulnerability rule")
+
+// VulnRule flags the banners of a product with a version from (inclusive) below (exclusive)
+// the versions given.  Banner is a case insensitive regular expression whose first group
+// captures the dotted version, a blank from matches every version below.
+type VulnRule struct {
+	Banner   string       `yaml:"banner"`
+	From     string       `yaml:"from"`
+	Below    string       `yaml:"below"`
+	CVEs     []string     `yaml:"cves"`
+	Severity VulnSeverity `yaml:"severity"`
+	Summary  string       `yaml:"summary"`
+}
+
+// VulnHint is a likely vulnerable service, told only by the version in its banner.  Vendors
+// backport fixes without changing the version, a hint is informational and not a finding
+// of a vulnerability scan.
+type VulnHint struct {
+	Port     int
+	Banner   string
+	Version  string
+	CVEs     []string
+	Severity VulnSeverity
+	Summary  string
+}
+
+// VulnMatcher matches the banners of a device against the rules
+type VulnMatcher struct {
+	rules []compiledVulnRule
+}
+
+type compiledVulnRule struct {
+	VulnRule
+	banner *regexp.Regexp
+	from   []int
+	below  []int
+}
+
+// NewVulnMatcher returns a matcher of the custom rules and the built in rules
+func NewVulnMatcher(custom []VulnRule) (*VulnMatcher, error) {
+	vm := &VulnMatcher{}
+	for _, r := range slices.Concat(custom, defaultVulnRules) {
+		cr, err := r.compile()
+		if err != nil {
+			return nil, err
+		}
+		vm.rules = append(vm.rules, cr)
+	}
+	return vm, nil
+}
+
+func (r VulnRule) compile() (compiledVulnRule, error) {
+	cr := compiledVulnRule{VulnRule: r}
+	re, err := regexp.Compile("(?i)" + r.Banner)
+	if err != nil {
+		return cr, fmt.Errorf("%w: %s: %w", ErrInvalidVulnRule, r.Banner, err)
+	}
+	if r.Banner == "" || re.NumSubexp() < 1 {
+		return cr, fmt.Errorf("%w: %q does not capture a version", ErrInvalidVulnRule, r.Banner)
+	}
+	cr.banner = re
+	cr.below = parseVersion(r.Below)
+	if len(cr.below) == 0 {
+		return cr, fmt.Errorf("%w: %s without a below version", ErrInvalidVulnRule, r.Banner)
+	}
+	cr.from = parseVersion(r.From)
+	return cr, nil
+}
+
+// Match returns the hints of the banners of the device, a banner matching several rules has
+// a hint for each
+func (vm *VulnMatcher) Match(d Device) []VulnHint {
+	hints := make([]VulnHint, 0)
+	for _, b := range d.Server.Banners {
+		for _, r := range vm.rules {
+			m := r.banner.FindStringSubmatch(b.Text)
+			if m == nil {
+				continue
+			}
+			v := parseVersion(m[1])
+			if len(v) == 0 || compareVersion(v, r.below) >= 0 ||
+				(len(r.from) > 0 && compareVersion(v, r.from) < 0) {
+				continue
+			}
+			hints = append(hints, VulnHint{
+				Port:     b.Port,
+				Banner:   b.Text,
+				Version:  m[1],
+				CVEs:     r.CVEs,
+				Severity: r.Severity,
+				Summary:  r.Summary,
+			})
+		}
+	}
+	return hints
+}
+
+// parseVersion reads the leading numbers of a dotted version, 7.4p1 is 7.4
+func parseVersion(s string) []int {
+	v := make([]int, 0, 4)
+	for _, part := range strings.Split(s, ".") {
+		end := strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' })
+		if end == 0 {
+			break
+		}
+		if end > 0 {
+			part = part[:end]
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		v = append(v, n)
+		if end > 0 {
+			break
+		}
+	}
+	return v
+}
+
+// compareVersion compares the versions part by part, missing parts are zero
+func compareVersion(a, b []int) int {
+	for i := range max(len(a), len(b)) {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
+// defaultVulnRules are a summary of well known vulnerabilities of services announcing their
+// version, matched after the custom rules
+var defaultVulnRules = []VulnRule{
+	{
+		Banner:   `^SSH-[\d.]+-OpenSSH_([\d.]+)`,
+		From:     "8.5",
+		Below:    "9.8",
+		CVEs:     []string{"CVE-2024-6387"},
+		Severity: VulnSeverityHigh,
+		Summary:  "regreSSHion, unauthenticated remote code execution through a signal handler race",
+	},
+	{
+		Banner:   `^SSH-[\d.]+-OpenSSH_([\d.]+)`,
+		Below:    "9.6",
+		CVEs:     []string{"CVE-2023-48795"},
+		Severity: VulnSeverityMedium,
+		Summary:  "Terrapin, prefix truncation weakening the integrity of the ssh channel",
+	},
+	{
+		Banner:   `^SSH-[\d.]+-OpenSSH_([\d.]+)`,
+		Below:    "7.8",
+		CVEs:     []string{"CVE-2018-15473"},
+		Severity: VulnSeverityMedium,
+		Summary:  "username enumeration",
+	},
+	{
+		Banner:   `vsFTPd ([\d.]+)`,
+		From:     "2.3.4",
+		Below:    "2.3.5",
+		CVEs:     []string{"CVE-2011-2523"},
+		Severity: VulnSeverityCritical,
+		Summary:  "backdoored release opening a root shell",
+	},
+	{
+		Banner:   `ProFTPD ([\d.]+)`,
+		From:     "1.3.5",
+		Below:    "1.3.6",
+		CVEs:     []string{"CVE-2015-3306"},
+		Severity: VulnSeverityCritical,
+		Summary:  "mod_copy lets unauthenticated clients copy files, fixed in 1.3.5a",
+	},
+	{
+		Banner:   `Exim ([\d.]+)`,
+		From:     "4.87",
+		Below:    "4.92",
+		CVEs:     []string{"CVE-2019-10149"},
+		Severity: VulnSeverityCritical,
+		Summary:  "remote command execution through the recipient address",
+	},
+	{
+		Banner:   `Apache/([\d.]+)`,
+		From:     "2.4.49",
+		Below:    "2.4.51",
+		CVEs:     []string{"CVE-2021-41773", "CVE-2021-42013"},
+		Severity: VulnSeverityCritical,
+		Summary:  "path traversal and remote code execution with cgi enabled",
+	},
+	{
+		Banner:   `nginx/([\d.]+)`,
+		From:     "0.6.18",
+		Below:    "1.20.1",
+		CVEs:     []string{"CVE-2021-23017"},
+		Severity: VulnSeverityHigh,
+		Summary:  "resolver off by one, when the resolver directive is used",
+	},
+	{
+		Banner:   `Microsoft-IIS/([\d.]+)`,
+		From:     "6.0",
+		Below:    "6.1",
+		CVEs:     []string{"CVE-2017-7269"},
+		Severity: VulnSeverityCritical,
+		Summary:  "webdav buffer overflow, the release is past its end of life",
+	},
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestVulnMatcher_Match(t *testing.T) {
+	custom := []VulnRule{
+		{Banner: `acmehttpd/([\d.]+)`, Below: "2", CVEs: []string{"CVE-0000-0001"}},
+	}
+	vm, err := NewVulnMatcher(custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cves := func(d Device) [][]string {
+		x := make([][]string, 0)
+		for _, h := range vm.Match(d) {
+			x = append(x, h.CVEs)
+		}
+		return x
+	}
+	banner := func(port int, text string) Device {
+		return Device{Server: Server{Banners: Banners{{Port: port, Text: text}}}}
+	}
+	tests := map[string]struct {
+		d    Device
+		want [][]string
+	}{
+		"NoBanners": {
+			want: [][]string{},
+		},
+		"RegreSSHion": {
+			d: banner(22, "SSH-2.0-OpenSSH_9.2p1 Debian-2+deb12u1"),
+			want: [][]string{
+				{"CVE-2024-6387"},
+				{"CVE-2023-48795"},
+			},
+		},
+		"OldOpenSSH": {
+			d: banner(22, "SSH-2.0-OpenSSH_7.4"),
+			want: [][]string{
+				{"CVE-2023-48795"},
+				{"CVE-2018-15473"},
+			},
+		},
+		"CurrentOpenSSH": {
+			d:    banner(22, "SSH-2.0-OpenSSH_9.8p1"),
+			want: [][]string{},
+		},
+		"ApacheInRange": {
+			d:    banner(80, "Apache/2.4.49 (Unix)"),
+			want: [][]string{{"CVE-2021-41773", "CVE-2021-42013"}},
+		},
+		"ApacheBeforeRange": {
+			d:    banner(80, "Apache/2.4.48 (Unix)"),
+			want: [][]string{},
+		},
+		"NoVersion": {
+			d:    banner(80, "nginx"),
+			want: [][]string{},
+		},
+		"Custom": {
+			d:    banner(8080, "AcmeHTTPd/1.9"),
+			want: [][]string{{"CVE-0000-0001"}},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := cves(tc.d)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewVulnMatcher_InvalidRules(t *testing.T) {
+	tests := map[string]VulnRule{
+		"NoBanner":  {Below: "2"},
+		"NoCapture": {Banner: `acme/[\d.]+`, Below: "2"},
+		"BadRegexp": {Banner: `(acme`, Below: "2"},
+		"NoBelow":   {Banner: `acme/([\d.]+)`},
+	}
+	for name, rule := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewVulnMatcher([]VulnRule{rule})
+			if !errors.Is(err, ErrInvalidVulnRule) {
+				t.Errorf("want: %v, got: %v", ErrInvalidVulnRule, err)
+			}
+		})
+	}
+}
+
+func TestBanners_ValueScan(t *testing.T) {
+	want := Banners{{Port: 22, Text: "SSH-2.0-OpenSSH_9.6"}, {Port: 80, Text: "nginx/1.24.0"}}
+	v, err := want.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Banners
+	if err = got.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
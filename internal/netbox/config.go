@@ -0,0 +1,156 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package netbox
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/networkables/mason/internal/flagset"
+)
+
+const (
+	PushNone    = "none"
+	PushJournal = "journal"
+	PushStaged  = "staged"
+)
+
+type (
+	// Config points to the NetBox instance.  NetBox is the source of truth, its prefixes and ip
+	// addresses replace what mason knows of them, while the devices only mason has found are
+	// pushed back for review, either as journal entries of their prefix or as ip addresses
+	// staged in a branch.
+	Config struct {
+		Enabled  bool
+		URL      string
+		Token    string
+		Insecure bool
+		Interval time.Duration
+		Timeout  time.Duration
+		Import   bool
+		Push     string
+		Branch   string
+		Status   string
+		Fields   *Fields
+	}
+
+	// Fields maps the NetBox fields onto the device fields of mason
+	Fields struct {
+		// Name lists the ip address fields the device name is taken from, the first one set
+		// wins [dns_name,device,description]
+		Name []string
+		// Notes is the ip address field copied into the notes of the device [description,comments]
+		Notes string
+		// Custom maps device fields [mac,manufacturer,type] onto NetBox custom fields, read on
+		// import and written on staged pushes
+		Custom map[string]string
+	}
+)
+
+func SetFlags(fs *pflag.FlagSet, cfg *Config) {
+	cfg.Fields = &Fields{}
+
+	configMajorKey := "netbox"
+
+	flagset.Bool(
+		fs,
+		&cfg.Enabled,
+		configMajorKey,
+		"enabled",
+		false,
+		"regularly sync the prefixes and devices with netbox",
+	)
+	flagset.String(
+		fs,
+		&cfg.URL,
+		configMajorKey,
+		"url",
+		"http://netbox:8000",
+		"url of netbox",
+	)
+	flagset.String(
+		fs,
+		&cfg.Token,
+		configMajorKey,
+		"token",
+		"",
+		"api token of netbox, write access is needed to push devices",
+	)
+	flagset.Bool(
+		fs,
+		&cfg.Insecure,
+		configMajorKey,
+		"insecure",
+		false,
+		"accept a self signed certificate of netbox",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Interval,
+		configMajorKey,
+		"interval",
+		time.Hour,
+		"time between scheduled netbox syncs",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Timeout,
+		configMajorKey,
+		"timeout",
+		30*time.Second,
+		"timeout of a netbox api request",
+	)
+	flagset.Bool(
+		fs,
+		&cfg.Import,
+		configMajorKey,
+		"import",
+		true,
+		"import the prefixes as networks and the ip addresses as devices",
+	)
+	flagset.String(
+		fs,
+		&cfg.Push,
+		configMajorKey,
+		"push",
+		PushJournal,
+		"how devices missing from netbox are pushed back [none,journal,staged]",
+	)
+	flagset.String(
+		fs,
+		&cfg.Branch,
+		configMajorKey,
+		"branch",
+		"",
+		"schema id of the netbox-branching branch staged ip addresses are created in",
+	)
+	flagset.String(
+		fs,
+		&cfg.Status,
+		configMajorKey,
+		"status",
+		"reserved",
+		"status of staged ip addresses",
+	)
+
+	fieldsConfigMajorKey := flagset.Key(configMajorKey, "fields")
+	flagset.StringSlice(
+		fs,
+		&cfg.Fields.Name,
+		fieldsConfigMajorKey,
+		"name",
+		[]string{FieldDNSName, FieldDevice},
+		"ip address fields the device name is taken from, the first one set wins [dns_name,device,description]",
+	)
+	flagset.String(
+		fs,
+		&cfg.Fields.Notes,
+		fieldsConfigMajorKey,
+		"notes",
+		"",
+		"ip address field copied into the device notes [description,comments]",
+	)
+}
@@ -0,0 +1,485 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package netbox syncs with a NetBox instance.  The prefixes and ip addresses documented in
+// NetBox are imported as networks and devices, the devices only mason has found are pushed
+// back as journal entries or staged ip addresses for an operator to review.
+package netbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	FieldDNSName     = "dns_name"
+	FieldDevice      = "device"
+	FieldDescription = "description"
+	FieldComments    = "comments"
+
+	// DiscoverySource marks the devices imported from netbox
+	DiscoverySource model.DiscoverySource = "NETBOX"
+
+	pageSize = 1000
+)
+
+var (
+	ErrTokenRequired   = errors.New("netbox needs an api token")
+	ErrUnknownPushMode = errors.New("unknown netbox push mode")
+
+	// NetboxTag marks every network and device imported from netbox
+	NetboxTag = model.Tag{Val: "netbox"}
+)
+
+type (
+	// Ref is a nested object of the api
+	Ref struct {
+		ID   int    `json:"id,omitempty"`
+		Name string `json:"name,omitempty"`
+		Slug string `json:"slug,omitempty"`
+	}
+
+	Prefix struct {
+		ID          int    `json:"id"`
+		Prefix      string `json:"prefix"`
+		Description string `json:"description"`
+		Site        *Ref   `json:"site"`
+		VLAN        *struct {
+			VID  int    `json:"vid"`
+			Name string `json:"name"`
+		} `json:"vlan"`
+		Tags []Ref `json:"tags"`
+	}
+
+	IPAddress struct {
+		ID             int    `json:"id"`
+		Address        string `json:"address"`
+		DNSName        string `json:"dns_name"`
+		Description    string `json:"description"`
+		Comments       string `json:"comments"`
+		Tenant         *Ref   `json:"tenant"`
+		Tags           []Ref  `json:"tags"`
+		AssignedObject *struct {
+			Name           string `json:"name"`
+			Device         *Ref   `json:"device"`
+			VirtualMachine *Ref   `json:"virtual_machine"`
+		} `json:"assigned_object"`
+		CustomFields map[string]any `json:"custom_fields"`
+	}
+
+	// NewIPAddress is an ip address to create
+	NewIPAddress struct {
+		Address      string         `json:"address"`
+		Status       string         `json:"status"`
+		DNSName      string         `json:"dns_name,omitempty"`
+		Description  string         `json:"description"`
+		CustomFields map[string]any `json:"custom_fields,omitempty"`
+	}
+
+	JournalEntry struct {
+		AssignedObjectType string `json:"assigned_object_type"`
+		AssignedObjectID   int    `json:"assigned_object_id"`
+		Kind               string `json:"kind"`
+		Comments           string `json:"comments"`
+	}
+
+	page[T any] struct {
+		Next    string `json:"next"`
+		Results []T    `json:"results"`
+	}
+)
+
+// Client calls the rest api of netbox
+type Client struct {
+	base   string
+	token  string
+	branch string
+	client *http.Client
+}
+
+func New(cfg *Config) (*Client, error) {
+	if cfg.Token == "" {
+		return nil, ErrTokenRequired
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, tre.New(err, "netbox url", "url", cfg.URL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("netbox url %q has no host", cfg.URL)
+	}
+	return &Client{
+		base:   strings.TrimSuffix(cfg.URL, "/"),
+		token:  cfg.Token,
+		branch: cfg.Branch,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			Transport: &http.Transport{
+				// #nosec G402 -- opt in for instances with a self signed certificate
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: cfg.Insecure},
+			},
+		},
+	}, nil
+}
+
+// URL returns the url of netbox
+func (c *Client) URL() string {
+	return c.base
+}
+
+// Prefixes lists the ipam prefixes
+func (c *Client) Prefixes(ctx context.Context) ([]Prefix, error) {
+	return list[Prefix](ctx, c, "/api/ipam/prefixes/", false)
+}
+
+// IPAddresses lists the ipam ip addresses
+func (c *Client) IPAddresses(ctx context.Context) ([]IPAddress, error) {
+	return list[IPAddress](ctx, c, "/api/ipam/ip-addresses/", false)
+}
+
+// BranchIPAddresses lists the ipam ip addresses as seen in the branch, with the ones staged
+// there.  Without a branch it is the same as IPAddresses.
+func (c *Client) BranchIPAddresses(ctx context.Context) ([]IPAddress, error) {
+	return list[IPAddress](ctx, c, "/api/ipam/ip-addresses/", true)
+}
+
+// JournalEntries lists the journal entries whose comments contain the query
+func (c *Client) JournalEntries(ctx context.Context, q string) ([]JournalEntry, error) {
+	return list[JournalEntry](ctx, c, "/api/extras/journal-entries/?q="+url.QueryEscape(q), false)
+}
+
+// AddJournalEntry writes a journal entry
+func (c *Client) AddJournalEntry(ctx context.Context, e JournalEntry) error {
+	return c.post(ctx, "/api/extras/journal-entries/", e, false)
+}
+
+// AddIPAddress creates the ip address, in the branch when one is configured
+func (c *Client) AddIPAddress(ctx context.Context, ip NewIPAddress) error {
+	return c.post(ctx, "/api/ipam/ip-addresses/", ip, true)
+}
+
+// list reads every page of the listing
+func list[T any](ctx context.Context, c *Client, path string, branched bool) ([]T, error) {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	next := c.base + path + sep + fmt.Sprintf("limit=%d", pageSize)
+	var items []T
+	for next != "" {
+		var p page[T]
+		err := c.do(ctx, http.MethodGet, next, nil, branched, &p)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, p.Results...)
+		next = p.Next
+	}
+	return items, nil
+}
+
+func (c *Client) post(ctx context.Context, path string, body any, branched bool) error {
+	return c.do(ctx, http.MethodPost, c.base+path, body, branched, nil)
+}
+
+func (c *Client) do(
+	ctx context.Context,
+	method string,
+	u string,
+	body any,
+	branched bool,
+	data any,
+) error {
+	var reqBody io.Reader
+	if body != nil {
+		x, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(x)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Token "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if branched && c.branch != "" {
+		req.Header.Set("X-NetBox-Branch", c.branch)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("netbox api %s %s: %s", method, req.URL.Path, resp.Status)
+	}
+	if data == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(data)
+}
+
+// Networks turns the IPv4 prefixes into networks, named after their description
+func Networks(prefixes []Prefix) []model.Network {
+	networks := make([]model.Network, 0, len(prefixes))
+	for _, p := range prefixes {
+		pfx, err := netip.ParsePrefix(p.Prefix)
+		if err != nil || !pfx.Addr().Is4() {
+			continue
+		}
+		name := p.Description
+		if name == "" {
+			name = "netbox " + pfx.String()
+		}
+		n, err := model.New(name, pfx.String())
+		if err != nil {
+			continue
+		}
+		n.Tags = tags(p.Tags)
+		if p.Site != nil {
+			n.Site = p.Site.Name
+		}
+		if p.VLAN != nil {
+			n.VLAN = model.VLAN{ID: p.VLAN.VID, Name: p.VLAN.Name}
+		}
+		networks = append(networks, n)
+	}
+	return networks
+}
+
+// Devices turns the IPv4 addresses into devices.  The name and notes are taken from the
+// mapped fields, the owner from the tenant and the site from the narrowest prefix holding the
+// address.
+func Devices(prefixes []Prefix, ips []IPAddress, fields *Fields) []model.Device {
+	devices := make([]model.Device, 0, len(ips))
+	for _, ip := range ips {
+		addr, ok := ipAddr(ip.Address)
+		if !ok {
+			continue
+		}
+		d := model.Device{
+			Addr:         model.AddrToModelAddr(addr),
+			DiscoveredBy: DiscoverySource,
+		}
+		d.Name = firstField(ip, fields.Name)
+		if d.Name == "" {
+			d.Name = addr.String()
+		}
+		d.Meta.DnsName = ip.DNSName
+		d.Meta.Notes = field(ip, fields.Notes)
+		d.Meta.Tags = tags(ip.Tags)
+		if ip.Tenant != nil {
+			d.Meta.Owner = ip.Tenant.Name
+		}
+		if p, ok := containing(prefixes, addr); ok && p.Site != nil {
+			d.Meta.Site = p.Site.Name
+		}
+		if mac, ok := customString(ip, fields.Custom["mac"]); ok {
+			if m, err := model.ParseMAC(mac); err == nil {
+				d.MAC = m
+			}
+		}
+		if manu, ok := customString(ip, fields.Custom["manufacturer"]); ok {
+			d.Meta.Manufacturer = manu
+		}
+		if dt, ok := customString(ip, fields.Custom["type"]); ok {
+			d.Meta.DeviceType = model.DeviceType(strings.ToLower(dt))
+		}
+		devices = append(devices, d)
+	}
+	return devices
+}
+
+// Missing returns the devices whose address is not in netbox
+func Missing(devices []model.Device, ips []IPAddress) []model.Device {
+	known := make(map[netip.Addr]bool, len(ips))
+	for _, ip := range ips {
+		if addr, ok := ipAddr(ip.Address); ok {
+			known[addr] = true
+		}
+	}
+	var missing []model.Device
+	for _, d := range devices {
+		if d.Addr.Addr().Is4() && !known[d.Addr.Addr()] {
+			missing = append(missing, d)
+		}
+	}
+	return missing
+}
+
+const (
+	// JournalQuery finds the journal entries written by Journal
+	JournalQuery  = "mason discovered"
+	journalPrefix = JournalQuery + " "
+)
+
+// Journal returns the journal entry for the device on the prefix holding it, false when no
+// prefix holds the device
+func Journal(d model.Device, prefixes []Prefix) (JournalEntry, bool) {
+	p, ok := containing(prefixes, d.Addr.Addr())
+	if !ok {
+		return JournalEntry{}, false
+	}
+	comments := journalPrefix + d.Addr.String()
+	details := []string{}
+	if !d.MAC.IsEmpty() {
+		details = append(details, "mac "+d.MAC.String())
+	}
+	if !d.IsNameAddr() {
+		details = append(details, "name "+d.Name)
+	}
+	if d.Meta.Manufacturer != "" {
+		details = append(details, "manufacturer "+d.Meta.Manufacturer)
+	}
+	if d.Meta.DeviceType != model.DeviceTypeUnknown {
+		details = append(details, "type "+string(d.Meta.DeviceType))
+	}
+	if len(details) > 0 {
+		comments += ", " + strings.Join(details, ", ")
+	}
+	return JournalEntry{
+		AssignedObjectType: "ipam.prefix",
+		AssignedObjectID:   p.ID,
+		Kind:               "info",
+		Comments:           comments,
+	}, true
+}
+
+// Journaled returns the addresses of the journal entries written by Journal
+func Journaled(entries []JournalEntry) map[netip.Addr]bool {
+	addrs := make(map[netip.Addr]bool, len(entries))
+	for _, e := range entries {
+		rest, ok := strings.CutPrefix(e.Comments, journalPrefix)
+		if !ok {
+			continue
+		}
+		s, _, _ := strings.Cut(rest, ",")
+		if addr, err := netip.ParseAddr(s); err == nil {
+			addrs[addr] = true
+		}
+	}
+	return addrs
+}
+
+// Staged returns the ip address to create for the device, with the length of the prefix
+// holding it
+func Staged(d model.Device, prefixes []Prefix, status string, fields *Fields) NewIPAddress {
+	addr := d.Addr.Addr()
+	bits := 32
+	if p, ok := containing(prefixes, addr); ok {
+		bits = netip.MustParsePrefix(p.Prefix).Bits()
+	}
+	ip := NewIPAddress{
+		Address:     netip.PrefixFrom(addr, bits).String(),
+		Status:      status,
+		DNSName:     d.Meta.DnsName,
+		Description: "discovered by mason",
+	}
+	if !d.IsNameAddr() {
+		ip.Description += ": " + d.Name
+	}
+	custom := map[string]string{
+		"mac":          "",
+		"manufacturer": d.Meta.Manufacturer,
+		"type":         string(d.Meta.DeviceType),
+	}
+	if !d.MAC.IsEmpty() {
+		custom["mac"] = d.MAC.String()
+	}
+	for k, v := range custom {
+		if cf := fields.Custom[k]; cf != "" && v != "" {
+			if ip.CustomFields == nil {
+				ip.CustomFields = make(map[string]any)
+			}
+			ip.CustomFields[cf] = v
+		}
+	}
+	return ip
+}
+
+// containing returns the narrowest prefix holding the addr
+func containing(prefixes []Prefix, addr netip.Addr) (Prefix, bool) {
+	best, bits := Prefix{}, -1
+	for _, p := range prefixes {
+		pfx, err := netip.ParsePrefix(p.Prefix)
+		if err != nil || !pfx.Contains(addr) || pfx.Bits() <= bits {
+			continue
+		}
+		best, bits = p, pfx.Bits()
+	}
+	return best, bits >= 0
+}
+
+func ipAddr(address string) (netip.Addr, bool) {
+	pfx, err := netip.ParsePrefix(address)
+	if err != nil || !pfx.Addr().Is4() {
+		return netip.Addr{}, false
+	}
+	return pfx.Addr(), true
+}
+
+func firstField(ip IPAddress, names []string) string {
+	for _, name := range names {
+		if v := field(ip, name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func field(ip IPAddress, name string) string {
+	switch name {
+	case FieldDNSName:
+		return ip.DNSName
+	case FieldDescription:
+		return ip.Description
+	case FieldComments:
+		return ip.Comments
+	case FieldDevice:
+		if ip.AssignedObject == nil {
+			return ""
+		}
+		if ip.AssignedObject.Device != nil {
+			return ip.AssignedObject.Device.Name
+		}
+		if ip.AssignedObject.VirtualMachine != nil {
+			return ip.AssignedObject.VirtualMachine.Name
+		}
+	}
+	return ""
+}
+
+func customString(ip IPAddress, name string) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+	s, ok := ip.CustomFields[name].(string)
+	return s, ok && s != ""
+}
+
+func tags(refs []Ref) model.Tags {
+	t := model.Tags{NetboxTag}
+	for _, r := range refs {
+		t = model.Add(model.Tag{Val: r.Slug}, t)
+	}
+	return t
+}
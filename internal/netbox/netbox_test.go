@@ -0,0 +1,243 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package netbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+var (
+	testPrefixes = []Prefix{
+		{ID: 1, Prefix: "192.168.0.0/16", Description: "campus"},
+		{ID: 2, Prefix: "192.168.1.0/24", Site: &Ref{Name: "hq"}, Tags: []Ref{{Slug: "lan"}}},
+		{ID: 3, Prefix: "2001:db8::/64"},
+	}
+	testIPs = []IPAddress{
+		{Address: "192.168.1.10/24", DNSName: "nas.lan", Description: "storage"},
+		{
+			Address: "192.168.1.11/24",
+			Tenant:  &Ref{Name: "ops"},
+			AssignedObject: &struct {
+				Name           string `json:"name"`
+				Device         *Ref   `json:"device"`
+				VirtualMachine *Ref   `json:"virtual_machine"`
+			}{Name: "eth0", Device: &Ref{Name: "router"}},
+			CustomFields: map[string]any{"mac_address": "00:11:22:33:44:55", "vendor": "Acme"},
+		},
+		{Address: "192.168.2.5/16"},
+		{Address: "2001:db8::1/64"},
+	}
+)
+
+func TestClient(t *testing.T) {
+	var staged []string
+	mux := http.NewServeMux()
+	authed := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Token secret" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			h(w, r)
+		}
+	}
+	var srv *httptest.Server
+	mux.HandleFunc("GET /api/ipam/prefixes/", authed(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("offset") == "" {
+			json.NewEncoder(w).Encode(page[Prefix]{
+				Next:    srv.URL + "/api/ipam/prefixes/?limit=1000&offset=1000",
+				Results: testPrefixes[:1],
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(page[Prefix]{Results: testPrefixes[1:]})
+	}))
+	mux.HandleFunc("POST /api/ipam/ip-addresses/", authed(func(w http.ResponseWriter, r *http.Request) {
+		staged = append(staged, r.Header.Get("X-NetBox-Branch"))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	mux.HandleFunc("POST /api/extras/journal-entries/", authed(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-NetBox-Branch") != "" {
+			t.Error("journal entries are not branched")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	srv = httptest.NewServer(mux)
+	defer srv.Close()
+
+	c, err := New(&Config{URL: srv.URL, Token: "secret", Branch: "td5smq0f", Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefixes, err := c.Prefixes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(testPrefixes, prefixes); diff != "" {
+		t.Errorf("prefixes (-want +got):\n%s", diff)
+	}
+	err = c.AddIPAddress(context.Background(), NewIPAddress{Address: "192.168.1.20/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.AddJournalEntry(context.Background(), JournalEntry{Comments: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"td5smq0f"}, staged); diff != "" {
+		t.Errorf("branch (-want +got):\n%s", diff)
+	}
+
+	bad, _ := New(&Config{URL: srv.URL, Token: "wrong", Timeout: time.Second})
+	_, err = bad.IPAddresses(context.Background())
+	if err == nil {
+		t.Error("want an error for a wrong token")
+	}
+}
+
+func TestNew_NoToken(t *testing.T) {
+	_, err := New(&Config{URL: "http://netbox:8000"})
+	if !errors.Is(err, ErrTokenRequired) {
+		t.Fatalf("want %v, got %v", ErrTokenRequired, err)
+	}
+}
+
+func TestNetworks(t *testing.T) {
+	got := make(map[string]model.Network)
+	for _, n := range Networks(testPrefixes) {
+		got[n.Name] = n
+	}
+	if len(got) != 2 {
+		t.Fatalf("want the 2 ipv4 prefixes, got %v", got)
+	}
+	n := got["netbox 192.168.1.0/24"]
+	if n.Site != "hq" || !n.Tags.Has("lan") || !n.Tags.Has(NetboxTag.Val) {
+		t.Errorf("want site and tags of the prefix, got %+v", n)
+	}
+}
+
+func TestDevices(t *testing.T) {
+	tests := map[string]struct {
+		fields *Fields
+		want   map[string]model.Device
+	}{
+		"Default": {
+			fields: &Fields{Name: []string{FieldDNSName, FieldDevice}},
+			want: map[string]model.Device{
+				"192.168.1.10": {Name: "nas.lan", Meta: model.Meta{DnsName: "nas.lan", Site: "hq"}},
+				"192.168.1.11": {Name: "router", Meta: model.Meta{Owner: "ops", Site: "hq"}},
+				"192.168.2.5":  {Name: "192.168.2.5"},
+			},
+		},
+		"Mapped": {
+			fields: &Fields{
+				Name:   []string{FieldDescription},
+				Notes:  FieldDescription,
+				Custom: map[string]string{"mac": "mac_address", "manufacturer": "vendor"},
+			},
+			want: map[string]model.Device{
+				"192.168.1.10": {
+					Name: "storage",
+					Meta: model.Meta{DnsName: "nas.lan", Site: "hq", Notes: "storage"},
+				},
+				"192.168.1.11": {
+					Name: "192.168.1.11",
+					MAC:  model.MustParseMAC("00:11:22:33:44:55"),
+					Meta: model.Meta{Owner: "ops", Site: "hq", Manufacturer: "Acme"},
+				},
+				"192.168.2.5": {Name: "192.168.2.5"},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := make(map[string]model.Device)
+			for _, d := range Devices(testPrefixes, testIPs, tc.fields) {
+				if d.DiscoveredBy != DiscoverySource || !d.Meta.Tags.Has(NetboxTag.Val) {
+					t.Errorf("%s: want netbox source and tag, got %s %v", d.Addr, d.DiscoveredBy, d.Meta.Tags)
+				}
+				addr := d.Addr.String()
+				d.Addr, d.DiscoveredBy, d.Meta.Tags = model.Addr{}, "", nil
+				got[addr] = d
+			}
+			opts := cmp.Options{
+				cmpopts.EquateComparable(netip.Addr{}),
+				cmpopts.IgnoreUnexported(model.Device{}),
+			}
+			if diff := cmp.Diff(tc.want, got, opts); diff != "" {
+				t.Errorf("devices (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPush(t *testing.T) {
+	devices := []model.Device{
+		{
+			Name: "printer",
+			Addr: model.AddrToModelAddr(netip.MustParseAddr("192.168.1.10")),
+		},
+		{
+			Name: "192.168.1.30",
+			Addr: model.AddrToModelAddr(netip.MustParseAddr("192.168.1.30")),
+			MAC:  model.MustParseMAC("00:11:22:33:44:66"),
+			Meta: model.Meta{Manufacturer: "Acme"},
+		},
+		{
+			Name: "10.0.0.1",
+			Addr: model.AddrToModelAddr(netip.MustParseAddr("10.0.0.1")),
+		},
+	}
+	missing := Missing(devices, testIPs)
+	if len(missing) != 2 {
+		t.Fatalf("want 2 devices missing from netbox, got %v", missing)
+	}
+
+	entry, ok := Journal(missing[0], testPrefixes)
+	want := JournalEntry{
+		AssignedObjectType: "ipam.prefix",
+		AssignedObjectID:   2,
+		Kind:               "info",
+		Comments:           "mason discovered 192.168.1.30, mac 00:11:22:33:44:66, manufacturer Acme",
+	}
+	if diff := cmp.Diff(want, entry); !ok || diff != "" {
+		t.Errorf("journal (-want +got):\n%s", diff)
+	}
+	if _, ok := Journal(missing[1], testPrefixes); ok {
+		t.Error("want no journal entry outside the prefixes")
+	}
+	journaled := Journaled([]JournalEntry{entry, {Comments: "checked the cabling"}})
+	if diff := cmp.Diff(map[netip.Addr]bool{netip.MustParseAddr("192.168.1.30"): true}, journaled); diff != "" {
+		t.Errorf("journaled (-want +got):\n%s", diff)
+	}
+
+	staged := Staged(missing[0], testPrefixes, "reserved", &Fields{
+		Custom: map[string]string{"mac": "mac_address"},
+	})
+	wantStaged := NewIPAddress{
+		Address:      "192.168.1.30/24",
+		Status:       "reserved",
+		Description:  "discovered by mason",
+		CustomFields: map[string]any{"mac_address": "00:11:22:33:44:66"},
+	}
+	if diff := cmp.Diff(wantStaged, staged); diff != "" {
+		t.Errorf("staged (-want +got):\n%s", diff)
+	}
+	if got := Staged(missing[1], testPrefixes, "reserved", &Fields{}).Address; got != "10.0.0.1/32" {
+		t.Errorf("want a host address outside the prefixes, got %s", got)
+	}
+}
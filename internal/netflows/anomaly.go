@@ -0,0 +1,202 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package netflows
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// Detector keeps an hourly baseline of the bytes and flows of each device along with the
+// destination countries and ports it talks to, and reports traffic which deviates from it.
+// Nothing is reported for a device until its baseline covers the minimum number of hours.
+type Detector struct {
+	cfg       *AnomalyConfig
+	country   func(asn string) string
+	lock      sync.Mutex
+	devices   map[model.Addr]*deviceBaseline
+	countries map[string]string
+}
+
+type deviceBaseline struct {
+	hour      time.Time
+	bytes     int
+	flows     int
+	spiked    bool
+	history   []hourTotal
+	countries map[string]bool
+	ports     map[string]bool
+}
+
+type hourTotal struct {
+	bytes int
+	flows int
+}
+
+// NewDetector creates a detector, country returns the country of an asn, blank if unknown
+func NewDetector(cfg *AnomalyConfig, country func(asn string) string) *Detector {
+	return &Detector{
+		cfg:       cfg,
+		country:   country,
+		devices:   make(map[model.Addr]*deviceBaseline),
+		countries: make(map[string]string),
+	}
+}
+
+// Observe adds the flows seen at now to the baselines of the devices and returns the
+// anomalies found.  isDevice selects the addresses which are tracked, flows between two
+// untracked addresses are ignored.
+func (d *Detector) Observe(
+	now time.Time,
+	flows []model.IpFlow,
+	isDevice func(model.Addr) bool,
+) []model.EventFlowAnomaly {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	hour := now.Truncate(time.Hour)
+	ret := make([]model.EventFlowAnomaly, 0)
+	touched := make(map[model.Addr]*deviceBaseline)
+	for _, flow := range flows {
+		if isDevice(flow.SrcAddr) {
+			b := d.baseline(flow.SrcAddr, hour)
+			b.add(flow)
+			touched[flow.SrcAddr] = b
+			ret = append(ret, d.checkOutbound(b, flow)...)
+		}
+		if isDevice(flow.DstAddr) {
+			b := d.baseline(flow.DstAddr, hour)
+			b.add(flow)
+			touched[flow.DstAddr] = b
+		}
+	}
+	for addr, b := range touched {
+		if e, ok := d.checkSpike(addr, b); ok {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+// baseline returns the baseline of the device rolled forward to hour
+func (d *Detector) baseline(addr model.Addr, hour time.Time) *deviceBaseline {
+	b, ok := d.devices[addr]
+	if !ok {
+		b = &deviceBaseline{
+			hour:      hour,
+			countries: make(map[string]bool),
+			ports:     make(map[string]bool),
+		}
+		d.devices[addr] = b
+	}
+	if hour.After(b.hour) {
+		b.roll(hour, d.cfg.BaselineHours)
+	}
+	return b
+}
+
+func (d *Detector) learned(b *deviceBaseline) bool {
+	return len(b.history) >= d.cfg.MinBaselineHours
+}
+
+// checkOutbound looks for a new destination country or port of a flow sent by the device.
+// The destination port is only the service port when it is below the source port, replies
+// to connections made to the device are skipped that way.
+func (d *Detector) checkOutbound(b *deviceBaseline, flow model.IpFlow) []model.EventFlowAnomaly {
+	ret := make([]model.EventFlowAnomaly, 0)
+	add := func(kind model.FlowAnomalyKind, detail string) {
+		if d.learned(b) {
+			ret = append(ret, model.EventFlowAnomaly{Addr: flow.SrcAddr, Kind: kind, Detail: detail})
+		}
+	}
+
+	if country := d.lookupCountry(flow.DstASN); country != "" && !b.countries[country] {
+		b.countries[country] = true
+		add(
+			model.FlowAnomalyNewCountry,
+			fmt.Sprintf("first traffic to %s (%s, AS%s)", country, flow.DstAddr, flow.DstASN),
+		)
+	}
+
+	if flow.DstPort == 0 || int(flow.DstPort) > d.cfg.MaxPort || flow.DstPort > flow.SrcPort {
+		return ret
+	}
+	port := fmt.Sprintf("%d/%s", flow.DstPort, flow.Protocol)
+	if !b.ports[port] {
+		b.ports[port] = true
+		add(
+			model.FlowAnomalyUnusualPort,
+			fmt.Sprintf("first traffic to port %s (%s)", port, flow.DstAddr),
+		)
+	}
+	return ret
+}
+
+// checkSpike reports the first hour where the bytes or flows of the device pass the spike
+// factor of its hourly average
+func (d *Detector) checkSpike(addr model.Addr, b *deviceBaseline) (model.EventFlowAnomaly, bool) {
+	if b.spiked || !d.learned(b) || b.bytes < d.cfg.MinBytes {
+		return model.EventFlowAnomaly{}, false
+	}
+	var bytes, flows int
+	for _, h := range b.history {
+		bytes += h.bytes
+		flows += h.flows
+	}
+	hours := len(b.history)
+	var detail string
+	switch {
+	case b.bytes*hours > bytes*d.cfg.SpikeFactor:
+		detail = fmt.Sprintf("%s this hour, average %s",
+			humanize.Bytes(uint64(b.bytes)), humanize.Bytes(uint64(bytes/hours)))
+	case b.flows*hours > flows*d.cfg.SpikeFactor:
+		detail = fmt.Sprintf("%d flows this hour, average %d", b.flows, flows/hours)
+	default:
+		return model.EventFlowAnomaly{}, false
+	}
+	b.spiked = true
+	return model.EventFlowAnomaly{Addr: addr, Kind: model.FlowAnomalySpike, Detail: detail}, true
+}
+
+// lookupCountry returns the country of the asn, known countries are kept to avoid repeated
+// lookups
+func (d *Detector) lookupCountry(asn string) string {
+	if asn == "" || d.country == nil {
+		return ""
+	}
+	if country, ok := d.countries[asn]; ok {
+		return country
+	}
+	country := d.country(asn)
+	if country != "" {
+		d.countries[asn] = country
+	}
+	return country
+}
+
+func (b *deviceBaseline) add(flow model.IpFlow) {
+	b.bytes += flow.Bytes
+	b.flows++
+}
+
+// roll closes the current hour into the history, hours without traffic are added as empty
+// hours, and keeps the last keep hours
+func (b *deviceBaseline) roll(hour time.Time, keep int) {
+	b.history = append(b.history, hourTotal{bytes: b.bytes, flows: b.flows})
+	idle := int(hour.Sub(b.hour)/time.Hour) - 1
+	for range min(idle, keep) {
+		b.history = append(b.history, hourTotal{})
+	}
+	if len(b.history) > keep {
+		b.history = b.history[len(b.history)-keep:]
+	}
+	b.hour = hour
+	b.bytes, b.flows, b.spiked = 0, 0, false
+}
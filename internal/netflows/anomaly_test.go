@@ -0,0 +1,120 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package netflows
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestDetector_Observe(t *testing.T) {
+	device := model.MustParseAddr("192.168.1.10")
+	remote := model.MustParseAddr("8.8.8.8")
+	isDevice := func(a model.Addr) bool { return a == device }
+	countries := map[string]string{"15169": "US", "13335": "AU"}
+	flow := func(asn string, port uint16, bytes int) model.IpFlow {
+		return model.IpFlow{
+			SrcAddr:  device,
+			SrcPort:  50000,
+			DstAddr:  remote,
+			DstPort:  port,
+			DstASN:   asn,
+			Bytes:    bytes,
+			Protocol: 6,
+		}
+	}
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		flows []model.IpFlow
+		want  []model.EventFlowAnomaly
+	}{
+		"Usual": {
+			flows: []model.IpFlow{flow("15169", 443, 1000)},
+			want:  []model.EventFlowAnomaly{},
+		},
+		"NewCountry": {
+			flows: []model.IpFlow{flow("13335", 443, 1000)},
+			want: []model.EventFlowAnomaly{{
+				Addr:   device,
+				Kind:   model.FlowAnomalyNewCountry,
+				Detail: "first traffic to AU (8.8.8.8, AS13335)",
+			}},
+		},
+		"UnusualPort": {
+			flows: []model.IpFlow{flow("15169", 6667, 1000)},
+			want: []model.EventFlowAnomaly{{
+				Addr:   device,
+				Kind:   model.FlowAnomalyUnusualPort,
+				Detail: "first traffic to port 6667/TCP (8.8.8.8)",
+			}},
+		},
+		"EphemeralPort": {
+			flows: []model.IpFlow{flow("15169", 60000, 1000)},
+			want:  []model.EventFlowAnomaly{},
+		},
+		"Spike": {
+			flows: []model.IpFlow{flow("15169", 443, 20000)},
+			want: []model.EventFlowAnomaly{{
+				Addr:   device,
+				Kind:   model.FlowAnomalySpike,
+				Detail: "20 kB this hour, average 1.0 kB",
+			}},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := NewDetector(
+				&AnomalyConfig{
+					SpikeFactor:      10,
+					BaselineHours:    24,
+					MinBaselineHours: 3,
+					MinBytes:         5000,
+					MaxPort:          49151,
+				},
+				func(asn string) string { return countries[asn] },
+			)
+			for hour := range 3 {
+				got := d.Observe(
+					start.Add(time.Duration(hour)*time.Hour),
+					[]model.IpFlow{flow("15169", 443, 1000)},
+					isDevice,
+				)
+				if len(got) != 0 {
+					t.Fatalf("learning hour %d raised anomalies: %v", hour, got)
+				}
+			}
+			got := d.Observe(start.Add(3*time.Hour), tc.flows, isDevice)
+			diff := cmp.Diff(tc.want, got, cmpopts.EquateComparable(netip.Addr{}))
+			if diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDeviceBaseline_roll(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	b := &deviceBaseline{hour: start, bytes: 10, flows: 1, spiked: true}
+	b.roll(start.Add(3*time.Hour), 24)
+	want := []hourTotal{{bytes: 10, flows: 1}, {}, {}}
+	if diff := cmp.Diff(want, b.history, cmp.AllowUnexported(hourTotal{})); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	if b.bytes != 0 || b.flows != 0 || b.spiked {
+		t.Errorf("current hour not reset: %+v", b)
+	}
+
+	b.roll(start.Add(100*time.Hour), 24)
+	if len(b.history) != 24 {
+		t.Errorf("history want: 24 hours, got: %d", len(b.history))
+	}
+}
@@ -5,19 +5,47 @@
 package netflows
 
 import (
+	"time"
+
 	"github.com/spf13/pflag"
 
 	"github.com/networkables/mason/internal/flagset"
 )
 
-type Config struct {
-	Enabled       bool
-	ListenAddress string
-	MaxWorkers    int
-	PacketSize    int
-}
+type (
+	Config struct {
+		Enabled       bool
+		ListenAddress string
+		MaxWorkers    int
+		PacketSize    int
+		Insert        *InsertConfig
+		Anomaly       *AnomalyConfig
+	}
+
+	// InsertConfig sets how received flows are batched into the flow store, flows beyond
+	// MaxQueued are dropped so a flood of flows can not block the server
+	InsertConfig struct {
+		FlushSize     int
+		FlushInterval time.Duration
+		MaxQueued     int
+		MaxRate       int
+	}
+
+	// AnomalyConfig sets the thresholds used to flag unusual traffic against the hourly
+	// baseline of each device
+	AnomalyConfig struct {
+		Enabled          bool
+		SpikeFactor      int
+		BaselineHours    int
+		MinBaselineHours int
+		MinBytes         int
+		MaxPort          int
+	}
+)
 
 func SetFlags(fs *pflag.FlagSet, cfg *Config) {
+	cfg.Insert = &InsertConfig{}
+	cfg.Anomaly = &AnomalyConfig{}
 	configMajorKey := "netflows"
 
 	flagset.Bool(
@@ -52,4 +80,92 @@ func SetFlags(fs *pflag.FlagSet, cfg *Config) {
 		16384,
 		"max size of packet buffer when listening (this is per packet)",
 	)
+
+	// Insert
+	insertMajorKey := flagset.Key(configMajorKey, "insert")
+
+	flagset.Int(
+		fs,
+		&cfg.Insert.FlushSize,
+		insertMajorKey,
+		"flushsize",
+		1000,
+		"flows written to the store in one transaction",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Insert.FlushInterval,
+		insertMajorKey,
+		"flushinterval",
+		time.Second,
+		"longest time received flows wait before being written",
+	)
+	flagset.Int(
+		fs,
+		&cfg.Insert.MaxQueued,
+		insertMajorKey,
+		"maxqueued",
+		100_000,
+		"flows waiting to be written before new flows are dropped",
+	)
+	flagset.Int(
+		fs,
+		&cfg.Insert.MaxRate,
+		insertMajorKey,
+		"maxrate",
+		0,
+		"most flows written per second, 0 for no limit",
+	)
+
+	// Anomaly
+	anomalyMajorKey := flagset.Key(configMajorKey, "anomaly")
+
+	flagset.Bool(
+		fs,
+		&cfg.Anomaly.Enabled,
+		anomalyMajorKey,
+		"enabled",
+		true,
+		"raise events for device traffic which deviates from its hourly baseline",
+	)
+	flagset.Int(
+		fs,
+		&cfg.Anomaly.SpikeFactor,
+		anomalyMajorKey,
+		"spikefactor",
+		10,
+		"multiple of the average hourly bytes or flows of a device reported as a spike",
+	)
+	flagset.Int(
+		fs,
+		&cfg.Anomaly.BaselineHours,
+		anomalyMajorKey,
+		"baselinehours",
+		168,
+		"number of hours of device traffic kept for the baseline",
+	)
+	flagset.Int(
+		fs,
+		&cfg.Anomaly.MinBaselineHours,
+		anomalyMajorKey,
+		"minbaselinehours",
+		24,
+		"hours of traffic needed for a device before anomalies are reported",
+	)
+	flagset.Int(
+		fs,
+		&cfg.Anomaly.MinBytes,
+		anomalyMajorKey,
+		"minbytes",
+		10*1024*1024,
+		"bytes a device must send or receive in an hour before a spike is reported",
+	)
+	flagset.Int(
+		fs,
+		&cfg.Anomaly.MaxPort,
+		anomalyMajorKey,
+		"maxport",
+		49151,
+		"highest destination port checked for unusual ports, higher ports are ephemeral",
+	)
 }
@@ -0,0 +1,182 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package netflows
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+type (
+	// Inserter queues flows from the worker and writes them to the store in batches on its own
+	// goroutine, Submit never blocks
+	Inserter struct {
+		cfg     *InsertConfig
+		prepare func([]model.IpFlow) []model.IpFlow
+		write   func(context.Context, []model.IpFlow) error
+		done    func(context.Context, []model.IpFlow)
+		in      chan []model.IpFlow
+		stopped chan struct{}
+
+		queued    atomic.Int64
+		written   atomic.Uint64
+		dropped   atomic.Uint64
+		filtered  atomic.Uint64
+		batches   atomic.Uint64
+		failed    atomic.Uint64
+		lastFlush atomic.Int64
+	}
+
+	// InsertStats shows whether the store keeps up with the received flows
+	InsertStats struct {
+		Queued    int64
+		Written   uint64
+		Dropped   uint64
+		Filtered  uint64
+		Batches   uint64
+		Failed    uint64
+		LastFlush time.Duration
+	}
+)
+
+// NewInserter builds the pipeline, prepare fills in the flows before a batch is written (e.g.
+// asn lookups) and returns the flows to keep, done runs after each batch is written
+func NewInserter(
+	cfg *InsertConfig,
+	prepare func([]model.IpFlow) []model.IpFlow,
+	write func(context.Context, []model.IpFlow) error,
+	done func(context.Context, []model.IpFlow),
+) *Inserter {
+	return &Inserter{
+		cfg:     cfg,
+		prepare: prepare,
+		write:   write,
+		done:    done,
+		in:      make(chan []model.IpFlow, 1024),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Submit queues the flows, they are dropped when the queue is full
+func (ins *Inserter) Submit(flows []model.IpFlow) bool {
+	count := int64(len(flows))
+	if ins.queued.Add(count) > int64(ins.cfg.MaxQueued) {
+		ins.queued.Add(-count)
+		ins.dropped.Add(uint64(count))
+		return false
+	}
+	select {
+	case ins.in <- flows:
+		return true
+	default:
+		ins.queued.Add(-count)
+		ins.dropped.Add(uint64(count))
+		return false
+	}
+}
+
+func (ins *Inserter) Stats() InsertStats {
+	return InsertStats{
+		Queued:    ins.queued.Load(),
+		Written:   ins.written.Load(),
+		Dropped:   ins.dropped.Load(),
+		Filtered:  ins.filtered.Load(),
+		Batches:   ins.batches.Load(),
+		Failed:    ins.failed.Load(),
+		LastFlush: time.Duration(ins.lastFlush.Load()),
+	}
+}
+
+// Run writes a batch once FlushSize flows are pending or the FlushInterval passed, the pending
+// flows are written when the context is done
+func (ins *Inserter) Run(ctx context.Context) {
+	defer close(ins.stopped)
+	ticker := time.NewTicker(ins.cfg.FlushInterval)
+	defer ticker.Stop()
+	size := max(1, ins.cfg.FlushSize)
+	pending := make([]model.IpFlow, 0, size)
+	for {
+		select {
+		case <-ctx.Done():
+			// the store outlives the run context, queued flows are still written
+			for {
+				select {
+				case flows := <-ins.in:
+					pending = append(pending, flows...)
+					continue
+				default:
+				}
+				break
+			}
+			pending = ins.flushFull(context.Background(), pending, size)
+			if len(pending) > 0 {
+				ins.flush(context.Background(), pending)
+			}
+			return
+		case flows := <-ins.in:
+			pending = ins.flushFull(ctx, append(pending, flows...), size)
+		case <-ticker.C:
+			if len(pending) > 0 {
+				ins.flush(ctx, pending)
+				pending = make([]model.IpFlow, 0, size)
+			}
+		}
+	}
+}
+
+// flushFull writes the pending flows in batches of size and returns the remainder
+func (ins *Inserter) flushFull(
+	ctx context.Context,
+	pending []model.IpFlow,
+	size int,
+) []model.IpFlow {
+	for len(pending) >= size {
+		ins.flush(ctx, pending[:size])
+		pending = pending[size:]
+	}
+	return append(make([]model.IpFlow, 0, size), pending...)
+}
+
+// Stopped is closed once Run has written the pending flows and returned
+func (ins *Inserter) Stopped() <-chan struct{} {
+	return ins.stopped
+}
+
+// flush writes one batch, with a MaxRate the next batch waits until the rate is kept
+func (ins *Inserter) flush(ctx context.Context, batch []model.IpFlow) {
+	start := time.Now()
+	ins.queued.Add(-int64(len(batch)))
+	kept := ins.prepare(batch)
+	ins.filtered.Add(uint64(len(batch) - len(kept)))
+	batch = kept
+	if len(batch) == 0 {
+		return
+	}
+	err := ins.write(ctx, batch)
+	elapsed := time.Since(start)
+	ins.batches.Add(1)
+	ins.lastFlush.Store(int64(elapsed))
+	if err != nil {
+		ins.failed.Add(uint64(len(batch)))
+	} else {
+		ins.written.Add(uint64(len(batch)))
+	}
+	ins.done(ctx, batch)
+
+	if ins.cfg.MaxRate <= 0 {
+		return
+	}
+	wait := time.Duration(len(batch))*time.Second/time.Duration(ins.cfg.MaxRate) - elapsed
+	if wait <= 0 {
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(wait):
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package netflows
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestInserter(t *testing.T) {
+	flows := func(n int) []model.IpFlow {
+		return make([]model.IpFlow, n)
+	}
+	tests := map[string]struct {
+		cfg      InsertConfig
+		submits  []int
+		writeErr error
+		keep     int
+		batches  []int
+		want     InsertStats
+	}{
+		"BatchedBySize": {
+			cfg:     InsertConfig{FlushSize: 4, FlushInterval: time.Hour, MaxQueued: 100},
+			submits: []int{3, 3, 3},
+			batches: []int{4, 4, 1},
+			want:    InsertStats{Written: 9, Batches: 3},
+		},
+		"DroppedPastMaxQueued": {
+			cfg:     InsertConfig{FlushSize: 100, FlushInterval: time.Hour, MaxQueued: 5},
+			submits: []int{3, 3, 2},
+			batches: []int{5},
+			want:    InsertStats{Written: 5, Dropped: 3, Batches: 1},
+		},
+		"FilteredByPrepare": {
+			cfg:     InsertConfig{FlushSize: 4, FlushInterval: time.Hour, MaxQueued: 100},
+			submits: []int{4, 2},
+			keep:    1,
+			batches: []int{1, 1},
+			want:    InsertStats{Written: 2, Filtered: 4, Batches: 2},
+		},
+		"WriteFailed": {
+			cfg:      InsertConfig{FlushSize: 2, FlushInterval: time.Hour, MaxQueued: 100},
+			submits:  []int{2},
+			writeErr: errors.New("database is locked"),
+			batches:  []int{2},
+			want:     InsertStats{Failed: 2, Batches: 1},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			batches := make([]int, 0)
+			prepared, done := 0, 0
+			ins := NewInserter(
+				&tc.cfg,
+				func(f []model.IpFlow) []model.IpFlow {
+					prepared += len(f)
+					if tc.keep > 0 {
+						return f[:min(tc.keep, len(f))]
+					}
+					return f
+				},
+				func(_ context.Context, f []model.IpFlow) error {
+					batches = append(batches, len(f))
+					return tc.writeErr
+				},
+				func(_ context.Context, f []model.IpFlow) { done += len(f) },
+			)
+			for _, n := range tc.submits {
+				ins.Submit(flows(n))
+			}
+			// the pending flows are written when the context is done
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			ins.Run(ctx)
+			<-ins.Stopped()
+
+			if diff := cmp.Diff(tc.batches, batches); diff != "" {
+				t.Errorf("batches (-want +got):\n%s", diff)
+			}
+			got := ins.Stats()
+			if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreFields(InsertStats{}, "LastFlush")); diff != "" {
+				t.Errorf("stats (-want +got):\n%s", diff)
+			}
+			want := int(tc.want.Written + tc.want.Failed)
+			if prepared != want+int(tc.want.Filtered) || done != want {
+				t.Errorf("want %d prepared and %d done, got %d, %d",
+					want+int(tc.want.Filtered), want, prepared, done)
+			}
+		})
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"sync"
+	"net/netip"
 	"time"
 )
 
@@ -25,8 +25,9 @@ type IpfixHeader struct {
 	ObservationDomainID int
 }
 
-func handlePacket(dat []byte) (flows []RawFlow, err error) {
+func handlePacket(ts *Templates, pkt Packet) (flows []RawFlow, err error) {
 	idx := 0
+	dat := pkt.Data
 
 	hdrsize := 16
 	if len(dat) < hdrsize {
@@ -38,8 +39,11 @@ func handlePacket(dat []byte) (flows []RawFlow, err error) {
 		return flows, err
 	}
 	idx += hdrsize
+	if hdr.DataSize < idx || hdr.DataSize > len(dat) {
+		return flows, errors.New("ipfix message length does not match the data")
+	}
 
-	flows, err = parseSets(hdr.ObservationDomainID, dat[idx:hdr.DataSize])
+	flows, err = parseSets(ts, pkt.Exporter, hdr.ObservationDomainID, dat[idx:hdr.DataSize])
 	if err != nil {
 		return flows, err
 	}
@@ -71,7 +75,12 @@ func parseHeader(dat []byte) (hdr IpfixHeader, err error) {
 
 var ErrSetHeaderTooSmall = errors.New("set header too small")
 
-func parseSets(obsid int, dat []byte) (flows []RawFlow, err error) {
+func parseSets(
+	ts *Templates,
+	exporter netip.Addr,
+	obsid int,
+	dat []byte,
+) (flows []RawFlow, err error) {
 	size := len(dat)
 	idx := 0
 	hdrsize := 4
@@ -86,7 +95,7 @@ func parseSets(obsid int, dat []byte) (flows []RawFlow, err error) {
 		}
 		// fmt.Printf("sethdr: %+v\n", hdr)
 		idx += hdrsize
-		flowz, err := parseSet(hdr, dat[idx:idx+hdr.DataLength])
+		flowz, err := parseSet(ts, exporter, obsid, hdr, dat[idx:idx+hdr.DataLength])
 		if err != nil {
 			return flows, err
 		}
@@ -120,27 +129,23 @@ const (
 	OptionTemplateSetDef = 3
 )
 
-var (
-	v2templates  = map[int]TemplateDef{}
-	templateLock sync.Mutex
-)
-
-func parseSet(hdr SetHeader, dat []byte) (flows []RawFlow, err error) {
+func parseSet(
+	ts *Templates,
+	exporter netip.Addr,
+	obsid int,
+	hdr SetHeader,
+	dat []byte,
+) (flows []RawFlow, err error) {
 	switch {
 	case hdr.ID == TemplateSetDef:
 		t, err := parseTemplateDef(dat[0:hdr.DataLength])
 		if err != nil {
 			return flows, err
 		}
-		// TODO: need to incorporate ObservationDomainID, what about a bit shift of obsid up above the template id range?
-		templateLock.Lock()
-		v2templates[t.ID] = t
-		templateLock.Unlock()
+		ts.learn(exporter, obsid, t)
 	case hdr.ID == OptionTemplateSetDef:
 	case hdr.ID > 255:
-		templateLock.Lock()
-		t, ok := v2templates[hdr.ID]
-		templateLock.Unlock()
+		t, ok := ts.lookup(exporter, obsid, hdr.ID)
 		if ok {
 			flows = parseFlow(t, dat)
 			// ff := rawsToIpFlows(rf)
@@ -39,6 +39,10 @@ func rawToIpFlow(dat RawFlow) (f model.IpFlow) {
 			f.Protocol = model.Protocol(field.Data[0])
 		case IPFIX_FIELD_tcpControlBits:
 			f.Flags = model.TcpFlags(field.Data[0])
+		case IPFIX_FIELD_vlanId, IPFIX_FIELD_dot1qVlanId:
+			if len(field.Data) == 2 {
+				f.VlanID = int(binary.BigEndian.Uint16(field.Data) & 0x0fff)
+			}
 		}
 	}
 
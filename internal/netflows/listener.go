@@ -8,8 +8,6 @@ import (
 	"context"
 	"net"
 
-	"github.com/charmbracelet/log"
-
 	"github.com/networkables/mason/internal/model"
 )
 
@@ -21,35 +19,35 @@ import (
 
 */
 
-func Listen(ctx context.Context, cfg *Config) chan []byte {
-	output := make(chan []byte)
+func Listen(ctx context.Context, cfg *Config) chan Packet {
+	output := make(chan Packet)
 	listenaddy, err := net.ResolveUDPAddr("udp", cfg.ListenAddress)
 	if err != nil {
-		log.Fatalf("resolveudpaddr: %v", err)
+		logger.Fatalf("resolveudpaddr: %v", err)
 	}
 	conn, err := net.ListenUDP("udp", listenaddy)
 	if err != nil {
-		log.Fatalf("listenudp: %v", err)
+		logger.Fatalf("listenudp: %v", err)
 	}
-	log.Info("starting netflow server", "addr", cfg.ListenAddress)
+	logger.Info("starting netflow server", "addr", cfg.ListenAddress)
 
 	go func(pktsize int) {
 		defer conn.Close()
 		defer close(output)
 		for {
 			if ctx.Err() != nil {
-				log.Info("netflow listener shutdown")
+				logger.Info("netflow listener shutdown")
 				return
 			}
 			buff := make([]byte, pktsize)
-			size, _, err := conn.ReadFromUDP(buff)
+			size, from, err := conn.ReadFromUDPAddrPort(buff)
 			if err != nil {
 				if size == 0 {
 					return
 				}
-				log.Fatalf("readfromudp: %v", err)
+				logger.Fatalf("readfromudp: %v", err)
 			}
-			output <- buff
+			output <- Packet{Exporter: from.Addr().Unmap(), Data: buff[:size]}
 		}
 	}(cfg.PacketSize)
 
@@ -59,19 +57,19 @@ func Listen(ctx context.Context, cfg *Config) chan []byte {
 	// for _, ipflow := range ipflows {
 	// 	err = db.CreateFlow(ctx, ipflow)
 	// 	if err != nil {
-	// 		log.Printf("createflow: %v\n", err)
+	// 		logger.Printf("createflow: %v\n", err)
 	// 		return
 	// 	}
 	// }
 }
 
-func parser(ctx context.Context, pkt []byte) ([]model.IpFlow, error) {
+func parser(ctx context.Context, ts *Templates, pkt Packet) ([]model.IpFlow, error) {
 	if ctx.Err() != nil {
 		return nil, nil
 	}
-	rawflows, err := handlePacket(pkt)
+	rawflows, err := handlePacket(ts, pkt)
 	if err != nil {
-		log.Errorf("handlepacket: %v", err)
+		logger.Errorf("handlepacket: %v", err)
 		return nil, err
 	}
 	ipflows := rawsToIpFlows(rawflows)
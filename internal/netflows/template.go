@@ -0,0 +1,97 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package netflows
+
+import (
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// Packet is a datagram received from an exporter
+type Packet struct {
+	Exporter netip.Addr
+	Data     []byte
+}
+
+// Templates holds the templates announced by each exporter and observation domain, template
+// ids are only unique within a domain of an exporter.  A new or changed template is handed
+// to the persist func so it can be restored after a restart.
+type Templates struct {
+	mu      sync.Mutex
+	defs    map[templateKey]TemplateDef
+	persist func(model.FlowTemplate)
+}
+
+type templateKey struct {
+	exporter netip.Addr
+	domain   int
+	id       int
+}
+
+// NewTemplates returns the templates restored from the saved ones, persist may be nil
+func NewTemplates(saved []model.FlowTemplate, persist func(model.FlowTemplate)) *Templates {
+	ts := &Templates{
+		defs:    make(map[templateKey]TemplateDef, len(saved)),
+		persist: persist,
+	}
+	for _, t := range saved {
+		def := TemplateDef{ID: t.ID, Fields: make([]FieldDef, len(t.Fields))}
+		for i, f := range t.Fields {
+			def.Fields[i] = FieldDef{
+				ID:               f.ID,
+				DataLength:       f.Length,
+				EnterpriseNumber: f.EnterpriseNumber,
+			}
+		}
+		ts.defs[templateKey{exporter: t.Exporter.Addr(), domain: t.DomainID, id: t.ID}] = def
+	}
+	return ts
+}
+
+// Len returns the number of templates known
+func (ts *Templates) Len() int {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return len(ts.defs)
+}
+
+func (ts *Templates) learn(exporter netip.Addr, domain int, def TemplateDef) {
+	key := templateKey{exporter: exporter, domain: domain, id: def.ID}
+	ts.mu.Lock()
+	prev, ok := ts.defs[key]
+	ts.defs[key] = def
+	ts.mu.Unlock()
+
+	// exporters announce their templates again every few seconds, only changes are saved
+	if ts.persist == nil || (ok && slices.Equal(prev.Fields, def.Fields)) {
+		return
+	}
+	t := model.FlowTemplate{
+		Exporter:  model.AddrToModelAddr(exporter),
+		DomainID:  domain,
+		ID:        def.ID,
+		Fields:    make([]model.FlowTemplateField, len(def.Fields)),
+		UpdatedAt: time.Now(),
+	}
+	for i, f := range def.Fields {
+		t.Fields[i] = model.FlowTemplateField{
+			ID:               f.ID,
+			Length:           f.DataLength,
+			EnterpriseNumber: f.EnterpriseNumber,
+		}
+	}
+	ts.persist(t)
+}
+
+func (ts *Templates) lookup(exporter netip.Addr, domain int, id int) (TemplateDef, bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	def, ok := ts.defs[templateKey{exporter: exporter, domain: domain, id: id}]
+	return def, ok
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package netflows
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// ipfixMessage wraps the sets in an ipfix message header of the observation domain
+func ipfixMessage(domain uint32, sets ...[]byte) []byte {
+	b := make([]byte, 16)
+	for _, set := range sets {
+		b = append(b, set...)
+	}
+	binary.BigEndian.PutUint16(b[0:2], ipfixVersion)
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(b)))
+	binary.BigEndian.PutUint32(b[12:16], domain)
+	return b
+}
+
+func ipfixSet(id uint16, dat ...byte) []byte {
+	b := make([]byte, 4, 4+len(dat))
+	binary.BigEndian.PutUint16(b[0:2], id)
+	binary.BigEndian.PutUint16(b[2:4], uint16(4+len(dat)))
+	return append(b, dat...)
+}
+
+// template 256 of a source and destination ipv4 address
+var (
+	testTemplateSet = ipfixSet(TemplateSetDef, 1, 0, 0, 2, 0, 8, 0, 4, 0, 12, 0, 4)
+	testDataSet     = ipfixSet(256, 192, 168, 1, 10, 1, 1, 1, 1)
+	testTemplate    = model.FlowTemplate{
+		Exporter: model.MustParseAddr("192.168.1.1"),
+		ID:       256,
+		Fields:   []model.FlowTemplateField{{ID: 8, Length: 4}, {ID: 12, Length: 4}},
+	}
+)
+
+func TestTemplates(t *testing.T) {
+	exporter := netip.MustParseAddr("192.168.1.1")
+	tests := map[string]struct {
+		saved    []model.FlowTemplate
+		packets  []Packet
+		flows    int
+		persists int
+	}{
+		"Announced": {
+			packets: []Packet{
+				{Exporter: exporter, Data: ipfixMessage(0, testTemplateSet)},
+				{Exporter: exporter, Data: ipfixMessage(0, testTemplateSet, testDataSet)},
+			},
+			flows:    1,
+			persists: 1,
+		},
+		"DataBeforeTemplate": {
+			packets: []Packet{{Exporter: exporter, Data: ipfixMessage(0, testDataSet)}},
+		},
+		"Restored": {
+			saved:   []model.FlowTemplate{testTemplate},
+			packets: []Packet{{Exporter: exporter, Data: ipfixMessage(0, testDataSet)}},
+			flows:   1,
+		},
+		"RestoredOtherExporter": {
+			saved: []model.FlowTemplate{testTemplate},
+			packets: []Packet{
+				{Exporter: netip.MustParseAddr("10.0.0.1"), Data: ipfixMessage(0, testDataSet)},
+			},
+		},
+		"RestoredOtherDomain": {
+			saved:   []model.FlowTemplate{testTemplate},
+			packets: []Packet{{Exporter: exporter, Data: ipfixMessage(1, testDataSet)}},
+		},
+		"RestoredAnnouncedAgain": {
+			saved: []model.FlowTemplate{testTemplate},
+			packets: []Packet{
+				{Exporter: exporter, Data: ipfixMessage(0, testTemplateSet, testDataSet)},
+			},
+			flows: 1,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var persisted []model.FlowTemplate
+			ts := NewTemplates(tc.saved, func(ft model.FlowTemplate) {
+				persisted = append(persisted, ft)
+			})
+			flows := 0
+			for _, pkt := range tc.packets {
+				raws, err := handlePacket(ts, pkt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				flows += len(raws)
+			}
+			if flows != tc.flows {
+				t.Errorf("flows want: %d, got: %d", tc.flows, flows)
+			}
+			if len(persisted) != tc.persists {
+				t.Fatalf("persists want: %d, got: %d", tc.persists, len(persisted))
+			}
+			for _, ft := range persisted {
+				diff := cmp.Diff(
+					testTemplate,
+					ft,
+					cmpopts.EquateComparable(netip.Addr{}),
+					cmpopts.IgnoreFields(model.FlowTemplate{}, "UpdatedAt"),
+				)
+				if diff != "" {
+					t.Errorf("(-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
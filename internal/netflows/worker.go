@@ -7,21 +7,25 @@ package netflows
 import (
 	"context"
 
-	"github.com/charmbracelet/log"
-
+	"github.com/networkables/mason/internal/logging"
 	"github.com/networkables/mason/internal/model"
 	"github.com/networkables/mason/internal/workerpool"
 )
 
+var logger = logging.For(logging.NetFlows)
+
 type Worker struct {
-	In chan []byte
-	*workerpool.Pool[[]byte, []model.IpFlow]
+	In chan Packet
+	*workerpool.Pool[Packet, []model.IpFlow]
 }
 
-func NewWorker(cfg *Config, input chan []byte) *Worker {
+// NewWorker returns the pool parsing the packets with the templates learned so far
+func NewWorker(cfg *Config, input chan Packet, templates *Templates) *Worker {
 	return &Worker{
-		In:   input,
-		Pool: workerpool.New("netflows", input, parser),
+		In: input,
+		Pool: workerpool.New("netflows", input, func(ctx context.Context, pkt Packet) ([]model.IpFlow, error) {
+			return parser(ctx, templates, pkt)
+		}),
 	}
 }
 
@@ -30,6 +34,6 @@ func (w *Worker) Run(ctx context.Context, max int) {
 }
 
 func (w *Worker) Close() {
-	log.Info("netflows workerpool shutdown")
+	logger.Info("netflows workerpool shutdown")
 	close(w.In)
 }
@@ -0,0 +1,156 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// telegramAPI is the bot api the telegram chat posts to
+var telegramAPI = "https://api.telegram.org"
+
+// discordMaxContent is the longest message discord accepts
+const discordMaxContent = 2000
+
+// Chat posts the alerts of its route to a chat service
+type Chat struct {
+	Name   string
+	route  Route
+	url    string
+	body   func(text string) any
+	client *http.Client
+}
+
+func newRoute(minSeverity string, tags []string) (Route, error) {
+	sev, err := ParseSeverity(minSeverity)
+	return Route{MinSeverity: sev, Tags: tags}, err
+}
+
+// NewSlack posts to a slack incoming webhook
+func NewSlack(cfg ChatConfig, timeout time.Duration) (*Chat, error) {
+	return newWebhookChat("slack", cfg, timeout, func(text string) any {
+		return map[string]string{"text": text}
+	})
+}
+
+// NewDiscord posts to a discord channel webhook
+func NewDiscord(cfg ChatConfig, timeout time.Duration) (*Chat, error) {
+	return newWebhookChat("discord", cfg, timeout, func(text string) any {
+		if len(text) > discordMaxContent {
+			text = text[:discordMaxContent]
+		}
+		return map[string]string{"content": text}
+	})
+}
+
+func newWebhookChat(
+	name string,
+	cfg ChatConfig,
+	timeout time.Duration,
+	body func(string) any,
+) (*Chat, error) {
+	if cfg.WebhookURL == "" {
+		return nil, fmt.Errorf("%s: %w", name, ErrNoWebhook)
+	}
+	route, err := newRoute(cfg.MinSeverity, cfg.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &Chat{
+		Name:   name,
+		route:  route,
+		url:    cfg.WebhookURL,
+		body:   body,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// NewTelegram posts through a telegram bot to a chat
+func NewTelegram(cfg TelegramConfig, timeout time.Duration) (*Chat, error) {
+	if cfg.Token == "" || cfg.ChatID == "" {
+		return nil, ErrNoTelegramChat
+	}
+	route, err := newRoute(cfg.MinSeverity, cfg.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("telegram: %w", err)
+	}
+	return &Chat{
+		Name:  "telegram",
+		route: route,
+		url:   telegramAPI + "/bot" + cfg.Token + "/sendMessage",
+		body: func(text string) any {
+			return map[string]string{"chat_id": cfg.ChatID, "text": text}
+		},
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Alert posts the alert when it matches the route of the chat
+func (c *Chat) Alert(ctx context.Context, a Alert) error {
+	if !c.route.Match(a) {
+		return nil
+	}
+	return c.post(ctx, chatText(a))
+}
+
+func (c *Chat) post(ctx context.Context, text string) error {
+	return postJSON(ctx, c.client, c.Name, c.url, nil, c.body(text))
+}
+
+// postJSON posts the body to the endpoint, a response status of 400 or above is an error with the
+// start of the response
+func postJSON(
+	ctx context.Context,
+	client *http.Client,
+	name string,
+	endpoint string,
+	header http.Header,
+	body any,
+) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		// the url of a telegram chat holds the bot token, it is left out of the error
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s: %s", name, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// chatText is the alert as a single chat message
+func chatText(a Alert) string {
+	text := fmt.Sprintf("[mason %s] %s", a.Kind.Severity(), a.Message)
+	if a.Addr != "" {
+		text += " (" + a.Addr + ")"
+	}
+	return text
+}
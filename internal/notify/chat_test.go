@@ -0,0 +1,153 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRoute_Match(t *testing.T) {
+	tests := map[string]struct {
+		route Route
+		alert Alert
+		want  bool
+	}{
+		"AtSeverity": {
+			route: Route{MinSeverity: SeverityWarning},
+			alert: Alert{Kind: AlertWarning},
+			want:  true,
+		},
+		"AboveSeverity": {
+			route: Route{MinSeverity: SeverityWarning},
+			alert: Alert{Kind: AlertFailure},
+			want:  true,
+		},
+		"BelowSeverity": {
+			route: Route{MinSeverity: SeverityWarning},
+			alert: Alert{Kind: AlertNewDevice},
+		},
+		"Tagged": {
+			route: Route{MinSeverity: SeverityInfo, Tags: []string{"server"}},
+			alert: Alert{Kind: AlertRecovery, Tags: []string{"rack", "server"}},
+			want:  true,
+		},
+		"NotTagged": {
+			route: Route{MinSeverity: SeverityInfo, Tags: []string{"server"}},
+			alert: Alert{Kind: AlertFailure, Tags: []string{"iot"}},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.route.Match(tc.alert); got != tc.want {
+				t.Fatalf("want %v got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestChat_Alert(t *testing.T) {
+	failed := Alert{
+		Kind:    AlertFailure,
+		Addr:    "192.168.1.9",
+		Message: "printer ipp down: refused",
+	}
+	text := "[mason critical] printer ipp down: refused (192.168.1.9)"
+	tests := map[string]struct {
+		chat func(url string) (*Chat, error)
+		path string
+		want map[string]string
+	}{
+		"Slack": {
+			chat: func(url string) (*Chat, error) {
+				return NewSlack(ChatConfig{WebhookURL: url + "/hook", MinSeverity: "info"}, time.Second)
+			},
+			path: "/hook",
+			want: map[string]string{"text": text},
+		},
+		"Discord": {
+			chat: func(url string) (*Chat, error) {
+				return NewDiscord(ChatConfig{WebhookURL: url + "/hook", MinSeverity: "info"}, time.Second)
+			},
+			path: "/hook",
+			want: map[string]string{"content": text},
+		},
+		"Telegram": {
+			chat: func(url string) (*Chat, error) {
+				telegramAPI = url
+				return NewTelegram(
+					TelegramConfig{Token: "123:abc", ChatID: "-42", MinSeverity: "critical"},
+					time.Second,
+				)
+			},
+			path: "/bot123:abc/sendMessage",
+			want: map[string]string{"chat_id": "-42", "text": text},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var (
+				path string
+				got  map[string]string
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				json.NewDecoder(r.Body).Decode(&got)
+			}))
+			defer srv.Close()
+			c, err := tc.chat(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = c.Alert(context.Background(), failed); err != nil {
+				t.Fatal(err)
+			}
+			if path != tc.path {
+				t.Fatalf("path: want %s got %s", tc.path, path)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestChat_AlertError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	c, err := NewSlack(ChatConfig{WebhookURL: srv.URL, MinSeverity: "info"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Alert(context.Background(), Alert{Kind: AlertFailure})
+	if err == nil || !strings.Contains(err.Error(), "invalid_token") {
+		t.Fatalf("want the response in the error, got %v", err)
+	}
+}
+
+func TestNewChat_Invalid(t *testing.T) {
+	_, err := NewDiscord(ChatConfig{MinSeverity: "info"}, time.Second)
+	if !errors.Is(err, ErrNoWebhook) {
+		t.Fatalf("want %v got %v", ErrNoWebhook, err)
+	}
+	_, err = NewSlack(ChatConfig{WebhookURL: "http://x", MinSeverity: "loud"}, time.Second)
+	if !errors.Is(err, ErrInvalidSeverity) {
+		t.Fatalf("want %v got %v", ErrInvalidSeverity, err)
+	}
+	_, err = NewTelegram(TelegramConfig{Token: "t", MinSeverity: "info"}, time.Second)
+	if !errors.Is(err, ErrNoTelegramChat) {
+		t.Fatalf("want %v got %v", ErrNoTelegramChat, err)
+	}
+}
@@ -0,0 +1,360 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/networkables/mason/internal/flagset"
+)
+
+// Config sets when the digest is sent and the channels alerts are sent to
+type Config struct {
+	DigestTime   string
+	CertWarnDays int
+	ChatTimeout  time.Duration
+	Email        *EmailConfig
+	Slack        *ChatConfig
+	Discord      *ChatConfig
+	Telegram     *TelegramConfig
+	PagerDuty    *IncidentConfig
+	Opsgenie     *IncidentConfig
+}
+
+// EmailConfig is the smtp server and recipients of the email channel.  Alerts are mailed as
+// they happen when Immediate is set and gathered into a daily digest when Digest is set.
+type EmailConfig struct {
+	Enabled        bool
+	Host           string
+	Port           int
+	TLS            string
+	Username       string
+	Password       string
+	From           string
+	To             []string
+	Immediate      bool
+	Digest         bool
+	Timeout        time.Duration
+	AlertTemplate  string
+	DigestTemplate string
+}
+
+// ChatConfig is a chat webhook and the alerts routed to it, alerts below the minimum
+// severity are not sent and, when tags are set, only the alerts of devices with one of them
+type ChatConfig struct {
+	Enabled     bool
+	WebhookURL  string
+	MinSeverity string
+	Tags        []string
+}
+
+// TelegramConfig is the bot posting to a telegram chat and the alerts routed to it
+type TelegramConfig struct {
+	Enabled     bool
+	Token       string
+	ChatID      string
+	MinSeverity string
+	Tags        []string
+}
+
+// IncidentConfig is an on call service and the alerts routed to it, Key is the routing key of
+// a pagerduty service or the api key of an opsgenie integration
+type IncidentConfig struct {
+	Enabled     bool
+	Key         string
+	URL         string
+	MinSeverity string
+	Tags        []string
+}
+
+func SetFlags(fs *pflag.FlagSet, cfg *Config) {
+	configMajorKey := "notify"
+
+	flagset.String(
+		fs,
+		&cfg.DigestTime,
+		configMajorKey,
+		"digesttime",
+		"08:00",
+		"local time of day the daily digest is sent (HH:MM)",
+	)
+	flagset.Int(
+		fs,
+		&cfg.CertWarnDays,
+		configMajorKey,
+		"certwarndays",
+		30,
+		"https check certificates expiring within this many days are listed in the digest",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.ChatTimeout,
+		configMajorKey,
+		"chattimeout",
+		10*time.Second,
+		"time allowed to post an alert to a chat or incident service",
+	)
+
+	cfg.Email = &EmailConfig{}
+	emailKey := flagset.Key(configMajorKey, "email")
+	flagset.Bool(
+		fs,
+		&cfg.Email.Enabled,
+		emailKey,
+		"enabled",
+		false,
+		"send notifications by email",
+	)
+	flagset.String(
+		fs,
+		&cfg.Email.Host,
+		emailKey,
+		"host",
+		"",
+		"smtp server host",
+	)
+	flagset.Int(
+		fs,
+		&cfg.Email.Port,
+		emailKey,
+		"port",
+		587,
+		"smtp server port",
+	)
+	flagset.String(
+		fs,
+		&cfg.Email.TLS,
+		emailKey,
+		"tls",
+		TLSStartTLS,
+		"how the smtp connection is secured [starttls,tls,none]",
+	)
+	flagset.String(
+		fs,
+		&cfg.Email.Username,
+		emailKey,
+		"username",
+		"",
+		"smtp username, no authentication when blank",
+	)
+	flagset.String(
+		fs,
+		&cfg.Email.Password,
+		emailKey,
+		"password",
+		"",
+		"smtp password",
+	)
+	flagset.String(
+		fs,
+		&cfg.Email.From,
+		emailKey,
+		"from",
+		"mason@localhost",
+		"sender address of the emails",
+	)
+	flagset.StringSlice(
+		fs,
+		&cfg.Email.To,
+		emailKey,
+		"to",
+		[]string{},
+		"recipient addresses of the emails",
+	)
+	flagset.Bool(
+		fs,
+		&cfg.Email.Immediate,
+		emailKey,
+		"immediate",
+		true,
+		"email each alert as it happens",
+	)
+	flagset.Bool(
+		fs,
+		&cfg.Email.Digest,
+		emailKey,
+		"digest",
+		true,
+		"email a daily digest of new devices, failures and expiring certificates",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Email.Timeout,
+		emailKey,
+		"timeout",
+		30*time.Second,
+		"time allowed to connect to the smtp server",
+	)
+	flagset.String(
+		fs,
+		&cfg.Email.AlertTemplate,
+		emailKey,
+		"alerttemplate",
+		"",
+		"text/template file overriding the alert email, defines subject and body",
+	)
+	flagset.String(
+		fs,
+		&cfg.Email.DigestTemplate,
+		emailKey,
+		"digesttemplate",
+		"",
+		"text/template file overriding the digest email, defines subject and body",
+	)
+
+	cfg.Slack = &ChatConfig{}
+	setChatFlags(fs, cfg.Slack, flagset.Key(configMajorKey, "slack"), "slack")
+	cfg.Discord = &ChatConfig{}
+	setChatFlags(fs, cfg.Discord, flagset.Key(configMajorKey, "discord"), "discord")
+
+	cfg.Telegram = &TelegramConfig{}
+	telegramKey := flagset.Key(configMajorKey, "telegram")
+	flagset.Bool(
+		fs,
+		&cfg.Telegram.Enabled,
+		telegramKey,
+		"enabled",
+		false,
+		"send alerts to a telegram chat",
+	)
+	flagset.String(
+		fs,
+		&cfg.Telegram.Token,
+		telegramKey,
+		"token",
+		"",
+		"telegram bot token",
+	)
+	flagset.String(
+		fs,
+		&cfg.Telegram.ChatID,
+		telegramKey,
+		"chatid",
+		"",
+		"telegram chat the bot posts to",
+	)
+	flagset.String(
+		fs,
+		&cfg.Telegram.MinSeverity,
+		telegramKey,
+		"minseverity",
+		string(SeverityWarning),
+		"least severity of the alerts sent to telegram [info,warning,critical]",
+	)
+	flagset.StringSlice(
+		fs,
+		&cfg.Telegram.Tags,
+		telegramKey,
+		"tags",
+		[]string{},
+		"only send the alerts of devices with one of these tags, all alerts when empty",
+	)
+
+	cfg.PagerDuty = &IncidentConfig{}
+	setIncidentFlags(
+		fs,
+		cfg.PagerDuty,
+		flagset.Key(configMajorKey, "pagerduty"),
+		"pagerduty",
+		"integration key of the pagerduty service (events api v2)",
+		DefaultPagerDutyURL,
+	)
+	cfg.Opsgenie = &IncidentConfig{}
+	setIncidentFlags(
+		fs,
+		cfg.Opsgenie,
+		flagset.Key(configMajorKey, "opsgenie"),
+		"opsgenie",
+		"api key of the opsgenie api integration",
+		DefaultOpsgenieURL,
+	)
+}
+
+func setChatFlags(fs *pflag.FlagSet, cfg *ChatConfig, key string, name string) {
+	flagset.Bool(
+		fs,
+		&cfg.Enabled,
+		key,
+		"enabled",
+		false,
+		"send alerts to a "+name+" webhook",
+	)
+	flagset.String(
+		fs,
+		&cfg.WebhookURL,
+		key,
+		"webhookurl",
+		"",
+		name+" incoming webhook url",
+	)
+	flagset.String(
+		fs,
+		&cfg.MinSeverity,
+		key,
+		"minseverity",
+		string(SeverityWarning),
+		"least severity of the alerts sent to "+name+" [info,warning,critical]",
+	)
+	flagset.StringSlice(
+		fs,
+		&cfg.Tags,
+		key,
+		"tags",
+		[]string{},
+		"only send the alerts of devices with one of these tags, all alerts when empty",
+	)
+}
+
+func setIncidentFlags(
+	fs *pflag.FlagSet,
+	cfg *IncidentConfig,
+	key string,
+	name string,
+	keyUsage string,
+	url string,
+) {
+	flagset.Bool(
+		fs,
+		&cfg.Enabled,
+		key,
+		"enabled",
+		false,
+		"open and resolve "+name+" incidents from alerts",
+	)
+	flagset.String(
+		fs,
+		&cfg.Key,
+		key,
+		"key",
+		"",
+		keyUsage,
+	)
+	flagset.String(
+		fs,
+		&cfg.URL,
+		key,
+		"url",
+		url,
+		name+" api url",
+	)
+	flagset.String(
+		fs,
+		&cfg.MinSeverity,
+		key,
+		"minseverity",
+		string(SeverityCritical),
+		"least severity of the alerts opening "+name+" incidents [info,warning,critical]",
+	)
+	flagset.StringSlice(
+		fs,
+		&cfg.Tags,
+		key,
+		"tags",
+		[]string{},
+		"only open incidents for devices with one of these tags, all alerts when empty",
+	)
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	TLSStartTLS = "starttls"
+	TLSImplicit = "tls"
+	TLSNone     = "none"
+)
+
+// Email sends alerts and digests through an smtp server
+type Email struct {
+	cfg    EmailConfig
+	alert  *template.Template
+	digest *template.Template
+}
+
+// NewEmail checks the config and parses the templates, a template file which cannot be read
+// or parsed is an error rather than a silent fallback to the default
+func NewEmail(cfg EmailConfig) (*Email, error) {
+	switch cfg.TLS {
+	case TLSStartTLS, TLSImplicit, TLSNone:
+	default:
+		return nil, fmt.Errorf("%w %q", ErrInvalidTLSMode, cfg.TLS)
+	}
+	if cfg.Host == "" {
+		return nil, ErrNoHost
+	}
+	if len(cfg.To) == 0 {
+		return nil, ErrNoRecipients
+	}
+	alert, err := parseTemplate("alert", cfg.AlertTemplate, defaultAlertTemplate)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := parseTemplate("digest", cfg.DigestTemplate, defaultDigestTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &Email{cfg: cfg, alert: alert, digest: digest}, nil
+}
+
+// Alert mails the alert when immediate alerts are enabled
+func (e *Email) Alert(ctx context.Context, a Alert) error {
+	if !e.cfg.Immediate {
+		return nil
+	}
+	subject, body, err := render(e.alert, a)
+	if err != nil {
+		return err
+	}
+	return e.send(ctx, subject, body)
+}
+
+// Digest mails the digest when digests are enabled, an empty digest is still sent so a
+// missing one stands out
+func (e *Email) Digest(ctx context.Context, d Digest) error {
+	if !e.cfg.Digest {
+		return nil
+	}
+	subject, body, err := render(e.digest, d)
+	if err != nil {
+		return err
+	}
+	return e.send(ctx, subject, body)
+}
+
+func (e *Email) send(ctx context.Context, subject string, body string) error {
+	addr := net.JoinHostPort(e.cfg.Host, strconv.Itoa(e.cfg.Port))
+	tlsConfig := &tls.Config{ServerName: e.cfg.Host}
+	dialer := &net.Dialer{Timeout: e.cfg.Timeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if e.cfg.TLS == TLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	if e.cfg.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(e.cfg.Timeout))
+	}
+	c, err := smtp.NewClient(conn, e.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if e.cfg.TLS == TLSStartTLS {
+		if err = c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if e.cfg.Username != "" {
+		err = c.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host))
+		if err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(e.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range e.cfg.To {
+		if err = c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buildMessage(e.cfg.From, e.cfg.To, subject, body, time.Now()))
+	if err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// buildMessage is a plain text email, line breaks are stripped from the header values so a
+// message can not add headers of its own
+func buildMessage(from string, to []string, subject string, body string, now time.Time) []byte {
+	header := strings.NewReplacer("\r", "", "\n", "")
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", header.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", header.Replace(strings.Join(to, ", ")))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", header.Replace(subject)))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return b.Bytes()
+}
@@ -0,0 +1,142 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+	DefaultOpsgenieURL  = "https://api.opsgenie.com"
+
+	// opsgenieMaxMessage is the longest alert message opsgenie accepts
+	opsgenieMaxMessage = 130
+)
+
+// Incidents opens an incident on an on call service for the alerts of its route and resolves
+// it when the alert recovers.  Incidents are matched by the dedup key of the alert so a
+// repeated failure updates the open incident rather than paging again.
+type Incidents struct {
+	Name    string
+	route   Route
+	client  *http.Client
+	open    func(ctx context.Context, a Alert) error
+	resolve func(ctx context.Context, a Alert) error
+}
+
+func newIncidents(name string, cfg IncidentConfig, timeout time.Duration) (*Incidents, error) {
+	if cfg.Key == "" {
+		return nil, fmt.Errorf("%s: %w", name, ErrNoIncidentKey)
+	}
+	route, err := newRoute(cfg.MinSeverity, cfg.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &Incidents{
+		Name:   name,
+		route:  route,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// NewPagerDuty sends the alerts to the events api (v2) of a pagerduty service
+func NewPagerDuty(cfg IncidentConfig, timeout time.Duration) (*Incidents, error) {
+	in, err := newIncidents("pagerduty", cfg, timeout)
+	if err != nil {
+		return nil, err
+	}
+	event := func(ctx context.Context, action string, a Alert) error {
+		body := map[string]any{
+			"routing_key":  cfg.Key,
+			"event_action": action,
+			"dedup_key":    a.DedupKey(),
+		}
+		if action == "trigger" {
+			body["payload"] = map[string]any{
+				"summary":   a.Message,
+				"source":    alertSource(a),
+				"severity":  string(a.Kind.Severity()),
+				"timestamp": a.Time.Format(time.RFC3339),
+				"component": a.Type,
+			}
+		}
+		return postJSON(ctx, in.client, in.Name, cfg.URL, nil, body)
+	}
+	in.open = func(ctx context.Context, a Alert) error { return event(ctx, "trigger", a) }
+	in.resolve = func(ctx context.Context, a Alert) error { return event(ctx, "resolve", a) }
+	return in, nil
+}
+
+// NewOpsgenie sends the alerts to the alert api of opsgenie, the alias of an opsgenie alert is
+// the dedup key
+func NewOpsgenie(cfg IncidentConfig, timeout time.Duration) (*Incidents, error) {
+	in, err := newIncidents("opsgenie", cfg, timeout)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{"Authorization": []string{"GenieKey " + cfg.Key}}
+	base := strings.TrimSuffix(cfg.URL, "/") + "/v2/alerts"
+	in.open = func(ctx context.Context, a Alert) error {
+		message := a.Message
+		if len(message) > opsgenieMaxMessage {
+			message = message[:opsgenieMaxMessage]
+		}
+		return postJSON(ctx, in.client, in.Name, base, header, map[string]any{
+			"message":     message,
+			"alias":       a.DedupKey(),
+			"description": a.Message,
+			"priority":    opsgeniePriority(a.Kind.Severity()),
+			"source":      alertSource(a),
+			"tags":        a.Tags,
+		})
+	}
+	in.resolve = func(ctx context.Context, a Alert) error {
+		closeURL := base + "/" + url.PathEscape(a.DedupKey()) + "/close?identifierType=alias"
+		return postJSON(ctx, in.client, in.Name, closeURL, header, map[string]any{
+			"source": alertSource(a),
+			"note":   a.Message,
+		})
+	}
+	return in, nil
+}
+
+// Alert opens an incident for the alerts matching the route and resolves the incident of a
+// recovery, recoveries are matched by tag only as they are below any minimum severity
+func (in *Incidents) Alert(ctx context.Context, a Alert) error {
+	if a.Kind == AlertRecovery {
+		if !(Route{MinSeverity: SeverityInfo, Tags: in.route.Tags}).Match(a) {
+			return nil
+		}
+		return in.resolve(ctx, a)
+	}
+	if !in.route.Match(a) {
+		return nil
+	}
+	return in.open(ctx, a)
+}
+
+// alertSource is the address the alert is about, mason when it is not about a device
+func alertSource(a Alert) string {
+	if a.Addr != "" {
+		return a.Addr
+	}
+	return "mason"
+}
+
+func opsgeniePriority(s Severity) string {
+	switch s {
+	case SeverityCritical:
+		return "P1"
+	case SeverityWarning:
+		return "P3"
+	}
+	return "P5"
+}
@@ -0,0 +1,156 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// posted is a request received by the fake incident service
+type posted struct {
+	Path   string
+	Auth   string
+	Fields map[string]any
+}
+
+func fakeIncidents(t *testing.T) (string, *[]posted) {
+	t.Helper()
+	got := make([]posted, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := posted{Path: r.URL.RequestURI(), Auth: r.Header.Get("Authorization")}
+		json.NewDecoder(r.Body).Decode(&p.Fields)
+		got = append(got, p)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, &got
+}
+
+func TestIncidents_Alert(t *testing.T) {
+	at := time.Date(2024, 6, 3, 8, 0, 0, 0, time.UTC)
+	down := Alert{
+		Time:    at,
+		Kind:    AlertFailure,
+		Type:    "EventServiceCheckChanged",
+		Addr:    "192.168.1.9",
+		Key:     "servicecheck/192.168.1.9:22",
+		Tags:    []string{"server"},
+		Message: "nas ssh down",
+	}
+	up := down
+	up.Kind, up.Message = AlertRecovery, "nas ssh up"
+	added := Alert{Kind: AlertNewDevice, Addr: "192.168.1.10", Message: "printer"}
+
+	tests := map[string]struct {
+		incidents func(url string) (*Incidents, error)
+		want      []posted
+	}{
+		"PagerDuty": {
+			incidents: func(url string) (*Incidents, error) {
+				return NewPagerDuty(IncidentConfig{
+					Key:         "rk",
+					URL:         url + "/v2/enqueue",
+					MinSeverity: "critical",
+				}, time.Second)
+			},
+			want: []posted{
+				{
+					Path: "/v2/enqueue",
+					Fields: map[string]any{
+						"routing_key":  "rk",
+						"event_action": "trigger",
+						"dedup_key":    "mason/servicecheck/192.168.1.9:22",
+						"payload": map[string]any{
+							"summary":   "nas ssh down",
+							"source":    "192.168.1.9",
+							"severity":  "critical",
+							"timestamp": "2024-06-03T08:00:00Z",
+							"component": "EventServiceCheckChanged",
+						},
+					},
+				},
+				{
+					Path: "/v2/enqueue",
+					Fields: map[string]any{
+						"routing_key":  "rk",
+						"event_action": "resolve",
+						"dedup_key":    "mason/servicecheck/192.168.1.9:22",
+					},
+				},
+			},
+		},
+		"Opsgenie": {
+			incidents: func(url string) (*Incidents, error) {
+				return NewOpsgenie(IncidentConfig{
+					Key:         "gk",
+					URL:         url + "/",
+					MinSeverity: "warning",
+					Tags:        []string{"server"},
+				}, time.Second)
+			},
+			want: []posted{
+				{
+					Path: "/v2/alerts",
+					Auth: "GenieKey gk",
+					Fields: map[string]any{
+						"message":     "nas ssh down",
+						"alias":       "mason/servicecheck/192.168.1.9:22",
+						"description": "nas ssh down",
+						"priority":    "P1",
+						"source":      "192.168.1.9",
+						"tags":        []any{"server"},
+					},
+				},
+				{
+					Path: "/v2/alerts/mason%2Fservicecheck%2F192.168.1.9:22/close?identifierType=alias",
+					Auth: "GenieKey gk",
+					Fields: map[string]any{
+						"source": "192.168.1.9",
+						"note":   "nas ssh up",
+					},
+				},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			url, got := fakeIncidents(t)
+			in, err := tc.incidents(url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, a := range []Alert{down, added, up} {
+				if err := in.Alert(context.Background(), a); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if diff := cmp.Diff(tc.want, *got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestAlert_DedupKey(t *testing.T) {
+	a := Alert{Type: "EventMACConflict", Addr: "192.168.1.1"}
+	if got, want := a.DedupKey(), "mason/EventMACConflict/192.168.1.1"; got != want {
+		t.Fatalf("want %s got %s", want, got)
+	}
+}
+
+func TestNewIncidents_NoKey(t *testing.T) {
+	_, err := NewPagerDuty(IncidentConfig{MinSeverity: "critical"}, time.Second)
+	if !errors.Is(err, ErrNoIncidentKey) {
+		t.Fatalf("want %v got %v", ErrNoIncidentKey, err)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package notify sends alerts to people, as they happen or gathered into a daily digest of
+// new devices, failures and expiring certificates.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// AlertKind is what an alert reports, it decides where an alert is listed in the digest
+type AlertKind string
+
+const (
+	AlertNewDevice AlertKind = "newdevice"
+	AlertFailure   AlertKind = "failure"
+	AlertRecovery  AlertKind = "recovery"
+	AlertWarning   AlertKind = "warning"
+)
+
+// Severity is how urgent an alert is, a chat is sent the alerts at or above its minimum
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+var severities = []Severity{SeverityInfo, SeverityWarning, SeverityCritical}
+
+var (
+	ErrInvalidDigestTime = errors.New("invalid digest time")
+	ErrInvalidTLSMode    = errors.New("invalid tls mode")
+	ErrNoRecipients      = errors.New("no recipients")
+	ErrNoHost            = errors.New("no smtp host")
+	ErrInvalidSeverity   = errors.New("invalid severity")
+	ErrNoWebhook         = errors.New("no webhook url")
+	ErrNoTelegramChat    = errors.New("no telegram token or chat id")
+	ErrNoIncidentKey     = errors.New("no incident service key")
+)
+
+func ParseSeverity(s string) (Severity, error) {
+	for _, sev := range severities {
+		if string(sev) == s {
+			return sev, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q", ErrInvalidSeverity, s)
+}
+
+func (s Severity) level() int {
+	return slices.Index(severities, s)
+}
+
+// Severity of the alert kind, failures are critical and new devices and recoveries are info
+func (k AlertKind) Severity() Severity {
+	switch k {
+	case AlertFailure:
+		return SeverityCritical
+	case AlertWarning:
+		return SeverityWarning
+	}
+	return SeverityInfo
+}
+
+// Alert is an event worth telling someone about, Tags are the tags of the device at Addr.
+// Key is the same on a failure and its recovery.
+type Alert struct {
+	Time    time.Time
+	Kind    AlertKind
+	Type    string
+	Addr    string
+	Key     string
+	Tags    []string
+	Message string
+}
+
+// DedupKey identifies the condition of the alert to an incident service, the type and address
+// of the alert when it has no key of its own
+func (a Alert) DedupKey() string {
+	if a.Key != "" {
+		return "mason/" + a.Key
+	}
+	return "mason/" + a.Type + "/" + a.Addr
+}
+
+// Notifier sends alerts to a channel as they happen
+type Notifier interface {
+	Alert(ctx context.Context, a Alert) error
+}
+
+// Route picks the alerts sent to a channel
+type Route struct {
+	MinSeverity Severity
+	Tags        []string
+}
+
+// Match is true when the alert is at or above the minimum severity and, when the route has
+// tags, its device has one of them
+func (r Route) Match(a Alert) bool {
+	if a.Kind.Severity().level() < r.MinSeverity.level() {
+		return false
+	}
+	if len(r.Tags) == 0 {
+		return true
+	}
+	for _, t := range a.Tags {
+		if slices.Contains(r.Tags, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// CertExpiry is the certificate of an https check, Err is why it could not be read
+type CertExpiry struct {
+	Name     string
+	URL      string
+	Expires  time.Time
+	DaysLeft int
+	Err      string
+}
+
+// Digest is what happened between Start and End
+type Digest struct {
+	Start        time.Time
+	End          time.Time
+	NewDevices   []Alert
+	Failures     []Alert
+	Warnings     []Alert
+	Certificates []CertExpiry
+}
+
+// Empty is true when there is nothing to report
+func (d Digest) Empty() bool {
+	return len(d.NewDevices) == 0 &&
+		len(d.Failures) == 0 &&
+		len(d.Warnings) == 0 &&
+		len(d.Certificates) == 0
+}
+
+// Collector gathers alerts until the digest is taken, recoveries are not gathered as the
+// failure they end is already listed
+type Collector struct {
+	mu    sync.Mutex
+	start time.Time
+	d     Digest
+}
+
+func NewCollector(start time.Time) *Collector {
+	return &Collector{start: start}
+}
+
+func (c *Collector) Add(a Alert) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch a.Kind {
+	case AlertNewDevice:
+		c.d.NewDevices = append(c.d.NewDevices, a)
+	case AlertFailure:
+		c.d.Failures = append(c.d.Failures, a)
+	case AlertWarning:
+		c.d.Warnings = append(c.d.Warnings, a)
+	}
+}
+
+// Take returns the gathered alerts as the digest ending now and starts a new one
+func (c *Collector) Take(now time.Time) Digest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.d
+	d.Start, d.End = c.start, now
+	c.start, c.d = now, Digest{}
+	return d
+}
+
+// NextDigest is the next time of day, after now, the digest is sent at.  The time of day is
+// HH:MM in the location of now.
+func NextDigest(now time.Time, timeOfDay string) (time.Time, error) {
+	tod, err := time.Parse("15:04", timeOfDay)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w %q: %w", ErrInvalidDigestTime, timeOfDay, err)
+	}
+	next := time.Date(
+		now.Year(), now.Month(), now.Day(),
+		tod.Hour(), tod.Minute(), 0, 0,
+		now.Location(),
+	)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next, nil
+}
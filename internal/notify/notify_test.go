@@ -0,0 +1,290 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNextDigest(t *testing.T) {
+	now := time.Date(2024, 6, 3, 10, 30, 0, 0, time.UTC)
+	tests := map[string]struct {
+		tod  string
+		want time.Time
+		err  error
+	}{
+		"LaterToday": {
+			tod:  "18:00",
+			want: time.Date(2024, 6, 3, 18, 0, 0, 0, time.UTC),
+		},
+		"Tomorrow": {
+			tod:  "08:00",
+			want: time.Date(2024, 6, 4, 8, 0, 0, 0, time.UTC),
+		},
+		"Now": {
+			tod:  "10:30",
+			want: time.Date(2024, 6, 4, 10, 30, 0, 0, time.UTC),
+		},
+		"Invalid": {
+			tod: "8am",
+			err: ErrInvalidDigestTime,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := NextDigest(now, tc.tod)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("error: want %v got %v", tc.err, err)
+			}
+			if !got.Equal(tc.want) {
+				t.Fatalf("want %v got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCollector(t *testing.T) {
+	start := time.Date(2024, 6, 3, 8, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	c := NewCollector(start)
+	added := Alert{Kind: AlertNewDevice, Message: "printer"}
+	failed := Alert{Kind: AlertFailure, Message: "web down"}
+	c.Add(added)
+	c.Add(failed)
+	c.Add(Alert{Kind: AlertRecovery, Message: "web up"})
+
+	want := Digest{
+		Start:      start,
+		End:        end,
+		NewDevices: []Alert{added},
+		Failures:   []Alert{failed},
+	}
+	if diff := cmp.Diff(want, c.Take(end)); diff != "" {
+		t.Fatal(diff)
+	}
+	if d := c.Take(end.Add(time.Hour)); !d.Empty() || !d.Start.Equal(end) {
+		t.Fatalf("second digest not empty or wrong start: %+v", d)
+	}
+}
+
+func TestRender(t *testing.T) {
+	at := time.Date(2024, 6, 3, 8, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		template string
+		data     any
+		subject  string
+		body     []string
+	}{
+		"Alert": {
+			template: defaultAlertTemplate,
+			data: Alert{
+				Time:    at,
+				Kind:    AlertFailure,
+				Type:    "EventHTTPCheckChanged",
+				Message: "web https://example.com down: timeout",
+			},
+			subject: "[mason] failure: web https://example.com down: timeout",
+			body:    []string{"2024-06-03 08:00:00 UTC EventHTTPCheckChanged"},
+		},
+		"Digest": {
+			template: defaultDigestTemplate,
+			data: Digest{
+				Start:      at,
+				End:        at.Add(24 * time.Hour),
+				NewDevices: []Alert{{Time: at, Message: "printer [192.168.1.9]"}},
+				Certificates: []CertExpiry{{
+					Name:     "web",
+					URL:      "https://example.com",
+					Expires:  at.Add(10 * 24 * time.Hour),
+					DaysLeft: 10,
+				}},
+			},
+			subject: "[mason] digest 2024-06-04: 1 new devices, 0 failures",
+			body: []string{
+				"New devices (1)\n  06-03 08:00 printer [192.168.1.9]",
+				"Failures (0)\n  none",
+				"web https://example.com expires 2024-06-13 (10 days)",
+			},
+		},
+		"UserTemplate": {
+			template: `{{define "subject"}}
+  alert
+  {{.Addr}}
+{{end}}{{define "body"}}{{.Message}}{{end}}`,
+			data:    Alert{Addr: "192.168.1.9", Message: "hello"},
+			subject: "alert 192.168.1.9",
+			body:    []string{"hello"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "template")
+			err := os.WriteFile(file, []byte(tc.template), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tmpl, err := parseTemplate(name, file, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			subject, body, err := render(tmpl, tc.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if subject != tc.subject {
+				t.Fatalf("subject: want %q got %q", tc.subject, subject)
+			}
+			for _, want := range tc.body {
+				if !strings.Contains(body, want) {
+					t.Fatalf("body missing %q:\n%s", want, body)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTemplate_MissingBody(t *testing.T) {
+	_, err := parseTemplate("alert", "", `{{define "subject"}}x{{end}}`)
+	if err == nil {
+		t.Fatal("want error for a template without a body")
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	at := time.Date(2024, 6, 3, 8, 0, 0, 0, time.UTC)
+	got := string(buildMessage(
+		"mason@localhost",
+		[]string{"a@example.com", "b@example.com"},
+		"down\r\nBcc: evil@example.com",
+		"line one\nline two\n",
+		at,
+	))
+	want := "From: mason@localhost\r\n" +
+		"To: a@example.com, b@example.com\r\n" +
+		"Subject: downBcc: evil@example.com\r\n" +
+		"Date: Mon, 03 Jun 2024 08:00:00 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"Content-Transfer-Encoding: 8bit\r\n" +
+		"\r\n" +
+		"line one\r\nline two\r\n"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestNewEmail(t *testing.T) {
+	tests := map[string]struct {
+		cfg EmailConfig
+		err error
+	}{
+		"Valid": {
+			cfg: EmailConfig{Host: "smtp.example.com", TLS: TLSStartTLS, To: []string{"a@example.com"}},
+		},
+		"BadTLS": {
+			cfg: EmailConfig{Host: "smtp.example.com", TLS: "ssl", To: []string{"a@example.com"}},
+			err: ErrInvalidTLSMode,
+		},
+		"NoHost": {
+			cfg: EmailConfig{TLS: TLSNone, To: []string{"a@example.com"}},
+			err: ErrNoHost,
+		},
+		"NoRecipients": {
+			cfg: EmailConfig{Host: "smtp.example.com", TLS: TLSImplicit},
+			err: ErrNoRecipients,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewEmail(tc.cfg)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("want %v got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+// fakeSMTP accepts one plain smtp session and returns the data of the message it was sent
+func fakeSMTP(t *testing.T) (host string, port int, data <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	out := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tc := textproto.NewConn(conn)
+		tc.PrintfLine("220 fake")
+		for {
+			line, err := tc.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb := strings.ToUpper(strings.Fields(line)[0]); verb {
+			case "EHLO", "HELO", "MAIL", "RCPT":
+				tc.PrintfLine("250 ok")
+			case "DATA":
+				tc.PrintfLine("354 go ahead")
+				b, err := tc.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				out <- string(b)
+				tc.PrintfLine("250 queued")
+			case "QUIT":
+				tc.PrintfLine("221 bye")
+				return
+			default:
+				tc.PrintfLine("502 unknown")
+			}
+		}
+	}()
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, out
+}
+
+func TestEmail_Alert(t *testing.T) {
+	host, port, data := fakeSMTP(t)
+	e, err := NewEmail(EmailConfig{
+		Host:      host,
+		Port:      port,
+		TLS:       TLSNone,
+		From:      "mason@localhost",
+		To:        []string{"ops@example.com"},
+		Immediate: true,
+		Timeout:   5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e.Alert(context.Background(), Alert{
+		Time:    time.Now(),
+		Kind:    AlertNewDevice,
+		Message: "printer [192.168.1.9]",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := <-data
+	if !strings.Contains(got, "Subject: [mason] newdevice: printer [192.168.1.9]") {
+		t.Fatalf("message missing subject:\n%s", got)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// The default templates, a user template replaces them and must define both the subject and
+// the body.  The alert template is given an Alert and the digest template a Digest.
+const (
+	defaultAlertTemplate = `{{define "subject"}}[mason] {{.Kind}}: {{.Message}}{{end}}
+{{define "body"}}{{.Time.Format "2006-01-02 15:04:05 MST"}} {{.Type}}
+{{if .Addr}}Address: {{.Addr}}
+{{end}}
+{{.Message}}
+{{end}}`
+
+	defaultDigestTemplate = `{{define "subject"}}[mason] digest {{.End.Format "2006-01-02"}}: {{len .NewDevices}} new devices, {{len .Failures}} failures{{end}}
+{{define "body"}}Mason digest from {{.Start.Format "2006-01-02 15:04"}} to {{.End.Format "2006-01-02 15:04 MST"}}
+
+New devices ({{len .NewDevices}})
+{{range .NewDevices}}  {{.Time.Format "01-02 15:04"}} {{.Message}}
+{{else}}  none
+{{end}}
+Failures ({{len .Failures}})
+{{range .Failures}}  {{.Time.Format "01-02 15:04"}} {{.Message}}
+{{else}}  none
+{{end}}
+Warnings ({{len .Warnings}})
+{{range .Warnings}}  {{.Time.Format "01-02 15:04"}} {{.Message}}
+{{else}}  none
+{{end}}
+Expiring certificates ({{len .Certificates}})
+{{range .Certificates}}  {{.Name}} {{.URL}} {{if .Err}}unreadable: {{.Err}}{{else}}expires {{.Expires.Format "2006-01-02"}} ({{.DaysLeft}} days){{end}}
+{{else}}  none
+{{end}}{{end}}`
+)
+
+// parseTemplate reads the template file, the fallback template when the file name is blank
+func parseTemplate(name string, file string, fallback string) (*template.Template, error) {
+	text := fallback
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		text = string(b)
+	}
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range []string{"subject", "body"} {
+		if t.Lookup(part) == nil {
+			return nil, fmt.Errorf("%s template does not define %q", name, part)
+		}
+	}
+	return t, nil
+}
+
+// render executes the subject and body of the template, the subject is a single line
+func render(t *template.Template, data any) (subject string, body string, err error) {
+	var b bytes.Buffer
+	err = t.ExecuteTemplate(&b, "subject", data)
+	if err != nil {
+		return "", "", err
+	}
+	subject = strings.Join(strings.Fields(b.String()), " ")
+	b.Reset()
+	err = t.ExecuteTemplate(&b, "body", data)
+	if err != nil {
+		return "", "", err
+	}
+	return subject, b.String(), nil
+}
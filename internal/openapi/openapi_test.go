@@ -0,0 +1,113 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package openapi
+
+import (
+	"net/http"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type testEmbedded struct {
+	Site string
+}
+
+type testItem struct {
+	testEmbedded
+	Name     string
+	Renamed  int  `json:"renamed,omitempty"`
+	Skipped  bool `json:"-"`
+	Addr     netip.Addr
+	Seen     time.Time
+	Every    time.Duration
+	Raw      []byte
+	Children []testChild
+	Labels   map[string]string
+	Parent   *testChild
+	hidden   string
+}
+
+type testChild struct {
+	Value float64
+}
+
+func TestNew(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"}, []Endpoint{
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/items",
+			OperationID: "listItems",
+			Parameters:  []Parameter{StringParameter("q", "query", "", false)},
+			Response:    []testItem{},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/items",
+			OperationID: "saveItem",
+			Request:     testChild{},
+		},
+	})
+
+	want := map[string]*Schema{
+		"testItem": {
+			Type: "object",
+			Properties: map[string]*Schema{
+				"Site":    {Type: "string"},
+				"Name":    {Type: "string"},
+				"renamed": {Type: "integer", Format: "int32"},
+				"Addr":    {Type: "string"},
+				"Seen":    {Type: "string", Format: "date-time"},
+				"Every":   {Type: "integer", Format: "int64", Description: "nanoseconds"},
+				"Raw":     {Type: "string", Format: "byte", Nullable: true},
+				"Children": {
+					Type:     "array",
+					Nullable: true,
+					Items:    &Schema{Ref: "#/components/schemas/testChild"},
+				},
+				"Labels": {
+					Type:                 "object",
+					Nullable:             true,
+					AdditionalProperties: &Schema{Type: "string"},
+				},
+				"Parent": {Ref: "#/components/schemas/testChild"},
+			},
+		},
+		"testChild": {
+			Type:       "object",
+			Properties: map[string]*Schema{"Value": {Type: "number", Format: "double"}},
+		},
+	}
+	if diff := cmp.Diff(want, doc.Components.Schemas); diff != "" {
+		t.Errorf("schemas (-want +got):\n%s", diff)
+	}
+
+	op := doc.Paths["/api/items"]["get"]
+	if op == nil {
+		t.Fatal("operation missing")
+	}
+	wantResponse := &Schema{
+		Type:     "array",
+		Nullable: true,
+		Items:    &Schema{Ref: "#/components/schemas/testItem"},
+	}
+	if diff := cmp.Diff(wantResponse, op.Responses["200"].Content["application/json"].Schema); diff != "" {
+		t.Errorf("response (-want +got):\n%s", diff)
+	}
+
+	op = doc.Paths["/api/items"]["post"]
+	if op == nil || op.RequestBody == nil {
+		t.Fatal("post operation or request body missing")
+	}
+	wantRequest := &Schema{Ref: "#/components/schemas/testChild"}
+	if diff := cmp.Diff(wantRequest, op.RequestBody.Content["application/json"].Schema); diff != "" {
+		t.Errorf("request (-want +got):\n%s", diff)
+	}
+	if _, ok := op.Responses["204"]; !ok {
+		t.Error("want a no content response without a response type")
+	}
+}
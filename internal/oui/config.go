@@ -40,7 +40,7 @@ func SetFlags(fs *pflag.FlagSet, cfg *Config) {
 		configMajorKey,
 		"url",
 		defaultUrl,
-		"url to fetch oui listing if local db is not found",
+		"url (or local file path) to fetch oui listing if local db is not found",
 	)
 	flagset.String(
 		fs,
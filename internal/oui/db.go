@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"io"
 	"net/http"
+	"os"
 	"slices"
 	"strings"
 
@@ -22,8 +23,12 @@ type Entry struct {
 	Name   string
 }
 
-func getdb(url string, filename string) (initialized bool, db []Entry, err error) {
+func getdb(url string, filename string, offline bool) (initialized bool, db []Entry, err error) {
 	if !cachedb.Exists(filename) {
+		if offline && isRemote(url) {
+			log.Warn("offline mode, oui local cache not found, lookups disabled", "filename", filename)
+			return false, db, nil
+		}
 		log.Info("building oui local cache (roughly 10s)")
 		db, err = builddb(url)
 		if err != nil {
@@ -44,7 +49,15 @@ func getdb(url string, filename string) (initialized bool, db []Entry, err error
 	return true, db, nil
 }
 
+func isRemote(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+// download fetches the listing from the url, non http urls are read from the local filesystem
 func download(url string) (dat []byte, err error) {
+	if !isRemote(url) {
+		return os.ReadFile(strings.TrimPrefix(url, "file://"))
+	}
 	resp, err := http.Get(url)
 	if err != nil {
 		return dat, err
@@ -8,6 +8,7 @@ type Options struct {
 	url       string
 	directory string
 	filename  string
+	offline   bool
 }
 
 type Option func(*Options)
@@ -48,3 +49,10 @@ func WithFilename(x string) Option {
 		o.filename = x
 	}
 }
+
+// WithOffline prevents any download of the oui listing, only local files are used
+func WithOffline(x bool) Option {
+	return func(o *Options) {
+		o.offline = x
+	}
+}
@@ -49,12 +49,17 @@ func load(s *store, opts ...Option) {
 	s.filename = datafile
 	s.url = popts.url
 
-	s.initialized, s.db, err = getdb(s.url, s.filename)
+	s.initialized, s.db, err = getdb(s.url, s.filename, popts.offline)
 	if err != nil {
 		log.Fatal("oui load: ", err)
 	}
 }
 
+// Available reports if the oui database has been loaded and lookups will return data
+func Available() bool {
+	return getstore().initialized
+}
+
 func Lookup(mac net.HardwareAddr) (name string) {
 	if len(mac) < 3 {
 		return ""
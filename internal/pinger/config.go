@@ -21,6 +21,12 @@ type Config struct {
 	CheckInterval   time.Duration
 	DefaultInterval time.Duration
 	ServerInterval  time.Duration
+	TcpFallback     bool
+	ArpFallback     bool
+	SharedSocket    bool
+	Rate            int
+	PayloadSize     int
+	DSCP            int
 }
 
 func SetFlags(fs *pflag.FlagSet, cfg *Config) {
@@ -90,4 +96,52 @@ func SetFlags(fs *pflag.FlagSet, cfg *Config) {
 		5*time.Minute,
 		"time between pings for server devices",
 	)
+	flagset.Bool(
+		fs,
+		&cfg.TcpFallback,
+		configMajorKey,
+		"tcpfallback",
+		true,
+		"time tcp connects to the first open port of devices not answering icmp",
+	)
+	flagset.Bool(
+		fs,
+		&cfg.ArpFallback,
+		configMajorKey,
+		"arpfallback",
+		false,
+		"time arp requests to devices on a local segment answering neither icmp nor tcp",
+	)
+	flagset.Bool(
+		fs,
+		&cfg.SharedSocket,
+		configMajorKey,
+		"sharedsocket",
+		true,
+		"send all pings through one shared icmp socket instead of a socket per ping",
+	)
+	flagset.Int(
+		fs,
+		&cfg.Rate,
+		configMajorKey,
+		"rate",
+		1000,
+		"max icmp echo requests sent per second through the shared socket, 0 is unlimited",
+	)
+	flagset.Int(
+		fs,
+		&cfg.PayloadSize,
+		configMajorKey,
+		"payloadsize",
+		0,
+		"icmp echo data bytes, 0 sends a short greeting and 1472 fills a 1500 byte mtu",
+	)
+	flagset.Int(
+		fs,
+		&cfg.DSCP,
+		configMajorKey,
+		"dscp",
+		0,
+		"dscp class the pings are marked with, such as 46 for expedited forwarding",
+	)
 }
@@ -0,0 +1,54 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package pinger
+
+import "time"
+
+// Downsample merges the points, oldest first, into at most limit points of equal time spans
+// so long ranges stay quick to draw.  A merged point keeps the lowest minimum and the highest
+// maximum, percentiles and failure run of its span, the average, jitter and loss are means.
+func Downsample(points []Point, limit int) []Point {
+	if limit <= 0 || len(points) <= limit {
+		return points
+	}
+	first := points[0].Start
+	span := points[len(points)-1].Start.Sub(first)/time.Duration(limit) + 1
+
+	merged := make([]Point, 0, limit)
+	var (
+		bucket  int64 = -1
+		count   int
+		sum     Point
+		current Point
+	)
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		n := time.Duration(count)
+		current.Average = sum.Average / n
+		current.Jitter = sum.Jitter / n
+		current.Loss = sum.Loss / float64(count)
+		merged = append(merged, current)
+	}
+	for _, p := range points {
+		b := int64(p.Start.Sub(first) / span)
+		if b != bucket {
+			flush()
+			bucket, count, sum, current = b, 0, Point{}, p
+		}
+		count++
+		sum.Average += p.Average
+		sum.Jitter += p.Jitter
+		sum.Loss += p.Loss
+		current.Minimum = min(current.Minimum, p.Minimum)
+		current.Maximum = max(current.Maximum, p.Maximum)
+		current.P95 = max(current.P95, p.P95)
+		current.P99 = max(current.P99, p.P99)
+		current.ConsecutiveFailures = max(current.ConsecutiveFailures, p.ConsecutiveFailures)
+	}
+	flush()
+	return merged
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package pinger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestDownsample(t *testing.T) {
+	start := time.Date(2024, 6, 3, 10, 0, 0, 0, time.UTC)
+	point := func(minute int, avg time.Duration, loss float64, failures int) Point {
+		return Point{
+			Start:               start.Add(time.Duration(minute) * time.Minute),
+			Minimum:             avg / 2,
+			Average:             avg,
+			Maximum:             avg * 2,
+			P95:                 avg * 2,
+			P99:                 avg * 2,
+			Jitter:              avg / 4,
+			Loss:                loss,
+			ConsecutiveFailures: failures,
+		}
+	}
+	points := []Point{
+		point(0, 10*time.Millisecond, 0, 0),
+		point(1, 30*time.Millisecond, 0.5, 2),
+		point(2, 20*time.Millisecond, 0, 0),
+		point(3, 40*time.Millisecond, 0.25, 1),
+	}
+
+	tests := map[string]struct {
+		limit int
+		want  []Point
+	}{
+		"UnderLimit": {
+			limit: 4,
+			want:  points,
+		},
+		"NoLimit": {
+			limit: 0,
+			want:  points,
+		},
+		"Halved": {
+			limit: 2,
+			want: []Point{
+				{
+					Start:               points[0].Start,
+					Minimum:             5 * time.Millisecond,
+					Average:             20 * time.Millisecond,
+					Maximum:             60 * time.Millisecond,
+					P95:                 60 * time.Millisecond,
+					P99:                 60 * time.Millisecond,
+					Jitter:              5 * time.Millisecond,
+					Loss:                0.25,
+					ConsecutiveFailures: 2,
+				},
+				{
+					Start:               points[2].Start,
+					Minimum:             10 * time.Millisecond,
+					Average:             30 * time.Millisecond,
+					Maximum:             80 * time.Millisecond,
+					P95:                 80 * time.Millisecond,
+					P99:                 80 * time.Millisecond,
+					Jitter:              7500 * time.Microsecond,
+					Loss:                0.125,
+					ConsecutiveFailures: 1,
+				},
+			},
+		},
+		"Single": {
+			limit: 1,
+			want: []Point{
+				{
+					Start:               points[0].Start,
+					Minimum:             5 * time.Millisecond,
+					Average:             25 * time.Millisecond,
+					Maximum:             80 * time.Millisecond,
+					P95:                 80 * time.Millisecond,
+					P99:                 80 * time.Millisecond,
+					Jitter:              6250 * time.Microsecond,
+					Loss:                0.1875,
+					ConsecutiveFailures: 2,
+				},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := Downsample(points, tc.limit)
+			if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreFields(Point{}, "Device")); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
@@ -7,10 +7,12 @@ package pinger
 import (
 	"context"
 	"errors"
+	"net/netip"
 	"time"
 
 	"github.com/emicklei/tre"
 
+	"github.com/networkables/mason/internal/logging"
 	"github.com/networkables/mason/internal/model"
 	"github.com/networkables/mason/nettools"
 )
@@ -30,6 +32,11 @@ type (
 		Average time.Duration
 		Maximum time.Duration
 		Loss    float64
+		// Jitter, P95 and P99 show the latency spread the mean and maximum hide
+		Jitter              time.Duration
+		P95                 time.Duration
+		P99                 time.Duration
+		ConsecutiveFailures int
 	}
 
 	PerformancePingResponseEvent struct {
@@ -40,8 +47,11 @@ type (
 	}
 )
 
+// BuildPingDevice returns the performance ping of a device, the echoes go through the
+// shared listener when one is given
 func BuildPingDevice(
 	cfg *Config,
+	listener *nettools.IcmpListener,
 ) func(context.Context, model.Device) (PerformancePingResponseEvent, error) {
 	return func(ctx context.Context, d model.Device) (pre PerformancePingResponseEvent, err error) {
 		responses, err := nettools.Icmp4Echo(
@@ -50,12 +60,38 @@ func BuildPingDevice(
 			nettools.I4EWithCount(cfg.PingCount),
 			nettools.I4EWithReadTimeout(cfg.Timeout),
 			nettools.I4EWithPrivileged(cfg.Privileged),
+			nettools.I4EWithPayloadSize(cfg.PayloadSize),
+			nettools.I4EWithDSCP(cfg.DSCP),
+			nettools.I4EWithListener(listener),
 		)
+		traceEchoes(d.Addr, "icmp echo", responses, err)
 		if err != nil && !errors.Is(err, nettools.ErrNoResponseFromRemote) {
 			return pre, tre.New(err, "icmp4 echo")
 		}
 		stats := nettools.CalculateIcmp4EchoResponseStatistics(responses)
+		probe := model.PingProbeICMP
+		if stats.SuccessCount == 0 && cfg.TcpFallback && !d.Server.Ports.IsEmpty() {
+			stats, err = tcpPingDevice(ctx, cfg, d)
+			if err != nil {
+				return pre, err
+			}
+			probe = model.PingProbeTCP
+		}
+		if stats.SuccessCount == 0 && cfg.ArpFallback {
+			arpStats, err := arpPingDevice(ctx, cfg, d)
+			switch {
+			case errors.Is(err, nettools.ErrNotOnLink), errors.Is(err, nettools.ErrIPv6Unsupported):
+				// arp does not reach the device, the failed ping stands
+			case err != nil:
+				return pre, err
+			default:
+				stats, probe = arpStats, model.PingProbeARP
+			}
+		}
 		d.UpdateFromPingStats(stats, stats.Start)
+		if stats.SuccessCount > 0 {
+			d.PerformancePing.Probe = probe
+		}
 		pre = PerformancePingResponseEvent{
 			Start:    stats.Start,
 			Device:   d,
@@ -67,19 +103,86 @@ func BuildPingDevice(
 	}
 }
 
-func PerformancePingerFilter(cfg *Config) model.DeviceFilter {
+// tcpPingDevice times connects to the first open port found by the port scan, for devices
+// which drop icmp but run a service
+func tcpPingDevice(
+	ctx context.Context,
+	cfg *Config,
+	d model.Device,
+) (nettools.Icmp4EchoResponseStatistics, error) {
+	target := netip.AddrPortFrom(d.Addr.Addr(), uint16(d.Server.Ports.Ports[0]))
+	responses, err := nettools.TcpPing(
+		ctx,
+		target,
+		nettools.I4EWithCount(cfg.PingCount),
+		nettools.I4EWithReadTimeout(cfg.Timeout),
+	)
+	traceEchoes(target, "tcp connect", responses, err)
+	if err != nil && !errors.Is(err, nettools.ErrNoResponseFromRemote) {
+		return nettools.Icmp4EchoResponseStatistics{}, tre.New(err, "tcp ping")
+	}
+	return nettools.CalculateIcmp4EchoResponseStatistics(responses), nil
+}
+
+// arpPingDevice times arp requests to a device on a local segment, for devices which drop
+// icmp and run no service.  The round trip includes the kernel's handling of the request so it
+// reads a little higher than an echo would.
+func arpPingDevice(
+	ctx context.Context,
+	cfg *Config,
+	d model.Device,
+) (nettools.Icmp4EchoResponseStatistics, error) {
+	responses, err := nettools.ArpPing(
+		ctx,
+		d.Addr.Addr(),
+		nettools.I4EWithCount(cfg.PingCount),
+		nettools.I4EWithReadTimeout(cfg.Timeout),
+	)
+	if errors.Is(err, nettools.ErrNotOnLink) || errors.Is(err, nettools.ErrIPv6Unsupported) {
+		return nettools.Icmp4EchoResponseStatistics{}, err
+	}
+	traceEchoes(d.Addr, "arp request", responses, err)
+	if err != nil && !errors.Is(err, nettools.ErrNoResponseFromRemote) {
+		return nettools.Icmp4EchoResponseStatistics{}, tre.New(err, "arp ping")
+	}
+	return nettools.CalculateIcmp4EchoResponseStatistics(responses), nil
+}
+
+// traceEchoes traces each echo of a ping, or the error of a ping which sent none
+func traceEchoes(
+	target any,
+	probe string,
+	responses []nettools.Icmp4EchoResponse,
+	err error,
+) {
+	if len(responses) == 0 {
+		logging.Probe(logging.Pinger, target, probe, 0, err)
+		return
+	}
+	for _, r := range responses {
+		logging.Probe(logging.Pinger, target, probe, r.Elapsed, r.Err)
+	}
+}
+
+// PerformancePingerFilter selects the devices due for a ping, the policy lookup may be nil
+// when only the global intervals apply
+func PerformancePingerFilter(cfg *Config, policy model.PolicyLookup) model.DeviceFilter {
 	return func(d model.Device) bool {
 		if d.PerformancePing.LastSeen.IsZero() {
 			return true
 		}
+		var override time.Duration
+		if policy != nil {
+			override = policy(d).PingInterval
+		}
 		since := time.Since(d.PerformancePing.LastSeen)
 		if d.IsServer() {
-			if since > cfg.ServerInterval {
+			if since > model.IntervalOrDefault(override, cfg.ServerInterval) {
 				return true
 			}
 			return false
 		}
-		if since > cfg.DefaultInterval {
+		if since > model.IntervalOrDefault(override, cfg.DefaultInterval) {
 			return true
 		}
 		return false
@@ -7,22 +7,44 @@ package pinger
 import (
 	"context"
 
-	"github.com/charmbracelet/log"
-
+	"github.com/networkables/mason/internal/logging"
 	"github.com/networkables/mason/internal/model"
 	"github.com/networkables/mason/internal/workerpool"
+	"github.com/networkables/mason/nettools"
 )
 
+var logger = logging.For(logging.Pinger)
+
 type Worker struct {
 	In chan model.Device
 	*workerpool.Pool[model.Device, PerformancePingResponseEvent]
+	// PingDevice pings a device through the same socket as the pool
+	PingDevice func(context.Context, model.Device) (PerformancePingResponseEvent, error)
+	listener   *nettools.IcmpListener
 }
 
 func NewWorker(cfg *Config) *Worker {
 	input := make(chan model.Device)
+	var listener *nettools.IcmpListener
+	if cfg.Enabled && cfg.SharedSocket {
+		var err error
+		listener, err = nettools.NewIcmpListener(
+			cfg.Rate,
+			nettools.I4EWithPrivileged(cfg.Privileged),
+			nettools.I4EWithPayloadSize(cfg.PayloadSize),
+			nettools.I4EWithDSCP(cfg.DSCP),
+		)
+		if err != nil {
+			logger.Warn("shared icmp socket unavailable, using a socket per ping", "error", err)
+			listener = nil
+		}
+	}
+	ping := BuildPingDevice(cfg, listener)
 	return &Worker{
-		In:   input,
-		Pool: workerpool.New("pinger", input, BuildPingDevice(cfg)),
+		In:         input,
+		Pool:       workerpool.New("pinger", input, ping),
+		PingDevice: ping,
+		listener:   listener,
 	}
 }
 
@@ -31,6 +53,9 @@ func (w *Worker) Run(ctx context.Context, max int) {
 }
 
 func (w *Worker) Close() {
-	log.Info("pinger workerpool shutdown")
+	logger.Info("pinger workerpool shutdown")
 	close(w.In)
+	if w.listener != nil {
+		w.listener.Close()
+	}
 }
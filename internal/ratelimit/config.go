@@ -0,0 +1,63 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/networkables/mason/internal/flagset"
+)
+
+type Config struct {
+	Global     int
+	PerNetwork int
+	Jitter     time.Duration
+}
+
+func SetFlags(fs *pflag.FlagSet, cfg *Config) {
+	configMajorKey := "ratelimit"
+
+	flagset.Int(
+		fs,
+		&cfg.Global,
+		configMajorKey,
+		"global",
+		0,
+		"max probes per second across all discovery and port scans, 0 for unlimited",
+	)
+	flagset.Int(
+		fs,
+		&cfg.PerNetwork,
+		configMajorKey,
+		"pernetwork",
+		0,
+		"max probes per second sent into a single network, 0 for unlimited",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Jitter,
+		configMajorKey,
+		"jitter",
+		0,
+		"random delay of up to this duration added before each probe",
+	)
+}
+
+// Rate is the most probes per second sent into a single network, the lower of the global and
+// per network limits, 0 when neither is set
+func (c *Config) Rate() int {
+	if c == nil {
+		return 0
+	}
+	switch {
+	case c.Global <= 0:
+		return max(c.PerNetwork, 0)
+	case c.PerNetwork <= 0:
+		return c.Global
+	}
+	return min(c.Global, c.PerNetwork)
+}
@@ -0,0 +1,218 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package ratelimit paces the probes sent by discovery and port scans so they stay under
+// IDS thresholds and do not saturate slow links
+package ratelimit
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Limiter hands out tokens at a fixed rate, spaced evenly so probes are never sent in bursts
+type Limiter struct {
+	name     string
+	rate     int
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+
+	waiting atomic.Int64
+	granted atomic.Uint64
+}
+
+// NewLimiter creates a limiter allowing rate tokens per second, a rate of zero or less
+// never blocks
+func NewLimiter(name string, rate int) *Limiter {
+	l := &Limiter{
+		name: name,
+		rate: rate,
+	}
+	if rate > 0 {
+		l.interval = time.Second / time.Duration(rate)
+	}
+	return l
+}
+
+// reserve claims the next token and returns how long the caller must wait for it
+func (l *Limiter) reserve(now time.Time) time.Duration {
+	if l.interval == 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	return delay
+}
+
+// refund gives back a token of a wait given up before its turn, so the waits reserved after it
+// are not held up by a probe which is never sent
+func (l *Limiter) refund() {
+	if l.interval == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.next = l.next.Add(-l.interval)
+}
+
+func refund(limiters []*Limiter) {
+	for _, l := range limiters {
+		l.refund()
+	}
+}
+
+// Wait blocks until a token is available or the context is done
+func (l *Limiter) Wait(ctx context.Context) error {
+	return wait(ctx, l.reserve(time.Now()), l)
+}
+
+// Stats is a point in time view of a limiter
+type Stats struct {
+	Name    string
+	Rate    int
+	Waiting int64
+	Granted uint64
+}
+
+func (l *Limiter) Stats() Stats {
+	return Stats{
+		Name:    l.name,
+		Rate:    l.rate,
+		Waiting: l.waiting.Load(),
+		Granted: l.granted.Load(),
+	}
+}
+
+func wait(ctx context.Context, delay time.Duration, limiters ...*Limiter) error {
+	for _, l := range limiters {
+		l.waiting.Add(1)
+		defer l.waiting.Add(-1)
+	}
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			refund(limiters)
+			return ctx.Err()
+		case <-t.C:
+		}
+	} else if ctx.Err() != nil {
+		refund(limiters)
+		return ctx.Err()
+	}
+	for _, l := range limiters {
+		l.granted.Add(1)
+	}
+	return nil
+}
+
+// Group holds the global limiter and a limiter for each network probes are sent into
+type Group struct {
+	cfg    *Config
+	global *Limiter
+
+	mu       sync.Mutex
+	networks map[netip.Prefix]*Limiter
+}
+
+func NewGroup(cfg *Config) *Group {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	return &Group{
+		cfg:      cfg,
+		global:   NewLimiter("Global", cfg.Global),
+		networks: make(map[netip.Prefix]*Limiter),
+	}
+}
+
+// Network returns the waiter used for probes sent into the network, the network is
+// remembered so later Addr lookups within it share the same limiter
+func (g *Group) Network(prefix netip.Prefix) Waiter {
+	if g == nil {
+		return Waiter{}
+	}
+	prefix = prefix.Masked()
+	g.mu.Lock()
+	l, ok := g.networks[prefix]
+	if !ok {
+		l = NewLimiter(prefix.String(), g.cfg.PerNetwork)
+		g.networks[prefix] = l
+	}
+	g.mu.Unlock()
+	return Waiter{limiters: []*Limiter{g.global, l}, jitter: g.cfg.Jitter}
+}
+
+// Addr returns the waiter used for probes sent to the address, an address outside of any
+// known network is only held to the global limit
+func (g *Group) Addr(addr netip.Addr) Waiter {
+	if g == nil {
+		return Waiter{}
+	}
+	g.mu.Lock()
+	var found *Limiter
+	bits := -1
+	for prefix, l := range g.networks {
+		if prefix.Contains(addr) && prefix.Bits() > bits {
+			found, bits = l, prefix.Bits()
+		}
+	}
+	g.mu.Unlock()
+	if found == nil {
+		return Waiter{limiters: []*Limiter{g.global}, jitter: g.cfg.Jitter}
+	}
+	return Waiter{limiters: []*Limiter{g.global, found}, jitter: g.cfg.Jitter}
+}
+
+// Stats returns the global limiter followed by the network limiters
+func (g *Group) Stats() []Stats {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ret := make([]Stats, 0, len(g.networks)+1)
+	ret = append(ret, g.global.Stats())
+	for _, l := range g.networks {
+		ret = append(ret, l.Stats())
+	}
+	slices.SortFunc(ret[1:], func(a, b Stats) int { return strings.Compare(a.Name, b.Name) })
+	return ret
+}
+
+// Waiter paces a probe against every limiter it applies to, plus the configured jitter
+type Waiter struct {
+	limiters []*Limiter
+	jitter   time.Duration
+}
+
+// Wait blocks until every limiter has a token for the probe, the longest reservation wins
+func (w Waiter) Wait(ctx context.Context) error {
+	now := time.Now()
+	var delay time.Duration
+	for _, l := range w.limiters {
+		d := l.reserve(now)
+		if d > delay {
+			delay = d
+		}
+	}
+	if w.jitter > 0 {
+		delay += rand.N(w.jitter)
+	}
+	return wait(ctx, delay, w.limiters...)
+}
@@ -0,0 +1,169 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLimiter_Reserve(t *testing.T) {
+	l := NewLimiter("test", 10)
+	now := time.Now()
+	want := []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond}
+	got := make([]time.Duration, 0, len(want))
+	for range want {
+		got = append(got, l.reserve(now))
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("reserve mismatch (-want +got):\n%s", diff)
+	}
+
+	// an idle limiter does not bank tokens for a burst
+	if d := l.reserve(now.Add(time.Second)); d != 0 {
+		t.Fatalf("idle reserve: want 0, got %s", d)
+	}
+	if d := l.reserve(now.Add(time.Second)); d != 100*time.Millisecond {
+		t.Fatalf("reserve after idle: want 100ms, got %s", d)
+	}
+}
+
+func TestLimiter_Unlimited(t *testing.T) {
+	l := NewLimiter("test", 0)
+	for range 100 {
+		if d := l.reserve(time.Now()); d != 0 {
+			t.Fatalf("unlimited reserve: want 0, got %s", d)
+		}
+	}
+}
+
+func TestLimiter_WaitCancelled(t *testing.T) {
+	l := NewLimiter("test", 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := l.Wait(ctx); err != nil {
+		t.Fatalf("first wait: %v", err)
+	}
+	cancel()
+	if err := l.Wait(ctx); err == nil {
+		t.Fatal("expected cancelled wait to fail")
+	}
+	want := Stats{Name: "test", Rate: 1, Waiting: 0, Granted: 1}
+	if diff := cmp.Diff(want, l.Stats()); diff != "" {
+		t.Fatalf("stats mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLimiter_WaitCancelledRefunds(t *testing.T) {
+	l := NewLimiter("test", 10)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("first wait: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	const waiters = 50
+	var wg sync.WaitGroup
+	for range waiters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Wait(ctx)
+		}()
+	}
+	for l.Stats().Waiting < waiters {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+
+	// the cancelled waits gave their tokens back, the next is due once the first one's
+	// interval has passed rather than after all of theirs
+	time.Sleep(l.interval)
+	ctx, cancel = context.WithTimeout(context.Background(), l.interval/2)
+	defer cancel()
+	if err := l.Wait(ctx); err != nil {
+		t.Fatalf("wait after cancelled waits: %v", err)
+	}
+}
+
+func TestGroup_Addr(t *testing.T) {
+	g := NewGroup(&Config{Global: 100, PerNetwork: 10})
+	g.Network(netip.MustParsePrefix("192.168.0.0/16"))
+	g.Network(netip.MustParsePrefix("192.168.1.7/24"))
+
+	type test struct {
+		addr netip.Addr
+		want []string
+	}
+	tests := map[string]test{
+		"MostSpecific": {
+			addr: netip.MustParseAddr("192.168.1.20"),
+			want: []string{"Global", "192.168.1.0/24"},
+		},
+		"Wider": {
+			addr: netip.MustParseAddr("192.168.2.20"),
+			want: []string{"Global", "192.168.0.0/16"},
+		},
+		"Unknown": {
+			addr: netip.MustParseAddr("10.0.0.1"),
+			want: []string{"Global"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := g.Addr(tc.addr)
+			got := make([]string, len(w.limiters))
+			for i, l := range w.limiters {
+				got[i] = l.name
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("limiters mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	stats := g.Stats()
+	names := make([]string, len(stats))
+	for i, s := range stats {
+		names[i] = s.Name
+	}
+	want := []string{"Global", "192.168.0.0/16", "192.168.1.0/24"}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Fatalf("stats mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGroup_Nil(t *testing.T) {
+	var g *Group
+	if err := g.Addr(netip.MustParseAddr("10.0.0.1")).Wait(context.Background()); err != nil {
+		t.Fatalf("nil group wait: %v", err)
+	}
+	if g.Stats() != nil {
+		t.Fatal("expected nil stats from nil group")
+	}
+}
+
+func TestConfig_Rate(t *testing.T) {
+	tests := map[string]struct {
+		cfg  *Config
+		want int
+	}{
+		"Nil":        {cfg: nil, want: 0},
+		"Unlimited":  {cfg: &Config{}, want: 0},
+		"Global":     {cfg: &Config{Global: 100}, want: 100},
+		"PerNetwork": {cfg: &Config{PerNetwork: 20}, want: 20},
+		"Lower":      {cfg: &Config{Global: 100, PerNetwork: 200}, want: 100},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.cfg.Rate(); got != tc.want {
+				t.Errorf("want: %d, got: %d", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package redact anonymizes inventory data so it can be shared without leaking the network layout
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/netip"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+var (
+	// public ipv4 addresses are remapped into the benchmarking range (198.18.0.0/15)
+	ipv4Replacement = netip.MustParsePrefix("198.18.0.0/15")
+	// public ipv6 addresses are remapped into the documentation range (2001:db8::/32)
+	ipv6Replacement = netip.MustParsePrefix("2001:db8::/32")
+)
+
+// Redactor replaces identifying values with keyed hashes.  The same key always produces the
+// same replacement, so separate exports made with one key can still be correlated.
+type Redactor struct {
+	key []byte
+}
+
+func New(key string) *Redactor {
+	return &Redactor{key: []byte(key)}
+}
+
+func (r *Redactor) sum(kind string, val []byte) []byte {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(kind))
+	mac.Write(val)
+	return mac.Sum(nil)
+}
+
+// Name replaces a hostname with a stable pseudonym
+func (r *Redactor) Name(name string) string {
+	if name == "" {
+		return name
+	}
+	return "host-" + hex.EncodeToString(r.sum("name", []byte(name))[:4])
+}
+
+// MAC replaces the hardware address, the result is flagged as locally administered
+func (r *Redactor) MAC(m model.MAC) model.MAC {
+	if m.IsEmpty() {
+		return m
+	}
+	sum := r.sum("mac", m.M)
+	hw := make(net.HardwareAddr, len(m.M))
+	copy(hw, sum)
+	hw[0] = (hw[0] | 0x02) &^ 0x01
+	return model.HardwareAddrToMAC(hw)
+}
+
+// Addr replaces public addresses, private/loopback/link-local addresses are kept as-is
+// since they do not identify the network they belong to.
+func (r *Redactor) Addr(a model.Addr) model.Addr {
+	addr := a.Addr()
+	if !isPublic(addr) {
+		return a
+	}
+	return model.AddrToModelAddr(r.addr(addr))
+}
+
+// Prefix replaces public prefixes while keeping the prefix length
+func (r *Redactor) Prefix(p model.Prefix) model.Prefix {
+	if !isPublic(p.P.Addr()) {
+		return p
+	}
+	addr := r.addr(p.P.Addr())
+	np, _ := addr.Prefix(p.P.Bits())
+	return model.PrefixToModelPrefix(np)
+}
+
+func (r *Redactor) addr(addr netip.Addr) netip.Addr {
+	replacement := ipv4Replacement
+	if !addr.Is4() {
+		replacement = ipv6Replacement
+	}
+	sum := r.sum("addr", addr.AsSlice())
+	base := replacement.Addr().AsSlice()
+	bits := replacement.Bits()
+	for i := range base {
+		keep := bits - i*8
+		switch {
+		case keep >= 8:
+			continue
+		case keep <= 0:
+			base[i] = sum[i]
+		default:
+			mask := byte(0xff) << (8 - keep)
+			base[i] = base[i]&mask | sum[i]&^mask
+		}
+	}
+	out, _ := netip.AddrFromSlice(base)
+	return out
+}
+
+func isPublic(addr netip.Addr) bool {
+	return addr.IsValid() &&
+		addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!ipv4Replacement.Contains(addr) &&
+		!ipv6Replacement.Contains(addr)
+}
+
+// Device returns a copy of the device with identifying fields replaced
+func (r *Redactor) Device(d model.Device) model.Device {
+	d.Name = r.Name(d.Name)
+	d.Addr = r.Addr(d.Addr)
+	d.Addresses = r.deviceAddrs(d.Addresses)
+	d.MAC = r.MAC(d.MAC)
+	d.ObservedMACs = r.macs(d.ObservedMACs)
+	d.Meta.DnsName = r.Name(d.Meta.DnsName)
+	d.Meta.MDNSName = r.Name(d.Meta.MDNSName)
+	d.Meta.DHCPHostname = r.Name(d.Meta.DHCPHostname)
+	d.Meta.DHCPFingerprint = ""
+	d.Meta.DHCPVendorClass = ""
+	d.SNMP.Name = r.Name(d.SNMP.Name)
+	d.SNMP.Description = ""
+	d.SNMP.Community = ""
+	d.Meta.Owner = ""
+	d.Meta.Notes = ""
+	d.Meta.Location = ""
+	d.Meta.Site = r.Name(d.Meta.Site)
+	d.VLAN.Name = r.Name(d.VLAN.Name)
+	d.Meta.Serial = ""
+	d.Meta.AssetTag = ""
+	d.Virtual.Guests = r.guests(d.Virtual.Guests)
+	d.Virtual.Parent = r.Addr(d.Virtual.Parent)
+	d.Link.SSID = r.Name(d.Link.SSID)
+	d.Link.Uplink = r.Name(d.Link.Uplink)
+	d.Link.UplinkMAC = r.macString(d.Link.UplinkMAC)
+	return d
+}
+
+// guests replaces the names, MACs and addrs of the guests in a new slice
+func (r *Redactor) guests(gs model.Guests) model.Guests {
+	if gs == nil {
+		return nil
+	}
+	out := make(model.Guests, len(gs))
+	for i, g := range gs {
+		g.Name = r.Name(g.Name)
+		g.MAC = r.macString(g.MAC)
+		g.Addr = r.addrString(g.Addr)
+		out[i] = g
+	}
+	return out
+}
+
+// macString replaces a hardware address kept as text, text which is not one is dropped
+func (r *Redactor) macString(s string) string {
+	if s == "" {
+		return s
+	}
+	m, err := model.ParseMAC(s)
+	if err != nil {
+		return ""
+	}
+	return r.MAC(m).String()
+}
+
+// addrString replaces an address kept as text, text which is not one is dropped
+func (r *Redactor) addrString(s string) string {
+	if s == "" {
+		return s
+	}
+	a, err := model.ParseAddr(s)
+	if err != nil {
+		return ""
+	}
+	return r.Addr(a).String()
+}
+
+// deviceAddrs replaces each of the additional addresses in a new slice
+func (r *Redactor) deviceAddrs(as model.DeviceAddrs) model.DeviceAddrs {
+	if as == nil {
+		return nil
+	}
+	out := make(model.DeviceAddrs, len(as))
+	for i, a := range as {
+		a.Addr = r.Addr(a.Addr)
+		out[i] = a
+	}
+	return out
+}
+
+// macs replaces each of the hardware addresses in a new slice, the device copy shares its
+// slices with the stored device
+func (r *Redactor) macs(ms []model.MAC) []model.MAC {
+	if ms == nil {
+		return nil
+	}
+	out := make([]model.MAC, len(ms))
+	for i, m := range ms {
+		out[i] = r.MAC(m)
+	}
+	return out
+}
+
+// Network returns a copy of the network with identifying fields replaced
+func (r *Redactor) Network(n model.Network) model.Network {
+	n.Name = r.Name(n.Name)
+	n.Prefix = r.Prefix(n.Prefix)
+	n.Site = r.Name(n.Site)
+	n.VLAN.Name = r.Name(n.VLAN.Name)
+	return n
+}
@@ -0,0 +1,307 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package redact
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestRedactor_Addr(t *testing.T) {
+	r := New("key")
+	tests := map[string]struct {
+		addr    string
+		changed bool
+	}{
+		"Private":   {addr: "192.168.1.10", changed: false},
+		"Loopback":  {addr: "127.0.0.1", changed: false},
+		"LinkLocal": {addr: "fe80::1", changed: false},
+		"PublicV4":  {addr: "8.8.8.8", changed: true},
+		"PublicV6":  {addr: "2606:4700::1111", changed: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			in := model.MustParseAddr(tc.addr)
+			got := r.Addr(in)
+			if changed := got.Compare(in) != 0; changed != tc.changed {
+				t.Fatalf("changed want: %v, got %v (%s)", tc.changed, changed, got)
+			}
+			if !tc.changed {
+				return
+			}
+			if got.Compare(r.Addr(in)) != 0 {
+				t.Errorf("replacement not consistent: %s", got)
+			}
+			if !ipv4Replacement.Contains(got.Addr()) && !ipv6Replacement.Contains(got.Addr()) {
+				t.Errorf("replacement outside of reserved range: %s", got)
+			}
+		})
+	}
+}
+
+func TestRedactor_Consistent(t *testing.T) {
+	a := New("key")
+	b := New("other")
+	mac := model.MustParseMAC("00:11:22:33:44:55")
+
+	if a.MAC(mac).String() != a.MAC(mac).String() {
+		t.Errorf("mac replacement not consistent")
+	}
+	if a.MAC(mac).String() == b.MAC(mac).String() {
+		t.Errorf("mac replacement does not depend on key")
+	}
+	if a.MAC(mac).M[0]&0x02 == 0 {
+		t.Errorf("mac replacement not locally administered: %s", a.MAC(mac))
+	}
+	if a.Name("router.lan") != a.Name("router.lan") {
+		t.Errorf("name replacement not consistent")
+	}
+	if a.Name("") != "" {
+		t.Errorf("empty name should stay empty")
+	}
+}
+
+func TestRedactor_Device(t *testing.T) {
+	r := New("key")
+	dev := model.Device{
+		Name: "laptop.home",
+		Addr: model.MustParseAddr("192.168.1.10"),
+		MAC:  model.MustParseMAC("00:11:22:33:44:55"),
+		Meta: model.Meta{
+			DnsName:      "laptop.home",
+			Manufacturer: "Acme",
+			Owner:        "alice",
+			Notes:        "behind the sofa",
+			Serial:       "SN123",
+			AssetTag:     "IT-0042",
+		},
+		SNMP: model.SNMP{Community: "secret", Description: "Linux laptop 6.1"},
+	}
+	got := r.Device(dev)
+	if got.Name == dev.Name || got.Meta.DnsName == dev.Meta.DnsName {
+		t.Errorf("names not redacted: %s %s", got.Name, got.Meta.DnsName)
+	}
+	if got.MAC.String() == dev.MAC.String() {
+		t.Errorf("mac not redacted")
+	}
+	if got.SNMP.Community != "" || got.SNMP.Description != "" {
+		t.Errorf("snmp details not redacted")
+	}
+	if got.Meta.Owner != "" || got.Meta.Notes != "" {
+		t.Errorf("owner and notes not redacted")
+	}
+	if got.Meta.Serial != "" || got.Meta.AssetTag != "" {
+		t.Errorf("asset identifiers not redacted")
+	}
+	if got.Meta.Manufacturer != dev.Meta.Manufacturer {
+		t.Errorf("manufacturer should be kept")
+	}
+}
+
+// fullDevice has every field set, the identifying values are the ones listed in
+// identifyingValues
+func fullDevice() model.Device {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return model.Device{
+		Name: "laptop.home",
+		Addr: model.MustParseAddr("203.0.113.10"),
+		Addresses: model.DeviceAddrs{
+			{Addr: model.MustParseAddr("2606:4700::10"), LastSeen: ts},
+		},
+		MAC:          model.MustParseMAC("00:11:22:33:44:55"),
+		ObservedMACs: []model.MAC{model.MustParseMAC("66:77:88:99:aa:bb")},
+		DiscoveredAt: ts,
+		DiscoveredBy: "arp",
+		VLAN:         model.VLAN{ID: 20, Name: "alices-office"},
+		Meta: model.Meta{
+			DnsName:         "laptop-dns.home",
+			Manufacturer:    "Acme",
+			Tags:            model.Tags{{Val: "work"}},
+			Policy:          model.MonitoringPolicy{PingInterval: time.Minute, PortScanInterval: time.Hour},
+			Approval:        model.ApprovalApproved,
+			Owner:           "Alice Example",
+			Notes:           "behind the sofa",
+			Site:            "alices-house",
+			DeviceType:      model.DeviceTypePhone,
+			Location:        "second floor cupboard",
+			PurchaseDate:    "2023-01-31",
+			Serial:          "SN123",
+			AssetTag:        "IT-0042",
+			MDNSName:        "alices-laptop.local",
+			DHCPHostname:    "alices-dhcp-laptop",
+			DHCPFingerprint: "1,121,3,6,15,119,252",
+			DHCPVendorClass: "MSFT 5.0",
+		},
+		Server: model.Server{
+			Ports:    model.PortList{Ports: []int{22, 80}},
+			Banners:  model.Banners{{Port: 22, Text: "SSH-2.0-OpenSSH_9.6"}},
+			LastScan: ts,
+		},
+		PerformancePing: model.Pinger{
+			FirstSeen:  ts,
+			LastSeen:   ts,
+			Mean:       time.Millisecond,
+			Maximum:    2 * time.Millisecond,
+			LastFailed: true,
+			Probe:      model.PingProbeICMP,
+		},
+		SNMP: model.SNMP{
+			Name:               "laptop-snmp",
+			Description:        "Linux laptop 6.1",
+			Community:          "secret",
+			Port:               161,
+			LastSNMPCheck:      ts,
+			HasArpTable:        true,
+			LastArpTableScan:   ts,
+			HasInterfaces:      true,
+			LastInterfacesScan: ts,
+		},
+		Virtual: model.Virtual{
+			Platform: model.VirtualPlatformDocker,
+			Guests: model.Guests{{
+				ID:    "c0ffee",
+				Name:  "guest-web",
+				Kind:  model.GuestContainer,
+				State: "running",
+				MAC:   "02:42:ac:11:00:02",
+				Addr:  "203.0.113.20",
+			}},
+			LastScan: ts,
+			Parent:   model.MustParseAddr("203.0.113.30"),
+		},
+		Link: model.Link{
+			Source:     "unifi",
+			Kind:       model.LinkWireless,
+			SSID:       "alices-wifi",
+			Radio:      "na",
+			Signal:     -60,
+			Uplink:     "lounge-ap",
+			UplinkMAC:  "aa:bb:cc:dd:ee:ff",
+			UplinkPort: 4,
+			LastSeen:   ts,
+		},
+	}
+}
+
+// identifyingValues of fullDevice which must not be left in a redacted copy
+var identifyingValues = []string{
+	"laptop.home",
+	"203.0.113.10",
+	"2606:4700::10",
+	"00:11:22:33:44:55",
+	"66:77:88:99:aa:bb",
+	"laptop-dns.home",
+	"Alice Example",
+	"behind the sofa",
+	"second floor cupboard",
+	"SN123",
+	"IT-0042",
+	"alices-laptop.local",
+	"alices-dhcp-laptop",
+	"1,121,3,6,15,119,252",
+	"MSFT 5.0",
+	"laptop-snmp",
+	"Linux laptop 6.1",
+	"secret",
+	"guest-web",
+	"02:42:ac:11:00:02",
+	"203.0.113.20",
+	"203.0.113.30",
+	"alices-wifi",
+	"lounge-ap",
+	"aa:bb:cc:dd:ee:ff",
+	"alices-house",
+	"alices-office",
+}
+
+func TestRedactor_DeviceLeavesNothing(t *testing.T) {
+	dev := fullDevice()
+	leaves(reflect.ValueOf(dev), "", func(path string, v reflect.Value) {
+		if v.IsZero() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+			t.Errorf("Device%s is not set in fullDevice", path)
+		}
+	})
+	var got strings.Builder
+	leaves(reflect.ValueOf(New("key").Device(dev)), "", func(path string, v reflect.Value) {
+		fmt.Fprintf(&got, "%s=%v\n", path, v.Interface())
+	})
+	for _, v := range identifyingValues {
+		if strings.Contains(got.String(), v) {
+			t.Errorf("%q left in redacted device", v)
+		}
+	}
+	if !reflect.DeepEqual(dev, fullDevice()) {
+		t.Errorf("redacting changed the original device")
+	}
+}
+
+// leaves calls fn with every field of the value which is not a struct or a slice of them, a
+// field with a String method counts as a single value.  A new field of the device is set in
+// fullDevice and decided on in Redactor.Device.
+func leaves(v reflect.Value, path string, fn func(string, reflect.Value)) {
+	if _, ok := v.Interface().(fmt.Stringer); ok && path != "" {
+		fn(path, v)
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		exported := 0
+		for i := range v.NumField() {
+			f := v.Type().Field(i)
+			if f.IsExported() {
+				exported++
+				leaves(v.Field(i), path+"."+f.Name, fn)
+			}
+		}
+		if exported == 0 {
+			fn(path, v)
+		}
+	case reflect.Slice:
+		if v.Len() == 0 || v.Type().Elem().Kind() == reflect.Uint8 {
+			fn(path, v)
+			return
+		}
+		for i := range v.Len() {
+			leaves(v.Index(i), fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	default:
+		fn(path, v)
+	}
+}
+
+func TestRedactor_Network(t *testing.T) {
+	r := New("key")
+	n := model.Network{
+		Name:   "alices-lan",
+		Prefix: model.MustParsePrefix("203.0.113.0/24"),
+		Tags:   model.Tags{{Val: "home"}},
+		VLAN:   model.VLAN{ID: 20, Name: "alices-office"},
+		Site:   "alices-house",
+	}
+	got := r.Network(n)
+	var out strings.Builder
+	leaves(reflect.ValueOf(got), "", func(path string, v reflect.Value) {
+		fmt.Fprintf(&out, "%s=%v\n", path, v.Interface())
+	})
+	for _, v := range []string{"alices-lan", "203.0.113.0", "alices-office", "alices-house"} {
+		if strings.Contains(out.String(), v) {
+			t.Errorf("%q left in redacted network", v)
+		}
+	}
+	if got.VLAN.ID != n.VLAN.ID || got.Tags[0].Val != "home" {
+		t.Errorf("vlan id and tags should be kept: %+v", got)
+	}
+	// devices are placed by the site and vlan of their network
+	d := r.Device(model.Device{Meta: model.Meta{Site: n.Site}, VLAN: n.VLAN})
+	if d.Meta.Site != got.Site || d.VLAN.Name != got.VLAN.Name {
+		t.Errorf("network %+v and device %+v redacted apart", got, d)
+	}
+}
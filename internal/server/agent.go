@@ -0,0 +1,101 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/agent"
+	"github.com/networkables/mason/internal/model"
+)
+
+// AgentStatus is the last report received from a remote agent
+type AgentStatus struct {
+	Name       string
+	Site       string
+	RemoteAddr string
+	Networks   []string
+	Devices    int
+	LastReport time.Time
+}
+
+// AgentReport accepts the devices found by a remote agent.  Each device is published as
+// discovered by the agent, so new devices wait for review and known devices are updated,
+// and its ping result is written to the performance history.  Devices without a site are
+// placed at the site of the report.
+func (m *Mason) AgentReport(
+	ctx context.Context,
+	token string,
+	remote string,
+	report agent.Report,
+) (int, error) {
+	if m.cfg.Agent.Token == "" {
+		return 0, agent.ErrAgentReportsDisabled
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(m.cfg.Agent.Token)) != 1 {
+		return 0, agent.ErrAgentUnauthorized
+	}
+	if report.Agent == "" {
+		return 0, agent.ErrAgentNameRequired
+	}
+	if report.Site != "" && !model.ValidSiteName(report.Site) {
+		return 0, model.ErrInvalidSiteName
+	}
+	count := 0
+	errs := make([]error, 0)
+	for _, rd := range report.Devices {
+		d, err := rd.Device(report.Agent)
+		if err != nil {
+			errs = append(
+				errs,
+				tre.New(err, "agent device", "agent", report.Agent, "addr", rd.Addr),
+			)
+			continue
+		}
+		if report.Site != "" {
+			known, err := m.store.GetDeviceByAddr(ctx, d.Addr)
+			if err != nil || known.Meta.Site == "" {
+				d.Meta.Site = report.Site
+			}
+		}
+		m.publish(model.EventDeviceDiscovered(d))
+		if rd.Ping != nil {
+			err = m.timeseries.WritePerformancePing(ctx, rd.Ping.Start, d, *rd.Ping)
+			m.recordIfError(err)
+			m.exportPing(rd.Ping.Start, d, *rd.Ping)
+		}
+		count++
+	}
+	m.agentsMu.Lock()
+	m.agents[report.Agent] = AgentStatus{
+		Name:       report.Agent,
+		Site:       report.Site,
+		RemoteAddr: remote,
+		Networks:   slices.Clone(report.Networks),
+		Devices:    count,
+		LastReport: time.Now(),
+	}
+	m.agentsMu.Unlock()
+	return count, errors.Join(errs...)
+}
+
+// Agents returns the last report of each agent which has reported since startup
+func (m *Mason) Agents() []AgentStatus {
+	m.agentsMu.Lock()
+	defer m.agentsMu.Unlock()
+	ret := make([]AgentStatus, 0, len(m.agents))
+	for _, a := range m.agents {
+		ret = append(ret, a)
+	}
+	slices.SortFunc(ret, func(a, b AgentStatus) int { return cmp.Compare(a.Name, b.Name) })
+	return ret
+}
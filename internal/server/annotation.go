@@ -0,0 +1,95 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+// updateDevice stores the device update and records annotations for any notable changes,
+// changes during a maintenance window are recorded as maintenance annotations.  Every
+// tracked field change is added to the device history along with the source.  Port scans
+// which open or close ports publish a ports changed event outside of maintenance.
+func (m *Mason) updateDevice(
+	ctx context.Context,
+	d model.Device,
+	source model.ChangeSource,
+) (bool, error) {
+	prev, err := m.store.GetDeviceByAddr(ctx, d.Addr)
+	if err == nil {
+		now := time.Now()
+		m.recordChanges(ctx, model.DeviceChanges(prev, prev.Merge(d), source, now))
+		annotations := model.DeviceChangeAnnotations(prev, d, now)
+		event, portsChanged := model.DevicePortsChanged(prev, d)
+		if len(annotations) > 0 || portsChanged {
+			window, inMaintenance := m.maintenanceLookup(ctx, now)(prev)
+			for _, a := range annotations {
+				if inMaintenance {
+					a = window.Suppress(a)
+				}
+				m.recordIfError(m.store.AddAnnotation(ctx, a))
+			}
+			if portsChanged && !inMaintenance {
+				m.publish(event)
+			}
+		}
+	}
+	return m.store.UpdateDevice(ctx, d)
+}
+
+// AddAnnotation stores a user or system provided annotation
+func (m *Mason) AddAnnotation(ctx context.Context, a model.Annotation) error {
+	err := m.store.AddAnnotation(ctx, a)
+	m.recordIfError(err)
+	return err
+}
+
+// ReadAnnotations returns the annotations for the device (and global ones) over the duration
+func (m *Mason) ReadAnnotations(
+	ctx context.Context,
+	addr model.Addr,
+	duration time.Duration,
+) ([]model.Annotation, error) {
+	annotations, err := m.store.ReadAnnotations(ctx, addr, duration)
+	m.recordIfError(err)
+	return annotations, err
+}
+
+// recordRoute compares the hops against the last traceroute to the same target and
+// records an annotation when the path has changed
+func (m *Mason) recordRoute(
+	ctx context.Context,
+	target model.Addr,
+	stats []nettools.Icmp4EchoResponseStatistics,
+) {
+	if m.store == nil {
+		return
+	}
+	hops := make([]string, 0, len(stats))
+	for _, stat := range stats {
+		hops = append(hops, stat.Peer.String())
+	}
+
+	m.routesMu.Lock()
+	prev, ok := m.routes[target.String()]
+	m.routes[target.String()] = hops
+	m.routesMu.Unlock()
+
+	if !ok || slices.Equal(prev, hops) {
+		return
+	}
+	m.recordIfError(m.store.AddAnnotation(ctx, model.Annotation{
+		Time: time.Now(),
+		Addr: target,
+		Kind: model.AnnotationRouteChanged,
+		Text: "route changed to " + strings.Join(hops, " > "),
+	}))
+}
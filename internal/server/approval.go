@@ -0,0 +1,46 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// SetDeviceApproval replaces the approval state of the device
+func (m *Mason) SetDeviceApproval(
+	ctx context.Context,
+	addr model.Addr,
+	state model.ApprovalState,
+) error {
+	state, err := model.ParseApprovalState(string(state))
+	if err != nil {
+		return err
+	}
+	d, err := m.store.GetDeviceByAddr(ctx, addr)
+	if err != nil {
+		return err
+	}
+	err = m.store.SetDeviceApproval(ctx, addr, state)
+	if err != nil {
+		return err
+	}
+	next := d
+	next.Meta.Approval = state
+	m.recordChanges(ctx, model.DeviceChanges(d, next, model.ChangeSourceUser, time.Now()))
+	return nil
+}
+
+// ReviewQueue returns the devices waiting for approval, oldest discovery first
+func (m *Mason) ReviewQueue(ctx context.Context) []model.Device {
+	devs := m.store.GetFilteredDevices(ctx, model.ApprovalDeviceFilter(model.ApprovalUnknown))
+	slices.SortFunc(devs, func(a, b model.Device) int {
+		return a.DiscoveredAt.Compare(b.DiscoveredAt)
+	})
+	return devs
+}
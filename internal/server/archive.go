@@ -0,0 +1,56 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+)
+
+const archiveCheckInterval = time.Hour
+
+var ErrArchiveUnsupported = errors.New("store does not support archiving")
+
+// ArchiveTimeseries moves old ping and flow data out of the live store and the timeseries
+// engine, returns the number of records archived
+func (m *Mason) ArchiveTimeseries(ctx context.Context) (int, error) {
+	archivers := make([]TimeseriesArchiver, 0, 2)
+	if a, ok := m.store.(TimeseriesArchiver); ok {
+		archivers = append(archivers, a)
+	}
+	if a, ok := m.timeseries.(TimeseriesArchiver); ok && any(m.timeseries) != any(m.store) {
+		archivers = append(archivers, a)
+	}
+	if len(archivers) == 0 {
+		return 0, ErrArchiveUnsupported
+	}
+	total := 0
+	for _, a := range archivers {
+		count, err := a.ArchiveTimeseries(ctx)
+		total += count
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (m *Mason) archiveTimeseries(ctx context.Context) {
+	count, err := m.ArchiveTimeseries(ctx)
+	if errors.Is(err, ErrArchiveUnsupported) {
+		return
+	}
+	if err != nil {
+		m.publish(tre.New(err, "archive timeseries"))
+		return
+	}
+	if count > 0 {
+		log.Info("archived timeseries", "records", count)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// MACBindings returns the MACs seen answering for the address, oldest first
+func (m *Mason) MACBindings(ctx context.Context, addr model.Addr) ([]model.MACBinding, error) {
+	bindings, err := m.store.ListMACBindings(ctx)
+	if err != nil {
+		m.recordIfError(err)
+		return nil, err
+	}
+	return slices.DeleteFunc(bindings, func(b model.MACBinding) bool {
+		return b.Addr != addr
+	}), nil
+}
+
+// watchMACBinding records the MAC of a discovered device as bound to its address and raises
+// a conflict when another MAC answered for the address last.  An address is raised at most
+// once per window so two devices taking turns do not flood the bus.
+func (m *Mason) watchMACBinding(ctx context.Context, d model.Device) {
+	cfg := m.cfg.ArpWatch
+	if !cfg.Enabled || d.MAC.IsEmpty() {
+		return
+	}
+	m.macBindingsMu.Lock()
+	defer m.macBindingsMu.Unlock()
+	m.loadMACBindings(ctx)
+
+	now := time.Now()
+	seen := model.MACBinding{
+		Addr:      d.Addr,
+		MAC:       d.MAC,
+		FirstSeen: now,
+		LastSeen:  now,
+		Source:    d.DiscoveredBy,
+	}
+	known := m.macBindings[d.Addr]
+	conflict, found := model.DetectMACConflict(known, seen, cfg.Window)
+
+	idx := slices.IndexFunc(known, func(b model.MACBinding) bool {
+		return b.MAC.String() == seen.MAC.String()
+	})
+	if idx < 0 {
+		m.macBindings[d.Addr] = append(known, seen)
+	} else {
+		known[idx].LastSeen = seen.LastSeen
+		known[idx].Source = seen.Source
+	}
+	m.recordIfError(m.store.UpsertMACBinding(ctx, seen))
+
+	if !found || m.macExcluded(d.Addr) || now.Sub(m.macConflicts[d.Addr]) < cfg.Window {
+		return
+	}
+	m.macConflicts[d.Addr] = now
+	m.publish(conflict)
+}
+
+// loadMACBindings reads the stored bindings and the exclusions on the first use, the lock
+// is held by the caller
+func (m *Mason) loadMACBindings(ctx context.Context) {
+	if m.macBindingsLoaded {
+		return
+	}
+	bindings, err := m.store.ListMACBindings(ctx)
+	if err != nil {
+		m.recordIfError(err)
+		return
+	}
+	for _, b := range bindings {
+		m.macBindings[b.Addr] = append(m.macBindings[b.Addr], b)
+	}
+	m.macExclusions, err = model.ParseMACExclusions(m.cfg.ArpWatch.Exclude)
+	if err != nil {
+		m.publish(tre.New(err, "parse arp watch exclusions", "exclude", m.cfg.ArpWatch.Exclude))
+	}
+	m.macBindingsLoaded = true
+}
+
+func (m *Mason) macExcluded(addr model.Addr) bool {
+	return slices.ContainsFunc(m.macExclusions, func(p netip.Prefix) bool {
+		return p.Contains(addr.Addr())
+	})
+}
+
+// purgeMACBindings removes the bindings not seen within the retention, the IPv6 neighbors
+// recorded by the ndp listener included
+func (m *Mason) purgeMACBindings(ctx context.Context) {
+	cfg := m.cfg.ArpWatch
+	if !cfg.Enabled && !m.cfg.Discovery.Ndp.Enabled {
+		return
+	}
+	cutoff := time.Now().Add(-1 * cfg.Retention)
+	removed, err := m.store.PurgeMACBindings(ctx, cutoff)
+	if err != nil {
+		m.recordIfError(err)
+		return
+	}
+	if removed == 0 {
+		return
+	}
+	log.Debug("purged mac bindings", "count", removed)
+
+	m.macBindingsMu.Lock()
+	defer m.macBindingsMu.Unlock()
+	for addr, bindings := range m.macBindings {
+		bindings = slices.DeleteFunc(bindings, func(b model.MACBinding) bool {
+			return b.LastSeen.Before(cutoff)
+		})
+		if len(bindings) == 0 {
+			delete(m.macBindings, addr)
+			continue
+		}
+		m.macBindings[addr] = bindings
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/asn"
+)
+
+const asnRefreshCheckInterval = time.Hour
+
+// AsnStatus returns the state of the asn listing, the zero value when asn lookups are
+// disabled
+func (m *Mason) AsnStatus() asn.Status {
+	if !m.cfg.Asn.Enabled {
+		return asn.Status{}
+	}
+	return asn.GetStatus()
+}
+
+// refreshAsnIfStale downloads the asn listings again once they are older than the refresh
+// interval, lookups keep using the old listing while the new one is built
+func (m *Mason) refreshAsnIfStale(ctx context.Context) {
+	if !m.cfg.Asn.Enabled || m.cfg.Asn.RefreshInterval <= 0 || m.IsOffline() {
+		return
+	}
+	if !asn.Stale(m.cfg.Asn.RefreshInterval) || asn.GetStatus().Refreshing {
+		return
+	}
+	log.Info("refreshing asn listings")
+	start := time.Now()
+	err := asn.Refresh(ctx)
+	if err != nil {
+		m.publish(tre.New(err, "asn refresh"))
+		return
+	}
+	log.Info("refreshed asn listings", "elapsed", time.Since(start).Round(time.Second))
+}
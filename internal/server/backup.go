@@ -0,0 +1,180 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/backup"
+	"github.com/networkables/mason/internal/combostore"
+	"github.com/networkables/mason/internal/sqlitestore"
+	"github.com/networkables/mason/internal/tsstore"
+)
+
+const (
+	BackupStoreSqlite  = "sqlite"
+	BackupStoreCombo   = "combo"
+	BackupStoreWhisper = "whisper"
+)
+
+var (
+	ErrBackupUnsupported = errors.New("no store supports backups")
+	ErrUnknownBackup     = errors.New("backup holds an unknown store")
+)
+
+type namedSnapshotter struct {
+	name string
+	backup.Snapshotter
+}
+
+// snapshotters returns the stores to back up, the timeseries engine only when it is not the
+// store itself
+func (m *Mason) snapshotters() []namedSnapshotter {
+	sources := []any{m.store}
+	if any(m.timeseries) != any(m.store) {
+		sources = append(sources, m.timeseries)
+	}
+	var stores []namedSnapshotter
+	for _, s := range sources {
+		switch s := s.(type) {
+		case *sqlitestore.Store:
+			stores = append(stores, namedSnapshotter{BackupStoreSqlite, s})
+		case *combostore.Store:
+			stores = append(stores, namedSnapshotter{BackupStoreCombo, s})
+		case *tsstore.Whisper:
+			stores = append(stores, namedSnapshotter{BackupStoreWhisper, s})
+		}
+	}
+	return stores
+}
+
+// Backup writes an archive of the stores to w, the stores stay in use while their snapshots
+// are taken
+func (m *Mason) Backup(ctx context.Context, w io.Writer) (backup.Manifest, error) {
+	manifest := backup.NewManifest(time.Now())
+	stores := m.snapshotters()
+	if len(stores) == 0 {
+		return manifest, ErrBackupUnsupported
+	}
+	tmp, err := os.MkdirTemp("", "mason-backup-")
+	if err != nil {
+		return manifest, err
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, s := range stores {
+		schema, err := s.Snapshot(ctx, filepath.Join(tmp, s.name))
+		if err != nil {
+			return manifest, tre.New(err, "snapshot store", "store", s.name)
+		}
+		manifest.Stores = append(manifest.Stores, backup.Store{Name: s.name, Schema: schema})
+	}
+	return manifest, backup.Write(w, manifest, tmp)
+}
+
+// Restore replaces the stores of the configuration with those in the archive.  The server
+// must be stopped, it would otherwise write over the restored files.  Nothing is replaced when
+// a store of the archive can not be restored by this version.
+func Restore(cfg *Config, r io.Reader) (backup.Manifest, error) {
+	tmp, err := os.MkdirTemp("", "mason-restore-")
+	if err != nil {
+		return backup.Manifest{}, err
+	}
+	defer os.RemoveAll(tmp)
+
+	manifest, err := backup.Read(r, tmp)
+	if err != nil {
+		return manifest, err
+	}
+	for _, s := range manifest.Stores {
+		switch s.Name {
+		case BackupStoreSqlite:
+			if s.Schema > sqlitestore.SchemaVersion() {
+				return manifest, fmt.Errorf(
+					"%w: sqlite schema %d, known %d",
+					backup.ErrSchemaTooNew,
+					s.Schema,
+					sqlitestore.SchemaVersion(),
+				)
+			}
+		case BackupStoreCombo, BackupStoreWhisper:
+		default:
+			return manifest, fmt.Errorf("%w: %s", ErrUnknownBackup, s.Name)
+		}
+	}
+	for _, s := range manifest.Stores {
+		dir := filepath.Join(tmp, s.Name)
+		switch s.Name {
+		case BackupStoreSqlite:
+			err = sqlitestore.Restore(cfg.Store.Sqlite, dir)
+		case BackupStoreCombo:
+			err = combostore.Restore(cfg.Store.Combo, dir)
+		case BackupStoreWhisper:
+			err = tsstore.RestoreWhisper(cfg.Store.Combo.Directory, dir)
+		}
+		if err != nil {
+			return manifest, tre.New(err, "restore store", "store", s.Name)
+		}
+	}
+	return manifest, nil
+}
+
+// scheduledBackup writes a backup into the backup directory and removes the oldest ones, a
+// run is skipped while the previous one is still going
+func (m *Mason) scheduledBackup(ctx context.Context) {
+	cfg := m.cfg.Backup
+	if !cfg.Enabled || !m.backupRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.backupRunning.Store(false)
+
+	filename, err := m.writeBackup(ctx, cfg.Directory)
+	if err != nil {
+		m.publish(tre.New(err, "scheduled backup", "directory", cfg.Directory))
+		return
+	}
+	log.Info("backup written", "file", filename)
+	removed, err := backup.Rotate(cfg.Directory, cfg.Keep)
+	if err != nil {
+		m.publish(tre.New(err, "rotate backups", "directory", cfg.Directory))
+		return
+	}
+	for _, name := range removed {
+		log.Info("backup removed", "file", name)
+	}
+}
+
+// writeBackup writes a backup archive into dir, the archive only gets its name once complete
+func (m *Mason) writeBackup(ctx context.Context, dir string) (string, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return "", err
+	}
+	filename := filepath.Join(dir, backup.Filename(time.Now()))
+	f, err := os.CreateTemp(dir, ".backup-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	_, err = m.Backup(ctx, f)
+	if err != nil {
+		f.Close()
+		return "", err
+	}
+	err = f.Close()
+	if err != nil {
+		return "", err
+	}
+	return filename, os.Rename(f.Name(), filename)
+}
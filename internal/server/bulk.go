@@ -0,0 +1,149 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/enrichment"
+	"github.com/networkables/mason/internal/model"
+)
+
+// BulkDeviceAction is an operation applied to a set of devices at once
+type BulkDeviceAction string
+
+const (
+	BulkDeviceEnrich   BulkDeviceAction = "enrich"
+	BulkDevicePortScan BulkDeviceAction = "portscan"
+	BulkDeviceDelete   BulkDeviceAction = "delete"
+	BulkDeviceTag      BulkDeviceAction = "tag"
+	BulkDeviceUntag    BulkDeviceAction = "untag"
+	BulkDevicePolicy   BulkDeviceAction = "policy"
+)
+
+var (
+	ErrInvalidBulkDeviceAction = errors.New("invalid bulk device action")
+	ErrNoDevicesSelected       = errors.New("no devices selected")
+)
+
+// BulkDeviceActions lists the valid bulk device actions
+var BulkDeviceActions = []BulkDeviceAction{
+	BulkDeviceEnrich,
+	BulkDevicePortScan,
+	BulkDeviceTag,
+	BulkDeviceUntag,
+	BulkDevicePolicy,
+	BulkDeviceDelete,
+}
+
+// ParseBulkDeviceAction converts the string into a BulkDeviceAction
+func ParseBulkDeviceAction(s string) (BulkDeviceAction, error) {
+	for _, a := range BulkDeviceActions {
+		if string(a) == s {
+			return a, nil
+		}
+	}
+	return "", ErrInvalidBulkDeviceAction
+}
+
+// BulkDeviceRequest applies the action to each of the devices, Tag is used by the tag and
+// untag actions and Policy by the policy action
+type BulkDeviceRequest struct {
+	Action BulkDeviceAction
+	Addrs  []model.Addr
+	Tag    string
+	Policy model.MonitoringPolicy
+}
+
+// BulkDevices applies the request to each device and returns how many devices it was
+// applied to.  Enrichment and port scans are published as one request per device and run
+// in the background; a failure on one device does not stop the others.
+func (m *Mason) BulkDevices(ctx context.Context, req BulkDeviceRequest) (int, error) {
+	if len(req.Addrs) == 0 {
+		return 0, ErrNoDevicesSelected
+	}
+	apply, err := m.bulkDeviceFunc(req)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	errs := make([]error, 0)
+	for _, addr := range req.Addrs {
+		err := apply(ctx, addr)
+		if err != nil {
+			errs = append(
+				errs,
+				tre.New(err, "bulk device action", "action", req.Action, "addr", addr),
+			)
+			continue
+		}
+		count++
+	}
+	return count, errors.Join(errs...)
+}
+
+func (m *Mason) bulkDeviceFunc(
+	req BulkDeviceRequest,
+) (func(context.Context, model.Addr) error, error) {
+	switch req.Action {
+	case BulkDeviceEnrich:
+		return m.bulkEnrich(enrichment.DefaultEnrichmentFields(m.cfg.Enrichment)), nil
+	case BulkDevicePortScan:
+		return m.bulkEnrich(enrichment.EnrichmentFields{
+			PerformPortScan: true,
+			PerformClassify: m.cfg.Enrichment.Classify.Enabled,
+			Cfg:             m.cfg.Enrichment,
+		}), nil
+	case BulkDeviceDelete:
+		return m.RemoveDevice, nil
+	case BulkDeviceTag:
+		if !model.ValidTagName(req.Tag) {
+			return nil, model.ErrInvalidTagName
+		}
+		return func(ctx context.Context, addr model.Addr) error {
+			return m.TagDevice(ctx, addr, req.Tag)
+		}, nil
+	case BulkDeviceUntag:
+		return func(ctx context.Context, addr model.Addr) error {
+			return m.UntagDevice(ctx, addr, req.Tag)
+		}, nil
+	case BulkDevicePolicy:
+		err := validatePolicy(req.Policy)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, addr model.Addr) error {
+			return m.SetDevicePolicy(ctx, addr, req.Policy)
+		}, nil
+	}
+	return nil, ErrInvalidBulkDeviceAction
+}
+
+// bulkEnrich publishes an enrichment request for the device.  Cached lookups are cleared so
+// the device is looked up again rather than keeping what was found the first time.
+func (m *Mason) bulkEnrich(
+	fields enrichment.EnrichmentFields,
+) func(context.Context, model.Addr) error {
+	return func(ctx context.Context, addr model.Addr) error {
+		d, err := m.store.GetDeviceByAddr(ctx, addr)
+		if err != nil {
+			return err
+		}
+		if fields.PerformDNSLookup {
+			d.Meta.DnsName = ""
+		}
+		if fields.PerformMDNSLookup {
+			d.Meta.MDNSName = ""
+		}
+		if fields.PerformOUILookup {
+			d.Meta.Manufacturer = ""
+		}
+		m.publish(enrichment.EnrichDeviceRequest{Device: d, Fields: fields})
+		return nil
+	}
+}
@@ -17,9 +17,9 @@ import (
 )
 
 func HasCapabilities(cfg *Config) bool {
-	// do not test if no privileges are required
-	if !cfg.Discovery.Icmp.Privileged && !cfg.Discovery.Arp.Enabled &&
-		!cfg.Pinger.Privileged {
+	// do not test if no privileges are required or the helper holds them
+	if cfg.Helper.Socket != "" ||
+		(!cfg.Discovery.Icmp.Privileged && !cfg.Discovery.Arp.Enabled && !cfg.Pinger.Privileged) {
 		return true
 	}
 
@@ -0,0 +1,351 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+var ErrCaptureDisabled = errors.New("packet capture is disabled, enable with --capture.enabled")
+
+// captureRun is a capture known to the server, cancel is set while it runs
+type captureRun struct {
+	capture model.PacketCapture
+	cancel  context.CancelFunc
+}
+
+// StartCapture begins a capture on the interface, the request is bounded by the configured
+// largest duration and size.  The capture runs in the background until a limit is reached or
+// it is stopped, the returned capture is in the running state.
+func (m *Mason) StartCapture(
+	ctx context.Context,
+	req model.PacketCaptureRequest,
+) (model.PacketCapture, error) {
+	cfg := m.cfg.Capture
+	if !cfg.Enabled {
+		return model.PacketCapture{}, ErrCaptureDisabled
+	}
+	maxBytes, err := model.ParseQuotaBytes(cfg.MaxSize)
+	if err != nil {
+		return model.PacketCapture{}, fmt.Errorf("capture.maxsize: %w", err)
+	}
+	req, err = req.Bound(cfg.MaxDuration, maxBytes)
+	if err != nil {
+		return model.PacketCapture{}, err
+	}
+	filter, err := nettools.ParseCaptureFilter(req.Filter)
+	if err != nil {
+		return model.PacketCapture{}, err
+	}
+	_, err = net.InterfaceByName(req.Interface)
+	if err != nil {
+		return model.PacketCapture{}, fmt.Errorf(
+			"%w: %s",
+			model.ErrCaptureInterfaceNotExists,
+			req.Interface,
+		)
+	}
+	err = os.MkdirAll(cfg.Directory, 0o750)
+	if err != nil {
+		return model.PacketCapture{}, err
+	}
+
+	m.capturesMu.Lock()
+	defer m.capturesMu.Unlock()
+	m.loadCaptures()
+	running := 0
+	for _, run := range m.captures {
+		if run.capture.State == model.CaptureRunning {
+			running++
+		}
+	}
+	if running >= cfg.MaxRunning {
+		return model.PacketCapture{}, model.ErrTooManyCapturesRunning
+	}
+
+	now := time.Now()
+	c := model.PacketCapture{
+		ID:        m.nextCaptureID(req.Interface, now),
+		Interface: req.Interface,
+		Filter:    filter.String(),
+		Duration:  req.Duration,
+		MaxBytes:  req.MaxBytes,
+		Started:   now,
+		State:     model.CaptureRunning,
+	}
+	f, err := os.Create(m.capturePath(c.Filename()))
+	if err != nil {
+		return model.PacketCapture{}, err
+	}
+	m.saveCaptureMeta(c)
+
+	runctx, cancel := context.WithCancel(context.Background())
+	m.captures[c.ID] = &captureRun{capture: c, cancel: cancel}
+	m.capturesWG.Add(1)
+	go func() {
+		defer m.capturesWG.Done()
+		defer cancel()
+		stats, err := nettools.Capture(runctx, req.Interface, nettools.CaptureOptions{
+			Filter:      filter,
+			Duration:    req.Duration,
+			MaxBytes:    req.MaxBytes,
+			SnapLen:     cfg.SnapLen,
+			Promiscuous: cfg.Promiscuous,
+			Progress: func(stats nettools.CaptureStats) {
+				m.capturesMu.Lock()
+				if run, ok := m.captures[c.ID]; ok {
+					run.capture.Packets, run.capture.Bytes = stats.Packets, stats.Bytes
+				}
+				m.capturesMu.Unlock()
+			},
+		}, f)
+		cerr := f.Close()
+		m.finishCapture(c.ID, stats, errors.Join(err, cerr))
+	}()
+	log.Info("packet capture started", "id", c.ID, "interface", c.Interface, "filter", c.Filter)
+	return c, nil
+}
+
+// finishCapture records the outcome of a capture, a canceled capture was stopped
+func (m *Mason) finishCapture(id string, stats nettools.CaptureStats, err error) {
+	m.capturesMu.Lock()
+	run, ok := m.captures[id]
+	if !ok {
+		m.capturesMu.Unlock()
+		return
+	}
+	run.cancel = nil
+	c := &run.capture
+	c.Finished = time.Now()
+	c.Packets = stats.Packets
+	c.Bytes = stats.Bytes
+	switch {
+	case errors.Is(err, context.Canceled):
+		c.State = model.CaptureStopped
+	case err != nil:
+		c.State = model.CaptureFailed
+		c.Err = err.Error()
+	default:
+		c.State = model.CaptureDone
+	}
+	m.saveCaptureMeta(*c)
+	finished := *c
+	m.capturesMu.Unlock()
+
+	if finished.State == model.CaptureFailed {
+		m.publish(tre.New(err, "packet capture", "id", id))
+	}
+	m.publish(model.EventCaptureFinished(finished))
+}
+
+// ListCaptures returns the running and finished captures, the newest first
+func (m *Mason) ListCaptures(ctx context.Context) ([]model.PacketCapture, error) {
+	m.capturesMu.Lock()
+	defer m.capturesMu.Unlock()
+	m.loadCaptures()
+	captures := make([]model.PacketCapture, 0, len(m.captures))
+	for _, run := range m.captures {
+		captures = append(captures, run.capture)
+	}
+	slices.SortFunc(captures, func(a, b model.PacketCapture) int {
+		return cmp.Or(b.Started.Compare(a.Started), strings.Compare(a.ID, b.ID))
+	})
+	return captures, nil
+}
+
+// StopCapture ends a running capture early, the packets captured so far are kept
+func (m *Mason) StopCapture(ctx context.Context, id string) error {
+	m.capturesMu.Lock()
+	defer m.capturesMu.Unlock()
+	m.loadCaptures()
+	run, ok := m.captures[id]
+	if !ok {
+		return model.ErrCaptureDoesNotExist
+	}
+	if run.cancel == nil {
+		return model.ErrCaptureNotRunning
+	}
+	run.cancel()
+	return nil
+}
+
+// OpenCapture returns the finished capture and its pcap file, the caller closes the file
+func (m *Mason) OpenCapture(ctx context.Context, id string) (model.PacketCapture, *os.File, error) {
+	m.capturesMu.Lock()
+	defer m.capturesMu.Unlock()
+	m.loadCaptures()
+	run, ok := m.captures[id]
+	if !ok {
+		return model.PacketCapture{}, nil, model.ErrCaptureDoesNotExist
+	}
+	if run.cancel != nil {
+		return run.capture, nil, model.ErrCaptureStillRunning
+	}
+	f, err := os.Open(m.capturePath(run.capture.Filename()))
+	return run.capture, f, err
+}
+
+// RemoveCapture deletes a finished capture and its file
+func (m *Mason) RemoveCapture(ctx context.Context, id string) error {
+	m.capturesMu.Lock()
+	defer m.capturesMu.Unlock()
+	m.loadCaptures()
+	run, ok := m.captures[id]
+	if !ok {
+		return model.ErrCaptureDoesNotExist
+	}
+	if run.cancel != nil {
+		return model.ErrCaptureStillRunning
+	}
+	return m.removeCapture(run.capture)
+}
+
+// purgeCaptures removes the finished captures older than the retention
+func (m *Mason) purgeCaptures(ctx context.Context) {
+	cfg := m.cfg.Capture
+	if !cfg.Enabled || cfg.Retention <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-1 * cfg.Retention)
+	m.capturesMu.Lock()
+	defer m.capturesMu.Unlock()
+	m.loadCaptures()
+	for _, run := range m.captures {
+		if run.cancel != nil || run.capture.Finished.After(cutoff) {
+			continue
+		}
+		err := m.removeCapture(run.capture)
+		if err != nil {
+			m.publish(tre.New(err, "purge packet capture", "id", run.capture.ID))
+		}
+	}
+}
+
+// stopCaptures cancels the running captures and waits for their files to be closed
+func (m *Mason) stopCaptures() {
+	m.capturesMu.Lock()
+	for _, run := range m.captures {
+		if run.cancel != nil {
+			run.cancel()
+		}
+	}
+	m.capturesMu.Unlock()
+	m.capturesWG.Wait()
+}
+
+// removeCapture deletes the files of the capture, the lock is held by the caller
+func (m *Mason) removeCapture(c model.PacketCapture) error {
+	err := os.Remove(m.capturePath(c.Filename()))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	err = os.Remove(m.capturePath(c.ID + captureMetaExt))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	delete(m.captures, c.ID)
+	return nil
+}
+
+// captureMetaExt is the extension of the json file kept next to each pcap file, so the
+// captures of an earlier run can still be listed and downloaded
+const captureMetaExt = ".json"
+
+// loadCaptures reads the captures left in the directory by an earlier run once, captures
+// which were running when the server stopped are marked failed.  The lock is held by the
+// caller.
+func (m *Mason) loadCaptures() {
+	if m.capturesLoaded {
+		return
+	}
+	m.capturesLoaded = true
+	matches, err := filepath.Glob(m.capturePath("*" + captureMetaExt))
+	if err != nil {
+		m.recordIfError(err)
+		return
+	}
+	for _, name := range matches {
+		buf, err := os.ReadFile(name)
+		if err != nil {
+			m.recordIfError(err)
+			continue
+		}
+		var c model.PacketCapture
+		err = json.Unmarshal(buf, &c)
+		if err != nil || c.ID == "" {
+			log.Warn("skipping unreadable packet capture", "file", name, "error", err)
+			continue
+		}
+		if c.State == model.CaptureRunning {
+			c.State = model.CaptureFailed
+			c.Err = "interrupted by a server stop"
+			if fi, err := os.Stat(m.capturePath(c.Filename())); err == nil {
+				c.Finished = fi.ModTime()
+				c.Bytes = fi.Size()
+			}
+		}
+		m.captures[c.ID] = &captureRun{capture: c}
+	}
+}
+
+func (m *Mason) saveCaptureMeta(c model.PacketCapture) {
+	buf, err := json.Marshal(c)
+	if err == nil {
+		err = os.WriteFile(m.capturePath(c.ID+captureMetaExt), buf, 0o640)
+	}
+	if err != nil {
+		m.publish(tre.New(err, "save packet capture", "id", c.ID))
+	}
+}
+
+// nextCaptureID names the capture after the interface and start time, the lock is held by
+// the caller
+func (m *Mason) nextCaptureID(ifname string, start time.Time) string {
+	base := ifname + "-" + start.Format("20060102-150405")
+	id := base
+	for i := 2; ; i++ {
+		if _, ok := m.captures[id]; !ok {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+func (m *Mason) capturePath(name string) string {
+	return filepath.Join(m.cfg.Capture.Directory, name)
+}
+
+// CaptureInterfaces returns the names of the interfaces which are up, to choose from when
+// starting a capture
+func (m *Mason) CaptureInterfaces(ctx context.Context) []string {
+	ifis, err := net.Interfaces()
+	if err != nil {
+		m.recordIfError(err)
+		return nil
+	}
+	names := make([]string, 0, len(ifis))
+	for _, ifi := range ifis {
+		if ifi.Flags&net.FlagUp != 0 {
+			names = append(names, ifi.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,41 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// DeviceHistory returns the recorded field changes of the device, newest first
+func (m *Mason) DeviceHistory(
+	ctx context.Context,
+	addr model.Addr,
+) ([]model.DeviceChange, error) {
+	changes, err := m.store.ReadDeviceChanges(ctx, addr)
+	m.recordIfError(err)
+	return changes, err
+}
+
+func (m *Mason) recordChanges(ctx context.Context, changes []model.DeviceChange) {
+	if len(changes) == 0 {
+		return
+	}
+	m.recordIfError(m.store.AddDeviceChanges(ctx, changes))
+}
+
+// setDeviceTags replaces the tags of the device and records the change as a user change
+func (m *Mason) setDeviceTags(ctx context.Context, d model.Device, tags model.Tags) error {
+	err := m.store.SetDeviceTags(ctx, d.Addr, tags)
+	if err != nil {
+		return err
+	}
+	next := d
+	next.Meta.Tags = tags
+	m.recordChanges(ctx, model.DeviceChanges(d, next, model.ChangeSourceUser, time.Now()))
+	return nil
+}
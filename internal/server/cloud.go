@@ -0,0 +1,96 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/cloud"
+	"github.com/networkables/mason/internal/model"
+)
+
+// CloudImportResult counts what an import of a cloud provider changed
+type CloudImportResult struct {
+	Provider        string
+	NetworksAdded   int
+	NetworksUpdated int
+	Devices         int
+}
+
+// ImportCloud reads the vpc subnets and network interfaces of the provider and stores them
+// as networks and devices.  The store is written directly so the import also works from the
+// command line without the bus running.
+func (m *Mason) ImportCloud(ctx context.Context, provider string) (CloudImportResult, error) {
+	result := CloudImportResult{Provider: provider}
+	inv, err := cloud.Fetch(ctx, m.cfg.Cloud, provider)
+	if err != nil {
+		return result, tre.New(err, "fetch cloud inventory", "provider", provider)
+	}
+	for _, n := range inv.Networks() {
+		err := m.store.AddNetwork(ctx, n)
+		if err == nil {
+			m.limits.Network(n.Prefix.P)
+			m.publish(model.NetworkAddedEvent(n))
+			result.NetworksAdded++
+			continue
+		}
+		if !errors.Is(err, model.ErrNetworkExists) {
+			return result, tre.New(err, "add cloud network", "network", n.Name)
+		}
+		prev, err := m.store.GetNetworkByName(ctx, n.Name)
+		if err != nil {
+			// the prefix is known under another name, leave it be
+			continue
+		}
+		tags := unionTags(prev.Tags, n.Tags)
+		if len(tags) == len(prev.Tags) {
+			continue
+		}
+		prev.Tags = tags
+		err = m.store.UpdateNetwork(ctx, prev)
+		if err != nil {
+			return result, tre.New(err, "update cloud network", "network", n.Name)
+		}
+		result.NetworksUpdated++
+	}
+	for _, d := range inv.Devices(time.Now()) {
+		// the discovered tags replace those of a known device, keep the ones set by users
+		if prev, err := m.store.GetDeviceByAddr(ctx, d.Addr); err == nil {
+			d.Meta.Tags = unionTags(prev.Meta.Tags, d.Meta.Tags)
+		}
+		m.addDiscoveredDevice(ctx, d)
+		result.Devices++
+	}
+	return result, nil
+}
+
+func unionTags(existing model.Tags, add model.Tags) model.Tags {
+	tags := slices.Clone(existing)
+	for _, tag := range add {
+		tags = model.Add(tag, tags)
+	}
+	return tags
+}
+
+// importClouds runs the scheduled import of each configured provider, a run is skipped while
+// the previous one is still going
+func (m *Mason) importClouds(ctx context.Context) {
+	if !m.cfg.Cloud.Enabled || !m.cloudRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.cloudRunning.Store(false)
+
+	for _, provider := range m.cfg.Cloud.Providers {
+		_, err := m.ImportCloud(ctx, provider)
+		if err != nil {
+			m.publish(err)
+		}
+	}
+}
@@ -13,21 +13,39 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
+	"github.com/networkables/mason/internal/agent"
 	"github.com/networkables/mason/internal/asn"
+	"github.com/networkables/mason/internal/backup"
 	"github.com/networkables/mason/internal/bus"
+	"github.com/networkables/mason/internal/cloud"
 	"github.com/networkables/mason/internal/combostore"
 	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/dnslog"
 	"github.com/networkables/mason/internal/enrichment"
+	"github.com/networkables/mason/internal/exporter"
 	"github.com/networkables/mason/internal/flagset"
+	"github.com/networkables/mason/internal/hooks"
+	"github.com/networkables/mason/internal/kubernetes"
+	"github.com/networkables/mason/internal/logging"
+	"github.com/networkables/mason/internal/netbox"
 	"github.com/networkables/mason/internal/netflows"
+	"github.com/networkables/mason/internal/notify"
 	"github.com/networkables/mason/internal/oui"
 	"github.com/networkables/mason/internal/pinger"
+	"github.com/networkables/mason/internal/ratelimit"
 	"github.com/networkables/mason/internal/sqlitestore"
+	"github.com/networkables/mason/internal/syslog"
+	"github.com/networkables/mason/internal/tracing"
+	"github.com/networkables/mason/internal/tsstore"
+	"github.com/networkables/mason/internal/unifi"
+	"github.com/networkables/mason/nettools"
+	"github.com/networkables/mason/pkg/provider"
 )
 
 type Store struct {
-	Combo  *combostore.Config
-	Sqlite *sqlitestore.Config
+	Combo      *combostore.Config
+	Sqlite     *sqlitestore.Config
+	Timeseries *tsstore.Config
 }
 
 type TuiConfig struct {
@@ -39,10 +57,204 @@ type TuiConfig struct {
 type WuiConfig struct {
 	Enabled       bool
 	ListenAddress string
+	// ExternalURL is where the web ui is reached, the device qr code labels link to it
+	ExternalURL string
+}
+
+type OfflineConfig struct {
+	Enabled bool
+}
+
+type IpamConfig struct {
+	WarnThreshold int
+}
+
+type SoftDeleteConfig struct {
+	GracePeriod time.Duration
+}
+
+type ConsistencyConfig struct {
+	CheckOnStartup bool
+	Repair         bool
+}
+
+type SiteConfig struct {
+	Name string
+}
+
+// InternetHealthConfig sets the anchors pinged and the urls fetched to measure the internet
+// connection, the anchor "isp" is the first public hop of the traceroute to the first anchor
+type InternetHealthConfig struct {
+	Enabled            bool
+	Interval           time.Duration
+	Anchors            []string
+	URLs               []string
+	TracerouteInterval time.Duration
+	Retention          time.Duration
+}
+
+// TopologyConfig sets how often a device of each network is traced to find the routers in
+// front of the networks, and how many devices of a network the topology shows
+type TopologyConfig struct {
+	Enabled    bool
+	Interval   time.Duration
+	MaxDevices int
+}
+
+// HTTPChecksConfig sets how often the http checks are looked at for being due, how long each
+// request may take and how long the results are kept
+type HTTPChecksConfig struct {
+	Enabled   bool
+	Interval  time.Duration
+	Timeout   time.Duration
+	Retention time.Duration
+}
+
+// ServiceChecksConfig sets which open ports of the devices with the tag are connected to
+// every interval to track the availability of each service
+type ServiceChecksConfig struct {
+	Enabled   bool
+	Interval  time.Duration
+	Tag       string
+	Ports     []int
+	Timeout   time.Duration
+	Retention time.Duration
+}
+
+// FullScanConfig sets the background sweep of every tcp port of the devices with the tag, a
+// chunk of ports of each device is scanned every interval so a sweep runs over hours
+type FullScanConfig struct {
+	Enabled        bool
+	Tag            string
+	Interval       time.Duration
+	ChunkSize      int
+	RescanInterval time.Duration
+}
+
+// SpeedTestConfig sets how the throughput of the internet connection is measured, either by
+// an http download and upload or by the iperf3 client against an iperf3 server
+type SpeedTestConfig struct {
+	Enabled      bool
+	Interval     time.Duration
+	Method       string
+	DownloadURL  string
+	UploadURL    string
+	UploadSize   int
+	Iperf3Server string
+	Iperf3Port   int
+	Duration     time.Duration
+}
+
+// EventHistoryConfig sets the persistence of bus events and errors, records are removed once
+// older than the retention or past the newest MaxRecords
+type EventHistoryConfig struct {
+	Enabled       bool
+	Retention     time.Duration
+	MaxRecords    int
+	FlushInterval time.Duration
+}
+
+// ArpWatchConfig sets how the MACs answering for each address are tracked.  An address
+// answered for by a new MAC is raised as a change, two MACs taking turns within the window as
+// a duplicate address.  The excluded addresses and prefixes, such as the VRRP addresses of a
+// router pair, are not raised.
+type ArpWatchConfig struct {
+	Enabled   bool
+	Window    time.Duration
+	Exclude   []string
+	Retention time.Duration
+}
+
+// DHCPWatchConfig sets the probe broadcasting a DHCP discover to find the DHCP servers and
+// default gateways handed out on the segment.  A server or gateway not in the trusted lists is
+// raised when first seen, with empty lists the ones seen by the first probe are trusted.
+type DHCPWatchConfig struct {
+	Enabled       bool
+	Interval      time.Duration
+	Timeout       time.Duration
+	ListenAddress string
+	Servers       []string
+	Gateways      []string
+}
+
+// QuotasConfig sets how often the netflow traffic of the devices is compared against their
+// bandwidth quotas.  A device is raised once per period when it reaches the warning percent
+// and once when it uses up the quota.
+type QuotasConfig struct {
+	Enabled     bool
+	Interval    time.Duration
+	WarnPercent int
+}
+
+// ExclusionsConfig lists the addresses and prefixes mason never scans, pings or port scans,
+// such as fragile OT gear.  They are added to the exclusions managed from the WUI.
+type ExclusionsConfig struct {
+	Scan     []string
+	Ping     []string
+	PortScan []string
+}
+
+// CaptureConfig bounds the packet captures started from the WUI or cli, the pcap files are
+// kept in the directory until removed or older than the retention.  Captures need net raw
+// privileges of the server itself, they are not sent through the privileged helper.
+type CaptureConfig struct {
+	Enabled     bool
+	Directory   string
+	MaxDuration time.Duration
+	MaxSize     string
+	MaxRunning  int
+	SnapLen     int
+	Promiscuous bool
+	Retention   time.Duration
+}
+
+// ProvidersConfig enables the discovery and enrichment providers registered by the packages
+// compiled into the binary (see pkg/provider), Settings holds the options of each provider
+// by name and is only read from the config file
+type ProvidersConfig struct {
+	Discovery  []string
+	Enrichment []string
+	Interval   time.Duration
+	Settings   map[string]provider.Settings
+}
+
+// IdentityConfig sets what identifies a device.  Keyed by mac a device found at a new
+// address is merged with its record at the old one, keyed by ip each address is a device.
+// Devices rotating a randomized mac are merged by the identity they announce about themselves.
+type IdentityConfig struct {
+	Key                 string
+	ReconcileInterval   time.Duration
+	CorrelateRandomized bool
+}
+
+// HelperConfig points to the privileged helper which sends the raw icmp and arp packets, so
+// the server itself runs without net admin privileges.
+type HelperConfig struct {
+	Socket string
 }
 
 type Config struct {
 	ConfigDirectory string
+	Helper          *HelperConfig
+	Offline         *OfflineConfig
+	Ipam            *IpamConfig
+	SoftDelete      *SoftDeleteConfig
+	Consistency     *ConsistencyConfig
+	Site            *SiteConfig
+	InternetHealth  *InternetHealthConfig
+	Topology        *TopologyConfig
+	HTTPChecks      *HTTPChecksConfig
+	ServiceChecks   *ServiceChecksConfig
+	FullScan        *FullScanConfig
+	SpeedTest       *SpeedTestConfig
+	EventHistory    *EventHistoryConfig
+	Identity        *IdentityConfig
+	ArpWatch        *ArpWatchConfig
+	DHCPWatch       *DHCPWatchConfig
+	Quotas          *QuotasConfig
+	Exclusions      *ExclusionsConfig
+	Capture         *CaptureConfig
+	Providers       *ProvidersConfig
 	Store           *Store
 	Wui             *WuiConfig
 	Tui             *TuiConfig
@@ -51,8 +263,22 @@ type Config struct {
 	Pinger          *pinger.Config
 	Enrichment      *enrichment.Config
 	NetFlows        *netflows.Config
+	DNSLog          *dnslog.Config
+	Syslog          *syslog.Config
+	Hooks           *hooks.Config
+	Notify          *notify.Config
 	Asn             *asn.Config
 	Oui             *oui.Config
+	RateLimit       *ratelimit.Config
+	Agent           *agent.Config
+	Exporter        *exporter.Config
+	Kubernetes      *kubernetes.Config
+	Unifi           *unifi.Config
+	Netbox          *netbox.Config
+	Backup          *backup.Config
+	Cloud           *cloud.Config
+	Logging         *logging.Config
+	Tracing         *tracing.Config
 }
 
 var (
@@ -74,6 +300,649 @@ func SetFlags(fs *pflag.FlagSet, cfg *Config) {
 		"location of config file(s)",
 	)
 
+	flagset.String(
+		fs,
+		&cfg.Helper.Socket,
+		"helper",
+		"socket",
+		"",
+		"unix socket of the privileged helper (mason helper) to send raw icmp and arp packets through",
+	)
+
+	flagset.Bool(
+		fs,
+		&cfg.Offline.Enabled,
+		"offline",
+		"enabled",
+		false,
+		"disable all outbound internet dependencies, only local data files are used",
+	)
+
+	flagset.Int(
+		fs,
+		&cfg.Ipam.WarnThreshold,
+		"ipam",
+		"warnthreshold",
+		80,
+		"warn when the percentage of used and reserved addresses in a network reaches this level, 0 to disable",
+	)
+
+	flagset.Duration(
+		fs,
+		&cfg.SoftDelete.GracePeriod,
+		"softdelete",
+		"graceperiod",
+		7*24*time.Hour,
+		"how long deleted devices and networks can be restored before they are purged",
+	)
+
+	flagset.Bool(
+		fs,
+		&cfg.Consistency.CheckOnStartup,
+		"consistency",
+		"checkonstartup",
+		true,
+		"check the stores for records referencing missing data at startup",
+	)
+	flagset.Bool(
+		fs,
+		&cfg.Consistency.Repair,
+		"consistency",
+		"repair",
+		false,
+		"repair or quarantine inconsistent records found by the startup check",
+	)
+
+	flagset.String(
+		fs,
+		&cfg.Site.Name,
+		"site",
+		"name",
+		"local",
+		"site of the networks and devices which are not assigned to another site",
+	)
+
+	internetHealthMajorKey := "internethealth"
+
+	flagset.Bool(
+		fs,
+		&cfg.InternetHealth.Enabled,
+		internetHealthMajorKey,
+		"enabled",
+		true,
+		"regularly measure the latency of the internet connection",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.InternetHealth.Interval,
+		internetHealthMajorKey,
+		"interval",
+		5*time.Minute,
+		"interval between internet health probes",
+	)
+	flagset.StringSlice(
+		fs,
+		&cfg.InternetHealth.Anchors,
+		internetHealthMajorKey,
+		"anchors",
+		[]string{"1.1.1.1", "8.8.8.8", internetHealthIspAnchor},
+		"addresses pinged to measure the internet connection, isp is the first public hop",
+	)
+	flagset.StringSlice(
+		fs,
+		&cfg.InternetHealth.URLs,
+		internetHealthMajorKey,
+		"urls",
+		[]string{"https://www.cloudflare.com", "https://www.google.com"},
+		"urls fetched to measure http latency",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.InternetHealth.TracerouteInterval,
+		internetHealthMajorKey,
+		"tracerouteinterval",
+		time.Hour,
+		"interval between traceroutes to the anchors (requires privileged icmp), 0 to disable",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.InternetHealth.Retention,
+		internetHealthMajorKey,
+		"retention",
+		30*24*time.Hour,
+		"how long internet health probes are kept",
+	)
+
+	topologyMajorKey := "topology"
+
+	flagset.Bool(
+		fs,
+		&cfg.Topology.Enabled,
+		topologyMajorKey,
+		"enabled",
+		true,
+		"trace a device of each network to layer the networks under their routers (requires privileged icmp)",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Topology.Interval,
+		topologyMajorKey,
+		"interval",
+		6*time.Hour,
+		"interval between the traceroutes of the networks",
+	)
+	flagset.Int(
+		fs,
+		&cfg.Topology.MaxDevices,
+		topologyMajorKey,
+		"maxdevices",
+		25,
+		"most devices of a network shown in the topology",
+	)
+
+	httpChecksMajorKey := "httpchecks"
+
+	flagset.Bool(
+		fs,
+		&cfg.HTTPChecks.Enabled,
+		httpChecksMajorKey,
+		"enabled",
+		true,
+		"run the configured http endpoint checks",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.HTTPChecks.Interval,
+		httpChecksMajorKey,
+		"interval",
+		15*time.Second,
+		"interval between looking for http checks which are due",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.HTTPChecks.Timeout,
+		httpChecksMajorKey,
+		"timeout",
+		10*time.Second,
+		"time allowed for each http check request",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.HTTPChecks.Retention,
+		httpChecksMajorKey,
+		"retention",
+		30*24*time.Hour,
+		"how long http check results are kept",
+	)
+
+	serviceChecksMajorKey := "servicechecks"
+
+	flagset.Bool(
+		fs,
+		&cfg.ServiceChecks.Enabled,
+		serviceChecksMajorKey,
+		"enabled",
+		true,
+		"regularly connect to the open well known ports of the tagged devices",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.ServiceChecks.Interval,
+		serviceChecksMajorKey,
+		"interval",
+		5*time.Minute,
+		"interval between service checks",
+	)
+	flagset.String(
+		fs,
+		&cfg.ServiceChecks.Tag,
+		serviceChecksMajorKey,
+		"tag",
+		"server",
+		"tag of the devices whose services are checked",
+	)
+	flagset.IntSlice(
+		fs,
+		&cfg.ServiceChecks.Ports,
+		serviceChecksMajorKey,
+		"ports",
+		[]int{
+			21, 22, 25, 53, 80, 143, 389, 443, 445, 636, 993,
+			1433, 3306, 3389, 5432, 6379, 8080, 8443,
+		},
+		"well known ports checked when found open by the port scan",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.ServiceChecks.Timeout,
+		serviceChecksMajorKey,
+		"timeout",
+		3*time.Second,
+		"time allowed for each service connect",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.ServiceChecks.Retention,
+		serviceChecksMajorKey,
+		"retention",
+		30*24*time.Hour,
+		"how long service check results are kept",
+	)
+
+	fullScanMajorKey := "fullscan"
+
+	flagset.Bool(
+		fs,
+		&cfg.FullScan.Enabled,
+		fullScanMajorKey,
+		"enabled",
+		false,
+		"sweep all 65535 tcp ports of the tagged devices in the background",
+	)
+	flagset.String(
+		fs,
+		&cfg.FullScan.Tag,
+		fullScanMajorKey,
+		"tag",
+		"fullscan",
+		"tag of the devices swept",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.FullScan.Interval,
+		fullScanMajorKey,
+		"interval",
+		time.Minute,
+		"time between the chunks of a sweep",
+	)
+	flagset.Int(
+		fs,
+		&cfg.FullScan.ChunkSize,
+		fullScanMajorKey,
+		"chunksize",
+		512,
+		"ports scanned on each device every interval",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.FullScan.RescanInterval,
+		fullScanMajorKey,
+		"rescaninterval",
+		30*24*time.Hour,
+		"time from a completed sweep to the start of the next",
+	)
+
+	speedTestMajorKey := "speedtest"
+
+	flagset.Bool(
+		fs,
+		&cfg.SpeedTest.Enabled,
+		speedTestMajorKey,
+		"enabled",
+		false,
+		"regularly measure the throughput of the internet connection",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.SpeedTest.Interval,
+		speedTestMajorKey,
+		"interval",
+		6*time.Hour,
+		"interval between speed tests",
+	)
+	flagset.String(
+		fs,
+		&cfg.SpeedTest.Method,
+		speedTestMajorKey,
+		"method",
+		speedTestMethodHTTP,
+		"how throughput is measured: http or iperf3",
+	)
+	flagset.String(
+		fs,
+		&cfg.SpeedTest.DownloadURL,
+		speedTestMajorKey,
+		"downloadurl",
+		"https://speed.cloudflare.com/__down?bytes=25000000",
+		"url downloaded by the http speed test",
+	)
+	flagset.String(
+		fs,
+		&cfg.SpeedTest.UploadURL,
+		speedTestMajorKey,
+		"uploadurl",
+		"https://speed.cloudflare.com/__up",
+		"url posted to by the http speed test, blank to skip the upload",
+	)
+	flagset.Int(
+		fs,
+		&cfg.SpeedTest.UploadSize,
+		speedTestMajorKey,
+		"uploadsize",
+		10_000_000,
+		"bytes posted by the http speed test",
+	)
+	flagset.String(
+		fs,
+		&cfg.SpeedTest.Iperf3Server,
+		speedTestMajorKey,
+		"iperf3server",
+		"",
+		"iperf3 server used by the iperf3 speed test",
+	)
+	flagset.Int(
+		fs,
+		&cfg.SpeedTest.Iperf3Port,
+		speedTestMajorKey,
+		"iperf3port",
+		5201,
+		"port of the iperf3 server",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.SpeedTest.Duration,
+		speedTestMajorKey,
+		"duration",
+		10*time.Second,
+		"length of each direction of the iperf3 speed test",
+	)
+
+	eventHistoryMajorKey := "eventhistory"
+
+	flagset.Bool(
+		fs,
+		&cfg.EventHistory.Enabled,
+		eventHistoryMajorKey,
+		"enabled",
+		false,
+		"keep bus events and errors in the store across restarts",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.EventHistory.Retention,
+		eventHistoryMajorKey,
+		"retention",
+		30*24*time.Hour,
+		"how long events are kept",
+	)
+	flagset.Int(
+		fs,
+		&cfg.EventHistory.MaxRecords,
+		eventHistoryMajorKey,
+		"maxrecords",
+		100_000,
+		"most events kept, 0 for no limit",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.EventHistory.FlushInterval,
+		eventHistoryMajorKey,
+		"flushinterval",
+		5*time.Second,
+		"interval between writes of new events to the store",
+	)
+
+	identityMajorKey := "identity"
+
+	flagset.String(
+		fs,
+		&cfg.Identity.Key,
+		identityMajorKey,
+		"key",
+		IdentityMAC,
+		"what identifies a device [mac,ip], by mac a device keeps its history when its address changes",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Identity.ReconcileInterval,
+		identityMajorKey,
+		"reconcileinterval",
+		time.Hour,
+		"interval between merges of offline devices into the device with the same mac at a new address",
+	)
+	flagset.Bool(
+		fs,
+		&cfg.Identity.CorrelateRandomized,
+		identityMajorKey,
+		"correlaterandomized",
+		true,
+		"merge devices with randomized macs which announce the same mdns name, dhcp hostname and fingerprint or dns name",
+	)
+
+	arpWatchMajorKey := "arpwatch"
+
+	flagset.Bool(
+		fs,
+		&cfg.ArpWatch.Enabled,
+		arpWatchMajorKey,
+		"enabled",
+		true,
+		"raise an event when the mac answering for an address changes or two macs claim it",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.ArpWatch.Window,
+		arpWatchMajorKey,
+		"window",
+		time.Hour,
+		"a mac seen again at its address within the window after another mac is a duplicate address",
+	)
+	flagset.StringSlice(
+		fs,
+		&cfg.ArpWatch.Exclude,
+		arpWatchMajorKey,
+		"exclude",
+		[]string{},
+		"addresses or prefixes whose mac is expected to change, such as vrrp or ha addresses",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.ArpWatch.Retention,
+		arpWatchMajorKey,
+		"retention",
+		90*24*time.Hour,
+		"how long a mac binding is kept after it was last seen",
+	)
+
+	dhcpWatchMajorKey := "dhcpwatch"
+
+	flagset.Bool(
+		fs,
+		&cfg.DHCPWatch.Enabled,
+		dhcpWatchMajorKey,
+		"enabled",
+		false,
+		"broadcast dhcp discovers to find unknown dhcp servers and gateways (requires privileges to bind the dhcp client port)",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.DHCPWatch.Interval,
+		dhcpWatchMajorKey,
+		"interval",
+		15*time.Minute,
+		"interval between dhcp discovers",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.DHCPWatch.Timeout,
+		dhcpWatchMajorKey,
+		"timeout",
+		5*time.Second,
+		"how long to wait for offers after a discover",
+	)
+	flagset.String(
+		fs,
+		&cfg.DHCPWatch.ListenAddress,
+		dhcpWatchMajorKey,
+		"listenaddress",
+		":68",
+		"address to listen for dhcp offers",
+	)
+	flagset.StringSlice(
+		fs,
+		&cfg.DHCPWatch.Servers,
+		dhcpWatchMajorKey,
+		"servers",
+		[]string{},
+		"addresses of the trusted dhcp servers",
+	)
+	flagset.StringSlice(
+		fs,
+		&cfg.DHCPWatch.Gateways,
+		dhcpWatchMajorKey,
+		"gateways",
+		[]string{},
+		"addresses of the trusted default gateways",
+	)
+
+	quotasMajorKey := "quotas"
+
+	flagset.Bool(
+		fs,
+		&cfg.Quotas.Enabled,
+		quotasMajorKey,
+		"enabled",
+		true,
+		"compare the netflow traffic of devices against their bandwidth quotas (requires netflows)",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Quotas.Interval,
+		quotasMajorKey,
+		"interval",
+		15*time.Minute,
+		"interval between bandwidth quota checks",
+	)
+	flagset.Int(
+		fs,
+		&cfg.Quotas.WarnPercent,
+		quotasMajorKey,
+		"warnpercent",
+		80,
+		"percent of a quota used which raises a warning, 0 to only raise exceeded quotas",
+	)
+
+	exclusionsMajorKey := "exclusions"
+
+	flagset.StringSlice(
+		fs,
+		&cfg.Exclusions.Scan,
+		exclusionsMajorKey,
+		"scan",
+		[]string{},
+		"addresses or prefixes never network scanned or actively enriched, port scans included",
+	)
+	flagset.StringSlice(
+		fs,
+		&cfg.Exclusions.Ping,
+		exclusionsMajorKey,
+		"ping",
+		[]string{},
+		"addresses or prefixes never pinged",
+	)
+	flagset.StringSlice(
+		fs,
+		&cfg.Exclusions.PortScan,
+		exclusionsMajorKey,
+		"portscan",
+		[]string{},
+		"addresses or prefixes never port scanned",
+	)
+
+	captureMajorKey := "capture"
+
+	flagset.Bool(
+		fs,
+		&cfg.Capture.Enabled,
+		captureMajorKey,
+		"enabled",
+		false,
+		"allow packet captures to be started from the wui and cli (requires net raw privileges)",
+	)
+	flagset.String(
+		fs,
+		&cfg.Capture.Directory,
+		captureMajorKey,
+		"directory",
+		"data/captures",
+		"directory of the pcap files",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Capture.MaxDuration,
+		captureMajorKey,
+		"maxduration",
+		5*time.Minute,
+		"longest a capture may run",
+	)
+	flagset.String(
+		fs,
+		&cfg.Capture.MaxSize,
+		captureMajorKey,
+		"maxsize",
+		"100MB",
+		"largest pcap file a capture may write",
+	)
+	flagset.Int(
+		fs,
+		&cfg.Capture.MaxRunning,
+		captureMajorKey,
+		"maxrunning",
+		2,
+		"captures which may run at the same time",
+	)
+	flagset.Int(
+		fs,
+		&cfg.Capture.SnapLen,
+		captureMajorKey,
+		"snaplen",
+		nettools.DefaultCaptureSnapLen,
+		"bytes of each packet kept",
+	)
+	flagset.Bool(
+		fs,
+		&cfg.Capture.Promiscuous,
+		captureMajorKey,
+		"promiscuous",
+		false,
+		"put the interface in promiscuous mode while capturing",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Capture.Retention,
+		captureMajorKey,
+		"retention",
+		7*24*time.Hour,
+		"how long finished captures are kept, 0 to keep them until removed",
+	)
+
+	providersMajorKey := "providers"
+
+	flagset.StringSlice(
+		fs,
+		&cfg.Providers.Discovery,
+		providersMajorKey,
+		"discovery",
+		[]string{},
+		"registered discovery providers to run",
+	)
+	flagset.StringSlice(
+		fs,
+		&cfg.Providers.Enrichment,
+		providersMajorKey,
+		"enrichment",
+		[]string{},
+		"registered enrichment providers to run on each device enrichment",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Providers.Interval,
+		providersMajorKey,
+		"interval",
+		15*time.Minute,
+		"interval between runs of the discovery providers",
+	)
+
 	wuiConfigMajorKey := "wui"
 
 	flagset.Bool(fs, &cfg.Wui.Enabled, wuiConfigMajorKey, "enabled", true, "enable the web ui")
@@ -85,6 +954,14 @@ func SetFlags(fs *pflag.FlagSet, cfg *Config) {
 		":4380",
 		"address to list for http requests",
 	)
+	flagset.String(
+		fs,
+		&cfg.Wui.ExternalURL,
+		wuiConfigMajorKey,
+		"externalurl",
+		"",
+		"url the web ui is reached at, used by the device qr code labels (blank uses the address the labels were opened with)",
+	)
 
 	tuiConfigMajorKey := "tui"
 
@@ -129,18 +1006,53 @@ const (
 func defaultConfig() *Config {
 	c := &Config{
 		Store: &Store{
-			Combo:  &combostore.Config{},
-			Sqlite: &sqlitestore.Config{},
+			Combo:      &combostore.Config{},
+			Sqlite:     &sqlitestore.Config{},
+			Timeseries: &tsstore.Config{},
 		},
-		Wui:        &WuiConfig{},
-		Tui:        &TuiConfig{},
-		Bus:        &bus.Config{},
-		Discovery:  &discovery.Config{},
-		Pinger:     &pinger.Config{},
-		Enrichment: &enrichment.Config{},
-		NetFlows:   &netflows.Config{},
-		Asn:        &asn.Config{},
-		Oui:        &oui.Config{},
+		Helper:         &HelperConfig{},
+		Offline:        &OfflineConfig{},
+		Ipam:           &IpamConfig{},
+		SoftDelete:     &SoftDeleteConfig{},
+		Consistency:    &ConsistencyConfig{},
+		Site:           &SiteConfig{},
+		InternetHealth: &InternetHealthConfig{},
+		Topology:       &TopologyConfig{},
+		HTTPChecks:     &HTTPChecksConfig{},
+		ServiceChecks:  &ServiceChecksConfig{},
+		FullScan:       &FullScanConfig{},
+		SpeedTest:      &SpeedTestConfig{},
+		EventHistory:   &EventHistoryConfig{},
+		Identity:       &IdentityConfig{},
+		ArpWatch:       &ArpWatchConfig{},
+		DHCPWatch:      &DHCPWatchConfig{},
+		Quotas:         &QuotasConfig{},
+		Exclusions:     &ExclusionsConfig{},
+		Capture:        &CaptureConfig{},
+		Providers:      &ProvidersConfig{},
+		Wui:            &WuiConfig{},
+		Tui:            &TuiConfig{},
+		Bus:            &bus.Config{},
+		Discovery:      &discovery.Config{},
+		Pinger:         &pinger.Config{},
+		Enrichment:     &enrichment.Config{},
+		NetFlows:       &netflows.Config{},
+		DNSLog:         &dnslog.Config{},
+		Syslog:         &syslog.Config{},
+		Hooks:          &hooks.Config{},
+		Notify:         &notify.Config{},
+		Asn:            &asn.Config{},
+		Oui:            &oui.Config{},
+		RateLimit:      &ratelimit.Config{},
+		Agent:          &agent.Config{},
+		Exporter:       &exporter.Config{},
+		Kubernetes:     &kubernetes.Config{},
+		Unifi:          &unifi.Config{},
+		Netbox:         &netbox.Config{},
+		Backup:         &backup.Config{},
+		Cloud:          &cloud.Config{},
+		Logging:        &logging.Config{},
+		Tracing:        &tracing.Config{},
 	}
 
 	// viper.SetConfigName(configName)
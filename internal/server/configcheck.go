@@ -0,0 +1,211 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/networkables/mason/internal/flagset"
+)
+
+// ConfigProblem is a setting mason can not start with, the problem tells how to fix it
+type ConfigProblem struct {
+	Key     string
+	Problem string
+}
+
+func (p ConfigProblem) Error() string {
+	return p.Key + ": " + p.Problem
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Validate checks the settings fit together, the problems found are joined into the error
+func (c *Config) Validate() error {
+	problems := c.Problems()
+	errs := make([]error, 0, len(problems))
+	for _, p := range problems {
+		errs = append(errs, p)
+	}
+	return errors.Join(errs...)
+}
+
+// Problems returns every setting mason can not start with, an empty list when the config is
+// usable
+func (c *Config) Problems() []ConfigProblem {
+	problems := make([]ConfigProblem, 0)
+	add := func(key string, format string, args ...any) {
+		problems = append(problems, ConfigProblem{Key: key, Problem: fmt.Sprintf(format, args...)})
+	}
+
+	walkConfig("", reflect.ValueOf(c).Elem(), func(key string, name string, v reflect.Value) {
+		switch {
+		case v.Type() == durationType:
+			if v.Int() < 0 {
+				add(key, "is %s, durations can not be negative", time.Duration(v.Int()))
+			}
+		case v.Kind() == reflect.String && name == "ListenAddress":
+			if err := checkListenAddress(v.String()); err != nil {
+				add(key, "%v, use host:port or :port", err)
+			}
+		case v.Kind() == reflect.Int && strings.HasSuffix(name, "Port"):
+			if v.Int() < 0 || v.Int() > 65535 {
+				add(key, "is %d, ports are 1 to 65535 (0 for the default)", v.Int())
+			}
+		case v.Kind() == reflect.Slice && name == "Ports":
+			ports, ok := v.Interface().([]int)
+			if !ok {
+				return
+			}
+			for _, port := range ports {
+				if port < 1 || port > 65535 {
+					add(key, "has port %d, ports are 1 to 65535", port)
+				}
+			}
+		}
+	})
+
+	// the run loop ticks on these while the features are disabled too
+	for _, iv := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"discovery.checkinterval", c.Discovery.CheckInterval},
+		{"discovery.snmp.arptablerescaninterval", c.Discovery.Snmp.ArpTableRescanInterval},
+		{"discovery.snmp.interfacerescaninterval", c.Discovery.Snmp.InterfaceRescanInterval},
+		{"discovery.hostarp.interval", c.Discovery.HostArp.Interval},
+		{"discovery.ndp.interval", c.Discovery.Ndp.Interval},
+		{"pinger.checkinterval", c.Pinger.CheckInterval},
+		{"enrichment.virtual.rescaninterval", c.Enrichment.Virtual.RescanInterval},
+		{"internethealth.interval", c.InternetHealth.Interval},
+		{"topology.interval", c.Topology.Interval},
+		{"httpchecks.interval", c.HTTPChecks.Interval},
+		{"servicechecks.interval", c.ServiceChecks.Interval},
+		{"fullscan.interval", c.FullScan.Interval},
+		{"dhcpwatch.interval", c.DHCPWatch.Interval},
+		{"quotas.interval", c.Quotas.Interval},
+		{"speedtest.interval", c.SpeedTest.Interval},
+		{"exporter.interval", c.Exporter.Interval},
+		{"kubernetes.interval", c.Kubernetes.Interval},
+		{"unifi.interval", c.Unifi.Interval},
+		{"netbox.interval", c.Netbox.Interval},
+		{"backup.interval", c.Backup.Interval},
+		{"cloud.interval", c.Cloud.Interval},
+		{"providers.interval", c.Providers.Interval},
+		{"identity.reconcileinterval", c.Identity.ReconcileInterval},
+	} {
+		if iv.value == 0 {
+			add(iv.key, "is 0, intervals must be longer than zero")
+		}
+	}
+	if c.EventHistory.Enabled && c.EventHistory.FlushInterval <= 0 {
+		add("eventhistory.flushinterval", "must be longer than zero while eventhistory.enabled")
+	}
+	if c.NetFlows.Enabled && c.NetFlows.Insert.FlushInterval <= 0 {
+		add("netflows.insert.flushinterval", "must be longer than zero while netflows.enabled")
+	}
+	if c.DNSLog.Enabled && c.DNSLog.FlushInterval <= 0 {
+		add("dnslog.flushinterval", "must be longer than zero while dnslog.enabled")
+	}
+
+	if c.Wui.ExternalURL != "" {
+		u, err := url.Parse(c.Wui.ExternalURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("wui.externalurl", "%q is not a url, use http(s)://host[:port]", c.Wui.ExternalURL)
+		}
+	}
+
+	if c.FullScan.Enabled && c.FullScan.ChunkSize < 1 {
+		add("fullscan.chunksize", "is %d, at least one port is scanned each interval", c.FullScan.ChunkSize)
+	}
+
+	for _, pct := range []struct {
+		key   string
+		value int
+	}{
+		{"ipam.warnthreshold", c.Ipam.WarnThreshold},
+		{"quotas.warnpercent", c.Quotas.WarnPercent},
+	} {
+		if pct.value < 0 || pct.value > 100 {
+			add(pct.key, "is %d, a percentage is 0 to 100", pct.value)
+		}
+	}
+
+	// flows and dns queries are kept in the sqlite store, which the combo store replaces
+	if !c.Store.Combo.Enabled && !c.Store.Sqlite.Enabled {
+		add("store.sqlite.enabled", "no store is enabled, enable store.sqlite or store.combo")
+	}
+	hasFlowStore := c.Store.Sqlite.Enabled && !c.Store.Combo.Enabled
+	if c.NetFlows.Enabled && !hasFlowStore {
+		add(
+			"netflows.enabled",
+			"flows are stored in the sqlite store, enable store.sqlite without store.combo or disable netflows",
+		)
+	}
+	if c.DNSLog.Enabled && !hasFlowStore {
+		add(
+			"dnslog.enabled",
+			"dns queries are stored in the sqlite store, enable store.sqlite without store.combo or disable dnslog",
+		)
+	}
+	return problems
+}
+
+// walkConfig calls fn with the key and field name of every setting below the section, the key
+// is the flag the setting was registered under and else its lowercased field path
+func walkConfig(
+	prefix string,
+	v reflect.Value,
+	fn func(key string, name string, v reflect.Value),
+) {
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key := strings.ToLower(field.Name)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct {
+			walkConfig(key, fv, fn)
+			continue
+		}
+		if flagKey, ok := flagset.KeyOf(fv.Addr().Interface()); ok {
+			key = flagKey
+		}
+		fn(key, field.Name, fv)
+	}
+}
+
+// checkListenAddress accepts an empty address, the listener is then not started
+func checkListenAddress(addr string) error {
+	if addr == "" {
+		return nil
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("%q is not an address", addr)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 0 || p > 65535 {
+		return fmt.Errorf("%q has no valid port", addr)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/pflag"
+
+	"github.com/networkables/mason/internal/agent"
+	"github.com/networkables/mason/internal/asn"
+	"github.com/networkables/mason/internal/backup"
+	"github.com/networkables/mason/internal/bus"
+	"github.com/networkables/mason/internal/cloud"
+	"github.com/networkables/mason/internal/combostore"
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/dnslog"
+	"github.com/networkables/mason/internal/enrichment"
+	"github.com/networkables/mason/internal/exporter"
+	"github.com/networkables/mason/internal/flagset"
+	"github.com/networkables/mason/internal/hooks"
+	"github.com/networkables/mason/internal/kubernetes"
+	"github.com/networkables/mason/internal/logging"
+	"github.com/networkables/mason/internal/netbox"
+	"github.com/networkables/mason/internal/netflows"
+	"github.com/networkables/mason/internal/notify"
+	"github.com/networkables/mason/internal/oui"
+	"github.com/networkables/mason/internal/pinger"
+	"github.com/networkables/mason/internal/ratelimit"
+	"github.com/networkables/mason/internal/sqlitestore"
+	"github.com/networkables/mason/internal/syslog"
+	"github.com/networkables/mason/internal/tracing"
+	"github.com/networkables/mason/internal/tsstore"
+	"github.com/networkables/mason/internal/unifi"
+)
+
+// flaggedConfig returns a config holding the defaults of every flag, as mason server starts with
+func flaggedConfig() *Config {
+	c := defaultConfig()
+	f := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	SetFlags(f, c)
+	discovery.SetFlags(f, c.Discovery)
+	combostore.SetFlags(f, c.Store.Combo)
+	sqlitestore.SetFlags(f, c.Store.Sqlite)
+	tsstore.SetFlags(f, c.Store.Timeseries)
+	bus.SetFlags(f, c.Bus)
+	pinger.SetFlags(f, c.Pinger)
+	enrichment.SetFlags(f, c.Enrichment)
+	netflows.SetFlags(f, c.NetFlows)
+	dnslog.SetFlags(f, c.DNSLog)
+	syslog.SetFlags(f, c.Syslog)
+	hooks.SetFlags(f, c.Hooks)
+	notify.SetFlags(f, c.Notify)
+	asn.SetFlags(f, c.Asn)
+	oui.SetFlags(f, c.Oui)
+	ratelimit.SetFlags(f, c.RateLimit)
+	agent.SetFlags(f, c.Agent)
+	exporter.SetFlags(f, c.Exporter)
+	kubernetes.SetFlags(f, c.Kubernetes)
+	unifi.SetFlags(f, c.Unifi)
+	netbox.SetFlags(f, c.Netbox)
+	backup.SetFlags(f, c.Backup)
+	cloud.SetFlags(f, c.Cloud)
+	logging.SetFlags(f, c.Logging)
+	tracing.SetFlags(f, c.Tracing)
+	return c
+}
+
+func TestConfig_Problems(t *testing.T) {
+	tests := map[string]struct {
+		change func(c *Config)
+		want   []ConfigProblem
+	}{
+		"Defaults": {
+			change: func(c *Config) {},
+			want:   []ConfigProblem{},
+		},
+		"NegativeDuration": {
+			change: func(c *Config) { c.Discovery.Icmp.Timeout = -time.Second },
+			want: []ConfigProblem{
+				{Key: "discovery.icmp.timeout", Problem: "is -1s, durations can not be negative"},
+			},
+		},
+		"ListenAddress": {
+			change: func(c *Config) { c.Wui.ListenAddress = "4000" },
+			want: []ConfigProblem{
+				{
+					Key:     "wui.listenaddress",
+					Problem: `"4000" is not an address, use host:port or :port`,
+				},
+			},
+		},
+		"ListenPort": {
+			change: func(c *Config) { c.Syslog.ListenAddress = ":70000" },
+			want: []ConfigProblem{
+				{
+					Key:     "syslog.listenaddress",
+					Problem: `":70000" has no valid port, use host:port or :port`,
+				},
+			},
+		},
+		"Port": {
+			change: func(c *Config) { c.SpeedTest.Iperf3Port = 65536 },
+			want: []ConfigProblem{
+				{Key: "speedtest.iperf3port", Problem: "is 65536, ports are 1 to 65535 (0 for the default)"},
+			},
+		},
+		"Ports": {
+			change: func(c *Config) { c.Discovery.Snmp.Ports = []int{161, 0} },
+			want: []ConfigProblem{
+				{Key: "discovery.snmp.ports", Problem: "has port 0, ports are 1 to 65535"},
+			},
+		},
+		"ZeroInterval": {
+			change: func(c *Config) { c.Pinger.CheckInterval = 0 },
+			want: []ConfigProblem{
+				{Key: "pinger.checkinterval", Problem: "is 0, intervals must be longer than zero"},
+			},
+		},
+		"FlushInterval": {
+			change: func(c *Config) {
+				c.EventHistory.Enabled = true
+				c.EventHistory.FlushInterval = 0
+			},
+			want: []ConfigProblem{
+				{
+					Key:     "eventhistory.flushinterval",
+					Problem: "must be longer than zero while eventhistory.enabled",
+				},
+			},
+		},
+		"ExternalURL": {
+			change: func(c *Config) { c.Wui.ExternalURL = "mason.lan" },
+			want: []ConfigProblem{
+				{
+					Key:     "wui.externalurl",
+					Problem: `"mason.lan" is not a url, use http(s)://host[:port]`,
+				},
+			},
+		},
+		"Percentage": {
+			change: func(c *Config) { c.Ipam.WarnThreshold = 101 },
+			want: []ConfigProblem{
+				{Key: "ipam.warnthreshold", Problem: "is 101, a percentage is 0 to 100"},
+			},
+		},
+		"Several": {
+			change: func(c *Config) {
+				c.Pinger.Timeout = -time.Second
+				c.Quotas.WarnPercent = -1
+			},
+			want: []ConfigProblem{
+				{Key: "pinger.timeout", Problem: "is -1s, durations can not be negative"},
+				{Key: "quotas.warnpercent", Problem: "is -1, a percentage is 0 to 100"},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := flaggedConfig()
+			tc.change(c)
+			if diff := cmp.Diff(tc.want, c.Problems()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := flaggedConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("want no error got %v", err)
+	}
+	c.Pinger.CheckInterval = 0
+	c.Ipam.WarnThreshold = 101
+	err := c.Validate()
+	for _, want := range []ConfigProblem{
+		{Key: "pinger.checkinterval", Problem: "is 0, intervals must be longer than zero"},
+		{Key: "ipam.warnthreshold", Problem: "is 101, a percentage is 0 to 100"},
+	} {
+		if !errors.Is(err, want) {
+			t.Fatalf("want %v in %v", want, err)
+		}
+	}
+}
+
+func TestWalkConfig_FlagKeys(t *testing.T) {
+	var c struct {
+		ConfigDirectory string
+		Section         *struct{ Timeout time.Duration }
+	}
+	c.Section = &struct{ Timeout time.Duration }{}
+	f := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flagset.String(f, &c.ConfigDirectory, "config", "directory", "config", "")
+
+	got := make([]string, 0)
+	walkConfig("", reflect.ValueOf(&c).Elem(), func(key string, name string, v reflect.Value) {
+		got = append(got, key)
+	})
+	// the timeout has no flag, its field path is used
+	want := []string{"config.directory", "section.timeout"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
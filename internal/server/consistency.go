@@ -0,0 +1,59 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+var ErrConsistencyUnsupported = errors.New("store does not support consistency checks")
+
+// CheckConsistency looks for records in the device and flow stores which reference missing
+// data, with repair the records are fixed or quarantined
+func (m *Mason) CheckConsistency(ctx context.Context, repair bool) ([]model.ConsistencyIssue, error) {
+	checkers := make([]ConsistencyChecker, 0, 2)
+	if c, ok := m.store.(ConsistencyChecker); ok {
+		checkers = append(checkers, c)
+	}
+	if c, ok := m.flowstore.(ConsistencyChecker); ok && any(m.flowstore) != any(m.store) {
+		checkers = append(checkers, c)
+	}
+	if len(checkers) == 0 {
+		return nil, ErrConsistencyUnsupported
+	}
+	issues := make([]model.ConsistencyIssue, 0)
+	for _, c := range checkers {
+		found, err := c.CheckConsistency(ctx, repair)
+		issues = append(issues, found...)
+		if err != nil {
+			return issues, err
+		}
+	}
+	return issues, nil
+}
+
+func (m *Mason) checkConsistency(ctx context.Context, repair bool) {
+	issues, err := m.CheckConsistency(ctx, repair)
+	if errors.Is(err, ErrConsistencyUnsupported) {
+		return
+	}
+	for _, issue := range issues {
+		log.Warn("store consistency", "issue", issue)
+	}
+	if err != nil {
+		m.publish(tre.New(err, "check consistency", "repair", repair))
+		return
+	}
+	if len(issues) > 0 && !repair {
+		log.Warn("store consistency issues found, run mason sys check --repair to fix them",
+			"count", len(issues))
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+var ErrDependencyNameRequired = errors.New("dependency name is required")
+
+// ListDependencies returns all device dependencies
+func (m *Mason) ListDependencies(ctx context.Context) ([]model.Dependency, error) {
+	deps, err := m.store.ListDependencies(ctx)
+	m.recordIfError(err)
+	return deps, err
+}
+
+// SaveDependency creates or updates a device dependency
+func (m *Mason) SaveDependency(ctx context.Context, dep model.Dependency) error {
+	if dep.Name == "" {
+		return ErrDependencyNameRequired
+	}
+	var err error
+	switch dep.Scope {
+	case model.DependencyScopeDevice:
+		var addr model.Addr
+		addr, err = model.ParseAddr(dep.Target)
+		if err == nil {
+			dep.Target = addr.String()
+		}
+	case model.DependencyScopeTag:
+		if !model.ValidTagName(dep.Target) {
+			err = model.ErrInvalidTagName
+		}
+	case model.DependencyScopeNetwork:
+		_, err = m.store.GetNetworkByName(ctx, dep.Target)
+	default:
+		err = model.ErrInvalidDependencyScope
+	}
+	if err != nil {
+		return err
+	}
+	if err = dep.Validate(); err != nil {
+		return err
+	}
+	return m.store.UpsertDependency(ctx, dep)
+}
+
+// RemoveDependency deletes the named dependency
+func (m *Mason) RemoveDependency(ctx context.Context, name string) error {
+	return m.store.RemoveDependency(ctx, name)
+}
+
+// DeviceParents returns the devices the device is declared to depend on
+func (m *Mason) DeviceParents(ctx context.Context, d model.Device) []model.Device {
+	return m.dependencies(ctx).Parents(d)
+}
+
+func (m *Mason) dependencies(ctx context.Context) model.Dependencies {
+	deps, err := m.store.ListDependencies(ctx)
+	if err != nil || len(deps) == 0 {
+		return model.Dependencies{}
+	}
+	return model.NewDependencies(deps, m.store.ListDevices(ctx), m.store.ListNetworks(ctx))
+}
+
+// upstreamLookup returns a func reporting the down device furthest up the dependencies of a
+// device.  A device is down when its last ping failed or when all of its service checks in
+// checksDown are failing, checksDown is nil outside of the service checks.
+func (m *Mason) upstreamLookup(
+	ctx context.Context,
+	checksDown map[model.Addr]bool,
+) func(model.Device) (model.Device, bool) {
+	ds := m.dependencies(ctx)
+	down := func(d model.Device) bool {
+		return d.PerformancePing.LastFailed || checksDown[d.Addr]
+	}
+	return func(d model.Device) (model.Device, bool) {
+		return ds.UpstreamDown(d, down)
+	}
+}
+
+// upstreamFailures groups the failures of devices behind a down parent, one event is raised
+// for each parent in the order the parents were first added
+type upstreamFailures struct {
+	events []model.EventUpstreamFailure
+}
+
+func (uf *upstreamFailures) add(parent model.Device, addr model.Addr) {
+	idx := slices.IndexFunc(uf.events, func(e model.EventUpstreamFailure) bool {
+		return e.Parent.Addr == parent.Addr
+	})
+	if idx < 0 {
+		uf.events = append(uf.events, model.EventUpstreamFailure{Parent: parent})
+		idx = len(uf.events) - 1
+	}
+	if !slices.Contains(uf.events[idx].Dependents, addr) {
+		uf.events[idx].Dependents = append(uf.events[idx].Dependents, addr)
+	}
+}
+
+// remove takes the address out of the failures, a parent left without dependents is dropped
+func (uf *upstreamFailures) remove(addr model.Addr) {
+	for idx := range uf.events {
+		uf.events[idx].Dependents = slices.DeleteFunc(
+			uf.events[idx].Dependents,
+			func(a model.Addr) bool { return a == addr },
+		)
+	}
+	uf.events = slices.DeleteFunc(uf.events, func(e model.EventUpstreamFailure) bool {
+		return len(e.Dependents) == 0
+	})
+}
+
+// pingUpstream holds the devices whose ping failure is grouped under a down parent.  The
+// groups gathered during a ping round are published as one upstream failure of each parent
+// when the next round starts, once published a group is only resolved by its parent
+// answering again.
+type pingUpstream struct {
+	mu        sync.Mutex
+	pending   upstreamFailures
+	published map[model.Addr]model.EventUpstreamFailure
+	grouped   map[model.Addr]model.Addr
+}
+
+func newPingUpstream() *pingUpstream {
+	return &pingUpstream{
+		published: make(map[model.Addr]model.EventUpstreamFailure),
+		grouped:   make(map[model.Addr]model.Addr),
+	}
+}
+
+// group puts the down device under the parent, a device joining a published group is not
+// published again
+func (pu *pingUpstream) group(parent model.Device, addr model.Addr) {
+	pu.mu.Lock()
+	defer pu.mu.Unlock()
+	if prev, ok := pu.grouped[addr]; ok && prev == parent.Addr {
+		return
+	}
+	pu.ungroupLocked(addr)
+	pu.grouped[addr] = parent.Addr
+	if e, ok := pu.published[parent.Addr]; ok {
+		e.Dependents = append(e.Dependents, addr)
+		pu.published[parent.Addr] = e
+		return
+	}
+	pu.pending.add(parent, addr)
+}
+
+// isGrouped is true when the failure of the device is grouped under a parent
+func (pu *pingUpstream) isGrouped(addr model.Addr) bool {
+	pu.mu.Lock()
+	defer pu.mu.Unlock()
+	_, ok := pu.grouped[addr]
+	return ok
+}
+
+// ungroup takes the device out of its group, false when its failure was not grouped
+func (pu *pingUpstream) ungroup(addr model.Addr) bool {
+	pu.mu.Lock()
+	defer pu.mu.Unlock()
+	return pu.ungroupLocked(addr)
+}
+
+func (pu *pingUpstream) ungroupLocked(addr model.Addr) bool {
+	parent, ok := pu.grouped[addr]
+	if !ok {
+		return false
+	}
+	delete(pu.grouped, addr)
+	pu.pending.remove(addr)
+	if e, ok := pu.published[parent]; ok {
+		e.Dependents = slices.DeleteFunc(e.Dependents, func(a model.Addr) bool { return a == addr })
+		pu.published[parent] = e
+	}
+	return true
+}
+
+// flush returns the groups gathered since the last flush, they are then published
+func (pu *pingUpstream) flush() []model.EventUpstreamFailure {
+	pu.mu.Lock()
+	defer pu.mu.Unlock()
+	events := pu.pending.events
+	pu.pending.events = nil
+	// the published events are not changed by later dependents
+	for _, e := range events {
+		e.Dependents = slices.Clone(e.Dependents)
+		pu.published[e.Parent.Addr] = e
+	}
+	return events
+}
+
+// recovered returns the recovery of the published group of the parent, the group is
+// resolved and a group not yet published is dropped.  The dependents stay grouped, their own
+// failure was never announced.
+func (pu *pingUpstream) recovered(parent model.Device) (model.EventUpstreamRecovered, bool) {
+	pu.mu.Lock()
+	defer pu.mu.Unlock()
+	pu.pending.events = slices.DeleteFunc(pu.pending.events, func(e model.EventUpstreamFailure) bool {
+		return e.Parent.Addr == parent.Addr
+	})
+	e, ok := pu.published[parent.Addr]
+	if !ok {
+		return model.EventUpstreamRecovered{}, false
+	}
+	delete(pu.published, parent.Addr)
+	return model.EventUpstreamRecovered{Parent: parent, Dependents: e.Dependents}, true
+}
@@ -0,0 +1,41 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// SetDeviceDetails replaces the name, owner, notes and asset fields of the device.  The edit is recorded
+// in the device history as a user change and published so open pages refresh.
+func (m *Mason) SetDeviceDetails(
+	ctx context.Context,
+	addr model.Addr,
+	details model.DeviceDetails,
+) error {
+	details, err := details.Clean()
+	if err != nil {
+		return err
+	}
+	d, err := m.store.GetDeviceByAddr(ctx, addr)
+	if err != nil {
+		return err
+	}
+	next := d.WithDetails(details)
+	changes := model.DeviceChanges(d, next, model.ChangeSourceUser, time.Now())
+	if len(changes) == 0 {
+		return nil
+	}
+	err = m.store.SetDeviceDetails(ctx, addr, details)
+	if err != nil {
+		return err
+	}
+	m.recordChanges(ctx, changes)
+	m.publish(model.EventDeviceEdited{Device: next, Changes: changes})
+	return nil
+}
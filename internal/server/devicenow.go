@@ -0,0 +1,167 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/enrichment"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/pinger"
+	"github.com/networkables/mason/internal/tracing"
+)
+
+// EnrichDeviceNow looks the device up again with the default enrichment fields and returns
+// the stored result.  Unlike the bulk enrich it waits for the enrichment instead of queueing
+// it, so it also works without a running server.
+func (m *Mason) EnrichDeviceNow(ctx context.Context, addr model.Addr) (model.Device, error) {
+	d, err := m.store.GetDeviceByAddr(ctx, addr)
+	if err != nil {
+		return d, err
+	}
+	fields := enrichment.DefaultEnrichmentFields(m.cfg.Enrichment)
+	// cached lookups are cleared, the same as the bulk enrich
+	d.Meta.DnsName = ""
+	d.Meta.MDNSName = ""
+	d.Meta.Manufacturer = ""
+	enrich := enrichment.BuildEnrichDeviceFunc(m.limits)
+	req := excludeEnrichment(
+		m.exclusions(ctx),
+		enrichment.EnrichDeviceRequest{Device: d, Fields: fields},
+	)
+	d, err = enrich(ctx, req)
+	if err != nil {
+		return d, tre.New(err, "enrich device", "addr", addr)
+	}
+	err = m.storeEnrichedDevice(ctx, d)
+	if err != nil {
+		return d, err
+	}
+	if !d.Virtual.LastScan.IsZero() {
+		m.linkGuests(ctx, d)
+	}
+	return m.store.GetDeviceByAddr(ctx, addr)
+}
+
+// PingDeviceNow runs a performance ping of the device outside of its schedule and returns
+// the stored result
+func (m *Mason) PingDeviceNow(ctx context.Context, addr model.Addr) (model.Device, error) {
+	d, err := m.store.GetDeviceByAddr(ctx, addr)
+	if err != nil {
+		return d, err
+	}
+	err = m.checkExcluded(ctx, addr, model.ExcludePing)
+	if err != nil {
+		return d, err
+	}
+	pingPerf, err := m.pingerWorker.PingDevice(ctx, d)
+	if err != nil {
+		return d, tre.New(err, "ping device", "addr", addr)
+	}
+	err = m.storePerformancePing(ctx, pingPerf)
+	if err != nil {
+		return d, err
+	}
+	return m.store.GetDeviceByAddr(ctx, addr)
+}
+
+func (m *Mason) storeEnrichedDevice(ctx context.Context, d model.Device) (err error) {
+	key := d.Addr.String()
+	ctx, span := tracing.Start(
+		tracing.Resume(ctx, key),
+		"mason.enriched",
+		tracing.AddrKey.String(key),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = m.updateDevice(ctx, d, model.ChangeSourceEnrichment)
+	if err != nil {
+		return tre.New(err, "enriched device store update", "addr", d.Addr)
+	}
+	if d.SNMP.Community != "" && !m.exclusions(ctx).Excludes(d.Addr, model.ExcludeScan) {
+		m.publish(discovery.DiscoverDevicesFromSNMPDevice{Device: d})
+		m.publish(discovery.DiscoverNetworksFromSNMPDevice{Device: d})
+	}
+	return nil
+}
+
+// storePerformancePing writes the ping to the device and the timeseries, a failed device
+// update does not stop the point from being written.  A device going down or coming back up
+// publishes a ping changed event, see publishPingChange.
+func (m *Mason) storePerformancePing(
+	ctx context.Context,
+	pingPerf pinger.PerformancePingResponseEvent,
+) (err error) {
+	ctx, span := tracing.Start(
+		ctx,
+		"mason.ping",
+		tracing.AddrKey.String(pingPerf.Device.Addr.String()),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	errs := make([]error, 0)
+	prev, prevErr := m.store.GetDeviceByAddr(ctx, pingPerf.Device.Addr)
+	_, err = m.updateDevice(ctx, pingPerf.Device, model.ChangeSourcePinger)
+	if err != nil {
+		errs = append(errs, tre.New(err, "update device to store", "addr", pingPerf.Device.Addr))
+	}
+	if err == nil && prevErr == nil {
+		m.publishPingChange(ctx, prev, pingPerf.Device)
+	}
+	err = m.timeseries.WritePerformancePing(
+		ctx,
+		pingPerf.Start,
+		pingPerf.Device,
+		pingPerf.Stats,
+	)
+	if err != nil {
+		errs = append(errs, tre.New(err, "write pinger point", "addr", pingPerf.Device.Addr))
+	}
+	m.exportPing(pingPerf.Start, pingPerf.Device, pingPerf.Stats)
+	m.publish(model.EventDeviceUpdated(pingPerf.Device))
+	return errors.Join(errs...)
+}
+
+// publishPingChange publishes the device going down or coming back up.  A device going down
+// behind a down parent is grouped under the parent instead, its recovery is then not
+// published, and a device left down once its parent answers is published as down then.  A
+// parent answering resolves the upstream failure of its group.
+func (m *Mason) publishPingChange(ctx context.Context, prev model.Device, d model.Device) {
+	changed := prev.PerformancePing.LastFailed != d.PerformancePing.LastFailed
+	if !d.PerformancePing.LastFailed {
+		if !changed {
+			return
+		}
+		if e, ok := m.pingUpstream.recovered(d); ok {
+			m.publish(e)
+		}
+		if !m.pingUpstream.ungroup(d.Addr) {
+			m.publish(model.EventDevicePingChanged{Device: d})
+		}
+		return
+	}
+	if parent, ok := m.upstreamLookup(ctx, nil)(d); ok {
+		// a failure published on its own before the parent went down stays its own
+		if changed || m.pingUpstream.isGrouped(d.Addr) {
+			m.pingUpstream.group(parent, d.Addr)
+		}
+		return
+	}
+	if changed || m.pingUpstream.ungroup(d.Addr) {
+		m.publish(model.EventDevicePingChanged{Device: d})
+	}
+}
+
+// publishPingUpstream publishes the ping failures grouped under each down parent since the
+// last ping round, one upstream failure for each parent
+func (m *Mason) publishPingUpstream() {
+	for _, e := range m.pingUpstream.flush() {
+		m.publish(e)
+	}
+}
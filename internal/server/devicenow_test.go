@@ -0,0 +1,202 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/networkables/mason/internal/bus"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/notify"
+	"github.com/networkables/mason/internal/pinger"
+	"github.com/networkables/mason/internal/sqlitestore"
+)
+
+// recordingBus keeps the published events, the rest of the bus is not used by the tests
+type recordingBus struct {
+	bus.Bus
+	mu     sync.Mutex
+	events []bus.Event
+}
+
+func (b *recordingBus) Publish(e bus.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, e)
+}
+
+// alerts are the alerts of the events published since the last call
+func (b *recordingBus) alerts() []notify.Alert {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	alerts := make([]notify.Alert, 0)
+	for _, e := range b.events {
+		if a, ok := toAlert(e, time.Time{}); ok {
+			alerts = append(alerts, a)
+		}
+	}
+	b.events = nil
+	return alerts
+}
+
+// testMason is a mason over a sqlite store in a temporary directory, with the devices added
+func testMason(t *testing.T, devices ...model.Device) (*Mason, *recordingBus) {
+	t.Helper()
+	store, err := sqlitestore.New(&sqlitestore.Config{
+		Enabled:               true,
+		Directory:             t.TempDir(),
+		Filename:              "unittest.db",
+		MaxOpenConnections:    2,
+		MaxIdleConnections:    1,
+		ConnectionMaxLifetime: time.Minute,
+		ConnectionMaxIdle:     time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	for _, d := range devices {
+		if err = store.AddDevice(context.Background(), d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := flaggedConfig()
+	cfg.Oui.Enabled = false
+	cfg.Asn.Enabled = false
+	b := &recordingBus{}
+	return New(WithConfig(cfg), WithBus(b), WithStore(store)), b
+}
+
+// storePing stores a ping of the device which failed or was answered
+func storePing(t *testing.T, m *Mason, d model.Device, failed bool) {
+	t.Helper()
+	d.PerformancePing.LastFailed = failed
+	err := m.storePerformancePing(context.Background(), pinger.PerformancePingResponseEvent{
+		Device: d,
+		Start:  time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStorePerformancePing_Upstream(t *testing.T) {
+	ctx := context.Background()
+	router := model.Device{Name: "router", Addr: model.MustParseAddr("192.168.1.1")}
+	children := []model.Device{
+		{Name: "nas", Addr: model.MustParseAddr("192.168.1.20")},
+		{Name: "printer", Addr: model.MustParseAddr("192.168.1.21")},
+		{Name: "camera", Addr: model.MustParseAddr("192.168.1.22")},
+	}
+	m, b := testMason(t, append([]model.Device{router}, children...)...)
+	for _, c := range children {
+		err := m.store.UpsertDependency(ctx, model.Dependency{
+			Name:   "router-" + c.Name,
+			Parent: router.Addr,
+			Scope:  model.DependencyScopeDevice,
+			Target: c.Addr.String(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	type sent struct {
+		Kind    notify.AlertKind
+		Key     string
+		Message string
+	}
+	sentAlerts := func() []sent {
+		got := make([]sent, 0)
+		for _, a := range b.alerts() {
+			got = append(got, sent{Kind: a.Kind, Key: a.Key, Message: a.Message})
+		}
+		return got
+	}
+
+	// the router goes down, then its children over the ping round
+	storePing(t, m, router, true)
+	for _, c := range children {
+		storePing(t, m, c, true)
+	}
+	m.publishPingUpstream()
+	want := []sent{
+		{
+			Kind:    notify.AlertFailure,
+			Key:     "ping/192.168.1.1",
+			Message: "router [192.168.1.1] down, no reply to ping",
+		},
+		{
+			Kind:    notify.AlertFailure,
+			Key:     "upstream/192.168.1.1",
+			Message: "upstream router [192.168.1.1] down, 3 dependent failures: 192.168.1.20, 192.168.1.21, 192.168.1.22",
+		},
+	}
+	if diff := cmp.Diff(want, sentAlerts()); diff != "" {
+		t.Fatalf("down (-want +got):\n%s", diff)
+	}
+
+	// a child answering again was never announced as down
+	storePing(t, m, children[0], false)
+	m.publishPingUpstream()
+	if diff := cmp.Diff([]sent{}, sentAlerts()); diff != "" {
+		t.Fatalf("child up (-want +got):\n%s", diff)
+	}
+
+	// the router answering resolves the grouped failure, as do the children after it
+	storePing(t, m, router, false)
+	for _, c := range children[1:] {
+		storePing(t, m, c, false)
+	}
+	m.publishPingUpstream()
+	want = []sent{
+		{
+			Kind:    notify.AlertRecovery,
+			Key:     "upstream/192.168.1.1",
+			Message: "upstream router [192.168.1.1] up, 2 dependents",
+		},
+		{Kind: notify.AlertRecovery, Key: "ping/192.168.1.1", Message: "router [192.168.1.1] up"},
+	}
+	if diff := cmp.Diff(want, sentAlerts()); diff != "" {
+		t.Fatalf("up (-want +got):\n%s", diff)
+	}
+}
+
+func TestStorePerformancePing_UpstreamLeftDown(t *testing.T) {
+	ctx := context.Background()
+	router := model.Device{Name: "router", Addr: model.MustParseAddr("192.168.1.1")}
+	nas := model.Device{Name: "nas", Addr: model.MustParseAddr("192.168.1.20")}
+	m, b := testMason(t, router, nas)
+	err := m.store.UpsertDependency(ctx, model.Dependency{
+		Name:   "router-nas",
+		Parent: router.Addr,
+		Scope:  model.DependencyScopeDevice,
+		Target: nas.Addr.String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storePing(t, m, router, true)
+	storePing(t, m, nas, true)
+	// the router answers before the next round, nothing was announced for the nas
+	storePing(t, m, router, false)
+	m.publishPingUpstream()
+	b.alerts()
+
+	// the nas is still down once the router is up, it is a failure of its own
+	storePing(t, m, nas, true)
+	got := make([]string, 0)
+	for _, a := range b.alerts() {
+		got = append(got, a.Key)
+	}
+	if diff := cmp.Diff([]string{"ping/192.168.1.20"}, got); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+// listenDHCP discovers the devices asking for an address in a known network, the hostname and
+// fingerprint of the request identify a device across MAC changes
+func (m *Mason) listenDHCP(ctx context.Context) {
+	cfg := m.cfg.Discovery.Dhcp
+	err := nettools.ListenDHCP(ctx, cfg.ListenAddress, func(req nettools.DHCPRequest) {
+		if !req.Addr.IsValid() || len(req.MAC) == 0 {
+			return
+		}
+		if !m.inKnownNetwork(ctx, req.Addr) {
+			return
+		}
+		m.publish(model.EventDeviceDiscovered{
+			Addr:         model.AddrToModelAddr(req.Addr),
+			MAC:          model.HardwareAddrToMAC(req.MAC),
+			DiscoveredBy: discovery.DHCPDiscoverySource,
+			DiscoveredAt: time.Now(),
+			Meta: model.Meta{
+				DHCPHostname:    req.Hostname,
+				DHCPFingerprint: req.Fingerprint,
+				DHCPVendorClass: req.VendorClass,
+			},
+		})
+	})
+	if err != nil {
+		m.publish(tre.New(err, "listen for dhcp requests", "address", cfg.ListenAddress))
+	}
+}
+
+// inKnownNetwork reports if the addr is in a saved network, devices are only discovered there
+func (m *Mason) inKnownNetwork(ctx context.Context, addr netip.Addr) bool {
+	return slices.ContainsFunc(m.store.ListNetworks(ctx), func(n model.Network) bool {
+		return n.Prefix.P.Contains(addr)
+	})
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+// DHCPSightings returns the DHCP servers and default gateways seen by the probe
+func (m *Mason) DHCPSightings(ctx context.Context) ([]model.DHCPSighting, error) {
+	sightings, err := m.store.ListDHCPSightings(ctx)
+	m.recordIfError(err)
+	return sightings, err
+}
+
+// TrustDHCPSighting marks the server or gateway at the address as expected on the segment
+func (m *Mason) TrustDHCPSighting(
+	ctx context.Context,
+	kind model.DHCPSightingKind,
+	addr model.Addr,
+) error {
+	m.dhcpSightingsMu.Lock()
+	defer m.dhcpSightingsMu.Unlock()
+	sightings, err := m.store.ListDHCPSightings(ctx)
+	if err != nil {
+		return err
+	}
+	idx := slices.IndexFunc(sightings, func(s model.DHCPSighting) bool {
+		return s.Kind == kind && s.Addr == addr
+	})
+	if idx < 0 {
+		return tre.New(
+			model.ErrDHCPSightingDoesNotExist,
+			"trust dhcp sighting",
+			"kind", kind,
+			"addr", addr,
+		)
+	}
+	sightings[idx].Trusted = true
+	return m.store.UpsertDHCPSighting(ctx, sightings[idx])
+}
+
+// ForgetDHCPSighting removes the server or gateway at the address, it is raised again when
+// the probe next sees it untrusted
+func (m *Mason) ForgetDHCPSighting(
+	ctx context.Context,
+	kind model.DHCPSightingKind,
+	addr model.Addr,
+) error {
+	m.dhcpSightingsMu.Lock()
+	defer m.dhcpSightingsMu.Unlock()
+	return m.store.RemoveDHCPSighting(ctx, kind, addr)
+}
+
+// probeDHCP broadcasts a discover and records the servers answering and the gateways they
+// hand out.  A server or gateway seen for the first time is published unless it is trusted,
+// the ones seen by the first probe are trusted when no trusted lists are configured.
+func (m *Mason) probeDHCP(ctx context.Context) {
+	cfg := m.cfg.DHCPWatch
+	if !cfg.Enabled || m.IsOffline() || !m.dhcpWatchRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.dhcpWatchRunning.Store(false)
+
+	offers, err := nettools.DHCPDiscover(
+		ctx,
+		nettools.WithDHCPProbeReplyTimeout(cfg.Timeout),
+		nettools.WithDHCPProbeListenAddress(cfg.ListenAddress),
+	)
+	if err != nil {
+		m.publish(tre.New(err, "dhcp discover", "listenaddress", cfg.ListenAddress))
+		return
+	}
+	log.Debug("dhcp discover", "offers", len(offers))
+
+	m.dhcpSightingsMu.Lock()
+	defer m.dhcpSightingsMu.Unlock()
+	known, err := m.store.ListDHCPSightings(ctx)
+	if err != nil {
+		m.recordIfError(err)
+		return
+	}
+	firstRun := len(known) == 0 && len(cfg.Servers) == 0 && len(cfg.Gateways) == 0
+
+	for _, seen := range model.DHCPSightingsFromOffers(offers, time.Now()) {
+		s, found := model.ObserveDHCPSighting(known, seen)
+		s.Trusted = s.Trusted || (!found && firstRun) || m.dhcpConfiguredTrust(s)
+		err = m.store.UpsertDHCPSighting(ctx, s)
+		if err != nil {
+			m.recordIfError(err)
+			continue
+		}
+		if !found && !s.Trusted {
+			m.publish(model.EventRogueDHCP(s))
+		}
+	}
+}
+
+// dhcpConfiguredTrust reports if the sighting is in the configured trusted lists
+func (m *Mason) dhcpConfiguredTrust(s model.DHCPSighting) bool {
+	trusted := m.cfg.DHCPWatch.Servers
+	if s.Kind == model.DHCPGatewaySighting {
+		trusted = m.cfg.DHCPWatch.Gateways
+	}
+	return slices.Contains(trusted, s.Addr.String())
+}
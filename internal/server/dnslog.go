@@ -0,0 +1,52 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// recentDomainsLimit is the number of domains shown for a device
+const recentDomainsLimit = 25
+
+func (m *Mason) writeDNSQueries(ctx context.Context, queries []model.DNSQuery) {
+	err := m.flowstore.AddDNSQueries(ctx, queries)
+	if err != nil {
+		m.publish(tre.New(err, "write dns queries", "count", len(queries)))
+	}
+}
+
+// RecentDomains returns the domains most recently queried by the device, nothing while dns
+// query collection is disabled
+func (m *Mason) RecentDomains(ctx context.Context, addr model.Addr) ([]model.DomainSummary, error) {
+	if !m.cfg.DNSLog.Enabled || m.flowstore == nil {
+		return nil, nil
+	}
+	domains, err := m.flowstore.RecentDomains(ctx, addr, recentDomainsLimit)
+	m.recordIfError(err)
+	return domains, err
+}
+
+// purgeDNSQueries removes the queries older than the retention
+func (m *Mason) purgeDNSQueries(ctx context.Context) {
+	cfg := m.cfg.DNSLog
+	if !cfg.Enabled || m.flowstore == nil {
+		return
+	}
+	removed, err := m.flowstore.PurgeDNSQueries(ctx, time.Now().Add(-1*cfg.Retention))
+	if err != nil {
+		m.recordIfError(err)
+		return
+	}
+	if removed > 0 {
+		log.Debug("purged dns queries", "count", removed)
+	}
+}
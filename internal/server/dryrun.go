@@ -0,0 +1,50 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/model"
+)
+
+// injectFixture adds the networks of the dry run fixture and reports its devices as
+// discovered, so the interface has something to show without a live network.  Known networks
+// are left as they are.
+func (m *Mason) injectFixture(ctx context.Context) {
+	cfg := m.cfg.Discovery.DryRun
+	if !cfg.Enabled || cfg.Fixture == "" {
+		return
+	}
+	networks, devices, err := discovery.LoadFixture(cfg.Fixture)
+	if err != nil {
+		m.publish(tre.New(err, "load dry run fixture", "file", cfg.Fixture))
+		return
+	}
+	for _, n := range networks {
+		err := m.store.AddNetwork(ctx, n)
+		if errors.Is(err, model.ErrNetworkExists) {
+			continue
+		}
+		if err != nil {
+			m.publish(tre.New(err, "add fixture network", "network", n.Name))
+			continue
+		}
+		m.limits.Network(n.Prefix.P)
+		m.publish(model.NetworkAddedEvent(n))
+	}
+	now := time.Now()
+	for _, d := range devices {
+		d.DiscoveredAt = now
+		m.publish(model.EventDeviceDiscovered(d))
+	}
+	log.Info("dry run fixture injected", "networks", len(networks), "devices", len(devices))
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/networkables/mason/internal/bus"
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	eventHistoryBuffer        = 1024
+	eventHistoryPurgeInterval = time.Hour
+)
+
+var ErrEventHistoryDisabled = errors.New("event history is not enabled")
+
+// EventHistory returns the matching events newest first, from the store when the event
+// history is enabled and from the in memory bus history otherwise
+func (m *Mason) EventHistory(
+	ctx context.Context,
+	q model.EventQuery,
+) ([]model.EventRecord, error) {
+	if m.cfg.EventHistory.Enabled {
+		return m.store.ReadEvents(ctx, q)
+	}
+	events, errs := m.bus.History(), m.bus.Errors()
+	records := make([]model.EventRecord, 0, len(events)+len(errs))
+	for _, he := range events {
+		records = append(records, he.Record())
+	}
+	for _, he := range errs {
+		records = append(records, he.Record())
+	}
+	slices.SortStableFunc(records, func(a, b model.EventRecord) int {
+		return a.Time.Compare(b.Time)
+	})
+	return model.FilterEventRecords(records, q), nil
+}
+
+// TailEvents returns the newest stored events matching the query, oldest first.  It only
+// reads the store so it also works from a command next to a running server.
+func (m *Mason) TailEvents(
+	ctx context.Context,
+	q model.EventQuery,
+) ([]model.EventRecord, error) {
+	if !m.cfg.EventHistory.Enabled {
+		return nil, ErrEventHistoryDisabled
+	}
+	records, err := m.store.ReadEvents(ctx, q)
+	slices.Reverse(records)
+	return records, err
+}
+
+// recordEventHistory writes the bus events and errors to the store every flush interval, the
+// events below the bus minimum priority level are skipped as they are for the bus history
+func (m *Mason) recordEventHistory(ctx context.Context) {
+	defer close(m.eventHistoryDone)
+	cfg := m.cfg.EventHistory
+	sub, unsubscribe := m.bus.Subscribe(eventHistoryBuffer)
+	defer unsubscribe()
+	flush := time.NewTicker(cfg.FlushInterval)
+	defer flush.Stop()
+	purge := time.NewTicker(eventHistoryPurgeInterval)
+	defer purge.Stop()
+
+	m.purgeEventHistory(ctx)
+	pending := make([]model.EventRecord, 0)
+	write := func(ctx context.Context) {
+		if len(pending) == 0 {
+			return
+		}
+		m.recordIfError(m.store.WriteEvents(ctx, pending))
+		pending = pending[:0]
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			write(context.Background())
+			return
+		case e, ok := <-sub:
+			if !ok {
+				write(context.Background())
+				return
+			}
+			now := time.Now()
+			if err, isErr := e.(error); isErr {
+				pending = append(pending, bus.HistoricalError{E: err, Ts: now}.Record())
+				continue
+			}
+			if bus.Priority(e) < m.cfg.Bus.MinimumPriorityLevel {
+				continue
+			}
+			pending = append(pending, bus.HistoricalEvent{E: e, Ts: now}.Record())
+		case <-flush.C:
+			write(ctx)
+		case <-purge.C:
+			m.purgeEventHistory(ctx)
+		}
+	}
+}
+
+func (m *Mason) purgeEventHistory(ctx context.Context) {
+	cfg := m.cfg.EventHistory
+	removed, err := m.store.PurgeEvents(ctx, time.Now().Add(-1*cfg.Retention), cfg.MaxRecords)
+	m.recordIfError(err)
+	if removed > 0 {
+		log.Debug("purged event history", "count", removed)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/enrichment"
+	"github.com/networkables/mason/internal/model"
+)
+
+var (
+	ErrExclusionNameRequired  = errors.New("exclusion name is required")
+	ErrExclusionRangeRequired = errors.New("exclusion range is required")
+	ErrExclusionConfigured    = errors.New("exclusion is set in the config file")
+)
+
+// configExclusionPrefix starts the names of the exclusions from the config file
+const configExclusionPrefix = "config:"
+
+// ListExclusions returns the exclusions of the config file followed by the ones managed at
+// runtime
+func (m *Mason) ListExclusions(ctx context.Context) ([]model.Exclusion, error) {
+	stored, err := m.store.ListExclusions(ctx)
+	m.recordIfError(err)
+	return append(m.configExclusions(), stored...), err
+}
+
+// SaveExclusion creates or updates an exclusion
+func (m *Mason) SaveExclusion(ctx context.Context, x model.Exclusion) error {
+	if x.Name == "" {
+		return ErrExclusionNameRequired
+	}
+	if !x.Prefix.P.IsValid() {
+		return ErrExclusionRangeRequired
+	}
+	if !x.Scan && !x.Ping && !x.PortScan {
+		return model.ErrExclusionNoAction
+	}
+	if isConfigExclusion(x.Name) {
+		return ErrExclusionConfigured
+	}
+	x.Prefix.P = x.Prefix.P.Masked()
+	x.Configured = false
+	return m.store.UpsertExclusion(ctx, x)
+}
+
+// RemoveExclusion deletes the named exclusion, the exclusions of the config file stay
+func (m *Mason) RemoveExclusion(ctx context.Context, name string) error {
+	if isConfigExclusion(name) {
+		return ErrExclusionConfigured
+	}
+	return m.store.RemoveExclusion(ctx, name)
+}
+
+// DeviceExclusions returns the exclusions whose range holds the device
+func (m *Mason) DeviceExclusions(ctx context.Context, d model.Device) model.Exclusions {
+	return m.exclusions(ctx).Matching(d.Addr)
+}
+
+// exclusions returns all exclusions in force, the ones of the config file still apply when
+// the store can not be read
+func (m *Mason) exclusions(ctx context.Context) model.Exclusions {
+	xs, _ := m.ListExclusions(ctx)
+	return xs
+}
+
+// checkExcluded returns an error when the action is excluded from the address
+func (m *Mason) checkExcluded(
+	ctx context.Context,
+	addr model.Addr,
+	action model.ExclusionAction,
+) error {
+	if m.exclusions(ctx).Excludes(addr, action) {
+		return fmt.Errorf("%w from %s: %s", model.ErrAddrExcluded, action, addr)
+	}
+	return nil
+}
+
+func isConfigExclusion(name string) bool {
+	return strings.HasPrefix(name, configExclusionPrefix)
+}
+
+// configExclusions returns the exclusions of the config file, one for each range with the
+// actions it is listed under.  The config is parsed once, a range which does not parse is
+// reported and left out.
+func (m *Mason) configExclusions() []model.Exclusion {
+	m.cfgExclusionsOnce.Do(func() {
+		cfg := m.cfg.Exclusions
+		if cfg == nil {
+			return
+		}
+		add := func(ranges []string, set func(*model.Exclusion)) {
+			for _, r := range ranges {
+				p, err := model.ParseAddrOrPrefix(r)
+				if err != nil {
+					m.publish(tre.New(err, "parse exclusion", "range", r))
+					continue
+				}
+				idx := slices.IndexFunc(m.cfgExclusions, func(x model.Exclusion) bool {
+					return x.Prefix.P == p
+				})
+				if idx < 0 {
+					m.cfgExclusions = append(m.cfgExclusions, model.Exclusion{
+						Name:       configExclusionPrefix + p.String(),
+						Prefix:     model.PrefixToModelPrefix(p),
+						Configured: true,
+					})
+					idx = len(m.cfgExclusions) - 1
+				}
+				set(&m.cfgExclusions[idx])
+			}
+		}
+		add(cfg.Scan, func(x *model.Exclusion) { x.Scan = true })
+		add(cfg.Ping, func(x *model.Exclusion) { x.Ping = true })
+		add(cfg.PortScan, func(x *model.Exclusion) { x.PortScan = true })
+	})
+	return slices.Clone(m.cfgExclusions)
+}
+
+// excludeEnrichment drops the enrichment probes the device is excluded from, the lookups
+// which do not reach the device itself are kept
+func excludeEnrichment(
+	xs model.Exclusions,
+	req enrichment.EnrichDeviceRequest,
+) enrichment.EnrichDeviceRequest {
+	addr := req.Device.Addr
+	if xs.Excludes(addr, model.ExcludeScan) {
+		req.Fields.PerformMDNSLookup = false
+		req.Fields.PerformSNMPScan = false
+		req.Fields.PerformVirtualScan = false
+	}
+	if xs.Excludes(addr, model.ExcludePortScan) {
+		req.Fields.PerformPortScan = false
+	}
+	return req
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/redact"
+)
+
+// InventoryExport is a point in time copy of the networks and devices known to mason
+type InventoryExport struct {
+	GeneratedAt time.Time
+	Anonymized  bool
+	Networks    []model.Network
+	Devices     []model.Device
+}
+
+// ExportInventory returns all networks and devices.  When anonymize is set the MACs, names and
+// public addresses are replaced using a keyed hash so exports made with the same key line up.
+func (m *Mason) ExportInventory(
+	ctx context.Context,
+	anonymize bool,
+	key string,
+) InventoryExport {
+	exp := InventoryExport{
+		GeneratedAt: time.Now(),
+		Anonymized:  anonymize,
+		Networks:    m.store.ListNetworks(ctx),
+		Devices:     m.store.ListDevices(ctx),
+	}
+	if !anonymize {
+		return exp
+	}
+	r := redact.New(key)
+	for i, n := range exp.Networks {
+		exp.Networks[i] = r.Network(n)
+	}
+	for i, d := range exp.Devices {
+		exp.Devices[i] = r.Device(d)
+	}
+	return exp
+}
+
+// assetColumns are the columns of the device asset register
+var assetColumns = []string{
+	"name", "addr", "mac", "type", "manufacturer", "site", "owner", "location",
+	"purchasedate", "serial", "assettag", "firstseen", "lastseen", "notes",
+}
+
+// WriteAssetCSV writes the devices of the export as an asset register, a row per device
+func (exp InventoryExport) WriteAssetCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write(assetColumns)
+	if err != nil {
+		return err
+	}
+	for _, d := range exp.Devices {
+		err = cw.Write([]string{
+			d.Name,
+			d.Addr.String(),
+			d.MAC.String(),
+			string(d.Meta.DeviceType),
+			d.Meta.Manufacturer,
+			d.Meta.Site,
+			d.Meta.Owner,
+			d.Meta.Location,
+			d.Meta.PurchaseDate,
+			d.Meta.Serial,
+			d.Meta.AssetTag,
+			d.FirstSeenString(),
+			d.LastSeenString(),
+			d.Meta.Notes,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
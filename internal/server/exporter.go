@@ -0,0 +1,78 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/exporter"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+func (m *Mason) newExporter() *exporter.Exporter {
+	if !m.cfg.Exporter.Enabled {
+		return nil
+	}
+	e, err := exporter.New(m.cfg.Exporter)
+	if err != nil {
+		log.Error("exporter disabled", "error", err)
+		return nil
+	}
+	return e
+}
+
+// exportPing queues the statistics of a performance ping for the next remote write
+func (m *Mason) exportPing(
+	start time.Time,
+	device model.Device,
+	stats nettools.Icmp4EchoResponseStatistics,
+) {
+	if m.exporter == nil {
+		return
+	}
+	m.exporter.Add(exporter.PingSamples(start, device, stats)...)
+}
+
+// exportMetrics polls the interface counters of the snmp devices and remote writes everything
+// queued since the last run.  A run is skipped while the previous one is still going.
+func (m *Mason) exportMetrics(ctx context.Context) {
+	if m.exporter == nil || !m.exportRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.exportRunning.Store(false)
+
+	if m.cfg.Exporter.SnmpCounters {
+		devs := m.store.GetFilteredDevices(ctx, m.exclusions(ctx).Filter(
+			model.ExcludeScan,
+			func(d model.Device) bool {
+				return d.SNMP.Community != ""
+			},
+		))
+		for _, d := range devs {
+			start := time.Now()
+			counters, err := nettools.SnmpGetInterfaceCounters(ctx, d.Addr.Addr(),
+				nettools.WithSnmpCommunity(d.SNMP.Community),
+				nettools.WithSnmpPort(d.SNMP.Port),
+			)
+			if err != nil {
+				log.Debug("snmp interface counters", "addr", d.Addr, "error", err)
+				continue
+			}
+			m.exporter.Add(exporter.InterfaceSamples(start, d, counters)...)
+		}
+	}
+
+	count, err := m.exporter.Flush(ctx)
+	if err != nil {
+		m.publish(tre.New(err, "remote write", "url", m.cfg.Exporter.URL))
+		return
+	}
+	log.Debug("remote write", "samples", count)
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/netflows"
+)
+
+// newFlowAnomalyDetector creates the detector for the flows of devices, nil when anomaly
+// detection is disabled
+func (m *Mason) newFlowAnomalyDetector(ctx context.Context) *netflows.Detector {
+	if !m.cfg.NetFlows.Enabled || !m.cfg.NetFlows.Anomaly.Enabled {
+		return nil
+	}
+	return netflows.NewDetector(m.cfg.NetFlows.Anomaly, func(asn string) string {
+		a, err := m.flowstore.GetAsn(ctx, asn)
+		if err != nil {
+			return ""
+		}
+		return a.Country
+	})
+}
+
+// detectFlowAnomalies adds the flows to the device baselines, each anomaly is recorded as an
+// annotation of the device and published unless the device is in maintenance.  Addresses
+// within the saved networks are the devices tracked.
+func (m *Mason) detectFlowAnomalies(ctx context.Context, flows []model.IpFlow) {
+	if m.flowAnomalies == nil {
+		return
+	}
+	nets := m.store.ListNetworks(ctx)
+	isDevice := func(addr model.Addr) bool {
+		for _, n := range nets {
+			if n.Prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+	now := time.Now()
+	anomalies := m.flowAnomalies.Observe(now, flows, isDevice)
+	if len(anomalies) == 0 {
+		return
+	}
+	inMaintenance := m.maintenanceLookup(ctx, now)
+	for _, fa := range anomalies {
+		a := model.Annotation{
+			Time: now,
+			Addr: fa.Addr,
+			Kind: model.AnnotationFlowAnomaly,
+			Text: string(fa.Kind) + ": " + fa.Detail,
+		}
+		dev, err := m.store.GetDeviceByAddr(ctx, fa.Addr)
+		if err == nil {
+			if window, ok := inMaintenance(dev); ok {
+				m.recordIfError(m.store.AddAnnotation(ctx, window.Suppress(a)))
+				continue
+			}
+		}
+		m.recordIfError(m.store.AddAnnotation(ctx, a))
+		m.publish(fa)
+	}
+}
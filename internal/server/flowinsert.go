@@ -0,0 +1,41 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// prepareFlows sets the asns of the flows and runs the flow hooks on them, run by the flow
+// inserter off the server loop
+func (m *Mason) prepareFlows(flows []model.IpFlow) []model.IpFlow {
+	m.lookupFlowAsns(flows)
+	return m.applyFlowHooks(flows)
+}
+
+// lookupFlowAsns sets the asn of both ends of the flows
+func (m *Mason) lookupFlowAsns(flows []model.IpFlow) {
+	for idx, flow := range flows {
+		flows[idx].SrcASN = m.LookupIP(flow.SrcAddr)
+		flows[idx].DstASN = m.LookupIP(flow.DstAddr)
+	}
+}
+
+func (m *Mason) writeFlows(ctx context.Context, flows []model.IpFlow) error {
+	err := m.flowstore.AddNetflows(ctx, flows)
+	if err != nil {
+		m.publish(tre.New(err, "write netflows", "count", len(flows)))
+	}
+	return err
+}
+
+func (m *Mason) afterFlowsWritten(ctx context.Context, flows []model.IpFlow) {
+	m.applyFlowVlans(ctx, flows)
+	m.detectFlowAnomalies(ctx, flows)
+}
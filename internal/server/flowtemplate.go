@@ -0,0 +1,27 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/netflows"
+)
+
+// flowTemplates restores the ipfix templates saved before the restart, the templates the
+// exporters announce are saved as they are learned or changed
+func (m *Mason) flowTemplates(ctx context.Context) *netflows.Templates {
+	saved, err := m.flowstore.ListFlowTemplates(ctx)
+	if err != nil {
+		log.Error("restore flow templates", "error", err)
+	}
+	log.Info("restored flow templates", "count", len(saved))
+	return netflows.NewTemplates(saved, func(t model.FlowTemplate) {
+		m.recordIfError(m.flowstore.UpsertFlowTemplate(ctx, t))
+	})
+}
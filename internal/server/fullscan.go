@@ -0,0 +1,122 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+// FullPortScans returns the progress of the sweeps of the devices with the full scan tag
+func (m *Mason) FullPortScans(ctx context.Context) ([]model.FullPortScan, error) {
+	scans, err := m.store.ListFullPortScans(ctx)
+	m.recordIfError(err)
+	return scans, err
+}
+
+// DeviceFullPortScan returns the progress of the sweep of the device, false when the device
+// has not been swept
+func (m *Mason) DeviceFullPortScan(ctx context.Context, addr model.Addr) (model.FullPortScan, bool) {
+	scans, err := m.store.ListFullPortScans(ctx)
+	if err != nil {
+		return model.FullPortScan{}, false
+	}
+	idx := slices.IndexFunc(scans, func(fs model.FullPortScan) bool { return fs.Addr == addr })
+	if idx < 0 {
+		return model.FullPortScan{}, false
+	}
+	return scans[idx], true
+}
+
+// runFullScans scans the next chunk of ports of each device with the full scan tag.  The
+// progress is stored after each chunk, a sweep interrupted by a restart resumes from the
+// chunk it was on.  A run is skipped while the previous one is still going.
+func (m *Mason) runFullScans(ctx context.Context) {
+	cfg := m.cfg.FullScan
+	if !cfg.Enabled || m.IsOffline() || !m.fullScanRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.fullScanRunning.Store(false)
+
+	scans, err := m.store.ListFullPortScans(ctx)
+	if err != nil {
+		m.recordIfError(err)
+		return
+	}
+	progress := make(map[model.Addr]model.FullPortScan, len(scans))
+	for _, fs := range scans {
+		progress[fs.Addr] = fs
+	}
+
+	devices := m.store.GetFilteredDevices(ctx, m.exclusions(ctx).Filter(
+		model.ExcludePortScan,
+		func(d model.Device) bool { return d.Meta.Tags.Has(cfg.Tag) },
+	))
+	now := time.Now()
+	swept := make(map[model.Addr]bool, len(devices))
+	for _, d := range devices {
+		swept[d.Addr] = true
+		fs, ok := progress[d.Addr]
+		if ok && !fs.Due(now, cfg.RescanInterval) {
+			continue
+		}
+		if !ok || fs.Done() {
+			fs = model.NewFullPortScan(d.Addr, now)
+		}
+		m.scanFullPortChunk(ctx, d, fs)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+
+	// a device untagged or excluded since starts over when it is swept again
+	for addr := range progress {
+		if !swept[addr] {
+			m.recordIfError(m.store.RemoveFullPortScan(ctx, addr))
+		}
+	}
+}
+
+// scanFullPortChunk scans the next chunk of the sweep with the port scan settings, a
+// completed sweep replaces the open ports of the device
+func (m *Mason) scanFullPortChunk(ctx context.Context, d model.Device, fs model.FullPortScan) {
+	pcfg := m.cfg.Enrichment.PortScan
+	chunk := fs.NextChunk(m.cfg.FullScan.ChunkSize)
+	open, err := nettools.ScanTcpPorts(ctx, d.Addr.Addr(),
+		nettools.WithPortscanReplyTimeout(pcfg.Timeout),
+		nettools.WithPortscanPorts(chunk),
+		nettools.WithPortscanMaxworkers(pcfg.MaxWorkers),
+		nettools.WithPortscanModeName(pcfg.Mode),
+		nettools.WithPortscanRateLimiter(m.limits.Addr(d.Addr.Addr())),
+	)
+	// a chunk cut short by shutdown is scanned again on the next start
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		m.recordIfError(tre.New(err, "full port scan", "addr", d.Addr))
+		return
+	}
+	fs = fs.Record(chunk, open, time.Now())
+	err = m.store.UpsertFullPortScan(ctx, fs)
+	if err != nil {
+		m.recordIfError(err)
+		return
+	}
+	if !fs.Done() {
+		return
+	}
+	log.Info("full port scan completed", "addr", d.Addr, "open", fs.OpenPorts.String())
+	d.Server.Ports = fs.OpenPorts.Clone()
+	d.Server.LastScan = fs.Completed
+	m.publish(model.EventDeviceUpdated(d))
+}
@@ -0,0 +1,176 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// heartbeatInterval is how often the main loop marks itself alive
+	heartbeatInterval = 10 * time.Second
+	// heartbeatStaleAfter is how long the main loop can go without a heartbeat, a loop stuck on
+	// an event stops beating
+	heartbeatStaleAfter = 6 * heartbeatInterval
+	// storePingTimeout bounds the store check of the readiness
+	storePingTimeout = 5 * time.Second
+)
+
+var (
+	ErrLoopStalled = errors.New("main loop stalled")
+	ErrBusStalled  = errors.New("bus stalled")
+)
+
+// HealthCheck is the result of one check of a health report
+type HealthCheck struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// NetworkScanTime is when a network was last scanned
+type NetworkScanTime struct {
+	Network  string
+	Prefix   string
+	LastScan time.Time
+}
+
+// HealthReport is the health of mason itself, it is OK when all of its checks are
+type HealthReport struct {
+	OK        bool
+	Checks    []HealthCheck
+	LastScans []NetworkScanTime
+}
+
+// Liveness reports whether the main loop, the worker pools and the enabled collectors are
+// running
+func (m *Mason) Liveness(ctx context.Context) HealthReport {
+	return m.healthReport(ctx, false)
+}
+
+// Readiness reports the liveness along with whether the stores can be reached
+func (m *Mason) Readiness(ctx context.Context) HealthReport {
+	return m.healthReport(ctx, true)
+}
+
+func (m *Mason) healthReport(ctx context.Context, ready bool) HealthReport {
+	report := HealthReport{
+		Checks:    m.livenessChecks(),
+		LastScans: make([]NetworkScanTime, 0),
+	}
+	if ready {
+		report.Checks = append(report.Checks, m.storeChecks(ctx)...)
+	}
+	report.OK = true
+	for _, c := range report.Checks {
+		report.OK = report.OK && c.OK
+	}
+	for _, n := range m.store.ListNetworks(ctx) {
+		report.LastScans = append(report.LastScans, NetworkScanTime{
+			Network:  n.Name,
+			Prefix:   n.Prefix.String(),
+			LastScan: n.LastScan,
+		})
+	}
+	return report
+}
+
+func (m *Mason) livenessChecks() []HealthCheck {
+	beat := m.heartbeat.Load()
+	if beat == 0 {
+		return []HealthCheck{{Name: "loop", Detail: "not started"}}
+	}
+	since := time.Since(time.Unix(0, beat))
+	checks := []HealthCheck{{
+		Name:   "loop",
+		OK:     since < heartbeatStaleAfter,
+		Detail: fmt.Sprintf("last heartbeat %s ago", since.Round(time.Second)),
+	}}
+	checks = append(checks,
+		runningCheck("worker.discovery", m.discoveryWorker.Running()),
+		runningCheck("worker.networkscan", m.networkScannerWorker.Running()),
+		runningCheck("worker.enrichment", m.enrichmentWorker.Running()),
+		runningCheck("worker.pinger", m.pingerWorker.Running()),
+	)
+	if m.netflowsWorker != nil {
+		// the netflow pool stops along with its listener
+		checks = append(checks, runningCheck("collector.netflows", m.netflowsWorker.Running()))
+	}
+	if m.dnsCollector != nil {
+		reading, err := m.dnsCollector.Reading()
+		c := runningCheck("collector.dnslog", reading)
+		if err != nil {
+			c.Detail = err.Error()
+		}
+		checks = append(checks, c)
+	}
+	if m.syslogReceiver != nil {
+		listening, err := m.syslogReceiver.Listening()
+		c := runningCheck("collector.syslog", listening)
+		if err != nil {
+			c.Detail = err.Error()
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+func runningCheck(name string, running bool) HealthCheck {
+	c := HealthCheck{Name: name, OK: running, Detail: "running"}
+	if !running {
+		c.Detail = "stopped"
+	}
+	return c
+}
+
+// storeChecks pings the device store and the flow store when it is a different one, stores
+// which cannot be pinged are reported as not checked
+func (m *Mason) storeChecks(ctx context.Context) []HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, storePingTimeout)
+	defer cancel()
+	checks := []HealthCheck{pingStore(ctx, "store", m.store)}
+	if m.flowstore != nil && any(m.flowstore) != any(m.store) {
+		checks = append(checks, pingStore(ctx, "store.flows", m.flowstore))
+	}
+	return checks
+}
+
+func pingStore(ctx context.Context, name string, store any) HealthCheck {
+	p, ok := store.(StorePinger)
+	if !ok {
+		return HealthCheck{Name: name, OK: true, Detail: "not checked"}
+	}
+	err := p.Ping(ctx)
+	if err != nil {
+		return HealthCheck{Name: name, Detail: err.Error()}
+	}
+	return HealthCheck{Name: name, OK: true, Detail: "reachable"}
+}
+
+// SelfCheck is the sanity check behind the service manager watchdog: the main loop has to be
+// beating, the bus dispatching its queued events and the stores reachable.  A stopped
+// collector does not fail it as a restart would not bring it back.  The bus is compared to
+// the previous self check, so it is only found stalled from the second check on.
+func (m *Mason) SelfCheck(ctx context.Context) error {
+	for _, c := range m.livenessChecks() {
+		if c.Name == "loop" && !c.OK {
+			return fmt.Errorf("%w: %s", ErrLoopStalled, c.Detail)
+		}
+	}
+	stats := m.bus.Stats()
+	prev := m.selfCheckDispatched.Swap(stats.Dispatched)
+	if stats.Queued > 0 && prev != 0 && stats.Dispatched == prev {
+		return fmt.Errorf("%w: %d events queued", ErrBusStalled, stats.Queued)
+	}
+	for _, c := range m.storeChecks(ctx) {
+		if !c.OK {
+			return fmt.Errorf("%s: %s", c.Name, c.Detail)
+		}
+	}
+	return nil
+}
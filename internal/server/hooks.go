@@ -0,0 +1,91 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/networkables/mason/internal/hooks"
+	"github.com/networkables/mason/internal/model"
+)
+
+// hookAlertKey limits the alerts of a hook per address
+type hookAlertKey struct {
+	hook string
+	addr model.Addr
+}
+
+// currentHooks returns the hooks of the hooks file, nil when hooks are disabled.  Errors
+// reading the file are published once per change of the file.
+func (m *Mason) currentHooks() *hooks.Set {
+	if m.hookLoader == nil {
+		return nil
+	}
+	set, err := m.hookLoader.Hooks()
+	m.recordIfError(err)
+	return set
+}
+
+// applyDeviceHooks runs the hooks of the trigger on the device, true when a hook dropped it
+func (m *Mason) applyDeviceHooks(ctx context.Context, on hooks.Trigger, d *model.Device) bool {
+	set := m.currentHooks()
+	if set.Len() == 0 {
+		return false
+	}
+	tags := len(d.Meta.Tags)
+	drop, alerts, err := set.ApplyDevice(on, d)
+	m.recordIfError(err)
+	m.publishHookAlerts(alerts)
+	if drop {
+		log.Debug("device dropped by hook", "addr", d.Addr)
+		return true
+	}
+	if on == hooks.OnDiscovered && len(d.Meta.Tags) > tags {
+		// - the tags of a discovered device replace the stored ones, keep those of a known device
+		if prev, err := m.store.GetDeviceByAddr(ctx, d.Addr); err == nil {
+			for _, t := range prev.Meta.Tags {
+				if !d.Meta.Tags.Has(t.Val) {
+					d.Meta.Tags = append(d.Meta.Tags, t)
+				}
+			}
+		}
+	}
+	return false
+}
+
+// applyFlowHooks runs the flow hooks on a batch of flows before it is written, run by the
+// flow inserter after the asn lookups
+func (m *Mason) applyFlowHooks(flows []model.IpFlow) []model.IpFlow {
+	set := m.currentHooks()
+	if set.Len() == 0 {
+		return flows
+	}
+	kept, alerts, err := set.ApplyFlows(flows)
+	m.recordIfError(err)
+	m.publishHookAlerts(alerts)
+	return kept
+}
+
+// publishHookAlerts raises the alerts, an alert of a hook for an address is raised once per
+// cooldown
+func (m *Mason) publishHookAlerts(alerts []model.EventHookAlert) {
+	if len(alerts) == 0 {
+		return
+	}
+	now := time.Now()
+	m.hookAlertsMu.Lock()
+	defer m.hookAlertsMu.Unlock()
+	for _, a := range alerts {
+		key := hookAlertKey{hook: a.Hook, addr: a.Addr}
+		if now.Sub(m.hookAlerts[key]) < m.cfg.Hooks.AlertCooldown {
+			continue
+		}
+		m.hookAlerts[key] = now
+		m.publish(a)
+	}
+}
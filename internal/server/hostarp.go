@@ -0,0 +1,41 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+// ingestHostArpTable discovers the devices in the arp table of the host which are in a known
+// network, the host fills the table from its own traffic so no probes are sent
+func (m *Mason) ingestHostArpTable(ctx context.Context) {
+	if !m.cfg.Discovery.Enabled || !m.cfg.Discovery.HostArp.Enabled {
+		return
+	}
+	entries, err := nettools.ReadHostArpTable(ctx)
+	if err != nil {
+		m.publish(tre.New(err, "read host arp table"))
+		return
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		if !m.inKnownNetwork(ctx, entry.Addr) {
+			continue
+		}
+		m.publish(model.EventDeviceDiscovered{
+			Addr:         model.AddrToModelAddr(entry.Addr),
+			MAC:          model.HardwareAddrToMAC(entry.MAC),
+			DiscoveredBy: discovery.HostArpDiscoverySource,
+			DiscoveredAt: now,
+		})
+	}
+}
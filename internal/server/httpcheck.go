@@ -0,0 +1,163 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+// httpCheckWindow is the span of results summarized for the http check status
+const httpCheckWindow = 24 * time.Hour
+
+// ListHTTPChecks returns all http checks
+func (m *Mason) ListHTTPChecks(ctx context.Context) ([]model.HTTPCheck, error) {
+	checks, err := m.store.ListHTTPChecks(ctx)
+	m.recordIfError(err)
+	return checks, err
+}
+
+// SaveHTTPCheck creates or updates an http check
+func (m *Mason) SaveHTTPCheck(ctx context.Context, c model.HTTPCheck) error {
+	c, err := c.Normalize()
+	if err != nil {
+		return err
+	}
+	return m.store.UpsertHTTPCheck(ctx, c)
+}
+
+// RemoveHTTPCheck deletes the named http check and its results
+func (m *Mason) RemoveHTTPCheck(ctx context.Context, name string) error {
+	err := m.store.RemoveHTTPCheck(ctx, name)
+	if err != nil {
+		return err
+	}
+	m.httpChecksMu.Lock()
+	delete(m.httpChecksLast, name)
+	m.httpChecksMu.Unlock()
+	return nil
+}
+
+// HTTPCheckStatus summarizes the results of the last day of each http check
+func (m *Mason) HTTPCheckStatus(ctx context.Context) ([]model.HTTPCheckStatus, error) {
+	checks, err := m.store.ListHTTPChecks(ctx)
+	if err != nil {
+		m.recordIfError(err)
+		return nil, err
+	}
+	results, err := m.store.ReadHTTPCheckResults(ctx, httpCheckWindow)
+	if err != nil {
+		m.recordIfError(err)
+		return nil, err
+	}
+	return model.SummarizeHTTPChecks(checks, results), nil
+}
+
+// runHTTPChecks requests each check whose interval has passed since its last run and stores
+// the results.  A check changing between up and down is published.  A run is skipped while
+// the previous one is still going.
+func (m *Mason) runHTTPChecks(ctx context.Context) {
+	cfg := m.cfg.HTTPChecks
+	if !cfg.Enabled || m.IsOffline() || !m.httpChecksRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.httpChecksRunning.Store(false)
+
+	checks, err := m.store.ListHTTPChecks(ctx)
+	if err != nil {
+		m.recordIfError(err)
+		return
+	}
+	m.loadHTTPCheckLast(ctx, checks)
+
+	now := time.Now()
+	due := make([]model.HTTPCheck, 0, len(checks))
+	m.httpChecksMu.Lock()
+	for _, c := range checks {
+		if last, ok := m.httpChecksLast[c.Name]; !ok || now.Sub(last.Start) >= c.Interval {
+			due = append(due, c)
+		}
+	}
+	m.httpChecksMu.Unlock()
+	if len(due) == 0 {
+		return
+	}
+
+	results := make([]model.HTTPCheckResult, len(due))
+	var wg sync.WaitGroup
+	for idx, c := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[idx] = m.runHTTPCheck(ctx, c, cfg.Timeout)
+		}()
+	}
+	wg.Wait()
+
+	m.httpChecksMu.Lock()
+	for idx, r := range results {
+		last, seen := m.httpChecksLast[r.Name]
+		m.httpChecksLast[r.Name] = r
+		if (seen && last.Failed() != r.Failed()) || (!seen && r.Failed()) {
+			m.publish(model.EventHTTPCheckChanged{Check: due[idx], Result: r})
+		}
+	}
+	m.httpChecksMu.Unlock()
+
+	m.recordIfError(m.store.WriteHTTPCheckResults(ctx, results))
+	removed, err := m.store.PurgeHTTPCheckResults(ctx, now.Add(-1*cfg.Retention))
+	m.recordIfError(err)
+	if removed > 0 {
+		log.Debug("purged http check results", "count", removed)
+	}
+}
+
+// loadHTTPCheckLast fills in the last result of the checks not run since the server started
+// from the stored results, so a restart neither reruns them early nor repeats their state
+func (m *Mason) loadHTTPCheckLast(ctx context.Context, checks []model.HTTPCheck) {
+	m.httpChecksMu.Lock()
+	defer m.httpChecksMu.Unlock()
+	if m.httpChecksLoaded {
+		return
+	}
+	results, err := m.store.ReadHTTPCheckResults(ctx, httpCheckWindow)
+	if err != nil {
+		m.recordIfError(err)
+		return
+	}
+	for _, s := range model.SummarizeHTTPChecks(checks, results) {
+		if s.Results > 0 {
+			m.httpChecksLast[s.Check.Name] = s.Last
+		}
+	}
+	m.httpChecksLoaded = true
+}
+
+func (m *Mason) runHTTPCheck(
+	ctx context.Context,
+	c model.HTTPCheck,
+	timeout time.Duration,
+) model.HTTPCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	r := model.HTTPCheckResult{Start: time.Now(), Name: c.Name}
+	resp, err := nettools.HTTPCheck(ctx, c.Method, c.URL, c.Insecure)
+	if err == nil {
+		err = c.Evaluate(resp.Status, resp.Body)
+	}
+	r.Status = resp.Status
+	r.Latency = resp.Latency
+	if err != nil {
+		r.Err = err.Error()
+	}
+	return r
+}
@@ -0,0 +1,281 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/enrichment"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/tracing"
+	"github.com/networkables/mason/nettools"
+)
+
+const (
+	// IdentityMAC follows a device by its MAC, its record moves with it to a new address
+	IdentityMAC = "mac"
+	// IdentityIP treats each address as its own device
+	IdentityIP = "ip"
+)
+
+// addDiscoveredDevice stores a newly discovered device waiting for review, a device
+// already known at the address is updated instead
+func (m *Mason) addDiscoveredDevice(ctx context.Context, d model.Device) {
+	key := d.Addr.String()
+	ctx, span := tracing.Start(
+		tracing.Resume(ctx, key),
+		"mason.discovered",
+		tracing.AddrKey.String(key),
+	)
+	defer span.End()
+	// the enrichment requested for the device continues its trace
+	tracing.Carry(ctx, key)
+
+	nd := d
+	nd.Meta.Approval = model.ApprovalUnknown
+	err := m.store.AddDevice(ctx, nd)
+	if err == nil {
+		// - if new emit new device event
+		m.publish(model.EventDeviceAdded(nd))
+		m.publish(model.EventDeviceNeedsReview(nd))
+		return
+	}
+	if errors.Is(err, model.ErrDeviceExists) {
+		enrich, err := m.updateDevice(ctx, d, model.ChangeSourceDiscovery)
+		if err == nil {
+			if enrich {
+				m.publish(
+					enrichment.EnrichDeviceRequest{
+						Device: d,
+						Fields: enrichment.DefaultEnrichmentFields(m.cfg.Enrichment),
+					})
+			}
+			return
+		}
+	}
+	span.RecordError(err)
+	m.publish(tre.New(err, "adding discovered device"))
+}
+
+// previousDevice finds the stored device a discovered device at an unknown address may be,
+// by its MAC, by the identity a device with a randomized MAC announces or otherwise by a name
+// only one stored device has
+func (m *Mason) previousDevice(ctx context.Context, d model.Device) (model.Device, bool) {
+	if m.cfg.Identity.Key != IdentityMAC {
+		return model.Device{}, false
+	}
+	if _, err := m.store.GetDeviceByAddr(ctx, d.Addr); err == nil {
+		return model.Device{}, false
+	}
+	if !d.MAC.IsEmpty() {
+		prev, err := m.store.GetDeviceByMAC(ctx, d.MAC)
+		if err == nil {
+			return prev, true
+		}
+		return m.correlatedDevice(ctx, d)
+	}
+	if d.Name == "" || d.IsNameAddr() {
+		return model.Device{}, false
+	}
+	devices := m.store.FindDevicesByName(ctx, d.Name)
+	if len(devices) != 1 {
+		return model.Device{}, false
+	}
+	return devices[0], true
+}
+
+// correlatedDevice finds the one stored device with a randomized MAC which announces the same
+// identity as a discovered device with a randomized MAC
+func (m *Mason) correlatedDevice(ctx context.Context, d model.Device) (model.Device, bool) {
+	keys := d.CorrelationKeys()
+	if !m.cfg.Identity.CorrelateRandomized || len(keys) == 0 ||
+		!nettools.IsRandomMac(d.MAC.Addr()) {
+		return model.Device{}, false
+	}
+	var found []model.Device
+	for _, dev := range m.store.ListDevices(ctx) {
+		if !dev.Meta.Tags.Has(model.RandomizedMacAddressTag.Val) {
+			continue
+		}
+		if slices.ContainsFunc(dev.CorrelationKeys(), func(key string) bool {
+			return slices.Contains(keys, key)
+		}) {
+			found = append(found, dev)
+		}
+	}
+	if len(found) != 1 {
+		return model.Device{}, false
+	}
+	return found[0], true
+}
+
+// checkDeviceMoved pings the old address of the matched device, a device still answering
+// there has more than one address and the discovered one is added as a new device.  An old
+// address excluded from pings is taken as moved.
+func (m *Mason) checkDeviceMoved(ctx context.Context, prev model.Device, d model.Device) {
+	if !m.exclusions(ctx).Excludes(prev.Addr, model.ExcludePing) {
+		responses, err := nettools.Icmp4Echo(
+			ctx,
+			prev.Addr.Addr(),
+			nettools.I4EWithCount(1),
+			nettools.I4EWithReadTimeout(m.cfg.Pinger.Timeout),
+			nettools.I4EWithPrivileged(m.cfg.Pinger.Privileged),
+		)
+		if err == nil &&
+			nettools.CalculateIcmp4EchoResponseStatistics(responses).SuccessCount > 0 {
+			m.addDiscoveredDevice(ctx, d)
+			return
+		}
+	}
+	err := m.mergeDevice(ctx, prev, d)
+	if errors.Is(err, model.ErrDeviceDoesNotExist) {
+		// - another discovery of the device merged it first
+		m.addDiscoveredDevice(ctx, d)
+		return
+	}
+	if err != nil {
+		m.publish(tre.New(err, "moving device", "from", prev.Addr, "to", d.Addr))
+	}
+}
+
+// reconcileIdentities merges the offline records of a device into the record at the address
+// it was last seen on, for devices found at a new address while the old one still answered
+// or before the identity was keyed by MAC.  Records of devices with a randomized MAC are
+// merged when they announce the same identity.
+func (m *Mason) reconcileIdentities(ctx context.Context) {
+	if m.cfg.Identity.Key != IdentityMAC || !m.reconcileRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.reconcileRunning.Store(false)
+
+	groups := make(map[string][]model.Device)
+	for _, d := range m.store.ListDevices(ctx) {
+		for _, mac := range d.MACs() {
+			groups["mac:"+mac.String()] = append(groups["mac:"+mac.String()], d)
+		}
+		if !m.cfg.Identity.CorrelateRandomized ||
+			!d.Meta.Tags.Has(model.RandomizedMacAddressTag.Val) {
+			continue
+		}
+		for _, key := range d.CorrelationKeys() {
+			groups[key] = append(groups[key], d)
+		}
+	}
+	merged := make(map[model.Addr]bool)
+	count := 0
+	for _, devices := range groups {
+		count += m.mergeOffline(ctx, devices, merged)
+	}
+	if count > 0 {
+		log.Info("merged devices found at a new address", "count", count)
+	}
+}
+
+// mergeOffline merges the offline devices of a group into the one seen most recently, the
+// addrs of merged devices are added to merged and skipped in the groups which follow
+func (m *Mason) mergeOffline(
+	ctx context.Context,
+	devices []model.Device,
+	merged map[model.Addr]bool,
+) int {
+	devices = slices.DeleteFunc(slices.Clone(devices), func(d model.Device) bool {
+		return merged[d.Addr]
+	})
+	if len(devices) < 2 {
+		return 0
+	}
+	current := slices.MaxFunc(devices, func(a, b model.Device) int {
+		return a.PerformancePing.LastSeen.Compare(b.PerformancePing.LastSeen)
+	})
+	count := 0
+	for _, d := range devices {
+		if d.Addr == current.Addr || !d.PerformancePing.LastFailed ||
+			!d.PerformancePing.LastSeen.Before(current.PerformancePing.LastSeen) {
+			continue
+		}
+		err := m.mergeDevice(ctx, d, current)
+		if err != nil {
+			m.publish(tre.New(err, "merging device", "from", d.Addr, "to", current.Addr))
+			continue
+		}
+		merged[d.Addr] = true
+		count++
+	}
+	return count
+}
+
+// mergeDevice moves the record of a device at its old address onto the new address.  The
+// ping history, tags and notes carry over and the old record is kept as a deleted device
+// tagged MergedDevice, which can be restored until the soft delete grace period ends.
+func (m *Mason) mergeDevice(ctx context.Context, old model.Device, next model.Device) error {
+	m.identityMu.Lock()
+	defer m.identityMu.Unlock()
+
+	old, err := m.store.GetDeviceByAddr(ctx, old.Addr)
+	if err != nil {
+		return err
+	}
+	base, err := m.store.GetDeviceByAddr(ctx, next.Addr)
+	var merged model.Device
+	switch {
+	case err == nil:
+		merged = base.MergeIdentity(old)
+		_, err = m.store.UpdateDevice(ctx, merged)
+	case errors.Is(err, model.ErrDeviceDoesNotExist):
+		base = old
+		merged = old.MovedTo(next)
+		err = m.store.AddDevice(ctx, merged)
+	}
+	if err != nil {
+		return err
+	}
+	if tm, ok := m.timeseries.(TimeseriesMerger); ok {
+		m.recordIfError(tm.MergePerformancePings(ctx, old.Addr, merged.Addr))
+	}
+
+	now := time.Now()
+	marked := old
+	marked.Meta.Tags = model.Add(model.MergedDeviceTag, slices.Clone(old.Meta.Tags))
+	err = m.store.UpsertTombstone(
+		ctx,
+		model.DeviceTombstone(marked, now, m.cfg.SoftDelete.GracePeriod),
+	)
+	if err != nil {
+		return tre.New(err, "save merged device tombstone", "addr", old.Addr)
+	}
+	err = m.store.RemoveDeviceByAddr(ctx, old.Addr)
+	if err != nil {
+		return err
+	}
+
+	changes := model.DeviceChanges(base, merged, model.ChangeSourceDiscovery, now)
+	for idx := range changes {
+		changes[idx].Addr = merged.Addr
+	}
+	changes = append(changes, model.DeviceChange{
+		Time:   now,
+		Addr:   merged.Addr,
+		Field:  "Addr",
+		Old:    old.Addr.String(),
+		New:    merged.Addr.String(),
+		Source: model.ChangeSourceDiscovery,
+	})
+	m.recordChanges(ctx, changes)
+	m.recordIfError(m.store.AddAnnotation(ctx, model.Annotation{
+		Time: now,
+		Addr: merged.Addr,
+		Kind: model.AnnotationAddrChanged,
+		Text: fmt.Sprintf("address changed from %s", old.Addr),
+	}))
+	m.publish(model.EventDeviceAddrChanged{Device: merged, From: old.Addr})
+	return nil
+}
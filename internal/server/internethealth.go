@@ -0,0 +1,176 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+const (
+	internetHealthIspAnchor = "isp"
+
+	// internetHealthWindow is the span of probes summarized for the internet status
+	internetHealthWindow = 24 * time.Hour
+)
+
+// InternetHealth summarizes the internet health probes of the last day by target
+func (m *Mason) InternetHealth(ctx context.Context) ([]model.HealthTargetStatus, error) {
+	probes, err := m.store.ReadHealthProbes(ctx, internetHealthWindow)
+	if err != nil {
+		m.recordIfError(err)
+		return nil, err
+	}
+	return model.SummarizeHealthProbes(probes), nil
+}
+
+// checkInternetHealth pings the anchors and fetches the urls, storing a probe for each.
+// Anchors are traced once per traceroute interval to record their hop count and find the
+// isp anchor.  A run is skipped while the previous one is still going.
+func (m *Mason) checkInternetHealth(ctx context.Context) {
+	cfg := m.cfg.InternetHealth
+	if !cfg.Enabled || m.IsOffline() || !m.healthRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.healthRunning.Store(false)
+
+	now := time.Now()
+	probes := make([]model.HealthProbe, 0, len(cfg.Anchors)+len(cfg.URLs))
+	anchors := make(map[string]netip.Addr)
+	for _, anchor := range cfg.Anchors {
+		if anchor == internetHealthIspAnchor {
+			continue
+		}
+		addr, err := resolveAnchor(anchor)
+		if err != nil {
+			probes = append(probes, model.HealthProbe{
+				Start:  now,
+				Kind:   model.HealthProbePing,
+				Target: anchor,
+				Loss:   1,
+				Err:    err.Error(),
+			})
+			continue
+		}
+		anchors[anchor] = addr
+	}
+
+	hops := make(map[string]int)
+	if cfg.TracerouteInterval > 0 && m.cfg.Discovery.Icmp.Privileged &&
+		now.Sub(m.healthTraceroute) >= cfg.TracerouteInterval {
+		hops = m.traceAnchors(ctx, cfg.Anchors, anchors)
+		m.healthTraceroute = now
+	}
+	if m.healthIsp.IsValid() {
+		anchors[internetHealthIspAnchor] = m.healthIsp
+	}
+
+	for _, anchor := range cfg.Anchors {
+		addr, ok := anchors[anchor]
+		if !ok {
+			continue
+		}
+		probe := m.pingAnchor(ctx, anchor, addr)
+		probe.Start = now
+		probe.Hops = hops[anchor]
+		probes = append(probes, probe)
+	}
+	for _, url := range cfg.URLs {
+		probe := model.HealthProbe{Start: now, Kind: model.HealthProbeHTTP, Target: url}
+		latency, err := nettools.HTTPLatency(ctx, url)
+		probe.Latency = latency
+		if err != nil {
+			probe.Err = err.Error()
+		}
+		probes = append(probes, probe)
+	}
+
+	m.recordIfError(m.store.WriteHealthProbes(ctx, probes))
+	removed, err := m.store.PurgeHealthProbes(ctx, now.Add(-1*cfg.Retention))
+	m.recordIfError(err)
+	if removed > 0 {
+		log.Debug("purged internet health probes", "count", removed)
+	}
+}
+
+// traceAnchors returns the hop count of each anchor and sets the isp anchor to the first
+// public hop on the way to the first anchor
+func (m *Mason) traceAnchors(
+	ctx context.Context,
+	order []string,
+	anchors map[string]netip.Addr,
+) map[string]int {
+	hops := make(map[string]int)
+	ispFound := false
+	for _, anchor := range order {
+		addr, ok := anchors[anchor]
+		if !ok {
+			continue
+		}
+		stats, err := m.TracerouteAddr(ctx, model.AddrToModelAddr(addr))
+		if err != nil {
+			continue
+		}
+		hops[anchor] = len(stats)
+		if ispFound {
+			continue
+		}
+		for idx, stat := range stats {
+			if isPublicHop(stat.Peer) {
+				m.healthIsp = stat.Peer
+				hops[internetHealthIspAnchor] = idx + 1
+				ispFound = true
+				break
+			}
+		}
+	}
+	return hops
+}
+
+func (m *Mason) pingAnchor(
+	ctx context.Context,
+	anchor string,
+	addr netip.Addr,
+) model.HealthProbe {
+	probe := model.HealthProbe{Kind: model.HealthProbePing, Target: anchor}
+	responses, err := nettools.Icmp4Echo(
+		ctx,
+		addr,
+		nettools.I4EWithCount(m.cfg.Pinger.PingCount),
+		nettools.I4EWithReadTimeout(m.cfg.Pinger.Timeout),
+		nettools.I4EWithPrivileged(m.cfg.Pinger.Privileged),
+	)
+	stats := nettools.CalculateIcmp4EchoResponseStatistics(responses)
+	probe.Latency = stats.Mean
+	probe.Loss = stats.PacketLoss
+	if len(responses) == 0 {
+		probe.Loss = 1
+	}
+	if err != nil && !errors.Is(err, nettools.ErrNoResponseFromRemote) {
+		probe.Err = err.Error()
+	}
+	return probe
+}
+
+// resolveAnchor returns the address of an anchor given as an address or a hostname
+func resolveAnchor(anchor string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(anchor)
+	if err == nil {
+		return addr, nil
+	}
+	return nettools.FindFirstAddrOf(anchor)
+}
+
+func isPublicHop(addr netip.Addr) bool {
+	return addr.IsValid() && !addr.IsPrivate() && !addr.IsLoopback() &&
+		!addr.IsLinkLocalUnicast() && !addr.IsUnspecified()
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// maxAddressGrid is the largest network (a /20) which is laid out address by address,
+// larger networks only report their utilization counts
+const maxAddressGrid = 4096
+
+var ErrReservationOutsideNetworks = errors.New("address is not part of a known network")
+
+// GetAddressPlans returns the address plan for every stored network
+func (m *Mason) GetAddressPlans(ctx context.Context) ([]model.AddressPlan, error) {
+	reservations, err := m.store.ListReservations(ctx)
+	if err != nil {
+		m.recordIfError(err)
+		return nil, err
+	}
+	devices := m.store.ListDevices(ctx)
+	nets := m.store.ListNetworks(ctx)
+	model.SortNetworksByAddr(nets)
+
+	plans := make([]model.AddressPlan, 0, len(nets))
+	for _, n := range nets {
+		plans = append(plans, m.buildAddressPlan(n, devices, reservations))
+	}
+	return plans, nil
+}
+
+// GetAddressPlan returns the address plan for the named network
+func (m *Mason) GetAddressPlan(ctx context.Context, name string) (model.AddressPlan, error) {
+	n, err := m.store.GetNetworkByName(ctx, name)
+	if err != nil {
+		return model.AddressPlan{}, err
+	}
+	reservations, err := m.store.ListReservations(ctx)
+	if err != nil {
+		m.recordIfError(err)
+		return model.AddressPlan{}, err
+	}
+	return m.buildAddressPlan(n, m.store.ListDevices(ctx), reservations), nil
+}
+
+func (m *Mason) buildAddressPlan(
+	n model.Network,
+	devices []model.Device,
+	reservations []model.Reservation,
+) model.AddressPlan {
+	plan := model.BuildAddressPlan(n, devices, reservations, maxAddressGrid)
+	plan.Warn = plan.OverThreshold(m.cfg.Ipam.WarnThreshold)
+	return plan
+}
+
+// ListReservations returns all address reservations
+func (m *Mason) ListReservations(ctx context.Context) ([]model.Reservation, error) {
+	reservations, err := m.store.ListReservations(ctx)
+	m.recordIfError(err)
+	return reservations, err
+}
+
+// ReserveAddress stores a reservation or static assignment, the address must belong to one
+// of the stored networks
+func (m *Mason) ReserveAddress(ctx context.Context, r model.Reservation) error {
+	_, err := model.ParseReservationKind(string(r.Kind))
+	if err != nil {
+		return err
+	}
+	nets := m.store.GetFilteredNetworks(ctx, func(n model.Network) bool {
+		return n.Prefix.Contains(r.Addr)
+	})
+	if len(nets) == 0 {
+		return ErrReservationOutsideNetworks
+	}
+	err = m.store.UpsertReservation(ctx, r)
+	m.recordIfError(err)
+	return err
+}
+
+// ReleaseAddress removes the reservation for the address
+func (m *Mason) ReleaseAddress(ctx context.Context, addr model.Addr) error {
+	return m.store.RemoveReservation(ctx, addr)
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/kubernetes"
+	"github.com/networkables/mason/internal/model"
+)
+
+func (m *Mason) newKubernetes() *kubernetes.Client {
+	if !m.cfg.Kubernetes.Enabled {
+		return nil
+	}
+	c, err := kubernetes.New(m.cfg.Kubernetes)
+	if err != nil {
+		log.Error("kubernetes integration disabled", "error", err)
+		return nil
+	}
+	return c
+}
+
+// syncKubernetes lists the nodes and services of the cluster and adds them as devices, and
+// the pod networks as networks.  A run is skipped while the previous one is still going.
+func (m *Mason) syncKubernetes(ctx context.Context) {
+	if m.kube == nil || !m.kubeRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.kubeRunning.Store(false)
+
+	nodes, err := m.kube.Nodes(ctx)
+	if err != nil {
+		m.publish(tre.New(err, "list kubernetes nodes", "server", m.kube.Server()))
+		return
+	}
+	services, err := m.kube.Services(ctx)
+	if err != nil {
+		m.publish(tre.New(err, "list kubernetes services", "server", m.kube.Server()))
+		return
+	}
+	devices, networks := kubernetes.Inventory(
+		nodes,
+		services,
+		m.cfg.Kubernetes.PodNetworks,
+		time.Now(),
+	)
+	for _, d := range devices {
+		// the discovered tags replace those of a known device, keep the ones set by users
+		if prev, err := m.store.GetDeviceByAddr(ctx, d.Addr); err == nil {
+			d.Meta.Tags = unionTags(prev.Meta.Tags, d.Meta.Tags)
+		}
+		m.publish(model.EventDeviceDiscovered(d))
+	}
+	for _, n := range networks {
+		if !m.inKnownNetwork(ctx, n.Prefix.P.Addr()) {
+			m.publish(model.DiscoveredNetwork(n))
+		}
+	}
+}
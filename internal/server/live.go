@@ -0,0 +1,120 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/networkables/mason/internal/bus"
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	LiveEventDevice = "device"
+	LiveEventScan   = "scan"
+	LiveEventCheck  = "check"
+	LiveEventError  = "error"
+
+	liveEventBuffer = 64
+)
+
+// LiveEvent is a bus event reduced to what a client needs to decide what to refresh
+type LiveEvent struct {
+	Kind    string `json:"kind"`
+	Type    string `json:"type"`
+	Addr    string `json:"addr,omitempty"`
+	Message string `json:"message"`
+}
+
+// SubscribeEvents streams the device, scan, http and service check and error events published on the bus until the
+// context is done or the returned func is called.  A slow reader misses events rather than
+// holding up the bus.
+func (m *Mason) SubscribeEvents(ctx context.Context) (<-chan LiveEvent, func()) {
+	sub, unsubscribe := m.bus.Subscribe(liveEventBuffer)
+	out := make(chan LiveEvent, liveEventBuffer)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				unsubscribe()
+				return
+			case e, ok := <-sub:
+				if !ok {
+					return
+				}
+				le, ok := toLiveEvent(e)
+				if !ok {
+					continue
+				}
+				select {
+				case out <- le:
+				default:
+				}
+			}
+		}
+	}()
+	return out, unsubscribe
+}
+
+func toLiveEvent(e bus.Event) (LiveEvent, bool) {
+	le := LiveEvent{Type: liveEventType(e)}
+	switch e := e.(type) {
+	case model.EventDeviceAdded:
+		le.Kind, le.Addr, le.Message = LiveEventDevice, e.Addr.String(), e.String()
+	case model.EventDeviceUpdated:
+		le.Kind, le.Addr, le.Message = LiveEventDevice, e.Addr.String(), e.String()
+	case model.EventDeviceNeedsReview:
+		le.Kind, le.Addr, le.Message = LiveEventDevice, e.Addr.String(), e.String()
+	case model.EventDevicePortsChanged:
+		le.Kind, le.Addr, le.Message = LiveEventDevice, e.Device.Addr.String(), e.String()
+	case model.EventDeviceEdited:
+		le.Kind, le.Addr, le.Message = LiveEventDevice, e.Device.Addr.String(), e.String()
+	case model.EventDeviceAddrChanged:
+		le.Kind, le.Addr, le.Message = LiveEventDevice, e.Device.Addr.String(), e.String()
+	case model.EventFlowAnomaly:
+		le.Kind, le.Addr, le.Message = LiveEventDevice, e.Addr.String(), e.String()
+	case model.EventMACConflict:
+		le.Kind, le.Addr, le.Message = LiveEventDevice, e.Addr.String(), e.String()
+	case discovery.EventNetworkScanStarted:
+		le.Kind, le.Message = LiveEventScan, e.String()
+	case discovery.EventNetworkScanFinished:
+		le.Kind, le.Message = LiveEventScan, e.String()
+	case model.EventHTTPCheckChanged:
+		le.Kind, le.Message = LiveEventCheck, e.String()
+	case model.EventServiceCheckChanged:
+		le.Kind, le.Addr, le.Message = LiveEventCheck, e.Check.Addr.String(), e.String()
+	case model.EventDevicePingChanged:
+		le.Kind, le.Addr, le.Message = LiveEventDevice, e.Device.Addr.String(), e.String()
+	case model.EventUpstreamFailure:
+		le.Kind, le.Addr, le.Message = LiveEventCheck, e.Parent.Addr.String(), e.String()
+	case model.EventUpstreamRecovered:
+		le.Kind, le.Addr, le.Message = LiveEventCheck, e.Parent.Addr.String(), e.String()
+	case model.EventRogueDHCP:
+		le.Kind, le.Addr, le.Message = LiveEventCheck, e.Addr.String(), e.String()
+	case model.EventQuotaAlert:
+		le.Kind, le.Addr, le.Message = LiveEventDevice, e.Usage.Addr.String(), e.String()
+	case model.EventHookAlert:
+		le.Kind, le.Addr, le.Message = LiveEventDevice, e.Addr.String(), e.String()
+	case model.EventSyslogAlert:
+		le.Kind, le.Addr, le.Message = LiveEventDevice, e.Addr.String(), e.String()
+	case error:
+		le.Kind, le.Message = LiveEventError, e.Error()
+	default:
+		return le, false
+	}
+	return le, true
+}
+
+func liveEventType(e bus.Event) string {
+	name := fmt.Sprintf("%T", e)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
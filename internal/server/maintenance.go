@@ -0,0 +1,87 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+var ErrMaintenanceNameRequired = errors.New("maintenance window name is required")
+
+// ListMaintenanceWindows returns all maintenance windows
+func (m *Mason) ListMaintenanceWindows(ctx context.Context) ([]model.MaintenanceWindow, error) {
+	windows, err := m.store.ListMaintenanceWindows(ctx)
+	m.recordIfError(err)
+	return windows, err
+}
+
+// SaveMaintenanceWindow creates or updates a maintenance window
+func (m *Mason) SaveMaintenanceWindow(ctx context.Context, w model.MaintenanceWindow) error {
+	if w.Name == "" {
+		return ErrMaintenanceNameRequired
+	}
+	if w.Duration <= 0 {
+		return model.ErrInvalidMaintenanceDuration
+	}
+	var err error
+	switch w.Scope {
+	case model.MaintenanceScopeDevice:
+		var addr model.Addr
+		addr, err = model.ParseAddr(w.Target)
+		if err == nil {
+			w.Target = addr.String()
+		}
+	case model.MaintenanceScopeTag:
+		if !model.ValidTagName(w.Target) {
+			err = model.ErrInvalidTagName
+		}
+	case model.MaintenanceScopeNetwork:
+		_, err = m.store.GetNetworkByName(ctx, w.Target)
+	default:
+		err = model.ErrInvalidMaintenanceScope
+	}
+	if err != nil {
+		return err
+	}
+	return m.store.UpsertMaintenanceWindow(ctx, w)
+}
+
+// RemoveMaintenanceWindow deletes the named maintenance window
+func (m *Mason) RemoveMaintenanceWindow(ctx context.Context, name string) error {
+	return m.store.RemoveMaintenanceWindow(ctx, name)
+}
+
+// DevicesInMaintenance returns the devices covered by an active maintenance window
+func (m *Mason) DevicesInMaintenance(ctx context.Context) []model.Device {
+	lookup := m.maintenanceLookup(ctx, time.Now())
+	devs := make([]model.Device, 0)
+	for _, d := range m.store.ListDevices(ctx) {
+		if _, ok := lookup(d); ok {
+			devs = append(devs, d)
+		}
+	}
+	return devs
+}
+
+// maintenanceLookup returns a func reporting the maintenance window active at t for a device
+func (m *Mason) maintenanceLookup(
+	ctx context.Context,
+	t time.Time,
+) func(model.Device) (model.MaintenanceWindow, bool) {
+	windows, err := m.store.ListMaintenanceWindows(ctx)
+	if err != nil || len(windows) == 0 {
+		return func(model.Device) (model.MaintenanceWindow, bool) {
+			return model.MaintenanceWindow{}, false
+		}
+	}
+	nets := m.store.ListNetworks(ctx)
+	return func(d model.Device) (model.MaintenanceWindow, bool) {
+		return model.ActiveMaintenance(windows, d, nets, t)
+	}
+}
@@ -8,12 +8,15 @@ package server
 import (
 	"context"
 	"errors"
+	"io"
 	"math"
 	"net"
 	"net/netip"
 	"runtime"
 	"runtime/debug"
 	"slices"
+	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -23,11 +26,19 @@ import (
 	"github.com/networkables/mason/internal/asn"
 	"github.com/networkables/mason/internal/bus"
 	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/dnslog"
 	"github.com/networkables/mason/internal/enrichment"
+	"github.com/networkables/mason/internal/exporter"
+	"github.com/networkables/mason/internal/hooks"
+	"github.com/networkables/mason/internal/kubernetes"
 	"github.com/networkables/mason/internal/model"
 	"github.com/networkables/mason/internal/netflows"
+	"github.com/networkables/mason/internal/notify"
 	"github.com/networkables/mason/internal/oui"
 	"github.com/networkables/mason/internal/pinger"
+	"github.com/networkables/mason/internal/ratelimit"
+	"github.com/networkables/mason/internal/syslog"
+	"github.com/networkables/mason/internal/unifi"
 	"github.com/networkables/mason/nettools"
 )
 
@@ -36,8 +47,9 @@ type Mason struct {
 	cfg *Config
 
 	// datastores
-	store     Storer
-	flowstore NetflowStorer
+	store      Storer
+	flowstore  NetflowStorer
+	timeseries TimeseriesStorer
 
 	// Workers
 	enrichmentWorker     *enrichment.Worker
@@ -45,21 +57,164 @@ type Mason struct {
 	networkScannerWorker *discovery.NetworkScannerWorker
 	pingerWorker         *pinger.Worker
 	netflowsWorker       *netflows.Worker
+	flowInserter         *netflows.Inserter
+	dnsCollector         *dnslog.Collector
+	syslogReceiver       *syslog.Receiver
+
+	// hourly traffic baselines of devices, nil when flow anomaly detection is disabled
+	flowAnomalies *netflows.Detector
+
+	// probe pacing shared by the network scans and port scans
+	limits *ratelimit.Group
+
+	// last traceroute hops by target, used to annotate route changes
+	routes   map[string][]string
+	routesMu sync.Mutex
+
+	// last report from each remote agent, by agent name
+	agents   map[string]AgentStatus
+	agentsMu sync.Mutex
+
+	// internet health prober state, the isp anchor is found by the traceroutes
+	healthRunning    atomic.Bool
+	healthTraceroute time.Time
+	healthIsp        netip.Addr
+
+	// topology tracer state, the last trace to a device of each network
+	topologyRunning atomic.Bool
+	topologyTraces  map[model.Prefix]model.TopologyTrace
+	topologyMu      sync.Mutex
+
+	// http check runner state, the last result by check name
+	httpChecksRunning atomic.Bool
+	httpChecksLast    map[string]model.HTTPCheckResult
+	httpChecksLoaded  bool
+	httpChecksMu      sync.Mutex
+
+	// service check runner state, whether each service was down on its last run
+	serviceChecksRunning atomic.Bool
+	serviceChecksLast    map[model.ServiceCheck]bool
+	serviceChecksLoaded  bool
+	serviceChecksMu      sync.Mutex
+
+	// ping failures of devices behind a down parent, grouped under the parent
+	pingUpstream *pingUpstream
+
+	// full port sweeps, a chunk of each sweep is scanned at a time
+	fullScanRunning atomic.Bool
+
+	// arp watch state, the MACs seen at each address and when its last conflict was raised
+	macBindings       map[model.Addr][]model.MACBinding
+	macConflicts      map[model.Addr]time.Time
+	macExclusions     []netip.Prefix
+	macBindingsLoaded bool
+	macBindingsMu     sync.Mutex
+
+	// dhcp watch state, a probe runs at a time and sightings are updated under the lock
+	dhcpWatchRunning atomic.Bool
+	dhcpSightingsMu  sync.Mutex
+
+	quotasRunning atomic.Bool
+
+	// exclusions of the config file, parsed on first use
+	cfgExclusions     []model.Exclusion
+	cfgExclusionsOnce sync.Once
+
+	// packet captures by id, the running ones are waited for at shutdown
+	captures       map[string]*captureRun
+	capturesLoaded bool
+	capturesMu     sync.Mutex
+	capturesWG     sync.WaitGroup
+
+	// scripting hooks, nil when disabled, with when each hook last alerted on each address
+	hookLoader   *hooks.Loader
+	hookAlerts   map[hookAlertKey]time.Time
+	hookAlertsMu sync.Mutex
+
+	// recent syslog messages of each device, nil when the receiver is disabled, with when
+	// each device last raised a syslog alert
+	syslogs             *syslog.Ring
+	syslogAlertSeverity model.SyslogSeverity
+	syslogAlerts        map[model.Addr]time.Time
+	syslogAlertsMu      sync.Mutex
+
+	// email channel of the notifications, nil when disabled, and the enabled chats and
+	// incident services
+	mailer    *notify.Email
+	notifiers []notify.Notifier
+
+	speedTestRunning atomic.Bool
+	eventHistoryDone chan struct{}
+
+	// unix nanos of the last pass of the main loop, zero until it runs
+	heartbeat atomic.Int64
+	// events dispatched by the bus as of the last self check
+	selfCheckDispatched atomic.Uint64
+
+	// device identity merges, discovery and the reconcile pass take turns
+	reconcileRunning atomic.Bool
+	identityMu       sync.Mutex
+
+	// remote write of ping statistics and snmp counters, nil when disabled
+	exporter      *exporter.Exporter
+	exportRunning atomic.Bool
+
+	// cluster api of the kubernetes integration, nil when disabled
+	kube        *kubernetes.Client
+	kubeRunning atomic.Bool
+
+	cloudRunning atomic.Bool
+
+	// unifi controller client, nil when disabled
+	unifi        *unifi.Client
+	unifiRunning atomic.Bool
+
+	netboxRunning atomic.Bool
+
+	backupRunning atomic.Bool
+
+	// discovery providers registered through pkg/provider and enabled by name
+	discoveryProviders []namedDiscoverer
+	providersRunning   atomic.Bool
 
 	// status stuff
-	currentNetworkScan *string
-	busBackPressure    atomic.Int32
+	networkScans       *discovery.ScanProgress
 	enrichBackPressure atomic.Int32
 }
 
 func New(opts ...Option) *Mason {
 	o := applyOptionsToDefault(opts...)
 	m := &Mason{
-		cfg:                o.cfg,
-		currentNetworkScan: o.scanstatus,
-		bus:                o.bus,
-		store:              o.store,
-		flowstore:          o.nfstore,
+		cfg:               o.cfg,
+		bus:               o.bus,
+		store:             o.store,
+		flowstore:         o.nfstore,
+		timeseries:        o.tsstore,
+		routes:            make(map[string][]string),
+		topologyTraces:    make(map[model.Prefix]model.TopologyTrace),
+		agents:            make(map[string]AgentStatus),
+		httpChecksLast:    make(map[string]model.HTTPCheckResult),
+		serviceChecksLast: make(map[model.ServiceCheck]bool),
+		pingUpstream:      newPingUpstream(),
+		macBindings:       make(map[model.Addr][]model.MACBinding),
+		macConflicts:      make(map[model.Addr]time.Time),
+		captures:          make(map[string]*captureRun),
+		hookAlerts:        make(map[hookAlertKey]time.Time),
+		syslogAlerts:      make(map[model.Addr]time.Time),
+		limits:            ratelimit.NewGroup(o.cfg.RateLimit),
+	}
+	if m.timeseries == nil {
+		m.timeseries = o.store
+	}
+	m.exporter = m.newExporter()
+	m.kube = m.newKubernetes()
+	m.unifi = m.newUnifi()
+	m.mailer = m.newMailer()
+	m.notifiers = m.newNotifiers()
+	m.networkScans = discovery.NewScanProgress(func(e any) { m.publish(e) })
+	m.discoveryProviders = m.newProviders()
+	if o.cfg.Hooks != nil && o.cfg.Hooks.Enabled {
+		m.hookLoader = hooks.NewLoader(o.cfg.Hooks.File)
 	}
 
 	if o.cfg.Oui.Enabled {
@@ -67,6 +222,7 @@ func New(opts ...Option) *Mason {
 			oui.WithUrl(o.cfg.Oui.Url),
 			oui.WithDirectory(o.cfg.Oui.Directory),
 			oui.WithFilename(o.cfg.Oui.Filename),
+			oui.WithOffline(o.cfg.Offline.Enabled),
 		)
 	}
 
@@ -77,6 +233,7 @@ func New(opts ...Option) *Mason {
 			asn.WithDirectory(o.cfg.Asn.Directory),
 			asn.WithCacheFilename(o.cfg.Asn.CacheFilename),
 			asn.WithStorer(o.nfstore),
+			asn.WithOffline(o.cfg.Offline.Enabled),
 		)
 	}
 
@@ -84,39 +241,74 @@ func New(opts ...Option) *Mason {
 }
 
 func (m *Mason) createWorkerPools(ctx context.Context) {
-	m.discoveryWorker = discovery.NewWorker(m.cfg.Discovery)
+	m.discoveryWorker = discovery.NewWorker(m.cfg.Discovery, m.networkScans)
 	m.networkScannerWorker = discovery.NewNetworkScannerWorker(
-		m.currentNetworkScan,
+		m.networkScans,
 		m.discoveryWorker.In,
+		m.limits,
+		m.exclusions,
 	)
-	m.enrichmentWorker = enrichment.NewWorker()
+	m.enrichmentWorker = enrichment.NewWorker(m.limits)
 	m.pingerWorker = pinger.NewWorker(m.cfg.Pinger)
 	if m.cfg.NetFlows.Enabled {
 		if m.flowstore == nil {
 			log.Fatal("netflows enabled, but flowstore is nil")
 		}
 		input := netflows.Listen(ctx, m.cfg.NetFlows)
-		m.netflowsWorker = netflows.NewWorker(m.cfg.NetFlows, input)
+		m.netflowsWorker = netflows.NewWorker(m.cfg.NetFlows, input, m.flowTemplates(ctx))
+		m.flowInserter = netflows.NewInserter(
+			m.cfg.NetFlows.Insert,
+			m.prepareFlows,
+			m.writeFlows,
+			m.afterFlowsWritten,
+		)
+		m.flowAnomalies = m.newFlowAnomalyDetector(ctx)
+	}
+	if m.cfg.DNSLog.Enabled {
+		if m.flowstore == nil {
+			log.Fatal("dnslog enabled, but flowstore is nil")
+		}
+		m.dnsCollector = dnslog.NewCollector(m.cfg.DNSLog, m.writeDNSQueries)
+	}
+	if m.cfg.Syslog.Enabled {
+		severity, err := model.ParseSyslogSeverity(m.cfg.Syslog.AlertSeverity)
+		if err != nil {
+			log.Fatal("syslog alert severity", "error", err)
+		}
+		m.syslogAlertSeverity = severity
+		m.syslogs = syslog.NewRing(m.cfg.Syslog.RingSize)
+		m.syslogReceiver = syslog.NewReceiver(m.cfg.Syslog, m.handleSyslogMessage)
 	}
 }
 
 func (m *Mason) shutdown() {
+	m.stopCaptures()
 	m.enrichmentWorker.Close()
 	m.discoveryWorker.Close()
 	m.networkScannerWorker.Close()
 	m.pingerWorker.Close()
 	if m.netflowsWorker != nil {
 		m.netflowsWorker.Close()
+		// the pending flows are written before the store closes
+		<-m.flowInserter.Stopped()
+	}
+	if m.exporter != nil {
+		// the run context is done, the flush is bounded by the exporter timeout
+		_, err := m.exporter.Flush(context.Background())
+		m.recordIfError(err)
+	}
+	if m.eventHistoryDone != nil {
+		// the events of the last flush interval are written before the store closes
+		<-m.eventHistoryDone
+	}
+	if c, ok := m.timeseries.(io.Closer); ok && any(m.timeseries) != any(m.store) {
+		c.Close()
 	}
 	m.store.Close()
 }
 
 func (m *Mason) publish(e bus.Event) {
-	m.busBackPressure.Add(1)
-	go func() {
-		m.bus.Publish(e)
-		m.busBackPressure.Add(-1)
-	}()
+	m.bus.Publish(e)
 }
 
 func (m *Mason) Run(ctx context.Context) {
@@ -127,26 +319,124 @@ func (m *Mason) Run(ctx context.Context) {
 
 	// Bus
 	go m.bus.Run(ctx)
+	if m.cfg.EventHistory.Enabled {
+		m.eventHistoryDone = make(chan struct{})
+		go m.recordEventHistory(ctx)
+	}
+	if m.notifying() {
+		go m.runNotifications(ctx)
+	}
 
 	// Setup timers (tickers) for regularly scheduled actions
 	networkScanTrigger := time.NewTicker(m.cfg.Discovery.CheckInterval)
-	pingerTrigger := time.NewTicker(m.cfg.Pinger.CheckInterval)
+	pingerTrigger := time.NewTicker(m.pingerCheckInterval(ctx))
 	snmpArpTableRescanTrigger := time.NewTicker(m.cfg.Discovery.Snmp.ArpTableRescanInterval)
 	snmpInterfaceRescanTrigger := time.NewTicker(m.cfg.Discovery.Snmp.InterfaceRescanInterval)
+	archiveTrigger := time.NewTicker(archiveCheckInterval)
+	purgeTrigger := time.NewTicker(tombstonePurgeInterval)
+	asnRefreshTrigger := time.NewTicker(asnRefreshCheckInterval)
+	internetHealthTrigger := time.NewTicker(m.cfg.InternetHealth.Interval)
+	topologyTrigger := time.NewTicker(m.cfg.Topology.Interval)
+	httpChecksTrigger := time.NewTicker(m.cfg.HTTPChecks.Interval)
+	serviceChecksTrigger := time.NewTicker(m.cfg.ServiceChecks.Interval)
+	fullScanTrigger := time.NewTicker(m.cfg.FullScan.Interval)
+	dhcpWatchTrigger := time.NewTicker(m.cfg.DHCPWatch.Interval)
+	quotasTrigger := time.NewTicker(m.cfg.Quotas.Interval)
+	speedTestTrigger := time.NewTicker(m.cfg.SpeedTest.Interval)
+	exportTrigger := time.NewTicker(m.cfg.Exporter.Interval)
+	kubernetesTrigger := time.NewTicker(m.cfg.Kubernetes.Interval)
+	unifiTrigger := time.NewTicker(m.cfg.Unifi.Interval)
+	netboxTrigger := time.NewTicker(m.cfg.Netbox.Interval)
+	backupTrigger := time.NewTicker(m.cfg.Backup.Interval)
+	cloudTrigger := time.NewTicker(m.cfg.Cloud.Interval)
+	providersTrigger := time.NewTicker(m.cfg.Providers.Interval)
+	reconcileTrigger := time.NewTicker(m.cfg.Identity.ReconcileInterval)
+	hostArpTrigger := time.NewTicker(m.cfg.Discovery.HostArp.Interval)
+	hostNdpTrigger := time.NewTicker(m.cfg.Discovery.Ndp.Interval)
+	virtualRescanTrigger := time.NewTicker(m.cfg.Enrichment.Virtual.RescanInterval)
+	heartbeatTrigger := time.NewTicker(heartbeatInterval)
 	defer func() {
 		networkScanTrigger.Stop()
 		pingerTrigger.Stop()
 		snmpArpTableRescanTrigger.Stop()
 		snmpInterfaceRescanTrigger.Stop()
+		archiveTrigger.Stop()
+		purgeTrigger.Stop()
+		asnRefreshTrigger.Stop()
+		internetHealthTrigger.Stop()
+		topologyTrigger.Stop()
+		httpChecksTrigger.Stop()
+		serviceChecksTrigger.Stop()
+		fullScanTrigger.Stop()
+		dhcpWatchTrigger.Stop()
+		quotasTrigger.Stop()
+		speedTestTrigger.Stop()
+		exportTrigger.Stop()
+		kubernetesTrigger.Stop()
+		unifiTrigger.Stop()
+		netboxTrigger.Stop()
+		backupTrigger.Stop()
+		cloudTrigger.Stop()
+		providersTrigger.Stop()
+		reconcileTrigger.Stop()
+		hostArpTrigger.Stop()
+		hostNdpTrigger.Stop()
+		virtualRescanTrigger.Stop()
+		heartbeatTrigger.Stop()
 	}()
 
+	// check the stores before any worker can change them
+	if m.cfg.Consistency.CheckOnStartup {
+		m.checkConsistency(ctx, m.cfg.Consistency.Repair)
+	}
+
+	// port scans of known networks share the network's rate limit from the start
+	for _, n := range m.store.ListNetworks(ctx) {
+		m.limits.Network(n.Prefix.P)
+	}
+
 	// kick off the worker pools
 	go m.discoveryWorker.Run(ctx, m.cfg.Discovery.MaxWorkers)
-	go m.networkScannerWorker.Run(ctx)
+	go m.networkScannerWorker.Run(ctx, m.cfg.Discovery.MaxNetworkScanners)
 	go m.enrichmentWorker.Run(ctx, m.cfg.Enrichment.MaxWorkers)
 	go m.pingerWorker.Run(ctx, m.cfg.Pinger.MaxWorkers)
 	if m.cfg.NetFlows.Enabled {
 		go m.netflowsWorker.Run(ctx, m.cfg.NetFlows.MaxWorkers)
+		go m.flowInserter.Run(ctx)
+	}
+	if m.cfg.DNSLog.Enabled {
+		go m.dnsCollector.Run(ctx)
+	}
+	if m.cfg.Syslog.Enabled {
+		go m.syslogReceiver.Run(ctx)
+	}
+	if m.cfg.Discovery.Enabled && m.cfg.Discovery.Dhcp.Enabled {
+		go m.listenDHCP(ctx)
+	}
+	if m.cfg.Discovery.Enabled && m.cfg.Discovery.Ndp.Enabled {
+		go m.listenNDP(ctx)
+	}
+
+	// a listing left over from a long shutdown is refreshed without waiting for the trigger
+	go m.refreshAsnIfStale(ctx)
+	go m.checkInternetHealth(ctx)
+	go m.traceTopology(ctx)
+	go m.runHTTPChecks(ctx)
+	go m.runServiceChecks(ctx)
+	go m.probeDHCP(ctx)
+	go m.checkQuotas(ctx)
+	go m.runSpeedTestIfDue(ctx)
+	go m.ingestHostArpTable(ctx)
+	go m.syncKubernetes(ctx)
+	go m.syncUnifi(ctx)
+	go m.syncNetbox(ctx)
+	go m.importClouds(ctx)
+	go m.discoverFromProviders(ctx)
+
+	// the fixture networks are in place before deciding on the bootstrap
+	m.injectFixture(ctx)
+	if m.cfg.Discovery.DryRun.Enabled {
+		log.Warn("discovery dry run, network scans do not send probes")
 	}
 
 	if m.store.CountNetworks(ctx) == 0 && m.cfg.Discovery.BootstrapOnFirstRun {
@@ -156,6 +446,7 @@ func (m *Mason) Run(ctx context.Context) {
 		}()
 	}
 
+	m.heartbeat.Store(time.Now().UnixNano())
 	for {
 		select {
 
@@ -169,6 +460,9 @@ func (m *Mason) Run(ctx context.Context) {
 			// Time Triggers
 			//
 			//
+		case <-heartbeatTrigger.C:
+			m.heartbeat.Store(time.Now().UnixNano())
+
 		case <-networkScanTrigger.C:
 			if m.cfg.Discovery.Enabled {
 				m.publish(model.ScanAllNetworksRequest{})
@@ -178,12 +472,15 @@ func (m *Mason) Run(ctx context.Context) {
 			if m.cfg.Pinger.Enabled {
 				m.publish(pinger.PerfPingDevicesEvent{})
 			}
+			// pick up tag policies which changed since the last check
+			pingerTrigger.Reset(m.pingerCheckInterval(ctx))
 
 		case <-snmpArpTableRescanTrigger.C:
 			go func() {
-				devs := m.store.GetFilteredDevices(ctx,
+				devs := m.store.GetFilteredDevices(ctx, m.exclusions(ctx).Filter(
+					model.ExcludeScan,
 					discovery.SnmpArpTableRescanFilter(m.cfg.Discovery.Snmp),
-				)
+				))
 				for _, dev := range devs {
 					m.publish(discovery.DiscoverDevicesFromSNMPDevice{Device: dev})
 				}
@@ -191,14 +488,86 @@ func (m *Mason) Run(ctx context.Context) {
 
 		case <-snmpInterfaceRescanTrigger.C:
 			go func() {
-				devs := m.store.GetFilteredDevices(ctx,
+				devs := m.store.GetFilteredDevices(ctx, m.exclusions(ctx).Filter(
+					model.ExcludeScan,
 					discovery.SnmpArpTableRescanFilter(m.cfg.Discovery.Snmp),
-				)
+				))
 				for _, dev := range devs {
 					m.publish(discovery.DiscoverNetworksFromSNMPDevice{Device: dev})
 				}
 			}()
 
+		case <-archiveTrigger.C:
+			go m.archiveTimeseries(ctx)
+
+		case <-purgeTrigger.C:
+			go m.purgeDeleted(ctx)
+			go m.purgeMACBindings(ctx)
+			go m.purgeDNSQueries(ctx)
+			go m.purgeCaptures(ctx)
+
+		case <-reconcileTrigger.C:
+			go m.reconcileIdentities(ctx)
+
+		case <-hostArpTrigger.C:
+			go m.ingestHostArpTable(ctx)
+
+		case <-hostNdpTrigger.C:
+			go m.ingestHostNdpTable(ctx)
+
+		case <-virtualRescanTrigger.C:
+			if m.cfg.Enrichment.Enabled && m.cfg.Enrichment.Virtual.Enabled {
+				go m.rescanVirtualHosts(ctx)
+			}
+
+		case <-asnRefreshTrigger.C:
+			go m.refreshAsnIfStale(ctx)
+
+		case <-internetHealthTrigger.C:
+			go m.checkInternetHealth(ctx)
+
+		case <-topologyTrigger.C:
+			go m.traceTopology(ctx)
+
+		case <-httpChecksTrigger.C:
+			go m.runHTTPChecks(ctx)
+
+		case <-serviceChecksTrigger.C:
+			go m.runServiceChecks(ctx)
+
+		case <-fullScanTrigger.C:
+			go m.runFullScans(ctx)
+
+		case <-dhcpWatchTrigger.C:
+			go m.probeDHCP(ctx)
+
+		case <-quotasTrigger.C:
+			go m.checkQuotas(ctx)
+
+		case <-speedTestTrigger.C:
+			go m.runSpeedTest(ctx)
+
+		case <-exportTrigger.C:
+			go m.exportMetrics(ctx)
+
+		case <-kubernetesTrigger.C:
+			go m.syncKubernetes(ctx)
+
+		case <-unifiTrigger.C:
+			go m.syncUnifi(ctx)
+
+		case <-netboxTrigger.C:
+			go m.syncNetbox(ctx)
+
+		case <-backupTrigger.C:
+			go m.scheduledBackup(ctx)
+
+		case <-cloudTrigger.C:
+			go m.importClouds(ctx)
+
+		case <-providersTrigger.C:
+			go m.discoverFromProviders(ctx)
+
 		//
 		//
 		// Permanent WorkerPool handling
@@ -216,14 +585,13 @@ func (m *Mason) Run(ctx context.Context) {
 			}
 
 		case enrichedDevice := <-m.enrichmentWorker.C:
-			_, err := m.store.UpdateDevice(ctx, enrichedDevice)
+			err := m.storeEnrichedDevice(ctx, enrichedDevice)
 			if err != nil {
-				// log.Error("enrich, update device", "error", err)
-				m.publish(tre.New(err, "enriched device store update", "addr", enrichedDevice.Addr))
+				m.publish(err)
 			}
-			if enrichedDevice.SNMP.Community != "" {
-				m.publish(discovery.DiscoverDevicesFromSNMPDevice{Device: enrichedDevice})
-				m.publish(discovery.DiscoverNetworksFromSNMPDevice{Device: enrichedDevice})
+			// hosts, and devices which stopped being one, hold the parent of their guests
+			if !enrichedDevice.Virtual.LastScan.IsZero() {
+				go m.linkGuests(ctx, enrichedDevice)
 			}
 
 		case err := <-m.enrichmentWorker.E:
@@ -236,38 +604,16 @@ func (m *Mason) Run(ctx context.Context) {
 			m.publish(tre.New(err, "networkscanner worker error"))
 
 		case pingPerf := <-m.pingerWorker.C:
-			_, err := m.store.UpdateDevice(ctx, pingPerf.Device)
-			if err != nil {
-				m.publish(tre.New(err, "update device to store", "addr", pingPerf.Device.Addr))
-			}
-			err = m.store.WritePerformancePing(
-				ctx,
-				pingPerf.Start,
-				pingPerf.Device,
-				pingPerf.Stats,
-			)
+			err := m.storePerformancePing(ctx, pingPerf)
 			if err != nil {
-				m.publish(tre.New(err, "write pinger point", "addr", pingPerf.Device.Addr))
+				m.publish(err)
 			}
-			m.publish(model.EventDeviceUpdated(pingPerf.Device))
 
 		case err := <-m.pingerWorker.E:
 			m.publish(tre.New(err, "pinger worker error"))
 
 		case flows := <-m.netflowsWorker.C:
-			go func() {
-				var err error
-				for idx, flow := range flows {
-					srcasn := m.LookupIP(flow.SrcAddr)
-					dstasn := m.LookupIP(flow.DstAddr)
-					flows[idx].SrcASN = srcasn
-					flows[idx].DstASN = dstasn
-				}
-				err = m.flowstore.AddNetflows(ctx, flows)
-				if err != nil {
-					m.publish(err)
-				}
-			}()
+			m.flowInserter.Submit(flows)
 
 		case err := <-m.netflowsWorker.E:
 			m.publish(tre.New(err, "netflows worker"))
@@ -287,38 +633,34 @@ func (m *Mason) Run(ctx context.Context) {
 			//
 			//
 			case model.EventDeviceDiscovered:
-				// - try to add to ds
+				// - try to add to ds, new devices wait for review
 				d := model.Device(event)
-				err := m.store.AddDevice(ctx, d)
-				if err == nil {
-					// - if new emit new device event
-					m.publish(model.EventDeviceAdded(event))
+				if m.applyDeviceHooks(ctx, hooks.OnDiscovered, &d) {
 					continue
 				}
-				if errors.Is(err, model.ErrDeviceExists) {
-					enrich, err := m.store.UpdateDevice(ctx, d)
-					if err == nil {
-						if enrich {
-							m.publish(
-								enrichment.EnrichDeviceRequest{
-									Device: model.Device(event),
-									Fields: enrichment.DefaultEnrichmentFields(m.cfg.Enrichment),
-								})
-						}
-						continue
-					}
+				m.watchMACBinding(ctx, d)
+				if prev, ok := m.previousDevice(ctx, d); ok {
+					// - a known device at a new address may have moved, checked off the loop
+					go m.checkDeviceMoved(ctx, prev, d)
+					continue
 				}
-				m.publish(tre.New(err, "adding discovered device"))
+				m.addDiscoveredDevice(ctx, d)
 
 			case model.EventDeviceUpdated:
-				enrich, err := m.store.UpdateDevice(ctx, model.Device(event))
+				d := model.Device(event)
+				m.applyDeviceHooks(ctx, hooks.OnUpdated, &d)
+				enrich, err := m.updateDevice(
+					ctx,
+					d,
+					model.ChangeSourceUpdate,
+				)
 				if err != nil {
 					m.publish(tre.New(err, "storing updated device"))
 				}
 				if enrich {
 					m.publish(
 						enrichment.EnrichDeviceRequest{
-							Device: model.Device(event),
+							Device: d,
 							Fields: enrichment.DefaultEnrichmentFields(m.cfg.Enrichment),
 						})
 				}
@@ -379,14 +721,27 @@ func (m *Mason) Run(ctx context.Context) {
 
 			// Ping all devices who need to be pinged again
 			case pinger.PerfPingDevicesEvent:
+				m.publishPingUpstream()
 				go func() {
-					devices := m.store.GetFilteredDevices(ctx, pinger.PerformancePingerFilter(m.cfg.Pinger))
+					devices := m.store.GetFilteredDevices(ctx, m.exclusions(ctx).Filter(
+						model.ExcludePing,
+						pinger.PerformancePingerFilter(m.cfg.Pinger, m.policyLookup(ctx)),
+					))
 					for _, device := range devices {
 						m.pingerWorker.In <- device
 					}
 				}()
 
 			case enrichment.EnrichDeviceRequest:
+				event = excludeEnrichment(m.exclusions(ctx), event)
+				if event.Fields.PerformPortScan && len(event.Ports) == 0 {
+					ports, err := m.DevicePorts(ctx, event.Device)
+					if err != nil {
+						m.recordIfError(err)
+						event.Fields.PerformPortScan = false
+					}
+					event.Ports = ports
+				}
 				m.enrichBackPressure.Add(1)
 				go func() {
 					select {
@@ -400,7 +755,7 @@ func (m *Mason) Run(ctx context.Context) {
 			case enrichment.EnrichAllDevicesEvent:
 				if m.cfg.Enrichment.Enabled {
 					go func() {
-						devices := m.store.GetFilteredDevices(ctx, enrichment.PortScannerFilter(m.cfg.Enrichment.PortScan))
+						devices := m.store.GetFilteredDevices(ctx, enrichment.PortScannerFilter(m.cfg.Enrichment.PortScan, m.policyLookup(ctx)))
 						for _, d := range devices {
 							m.publish(enrichment.EnrichDeviceRequest{Device: d, Fields: enrichment.EnrichmentFields(event)})
 						}
@@ -411,7 +766,7 @@ func (m *Mason) Run(ctx context.Context) {
 				go discoverNetworksFromSnmp(ctx, event, m.cfg.Enrichment.Snmp.Timeout, m.publish, m.AddNetworkByName)
 
 			case discovery.DiscoverDevicesFromSNMPDevice:
-				go discoverDevicesFromSnmp(ctx, event, m.cfg.Enrichment.Snmp.Timeout, m.publish)
+				go discoverDevicesFromSnmp(ctx, event, m.cfg.Enrichment.Snmp.Timeout, m.publish, m.ListDevices)
 			}
 		}
 	}
@@ -465,7 +820,9 @@ func discoverDevicesFromSnmp(
 	event discovery.DiscoverDevicesFromSNMPDevice,
 	timeout time.Duration,
 	publish func(bus.Event),
+	listDevices func(context.Context) []model.Device,
 ) {
+	vlans := snmpVlansByMAC(ctx, event, timeout, publish)
 	arps, err := nettools.SnmpGetArpTable(ctx, event.Addr.Addr(),
 		nettools.WithSnmpCommunity(event.SNMP.Community),
 		nettools.WithSnmpPort(event.SNMP.Port),
@@ -483,10 +840,23 @@ func discoverDevicesFromSnmp(
 		publish(model.EventDeviceDiscovered{
 			Addr:         model.AddrToModelAddr(arp.Addr),
 			MAC:          model.HardwareAddrToMAC(arp.MAC),
+			VLAN:         vlans[arp.MAC.String()],
 			DiscoveredBy: discovery.SNMPArpDiscoverySource,
 			DiscoveredAt: time.Now(),
 		})
 	}
+	// bridges learn macs of devices they do not route for, so apply to any known device
+	if len(vlans) > 0 {
+		for _, dev := range listDevices(ctx) {
+			vlan, ok := vlans[dev.MAC.String()]
+			if !ok || dev.VLAN == vlan {
+				continue
+			}
+			dev.VLAN = vlan
+			dev.SetUpdated()
+			publish(model.EventDeviceUpdated(dev))
+		}
+	}
 	event.Device.SNMP.LastArpTableScan = time.Now()
 	if len(arps) > 0 {
 		event.Device.SNMP.HasArpTable = true
@@ -495,6 +865,49 @@ func discoverDevicesFromSnmp(
 	publish(model.EventDeviceUpdated(event.Device))
 }
 
+// applyFlowVlans sets the vlan of source devices from the dot1q fields of the flows
+func (m *Mason) applyFlowVlans(ctx context.Context, flows []model.IpFlow) {
+	seen := make(map[string]bool)
+	for _, flow := range flows {
+		if flow.VlanID == 0 || seen[flow.SrcAddr.String()] {
+			continue
+		}
+		seen[flow.SrcAddr.String()] = true
+		dev, err := m.store.GetDeviceByAddr(ctx, flow.SrcAddr)
+		if err != nil || dev.VLAN.ID == flow.VlanID {
+			continue
+		}
+		dev.VLAN = model.VLAN{ID: flow.VlanID}
+		dev.SetUpdated()
+		m.publish(model.EventDeviceUpdated(dev))
+	}
+}
+
+func snmpVlansByMAC(
+	ctx context.Context,
+	event discovery.DiscoverDevicesFromSNMPDevice,
+	timeout time.Duration,
+	publish func(bus.Event),
+) map[string]model.VLAN {
+	vlans := make(map[string]model.VLAN)
+	entries, err := nettools.SnmpGetVlanTable(ctx, event.Addr.Addr(),
+		nettools.WithSnmpCommunity(event.SNMP.Community),
+		nettools.WithSnmpPort(event.SNMP.Port),
+		nettools.WithSnmpReplyTimeout(timeout),
+	)
+	if err != nil {
+		if !errors.Is(err, nettools.ErrConnectionRefused) &&
+			!errors.Is(err, nettools.ErrNoResponseFromRemote) {
+			publish(tre.New(err, "snmp get vlan table", "addr", event.Addr))
+		}
+		return vlans
+	}
+	for _, entry := range entries {
+		vlans[entry.MAC.String()] = model.VLAN{ID: entry.VlanID, Name: entry.VlanName}
+	}
+	return vlans
+}
+
 // AddNetwork is a helper function to introduce a new network into the system
 func (m *Mason) AddNetworkByName(
 	ctx context.Context,
@@ -510,6 +923,17 @@ func (m *Mason) AddNetworkByName(
 	return nil
 }
 
+// EstimateNetworkScan returns the expected cost of scanning the prefix, an error is returned
+// if the scan would exceed the configured limit for manual scans
+func (m *Mason) EstimateNetworkScan(name string, prefix string) (discovery.ScanEstimate, error) {
+	newnet, err := model.New(name, prefix)
+	if err != nil {
+		return discovery.ScanEstimate{}, err
+	}
+	est := discovery.EstimateScan(m.cfg.Discovery, m.cfg.RateLimit, newnet)
+	return est, discovery.CheckScanSize(m.cfg.Discovery, est)
+}
+
 func (m *Mason) bootstrapnetworks() {
 	ctx := context.Background()
 	ifaces, err := net.Interfaces()
@@ -551,6 +975,12 @@ func (m *Mason) ListNetworks(ctx context.Context) []model.Network {
 	return m.store.ListNetworks(ctx)
 }
 
+// NetworkScans returns the progress of the running network scans and the last finished
+// scan of each network
+func (m *Mason) NetworkScans(ctx context.Context) []discovery.NetworkScanProgress {
+	return m.networkScans.List()
+}
+
 func (m *Mason) CountNetworks(ctx context.Context) int {
 	return m.store.CountNetworks(ctx)
 }
@@ -565,6 +995,11 @@ func (m *Mason) ListDevices(ctx context.Context) []model.Device {
 	return m.store.ListDevices(ctx)
 }
 
+// QueryDevices returns one page of the devices matching the search, filter and sort
+func (m *Mason) QueryDevices(ctx context.Context, q model.DeviceQuery) model.DevicePage {
+	return m.store.QueryDevices(ctx, q)
+}
+
 func (m *Mason) CountDevices(ctx context.Context) int {
 	return m.store.CountDevices(ctx)
 }
@@ -580,7 +1015,7 @@ func (m *Mason) ReadPerformancePings(
 	device model.Device,
 	duration time.Duration,
 ) ([]pinger.Point, error) {
-	points, err := m.store.ReadPerformancePings(ctx, device, duration)
+	points, err := m.timeseries.ReadPerformancePings(ctx, device, duration)
 	m.recordIfError(err)
 	return points, err
 }
@@ -604,6 +1039,10 @@ func (m *Mason) IcmpPingAddr(
 	timeout time.Duration,
 	priviledged bool,
 ) (nettools.Icmp4EchoResponseStatistics, error) {
+	err := m.checkExcluded(ctx, addr, model.ExcludePing)
+	if err != nil {
+		return nettools.Icmp4EchoResponseStatistics{}, err
+	}
 	responses, err := nettools.Icmp4Echo(
 		ctx,
 		addr.Addr(),
@@ -638,6 +1077,40 @@ func (m *Mason) IcmpPing(
 	return stats, err
 }
 
+// TcpPing times tcp connects to the target given as host:port
+func (m *Mason) TcpPing(
+	ctx context.Context,
+	target string,
+	count int,
+	timeout time.Duration,
+) (stats nettools.Icmp4EchoResponseStatistics, err error) {
+	host, portstr, err := net.SplitHostPort(target)
+	if err != nil {
+		return stats, err
+	}
+	port, err := strconv.ParseUint(portstr, 10, 16)
+	if err != nil {
+		return stats, tre.New(err, "invalid port", "port", portstr)
+	}
+	addr, err := m.StringToAddr(host)
+	if err != nil {
+		return stats, err
+	}
+	err = m.checkExcluded(ctx, addr, model.ExcludePing)
+	if err != nil {
+		return stats, err
+	}
+	responses, err := nettools.TcpPing(
+		ctx,
+		netip.AddrPortFrom(addr.Addr(), uint16(port)),
+		nettools.I4EWithCount(count),
+		nettools.I4EWithReadTimeout(timeout),
+	)
+	m.recordIfError(err)
+	stats = nettools.CalculateIcmp4EchoResponseStatistics(responses)
+	return stats, err
+}
+
 func (m *Mason) ArpPing(
 	ctx context.Context,
 	target string,
@@ -647,6 +1120,10 @@ func (m *Mason) ArpPing(
 	if err != nil {
 		return model.MAC{}, err
 	}
+	err = m.checkExcluded(ctx, addr, model.ExcludePing)
+	if err != nil {
+		return model.MAC{}, err
+	}
 	entry, err := nettools.FindHardwareAddrOf(
 		ctx,
 		addr.Addr(),
@@ -665,16 +1142,41 @@ func (m *Mason) Portscan(
 	if err != nil {
 		return nil, err
 	}
+	err = m.checkExcluded(ctx, addr, model.ExcludePortScan)
+	if err != nil {
+		return nil, err
+	}
+	// the cli scans without a store, only a portlist of port ranges is resolved then
+	var pps []model.PortProfile
+	if m.store != nil {
+		pps, err = m.store.ListPortProfiles(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	list, err := portlistPorts(cfg.PortList, pps)
+	if err != nil {
+		return nil, err
+	}
+	portlist := nettools.WithPortscanPorts(list)
+	if len(list) == 0 {
+		portlist = nettools.WithPortscanPortlistName(cfg.PortList)
+	}
 	ports, err := nettools.ScanTcpPorts(ctx, addr.Addr(),
 		nettools.WithPortscanReplyTimeout(cfg.Timeout),
-		nettools.WithPortscanPortlistName(cfg.PortList),
+		portlist,
 		nettools.WithPortscanMaxworkers(cfg.MaxWorkers),
+		nettools.WithPortscanModeName(cfg.Mode),
+		nettools.WithPortscanRateLimiter(m.limits.Addr(addr.Addr())),
 	)
 	m.recordIfError(err)
 	return ports, err
 }
 
 func (m *Mason) GetExternalAddr(ctx context.Context) (model.Addr, error) {
+	if m.cfg.Offline.Enabled {
+		return model.Addr{}, ErrOffline
+	}
 	addr, err := nettools.GetExternalAddr(ctx)
 	m.recordIfError(err)
 	return model.AddrToModelAddr(addr), err
@@ -683,25 +1185,34 @@ func (m *Mason) GetExternalAddr(ctx context.Context) (model.Addr, error) {
 func (m *Mason) Traceroute(
 	ctx context.Context,
 	target string,
+	opts ...nettools.Icmp4EchoOption,
 ) (stat []nettools.Icmp4EchoResponseStatistics, err error) {
 	addr, err := m.StringToAddr(target)
 	if err != nil {
 		return stat, err
 	}
-	return m.TracerouteAddr(ctx, addr)
+	return m.TracerouteAddr(ctx, addr, opts...)
 }
 
 func (m *Mason) TracerouteAddr(
 	ctx context.Context,
 	target model.Addr,
+	opts ...nettools.Icmp4EchoOption,
 ) (stats []nettools.Icmp4EchoResponseStatistics, err error) {
 	if !m.cfg.Discovery.Icmp.Privileged {
 		return nil, errors.New("cannot execute traceroute in unpriviledged mode")
 	}
+	err = m.checkExcluded(ctx, target, model.ExcludePing)
+	if err != nil {
+		return nil, err
+	}
 	respOfResp, err := nettools.Traceroute4(
 		ctx,
 		target.Addr(),
-		nettools.I4EWithPrivileged(m.cfg.Discovery.Icmp.Privileged),
+		append(
+			[]nettools.Icmp4EchoOption{nettools.I4EWithPrivileged(m.cfg.Discovery.Icmp.Privileged)},
+			opts...,
+		)...,
 	)
 	if err != nil {
 		m.recordIfError(err)
@@ -732,6 +1243,7 @@ func (m *Mason) TracerouteAddr(
 			stats[idx].OrgName = asninfo.Name
 		}
 	}
+	m.recordRoute(ctx, target, stats)
 	return stats, err
 }
 
@@ -755,6 +1267,10 @@ func (m *Mason) FetchSNMPInfoAddr(
 	ctx context.Context,
 	target model.Addr,
 ) (info nettools.SnmpInfo, err error) {
+	err = m.checkExcluded(ctx, target, model.ExcludeScan)
+	if err != nil {
+		return info, err
+	}
 	return nettools.FetchSNMPInfo(ctx, target.Addr())
 }
 
@@ -765,6 +1281,15 @@ func (m *Mason) CheckDNS(
 	return nettools.DNSCheckAllServers(ctx, target)
 }
 
+// CompareDNSResolvers compares the plain answers of public resolvers for target against their
+// DNS-over-TLS and DNS-over-HTTPS answers
+func (m *Mason) CompareDNSResolvers(
+	ctx context.Context,
+	target string,
+) ([]nettools.DNSResolverResult, error) {
+	return nettools.DNSCompareResolvers(ctx, target)
+}
+
 func (m *Mason) GetUserAgent() string {
 	return nettools.GetUserAgent()
 }
@@ -784,17 +1309,23 @@ type MasonInternalsView struct {
 	EnrichmentBackPressure int
 	PortScanMaxWorkers     int
 	PingerMaxWorkers       int
+	NetworkScanMaxWorkers  int
 
 	AddressScanActive  int
 	DeviceEnrichActive int
 	PerfPingActive     int
 	NetworkScanActive  int
 
-	BusBackPressure int
+	Bus         bus.Stats
+	FlowInserts *netflows.InsertStats
+
+	RateLimits []ratelimit.Stats
 
-	CurrentNetworkScan string
-	Events             []bus.HistoricalEvent
-	Errors             []bus.HistoricalError
+	NetworkScans []discovery.NetworkScanProgress
+	Agents       []AgentStatus
+	Asn          asn.Status
+	Events       []bus.HistoricalEvent
+	Errors       []bus.HistoricalError
 
 	Memstats  runtime.MemStats
 	Buildinfo debug.BuildInfo
@@ -813,14 +1344,22 @@ func (m *Mason) GetInternalsSnapshot(ctx context.Context) MasonInternalsView {
 	iv.EnrichmentMaxWorkers = m.cfg.Enrichment.MaxWorkers
 	iv.EnrichmentBackPressure = int(m.enrichBackPressure.Load())
 	iv.PortScanMaxWorkers = m.cfg.Enrichment.PortScan.MaxWorkers
-	iv.CurrentNetworkScan = *m.currentNetworkScan
+	iv.NetworkScanMaxWorkers = m.cfg.Discovery.MaxNetworkScanners
+	iv.NetworkScans = m.networkScans.List()
 
 	iv.AddressScanActive = m.discoveryWorker.Active()
 	iv.DeviceEnrichActive = m.enrichmentWorker.Active()
 	iv.PerfPingActive = m.pingerWorker.Active()
 	iv.NetworkScanActive = m.networkScannerWorker.Active()
 
-	iv.BusBackPressure = int(m.busBackPressure.Load())
+	iv.Bus = m.bus.Stats()
+	if m.flowInserter != nil {
+		stats := m.flowInserter.Stats()
+		iv.FlowInserts = &stats
+	}
+	iv.RateLimits = m.limits.Stats()
+	iv.Agents = m.Agents()
+	iv.Asn = m.AsnStatus()
 
 	iv.Events = m.bus.History()
 	slices.Reverse(iv.Events)
@@ -840,12 +1379,24 @@ func (m *Mason) GetNetworkStats(ctx context.Context) []model.NetworkStats {
 	return buildNetworkStats(m.store.ListNetworks(ctx), m.store.ListDevices(ctx))
 }
 
+// PingFailures returns the devices which failed their last ping, devices in an active
+// maintenance window are not included.  Devices behind a down parent are left out, the
+// parent is the failure.
 func (m *Mason) PingFailures(ctx context.Context) []model.Device {
 	pf := make([]model.Device, 0)
+	inMaintenance := m.maintenanceLookup(ctx, time.Now())
+	upstreamDown := m.upstreamLookup(ctx, nil)
 	for _, d := range m.ListDevices(ctx) {
-		if d.PerformancePing.LastFailed {
-			pf = append(pf, d)
+		if _, ok := inMaintenance(d); ok {
+			continue
+		}
+		if !d.PerformancePing.LastFailed {
+			continue
+		}
+		if _, ok := upstreamDown(d); ok {
+			continue
 		}
+		pf = append(pf, d)
 	}
 	return pf
 }
@@ -894,10 +1445,14 @@ func buildNetworkStats(
 			ns.IPTotal = math.Pow(float64(2), float64(128-nw.Prefix.Bits()))
 		}
 		var totalavg, totalmax time.Duration
+		vlans := make(map[model.VLAN]int)
 		for _, dv := range devices {
 			if !nw.Contains(dv) {
 				continue
 			}
+			if !dv.VLAN.IsEmpty() {
+				vlans[dv.VLAN]++
+			}
 			if dv.PerformancePing.LastFailed {
 				continue
 			}
@@ -909,6 +1464,15 @@ func buildNetworkStats(
 			ns.AvgPing = totalavg / time.Duration(ns.IPUsed)
 			ns.MaxPing = totalmax / time.Duration(ns.IPUsed)
 		}
+		// use the most common vlan of the member devices when one has not been assigned
+		if ns.VLAN.IsEmpty() {
+			for vlan, count := range vlans {
+				best := vlans[ns.VLAN]
+				if count > best || (count == best && vlan.ID < ns.VLAN.ID) {
+					ns.VLAN = vlan
+				}
+			}
+		}
 		nss = append(nss, ns)
 	}
 	return nss
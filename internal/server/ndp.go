@@ -0,0 +1,107 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+// ndpSeenResolution is how often a neighbor seen again has its last seen written to the
+// store, neighbor solicitations can come several times a minute
+const ndpSeenResolution = time.Minute
+
+// listenNDP records the neighbors of the IPv6 neighbor discovery messages as bound to their
+// MAC, the prefixes routers advertise for autoconfiguration are added as networks
+func (m *Mason) listenNDP(ctx context.Context) {
+	cfg := m.cfg.Discovery.Ndp
+	err := nettools.ListenNDP(ctx, cfg.Interval, func(nb nettools.NDPNeighbor) {
+		m.recordNeighbor(ctx, nb.Addr, nb.MAC)
+		for _, prefix := range nb.Prefixes {
+			m.discoverIPv6Network(ctx, prefix)
+		}
+	})
+	if err != nil {
+		m.publish(tre.New(err, "listen for ipv6 neighbor discovery"))
+	}
+}
+
+// ingestHostNdpTable records the IPv6 neighbors of the host, the table holds the global
+// addresses of the neighbors the host talked to which the discovery messages rarely carry
+func (m *Mason) ingestHostNdpTable(ctx context.Context) {
+	if !m.cfg.Discovery.Enabled || !m.cfg.Discovery.Ndp.Enabled {
+		return
+	}
+	entries, err := nettools.ReadHostNdpTable(ctx)
+	if err != nil {
+		m.publish(tre.New(err, "read host ipv6 neighbor table"))
+		return
+	}
+	for _, entry := range entries {
+		m.recordNeighbor(ctx, entry.Addr, entry.MAC)
+	}
+}
+
+// recordNeighbor binds the IPv6 address to the MAC alongside the IPv4 bindings of arp watch
+// and adds it to the addresses of the device with the MAC
+func (m *Mason) recordNeighbor(ctx context.Context, addr netip.Addr, mac []byte) {
+	now := time.Now()
+	seen := model.MACBinding{
+		Addr:      model.AddrToModelAddr(addr),
+		MAC:       model.HardwareAddrToMAC(mac),
+		FirstSeen: now,
+		LastSeen:  now,
+		Source:    discovery.NDPDiscoverySource,
+	}
+	if !m.bindNeighbor(ctx, seen) {
+		return
+	}
+	d, err := m.store.GetDeviceByMAC(ctx, seen.MAC)
+	if err != nil || d.Addr == seen.Addr {
+		return
+	}
+	d.Addresses = d.Addresses.Seen(seen.Addr, now)
+	m.publish(model.EventDeviceUpdated(d))
+}
+
+// bindNeighbor stores the binding, a neighbor seen again within the resolution is skipped
+func (m *Mason) bindNeighbor(ctx context.Context, seen model.MACBinding) bool {
+	m.macBindingsMu.Lock()
+	defer m.macBindingsMu.Unlock()
+	m.loadMACBindings(ctx)
+
+	known := m.macBindings[seen.Addr]
+	idx := slices.IndexFunc(known, func(b model.MACBinding) bool {
+		return b.MAC.String() == seen.MAC.String()
+	})
+	if idx < 0 {
+		m.macBindings[seen.Addr] = append(known, seen)
+	} else {
+		if seen.LastSeen.Sub(known[idx].LastSeen) < ndpSeenResolution {
+			return false
+		}
+		known[idx].LastSeen = seen.LastSeen
+	}
+	m.recordIfError(m.store.UpsertMACBinding(ctx, seen))
+	return true
+}
+
+// discoverIPv6Network adds an advertised prefix unless it is a network already
+func (m *Mason) discoverIPv6Network(ctx context.Context, prefix netip.Prefix) {
+	known := slices.ContainsFunc(m.store.ListNetworks(ctx), func(n model.Network) bool {
+		return n.Prefix.P == prefix
+	})
+	if !known {
+		m.publish(model.DiscoveredNetwork(model.NewNetworkFromPrefix(prefix)))
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/netbox"
+)
+
+// NetboxSyncResult counts what a sync with netbox changed
+type NetboxSyncResult struct {
+	NetworksAdded   int
+	NetworksUpdated int
+	DevicesAdded    int
+	DevicesUpdated  int
+	DevicesPushed   int
+}
+
+// SyncNetbox imports the prefixes and ip addresses of netbox as networks and devices, netbox
+// being the source of truth for their names, owners and sites, then pushes the devices netbox
+// does not know back for review.  The store is written directly so the sync also works from
+// the command line without the bus running.
+func (m *Mason) SyncNetbox(ctx context.Context) (NetboxSyncResult, error) {
+	var result NetboxSyncResult
+	cfg := m.cfg.Netbox
+	c, err := netbox.New(cfg)
+	if err != nil {
+		return result, err
+	}
+	prefixes, err := c.Prefixes(ctx)
+	if err != nil {
+		return result, tre.New(err, "list netbox prefixes", "url", c.URL())
+	}
+	if cfg.Import {
+		ips, err := c.IPAddresses(ctx)
+		if err != nil {
+			return result, tre.New(err, "list netbox ip addresses", "url", c.URL())
+		}
+		err = m.importNetboxNetworks(ctx, prefixes, &result)
+		if err != nil {
+			return result, err
+		}
+		err = m.importNetboxDevices(ctx, netbox.Devices(prefixes, ips, cfg.Fields), &result)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	switch cfg.Push {
+	case netbox.PushNone, "":
+		return result, nil
+	case netbox.PushJournal, netbox.PushStaged:
+	default:
+		return result, tre.New(netbox.ErrUnknownPushMode, "push netbox", "push", cfg.Push)
+	}
+	// staged addresses are only in the branch, which is read so they are not staged again
+	ips, err := c.BranchIPAddresses(ctx)
+	if err != nil {
+		return result, tre.New(err, "list netbox ip addresses", "url", c.URL())
+	}
+	missing := slices.DeleteFunc(
+		netbox.Missing(m.store.ListDevices(ctx), ips),
+		func(d model.Device) bool { return d.Meta.Approval == model.ApprovalBlocked },
+	)
+	if cfg.Push == netbox.PushStaged {
+		for _, d := range missing {
+			err := c.AddIPAddress(ctx, netbox.Staged(d, prefixes, cfg.Status, cfg.Fields))
+			if err != nil {
+				return result, tre.New(err, "stage netbox ip address", "addr", d.Addr)
+			}
+			result.DevicesPushed++
+		}
+		return result, nil
+	}
+	entries, err := c.JournalEntries(ctx, netbox.JournalQuery)
+	if err != nil {
+		return result, tre.New(err, "list netbox journal entries", "url", c.URL())
+	}
+	journaled := netbox.Journaled(entries)
+	for _, d := range missing {
+		if journaled[d.Addr.Addr()] {
+			continue
+		}
+		entry, ok := netbox.Journal(d, prefixes)
+		if !ok {
+			continue
+		}
+		err := c.AddJournalEntry(ctx, entry)
+		if err != nil {
+			return result, tre.New(err, "add netbox journal entry", "addr", d.Addr)
+		}
+		result.DevicesPushed++
+	}
+	return result, nil
+}
+
+func (m *Mason) importNetboxNetworks(
+	ctx context.Context,
+	prefixes []netbox.Prefix,
+	result *NetboxSyncResult,
+) error {
+	for _, n := range netbox.Networks(prefixes) {
+		err := m.store.AddNetwork(ctx, n)
+		if err == nil {
+			m.limits.Network(n.Prefix.P)
+			m.publish(model.NetworkAddedEvent(n))
+			result.NetworksAdded++
+			continue
+		}
+		if !errors.Is(err, model.ErrNetworkExists) {
+			return tre.New(err, "add netbox network", "network", n.Name)
+		}
+		prev, err := m.store.GetNetworkByName(ctx, n.Name)
+		if err != nil {
+			// the prefix is known under another name, leave it be
+			continue
+		}
+		next := prev
+		next.Tags = unionTags(prev.Tags, n.Tags)
+		if n.Site != "" {
+			next.Site = n.Site
+		}
+		if !n.VLAN.IsEmpty() {
+			next.VLAN = n.VLAN
+		}
+		if len(next.Tags) == len(prev.Tags) && next.Site == prev.Site && next.VLAN == prev.VLAN {
+			continue
+		}
+		err = m.store.UpdateNetwork(ctx, next)
+		if err != nil {
+			return tre.New(err, "update netbox network", "network", n.Name)
+		}
+		result.NetworksUpdated++
+	}
+	return nil
+}
+
+// importNetboxDevices adds the devices as approved, documented devices.  The name, owner, notes
+// and site of a known device are replaced by those of netbox.
+func (m *Mason) importNetboxDevices(
+	ctx context.Context,
+	devices []model.Device,
+	result *NetboxSyncResult,
+) error {
+	now := time.Now()
+	for _, d := range devices {
+		prev, err := m.store.GetDeviceByAddr(ctx, d.Addr)
+		if err != nil {
+			d.DiscoveredAt = now
+			d.Meta.Approval = model.ApprovalApproved
+			err := m.store.AddDevice(ctx, d)
+			if err != nil {
+				return tre.New(err, "add netbox device", "addr", d.Addr)
+			}
+			m.publish(model.EventDeviceAdded(d))
+			result.DevicesAdded++
+			continue
+		}
+
+		details := prev.Details()
+		if !d.IsNameAddr() {
+			details.Name = d.Name
+		}
+		if d.Meta.Owner != "" {
+			details.Owner = d.Meta.Owner
+		}
+		if d.Meta.Notes != "" {
+			details.Notes = d.Meta.Notes
+		}
+		if d.Meta.Site != "" {
+			details.Site = d.Meta.Site
+		}
+		if details != prev.Details() {
+			err := m.store.SetDeviceDetails(ctx, d.Addr, details)
+			if err != nil {
+				return tre.New(err, "set netbox device details", "addr", d.Addr)
+			}
+			next := prev.WithDetails(details)
+			m.recordChanges(ctx, model.DeviceChanges(prev, next, model.ChangeSourceImport, now))
+			prev = next
+		}
+
+		// an update replaces the name, keep the one just set
+		d.Name = prev.Name
+		// the tags replace those of the device, keep the ones set by users
+		d.Meta.Tags = unionTags(prev.Meta.Tags, d.Meta.Tags)
+		if prev.Meta.Approval == model.ApprovalUnknown || prev.Meta.Approval == "" {
+			d.Meta.Approval = model.ApprovalApproved
+		}
+		_, err = m.updateDevice(ctx, d, model.ChangeSourceImport)
+		if err != nil {
+			return tre.New(err, "update netbox device", "addr", d.Addr)
+		}
+		result.DevicesUpdated++
+	}
+	return nil
+}
+
+// syncNetbox runs the scheduled sync, a run is skipped while the previous one is still going
+func (m *Mason) syncNetbox(ctx context.Context) {
+	if !m.cfg.Netbox.Enabled || !m.netboxRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.netboxRunning.Store(false)
+
+	_, err := m.SyncNetbox(ctx)
+	if err != nil {
+		m.publish(err)
+	}
+}
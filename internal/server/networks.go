@@ -0,0 +1,166 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	// networkUsageWindow is the span of the utilization history of a network
+	networkUsageWindow = 30 * 24 * time.Hour
+	networkUsageStep   = 24 * time.Hour
+)
+
+// NetworkDetail is a network with its devices, address use and latest scan.  Scan is zero
+// when the network has not been scanned since startup.
+type NetworkDetail struct {
+	Network model.Network
+	Devices []model.Device
+	Plan    model.AddressPlan
+	Usage   []model.NetworkUsage
+	Scan    discovery.NetworkScanProgress
+}
+
+// CreateNetwork stores the network and returns it, unlike AddNetworkByName an existing
+// prefix is reported as ErrNetworkExists.  With scan the network is scanned right away,
+// otherwise it waits for the next rescan check.
+func (m *Mason) CreateNetwork(
+	ctx context.Context,
+	name string,
+	prefix string,
+	scan bool,
+) (model.Network, error) {
+	n, err := model.New(name, prefix)
+	if err != nil {
+		return n, err
+	}
+	if scan {
+		est := discovery.EstimateScan(m.cfg.Discovery, m.cfg.RateLimit, n)
+		err = discovery.CheckScanSize(m.cfg.Discovery, est)
+		if err != nil {
+			return n, err
+		}
+	}
+	err = m.store.AddNetwork(ctx, n)
+	if err != nil {
+		return n, err
+	}
+	m.publish(model.NetworkAddedEvent(n))
+	if scan {
+		n.LastScan = time.Now()
+		m.publish(model.ScanNetworkRequest(n))
+	}
+	return n, nil
+}
+
+// ScanNetwork requests a scan of the known network with the name or prefix, the LastScan
+// of the returned network is the time of the request.  The progress of the scan is listed
+// by NetworkScans.
+func (m *Mason) ScanNetwork(ctx context.Context, network string) (model.Network, error) {
+	n, err := m.findNetwork(ctx, network)
+	if err != nil {
+		return n, err
+	}
+	est := discovery.EstimateScan(m.cfg.Discovery, m.cfg.RateLimit, n)
+	err = discovery.CheckScanSize(m.cfg.Discovery, est)
+	if err != nil {
+		return n, err
+	}
+	n.LastScan = time.Now()
+	m.publish(model.ScanNetworkRequest(n))
+	return n, nil
+}
+
+// findNetwork returns the network by its name, or else by its prefix
+func (m *Mason) findNetwork(ctx context.Context, network string) (model.Network, error) {
+	n, err := m.store.GetNetworkByName(ctx, network)
+	if err == nil {
+		return n, nil
+	}
+	if prefix, perr := netip.ParsePrefix(network); perr == nil {
+		prefix = prefix.Masked()
+		for _, n := range m.store.ListNetworks(ctx) {
+			if n.Prefix.P == prefix {
+				return n, nil
+			}
+		}
+	}
+	return model.Network{}, tre.New(model.ErrNetworkDoesNotExist, "find network", "network", network)
+}
+
+// NetworkDetail returns the network with the name or prefix along with its devices, sorted by
+// address, its address plan, the daily count of its devices over the last month and its
+// latest scan
+func (m *Mason) NetworkDetail(ctx context.Context, network string) (NetworkDetail, error) {
+	n, err := m.findNetwork(ctx, network)
+	if err != nil {
+		return NetworkDetail{}, err
+	}
+	all := m.store.ListDevices(ctx)
+	devices := make([]model.Device, 0)
+	for _, d := range all {
+		if n.Contains(d) {
+			devices = append(devices, d)
+		}
+	}
+	slices.SortFunc(devices, func(a, b model.Device) int { return a.Addr.Compare(b.Addr) })
+	reservations, err := m.store.ListReservations(ctx)
+	if err != nil {
+		return NetworkDetail{}, err
+	}
+	deleted, err := m.store.ListTombstones(ctx)
+	if err != nil {
+		return NetworkDetail{}, err
+	}
+	end := time.Now().Truncate(networkUsageStep)
+	detail := NetworkDetail{
+		Network: n,
+		Devices: devices,
+		Plan:    model.BuildAddressPlan(n, devices, reservations, 0),
+		Usage: model.NetworkUsageHistory(
+			n,
+			devices,
+			deleted,
+			end.Add(-networkUsageWindow),
+			end,
+			networkUsageStep,
+		),
+	}
+	for _, p := range m.networkScans.List() {
+		if p.Prefix == n.Prefix.String() {
+			detail.Scan = p
+		}
+	}
+	return detail, nil
+}
+
+// RenameNetwork gives the network with the name or prefix a new name, the name of another
+// network is refused
+func (m *Mason) RenameNetwork(ctx context.Context, network string, name string) error {
+	n, err := m.findNetwork(ctx, network)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = n.Prefix.String()
+	}
+	if name == n.Name {
+		return nil
+	}
+	if _, err := m.store.GetNetworkByName(ctx, name); err == nil {
+		return tre.New(model.ErrNetworkExists, "rename network", "name", name)
+	}
+	n.Name = name
+	return m.store.UpdateNetwork(ctx, n)
+}
@@ -0,0 +1,256 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/networkables/mason/internal/bus"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/notify"
+)
+
+const notifyBuffer = 256
+
+// newMailer is the email channel of the notifications, nil when disabled
+func (m *Mason) newMailer() *notify.Email {
+	if m.cfg.Notify == nil || m.cfg.Notify.Email == nil || !m.cfg.Notify.Email.Enabled {
+		return nil
+	}
+	_, err := notify.NextDigest(time.Now(), m.cfg.Notify.DigestTime)
+	if err != nil {
+		log.Fatal("notify digest time", "error", err)
+	}
+	mailer, err := notify.NewEmail(*m.cfg.Notify.Email)
+	if err != nil {
+		log.Fatal("notify email", "error", err)
+	}
+	return mailer
+}
+
+// newNotifiers are the enabled chats and incident services of the notifications
+func (m *Mason) newNotifiers() []notify.Notifier {
+	cfg := m.cfg.Notify
+	if cfg == nil {
+		return nil
+	}
+	notifiers := make([]notify.Notifier, 0)
+	addChat := func(c *notify.Chat, err error) {
+		if err != nil {
+			log.Fatal("notify chat", "error", err)
+		}
+		notifiers = append(notifiers, c)
+	}
+	if cfg.Slack != nil && cfg.Slack.Enabled {
+		addChat(notify.NewSlack(*cfg.Slack, cfg.ChatTimeout))
+	}
+	if cfg.Discord != nil && cfg.Discord.Enabled {
+		addChat(notify.NewDiscord(*cfg.Discord, cfg.ChatTimeout))
+	}
+	if cfg.Telegram != nil && cfg.Telegram.Enabled {
+		addChat(notify.NewTelegram(*cfg.Telegram, cfg.ChatTimeout))
+	}
+	addIncidents := func(in *notify.Incidents, err error) {
+		if err != nil {
+			log.Fatal("notify incidents", "error", err)
+		}
+		notifiers = append(notifiers, in)
+	}
+	if cfg.PagerDuty != nil && cfg.PagerDuty.Enabled {
+		addIncidents(notify.NewPagerDuty(*cfg.PagerDuty, cfg.ChatTimeout))
+	}
+	if cfg.Opsgenie != nil && cfg.Opsgenie.Enabled {
+		addIncidents(notify.NewOpsgenie(*cfg.Opsgenie, cfg.ChatTimeout))
+	}
+	return notifiers
+}
+
+// notifying is true when any notification channel is enabled
+func (m *Mason) notifying() bool {
+	return m.mailer != nil || len(m.notifiers) > 0
+}
+
+// runNotifications sends the alerts published on the bus to the chats, incident services and
+// by email as they happen, and gathers them into the email digest sent at the digest time each day.
+// Alerts about a device in an active maintenance window are dropped, see prepareAlert.
+func (m *Mason) runNotifications(ctx context.Context) {
+	sub, unsubscribe := m.bus.Subscribe(notifyBuffer)
+	defer unsubscribe()
+	collector := notify.NewCollector(time.Now())
+	// without email there is no digest, the timer is left stopped
+	digest := time.NewTimer(time.Hour)
+	digest.Stop()
+	if m.mailer != nil {
+		next, _ := notify.NextDigest(time.Now(), m.cfg.Notify.DigestTime)
+		digest.Reset(time.Until(next))
+	}
+	defer digest.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-sub:
+			if !ok {
+				return
+			}
+			a, ok := toAlert(e, time.Now())
+			if !ok {
+				continue
+			}
+			a, ok = m.prepareAlert(ctx, a)
+			if !ok {
+				continue
+			}
+			// a slow channel holds up the alerts behind it, not the bus
+			for _, n := range m.notifiers {
+				m.recordIfError(n.Alert(ctx, a))
+			}
+			if m.mailer != nil {
+				collector.Add(a)
+				m.recordIfError(m.mailer.Alert(ctx, a))
+			}
+		case now := <-digest.C:
+			d := collector.Take(now)
+			d.Certificates = m.expiringCertificates(ctx)
+			m.recordIfError(m.mailer.Digest(ctx, d))
+			next, _ := notify.NextDigest(time.Now(), m.cfg.Notify.DigestTime)
+			digest.Reset(time.Until(next))
+		}
+	}
+}
+
+// prepareAlert adds the tags of the device the alert is about, false when the alert is dropped
+// for the device being in an active maintenance window.  Recoveries are still sent, they
+// clear a failure raised before the window started.
+func (m *Mason) prepareAlert(ctx context.Context, a notify.Alert) (notify.Alert, bool) {
+	d, known := m.alertDevice(ctx, a.Addr)
+	if known && a.Kind != notify.AlertRecovery && m.inMaintenance(ctx, d, a.Time) {
+		return a, false
+	}
+	a.Tags = alertTags(d)
+	return a, true
+}
+
+// alertDevice is the device an alert is about, false when the address is not a known device
+func (m *Mason) alertDevice(ctx context.Context, addr string) (model.Device, bool) {
+	if addr == "" {
+		return model.Device{}, false
+	}
+	a, err := model.ParseAddr(addr)
+	if err != nil {
+		return model.Device{}, false
+	}
+	d, err := m.store.GetDeviceByAddr(ctx, a)
+	if err != nil {
+		return model.Device{}, false
+	}
+	return d, true
+}
+
+// inMaintenance is true when the device is in an active maintenance window, whatever the
+// source of an alert about it
+func (m *Mason) inMaintenance(ctx context.Context, d model.Device, t time.Time) bool {
+	_, ok := m.maintenanceLookup(ctx, t)(d)
+	return ok
+}
+
+// alertTags are the tags of the device an alert is about
+func alertTags(d model.Device) []string {
+	if len(d.Meta.Tags) == 0 {
+		return nil
+	}
+	tags := make([]string, 0, len(d.Meta.Tags))
+	for _, t := range d.Meta.Tags {
+		tags = append(tags, t.Val)
+	}
+	return tags
+}
+
+// expiringCertificates are the certificates of the https checks expiring within the warning
+// days, along with the ones which could not be read
+func (m *Mason) expiringCertificates(ctx context.Context) []notify.CertExpiry {
+	checks, err := m.ListHTTPChecks(ctx)
+	if err != nil {
+		m.recordIfError(err)
+		return nil
+	}
+	certs := make([]notify.CertExpiry, 0)
+	for _, hc := range checks {
+		if !strings.HasPrefix(strings.ToLower(hc.URL), "https://") {
+			continue
+		}
+		ce := notify.CertExpiry{Name: hc.Name, URL: hc.URL}
+		info, err := m.FetchTLSInfo(ctx, hc.URL)
+		if err != nil {
+			ce.Err = err.Error()
+			certs = append(certs, ce)
+			continue
+		}
+		if info.TLSData == nil || len(info.TLSData.PeerCertificates) == 0 {
+			continue
+		}
+		ce.Expires = info.TLSData.PeerCertificates[0].NotAfter
+		ce.DaysLeft = info.DaysTilExpire
+		if ce.DaysLeft <= m.cfg.Notify.CertWarnDays {
+			certs = append(certs, ce)
+		}
+	}
+	return certs
+}
+
+// toAlert is the alert of the bus events worth telling someone about
+func toAlert(e bus.Event, now time.Time) (notify.Alert, bool) {
+	a := notify.Alert{Time: now, Type: liveEventType(e)}
+	switch e := e.(type) {
+	case model.EventDeviceAdded:
+		a.Kind, a.Addr = notify.AlertNewDevice, e.Addr.String()
+		a.Message = fmt.Sprintf("%s [%s %s]", e.Name, e.Addr, e.MAC)
+	case model.EventHTTPCheckChanged:
+		a.Kind, a.Message = checkAlertKind(e.Result.Failed()), e.String()
+		a.Key = "httpcheck/" + e.Check.Name
+	case model.EventServiceCheckChanged:
+		a.Kind, a.Addr, a.Message = checkAlertKind(e.Result.Failed()), e.Check.Addr.String(), e.String()
+		a.Key = "servicecheck/" + e.Check.AddrPort().String()
+	case model.EventDevicePingChanged:
+		a.Kind, a.Addr, a.Message = checkAlertKind(e.Down()), e.Device.Addr.String(), e.String()
+		a.Key = "ping/" + e.Device.Addr.String()
+	case model.EventUpstreamFailure:
+		a.Kind, a.Addr, a.Message = notify.AlertFailure, e.Parent.Addr.String(), e.String()
+		a.Key = "upstream/" + e.Parent.Addr.String()
+	case model.EventUpstreamRecovered:
+		a.Kind, a.Addr, a.Message = notify.AlertRecovery, e.Parent.Addr.String(), e.String()
+		a.Key = "upstream/" + e.Parent.Addr.String()
+	case model.EventDeviceAddrChanged:
+		a.Kind, a.Addr, a.Message = notify.AlertWarning, e.Device.Addr.String(), e.String()
+	case model.EventMACConflict:
+		a.Kind, a.Addr, a.Message = notify.AlertWarning, e.Addr.String(), e.String()
+	case model.EventRogueDHCP:
+		a.Kind, a.Addr, a.Message = notify.AlertWarning, e.Addr.String(), e.String()
+	case model.EventFlowAnomaly:
+		a.Kind, a.Addr, a.Message = notify.AlertWarning, e.Addr.String(), e.String()
+	case model.EventQuotaAlert:
+		a.Kind, a.Addr, a.Message = notify.AlertWarning, e.Usage.Addr.String(), e.String()
+	case model.EventHookAlert:
+		a.Kind, a.Addr, a.Message = notify.AlertWarning, e.Addr.String(), e.String()
+	case model.EventSyslogAlert:
+		a.Kind, a.Addr, a.Message = notify.AlertFailure, e.Addr.String(), e.String()
+	default:
+		return a, false
+	}
+	return a, true
+}
+
+func checkAlertKind(failed bool) notify.AlertKind {
+	if failed {
+		return notify.AlertFailure
+	}
+	return notify.AlertRecovery
+}
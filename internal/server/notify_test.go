@@ -0,0 +1,345 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/networkables/mason/internal/bus"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/notify"
+)
+
+func pingedDevice(failed bool) model.Device {
+	d := model.Device{Name: "nas", Addr: model.MustParseAddr("192.168.1.20")}
+	d.PerformancePing.LastFailed = failed
+	return d
+}
+
+func TestToAlert(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		event bus.Event
+		want  notify.Alert
+		ok    bool
+	}{
+		"DeviceDown": {
+			event: model.EventDevicePingChanged{Device: pingedDevice(true)},
+			want: notify.Alert{
+				Time:    now,
+				Kind:    notify.AlertFailure,
+				Type:    "EventDevicePingChanged",
+				Addr:    "192.168.1.20",
+				Key:     "ping/192.168.1.20",
+				Message: "nas [192.168.1.20] down, no reply to ping",
+			},
+			ok: true,
+		},
+		"DeviceUp": {
+			event: model.EventDevicePingChanged{Device: pingedDevice(false)},
+			want: notify.Alert{
+				Time:    now,
+				Kind:    notify.AlertRecovery,
+				Type:    "EventDevicePingChanged",
+				Addr:    "192.168.1.20",
+				Key:     "ping/192.168.1.20",
+				Message: "nas [192.168.1.20] up",
+			},
+			ok: true,
+		},
+		"Upstream": {
+			event: model.EventUpstreamFailure{
+				Parent:     pingedDevice(true),
+				Dependents: []model.Addr{model.MustParseAddr("192.168.1.21")},
+			},
+			want: notify.Alert{
+				Time:    now,
+				Kind:    notify.AlertFailure,
+				Type:    "EventUpstreamFailure",
+				Addr:    "192.168.1.20",
+				Key:     "upstream/192.168.1.20",
+				Message: "upstream nas [192.168.1.20] down, 1 dependent failures: 192.168.1.21",
+			},
+			ok: true,
+		},
+		"UpstreamRecovered": {
+			event: model.EventUpstreamRecovered{
+				Parent:     pingedDevice(false),
+				Dependents: []model.Addr{model.MustParseAddr("192.168.1.21")},
+			},
+			want: notify.Alert{
+				Time:    now,
+				Kind:    notify.AlertRecovery,
+				Type:    "EventUpstreamRecovered",
+				Addr:    "192.168.1.20",
+				Key:     "upstream/192.168.1.20",
+				Message: "upstream nas [192.168.1.20] up, 1 dependents",
+			},
+			ok: true,
+		},
+		"NotAlerted": {
+			event: model.EventDeviceUpdated(pingedDevice(false)),
+			want:  notify.Alert{Time: now, Type: "EventDeviceUpdated"},
+			ok:    false,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := toAlert(tc.event, now)
+			if ok != tc.ok {
+				t.Fatalf("ok want: %v, got: %v", tc.ok, ok)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestToAlert_DeviceDownInDigest(t *testing.T) {
+	now := time.Now()
+	c := notify.NewCollector(now)
+	for _, failed := range []bool{true, false} {
+		a, ok := toAlert(model.EventDevicePingChanged{Device: pingedDevice(failed)}, now)
+		if !ok {
+			t.Fatal("ping changed event not alerted")
+		}
+		c.Add(a)
+	}
+	d := c.Take(now)
+	if len(d.Failures) != 1 || d.Failures[0].Kind != notify.AlertFailure {
+		t.Errorf("want the down device in the digest failures, got %+v", d.Failures)
+	}
+}
+
+func TestDeviceDown_Chats(t *testing.T) {
+	text := "[mason critical] nas [192.168.1.20] down, no reply to ping (192.168.1.20)"
+	tests := map[string]struct {
+		chat func(cfg notify.ChatConfig) (*notify.Chat, error)
+		want map[string]string
+	}{
+		"Slack": {
+			chat: func(cfg notify.ChatConfig) (*notify.Chat, error) {
+				return notify.NewSlack(cfg, time.Second)
+			},
+			want: map[string]string{"text": text},
+		},
+		"Discord": {
+			chat: func(cfg notify.ChatConfig) (*notify.Chat, error) {
+				return notify.NewDiscord(cfg, time.Second)
+			},
+			want: map[string]string{"content": text},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := make([]map[string]string, 0)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]string
+				json.NewDecoder(r.Body).Decode(&body)
+				got = append(got, body)
+			}))
+			defer srv.Close()
+			// the default route of a chat, warnings and above
+			c, err := tc.chat(notify.ChatConfig{WebhookURL: srv.URL, MinSeverity: "warning"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, failed := range []bool{true, false} {
+				a, _ := toAlert(model.EventDevicePingChanged{Device: pingedDevice(failed)}, time.Now())
+				if err = c.Alert(context.Background(), a); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if diff := cmp.Diff([]map[string]string{tc.want}, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDeviceDown_Incidents(t *testing.T) {
+	tests := map[string]struct {
+		incidents func(cfg notify.IncidentConfig) (*notify.Incidents, error)
+		want      []string
+	}{
+		"PagerDuty": {
+			incidents: func(cfg notify.IncidentConfig) (*notify.Incidents, error) {
+				cfg.URL += "/v2/enqueue"
+				return notify.NewPagerDuty(cfg, time.Second)
+			},
+			want: []string{
+				"/v2/enqueue trigger mason/ping/192.168.1.20",
+				"/v2/enqueue resolve mason/ping/192.168.1.20",
+			},
+		},
+		"Opsgenie": {
+			incidents: func(cfg notify.IncidentConfig) (*notify.Incidents, error) {
+				return notify.NewOpsgenie(cfg, time.Second)
+			},
+			want: []string{
+				"/v2/alerts  mason/ping/192.168.1.20",
+				"/v2/alerts/mason%2Fping%2F192.168.1.20/close?identifierType=alias  ",
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			url, got := fakeIncidents(t)
+			// the default route of an incident service, failures only
+			in, err := tc.incidents(notify.IncidentConfig{
+				Key:         "key",
+				URL:         url,
+				MinSeverity: "critical",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, failed := range []bool{true, false} {
+				a, _ := toAlert(model.EventDevicePingChanged{Device: pingedDevice(failed)}, time.Now())
+				if err = in.Alert(context.Background(), a); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if diff := cmp.Diff(tc.want, *got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUpstream_Incidents(t *testing.T) {
+	tests := map[string]struct {
+		incidents func(cfg notify.IncidentConfig) (*notify.Incidents, error)
+		want      []string
+	}{
+		"PagerDuty": {
+			incidents: func(cfg notify.IncidentConfig) (*notify.Incidents, error) {
+				cfg.URL += "/v2/enqueue"
+				return notify.NewPagerDuty(cfg, time.Second)
+			},
+			want: []string{
+				"/v2/enqueue trigger mason/ping/192.168.1.1",
+				"/v2/enqueue trigger mason/upstream/192.168.1.1",
+				"/v2/enqueue resolve mason/upstream/192.168.1.1",
+				"/v2/enqueue resolve mason/ping/192.168.1.1",
+			},
+		},
+		"Opsgenie": {
+			incidents: func(cfg notify.IncidentConfig) (*notify.Incidents, error) {
+				return notify.NewOpsgenie(cfg, time.Second)
+			},
+			want: []string{
+				"/v2/alerts  mason/ping/192.168.1.1",
+				"/v2/alerts  mason/upstream/192.168.1.1",
+				"/v2/alerts/mason%2Fupstream%2F192.168.1.1/close?identifierType=alias  ",
+				"/v2/alerts/mason%2Fping%2F192.168.1.1/close?identifierType=alias  ",
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			url, got := fakeIncidents(t)
+			in, err := tc.incidents(notify.IncidentConfig{
+				Key:         "key",
+				URL:         url,
+				MinSeverity: "critical",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			router := model.Device{Name: "router", Addr: model.MustParseAddr("192.168.1.1")}
+			nas := model.Device{Name: "nas", Addr: model.MustParseAddr("192.168.1.20")}
+			m, b := testMason(t, router, nas)
+			err = m.store.UpsertDependency(context.Background(), model.Dependency{
+				Name:   "router-nas",
+				Parent: router.Addr,
+				Scope:  model.DependencyScopeDevice,
+				Target: nas.Addr.String(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the nas goes down behind the router, then both answer again
+			storePing(t, m, router, true)
+			storePing(t, m, nas, true)
+			m.publishPingUpstream()
+			storePing(t, m, router, false)
+			storePing(t, m, nas, false)
+			for _, a := range b.alerts() {
+				if err = in.Alert(context.Background(), a); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if diff := cmp.Diff(tc.want, *got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// fakeIncidents is an incident service recording the path, action and key of each request
+func fakeIncidents(t *testing.T) (string, *[]string) {
+	t.Helper()
+	got := make([]string, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Action   string `json:"event_action"`
+			DedupKey string `json:"dedup_key"`
+			Alias    string `json:"alias"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		got = append(got, r.URL.RequestURI()+" "+body.Action+" "+body.DedupKey+body.Alias)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, &got
+}
+
+func TestPrepareAlert_Maintenance(t *testing.T) {
+	ctx := context.Background()
+	nas := pingedDevice(false)
+	m, _ := testMason(t, nas)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	err := m.store.UpsertMaintenanceWindow(ctx, model.MaintenanceWindow{
+		Name:     "nas-upgrade",
+		Scope:    model.MaintenanceScopeDevice,
+		Target:   nas.Addr.String(),
+		Start:    start,
+		Duration: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alert := func(failed bool, at time.Time) notify.Alert {
+		a, _ := toAlert(model.EventDevicePingChanged{Device: pingedDevice(failed)}, at)
+		return a
+	}
+
+	tests := map[string]struct {
+		alert notify.Alert
+		want  bool
+	}{
+		"FailedBeforeWindow": {alert: alert(true, start.Add(-time.Minute)), want: true},
+		"FailedDuringWindow": {alert: alert(true, start.Add(time.Minute)), want: false},
+		// the failure raised before the window is cleared
+		"RecoveredDuringWindow": {alert: alert(false, start.Add(time.Minute)), want: true},
+		"FailedAfterWindow":     {alert: alert(true, start.Add(2*time.Hour)), want: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, got := m.prepareAlert(ctx, tc.alert); got != tc.want {
+				t.Fatalf("sent want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
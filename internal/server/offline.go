@@ -0,0 +1,70 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"errors"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/networkables/mason/internal/asn"
+	"github.com/networkables/mason/internal/oui"
+)
+
+var ErrOffline = errors.New("unavailable in offline mode")
+
+// EnrichmentStatus describes if an enrichment source is usable and why not
+type EnrichmentStatus struct {
+	Name     string
+	Degraded bool
+	Reason   string
+}
+
+// IsOffline reports if mason is running without outbound internet access
+func (m *Mason) IsOffline() bool {
+	return m.cfg.Offline.Enabled
+}
+
+// GetEnrichmentStatus returns the state of each enabled enrichment source
+func (m *Mason) GetEnrichmentStatus() []EnrichmentStatus {
+	stats := make([]EnrichmentStatus, 0, 3)
+	if m.cfg.Oui.Enabled {
+		stats = append(stats, sourceStatus("oui", oui.Available(), m.IsOffline()))
+	}
+	if m.cfg.Asn.Enabled {
+		stats = append(stats, m.asnSourceStatus())
+	}
+	ipify := EnrichmentStatus{Name: "external ip"}
+	if m.IsOffline() {
+		ipify.Degraded = true
+		ipify.Reason = ErrOffline.Error()
+	}
+	return append(stats, ipify)
+}
+
+// asnSourceStatus also reports a loaded listing as degraded once it is older than the
+// refresh interval, which happens offline or when refreshes keep failing
+func (m *Mason) asnSourceStatus() EnrichmentStatus {
+	s := sourceStatus("asn/geo", asn.Available(), m.IsOffline())
+	if s.Degraded || m.cfg.Asn.RefreshInterval <= 0 || !asn.Stale(m.cfg.Asn.RefreshInterval) {
+		return s
+	}
+	s.Degraded = true
+	s.Reason = "listing built " + humanize.Time(asn.GetStatus().BuiltAt)
+	return s
+}
+
+func sourceStatus(name string, available bool, offline bool) EnrichmentStatus {
+	s := EnrichmentStatus{Name: name}
+	if available {
+		return s
+	}
+	s.Degraded = true
+	s.Reason = "database not loaded"
+	if offline {
+		s.Reason = "local data file not found, " + ErrOffline.Error()
+	}
+	return s
+}
@@ -10,11 +10,11 @@ import (
 )
 
 type Options struct {
-	scanstatus *string
-	cfg        *Config
-	bus        bus.Bus
-	store      Storer
-	nfstore    NetflowStorer
+	cfg     *Config
+	bus     bus.Bus
+	store   Storer
+	nfstore NetflowStorer
+	tsstore TimeseriesStorer
 }
 
 type Option func(*Options)
@@ -32,10 +32,7 @@ func applyOptions(base *Options, opts ...Option) *Options {
 }
 
 func defaultOptions() *Options {
-	scanstat := ""
-	return &Options{
-		scanstatus: &scanstat,
-	}
+	return &Options{}
 }
 
 func WithConfig(x *Config) Option {
@@ -61,3 +58,10 @@ func WithNetflowStorer(x NetflowStorer) Option {
 		o.nfstore = x
 	}
 }
+
+// WithTimeseriesStorer keeps the performance pings apart from the store
+func WithTimeseriesStorer(x TimeseriesStorer) Option {
+	return func(o *Options) {
+		o.tsstore = x
+	}
+}
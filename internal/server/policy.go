@@ -0,0 +1,87 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// minPolicyInterval keeps monitoring policies from flooding the network with pings or scans
+const minPolicyInterval = 10 * time.Second
+
+var ErrPolicyIntervalTooShort = errors.New("policy interval must be at least 10s")
+
+// SetDevicePolicy replaces the monitoring policy of the device, an empty policy returns the
+// device to its tag policies and the global intervals
+func (m *Mason) SetDevicePolicy(
+	ctx context.Context,
+	addr model.Addr,
+	policy model.MonitoringPolicy,
+) error {
+	err := validatePolicy(policy)
+	if err != nil {
+		return err
+	}
+	d, err := m.store.GetDeviceByAddr(ctx, addr)
+	if err != nil {
+		return err
+	}
+	err = m.store.SetDevicePolicy(ctx, addr, policy)
+	if err != nil {
+		return err
+	}
+	next := d
+	next.Meta.Policy = policy
+	m.recordChanges(ctx, model.DeviceChanges(d, next, model.ChangeSourceUser, time.Now()))
+	return nil
+}
+
+func validatePolicy(p model.MonitoringPolicy) error {
+	for _, interval := range []time.Duration{p.PingInterval, p.PortScanInterval} {
+		if interval != 0 && interval < minPolicyInterval {
+			return ErrPolicyIntervalTooShort
+		}
+	}
+	return nil
+}
+
+// policyLookup returns the lookup used by the pinger and port scanner filters to find
+// the monitoring overrides for a device
+func (m *Mason) policyLookup(ctx context.Context) model.PolicyLookup {
+	defs, err := m.store.ListTagDefinitions(ctx)
+	m.recordIfError(err)
+	return model.DevicePolicyLookup(defs)
+}
+
+// pingerCheckInterval returns how often to look for devices to ping, a device or tag policy
+// with a shorter ping interval than the configured check interval shortens the check interval
+func (m *Mason) pingerCheckInterval(ctx context.Context) time.Duration {
+	interval := m.cfg.Pinger.CheckInterval
+	shorten := func(p model.MonitoringPolicy) {
+		if p.PingInterval > 0 && p.PingInterval < interval {
+			interval = p.PingInterval
+		}
+	}
+	defs, err := m.store.ListTagDefinitions(ctx)
+	if err == nil {
+		for _, def := range defs {
+			shorten(def.Policy)
+		}
+	}
+	for _, d := range m.store.ListDevices(ctx) {
+		shorten(d.Meta.Policy)
+	}
+	return interval
+}
+
+// EffectivePolicy returns the monitoring overrides which apply to the device after combining
+// its tags with its own policy, zero intervals use the global settings
+func (m *Mason) EffectivePolicy(ctx context.Context, d model.Device) model.MonitoringPolicy {
+	return m.policyLookup(ctx)(d)
+}
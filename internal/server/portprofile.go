@@ -0,0 +1,91 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+var (
+	ErrPortProfileNameRequired = errors.New("port profile name is required")
+	ErrUnknownPortList         = errors.New(
+		"portlist is not a builtin list, a port profile or port ranges",
+	)
+)
+
+// ListPortProfiles returns all custom port profiles
+func (m *Mason) ListPortProfiles(ctx context.Context) ([]model.PortProfile, error) {
+	pps, err := m.store.ListPortProfiles(ctx)
+	m.recordIfError(err)
+	return pps, err
+}
+
+// SavePortProfile creates or updates a custom port profile
+func (m *Mason) SavePortProfile(ctx context.Context, pp model.PortProfile) error {
+	if pp.Name == "" {
+		return ErrPortProfileNameRequired
+	}
+	var err error
+	switch pp.Scope {
+	case model.PortProfileScopeNone:
+		pp.Target = ""
+	case model.PortProfileScopeTag:
+		if !model.ValidTagName(pp.Target) {
+			err = model.ErrInvalidTagName
+		}
+	case model.PortProfileScopeNetwork:
+		_, err = m.store.GetNetworkByName(ctx, pp.Target)
+	default:
+		err = model.ErrInvalidPortProfileScope
+	}
+	if err != nil {
+		return err
+	}
+	if err = pp.Validate(); err != nil {
+		return err
+	}
+	return m.store.UpsertPortProfile(ctx, pp)
+}
+
+// RemovePortProfile deletes the named port profile
+func (m *Mason) RemovePortProfile(ctx context.Context, name string) error {
+	return m.store.RemovePortProfile(ctx, name)
+}
+
+// DevicePorts returns the ports a port scan of the device covers, nil when the builtin port
+// list of the config is scanned
+func (m *Mason) DevicePorts(ctx context.Context, d model.Device) ([]int, error) {
+	pps, err := m.store.ListPortProfiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ports := model.DevicePortsLookup(pps, m.store.ListNetworks(ctx))(d)
+	if len(ports) > 0 {
+		return ports, nil
+	}
+	return portlistPorts(m.cfg.Enrichment.PortScan.PortList, pps)
+}
+
+// portlistPorts resolves a portlist setting naming a port profile or giving port ranges, nil
+// is returned for the builtin port lists
+func portlistPorts(list string, pps []model.PortProfile) ([]int, error) {
+	if nettools.IsPortListName(list) {
+		return nil, nil
+	}
+	for _, pp := range pps {
+		if pp.Name == list {
+			return pp.Ports.Ports(), nil
+		}
+	}
+	ranges, err := model.ParsePortRanges(list)
+	if err != nil || len(ranges) == 0 {
+		return nil, ErrUnknownPortList
+	}
+	return ranges.Ports(), nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/enrichment"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/pkg/provider"
+)
+
+type namedDiscoverer struct {
+	name string
+	provider.Discoverer
+}
+
+// ProviderDiscoverySource is the source recorded on the devices found by a provider
+func ProviderDiscoverySource(name string) model.DiscoverySource {
+	return model.DiscoverySource("PROVIDER:" + name)
+}
+
+// newProviders builds the enabled discovery and enrichment providers, the ones which fail to
+// build are left out
+func (m *Mason) newProviders() []namedDiscoverer {
+	cfg := m.cfg.Providers
+	if cfg == nil {
+		return nil
+	}
+	err := enrichment.UseProviders(cfg.Enrichment, cfg.Settings)
+	if err != nil {
+		log.Error("enrichment providers disabled", "error", err)
+	}
+	discoverers := make([]namedDiscoverer, 0, len(cfg.Discovery))
+	for _, name := range cfg.Discovery {
+		d, err := provider.NewDiscoverer(name, cfg.Settings[name])
+		if err != nil {
+			log.Error("discovery provider disabled", "name", name, "error", err)
+			continue
+		}
+		discoverers = append(discoverers, namedDiscoverer{name: name, Discoverer: d})
+	}
+	return discoverers
+}
+
+// discoverFromProviders runs the discovery providers, their devices are handled as any other
+// discovered device.  A run is skipped while the previous one is still going.
+func (m *Mason) discoverFromProviders(ctx context.Context) {
+	if len(m.discoveryProviders) == 0 || !m.providersRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.providersRunning.Store(false)
+
+	for _, p := range m.discoveryProviders {
+		devices, err := p.Discover(ctx)
+		if err != nil {
+			m.publish(tre.New(err, "discovery provider", "name", p.name))
+			continue
+		}
+		now := time.Now()
+		for _, pd := range devices {
+			if !pd.Addr.IsValid() {
+				continue
+			}
+			d := model.Device{
+				Addr:         model.AddrToModelAddr(pd.Addr),
+				DiscoveredBy: ProviderDiscoverySource(p.name),
+				DiscoveredAt: now,
+			}
+			d.Name = d.Addr.String()
+			enrichment.MergeProviderDevice(&d, pd)
+			// the discovered tags replace those of a known device, keep the ones set by users
+			if prev, err := m.store.GetDeviceByAddr(ctx, d.Addr); err == nil && len(d.Meta.Tags) > 0 {
+				d.Meta.Tags = unionTags(prev.Meta.Tags, d.Meta.Tags)
+			}
+			m.publish(model.EventDeviceDiscovered(d))
+		}
+	}
+}
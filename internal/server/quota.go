@@ -0,0 +1,166 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+var ErrQuotaNameRequired = errors.New("bandwidth quota name is required")
+
+// ListBandwidthQuotas returns all bandwidth quotas
+func (m *Mason) ListBandwidthQuotas(ctx context.Context) ([]model.BandwidthQuota, error) {
+	quotas, err := m.store.ListBandwidthQuotas(ctx)
+	m.recordIfError(err)
+	return quotas, err
+}
+
+// SaveBandwidthQuota creates or updates a bandwidth quota
+func (m *Mason) SaveBandwidthQuota(ctx context.Context, q model.BandwidthQuota) error {
+	if q.Name == "" {
+		return ErrQuotaNameRequired
+	}
+	if q.Bytes <= 0 {
+		return model.ErrInvalidQuotaBytes
+	}
+	_, err := model.ParseQuotaPeriod(string(q.Period))
+	if err != nil {
+		return err
+	}
+	switch q.Scope {
+	case model.QuotaScopeDevice:
+		var addr model.Addr
+		addr, err = model.ParseAddr(q.Target)
+		if err == nil {
+			q.Target = addr.String()
+		}
+	case model.QuotaScopeTag:
+		if !model.ValidTagName(q.Target) {
+			err = model.ErrInvalidTagName
+		}
+	default:
+		err = model.ErrInvalidQuotaScope
+	}
+	if err != nil {
+		return err
+	}
+	return m.store.UpsertBandwidthQuota(ctx, q)
+}
+
+// RemoveBandwidthQuota deletes the named bandwidth quota
+func (m *Mason) RemoveBandwidthQuota(ctx context.Context, name string) error {
+	return m.store.RemoveBandwidthQuota(ctx, name)
+}
+
+// QuotaUsage returns the usage of each device against each of its quotas in the current
+// period, nothing is used while netflows are disabled
+func (m *Mason) QuotaUsage(ctx context.Context) ([]model.QuotaUsage, error) {
+	quotas, err := m.ListBandwidthQuotas(ctx)
+	if err != nil || len(quotas) == 0 {
+		return nil, err
+	}
+	usage := make([]model.QuotaUsage, 0)
+	for _, d := range m.store.ListDevices(ctx) {
+		du, err := m.deviceQuotaUsage(ctx, model.QuotasFor(quotas, d), d.Addr, time.Now())
+		if err != nil {
+			return usage, err
+		}
+		usage = append(usage, du...)
+	}
+	return usage, nil
+}
+
+// DeviceQuotaUsage returns the usage of the device against each of its quotas in the current
+// period
+func (m *Mason) DeviceQuotaUsage(ctx context.Context, d model.Device) ([]model.QuotaUsage, error) {
+	quotas, err := m.ListBandwidthQuotas(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.deviceQuotaUsage(ctx, model.QuotasFor(quotas, d), d.Addr, time.Now())
+}
+
+// deviceQuotaUsage reads the flows of the device once from the start of the longest period
+func (m *Mason) deviceQuotaUsage(
+	ctx context.Context,
+	quotas []model.BandwidthQuota,
+	addr model.Addr,
+	t time.Time,
+) ([]model.QuotaUsage, error) {
+	if len(quotas) == 0 || !m.cfg.NetFlows.Enabled || m.flowstore == nil {
+		return nil, nil
+	}
+	since := t
+	for _, q := range quotas {
+		if start := q.PeriodStart(t); start.Before(since) {
+			since = start
+		}
+	}
+	days, err := m.flowstore.FlowUsage(ctx, addr, since)
+	if err != nil {
+		m.recordIfError(err)
+		return nil, err
+	}
+	usage := make([]model.QuotaUsage, len(quotas))
+	for idx, q := range quotas {
+		usage[idx] = model.NewQuotaUsage(q, addr, days, t)
+	}
+	return usage, nil
+}
+
+// checkQuotas compares the traffic of each device against its quotas.  Reaching the warning
+// percent or using up a quota is annotated on the device and published once per period, the
+// annotations of the period tell if it was already raised.  A device in maintenance is only
+// annotated.
+func (m *Mason) checkQuotas(ctx context.Context) {
+	cfg := m.cfg.Quotas
+	if !cfg.Enabled || !m.quotasRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.quotasRunning.Store(false)
+
+	quotas, err := m.store.ListBandwidthQuotas(ctx)
+	if err != nil {
+		m.recordIfError(err)
+		return
+	}
+	if len(quotas) == 0 {
+		return
+	}
+
+	now := time.Now()
+	inMaintenance := m.maintenanceLookup(ctx, now)
+	for _, d := range m.store.ListDevices(ctx) {
+		usage, err := m.deviceQuotaUsage(ctx, model.QuotasFor(quotas, d), d.Addr, now)
+		if err != nil {
+			return
+		}
+		for _, u := range usage {
+			level := u.Level(cfg.WarnPercent)
+			if level == model.QuotaOK {
+				continue
+			}
+			annotations, err := m.store.ReadAnnotations(ctx, d.Addr, now.Sub(u.Start))
+			if err != nil {
+				m.recordIfError(err)
+				continue
+			}
+			if model.QuotaAnnotated(annotations, u.Quota.Name, level) {
+				continue
+			}
+			a := u.Annotation(level, now)
+			if window, ok := inMaintenance(d); ok {
+				m.recordIfError(m.store.AddAnnotation(ctx, window.Suppress(a)))
+				continue
+			}
+			m.recordIfError(m.store.AddAnnotation(ctx, a))
+			m.publish(model.EventQuotaAlert{Usage: u, Level: level})
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+// serviceCheckWindow is the span of results summarized for the service check status
+const serviceCheckWindow = 24 * time.Hour
+
+// ServiceCheckStatus summarizes the results of the last day of each service check
+func (m *Mason) ServiceCheckStatus(ctx context.Context) ([]model.ServiceCheckStatus, error) {
+	results, err := m.store.ReadServiceCheckResults(ctx, serviceCheckWindow)
+	if err != nil {
+		m.recordIfError(err)
+		return nil, err
+	}
+	return model.SummarizeServiceChecks(m.serviceChecks(ctx), results), nil
+}
+
+// serviceChecks derives the checks from the open ports of the tagged devices, so the ports
+// found by a port scan are checked from the next run on
+func (m *Mason) serviceChecks(ctx context.Context) []model.ServiceCheck {
+	cfg := m.cfg.ServiceChecks
+	return model.ServiceChecksFor(m.store.ListDevices(ctx), cfg.Tag, cfg.Ports)
+}
+
+// runServiceChecks connects to each service check and stores the results.  A service
+// changing between up and down is published unless its device is in maintenance, the
+// services going down behind a down parent device are published as one upstream failure of
+// the parent.  A run is skipped while the previous one is still going.
+func (m *Mason) runServiceChecks(ctx context.Context) {
+	cfg := m.cfg.ServiceChecks
+	if !cfg.Enabled || m.IsOffline() || !m.serviceChecksRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.serviceChecksRunning.Store(false)
+
+	checks := m.serviceChecks(ctx)
+	m.loadServiceCheckLast(ctx, checks)
+	if len(checks) == 0 {
+		return
+	}
+
+	now := time.Now()
+	results := make([]model.ServiceCheckResult, len(checks))
+	var wg sync.WaitGroup
+	for idx, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[idx] = serviceConnect(ctx, c, cfg.Timeout)
+		}()
+	}
+	wg.Wait()
+
+	inMaintenance := m.maintenanceLookup(ctx, now)
+	upstreamDown := m.upstreamLookup(ctx, serviceChecksAllDown(results))
+	var upstream upstreamFailures
+	m.serviceChecksMu.Lock()
+	for idx, r := range results {
+		key := r.Key()
+		failed, seen := m.serviceChecksLast[key]
+		m.serviceChecksLast[key] = r.Failed()
+		if (seen && failed == r.Failed()) || (!seen && !r.Failed()) {
+			continue
+		}
+		d, err := m.store.GetDeviceByAddr(ctx, key.Addr)
+		if err == nil {
+			if _, ok := inMaintenance(d); ok {
+				continue
+			}
+			if parent, ok := upstreamDown(d); ok && r.Failed() {
+				upstream.add(parent, d.Addr)
+				continue
+			}
+		}
+		m.publish(model.EventServiceCheckChanged{Check: checks[idx], Result: r})
+	}
+	m.serviceChecksMu.Unlock()
+	for _, e := range upstream.events {
+		m.publish(e)
+	}
+
+	m.recordIfError(m.store.WriteServiceCheckResults(ctx, results))
+	removed, err := m.store.PurgeServiceCheckResults(ctx, now.Add(-1*cfg.Retention))
+	m.recordIfError(err)
+	if removed > 0 {
+		log.Debug("purged service check results", "count", removed)
+	}
+}
+
+// serviceChecksAllDown are the addresses with all of their service checks failing
+func serviceChecksAllDown(results []model.ServiceCheckResult) map[model.Addr]bool {
+	down := make(map[model.Addr]bool)
+	for _, r := range results {
+		failed, seen := down[r.Addr]
+		down[r.Addr] = r.Failed() && (failed || !seen)
+	}
+	return down
+}
+
+// loadServiceCheckLast fills in whether each check was down from the stored results on the
+// first run since the server started, so a restart does not repeat their state
+func (m *Mason) loadServiceCheckLast(ctx context.Context, checks []model.ServiceCheck) {
+	m.serviceChecksMu.Lock()
+	defer m.serviceChecksMu.Unlock()
+	if m.serviceChecksLoaded {
+		return
+	}
+	results, err := m.store.ReadServiceCheckResults(ctx, serviceCheckWindow)
+	if err != nil {
+		m.recordIfError(err)
+		return
+	}
+	for _, s := range model.SummarizeServiceChecks(checks, results) {
+		if s.Results > 0 {
+			m.serviceChecksLast[s.Last.Key()] = s.Last.Failed()
+		}
+	}
+	m.serviceChecksLoaded = true
+}
+
+func serviceConnect(
+	ctx context.Context,
+	c model.ServiceCheck,
+	timeout time.Duration,
+) model.ServiceCheckResult {
+	r := model.ServiceCheckResult{Start: time.Now(), Addr: c.Addr, Port: c.Port}
+	responses, err := nettools.TcpPing(
+		ctx,
+		c.AddrPort(),
+		nettools.I4EWithCount(1),
+		nettools.I4EWithReadTimeout(timeout),
+	)
+	if len(responses) > 0 {
+		// the response carries why the connect failed, err only that no connect succeeded
+		r.Latency = responses[0].Elapsed
+		err = responses[0].Err
+	}
+	if err != nil {
+		r.Err = err.Error()
+	}
+	return r
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// LocalSite is the site of the networks and devices not assigned to another site
+func (m *Mason) LocalSite() string {
+	return m.cfg.Site.Name
+}
+
+// ListSites returns the local site followed by the defined sites and any site only
+// referenced by a network or device
+func (m *Mason) ListSites(ctx context.Context) ([]model.Site, error) {
+	summaries, err := m.summarizeSites(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sites := make([]model.Site, 0, len(summaries))
+	for _, ss := range summaries {
+		sites = append(sites, ss.Site)
+	}
+	return sites, nil
+}
+
+// SaveSite creates or updates a site
+func (m *Mason) SaveSite(ctx context.Context, site model.Site) error {
+	if !model.ValidSiteName(site.Name) {
+		return model.ErrInvalidSiteName
+	}
+	err := m.store.UpsertSite(ctx, site)
+	m.recordIfError(err)
+	return err
+}
+
+// RemoveSite deletes the site, the local site and sites still holding networks or devices
+// are refused
+func (m *Mason) RemoveSite(ctx context.Context, name string) error {
+	if name == m.LocalSite() {
+		return model.ErrSiteInUse
+	}
+	for _, n := range m.store.ListNetworks(ctx) {
+		if n.Site == name {
+			return model.ErrSiteInUse
+		}
+	}
+	for _, d := range m.store.ListDevices(ctx) {
+		if d.Meta.Site == name {
+			return model.ErrSiteInUse
+		}
+	}
+	return m.store.RemoveSite(ctx, name)
+}
+
+// SetNetworkSite moves the named network to the site, the local site or a blank site
+// returns the network to the local site
+func (m *Mason) SetNetworkSite(ctx context.Context, network string, site string) error {
+	if site == m.LocalSite() {
+		site = ""
+	}
+	if site != "" && !model.ValidSiteName(site) {
+		return model.ErrInvalidSiteName
+	}
+	n, err := m.store.GetNetworkByName(ctx, network)
+	if err != nil {
+		return err
+	}
+	n.Site = site
+	return m.store.UpdateNetwork(ctx, n)
+}
+
+// SiteLookup returns a function giving the site of a device
+func (m *Mason) SiteLookup(ctx context.Context) func(model.Device) string {
+	return model.SiteLookup(m.store.ListNetworks(ctx), m.LocalSite())
+}
+
+// SiteSummaries returns the network, device, ping and flow statistics of each site.  Flow
+// totals are only gathered when netflows are enabled.
+func (m *Mason) SiteSummaries(ctx context.Context) ([]model.SiteSummary, error) {
+	summaries, err := m.summarizeSites(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !m.cfg.NetFlows.Enabled || m.flowstore == nil {
+		return summaries, nil
+	}
+	index := make(map[string]int, len(summaries))
+	for idx, ss := range summaries {
+		index[ss.Site.Name] = idx
+	}
+	lookup := m.SiteLookup(ctx)
+	for _, d := range m.store.ListDevices(ctx) {
+		flows, err := m.flowstore.FlowSummaryByName(ctx, d.Addr)
+		if err != nil {
+			return summaries, err
+		}
+		ss := &summaries[index[lookup(d)]]
+		for _, f := range flows {
+			ss.RecvBytes += f.RecvBytes
+			ss.XmitBytes += f.XmitBytes
+		}
+	}
+	return summaries, nil
+}
+
+func (m *Mason) summarizeSites(ctx context.Context) ([]model.SiteSummary, error) {
+	stored, err := m.store.ListSites(ctx)
+	if err != nil {
+		m.recordIfError(err)
+		return nil, err
+	}
+	sites := append([]model.Site{{Name: m.LocalSite()}}, stored...)
+	return model.SummarizeSites(
+		sites,
+		m.LocalSite(),
+		m.store.ListNetworks(ctx),
+		m.store.ListDevices(ctx),
+	), nil
+}
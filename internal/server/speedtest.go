@@ -0,0 +1,101 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+const (
+	speedTestMethodHTTP   = "http"
+	speedTestMethodIperf3 = "iperf3"
+)
+
+// SpeedTests returns the bandwidth tests from Now() minus the duration, oldest first
+func (m *Mason) SpeedTests(
+	ctx context.Context,
+	duration time.Duration,
+) ([]model.SpeedTest, error) {
+	tests, err := m.store.ReadSpeedTests(ctx, duration)
+	m.recordIfError(err)
+	return tests, err
+}
+
+// HealthProbes returns the internet health probes from Now() minus the duration, oldest first
+func (m *Mason) HealthProbes(
+	ctx context.Context,
+	duration time.Duration,
+) ([]model.HealthProbe, error) {
+	probes, err := m.store.ReadHealthProbes(ctx, duration)
+	m.recordIfError(err)
+	return probes, err
+}
+
+// runSpeedTestIfDue runs a speed test when the last stored one is older than the interval, so a
+// restart does not postpone the test by a whole interval
+func (m *Mason) runSpeedTestIfDue(ctx context.Context) {
+	if !m.cfg.SpeedTest.Enabled {
+		return
+	}
+	tests, err := m.store.ReadSpeedTests(ctx, m.cfg.SpeedTest.Interval)
+	if err != nil {
+		m.recordIfError(err)
+		return
+	}
+	if len(tests) > 0 {
+		return
+	}
+	m.runSpeedTest(ctx)
+}
+
+// runSpeedTest measures and stores the throughput of the internet connection, a failed test is
+// stored with its error.  A run is skipped while the previous one is still going.
+func (m *Mason) runSpeedTest(ctx context.Context) {
+	cfg := m.cfg.SpeedTest
+	if !cfg.Enabled || m.IsOffline() || !m.speedTestRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.speedTestRunning.Store(false)
+
+	st := model.SpeedTest{Start: time.Now(), Method: cfg.Method}
+	var (
+		result nettools.SpeedTestResult
+		err    error
+	)
+	switch cfg.Method {
+	case speedTestMethodHTTP:
+		st.Server = cfg.DownloadURL
+		result, err = nettools.HTTPSpeedTest(ctx, cfg.DownloadURL, cfg.UploadURL, cfg.UploadSize)
+	case speedTestMethodIperf3:
+		st.Server = net.JoinHostPort(cfg.Iperf3Server, strconv.Itoa(cfg.Iperf3Port))
+		if cfg.Iperf3Server == "" {
+			err = errors.New("no iperf3 server configured")
+			break
+		}
+		result, err = nettools.Iperf3SpeedTest(ctx, cfg.Iperf3Server, cfg.Iperf3Port, cfg.Duration)
+	default:
+		err = fmt.Errorf("unknown speed test method: %s", cfg.Method)
+	}
+	st.Latency = result.Latency
+	st.Download = result.Download
+	st.Upload = result.Upload
+	if err != nil {
+		st.Err = err.Error()
+		log.Warn("speed test failed", "method", cfg.Method, "error", err)
+	} else {
+		log.Debug("speed test", "download", st.Download, "upload", st.Upload)
+	}
+	m.recordIfError(m.store.WriteSpeedTest(ctx, st))
+}
@@ -19,7 +19,26 @@ type (
 	Storer interface {
 		NetworkStorer
 		DeviceStorer
-		PerformancePingStorer
+		TimeseriesStorer
+		AnnotationStorer
+		ChangeStorer
+		ReservationStorer
+		TagStorer
+		SiteStorer
+		InternetHealthStorer
+		SpeedTestStorer
+		EventStorer
+		TombstoneStorer
+		MaintenanceStorer
+		HTTPCheckStorer
+		ServiceCheckStorer
+		MACBindingStorer
+		DHCPSightingStorer
+		QuotaStorer
+		ExclusionStorer
+		DependencyStorer
+		PortProfileStorer
+		FullPortScanStorer
 		Close() error
 	}
 
@@ -39,14 +58,22 @@ type (
 		AddDevice(context.Context, model.Device) error
 		RemoveDeviceByAddr(context.Context, model.Addr) error
 		UpdateDevice(context.Context, model.Device) (bool, error)
+		SetDeviceTags(context.Context, model.Addr, model.Tags) error
+		SetDevicePolicy(context.Context, model.Addr, model.MonitoringPolicy) error
+		SetDeviceParent(context.Context, model.Addr, model.Addr) error
+		SetDeviceApproval(context.Context, model.Addr, model.ApprovalState) error
+		SetDeviceDetails(context.Context, model.Addr, model.DeviceDetails) error
 		GetDeviceByAddr(context.Context, model.Addr) (model.Device, error)
+		GetDeviceByMAC(context.Context, model.MAC) (model.Device, error)
+		FindDevicesByName(context.Context, string) []model.Device
 		GetFilteredDevices(context.Context, model.DeviceFilter) []model.Device
+		QueryDevices(context.Context, model.DeviceQuery) model.DevicePage
 		ListDevices(context.Context) []model.Device
 		CountDevices(context.Context) int
 	}
 
-	// PerformancePingStorer allows for the saving and fetching of timeseries data.
-	PerformancePingStorer interface {
+	// TimeseriesStorer allows for the saving and fetching of performance ping timeseries data.
+	TimeseriesStorer interface {
 		WritePerformancePing(
 			context.Context,
 			time.Time,
@@ -60,8 +87,167 @@ type (
 		) ([]pinger.Point, error)
 	}
 
+	// AnnotationStorer allows for the saving and fetching of timeseries annotations.
+	AnnotationStorer interface {
+		AddAnnotation(context.Context, model.Annotation) error
+		ReadAnnotations(context.Context, model.Addr, time.Duration) ([]model.Annotation, error)
+	}
+
+	// ChangeStorer allows for the saving and fetching of the device change history.
+	ChangeStorer interface {
+		AddDeviceChanges(context.Context, []model.DeviceChange) error
+		ReadDeviceChanges(context.Context, model.Addr) ([]model.DeviceChange, error)
+	}
+
+	// ReservationStorer allows for the saving and fetching of address reservations.
+	ReservationStorer interface {
+		UpsertReservation(context.Context, model.Reservation) error
+		RemoveReservation(context.Context, model.Addr) error
+		ListReservations(context.Context) ([]model.Reservation, error)
+	}
+
+	// TagStorer allows for the saving and fetching of tag definitions.
+	TagStorer interface {
+		UpsertTagDefinition(context.Context, model.TagDefinition) error
+		RemoveTagDefinition(context.Context, string) error
+		ListTagDefinitions(context.Context) ([]model.TagDefinition, error)
+	}
+
+	// SiteStorer allows for the saving and fetching of sites.
+	SiteStorer interface {
+		UpsertSite(context.Context, model.Site) error
+		RemoveSite(context.Context, string) error
+		ListSites(context.Context) ([]model.Site, error)
+	}
+
+	// InternetHealthStorer allows for the saving and fetching of internet health probes.
+	InternetHealthStorer interface {
+		WriteHealthProbes(context.Context, []model.HealthProbe) error
+		ReadHealthProbes(context.Context, time.Duration) ([]model.HealthProbe, error)
+		PurgeHealthProbes(context.Context, time.Time) (int, error)
+	}
+
+	// SpeedTestStorer allows for the saving and fetching of bandwidth test results.
+	SpeedTestStorer interface {
+		WriteSpeedTest(context.Context, model.SpeedTest) error
+		ReadSpeedTests(context.Context, time.Duration) ([]model.SpeedTest, error)
+	}
+
+	// EventStorer allows for the saving and fetching of the event history.
+	EventStorer interface {
+		WriteEvents(context.Context, []model.EventRecord) error
+		ReadEvents(context.Context, model.EventQuery) ([]model.EventRecord, error)
+		PurgeEvents(context.Context, time.Time, int) (int, error)
+	}
+
+	// TombstoneStorer allows for the saving and fetching of deleted devices and networks.
+	TombstoneStorer interface {
+		UpsertTombstone(context.Context, model.Tombstone) error
+		RemoveTombstone(context.Context, model.TombstoneKind, string) error
+		ListTombstones(context.Context) ([]model.Tombstone, error)
+	}
+
+	// MaintenanceStorer allows for the saving and fetching of maintenance windows.
+	MaintenanceStorer interface {
+		UpsertMaintenanceWindow(context.Context, model.MaintenanceWindow) error
+		RemoveMaintenanceWindow(context.Context, string) error
+		ListMaintenanceWindows(context.Context) ([]model.MaintenanceWindow, error)
+	}
+
+	// HTTPCheckStorer allows for the saving and fetching of http checks and their results.
+	HTTPCheckStorer interface {
+		UpsertHTTPCheck(context.Context, model.HTTPCheck) error
+		RemoveHTTPCheck(context.Context, string) error
+		ListHTTPChecks(context.Context) ([]model.HTTPCheck, error)
+		WriteHTTPCheckResults(context.Context, []model.HTTPCheckResult) error
+		ReadHTTPCheckResults(context.Context, time.Duration) ([]model.HTTPCheckResult, error)
+		PurgeHTTPCheckResults(context.Context, time.Time) (int, error)
+	}
+
+	// ServiceCheckStorer allows for the saving and fetching of service check results.
+	ServiceCheckStorer interface {
+		WriteServiceCheckResults(context.Context, []model.ServiceCheckResult) error
+		ReadServiceCheckResults(context.Context, time.Duration) ([]model.ServiceCheckResult, error)
+		PurgeServiceCheckResults(context.Context, time.Time) (int, error)
+	}
+
+	// MACBindingStorer allows for the saving and fetching of the MACs seen at each address.
+	MACBindingStorer interface {
+		UpsertMACBinding(context.Context, model.MACBinding) error
+		ListMACBindings(context.Context) ([]model.MACBinding, error)
+		PurgeMACBindings(context.Context, time.Time) (int, error)
+	}
+
+	// DHCPSightingStorer allows for the saving and fetching of the DHCP servers and gateways
+	// seen answering discovers.
+	DHCPSightingStorer interface {
+		UpsertDHCPSighting(context.Context, model.DHCPSighting) error
+		ListDHCPSightings(context.Context) ([]model.DHCPSighting, error)
+		RemoveDHCPSighting(context.Context, model.DHCPSightingKind, model.Addr) error
+	}
+
+	// QuotaStorer allows for the saving and fetching of bandwidth quotas.
+	QuotaStorer interface {
+		UpsertBandwidthQuota(context.Context, model.BandwidthQuota) error
+		RemoveBandwidthQuota(context.Context, string) error
+		ListBandwidthQuotas(context.Context) ([]model.BandwidthQuota, error)
+	}
+
+	// ExclusionStorer allows for the saving and fetching of the exclusions managed at runtime.
+	ExclusionStorer interface {
+		UpsertExclusion(context.Context, model.Exclusion) error
+		RemoveExclusion(context.Context, string) error
+		ListExclusions(context.Context) ([]model.Exclusion, error)
+	}
+
+	// DependencyStorer allows for the saving and fetching of the device dependencies.
+	DependencyStorer interface {
+		UpsertDependency(context.Context, model.Dependency) error
+		RemoveDependency(context.Context, string) error
+		ListDependencies(context.Context) ([]model.Dependency, error)
+	}
+
+	// PortProfileStorer allows for the saving and fetching of the custom port scan profiles.
+	PortProfileStorer interface {
+		UpsertPortProfile(context.Context, model.PortProfile) error
+		RemovePortProfile(context.Context, string) error
+		ListPortProfiles(context.Context) ([]model.PortProfile, error)
+	}
+
+	// FullPortScanStorer allows for the saving and fetching of the progress of full port
+	// sweeps.
+	FullPortScanStorer interface {
+		UpsertFullPortScan(context.Context, model.FullPortScan) error
+		RemoveFullPortScan(context.Context, model.Addr) error
+		ListFullPortScans(context.Context) ([]model.FullPortScan, error)
+	}
+
+	// TimeseriesArchiver is implemented by stores which can move old timeseries data out of
+	// the live store.
+	TimeseriesArchiver interface {
+		ArchiveTimeseries(context.Context) (int, error)
+	}
+
+	// TimeseriesMerger is implemented by timeseries stores which can move the history of a
+	// device onto another address.
+	TimeseriesMerger interface {
+		MergePerformancePings(context.Context, model.Addr, model.Addr) error
+	}
+
+	// StorePinger is implemented by stores which can check they are reachable.
+	StorePinger interface {
+		Ping(context.Context) error
+	}
+
+	// ConsistencyChecker is implemented by stores which can find (and repair) records
+	// referencing data which does not exist.
+	ConsistencyChecker interface {
+		CheckConsistency(context.Context, bool) ([]model.ConsistencyIssue, error)
+	}
+
 	NetflowStorer interface {
 		AsnStorer
+		DNSQueryStorer
 		AddNetflows(context.Context, []model.IpFlow) error
 		GetNetflows(context.Context, model.Addr) ([]model.IpFlow, error)
 		FlowSummaryByIP(context.Context, model.Addr) ([]model.FlowSummaryForAddrByIP, error)
@@ -70,6 +256,15 @@ type (
 			context.Context,
 			model.Addr,
 		) ([]model.FlowSummaryForAddrByCountry, error)
+		UpsertFlowTemplate(context.Context, model.FlowTemplate) error
+		ListFlowTemplates(context.Context) ([]model.FlowTemplate, error)
+		FlowUsage(context.Context, model.Addr, time.Time) ([]model.FlowUsage, error)
+	}
+
+	DNSQueryStorer interface {
+		AddDNSQueries(context.Context, []model.DNSQuery) error
+		RecentDomains(context.Context, model.Addr, int) ([]model.DomainSummary, error)
+		PurgeDNSQueries(context.Context, time.Time) (int, error)
 	}
 
 	AsnStorer interface {
@@ -0,0 +1,41 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// handleSyslogMessage keeps the message of a known device with its recent logs, a message at
+// the alert severity or above raises an alert once per cooldown for the device
+func (m *Mason) handleSyslogMessage(ctx context.Context, msg model.SyslogMessage) {
+	if _, err := m.store.GetDeviceByAddr(ctx, msg.Addr); err != nil {
+		return
+	}
+	m.syslogs.Add(msg)
+	if msg.Severity > m.syslogAlertSeverity {
+		return
+	}
+	now := time.Now()
+	m.syslogAlertsMu.Lock()
+	defer m.syslogAlertsMu.Unlock()
+	if now.Sub(m.syslogAlerts[msg.Addr]) < m.cfg.Syslog.AlertCooldown {
+		return
+	}
+	m.syslogAlerts[msg.Addr] = now
+	m.publish(model.EventSyslogAlert(msg))
+}
+
+// RecentSyslog returns the syslog messages most recently received from the device, newest
+// first, nothing while the syslog receiver is disabled
+func (m *Mason) RecentSyslog(ctx context.Context, addr model.Addr) []model.SyslogMessage {
+	if m.syslogs == nil {
+		return nil
+	}
+	return m.syslogs.Recent(addr)
+}
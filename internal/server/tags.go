@@ -0,0 +1,103 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"slices"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// ListTagDefinitions returns all tag definitions
+func (m *Mason) ListTagDefinitions(ctx context.Context) ([]model.TagDefinition, error) {
+	defs, err := m.store.ListTagDefinitions(ctx)
+	m.recordIfError(err)
+	return defs, err
+}
+
+// SaveTagDefinition creates or updates a tag definition
+func (m *Mason) SaveTagDefinition(ctx context.Context, def model.TagDefinition) error {
+	if !model.ValidTagName(def.Name) {
+		return model.ErrInvalidTagName
+	}
+	err := validatePolicy(def.Policy)
+	if err != nil {
+		return err
+	}
+	err = m.store.UpsertTagDefinition(ctx, def)
+	m.recordIfError(err)
+	return err
+}
+
+// RemoveTagDefinition deletes the tag definition and removes the tag from all devices
+// and networks
+func (m *Mason) RemoveTagDefinition(ctx context.Context, name string) error {
+	err := m.store.RemoveTagDefinition(ctx, name)
+	if err != nil {
+		return err
+	}
+	for _, d := range m.store.GetFilteredDevices(ctx, model.TagDeviceFilter(name)) {
+		tags := model.Remove(model.Tag{Val: name}, slices.Clone(d.Meta.Tags))
+		err = m.setDeviceTags(ctx, d, tags)
+		if err != nil {
+			return err
+		}
+	}
+	for _, n := range m.store.GetFilteredNetworks(ctx, model.TagNetworkFilter(name)) {
+		n.Tags = model.Remove(model.Tag{Val: name}, slices.Clone(n.Tags))
+		err = m.store.UpdateNetwork(ctx, n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TagDevice adds the tag to the device
+func (m *Mason) TagDevice(ctx context.Context, addr model.Addr, name string) error {
+	if !model.ValidTagName(name) {
+		return model.ErrInvalidTagName
+	}
+	d, err := m.store.GetDeviceByAddr(ctx, addr)
+	if err != nil {
+		return err
+	}
+	tags := model.Add(model.Tag{Val: name}, slices.Clone(d.Meta.Tags))
+	return m.setDeviceTags(ctx, d, tags)
+}
+
+// UntagDevice removes the tag from the device
+func (m *Mason) UntagDevice(ctx context.Context, addr model.Addr, name string) error {
+	d, err := m.store.GetDeviceByAddr(ctx, addr)
+	if err != nil {
+		return err
+	}
+	tags := model.Remove(model.Tag{Val: name}, slices.Clone(d.Meta.Tags))
+	return m.setDeviceTags(ctx, d, tags)
+}
+
+// TagNetwork adds the tag to the network with the name or prefix
+func (m *Mason) TagNetwork(ctx context.Context, network string, name string) error {
+	if !model.ValidTagName(name) {
+		return model.ErrInvalidTagName
+	}
+	n, err := m.findNetwork(ctx, network)
+	if err != nil {
+		return err
+	}
+	n.Tags = model.Add(model.Tag{Val: name}, slices.Clone(n.Tags))
+	return m.store.UpdateNetwork(ctx, n)
+}
+
+// UntagNetwork removes the tag from the network with the name or prefix
+func (m *Mason) UntagNetwork(ctx context.Context, network string, name string) error {
+	n, err := m.findNetwork(ctx, network)
+	if err != nil {
+		return err
+	}
+	n.Tags = model.Remove(model.Tag{Val: name}, slices.Clone(n.Tags))
+	return m.store.UpdateNetwork(ctx, n)
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const tombstonePurgeInterval = time.Hour
+
+// RemoveDevice deletes the device, it can be restored with RestoreDeleted until the
+// grace period has passed
+func (m *Mason) RemoveDevice(ctx context.Context, addr model.Addr) error {
+	d, err := m.store.GetDeviceByAddr(ctx, addr)
+	if err != nil {
+		return err
+	}
+	err = m.store.UpsertTombstone(
+		ctx,
+		model.DeviceTombstone(d, time.Now(), m.cfg.SoftDelete.GracePeriod),
+	)
+	if err != nil {
+		return tre.New(err, "save device tombstone", "addr", addr)
+	}
+	return m.store.RemoveDeviceByAddr(ctx, addr)
+}
+
+// RemoveNetwork deletes the network with the name or prefix, it can be restored with
+// RestoreDeleted until the grace period has passed
+func (m *Mason) RemoveNetwork(ctx context.Context, name string) error {
+	n, err := m.findNetwork(ctx, name)
+	if err != nil {
+		return err
+	}
+	err = m.store.UpsertTombstone(
+		ctx,
+		model.NetworkTombstone(n, time.Now(), m.cfg.SoftDelete.GracePeriod),
+	)
+	if err != nil {
+		return tre.New(err, "save network tombstone", "name", name)
+	}
+	return m.store.RemoveNetworkByName(ctx, n.Name)
+}
+
+// ListDeleted returns the deleted devices and networks which can still be restored
+func (m *Mason) ListDeleted(ctx context.Context) ([]model.Tombstone, error) {
+	ts, err := m.store.ListTombstones(ctx)
+	m.recordIfError(err)
+	return ts, err
+}
+
+// RestoreDeleted undoes the delete of a device or network, a device which has been
+// rediscovered since the delete is merged with the deleted copy
+func (m *Mason) RestoreDeleted(ctx context.Context, kind model.TombstoneKind, key string) error {
+	t, err := m.findTombstone(ctx, kind, key)
+	if err != nil {
+		return err
+	}
+	switch t.Kind {
+	case model.TombstoneDevice:
+		err = m.store.AddDevice(ctx, t.Device)
+		if errors.Is(err, model.ErrDeviceExists) {
+			_, err = m.store.UpdateDevice(ctx, t.Device)
+		}
+	case model.TombstoneNetwork:
+		err = m.store.AddNetwork(ctx, t.Network)
+		if errors.Is(err, model.ErrNetworkExists) {
+			err = m.store.UpdateNetwork(ctx, t.Network)
+		}
+	}
+	if err != nil {
+		return tre.New(err, "restore deleted", "kind", kind, "key", key)
+	}
+	return m.store.RemoveTombstone(ctx, kind, key)
+}
+
+// PurgeDeleted permanently removes the deleted items whose grace period has passed, returns
+// the number of items purged
+func (m *Mason) PurgeDeleted(ctx context.Context) (int, error) {
+	ts, err := m.store.ListTombstones(ctx)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	count := 0
+	for _, t := range ts {
+		if !t.Expired(now) {
+			continue
+		}
+		err = m.store.RemoveTombstone(ctx, t.Kind, t.Key)
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func (m *Mason) findTombstone(
+	ctx context.Context,
+	kind model.TombstoneKind,
+	key string,
+) (model.Tombstone, error) {
+	ts, err := m.store.ListTombstones(ctx)
+	if err != nil {
+		return model.Tombstone{}, err
+	}
+	for _, t := range ts {
+		if t.Kind == kind && t.Key == key {
+			return t, nil
+		}
+	}
+	return model.Tombstone{}, model.ErrTombstoneDoesNotExist
+}
+
+func (m *Mason) purgeDeleted(ctx context.Context) {
+	count, err := m.PurgeDeleted(ctx)
+	if err != nil {
+		m.publish(tre.New(err, "purge deleted"))
+		return
+	}
+	if count > 0 {
+		log.Info("purged deleted items", "count", count)
+	}
+}
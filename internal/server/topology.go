@@ -0,0 +1,87 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// topologyCandidates is how many devices of a network are tried before giving up on tracing it
+const topologyCandidates = 3
+
+// Topology layers the networks under the routers found by the last traces, with their devices
+func (m *Mason) Topology(ctx context.Context) model.Topology {
+	m.topologyMu.Lock()
+	traces := make([]model.TopologyTrace, 0, len(m.topologyTraces))
+	for _, t := range m.topologyTraces {
+		traces = append(traces, t)
+	}
+	m.topologyMu.Unlock()
+	return model.BuildTopology(
+		traces,
+		m.store.ListNetworks(ctx),
+		m.store.ListDevices(ctx),
+		m.cfg.Topology.MaxDevices,
+	)
+}
+
+// traceTopology traceroutes a device of each network, the hops on the way are the routers in
+// front of it.  Devices answering pings are tried first, a network none of its candidates
+// could be traced to keeps its previous trace.  A run is skipped while the previous one is
+// still going.
+func (m *Mason) traceTopology(ctx context.Context) {
+	if !m.cfg.Topology.Enabled || !m.cfg.Discovery.Icmp.Privileged || m.IsOffline() ||
+		!m.topologyRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.topologyRunning.Store(false)
+
+	devices := m.store.ListDevices(ctx)
+	slices.SortFunc(devices, func(a, b model.Device) int {
+		if a.PerformancePing.LastFailed != b.PerformancePing.LastFailed {
+			if a.PerformancePing.LastFailed {
+				return 1
+			}
+			return -1
+		}
+		return a.Addr.Compare(b.Addr)
+	})
+	for _, n := range m.store.ListNetworks(ctx) {
+		tried := 0
+		for _, d := range devices {
+			if tried >= topologyCandidates || ctx.Err() != nil {
+				break
+			}
+			if !n.Contains(d) {
+				continue
+			}
+			tried++
+			stats, err := m.TracerouteAddr(ctx, d.Addr)
+			if err != nil || len(stats) == 0 {
+				continue
+			}
+			trace := model.TopologyTrace{
+				Network: n.Prefix,
+				Target:  d.Addr,
+				Hops:    make([]model.Addr, 0, len(stats)),
+				Traced:  time.Now(),
+			}
+			for _, stat := range stats {
+				trace.Hops = append(trace.Hops, model.AddrToModelAddr(stat.Peer))
+			}
+			m.topologyMu.Lock()
+			m.topologyTraces[n.Prefix] = trace
+			m.topologyMu.Unlock()
+			log.Debug("traced network", "network", n.Prefix, "target", d.Addr, "hops", len(stats))
+			break
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/unifi"
+)
+
+func (m *Mason) newUnifi() *unifi.Client {
+	if !m.cfg.Unifi.Enabled {
+		return nil
+	}
+	c, err := unifi.New(m.cfg.Unifi)
+	if err != nil {
+		log.Error("unifi integration disabled", "error", err)
+		return nil
+	}
+	return c
+}
+
+// syncUnifi reads the network devices and clients of the controller and adds them as devices
+// with how they are connected.  A run is skipped while the previous one is still going.
+func (m *Mason) syncUnifi(ctx context.Context) {
+	if m.unifi == nil || !m.unifiRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.unifiRunning.Store(false)
+
+	netdevs, err := m.unifi.NetworkDevices(ctx)
+	if err != nil {
+		m.publish(tre.New(err, "read unifi devices", "url", m.unifi.URL()))
+		return
+	}
+	stations, err := m.unifi.Stations(ctx)
+	if err != nil {
+		m.publish(tre.New(err, "read unifi clients", "url", m.unifi.URL()))
+		return
+	}
+	for _, d := range unifi.Inventory(stations, netdevs, time.Now()) {
+		// the discovered tags replace those of a known device, keep the ones set by users
+		if prev, err := m.store.GetDeviceByAddr(ctx, d.Addr); err == nil {
+			d.Meta.Tags = unionTags(prev.Meta.Tags, d.Meta.Tags)
+		}
+		m.publish(model.EventDeviceDiscovered(d))
+	}
+}
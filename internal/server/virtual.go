@@ -0,0 +1,55 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/enrichment"
+	"github.com/networkables/mason/internal/model"
+)
+
+// rescanVirtualHosts lists the guests of the hosts again, guests come and go far more often
+// than the hosts are enriched
+func (m *Mason) rescanVirtualHosts(ctx context.Context) {
+	hosts := m.store.GetFilteredDevices(ctx,
+		enrichment.VirtualHostRescanFilter(m.cfg.Enrichment.Virtual),
+	)
+	for _, host := range hosts {
+		m.publish(enrichment.EnrichDeviceRequest{
+			Device: host,
+			Fields: enrichment.EnrichmentFields{
+				PerformVirtualScan: true,
+				Cfg:                m.cfg.Enrichment,
+			},
+		})
+	}
+}
+
+// linkGuests makes the host the parent of the devices it lists as guests, devices it no
+// longer lists lose it as their parent
+func (m *Mason) linkGuests(ctx context.Context, host model.Device) {
+	for _, d := range m.store.ListDevices(ctx) {
+		if d.Addr.Compare(host.Addr) == 0 {
+			continue
+		}
+		_, isGuest := host.Virtual.GuestOf(d)
+		hasParent := d.Virtual.Parent.Compare(host.Addr) == 0
+		var parent model.Addr
+		switch {
+		case isGuest && !hasParent:
+			parent = host.Addr
+		case !isGuest && hasParent:
+		default:
+			continue
+		}
+		err := m.store.SetDeviceParent(ctx, d.Addr, parent)
+		if err != nil {
+			m.publish(tre.New(err, "set device parent", "addr", d.Addr, "parent", host.Addr))
+		}
+	}
+}
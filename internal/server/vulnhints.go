@@ -0,0 +1,24 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+
+	"github.com/networkables/mason/internal/enrichment"
+	"github.com/networkables/mason/internal/model"
+)
+
+// DeviceVulnHints returns the services of the device whose banners announce a version with
+// known vulnerabilities, none when vulnerability hints are disabled
+func (m *Mason) DeviceVulnHints(ctx context.Context, d model.Device) ([]model.VulnHint, error) {
+	cfg := m.cfg.Enrichment.VulnHints
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	hints, err := enrichment.VulnHints(d, cfg)
+	m.recordIfError(err)
+	return hints, err
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"time"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// AddAnnotation stores an annotation, global annotations are stored with an empty addr
+func (cs *Store) AddAnnotation(ctx context.Context, a model.Annotation) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	stmt, err := conn.Prepare(
+		`insert into annotations (time, addr, kind, text)
+    values (:time, :addr, :kind, :text)`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":time", a.Time.Format(time.RFC3339Nano))
+	stmt.SetText(":addr", annotationAddrString(a))
+	stmt.SetText(":kind", string(a.Kind))
+	stmt.SetText(":text", a.Text)
+
+	_, err = stmt.Step()
+	return err
+}
+
+// ReadAnnotations returns the annotations for the addr, including global annotations,
+// from Now() minus the duration
+func (cs *Store) ReadAnnotations(
+	ctx context.Context,
+	addr model.Addr,
+	duration time.Duration,
+) (annotations []model.Annotation, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      time, addr, kind, text
+    from annotations
+    where (addr = :addr or addr = '') and time > :start
+    order by time`)
+	if err != nil {
+		return annotations, err
+	}
+	stmt.SetText(":addr", addr.String())
+	stmt.SetText(":start", time.Now().Add(-1*duration).Format(time.RFC3339Nano))
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return annotations, err
+		}
+		if !hasRow {
+			break
+		}
+		a := model.Annotation{
+			Kind: model.AnnotationKind(stmt.GetText("kind")),
+			Text: stmt.GetText("text"),
+		}
+		if s := stmt.GetText("addr"); s != "" {
+			a.Addr, err = model.ParseAddr(s)
+			if err != nil {
+				return annotations, err
+			}
+		}
+		a.Time, err = time.Parse(time.RFC3339Nano, stmt.GetText("time"))
+		if err != nil {
+			return annotations, err
+		}
+		annotations = append(annotations, a)
+	}
+	return annotations, nil
+}
+
+func annotationAddrString(a model.Annotation) string {
+	if a.IsGlobal() {
+		return ""
+	}
+	return a.Addr.String()
+}
@@ -0,0 +1,63 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_Annotations(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+	addr := model.MustParseAddr("192.168.86.1")
+
+	device := model.Annotation{
+		Time: now.Add(-time.Minute),
+		Addr: addr,
+		Kind: model.AnnotationPortsChanged,
+		Text: "ports",
+	}
+	global := model.Annotation{Time: now, Kind: model.AnnotationMaintenance, Text: "window"}
+	other := model.Annotation{
+		Time: now,
+		Addr: model.MustParseAddr("192.168.86.2"),
+		Kind: model.AnnotationOffline,
+	}
+	old := model.Annotation{Time: now.Add(-48 * time.Hour), Addr: addr, Kind: model.AnnotationOffline}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, a := range []model.Annotation{device, global, other, old} {
+		err := db.AddAnnotation(ctx, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := db.ReadAnnotations(ctx, addr, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff(
+		[]model.Annotation{device, global},
+		got,
+		cmpopts.EquateComparable(netip.Addr{}),
+		cmpopts.EquateApproxTime(time.Millisecond),
+	)
+	if diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
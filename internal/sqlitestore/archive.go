@@ -0,0 +1,272 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/cachedb"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/pinger"
+)
+
+const (
+	archiveKindPings = "pings"
+	archiveKindFlows = "flows"
+)
+
+// archivedPing is the on disk form of a performance ping row
+type archivedPing struct {
+	Addr  model.Addr
+	Point pinger.Point
+}
+
+// ArchiveTimeseries moves ping and flow rows older than the configured archive age out of the
+// database and into one compressed file per kind and day.  Returns the number of rows moved.
+func (cs *Store) ArchiveTimeseries(ctx context.Context) (count int, err error) {
+	if cs.archiveAfter <= 0 {
+		return 0, nil
+	}
+	ensureDirectory(cs.archiveDirectory)
+	cutoff := time.Now().Add(-1 * cs.archiveAfter)
+
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return 0, err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	pings, err := selectPerformancePingsBefore(conn, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	flows, err := selectNetflowsBefore(conn, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	err = appendArchive(cs.archiveDirectory, archiveKindPings, pings, func(p archivedPing) time.Time {
+		return p.Point.Start
+	})
+	if err != nil {
+		return 0, err
+	}
+	err = appendArchive(cs.archiveDirectory, archiveKindFlows, flows, func(f model.IpFlow) time.Time {
+		return f.Start
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, table := range []string{"performancepings", "flows"} {
+		stmt, err := conn.Prepare(`delete from ` + table + ` where start < :cutoff`)
+		if err != nil {
+			return 0, err
+		}
+		stmt.SetText(":cutoff", cutoff.Format(time.RFC3339Nano))
+		_, err = stmt.Step()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(pings) + len(flows), nil
+}
+
+// archiveBoundary returns the time before which data may only be found in the archive,
+// the zero time is returned when archiving is disabled
+func (cs *Store) archiveBoundary() time.Time {
+	if cs.archiveAfter <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-1 * cs.archiveAfter)
+}
+
+// readArchivedPerformancePings loads the archived points for the addr after from
+func (cs *Store) readArchivedPerformancePings(
+	addr model.Addr,
+	from time.Time,
+) ([]pinger.Point, error) {
+	recs, err := readArchive[archivedPing](cs.archiveDirectory, archiveKindPings, from)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]pinger.Point, 0)
+	for _, r := range recs {
+		if r.Addr.Compare(addr) == 0 && r.Point.Start.After(from) {
+			points = append(points, r.Point)
+		}
+	}
+	return points, nil
+}
+
+// readArchivedNetflows loads all archived flows to or from the addr
+func (cs *Store) readArchivedNetflows(addr model.Addr) ([]model.IpFlow, error) {
+	recs, err := readArchive[model.IpFlow](cs.archiveDirectory, archiveKindFlows, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	flows := make([]model.IpFlow, 0)
+	for _, f := range recs {
+		if f.SrcAddr.Compare(addr) == 0 || f.DstAddr.Compare(addr) == 0 {
+			flows = append(flows, f)
+		}
+	}
+	return flows, nil
+}
+
+func selectPerformancePingsBefore(
+	conn *sqlite.Conn,
+	cutoff time.Time,
+) (pings []archivedPing, err error) {
+	stmt, err := conn.Prepare(
+		`select
+      start, addr, minimum, average, maximum, loss, jitter, p95, p99, consecutivefailures
+    from performancepings
+    where start < :cutoff
+    order by start`)
+	if err != nil {
+		return pings, err
+	}
+	stmt.SetText(":cutoff", cutoff.Format(time.RFC3339Nano))
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return pings, err
+		}
+		if !hasRow {
+			break
+		}
+		p := archivedPing{
+			Point: pinger.Point{
+				Minimum: time.Duration(stmt.GetInt64("minimum")),
+				Average: time.Duration(stmt.GetInt64("average")),
+				Maximum: time.Duration(stmt.GetInt64("maximum")),
+				Loss:    stmt.GetFloat("loss"),
+
+				Jitter:              time.Duration(stmt.GetInt64("jitter")),
+				P95:                 time.Duration(stmt.GetInt64("p95")),
+				P99:                 time.Duration(stmt.GetInt64("p99")),
+				ConsecutiveFailures: int(stmt.GetInt64("consecutivefailures")),
+			},
+		}
+		p.Point.Start, err = time.Parse(time.RFC3339Nano, stmt.GetText("start"))
+		if err != nil {
+			return pings, err
+		}
+		p.Addr, err = model.ParseAddr(stmt.GetText("addr"))
+		if err != nil {
+			return pings, err
+		}
+		pings = append(pings, p)
+	}
+	return pings, nil
+}
+
+func selectNetflowsBefore(conn *sqlite.Conn, cutoff time.Time) (flows []model.IpFlow, err error) {
+	stmt, err := conn.Prepare(
+		`SELECT start, end, srcaddr, srcport, srcasn, dstaddr, dstport, dstasn, protocol, bytes, packets
+     FROM flows
+    WHERE start < :cutoff
+    ORDER BY start`)
+	if err != nil {
+		return flows, err
+	}
+	stmt.SetText(":cutoff", cutoff.Format(time.RFC3339Nano))
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return flows, err
+		}
+		if !hasRow {
+			break
+		}
+		flow, err := scanNetflow(stmt)
+		if err != nil {
+			return flows, err
+		}
+		flows = append(flows, flow)
+	}
+	return flows, nil
+}
+
+// appendArchive adds the records to the daily archive files of the kind, records for a day
+// which has already been archived are appended to the existing file
+func appendArchive[T any](dir string, kind string, recs []T, ts func(T) time.Time) error {
+	days := make(map[string][]T)
+	for _, r := range recs {
+		fname := archiveFilename(dir, kind, ts(r))
+		days[fname] = append(days[fname], r)
+	}
+	for fname, dayrecs := range days {
+		if cachedb.Exists(fname) {
+			prev, err := cachedb.Read[T](fname)
+			if err != nil {
+				return err
+			}
+			dayrecs = append(prev, dayrecs...)
+		}
+		err := cachedb.Write(fname, dayrecs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readArchive returns the records of every daily archive file of the kind which can contain
+// data after from, files are read in date order
+func readArchive[T any](dir string, kind string, from time.Time) ([]T, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	recs := make([]T, 0)
+	for _, entry := range entries {
+		day, ok := archiveDay(kind, entry.Name())
+		if !ok || day.Add(24*time.Hour).Before(from) {
+			continue
+		}
+		dayrecs, err := cachedb.Read[T](filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return recs, err
+		}
+		recs = append(recs, dayrecs...)
+	}
+	return recs, nil
+}
+
+func archiveFilename(dir string, kind string, ts time.Time) string {
+	return cachedb.Filename(filepath.Join(dir, kind+"-"+ts.UTC().Format(time.DateOnly)))
+}
+
+func archiveDay(kind string, filename string) (time.Time, bool) {
+	name, ok := strings.CutPrefix(filename, kind+"-")
+	if !ok {
+		return time.Time{}, false
+	}
+	day, err := time.Parse(time.DateOnly, strings.TrimSuffix(name, filepath.Ext(name)))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return day, true
+}
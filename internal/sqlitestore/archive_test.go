@@ -0,0 +1,90 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+func TestSqliteStore_ArchiveTimeseries(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	dev := model.Device{Addr: model.MustParseAddr("192.168.86.1")}
+	other := model.MustParseAddr("1.1.1.1")
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	db.archiveAfter = 24 * time.Hour
+	db.archiveDirectory = filepath.Join(testdbdir, "archive")
+
+	for _, ts := range []time.Time{now.Add(-72 * time.Hour), now.Add(-48 * time.Hour), now} {
+		err := db.WritePerformancePing(
+			ctx,
+			ts,
+			dev,
+			nettools.Icmp4EchoResponseStatistics{Mean: time.Millisecond},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := db.AddNetflows(ctx, []model.IpFlow{
+		{SrcAddr: dev.Addr, DstAddr: other, Start: now.Add(-48 * time.Hour), Protocol: 6},
+		{SrcAddr: other, DstAddr: dev.Addr, Start: now, Protocol: 17},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := db.ArchiveTimeseries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("archived want: 3, got: %d", count)
+	}
+
+	hot, err := db.selectPerformancePings(ctx, dev.Addr, now.Add(-96*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hot) != 1 {
+		t.Errorf("hot points want: 1, got: %d", len(hot))
+	}
+	points, err := db.ReadPerformancePings(ctx, dev, 96*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 3 {
+		t.Errorf("points want: 3, got: %d", len(points))
+	}
+	points, err = db.ReadPerformancePings(ctx, dev, 60*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 2 {
+		t.Errorf("points in range want: 2, got: %d", len(points))
+	}
+
+	flows, err := db.GetNetflows(ctx, dev.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flows) != 2 {
+		t.Fatalf("flows want: 2, got: %d", len(flows))
+	}
+	if flows[0].Protocol != 6 || flows[1].Protocol != 17 {
+		t.Errorf("flow protocols mismatch: %s %s", flows[0].Protocol, flows[1].Protocol)
+	}
+}
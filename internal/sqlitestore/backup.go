@@ -0,0 +1,76 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/backup"
+)
+
+const (
+	snapshotFilename   = "mason.db"
+	snapshotArchiveDir = "archive"
+)
+
+// Snapshot writes a copy of the database into dir with VACUUM INTO, so the copy is consistent
+// while the store is in use, along with the archive files
+func (cs *Store) Snapshot(ctx context.Context, dir string) (int, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return 0, err
+	}
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cs.Pool.Put(conn)
+
+	version, err := schemaVersion(conn)
+	if err != nil {
+		return 0, err
+	}
+	err = sqlitex.ExecuteTransient(conn, "VACUUM INTO ?;", &sqlitex.ExecOptions{
+		Args: []any{filepath.Join(dir, snapshotFilename)},
+	})
+	if err != nil {
+		return 0, err
+	}
+	_, err = backup.CopyFiles(
+		cs.archiveDirectory,
+		filepath.Join(dir, snapshotArchiveDir),
+		func(string) bool { return true },
+	)
+	return version, err
+}
+
+// Restore replaces the database and the archive files of the configuration with the snapshot
+// in dir
+func Restore(cfg *Config, dir string) error {
+	db := filepath.Join(cfg.Directory, cfg.Filename)
+	// the write ahead log of the replaced database must not be replayed onto the snapshot
+	for _, suffix := range []string{"-wal", "-shm"} {
+		err := os.Remove(db + suffix)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	err := backup.CopyFile(filepath.Join(dir, snapshotFilename), db)
+	if err != nil {
+		return err
+	}
+	_, err = backup.CopyFiles(
+		filepath.Join(dir, snapshotArchiveDir),
+		cfg.ArchiveDirectory,
+		func(string) bool { return true },
+	)
+	return err
+}
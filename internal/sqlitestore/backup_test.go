@@ -0,0 +1,72 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_SnapshotRestore(t *testing.T) {
+	ctx := context.Background()
+	dev := model.Device{Name: "router", Addr: model.MustParseAddr("192.168.86.1")}
+
+	db := createTestDatabase(t)
+	defer removeTestDatabase(t)
+	db.archiveDirectory = filepath.Join(testdbdir, "archive")
+	err := os.MkdirAll(db.archiveDirectory, 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(db.archiveDirectory, "pings-2024-01-01.gz"), []byte("x"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AddDevice(ctx, dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := filepath.Join(testdbdir, "snapshot")
+	version, err := db.Snapshot(ctx, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != SchemaVersion() {
+		t.Errorf("schema want: %d, got: %d", SchemaVersion(), version)
+	}
+	db.Close()
+
+	cfg := &Config{
+		Directory:          filepath.Join(testdbdir, "restored"),
+		Filename:           "restored.db",
+		MaxOpenConnections: 2,
+		ArchiveDirectory:   filepath.Join(testdbdir, "restored", "archive"),
+	}
+	err = Restore(cfg, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.Stat(filepath.Join(cfg.ArchiveDirectory, "pings-2024-01-01.gz"))
+	if err != nil {
+		t.Errorf("archive file not restored: %v", err)
+	}
+	restored, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	got, err := restored.GetDeviceByAddr(ctx, dev.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != dev.Name {
+		t.Errorf("restored device want: %s, got: %s", dev.Name, got.Name)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// AddDeviceChanges stores the device changes
+func (cs *Store) AddDeviceChanges(ctx context.Context, changes []model.DeviceChange) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	for _, c := range changes {
+		err = insertDeviceChange(conn, c)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertDeviceChange(conn *sqlite.Conn, c model.DeviceChange) error {
+	stmt, err := conn.Prepare(
+		`insert into devicechanges (time, addr, field, old, new, source)
+    values (:time, :addr, :field, :old, :new, :source)`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":time", c.Time.Format(time.RFC3339Nano))
+	stmt.SetText(":addr", c.Addr.String())
+	stmt.SetText(":field", c.Field)
+	stmt.SetText(":old", c.Old)
+	stmt.SetText(":new", c.New)
+	stmt.SetText(":source", string(c.Source))
+
+	_, err = stmt.Step()
+	return err
+}
+
+// ReadDeviceChanges returns the change history of the addr, newest first
+func (cs *Store) ReadDeviceChanges(
+	ctx context.Context,
+	addr model.Addr,
+) (changes []model.DeviceChange, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      time, addr, field, old, new, source
+    from devicechanges
+    where addr = :addr
+    order by time desc, rowid desc`)
+	if err != nil {
+		return changes, err
+	}
+	stmt.SetText(":addr", addr.String())
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return changes, err
+		}
+		if !hasRow {
+			break
+		}
+		c := model.DeviceChange{
+			Field:  stmt.GetText("field"),
+			Old:    stmt.GetText("old"),
+			New:    stmt.GetText("new"),
+			Source: model.ChangeSource(stmt.GetText("source")),
+		}
+		c.Addr, err = model.ParseAddr(stmt.GetText("addr"))
+		if err != nil {
+			return changes, err
+		}
+		c.Time, err = time.Parse(time.RFC3339Nano, stmt.GetText("time"))
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
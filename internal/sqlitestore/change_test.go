@@ -0,0 +1,75 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_DeviceChanges(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+	addr := model.MustParseAddr("192.168.86.1")
+
+	mac := model.DeviceChange{
+		Time:   now.Add(-time.Hour),
+		Addr:   addr,
+		Field:  "MAC",
+		Old:    "00:11:22:33:44:55",
+		New:    "00:11:22:33:44:66",
+		Source: model.ChangeSourceDiscovery,
+	}
+	ports := model.DeviceChange{
+		Time:   now,
+		Addr:   addr,
+		Field:  "Ports",
+		Old:    "22",
+		New:    "22 80",
+		Source: model.ChangeSourceEnrichment,
+	}
+	other := model.DeviceChange{
+		Time:   now,
+		Addr:   model.MustParseAddr("192.168.86.2"),
+		Field:  "DnsName",
+		New:    "printer",
+		Source: model.ChangeSourceEnrichment,
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	err := db.AddDeviceChanges(ctx, []model.DeviceChange{mac, other})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AddDeviceChanges(ctx, []model.DeviceChange{ports})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.ReadDeviceChanges(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff(
+		[]model.DeviceChange{ports, mac},
+		got,
+		cmpopts.EquateComparable(netip.Addr{}),
+		cmpopts.EquateApproxTime(time.Millisecond),
+	)
+	if diff != "" {
+		t.Errorf("DeviceChanges mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -21,6 +21,8 @@ type Config struct {
 	MaxIdleConnections    int
 	ConnectionMaxLifetime time.Duration
 	ConnectionMaxIdle     time.Duration
+	ArchiveAfter          time.Duration
+	ArchiveDirectory      string
 }
 
 func SetFlags(fs *pflag.FlagSet, cfg *Config) {
@@ -90,4 +92,20 @@ func SetFlags(fs *pflag.FlagSet, cfg *Config) {
 		time.Hour,
 		"max time a connection can be idle",
 	)
+	flagset.Duration(
+		fs,
+		&cfg.ArchiveAfter,
+		configMajorKey,
+		"archiveafter",
+		30*24*time.Hour,
+		"move ping and flow data older than this into compressed archive files, 0 to keep everything in the database",
+	)
+	flagset.String(
+		fs,
+		&cfg.ArchiveDirectory,
+		configMajorKey,
+		"archivedirectory",
+		"data/archive",
+		"directory to store timeseries archive files",
+	)
 }
@@ -0,0 +1,133 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"fmt"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// CheckConsistency looks for ping data of addrs which are not a device (or a deleted device
+// which can still be restored) and flows referencing asns missing from the asn table.  With
+// repair the orphaned ping rows are deleted and the unknown asns are cleared from the flows.
+func (cs *Store) CheckConsistency(
+	ctx context.Context,
+	repair bool,
+) (issues []model.ConsistencyIssue, err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	orphans, err := checkOrphanedPings(conn, repair)
+	if err != nil {
+		return issues, err
+	}
+	issues = append(issues, orphans...)
+
+	asns, err := checkUnknownAsns(conn, repair)
+	if err != nil {
+		return issues, err
+	}
+	return append(issues, asns...), nil
+}
+
+const orphanedPingsWhere = `addr not in (select addr from devices)
+    and addr not in (select key from tombstones where kind = 'device')`
+
+func checkOrphanedPings(conn *sqlite.Conn, repair bool) ([]model.ConsistencyIssue, error) {
+	issues := make([]model.ConsistencyIssue, 0)
+	err := sqlitex.Execute(
+		conn,
+		`select addr, count(*) as points from performancepings
+    where `+orphanedPingsWhere+`
+    group by addr`,
+		&sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				issues = append(issues, model.ConsistencyIssue{
+					Check:  model.ConsistencyOrphanedTimeseries,
+					Ref:    stmt.GetText("addr"),
+					Detail: fmt.Sprintf("%d ping points for an unknown device", stmt.GetInt64("points")),
+				})
+				return nil
+			},
+		},
+	)
+	if err != nil || !repair || len(issues) == 0 {
+		return issues, err
+	}
+	err = sqlitex.Execute(conn, `delete from performancepings where `+orphanedPingsWhere, nil)
+	if err != nil {
+		return issues, err
+	}
+	for idx := range issues {
+		issues[idx].Repaired = true
+		issues[idx].Action = "deleted"
+	}
+	return issues, nil
+}
+
+func checkUnknownAsns(conn *sqlite.Conn, repair bool) ([]model.ConsistencyIssue, error) {
+	issues := make([]model.ConsistencyIssue, 0)
+	// without a loaded asn table every asn would be unknown
+	loaded := false
+	err := sqlitex.Execute(conn, `select 1 from asns limit 1`, &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			loaded = true
+			return nil
+		},
+	})
+	if err != nil || !loaded {
+		return issues, err
+	}
+
+	err = sqlitex.Execute(
+		conn,
+		`select asn, count(*) as flows from (
+      select srcasn as asn from flows union all select dstasn as asn from flows
+    )
+    where asn != '' and asn not in (select asn from asns)
+    group by asn`,
+		&sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				issues = append(issues, model.ConsistencyIssue{
+					Check:  model.ConsistencyUnknownAsn,
+					Ref:    stmt.GetText("asn"),
+					Detail: fmt.Sprintf("referenced by %d flows", stmt.GetInt64("flows")),
+				})
+				return nil
+			},
+		},
+	)
+	if err != nil || !repair || len(issues) == 0 {
+		return issues, err
+	}
+	for _, col := range []string{"srcasn", "dstasn"} {
+		err = sqlitex.Execute(
+			conn,
+			`update flows set `+col+` = ''
+      where `+col+` != '' and `+col+` not in (select asn from asns)`,
+			nil,
+		)
+		if err != nil {
+			return issues, err
+		}
+	}
+	for idx := range issues {
+		issues[idx].Repaired = true
+		issues[idx].Action = "cleared from flows"
+	}
+	return issues, nil
+}
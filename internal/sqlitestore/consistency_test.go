@@ -0,0 +1,90 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+func TestSqliteStore_CheckConsistency(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	dev := model.Device{Addr: model.MustParseAddr("192.168.86.1")}
+	gone := model.Device{Addr: model.MustParseAddr("192.168.86.2")}
+	other := model.MustParseAddr("1.1.1.1")
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	err := db.AddDevice(ctx, dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []model.Device{dev, gone} {
+		err = db.WritePerformancePing(ctx, now, d, nettools.Icmp4EchoResponseStatistics{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = db.UpsertAsn(ctx, model.Asn{Asn: "13335", Name: "cloudflare"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AddNetflows(ctx, []model.IpFlow{
+		{SrcAddr: dev.Addr, DstAddr: other, DstASN: "13335", Start: now, Protocol: 6},
+		{SrcAddr: other, SrcASN: "64512", DstAddr: dev.Addr, Start: now, Protocol: 6},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []model.ConsistencyIssue{
+		{
+			Check:  model.ConsistencyOrphanedTimeseries,
+			Ref:    gone.Addr.String(),
+			Detail: "1 ping points for an unknown device",
+		},
+		{
+			Check:  model.ConsistencyUnknownAsn,
+			Ref:    "64512",
+			Detail: "referenced by 1 flows",
+		},
+	}
+	got, err := db.CheckConsistency(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff(want, got)
+	if diff != "" {
+		t.Errorf("check mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = db.CheckConsistency(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for idx := range got {
+		if !got[idx].Repaired {
+			t.Errorf("issue not repaired: %s", got[idx])
+		}
+	}
+
+	got, err = db.CheckConsistency(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("issues remain after repair: %v", got)
+	}
+}
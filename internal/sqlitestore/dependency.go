@@ -0,0 +1,101 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// UpsertDependency adds the dependency or replaces the existing one with the same name
+func (cs *Store) UpsertDependency(ctx context.Context, dep model.Dependency) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	stmt, err := conn.Prepare(
+		`insert into dependencies (name, parent, scope, target, note)
+    values (:name, :parent, :scope, :target, :note)
+    on conflict (name) do update set
+      parent=:parent, scope=:scope, target=:target, note=:note`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":name", dep.Name)
+	stmt.SetText(":parent", dep.Parent.String())
+	stmt.SetText(":scope", string(dep.Scope))
+	stmt.SetText(":target", dep.Target)
+	stmt.SetText(":note", dep.Note)
+
+	_, err = stmt.Step()
+	return err
+}
+
+// RemoveDependency deletes the named dependency
+func (cs *Store) RemoveDependency(ctx context.Context, name string) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from dependencies where name = :name`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":name", name)
+	_, err = stmt.Step()
+	if err != nil {
+		return err
+	}
+	if conn.Changes() == 0 {
+		return model.ErrDependencyDoesNotExist
+	}
+	return nil
+}
+
+// ListDependencies returns all dependencies ordered by name
+func (cs *Store) ListDependencies(ctx context.Context) (deps []model.Dependency, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      name, parent, scope, target, note
+    from dependencies
+    order by name`)
+	if err != nil {
+		return deps, err
+	}
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return deps, err
+		}
+		if !hasRow {
+			break
+		}
+		dep := model.Dependency{
+			Name:   stmt.GetText("name"),
+			Scope:  model.DependencyScope(stmt.GetText("scope")),
+			Target: stmt.GetText("target"),
+			Note:   stmt.GetText("note"),
+		}
+		dep.Parent, err = model.ParseAddr(stmt.GetText("parent"))
+		if err != nil {
+			return deps, err
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
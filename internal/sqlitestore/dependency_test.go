@@ -0,0 +1,69 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_Dependencies(t *testing.T) {
+	ctx := context.Background()
+	office := model.Dependency{
+		Name:   "office",
+		Parent: model.MustParseAddr("192.168.1.1"),
+		Scope:  model.DependencyScopeNetwork,
+		Target: "office",
+		Note:   "behind the router",
+	}
+	rack := model.Dependency{
+		Name:   "rack",
+		Parent: model.MustParseAddr("192.168.1.2"),
+		Scope:  model.DependencyScopeTag,
+		Target: "rack1",
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, dep := range []model.Dependency{rack, office} {
+		err := db.UpsertDependency(ctx, dep)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	rack.Parent = model.MustParseAddr("192.168.1.3")
+	err := db.UpsertDependency(ctx, rack)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.ListDependencies(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff([]model.Dependency{office, rack}, got, cmpopts.EquateComparable(netip.Addr{}))
+	if diff != "" {
+		t.Errorf("dependencies mismatch (-want +got):\n%s", diff)
+	}
+
+	err = db.RemoveDependency(ctx, rack.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.RemoveDependency(ctx, rack.Name)
+	if !errors.Is(err, model.ErrDependencyDoesNotExist) {
+		t.Errorf("remove missing want: %v, got: %v", model.ErrDependencyDoesNotExist, err)
+	}
+}
@@ -11,31 +11,39 @@ import (
 	"time"
 
 	"zombiezen.com/go/sqlite"
-	"zombiezen.com/go/sqlite/sqlitex"
 
 	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/tracing"
 )
 
 // AddDevice adds a device to the store, will return error if the device already exists
 func (cs *Store) AddDevice(ctx context.Context, newdevice model.Device) error {
-	for _, d := range cs.devices {
-		if d.Addr.Compare(newdevice.Addr) == 0 {
-			return model.ErrDeviceExists
-		}
+	cs.deviceLock.Lock()
+	defer cs.deviceLock.Unlock()
+	if _, ok := cs.deviceIndex[newdevice.Addr]; ok {
+		return model.ErrDeviceExists
 	}
+	cs.deviceIndex[newdevice.Addr] = len(cs.devices)
 	cs.devices = append(cs.devices, newdevice)
-	return cs.saveDevices(ctx)
+	cs.indexDevice(newdevice)
+	return cs.saveDevice(ctx, newdevice)
 }
 
 // RemoveDeviceByAddr will remove the device with the given Addr from the store
 func (cs *Store) RemoveDeviceByAddr(ctx context.Context, addr model.Addr) error {
-	for idx, device := range cs.devices {
-		if device.Addr.Compare(addr) == 0 {
-			cs.devices = slices.Delete(cs.devices, idx, idx+1)
-			return cs.saveDevices(ctx)
-		}
+	cs.deviceLock.Lock()
+	defer cs.deviceLock.Unlock()
+	idx, ok := cs.deviceIndex[addr]
+	if !ok {
+		return model.ErrDeviceDoesNotExist
 	}
-	return model.ErrDeviceDoesNotExist
+	cs.unindexDevice(cs.devices[idx])
+	cs.devices = slices.Delete(cs.devices, idx, idx+1)
+	delete(cs.deviceIndex, addr)
+	for ; idx < len(cs.devices); idx++ {
+		cs.deviceIndex[cs.devices[idx].Addr] = idx
+	}
+	return cs.deleteDevice(ctx, addr)
 }
 
 // UpdateDevice will fresnen up the device using the given device
@@ -46,14 +54,58 @@ func (cs *Store) UpdateDevice(
 	// if !newdevice.IsUpdated() {
 	// 	return enrich, nil
 	// }
-	for idx, device := range cs.devices {
-		if device.Addr.Compare(newdevice.Addr) == 0 {
-			enrich = !newdevice.MAC.IsEmpty() && device.MAC.Compare(newdevice.MAC) != 0
-			cs.devices[idx] = cs.devices[idx].Merge(newdevice)
-			return enrich, cs.saveDevices(ctx)
-		}
-	}
-	return enrich, model.ErrDeviceDoesNotExist
+	err = cs.changeDevice(ctx, newdevice.Addr, func(device *model.Device) {
+		enrich = !newdevice.MAC.IsEmpty() && device.MAC.Compare(newdevice.MAC) != 0
+		*device = device.Merge(newdevice)
+	})
+	return enrich, err
+}
+
+// SetDeviceTags replaces the tags of the device
+func (cs *Store) SetDeviceTags(ctx context.Context, addr model.Addr, tags model.Tags) error {
+	return cs.changeDevice(ctx, addr, func(device *model.Device) {
+		device.Meta.Tags = slices.Clone(tags)
+	})
+}
+
+// SetDeviceParent sets the host the device runs on as a guest, an empty addr clears it
+func (cs *Store) SetDeviceParent(ctx context.Context, addr model.Addr, parent model.Addr) error {
+	return cs.changeDevice(ctx, addr, func(device *model.Device) {
+		device.Virtual.Parent = parent
+	})
+}
+
+// SetDevicePolicy replaces the monitoring policy of the device
+func (cs *Store) SetDevicePolicy(
+	ctx context.Context,
+	addr model.Addr,
+	policy model.MonitoringPolicy,
+) error {
+	return cs.changeDevice(ctx, addr, func(device *model.Device) {
+		device.Meta.Policy = policy
+	})
+}
+
+// SetDeviceApproval replaces the approval state of the device
+func (cs *Store) SetDeviceApproval(
+	ctx context.Context,
+	addr model.Addr,
+	state model.ApprovalState,
+) error {
+	return cs.changeDevice(ctx, addr, func(device *model.Device) {
+		device.Meta.Approval = state
+	})
+}
+
+// SetDeviceDetails replaces the name, owner, notes and asset fields of the device
+func (cs *Store) SetDeviceDetails(
+	ctx context.Context,
+	addr model.Addr,
+	details model.DeviceDetails,
+) error {
+	return cs.changeDevice(ctx, addr, func(device *model.Device) {
+		*device = device.WithDetails(details)
+	})
 }
 
 // GetDeviceByAddr returns the device with the matching Addr
@@ -61,12 +113,39 @@ func (cs *Store) GetDeviceByAddr(
 	ctx context.Context,
 	addr model.Addr,
 ) (model.Device, error) {
-	for _, d := range cs.devices {
-		if d.Addr.Compare(addr) == 0 {
-			return d, nil
-		}
+	cs.deviceLock.RLock()
+	defer cs.deviceLock.RUnlock()
+	idx, ok := cs.deviceIndex[addr]
+	if !ok {
+		return model.Device{}, model.ErrDeviceDoesNotExist
+	}
+	return cs.devices[idx], nil
+}
+
+// GetDeviceByMAC returns the first stored device seen with the MAC
+func (cs *Store) GetDeviceByMAC(ctx context.Context, mac model.MAC) (model.Device, error) {
+	cs.deviceLock.RLock()
+	defer cs.deviceLock.RUnlock()
+	if mac.IsEmpty() {
+		return model.Device{}, model.ErrDeviceDoesNotExist
+	}
+	addrs, ok := cs.macIndex[mac.String()]
+	if !ok {
+		return model.Device{}, model.ErrDeviceDoesNotExist
+	}
+	return cs.devices[cs.deviceIndex[addrs[0]]], nil
+}
+
+// FindDevicesByName returns the devices named name, ignoring case
+func (cs *Store) FindDevicesByName(ctx context.Context, name string) []model.Device {
+	cs.deviceLock.RLock()
+	defer cs.deviceLock.RUnlock()
+	addrs := cs.nameIndex[strings.ToLower(name)]
+	devices := make([]model.Device, 0, len(addrs))
+	for _, addr := range addrs {
+		devices = append(devices, cs.devices[cs.deviceIndex[addr]])
 	}
-	return model.Device{}, model.ErrDeviceDoesNotExist
+	return devices
 }
 
 // GetFilteredDevices returns the devices which match the given GetFilteredDevices
@@ -74,6 +153,8 @@ func (cs *Store) GetFilteredDevices(
 	ctx context.Context,
 	filter model.DeviceFilter,
 ) []model.Device {
+	cs.deviceLock.RLock()
+	defer cs.deviceLock.RUnlock()
 	devices := make([]model.Device, 0)
 	for _, n := range cs.devices {
 		if filter(n) {
@@ -83,33 +164,108 @@ func (cs *Store) GetFilteredDevices(
 	return devices
 }
 
+// QueryDevices returns one page of the devices matching the query
+func (cs *Store) QueryDevices(ctx context.Context, q model.DeviceQuery) model.DevicePage {
+	cs.deviceLock.RLock()
+	defer cs.deviceLock.RUnlock()
+	return model.QueryDevices(cs.devices, q)
+}
+
 // ListDevices returns all the stored devices
 func (cs *Store) ListDevices(ctx context.Context) []model.Device {
+	cs.deviceLock.RLock()
+	defer cs.deviceLock.RUnlock()
 	return slices.Clone(cs.devices)
 }
 
 // CountDevices return the number of devices in the store
 func (cs *Store) CountDevices(ctx context.Context) int {
+	cs.deviceLock.RLock()
+	defer cs.deviceLock.RUnlock()
 	return len(cs.devices)
 }
 
-func (cs *Store) saveDevices(ctx context.Context) (err error) {
-	conn, err := cs.Pool.Get(ctx)
+// changeDevice applies the change to the cached device and writes only that device, the
+// lock is held through the write so concurrent changes reach the database in order
+func (cs *Store) changeDevice(
+	ctx context.Context,
+	addr model.Addr,
+	change func(*model.Device),
+) error {
+	cs.deviceLock.Lock()
+	defer cs.deviceLock.Unlock()
+	idx, ok := cs.deviceIndex[addr]
+	if !ok {
+		return model.ErrDeviceDoesNotExist
+	}
+	prev := cs.devices[idx]
+	change(&cs.devices[idx])
+	if macsString(prev.MACs()) != macsString(cs.devices[idx].MACs()) ||
+		prev.Name != cs.devices[idx].Name {
+		cs.unindexDevice(prev)
+		cs.indexDevice(cs.devices[idx])
+	}
+	return cs.saveDevice(ctx, cs.devices[idx])
+}
+
+// indexDevice adds the device to the mac and name lookups, the device lock must be held
+func (cs *Store) indexDevice(device model.Device) {
+	for _, mac := range device.MACs() {
+		key := mac.String()
+		cs.macIndex[key] = append(cs.macIndex[key], device.Addr)
+	}
+	if device.Name != "" {
+		key := strings.ToLower(device.Name)
+		cs.nameIndex[key] = append(cs.nameIndex[key], device.Addr)
+	}
+}
+
+// unindexDevice removes the device from the mac and name lookups, the device lock must be held
+func (cs *Store) unindexDevice(device model.Device) {
+	for _, mac := range device.MACs() {
+		removeIndexAddr(cs.macIndex, mac.String(), device.Addr)
+	}
+	removeIndexAddr(cs.nameIndex, strings.ToLower(device.Name), device.Addr)
+}
+
+func removeIndexAddr(index map[string][]model.Addr, key string, addr model.Addr) {
+	addrs := slices.DeleteFunc(index[key], func(a model.Addr) bool { return a == addr })
+	if len(addrs) == 0 {
+		delete(index, key)
+		return
+	}
+	index[key] = addrs
+}
+
+func (cs *Store) saveDevice(ctx context.Context, device model.Device) (err error) {
+	ctx, span := tracing.Start(ctx, "sqlite.savedevice", tracing.AddrKey.String(device.Addr.String()))
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := cs.Pool.Take(ctx)
 	if err != nil {
 		return err
 	}
-	fn := sqlitex.Transaction(conn)
-	defer func() {
-		fn(&err)
-		cs.Pool.Put(conn)
-	}()
-	for _, device := range cs.devices {
-		err = upsertDevice(conn, device)
-		if err != nil {
-			return err
-		}
+	defer cs.Pool.Put(conn)
+	return upsertDevice(conn, device)
+}
+
+func (cs *Store) deleteDevice(ctx context.Context, addr model.Addr) (err error) {
+	ctx, span := tracing.Start(ctx, "sqlite.deletedevice", tracing.AddrKey.String(addr.String()))
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
 	}
-	return nil
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from devices where addr = :addr`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":addr", addr.String())
+	_, err = stmt.Step()
+	return err
 }
 
 func (cs *Store) readDevicesInitial(ctx context.Context) (err error) {
@@ -120,19 +276,41 @@ func (cs *Store) readDevicesInitial(ctx context.Context) (err error) {
 	return err
 }
 
-func (cs *Store) readDevices(ctx context.Context) (err error) {
-	cs.devices, err = cs.selectDevices(ctx)
-	return err
+func (cs *Store) readDevices(ctx context.Context) error {
+	devices, err := cs.selectDevices(ctx)
+	if err != nil {
+		return err
+	}
+	cs.deviceLock.Lock()
+	defer cs.deviceLock.Unlock()
+	cs.devices = devices
+	cs.deviceIndex = make(map[model.Addr]int, len(devices))
+	cs.macIndex = make(map[string][]model.Addr)
+	cs.nameIndex = make(map[string][]model.Addr)
+	for idx, d := range devices {
+		cs.deviceIndex[d.Addr] = idx
+		cs.indexDevice(d)
+	}
+	return nil
 }
 
 func (cs *Store) selectDevices(ctx context.Context) (devices []model.Device, err error) {
 	stmt, err := cs.DB.Prepare(
 		`SELECT 
-      name, addr, mac, discoveredat, discoveredby,
+      name, addr, addresses, mac, observedmacs, discoveredat, discoveredby, vlanid, vlanname,
       metadnsname AS "meta.dnsname", metamanufacturer AS "meta.manufacturer", metatags AS "meta.tags",
-      serverports AS "server.ports", serverlastscan AS "server.lastscan",
-      perfpingfirstseen AS "performanceping.firstseen", perfpinglastseen AS "performanceping.lastseen", perfpingmeanping AS "performanceping.mean", perfpingmaxping AS "performanceping.maximum", perfpinglastfailed AS "performanceping.lastfailed",
-      snmpname AS "snmp.name", snmpdescription AS "snmp.description", snmpcommunity AS "snmp.community", snmpport AS "snmp.port", snmplastcheck AS "snmp.lastsnmpcheck", snmphasarptable AS "snmp.hasarptable", snmplastarptablescan AS "snmp.lastarptablescan", snmphasinterfaces AS "snmp.hasinterfaces", snmplastinterfacesscan AS "snmp.lastinterfacesscan"
+      metapolicyping AS "meta.policyping", metapolicyportscan AS "meta.policyportscan",
+      metaapproval AS "meta.approval", metaowner AS "meta.owner", metanotes AS "meta.notes",
+      metasite AS "meta.site", metamdnsname AS "meta.mdnsname",
+      metadhcphostname AS "meta.dhcphostname", metadhcpfingerprint AS "meta.dhcpfingerprint",
+      metadhcpvendorclass AS "meta.dhcpvendorclass", metadevicetype AS "meta.devicetype",
+      metalocation AS "meta.location", metapurchasedate AS "meta.purchasedate",
+      metaserial AS "meta.serial", metaassettag AS "meta.assettag",
+      serverports AS "server.ports", serverbanners AS "server.banners", serverlastscan AS "server.lastscan",
+      perfpingfirstseen AS "performanceping.firstseen", perfpinglastseen AS "performanceping.lastseen", perfpingmeanping AS "performanceping.mean", perfpingmaxping AS "performanceping.maximum", perfpinglastfailed AS "performanceping.lastfailed", perfpingprobe AS "performanceping.probe",
+      snmpname AS "snmp.name", snmpdescription AS "snmp.description", snmpcommunity AS "snmp.community", snmpport AS "snmp.port", snmplastcheck AS "snmp.lastsnmpcheck", snmphasarptable AS "snmp.hasarptable", snmplastarptablescan AS "snmp.lastarptablescan", snmphasinterfaces AS "snmp.hasinterfaces", snmplastinterfacesscan AS "snmp.lastinterfacesscan",
+      virtualplatform AS "virtual.platform", virtualguests AS "virtual.guests", virtuallastscan AS "virtual.lastscan", virtualparent AS "virtual.parent",
+      link
     FROM devices`,
 	)
 	if err != nil {
@@ -150,14 +328,36 @@ func (cs *Store) selectDevices(ctx context.Context) (devices []model.Device, err
 		}
 		device := model.Device{
 			Name: stmt.GetText("name"),
+			VLAN: model.VLAN{
+				ID:   int(stmt.GetInt64("vlanid")),
+				Name: stmt.GetText("vlanname"),
+			},
 			Meta: model.Meta{
-				DnsName:      stmt.GetText("meta.dnsname"),
-				Manufacturer: stmt.GetText("meta.manufacturer"),
+				DnsName:         stmt.GetText("meta.dnsname"),
+				Manufacturer:    stmt.GetText("meta.manufacturer"),
+				Approval:        model.ApprovalState(stmt.GetText("meta.approval")),
+				Owner:           stmt.GetText("meta.owner"),
+				Notes:           stmt.GetText("meta.notes"),
+				Site:            stmt.GetText("meta.site"),
+				MDNSName:        stmt.GetText("meta.mdnsname"),
+				DHCPHostname:    stmt.GetText("meta.dhcphostname"),
+				DHCPFingerprint: stmt.GetText("meta.dhcpfingerprint"),
+				DHCPVendorClass: stmt.GetText("meta.dhcpvendorclass"),
+				DeviceType:      model.DeviceType(stmt.GetText("meta.devicetype")),
+				Location:        stmt.GetText("meta.location"),
+				PurchaseDate:    stmt.GetText("meta.purchasedate"),
+				Serial:          stmt.GetText("meta.serial"),
+				AssetTag:        stmt.GetText("meta.assettag"),
+				Policy: model.MonitoringPolicy{
+					PingInterval:     time.Duration(stmt.GetInt64("meta.policyping")),
+					PortScanInterval: time.Duration(stmt.GetInt64("meta.policyportscan")),
+				},
 			},
 			PerformancePing: model.Pinger{
 				LastFailed: stmt.GetBool("performanceping.lastfailed"),
 				Mean:       time.Duration(stmt.GetInt64("performanceping.mean")),
 				Maximum:    time.Duration(stmt.GetInt64("performanceping.maximum")),
+				Probe:      model.PingProbe(stmt.GetText("performanceping.probe")),
 			},
 			SNMP: model.SNMP{
 				Name:          stmt.GetText("snmp.name"),
@@ -167,15 +367,26 @@ func (cs *Store) selectDevices(ctx context.Context) (devices []model.Device, err
 				HasArpTable:   stmt.GetBool("snmp.hasarptable"),
 				HasInterfaces: stmt.GetBool("snmp.hasinterfaces"),
 			},
+			Virtual: model.Virtual{
+				Platform: model.VirtualPlatform(stmt.GetText("virtual.platform")),
+			},
 		}
 		err = device.Addr.Scan(stmt.GetText("addr"))
 		if err != nil {
 			return devices, err
 		}
+		err = device.Addresses.Scan(stmt.GetText("addresses"))
+		if err != nil {
+			return devices, err
+		}
 		err = device.MAC.Scan(stmt.GetText("mac"))
 		if err != nil {
 			return devices, err
 		}
+		device.ObservedMACs, err = scanMACs(stmt.GetText("observedmacs"))
+		if err != nil {
+			return devices, err
+		}
 		device.DiscoveredAt, err = time.Parse(time.RFC3339Nano, stmt.GetText("discoveredat"))
 		if err != nil {
 			return devices, err
@@ -193,6 +404,10 @@ func (cs *Store) selectDevices(ctx context.Context) (devices []model.Device, err
 		if err != nil {
 			return devices, err
 		}
+		err = device.Server.Banners.Scan(stmt.GetText("server.banners"))
+		if err != nil {
+			return devices, err
+		}
 		device.Server.LastScan, err = time.Parse(time.RFC3339Nano, stmt.GetText("server.lastscan"))
 		if err != nil {
 			return devices, err
@@ -233,6 +448,27 @@ func (cs *Store) selectDevices(ctx context.Context) (devices []model.Device, err
 		if err != nil {
 			return devices, err
 		}
+		err = device.Virtual.Guests.Scan(stmt.GetText("virtual.guests"))
+		if err != nil {
+			return devices, err
+		}
+		device.Virtual.LastScan, err = time.Parse(
+			time.RFC3339Nano,
+			stmt.GetText("virtual.lastscan"),
+		)
+		if err != nil {
+			return devices, err
+		}
+		if parent := stmt.GetText("virtual.parent"); parent != "" {
+			err = device.Virtual.Parent.Scan(parent)
+			if err != nil {
+				return devices, err
+			}
+		}
+		err = device.Link.Scan(stmt.GetText("link"))
+		if err != nil {
+			return devices, err
+		}
 
 		devices = append(devices, device)
 	}
@@ -243,46 +479,84 @@ func (cs *Store) selectDevices(ctx context.Context) (devices []model.Device, err
 func upsertDevice(conn *sqlite.Conn, d model.Device) error {
 	stmt, err := conn.Prepare(
 		`INSERT INTO devices (
-      name, addr, mac, discoveredat, discoveredby,
-      metadnsname, metamanufacturer, metatags,
-      serverports, serverlastscan,
-      perfpingfirstseen, perfpinglastseen, perfpingmeanping, perfpingmaxping, perfpinglastfailed,
-      snmpname, snmpdescription, snmpcommunity, snmpport, snmplastcheck, snmphasarptable, snmplastarptablescan, snmphasinterfaces, snmplastinterfacesscan
+      name, addr, addresses, mac, observedmacs, discoveredat, discoveredby, vlanid, vlanname,
+      metadnsname, metamanufacturer, metatags, metapolicyping, metapolicyportscan, metaapproval,
+      metaowner, metanotes, metasite, metamdnsname, metadhcphostname, metadhcpfingerprint,
+      metadhcpvendorclass, metadevicetype,
+      metalocation, metapurchasedate, metaserial, metaassettag,
+      serverports, serverbanners, serverlastscan,
+      perfpingfirstseen, perfpinglastseen, perfpingmeanping, perfpingmaxping, perfpinglastfailed, perfpingprobe,
+      snmpname, snmpdescription, snmpcommunity, snmpport, snmplastcheck, snmphasarptable, snmplastarptablescan, snmphasinterfaces, snmplastinterfacesscan,
+      virtualplatform, virtualguests, virtuallastscan, virtualparent,
+      link
     )
     VALUES (
-      :name, :addr, :mac, :discoveredat, :discoveredby,
-      :metadnsname, :metamanufacturer, :metatags,
-      :serverports, :serverlastscan,
-      :performancepingfirstseen, :performancepinglastseen, :performancepingmean, :performancepingmaximum, :performancepinglastfailed,
-      :snmpname, :snmpdescription, :snmpcommunity, :snmpport, :snmplastsnmpcheck, :snmphasarptable, :snmplastarptablescan, :snmphasinterfaces, :snmplastinterfacesscan
+      :name, :addr, :addresses, :mac, :observedmacs, :discoveredat, :discoveredby, :vlanid, :vlanname,
+      :metadnsname, :metamanufacturer, :metatags, :metapolicyping, :metapolicyportscan, :metaapproval,
+      :metaowner, :metanotes, :metasite, :metamdnsname, :metadhcphostname, :metadhcpfingerprint,
+      :metadhcpvendorclass, :metadevicetype,
+      :metalocation, :metapurchasedate, :metaserial, :metaassettag,
+      :serverports, :serverbanners, :serverlastscan,
+      :performancepingfirstseen, :performancepinglastseen, :performancepingmean, :performancepingmaximum, :performancepinglastfailed, :performancepingprobe,
+      :snmpname, :snmpdescription, :snmpcommunity, :snmpport, :snmplastsnmpcheck, :snmphasarptable, :snmplastarptablescan, :snmphasinterfaces, :snmplastinterfacesscan,
+      :virtualplatform, :virtualguests, :virtuallastscan, :virtualparent,
+      :link
     )
     ON CONFLICT (addr) DO UPDATE SET 
-      name=:name, addr=:addr, mac=:mac, discoveredat=:discoveredat, discoveredby=:discoveredby,
+      name=:name, addr=:addr, addresses=:addresses, mac=:mac, observedmacs=:observedmacs, discoveredat=:discoveredat, discoveredby=:discoveredby, vlanid=:vlanid, vlanname=:vlanname,
       metadnsname=:metadnsname, metamanufacturer=:metamanufacturer, metatags=:metatags,
-      serverports=:serverports, serverlastscan=:serverlastscan,
-      perfpingfirstseen=:performancepingfirstseen, perfpinglastseen=:performancepinglastseen, perfpingmeanping=:performancepingmean, perfpingmaxping=:performancepingmaximum, perfpinglastfailed=:performancepinglastfailed,
+      metapolicyping=:metapolicyping, metapolicyportscan=:metapolicyportscan, metaapproval=:metaapproval,
+      metaowner=:metaowner, metanotes=:metanotes, metasite=:metasite,
+      metamdnsname=:metamdnsname, metadhcphostname=:metadhcphostname, metadhcpfingerprint=:metadhcpfingerprint,
+      metadhcpvendorclass=:metadhcpvendorclass, metadevicetype=:metadevicetype,
+      metalocation=:metalocation, metapurchasedate=:metapurchasedate, metaserial=:metaserial, metaassettag=:metaassettag,
+      serverports=:serverports, serverbanners=:serverbanners, serverlastscan=:serverlastscan,
+      perfpingfirstseen=:performancepingfirstseen, perfpinglastseen=:performancepinglastseen, perfpingmeanping=:performancepingmean, perfpingmaxping=:performancepingmaximum, perfpinglastfailed=:performancepinglastfailed, perfpingprobe=:performancepingprobe,
       snmpname=:snmpname, snmpdescription=:snmpdescription, snmpcommunity=:snmpcommunity, snmpport=:snmpport, snmplastcheck=:snmplastsnmpcheck, 
       snmphasarptable=:snmphasarptable, snmplastarptablescan=:snmplastarptablescan, 
-      snmphasinterfaces=:snmphasinterfaces, snmplastinterfacesscan=:snmplastinterfacesscan
+      snmphasinterfaces=:snmphasinterfaces, snmplastinterfacesscan=:snmplastinterfacesscan,
+      virtualplatform=:virtualplatform, virtualguests=:virtualguests, virtuallastscan=:virtuallastscan, virtualparent=:virtualparent,
+      link=:link
     `)
 	if err != nil {
 		return err
 	}
 	stmt.SetText(":name", d.Name)
 	stmt.SetText(":addr", d.Addr.String())
+	stmt.SetText(":addresses", d.Addresses.String())
 	stmt.SetText(":mac", d.MAC.String())
+	stmt.SetText(":observedmacs", macsString(d.ObservedMACs))
 	stmt.SetText(":discoveredat", d.DiscoveredAt.Format(time.RFC3339Nano))
 	stmt.SetText(":discoveredby", d.DiscoveredBy.String())
+	stmt.SetInt64(":vlanid", int64(d.VLAN.ID))
+	stmt.SetText(":vlanname", d.VLAN.Name)
 	stmt.SetText(":metadnsname", d.Meta.DnsName)
 	stmt.SetText(":metamanufacturer", d.Meta.Manufacturer)
 	stmt.SetText(":metatags", d.Meta.Tags.String())
+	stmt.SetInt64(":metapolicyping", d.Meta.Policy.PingInterval.Nanoseconds())
+	stmt.SetInt64(":metapolicyportscan", d.Meta.Policy.PortScanInterval.Nanoseconds())
+	stmt.SetText(":metaapproval", string(d.Meta.Approval))
+	stmt.SetText(":metaowner", d.Meta.Owner)
+	stmt.SetText(":metanotes", d.Meta.Notes)
+	stmt.SetText(":metasite", d.Meta.Site)
+	stmt.SetText(":metamdnsname", d.Meta.MDNSName)
+	stmt.SetText(":metadhcphostname", d.Meta.DHCPHostname)
+	stmt.SetText(":metadhcpfingerprint", d.Meta.DHCPFingerprint)
+	stmt.SetText(":metadhcpvendorclass", d.Meta.DHCPVendorClass)
+	stmt.SetText(":metadevicetype", string(d.Meta.DeviceType))
+	stmt.SetText(":metalocation", d.Meta.Location)
+	stmt.SetText(":metapurchasedate", d.Meta.PurchaseDate)
+	stmt.SetText(":metaserial", d.Meta.Serial)
+	stmt.SetText(":metaassettag", d.Meta.AssetTag)
 	stmt.SetText(":serverports", d.Server.Ports.String())
+	stmt.SetText(":serverbanners", d.Server.Banners.String())
 	stmt.SetText(":serverlastscan", d.Server.LastScan.Format(time.RFC3339Nano))
 	stmt.SetText(":performancepingfirstseen", d.PerformancePing.FirstSeen.Format(time.RFC3339Nano))
 	stmt.SetText(":performancepinglastseen", d.PerformancePing.LastSeen.Format(time.RFC3339Nano))
 	stmt.SetInt64(":performancepingmean", d.PerformancePing.Mean.Nanoseconds())
 	stmt.SetInt64(":performancepingmaximum", d.PerformancePing.Maximum.Nanoseconds())
 	stmt.SetBool(":performancepinglastfailed", d.PerformancePing.LastFailed)
+	stmt.SetText(":performancepingprobe", string(d.PerformancePing.Probe))
 	stmt.SetText(":snmpname", d.SNMP.Name)
 	stmt.SetText(":snmpdescription", d.SNMP.Description)
 	stmt.SetText(":snmpcommunity", d.SNMP.Community)
@@ -292,7 +566,49 @@ func upsertDevice(conn *sqlite.Conn, d model.Device) error {
 	stmt.SetText(":snmplastarptablescan", d.SNMP.LastArpTableScan.Format(time.RFC3339Nano))
 	stmt.SetBool(":snmphasinterfaces", d.SNMP.HasInterfaces)
 	stmt.SetText(":snmplastinterfacesscan", d.SNMP.LastInterfacesScan.Format(time.RFC3339Nano))
+	stmt.SetText(":virtualplatform", string(d.Virtual.Platform))
+	stmt.SetText(":virtualguests", d.Virtual.Guests.String())
+	stmt.SetText(":virtuallastscan", d.Virtual.LastScan.Format(time.RFC3339Nano))
+	stmt.SetText(":virtualparent", parentString(d.Virtual.Parent))
+	link, err := d.Link.Value()
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":link", link.(string))
 
 	_, err = stmt.Step()
 	return err
 }
+
+// parentString leaves the virtualparent column empty for a device without a host
+func parentString(parent model.Addr) string {
+	if !parent.A.IsValid() {
+		return ""
+	}
+	return parent.String()
+}
+
+// macsString joins the macs for the observedmacs column
+func macsString(macs []model.MAC) string {
+	strs := make([]string, 0, len(macs))
+	for _, mac := range macs {
+		strs = append(strs, mac.String())
+	}
+	return strings.Join(strs, ",")
+}
+
+func scanMACs(s string) ([]model.MAC, error) {
+	if s == "" {
+		return nil, nil
+	}
+	macs := make([]model.MAC, 0)
+	for _, str := range strings.Split(s, ",") {
+		var mac model.MAC
+		err := mac.Scan(str)
+		if err != nil {
+			return nil, err
+		}
+		macs = append(macs, mac)
+	}
+	return macs, nil
+}
@@ -6,6 +6,7 @@ package sqlitestore
 
 import (
 	"context"
+	"errors"
 	"net/netip"
 	"testing"
 	"time"
@@ -68,8 +69,12 @@ func TestSqliteStore_AddDevice(t *testing.T) {
 		},
 		"fullmodel": {
 			input: model.Device{
-				Name:         "allmodel",
-				Addr:         model.MustParseAddr("1.2.3.4"),
+				Name: "allmodel",
+				Addr: model.MustParseAddr("1.2.3.4"),
+				Addresses: model.DeviceAddrs{
+					{Addr: model.MustParseAddr("2001:db8::1234"), LastSeen: ts},
+					{Addr: model.MustParseAddr("fe80::a255:99ff:fe4b:1fe2"), LastSeen: ts},
+				},
 				MAC:          model.MustParseMAC("a0:55:99:4b:1f:e2"),
 				DiscoveredAt: ts,
 				DiscoveredBy: discovery.ArpDiscoverySource,
@@ -77,9 +82,14 @@ func TestSqliteStore_AddDevice(t *testing.T) {
 					DnsName:      "allmodel.dns",
 					Manufacturer: "Acme Inc",
 					Tags:         model.Tags{model.RandomizedMacAddressTag},
+					Location:     "rack 2",
+					PurchaseDate: "2023-04-01",
+					Serial:       "SN123",
+					AssetTag:     "IT-0042",
 				},
 				Server: model.Server{
 					Ports:    model.PortList{Ports: []int{1, 2, 3, 4}},
+					Banners:  model.Banners{{Port: 1, Text: "SSH-2.0-OpenSSH_9.6"}},
 					LastScan: ts,
 				},
 				PerformancePing: model.Pinger{
@@ -88,6 +98,7 @@ func TestSqliteStore_AddDevice(t *testing.T) {
 					Mean:       time.Minute,
 					Maximum:    time.Hour,
 					LastFailed: true,
+					Probe:      model.PingProbeARP,
 				},
 				SNMP: model.SNMP{
 					Name:               "The Snmp Name",
@@ -102,8 +113,12 @@ func TestSqliteStore_AddDevice(t *testing.T) {
 				},
 			},
 			want: []model.Device{{
-				Name:         "allmodel",
-				Addr:         model.MustParseAddr("1.2.3.4"),
+				Name: "allmodel",
+				Addr: model.MustParseAddr("1.2.3.4"),
+				Addresses: model.DeviceAddrs{
+					{Addr: model.MustParseAddr("2001:db8::1234"), LastSeen: ts},
+					{Addr: model.MustParseAddr("fe80::a255:99ff:fe4b:1fe2"), LastSeen: ts},
+				},
 				MAC:          model.MustParseMAC("a0:55:99:4b:1f:e2"),
 				DiscoveredAt: ts,
 				DiscoveredBy: discovery.ArpDiscoverySource,
@@ -111,9 +126,14 @@ func TestSqliteStore_AddDevice(t *testing.T) {
 					DnsName:      "allmodel.dns",
 					Manufacturer: "Acme Inc",
 					Tags:         model.Tags{model.RandomizedMacAddressTag},
+					Location:     "rack 2",
+					PurchaseDate: "2023-04-01",
+					Serial:       "SN123",
+					AssetTag:     "IT-0042",
 				},
 				Server: model.Server{
 					Ports:    model.PortList{Ports: []int{1, 2, 3, 4}},
+					Banners:  model.Banners{{Port: 1, Text: "SSH-2.0-OpenSSH_9.6"}},
 					LastScan: ts,
 				},
 				PerformancePing: model.Pinger{
@@ -122,6 +142,7 @@ func TestSqliteStore_AddDevice(t *testing.T) {
 					Mean:       time.Minute,
 					Maximum:    time.Hour,
 					LastFailed: true,
+					Probe:      model.PingProbeARP,
 				},
 				SNMP: model.SNMP{
 					Name:               "The Snmp Name",
@@ -490,6 +511,13 @@ func TestSqliteStore_RemoveDeviceByAddr(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	err = db.readDevices(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.CountDevices(ctx) != 0 {
+		t.Errorf("removed device was read back from the database")
+	}
 
 	err = db.RemoveDeviceByAddr(ctx, model.MustParseAddr("192.168.100.1"))
 	if err == nil {
@@ -501,3 +529,425 @@ func TestSqliteStore_RemoveDeviceByAddr(t *testing.T) {
 		t.Fatalf("error mismatch (-want +got):\n%s", diff)
 	}
 }
+
+func TestSqliteStore_SetDevicePolicy(t *testing.T) {
+	ctx := context.Background()
+	addr := model.MustParseAddr("192.168.0.1")
+	policy := model.MonitoringPolicy{PingInterval: 15 * time.Second, PortScanInterval: time.Hour}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	err := db.AddDevice(ctx, model.Device{Name: "gateway", Addr: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.SetDevicePolicy(ctx, addr, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.readDevices(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := db.GetDeviceByAddr(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Meta.Policy != policy {
+		t.Errorf("policy want: %+v, got: %+v", policy, got.Meta.Policy)
+	}
+
+	err = db.SetDevicePolicy(ctx, model.MustParseAddr("192.168.100.1"), policy)
+	if !errors.Is(err, model.ErrDeviceDoesNotExist) {
+		t.Errorf("unknown device want: %v, got: %v", model.ErrDeviceDoesNotExist, err)
+	}
+}
+
+func TestSqliteStore_SetDeviceApproval(t *testing.T) {
+	ctx := context.Background()
+	addr := model.MustParseAddr("192.168.0.1")
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	err := db.AddDevice(
+		ctx,
+		model.Device{Name: "gateway", Addr: addr, Meta: model.Meta{Approval: model.ApprovalUnknown}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.SetDeviceApproval(ctx, addr, model.ApprovalBlocked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.readDevices(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := db.GetDeviceByAddr(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Meta.Approval != model.ApprovalBlocked {
+		t.Errorf("approval want: %s, got: %s", model.ApprovalBlocked, got.Meta.Approval)
+	}
+
+	err = db.SetDeviceApproval(ctx, model.MustParseAddr("192.168.100.1"), model.ApprovalApproved)
+	if !errors.Is(err, model.ErrDeviceDoesNotExist) {
+		t.Errorf("unknown device want: %v, got: %v", model.ErrDeviceDoesNotExist, err)
+	}
+}
+
+func TestSqliteStore_SetDeviceDetails(t *testing.T) {
+	ctx := context.Background()
+	addr := model.MustParseAddr("192.168.0.1")
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	err := db.AddDevice(
+		ctx,
+		model.Device{Name: "gateway", Addr: addr, Meta: model.Meta{Owner: "ops"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	details := model.DeviceDetails{
+		Name:         "router",
+		Notes:        "rack 2\nshelf 1",
+		Site:         "branch",
+		Location:     "comms room",
+		PurchaseDate: "2023-04-01",
+		Serial:       "SN123",
+		AssetTag:     "IT-0042",
+	}
+	err = db.SetDeviceDetails(ctx, addr, details)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.readDevices(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := db.GetDeviceByAddr(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Details() != details {
+		t.Errorf("details want: %+v, got: %+v", details, got.Details())
+	}
+
+	err = db.SetDeviceDetails(ctx, model.MustParseAddr("192.168.100.1"), details)
+	if !errors.Is(err, model.ErrDeviceDoesNotExist) {
+		t.Errorf("unknown device want: %v, got: %v", model.ErrDeviceDoesNotExist, err)
+	}
+}
+
+func TestSqliteStore_UpdateDeviceWritesOneRow(t *testing.T) {
+	ctx := context.Background()
+	first := model.Device{Name: "first", Addr: model.MustParseAddr("192.168.0.1")}
+	second := model.Device{Name: "second", Addr: model.MustParseAddr("192.168.0.2")}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, d := range []model.Device{first, second} {
+		err := db.AddDevice(ctx, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// change the second row behind the cache, an update of the first must not overwrite it
+	conn, err := db.Pool.Take(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := conn.Prepare(`update devices set name = 'outside' where addr = :addr`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt.SetText(":addr", second.Addr.String())
+	_, err = stmt.Step()
+	db.Pool.Put(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first.Name = "renamed"
+	_, err = db.UpdateDevice(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.readDevices(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0)
+	for _, d := range db.ListDevices(ctx) {
+		got = append(got, d.Name)
+	}
+	if diff := cmp.Diff([]string{"renamed", "outside"}, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSqliteStore_GetDeviceByMAC(t *testing.T) {
+	ctx := context.Background()
+	mac := model.MustParseMAC("a0:55:99:4b:1f:e2")
+	router := model.Device{Name: "router", Addr: model.MustParseAddr("192.168.0.1"), MAC: mac}
+	nomac := model.Device{Name: "nomac", Addr: model.MustParseAddr("192.168.0.2")}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, d := range []model.Device{router, nomac} {
+		err := db.AddDevice(ctx, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := db.GetDeviceByMAC(ctx, mac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Addr != router.Addr {
+		t.Errorf("want: %s, got: %s", router.Addr, got.Addr)
+	}
+	_, err = db.GetDeviceByMAC(ctx, model.MAC{})
+	if !errors.Is(err, model.ErrDeviceDoesNotExist) {
+		t.Errorf("empty mac want: %v, got: %v", model.ErrDeviceDoesNotExist, err)
+	}
+
+	// the lookup follows a changed mac and a removed device, also after a reload
+	changed := model.MustParseMAC("a0:55:99:4b:1f:e3")
+	_, err = db.UpdateDevice(ctx, model.Device{Addr: nomac.Addr, MAC: changed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.RemoveDeviceByAddr(ctx, router.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.readDevices(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.GetDeviceByMAC(ctx, mac)
+	if !errors.Is(err, model.ErrDeviceDoesNotExist) {
+		t.Errorf("removed device want: %v, got: %v", model.ErrDeviceDoesNotExist, err)
+	}
+	got, err = db.GetDeviceByMAC(ctx, changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Addr != nomac.Addr {
+		t.Errorf("want: %s, got: %s", nomac.Addr, got.Addr)
+	}
+}
+
+func TestSqliteStore_FindDevicesByName(t *testing.T) {
+	ctx := context.Background()
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, d := range []model.Device{
+		{Name: "printer", Addr: model.MustParseAddr("192.168.0.1")},
+		{Name: "Printer", Addr: model.MustParseAddr("192.168.0.2")},
+		{Name: "nas", Addr: model.MustParseAddr("192.168.0.3")},
+	} {
+		err := db.AddDevice(ctx, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := db.SetDeviceDetails(
+		ctx,
+		model.MustParseAddr("192.168.0.3"),
+		model.DeviceDetails{Name: "PRINTER"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		input string
+		want  []string
+	}{
+		"IgnoresCase": {
+			input: "printer",
+			want:  []string{"192.168.0.1", "192.168.0.2", "192.168.0.3"},
+		},
+		"Renamed": {input: "nas", want: []string{}},
+		"Blank":   {input: "", want: []string{}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, d := range db.FindDevicesByName(ctx, tc.input) {
+				got = append(got, d.Addr.String())
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSqliteStore_ObservedMACs(t *testing.T) {
+	ctx := context.Background()
+	first := model.MustParseMAC("da:55:01:02:03:04")
+	second := model.MustParseMAC("de:55:01:02:03:05")
+	phone := model.Device{
+		Name: "phone",
+		Addr: model.MustParseAddr("192.168.0.20"),
+		MAC:  second,
+		Meta: model.Meta{
+			Tags:            model.Tags{model.RandomizedMacAddressTag},
+			MDNSName:        "phone.local",
+			DHCPHostname:    "phone",
+			DHCPFingerprint: "1,3,6,15",
+			DHCPVendorClass: "android-dhcp-13",
+			DeviceType:      model.DeviceTypePhone,
+		},
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	err := db.AddDevice(ctx, model.Device{Addr: phone.Addr, MAC: first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.UpdateDevice(ctx, phone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.readDevices(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := phone
+	want.ObservedMACs = []model.MAC{first, second}
+	for _, mac := range []model.MAC{first, second} {
+		got, err := db.GetDeviceByMAC(ctx, mac)
+		if err != nil {
+			t.Fatalf("%s: %v", mac, err)
+		}
+		if diff := deviceCmp(t, want, got); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", mac, diff)
+		}
+	}
+}
+
+func TestSqliteStore_Virtual(t *testing.T) {
+	ctx := context.Background()
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	host := model.Device{
+		Name: "pve",
+		Addr: model.MustParseAddr("192.168.0.10"),
+		Meta: model.Meta{Tags: model.Tags{}},
+		Virtual: model.Virtual{
+			Platform: model.VirtualPlatformProxmox,
+			LastScan: ts,
+			Guests: model.Guests{
+				{ID: "100", Name: "nas", Kind: model.GuestVM, State: "running"},
+				{ID: "101", Name: "dns", Kind: model.GuestContainer, MAC: "bc:24:11:00:00:01"},
+			},
+		},
+	}
+	guest := model.Device{
+		Name: "dns",
+		Addr: model.MustParseAddr("192.168.0.11"),
+		Meta: model.Meta{Tags: model.Tags{}},
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, d := range []model.Device{host, guest} {
+		err := db.AddDevice(ctx, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := db.SetDeviceParent(ctx, guest.Addr, host.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.readDevices(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	guest.Virtual.Parent = host.Addr
+	for _, want := range []model.Device{host, guest} {
+		got, err := db.GetDeviceByAddr(ctx, want.Addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := deviceCmp(t, want, got); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", want.Name, diff)
+		}
+	}
+}
+
+func TestSqliteStore_Link(t *testing.T) {
+	ctx := context.Background()
+	d := model.Device{
+		Name: "phone",
+		Addr: model.MustParseAddr("192.168.0.20"),
+		Meta: model.Meta{Tags: model.Tags{}},
+		Link: model.Link{
+			Source:    "unifi",
+			Kind:      model.LinkWireless,
+			SSID:      "home",
+			Radio:     "na",
+			Signal:    -61,
+			Uplink:    "ap-hall",
+			UplinkMAC: "f0:9f:c2:00:00:01",
+			LastSeen:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	err := db.AddDevice(ctx, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.readDevices(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := db.GetDeviceByAddr(ctx, d.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deviceCmp(t, d, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"time"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// UpsertDHCPSighting adds the sighting or updates the existing sighting of the same kind and
+// address, the first seen time is kept
+func (cs *Store) UpsertDHCPSighting(ctx context.Context, s model.DHCPSighting) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	stmt, err := conn.Prepare(
+		`insert into dhcpsightings (kind, addr, server, firstseen, lastseen, trusted)
+    values (:kind, :addr, :server, :firstseen, :lastseen, :trusted)
+    on conflict (kind, addr) do update set
+      server=:server, lastseen=:lastseen, trusted=:trusted`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":kind", string(s.Kind))
+	stmt.SetText(":addr", s.Addr.String())
+	stmt.SetText(":server", s.Server.String())
+	stmt.SetText(":firstseen", s.FirstSeen.Format(time.RFC3339Nano))
+	stmt.SetText(":lastseen", s.LastSeen.Format(time.RFC3339Nano))
+	stmt.SetBool(":trusted", s.Trusted)
+
+	_, err = stmt.Step()
+	return err
+}
+
+// ListDHCPSightings returns all sightings ordered by kind and address
+func (cs *Store) ListDHCPSightings(ctx context.Context) (sightings []model.DHCPSighting, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      kind, addr, server, firstseen, lastseen, trusted
+    from dhcpsightings
+    order by kind desc, addr`)
+	if err != nil {
+		return sightings, err
+	}
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return sightings, err
+		}
+		if !hasRow {
+			break
+		}
+		s := model.DHCPSighting{
+			Kind:    model.DHCPSightingKind(stmt.GetText("kind")),
+			Trusted: stmt.GetBool("trusted"),
+		}
+		s.Addr, err = model.ParseAddr(stmt.GetText("addr"))
+		if err != nil {
+			return sightings, err
+		}
+		s.Server, err = model.ParseAddr(stmt.GetText("server"))
+		if err != nil {
+			return sightings, err
+		}
+		s.FirstSeen, err = time.Parse(time.RFC3339Nano, stmt.GetText("firstseen"))
+		if err != nil {
+			return sightings, err
+		}
+		s.LastSeen, err = time.Parse(time.RFC3339Nano, stmt.GetText("lastseen"))
+		if err != nil {
+			return sightings, err
+		}
+		sightings = append(sightings, s)
+	}
+	return sightings, nil
+}
+
+// RemoveDHCPSighting removes the sighting of the kind at the address
+func (cs *Store) RemoveDHCPSighting(ctx context.Context, kind model.DHCPSightingKind, addr model.Addr) error {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from dhcpsightings where kind = :kind and addr = :addr`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":kind", string(kind))
+	stmt.SetText(":addr", addr.String())
+	_, err = stmt.Step()
+	return err
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_DHCPSightings(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	server := model.DHCPSighting{
+		Kind:      model.DHCPServerSighting,
+		Addr:      model.MustParseAddr("192.168.1.1"),
+		Server:    model.MustParseAddr("192.168.1.1"),
+		FirstSeen: start,
+		LastSeen:  start,
+		Trusted:   true,
+	}
+	gateway := model.DHCPSighting{
+		Kind:      model.DHCPGatewaySighting,
+		Addr:      model.MustParseAddr("192.168.1.254"),
+		Server:    model.MustParseAddr("192.168.1.1"),
+		FirstSeen: start,
+		LastSeen:  start,
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, s := range []model.DHCPSighting{gateway, server} {
+		err := db.UpsertDHCPSighting(ctx, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// seen again and trusted, the first seen time is kept
+	seen := gateway
+	seen.FirstSeen = start.Add(time.Hour)
+	seen.LastSeen = start.Add(time.Hour)
+	seen.Trusted = true
+	err := db.UpsertDHCPSighting(ctx, seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gateway.LastSeen = seen.LastSeen
+	gateway.Trusted = true
+
+	got, err := db.ListDHCPSightings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff(
+		[]model.DHCPSighting{server, gateway},
+		got,
+		cmpopts.EquateComparable(netip.Addr{}),
+	)
+	if diff != "" {
+		t.Errorf("dhcp sightings mismatch (-want +got):\n%s", diff)
+	}
+
+	err = db.RemoveDHCPSighting(ctx, gateway.Kind, gateway.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = db.ListDHCPSightings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Errorf("want 1 sighting after remove, got: %d", len(got))
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// AddDNSQueries records the queries
+func (cs *Store) AddDNSQueries(ctx context.Context, queries []model.DNSQuery) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	for _, q := range queries {
+		err = insertDNSQuery(conn, q)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertDNSQuery(conn *sqlite.Conn, q model.DNSQuery) error {
+	stmt, err := conn.Prepare(
+		`insert into dnsqueries (time, addr, domain, type)
+    values (:time, :addr, :domain, :type)`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":time", q.Time.UTC().Format(time.RFC3339Nano))
+	stmt.SetText(":addr", q.Addr.String())
+	stmt.SetText(":domain", q.Domain)
+	stmt.SetText(":type", q.Type)
+	_, err = stmt.Step()
+	return err
+}
+
+// RecentDomains returns up to limit domains queried by the device, the most recently queried
+// first
+func (cs *Store) RecentDomains(
+	ctx context.Context,
+	addr model.Addr,
+	limit int,
+) (domains []model.DomainSummary, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select domain, count(*) as queries, max(time) as lastseen
+       from dnsqueries
+      where addr = :addr
+      group by domain
+      order by lastseen desc, domain
+      limit :limit`)
+	if err != nil {
+		return domains, err
+	}
+	stmt.SetText(":addr", addr.String())
+	stmt.SetInt64(":limit", int64(limit))
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return domains, err
+		}
+		if !hasRow {
+			break
+		}
+		d := model.DomainSummary{
+			Domain:  stmt.GetText("domain"),
+			Queries: int(stmt.GetInt64("queries")),
+		}
+		d.LastSeen, err = time.Parse(time.RFC3339Nano, stmt.GetText("lastseen"))
+		if err != nil {
+			return domains, err
+		}
+		domains = append(domains, d)
+	}
+	return domains, nil
+}
+
+// PurgeDNSQueries removes the queries made before the cutoff, returns the number removed
+func (cs *Store) PurgeDNSQueries(ctx context.Context, cutoff time.Time) (int, error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from dnsqueries where time < :cutoff`)
+	if err != nil {
+		return 0, err
+	}
+	stmt.SetText(":cutoff", cutoff.UTC().Format(time.RFC3339Nano))
+	_, err = stmt.Step()
+	if err != nil {
+		return 0, err
+	}
+	return conn.Changes(), nil
+}
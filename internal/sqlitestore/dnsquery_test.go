@@ -0,0 +1,73 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_DNSQueries(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	addr := model.MustParseAddr("192.168.1.20")
+	other := model.MustParseAddr("192.168.1.30")
+	queries := []model.DNSQuery{
+		{Time: start, Addr: addr, Domain: "example.com", Type: "A"},
+		{Time: start.Add(time.Minute), Addr: addr, Domain: "example.com", Type: "AAAA"},
+		{Time: start.Add(2 * time.Minute), Addr: addr, Domain: "example.org", Type: "A"},
+		{Time: start.Add(3 * time.Minute), Addr: other, Domain: "example.net", Type: "A"},
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	err := db.AddDNSQueries(ctx, queries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.RecentDomains(ctx, addr, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.DomainSummary{
+		{Domain: "example.org", Queries: 1, LastSeen: start.Add(2 * time.Minute)},
+		{Domain: "example.com", Queries: 2, LastSeen: start.Add(time.Minute)},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	got, err = db.RecentDomains(ctx, addr, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want[:1], got); diff != "" {
+		t.Errorf("limit (-want +got):\n%s", diff)
+	}
+
+	removed, err := db.PurgeDNSQueries(ctx, start.Add(90*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("removed want 2 got %d", removed)
+	}
+	got, err = db.RecentDomains(ctx, addr, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want[:1], got); diff != "" {
+		t.Errorf("after purge (-want +got):\n%s", diff)
+	}
+}
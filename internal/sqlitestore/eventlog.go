@@ -0,0 +1,139 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// WriteEvents stores bus events and errors in the event history.  The time is kept as unix
+// nanoseconds so records sort and compare exactly, a tail polls with the last time it saw.
+func (cs *Store) WriteEvents(ctx context.Context, records []model.EventRecord) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	for _, rec := range records {
+		err = insertEvent(conn, rec)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertEvent(conn *sqlite.Conn, rec model.EventRecord) error {
+	stmt, err := conn.Prepare(
+		`insert into events (time, kind, type, message)
+    values (:time, :kind, :type, :message)`)
+	if err != nil {
+		return err
+	}
+	stmt.SetInt64(":time", rec.Time.UnixNano())
+	stmt.SetText(":kind", string(rec.Kind))
+	stmt.SetText(":type", rec.Type)
+	stmt.SetText(":message", rec.Message)
+	_, err = stmt.Step()
+	return err
+}
+
+// ReadEvents returns the records matching the query, newest first
+func (cs *Store) ReadEvents(
+	ctx context.Context,
+	q model.EventQuery,
+) (records []model.EventRecord, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      time, kind, type, message
+    from events
+    where time > :after
+      and (:kind = '' or kind = :kind)
+      and (:search = ''
+        or instr(lower(type), lower(:search)) > 0
+        or instr(lower(message), lower(:search)) > 0)
+    order by time desc
+    limit :limit`)
+	if err != nil {
+		return records, err
+	}
+	var after int64
+	if !q.After.IsZero() {
+		after = q.After.UnixNano()
+	}
+	limit := int64(-1)
+	if q.Limit > 0 {
+		limit = int64(q.Limit)
+	}
+	stmt.SetInt64(":after", after)
+	stmt.SetText(":kind", string(q.Kind))
+	stmt.SetText(":search", q.Search)
+	stmt.SetInt64(":limit", limit)
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return records, err
+		}
+		if !hasRow {
+			break
+		}
+		records = append(records, model.EventRecord{
+			Time:    time.Unix(0, stmt.GetInt64("time")),
+			Kind:    model.EventRecordKind(stmt.GetText("kind")),
+			Type:    stmt.GetText("type"),
+			Message: stmt.GetText("message"),
+		})
+	}
+	return records, nil
+}
+
+// PurgeEvents removes the records from before the cutoff and all but the newest keep records
+// (zero keeps them all), returns the number removed
+func (cs *Store) PurgeEvents(ctx context.Context, cutoff time.Time, keep int) (int, error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from events where time < :cutoff`)
+	if err != nil {
+		return 0, err
+	}
+	stmt.SetInt64(":cutoff", cutoff.UnixNano())
+	_, err = stmt.Step()
+	if err != nil {
+		return 0, err
+	}
+	removed := conn.Changes()
+	if keep <= 0 {
+		return removed, nil
+	}
+	stmt, err = conn.Prepare(
+		`delete from events
+    where rowid not in (select rowid from events order by time desc limit :keep)`)
+	if err != nil {
+		return removed, err
+	}
+	stmt.SetInt64(":keep", int64(keep))
+	_, err = stmt.Step()
+	if err != nil {
+		return removed, err
+	}
+	return removed + conn.Changes(), nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_Events(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	old := model.EventRecord{
+		Time:    now.Add(-48 * time.Hour),
+		Kind:    model.EventRecordEvent,
+		Type:    "model.NetworkAddedEvent",
+		Message: "network added: lan",
+	}
+	added := model.EventRecord{
+		Time:    now.Add(-time.Minute),
+		Kind:    model.EventRecordEvent,
+		Type:    "model.EventDeviceAdded",
+		Message: "device added: 192.168.1.10",
+	}
+	failed := model.EventRecord{
+		Time:    now,
+		Kind:    model.EventRecordError,
+		Type:    "*tre.TracingError",
+		Message: "storing updated device 192.168.1.10",
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	err := db.WriteEvents(ctx, []model.EventRecord{old, added, failed})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		query model.EventQuery
+		want  []model.EventRecord
+	}{
+		"All":   {want: []model.EventRecord{failed, added, old}},
+		"Limit": {query: model.EventQuery{Limit: 1}, want: []model.EventRecord{failed}},
+		"Kind": {
+			query: model.EventQuery{Kind: model.EventRecordError},
+			want:  []model.EventRecord{failed},
+		},
+		"Search": {
+			query: model.EventQuery{Search: "192.168.1.10"},
+			want:  []model.EventRecord{failed, added},
+		},
+		"SearchType": {query: model.EventQuery{Search: "NETWORKADDED"}, want: []model.EventRecord{old}},
+		"After":      {query: model.EventQuery{After: added.Time}, want: []model.EventRecord{failed}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := db.ReadEvents(ctx, tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateApproxTime(0)); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	removed, err := db.PurgeEvents(ctx, now.Add(-24*time.Hour), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("purge want: 2 removed, got: %d", removed)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// UpsertExclusion adds the exclusion or replaces the existing one with the same name
+func (cs *Store) UpsertExclusion(ctx context.Context, x model.Exclusion) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	stmt, err := conn.Prepare(
+		`insert into exclusions (name, prefix, scan, ping, portscan, note)
+    values (:name, :prefix, :scan, :ping, :portscan, :note)
+    on conflict (name) do update set
+      prefix=:prefix, scan=:scan, ping=:ping, portscan=:portscan, note=:note`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":name", x.Name)
+	stmt.SetText(":prefix", x.Prefix.String())
+	stmt.SetBool(":scan", x.Scan)
+	stmt.SetBool(":ping", x.Ping)
+	stmt.SetBool(":portscan", x.PortScan)
+	stmt.SetText(":note", x.Note)
+
+	_, err = stmt.Step()
+	return err
+}
+
+// RemoveExclusion deletes the named exclusion
+func (cs *Store) RemoveExclusion(ctx context.Context, name string) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from exclusions where name = :name`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":name", name)
+	_, err = stmt.Step()
+	if err != nil {
+		return err
+	}
+	if conn.Changes() == 0 {
+		return model.ErrExclusionDoesNotExist
+	}
+	return nil
+}
+
+// ListExclusions returns all exclusions ordered by name
+func (cs *Store) ListExclusions(ctx context.Context) (exclusions []model.Exclusion, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      name, prefix, scan, ping, portscan, note
+    from exclusions
+    order by name`)
+	if err != nil {
+		return exclusions, err
+	}
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return exclusions, err
+		}
+		if !hasRow {
+			break
+		}
+		x := model.Exclusion{
+			Name:     stmt.GetText("name"),
+			Scan:     stmt.GetBool("scan"),
+			Ping:     stmt.GetBool("ping"),
+			PortScan: stmt.GetBool("portscan"),
+			Note:     stmt.GetText("note"),
+		}
+		err = x.Prefix.Scan(stmt.GetText("prefix"))
+		if err != nil {
+			return exclusions, err
+		}
+		exclusions = append(exclusions, x)
+	}
+	return exclusions, nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_Exclusions(t *testing.T) {
+	ctx := context.Background()
+	plcs := model.Exclusion{
+		Name:   "plcs",
+		Prefix: model.MustParsePrefix("10.20.0.0/24"),
+		Scan:   true,
+		Ping:   true,
+		Note:   "line 2 controllers",
+	}
+	hmi := model.Exclusion{
+		Name:     "hmi",
+		Prefix:   model.MustParsePrefix("10.20.1.5/32"),
+		PortScan: true,
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, x := range []model.Exclusion{plcs, hmi} {
+		err := db.UpsertExclusion(ctx, x)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	plcs.PortScan = true
+	err := db.UpsertExclusion(ctx, plcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.ListExclusions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff([]model.Exclusion{hmi, plcs}, got, cmpopts.EquateComparable(netip.Prefix{}))
+	if diff != "" {
+		t.Errorf("exclusions mismatch (-want +got):\n%s", diff)
+	}
+
+	err = db.RemoveExclusion(ctx, hmi.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.RemoveExclusion(ctx, hmi.Name)
+	if !errors.Is(err, model.ErrExclusionDoesNotExist) {
+		t.Errorf("remove missing want: %v, got: %v", model.ErrExclusionDoesNotExist, err)
+	}
+}
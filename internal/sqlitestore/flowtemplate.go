@@ -0,0 +1,110 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// UpsertFlowTemplate adds the template or replaces the fields of the template with the same
+// id in the observation domain of the exporter
+func (cs *Store) UpsertFlowTemplate(ctx context.Context, t model.FlowTemplate) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	stmt, err := conn.Prepare(
+		`insert into flowtemplates (exporter, domainid, id, fields, updatedat)
+    values (:exporter, :domainid, :id, :fields, :updatedat)
+    on conflict (exporter, domainid, id) do update set
+      fields=:fields, updatedat=:updatedat`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":exporter", t.Exporter.String())
+	stmt.SetInt64(":domainid", int64(t.DomainID))
+	stmt.SetInt64(":id", int64(t.ID))
+	stmt.SetText(":fields", formatFlowTemplateFields(t.Fields))
+	stmt.SetText(":updatedat", t.UpdatedAt.Format(time.RFC3339Nano))
+
+	_, err = stmt.Step()
+	return err
+}
+
+// ListFlowTemplates returns all templates ordered by exporter, domain and id
+func (cs *Store) ListFlowTemplates(ctx context.Context) (templates []model.FlowTemplate, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      exporter, domainid, id, fields, updatedat
+    from flowtemplates
+    order by exporter, domainid, id`)
+	if err != nil {
+		return templates, err
+	}
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return templates, err
+		}
+		if !hasRow {
+			break
+		}
+		t := model.FlowTemplate{
+			DomainID: int(stmt.GetInt64("domainid")),
+			ID:       int(stmt.GetInt64("id")),
+		}
+		t.Exporter, err = model.ParseAddr(stmt.GetText("exporter"))
+		if err != nil {
+			return templates, err
+		}
+		t.Fields, err = parseFlowTemplateFields(stmt.GetText("fields"))
+		if err != nil {
+			return templates, err
+		}
+		t.UpdatedAt, err = time.Parse(time.RFC3339Nano, stmt.GetText("updatedat"))
+		if err != nil {
+			return templates, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// formatFlowTemplateFields writes the fields as id:length:enterprise separated by spaces
+func formatFlowTemplateFields(fields []model.FlowTemplateField) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = fmt.Sprintf("%d:%d:%d", f.ID, f.Length, f.EnterpriseNumber)
+	}
+	return strings.Join(parts, " ")
+}
+
+func parseFlowTemplateFields(s string) ([]model.FlowTemplateField, error) {
+	parts := strings.Fields(s)
+	fields := make([]model.FlowTemplateField, len(parts))
+	for i, part := range parts {
+		f := &fields[i]
+		_, err := fmt.Sscanf(part, "%d:%d:%d", &f.ID, &f.Length, &f.EnterpriseNumber)
+		if err != nil {
+			return nil, fmt.Errorf("template field %q: %w", part, err)
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_FlowTemplates(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	router := model.FlowTemplate{
+		Exporter: model.MustParseAddr("192.168.1.1"),
+		DomainID: 0,
+		ID:       256,
+		Fields: []model.FlowTemplateField{
+			{ID: 8, Length: 4},
+			{ID: 12, Length: 4},
+			{ID: 1, Length: 8, EnterpriseNumber: 29305},
+		},
+		UpdatedAt: start,
+	}
+	other := model.FlowTemplate{
+		Exporter:  model.MustParseAddr("10.0.0.1"),
+		DomainID:  1,
+		ID:        256,
+		Fields:    []model.FlowTemplateField{{ID: 7, Length: 2}},
+		UpdatedAt: start,
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, ft := range []model.FlowTemplate{router, other} {
+		err := db.UpsertFlowTemplate(ctx, ft)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// announced again with other fields, the saved template is replaced
+	router.Fields = router.Fields[:2]
+	router.UpdatedAt = start.Add(time.Hour)
+	err := db.UpsertFlowTemplate(ctx, router)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.ListFlowTemplates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff(
+		[]model.FlowTemplate{other, router},
+		got,
+		cmpopts.EquateComparable(netip.Addr{}),
+	)
+	if diff != "" {
+		t.Errorf("flow templates mismatch (-want +got):\n%s", diff)
+	}
+}
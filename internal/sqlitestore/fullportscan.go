@@ -0,0 +1,115 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"time"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// UpsertFullPortScan stores the progress of the sweep of the device's ports
+func (cs *Store) UpsertFullPortScan(ctx context.Context, fs model.FullPortScan) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	stmt, err := conn.Prepare(
+		`insert into fullportscans (addr, nextport, openports, started, updated, completed)
+    values (:addr, :nextport, :openports, :started, :updated, :completed)
+    on conflict (addr) do update set
+      nextport=:nextport, openports=:openports, started=:started, updated=:updated,
+      completed=:completed`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":addr", fs.Addr.String())
+	stmt.SetInt64(":nextport", int64(fs.NextPort))
+	stmt.SetText(":openports", fs.OpenPorts.String())
+	stmt.SetText(":started", fs.Started.Format(time.RFC3339Nano))
+	stmt.SetText(":updated", fs.Updated.Format(time.RFC3339Nano))
+	stmt.SetText(":completed", fs.Completed.Format(time.RFC3339Nano))
+
+	_, err = stmt.Step()
+	return err
+}
+
+// RemoveFullPortScan deletes the progress of the sweep of the device's ports
+func (cs *Store) RemoveFullPortScan(ctx context.Context, addr model.Addr) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from fullportscans where addr = :addr`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":addr", addr.String())
+	_, err = stmt.Step()
+	if err != nil {
+		return err
+	}
+	if conn.Changes() == 0 {
+		return model.ErrFullPortScanDoesNotExist
+	}
+	return nil
+}
+
+// ListFullPortScans returns the progress of every sweep ordered by addr
+func (cs *Store) ListFullPortScans(ctx context.Context) (scans []model.FullPortScan, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      addr, nextport, openports, started, updated, completed
+    from fullportscans
+    order by addr`)
+	if err != nil {
+		return scans, err
+	}
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return scans, err
+		}
+		if !hasRow {
+			break
+		}
+		fs := model.FullPortScan{
+			NextPort: int(stmt.GetInt64("nextport")),
+		}
+		fs.Addr, err = model.ParseAddr(stmt.GetText("addr"))
+		if err != nil {
+			return scans, err
+		}
+		err = fs.OpenPorts.Scan(stmt.GetText("openports"))
+		if err != nil {
+			return scans, err
+		}
+		for col, ts := range map[string]*time.Time{
+			"started":   &fs.Started,
+			"updated":   &fs.Updated,
+			"completed": &fs.Completed,
+		} {
+			*ts, err = time.Parse(time.RFC3339Nano, stmt.GetText(col))
+			if err != nil {
+				return scans, err
+			}
+		}
+		scans = append(scans, fs)
+	}
+	return scans, nil
+}
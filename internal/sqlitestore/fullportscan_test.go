@@ -0,0 +1,57 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_FullPortScans(t *testing.T) {
+	ctx := context.Background()
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fs := model.NewFullPortScan(model.MustParseAddr("192.168.1.20"), ts)
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	err := db.UpsertFullPortScan(ctx, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs = fs.Record(fs.NextChunk(1024), []int{22, 443}, ts.Add(time.Minute))
+	err = db.UpsertFullPortScan(ctx, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.ListFullPortScans(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff([]model.FullPortScan{fs}, got, cmpopts.EquateComparable(netip.Addr{}))
+	if diff != "" {
+		t.Errorf("full port scans mismatch (-want +got):\n%s", diff)
+	}
+
+	err = db.RemoveFullPortScan(ctx, fs.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.RemoveFullPortScan(ctx, fs.Addr)
+	if !errors.Is(err, model.ErrFullPortScanDoesNotExist) {
+		t.Errorf("remove missing want: %v, got: %v", model.ErrFullPortScanDoesNotExist, err)
+	}
+}
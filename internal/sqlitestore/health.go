@@ -0,0 +1,116 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// WriteHealthProbes stores the results of an internet health run
+func (cs *Store) WriteHealthProbes(ctx context.Context, probes []model.HealthProbe) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	for _, p := range probes {
+		err = insertHealthProbe(conn, p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertHealthProbe(conn *sqlite.Conn, p model.HealthProbe) error {
+	stmt, err := conn.Prepare(
+		`insert into healthprobes (start, kind, target, latency, loss, hops, error)
+    values (:start, :kind, :target, :latency, :loss, :hops, :error)`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":start", p.Start.Format(time.RFC3339Nano))
+	stmt.SetText(":kind", string(p.Kind))
+	stmt.SetText(":target", p.Target)
+	stmt.SetInt64(":latency", p.Latency.Nanoseconds())
+	stmt.SetFloat(":loss", p.Loss)
+	stmt.SetInt64(":hops", int64(p.Hops))
+	stmt.SetText(":error", p.Err)
+	_, err = stmt.Step()
+	return err
+}
+
+// ReadHealthProbes returns the probes from Now() minus the duration, oldest first
+func (cs *Store) ReadHealthProbes(
+	ctx context.Context,
+	duration time.Duration,
+) (probes []model.HealthProbe, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      start, kind, target, latency, loss, hops, error
+    from healthprobes
+    where start > :start
+    order by start`)
+	if err != nil {
+		return probes, err
+	}
+	stmt.SetText(":start", time.Now().Add(-1*duration).Format(time.RFC3339Nano))
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return probes, err
+		}
+		if !hasRow {
+			break
+		}
+		p := model.HealthProbe{
+			Kind:    model.HealthProbeKind(stmt.GetText("kind")),
+			Target:  stmt.GetText("target"),
+			Latency: time.Duration(stmt.GetInt64("latency")),
+			Loss:    stmt.GetFloat("loss"),
+			Hops:    int(stmt.GetInt64("hops")),
+			Err:     stmt.GetText("error"),
+		}
+		p.Start, err = time.Parse(time.RFC3339Nano, stmt.GetText("start"))
+		if err != nil {
+			return probes, err
+		}
+		probes = append(probes, p)
+	}
+	return probes, nil
+}
+
+// PurgeHealthProbes removes the probes started before the cutoff, returns the number removed
+func (cs *Store) PurgeHealthProbes(ctx context.Context, cutoff time.Time) (int, error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from healthprobes where start < :cutoff`)
+	if err != nil {
+		return 0, err
+	}
+	stmt.SetText(":cutoff", cutoff.Format(time.RFC3339Nano))
+	_, err = stmt.Step()
+	if err != nil {
+		return 0, err
+	}
+	return conn.Changes(), nil
+}
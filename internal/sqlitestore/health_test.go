@@ -0,0 +1,72 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_HealthProbes(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+
+	ping := model.HealthProbe{
+		Start:   now.Add(-time.Minute),
+		Kind:    model.HealthProbePing,
+		Target:  "1.1.1.1",
+		Latency: 12 * time.Millisecond,
+		Loss:    0.5,
+		Hops:    8,
+	}
+	web := model.HealthProbe{
+		Start:  now,
+		Kind:   model.HealthProbeHTTP,
+		Target: "https://example.com",
+		Err:    "timeout",
+	}
+	old := model.HealthProbe{
+		Start:  now.Add(-48 * time.Hour),
+		Kind:   model.HealthProbePing,
+		Target: "8.8.8.8",
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	err := db.WriteHealthProbes(ctx, []model.HealthProbe{old, web, ping})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.ReadHealthProbes(ctx, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff(
+		[]model.HealthProbe{ping, web},
+		got,
+		cmpopts.EquateApproxTime(time.Millisecond),
+	)
+	if diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	removed, err := db.PurgeHealthProbes(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("purge want: 1 removed, got: %d", removed)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// UpsertHTTPCheck adds the check or replaces the existing one with the same name
+func (cs *Store) UpsertHTTPCheck(ctx context.Context, c model.HTTPCheck) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	stmt, err := conn.Prepare(
+		`insert into httpchecks (name, url, method, expectstatus, expectbody, interval, insecure)
+    values (:name, :url, :method, :expectstatus, :expectbody, :interval, :insecure)
+    on conflict (name) do update set
+      url=:url, method=:method, expectstatus=:expectstatus, expectbody=:expectbody,
+      interval=:interval, insecure=:insecure`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":name", c.Name)
+	stmt.SetText(":url", c.URL)
+	stmt.SetText(":method", c.Method)
+	stmt.SetInt64(":expectstatus", int64(c.ExpectStatus))
+	stmt.SetText(":expectbody", c.ExpectBody)
+	stmt.SetInt64(":interval", c.Interval.Nanoseconds())
+	stmt.SetBool(":insecure", c.Insecure)
+
+	_, err = stmt.Step()
+	return err
+}
+
+// RemoveHTTPCheck deletes the named check and its results
+func (cs *Store) RemoveHTTPCheck(ctx context.Context, name string) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	stmt, err := conn.Prepare(`delete from httpchecks where name = :name`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":name", name)
+	_, err = stmt.Step()
+	if err != nil {
+		return err
+	}
+	if conn.Changes() == 0 {
+		return model.ErrHTTPCheckDoesNotExist
+	}
+
+	stmt, err = conn.Prepare(`delete from httpcheckresults where name = :name`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":name", name)
+	_, err = stmt.Step()
+	return err
+}
+
+// ListHTTPChecks returns all http checks ordered by name
+func (cs *Store) ListHTTPChecks(ctx context.Context) (checks []model.HTTPCheck, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      name, url, method, expectstatus, expectbody, interval, insecure
+    from httpchecks
+    order by name`)
+	if err != nil {
+		return checks, err
+	}
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return checks, err
+		}
+		if !hasRow {
+			break
+		}
+		checks = append(checks, model.HTTPCheck{
+			Name:         stmt.GetText("name"),
+			URL:          stmt.GetText("url"),
+			Method:       stmt.GetText("method"),
+			ExpectStatus: int(stmt.GetInt64("expectstatus")),
+			ExpectBody:   stmt.GetText("expectbody"),
+			Interval:     time.Duration(stmt.GetInt64("interval")),
+			Insecure:     stmt.GetBool("insecure"),
+		})
+	}
+	return checks, nil
+}
+
+// WriteHTTPCheckResults stores the results of a run of the http checks
+func (cs *Store) WriteHTTPCheckResults(
+	ctx context.Context,
+	results []model.HTTPCheckResult,
+) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	for _, r := range results {
+		err = insertHTTPCheckResult(conn, r)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertHTTPCheckResult(conn *sqlite.Conn, r model.HTTPCheckResult) error {
+	stmt, err := conn.Prepare(
+		`insert into httpcheckresults (start, name, status, latency, error)
+    values (:start, :name, :status, :latency, :error)`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":start", r.Start.Format(time.RFC3339Nano))
+	stmt.SetText(":name", r.Name)
+	stmt.SetInt64(":status", int64(r.Status))
+	stmt.SetInt64(":latency", r.Latency.Nanoseconds())
+	stmt.SetText(":error", r.Err)
+	_, err = stmt.Step()
+	return err
+}
+
+// ReadHTTPCheckResults returns the results from Now() minus the duration, oldest first
+func (cs *Store) ReadHTTPCheckResults(
+	ctx context.Context,
+	duration time.Duration,
+) (results []model.HTTPCheckResult, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      start, name, status, latency, error
+    from httpcheckresults
+    where start > :start
+    order by start`)
+	if err != nil {
+		return results, err
+	}
+	stmt.SetText(":start", time.Now().Add(-1*duration).Format(time.RFC3339Nano))
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return results, err
+		}
+		if !hasRow {
+			break
+		}
+		r := model.HTTPCheckResult{
+			Name:    stmt.GetText("name"),
+			Status:  int(stmt.GetInt64("status")),
+			Latency: time.Duration(stmt.GetInt64("latency")),
+			Err:     stmt.GetText("error"),
+		}
+		r.Start, err = time.Parse(time.RFC3339Nano, stmt.GetText("start"))
+		if err != nil {
+			return results, err
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// PurgeHTTPCheckResults removes the results started before the cutoff, returns the number
+// removed
+func (cs *Store) PurgeHTTPCheckResults(ctx context.Context, cutoff time.Time) (int, error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from httpcheckresults where start < :cutoff`)
+	if err != nil {
+		return 0, err
+	}
+	stmt.SetText(":cutoff", cutoff.Format(time.RFC3339Nano))
+	_, err = stmt.Step()
+	if err != nil {
+		return 0, err
+	}
+	return conn.Changes(), nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_HTTPChecks(t *testing.T) {
+	ctx := context.Background()
+	wiki := model.HTTPCheck{
+		Name:       "wiki",
+		URL:        "https://wiki.lan/health",
+		Method:     "GET",
+		ExpectBody: "ok",
+		Interval:   time.Minute,
+		Insecure:   true,
+	}
+	git := model.HTTPCheck{
+		Name:         "git",
+		URL:          "http://git.lan",
+		Method:       "HEAD",
+		ExpectStatus: 200,
+		Interval:     5 * time.Minute,
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, c := range []model.HTTPCheck{wiki, git} {
+		err := db.UpsertHTTPCheck(ctx, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	wiki.Interval = 2 * time.Minute
+	err := db.UpsertHTTPCheck(ctx, wiki)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.ListHTTPChecks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff([]model.HTTPCheck{git, wiki}, got)
+	if diff != "" {
+		t.Errorf("http checks mismatch (-want +got):\n%s", diff)
+	}
+
+	now := time.Now()
+	results := []model.HTTPCheckResult{
+		{Start: now.Add(-2 * time.Hour), Name: "wiki", Status: 200, Latency: time.Millisecond},
+		{Start: now.Add(-time.Minute), Name: "wiki", Status: 503, Err: "status 503"},
+		{Start: now, Name: "git", Status: 200, Latency: 3 * time.Millisecond},
+	}
+	err = db.WriteHTTPCheckResults(ctx, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotResults, err := db.ReadHTTPCheckResults(ctx, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff = cmp.Diff(results[1:], gotResults, cmpopts.EquateApproxTime(time.Millisecond))
+	if diff != "" {
+		t.Errorf("http check results mismatch (-want +got):\n%s", diff)
+	}
+
+	removed, err := db.PurgeHTTPCheckResults(ctx, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("purge want: 1, got: %d", removed)
+	}
+
+	err = db.RemoveHTTPCheck(ctx, wiki.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotResults, err = db.ReadHTTPCheckResults(ctx, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gotResults) != 1 || gotResults[0].Name != git.Name {
+		t.Errorf("results of removed check were kept: %v", gotResults)
+	}
+	err = db.RemoveHTTPCheck(ctx, wiki.Name)
+	if !errors.Is(err, model.ErrHTTPCheckDoesNotExist) {
+		t.Errorf("remove missing want: %v, got: %v", model.ErrHTTPCheckDoesNotExist, err)
+	}
+}
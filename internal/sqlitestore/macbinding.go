@@ -0,0 +1,108 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"time"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// UpsertMACBinding adds the binding or moves the last seen time and source of the existing
+// binding of the MAC to the address
+func (cs *Store) UpsertMACBinding(ctx context.Context, b model.MACBinding) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	stmt, err := conn.Prepare(
+		`insert into macbindings (addr, mac, firstseen, lastseen, source)
+    values (:addr, :mac, :firstseen, :lastseen, :source)
+    on conflict (addr, mac) do update set
+      lastseen=:lastseen, source=:source`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":addr", b.Addr.String())
+	stmt.SetText(":mac", b.MAC.String())
+	stmt.SetText(":firstseen", b.FirstSeen.Format(time.RFC3339Nano))
+	stmt.SetText(":lastseen", b.LastSeen.Format(time.RFC3339Nano))
+	stmt.SetText(":source", b.Source.String())
+
+	_, err = stmt.Step()
+	return err
+}
+
+// ListMACBindings returns all bindings ordered by address and first seen
+func (cs *Store) ListMACBindings(ctx context.Context) (bindings []model.MACBinding, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      addr, mac, firstseen, lastseen, source
+    from macbindings
+    order by addr, firstseen`)
+	if err != nil {
+		return bindings, err
+	}
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return bindings, err
+		}
+		if !hasRow {
+			break
+		}
+		b := model.MACBinding{Source: model.DiscoverySource(stmt.GetText("source"))}
+		b.Addr, err = model.ParseAddr(stmt.GetText("addr"))
+		if err != nil {
+			return bindings, err
+		}
+		b.MAC, err = model.ParseMAC(stmt.GetText("mac"))
+		if err != nil {
+			return bindings, err
+		}
+		b.FirstSeen, err = time.Parse(time.RFC3339Nano, stmt.GetText("firstseen"))
+		if err != nil {
+			return bindings, err
+		}
+		b.LastSeen, err = time.Parse(time.RFC3339Nano, stmt.GetText("lastseen"))
+		if err != nil {
+			return bindings, err
+		}
+		bindings = append(bindings, b)
+	}
+	return bindings, nil
+}
+
+// PurgeMACBindings removes the bindings last seen before the cutoff, returns the number
+// removed
+func (cs *Store) PurgeMACBindings(ctx context.Context, cutoff time.Time) (int, error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from macbindings where lastseen < :cutoff`)
+	if err != nil {
+		return 0, err
+	}
+	stmt.SetText(":cutoff", cutoff.Format(time.RFC3339Nano))
+	_, err = stmt.Step()
+	if err != nil {
+		return 0, err
+	}
+	return conn.Changes(), nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_MACBindings(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	addr := model.MustParseAddr("192.168.1.1")
+	gateway := model.MACBinding{
+		Addr:      addr,
+		MAC:       model.MustParseMAC("aa:aa:aa:aa:aa:01"),
+		FirstSeen: start,
+		LastSeen:  start,
+		Source:    "arp",
+	}
+	spoof := model.MACBinding{
+		Addr:      addr,
+		MAC:       model.MustParseMAC("bb:bb:bb:bb:bb:02"),
+		FirstSeen: start.Add(time.Hour),
+		LastSeen:  start.Add(time.Hour),
+		Source:    "hostarp",
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, b := range []model.MACBinding{gateway, spoof} {
+		err := db.UpsertMACBinding(ctx, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// seen again the first seen time is kept
+	seen := gateway
+	seen.FirstSeen = start.Add(2 * time.Hour)
+	seen.LastSeen = start.Add(2 * time.Hour)
+	err := db.UpsertMACBinding(ctx, seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gateway.LastSeen = seen.LastSeen
+
+	got, err := db.ListMACBindings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff(
+		[]model.MACBinding{gateway, spoof},
+		got,
+		cmpopts.EquateComparable(netip.Addr{}),
+		cmp.Comparer(func(a, b net.HardwareAddr) bool { return a.String() == b.String() }),
+	)
+	if diff != "" {
+		t.Errorf("mac bindings mismatch (-want +got):\n%s", diff)
+	}
+
+	removed, err := db.PurgeMACBindings(ctx, start.Add(90*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("purge want: 1, got: %d", removed)
+	}
+}
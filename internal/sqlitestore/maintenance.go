@@ -0,0 +1,111 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"time"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// UpsertMaintenanceWindow adds the window or replaces the existing one with the same name
+func (cs *Store) UpsertMaintenanceWindow(
+	ctx context.Context,
+	w model.MaintenanceWindow,
+) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	stmt, err := conn.Prepare(
+		`insert into maintenancewindows (name, scope, target, start, duration, repeat, note)
+    values (:name, :scope, :target, :start, :duration, :repeat, :note)
+    on conflict (name) do update set
+      scope=:scope, target=:target, start=:start, duration=:duration, repeat=:repeat, note=:note`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":name", w.Name)
+	stmt.SetText(":scope", string(w.Scope))
+	stmt.SetText(":target", w.Target)
+	stmt.SetText(":start", w.Start.Format(time.RFC3339Nano))
+	stmt.SetInt64(":duration", w.Duration.Nanoseconds())
+	stmt.SetText(":repeat", string(w.Repeat))
+	stmt.SetText(":note", w.Note)
+
+	_, err = stmt.Step()
+	return err
+}
+
+// RemoveMaintenanceWindow deletes the named maintenance window
+func (cs *Store) RemoveMaintenanceWindow(ctx context.Context, name string) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from maintenancewindows where name = :name`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":name", name)
+	_, err = stmt.Step()
+	if err != nil {
+		return err
+	}
+	if conn.Changes() == 0 {
+		return model.ErrMaintenanceWindowDoesNotExist
+	}
+	return nil
+}
+
+// ListMaintenanceWindows returns all maintenance windows ordered by start
+func (cs *Store) ListMaintenanceWindows(
+	ctx context.Context,
+) (windows []model.MaintenanceWindow, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      name, scope, target, start, duration, repeat, note
+    from maintenancewindows
+    order by start, name`)
+	if err != nil {
+		return windows, err
+	}
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return windows, err
+		}
+		if !hasRow {
+			break
+		}
+		w := model.MaintenanceWindow{
+			Name:     stmt.GetText("name"),
+			Scope:    model.MaintenanceScope(stmt.GetText("scope")),
+			Target:   stmt.GetText("target"),
+			Duration: time.Duration(stmt.GetInt64("duration")),
+			Repeat:   model.MaintenanceRepeat(stmt.GetText("repeat")),
+			Note:     stmt.GetText("note"),
+		}
+		w.Start, err = time.Parse(time.RFC3339Nano, stmt.GetText("start"))
+		if err != nil {
+			return windows, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_MaintenanceWindows(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 6, 1, 2, 0, 0, 0, time.UTC)
+	nightly := model.MaintenanceWindow{
+		Name:     "nightly",
+		Scope:    model.MaintenanceScopeTag,
+		Target:   "printer",
+		Start:    start,
+		Duration: time.Hour,
+		Repeat:   model.MaintenanceDaily,
+		Note:     "reboot",
+	}
+	upgrade := model.MaintenanceWindow{
+		Name:     "upgrade",
+		Scope:    model.MaintenanceScopeDevice,
+		Target:   "192.168.86.1",
+		Start:    start.Add(time.Hour),
+		Duration: 30 * time.Minute,
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, w := range []model.MaintenanceWindow{upgrade, nightly} {
+		err := db.UpsertMaintenanceWindow(ctx, w)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	nightly.Duration = 2 * time.Hour
+	err := db.UpsertMaintenanceWindow(ctx, nightly)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.ListMaintenanceWindows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff([]model.MaintenanceWindow{nightly, upgrade}, got)
+	if diff != "" {
+		t.Errorf("maintenance windows mismatch (-want +got):\n%s", diff)
+	}
+
+	err = db.RemoveMaintenanceWindow(ctx, upgrade.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.RemoveMaintenanceWindow(ctx, upgrade.Name)
+	if !errors.Is(err, model.ErrMaintenanceWindowDoesNotExist) {
+		t.Errorf("remove missing want: %v, got: %v", model.ErrMaintenanceWindowDoesNotExist, err)
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// migrationFiles holds the schema changes as NNNN_name.up.sql files, each with a
+// NNNN_name.down.sql reverting it.  New migrations are only ever added with the next number,
+// a released migration is never edited.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var (
+	ErrSchemaTooNew          = errors.New("database schema is newer than this version of mason")
+	ErrUnknownMigration      = errors.New("unknown migration version")
+	ErrIrreversibleMigration = errors.New("migration can not be reverted")
+	ErrInvalidMigrations     = errors.New("invalid migration files")
+)
+
+type (
+	// Migration is a numbered change of the schema
+	Migration struct {
+		Version int
+		Name    string
+		Up      string
+		Down    string
+	}
+
+	// MigrationStatus is a migration along with when it was applied, zero while pending
+	MigrationStatus struct {
+		Migration
+		Applied time.Time
+	}
+)
+
+// migrations are the migrations embedded in this version, ordered by version
+var migrations = mustLoadMigrations(migrationFiles, "migrations")
+
+func mustLoadMigrations(fsys fs.FS, dir string) []Migration {
+	m, err := loadMigrations(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// loadMigrations reads the migration files of dir, the versions must run from 1 without gaps
+// and each must have an up script
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".sql")
+		if e.IsDir() || !ok {
+			continue
+		}
+		name, direction := strings.TrimSuffix(name, path.Ext(name)), path.Ext(name)
+		num, name, ok := strings.Cut(name, "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil || version < 1 || (direction != ".up" && direction != ".down") {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMigrations, e.Name())
+		}
+		x, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("%w: version %d is named %s and %s", ErrInvalidMigrations, version, m.Name, name)
+		}
+		if direction == ".up" {
+			m.Up = string(x)
+		} else {
+			m.Down = string(x)
+		}
+	}
+	list := make([]Migration, 0, len(byVersion))
+	for version := 1; version <= len(byVersion); version++ {
+		m, ok := byVersion[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: version %d is missing", ErrInvalidMigrations, version)
+		}
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("%w: version %d has no up script", ErrInvalidMigrations, version)
+		}
+		list = append(list, *m)
+	}
+	return list, nil
+}
+
+// SchemaVersion is the newest migration this version knows, a database with a newer one was
+// written by a newer version
+func SchemaVersion() int {
+	return len(migrations)
+}
+
+// Migrations returns the migrations of this version and when they were applied to the
+// database of the configuration
+func Migrations(cfg *Config) ([]MigrationStatus, error) {
+	conn, err := openMigrationConn(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	err = ensureMigrationTable(conn)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		list = append(list, MigrationStatus{Migration: m, Applied: applied[m.Version]})
+	}
+	return list, nil
+}
+
+// Migrate brings the database of the configuration to the target version, applying the up
+// scripts of newer migrations or the down scripts of older ones, a negative target being the
+// newest.  The server must be stopped while reverting, it expects the newest schema.
+func Migrate(cfg *Config, target int) ([]Migration, error) {
+	conn, err := openMigrationConn(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return migrate(conn, target)
+}
+
+func openMigrationConn(cfg *Config) (*sqlite.Conn, error) {
+	conn, err := sqlite.OpenConn(
+		dbURL(cfg),
+		sqlite.OpenCreate|sqlite.OpenReadWrite|sqlite.OpenWAL,
+	)
+	if err != nil {
+		return nil, err
+	}
+	err = sqlitex.ExecuteTransient(conn, "PRAGMA foreign_keys = ON;", nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// migrate applies the migrations to reach the target version and returns them in the order
+// they ran, each runs in its own transaction along with its record in schema_migrations
+func migrate(conn *sqlite.Conn, target int) ([]Migration, error) {
+	if target < 0 {
+		target = len(migrations)
+	}
+	if target > len(migrations) {
+		return nil, fmt.Errorf("%w: %d, known %d", ErrUnknownMigration, target, len(migrations))
+	}
+	err := ensureMigrationTable(conn)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return nil, err
+	}
+	current := 0
+	for version := range applied {
+		current = max(current, version)
+	}
+	if current > len(migrations) {
+		return nil, fmt.Errorf("%w: version %d, known %d", ErrSchemaTooNew, current, len(migrations))
+	}
+
+	var ran []Migration
+	for _, m := range migrations[:target] {
+		if !applied[m.Version].IsZero() {
+			continue
+		}
+		err := runMigration(conn, m.Up, func() error {
+			return sqlitex.Execute(
+				conn,
+				"insert into schema_migrations (version, name, applied) values (?, ?, ?);",
+				&sqlitex.ExecOptions{Args: []any{m.Version, m.Name, time.Now().UTC().Format(time.RFC3339Nano)}},
+			)
+		})
+		if err != nil {
+			return ran, fmt.Errorf("apply migration %d %s: %w", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	for i := len(migrations) - 1; i >= target; i-- {
+		m := migrations[i]
+		if applied[m.Version].IsZero() {
+			continue
+		}
+		if strings.TrimSpace(m.Down) == "" {
+			return ran, fmt.Errorf("%w: %d %s", ErrIrreversibleMigration, m.Version, m.Name)
+		}
+		err := runMigration(conn, m.Down, func() error {
+			return sqlitex.Execute(
+				conn,
+				"delete from schema_migrations where version = ?;",
+				&sqlitex.ExecOptions{Args: []any{m.Version}},
+			)
+		})
+		if err != nil {
+			return ran, fmt.Errorf("revert migration %d %s: %w", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+func runMigration(conn *sqlite.Conn, script string, record func() error) (err error) {
+	endFn, err := sqlitex.ImmediateTransaction(conn)
+	if err != nil {
+		return err
+	}
+	defer endFn(&err)
+	err = sqlitex.ExecuteScript(conn, script, nil)
+	if err != nil {
+		return err
+	}
+	return record()
+}
+
+// ensureMigrationTable creates the schema_migrations table.  A database migrated before the
+// table existed only counted its migrations in user_version, those are recorded as applied
+// and user_version is cleared so they are not recorded again.
+func ensureMigrationTable(conn *sqlite.Conn) (err error) {
+	endFn, err := sqlitex.ImmediateTransaction(conn)
+	if err != nil {
+		return err
+	}
+	defer endFn(&err)
+
+	err = sqlitex.ExecuteTransient(conn, `create table if not exists schema_migrations (
+  version integer primary key,
+  name text not null,
+  applied timestamp not null
+);`, nil)
+	if err != nil {
+		return err
+	}
+	legacy := 0
+	err = sqlitex.ExecuteTransient(conn, "PRAGMA user_version;", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			legacy = stmt.ColumnInt(0)
+			return nil
+		},
+	})
+	if err != nil || legacy == 0 {
+		return err
+	}
+	if legacy > len(migrations) {
+		return fmt.Errorf("%w: version %d, known %d", ErrSchemaTooNew, legacy, len(migrations))
+	}
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	for _, m := range migrations[:legacy] {
+		err = sqlitex.Execute(
+			conn,
+			"insert or ignore into schema_migrations (version, name, applied) values (?, ?, ?);",
+			&sqlitex.ExecOptions{Args: []any{m.Version, m.Name, now}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return sqlitex.ExecuteTransient(conn, "PRAGMA user_version = 0;", nil)
+}
+
+func appliedMigrations(conn *sqlite.Conn) (map[int]time.Time, error) {
+	applied := make(map[int]time.Time)
+	err := sqlitex.Execute(
+		conn,
+		"select version, applied from schema_migrations;",
+		&sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				t, err := time.Parse(time.RFC3339Nano, stmt.ColumnText(1))
+				applied[stmt.ColumnInt(0)] = t
+				return err
+			},
+		},
+	)
+	return applied, err
+}
+
+// schemaVersion returns the newest migration applied to the database
+func schemaVersion(conn *sqlite.Conn) (int, error) {
+	version := 0
+	err := sqlitex.Execute(
+		conn,
+		"select coalesce(max(version), 0) from schema_migrations;",
+		&sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				version = stmt.ColumnInt(0)
+				return nil
+			},
+		},
+	)
+	return version, err
+}
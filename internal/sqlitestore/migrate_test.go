@@ -0,0 +1,171 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	tests := map[string]struct {
+		files fstest.MapFS
+		want  []int
+		err   error
+	}{
+		"ordered": {
+			files: fstest.MapFS{
+				"m/0002_b.up.sql":   {Data: []byte("create table b (x);")},
+				"m/0001_a.up.sql":   {Data: []byte("create table a (x);")},
+				"m/0001_a.down.sql": {Data: []byte("drop table a;")},
+				"m/README":          {Data: []byte("not a migration")},
+			},
+			want: []int{1, 2},
+		},
+		"gap": {
+			files: fstest.MapFS{
+				"m/0001_a.up.sql": {Data: []byte("create table a (x);")},
+				"m/0003_c.up.sql": {Data: []byte("create table c (x);")},
+			},
+			err: ErrInvalidMigrations,
+		},
+		"no up": {
+			files: fstest.MapFS{
+				"m/0001_a.down.sql": {Data: []byte("drop table a;")},
+			},
+			err: ErrInvalidMigrations,
+		},
+		"bad name": {
+			files: fstest.MapFS{
+				"m/first.up.sql": {Data: []byte("create table a (x);")},
+			},
+			err: ErrInvalidMigrations,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := loadMigrations(tc.files, "m")
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("error want: %v, got: %v", tc.err, err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("migrations want: %v, got: %v", tc.want, got)
+			}
+			for i, m := range got {
+				if m.Version != tc.want[i] {
+					t.Errorf("version %d want: %d, got: %d", i, tc.want[i], m.Version)
+				}
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrationsReversible(t *testing.T) {
+	for _, m := range migrations {
+		if m.Down == "" {
+			t.Errorf("migration %d %s has no down script", m.Version, m.Name)
+		}
+	}
+}
+
+func TestMigrate_DownAndUp(t *testing.T) {
+	dir, err := os.MkdirTemp("", "dbt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := &Config{Directory: dir, Filename: "migrate.db"}
+
+	ran, err := Migrate(cfg, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != SchemaVersion() {
+		t.Errorf("applied want: %d, got: %d", SchemaVersion(), len(ran))
+	}
+
+	ran, err = Migrate(cfg, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != SchemaVersion()-1 || ran[0].Version != SchemaVersion() {
+		t.Errorf("reverted want: %d from %d, got: %v", SchemaVersion()-1, SchemaVersion(), ran)
+	}
+	status, err := Migrations(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if applied := !s.Applied.IsZero(); applied != (s.Version == 1) {
+			t.Errorf("migration %d applied: %v", s.Version, applied)
+		}
+	}
+
+	// reapplying must bring back the devices table as it is used by the store
+	ran, err = Migrate(cfg, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != SchemaVersion()-1 {
+		t.Errorf("reapplied want: %d, got: %d", SchemaVersion()-1, len(ran))
+	}
+	db, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	_, err = Migrate(cfg, SchemaVersion()+1)
+	if !errors.Is(err, ErrUnknownMigration) {
+		t.Errorf("error want: %v, got: %v", ErrUnknownMigration, err)
+	}
+}
+
+func TestMigrate_AdoptsUserVersion(t *testing.T) {
+	dir, err := os.MkdirTemp("", "dbt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := &Config{Directory: dir, Filename: "legacy.db"}
+
+	// a database migrated before schema_migrations only counted its migrations in user_version
+	conn, err := openMigrationConn(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := 5
+	for _, m := range migrations[:legacy] {
+		err = sqlitex.ExecuteScript(conn, m.Up, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = sqlitex.ExecuteTransient(conn, fmt.Sprintf("PRAGMA user_version = %d;", legacy), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	ran, err := Migrate(cfg, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != SchemaVersion()-legacy || ran[0].Version != legacy+1 {
+		t.Errorf("applied want: %d from %d, got: %v", SchemaVersion()-legacy, legacy+1, ran)
+	}
+	ran, err = Migrate(cfg, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != 0 {
+		t.Errorf("second run applied: %v", ran)
+	}
+}
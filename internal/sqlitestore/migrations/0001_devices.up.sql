@@ -0,0 +1,30 @@
+create table devices (
+  addr text primary key,
+  name text,
+  mac text,
+  discoveredat timestamp,
+  discoveredby text,
+  -- Meta
+  metadnsname text,
+  metamanufacturer text,
+  metatags text,
+  -- Server
+  serverports text,
+  serverlastscan timestamp,
+  -- PerfPing
+  perfpingfirstseen timestamp,
+  perfpinglastseen timestamp,
+  perfpingmeanping integer,
+  perfpingmaxping integer,
+  perfpinglastfailed integer,
+  -- Snmp
+  snmpname text,
+  snmpdescription text,
+  snmpcommunity text,
+  snmpport integer,
+  snmplastcheck timestamp,
+  snmphasarptable text,
+  snmplastarptablescan timestamp,
+  snmphasinterfaces text,
+  snmplastinterfacesscan timestamp
+);
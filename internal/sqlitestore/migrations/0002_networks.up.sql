@@ -0,0 +1,6 @@
+create table networks (
+  prefix text primary key,
+  name string,
+  lastscan timestamp,
+  tags text
+);
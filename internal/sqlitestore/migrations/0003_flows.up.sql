@@ -0,0 +1,13 @@
+create table flows (
+  start timestamp,
+  end timestamp,
+  srcaddr text,
+  srcport integer,
+  srcasn text,
+  dstaddr text,
+  dstport integer,
+  dstasn text,
+  protocol text,
+  bytes integer,
+  packets integer
+);
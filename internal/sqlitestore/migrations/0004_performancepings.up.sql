@@ -0,0 +1,8 @@
+create table performancepings (
+  start timestamp,
+  addr text,
+  minimum integer,
+  average integer,
+  maximum integer,
+  loss float
+);
@@ -0,0 +1,7 @@
+create table asns (
+  asn text primary key,
+  country text,
+  name text,
+  iprange text,
+  created timestamp
+);
@@ -0,0 +1,2 @@
+drop index annotations_addr_time;
+drop table annotations;
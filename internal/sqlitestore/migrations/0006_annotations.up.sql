@@ -0,0 +1,7 @@
+create table annotations (
+  time timestamp,
+  addr text,
+  kind text,
+  text text
+);
+create index annotations_addr_time on annotations (addr, time);
@@ -0,0 +1,4 @@
+alter table networks drop column vlanname;
+alter table networks drop column vlanid;
+alter table devices drop column vlanname;
+alter table devices drop column vlanid;
@@ -0,0 +1,4 @@
+alter table devices add column vlanid integer not null default 0;
+alter table devices add column vlanname text not null default '';
+alter table networks add column vlanid integer not null default 0;
+alter table networks add column vlanname text not null default '';
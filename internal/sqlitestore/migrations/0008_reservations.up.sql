@@ -0,0 +1,7 @@
+create table reservations (
+  addr text primary key,
+  kind text,
+  mac text,
+  name text,
+  note text
+);
@@ -0,0 +1,6 @@
+create table tagdefinitions (
+  name text primary key,
+  description text,
+  pinginterval integer,
+  portscaninterval integer
+);
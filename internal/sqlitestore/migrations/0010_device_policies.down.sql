@@ -0,0 +1,2 @@
+alter table devices drop column metapolicyportscan;
+alter table devices drop column metapolicyping;
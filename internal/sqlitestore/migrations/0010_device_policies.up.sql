@@ -0,0 +1,2 @@
+alter table devices add column metapolicyping integer not null default 0;
+alter table devices add column metapolicyportscan integer not null default 0;
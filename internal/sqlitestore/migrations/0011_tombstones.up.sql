@@ -0,0 +1,8 @@
+create table tombstones (
+  kind text,
+  key text,
+  deletedat timestamp,
+  purgeat timestamp,
+  data blob,
+  primary key (kind, key)
+);
@@ -0,0 +1,9 @@
+create table maintenancewindows (
+  name text primary key,
+  scope text,
+  target text,
+  start timestamp,
+  duration integer,
+  repeat text,
+  note text
+);
@@ -0,0 +1,2 @@
+drop index devicechanges_addr_time;
+drop table devicechanges;
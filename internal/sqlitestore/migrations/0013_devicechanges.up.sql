@@ -0,0 +1,9 @@
+create table devicechanges (
+  time timestamp,
+  addr text,
+  field text,
+  old text,
+  new text,
+  source text
+);
+create index devicechanges_addr_time on devicechanges (addr, time);
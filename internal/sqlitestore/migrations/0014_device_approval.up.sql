@@ -0,0 +1 @@
+alter table devices add column metaapproval text not null default '';
@@ -0,0 +1,2 @@
+alter table devices drop column metanotes;
+alter table devices drop column metaowner;
@@ -0,0 +1,2 @@
+alter table devices add column metaowner text not null default '';
+alter table devices add column metanotes text not null default '';
@@ -0,0 +1,3 @@
+alter table devices drop column metasite;
+alter table networks drop column site;
+drop table sites;
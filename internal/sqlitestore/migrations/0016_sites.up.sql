@@ -0,0 +1,6 @@
+create table sites (
+  name text primary key,
+  description text
+);
+alter table networks add column site text not null default '';
+alter table devices add column metasite text not null default '';
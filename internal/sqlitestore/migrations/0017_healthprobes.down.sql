@@ -0,0 +1,2 @@
+drop index healthprobes_start;
+drop table healthprobes;
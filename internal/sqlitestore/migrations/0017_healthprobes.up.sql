@@ -0,0 +1,10 @@
+create table healthprobes (
+  start timestamp,
+  kind text,
+  target text,
+  latency integer,
+  loss float,
+  hops integer,
+  error text
+);
+create index healthprobes_start on healthprobes (start);
@@ -0,0 +1,2 @@
+drop index speedtests_start;
+drop table speedtests;
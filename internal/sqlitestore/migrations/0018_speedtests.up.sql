@@ -0,0 +1,10 @@
+create table speedtests (
+  start timestamp,
+  method text,
+  server text,
+  latency integer,
+  download float,
+  upload float,
+  error text
+);
+create index speedtests_start on speedtests (start);
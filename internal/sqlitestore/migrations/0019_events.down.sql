@@ -0,0 +1,2 @@
+drop index events_time;
+drop table events;
@@ -0,0 +1,7 @@
+create table events (
+  time integer,
+  kind text,
+  type text,
+  message text
+);
+create index events_time on events (time);
@@ -0,0 +1,2 @@
+drop index devices_name;
+drop index devices_mac;
@@ -0,0 +1,2 @@
+create index devices_mac on devices (mac);
+create index devices_name on devices (name collate nocase);
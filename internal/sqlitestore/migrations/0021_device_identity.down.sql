@@ -0,0 +1,4 @@
+alter table devices drop column metadhcpfingerprint;
+alter table devices drop column metadhcphostname;
+alter table devices drop column metamdnsname;
+alter table devices drop column observedmacs;
@@ -0,0 +1,4 @@
+alter table devices add column observedmacs text not null default '';
+alter table devices add column metamdnsname text not null default '';
+alter table devices add column metadhcphostname text not null default '';
+alter table devices add column metadhcpfingerprint text not null default '';
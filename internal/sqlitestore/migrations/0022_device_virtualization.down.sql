@@ -0,0 +1,4 @@
+alter table devices drop column virtualparent;
+alter table devices drop column virtuallastscan;
+alter table devices drop column virtualguests;
+alter table devices drop column virtualplatform;
@@ -0,0 +1,4 @@
+alter table devices add column virtualplatform text not null default '';
+alter table devices add column virtualguests text not null default '';
+alter table devices add column virtuallastscan timestamp not null default '0001-01-01T00:00:00Z';
+alter table devices add column virtualparent text not null default '';
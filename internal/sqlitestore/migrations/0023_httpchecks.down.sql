@@ -0,0 +1,3 @@
+drop index httpcheckresults_start;
+drop table httpcheckresults;
+drop table httpchecks;
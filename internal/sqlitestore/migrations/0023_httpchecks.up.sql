@@ -0,0 +1,17 @@
+create table httpchecks (
+  name text primary key,
+  url text,
+  method text,
+  expectstatus integer,
+  expectbody text,
+  interval integer,
+  insecure integer
+);
+create table httpcheckresults (
+  start timestamp,
+  name text,
+  status integer,
+  latency integer,
+  error text
+);
+create index httpcheckresults_start on httpcheckresults (start);
@@ -0,0 +1,2 @@
+drop index servicecheckresults_start;
+drop table servicecheckresults;
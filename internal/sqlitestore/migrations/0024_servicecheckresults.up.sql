@@ -0,0 +1,8 @@
+create table servicecheckresults (
+  start timestamp,
+  addr text,
+  port integer,
+  latency integer,
+  error text
+);
+create index servicecheckresults_start on servicecheckresults (start);
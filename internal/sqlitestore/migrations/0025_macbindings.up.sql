@@ -0,0 +1,8 @@
+create table macbindings (
+  addr text,
+  mac text,
+  firstseen timestamp,
+  lastseen timestamp,
+  source text,
+  primary key (addr, mac)
+);
@@ -0,0 +1,9 @@
+create table dhcpsightings (
+  kind text,
+  addr text,
+  server text,
+  firstseen timestamp,
+  lastseen timestamp,
+  trusted integer,
+  primary key (kind, addr)
+);
@@ -0,0 +1,2 @@
+alter table devices drop column metadhcpvendorclass;
+alter table devices drop column metadevicetype;
@@ -0,0 +1,2 @@
+alter table devices add column metadevicetype text not null default '';
+alter table devices add column metadhcpvendorclass text not null default '';
@@ -0,0 +1,8 @@
+create table flowtemplates (
+  exporter text,
+  domainid integer,
+  id integer,
+  fields text,
+  updatedat timestamp,
+  primary key (exporter, domainid, id)
+);
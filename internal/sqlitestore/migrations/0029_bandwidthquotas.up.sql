@@ -0,0 +1,8 @@
+create table bandwidthquotas (
+  name text primary key,
+  scope text,
+  target text,
+  period text,
+  bytes integer,
+  note text
+);
@@ -0,0 +1,2 @@
+drop index dnsqueries_addr_domain;
+drop table dnsqueries;
@@ -0,0 +1,7 @@
+create table dnsqueries (
+  time timestamp,
+  addr text,
+  domain text,
+  type text
+);
+create index dnsqueries_addr_domain on dnsqueries (addr, domain);
@@ -0,0 +1 @@
+alter table devices add column link text not null default '';
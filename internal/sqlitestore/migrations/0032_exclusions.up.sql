@@ -0,0 +1,8 @@
+create table exclusions (
+  name text primary key,
+  prefix text,
+  scan integer,
+  ping integer,
+  portscan integer,
+  note text
+);
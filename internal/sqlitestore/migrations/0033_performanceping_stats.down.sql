@@ -0,0 +1,4 @@
+alter table performancepings drop column consecutivefailures;
+alter table performancepings drop column p99;
+alter table performancepings drop column p95;
+alter table performancepings drop column jitter;
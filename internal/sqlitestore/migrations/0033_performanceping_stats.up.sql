@@ -0,0 +1,4 @@
+alter table performancepings add column jitter integer not null default 0;
+alter table performancepings add column p95 integer not null default 0;
+alter table performancepings add column p99 integer not null default 0;
+alter table performancepings add column consecutivefailures integer not null default 0;
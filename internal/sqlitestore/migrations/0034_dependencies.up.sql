@@ -0,0 +1,7 @@
+create table dependencies (
+  name text primary key,
+  parent text,
+  scope text,
+  target text,
+  note text
+);
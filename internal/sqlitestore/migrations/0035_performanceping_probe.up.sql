@@ -0,0 +1 @@
+alter table devices add column perfpingprobe text not null default '';
@@ -0,0 +1 @@
+alter table devices drop column addresses;
@@ -0,0 +1 @@
+alter table devices add column addresses text not null default '';
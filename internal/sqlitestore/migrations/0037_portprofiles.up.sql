@@ -0,0 +1,7 @@
+create table portprofiles (
+  name text primary key,
+  description text,
+  ports text,
+  scope text,
+  target text
+);
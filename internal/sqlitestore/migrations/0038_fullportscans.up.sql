@@ -0,0 +1,8 @@
+create table fullportscans (
+  addr text primary key,
+  nextport integer,
+  openports text,
+  started timestamp,
+  updated timestamp,
+  completed timestamp
+);
@@ -0,0 +1 @@
+alter table devices add column serverbanners text not null default '';
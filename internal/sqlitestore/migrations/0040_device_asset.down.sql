@@ -0,0 +1,4 @@
+alter table devices drop column metaassettag;
+alter table devices drop column metaserial;
+alter table devices drop column metapurchasedate;
+alter table devices drop column metalocation;
@@ -0,0 +1,4 @@
+alter table devices add column metalocation text not null default '';
+alter table devices add column metapurchasedate text not null default '';
+alter table devices add column metaserial text not null default '';
+alter table devices add column metaassettag text not null default '';
@@ -6,8 +6,7 @@ package sqlitestore
 
 import (
 	"context"
-	"fmt"
-	"strconv"
+	"slices"
 	"time"
 
 	"zombiezen.com/go/sqlite"
@@ -36,11 +35,20 @@ func (cs *Store) AddNetflows(ctx context.Context, flows []model.IpFlow) (err err
 	return nil
 }
 
+// GetNetflows returns all flows to or from the addr, including flows which have been archived
 func (cs *Store) GetNetflows(
 	ctx context.Context,
 	addr model.Addr,
 ) (flows []model.IpFlow, err error) {
-	return cs.selectNetflow(ctx, addr)
+	flows, err = cs.selectNetflow(ctx, addr)
+	if err != nil || cs.archiveAfter <= 0 {
+		return flows, err
+	}
+	archived, err := cs.readArchivedNetflows(addr)
+	if err != nil {
+		return flows, err
+	}
+	return append(archived, flows...), nil
 }
 
 func insertNetflow(conn *sqlite.Conn, n model.IpFlow) error {
@@ -76,9 +84,13 @@ func (cs *Store) selectNetflow(
      FROM flows 
     WHERE srcaddr = :srcaddr OR dstaddr = :dstaddr`,
 	)
+	if err != nil {
+		return fs, err
+	}
+	stmt.SetText(":srcaddr", addr.String())
+	stmt.SetText(":dstaddr", addr.String())
 	var hasRow bool
 	for {
-		fmt.Println("device")
 		hasRow, err = stmt.Step()
 		if err != nil {
 			return fs, err
@@ -86,41 +98,44 @@ func (cs *Store) selectNetflow(
 		if !hasRow {
 			break
 		}
-		flow := model.IpFlow{
-			SrcPort: uint16(stmt.GetInt64("srcport")),
-			SrcASN:  stmt.GetText("srcasn"),
-			DstPort: uint16(stmt.GetInt64("dstport")),
-			DstASN:  stmt.GetText("dstasn"),
-			Bytes:   int(stmt.GetInt64("bytes")),
-			Packets: int(stmt.GetInt64("packets")),
-		}
-		flow.Start, err = time.Parse(time.RFC3339Nano, stmt.GetText("start"))
-		if err != nil {
-			return fs, err
-		}
-		flow.End, err = time.Parse(time.RFC3339Nano, stmt.GetText("end"))
-		if err != nil {
-			return fs, err
-		}
-		err = flow.SrcAddr.Scan(stmt.GetText("srcaddr"))
-		if err != nil {
-			return fs, err
-		}
-		err = flow.DstAddr.Scan(stmt.GetText("dstaddr"))
+		flow, err := scanNetflow(stmt)
 		if err != nil {
 			return fs, err
 		}
-		p, err := strconv.Atoi(stmt.GetText("protocol"))
-		if err != nil {
-			return fs, err
-		}
-		flow.Protocol = model.Protocol(p)
-
 		fs = append(fs, flow)
 	}
 	return fs, err
 }
 
+func scanNetflow(stmt *sqlite.Stmt) (flow model.IpFlow, err error) {
+	flow = model.IpFlow{
+		SrcPort: uint16(stmt.GetInt64("srcport")),
+		SrcASN:  stmt.GetText("srcasn"),
+		DstPort: uint16(stmt.GetInt64("dstport")),
+		DstASN:  stmt.GetText("dstasn"),
+		Bytes:   int(stmt.GetInt64("bytes")),
+		Packets: int(stmt.GetInt64("packets")),
+	}
+	flow.Start, err = time.Parse(time.RFC3339Nano, stmt.GetText("start"))
+	if err != nil {
+		return flow, err
+	}
+	flow.End, err = time.Parse(time.RFC3339Nano, stmt.GetText("end"))
+	if err != nil {
+		return flow, err
+	}
+	err = flow.SrcAddr.Scan(stmt.GetText("srcaddr"))
+	if err != nil {
+		return flow, err
+	}
+	err = flow.DstAddr.Scan(stmt.GetText("dstaddr"))
+	if err != nil {
+		return flow, err
+	}
+	flow.Protocol, err = model.ParseProtocol(stmt.GetText("protocol"))
+	return flow, err
+}
+
 func (cs *Store) FlowSummaryByIP(
 	ctx context.Context,
 	addr model.Addr,
@@ -197,3 +212,86 @@ func (cs *Store) selectNetflowsSummaryByIP(
 	}
 	return fs, err
 }
+
+// FlowUsage returns the bytes received and sent by the addr on each day from since on,
+// including the archived flows when since is before the archive boundary
+func (cs *Store) FlowUsage(
+	ctx context.Context,
+	addr model.Addr,
+	since time.Time,
+) ([]model.FlowUsage, error) {
+	usage, err := cs.selectFlowUsage(addr, since)
+	if err != nil {
+		return usage, err
+	}
+	boundary := cs.archiveBoundary()
+	if boundary.IsZero() || !since.Before(boundary) {
+		return usage, nil
+	}
+	archived, err := cs.readArchivedNetflows(addr)
+	if err != nil {
+		return usage, err
+	}
+	days := make(map[string]int, len(usage))
+	for idx, u := range usage {
+		days[u.Day.Format(time.DateOnly)] = idx
+	}
+	for _, f := range archived {
+		if f.Start.Before(since) {
+			continue
+		}
+		day := f.Start.Format(time.DateOnly)
+		idx, ok := days[day]
+		if !ok {
+			d, _ := time.ParseInLocation(time.DateOnly, day, time.Local)
+			usage = append(usage, model.FlowUsage{Day: d})
+			idx = len(usage) - 1
+			days[day] = idx
+		}
+		if f.DstAddr.Compare(addr) == 0 {
+			usage[idx].In += int64(f.Bytes)
+		}
+		if f.SrcAddr.Compare(addr) == 0 {
+			usage[idx].Out += int64(f.Bytes)
+		}
+	}
+	slices.SortFunc(usage, func(a, b model.FlowUsage) int { return a.Day.Compare(b.Day) })
+	return usage, nil
+}
+
+func (cs *Store) selectFlowUsage(addr model.Addr, since time.Time) (usage []model.FlowUsage, err error) {
+	stmt, err := cs.DB.Prepare(
+		`SELECT substr(start, 1, 10) AS day,
+            SUM(CASE WHEN dstaddr = :addr THEN bytes ELSE 0 END) AS recvbytes,
+            SUM(CASE WHEN srcaddr = :addr THEN bytes ELSE 0 END) AS xmitbytes
+       FROM flows
+      WHERE (srcaddr = :addr OR dstaddr = :addr)
+        AND start >= :since
+      GROUP BY day
+      ORDER BY day`)
+	if err != nil {
+		return usage, err
+	}
+	stmt.SetText(":addr", addr.String())
+	stmt.SetText(":since", since.Format(time.RFC3339Nano))
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return usage, err
+		}
+		if !hasRow {
+			break
+		}
+		u := model.FlowUsage{
+			In:  stmt.GetInt64("recvbytes"),
+			Out: stmt.GetInt64("xmitbytes"),
+		}
+		u.Day, err = time.ParseInLocation(time.DateOnly, stmt.GetText("day"), time.Local)
+		if err != nil {
+			return usage, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, nil
+}
@@ -15,6 +15,7 @@ import (
 	"zombiezen.com/go/sqlite/sqlitex"
 
 	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/tracing"
 )
 
 // AddNetwork adds a network to the store, will return error if the network already exists in the store
@@ -33,7 +34,7 @@ func (cs *Store) RemoveNetworkByName(ctx context.Context, name string) error {
 	for idx, n := range cs.networks {
 		if n.Name == name {
 			cs.networks = slices.Delete(cs.networks, idx, idx+1)
-			return cs.saveNetworks(ctx)
+			return cs.deleteNetwork(ctx, n)
 		}
 	}
 	return model.ErrNetworkDoesNotExist
@@ -69,9 +70,9 @@ func (cs *Store) UpsertNetwork(ctx context.Context, newnetwork model.Network) (e
 // upsertNetwork will either add the given network and if it already exists then it will run an update
 func upsertNetwork(conn *sqlite.Conn, n model.Network) error {
 	stmt, err := conn.Prepare(
-		`insert into networks (prefix, name, lastscan, tags)
-    values (:prefix, :name, :lastscan, :tags)
-    on conflict (prefix) do update set name=:name, lastscan=:lastscan, tags=:tags`)
+		`insert into networks (prefix, name, lastscan, tags, vlanid, vlanname, site)
+    values (:prefix, :name, :lastscan, :tags, :vlanid, :vlanname, :site)
+    on conflict (prefix) do update set name=:name, lastscan=:lastscan, tags=:tags, vlanid=:vlanid, vlanname=:vlanname, site=:site`)
 	if err != nil {
 		return err
 	}
@@ -79,6 +80,9 @@ func upsertNetwork(conn *sqlite.Conn, n model.Network) error {
 	stmt.SetText(":name", n.Name)
 	stmt.SetText(":lastscan", n.LastScan.Format(time.RFC3339Nano))
 	stmt.SetText(":tags", n.Tags.String())
+	stmt.SetInt64(":vlanid", int64(n.VLAN.ID))
+	stmt.SetText(":vlanname", n.VLAN.Name)
+	stmt.SetText(":site", n.Site)
 
 	_, err = stmt.Step()
 
@@ -120,7 +124,10 @@ func (cs *Store) CountNetworks(ctx context.Context) int {
 }
 
 func (cs *Store) saveNetworks(ctx context.Context) (err error) {
-	conn, err := cs.Pool.Get(ctx)
+	ctx, span := tracing.Start(ctx, "sqlite.savenetworks")
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := cs.Pool.Take(ctx)
 	if err != nil {
 		return err
 	}
@@ -138,6 +145,25 @@ func (cs *Store) saveNetworks(ctx context.Context) (err error) {
 	return nil
 }
 
+func (cs *Store) deleteNetwork(ctx context.Context, n model.Network) (err error) {
+	ctx, span := tracing.Start(ctx, "sqlite.deletenetwork")
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from networks where prefix = :prefix`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":prefix", n.Prefix.String())
+	_, err = stmt.Step()
+	return err
+}
+
 func (cs *Store) readNetworksInitial(ctx context.Context) (err error) {
 	err = cs.readNetworks(ctx)
 	if err != nil && strings.EqualFold(err.Error(), "no such table: networks") {
@@ -153,7 +179,7 @@ func (cs *Store) readNetworks(ctx context.Context) (err error) {
 
 func (cs *Store) selectNetworks(ctx context.Context) (fs []model.Network, err error) {
 	stmt, err := cs.DB.Prepare(
-		`select name, prefix, lastscan, tags from networks`)
+		`select name, prefix, lastscan, tags, vlanid, vlanname, site from networks`)
 	if err != nil {
 		return fs, err
 	}
@@ -168,6 +194,11 @@ func (cs *Store) selectNetworks(ctx context.Context) (fs []model.Network, err er
 		}
 		n := model.Network{
 			Name: stmt.GetText("name"),
+			Site: stmt.GetText("site"),
+			VLAN: model.VLAN{
+				ID:   int(stmt.GetInt64("vlanid")),
+				Name: stmt.GetText("vlanname"),
+			},
 		}
 		err = n.Prefix.Scan(stmt.GetText("prefix"))
 		if err != nil {
@@ -177,7 +208,7 @@ func (cs *Store) selectNetworks(ctx context.Context) (fs []model.Network, err er
 		if err != nil {
 			return fs, err
 		}
-		err = n.Tags.Scan(stmt.GetText("scan"))
+		err = n.Tags.Scan(stmt.GetText("tags"))
 		if err != nil {
 			return fs, err
 		}
@@ -397,6 +397,13 @@ func TestSqliteStore_RemoveNetworkByName(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	err = db.readNetworks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.CountNetworks(ctx) != 0 {
+		t.Errorf("removed network was read back from the database")
+	}
 
 	err = db.RemoveNetworkByName(ctx, "notfound")
 	if err == nil {
@@ -13,6 +13,7 @@ import (
 
 	"github.com/networkables/mason/internal/model"
 	"github.com/networkables/mason/internal/pinger"
+	"github.com/networkables/mason/internal/tracing"
 	"github.com/networkables/mason/nettools"
 )
 
@@ -23,7 +24,10 @@ func (cs *Store) WritePerformancePing(
 	device model.Device,
 	point nettools.Icmp4EchoResponseStatistics,
 ) (err error) {
-	conn, err := cs.Pool.Get(ctx)
+	ctx, span := tracing.Start(ctx, "sqlite.writeping", tracing.AddrKey.String(device.Addr.String()))
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := cs.Pool.Take(ctx)
 	if err != nil {
 		return err
 	}
@@ -46,10 +50,21 @@ func (cs *Store) ReadPerformancePings(
 	device model.Device,
 	duration time.Duration,
 ) (points []pinger.Point, err error) {
-	points, err = cs.selectPerformancePings(ctx, device.Addr, time.Now().Add(-1*duration))
+	ctx, span := tracing.Start(ctx, "sqlite.readpings", tracing.AddrKey.String(device.Addr.String()))
+	defer func() { tracing.End(span, err) }()
+
+	from := time.Now().Add(-1 * duration)
+	points, err = cs.selectPerformancePings(ctx, device.Addr, from)
 	if err != nil {
 		return points, err
 	}
+	if from.Before(cs.archiveBoundary()) {
+		archived, err := cs.readArchivedPerformancePings(device.Addr, from)
+		if err != nil {
+			return points, err
+		}
+		points = append(archived, points...)
+	}
 	return points, nil
 }
 
@@ -60,7 +75,7 @@ func (cs *Store) selectPerformancePings(
 ) (points []pinger.Point, err error) {
 	stmt, err := cs.DB.Prepare(
 		`select 
-      start, minimum, average, maximum, loss
+      start, minimum, average, maximum, loss, jitter, p95, p99, consecutivefailures
     from performancepings
     where addr = :addr and start > :start`)
 	if err != nil {
@@ -84,6 +99,11 @@ func (cs *Store) selectPerformancePings(
 			Average: time.Duration(stmt.GetInt64("average")),
 			Maximum: time.Duration(stmt.GetInt64("maximum")),
 			Loss:    stmt.GetFloat("loss"),
+
+			Jitter:              time.Duration(stmt.GetInt64("jitter")),
+			P95:                 time.Duration(stmt.GetInt64("p95")),
+			P99:                 time.Duration(stmt.GetInt64("p99")),
+			ConsecutiveFailures: int(stmt.GetInt64("consecutivefailures")),
 		}
 		p.Start, err = time.Parse(time.RFC3339Nano, stmt.GetText("start"))
 
@@ -93,6 +113,25 @@ func (cs *Store) selectPerformancePings(
 	return points, err
 }
 
+// MergePerformancePings moves the live ping history of the from addr onto the to addr,
+// archived points stay under the from addr
+func (cs *Store) MergePerformancePings(ctx context.Context, from, to model.Addr) error {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`update performancepings set addr = :to where addr = :from`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":from", from.String())
+	stmt.SetText(":to", to.String())
+	_, err = stmt.Step()
+	return err
+}
+
 func insertPerformancePing(
 	conn *sqlite.Conn,
 	ts time.Time,
@@ -100,8 +139,10 @@ func insertPerformancePing(
 	p nettools.Icmp4EchoResponseStatistics,
 ) (err error) {
 	stmt, err := conn.Prepare(
-		`insert into performancepings (start, addr, minimum, average, maximum, loss)
-    values (:start, :addr, :minimum, :average, :maximum, :loss)`)
+		`insert into performancepings
+      (start, addr, minimum, average, maximum, loss, jitter, p95, p99, consecutivefailures)
+    values
+      (:start, :addr, :minimum, :average, :maximum, :loss, :jitter, :p95, :p99, :consecutivefailures)`)
 	if err != nil {
 		return err
 	}
@@ -111,6 +152,10 @@ func insertPerformancePing(
 	stmt.SetInt64(":average", p.Mean.Nanoseconds())
 	stmt.SetInt64(":maximum", p.Maximum.Nanoseconds())
 	stmt.SetFloat(":loss", p.PacketLoss)
+	stmt.SetInt64(":jitter", p.Jitter.Nanoseconds())
+	stmt.SetInt64(":p95", p.P95.Nanoseconds())
+	stmt.SetInt64(":p99", p.P99.Nanoseconds())
+	stmt.SetInt64(":consecutivefailures", int64(p.ConsecutiveFailures))
 
 	_, err = stmt.Step()
 
@@ -70,3 +70,100 @@ func TestSqliteStore_WritePerformancePing(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
 }
+
+func TestSqliteStore_PerformancePingStats(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	dev := model.Device{Addr: model.MustParseAddr("192.168.86.1")}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	err := db.WritePerformancePing(
+		ctx,
+		now,
+		dev,
+		nettools.Icmp4EchoResponseStatistics{
+			Mean:                10 * time.Millisecond,
+			Jitter:              3 * time.Millisecond,
+			P95:                 40 * time.Millisecond,
+			P99:                 55 * time.Millisecond,
+			ConsecutiveFailures: 2,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	points, err := db.ReadPerformancePings(ctx, dev, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []pinger.Point{{
+		Start:               now,
+		Average:             10 * time.Millisecond,
+		Jitter:              3 * time.Millisecond,
+		P95:                 40 * time.Millisecond,
+		P99:                 55 * time.Millisecond,
+		ConsecutiveFailures: 2,
+	}}
+	diff := cmp.Diff(
+		want,
+		points,
+		cmpopts.EquateApproxTime(time.Microsecond),
+		cmpopts.EquateComparable(netip.Addr{}),
+		cmpopts.IgnoreUnexported(model.Device{}),
+	)
+	if diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestSqliteStore_MergePerformancePings(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	old := model.Device{Addr: model.MustParseAddr("192.168.86.1")}
+	next := model.Device{Addr: model.MustParseAddr("192.168.86.9")}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for i, d := range []model.Device{old, next, old} {
+		err := db.WritePerformancePing(
+			ctx,
+			now.Add(time.Duration(i-3)*time.Minute),
+			d,
+			nettools.Icmp4EchoResponseStatistics{Mean: time.Duration(i)},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := db.MergePerformancePings(ctx, old.Addr, next.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	points, err := db.ReadPerformancePings(ctx, next, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]time.Duration, 0, len(points))
+	for _, p := range points {
+		got = append(got, p.Average)
+	}
+	if diff := cmp.Diff([]time.Duration{0, 1, 2}, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	points, err = db.ReadPerformancePings(ctx, old, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 0 {
+		t.Errorf("old addr still has %d points", len(points))
+	}
+}
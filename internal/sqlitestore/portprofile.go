@@ -0,0 +1,101 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// UpsertPortProfile adds the port profile or replaces the existing one with the same name
+func (cs *Store) UpsertPortProfile(ctx context.Context, pp model.PortProfile) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	stmt, err := conn.Prepare(
+		`insert into portprofiles (name, description, ports, scope, target)
+    values (:name, :description, :ports, :scope, :target)
+    on conflict (name) do update set
+      description=:description, ports=:ports, scope=:scope, target=:target`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":name", pp.Name)
+	stmt.SetText(":description", pp.Description)
+	stmt.SetText(":ports", pp.Ports.String())
+	stmt.SetText(":scope", string(pp.Scope))
+	stmt.SetText(":target", pp.Target)
+
+	_, err = stmt.Step()
+	return err
+}
+
+// RemovePortProfile deletes the named port profile
+func (cs *Store) RemovePortProfile(ctx context.Context, name string) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from portprofiles where name = :name`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":name", name)
+	_, err = stmt.Step()
+	if err != nil {
+		return err
+	}
+	if conn.Changes() == 0 {
+		return model.ErrPortProfileDoesNotExist
+	}
+	return nil
+}
+
+// ListPortProfiles returns all port profiles ordered by name
+func (cs *Store) ListPortProfiles(ctx context.Context) (pps []model.PortProfile, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      name, description, ports, scope, target
+    from portprofiles
+    order by name`)
+	if err != nil {
+		return pps, err
+	}
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return pps, err
+		}
+		if !hasRow {
+			break
+		}
+		pp := model.PortProfile{
+			Name:        stmt.GetText("name"),
+			Description: stmt.GetText("description"),
+			Scope:       model.PortProfileScope(stmt.GetText("scope")),
+			Target:      stmt.GetText("target"),
+		}
+		pp.Ports, err = model.ParsePortRanges(stmt.GetText("ports"))
+		if err != nil {
+			return pps, err
+		}
+		pps = append(pps, pp)
+	}
+	return pps, nil
+}
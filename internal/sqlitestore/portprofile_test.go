@@ -0,0 +1,67 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_PortProfiles(t *testing.T) {
+	ctx := context.Background()
+	web := model.PortProfile{
+		Name:        "web",
+		Description: "web servers",
+		Ports:       model.PortRanges{{First: 80, Last: 80}, {First: 8000, Last: 8100}},
+		Scope:       model.PortProfileScopeTag,
+		Target:      "web",
+	}
+	lab := model.PortProfile{
+		Name:   "lab",
+		Ports:  model.PortRanges{{First: 22, Last: 22}},
+		Scope:  model.PortProfileScopeNetwork,
+		Target: "lab",
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, pp := range []model.PortProfile{web, lab} {
+		err := db.UpsertPortProfile(ctx, pp)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	web.Ports = append(web.Ports, model.PortRange{First: 443, Last: 443})
+	err := db.UpsertPortProfile(ctx, web)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.ListPortProfiles(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff([]model.PortProfile{lab, web}, got)
+	if diff != "" {
+		t.Errorf("port profiles mismatch (-want +got):\n%s", diff)
+	}
+
+	err = db.RemovePortProfile(ctx, web.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.RemovePortProfile(ctx, web.Name)
+	if !errors.Is(err, model.ErrPortProfileDoesNotExist) {
+		t.Errorf("remove missing want: %v, got: %v", model.ErrPortProfileDoesNotExist, err)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// UpsertBandwidthQuota adds the quota or replaces the existing one with the same name
+func (cs *Store) UpsertBandwidthQuota(ctx context.Context, q model.BandwidthQuota) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	stmt, err := conn.Prepare(
+		`insert into bandwidthquotas (name, scope, target, period, bytes, note)
+    values (:name, :scope, :target, :period, :bytes, :note)
+    on conflict (name) do update set
+      scope=:scope, target=:target, period=:period, bytes=:bytes, note=:note`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":name", q.Name)
+	stmt.SetText(":scope", string(q.Scope))
+	stmt.SetText(":target", q.Target)
+	stmt.SetText(":period", string(q.Period))
+	stmt.SetInt64(":bytes", q.Bytes)
+	stmt.SetText(":note", q.Note)
+
+	_, err = stmt.Step()
+	return err
+}
+
+// RemoveBandwidthQuota deletes the named quota
+func (cs *Store) RemoveBandwidthQuota(ctx context.Context, name string) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from bandwidthquotas where name = :name`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":name", name)
+	_, err = stmt.Step()
+	if err != nil {
+		return err
+	}
+	if conn.Changes() == 0 {
+		return model.ErrBandwidthQuotaDoesNotExist
+	}
+	return nil
+}
+
+// ListBandwidthQuotas returns all quotas ordered by name
+func (cs *Store) ListBandwidthQuotas(
+	ctx context.Context,
+) (quotas []model.BandwidthQuota, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      name, scope, target, period, bytes, note
+    from bandwidthquotas
+    order by name`)
+	if err != nil {
+		return quotas, err
+	}
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return quotas, err
+		}
+		if !hasRow {
+			break
+		}
+		quotas = append(quotas, model.BandwidthQuota{
+			Name:   stmt.GetText("name"),
+			Scope:  model.QuotaScope(stmt.GetText("scope")),
+			Target: stmt.GetText("target"),
+			Period: model.QuotaPeriod(stmt.GetText("period")),
+			Bytes:  stmt.GetInt64("bytes"),
+			Note:   stmt.GetText("note"),
+		})
+	}
+	return quotas, nil
+}
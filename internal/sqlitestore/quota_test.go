@@ -0,0 +1,117 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_BandwidthQuotas(t *testing.T) {
+	ctx := context.Background()
+	backups := model.BandwidthQuota{
+		Name:   "backups",
+		Scope:  model.QuotaScopeTag,
+		Target: "nas",
+		Period: model.QuotaDaily,
+		Bytes:  50 << 30,
+		Note:   "offsite sync",
+	}
+	metered := model.BandwidthQuota{
+		Name:   "metered",
+		Scope:  model.QuotaScopeDevice,
+		Target: "192.168.86.20",
+		Period: model.QuotaMonthly,
+		Bytes:  10 << 30,
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, q := range []model.BandwidthQuota{metered, backups} {
+		err := db.UpsertBandwidthQuota(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups.Bytes = 100 << 30
+	err := db.UpsertBandwidthQuota(ctx, backups)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.ListBandwidthQuotas(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff([]model.BandwidthQuota{backups, metered}, got)
+	if diff != "" {
+		t.Errorf("bandwidth quotas mismatch (-want +got):\n%s", diff)
+	}
+
+	err = db.RemoveBandwidthQuota(ctx, metered.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.RemoveBandwidthQuota(ctx, metered.Name)
+	if !errors.Is(err, model.ErrBandwidthQuotaDoesNotExist) {
+		t.Errorf("remove missing want: %v, got: %v", model.ErrBandwidthQuotaDoesNotExist, err)
+	}
+}
+
+func TestSqliteStore_FlowUsage(t *testing.T) {
+	ctx := context.Background()
+	addr := model.MustParseAddr("192.168.86.20")
+	other := model.MustParseAddr("1.1.1.1")
+	day1 := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	flow := func(start time.Time, src, dst model.Addr, bytes int) model.IpFlow {
+		return model.IpFlow{
+			SrcAddr:  src,
+			DstAddr:  dst,
+			Start:    start,
+			End:      start.Add(time.Minute),
+			Bytes:    bytes,
+			Protocol: 6,
+		}
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	err := db.AddNetflows(ctx, []model.IpFlow{
+		flow(day1.Add(-time.Hour), other, addr, 1000), // before since
+		flow(day1.Add(time.Hour), other, addr, 300),
+		flow(day1.Add(2*time.Hour), addr, other, 200),
+		flow(day2.Add(time.Hour), other, addr, 50),
+		flow(day2.Add(time.Hour), other, model.MustParseAddr("192.168.86.21"), 7000),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.FlowUsage(ctx, addr, day1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.FlowUsage{
+		{Day: day1, In: 300, Out: 200},
+		{Day: day2, In: 50},
+	}
+	diff := cmp.Diff(want, got)
+	if diff != "" {
+		t.Errorf("flow usage mismatch (-want +got):\n%s", diff)
+	}
+}
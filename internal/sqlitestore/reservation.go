@@ -0,0 +1,106 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// UpsertReservation adds the reservation or replaces the existing one for the same addr
+func (cs *Store) UpsertReservation(ctx context.Context, r model.Reservation) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	stmt, err := conn.Prepare(
+		`insert into reservations (addr, kind, mac, name, note)
+    values (:addr, :kind, :mac, :name, :note)
+    on conflict (addr) do update set
+      kind=:kind, mac=:mac, name=:name, note=:note`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":addr", r.Addr.String())
+	stmt.SetText(":kind", string(r.Kind))
+	stmt.SetText(":mac", r.MAC.String())
+	stmt.SetText(":name", r.Name)
+	stmt.SetText(":note", r.Note)
+
+	_, err = stmt.Step()
+	return err
+}
+
+// RemoveReservation deletes the reservation for the addr
+func (cs *Store) RemoveReservation(ctx context.Context, addr model.Addr) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from reservations where addr = :addr`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":addr", addr.String())
+	_, err = stmt.Step()
+	if err != nil {
+		return err
+	}
+	if conn.Changes() == 0 {
+		return model.ErrReservationDoesNotExist
+	}
+	return nil
+}
+
+// ListReservations returns all stored reservations ordered by addr
+func (cs *Store) ListReservations(
+	ctx context.Context,
+) (reservations []model.Reservation, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      addr, kind, mac, name, note
+    from reservations
+    order by addr`)
+	if err != nil {
+		return reservations, err
+	}
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return reservations, err
+		}
+		if !hasRow {
+			break
+		}
+		r := model.Reservation{
+			Kind: model.ReservationKind(stmt.GetText("kind")),
+			Name: stmt.GetText("name"),
+			Note: stmt.GetText("note"),
+		}
+		r.Addr, err = model.ParseAddr(stmt.GetText("addr"))
+		if err != nil {
+			return reservations, err
+		}
+		err = r.MAC.Scan(stmt.GetText("mac"))
+		if err != nil {
+			return reservations, err
+		}
+		reservations = append(reservations, r)
+	}
+	return reservations, nil
+}
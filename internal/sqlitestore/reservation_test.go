@@ -0,0 +1,71 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_Reservations(t *testing.T) {
+	ctx := context.Background()
+	gateway := model.Reservation{
+		Addr: model.MustParseAddr("192.168.86.1"),
+		Kind: model.ReservationReserved,
+		Note: "gateway",
+	}
+	nas := model.Reservation{
+		Addr: model.MustParseAddr("192.168.86.2"),
+		Kind: model.ReservationStatic,
+		MAC:  model.MustParseMAC("00:00:5e:00:53:01"),
+		Name: "nas",
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, r := range []model.Reservation{gateway, nas} {
+		err := db.UpsertReservation(ctx, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	gateway.Note = "core router"
+	err := db.UpsertReservation(ctx, gateway)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.ListReservations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff(
+		[]model.Reservation{gateway, nas},
+		got,
+		cmpopts.EquateComparable(netip.Addr{}),
+	)
+	if diff != "" {
+		t.Errorf("reservations mismatch (-want +got):\n%s", diff)
+	}
+
+	err = db.RemoveReservation(ctx, nas.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.RemoveReservation(ctx, nas.Addr)
+	if !errors.Is(err, model.ErrReservationDoesNotExist) {
+		t.Errorf("remove missing want: %v, got: %v", model.ErrReservationDoesNotExist, err)
+	}
+}
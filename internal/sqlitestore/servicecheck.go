@@ -0,0 +1,119 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// WriteServiceCheckResults stores the results of a run of the service checks
+func (cs *Store) WriteServiceCheckResults(
+	ctx context.Context,
+	results []model.ServiceCheckResult,
+) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	for _, r := range results {
+		err = insertServiceCheckResult(conn, r)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertServiceCheckResult(conn *sqlite.Conn, r model.ServiceCheckResult) error {
+	stmt, err := conn.Prepare(
+		`insert into servicecheckresults (start, addr, port, latency, error)
+    values (:start, :addr, :port, :latency, :error)`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":start", r.Start.Format(time.RFC3339Nano))
+	stmt.SetText(":addr", r.Addr.String())
+	stmt.SetInt64(":port", int64(r.Port))
+	stmt.SetInt64(":latency", r.Latency.Nanoseconds())
+	stmt.SetText(":error", r.Err)
+	_, err = stmt.Step()
+	return err
+}
+
+// ReadServiceCheckResults returns the results from Now() minus the duration, oldest first
+func (cs *Store) ReadServiceCheckResults(
+	ctx context.Context,
+	duration time.Duration,
+) (results []model.ServiceCheckResult, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      start, addr, port, latency, error
+    from servicecheckresults
+    where start > :start
+    order by start`)
+	if err != nil {
+		return results, err
+	}
+	stmt.SetText(":start", time.Now().Add(-1*duration).Format(time.RFC3339Nano))
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return results, err
+		}
+		if !hasRow {
+			break
+		}
+		r := model.ServiceCheckResult{
+			Port:    int(stmt.GetInt64("port")),
+			Latency: time.Duration(stmt.GetInt64("latency")),
+			Err:     stmt.GetText("error"),
+		}
+		r.Addr, err = model.ParseAddr(stmt.GetText("addr"))
+		if err != nil {
+			return results, err
+		}
+		r.Start, err = time.Parse(time.RFC3339Nano, stmt.GetText("start"))
+		if err != nil {
+			return results, err
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// PurgeServiceCheckResults removes the results started before the cutoff, returns the
+// number removed
+func (cs *Store) PurgeServiceCheckResults(ctx context.Context, cutoff time.Time) (int, error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from servicecheckresults where start < :cutoff`)
+	if err != nil {
+		return 0, err
+	}
+	stmt.SetText(":cutoff", cutoff.Format(time.RFC3339Nano))
+	_, err = stmt.Step()
+	if err != nil {
+		return 0, err
+	}
+	return conn.Changes(), nil
+}
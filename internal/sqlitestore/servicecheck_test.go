@@ -0,0 +1,60 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_ServiceCheckResults(t *testing.T) {
+	ctx := context.Background()
+	addr := model.MustParseAddr("192.168.1.20")
+	now := time.Now()
+	results := []model.ServiceCheckResult{
+		{Start: now.Add(-2 * time.Hour), Addr: addr, Port: 22, Latency: time.Millisecond},
+		{Start: now.Add(-time.Minute), Addr: addr, Port: 443, Err: "connection refused"},
+		{Start: now, Addr: addr, Port: 22, Latency: 2 * time.Millisecond},
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	err := db.WriteServiceCheckResults(ctx, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.ReadServiceCheckResults(ctx, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff(
+		results[1:],
+		got,
+		cmpopts.EquateApproxTime(time.Millisecond),
+		cmpopts.EquateComparable(netip.Addr{}),
+	)
+	if diff != "" {
+		t.Errorf("service check results mismatch (-want +got):\n%s", diff)
+	}
+
+	removed, err := db.PurgeServiceCheckResults(ctx, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("purge want: 1, got: %d", removed)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// UpsertSite adds the site or replaces the existing one with the same name
+func (cs *Store) UpsertSite(ctx context.Context, site model.Site) error {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(
+		`insert into sites (name, description)
+    values (:name, :description)
+    on conflict (name) do update set description=:description`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":name", site.Name)
+	stmt.SetText(":description", site.Description)
+
+	_, err = stmt.Step()
+	return err
+}
+
+// RemoveSite deletes the named site
+func (cs *Store) RemoveSite(ctx context.Context, name string) error {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from sites where name = :name`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":name", name)
+	_, err = stmt.Step()
+	if err != nil {
+		return err
+	}
+	if conn.Changes() == 0 {
+		return model.ErrSiteDoesNotExist
+	}
+	return nil
+}
+
+// ListSites returns all sites ordered by name
+func (cs *Store) ListSites(ctx context.Context) (sites []model.Site, err error) {
+	stmt, err := cs.DB.Prepare(`select name, description from sites order by name`)
+	if err != nil {
+		return sites, err
+	}
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return sites, err
+		}
+		if !hasRow {
+			break
+		}
+		sites = append(sites, model.Site{
+			Name:        stmt.GetText("name"),
+			Description: stmt.GetText("description"),
+		})
+	}
+	return sites, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_Sites(t *testing.T) {
+	ctx := context.Background()
+	hq := model.Site{Name: "hq", Description: "head office"}
+	branch := model.Site{Name: "branch"}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, site := range []model.Site{hq, branch} {
+		err := db.UpsertSite(ctx, site)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	branch.Description = "remote office"
+	err := db.UpsertSite(ctx, branch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.ListSites(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff([]model.Site{branch, hq}, got)
+	if diff != "" {
+		t.Errorf("sites mismatch (-want +got):\n%s", diff)
+	}
+
+	err = db.RemoveSite(ctx, branch.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.RemoveSite(ctx, branch.Name)
+	if !errors.Is(err, model.ErrSiteDoesNotExist) {
+		t.Errorf("remove missing want: %v, got: %v", model.ErrSiteDoesNotExist, err)
+	}
+}
+
+func TestSqliteStore_NetworkSite(t *testing.T) {
+	ctx := context.Background()
+	network := model.Network{
+		Name:   "branch",
+		Prefix: model.MustParsePrefix("10.1.0.0/24"),
+		Site:   "branch",
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	err := db.AddNetwork(ctx, network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.readNetworks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := db.GetNetworkByName(ctx, network.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Site != network.Site {
+		t.Errorf("site want: %q, got: %q", network.Site, got.Site)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// WriteSpeedTest stores the result of a bandwidth test
+func (cs *Store) WriteSpeedTest(ctx context.Context, st model.SpeedTest) error {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(
+		`insert into speedtests (start, method, server, latency, download, upload, error)
+    values (:start, :method, :server, :latency, :download, :upload, :error)`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":start", st.Start.Format(time.RFC3339Nano))
+	stmt.SetText(":method", st.Method)
+	stmt.SetText(":server", st.Server)
+	stmt.SetInt64(":latency", st.Latency.Nanoseconds())
+	stmt.SetFloat(":download", st.Download)
+	stmt.SetFloat(":upload", st.Upload)
+	stmt.SetText(":error", st.Err)
+	_, err = stmt.Step()
+	return err
+}
+
+// ReadSpeedTests returns the bandwidth tests from Now() minus the duration, oldest first
+func (cs *Store) ReadSpeedTests(
+	ctx context.Context,
+	duration time.Duration,
+) (tests []model.SpeedTest, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      start, method, server, latency, download, upload, error
+    from speedtests
+    where start > :start
+    order by start`)
+	if err != nil {
+		return tests, err
+	}
+	stmt.SetText(":start", time.Now().Add(-1*duration).Format(time.RFC3339Nano))
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return tests, err
+		}
+		if !hasRow {
+			break
+		}
+		st := model.SpeedTest{
+			Method:   stmt.GetText("method"),
+			Server:   stmt.GetText("server"),
+			Latency:  time.Duration(stmt.GetInt64("latency")),
+			Download: stmt.GetFloat("download"),
+			Upload:   stmt.GetFloat("upload"),
+			Err:      stmt.GetText("error"),
+		}
+		st.Start, err = time.Parse(time.RFC3339Nano, stmt.GetText("start"))
+		if err != nil {
+			return tests, err
+		}
+		tests = append(tests, st)
+	}
+	return tests, nil
+}
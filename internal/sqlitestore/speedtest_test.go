@@ -0,0 +1,62 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_SpeedTests(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+
+	old := model.SpeedTest{Start: now.Add(-48 * time.Hour), Method: "http", Download: 1e6}
+	iperf := model.SpeedTest{
+		Start:    now.Add(-time.Minute),
+		Method:   "iperf3",
+		Server:   "iperf.example.com:5201",
+		Download: 9.4e8,
+		Upload:   4.1e7,
+	}
+	failed := model.SpeedTest{
+		Start:   now,
+		Method:  "http",
+		Server:  "https://speed.example.com",
+		Latency: 30 * time.Millisecond,
+		Err:     "timeout",
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, st := range []model.SpeedTest{old, failed, iperf} {
+		err := db.WriteSpeedTest(ctx, st)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := db.ReadSpeedTests(ctx, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff(
+		[]model.SpeedTest{iperf, failed},
+		got,
+		cmpopts.EquateApproxTime(time.Millisecond),
+	)
+	if diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -8,138 +8,92 @@ import (
 	"context"
 	"errors"
 	"os"
+	"sync"
+	"time"
 
-	"github.com/charmbracelet/log"
 	"zombiezen.com/go/sqlite"
-	"zombiezen.com/go/sqlite/sqlitemigration"
 	"zombiezen.com/go/sqlite/sqlitex"
 
+	"github.com/networkables/mason/internal/logging"
 	"github.com/networkables/mason/internal/model"
 )
 
+var logger = logging.For(logging.Store)
+
 type Store struct {
 	DB   *sqlite.Conn
-	Pool *sqlitemigration.Pool
+	Pool *sqlitex.Pool
 	url  string
 
 	directory string
 	filename  string
 	networks  []model.Network
-	devices   []model.Device
-}
 
-func newSqliteDatabase(cfg *Config) *Store {
-	schema := sqlitemigration.Schema{
-		Migrations: []string{
-			`create table devices (
-  addr text primary key,
-  name text,
-  mac text,
-  discoveredat timestamp,
-  discoveredby text,
-  -- Meta
-  metadnsname text,
-  metamanufacturer text,
-  metatags text,
-  -- Server
-  serverports text,
-  serverlastscan timestamp,
-  -- PerfPing
-  perfpingfirstseen timestamp,
-  perfpinglastseen timestamp,
-  perfpingmeanping integer,
-  perfpingmaxping integer,
-  perfpinglastfailed integer,
-  -- Snmp
-  snmpname text,
-  snmpdescription text,
-  snmpcommunity text,
-  snmpport integer,
-  snmplastcheck timestamp,
-  snmphasarptable text,
-  snmplastarptablescan timestamp,
-  snmphasinterfaces text,
-  snmplastinterfacesscan timestamp
-);`,
-
-			`create table networks (
-  prefix text primary key,
-  name string,
-  lastscan timestamp,
-  tags text
-);`,
-
-			`create table flows (
-  start timestamp,
-  end timestamp,
-  srcaddr text,
-  srcport integer,
-  srcasn text,
-  dstaddr text,
-  dstport integer,
-  dstasn text,
-  protocol text,
-  bytes integer,
-  packets integer
-);`,
-
-			`create table performancepings (
-  start timestamp,
-  addr text,
-  minimum integer,
-  average integer,
-  maximum integer,
-  loss float
-);`,
-
-			`create table asns (
-  asn text primary key,
-  country text,
-  name text,
-  iprange text,
-  created timestamp
-);`,
-		},
-	}
+	// devices caches the devices table, indexed by addr so a change only writes its own row
+	// and by mac and lowercased name for the discovery lookups
+	devices     []model.Device
+	deviceIndex map[model.Addr]int
+	macIndex    map[string][]model.Addr
+	nameIndex   map[string][]model.Addr
+	deviceLock  sync.RWMutex
 
-	var url string
+	archiveAfter     time.Duration
+	archiveDirectory string
+}
 
-	if cfg.Filename != "" {
-		// url = "file:"
-		if cfg.Directory != "" {
-			ensureDirectory(cfg.Directory)
-			url += cfg.Directory + "/"
-		}
-		url += cfg.Filename
-	}
-	url += cfg.URL
+func newSqliteDatabase(cfg *Config) *Store {
+	url := dbURL(cfg)
 
-	pool := sqlitemigration.NewPool(url, schema, sqlitemigration.Options{
+	pool, err := sqlitex.NewPool(url, sqlitex.PoolOptions{
 		Flags:    sqlite.OpenCreate | sqlite.OpenReadWrite | sqlite.OpenWAL,
 		PoolSize: cfg.MaxOpenConnections,
 		PrepareConn: func(conn *sqlite.Conn) error {
 			return sqlitex.ExecuteTransient(conn, "PRAGMA foreign_keys = ON;", nil)
 		},
-		OnError: func(err error) {
-			log.Error("sqlitepool", "error", err)
-		},
 	})
+	if err != nil {
+		logger.Fatal("open sqlite pool", "error", err)
+	}
 
 	// TODO: Need a better solution for using the pool
-	conn, err := pool.Get(context.TODO())
+	conn, err := pool.Take(context.TODO())
+	if err != nil {
+		logger.Fatal("pool get conn", "error", err)
+	}
+	_, err = migrate(conn, -1)
 	if err != nil {
-		log.Fatal("pool get conn", "error", err)
+		logger.Fatal("migrate sqlite schema", "error", err)
 	}
 
 	cs := &Store{
-		url:      url,
-		filename: cfg.Filename,
-		Pool:     pool,
-		DB:       conn,
+		url:              url,
+		filename:         cfg.Filename,
+		Pool:             pool,
+		DB:               conn,
+		archiveAfter:     cfg.ArchiveAfter,
+		archiveDirectory: cfg.ArchiveDirectory,
+		deviceIndex:      make(map[model.Addr]int),
+		macIndex:         make(map[string][]model.Addr),
+		nameIndex:        make(map[string][]model.Addr),
 	}
 	return cs
 }
 
+// dbURL returns the url of the database file, creating its directory
+func dbURL(cfg *Config) string {
+	var url string
+	if cfg.Filename != "" {
+		// url = "file:"
+		if cfg.Directory != "" {
+			ensureDirectory(cfg.Directory)
+			url += cfg.Directory + "/"
+		}
+		url += cfg.Filename
+	}
+	url += cfg.URL
+	return url
+}
+
 func New(cfg *Config) (*Store, error) {
 	ctx := context.TODO()
 
@@ -157,6 +111,16 @@ func New(cfg *Config) (*Store, error) {
 	return cs, nil
 }
 
+// Ping checks a connection can be taken from the pool and answers a query
+func (cs *Store) Ping(ctx context.Context) error {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	defer cs.Pool.Put(conn)
+	return sqlitex.ExecuteTransient(conn, "select 1", nil)
+}
+
 func (cs *Store) Close() error {
 	cs.Pool.Put(cs.DB)
 	return cs.Pool.Close()
@@ -170,15 +134,15 @@ func ensureDirectory(dir string) {
 	if err != nil && errors.Is(err, os.ErrNotExist) {
 		err = os.MkdirAll(dir, 0755)
 		if err != nil {
-			log.Fatal(err)
+			logger.Fatal(err)
 		}
 		return
 	}
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal(err)
 	}
 	if stat.IsDir() {
 		return
 	}
-	log.Fatal("not a directory", "dir", dir)
+	logger.Fatal("not a directory", "dir", dir)
 }
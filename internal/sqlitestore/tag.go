@@ -0,0 +1,100 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"time"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// UpsertTagDefinition adds the tag definition or replaces the existing one with the same name
+func (cs *Store) UpsertTagDefinition(ctx context.Context, def model.TagDefinition) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	stmt, err := conn.Prepare(
+		`insert into tagdefinitions (name, description, pinginterval, portscaninterval)
+    values (:name, :description, :pinginterval, :portscaninterval)
+    on conflict (name) do update set
+      description=:description, pinginterval=:pinginterval, portscaninterval=:portscaninterval`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":name", def.Name)
+	stmt.SetText(":description", def.Description)
+	stmt.SetInt64(":pinginterval", def.Policy.PingInterval.Nanoseconds())
+	stmt.SetInt64(":portscaninterval", def.Policy.PortScanInterval.Nanoseconds())
+
+	_, err = stmt.Step()
+	return err
+}
+
+// RemoveTagDefinition deletes the named tag definition
+func (cs *Store) RemoveTagDefinition(ctx context.Context, name string) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from tagdefinitions where name = :name`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":name", name)
+	_, err = stmt.Step()
+	if err != nil {
+		return err
+	}
+	if conn.Changes() == 0 {
+		return model.ErrTagDoesNotExist
+	}
+	return nil
+}
+
+// ListTagDefinitions returns all tag definitions ordered by name
+func (cs *Store) ListTagDefinitions(
+	ctx context.Context,
+) (defs []model.TagDefinition, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      name, description, pinginterval, portscaninterval
+    from tagdefinitions
+    order by name`)
+	if err != nil {
+		return defs, err
+	}
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return defs, err
+		}
+		if !hasRow {
+			break
+		}
+		defs = append(defs, model.TagDefinition{
+			Name:        stmt.GetText("name"),
+			Description: stmt.GetText("description"),
+			Policy: model.MonitoringPolicy{
+				PingInterval:     time.Duration(stmt.GetInt64("pinginterval")),
+				PortScanInterval: time.Duration(stmt.GetInt64("portscaninterval")),
+			},
+		})
+	}
+	return defs, nil
+}
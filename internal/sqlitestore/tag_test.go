@@ -0,0 +1,64 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_TagDefinitions(t *testing.T) {
+	ctx := context.Background()
+	critical := model.TagDefinition{
+		Name:        "critical",
+		Description: "core network gear",
+		Policy:      model.MonitoringPolicy{PingInterval: 30 * time.Second},
+	}
+	printer := model.TagDefinition{
+		Name:   "printer",
+		Policy: model.MonitoringPolicy{PingInterval: time.Hour, PortScanInterval: 24 * time.Hour},
+	}
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, def := range []model.TagDefinition{printer, critical} {
+		err := db.UpsertTagDefinition(ctx, def)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	critical.Policy.PingInterval = 15 * time.Second
+	err := db.UpsertTagDefinition(ctx, critical)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.ListTagDefinitions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff([]model.TagDefinition{critical, printer}, got)
+	if diff != "" {
+		t.Errorf("tag definitions mismatch (-want +got):\n%s", diff)
+	}
+
+	err = db.RemoveTagDefinition(ctx, printer.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.RemoveTagDefinition(ctx, printer.Name)
+	if !errors.Is(err, model.ErrTagDoesNotExist) {
+		t.Errorf("remove missing want: %v, got: %v", model.ErrTagDoesNotExist, err)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// UpsertTombstone stores the tombstone, replacing an existing one for the same item
+func (cs *Store) UpsertTombstone(ctx context.Context, t model.Tombstone) (err error) {
+	var data []byte
+	switch t.Kind {
+	case model.TombstoneDevice:
+		data, err = msgpack.Marshal(t.Device)
+	case model.TombstoneNetwork:
+		data, err = msgpack.Marshal(t.Network)
+	default:
+		return model.ErrInvalidTombstoneKind
+	}
+	if err != nil {
+		return err
+	}
+
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	fn := sqlitex.Transaction(conn)
+	defer func() {
+		fn(&err)
+		cs.Pool.Put(conn)
+	}()
+
+	stmt, err := conn.Prepare(
+		`insert into tombstones (kind, key, deletedat, purgeat, data)
+    values (:kind, :key, :deletedat, :purgeat, :data)
+    on conflict (kind, key) do update set
+      deletedat=:deletedat, purgeat=:purgeat, data=:data`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":kind", string(t.Kind))
+	stmt.SetText(":key", t.Key)
+	stmt.SetText(":deletedat", t.DeletedAt.Format(time.RFC3339Nano))
+	stmt.SetText(":purgeat", t.PurgeAt.Format(time.RFC3339Nano))
+	stmt.SetBytes(":data", data)
+
+	_, err = stmt.Step()
+	return err
+}
+
+// RemoveTombstone deletes the tombstone of the item
+func (cs *Store) RemoveTombstone(
+	ctx context.Context,
+	kind model.TombstoneKind,
+	key string,
+) (err error) {
+	conn, err := cs.Pool.Take(ctx)
+	if err != nil {
+		return err
+	}
+	defer cs.Pool.Put(conn)
+
+	stmt, err := conn.Prepare(`delete from tombstones where kind = :kind and key = :key`)
+	if err != nil {
+		return err
+	}
+	stmt.SetText(":kind", string(kind))
+	stmt.SetText(":key", key)
+	_, err = stmt.Step()
+	if err != nil {
+		return err
+	}
+	if conn.Changes() == 0 {
+		return model.ErrTombstoneDoesNotExist
+	}
+	return nil
+}
+
+// ListTombstones returns all tombstones, most recently deleted first
+func (cs *Store) ListTombstones(ctx context.Context) (ts []model.Tombstone, err error) {
+	stmt, err := cs.DB.Prepare(
+		`select
+      kind, key, deletedat, purgeat, data
+    from tombstones
+    order by deletedat desc`)
+	if err != nil {
+		return ts, err
+	}
+
+	var hasRow bool
+	for {
+		hasRow, err = stmt.Step()
+		if err != nil {
+			return ts, err
+		}
+		if !hasRow {
+			break
+		}
+		t := model.Tombstone{
+			Kind: model.TombstoneKind(stmt.GetText("kind")),
+			Key:  stmt.GetText("key"),
+		}
+		t.DeletedAt, err = time.Parse(time.RFC3339Nano, stmt.GetText("deletedat"))
+		if err != nil {
+			return ts, err
+		}
+		t.PurgeAt, err = time.Parse(time.RFC3339Nano, stmt.GetText("purgeat"))
+		if err != nil {
+			return ts, err
+		}
+		data := make([]byte, stmt.GetLen("data"))
+		stmt.GetBytes("data", data)
+		switch t.Kind {
+		case model.TombstoneDevice:
+			err = msgpack.Unmarshal(data, &t.Device)
+		case model.TombstoneNetwork:
+			err = msgpack.Unmarshal(data, &t.Network)
+		}
+		if err != nil {
+			return ts, err
+		}
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
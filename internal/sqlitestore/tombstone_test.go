@@ -0,0 +1,68 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlitestore
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestSqliteStore_Tombstones(t *testing.T) {
+	ctx := context.Background()
+	ts := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	device := model.DeviceTombstone(model.Device{
+		Name: "printer",
+		Addr: model.MustParseAddr("192.168.86.20"),
+		MAC:  model.MustParseMAC("00:00:5e:00:53:01"),
+		Meta: model.Meta{Tags: model.Tags{{Val: "office"}}},
+	}, ts, time.Hour)
+	network := model.NetworkTombstone(model.Network{
+		Name:   "lab",
+		Prefix: model.MustParsePrefix("10.0.0.0/24"),
+	}, ts.Add(time.Minute), time.Hour)
+
+	db := createTestDatabase(t)
+	defer func() {
+		db.Close()
+		removeTestDatabase(t)
+	}()
+	for _, x := range []model.Tombstone{device, network} {
+		err := db.UpsertTombstone(ctx, x)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := db.ListTombstones(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff(
+		[]model.Tombstone{network, device},
+		got,
+		cmpopts.EquateComparable(netip.Prefix{}, netip.Addr{}),
+		cmpopts.IgnoreUnexported(model.Device{}),
+	)
+	if diff != "" {
+		t.Errorf("tombstones mismatch (-want +got):\n%s", diff)
+	}
+
+	err = db.RemoveTombstone(ctx, model.TombstoneNetwork, "lab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.RemoveTombstone(ctx, model.TombstoneNetwork, "lab")
+	if !errors.Is(err, model.ErrTombstoneDoesNotExist) {
+		t.Errorf("remove missing want: %v, got: %v", model.ErrTombstoneDoesNotExist, err)
+	}
+}
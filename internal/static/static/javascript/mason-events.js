@@ -0,0 +1,20 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Forwards the mason event stream to the page as mason:<kind> events on the body, so htmx
+// elements can refresh with hx-trigger="mason:device from:body".  The browser reconnects
+// the stream by itself when the server restarts.
+(function () {
+  if (!window.EventSource) {
+    return;
+  }
+  const source = new EventSource("/api/events");
+  for (const kind of ["device", "scan", "error"]) {
+    source.addEventListener(kind, function (e) {
+      document.body.dispatchEvent(
+        new CustomEvent("mason:" + kind, { detail: JSON.parse(e.data) }),
+      );
+    });
+  }
+})();
@@ -0,0 +1,46 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Applies the theme saved in this browser before the page is drawn, theme-change.js only
+// applies it once the page has loaded which flashes the default theme.  No saved theme
+// follows the operating system preference.
+(function () {
+  const theme = localStorage.getItem("theme");
+  if (theme) {
+    document.documentElement.setAttribute("data-theme", theme);
+  }
+})();
+
+// daisyUI themes with a dark background, charts drawn under these use the echarts dark theme
+const masonDarkThemes = [
+  "dark", "synthwave", "halloween", "forest", "black", "luxury", "dracula", "business",
+  "night", "coffee", "dim", "sunset",
+];
+
+// masonChartTheme returns the echarts theme matching the page theme
+function masonChartTheme() {
+  let theme = document.documentElement.getAttribute("data-theme");
+  if (!theme) {
+    theme = window.matchMedia("(prefers-color-scheme: dark)").matches ? "dark" : "light";
+  }
+  return masonDarkThemes.includes(theme) ? "dark" : "";
+}
+
+// masonChartDownload saves the chart drawn inside the element with the id as a png, the
+// page background is filled in as the charts themselves are transparent
+function masonChartDownload(id, filename) {
+  const el = document.querySelector("#" + id + " .item");
+  const chart = el && echarts.getInstanceByDom(el);
+  if (!chart) {
+    return;
+  }
+  const link = document.createElement("a");
+  link.href = chart.getDataURL({
+    type: "png",
+    pixelRatio: 2,
+    backgroundColor: getComputedStyle(document.body).backgroundColor,
+  });
+  link.download = filename;
+  link.click();
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package syslog
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/networkables/mason/internal/flagset"
+)
+
+// Config sets where the syslog receiver listens and what it keeps.  Messages are matched to
+// known devices by their source address, messages of other addresses are dropped.
+type Config struct {
+	Enabled       bool
+	ListenAddress string
+	UDP           bool
+	TCP           bool
+	RingSize      int
+	AlertSeverity string
+	AlertCooldown time.Duration
+}
+
+func SetFlags(fs *pflag.FlagSet, cfg *Config) {
+	configMajorKey := "syslog"
+
+	flagset.Bool(
+		fs,
+		&cfg.Enabled,
+		configMajorKey,
+		"enabled",
+		false,
+		"receive the syslog messages of devices",
+	)
+	flagset.String(
+		fs,
+		&cfg.ListenAddress,
+		configMajorKey,
+		"listenaddress",
+		":514",
+		"address to receive syslog messages on",
+	)
+	flagset.Bool(
+		fs,
+		&cfg.UDP,
+		configMajorKey,
+		"udp",
+		true,
+		"receive syslog messages over udp",
+	)
+	flagset.Bool(
+		fs,
+		&cfg.TCP,
+		configMajorKey,
+		"tcp",
+		true,
+		"receive syslog messages over tcp, newline or octet count framed",
+	)
+	flagset.Int(
+		fs,
+		&cfg.RingSize,
+		configMajorKey,
+		"ringsize",
+		200,
+		"number of recent messages kept for each device",
+	)
+	flagset.String(
+		fs,
+		&cfg.AlertSeverity,
+		configMajorKey,
+		"alertseverity",
+		"crit",
+		"messages at this severity or above raise an alert [emerg,alert,crit,err,warning,notice,info,debug]",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.AlertCooldown,
+		configMajorKey,
+		"alertcooldown",
+		15*time.Minute,
+		"least time between syslog alerts of a device",
+	)
+}
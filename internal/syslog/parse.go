@@ -0,0 +1,145 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package syslog
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+var ErrEmptyMessage = errors.New("empty syslog message")
+
+// defaultPriority is user.notice, given to messages without a priority (rfc 3164 4.3.3)
+const defaultPriority = 13
+
+// rfc3164Stamp is the timestamp of a bsd syslog message, the day padded with a space
+const rfc3164Stamp = time.Stamp
+
+// Parse reads a syslog message in either the rfc 5424 or the bsd (rfc 3164) format.  The
+// parts of a bsd message which do not match the format are kept in the message text.
+func Parse(b []byte, addr model.Addr, received time.Time) (model.SyslogMessage, error) {
+	line := strings.TrimRight(string(b), "\r\n\x00 ")
+	if line == "" {
+		return model.SyslogMessage{}, ErrEmptyMessage
+	}
+	msg := model.SyslogMessage{Addr: addr, Received: received, Time: received}
+	pri, rest := parsePriority(line)
+	msg.Facility = pri / 8
+	msg.Severity = model.SyslogSeverity(pri % 8)
+	if strings.HasPrefix(rest, "1 ") {
+		parse5424(&msg, rest[2:])
+	} else {
+		parse3164(&msg, rest, received)
+	}
+	return msg, nil
+}
+
+// parsePriority strips the <PRI> from the line, the default priority is returned when it
+// is missing or out of range
+func parsePriority(line string) (int, string) {
+	if !strings.HasPrefix(line, "<") {
+		return defaultPriority, line
+	}
+	end := strings.IndexByte(line, '>')
+	if end < 2 || end > 4 {
+		return defaultPriority, line
+	}
+	pri, err := strconv.Atoi(line[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return defaultPriority, line
+	}
+	return pri, line[end+1:]
+}
+
+// parse5424 reads TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG, a "-" is
+// a field without a value
+func parse5424(msg *model.SyslogMessage, rest string) {
+	fields := make([]string, 0, 5)
+	for len(fields) < 5 {
+		field, tail, _ := strings.Cut(rest, " ")
+		fields = append(fields, field)
+		rest = tail
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+		msg.Time = ts
+	}
+	msg.Hostname = nilValue(fields[1])
+	msg.App = nilValue(fields[2])
+	rest = skipStructuredData(rest)
+	msg.Message = strings.TrimPrefix(rest, "\ufeff")
+}
+
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+// skipStructuredData returns what follows the structured data elements
+func skipStructuredData(s string) string {
+	if strings.HasPrefix(s, "-") {
+		return strings.TrimPrefix(s[1:], " ")
+	}
+	inValue := false
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case inValue && c == '\\':
+			i++
+		case c == '"' && depth > 0:
+			inValue = !inValue
+		case inValue:
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case depth == 0:
+			return strings.TrimPrefix(s[i:], " ")
+		}
+	}
+	return ""
+}
+
+// parse3164 reads TIMESTAMP HOSTNAME TAG: MSG, many devices leave out the hostname and some
+// the timestamp
+func parse3164(msg *model.SyslogMessage, rest string, received time.Time) {
+	if len(rest) >= len(rfc3164Stamp) {
+		ts, err := time.ParseInLocation(rfc3164Stamp, rest[:len(rfc3164Stamp)], time.Local)
+		if err == nil {
+			msg.Time = withYear(ts, received)
+			rest = strings.TrimPrefix(rest[len(rfc3164Stamp):], " ")
+			if host, tail, ok := strings.Cut(rest, " "); ok && !isTag(host) {
+				msg.Hostname = host
+				rest = tail
+			}
+		}
+	}
+	if tag, tail, ok := strings.Cut(rest, " "); ok && isTag(tag) {
+		app, _, _ := strings.Cut(strings.TrimSuffix(tag, ":"), "[")
+		msg.App = app
+		rest = tail
+	}
+	msg.Message = rest
+}
+
+// isTag reports if the word is a tag such as "sshd[42]:" or "kernel:"
+func isTag(word string) bool {
+	return strings.HasSuffix(word, ":") && len(word) > 1
+}
+
+// withYear sets the year of a bsd timestamp, a timestamp further ahead than a day is taken
+// from the year before the message arrived
+func withYear(ts time.Time, received time.Time) time.Time {
+	ts = ts.AddDate(received.Year(), 0, 0)
+	if ts.After(received.Add(24 * time.Hour)) {
+		ts = ts.AddDate(-1, 0, 0)
+	}
+	return ts
+}
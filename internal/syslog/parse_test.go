@@ -0,0 +1,213 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package syslog
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestParse(t *testing.T) {
+	addr := model.MustParseAddr("192.168.1.20")
+	received := time.Date(2024, 6, 3, 10, 0, 0, 0, time.Local)
+	tests := map[string]struct {
+		line string
+		want model.SyslogMessage
+		err  error
+	}{
+		"BSD": {
+			line: "<34>Jun  3 09:58:01 switch1 sshd[42]: Failed password for root",
+			want: model.SyslogMessage{
+				Time:     time.Date(2024, 6, 3, 9, 58, 1, 0, time.Local),
+				Facility: 4,
+				Severity: model.SyslogCritical,
+				Hostname: "switch1",
+				App:      "sshd",
+				Message:  "Failed password for root",
+			},
+		},
+		"BSDNoHostname": {
+			line: "<14>Jun  3 09:58:01 kernel: link up\n",
+			want: model.SyslogMessage{
+				Time:     time.Date(2024, 6, 3, 9, 58, 1, 0, time.Local),
+				Facility: 1,
+				Severity: model.SyslogInfo,
+				App:      "kernel",
+				Message:  "link up",
+			},
+		},
+		"BSDNoTimestamp": {
+			line: "<11>dhcpd: pool exhausted",
+			want: model.SyslogMessage{
+				Time:     received,
+				Facility: 1,
+				Severity: model.SyslogError,
+				App:      "dhcpd",
+				Message:  "pool exhausted",
+			},
+		},
+		"BSDLastYear": {
+			line: "<13>Dec 31 23:59:59 router ntpd: clock stepped",
+			want: model.SyslogMessage{
+				Time:     time.Date(2023, 12, 31, 23, 59, 59, 0, time.Local),
+				Facility: 1,
+				Severity: model.SyslogNotice,
+				Hostname: "router",
+				App:      "ntpd",
+				Message:  "clock stepped",
+			},
+		},
+		"NoPriority": {
+			line: "plain text message",
+			want: model.SyslogMessage{
+				Time:     received,
+				Facility: 1,
+				Severity: model.SyslogNotice,
+				Message:  "plain text message",
+			},
+		},
+		"RFC5424": {
+			line: "<165>1 2024-06-03T09:58:01.003Z ap1 hostapd 1234 ID47 - \ufeffclient associated",
+			want: model.SyslogMessage{
+				Time:     time.Date(2024, 6, 3, 9, 58, 1, 3000000, time.UTC),
+				Facility: 20,
+				Severity: model.SyslogNotice,
+				Hostname: "ap1",
+				App:      "hostapd",
+				Message:  "client associated",
+			},
+		},
+		"RFC5424StructuredData": {
+			line: `<10>1 2024-06-03T09:58:01Z - ups - - [alarm@1 state="on \] battery"][x@2 a="b"] battery low`,
+			want: model.SyslogMessage{
+				Time:     time.Date(2024, 6, 3, 9, 58, 1, 0, time.UTC),
+				Facility: 1,
+				Severity: model.SyslogCritical,
+				App:      "ups",
+				Message:  "battery low",
+			},
+		},
+		"RFC5424NoMessage": {
+			line: "<10>1 - - - - - -",
+			want: model.SyslogMessage{
+				Time:     received,
+				Facility: 1,
+				Severity: model.SyslogCritical,
+			},
+		},
+		"Empty": {
+			line: "\r\n",
+			err:  ErrEmptyMessage,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Parse([]byte(tc.line), addr, received)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("error: want %v, got %v", tc.err, err)
+			}
+			if tc.err != nil {
+				return
+			}
+			tc.want.Addr = addr
+			tc.want.Received = received
+			diff := cmp.Diff(
+				tc.want,
+				got,
+				cmpopts.EquateComparable(model.Addr{}),
+				cmpopts.EquateApproxTime(0),
+			)
+			if diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	tests := map[string]struct {
+		stream string
+		want   []string
+		err    error
+	}{
+		"Newline": {
+			stream: "<13>one\n<13>two\n",
+			want:   []string{"<13>one\n", "<13>two\n"},
+		},
+		"OctetCount": {
+			stream: "7 <13>one12 <13>two\nmore",
+			want:   []string{"<13>one", "<13>two\nmore"},
+		},
+		"Unterminated": {
+			stream: "<13>last",
+			want:   []string{"<13>last"},
+		},
+		"BadCount": {
+			stream: "8x <13>one",
+			err:    errInvalidFraming,
+		},
+		"TooLarge": {
+			stream: "9000 <13>",
+			err:    ErrFrameTooLarge,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			br := bufio.NewReaderSize(strings.NewReader(tc.stream), maxMessageSize)
+			got := make([]string, 0, len(tc.want))
+			var err error
+			for {
+				var frame []byte
+				frame, err = ReadFrame(br)
+				if err != nil {
+					break
+				}
+				got = append(got, string(frame))
+			}
+			if tc.err == nil && !errors.Is(err, io.EOF) || tc.err != nil && !errors.Is(err, tc.err) {
+				t.Fatalf("error: want %v, got %v", tc.err, err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestRing(t *testing.T) {
+	a := model.MustParseAddr("192.168.1.20")
+	b := model.MustParseAddr("192.168.1.21")
+	r := NewRing(3)
+	for i := range 5 {
+		r.Add(model.SyslogMessage{Addr: a, Facility: i})
+	}
+	r.Add(model.SyslogMessage{Addr: b, Facility: 9})
+
+	facilities := func(msgs []model.SyslogMessage) []int {
+		fs := make([]int, 0, len(msgs))
+		for _, m := range msgs {
+			fs = append(fs, m.Facility)
+		}
+		return fs
+	}
+	if diff := cmp.Diff([]int{4, 3, 2}, facilities(r.Recent(a))); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff([]int{9}, facilities(r.Recent(b))); diff != "" {
+		t.Fatal(diff)
+	}
+	if got := r.Recent(model.MustParseAddr("192.168.1.22")); got != nil {
+		t.Fatalf("want no messages, got %v", got)
+	}
+}
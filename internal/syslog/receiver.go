@@ -0,0 +1,217 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// maxMessageSize is the longest message read, longer udp messages are cut and longer tcp
+// frames close the connection
+const maxMessageSize = 8192
+
+var (
+	ErrNoProtocol     = errors.New("syslog needs udp or tcp enabled")
+	ErrFrameTooLarge  = errors.New("syslog frame too large")
+	errInvalidFraming = errors.New("invalid syslog octet count")
+)
+
+// Receiver listens for syslog messages and passes each parsed message to handle
+type Receiver struct {
+	cfg    *Config
+	handle func(context.Context, model.SyslogMessage)
+
+	// whether the listeners run and the error which stopped them
+	mu        sync.Mutex
+	listening bool
+	listenErr error
+}
+
+func NewReceiver(cfg *Config, handle func(context.Context, model.SyslogMessage)) *Receiver {
+	return &Receiver{cfg: cfg, handle: handle}
+}
+
+// Run receives messages until the context is done or a listener fails
+func (r *Receiver) Run(ctx context.Context) {
+	r.setListening(true, nil)
+	err := r.listen(ctx)
+	r.setListening(false, err)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Error("syslog receiver stopped", "address", r.cfg.ListenAddress, "error", err)
+	}
+}
+
+// Listening reports whether messages are received, with the error which stopped the
+// receiver otherwise
+func (r *Receiver) Listening() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.listening, r.listenErr
+}
+
+func (r *Receiver) setListening(listening bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listening = listening
+	r.listenErr = err
+}
+
+func (r *Receiver) listen(ctx context.Context) error {
+	if !r.cfg.UDP && !r.cfg.TCP {
+		return ErrNoProtocol
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var lc net.ListenConfig
+	closers := make([]io.Closer, 0, 2)
+	serves := make([]func() error, 0, 2)
+	if r.cfg.UDP {
+		conn, err := lc.ListenPacket(ctx, "udp", r.cfg.ListenAddress)
+		if err != nil {
+			return err
+		}
+		closers = append(closers, conn)
+		serves = append(serves, func() error { return r.serveUDP(ctx, conn) })
+	}
+	if r.cfg.TCP {
+		ln, err := lc.Listen(ctx, "tcp", r.cfg.ListenAddress)
+		if err != nil {
+			for _, c := range closers {
+				c.Close()
+			}
+			return err
+		}
+		closers = append(closers, ln)
+		serves = append(serves, func() error { return r.serveTCP(ctx, ln) })
+	}
+	log.Info("starting syslog receiver", "addr", r.cfg.ListenAddress)
+
+	errs := make(chan error, len(serves))
+	for _, serve := range serves {
+		go func() { errs <- serve() }()
+	}
+	var err error
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case err = <-errs:
+	}
+	for _, c := range closers {
+		c.Close()
+	}
+	return err
+}
+
+func (r *Receiver) serveUDP(ctx context.Context, conn net.PacketConn) error {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		r.receive(ctx, buf[:n], from)
+	}
+}
+
+func (r *Receiver) serveTCP(ctx context.Context, ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		go r.serveConn(ctx, conn)
+	}
+}
+
+// serveConn reads the messages of a tcp connection until it closes
+func (r *Receiver) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	br := bufio.NewReaderSize(conn, maxMessageSize)
+	for {
+		frame, err := ReadFrame(br)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				log.Debug("syslog connection closed", "remote", conn.RemoteAddr(), "error", err)
+			}
+			return
+		}
+		r.receive(ctx, frame, conn.RemoteAddr())
+	}
+}
+
+func (r *Receiver) receive(ctx context.Context, b []byte, from net.Addr) {
+	addr, ok := remoteAddr(from)
+	if !ok {
+		return
+	}
+	msg, err := Parse(b, model.AddrToModelAddr(addr), time.Now())
+	if err != nil {
+		return
+	}
+	r.handle(ctx, msg)
+}
+
+func remoteAddr(from net.Addr) (netip.Addr, bool) {
+	switch a := from.(type) {
+	case *net.UDPAddr:
+		return a.AddrPort().Addr().Unmap(), true
+	case *net.TCPAddr:
+		return a.AddrPort().Addr().Unmap(), true
+	}
+	return netip.Addr{}, false
+}
+
+// ReadFrame reads a message of a tcp stream, framed by its octet count (rfc 6587 3.4.1) or
+// ended by a newline
+func ReadFrame(br *bufio.Reader) ([]byte, error) {
+	first, err := br.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if first[0] < '0' || first[0] > '9' {
+		line, err := br.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return nil, ErrFrameTooLarge
+		}
+		if err != nil && len(line) == 0 {
+			return nil, err
+		}
+		return append([]byte(nil), line...), nil
+	}
+	count, err := br.ReadSlice(' ')
+	if err != nil {
+		return nil, errInvalidFraming
+	}
+	n, err := strconv.Atoi(string(count[:len(count)-1]))
+	if err != nil || n <= 0 {
+		return nil, errInvalidFraming
+	}
+	if n > maxMessageSize {
+		return nil, ErrFrameTooLarge
+	}
+	frame := make([]byte, n)
+	_, err = io.ReadFull(br, frame)
+	return frame, err
+}
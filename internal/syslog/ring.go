@@ -0,0 +1,62 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package syslog
+
+import (
+	"sync"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// Ring keeps the most recent messages of each address, older messages are overwritten once
+// an address has size messages
+type Ring struct {
+	size int
+
+	mu    sync.Mutex
+	addrs map[model.Addr]*ring
+}
+
+type ring struct {
+	msgs []model.SyslogMessage
+	next int
+}
+
+func NewRing(size int) *Ring {
+	return &Ring{size: max(size, 1), addrs: make(map[model.Addr]*ring)}
+}
+
+// Add keeps the message with the recent messages of its address
+func (r *Ring) Add(msg model.SyslogMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rg, ok := r.addrs[msg.Addr]
+	if !ok {
+		rg = &ring{msgs: make([]model.SyslogMessage, 0, min(r.size, 16))}
+		r.addrs[msg.Addr] = rg
+	}
+	if len(rg.msgs) < r.size {
+		rg.msgs = append(rg.msgs, msg)
+		return
+	}
+	rg.msgs[rg.next] = msg
+	rg.next = (rg.next + 1) % r.size
+}
+
+// Recent returns the kept messages of the address, newest first
+func (r *Ring) Recent(addr model.Addr) []model.SyslogMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rg, ok := r.addrs[addr]
+	if !ok {
+		return nil
+	}
+	msgs := make([]model.SyslogMessage, 0, len(rg.msgs))
+	for i := range len(rg.msgs) {
+		idx := (rg.next - 1 - i + 2*len(rg.msgs)) % len(rg.msgs)
+		msgs = append(msgs, rg.msgs[idx])
+	}
+	return msgs
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package systemd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	sent, err := Notify(Ready)
+	if sent || err != nil {
+		t.Fatalf("unset socket want: false, nil, got: %v, %v", sent, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	sent, err = Notify(Status("scanning"))
+	if !sent || err != nil {
+		t.Fatalf("want: true, nil, got: %v, %v", sent, err)
+	}
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "STATUS=scanning" {
+		t.Errorf("state want: STATUS=scanning, got: %s", got)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	tests := map[string]struct {
+		usec string
+		pid  string
+		want time.Duration
+		ok   bool
+	}{
+		"Unset":        {},
+		"Invalid":      {usec: "soon"},
+		"Enabled":      {usec: "30000000", want: 30 * time.Second, ok: true},
+		"OwnPid":       {usec: "30000000", pid: strconv.Itoa(os.Getpid()), want: 30 * time.Second, ok: true},
+		"OtherProcess": {usec: "30000000", pid: "1"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", tc.usec)
+			t.Setenv("WATCHDOG_PID", tc.pid)
+			got, ok := WatchdogInterval()
+			if got != tc.want || ok != tc.ok {
+				t.Errorf("want: %v %v, got: %v %v", tc.want, tc.ok, got, ok)
+			}
+		})
+	}
+}
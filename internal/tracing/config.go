@@ -0,0 +1,64 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package tracing
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/networkables/mason/internal/flagset"
+)
+
+type Config struct {
+	Enabled       bool
+	Endpoint      string
+	Insecure      bool
+	SamplePercent int
+	ServiceName   string
+}
+
+func SetFlags(fs *pflag.FlagSet, cfg *Config) {
+	configMajorKey := "tracing"
+
+	flagset.Bool(
+		fs,
+		&cfg.Enabled,
+		configMajorKey,
+		"enabled",
+		false,
+		"export opentelemetry spans of the bus, workers, store and probes",
+	)
+	flagset.String(
+		fs,
+		&cfg.Endpoint,
+		configMajorKey,
+		"endpoint",
+		"localhost:4318",
+		"host:port of the otlp/http collector receiving the spans",
+	)
+	flagset.Bool(
+		fs,
+		&cfg.Insecure,
+		configMajorKey,
+		"insecure",
+		false,
+		"send the spans over plain http instead of https",
+	)
+	flagset.Int(
+		fs,
+		&cfg.SamplePercent,
+		configMajorKey,
+		"samplepercent",
+		100,
+		"percent of the traces kept (0-100), spans of a kept trace are all kept",
+	)
+	flagset.String(
+		fs,
+		&cfg.ServiceName,
+		configMajorKey,
+		"servicename",
+		"mason",
+		"service name the spans are reported under",
+	)
+}
@@ -0,0 +1,142 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package tracing sets up the opentelemetry tracer provider and holds the helpers the bus,
+// workers, stores and probes start their spans with.  Without the provider set up the spans
+// are no-ops.
+//
+// A device moves between subsystems over channels and the bus, which do not carry a context.
+// The span context is carried alongside, keyed by the device address, so the trace of a
+// device follows it from discovery through enrichment to storage.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/networkables/mason"
+
+// AddrKey is the attribute of the address a span works on
+const AddrKey = attribute.Key("mason.addr")
+
+// carryTTL bounds how long a carried span context can be resumed
+const carryTTL = 5 * time.Minute
+
+var ErrInvalidSamplePercent = errors.New("tracing sample percent must be within 0-100")
+
+// Setup installs the tracer provider exporting to the otlp/http collector of the
+// configuration.  The returned func flushes the pending spans and stops the exporter, it does
+// nothing when tracing is not enabled.
+func Setup(ctx context.Context, cfg *Config) (func(context.Context) error, error) {
+	if cfg == nil || !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+	if cfg.SamplePercent < 0 || cfg.SamplePercent > 100 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidSamplePercent, cfg.SamplePercent)
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	version := "dev_unknown"
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		version = bi.Main.Version
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", cfg.ServiceName),
+			attribute.String("service.version", version),
+		)),
+		sdktrace.WithSampler(sdktrace.ParentBased(
+			sdktrace.TraceIDRatioBased(float64(cfg.SamplePercent)/100),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// Start starts a span as a child of the span of the context
+func Start(
+	ctx context.Context,
+	name string,
+	attrs ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records the error, if any, on the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+type carried struct {
+	sc trace.SpanContext
+	at time.Time
+}
+
+var carrier = struct {
+	sync.Mutex
+	spans map[string]carried
+	swept time.Time
+}{
+	spans: make(map[string]carried),
+}
+
+// Carry keeps the span context of the context under the key, for the next step of the work to
+// resume.  Nothing is kept when the span is not recorded.
+func Carry(ctx context.Context, key string) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() || !sc.IsSampled() {
+		return
+	}
+	now := time.Now()
+	carrier.Lock()
+	defer carrier.Unlock()
+	carrier.spans[key] = carried{sc: sc, at: now}
+	if now.Sub(carrier.swept) < carryTTL {
+		return
+	}
+	for k, c := range carrier.spans {
+		if now.Sub(c.at) > carryTTL {
+			delete(carrier.spans, k)
+		}
+	}
+	carrier.swept = now
+}
+
+// Resume returns the context with the span context carried under the key as its parent span,
+// the context is returned as is when nothing is carried or it has expired
+func Resume(ctx context.Context, key string) context.Context {
+	carrier.Lock()
+	c, ok := carrier.spans[key]
+	carrier.Unlock()
+	if !ok || time.Since(c.at) > carryTTL {
+		return ctx
+	}
+	return trace.ContextWithSpanContext(ctx, c.sc)
+}
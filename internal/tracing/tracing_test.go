@@ -0,0 +1,90 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	prev := otel.GetTracerProvider()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prev)
+		carrier.Lock()
+		carrier.spans = make(map[string]carried)
+		carrier.Unlock()
+	})
+	return recorder
+}
+
+func TestSetup(t *testing.T) {
+	tests := map[string]struct {
+		cfg *Config
+		err error
+	}{
+		"Nil":      {},
+		"Disabled": {cfg: &Config{Endpoint: "localhost:4318", SamplePercent: 100}},
+		"Percent":  {cfg: &Config{Enabled: true, SamplePercent: 101}, err: ErrInvalidSamplePercent},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			shutdown, err := Setup(context.Background(), tc.cfg)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("error want: %v, got: %v", tc.err, err)
+			}
+			if err == nil {
+				if err := shutdown(context.Background()); err != nil {
+					t.Errorf("shutdown: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCarryResume(t *testing.T) {
+	recorder := recordSpans(t)
+
+	ctx, discovered := Start(context.Background(), "discovery.work")
+	Carry(ctx, "10.0.0.1")
+	End(discovered, nil)
+
+	_, stored := Start(Resume(context.Background(), "10.0.0.1"), "store.device")
+	End(stored, errors.New("locked"))
+	_, other := Start(Resume(context.Background(), "10.0.0.2"), "store.device")
+	End(other, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("spans want: 3, got: %d", len(spans))
+	}
+	if spans[1].Parent().SpanID() != spans[0].SpanContext().SpanID() ||
+		spans[1].SpanContext().TraceID() != spans[0].SpanContext().TraceID() {
+		t.Error("resumed span is not a child of the carried span")
+	}
+	if spans[2].Parent().IsValid() {
+		t.Error("span of an address without a carried span has a parent")
+	}
+	if len(spans[1].Events()) != 1 || spans[1].Status().Description != "locked" {
+		t.Errorf("error not recorded on span: %v", spans[1].Status())
+	}
+}
+
+func TestCarry_NotRecorded(t *testing.T) {
+	recordSpans(t)
+	Carry(context.Background(), "10.0.0.1")
+	if ctx := Resume(context.Background(), "10.0.0.1"); trace.SpanContextFromContext(ctx).IsValid() {
+		t.Error("span context resumed without a recorded span")
+	}
+}
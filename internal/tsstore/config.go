@@ -0,0 +1,50 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package tsstore provides the engines which can hold the performance ping timeseries apart
+// from the device store
+package tsstore
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/networkables/mason/internal/flagset"
+)
+
+const (
+	// EngineStore keeps the timeseries in the enabled device store
+	EngineStore = "store"
+	// EngineWhisper keeps the timeseries in whisper files in the combo store directory
+	EngineWhisper = "whisper"
+	// EngineSqlite keeps the timeseries in the sqlite store database
+	EngineSqlite = "sqlite"
+	// EngineMemory keeps the most recent points of each device in memory, lost on restart
+	EngineMemory = "memory"
+)
+
+type Config struct {
+	Engine         string
+	MemoryCapacity int
+}
+
+func SetFlags(fs *pflag.FlagSet, cfg *Config) {
+	configMajorKey := "store.timeseries"
+
+	flagset.String(
+		fs,
+		&cfg.Engine,
+		configMajorKey,
+		"engine",
+		EngineStore,
+		"where performance pings are kept: store, whisper, sqlite or memory",
+	)
+	flagset.Int(
+		fs,
+		&cfg.MemoryCapacity,
+		configMajorKey,
+		"memorycapacity",
+		2016,
+		"points kept per device by the memory engine",
+	)
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package tsstore
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/pinger"
+	"github.com/networkables/mason/nettools"
+)
+
+// Memory keeps a fixed number of points for each device in a ring, the oldest point is
+// overwritten once the ring is full
+type Memory struct {
+	capacity int
+	lock     sync.Mutex
+	series   map[model.Addr]*ring
+}
+
+type ring struct {
+	points []pinger.Point
+	next   int
+}
+
+func NewMemory(capacity int) *Memory {
+	return &Memory{
+		capacity: max(1, capacity),
+		series:   make(map[model.Addr]*ring),
+	}
+}
+
+// WritePerformancePing stores a given point for a device
+func (ms *Memory) WritePerformancePing(
+	ctx context.Context,
+	timestamp time.Time,
+	device model.Device,
+	point nettools.Icmp4EchoResponseStatistics,
+) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	r, ok := ms.series[device.Addr]
+	if !ok {
+		r = &ring{}
+		ms.series[device.Addr] = r
+	}
+	p := pinger.Point{
+		Start:   timestamp,
+		Minimum: point.Minimum,
+		Average: point.Mean,
+		Maximum: point.Maximum,
+		Loss:    point.PacketLoss,
+
+		Jitter:              point.Jitter,
+		P95:                 point.P95,
+		P99:                 point.P99,
+		ConsecutiveFailures: point.ConsecutiveFailures,
+	}
+	if len(r.points) < ms.capacity {
+		r.points = append(r.points, p)
+		return nil
+	}
+	r.points[r.next] = p
+	r.next = (r.next + 1) % ms.capacity
+	return nil
+}
+
+// ReadPerformancePings returns the points from Now() minus the duration, oldest first
+func (ms *Memory) ReadPerformancePings(
+	ctx context.Context,
+	device model.Device,
+	duration time.Duration,
+) ([]pinger.Point, error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	r, ok := ms.series[device.Addr]
+	if !ok {
+		return nil, nil
+	}
+	from := time.Now().Add(-1 * duration)
+	points := make([]pinger.Point, 0, len(r.points))
+	for _, p := range r.ordered() {
+		if p.Start.After(from) {
+			points = append(points, p)
+		}
+	}
+	return points, nil
+}
+
+// MergePerformancePings moves the points of the from addr onto the to addr, the newest points
+// of both are kept when together they are more than the capacity
+func (ms *Memory) MergePerformancePings(ctx context.Context, from, to model.Addr) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	old, ok := ms.series[from]
+	if !ok {
+		return nil
+	}
+	delete(ms.series, from)
+	r, ok := ms.series[to]
+	if !ok {
+		ms.series[to] = old
+		return nil
+	}
+	points := append(old.ordered(), r.ordered()...)
+	slices.SortStableFunc(points, func(a, b pinger.Point) int {
+		return a.Start.Compare(b.Start)
+	})
+	if len(points) > ms.capacity {
+		points = points[len(points)-ms.capacity:]
+	}
+	ms.series[to] = &ring{points: points, next: 0}
+	return nil
+}
+
+func (ms *Memory) Close() error {
+	return nil
+}
+
+// ordered returns the points of the ring oldest first
+func (r *ring) ordered() []pinger.Point {
+	points := make([]pinger.Point, 0, len(r.points))
+	for i := range len(r.points) {
+		points = append(points, r.points[(r.next+i)%len(r.points)])
+	}
+	return points
+}
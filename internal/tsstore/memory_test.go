@@ -0,0 +1,115 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package tsstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/pinger"
+	"github.com/networkables/mason/nettools"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	device := model.Device{Addr: model.MustParseAddr("192.168.1.1")}
+	other := model.Device{Addr: model.MustParseAddr("192.168.1.2")}
+
+	tests := map[string]struct {
+		capacity int
+		writes   int
+		duration time.Duration
+		want     []int
+	}{
+		"NotFull":    {capacity: 5, writes: 3, duration: time.Hour, want: []int{0, 1, 2}},
+		"Wrapped":    {capacity: 3, writes: 5, duration: time.Hour, want: []int{2, 3, 4}},
+		"WindowOnly": {capacity: 5, writes: 5, duration: 90 * time.Second, want: []int{3, 4}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ms := NewMemory(tc.capacity)
+			start := now.Add(-time.Duration(tc.writes-1) * time.Minute)
+			for i := range tc.writes {
+				stats := nettools.Icmp4EchoResponseStatistics{Mean: time.Duration(i)}
+				err := ms.WritePerformancePing(ctx, start.Add(time.Duration(i)*time.Minute), device, stats)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := ms.WritePerformancePing(ctx, now, other, nettools.Icmp4EchoResponseStatistics{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			points, err := ms.ReadPerformancePings(ctx, device, tc.duration)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]int, 0, len(points))
+			for _, p := range points {
+				got = append(got, int(p.Average))
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMemory_UnknownDevice(t *testing.T) {
+	ms := NewMemory(5)
+	device := model.Device{Addr: model.MustParseAddr("192.168.1.1")}
+	points, err := ms.ReadPerformancePings(context.Background(), device, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]pinger.Point(nil), points); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestMemory_MergePerformancePings(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	old := model.Device{Addr: model.MustParseAddr("192.168.1.1")}
+	next := model.Device{Addr: model.MustParseAddr("192.168.1.9")}
+
+	ms := NewMemory(4)
+	for i, d := range []model.Device{old, next, old, next, old} {
+		stats := nettools.Icmp4EchoResponseStatistics{Mean: time.Duration(i)}
+		err := ms.WritePerformancePing(ctx, now.Add(time.Duration(i-5)*time.Minute), d, stats)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := ms.MergePerformancePings(ctx, old.Addr, next.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	points, err := ms.ReadPerformancePings(ctx, next, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]int, 0, len(points))
+	for _, p := range points {
+		got = append(got, int(p.Average))
+	}
+	if diff := cmp.Diff([]int{1, 2, 3, 4}, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	points, err = ms.ReadPerformancePings(ctx, old, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 0 {
+		t.Errorf("old addr still has %d points", len(points))
+	}
+}
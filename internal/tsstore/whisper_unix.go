@@ -0,0 +1,290 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build linux || freebsd || openbsd || darwin
+
+package tsstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	whisper "github.com/go-graphite/go-whisper"
+
+	"github.com/networkables/mason/internal/backup"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/pinger"
+	"github.com/networkables/mason/nettools"
+)
+
+// WhisperSeries are the files kept for each device, named <addr>_<series>.wsp
+var WhisperSeries = []string{
+	"pingavg",
+	"pingmax",
+	"pingloss",
+	"pingjitter",
+	"pingp95",
+	"pingp99",
+	"pingfailures",
+}
+
+// Whisper keeps each series of a device in its own whisper file, the files are created with
+// the retentions on the first write
+type Whisper struct {
+	directory  string
+	retentions whisper.Retentions
+}
+
+func NewWhisper(directory string, retention string) (*Whisper, error) {
+	retentions, err := whisper.ParseRetentionDefs(retention)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(directory, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &Whisper{directory: directory, retentions: retentions}, nil
+}
+
+// WritePerformancePing stores a given point for a device
+func (ws *Whisper) WritePerformancePing(
+	ctx context.Context,
+	timestamp time.Time,
+	device model.Device,
+	point nettools.Icmp4EchoResponseStatistics,
+) error {
+	values := []float64{
+		convertPingDuration(point.Mean),
+		convertPingDuration(point.Maximum),
+		point.PacketLoss,
+		convertPingDuration(point.Jitter),
+		convertPingDuration(point.P95),
+		convertPingDuration(point.P99),
+		float64(point.ConsecutiveFailures),
+	}
+	for idx, series := range WhisperSeries {
+		wsp, err := ws.open(ws.Filename(device.Addr, series))
+		if err != nil {
+			return err
+		}
+		err = wsp.Update(values[idx], timeToWspTime(timestamp))
+		if err != nil {
+			wsp.Close()
+			return err
+		}
+		err = wsp.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadPerformancePings returns the points from Now() minus the duration, intervals without a
+// ping are left out
+func (ws *Whisper) ReadPerformancePings(
+	ctx context.Context,
+	device model.Device,
+	duration time.Duration,
+) ([]pinger.Point, error) {
+	from, until := fetchLast(duration)
+	var (
+		points []pinger.Point
+		seen   []bool
+	)
+	for _, series := range WhisperSeries {
+		wsp, err := ws.open(ws.Filename(device.Addr, series))
+		if err != nil {
+			return nil, err
+		}
+		ts, err := wsp.Fetch(from, until)
+		wsp.Close()
+		if err != nil {
+			return nil, err
+		}
+		if ts == nil {
+			return nil, nil
+		}
+		values := ts.Points()
+		if points == nil {
+			points = make([]pinger.Point, len(values))
+			seen = make([]bool, len(values))
+			for idx, value := range values {
+				points[idx] = pinger.Point{Start: time.Unix(int64(value.Time), 0)}
+			}
+		}
+		if len(values) != len(points) {
+			return nil, fmt.Errorf("%s point count does not equal pingavg count", series)
+		}
+		for idx, value := range values {
+			if math.IsNaN(value.Value) {
+				continue
+			}
+			seen[idx] = true
+			switch series {
+			case "pingavg":
+				points[idx].Average = pingDuration(value.Value)
+			case "pingmax":
+				points[idx].Maximum = pingDuration(value.Value)
+			case "pingloss":
+				points[idx].Loss = value.Value
+			case "pingjitter":
+				points[idx].Jitter = pingDuration(value.Value)
+			case "pingp95":
+				points[idx].P95 = pingDuration(value.Value)
+			case "pingp99":
+				points[idx].P99 = pingDuration(value.Value)
+			case "pingfailures":
+				points[idx].ConsecutiveFailures = int(math.Round(value.Value))
+			}
+		}
+	}
+	ret := make([]pinger.Point, 0, len(points))
+	for idx, p := range points {
+		if seen[idx] {
+			ret = append(ret, p)
+		}
+	}
+	return ret, nil
+}
+
+// MergePerformancePings moves the series of the from addr onto the to addr.  A series the
+// to addr does not have yet is renamed, otherwise the points of each archive of the old file
+// are written into the new one where it has no point of its own.
+func (ws *Whisper) MergePerformancePings(ctx context.Context, from, to model.Addr) error {
+	for _, series := range WhisperSeries {
+		src, dst := ws.Filename(from, series), ws.Filename(to, series)
+		_, err := os.Stat(src)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		_, err = os.Stat(dst)
+		if errors.Is(err, os.ErrNotExist) {
+			err = os.Rename(src, dst)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err = mergeWhisperFile(src, dst)
+		if err != nil {
+			return err
+		}
+		err = os.Remove(src)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeWhisperFile copies the points of src into dst, each archive of src covers the time
+// before the finer archive above it so every point is written once
+func mergeWhisperFile(src string, dst string) error {
+	old, err := whisper.Open(src)
+	if err != nil {
+		return err
+	}
+	defer old.Close()
+	wsp, err := whisper.Open(dst)
+	if err != nil {
+		return err
+	}
+	defer wsp.Close()
+
+	now := time.Now()
+	until := timeToWspTime(now)
+	points := make([]*whisper.TimeSeriesPoint, 0)
+	for _, r := range old.Retentions() {
+		from := timeToWspTime(now.Add(-1 * time.Duration(r.MaxRetention()) * time.Second))
+		ts, err := old.Fetch(from, until)
+		if err != nil {
+			return err
+		}
+		existing, err := wsp.Fetch(from, until)
+		if err != nil {
+			return err
+		}
+		have := make(map[int]bool)
+		if existing != nil {
+			for _, p := range existing.Points() {
+				have[p.Time] = !math.IsNaN(p.Value)
+			}
+		}
+		if ts != nil {
+			for _, p := range ts.PointPointers() {
+				if !math.IsNaN(p.Value) && !have[p.Time] {
+					points = append(points, p)
+				}
+			}
+		}
+		until = from
+	}
+	return wsp.UpdateMany(points)
+}
+
+func (ws *Whisper) Close() error {
+	return nil
+}
+
+// Snapshot copies the whisper files into dir, the series have no schema
+func (ws *Whisper) Snapshot(ctx context.Context, dir string) (int, error) {
+	_, err := backup.CopyFiles(ws.directory, dir, isWhisperFile)
+	return 0, err
+}
+
+// RestoreWhisper replaces the whisper files of the directory with the snapshot in dir
+func RestoreWhisper(directory string, dir string) error {
+	_, err := backup.CopyFiles(dir, directory, isWhisperFile)
+	return err
+}
+
+func isWhisperFile(name string) bool {
+	return strings.HasSuffix(name, ".wsp")
+}
+
+// Filename returns the path of the whisper file of a series of a device
+func (ws *Whisper) Filename(addr model.Addr, series string) string {
+	return fmt.Sprintf("%s/%s_%s.wsp", ws.directory, SanitizeAddrString(addr), series)
+}
+
+// SanitizeAddrString is the addr as used in whisper filenames
+func SanitizeAddrString(addr model.Addr) string {
+	return strings.Replace(addr.String(), ".", "-", -1)
+}
+
+func (ws *Whisper) open(filename string) (*whisper.Whisper, error) {
+	wsp, err := whisper.Open(filename)
+	if err == nil {
+		return wsp, nil
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return whisper.Create(filename, ws.retentions, whisper.Average, 0.5)
+	}
+	return nil, err
+}
+
+func fetchLast(dur time.Duration) (int, int) {
+	t2 := time.Now()
+	t1 := t2.Add(-1 * dur)
+	return timeToWspTime(t1), timeToWspTime(t2)
+}
+
+func timeToWspTime(timestamp time.Time) int {
+	return int(timestamp.Unix())
+}
+
+func convertPingDuration(t time.Duration) float64 {
+	return float64(t) / float64(time.Millisecond)
+}
+
+func pingDuration(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build linux || freebsd || openbsd || darwin
+
+package tsstore
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/pinger"
+	"github.com/networkables/mason/nettools"
+)
+
+func TestWhisper(t *testing.T) {
+	ctx := context.Background()
+	device := model.Device{Addr: model.MustParseAddr("192.168.1.1")}
+	ws, err := NewWhisper(t.TempDir(), "1m:1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now().Truncate(time.Minute).Add(-5 * time.Minute)
+	stats := nettools.Icmp4EchoResponseStatistics{
+		Mean:       12 * time.Millisecond,
+		Maximum:    30 * time.Millisecond,
+		PacketLoss: 0.25,
+
+		Jitter:              4 * time.Millisecond,
+		P95:                 28 * time.Millisecond,
+		P99:                 30 * time.Millisecond,
+		ConsecutiveFailures: 2,
+	}
+	for _, ts := range []time.Time{start, start.Add(2 * time.Minute)} {
+		err = ws.WritePerformancePing(ctx, ts, device, stats)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ws.ReadPerformancePings(ctx, device, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	point := pinger.Point{
+		Average: stats.Mean,
+		Maximum: stats.Maximum,
+		Loss:    stats.PacketLoss,
+
+		Jitter:              stats.Jitter,
+		P95:                 stats.P95,
+		P99:                 stats.P99,
+		ConsecutiveFailures: stats.ConsecutiveFailures,
+	}
+	first, second := point, point
+	first.Start, second.Start = start, start.Add(2*time.Minute)
+	diff := cmp.Diff(
+		[]pinger.Point{first, second},
+		got,
+		cmpopts.EquateComparable(netip.Addr{}),
+		cmpopts.IgnoreUnexported(model.Device{}),
+	)
+	if diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestWhisper_MergePerformancePings(t *testing.T) {
+	ctx := context.Background()
+	old := model.Device{Addr: model.MustParseAddr("192.168.1.1")}
+	next := model.Device{Addr: model.MustParseAddr("192.168.1.9")}
+	moved := model.Device{Addr: model.MustParseAddr("192.168.1.10")}
+	ws, err := NewWhisper(t.TempDir(), "1m:1h,10m:1d")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now().Truncate(time.Minute).Add(-5 * time.Minute)
+	writes := []struct {
+		device model.Device
+		ts     time.Time
+		mean   time.Duration
+	}{
+		{old, start, 10 * time.Millisecond},
+		{old, start.Add(2 * time.Minute), 20 * time.Millisecond},
+		{next, start.Add(2 * time.Minute), 30 * time.Millisecond},
+		{next, start.Add(3 * time.Minute), 40 * time.Millisecond},
+	}
+	for _, w := range writes {
+		err = ws.WritePerformancePing(
+			ctx,
+			w.ts,
+			w.device,
+			nettools.Icmp4EchoResponseStatistics{Mean: w.mean},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the second merge runs on the series the first merged into
+	tests := []struct {
+		name string
+		from model.Device
+		to   model.Device
+		want []time.Duration
+	}{
+		{name: "KeepsExistingPoints", from: old, to: next, want: []time.Duration{10, 30, 40}},
+		{name: "RenamesSeries", from: next, to: moved, want: []time.Duration{10, 30, 40}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ws.MergePerformancePings(ctx, tc.from.Addr, tc.to.Addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			points, err := ws.ReadPerformancePings(ctx, tc.to, 10*time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]time.Duration, 0, len(points))
+			for _, p := range points {
+				got = append(got, p.Average/time.Millisecond)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+			for _, series := range WhisperSeries {
+				_, err = os.Stat(ws.Filename(tc.from.Addr, series))
+				if !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("%s of the old addr remains: %v", series, err)
+				}
+			}
+		})
+	}
+}
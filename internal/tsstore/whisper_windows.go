@@ -0,0 +1,61 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build windows
+
+package tsstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/pinger"
+	"github.com/networkables/mason/nettools"
+)
+
+var unsupported = errors.New("platform is not supported")
+
+type Whisper struct{}
+
+func NewWhisper(directory string, retention string) (*Whisper, error) {
+	return nil, unsupported
+}
+
+func (ws *Whisper) Snapshot(ctx context.Context, dir string) (int, error) {
+	return 0, unsupported
+}
+
+func RestoreWhisper(directory string, dir string) error {
+	return unsupported
+}
+
+// WritePerformancePing stores a given point for a device
+func (ws *Whisper) WritePerformancePing(
+	ctx context.Context,
+	timestamp time.Time,
+	device model.Device,
+	point nettools.Icmp4EchoResponseStatistics,
+) error {
+	return unsupported
+}
+
+// ReadPerformancePings returns the points from Now() minus the duration
+func (ws *Whisper) ReadPerformancePings(
+	ctx context.Context,
+	device model.Device,
+	duration time.Duration,
+) ([]pinger.Point, error) {
+	return nil, unsupported
+}
+
+// MergePerformancePings moves the series of the from addr onto the to addr
+func (ws *Whisper) MergePerformancePings(ctx context.Context, from, to model.Addr) error {
+	return unsupported
+}
+
+func (ws *Whisper) Close() error {
+	return unsupported
+}
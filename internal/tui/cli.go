@@ -182,6 +182,10 @@ func (c tuicli) processCmd(ctx context.Context, s string) ([]string, tea.Cmd) {
 				devs := c.mason.ListDevices(ctx)
 				lines := make([]string, 0, len(devs))
 				for _, dev := range devs {
+					// show devices <type> lists the devices of the type
+					if len(args) > 2 && string(dev.Meta.DeviceType) != strings.ToLower(args[2]) {
+						continue
+					}
 					lines = append(lines, dev.String())
 				}
 				s += "\n" + strings.Join(lines, "\n")
@@ -189,7 +193,7 @@ func (c tuicli) processCmd(ctx context.Context, s string) ([]string, tea.Cmd) {
 				s += "\n" + "unknown show command: " + showCmd
 			}
 		} else {
-			s += "\n" + "show networks|devices"
+			s += "\n" + "show networks|devices [type]"
 		}
 	case cmdAdd:
 		if len(args) > 1 {
@@ -70,10 +70,15 @@ type MasonNetworker interface {
 	ArpPing(context.Context, string, time.Duration) (model.MAC, error)
 	Portscan(context.Context, string, *enrichment.PortScanConfig) ([]int, error)
 	GetExternalAddr(ctx context.Context) (model.Addr, error)
-	Traceroute(context.Context, string) ([]nettools.Icmp4EchoResponseStatistics, error)
+	Traceroute(
+		context.Context,
+		string,
+		...nettools.Icmp4EchoOption,
+	) ([]nettools.Icmp4EchoResponseStatistics, error)
 	TracerouteAddr(
 		context.Context,
 		model.Addr,
+		...nettools.Icmp4EchoOption,
 	) ([]nettools.Icmp4EchoResponseStatistics, error)
 	FetchTLSInfo(context.Context, string) (nettools.TLS, error)
 	FetchSNMPInfo(context.Context, string) (nettools.SnmpInfo, error)
@@ -0,0 +1,105 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package unifi
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/networkables/mason/internal/flagset"
+)
+
+// Config points to the UniFi Network controller, either a self hosted controller or a UniFi
+// OS console (UDM, Cloud Key).  An api key needs a UniFi OS console, a local user works with
+// both.
+type Config struct {
+	Enabled  bool
+	URL      string
+	APIKey   string
+	Username string
+	Password string
+	Site     string
+	Insecure bool
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+func SetFlags(fs *pflag.FlagSet, cfg *Config) {
+	configMajorKey := "unifi"
+
+	flagset.Bool(
+		fs,
+		&cfg.Enabled,
+		configMajorKey,
+		"enabled",
+		false,
+		"add the clients and devices of a unifi controller with how they are connected",
+	)
+	flagset.String(
+		fs,
+		&cfg.URL,
+		configMajorKey,
+		"url",
+		"https://unifi:8443",
+		"url of the unifi controller or console",
+	)
+	flagset.String(
+		fs,
+		&cfg.APIKey,
+		configMajorKey,
+		"apikey",
+		"",
+		"api key of a unifi os console, used in place of the username and password",
+	)
+	flagset.String(
+		fs,
+		&cfg.Username,
+		configMajorKey,
+		"username",
+		"",
+		"local user of the controller, read only access is enough",
+	)
+	flagset.String(
+		fs,
+		&cfg.Password,
+		configMajorKey,
+		"password",
+		"",
+		"password of the local user",
+	)
+	flagset.String(
+		fs,
+		&cfg.Site,
+		configMajorKey,
+		"site",
+		"default",
+		"site name of the controller, as shown in the url of the site",
+	)
+	flagset.Bool(
+		fs,
+		&cfg.Insecure,
+		configMajorKey,
+		"insecure",
+		false,
+		"accept the self signed certificate of the controller",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Interval,
+		configMajorKey,
+		"interval",
+		5*time.Minute,
+		"time between reads of the controller",
+	)
+	flagset.Duration(
+		fs,
+		&cfg.Timeout,
+		configMajorKey,
+		"timeout",
+		10*time.Second,
+		"timeout of each request to the controller",
+	)
+}
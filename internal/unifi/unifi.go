@@ -0,0 +1,337 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package unifi reads the clients and network devices of a UniFi Network controller, with the
+// access point, ssid and signal of wireless clients and the switch port of wired ones
+package unifi
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emicklei/tre"
+
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/model"
+)
+
+const LinkSource = "unifi"
+
+var (
+	ErrCredentialsRequired = errors.New("unifi needs an api key or a username and password")
+
+	// UnifiTag marks the access points, switches and gateways of the controller
+	UnifiTag = model.Tag{Val: "unifi"}
+)
+
+// Client reads the controller api, logging in again when the session expires
+type Client struct {
+	cfg    *Config
+	base   string
+	client *http.Client
+
+	mu     sync.Mutex
+	prefix string // the network application is below /proxy/network on unifi os
+	csrf   string
+	authed bool
+}
+
+// Station is a client of the network as the controller last saw it
+type Station struct {
+	MAC      string `json:"mac"`
+	IP       string `json:"ip"`
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	OUI      string `json:"oui"`
+	IsWired  bool   `json:"is_wired"`
+	ESSID    string `json:"essid"`
+	Radio    string `json:"radio"`
+	Signal   int    `json:"signal"`
+	APMAC    string `json:"ap_mac"`
+	SwMAC    string `json:"sw_mac"`
+	SwPort   int    `json:"sw_port"`
+	LastSeen int64  `json:"last_seen"`
+}
+
+// NetworkDevice is an access point, switch or gateway adopted by the controller
+type NetworkDevice struct {
+	MAC    string `json:"mac"`
+	IP     string `json:"ip"`
+	Name   string `json:"name"`
+	Model  string `json:"model"`
+	Type   string `json:"type"`
+	Uplink struct {
+		Type       string `json:"type"`
+		UplinkMAC  string `json:"uplink_mac"`
+		RemotePort int    `json:"uplink_remote_port"`
+	} `json:"uplink"`
+	LastSeen int64 `json:"last_seen"`
+}
+
+type response[T any] struct {
+	Meta struct {
+		RC  string `json:"rc"`
+		Msg string `json:"msg"`
+	} `json:"meta"`
+	Data []T `json:"data"`
+}
+
+func New(cfg *Config) (*Client, error) {
+	if cfg.APIKey == "" && (cfg.Username == "" || cfg.Password == "") {
+		return nil, ErrCredentialsRequired
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, tre.New(err, "unifi url", "url", cfg.URL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("unifi url %q has no host", cfg.URL)
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		cfg:  cfg,
+		base: strings.TrimSuffix(cfg.URL, "/"),
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			Jar:     jar,
+			Transport: &http.Transport{
+				// #nosec G402 -- controllers usually have a self signed certificate, opt in
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: cfg.Insecure},
+			},
+		},
+	}
+	if cfg.APIKey != "" {
+		// api keys are only issued by unifi os consoles
+		c.prefix, c.authed = "/proxy/network", true
+	}
+	return c, nil
+}
+
+// URL returns the url of the controller
+func (c *Client) URL() string {
+	return c.base
+}
+
+// Stations lists the clients currently connected
+func (c *Client) Stations(ctx context.Context) ([]Station, error) {
+	var resp response[Station]
+	err := c.get(ctx, "/api/s/"+url.PathEscape(c.cfg.Site)+"/stat/sta", &resp)
+	return resp.Data, err
+}
+
+// NetworkDevices lists the adopted access points, switches and gateways
+func (c *Client) NetworkDevices(ctx context.Context) ([]NetworkDevice, error) {
+	var resp response[NetworkDevice]
+	err := c.get(ctx, "/api/s/"+url.PathEscape(c.cfg.Site)+"/stat/device", &resp)
+	return resp.Data, err
+}
+
+// get reads the path of the network application, an expired session is logged in again once
+func (c *Client) get(ctx context.Context, path string, data apiResponse) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for attempt := 0; ; attempt++ {
+		if !c.authed {
+			err := c.login(ctx)
+			if err != nil {
+				return tre.New(err, "unifi login", "url", c.base)
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+c.prefix+path, nil)
+		if err != nil {
+			return err
+		}
+		c.setHeaders(req)
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && c.cfg.APIKey == "" && attempt == 0 {
+			resp.Body.Close()
+			c.authed = false
+			continue
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unifi api %s: %s", path, resp.Status)
+		}
+		err = json.NewDecoder(resp.Body).Decode(data)
+		if err != nil {
+			return err
+		}
+		return data.err()
+	}
+}
+
+// login starts a session, first as on a unifi os console and then as on a self hosted
+// controller
+func (c *Client) login(ctx context.Context) error {
+	body, err := json.Marshal(map[string]any{
+		"username": c.cfg.Username,
+		"password": c.cfg.Password,
+		"remember": true,
+	})
+	if err != nil {
+		return err
+	}
+	for _, try := range []struct{ path, prefix string }{
+		{"/api/auth/login", "/proxy/network"},
+		{"/api/login", ""},
+	} {
+		req, err := http.NewRequestWithContext(
+			ctx,
+			http.MethodPost,
+			c.base+try.path,
+			bytes.NewReader(body),
+		)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		switch resp.StatusCode {
+		case http.StatusOK:
+			c.prefix, c.csrf, c.authed = try.prefix, resp.Header.Get("X-Csrf-Token"), true
+			return nil
+		case http.StatusNotFound:
+			continue
+		default:
+			return fmt.Errorf("%s: %s", try.path, resp.Status)
+		}
+	}
+	return errors.New("no login endpoint found, is the url of a unifi controller")
+}
+
+func (c *Client) setHeaders(req *http.Request) {
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.APIKey != "" {
+		req.Header.Set("X-API-KEY", c.cfg.APIKey)
+	}
+	if c.csrf != "" {
+		req.Header.Set("X-Csrf-Token", c.csrf)
+	}
+}
+
+// apiResponse is a response of the api, which reports errors in its meta field
+type apiResponse interface {
+	err() error
+}
+
+func (r *response[T]) err() error {
+	if r.Meta.RC != "" && r.Meta.RC != "ok" {
+		return fmt.Errorf("unifi api: %s", r.Meta.Msg)
+	}
+	return nil
+}
+
+// Inventory turns the stations and network devices into devices with their link, the uplinks
+// are named after the network devices
+func Inventory(stations []Station, netdevs []NetworkDevice, now time.Time) []model.Device {
+	names := make(map[string]string, len(netdevs))
+	for _, nd := range netdevs {
+		names[strings.ToLower(nd.MAC)] = firstOf(nd.Name, nd.Model, nd.MAC)
+	}
+	uplink := func(mac string) string {
+		if name, ok := names[strings.ToLower(mac)]; ok {
+			return name
+		}
+		return mac
+	}
+
+	devices := make([]model.Device, 0, len(stations)+len(netdevs))
+	for _, nd := range netdevs {
+		addr, err := netip.ParseAddr(nd.IP)
+		if err != nil || !addr.Is4() {
+			continue
+		}
+		d := newDevice(addr, nd.MAC, firstOf(nd.Name, nd.Model), now)
+		d.Meta.Manufacturer = "Ubiquiti"
+		d.Meta.DeviceType = model.DeviceTypeNetwork
+		d.Meta.Tags = model.Tags{UnifiTag}
+		if nd.Uplink.UplinkMAC != "" {
+			d.Link = model.Link{
+				Source:     LinkSource,
+				Kind:       model.LinkWired,
+				Uplink:     uplink(nd.Uplink.UplinkMAC),
+				UplinkMAC:  nd.Uplink.UplinkMAC,
+				UplinkPort: nd.Uplink.RemotePort,
+				LastSeen:   seen(nd.LastSeen, now),
+			}
+			if nd.Uplink.Type == "wireless" {
+				d.Link.Kind = model.LinkWireless
+			}
+		}
+		devices = append(devices, d)
+	}
+	for _, st := range stations {
+		addr, err := netip.ParseAddr(st.IP)
+		if err != nil || !addr.Is4() {
+			continue
+		}
+		d := newDevice(addr, st.MAC, firstOf(st.Name, st.Hostname), now)
+		d.Meta.DHCPHostname = st.Hostname
+		link := model.Link{Source: LinkSource, LastSeen: seen(st.LastSeen, now)}
+		if st.IsWired {
+			link.Kind = model.LinkWired
+			link.Uplink, link.UplinkMAC, link.UplinkPort = uplink(st.SwMAC), st.SwMAC, st.SwPort
+		} else {
+			link.Kind = model.LinkWireless
+			link.SSID, link.Radio, link.Signal = st.ESSID, st.Radio, st.Signal
+			link.Uplink, link.UplinkMAC = uplink(st.APMAC), st.APMAC
+		}
+		d.Link = link
+		devices = append(devices, d)
+	}
+	return devices
+}
+
+func newDevice(addr netip.Addr, mac string, name string, now time.Time) model.Device {
+	d := model.Device{
+		Name:         name,
+		Addr:         model.AddrToModelAddr(addr),
+		DiscoveredBy: discovery.UnifiDiscoverySource,
+		DiscoveredAt: now,
+	}
+	if m, err := model.ParseMAC(mac); err == nil {
+		d.MAC = m
+	}
+	if d.Name == "" {
+		d.Name = addr.String()
+	}
+	return d
+}
+
+func seen(unix int64, now time.Time) time.Time {
+	if unix <= 0 {
+		return now
+	}
+	return time.Unix(unix, 0).UTC()
+}
+
+func firstOf(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,169 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package unifi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	testDevices = `{"meta":{"rc":"ok"},"data":[
+  {"mac":"f0:9f:c2:00:00:01","ip":"192.168.1.2","name":"switch-core","model":"US8P60","type":"usw",
+   "uplink":{"type":"wire","uplink_mac":"f0:9f:c2:00:00:00","uplink_remote_port":1},"last_seen":1704164645},
+  {"mac":"f0:9f:c2:00:00:02","ip":"192.168.1.3","name":"","model":"U6-Lite","type":"uap",
+   "uplink":{"type":"wire","uplink_mac":"f0:9f:c2:00:00:01","uplink_remote_port":5},"last_seen":1704164645}
+]}`
+	testStations = `{"meta":{"rc":"ok"},"data":[
+  {"mac":"00:11:22:33:44:55","ip":"192.168.1.20","hostname":"phone","is_wired":false,"essid":"home",
+   "radio":"na","signal":-61,"ap_mac":"f0:9f:c2:00:00:02","last_seen":1704164645},
+  {"mac":"00:11:22:33:44:66","ip":"192.168.1.21","name":"nas","is_wired":true,
+   "sw_mac":"f0:9f:c2:00:00:01","sw_port":3,"last_seen":1704164645},
+  {"mac":"00:11:22:33:44:77","hostname":"no-ip"}
+]}`
+)
+
+func testController(t *testing.T, unifiOS bool) *httptest.Server {
+	t.Helper()
+	prefix := ""
+	if unifiOS {
+		prefix = "/proxy/network"
+	}
+	mux := http.NewServeMux()
+	login := "/api/login"
+	if unifiOS {
+		login = "/api/auth/login"
+	}
+	mux.HandleFunc("POST "+login, func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "unifises", Value: "session", Path: "/"})
+		w.Header().Set("X-Csrf-Token", "csrf")
+	})
+	authed := func(h string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if c, err := r.Cookie("unifises"); err != nil || c.Value != "session" {
+				if r.Header.Get("X-API-KEY") != "key" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+			}
+			w.Write([]byte(h))
+		}
+	}
+	mux.HandleFunc("GET "+prefix+"/api/s/default/stat/device", authed(testDevices))
+	mux.HandleFunc("GET "+prefix+"/api/s/default/stat/sta", authed(testStations))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	tests := map[string]struct {
+		unifiOS bool
+		cfg     Config
+	}{
+		"Controller": {
+			cfg: Config{Username: "ro", Password: "pw"},
+		},
+		"UnifiOS": {
+			unifiOS: true,
+			cfg:     Config{Username: "ro", Password: "pw"},
+		},
+		"APIKey": {
+			unifiOS: true,
+			cfg:     Config{APIKey: "key"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			srv := testController(t, tc.unifiOS)
+			tc.cfg.URL, tc.cfg.Site, tc.cfg.Timeout = srv.URL, "default", time.Second
+			c, err := New(&tc.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			netdevs, err := c.NetworkDevices(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			stations, err := c.Stations(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(netdevs) != 2 || len(stations) != 3 {
+				t.Errorf("want 2 devices and 3 stations, got %d, %d", len(netdevs), len(stations))
+			}
+		})
+	}
+}
+
+func TestNew_NoCredentials(t *testing.T) {
+	_, err := New(&Config{URL: "https://unifi:8443"})
+	if !errors.Is(err, ErrCredentialsRequired) {
+		t.Fatalf("want %v, got %v", ErrCredentialsRequired, err)
+	}
+}
+
+func TestInventory(t *testing.T) {
+	srv := testController(t, false)
+	c, err := New(&Config{URL: srv.URL, Site: "default", Username: "ro", Password: "pw"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	netdevs, _ := c.NetworkDevices(context.Background())
+	stations, _ := c.Stations(context.Background())
+	seen := time.Unix(1704164645, 0).UTC()
+
+	got := make(map[string]model.Link)
+	for _, d := range Inventory(stations, netdevs, time.Now()) {
+		got[d.Name] = d.Link
+	}
+	want := map[string]model.Link{
+		"switch-core": {
+			Source:     LinkSource,
+			Kind:       model.LinkWired,
+			Uplink:     "f0:9f:c2:00:00:00",
+			UplinkMAC:  "f0:9f:c2:00:00:00",
+			UplinkPort: 1,
+			LastSeen:   seen,
+		},
+		"U6-Lite": {
+			Source:     LinkSource,
+			Kind:       model.LinkWired,
+			Uplink:     "switch-core",
+			UplinkMAC:  "f0:9f:c2:00:00:01",
+			UplinkPort: 5,
+			LastSeen:   seen,
+		},
+		"phone": {
+			Source:    LinkSource,
+			Kind:      model.LinkWireless,
+			SSID:      "home",
+			Radio:     "na",
+			Signal:    -61,
+			Uplink:    "U6-Lite",
+			UplinkMAC: "f0:9f:c2:00:00:02",
+			LastSeen:  seen,
+		},
+		"nas": {
+			Source:     LinkSource,
+			Kind:       model.LinkWired,
+			Uplink:     "switch-core",
+			UplinkMAC:  "f0:9f:c2:00:00:01",
+			UplinkPort: 3,
+			LastSeen:   seen,
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("links (-want +got):\n%s", diff)
+	}
+}
@@ -6,8 +6,11 @@ package workerpool
 
 import (
 	"context"
+	"sync/atomic"
 
 	"github.com/charmbracelet/log"
+
+	"github.com/networkables/mason/internal/tracing"
 )
 
 type Pool[Inbound, Outbound any] struct {
@@ -17,6 +20,10 @@ type Pool[Inbound, Outbound any] struct {
 	C             chan Outbound
 	E             chan error
 	activeworkers chan bool
+	running       atomic.Bool
+	// TraceKey, when set, keys the work by the address of its device so the span of the work
+	// resumes the trace carried under the address and is carried on once the work succeeds
+	TraceKey func(Inbound) string
 }
 
 func New[Inbound, Outbound any](
@@ -43,6 +50,7 @@ func (wp *Pool[Inbound, Outbound]) Run(ctx context.Context, maxworkers int) {
 		maxworkers = 1
 	}
 	wp.activeworkers = make(chan bool, maxworkers)
+	wp.running.Store(true)
 	keepworking := true
 	for keepworking {
 		select {
@@ -56,7 +64,7 @@ func (wp *Pool[Inbound, Outbound]) Run(ctx context.Context, maxworkers int) {
 			wp.activeworkers <- true
 
 			go func(ctx context.Context, i Inbound) {
-				out, err := wp.doit(ctx, i)
+				out, err := wp.work(ctx, i)
 				if err != nil {
 					wp.E <- err
 				} else {
@@ -67,6 +75,7 @@ func (wp *Pool[Inbound, Outbound]) Run(ctx context.Context, maxworkers int) {
 
 		}
 	}
+	wp.running.Store(false)
 	for ; maxworkers > 0; maxworkers-- {
 		wp.activeworkers <- true
 	}
@@ -74,6 +83,30 @@ func (wp *Pool[Inbound, Outbound]) Run(ctx context.Context, maxworkers int) {
 	close(wp.E)
 }
 
+// work runs the work of the input within a span of the pool
+func (wp *Pool[Inbound, Outbound]) work(ctx context.Context, i Inbound) (Outbound, error) {
+	key := ""
+	if wp.TraceKey != nil {
+		key = wp.TraceKey(i)
+		ctx = tracing.Resume(ctx, key)
+	}
+	ctx, span := tracing.Start(ctx, wp.Name+".work")
+	if key != "" {
+		span.SetAttributes(tracing.AddrKey.String(key))
+	}
+	out, err := wp.doit(ctx, i)
+	if key != "" && err == nil {
+		tracing.Carry(ctx, key)
+	}
+	tracing.End(span, err)
+	return out, err
+}
+
 func (wp *Pool[Inbound, Outbound]) Active() int {
 	return len(wp.activeworkers)
 }
+
+// Running reports whether the pool is taking work, it stops once its input is closed
+func (wp *Pool[Inbound, Outbound]) Running() bool {
+	return wp.running.Load()
+}
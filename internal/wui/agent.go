@@ -0,0 +1,80 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	g "github.com/maragudk/gomponents"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/agent"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/server"
+)
+
+// maxAgentReportSize bounds the body of an agent report, a /16 full of devices fits well
+// within it
+const maxAgentReportSize = 32 << 20
+
+// wuiApiAgentReportHandler accepts a report from a remote agent, the agent authenticates
+// with the shared token as a bearer token
+func (w WUI) wuiApiAgentReportHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		http.Error(wr, agent.ErrAgentUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+	var report agent.Report
+	err := json.NewDecoder(http.MaxBytesReader(wr, r.Body, maxAgentReportSize)).Decode(&report)
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	count, err := w.m.AgentReport(ctx, token, r.RemoteAddr, report)
+	switch {
+	case errors.Is(err, agent.ErrAgentReportsDisabled):
+		http.Error(wr, err.Error(), http.StatusForbidden)
+		return
+	case errors.Is(err, agent.ErrAgentUnauthorized):
+		http.Error(wr, err.Error(), http.StatusUnauthorized)
+		return
+	case errors.Is(err, agent.ErrAgentNameRequired), errors.Is(err, model.ErrInvalidSiteName):
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		logger.Warn("agent report", "agent", report.Agent, "error", err)
+	}
+	wr.Header().Set("Content-Type", "application/json")
+	wr.WriteHeader(http.StatusAccepted)
+	err = json.NewEncoder(wr).Encode(map[string]int{"accepted": count})
+	if err != nil {
+		logger.Error("agent report encode", "error", err)
+	}
+}
+
+func agentsToTable(agents []server.AgentStatus) g.Node {
+	return wuiTable([]string{"Agent", "Site", "Remote", "Networks", "Devices", "Last Report"},
+		g.Group(
+			g.Map(agents, func(a server.AgentStatus) g.Node {
+				return h.Tr(
+					h.Td(g.Text(a.Name)),
+					h.Td(g.Text(a.Site)),
+					h.Td(g.Text(a.RemoteAddr)),
+					h.Td(g.Text(strings.Join(a.Networks, ", "))),
+					h.Td(g.Text(strconv.Itoa(a.Devices))),
+					h.Td(g.Text(humanize.Time(a.LastReport))),
+				)
+			}),
+		),
+	)
+}
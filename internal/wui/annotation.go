@@ -0,0 +1,43 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+const defaultAnnotationDuration = 6 * time.Hour
+
+// wuiApiAnnotationsHandler returns the annotations for a device as json, the lookback
+// can be changed with the duration query parameter (ex: ?duration=24h)
+func (w WUI) wuiApiAnnotationsHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	addr, err := w.m.StringToAddr(r.PathValue("id"))
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dur := defaultAnnotationDuration
+	if s := r.FormValue("duration"); s != "" {
+		dur, err = time.ParseDuration(s)
+		if err != nil {
+			http.Error(wr, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	annotations, err := w.m.ReadAnnotations(ctx, addr, dur)
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	wr.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(wr).Encode(annotations)
+	if err != nil {
+		logger.Error("annotations encode", "error", err)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/server"
+)
+
+const (
+	wuiBulkFormAddr   = "addr"
+	wuiBulkFormAction = "action"
+	wuiBulkFormTag    = "bulktag"
+
+	// wuiBulkSelected finds the devices ticked in the device list
+	wuiBulkSelected = "#devicelist input[name='" + wuiBulkFormAddr + "']:checked"
+
+	// wuiBulkIdle is an htmx trigger filter which holds back list refreshes while devices
+	// are ticked, a refresh would clear the selection
+	wuiBulkIdle = "[!document.querySelector(\"" + wuiBulkSelected + "\")]"
+)
+
+var wuiBulkActionLabels = map[server.BulkDeviceAction]string{
+	server.BulkDeviceEnrich:   "Re-enrich",
+	server.BulkDevicePortScan: "Port scan now",
+	server.BulkDeviceTag:      "Add tag",
+	server.BulkDeviceUntag:    "Remove tag",
+	server.BulkDevicePolicy:   "Set policy",
+	server.BulkDeviceDelete:   "Delete",
+}
+
+// wuiApiDevicesBulkHandler applies an action to the devices ticked in the device list and
+// returns the list, keeping the current search, filters, sort and page
+func (w WUI) wuiApiDevicesBulkHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	req, err := bulkDeviceRequestFromForm(w, r)
+	count := 0
+	if err == nil {
+		count, err = w.m.BulkDevices(ctx, req)
+	}
+	status := errAlert(err)
+	if count > 0 {
+		status = g.Group([]g.Node{
+			successAlert(fmt.Sprintf("%s applied to %d devices", bulkActionLabel(req.Action), count)),
+			status,
+		})
+	}
+	q, params := w.deviceQueryFromRequest(ctx, r)
+	w.wuiDeviceList(ctx, q, params, status).Render(wr)
+}
+
+func bulkDeviceRequestFromForm(w WUI, r *http.Request) (server.BulkDeviceRequest, error) {
+	var req server.BulkDeviceRequest
+	err := r.ParseForm()
+	if err != nil {
+		return req, err
+	}
+	req.Action, err = server.ParseBulkDeviceAction(r.PostFormValue(wuiBulkFormAction))
+	if err != nil {
+		return req, err
+	}
+	for _, s := range r.PostForm[wuiBulkFormAddr] {
+		addr, err := w.m.StringToAddr(s)
+		if err != nil {
+			return req, err
+		}
+		req.Addrs = append(req.Addrs, addr)
+	}
+	req.Tag = r.PostFormValue(wuiBulkFormTag)
+	if req.Action == server.BulkDevicePolicy {
+		req.Policy.PingInterval, err = formDuration(r, wuiTagsFormPingInterval)
+		if err != nil {
+			return req, err
+		}
+		req.Policy.PortScanInterval, err = formDuration(r, wuiTagsFormPortScanInterval)
+	}
+	return req, err
+}
+
+func bulkActionLabel(a server.BulkDeviceAction) string {
+	if label, ok := wuiBulkActionLabels[a]; ok {
+		return label
+	}
+	return string(a)
+}
+
+// deviceBulkForm is the action bar above the device list, it posts the ticked devices
+// together with the list state so the list comes back as it was
+func deviceBulkForm() g.Node {
+	return h.FormEl(
+		hx.Post(urlApiDevicesBulk),
+		hx.Target("#devicelist"),
+		hx.Swap("outerHTML"),
+		hx.Include(wuiBulkSelected+", #devicelist-state, input[name='"+wuiDevicesFormSearch+"']"),
+		hx.Confirm("Apply the action to the selected devices?"),
+		h.Class("flex flex-wrap items-center gap-2 py-4"),
+		h.Select(
+			h.Name(wuiBulkFormAction),
+			h.Class("select select-bordered select-sm"),
+			g.Group(g.Map(server.BulkDeviceActions, func(a server.BulkDeviceAction) g.Node {
+				return h.Option(h.Value(string(a)), g.Text(bulkActionLabel(a)))
+			})),
+		),
+		h.Input(
+			h.Type("text"),
+			h.Name(wuiBulkFormTag),
+			h.Placeholder("tag"),
+			h.Class("input input-bordered input-sm"),
+		),
+		h.Input(
+			h.Type("text"),
+			h.Name(wuiTagsFormPingInterval),
+			h.Placeholder("ping interval"),
+			h.Class("input input-bordered input-sm"),
+		),
+		h.Input(
+			h.Type("text"),
+			h.Name(wuiTagsFormPortScanInterval),
+			h.Placeholder("port scan interval"),
+			h.Class("input input-bordered input-sm"),
+		),
+		h.Button(h.Class("btn btn-primary btn-sm"), g.Text("Apply to selected")),
+		deviceLabelsButton(),
+	)
+}
+
+// deviceLabelsButton opens the label sheet of the ticked devices, or of every device when
+// none is ticked
+func deviceLabelsButton() g.Node {
+	return h.Button(
+		h.Type("button"),
+		h.Class("btn btn-sm"),
+		g.Attr("onclick", "window.open('"+urlLabels+"?' + Array.from(document.querySelectorAll(\""+
+			wuiBulkSelected+"\")).map(c => '"+wuiLabelsFormAddr+"=' + encodeURIComponent(c.value)).join('&'))"),
+		g.Text("Print labels"),
+	)
+}
+
+// deviceSelectAll ticks or clears every device checkbox in the list
+func deviceSelectAll() g.Node {
+	return h.Input(
+		h.Type("checkbox"),
+		h.Class("checkbox checkbox-sm"),
+		h.Title("Select all"),
+		g.Attr("onclick", "document.querySelectorAll(\"#devicelist input[name='"+
+			wuiBulkFormAddr+"']\").forEach(c => c.checked = this.checked)"),
+	)
+}
+
+func deviceSelect(d model.Device) g.Node {
+	return h.Input(
+		h.Type("checkbox"),
+		h.Name(wuiBulkFormAddr),
+		h.Value(d.Addr.String()),
+		h.Class("checkbox checkbox-sm"),
+	)
+}
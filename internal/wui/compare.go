@@ -0,0 +1,224 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-echarts/go-echarts/v2/opts"
+	g "github.com/maragudk/gomponents"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/pinger"
+)
+
+const (
+	wuiCompareFormDevice = "device"
+	wuiCompareFormTag    = "tag"
+
+	// maxCompareDevices is the most devices drawn on the comparison graphs, a tag on many
+	// devices would make the graphs unreadable
+	maxCompareDevices = 20
+)
+
+// comparedDevice is a device with its pings over the compared range
+type comparedDevice struct {
+	model.Device
+	points []pinger.Point
+}
+
+func (w WUI) wuiComparePageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiCompareMain(ctx, r),
+	)
+	extra := h.Script(h.Src("/static/javascript/echarts.min.js"))
+	w.basePage(ctx, "compare", content, extra).Render(wr)
+}
+
+func (w WUI) wuiCompareMain(ctx context.Context, r *http.Request) g.Node {
+	devices := w.m.ListDevices(ctx)
+	slices.SortFunc(devices, func(a, b model.Device) int {
+		return a.Addr.Compare(b.Addr)
+	})
+	tag := r.FormValue(wuiCompareFormTag)
+	picked := r.Form[wuiCompareFormDevice]
+	rangeLabel := r.FormValue(wuiDeviceFormRange)
+	dur := parsePingRange(rangeLabel)
+
+	selected := make([]model.Device, 0)
+	for _, d := range devices {
+		if slices.Contains(picked, d.Addr.String()) || tag != "" && d.Meta.Tags.Has(tag) {
+			selected = append(selected, d)
+		}
+	}
+	var notice g.Node
+	if len(selected) > maxCompareDevices {
+		notice = warnAlert(fmt.Sprintf(
+			"%d devices picked, the first %d are compared",
+			len(selected),
+			maxCompareDevices,
+		))
+		selected = selected[:maxCompareDevices]
+	}
+
+	var errNode g.Node
+	compared := make([]comparedDevice, 0, len(selected))
+	for _, d := range selected {
+		points, err := w.m.ReadPerformancePings(ctx, d, dur)
+		if err != nil {
+			errNode = errAlert(err)
+			continue
+		}
+		compared = append(compared, comparedDevice{
+			Device: d,
+			points: pinger.Downsample(points, maxPingGraphPoints),
+		})
+	}
+
+	return grid("",
+		widecard("Compare Devices", h.Div(
+			errNode,
+			notice,
+			compareForm(devices, picked, tag, dur),
+		)),
+		g.If(len(compared) > 0, widecard("Summary", compareSummaryTable(compared))),
+		g.If(len(compared) > 0, graphcard("Mean Latency", compareLatencyGraph(compared))),
+		g.If(len(compared) > 0, graphcard("Packet Loss", compareLossGraph(compared))),
+	)
+}
+
+// compareForm picks the devices, by address or by tag, and the range to compare them over
+func compareForm(devices []model.Device, picked []string, tag string, dur time.Duration) g.Node {
+	tags := make([]string, 0)
+	for _, d := range devices {
+		for _, t := range d.Meta.Tags {
+			if !slices.Contains(tags, t.Val) {
+				tags = append(tags, t.Val)
+			}
+		}
+	}
+	slices.Sort(tags)
+	return h.FormEl(
+		h.Action(urlCompare),
+		h.Method("get"),
+		h.Div(
+			h.Class("form-control"),
+			wuiFormInput("Devices",
+				h.Select(
+					h.Name(wuiCompareFormDevice),
+					h.Multiple(),
+					g.Attr("size", "8"),
+					h.Class("select select-bordered w-full md:w-1/2"),
+					g.Group(g.Map(devices, func(d model.Device) g.Node {
+						addr := d.Addr.String()
+						return h.Option(
+							h.Value(addr),
+							g.If(slices.Contains(picked, addr), h.Selected()),
+							g.Text(d.Name+" ("+addr+")"),
+						)
+					})),
+				),
+			),
+			wuiFormInput("Or Tag",
+				h.Select(
+					h.Name(wuiCompareFormTag),
+					h.Class("select select-bordered w-full md:w-1/2"),
+					h.Option(h.Value(""), g.Text("none")),
+					g.Group(g.Map(tags, func(t string) g.Node {
+						return h.Option(h.Value(t), g.If(t == tag, h.Selected()), g.Text(t))
+					})),
+				),
+			),
+			wuiFormInput("Range",
+				h.Select(
+					h.Name(wuiDeviceFormRange),
+					h.Class("select select-bordered w-full md:w-1/2"),
+					g.Group(g.Map(pingRanges, func(pr pingRange) g.Node {
+						return h.Option(
+							h.Value(pr.label),
+							g.If(pr.dur == dur, h.Selected()),
+							g.Text(pr.label),
+						)
+					})),
+				),
+			),
+			wuiFormButton("Compare"),
+		),
+	)
+}
+
+func compareSummaryTable(compared []comparedDevice) g.Node {
+	return wuiTable(
+		[]string{"Name", "IP", "Pings", "Mean", "Maximum", "Loss"},
+		g.Group(g.Map(compared, func(c comparedDevice) g.Node {
+			var (
+				sum     time.Duration
+				maximum time.Duration
+				loss    float64
+			)
+			for _, p := range c.points {
+				sum += p.Average
+				maximum = max(maximum, p.Maximum)
+				loss += p.Loss
+			}
+			mean, meanLoss := "", ""
+			if n := len(c.points); n > 0 {
+				mean = fmtDur(sum / time.Duration(n))
+				meanLoss = fmt.Sprintf("%.1f %%", loss/float64(n)*100)
+			}
+			return h.Tr(
+				h.Td(h.A(h.Class("link"), h.Href(urlDevice+"/"+c.Addr.String()), g.Text(c.Name))),
+				h.Td(g.Text(c.Addr.String())),
+				h.Td(g.Text(fmt.Sprintf("%d", len(c.points)))),
+				h.Td(g.Text(mean)),
+				h.Td(g.Text(fmtDur(maximum))),
+				h.Td(g.Text(meanLoss)),
+			)
+		})),
+	)
+}
+
+// compareLatencyGraph has a series of the mean latency for each device
+func compareLatencyGraph(compared []comparedDevice) g.Node {
+	line := timeLineGraph("duration (ms)", "{value} ms")
+	line.SetGlobalOptions(pingDataZoom())
+	for _, c := range compared {
+		line.AddSeries(compareSeriesName(c), durationtspoints2linedata(
+			c.points,
+			func(p pinger.Point) time.Duration { return p.Average },
+		))
+	}
+	return renderLineGraph(line)
+}
+
+// compareLossGraph has a series of the packet loss for each device
+func compareLossGraph(compared []comparedDevice) g.Node {
+	line := timeLineGraph("packet loss (%)", "{value} %")
+	line.SetGlobalOptions(pingDataZoom())
+	for _, c := range compared {
+		loss := make([]opts.LineData, len(c.points))
+		for i, p := range c.points {
+			loss[i] = opts.LineData{Value: EChartPoint{p.Start, p.Loss * 100}}
+		}
+		line.AddSeries(compareSeriesName(c), loss)
+	}
+	return renderLineGraph(line)
+}
+
+// compareSeriesName is the name of the device with its address, names need not be unique
+func compareSeriesName(c comparedDevice) string {
+	if c.Name == "" || c.Name == c.Addr.String() {
+		return c.Addr.String()
+	}
+	return c.Name + " (" + c.Addr.String() + ")"
+}
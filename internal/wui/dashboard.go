@@ -8,8 +8,10 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"strings"
 
 	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
 	h "github.com/maragudk/gomponents/html"
 
 	"github.com/networkables/mason/internal/model"
@@ -19,14 +21,52 @@ func (w WUI) wuiHomePageHandler(wr http.ResponseWriter, r *http.Request) {
 	ctx := context.TODO()
 	content := h.Main(
 		h.Class("drawer-content"),
+		w.degradedEnrichmentAlert(),
 		w.dashboardContent(ctx),
 	)
 	w.basePage(ctx, "dashboard", content, nil).Render(wr)
 }
 
+func (w WUI) degradedEnrichmentAlert() g.Node {
+	names := make([]string, 0)
+	for _, es := range w.m.GetEnrichmentStatus() {
+		if es.Degraded {
+			names = append(names, es.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return h.Div(
+		h.Class("p-2"),
+		warnAlert("degraded enrichments: "+strings.Join(names, ", ")+" (see config page)"),
+	)
+}
+
+// dashboardContent refreshes itself from the full page whenever a device or scan event
+// arrives on the event stream
 func (w WUI) dashboardContent(ctx context.Context) g.Node {
+	return h.Div(
+		h.ID("dashboard"),
+		hx.Get(urlRoot),
+		hx.Trigger(liveDeviceTrigger+", "+liveScanTrigger),
+		hx.Select("#dashboard"),
+		hx.Swap("outerHTML"),
+		w.dashboardCards(ctx),
+	)
+}
+
+func (w WUI) dashboardCards(ctx context.Context) g.Node {
+	health, err := w.m.InternetHealth(ctx)
 	return grid(
 		"",
+		g.If(len(health) > 0,
+			wuiStatBox(
+				"internet",
+				string(model.GetInternetStatus(health)),
+				"latest anchor and url probes",
+			),
+		),
 		wuiStatBox("devices", strconv.Itoa(w.m.CountDevices(ctx)), ""),
 		wuiStatBox(
 			"networks",
@@ -38,6 +78,16 @@ func (w WUI) dashboardContent(ctx context.Context) g.Node {
 			strconv.Itoa(len(w.m.PingFailures(ctx))),
 			"",
 		),
+		wuiStatBox(
+			"awaiting review",
+			strconv.Itoa(len(w.m.ReviewQueue(ctx))),
+			"newly discovered devices",
+		),
+		wuiStatBox(
+			"in maintenance",
+			strconv.Itoa(len(w.m.DevicesInMaintenance(ctx))),
+			"ping failures suppressed",
+		),
 		wuiStatBox(
 			"servers",
 			strconv.Itoa(len(w.m.ServerDevices(ctx))),
@@ -49,6 +99,7 @@ func (w WUI) dashboardContent(ctx context.Context) g.Node {
 					return netStatBox(
 						ns.Name,
 						ns.Prefix,
+						ns.VLAN,
 						ns.IPUsed,
 						ns.IPTotal,
 						ns.AvgPing,
@@ -57,5 +108,37 @@ func (w WUI) dashboardContent(ctx context.Context) g.Node {
 				},
 			),
 		),
+		g.If(err != nil, widecard("Internet", errAlert(err))),
+		g.If(len(health) > 0, widecard("Internet", internetHealthTable(health))),
+	)
+}
+
+// internetHealthTable lists the latest probe and the daily average of each target
+func internetHealthTable(health []model.HealthTargetStatus) g.Node {
+	return wuiTable(
+		[]string{"Target", "Probe", "Last", "Avg (24h)", "Failures", "Hops", "Error"},
+		g.Group(g.Map(health, func(ts model.HealthTargetStatus) g.Node {
+			last := "down"
+			if !ts.Last.Failed() {
+				last = fmtDur(ts.Last.Latency)
+			}
+			avg := ""
+			if ts.Failures < ts.Probes {
+				avg = fmtDur(ts.Average)
+			}
+			hops := ""
+			if ts.Hops > 0 {
+				hops = strconv.Itoa(ts.Hops)
+			}
+			return h.Tr(
+				h.Td(g.Text(ts.Target)),
+				h.Td(g.Text(string(ts.Kind))),
+				h.Td(g.Text(last)),
+				h.Td(g.Text(avg)),
+				h.Td(g.Text(strconv.Itoa(ts.Failures)+" / "+strconv.Itoa(ts.Probes))),
+				h.Td(g.Text(hops)),
+				h.Td(g.Text(ts.Last.Err)),
+			)
+		})),
 	)
 }
@@ -0,0 +1,116 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dustin/go-humanize"
+	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	wuiDeletedFormKind = "kind"
+	wuiDeletedFormKey  = "key"
+)
+
+func (w WUI) wuiDeletedPageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiDeletedMain(ctx, nil),
+	)
+	w.basePage(ctx, "deleted", content, nil).Render(wr)
+}
+
+// wuiApiDeviceDeleteHandler deletes the device and shows the deleted items so the
+// delete can be undone
+func (w WUI) wuiApiDeviceDeleteHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	addr, err := w.m.StringToAddr(r.PathValue("id"))
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = w.m.RemoveDevice(ctx, addr)
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Redirect(wr, r, urlDeleted, http.StatusSeeOther)
+}
+
+func (w WUI) wuiApiDeletedRestore(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	kind, err := model.ParseTombstoneKind(r.PostFormValue(wuiDeletedFormKind))
+	if err == nil {
+		err = w.m.RestoreDeleted(ctx, kind, r.PostFormValue(wuiDeletedFormKey))
+	}
+	w.wuiDeletedMain(ctx, err).Render(wr)
+}
+
+func (w WUI) wuiDeletedMain(ctx context.Context, err error) g.Node {
+	ts, lerr := w.m.ListDeleted(ctx)
+	if err == nil {
+		err = lerr
+	}
+	return grid("deletedcontent",
+		wuiCard("Deleted",
+			h.Div(
+				errAlert(err),
+				wuiTable(
+					[]string{"Type", "Name", "Deleted", "Purged", " "},
+					g.Group(g.Map(ts, tombstoneToTD)),
+				),
+			),
+		),
+	)
+}
+
+func tombstoneToTD(t model.Tombstone) g.Node {
+	name := t.Key
+	if t.Kind == model.TombstoneDevice && t.Device.Name != "" && t.Device.Name != t.Key {
+		name = t.Device.Name + " (" + t.Key + ")"
+	}
+	return h.Tr(
+		h.Td(g.Text(string(t.Kind))),
+		h.Td(
+			g.Text(name),
+			g.If(
+				t.Device.Meta.Tags.Has(model.MergedDeviceTag.Val),
+				h.Span(h.Class("badge badge-outline ml-2"), g.Text("merged")),
+			),
+		),
+		h.Td(g.Text(humanize.Time(t.DeletedAt))),
+		h.Td(g.Text(humanize.Time(t.PurgeAt))),
+		h.Td(
+			h.FormEl(
+				hx.Post(urlApiDeleted+"/restore"),
+				hx.Target("#deletedcontent"),
+				hx.Swap("outerHTML"),
+				h.Input(h.Type("hidden"), h.Name(wuiDeletedFormKind), h.Value(string(t.Kind))),
+				h.Input(h.Type("hidden"), h.Name(wuiDeletedFormKey), h.Value(t.Key)),
+				h.Button(h.Class("btn btn-xs"), g.Text("Undo")),
+			),
+		),
+	)
+}
+
+// deviceDeleteForm is the button to delete the device
+func deviceDeleteForm(d model.Device) g.Node {
+	return h.FormEl(
+		h.Action(urlApiDevice+"/"+d.Addr.String()+"/delete"),
+		h.Method("post"),
+		h.Class("flex justify-end py-4"),
+		g.Attr("onsubmit", "return confirm('Delete the device "+d.Addr.String()+"?')"),
+		h.Button(h.Class("btn btn-error btn-sm"), g.Text("Delete Device")),
+	)
+}
@@ -0,0 +1,183 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	wuiDependencyFormName   = "name"
+	wuiDependencyFormParent = "parent"
+	wuiDependencyFormScope  = "scope"
+	wuiDependencyFormTarget = "target"
+	wuiDependencyFormNote   = "note"
+)
+
+func (w WUI) wuiDependenciesPageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiDependenciesMain(ctx, nil),
+	)
+	w.basePage(ctx, "dependencies", content, nil).Render(wr)
+}
+
+func (w WUI) wuiApiDependencyCreate(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	dep, err := dependencyFromForm(r)
+	if err == nil {
+		err = w.m.SaveDependency(ctx, dep)
+	}
+	w.wuiDependenciesMain(ctx, err).Render(wr)
+}
+
+func (w WUI) wuiApiDependencyDelete(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	err := w.m.RemoveDependency(ctx, r.PostFormValue(wuiDependencyFormName))
+	w.wuiDependenciesMain(ctx, err).Render(wr)
+}
+
+func dependencyFromForm(r *http.Request) (dep model.Dependency, err error) {
+	dep.Name = r.PostFormValue(wuiDependencyFormName)
+	dep.Target = r.PostFormValue(wuiDependencyFormTarget)
+	dep.Note = r.PostFormValue(wuiDependencyFormNote)
+	dep.Scope, err = model.ParseDependencyScope(r.PostFormValue(wuiDependencyFormScope))
+	if err != nil {
+		return dep, err
+	}
+	dep.Parent, err = model.ParseAddr(r.PostFormValue(wuiDependencyFormParent))
+	return dep, err
+}
+
+func (w WUI) wuiDependenciesMain(ctx context.Context, err error) g.Node {
+	deps, lerr := w.m.ListDependencies(ctx)
+	if err == nil {
+		err = lerr
+	}
+	devices := w.m.ListDevices(ctx)
+	slices.SortFunc(devices, func(a, b model.Device) int {
+		return a.Addr.Compare(b.Addr)
+	})
+	nets := w.m.ListNetworks(ctx)
+	return grid("dependenciescontent",
+		wuiCard("Dependencies",
+			wuiTable(
+				[]string{"Name", "Parent", "Scope", "Target", "Devices", "Note", " "},
+				g.Group(g.Map(deps, func(dep model.Dependency) g.Node {
+					return dependencyToTD(dep, devices, nets)
+				})),
+			),
+		),
+		wuiCard("Add / Update Dependency",
+			h.Div(
+				errAlert(err),
+				h.FormEl(
+					hx.Post(urlApiDependencies),
+					hx.Target("#dependenciescontent"),
+					hx.Swap("outerHTML"),
+					h.Div(
+						h.Class("form-control"),
+						wuiFormInput("Name",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiDependencyFormName),
+								h.Placeholder("behind core switch"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Parent",
+							h.Select(
+								h.Name(wuiDependencyFormParent),
+								h.Class("select select-bordered w-full md:w-1/2"),
+								g.Group(g.Map(devices, func(d model.Device) g.Node {
+									return h.Option(
+										h.Value(d.Addr.String()),
+										g.Text(d.Name+" ("+d.Addr.String()+")"),
+									)
+								})),
+							),
+						),
+						wuiFormInput("Scope",
+							h.Select(
+								h.Name(wuiDependencyFormScope),
+								h.Class("select select-bordered w-full md:w-1/2"),
+								h.Option(h.Value(string(model.DependencyScopeDevice)), g.Text("Device")),
+								h.Option(h.Value(string(model.DependencyScopeTag)), g.Text("Tag")),
+								h.Option(h.Value(string(model.DependencyScopeNetwork)), g.Text("Network")),
+							),
+						),
+						wuiFormInput("Target",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiDependencyFormTarget),
+								h.Placeholder("device addr, tag or network name"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Note",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiDependencyFormNote),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+					),
+					wuiFormButton("Save Dependency"),
+				),
+			),
+		),
+	)
+}
+
+func dependencyToTD(dep model.Dependency, devices []model.Device, nets []model.Network) g.Node {
+	parent := g.Text(dep.Parent.String())
+	var down g.Node
+	for _, d := range devices {
+		if d.Addr != dep.Parent {
+			continue
+		}
+		parent = h.A(
+			h.Class("link"),
+			h.Href(urlDevice+"/"+d.Addr.String()),
+			g.Text(d.Name+" ("+d.Addr.String()+")"),
+		)
+		if d.PerformancePing.LastFailed {
+			down = h.Span(h.Class("badge badge-error ml-2"), g.Text("down"))
+		}
+	}
+	count := 0
+	for _, d := range devices {
+		if dep.Applies(d, nets) {
+			count++
+		}
+	}
+	return h.Tr(
+		h.Td(g.Text(dep.Name)),
+		h.Td(parent, down),
+		h.Td(g.Text(string(dep.Scope))),
+		h.Td(g.Text(dep.Target)),
+		h.Td(g.Textf("%d", count)),
+		h.Td(g.Text(dep.Note)),
+		h.Td(
+			h.FormEl(
+				hx.Post(urlApiDependencies+"/delete"),
+				hx.Target("#dependenciescontent"),
+				hx.Swap("outerHTML"),
+				h.Input(h.Type("hidden"), h.Name(wuiDependencyFormName), h.Value(dep.Name)),
+				h.Button(h.Class("btn btn-xs"), g.Text("Delete")),
+			),
+		),
+	)
+}
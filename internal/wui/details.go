@@ -0,0 +1,130 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	g "github.com/maragudk/gomponents"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	wuiDetailsFormName         = "name"
+	wuiDetailsFormOwner        = "owner"
+	wuiDetailsFormNotes        = "notes"
+	wuiDetailsFormSite         = "site"
+	wuiDetailsFormLocation     = "location"
+	wuiDetailsFormPurchaseDate = "purchasedate"
+	wuiDetailsFormSerial       = "serial"
+	wuiDetailsFormAssetTag     = "assettag"
+)
+
+// wuiApiDeviceDetailsHandler replaces the name, owner, notes, site and asset fields of a
+// device and returns to the device page
+func (w WUI) wuiApiDeviceDetailsHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	id := r.PathValue("id")
+	addr, err := w.m.StringToAddr(id)
+	if err == nil {
+		err = w.m.SetDeviceDetails(ctx, addr, model.DeviceDetails{
+			Name:         r.PostFormValue(wuiDetailsFormName),
+			Owner:        r.PostFormValue(wuiDetailsFormOwner),
+			Notes:        r.PostFormValue(wuiDetailsFormNotes),
+			Site:         r.PostFormValue(wuiDetailsFormSite),
+			Location:     r.PostFormValue(wuiDetailsFormLocation),
+			PurchaseDate: r.PostFormValue(wuiDetailsFormPurchaseDate),
+			Serial:       r.PostFormValue(wuiDetailsFormSerial),
+			AssetTag:     r.PostFormValue(wuiDetailsFormAssetTag),
+		})
+	}
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Redirect(wr, r, urlDevice+"/"+id, http.StatusSeeOther)
+}
+
+func deviceDetailsForm(d model.Device, sites []model.Site) g.Node {
+	return h.FormEl(
+		h.Action(urlApiDevice+"/"+d.Addr.String()+"/details"),
+		h.Method("post"),
+		h.Div(
+			h.Class("form-control"),
+			wuiFormInput("Name",
+				h.Input(
+					h.Type("text"),
+					h.Name(wuiDetailsFormName),
+					h.Value(d.Name),
+					h.Required(),
+					h.Class("input input-bordered w-full"),
+				),
+			),
+			wuiFormInput("Owner",
+				h.Input(
+					h.Type("text"),
+					h.Name(wuiDetailsFormOwner),
+					h.Value(d.Meta.Owner),
+					h.Placeholder("who to ask about this device"),
+					h.Class("input input-bordered w-full"),
+				),
+			),
+			wuiFormInput("Site",
+				h.Select(
+					h.Name(wuiDetailsFormSite),
+					h.Class("select select-bordered w-full"),
+					siteOptions(sites, d.Meta.Site, "By network"),
+				),
+			),
+			wuiFormInput("Location",
+				h.Input(
+					h.Type("text"),
+					h.Name(wuiDetailsFormLocation),
+					h.Value(d.Meta.Location),
+					h.Placeholder("room, rack or desk"),
+					h.Class("input input-bordered w-full"),
+				),
+			),
+			wuiFormInput("Purchase Date",
+				h.Input(
+					h.Type("date"),
+					h.Name(wuiDetailsFormPurchaseDate),
+					h.Value(d.Meta.PurchaseDate),
+					h.Class("input input-bordered w-full"),
+				),
+			),
+			wuiFormInput("Serial",
+				h.Input(
+					h.Type("text"),
+					h.Name(wuiDetailsFormSerial),
+					h.Value(d.Meta.Serial),
+					h.Class("input input-bordered w-full"),
+				),
+			),
+			wuiFormInput("Asset Tag",
+				h.Input(
+					h.Type("text"),
+					h.Name(wuiDetailsFormAssetTag),
+					h.Value(d.Meta.AssetTag),
+					h.Class("input input-bordered w-full"),
+				),
+			),
+			wuiFormInput("Notes",
+				h.Textarea(
+					h.Name(wuiDetailsFormNotes),
+					h.MaxLength(strconv.Itoa(model.MaxDeviceNotesLength)),
+					h.Rows("4"),
+					h.Class("textarea textarea-bordered w-full"),
+					g.Text(d.Meta.Notes),
+				),
+			),
+		),
+		wuiFormButton("Save Details"),
+	)
+}
@@ -12,17 +12,18 @@ import (
 	"io"
 	"net/http"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/charmbracelet/log"
 	"github.com/dustin/go-humanize"
 	charts "github.com/go-echarts/go-echarts/v2/charts"
 	opts "github.com/go-echarts/go-echarts/v2/opts"
 	chartrender "github.com/go-echarts/go-echarts/v2/render"
 	"github.com/go-echarts/go-echarts/v2/types"
 	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
 	h "github.com/maragudk/gomponents/html"
 
 	"github.com/networkables/mason/internal/model"
@@ -33,9 +34,19 @@ type EChartPoint []interface{}
 
 func (w WUI) wuiDevicePageHandler(wr http.ResponseWriter, r *http.Request) {
 	ctx := context.TODO()
+	var live g.Node
+	if addr, err := w.m.StringToAddr(r.PathValue("id")); err == nil {
+		live = g.Group([]g.Node{
+			hx.Get(r.URL.RequestURI()),
+			hx.Trigger(liveDeviceAddrTrigger(addr)),
+			hx.Select("#maincontent"),
+			hx.Swap("outerHTML"),
+		})
+	}
 	content := h.Main(
 		h.ID("maincontent"),
 		h.Class("drawer-content"),
+		live,
 		w.wuiDeviceMain(ctx, r),
 	)
 	extra := h.Script(h.Src("/static/javascript/echarts.min.js"))
@@ -57,12 +68,22 @@ func (w WUI) wuiDeviceMain(ctx context.Context, r *http.Request) g.Node {
 	if err != nil {
 		errNode = errAlert(err)
 	}
-	dur := 6 * time.Hour
+	dur := parsePingRange(r.FormValue(wuiDeviceFormRange))
 
 	pingdata, err := w.m.ReadPerformancePings(ctx, d, dur)
 	if err != nil {
 		errNode = errAlert(err)
 	}
+	pingdata = pinger.Downsample(pingdata, maxPingGraphPoints)
+	annotations, err := w.m.ReadAnnotations(ctx, d.Addr, dur)
+	if err != nil {
+		errNode = errAlert(err)
+	}
+
+	history, err := w.m.DeviceHistory(ctx, d.Addr)
+	if err != nil {
+		errNode = errAlert(err)
+	}
 
 	ipflow, err := w.m.FlowSummaryByIP(ctx, d.Addr)
 	if err != nil {
@@ -77,43 +98,375 @@ func (w WUI) wuiDeviceMain(ctx context.Context, r *http.Request) g.Node {
 		errNode = errAlert(err)
 	}
 
+	sites, err := w.m.ListSites(ctx)
+	if err != nil {
+		errNode = errAlert(err)
+	}
+
+	services, err := w.m.ServiceCheckStatus(ctx)
+	if err != nil {
+		errNode = errAlert(err)
+	}
+	services = slices.DeleteFunc(services, func(s model.ServiceCheckStatus) bool {
+		return s.Check.Addr != d.Addr
+	})
+
+	bindings, err := w.m.MACBindings(ctx, d.Addr)
+	if err != nil {
+		errNode = errAlert(err)
+	}
+	quotas, err := w.m.DeviceQuotaUsage(ctx, d)
+	if err != nil {
+		errNode = errAlert(err)
+	}
+	domains, err := w.m.RecentDomains(ctx, d.Addr)
+	if err != nil {
+		errNode = errAlert(err)
+	}
+	logs := w.m.RecentSyslog(ctx, d.Addr)
+	site := w.m.SiteLookup(ctx)(d)
+	exclusions := w.m.DeviceExclusions(ctx, d)
+	parents := w.m.DeviceParents(ctx, d)
+	fullScan, swept := w.m.DeviceFullPortScan(ctx, d.Addr)
+	hints, err := w.m.DeviceVulnHints(ctx, d)
+	if err != nil {
+		errNode = errAlert(err)
+	}
+
+	// guests known as devices link to them
+	guestDevices := make(map[string]model.Addr)
+	if d.Virtual.IsHost() {
+		for _, dev := range w.m.ListDevices(ctx) {
+			if guest, ok := d.Virtual.GuestOf(dev); ok && dev.Addr != d.Addr {
+				guestDevices[guest.ID] = dev.Addr
+			}
+		}
+	}
+
 	return grid("",
-		widecard("Details", deviceToTable(d)),
+		widecard(
+			"Details",
+			h.Div(
+				deviceToTable(d, site, exclusions, parents),
+				deviceApprovalForm(d),
+				deviceLinks(d, w.m.GetConfig().Capture.Enabled),
+				deviceDeleteForm(d),
+			),
+		),
 		g.If(errNode != nil, widecard("Error", errNode)),
+		g.If(
+			d.Virtual.IsHost(),
+			widecard("Guests", deviceGuestsTable(d.Virtual.Guests, guestDevices)),
+		),
+		widecard("Edit", deviceDetailsForm(d, sites)),
+		widecard("Tags", deviceTagsForm(d)),
+		widecard("Monitoring", devicePolicyForm(d, w.m.EffectivePolicy(ctx, d), w.m.GetConfig())),
+		g.If(len(services) > 0, widecard("Services", serviceCheckTable(services, false))),
+		g.If(len(d.Addresses) > 0, widecard("Addresses", deviceAddrTable(d))),
+		g.If(swept, widecard("Full Port Scan", fullPortScanTable(fullScan))),
+		g.If(len(d.Server.Banners) > 0, widecard("Vulnerability Hints", vulnHintsTable(d, hints))),
+		g.If(len(bindings) > 1, widecard("MAC History", macBindingTable(bindings))),
 		graphcard("Ping Performance",
-			lineGraph3(
-				meantspoints2echartpoints(pingdata),
-				maxtspoints2echartpoints(pingdata),
-			),
-			lineGraph4(
-				losstspoints2echartpoints(pingdata),
+			h.Div(
+				h.Class("flex flex-wrap justify-between gap-2"),
+				pingRangeSelector(d.Addr, dur),
+				pingDownloadButtons(d.Addr, dur),
 			),
+			h.Div(h.ID("pinglatency"), lineGraph3(pingdata, annotations)),
+			h.Div(h.ID("pingloss"), lineGraph4(pingdata, annotations)),
 		),
+		g.If(len(quotas) > 0, graphcard("Bandwidth Quota",
+			quotaUsageTable(quotas, w.m.GetConfig().Quotas.WarnPercent),
+			quotaUsageGraph(quotas),
+		)),
+		g.If(len(domains) > 0, widecard("Recent Domains", recentDomainsTable(domains))),
+		g.If(len(logs) > 0, widecard("Recent Logs", recentSyslogTable(logs))),
 		widecard("NetOrg Stats", nameflowSummIPToTable(nameflow)),
 		widecard("Country Stats", countryflowSummIPToTable(countryflow)),
 		widecard("IP Stats", ipflowSummIPToTable(ipflow)),
+		widecard("History", deviceHistoryTable(history)),
+	)
+}
+
+// pingRangeSelector reloads the device page with the pings over another range
+func pingRangeSelector(addr model.Addr, current time.Duration) g.Node {
+	return h.Div(
+		h.Class("join"),
+		g.Group(g.Map(pingRanges, func(pr pingRange) g.Node {
+			class := "btn btn-sm join-item"
+			if pr.dur == current {
+				class += " btn-active"
+			}
+			return h.A(
+				h.Class(class),
+				h.Href(urlDevice+"/"+addr.String()+"?"+wuiDeviceFormRange+"="+pr.label),
+				g.Text(pr.label),
+			)
+		})),
+	)
+}
+
+// pingDownloadButtons saves the ping history shown in the graphs as csv, or the graphs as
+// images, to attach to a support ticket
+func pingDownloadButtons(addr model.Addr, window time.Duration) g.Node {
+	png := func(label string, id string) g.Node {
+		fname := "mason_" + id + "_" + strings.ReplaceAll(addr.String(), ":", "-") + ".png"
+		return h.Button(
+			h.Class("btn btn-sm"),
+			h.Type("button"),
+			g.Attr("onclick", "masonChartDownload('"+id+"', '"+fname+"')"),
+			g.Text(label),
+		)
+	}
+	return h.Div(
+		h.Class("flex flex-wrap gap-2"),
+		h.A(
+			h.Class("btn btn-sm"),
+			h.Href(pingsURL(addr, window, pingFormatCSV)),
+			g.Text("Download CSV"),
+		),
+		png("Latency PNG", "pinglatency"),
+		png("Loss PNG", "pingloss"),
+	)
+}
+
+// macBindingTable lists the MACs seen answering for the address, more than one is either a
+// replaced device or two devices claiming the address
+func macBindingTable(bindings []model.MACBinding) g.Node {
+	return wuiTable(
+		[]string{"MAC", "First Seen", "Last Seen", "Source"},
+		g.Group(g.Map(bindings, func(b model.MACBinding) g.Node {
+			return h.Tr(
+				h.Td(g.Text(b.MAC.String())),
+				h.Td(g.Text(b.FirstSeen.Local().Format(time.DateTime))),
+				h.Td(g.Text(b.LastSeen.Local().Format(time.DateTime))),
+				h.Td(g.Text(b.Source.String())),
+			)
+		})),
+	)
+}
+
+// deviceAddrTable lists the primary addr of the device followed by its additional addresses
+func deviceAddrTable(d model.Device) g.Node {
+	row := func(addr model.Addr, kind string, lastSeen time.Time) g.Node {
+		return h.Tr(
+			h.Td(g.Text(addr.String())),
+			h.Td(g.Text(kind)),
+			h.Td(g.Text(model.DateTimeFmt(lastSeen.Local()))),
+		)
+	}
+	return wuiTable(
+		[]string{"Address", "Kind", "Last Seen"},
+		g.Group([]g.Node{
+			row(d.Addr, "primary", d.PerformancePing.LastSeen),
+			g.Group(g.Map(d.Addresses, func(a model.DeviceAddr) g.Node {
+				return row(a.Addr, string(model.ClassifyIPv6(a.Addr, d.MAC)), a.LastSeen)
+			})),
+		}),
+	)
+}
+
+// fullPortScanTable shows how far the sweep of every port of the device has come, the open
+// ports are those found so far
+func fullPortScanTable(fs model.FullPortScan) g.Node {
+	class := "progress progress-primary w-32"
+	completed := "next port " + strconv.Itoa(fs.NextPort)
+	if fs.Done() {
+		class = "progress progress-success w-32"
+		completed = model.DateTimeFmt(fs.Completed.Local())
+	}
+	return wuiTable(
+		[]string{"Progress", "Open Ports", "Started", "Last Chunk", "Completed"},
+		h.Tr(
+			h.Td(
+				h.Progress(
+					h.Class(class),
+					h.Value(fmt.Sprint(fs.Percent())),
+					h.Max("100"),
+				),
+			),
+			h.Td(g.Text(fs.OpenPorts.String())),
+			h.Td(g.Text(model.DateTimeFmt(fs.Started.Local()))),
+			h.Td(g.Text(model.DateTimeFmt(fs.Updated.Local()))),
+			h.Td(g.Text(completed)),
+		),
 	)
 }
 
-func deviceToTable(d model.Device) g.Node {
+// vulnHintsTable lists the services whose banners announce a likely vulnerable version,
+// followed by the banners read from the open ports
+func vulnHintsTable(d model.Device, hints []model.VulnHint) g.Node {
+	return h.Div(
+		h.Class("flex flex-col gap-4"),
+		h.P(
+			h.Class("text-sm opacity-70"),
+			g.Text("Informational only, told from the version in the banner. "+
+				"Vendors backport fixes without changing the version, confirm with the vendor advisories."),
+		),
+		g.If(len(hints) == 0, h.P(g.Text("No banner matches a known vulnerable version."))),
+		g.If(len(hints) > 0, wuiTable(
+			[]string{"Port", "Severity", "Version", "CVEs", "Summary"},
+			g.Group(g.Map(hints, func(vh model.VulnHint) g.Node {
+				return h.Tr(
+					h.Td(g.Textf("%d", vh.Port)),
+					h.Td(vulnSeverityBadge(vh.Severity)),
+					h.Td(g.Text(vh.Version)),
+					h.Td(g.Group(g.Map(vh.CVEs, func(cve string) g.Node {
+						return h.A(
+							h.Class("link block"),
+							h.Href("https://nvd.nist.gov/vuln/detail/"+cve),
+							g.Attr("target", "_blank"),
+							g.Attr("rel", "noreferrer"),
+							g.Text(cve),
+						)
+					}))),
+					h.Td(g.Text(vh.Summary)),
+				)
+			})),
+		)),
+		wuiTable(
+			[]string{"Port", "Banner"},
+			g.Group(g.Map(d.Server.Banners, func(b model.Banner) g.Node {
+				return h.Tr(
+					h.Td(g.Textf("%d", b.Port)),
+					h.Td(g.Text(b.Text)),
+				)
+			})),
+		),
+	)
+}
+
+func vulnSeverityBadge(s model.VulnSeverity) g.Node {
+	class := "badge badge-ghost"
+	switch s {
+	case model.VulnSeverityCritical, model.VulnSeverityHigh:
+		class = "badge badge-error"
+	case model.VulnSeverityMedium:
+		class = "badge badge-warning"
+	}
+	return h.Span(h.Class(class), g.Text(string(s)))
+}
+
+// deviceLinks opens the label sheet of the device and, with captures enabled, the capture
+// page with a filter for the traffic of the device
+func deviceLinks(d model.Device, capture bool) g.Node {
+	return h.Div(
+		h.Class("flex justify-end gap-2 pt-4"),
+		h.A(
+			h.Class("btn btn-sm"),
+			h.Href(labelsURL(d.Addr)),
+			h.Target("_blank"),
+			g.Text("Print Label"),
+		),
+		g.If(capture, h.A(
+			h.Class("btn btn-sm"),
+			h.Href(captureURL("host "+d.Addr.String())),
+			g.Text("Capture Traffic"),
+		)),
+	)
+}
+
+// recentDomainsTable lists the domains looked up by the device, the latest first
+func recentDomainsTable(domains []model.DomainSummary) g.Node {
+	return wuiTable(
+		[]string{"Domain", "Queries", "Last Seen"},
+		g.Group(g.Map(domains, func(d model.DomainSummary) g.Node {
+			return h.Tr(
+				h.Td(g.Text(d.Domain)),
+				h.Td(g.Text(strconv.Itoa(d.Queries))),
+				h.Td(g.Text(d.LastSeen.Local().Format(time.DateTime))),
+			)
+		})),
+	)
+}
+
+func recentSyslogTable(logs []model.SyslogMessage) g.Node {
+	return wuiTable(
+		[]string{"Time", "Severity", "App", "Message"},
+		g.Group(g.Map(logs, func(msg model.SyslogMessage) g.Node {
+			return h.Tr(
+				h.Td(g.Text(msg.Time.Local().Format(time.DateTime))),
+				h.Td(syslogSeverityBadge(msg.Severity)),
+				h.Td(g.Text(msg.App)),
+				h.Td(g.Text(msg.Message)),
+			)
+		})),
+	)
+}
+
+func syslogSeverityBadge(s model.SyslogSeverity) g.Node {
+	class := "badge badge-ghost"
+	switch {
+	case s <= model.SyslogCritical:
+		class = "badge badge-error"
+	case s <= model.SyslogWarning:
+		class = "badge badge-warning"
+	}
+	return h.Span(h.Class(class), g.Text(s.String()))
+}
+
+func deviceToTable(
+	d model.Device,
+	site string,
+	exclusions model.Exclusions,
+	parents []model.Device,
+) g.Node {
 	return h.Table(
 		h.Class("table table-zebra"),
 		h.TBody(
-			toTHTD("Name", d.Name),
+			h.Tr(h.Th(g.Text("Name")), h.Td(g.Text(d.Name), excludedBadge(exclusions))),
+			g.If(len(exclusions) > 0, h.Tr(
+				h.Th(g.Text("Exclusions")),
+				h.Td(g.Group(g.Map(exclusions, func(x model.Exclusion) g.Node {
+					return h.Div(h.A(
+						h.Href(urlExclusions),
+						h.Class("link"),
+						g.Text(exclusionSummary(x)),
+					))
+				}))),
+			)),
+			g.If(len(parents) > 0, h.Tr(
+				h.Th(h.A(h.Href(urlDependencies), h.Class("link"), g.Text("Depends On"))),
+				h.Td(g.Group(g.Map(parents, func(p model.Device) g.Node {
+					return h.Div(h.A(
+						h.Href(urlDevice+"/"+p.Addr.String()),
+						h.Class("link"),
+						g.Text(p.Name+" ("+p.Addr.String()+")"),
+					))
+				}))),
+			)),
 			toTHTD("DNS Name", d.Meta.DnsName),
 			toTHTD("Addr", d.Addr.String()),
 			toTHTD("MAC", d.MAC.String()),
+			toTHTD("Observed MACs", macsString(d.ObservedMACs)),
+			toTHTD("VLAN", d.VLAN.String()),
+			toTHTD("Connection", d.Link.String()),
 			toTHTD("Manufacturer", d.Meta.Manufacturer),
+			toTHTD("Owner", d.Meta.Owner),
+			toTHTD("Site", site),
+			toTHTD("Location", d.Meta.Location),
+			toTHTD("Purchase Date", d.Meta.PurchaseDate),
+			toTHTD("Serial", d.Meta.Serial),
+			toTHTD("Asset Tag", d.Meta.AssetTag),
+			h.Tr(h.Th(g.Text("Notes")), h.Td(h.Class("whitespace-pre-wrap"), g.Text(d.Meta.Notes))),
+			toTHTD("Approval", string(d.Approval())),
+			h.Tr(h.Th(g.Text("Type")), h.Td(deviceTypeLink(d.Meta.DeviceType))),
 			toTHTD("Discovered", d.DiscoveredAtString()+" by "+string(d.DiscoveredBy)),
 			toTHTD("First Seen", d.FirstSeenString()),
 			toTHTD("Last Seen", d.LastSeenString()+"("+d.LastSeenDurString(time.Since)+")"),
 			toTHTD("Last Ping Mean", d.LastPingMeanString()),
 			toTHTD("Last Ping Maximum", d.LastPingMaximumString()),
+			toTHTD("Ping Probe", string(d.PerformancePing.Probe)),
 
 			toTHTD("Open Ports", fmt.Sprintf("%d", d.Server.Ports)),
 			toTHTD("Last Port Scan", fmt.Sprintf("%s", model.DateTimeFmt(d.Server.LastScan))),
 			toTHTD("Tags", fmt.Sprintf("%s", d.Meta.Tags)),
 
+			toTHTD("mDNS Name", d.Meta.MDNSName),
+			toTHTD("DHCP Hostname", d.Meta.DHCPHostname),
+			toTHTD("DHCP Fingerprint", d.Meta.DHCPFingerprint),
+			toTHTD("DHCP Vendor Class", d.Meta.DHCPVendorClass),
+
 			toTHTD("SNMP Name", d.SNMP.Name),
 			toTHTD("SNMP Description", d.SNMP.Description),
 			toTHTD("SNMP Community", d.SNMP.Community),
@@ -123,10 +476,52 @@ func deviceToTable(d model.Device) g.Node {
 			toTHTD("SNMP LastArpTableScan", model.DateTimeFmt(d.SNMP.LastArpTableScan)),
 			toTHTD("SNMP Interfaces", fmt.Sprintf("%t", d.SNMP.HasInterfaces)),
 			toTHTD("SNMP LastInterfacesScan", model.DateTimeFmt(d.SNMP.LastInterfacesScan)),
+
+			toTHTD("Link Source", d.Link.Source),
+			toTHTD("Link Radio", d.Link.Radio),
+			toTHTD("Link Uplink MAC", d.Link.UplinkMAC),
+			toTHTD("Link LastSeen", model.DateTimeFmt(d.Link.LastSeen)),
+
+			toTHTD("Virtual Platform", string(d.Virtual.Platform)),
+			toTHTD("Virtual LastScan", model.DateTimeFmt(d.Virtual.LastScan)),
+			h.Tr(h.Th(g.Text("Runs On")), h.Td(deviceLink(d.Virtual.Parent))),
 		),
 	)
 }
 
+// deviceLink links to the device page, nothing is shown for an empty addr
+func deviceLink(addr model.Addr) g.Node {
+	if !addr.A.IsValid() {
+		return nil
+	}
+	return h.A(h.Href("/device/"+addr.String()), h.Class("link"), g.Text(addr.String()))
+}
+
+func deviceGuestsTable(guests model.Guests, devices map[string]model.Addr) g.Node {
+	return wuiTable([]string{"Name", "Kind", "State", "MAC", "IP", "Device"},
+		g.Group(
+			g.Map(guests, func(guest model.Guest) g.Node {
+				return h.Tr(
+					h.Td(g.Text(guest.Name)),
+					h.Td(g.Text(string(guest.Kind))),
+					h.Td(g.Text(guest.State)),
+					h.Td(g.Text(guest.MAC)),
+					h.Td(g.Text(guest.Addr)),
+					h.Td(deviceLink(devices[guest.ID])),
+				)
+			}),
+		),
+	)
+}
+
+func macsString(macs []model.MAC) string {
+	strs := make([]string, len(macs))
+	for idx, mac := range macs {
+		strs[idx] = mac.String()
+	}
+	return strings.Join(strs, ", ")
+}
+
 func ipflowSummIPToTable(fs []model.FlowSummaryForAddrByIP) g.Node {
 	return wuiTable([]string{"IP", "Country", "Org", "ASN", "In", "Out"},
 		g.Group(
@@ -182,14 +577,16 @@ const (
 var (
 	graphTpl = `
 <div class="container">
-  <div class="item" id="{{ .ChartID }}" style="width:{{ .Initialization.Width }};height:{{ .Initialization.Height }};"></div>
+  <div class="item" id="{{ .ChartID }}" style="width:{{ .Initialization.Width }};max-width:100%;height:{{ .Initialization.Height }};"></div>
 </div>
 
 <script type="text/javascript">
     "use strict";
-    let goecharts_{{ .ChartID | safeJS }} = echarts.init(document.getElementById('{{ .ChartID | safeJS }}'), "{{ .Theme }}", { renderer: "{{  .Initialization.Renderer }}" });
+    let goecharts_{{ .ChartID | safeJS }} = echarts.init(document.getElementById('{{ .ChartID | safeJS }}'), masonChartTheme(), { renderer: "{{  .Initialization.Renderer }}" });
     let option_{{ .ChartID | safeJS }} = {{ .JSONNotEscaped | safeJS }};
     goecharts_{{ .ChartID | safeJS }}.setOption(option_{{ .ChartID | safeJS }});
+    goecharts_{{ .ChartID | safeJS }}.setOption({ backgroundColor: "transparent" });
+    window.addEventListener("resize", () => goecharts_{{ .ChartID | safeJS }}.resize());
 
   {{- range  $listener := .EventListeners }}
     {{if .Query  }}
@@ -308,27 +705,30 @@ var (
 // 	return g.Raw(htmlsnippet)
 // }
 
-func lineGraph3(avg []EChartPoint, maxi []EChartPoint) g.Node {
+func lineGraph3(points []pinger.Point, annotations []model.Annotation) g.Node {
 	line := charts.NewLine()
 	line.Initialization.Width = "800px"
 	//line.Theme = "wonderland"
 
-	// preformat data
-	avgdata := make([]opts.LineData, len(avg))
-	for i, point := range avg {
-		avgdata[i] = opts.LineData{Value: point}
+	series := []struct {
+		name  string
+		value func(pinger.Point) time.Duration
+	}{
+		{name: "Average Response", value: func(p pinger.Point) time.Duration { return p.Average }},
+		{name: "Max Response", value: func(p pinger.Point) time.Duration { return p.Maximum }},
+		{name: "P95 Response", value: func(p pinger.Point) time.Duration { return p.P95 }},
+		{name: "P99 Response", value: func(p pinger.Point) time.Duration { return p.P99 }},
+		{name: "Jitter", value: func(p pinger.Point) time.Duration { return p.Jitter }},
 	}
-	maxdata := make([]opts.LineData, len(maxi))
-	for i, point := range maxi {
-		maxdata[i] = opts.LineData{Value: point}
+	for idx, s := range series {
+		seriesOpts := []charts.SeriesOpts{charts.WithLabelOpts(
+			opts.Label{Show: opts.Bool(true), Position: "bottom"},
+		)}
+		if idx == 0 {
+			seriesOpts = append(seriesOpts, annotationMarkLines(annotations))
+		}
+		line.AddSeries(s.name, durationtspoints2linedata(points, s.value), seriesOpts...)
 	}
-
-	line.AddSeries("Average Response", avgdata, charts.WithLabelOpts(
-		opts.Label{Show: opts.Bool(true), Position: "bottom"},
-	))
-	line.AddSeries("Max Response", maxdata, charts.WithLabelOpts(
-		opts.Label{Show: opts.Bool(true), Position: "bottom"},
-	))
 	line.SetGlobalOptions(
 		charts.WithTooltipOpts(opts.Tooltip{
 			Trigger: "axis",
@@ -341,6 +741,7 @@ func lineGraph3(avg []EChartPoint, maxi []EChartPoint) g.Node {
 			NameLocation: "middle",
 			Type:         "time",
 		}),
+		pingDataZoom(),
 		charts.WithYAxisOpts(opts.YAxis{
 			Name:         "duration (ms)",
 			NameLocation: "end",
@@ -365,19 +766,31 @@ func lineGraph3(avg []EChartPoint, maxi []EChartPoint) g.Node {
 	return g.Raw(htmlsnippet)
 }
 
-func lineGraph4(loss []EChartPoint) g.Node {
+func lineGraph4(points []pinger.Point, annotations []model.Annotation) g.Node {
 	line := charts.NewLine()
 	line.Initialization.Width = "800px"
 	//line.Theme = "wonderland"
 
-	lossdata := make([]opts.LineData, len(loss))
-	for i, point := range loss {
-		lossdata[i] = opts.LineData{Value: point}
+	lossdata := make([]opts.LineData, len(points))
+	failuresdata := make([]opts.LineData, len(points))
+	for i, point := range points {
+		lossdata[i] = opts.LineData{Value: EChartPoint{point.Start, point.Loss * 100.0}}
+		failuresdata[i] = opts.LineData{Value: EChartPoint{point.Start, point.ConsecutiveFailures}}
 	}
 
 	line.AddSeries("Packet Loss", lossdata, charts.WithLabelOpts(
 		opts.Label{Show: opts.Bool(true), Position: "bottom"},
-	))
+	), charts.WithLineChartOpts(opts.LineChart{
+		Smooth: opts.Bool(true),
+	}), annotationMarkLines(annotations))
+	// a burst of lost echoes points at bufferbloat or a flapping link, where the loss alone
+	// looks the same as scattered drops
+	line.AddSeries("Consecutive Failures", failuresdata, charts.WithLabelOpts(
+		opts.Label{Show: opts.Bool(true), Position: "bottom"},
+	), charts.WithLineChartOpts(opts.LineChart{
+		Step:       "end",
+		YAxisIndex: 1,
+	}))
 	line.SetGlobalOptions(
 		charts.WithTooltipOpts(opts.Tooltip{
 			Trigger: "axis",
@@ -390,6 +803,7 @@ func lineGraph4(loss []EChartPoint) g.Node {
 			NameLocation: "middle",
 			Type:         "time",
 		}),
+		pingDataZoom(),
 		charts.WithYAxisOpts(opts.YAxis{
 			Name:         "packet loss (%)",
 			NameLocation: "end",
@@ -399,10 +813,12 @@ func lineGraph4(loss []EChartPoint) g.Node {
 			},
 		}),
 	)
+	line.ExtendYAxis(opts.YAxis{
+		Name:         "failures",
+		NameLocation: "end",
+		Type:         "value",
+	})
 	line.SetSeriesOptions(
-		charts.WithLineChartOpts(opts.LineChart{
-			Smooth: opts.Bool(true),
-		}),
 		charts.WithLabelOpts(opts.Label{
 			Show:      opts.Bool(false),
 			Formatter: "{a}",
@@ -414,6 +830,41 @@ func lineGraph4(loss []EChartPoint) g.Node {
 	return g.Raw(htmlsnippet)
 }
 
+// pingDataZoom zooms the ping graphs with the mouse wheel or a pinch and pans them by
+// dragging, the slider below shows the part of the range in view
+func pingDataZoom() charts.GlobalOpts {
+	return charts.WithDataZoomOpts(
+		opts.DataZoom{Type: "inside", XAxisIndex: 0},
+		opts.DataZoom{Type: "slider", XAxisIndex: 0},
+	)
+}
+
+// annotationMarkLines draws a vertical marker for each annotation so changes can be
+// lined up against the series data
+func annotationMarkLines(annotations []model.Annotation) charts.SeriesOpts {
+	items := make([]opts.MarkLineNameXAxisItem, 0, len(annotations))
+	for _, a := range annotations {
+		items = append(items, opts.MarkLineNameXAxisItem{
+			Name:  string(a.Kind) + ": " + a.Text,
+			XAxis: a.Time,
+		})
+	}
+	return func(s *charts.SingleSeries) {
+		if len(items) == 0 {
+			return
+		}
+		charts.WithMarkLineNameXAxisItemOpts(items...)(s)
+		charts.WithMarkLineStyleOpts(opts.MarkLineStyle{
+			Symbol: []string{"none", "none"},
+			Label: &opts.Label{
+				Show:      opts.Bool(true),
+				Formatter: "{b}",
+				Position:  "insideEndTop",
+			},
+		})(s)
+	}
+}
+
 // func lineGraph(title string, seriesname string, points []EChartPoint) g.Node {
 // 	line := charts.NewLine()
 // 	line.Initialization.Width = "800px"
@@ -510,7 +961,7 @@ func renderToHtml(c interface{}) template.HTML {
 	r := c.(chartrender.Renderer)
 	err := r.Render(&buf)
 	if err != nil {
-		log.Error(
+		logger.Error(
 			"failed to render chart",
 			"error",
 			err,
@@ -527,7 +978,7 @@ func renderToString(c interface{}) string {
 	r := c.(chartrender.Renderer)
 	err := r.Render(&buf)
 	if err != nil {
-		log.Error(
+		logger.Error(
 			"failed to render chart",
 			"error",
 			err,
@@ -551,26 +1002,16 @@ func renderToString(c interface{}) string {
 // 	return ret
 // }
 
-func meantspoints2echartpoints(points []pinger.Point) []EChartPoint {
-	ret := make([]EChartPoint, len(points))
-	for i, point := range points {
-		ret[i] = EChartPoint{point.Start, float64(point.Average) / float64(time.Millisecond)}
-	}
-	return ret
-}
-
-func maxtspoints2echartpoints(points []pinger.Point) []EChartPoint {
-	ret := make([]EChartPoint, len(points))
-	for i, point := range points {
-		ret[i] = EChartPoint{point.Start, float64(point.Maximum) / float64(time.Millisecond)}
-	}
-	return ret
-}
-
-func losstspoints2echartpoints(points []pinger.Point) []EChartPoint {
-	ret := make([]EChartPoint, len(points))
+// durationtspoints2linedata charts a duration of the points in milliseconds
+func durationtspoints2linedata(
+	points []pinger.Point,
+	value func(pinger.Point) time.Duration,
+) []opts.LineData {
+	ret := make([]opts.LineData, len(points))
 	for i, point := range points {
-		ret[i] = EChartPoint{point.Start, point.Loss * 100.0}
+		ret[i] = opts.LineData{
+			Value: EChartPoint{point.Start, float64(value(point)) / float64(time.Millisecond)},
+		}
 	}
 	return ret
 }
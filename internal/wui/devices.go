@@ -6,9 +6,15 @@ package wui
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/dustin/go-humanize"
 	g "github.com/maragudk/gomponents"
 	hx "github.com/maragudk/gomponents-htmx"
 	h "github.com/maragudk/gomponents/html"
@@ -21,22 +27,52 @@ func (w WUI) wuiDevicesPageHandler(wr http.ResponseWriter, r *http.Request) {
 	content := h.Main(
 		h.ID("maincontent"),
 		h.Class("drawer-content"),
-		w.wuiDevicesMain(ctx),
+		w.wuiDevicesMain(ctx, r),
 	)
 	w.basePage(ctx, "devices", content, nil).Render(wr)
 }
 
-func (w WUI) wuiDevicesMain(ctx context.Context) g.Node {
-	devs := w.m.ListDevices(ctx)
-	model.SortDevicesByAddr(devs)
-	return h.Div(
-		hx.Get("/api/devices"),
-		hx.Trigger("every 60s"),
-		hx.Swap("innerHTML"),
-		grid("",
-			wuiCard(
-				"Devices as of "+time.Now().Format("15:04"),
-				devicesToTable(devs),
+const (
+	wuiDevicesFormSearch   = "q"
+	wuiDevicesFormSort     = "sort"
+	wuiDevicesFormDir      = "dir"
+	wuiDevicesFormPage     = "page"
+	wuiDevicesFormVLAN     = "vlan"
+	wuiDevicesFormTag      = "tag"
+	wuiDevicesFormApproval = "approval"
+	wuiDevicesFormType     = "type"
+
+	wuiDevicesDirDesc = "desc"
+)
+
+// wuiDevicesMain holds the search box and the device list.  The list can be limited to a
+// single vlan with ?vlan=<id>, to a single tag with ?tag=<name>, to an approval state with
+// ?approval=<state> and to a device type with ?type=<type>; ?q= searches,
+// ?sort=<column>&dir=desc orders and ?page= pages.
+// Only devices at the selected site are listed.
+func (w WUI) wuiDevicesMain(ctx context.Context, r *http.Request) g.Node {
+	q, params := w.deviceQueryFromRequest(ctx, r)
+	return grid("",
+		wuiCard("Devices",
+			h.Div(
+				h.FormEl(
+					h.Action(urlDevices),
+					h.Method("get"),
+					hx.Get(urlApiDevices),
+					hx.Target("#devicelist"),
+					hx.Swap("outerHTML"),
+					hx.Trigger("input changed delay:300ms, submit"),
+					hx.Include("#devicelist-state"),
+					h.Input(
+						h.Type("search"),
+						h.Name(wuiDevicesFormSearch),
+						h.Value(q.Search),
+						h.Placeholder("Search name, address, MAC, manufacturer, type or tag"),
+						h.Class("input input-bordered w-full md:w-1/2"),
+					),
+				),
+				deviceBulkForm(),
+				w.wuiDeviceList(ctx, q, params, nil),
 			),
 		),
 	)
@@ -44,23 +80,218 @@ func (w WUI) wuiDevicesMain(ctx context.Context) g.Node {
 
 func (w WUI) wuiDevicesApiHandler(wr http.ResponseWriter, r *http.Request) {
 	ctx := context.TODO()
-	w.wuiDevicesMain(ctx).Render(wr)
+	q, params := w.deviceQueryFromRequest(ctx, r)
+	w.wuiDeviceList(ctx, q, params, nil).Render(wr)
+}
+
+// deviceQueryFromRequest builds the device query from the request, the returned values hold
+// the recognized parameters so links can carry them forward
+func (w WUI) deviceQueryFromRequest(
+	ctx context.Context,
+	r *http.Request,
+) (model.DeviceQuery, url.Values) {
+	q := model.DeviceQuery{Limit: model.DefaultDevicePageSize}
+	params := url.Values{}
+	filters := make([]model.DeviceFilter, 0)
+	if vlanstr := r.FormValue(wuiDevicesFormVLAN); vlanstr != "" {
+		vlan, err := strconv.Atoi(vlanstr)
+		if err == nil {
+			filters = append(filters, model.VLANDeviceFilter(vlan))
+			params.Set(wuiDevicesFormVLAN, vlanstr)
+		}
+	}
+	if tag := r.FormValue(wuiDevicesFormTag); tag != "" {
+		filters = append(filters, model.TagDeviceFilter(tag))
+		params.Set(wuiDevicesFormTag, tag)
+	}
+	if approval := r.FormValue(wuiDevicesFormApproval); approval != "" {
+		state, err := model.ParseApprovalState(approval)
+		if err == nil {
+			filters = append(filters, model.ApprovalDeviceFilter(state))
+			params.Set(wuiDevicesFormApproval, approval)
+		}
+	}
+	if t := r.FormValue(wuiDevicesFormType); t != "" {
+		filters = append(filters, model.DeviceTypeFilter(model.DeviceType(strings.ToLower(t))))
+		params.Set(wuiDevicesFormType, t)
+	}
+	if site := wuiSelectedSite(r); site != "" {
+		filters = append(filters, model.SiteDeviceFilter(site, w.m.SiteLookup(ctx)))
+	}
+	if len(filters) > 0 {
+		q.Filter = func(d model.Device) bool {
+			for _, f := range filters {
+				if !f(d) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	if search := r.FormValue(wuiDevicesFormSearch); search != "" {
+		q.Search = search
+		params.Set(wuiDevicesFormSearch, search)
+	}
+	if sort, err := model.ParseDeviceSort(r.FormValue(wuiDevicesFormSort)); err == nil {
+		q.Sort = sort
+		params.Set(wuiDevicesFormSort, string(sort))
+	}
+	if r.FormValue(wuiDevicesFormDir) == wuiDevicesDirDesc {
+		q.Descending = true
+		params.Set(wuiDevicesFormDir, wuiDevicesDirDesc)
+	}
+	if page, err := strconv.Atoi(r.FormValue(wuiDevicesFormPage)); err == nil && page > 1 {
+		q.Offset = (page - 1) * q.Limit
+		params.Set(wuiDevicesFormPage, strconv.Itoa(page))
+	}
+	return q, params
+}
+
+// withParams copies the params replacing the given key value pairs, an empty value removes
+// the key
+func withParams(params url.Values, kv ...string) string {
+	out := url.Values{}
+	for k, v := range params {
+		out[k] = slices.Clone(v)
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		if kv[i+1] == "" {
+			out.Del(kv[i])
+			continue
+		}
+		out.Set(kv[i], kv[i+1])
+	}
+	if len(out) == 0 {
+		return ""
+	}
+	return "?" + out.Encode()
+}
+
+// wuiDeviceList renders one page of the device query with an optional status above it.  It
+// refreshes itself in place, the hidden state inputs let the search box and bulk actions
+// keep the current filters and sort.
+func (w WUI) wuiDeviceList(
+	ctx context.Context,
+	q model.DeviceQuery,
+	params url.Values,
+	status g.Node,
+) g.Node {
+	page := w.m.QueryDevices(ctx, q)
+	exclusions, _ := w.m.ListExclusions(ctx)
+	state := make([]g.Node, 0)
+	for _, key := range []string{
+		wuiDevicesFormVLAN,
+		wuiDevicesFormTag,
+		wuiDevicesFormApproval,
+		wuiDevicesFormType,
+		wuiDevicesFormSort,
+		wuiDevicesFormDir,
+	} {
+		if v := params.Get(key); v != "" {
+			state = append(state, h.Input(h.Type("hidden"), h.Name(key), h.Value(v)))
+		}
+	}
+	return h.Div(
+		h.ID("devicelist"),
+		hx.Get(urlApiDevices+withParams(params)),
+		hx.Trigger("every 60s "+wuiBulkIdle+", mason:device"+wuiBulkIdle+" from:body delay:1s"),
+		hx.Swap("outerHTML"),
+		status,
+		h.Div(h.ID("devicelist-state"), g.Group(state)),
+		devicesToTable(page.Devices, exclusions, q, params),
+		devicePager(page, params),
+	)
+}
+
+func devicePager(page model.DevicePage, params url.Values) g.Node {
+	first := 0
+	if page.Total > 0 {
+		first = page.Offset + 1
+	}
+	current := page.Offset/page.Limit + 1
+	pageLink := func(label string, n int, enabled bool) g.Node {
+		if !enabled {
+			return h.Button(h.Class("btn btn-sm join-item btn-disabled"), g.Text(label))
+		}
+		return h.Button(
+			h.Class("btn btn-sm join-item"),
+			hx.Get(urlApiDevices+withParams(params, wuiDevicesFormPage, strconv.Itoa(n))),
+			hx.Target("#devicelist"),
+			hx.Swap("outerHTML"),
+			g.Text(label),
+		)
+	}
+	return h.Div(
+		h.Class("flex items-center justify-between pt-4"),
+		h.Span(
+			h.Class("text-sm opacity-70"),
+			g.Textf(
+				"%s-%s of %s as of %s",
+				humanize.Comma(int64(first)),
+				humanize.Comma(int64(page.Offset+len(page.Devices))),
+				humanize.Comma(int64(page.Total)),
+				time.Now().Format("15:04"),
+			),
+		),
+		h.Div(
+			h.Class("join"),
+			pageLink("Prev", current-1, page.HasPrev()),
+			pageLink("Next", current+1, page.HasNext()),
+		),
+	)
 }
 
-func devicesToTable(devs []model.Device) g.Node {
+// sortHeader is a column heading which sorts the list by the column, clicking the active
+// column flips the direction
+func sortHeader(label string, sort model.DeviceSort, q model.DeviceQuery, params url.Values) g.Node {
+	dir, arrow := "", ""
+	if q.Sort == sort {
+		arrow = " ▲"
+		if q.Descending {
+			arrow = " ▼"
+		} else {
+			dir = wuiDevicesDirDesc
+		}
+	}
+	return h.Th(
+		h.A(
+			h.Class("link link-hover"),
+			hx.Get(urlApiDevices+withParams(
+				params,
+				wuiDevicesFormSort, string(sort),
+				wuiDevicesFormDir, dir,
+				wuiDevicesFormPage, "",
+			)),
+			hx.Target("#devicelist"),
+			hx.Swap("outerHTML"),
+			g.Text(label+arrow),
+		),
+	)
+}
+
+func devicesToTable(
+	devs []model.Device,
+	exclusions model.Exclusions,
+	q model.DeviceQuery,
+	params url.Values,
+) g.Node {
 	rows := make([]g.Node, 0, len(devs))
-	for _, dev := range devs {
-		rows = append(rows, deviceToTD(dev))
+	for _, dev := range nestGuests(devs) {
+		rows = append(rows, deviceToTD(dev.Device, dev.depth, exclusions.Matching(dev.Addr)))
 	}
 	return h.Table(
 		h.Class("table table-zebra"),
 		h.THead(
 			h.Tr(
+				h.Th(deviceSelectAll()),
 				h.Th(g.Text("")),
-				h.Th(g.Text("Name")),
-				h.Th(g.Text("IP")),
-				h.Th(g.Text("Last Seen")),
-				h.Th(g.Text("Ping")),
+				sortHeader("Name", model.DeviceSortName, q, params),
+				sortHeader("IP", model.DeviceSortAddr, q, params),
+				h.Th(h.Class(wuiNarrowHidden), g.Text("Type")),
+				h.Th(h.Class(wuiNarrowHidden), g.Text("VLAN")),
+				h.Th(h.Class(wuiNarrowHidden), g.Text("Tags")),
+				sortHeader("Last Seen", model.DeviceSortLastSeen, q, params),
+				sortHeader("Ping", model.DeviceSortPing, q, params),
 			),
 		),
 		h.TBody(
@@ -69,18 +300,105 @@ func devicesToTable(devs []model.Device) g.Node {
 	)
 }
 
-func deviceToTD(d model.Device) g.Node {
+// nestedDevice is a row of the device list, guests are listed under their host
+type nestedDevice struct {
+	model.Device
+	depth int
+}
+
+// nestGuests moves the guests right below their host when the host is on the same page, the
+// order of the page is kept otherwise
+func nestGuests(devs []model.Device) []nestedDevice {
+	onPage := make(map[model.Addr]bool, len(devs))
+	for _, dev := range devs {
+		onPage[dev.Addr] = true
+	}
+	nested := func(dev model.Device) bool {
+		return dev.Virtual.IsGuest() && onPage[dev.Virtual.Parent]
+	}
+	guests := make(map[model.Addr][]model.Device)
+	for _, dev := range devs {
+		if nested(dev) {
+			guests[dev.Virtual.Parent] = append(guests[dev.Virtual.Parent], dev)
+		}
+	}
+	rows := make([]nestedDevice, 0, len(devs))
+	listed := make(map[model.Addr]bool, len(devs))
+	var add func(dev model.Device, depth int)
+	add = func(dev model.Device, depth int) {
+		if listed[dev.Addr] {
+			return
+		}
+		listed[dev.Addr] = true
+		rows = append(rows, nestedDevice{Device: dev, depth: depth})
+		for _, guest := range guests[dev.Addr] {
+			add(guest, depth+1)
+		}
+	}
+	for _, dev := range devs {
+		if !nested(dev) {
+			add(dev, 0)
+		}
+	}
+	// hosts which are guests of each other have no top row, keep them listed
+	for _, dev := range devs {
+		add(dev, 0)
+	}
+	return rows
+}
+
+func deviceToTD(d model.Device, depth int, exclusions model.Exclusions) g.Node {
 	url := "/device/" + d.Addr.String()
+	name := g.Text(d.Name)
+	if depth > 0 {
+		name = h.Span(
+			h.Class("opacity-70"),
+			h.StyleAttr(fmt.Sprintf("padding-left: %drem", depth)),
+			g.Text("↳ "+d.Name),
+		)
+	}
 	detailsBtn := h.A(h.Href(url), svgMagnifyGlass())
 	// graphBtn := h.A(h.Href(url), svgBarChart())
 	return h.Tr(
+		h.Td(deviceSelect(d)),
 		h.Td(
 			detailsBtn,
 			// graphBtn,
 		),
-		h.Td(g.Text(d.Name)),
+		h.Td(h.A(h.Href(url), h.Class("link-hover"), name), excludedBadge(exclusions)),
 		h.Td(g.Text(d.Addr.String())),
+		h.Td(h.Class(wuiNarrowHidden), deviceTypeLink(d.Meta.DeviceType)),
+		h.Td(h.Class(wuiNarrowHidden), vlanLink(d.VLAN)),
+		h.Td(h.Class(wuiNarrowHidden), tagLinks(urlDevices, d.Meta.Tags)),
 		h.Td(g.Text(d.LastSeenDurString(time.Since))),
 		h.Td(g.Text(d.LastPingMeanString())),
 	)
 }
+
+func filterDevices(devs []model.Device, filter model.DeviceFilter) []model.Device {
+	return slices.DeleteFunc(devs, func(d model.Device) bool {
+		return !filter(d)
+	})
+}
+
+func deviceTypeLink(t model.DeviceType) g.Node {
+	if t == model.DeviceTypeUnknown {
+		return nil
+	}
+	return h.A(
+		h.Href(urlDevices+"?type="+url.QueryEscape(string(t))),
+		h.Class("link"),
+		g.Text(t.String()),
+	)
+}
+
+func vlanLink(v model.VLAN) g.Node {
+	if v.IsEmpty() {
+		return nil
+	}
+	return h.A(
+		h.Href(urlDevices+"?vlan="+strconv.Itoa(v.ID)),
+		h.Class("link"),
+		g.Text(v.String()),
+	)
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	wuiDHCPSightingFormKind = "kind"
+	wuiDHCPSightingFormAddr = "addr"
+)
+
+func (w WUI) wuiApiDHCPSightingTrust(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	kind, addr, err := dhcpSightingFromForm(r)
+	if err == nil {
+		err = w.m.TrustDHCPSighting(ctx, kind, addr)
+	}
+	w.wuiHTTPChecksMain(ctx, err).Render(wr)
+}
+
+func (w WUI) wuiApiDHCPSightingForget(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	kind, addr, err := dhcpSightingFromForm(r)
+	if err == nil {
+		err = w.m.ForgetDHCPSighting(ctx, kind, addr)
+	}
+	w.wuiHTTPChecksMain(ctx, err).Render(wr)
+}
+
+func dhcpSightingFromForm(r *http.Request) (model.DHCPSightingKind, model.Addr, error) {
+	addr, err := model.ParseAddr(r.PostFormValue(wuiDHCPSightingFormAddr))
+	return model.DHCPSightingKind(r.PostFormValue(wuiDHCPSightingFormKind)), addr, err
+}
+
+func dhcpSightingTable(sightings []model.DHCPSighting) g.Node {
+	return wuiTable(
+		[]string{"Kind", "Address", "Server", "Trust", "First Seen", "Last Seen", " "},
+		g.Group(g.Map(sightings, func(s model.DHCPSighting) g.Node {
+			trust := h.Span(h.Class("badge badge-success"), g.Text("trusted"))
+			if !s.Trusted {
+				trust = h.Span(h.Class("badge badge-error"), g.Text("unknown"))
+			}
+			return h.Tr(
+				h.Td(g.Text(string(s.Kind))),
+				h.Td(g.Text(s.Addr.String())),
+				h.Td(g.Text(s.Server.String())),
+				h.Td(trust),
+				h.Td(g.Text(s.FirstSeen.Local().Format(time.DateTime))),
+				h.Td(g.Text(s.LastSeen.Local().Format(time.DateTime))),
+				h.Td(
+					h.Class("flex gap-2"),
+					g.If(!s.Trusted, dhcpSightingAction(s, "trust", "Trust")),
+					dhcpSightingAction(s, "forget", "Forget"),
+				),
+			)
+		})),
+	)
+}
+
+func dhcpSightingAction(s model.DHCPSighting, action string, label string) g.Node {
+	return h.FormEl(
+		hx.Post(urlApiDHCPWatch+"/"+action),
+		hx.Target("#httpcheckcontent"),
+		hx.Swap("outerHTML"),
+		h.Input(h.Type("hidden"), h.Name(wuiDHCPSightingFormKind), h.Value(string(s.Kind))),
+		h.Input(h.Type("hidden"), h.Name(wuiDHCPSightingFormAddr), h.Value(s.Addr.String())),
+		h.Button(h.Class("btn btn-xs"), g.Text(label)),
+	)
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	wuiEventLogFormSearch = "q"
+	wuiEventLogFormKind   = "kind"
+
+	// wuiEventLogLimit is the most events listed, a search narrows older ones down
+	wuiEventLogLimit = 200
+)
+
+func (w WUI) wuiEventLogPageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiEventLogMain(ctx, r),
+	)
+	w.basePage(ctx, "eventlog", content, nil).Render(wr)
+}
+
+func (w WUI) wuiEventLogApiHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	w.wuiEventLogList(ctx, eventQueryFromRequest(r)).Render(wr)
+}
+
+// wuiEventLogMain searches the event history, ?q= matches the type or message and ?kind=
+// limits the list to events or errors
+func (w WUI) wuiEventLogMain(ctx context.Context, r *http.Request) g.Node {
+	q := eventQueryFromRequest(r)
+	kinds := []model.EventRecordKind{"", model.EventRecordEvent, model.EventRecordError}
+	return grid("",
+		wuiCard("Event Log",
+			h.Div(
+				g.If(
+					!w.m.GetConfig().EventHistory.Enabled,
+					warnAlert(
+						"Only the latest events held in memory are shown, "+
+							"start with --eventhistory.enabled to keep them across restarts",
+					),
+				),
+				h.FormEl(
+					h.Action(urlEventLog),
+					h.Method("get"),
+					hx.Get(urlApiEventLog),
+					hx.Target("#eventlog"),
+					hx.Swap("outerHTML"),
+					hx.Trigger("input changed delay:300ms, change, submit"),
+					h.Class("flex flex-wrap items-center gap-2 py-4"),
+					h.Input(
+						h.Type("search"),
+						h.Name(wuiEventLogFormSearch),
+						h.Value(q.Search),
+						h.Placeholder("Search type or message"),
+						h.Class("input input-bordered w-full md:w-1/2"),
+					),
+					h.Select(
+						h.Name(wuiEventLogFormKind),
+						h.Class("select select-bordered"),
+						g.Group(g.Map(kinds, func(k model.EventRecordKind) g.Node {
+							label := string(k) + "s"
+							if k == "" {
+								label = "events and errors"
+							}
+							return h.Option(
+								h.Value(string(k)),
+								g.If(k == q.Kind, h.Selected()),
+								g.Text(label),
+							)
+						})),
+					),
+				),
+				w.wuiEventLogList(ctx, q),
+			),
+		),
+	)
+}
+
+func (w WUI) wuiEventLogList(ctx context.Context, q model.EventQuery) g.Node {
+	records, err := w.m.EventHistory(ctx, q)
+	return h.Div(
+		h.ID("eventlog"),
+		h.Class("overflow-x-auto"),
+		errAlert(err),
+		wuiTable([]string{"Time", "Kind", "Type", "Message"},
+			g.Group(g.Map(records, func(rec model.EventRecord) g.Node {
+				return h.Tr(
+					h.Td(
+						h.Class("whitespace-nowrap"),
+						g.Text(rec.Time.Local().Format(time.DateTime)),
+					),
+					h.Td(eventKindBadge(rec.Kind)),
+					h.Td(g.Text(rec.Type)),
+					h.Td(g.Text(rec.Message)),
+				)
+			})),
+		),
+	)
+}
+
+func eventKindBadge(kind model.EventRecordKind) g.Node {
+	if kind == model.EventRecordError {
+		return h.Span(h.Class("badge badge-error"), g.Text(string(kind)))
+	}
+	return h.Span(h.Class("badge badge-outline"), g.Text(string(kind)))
+}
+
+func eventQueryFromRequest(r *http.Request) model.EventQuery {
+	q := model.EventQuery{
+		Search: r.FormValue(wuiEventLogFormSearch),
+		Limit:  wuiEventLogLimit,
+	}
+	switch kind := model.EventRecordKind(r.FormValue(wuiEventLogFormKind)); kind {
+	case model.EventRecordEvent, model.EventRecordError:
+		q.Kind = kind
+	}
+	return q
+}
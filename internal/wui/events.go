@@ -0,0 +1,76 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	eventStreamKeepalive = 30 * time.Second
+
+	// htmx triggers used by pages which refresh themselves from the event stream, the
+	// delay lets the server finish storing the change before the page asks for it
+	liveDeviceTrigger = "mason:device from:body delay:1s"
+	liveScanTrigger   = "mason:scan from:body delay:1s"
+)
+
+// liveDeviceAddrTrigger only fires for events about the given device, and not while a form
+// field has focus so a refresh does not throw away an edit in progress
+func liveDeviceAddrTrigger(addr model.Addr) string {
+	return fmt.Sprintf(
+		"mason:device[detail.addr=='%s' && !document.activeElement.matches('%s')] "+
+			"from:body delay:1s",
+		addr,
+		"input,textarea,select",
+	)
+}
+
+// wuiApiEventsHandler streams device, scan and error events as server-sent events.  The
+// mason-events.js script turns them into mason:<kind> events on the page body.
+func (w WUI) wuiApiEventsHandler(wr http.ResponseWriter, r *http.Request) {
+	flusher, ok := wr.(http.Flusher)
+	if !ok {
+		http.Error(wr, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	events, stop := w.m.SubscribeEvents(r.Context())
+	defer stop()
+
+	wr.Header().Set("Content-Type", "text/event-stream")
+	wr.Header().Set("Cache-Control", "no-cache")
+	wr.Header().Set("Connection", "keep-alive")
+	wr.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(eventStreamKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-w.stopping:
+			return
+		case <-keepalive.C:
+			fmt.Fprint(wr, ": keepalive\n\n")
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				logger.Error("event stream encode", "error", err)
+				continue
+			}
+			fmt.Fprintf(wr, "event: %s\ndata: %s\n\n", e.Kind, data)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	wuiExclusionFormName     = "name"
+	wuiExclusionFormRange    = "range"
+	wuiExclusionFormScan     = "scan"
+	wuiExclusionFormPing     = "ping"
+	wuiExclusionFormPortScan = "portscan"
+	wuiExclusionFormNote     = "note"
+)
+
+func (w WUI) wuiExclusionsPageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiExclusionsMain(ctx, nil),
+	)
+	w.basePage(ctx, "exclusions", content, nil).Render(wr)
+}
+
+func (w WUI) wuiApiExclusionCreate(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	x, err := exclusionFromForm(r)
+	if err == nil {
+		err = w.m.SaveExclusion(ctx, x)
+	}
+	w.wuiExclusionsMain(ctx, err).Render(wr)
+}
+
+func (w WUI) wuiApiExclusionDelete(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	err := w.m.RemoveExclusion(ctx, r.PostFormValue(wuiExclusionFormName))
+	w.wuiExclusionsMain(ctx, err).Render(wr)
+}
+
+func exclusionFromForm(r *http.Request) (x model.Exclusion, err error) {
+	x.Name = r.PostFormValue(wuiExclusionFormName)
+	x.Note = r.PostFormValue(wuiExclusionFormNote)
+	x.Scan = r.PostFormValue(wuiExclusionFormScan) == "on"
+	x.Ping = r.PostFormValue(wuiExclusionFormPing) == "on"
+	x.PortScan = r.PostFormValue(wuiExclusionFormPortScan) == "on"
+	if rng := strings.TrimSpace(r.PostFormValue(wuiExclusionFormRange)); rng != "" {
+		x.Prefix.P, err = model.ParseAddrOrPrefix(rng)
+	}
+	return x, err
+}
+
+func (w WUI) wuiExclusionsMain(ctx context.Context, err error) g.Node {
+	exclusions, lerr := w.m.ListExclusions(ctx)
+	if err == nil {
+		err = lerr
+	}
+	devices := make(map[string]int)
+	for _, d := range w.m.ListDevices(ctx) {
+		for _, x := range model.Exclusions(exclusions).Matching(d.Addr) {
+			devices[x.Name]++
+		}
+	}
+	return grid("exclusionscontent",
+		wuiCard("Exclusions",
+			wuiTable(
+				[]string{"Name", "Range", "Never", "Devices", "Note", " "},
+				g.Group(g.Map(exclusions, func(x model.Exclusion) g.Node {
+					return exclusionToTD(x, devices[x.Name])
+				})),
+			),
+		),
+		wuiCard("Add / Update Exclusion",
+			h.Div(
+				errAlert(err),
+				h.FormEl(
+					hx.Post(urlApiExclusions),
+					hx.Target("#exclusionscontent"),
+					hx.Swap("outerHTML"),
+					h.Div(
+						h.Class("form-control"),
+						wuiFormInput("Name",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiExclusionFormName),
+								h.Placeholder("line 2 plcs"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Range",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiExclusionFormRange),
+								h.Placeholder("address or prefix"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						exclusionCheckbox("Never Scan", wuiExclusionFormScan),
+						exclusionCheckbox("Never Ping", wuiExclusionFormPing),
+						exclusionCheckbox("Never Port Scan", wuiExclusionFormPortScan),
+						wuiFormInput("Note",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiExclusionFormNote),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+					),
+					wuiFormButton("Save Exclusion"),
+				),
+			),
+		),
+	)
+}
+
+func exclusionCheckbox(label string, name string) g.Node {
+	return wuiFormInput(label,
+		h.Input(
+			h.Type("checkbox"),
+			h.Name(name),
+			h.Class("checkbox"),
+			h.Checked(),
+		),
+	)
+}
+
+// exclusionSummary is the name, range and excluded actions of the exclusion
+func exclusionSummary(x model.Exclusion) string {
+	return x.Name + " (" + x.Prefix.String() + ") never " + exclusionActions(x)
+}
+
+func exclusionActions(x model.Exclusion) string {
+	actions := make([]string, 0, 3)
+	for _, a := range x.Actions() {
+		actions = append(actions, string(a))
+	}
+	return strings.Join(actions, ", ")
+}
+
+func exclusionToTD(x model.Exclusion, devices int) g.Node {
+	var remove g.Node
+	if x.Configured {
+		remove = h.Span(h.Class("badge badge-ghost"), g.Text("config"))
+	} else {
+		remove = h.FormEl(
+			hx.Post(urlApiExclusions+"/delete"),
+			hx.Target("#exclusionscontent"),
+			hx.Swap("outerHTML"),
+			h.Input(h.Type("hidden"), h.Name(wuiExclusionFormName), h.Value(x.Name)),
+			h.Button(h.Class("btn btn-xs"), g.Text("Delete")),
+		)
+	}
+	return h.Tr(
+		h.Td(g.Text(x.Name)),
+		h.Td(g.Text(x.Prefix.String())),
+		h.Td(g.Text(exclusionActions(x))),
+		h.Td(g.Textf("%d", devices)),
+		h.Td(g.Text(x.Note)),
+		h.Td(remove),
+	)
+}
+
+// excludedBadge marks a device inside an exclusion range, mason leaves it alone for the
+// listed actions
+func excludedBadge(xs model.Exclusions) g.Node {
+	if len(xs) == 0 {
+		return nil
+	}
+	actions := make([]string, 0, 3)
+	for _, x := range xs {
+		for _, a := range x.Actions() {
+			if !slices.Contains(actions, string(a)) {
+				actions = append(actions, string(a))
+			}
+		}
+	}
+	return h.Span(
+		h.Class("badge badge-error badge-sm ml-2"),
+		h.Title("never "+strings.Join(actions, ", ")),
+		g.Text("excluded"),
+	)
+}
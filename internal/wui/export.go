@@ -0,0 +1,48 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+func (w WUI) wuiApiExportHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	anonymize := r.FormValue("anonymize") == "true"
+	key := r.FormValue("key")
+	if anonymize && key == "" {
+		// no key given, still produce a consistent export but one that cannot be correlated
+		buf := make([]byte, 16)
+		rand.Read(buf)
+		key = hex.EncodeToString(buf)
+	}
+
+	exp := w.m.ExportInventory(ctx, anonymize, key)
+	if r.FormValue("format") == "csv" {
+		fname := fmt.Sprintf("mason_assets_%s.csv", time.Now().Format("20060102150405"))
+		wr.Header().Set("Content-Type", "text/csv")
+		wr.Header().Set("Content-Disposition", "attachment; filename=\""+fname+"\"")
+		err := exp.WriteAssetCSV(wr)
+		if err != nil {
+			logger.Error("export csv", "error", err)
+		}
+		return
+	}
+	fname := fmt.Sprintf("mason_export_%s.json", time.Now().Format("20060102150405"))
+	wr.Header().Set("Content-Type", "application/json")
+	wr.Header().Set("Content-Disposition", "attachment; filename=\""+fname+"\"")
+	enc := json.NewEncoder(wr)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(exp)
+	if err != nil {
+		logger.Error("export encode", "error", err)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/networkables/mason/internal/server"
+)
+
+// wuiHealthzHandler answers the liveness probes of orchestrators and uptime monitors, a
+// failing check answers 503
+func (w WUI) wuiHealthzHandler(wr http.ResponseWriter, r *http.Request) {
+	writeHealthReport(wr, w.m.Liveness(r.Context()))
+}
+
+// wuiReadyzHandler answers the readiness probes, the stores are checked along with the
+// liveness
+func (w WUI) wuiReadyzHandler(wr http.ResponseWriter, r *http.Request) {
+	writeHealthReport(wr, w.m.Readiness(r.Context()))
+}
+
+func writeHealthReport(wr http.ResponseWriter, report server.HealthReport) {
+	wr.Header().Set("Content-Type", "application/json")
+	wr.Header().Set("Cache-Control", "no-store")
+	if !report.OK {
+		wr.WriteHeader(http.StatusServiceUnavailable)
+	}
+	err := json.NewEncoder(wr).Encode(report)
+	if err != nil {
+		logger.Error("health report encode", "error", err)
+	}
+}
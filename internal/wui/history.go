@@ -0,0 +1,34 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"time"
+
+	g "github.com/maragudk/gomponents"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+// deviceHistoryTable lists the recorded field changes of a device, newest first
+func deviceHistoryTable(changes []model.DeviceChange) g.Node {
+	if len(changes) == 0 {
+		return h.P(g.Text("No changes recorded"))
+	}
+	return wuiTable([]string{"Time", "Field", "Old", "New", "Source"},
+		g.Group(
+			g.Map(changes, func(c model.DeviceChange) g.Node {
+				return h.Tr(
+					h.Td(g.Text(c.Time.Local().Format(time.DateTime))),
+					h.Td(g.Text(c.Field)),
+					h.Td(g.Text(c.Old)),
+					h.Td(g.Text(c.New)),
+					h.Td(g.Text(string(c.Source))),
+				)
+			}),
+		),
+	)
+}
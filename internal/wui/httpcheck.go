@@ -0,0 +1,271 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	wuiHTTPCheckFormName     = "name"
+	wuiHTTPCheckFormURL      = "url"
+	wuiHTTPCheckFormMethod   = "method"
+	wuiHTTPCheckFormStatus   = "status"
+	wuiHTTPCheckFormBody     = "body"
+	wuiHTTPCheckFormInterval = "interval"
+	wuiHTTPCheckFormInsecure = "insecure"
+)
+
+func (w WUI) wuiHTTPChecksPageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiHTTPChecksMain(ctx, nil),
+	)
+	w.basePage(ctx, "checks", content, nil).Render(wr)
+}
+
+func (w WUI) wuiApiHTTPCheckCreate(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	c, err := httpCheckFromForm(r)
+	if err == nil {
+		err = w.m.SaveHTTPCheck(ctx, c)
+	}
+	w.wuiHTTPChecksMain(ctx, err).Render(wr)
+}
+
+func (w WUI) wuiApiHTTPCheckDelete(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	err := w.m.RemoveHTTPCheck(ctx, r.PostFormValue(wuiHTTPCheckFormName))
+	w.wuiHTTPChecksMain(ctx, err).Render(wr)
+}
+
+func httpCheckFromForm(r *http.Request) (c model.HTTPCheck, err error) {
+	c.Name = r.PostFormValue(wuiHTTPCheckFormName)
+	c.URL = r.PostFormValue(wuiHTTPCheckFormURL)
+	c.Method = r.PostFormValue(wuiHTTPCheckFormMethod)
+	c.ExpectBody = r.PostFormValue(wuiHTTPCheckFormBody)
+	c.Insecure = r.PostFormValue(wuiHTTPCheckFormInsecure) == "on"
+	if s := r.PostFormValue(wuiHTTPCheckFormStatus); s != "" {
+		c.ExpectStatus, err = strconv.Atoi(s)
+		if err != nil {
+			return c, model.ErrInvalidHTTPCheckStatus
+		}
+	}
+	c.Interval, err = formDuration(r, wuiHTTPCheckFormInterval)
+	return c, err
+}
+
+func (w WUI) wuiHTTPChecksMain(ctx context.Context, err error) g.Node {
+	statuses, lerr := w.m.HTTPCheckStatus(ctx)
+	if err == nil {
+		err = lerr
+	}
+	services, lerr := w.m.ServiceCheckStatus(ctx)
+	if err == nil {
+		err = lerr
+	}
+	sightings, lerr := w.m.DHCPSightings(ctx)
+	if err == nil {
+		err = lerr
+	}
+	up, down := 0, 0
+	for _, s := range statuses {
+		switch s.State() {
+		case model.CheckUp:
+			up++
+		case model.CheckDown:
+			down++
+		}
+	}
+	for _, s := range services {
+		switch s.State() {
+		case model.CheckUp:
+			up++
+		case model.CheckDown:
+			down++
+		}
+	}
+	return grid("httpcheckcontent",
+		wuiStatBox("up", strconv.Itoa(up), "http and service checks passing"),
+		wuiStatBox("down", strconv.Itoa(down), "http and service checks failing"),
+		widecard("HTTP Checks", httpCheckTable(statuses)),
+		g.If(len(services) > 0, widecard("Service Checks", serviceCheckTable(services, true))),
+		g.If(len(sightings) > 0, widecard("DHCP Servers & Gateways", dhcpSightingTable(sightings))),
+		wuiCard("Add / Update HTTP Check",
+			h.Div(
+				errAlert(err),
+				h.FormEl(
+					hx.Post(urlApiHTTPChecks),
+					hx.Target("#httpcheckcontent"),
+					hx.Swap("outerHTML"),
+					h.Div(
+						h.Class("form-control"),
+						wuiFormInput("Name",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiHTTPCheckFormName),
+								h.Placeholder("wiki"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("URL",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiHTTPCheckFormURL),
+								h.Placeholder("https://wiki.lan/health"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Method",
+							h.Select(
+								h.Name(wuiHTTPCheckFormMethod),
+								h.Class("select select-bordered w-full md:w-1/2"),
+								h.Option(h.Value(http.MethodGet), g.Text(http.MethodGet)),
+								h.Option(h.Value(http.MethodHead), g.Text(http.MethodHead)),
+								h.Option(h.Value(http.MethodPost), g.Text(http.MethodPost)),
+								h.Option(h.Value(http.MethodOptions), g.Text(http.MethodOptions)),
+							),
+						),
+						wuiFormInput("Expected Status",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiHTTPCheckFormStatus),
+								h.Placeholder("any below 400"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Expected Body",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiHTTPCheckFormBody),
+								h.Placeholder("text the response contains"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Interval",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiHTTPCheckFormInterval),
+								h.Value("1m"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Skip Certificate Verify",
+							h.Input(
+								h.Type("checkbox"),
+								h.Name(wuiHTTPCheckFormInsecure),
+								h.Class("checkbox"),
+							),
+						),
+					),
+					wuiFormButton("Save Check"),
+				),
+			),
+		),
+	)
+}
+
+func httpCheckTable(statuses []model.HTTPCheckStatus) g.Node {
+	return wuiTable(
+		[]string{
+			"Name", "URL", "State", "Status", "Last", "Avg (24h)", "Uptime (24h)", "Error", " ",
+		},
+		g.Group(g.Map(statuses, func(s model.HTTPCheckStatus) g.Node {
+			status, last, avg, uptime := "", "", "", ""
+			if s.Last.Status > 0 {
+				status = strconv.Itoa(s.Last.Status)
+			}
+			if s.Results > 0 {
+				uptime = fmt.Sprintf("%.1f%%", s.Uptime())
+			}
+			if s.Results > 0 && !s.Last.Failed() {
+				last = fmtDur(s.Last.Latency)
+			}
+			if s.Failures < s.Results {
+				avg = fmtDur(s.Average)
+			}
+			return h.Tr(
+				h.Td(g.Text(s.Check.Name)),
+				h.Td(g.Text(s.Check.Method+" "+s.Check.URL)),
+				h.Td(checkStateBadge(s.State())),
+				h.Td(g.Text(status)),
+				h.Td(g.Text(last)),
+				h.Td(g.Text(avg)),
+				h.Td(g.Text(uptime)),
+				h.Td(g.Text(s.Last.Err)),
+				h.Td(
+					h.FormEl(
+						hx.Post(urlApiHTTPChecks+"/delete"),
+						hx.Target("#httpcheckcontent"),
+						hx.Swap("outerHTML"),
+						h.Input(h.Type("hidden"), h.Name(wuiHTTPCheckFormName), h.Value(s.Check.Name)),
+						h.Button(h.Class("btn btn-xs"), g.Text("Delete")),
+					),
+				),
+			)
+		})),
+	)
+}
+
+func checkStateBadge(state model.CheckState) g.Node {
+	class := "badge badge-ghost"
+	switch state {
+	case model.CheckUp:
+		class = "badge badge-success"
+	case model.CheckDown:
+		class = "badge badge-error"
+	}
+	return h.Span(h.Class(class), g.Text(string(state)))
+}
+
+// serviceCheckTable lists the service checks, with the device column when they span devices
+func serviceCheckTable(statuses []model.ServiceCheckStatus, withDevice bool) g.Node {
+	headers := []string{"Service", "Port", "State", "Last", "Avg (24h)", "Uptime (24h)", "Error"}
+	if withDevice {
+		headers = append([]string{"Device"}, headers...)
+	}
+	return wuiTable(
+		headers,
+		g.Group(g.Map(statuses, func(s model.ServiceCheckStatus) g.Node {
+			last, avg, uptime := "", "", ""
+			if s.Results > 0 {
+				uptime = fmt.Sprintf("%.1f%%", s.Uptime())
+			}
+			if s.Results > 0 && !s.Last.Failed() {
+				last = fmtDur(s.Last.Latency)
+			}
+			if s.Failures < s.Results {
+				avg = fmtDur(s.Average)
+			}
+			return h.Tr(
+				g.If(withDevice, h.Td(
+					h.A(
+						h.Href(urlDevice+"/"+s.Check.Addr.String()),
+						h.Class("link"),
+						g.Text(s.Check.Device),
+					),
+				)),
+				h.Td(g.Text(model.ServiceName(s.Check.Port))),
+				h.Td(g.Text(strconv.Itoa(s.Check.Port))),
+				h.Td(checkStateBadge(s.State())),
+				h.Td(g.Text(last)),
+				h.Td(g.Text(avg)),
+				h.Td(g.Text(uptime)),
+				h.Td(g.Text(s.Last.Err)),
+			)
+		})),
+	)
+}
@@ -0,0 +1,190 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+	g "github.com/maragudk/gomponents"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	// wuiInternetWindow is the span of the throughput and latency charts
+	wuiInternetWindow = 7 * 24 * time.Hour
+
+	// wuiSpeedTestRows is the number of recent speed tests listed
+	wuiSpeedTestRows = 10
+)
+
+func (w WUI) wuiInternetPageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiInternetMain(ctx),
+	)
+	extra := h.Script(h.Src("/static/javascript/echarts.min.js"))
+	w.basePage(ctx, "internet", content, extra).Render(wr)
+}
+
+// wuiInternetMain charts the speed test throughput next to the anchor latency so a slower
+// connection can be told apart from a congested one
+func (w WUI) wuiInternetMain(ctx context.Context) g.Node {
+	health, err := w.m.InternetHealth(ctx)
+	if err != nil {
+		return grid("", widecard("Internet", errAlert(err)))
+	}
+	probes, err := w.m.HealthProbes(ctx, wuiInternetWindow)
+	if err != nil {
+		return grid("", widecard("Internet", errAlert(err)))
+	}
+	tests, err := w.m.SpeedTests(ctx, wuiInternetWindow)
+	if err != nil {
+		return grid("", widecard("Internet", errAlert(err)))
+	}
+
+	var last model.SpeedTest
+	for _, st := range tests {
+		if !st.Failed() {
+			last = st
+		}
+	}
+	recent := slices.Clone(tests[max(0, len(tests)-wuiSpeedTestRows):])
+	slices.Reverse(recent)
+
+	return grid(
+		"",
+		wuiStatBox(
+			"internet",
+			string(model.GetInternetStatus(health)),
+			"latest anchor and url probes",
+		),
+		g.If(!last.Start.IsZero(), wuiStatBox("download", fmtMbps(last.Download), last.Method)),
+		g.If(!last.Start.IsZero(), wuiStatBox("upload", fmtMbps(last.Upload), last.Method)),
+		g.If(len(health) > 0, widecard("Internet", internetHealthTable(health))),
+		g.If(len(tests) > 0, graphcard("Throughput", throughputGraph(tests))),
+		g.If(len(probes) > 0, graphcard("Latency", anchorLatencyGraph(probes))),
+		g.If(len(recent) > 0, widecard("Speed Tests", speedTestTable(recent))),
+	)
+}
+
+func speedTestTable(tests []model.SpeedTest) g.Node {
+	return wuiTable(
+		[]string{"Start", "Method", "Server", "Download", "Upload", "Latency", "Error"},
+		g.Group(g.Map(tests, func(st model.SpeedTest) g.Node {
+			// a test failing on the upload still shows the download it measured
+			download, upload, latency := "", "", ""
+			if st.Download > 0 {
+				download = fmtMbps(st.Download)
+			}
+			if st.Upload > 0 {
+				upload = fmtMbps(st.Upload)
+			}
+			if st.Latency > 0 {
+				latency = fmtDur(st.Latency)
+			}
+			return h.Tr(
+				h.Td(g.Text(st.Start.Format(time.DateTime))),
+				h.Td(g.Text(st.Method)),
+				h.Td(g.Text(st.Server)),
+				h.Td(g.Text(download)),
+				h.Td(g.Text(upload)),
+				h.Td(g.Text(latency)),
+				h.Td(g.Text(st.Err)),
+			)
+		})),
+	)
+}
+
+func fmtMbps(bps float64) string {
+	return fmt.Sprintf("%.1f Mbps", bps/1e6)
+}
+
+func throughputGraph(tests []model.SpeedTest) g.Node {
+	download := make([]opts.LineData, 0, len(tests))
+	upload := make([]opts.LineData, 0, len(tests))
+	for _, st := range tests {
+		if st.Failed() {
+			continue
+		}
+		download = append(download, opts.LineData{Value: EChartPoint{st.Start, st.Download / 1e6}})
+		upload = append(upload, opts.LineData{Value: EChartPoint{st.Start, st.Upload / 1e6}})
+	}
+	line := timeLineGraph("throughput (Mbps)", "{value} Mbps")
+	line.AddSeries("Download", download)
+	line.AddSeries("Upload", upload)
+	return renderLineGraph(line)
+}
+
+// anchorLatencyGraph has a series for each pinged anchor, failed probes are left out
+func anchorLatencyGraph(probes []model.HealthProbe) g.Node {
+	names := make([]string, 0)
+	series := make(map[string][]opts.LineData)
+	for _, p := range probes {
+		if p.Kind != model.HealthProbePing || p.Failed() {
+			continue
+		}
+		if _, ok := series[p.Target]; !ok {
+			names = append(names, p.Target)
+		}
+		ms := float64(p.Latency) / float64(time.Millisecond)
+		series[p.Target] = append(series[p.Target], opts.LineData{Value: EChartPoint{p.Start, ms}})
+	}
+	slices.Sort(names)
+	line := timeLineGraph("duration (ms)", "{value} ms")
+	for _, name := range names {
+		line.AddSeries(name, series[name])
+	}
+	return renderLineGraph(line)
+}
+
+func timeLineGraph(yname string, formatter string) *charts.Line {
+	line := charts.NewLine()
+	line.Initialization.Width = "800px"
+	line.SetGlobalOptions(
+		charts.WithTooltipOpts(opts.Tooltip{
+			Trigger: "axis",
+			AxisPointer: &opts.AxisPointer{
+				Type: "cross",
+			},
+		}),
+		charts.WithXAxisOpts(opts.XAxis{
+			Name:         "Time",
+			NameLocation: "middle",
+			Type:         "time",
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name:         yname,
+			NameLocation: "end",
+			Type:         "value",
+			AxisLabel: &opts.AxisLabel{
+				Formatter: formatter,
+			},
+		}),
+	)
+	return line
+}
+
+func renderLineGraph(line *charts.Line) g.Node {
+	line.SetSeriesOptions(
+		charts.WithLineChartOpts(opts.LineChart{
+			Smooth: opts.Bool(true),
+		}),
+		charts.WithLabelOpts(opts.Label{
+			Show: opts.Bool(false),
+		}),
+	)
+	line.Renderer = newSnippetRenderer(line, line.Validate)
+	return g.Raw(renderToString(line))
+}
@@ -0,0 +1,250 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/dustin/go-humanize"
+	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	wuiIpamFormAddr = "addr"
+	wuiIpamFormKind = "kind"
+	wuiIpamFormMAC  = "mac"
+	wuiIpamFormName = "name"
+	wuiIpamFormNote = "note"
+)
+
+func (w WUI) wuiIpamPageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiIpamMain(ctx, nil),
+	)
+	w.basePage(ctx, "ipam", content, nil).Render(wr)
+}
+
+// wuiApiIpamHandler returns the address plan of every network as json
+func (w WUI) wuiApiIpamHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	plans, err := w.m.GetAddressPlans(ctx)
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	wr.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(wr).Encode(plans)
+	if err != nil {
+		logger.Error("ipam encode", "error", err)
+	}
+}
+
+func (w WUI) wuiApiReservationCreate(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	res, err := reservationFromForm(r)
+	if err == nil {
+		err = w.m.ReserveAddress(ctx, res)
+	}
+	w.wuiIpamMain(ctx, err).Render(wr)
+}
+
+func (w WUI) wuiApiReservationDelete(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	addr, err := model.ParseAddr(r.PostFormValue(wuiIpamFormAddr))
+	if err == nil {
+		err = w.m.ReleaseAddress(ctx, addr)
+	}
+	w.wuiIpamMain(ctx, err).Render(wr)
+}
+
+func reservationFromForm(r *http.Request) (res model.Reservation, err error) {
+	res.Addr, err = model.ParseAddr(r.PostFormValue(wuiIpamFormAddr))
+	if err != nil {
+		return res, err
+	}
+	res.Kind, err = model.ParseReservationKind(r.PostFormValue(wuiIpamFormKind))
+	if err != nil {
+		return res, err
+	}
+	if mac := r.PostFormValue(wuiIpamFormMAC); mac != "" {
+		res.MAC, err = model.ParseMAC(mac)
+		if err != nil {
+			return res, err
+		}
+	}
+	res.Name = r.PostFormValue(wuiIpamFormName)
+	res.Note = r.PostFormValue(wuiIpamFormNote)
+	return res, nil
+}
+
+func (w WUI) wuiIpamMain(ctx context.Context, err error) g.Node {
+	plans, perr := w.m.GetAddressPlans(ctx)
+	if err == nil {
+		err = perr
+	}
+	reservations, rerr := w.m.ListReservations(ctx)
+	if err == nil {
+		err = rerr
+	}
+
+	cards := make([]g.Node, 0, len(plans)+2)
+	for _, plan := range plans {
+		cards = append(cards, addressPlanCard(plan, w.m.GetConfig().Ipam.WarnThreshold))
+	}
+	cards = append(cards,
+		wuiCard("Reserve Address",
+			h.Div(
+				errAlert(err),
+				h.FormEl(
+					hx.Post(urlApiReservations),
+					hx.Target("#ipamcontent"),
+					hx.Swap("outerHTML"),
+					h.Div(
+						h.Class("form-control"),
+						wuiFormInput("Address",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiIpamFormAddr),
+								h.Placeholder("192.168.1.10"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Type",
+							h.Select(
+								h.Name(wuiIpamFormKind),
+								h.Class("select select-bordered w-full md:w-1/2"),
+								h.Option(
+									h.Value(string(model.ReservationReserved)),
+									g.Text("Reserved"),
+								),
+								h.Option(
+									h.Value(string(model.ReservationStatic)),
+									g.Text("Static Assignment"),
+								),
+							),
+						),
+						wuiFormInput("Name",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiIpamFormName),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("MAC",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiIpamFormMAC),
+								h.Placeholder("optional"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Note",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiIpamFormNote),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+					),
+					wuiFormButton("Reserve"),
+				),
+			),
+		),
+		wuiCard("Reservations", reservationsToTable(reservations)),
+	)
+	return grid("ipamcontent", cards...)
+}
+
+func addressPlanCard(plan model.AddressPlan, threshold int) g.Node {
+	var warn g.Node
+	if plan.Warn {
+		warn = warnAlert(
+			fmt.Sprintf(
+				"%.0f%% of addresses are in use or reserved (threshold %d%%)",
+				plan.Utilization*100,
+				threshold,
+			),
+		)
+	}
+	var addrgrid g.Node
+	if plan.Addresses != nil {
+		addrgrid = h.Div(
+			h.Class("flex flex-wrap gap-1 py-4"),
+			g.Group(g.Map(plan.Addresses, addressToCell)),
+		)
+	}
+	return wuiCard(plan.Network.String(),
+		h.Div(
+			warn,
+			wuiTable([]string{" ", " "},
+				toTD("Utilization", fmt.Sprintf("%.1f%%", plan.Utilization*100)),
+				toTD("Used", humanize.Comma(int64(plan.Used))),
+				toTD("Static", humanize.Comma(int64(plan.Static))),
+				toTD("Reserved", humanize.Comma(int64(plan.Reserved))),
+				toTD("Free", humanize.Comma(int64(plan.Free()))),
+			),
+			addrgrid,
+		),
+	)
+}
+
+func addressToCell(a model.AddressAllocation) g.Node {
+	color := "bg-base-300"
+	switch a.State {
+	case model.AddressUsed:
+		color = "bg-success"
+	case model.AddressStatic:
+		color = "bg-info"
+	case model.AddressReserved:
+		color = "bg-warning"
+	}
+	title := a.Addr.String() + " " + string(a.State)
+	if a.Name != "" {
+		title += " " + a.Name
+	}
+	if a.Note != "" {
+		title += " (" + a.Note + ")"
+	}
+	return h.Div(
+		h.Class("w-3 h-3 rounded-sm "+color),
+		h.Title(title),
+	)
+}
+
+func reservationsToTable(reservations []model.Reservation) g.Node {
+	return wuiTable(
+		[]string{"Address", "Type", "Name", "MAC", "Note", " "},
+		g.Group(g.Map(reservations, reservationToTD)),
+	)
+}
+
+func reservationToTD(r model.Reservation) g.Node {
+	return h.Tr(
+		h.Td(g.Text(r.Addr.String())),
+		h.Td(g.Text(string(r.Kind))),
+		h.Td(g.Text(r.Name)),
+		h.Td(g.Text(r.MAC.String())),
+		h.Td(g.Text(r.Note)),
+		h.Td(
+			h.FormEl(
+				hx.Post(urlApiReservations+"/delete"),
+				hx.Target("#ipamcontent"),
+				hx.Swap("outerHTML"),
+				h.Input(h.Type("hidden"), h.Name(wuiIpamFormAddr), h.Value(r.Addr.String())),
+				h.Button(h.Class("btn btn-xs"), g.Text("Release")),
+			),
+		),
+	)
+}
@@ -0,0 +1,164 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	g "github.com/maragudk/gomponents"
+	h "github.com/maragudk/gomponents/html"
+	"github.com/skip2/go-qrcode"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	wuiLabelsFormAddr = "addr"
+	wuiLabelsFormTag  = "tag"
+
+	// wuiLabelQRSize is the width in pixels of the qr code images, printed at about 2.5cm
+	wuiLabelQRSize = 256
+)
+
+// wuiLabelsPageHandler renders a printable sheet of device labels, a qr code linking to the
+// device page with its name, addr, MAC and asset tag.  The devices are those given by addr,
+// otherwise the devices with the tag, otherwise every device.
+func (w WUI) wuiLabelsPageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	devices, err := w.labelDevices(ctx, r)
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	labelsPage(devices).Render(wr)
+}
+
+func (w WUI) labelDevices(ctx context.Context, r *http.Request) ([]model.Device, error) {
+	if addrs := r.Form[wuiLabelsFormAddr]; len(addrs) > 0 {
+		devices := make([]model.Device, 0, len(addrs))
+		for _, s := range addrs {
+			addr, err := w.m.StringToAddr(s)
+			if err != nil {
+				return nil, err
+			}
+			d, err := w.m.GetDeviceByAddr(ctx, addr)
+			if err != nil {
+				return nil, err
+			}
+			devices = append(devices, d)
+		}
+		return devices, nil
+	}
+	devices := w.m.ListDevices(ctx)
+	if tag := r.FormValue(wuiLabelsFormTag); tag != "" {
+		devices = slices.DeleteFunc(devices, func(d model.Device) bool {
+			return !model.TagDeviceFilter(tag)(d)
+		})
+	}
+	slices.SortFunc(devices, func(a, b model.Device) int { return a.Addr.Compare(b.Addr) })
+	return devices, nil
+}
+
+// wuiApiDeviceQRHandler returns a png qr code of the link to the device page
+func (w WUI) wuiApiDeviceQRHandler(wr http.ResponseWriter, r *http.Request) {
+	addr, err := w.m.StringToAddr(r.PathValue("id"))
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	png, err := qrcode.Encode(w.deviceURL(r, addr), qrcode.Medium, wuiLabelQRSize)
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	wr.Header().Set("Content-Type", "image/png")
+	wr.Write(png)
+}
+
+// deviceURL is the absolute link to the device page, below wui.externalurl when it is set and
+// otherwise on the host the request was made to
+func (w WUI) deviceURL(r *http.Request, addr model.Addr) string {
+	base := strings.TrimSuffix(w.m.GetConfig().Wui.ExternalURL, "/")
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return base + urlDevice + "/" + url.PathEscape(addr.String())
+}
+
+// labelsURL opens the label sheet of the device
+func labelsURL(addr model.Addr) string {
+	return urlLabels + "?" + url.Values{wuiLabelsFormAddr: {addr.String()}}.Encode()
+}
+
+// labelsPage is a page of its own without the sidebar, the toolbar is left off the print
+func labelsPage(devices []model.Device) g.Node {
+	return h.Doctype(
+		h.HTML(
+			h.Lang("en"),
+			h.Head(
+				h.Meta(h.Charset("utf-8")),
+				h.TitleEl(g.Text("Mason Labels")),
+				h.Link(
+					h.Rel("stylesheet"),
+					h.Href("/static/css/daisyui-4.11.1.css"),
+				),
+				h.Script(h.Src("/static/javascript/tailwindcss-3.4.3.js")),
+			),
+			h.Body(
+				g.Attr("data-theme", "light"),
+				h.Class("bg-white p-4 print:p-0"),
+				h.Div(
+					h.Class("flex flex-wrap items-center gap-2 pb-4 print:hidden"),
+					h.A(h.Class("btn btn-sm"), h.Href(urlDevices), g.Text("Back to devices")),
+					h.Button(
+						h.Class("btn btn-primary btn-sm"),
+						h.Type("button"),
+						g.Attr("onclick", "window.print()"),
+						g.Text("Print"),
+					),
+					h.Span(h.Class("text-sm opacity-70"), g.Textf("%d labels", len(devices))),
+				),
+				h.Div(
+					h.Class("grid grid-cols-2 md:grid-cols-3 print:grid-cols-3 gap-2"),
+					g.Group(g.Map(devices, deviceLabel)),
+				),
+			),
+		),
+	)
+}
+
+func deviceLabel(d model.Device) g.Node {
+	line := func(value string) g.Node {
+		return g.If(value != "", h.Div(h.Class("truncate"), g.Text(value)))
+	}
+	return h.Div(
+		h.Class("flex items-center gap-2 border border-black rounded p-2 break-inside-avoid"),
+		h.Img(
+			h.Src(urlApiDevice+"/"+d.Addr.String()+"/qr"),
+			h.Alt("qr code of "+d.Addr.String()),
+			h.Class("w-24 h-24 shrink-0"),
+		),
+		h.Div(
+			h.Class("min-w-0 font-mono text-xs leading-snug"),
+			h.Div(h.Class("truncate font-bold text-sm"), g.Text(d.Name)),
+			line(d.Addr.String()),
+			g.If(!d.MAC.IsEmpty(), line(d.MAC.String())),
+			g.If(d.Meta.AssetTag != "", h.Div(h.Class("truncate"), g.Text("asset "+d.Meta.AssetTag))),
+		),
+	)
+}
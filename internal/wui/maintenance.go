@@ -0,0 +1,196 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	wuiMaintenanceFormName     = "name"
+	wuiMaintenanceFormScope    = "scope"
+	wuiMaintenanceFormTarget   = "target"
+	wuiMaintenanceFormStart    = "start"
+	wuiMaintenanceFormDuration = "duration"
+	wuiMaintenanceFormRepeat   = "repeat"
+	wuiMaintenanceFormNote     = "note"
+
+	// wuiDateTimeLocal is the value format of a datetime-local input
+	wuiDateTimeLocal = "2006-01-02T15:04"
+)
+
+func (w WUI) wuiMaintenancePageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiMaintenanceMain(ctx, nil),
+	)
+	w.basePage(ctx, "maintenance", content, nil).Render(wr)
+}
+
+func (w WUI) wuiApiMaintenanceCreate(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	mw, err := maintenanceWindowFromForm(r)
+	if err == nil {
+		err = w.m.SaveMaintenanceWindow(ctx, mw)
+	}
+	w.wuiMaintenanceMain(ctx, err).Render(wr)
+}
+
+func (w WUI) wuiApiMaintenanceDelete(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	err := w.m.RemoveMaintenanceWindow(ctx, r.PostFormValue(wuiMaintenanceFormName))
+	w.wuiMaintenanceMain(ctx, err).Render(wr)
+}
+
+func maintenanceWindowFromForm(r *http.Request) (mw model.MaintenanceWindow, err error) {
+	mw.Name = r.PostFormValue(wuiMaintenanceFormName)
+	mw.Target = r.PostFormValue(wuiMaintenanceFormTarget)
+	mw.Note = r.PostFormValue(wuiMaintenanceFormNote)
+	mw.Scope, err = model.ParseMaintenanceScope(r.PostFormValue(wuiMaintenanceFormScope))
+	if err != nil {
+		return mw, err
+	}
+	mw.Repeat, err = model.ParseMaintenanceRepeat(r.PostFormValue(wuiMaintenanceFormRepeat))
+	if err != nil {
+		return mw, err
+	}
+	mw.Start, err = time.ParseInLocation(
+		wuiDateTimeLocal,
+		r.PostFormValue(wuiMaintenanceFormStart),
+		time.Local,
+	)
+	if err != nil {
+		return mw, err
+	}
+	mw.Duration, err = formDuration(r, wuiMaintenanceFormDuration)
+	return mw, err
+}
+
+func (w WUI) wuiMaintenanceMain(ctx context.Context, err error) g.Node {
+	windows, lerr := w.m.ListMaintenanceWindows(ctx)
+	if err == nil {
+		err = lerr
+	}
+	now := time.Now()
+	return grid("maintenancecontent",
+		wuiCard("Maintenance Windows",
+			wuiTable(
+				[]string{"Name", "Scope", "Target", "Start", "Duration", "Repeat", "Note", " "},
+				g.Group(g.Map(windows, func(mw model.MaintenanceWindow) g.Node {
+					return maintenanceWindowToTD(mw, now)
+				})),
+			),
+		),
+		wuiCard("Add / Update Maintenance Window",
+			h.Div(
+				errAlert(err),
+				h.FormEl(
+					hx.Post(urlApiMaintenance),
+					hx.Target("#maintenancecontent"),
+					hx.Swap("outerHTML"),
+					h.Div(
+						h.Class("form-control"),
+						wuiFormInput("Name",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiMaintenanceFormName),
+								h.Placeholder("nightly reboot"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Scope",
+							h.Select(
+								h.Name(wuiMaintenanceFormScope),
+								h.Class("select select-bordered w-full md:w-1/2"),
+								h.Option(h.Value(string(model.MaintenanceScopeDevice)), g.Text("Device")),
+								h.Option(h.Value(string(model.MaintenanceScopeTag)), g.Text("Tag")),
+								h.Option(h.Value(string(model.MaintenanceScopeNetwork)), g.Text("Network")),
+							),
+						),
+						wuiFormInput("Target",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiMaintenanceFormTarget),
+								h.Placeholder("device addr, tag or network name"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Start",
+							h.Input(
+								h.Type("datetime-local"),
+								h.Name(wuiMaintenanceFormStart),
+								h.Value(now.Format(wuiDateTimeLocal)),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Duration",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiMaintenanceFormDuration),
+								h.Placeholder("1h"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Repeat",
+							h.Select(
+								h.Name(wuiMaintenanceFormRepeat),
+								h.Class("select select-bordered w-full md:w-1/2"),
+								h.Option(h.Value("once"), g.Text("Once")),
+								h.Option(h.Value(string(model.MaintenanceDaily)), g.Text("Daily")),
+								h.Option(h.Value(string(model.MaintenanceWeekly)), g.Text("Weekly")),
+							),
+						),
+						wuiFormInput("Note",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiMaintenanceFormNote),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+					),
+					wuiFormButton("Save Window"),
+				),
+			),
+		),
+	)
+}
+
+func maintenanceWindowToTD(mw model.MaintenanceWindow, now time.Time) g.Node {
+	repeat := string(mw.Repeat)
+	if mw.Repeat == model.MaintenanceOnce {
+		repeat = "once"
+	}
+	return h.Tr(
+		h.Td(
+			g.Text(mw.Name),
+			g.If(mw.ActiveAt(now), h.Span(h.Class("badge badge-warning ml-2"), g.Text("active"))),
+		),
+		h.Td(g.Text(string(mw.Scope))),
+		h.Td(g.Text(mw.Target)),
+		h.Td(g.Text(mw.Start.Local().Format(time.DateTime))),
+		h.Td(g.Text(mw.Duration.String())),
+		h.Td(g.Text(repeat)),
+		h.Td(g.Text(mw.Note)),
+		h.Td(
+			h.FormEl(
+				hx.Post(urlApiMaintenance+"/delete"),
+				hx.Target("#maintenancecontent"),
+				hx.Swap("outerHTML"),
+				h.Input(h.Type("hidden"), h.Name(wuiMaintenanceFormName), h.Value(mw.Name)),
+				h.Button(h.Class("btn btn-xs"), g.Text("Delete")),
+			),
+		),
+	)
+}
@@ -0,0 +1,268 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/go-echarts/go-echarts/v2/opts"
+	g "github.com/maragudk/gomponents"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/server"
+)
+
+// networkURL is the detail page of the network, the prefix keeps its slash
+func networkURL(n model.Network) string {
+	return urlNetworks + "/" + n.Prefix.String()
+}
+
+func (w WUI) wuiNetworkPageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiNetworkMain(ctx, r.PathValue("prefix")),
+	)
+	extra := h.Script(h.Src("/static/javascript/echarts.min.js"))
+	w.basePage(ctx, "networks", content, extra).Render(wr)
+}
+
+// wuiApiNetworkScanHandler rescans the posted network
+func (w WUI) wuiApiNetworkScanHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	n, err := w.m.ScanNetwork(ctx, r.PostFormValue(wuiNetworksFormPrefix))
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Redirect(wr, r, networkURL(n), http.StatusSeeOther)
+}
+
+// wuiApiNetworkDetailsHandler renames the posted network
+func (w WUI) wuiApiNetworkDetailsHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	prefix := r.PostFormValue(wuiNetworksFormPrefix)
+	err := w.m.RenameNetwork(ctx, prefix, r.PostFormValue(wuiNetworksFormName))
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Redirect(wr, r, urlNetworks+"/"+prefix, http.StatusSeeOther)
+}
+
+// wuiApiNetworkTagHandler adds or removes a tag of the posted network
+func (w WUI) wuiApiNetworkTagHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	prefix := r.PostFormValue(wuiNetworksFormPrefix)
+	name := r.PostFormValue(wuiTagsFormName)
+	var err error
+	if r.PostFormValue("remove") == "yes" {
+		err = w.m.UntagNetwork(ctx, prefix, name)
+	} else {
+		err = w.m.TagNetwork(ctx, prefix, name)
+	}
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Redirect(wr, r, urlNetworks+"/"+prefix, http.StatusSeeOther)
+}
+
+// wuiApiNetworkDeleteHandler deletes the posted network, it is listed on the deleted page
+// until it is purged
+func (w WUI) wuiApiNetworkDeleteHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	err := w.m.RemoveNetwork(ctx, r.PostFormValue(wuiNetworksFormPrefix))
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Redirect(wr, r, urlDeleted, http.StatusSeeOther)
+}
+
+func (w WUI) wuiNetworkMain(ctx context.Context, prefix string) g.Node {
+	detail, err := w.m.NetworkDetail(ctx, prefix)
+	if err != nil {
+		return grid("", widecard("Error", errAlert(err)))
+	}
+	n := detail.Network
+	return grid("",
+		widecard(
+			"Details",
+			h.Div(
+				networkToTable(detail),
+				networkActions(n),
+			),
+		),
+		widecard("Last Scan", networkScanSummary(n, detail.Scan)),
+		widecard("Edit", networkDetailsForm(n)),
+		widecard("Tags", networkTagsForm(n)),
+		graphcard("Utilization", networkUsageGraph(detail.Usage, detail.Plan.Size)),
+		widecard(
+			fmt.Sprintf("Devices (%d)", len(detail.Devices)),
+			networkDevicesTable(detail.Devices),
+		),
+	)
+}
+
+func networkToTable(detail server.NetworkDetail) g.Node {
+	n := detail.Network
+	plan := detail.Plan
+	lastScan := "never"
+	if !n.LastScan.IsZero() {
+		lastScan = n.LastScan.Local().Format(time.DateTime)
+	}
+	return h.Table(
+		h.Class("table table-zebra"),
+		h.TBody(
+			toTHTD("Name", n.Name),
+			toTHTD("Prefix", n.Prefix.String()),
+			h.Tr(h.Th(g.Text("Tags")), h.Td(tagLinks(urlNetworks, n.Tags))),
+			toTHTD("Site", n.Site),
+			toTHTD("VLAN", n.VLAN.String()),
+			toTHTD("Last Scan", lastScan),
+			toTHTD("Size", humanize.Comma(int64(plan.Size))),
+			toTHTD("Used", humanize.Comma(int64(plan.Used))),
+			toTHTD("Static", humanize.Comma(int64(plan.Static))),
+			toTHTD("Reserved", humanize.Comma(int64(plan.Reserved))),
+			toTHTD("Utilization", fmt.Sprintf("%.1f%%", plan.Utilization*100)),
+		),
+	)
+}
+
+// networkActions rescans or deletes the network
+func networkActions(n model.Network) g.Node {
+	return h.Div(
+		h.Class("flex justify-end gap-4 py-4"),
+		h.FormEl(
+			h.Action(urlApiNetwork+"/scan"),
+			h.Method("post"),
+			networkPrefixInput(n),
+			h.Button(h.Class("btn btn-primary btn-sm"), g.Text("Rescan Network")),
+		),
+		h.FormEl(
+			h.Action(urlApiNetwork+"/delete"),
+			h.Method("post"),
+			g.Attr("onsubmit", "return confirm('Delete the network "+n.Name+"?')"),
+			networkPrefixInput(n),
+			h.Button(h.Class("btn btn-error btn-sm"), g.Text("Delete Network")),
+		),
+	)
+}
+
+// networkScanSummary shows the running or last finished scan of the network since startup
+func networkScanSummary(n model.Network, scan discovery.NetworkScanProgress) g.Node {
+	if scan.Started.IsZero() {
+		if n.LastScan.IsZero() {
+			return h.P(g.Text("The network has not been scanned"))
+		}
+		return h.P(g.Text("Last scanned " + n.LastScan.Local().Format(time.DateTime) +
+			", the details of scans before startup are not kept"))
+	}
+	return networkScansToTable([]discovery.NetworkScanProgress{scan})
+}
+
+// networkPrefixInput posts the prefix of the network, its name may change
+func networkPrefixInput(n model.Network) g.Node {
+	return h.Input(h.Type("hidden"), h.Name(wuiNetworksFormPrefix), h.Value(n.Prefix.String()))
+}
+
+func networkDetailsForm(n model.Network) g.Node {
+	return h.FormEl(
+		h.Action(urlApiNetwork+"/details"),
+		h.Method("post"),
+		networkPrefixInput(n),
+		h.Div(
+			h.Class("form-control"),
+			wuiFormInput("Name",
+				h.Input(
+					h.Type("text"),
+					h.Name(wuiNetworksFormName),
+					h.Value(n.Name),
+					h.Class("input input-bordered w-1/2"),
+				),
+			),
+		),
+		wuiFormButton("Save"),
+	)
+}
+
+// networkTagsForm lists the tags of the network with remove buttons and a form to add a tag
+func networkTagsForm(n model.Network) g.Node {
+	action := urlApiNetwork + "/tags"
+	return h.Div(
+		h.Div(
+			h.Class("flex flex-wrap gap-2"),
+			g.Group(g.Map(n.Tags, func(t model.Tag) g.Node {
+				return h.FormEl(
+					h.Action(action),
+					h.Method("post"),
+					networkPrefixInput(n),
+					h.Input(h.Type("hidden"), h.Name(wuiTagsFormName), h.Value(t.Val)),
+					h.Input(h.Type("hidden"), h.Name("remove"), h.Value("yes")),
+					h.Button(
+						h.Class("badge badge-outline gap-1"),
+						g.Text(t.Val+" ✕"),
+					),
+				)
+			})),
+		),
+		h.FormEl(
+			h.Action(action),
+			h.Method("post"),
+			h.Class("flex gap-4 py-4"),
+			networkPrefixInput(n),
+			h.Input(
+				h.Type("text"),
+				h.Name(wuiTagsFormName),
+				h.Placeholder("tag"),
+				h.Class("input input-bordered grow"),
+			),
+			h.Button(h.Class("btn btn-primary"), g.Text("Add Tag")),
+		),
+	)
+}
+
+// networkUsageGraph charts the daily device count of the network, with the size of the
+// network while it is small enough to compare
+func networkUsageGraph(usage []model.NetworkUsage, size int) g.Node {
+	used := make([]opts.LineData, 0, len(usage))
+	for _, u := range usage {
+		used = append(used, opts.LineData{Value: EChartPoint{u.Time, u.Used}})
+	}
+	line := timeLineGraph("devices", "{value}")
+	line.AddSeries("Devices", used)
+	if size <= 1<<16 && len(usage) > 0 {
+		line.AddSeries("Size", []opts.LineData{
+			{Value: EChartPoint{usage[0].Time, size}},
+			{Value: EChartPoint{usage[len(usage)-1].Time, size}},
+		})
+	}
+	return renderLineGraph(line)
+}
+
+func networkDevicesTable(devices []model.Device) g.Node {
+	return wuiTable(
+		[]string{" ", "Name", "IP", "Type", "Tags", "Last Seen", "Ping"},
+		g.Group(g.Map(devices, func(d model.Device) g.Node {
+			return h.Tr(
+				h.Td(h.A(h.Href(urlDevice+"/"+d.Addr.String()), svgMagnifyGlass())),
+				h.Td(g.Text(d.Name)),
+				h.Td(g.Text(d.Addr.String())),
+				h.Td(deviceTypeLink(d.Meta.DeviceType)),
+				h.Td(tagLinks(urlDevices, d.Meta.Tags)),
+				h.Td(g.Text(d.LastSeenDurString(time.Since))),
+				h.Td(g.Text(d.LastPingMeanString())),
+			)
+		})),
+	)
+}
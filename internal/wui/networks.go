@@ -6,12 +6,18 @@ package wui
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
+	"time"
 
+	"github.com/dustin/go-humanize"
 	g "github.com/maragudk/gomponents"
 	hx "github.com/maragudk/gomponents-htmx"
 	h "github.com/maragudk/gomponents/html"
 
+	"github.com/networkables/mason/internal/discovery"
 	"github.com/networkables/mason/internal/model"
 )
 
@@ -20,7 +26,7 @@ func (w WUI) wuiNetworksPageHandler(wr http.ResponseWriter, r *http.Request) {
 	content := h.Main(
 		h.ID("maincontent"),
 		h.Class("drawer-content"),
-		w.wuiNetworksMain(ctx, nil),
+		w.wuiNetworksMain(ctx, r.FormValue("tag"), wuiSelectedSite(r), nil),
 	)
 	w.basePage(ctx, "networks", content, nil).Render(wr)
 }
@@ -29,6 +35,8 @@ const (
 	wuiNetworksFormName    = "netname"
 	wuiNetworksFormPrefix  = "netprefix"
 	wuiNetworksFormScanNow = "scannow"
+	wuiNetworksFormConfirm = "confirm"
+	wuiNetworksFormSite    = "netsite"
 )
 
 func (w *WUI) wuiNetworksApiCreate(wr http.ResponseWriter, r *http.Request) {
@@ -41,22 +49,63 @@ func (w *WUI) wuiNetworksApiCreate(wr http.ResponseWriter, r *http.Request) {
 	if scannowstr == "on" {
 		scannow = true
 	}
+	if scannow {
+		est, err := w.m.EstimateNetworkScan(name, prefix)
+		if err != nil {
+			w.wuiNetworksMain(ctx, "", wuiSelectedSite(r), err).Render(wr)
+			return
+		}
+		if r.PostFormValue(wuiNetworksFormConfirm) != "yes" {
+			w.wuiNetworksConfirm(ctx, name, prefix, est).Render(wr)
+			return
+		}
+	}
 	err := w.m.AddNetworkByName(ctx, name, prefix, scannow)
 
-	w.wuiNetworksMain(ctx, err).Render(wr)
+	w.wuiNetworksMain(ctx, "", wuiSelectedSite(r), err).Render(wr)
+}
+
+func (w *WUI) wuiNetworksApiDelete(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	err := w.m.RemoveNetwork(ctx, r.PostFormValue(wuiNetworksFormName))
+	w.wuiNetworksMain(ctx, "", wuiSelectedSite(r), err).Render(wr)
+}
+
+// wuiNetworksApiSite moves a network to the posted site
+func (w *WUI) wuiNetworksApiSite(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	err := w.m.SetNetworkSite(
+		ctx,
+		r.PostFormValue(wuiNetworksFormName),
+		r.PostFormValue(wuiNetworksFormSite),
+	)
+	w.wuiNetworksMain(ctx, "", wuiSelectedSite(r), err).Render(wr)
 }
 
-func (w WUI) wuiNetworksMain(ctx context.Context, err error) g.Node {
+// wuiNetworksMain lists the networks, when tag is set only networks with the tag are listed
+// and when site is set only networks at the site
+func (w WUI) wuiNetworksMain(ctx context.Context, tag string, site string, err error) g.Node {
 	var errNode g.Node
 	if err != nil {
 		errNode = errAlert(err)
 	}
 	nets := w.m.ListNetworks(ctx)
+	if tag != "" {
+		nets = slices.DeleteFunc(nets, func(n model.Network) bool {
+			return !model.TagNetworkFilter(tag)(n)
+		})
+	}
+	if site != "" {
+		nets = slices.DeleteFunc(nets, func(n model.Network) bool {
+			return !model.SiteNetworkFilter(site, w.m.LocalSite())(n)
+		})
+	}
 	model.SortNetworksByAddr(nets)
 	return grid("networkscontent",
 		wuiCard("Networks",
-			networksToTable(nets),
+			w.networksToTable(ctx, nets),
 		),
+		wuiCard("Scans", w.wuiNetworkScans(ctx)),
 		wuiCard("Add Network",
 			h.Div(
 				errNode,
@@ -73,7 +122,7 @@ func (w WUI) wuiNetworksMain(ctx context.Context, err error) g.Node {
 								h.Type("text"),
 								h.Name(wuiNetworksFormName),
 								h.Placeholder("Custom Name"),
-								h.Class("input input-bordered w-1/2"),
+								h.Class("input input-bordered w-full md:w-1/2"),
 							),
 						),
 						h.Label(
@@ -83,7 +132,7 @@ func (w WUI) wuiNetworksMain(ctx context.Context, err error) g.Node {
 								h.Type("text"),
 								h.Name(wuiNetworksFormPrefix),
 								h.Placeholder("192.168.1.1/24"),
-								h.Class("input input-bordered w-1/2"),
+								h.Class("input input-bordered w-full md:w-1/2"),
 							),
 						),
 						h.Label(
@@ -107,22 +156,162 @@ func (w WUI) wuiNetworksMain(ctx context.Context, err error) g.Node {
 	)
 }
 
-func networksToTable(nets []model.Network) g.Node {
+func (w WUI) wuiNetworksConfirm(
+	ctx context.Context,
+	name string,
+	prefix string,
+	est discovery.ScanEstimate,
+) g.Node {
+	nets := w.m.ListNetworks(ctx)
+	model.SortNetworksByAddr(nets)
+	return grid("networkscontent",
+		wuiCard("Networks",
+			w.networksToTable(ctx, nets),
+		),
+		wuiCard("Confirm Scan of "+prefix,
+			h.Div(
+				wuiTable([]string{" ", " "},
+					toTD("Addresses", humanize.Comma(int64(est.Addresses))),
+					toTD("Packets", humanize.Comma(int64(est.Packets))),
+					toTD("Expected Duration", est.Duration.Round(time.Second).String()),
+				),
+				h.FormEl(
+					hx.Post(urlApiNetworks),
+					hx.Target("#networkscontent"),
+					hx.Swap("outerHTML"),
+					h.Input(h.Type("hidden"), h.Name(wuiNetworksFormName), h.Value(name)),
+					h.Input(h.Type("hidden"), h.Name(wuiNetworksFormPrefix), h.Value(prefix)),
+					h.Input(h.Type("hidden"), h.Name(wuiNetworksFormScanNow), h.Value("on")),
+					h.Input(h.Type("hidden"), h.Name(wuiNetworksFormConfirm), h.Value("yes")),
+					h.Div(
+						h.Class("flex gap-4 py-4"),
+						h.A(
+							h.Href(urlNetworks),
+							h.Class("btn grow"),
+							g.Text("Cancel"),
+						),
+						h.Button(h.Class("btn btn-warning grow"), g.Text("Add and Scan")),
+					),
+				),
+			),
+		),
+	)
+}
+
+func (w WUI) wuiNetworkScansApiHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	w.wuiNetworkScans(ctx).Render(wr)
+}
+
+// wuiNetworkScans shows the scan progress, refreshing itself while the page is open
+func (w WUI) wuiNetworkScans(ctx context.Context) g.Node {
+	return h.Div(
+		hx.Get(urlApiNetworkScans),
+		hx.Trigger("every 2s, "+liveScanTrigger),
+		hx.Swap("outerHTML"),
+		networkScansToTable(w.m.NetworkScans(ctx)),
+	)
+}
+
+func networkScansToTable(scans []discovery.NetworkScanProgress) g.Node {
+	if len(scans) == 0 {
+		return h.P(g.Text("No networks have been scanned since startup"))
+	}
+	return wuiTable(
+		[]string{"Name", "Prefix", "Progress", "Probed", "Found", "Elapsed", "ETA"},
+		g.Group(
+			g.Map(scans, func(p discovery.NetworkScanProgress) g.Node {
+				class := "progress progress-primary w-32"
+				eta := "-"
+				if p.Done() {
+					class = "progress progress-success w-32"
+				} else if p.Probed > 0 {
+					eta = p.ETA.Round(time.Second).String()
+				}
+				return h.Tr(
+					h.Td(g.Text(p.Name)),
+					h.Td(g.Text(p.Prefix)),
+					h.Td(
+						h.Progress(
+							h.Class(class),
+							h.Value(fmt.Sprint(p.Percent())),
+							h.Max("100"),
+						),
+					),
+					h.Td(g.Textf(
+						"%s / %s",
+						humanize.Comma(int64(p.Probed)),
+						humanize.Comma(int64(p.Total)),
+					)),
+					h.Td(g.Text(humanize.Comma(int64(p.Found)))),
+					h.Td(g.Text(p.Elapsed().Round(time.Second).String())),
+					h.Td(g.Text(eta)),
+				)
+			}),
+		),
+	)
+}
+
+// wuiApiScanProgressHandler returns the progress of the network scans as json
+func (w WUI) wuiApiScanProgressHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	wr.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(wr).Encode(w.m.NetworkScans(ctx))
+	if err != nil {
+		logger.Error("scan progress encode", "error", err)
+	}
+}
+
+func (w WUI) networksToTable(ctx context.Context, nets []model.Network) g.Node {
+	sites, _ := w.m.ListSites(ctx)
+	local := w.m.LocalSite()
 	return wuiTable(
-		[]string{"Name", "Prefix"},
+		[]string{"Name", "Prefix", "Tags", "Site", " "},
 		g.Group(
 			g.Map(
 				nets,
 				func(n model.Network) g.Node {
-					return networkToTD(n)
+					return networkToTD(n, sites, local)
 				}),
 		),
 	)
 }
 
-func networkToTD(n model.Network) g.Node {
+func networkToTD(n model.Network, sites []model.Site, local string) g.Node {
 	return h.Tr(
-		h.Td(g.Text(n.Name)),
+		h.Td(h.A(h.Href(networkURL(n)), h.Class("link"), g.Text(n.Name))),
 		h.Td(g.Text(n.Prefix.String())),
+		h.Td(tagLinks(urlNetworks, n.Tags)),
+		h.Td(networkSiteForm(n, sites, local)),
+		h.Td(
+			h.FormEl(
+				hx.Post(urlApiNetworks+"/delete"),
+				hx.Target("#networkscontent"),
+				hx.Swap("outerHTML"),
+				hx.Confirm("Delete the network "+n.Name+"?"),
+				h.Input(h.Type("hidden"), h.Name(wuiNetworksFormName), h.Value(n.Name)),
+				h.Button(h.Class("btn btn-xs"), g.Text("Delete")),
+			),
+		),
+	)
+}
+
+// networkSiteForm moves the network to another site as soon as a site is picked, sites
+// other than the local one are listed
+func networkSiteForm(n model.Network, sites []model.Site, local string) g.Node {
+	others := slices.DeleteFunc(slices.Clone(sites), func(s model.Site) bool {
+		return s.Name == local
+	})
+	return h.FormEl(
+		hx.Post(urlApiNetworks+"/site"),
+		hx.Target("#networkscontent"),
+		hx.Swap("outerHTML"),
+		hx.Trigger("change"),
+		h.Input(h.Type("hidden"), h.Name(wuiNetworksFormName), h.Value(n.Name)),
+		h.Select(
+			h.Name(wuiNetworksFormSite),
+			h.Class("select select-bordered select-xs"),
+			siteOptions(others, n.Site, local),
+		),
 	)
 }
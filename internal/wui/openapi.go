@@ -0,0 +1,399 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+	"sync"
+
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/logging"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/openapi"
+	"github.com/networkables/mason/internal/server"
+)
+
+// jsonEndpoints are the api routes answering with json and the remote api used by the cli,
+// the html fragments for the pages are left out of the document
+var jsonEndpoints = []openapi.Endpoint{
+	{
+		Method:      http.MethodGet,
+		Path:        urlHealthz,
+		OperationID: "liveness",
+		Summary:     "liveness of the main loop, worker pools and collectors, 503 when a check fails",
+		Response:    server.HealthReport{},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlReadyz,
+		OperationID: "readiness",
+		Summary:     "liveness along with the store connectivity, 503 when a check fails",
+		Response:    server.HealthReport{},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlApiExport,
+		OperationID: "exportInventory",
+		Summary:     "all networks and devices, or the devices as a csv asset register",
+		Parameters: []openapi.Parameter{
+			openapi.StringParameter("anonymize", "query", "true to hash macs, names and public ips", false),
+			openapi.StringParameter("key", "query", "key used to hash values", false),
+			openapi.StringParameter("format", "query", "json (default) or csv", false),
+		},
+		Response: server.InventoryExport{},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlApiTags,
+		OperationID: "listTags",
+		Summary:     "tag definitions",
+		Response:    []model.TagDefinition{},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlApiIpam,
+		OperationID: "listAddressPlans",
+		Summary:     "address plan of every network",
+		Response:    []model.AddressPlan{},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlApiScanProgress,
+		OperationID: "listNetworkScans",
+		Summary:     "progress of the running and recent network scans",
+		Response:    []discovery.NetworkScanProgress{},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlApiAnnotations + "/{id}",
+		OperationID: "listAnnotations",
+		Summary:     "annotations of a device",
+		Parameters: []openapi.Parameter{
+			openapi.StringParameter("id", "path", "address of the device", true),
+			openapi.StringParameter("duration", "query", "lookback, ex: 24h (default 6h)", false),
+		},
+		Response: []model.Annotation{},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlApiV1 + "/devices/{id}/pings",
+		OperationID: "listDevicePings",
+		Summary:     "performance pings of a device, a csv download with format=csv",
+		Parameters: []openapi.Parameter{
+			openapi.StringParameter("id", "path", "address of the device", true),
+			openapi.StringParameter("window", "query", "lookback, ex: 24h (default 6h)", false),
+			openapi.StringParameter("format", "query", "json or csv (default json)", false),
+		},
+		Response: []PingSample{},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlApiRemote + "/devices",
+		OperationID: "listDevices",
+		Response:    []model.Device{},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlApiRemote + "/review",
+		OperationID: "reviewQueue",
+		Summary:     "devices waiting for review, oldest first",
+		Response:    []model.Device{},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlApiRemote + "/devices/{id}",
+		OperationID: "getDevice",
+		Parameters:  []openapi.Parameter{deviceParameter},
+		Response:    model.Device{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/devices/{id}/enrich",
+		OperationID: "enrichDevice",
+		Summary:     "look the device up again and return the result",
+		Parameters:  []openapi.Parameter{deviceParameter},
+		Response:    model.Device{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/devices/{id}/ping",
+		OperationID: "pingDevice",
+		Summary:     "performance ping the device now and return the result",
+		Parameters:  []openapi.Parameter{deviceParameter},
+		Response:    model.Device{},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlApiRemote + "/devices/{id}/history",
+		OperationID: "deviceHistory",
+		Parameters:  []openapi.Parameter{deviceParameter},
+		Response:    []model.DeviceChange{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/devices/{id}/policy",
+		OperationID: "setDevicePolicy",
+		Parameters:  []openapi.Parameter{deviceParameter},
+		Request:     model.MonitoringPolicy{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/devices/{id}/details",
+		OperationID: "setDeviceDetails",
+		Summary:     "replace the name, owner, notes, site and asset fields of the device",
+		Parameters:  []openapi.Parameter{deviceParameter},
+		Request:     model.DeviceDetails{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/devices/{id}/approval/{state}",
+		OperationID: "setDeviceApproval",
+		Parameters: []openapi.Parameter{
+			deviceParameter,
+			openapi.StringParameter("state", "path", "unknown, approved or blocked", true),
+		},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/devices/{id}/delete",
+		OperationID: "removeDevice",
+		Parameters:  []openapi.Parameter{deviceParameter},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/devices/{id}/tags/{tag}",
+		OperationID: "tagDevice",
+		Parameters:  []openapi.Parameter{deviceParameter, tagParameter, removeParameter},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlApiRemote + "/networks",
+		OperationID: "listNetworks",
+		Response:    []model.Network{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/networks",
+		OperationID: "createNetwork",
+		Summary:     "add a network, an existing prefix is an error",
+		Parameters: []openapi.Parameter{
+			openapi.StringParameter("prefix", "query", "prefix of the network, ex: 10.0.0.0/24", true),
+			openapi.StringParameter("name", "query", "name of the network, the prefix when blank", false),
+			openapi.StringParameter("scan", "query", "true to scan the network once added", false),
+		},
+		Response: model.Network{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/networks/{name}/scan",
+		OperationID: "scanNetwork",
+		Summary:     "request a scan, the LastScan of the network is the time of the request",
+		Parameters: []openapi.Parameter{
+			openapi.StringParameter("name", "path", "name or prefix of the network", true),
+		},
+		Response: model.Network{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/networks/{name}/delete",
+		OperationID: "removeNetwork",
+		Parameters:  []openapi.Parameter{nameParameter},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/networks/{name}/tags/{tag}",
+		OperationID: "tagNetwork",
+		Parameters:  []openapi.Parameter{nameParameter, tagParameter, removeParameter},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlApiRemote + "/deleted",
+		OperationID: "listDeleted",
+		Response:    []model.Tombstone{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/deleted/{kind}/{key}/restore",
+		OperationID: "restoreDeleted",
+		Parameters: []openapi.Parameter{
+			openapi.StringParameter("kind", "path", "device or network", true),
+			openapi.StringParameter("key", "path", "address of the device or name of the network", true),
+		},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/deleted/purge",
+		OperationID: "purgeDeleted",
+		Summary:     "number of deleted items purged",
+		Response:    0,
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlApiRemote + "/maintenance",
+		OperationID: "listMaintenanceWindows",
+		Response:    []model.MaintenanceWindow{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/maintenance",
+		OperationID: "saveMaintenanceWindow",
+		Request:     model.MaintenanceWindow{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/maintenance/{name}/delete",
+		OperationID: "removeMaintenanceWindow",
+		Parameters:  []openapi.Parameter{nameParameter},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/tags",
+		OperationID: "saveTagDefinition",
+		Request:     model.TagDefinition{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/tags/{name}/delete",
+		OperationID: "removeTagDefinition",
+		Parameters:  []openapi.Parameter{nameParameter},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlApiRemote + "/portprofiles",
+		OperationID: "listPortProfiles",
+		Response:    []model.PortProfile{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/portprofiles",
+		OperationID: "savePortProfile",
+		Request:     model.PortProfile{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/portprofiles/{name}/delete",
+		OperationID: "removePortProfile",
+		Parameters:  []openapi.Parameter{nameParameter},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlApiRemote + "/events",
+		OperationID: "tailEvents",
+		Summary:     "latest stored events, oldest first",
+		Parameters: []openapi.Parameter{
+			openapi.StringParameter("kind", "query", "event or error", false),
+			openapi.StringParameter("search", "query", "text in the type or message", false),
+			openapi.StringParameter("limit", "query", "number of events", false),
+			openapi.StringParameter("after", "query", "only events after the RFC 3339 time", false),
+		},
+		Response: []model.EventRecord{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/archive",
+		OperationID: "archiveTimeseries",
+		Summary:     "number of records archived",
+		Response:    0,
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/check",
+		OperationID: "checkConsistency",
+		Parameters: []openapi.Parameter{
+			openapi.StringParameter("repair", "query", "true to fix or quarantine the records", false),
+		},
+		Response: []model.ConsistencyIssue{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/import/cloud/{provider}",
+		OperationID: "importCloud",
+		Parameters: []openapi.Parameter{
+			openapi.StringParameter("provider", "path", "aws or gcp", true),
+		},
+		Response: server.CloudImportResult{},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlApiRemote + "/captures",
+		OperationID: "listCaptures",
+		Summary:     "packet captures, the newest first",
+		Response:    []model.PacketCapture{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/captures",
+		OperationID: "startCapture",
+		Summary:     "running capture, the pcap file is at /api/captures/{id}/file once finished",
+		Request:     model.PacketCaptureRequest{},
+		Response:    model.PacketCapture{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/captures/{id}/stop",
+		OperationID: "stopCapture",
+		Parameters:  []openapi.Parameter{captureParameter},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/captures/{id}/delete",
+		OperationID: "removeCapture",
+		Parameters:  []openapi.Parameter{captureParameter},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        urlApiRemote + "/logging",
+		OperationID: "listLogLevels",
+		Summary:     "global log level followed by the level and probe traces of each subsystem",
+		Response:    []logging.SubsystemLevel{},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        urlApiRemote + "/logging",
+		OperationID: "setLogLevel",
+		Summary:     "change a log level or the probe traces of a subsystem, a blank subsystem is the global level",
+		Request:     logging.Change{},
+		Response:    []logging.SubsystemLevel{},
+	},
+}
+
+var (
+	deviceParameter = openapi.StringParameter("id", "path", "address of the device", true)
+	nameParameter   = openapi.StringParameter("name", "path", "name", true)
+	tagParameter    = openapi.StringParameter("tag", "path", "tag name", true)
+	removeParameter = openapi.StringParameter("remove", "query", "true to remove the tag", false)
+
+	captureParameter = openapi.StringParameter("id", "path", "id of the capture", true)
+)
+
+var (
+	openapiOnce     sync.Once
+	openapiDocument []byte
+)
+
+// wuiApiOpenAPIHandler returns the OpenAPI document of the json endpoints, it is built on the
+// first request
+func (w WUI) wuiApiOpenAPIHandler(wr http.ResponseWriter, r *http.Request) {
+	openapiOnce.Do(func() {
+		version := "dev_unknown"
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			version = bi.Main.Version
+		}
+		doc := openapi.New(openapi.Info{
+			Title:       "mason",
+			Description: "json endpoints of the mason server",
+			Version:     version,
+		}, jsonEndpoints)
+		var err error
+		openapiDocument, err = json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			logger.Error("openapi encode", "error", err)
+		}
+	})
+	wr.Header().Set("Content-Type", "application/json")
+	wr.Write(openapiDocument)
+}
@@ -0,0 +1,189 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/pinger"
+)
+
+const (
+	urlApiV1 = "/api/v1"
+
+	// defaultPingWindow is the lookback of the ping graphs on the device page
+	defaultPingWindow = 6 * time.Hour
+
+	pingFormatCSV = "csv"
+
+	// maxPingGraphPoints is the most points drawn in a ping graph, longer ranges are
+	// downsampled
+	maxPingGraphPoints = 720
+
+	wuiDeviceFormRange = "range"
+)
+
+// pingRange is a range the device page graphs the pings over
+type pingRange struct {
+	label string
+	dur   time.Duration
+}
+
+var pingRanges = []pingRange{
+	{"1h", time.Hour},
+	{"6h", defaultPingWindow},
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+}
+
+// parsePingRange is the range of the label, the default window for an unknown label
+func parsePingRange(label string) time.Duration {
+	for _, pr := range pingRanges {
+		if pr.label == label {
+			return pr.dur
+		}
+	}
+	return defaultPingWindow
+}
+
+// PingSample is a performance ping of a device, durations are in milliseconds so the
+// export reads the same as the graphs
+type PingSample struct {
+	Time                time.Time `json:"time"`
+	MinimumMs           float64   `json:"minimum_ms"`
+	AverageMs           float64   `json:"average_ms"`
+	MaximumMs           float64   `json:"maximum_ms"`
+	P95Ms               float64   `json:"p95_ms"`
+	P99Ms               float64   `json:"p99_ms"`
+	JitterMs            float64   `json:"jitter_ms"`
+	LossPercent         float64   `json:"loss_percent"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+}
+
+var pingSampleCSVHeader = []string{
+	"time",
+	"minimum_ms",
+	"average_ms",
+	"maximum_ms",
+	"p95_ms",
+	"p99_ms",
+	"jitter_ms",
+	"loss_percent",
+	"consecutive_failures",
+}
+
+func toPingSample(p pinger.Point) PingSample {
+	ms := func(d time.Duration) float64 {
+		return float64(d.Microseconds()) / 1000
+	}
+	return PingSample{
+		Time:                p.Start,
+		MinimumMs:           ms(p.Minimum),
+		AverageMs:           ms(p.Average),
+		MaximumMs:           ms(p.Maximum),
+		P95Ms:               ms(p.P95),
+		P99Ms:               ms(p.P99),
+		JitterMs:            ms(p.Jitter),
+		LossPercent:         p.Loss * 100,
+		ConsecutiveFailures: p.ConsecutiveFailures,
+	}
+}
+
+func (s PingSample) csvRecord() []string {
+	f := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', 3, 64)
+	}
+	return []string{
+		s.Time.UTC().Format(time.RFC3339),
+		f(s.MinimumMs),
+		f(s.AverageMs),
+		f(s.MaximumMs),
+		f(s.P95Ms),
+		f(s.P99Ms),
+		f(s.JitterMs),
+		f(s.LossPercent),
+		strconv.Itoa(s.ConsecutiveFailures),
+	}
+}
+
+// pingsURL is the ping history of the device over the window in the format, json when blank
+func pingsURL(addr model.Addr, window time.Duration, format string) string {
+	u := urlApiV1 + "/devices/" + addr.String() + "/pings?window=" + window.String()
+	if format != "" {
+		u += "&format=" + format
+	}
+	return u
+}
+
+// wuiApiDevicePingsHandler returns the performance pings of a device as json, or as a csv
+// download with ?format=csv, the lookback is changed with the window query parameter
+// (ex: ?window=24h)
+func (w WUI) wuiApiDevicePingsHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	addr, err := w.m.StringToAddr(r.PathValue("id"))
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	window := defaultPingWindow
+	if s := r.FormValue("window"); s != "" {
+		window, err = time.ParseDuration(s)
+		if err == nil && window <= 0 {
+			err = fmt.Errorf("window %s is not positive", s)
+		}
+		if err != nil {
+			http.Error(wr, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	d, err := w.m.GetDeviceByAddr(ctx, addr)
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusNotFound)
+		return
+	}
+	points, err := w.m.ReadPerformancePings(ctx, d, window)
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	samples := make([]PingSample, 0, len(points))
+	for _, p := range points {
+		samples = append(samples, toPingSample(p))
+	}
+
+	if r.FormValue("format") != pingFormatCSV {
+		wr.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(wr).Encode(samples)
+		if err != nil {
+			logger.Error("device pings encode", "error", err)
+		}
+		return
+	}
+	fname := fmt.Sprintf(
+		"mason_pings_%s_%s.csv",
+		strings.ReplaceAll(addr.String(), ":", "-"),
+		time.Now().Format("20060102150405"),
+	)
+	wr.Header().Set("Content-Type", "text/csv")
+	wr.Header().Set("Content-Disposition", "attachment; filename=\""+fname+"\"")
+	cw := csv.NewWriter(wr)
+	cw.Write(pingSampleCSVHeader)
+	for _, s := range samples {
+		cw.Write(s.csvRecord())
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		logger.Error("device pings csv", "error", err)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	g "github.com/maragudk/gomponents"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/internal/server"
+)
+
+// wuiApiDevicePolicyHandler sets the monitoring policy of a device and returns to the
+// device page
+func (w WUI) wuiApiDevicePolicyHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	id := r.PathValue("id")
+	addr, err := w.m.StringToAddr(id)
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var policy model.MonitoringPolicy
+	policy.PingInterval, err = formDuration(r, wuiTagsFormPingInterval)
+	if err == nil {
+		policy.PortScanInterval, err = formDuration(r, wuiTagsFormPortScanInterval)
+	}
+	if err == nil {
+		err = w.m.SetDevicePolicy(ctx, addr, policy)
+	}
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Redirect(wr, r, urlDevice+"/"+id, http.StatusSeeOther)
+}
+
+// devicePolicyForm shows the intervals used to monitor the device and a form to override them
+func devicePolicyForm(
+	d model.Device,
+	effective model.MonitoringPolicy,
+	cfg *server.Config,
+) g.Node {
+	pingdef, scandef := cfg.Pinger.DefaultInterval, cfg.Enrichment.PortScan.DefaultScanInterval
+	if d.IsServer() {
+		pingdef, scandef = cfg.Pinger.ServerInterval, cfg.Enrichment.PortScan.ServerScanInterval
+	}
+	return h.Div(
+		wuiTable([]string{" ", " "},
+			toTD("Ping Interval", model.IntervalOrDefault(effective.PingInterval, pingdef).String()),
+			toTD(
+				"Port Scan Interval",
+				model.IntervalOrDefault(effective.PortScanInterval, scandef).String(),
+			),
+		),
+		h.FormEl(
+			h.Action(urlApiDevice+"/"+d.Addr.String()+"/policy"),
+			h.Method("post"),
+			h.Class("flex gap-4 py-4"),
+			policyInput(
+				wuiTagsFormPingInterval,
+				"ping (blank for tags/default)",
+				d.Meta.Policy.PingInterval,
+			),
+			policyInput(
+				wuiTagsFormPortScanInterval,
+				"port scan (blank for tags/default)",
+				d.Meta.Policy.PortScanInterval,
+			),
+			h.Button(h.Class("btn btn-primary"), g.Text("Save")),
+		),
+	)
+}
+
+func policyInput(name string, placeholder string, d time.Duration) g.Node {
+	value := ""
+	if d > 0 {
+		value = d.String()
+	}
+	return h.Input(
+		h.Type("text"),
+		h.Name(name),
+		h.Value(value),
+		h.Placeholder(placeholder),
+		h.Class("input input-bordered grow"),
+	)
+}
@@ -0,0 +1,164 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"net/http"
+
+	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	wuiPortProfileFormName        = "name"
+	wuiPortProfileFormPorts       = "ports"
+	wuiPortProfileFormScope       = "scope"
+	wuiPortProfileFormTarget      = "target"
+	wuiPortProfileFormDescription = "description"
+)
+
+func (w WUI) wuiPortProfilesPageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiPortProfilesMain(ctx, nil),
+	)
+	w.basePage(ctx, "portprofiles", content, nil).Render(wr)
+}
+
+func (w WUI) wuiApiPortProfileCreate(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	pp, err := portProfileFromForm(r)
+	if err == nil {
+		err = w.m.SavePortProfile(ctx, pp)
+	}
+	w.wuiPortProfilesMain(ctx, err).Render(wr)
+}
+
+func (w WUI) wuiApiPortProfileDelete(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	err := w.m.RemovePortProfile(ctx, r.PostFormValue(wuiPortProfileFormName))
+	w.wuiPortProfilesMain(ctx, err).Render(wr)
+}
+
+func portProfileFromForm(r *http.Request) (pp model.PortProfile, err error) {
+	pp.Name = r.PostFormValue(wuiPortProfileFormName)
+	pp.Target = r.PostFormValue(wuiPortProfileFormTarget)
+	pp.Description = r.PostFormValue(wuiPortProfileFormDescription)
+	pp.Scope, err = model.ParsePortProfileScope(r.PostFormValue(wuiPortProfileFormScope))
+	if err != nil {
+		return pp, err
+	}
+	pp.Ports, err = model.ParsePortRanges(r.PostFormValue(wuiPortProfileFormPorts))
+	return pp, err
+}
+
+func (w WUI) wuiPortProfilesMain(ctx context.Context, err error) g.Node {
+	pps, lerr := w.m.ListPortProfiles(ctx)
+	if err == nil {
+		err = lerr
+	}
+	devices := w.m.ListDevices(ctx)
+	nets := w.m.ListNetworks(ctx)
+	return grid("portprofilescontent",
+		wuiCard("Port Profiles",
+			wuiTable(
+				[]string{"Name", "Ports", "Scope", "Target", "Devices", "Description", " "},
+				g.Group(g.Map(pps, func(pp model.PortProfile) g.Node {
+					return portProfileToTD(pp, devices, nets)
+				})),
+			),
+		),
+		wuiCard("Add / Update Port Profile",
+			h.Div(
+				errAlert(err),
+				h.FormEl(
+					hx.Post(urlApiPortProfiles),
+					hx.Target("#portprofilescontent"),
+					hx.Swap("outerHTML"),
+					h.Div(
+						h.Class("form-control"),
+						wuiFormInput("Name",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiPortProfileFormName),
+								h.Placeholder("web"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Ports",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiPortProfileFormPorts),
+								h.Placeholder("80,443,8000-8100"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Scope",
+							h.Select(
+								h.Name(wuiPortProfileFormScope),
+								h.Class("select select-bordered w-full md:w-1/2"),
+								h.Option(h.Value(string(model.PortProfileScopeNone)), g.Text("Unassigned")),
+								h.Option(h.Value(string(model.PortProfileScopeTag)), g.Text("Tag")),
+								h.Option(h.Value(string(model.PortProfileScopeNetwork)), g.Text("Network")),
+							),
+						),
+						wuiFormInput("Target",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiPortProfileFormTarget),
+								h.Placeholder("tag or network name"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Description",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiPortProfileFormDescription),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+					),
+					wuiFormButton("Save Port Profile"),
+				),
+			),
+		),
+	)
+}
+
+func portProfileToTD(pp model.PortProfile, devices []model.Device, nets []model.Network) g.Node {
+	count := 0
+	for _, d := range devices {
+		if pp.Applies(d, nets) {
+			count++
+		}
+	}
+	scope := string(pp.Scope)
+	if pp.Scope == model.PortProfileScopeNone {
+		scope = "unassigned"
+	}
+	return h.Tr(
+		h.Td(g.Text(pp.Name)),
+		h.Td(g.Text(pp.Ports.String())),
+		h.Td(g.Text(scope)),
+		h.Td(g.Text(pp.Target)),
+		h.Td(g.Textf("%d", count)),
+		h.Td(g.Text(pp.Description)),
+		h.Td(
+			h.FormEl(
+				hx.Post(urlApiPortProfiles+"/delete"),
+				hx.Target("#portprofilescontent"),
+				hx.Swap("outerHTML"),
+				h.Input(h.Type("hidden"), h.Name(wuiPortProfileFormName), h.Value(pp.Name)),
+				h.Button(h.Class("btn btn-xs"), g.Text("Delete")),
+			),
+		),
+	)
+}
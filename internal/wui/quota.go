@@ -0,0 +1,247 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	opts "github.com/go-echarts/go-echarts/v2/opts"
+	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	wuiQuotaFormName   = "name"
+	wuiQuotaFormScope  = "scope"
+	wuiQuotaFormTarget = "target"
+	wuiQuotaFormPeriod = "period"
+	wuiQuotaFormBytes  = "bytes"
+	wuiQuotaFormNote   = "note"
+)
+
+func (w WUI) wuiQuotasPageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiQuotasMain(ctx, nil),
+	)
+	w.basePage(ctx, "quotas", content, nil).Render(wr)
+}
+
+func (w WUI) wuiApiQuotaCreate(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	q, err := bandwidthQuotaFromForm(r)
+	if err == nil {
+		err = w.m.SaveBandwidthQuota(ctx, q)
+	}
+	w.wuiQuotasMain(ctx, err).Render(wr)
+}
+
+func (w WUI) wuiApiQuotaDelete(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	err := w.m.RemoveBandwidthQuota(ctx, r.PostFormValue(wuiQuotaFormName))
+	w.wuiQuotasMain(ctx, err).Render(wr)
+}
+
+func bandwidthQuotaFromForm(r *http.Request) (q model.BandwidthQuota, err error) {
+	q.Name = r.PostFormValue(wuiQuotaFormName)
+	q.Target = r.PostFormValue(wuiQuotaFormTarget)
+	q.Note = r.PostFormValue(wuiQuotaFormNote)
+	q.Scope, err = model.ParseQuotaScope(r.PostFormValue(wuiQuotaFormScope))
+	if err != nil {
+		return q, err
+	}
+	q.Period, err = model.ParseQuotaPeriod(r.PostFormValue(wuiQuotaFormPeriod))
+	if err != nil {
+		return q, err
+	}
+	q.Bytes, err = model.ParseQuotaBytes(r.PostFormValue(wuiQuotaFormBytes))
+	return q, err
+}
+
+func (w WUI) wuiQuotasMain(ctx context.Context, err error) g.Node {
+	quotas, lerr := w.m.ListBandwidthQuotas(ctx)
+	if err == nil {
+		err = lerr
+	}
+	usage, uerr := w.m.QuotaUsage(ctx)
+	if err == nil {
+		err = uerr
+	}
+	return grid("quotascontent",
+		wuiCard("Bandwidth Quotas",
+			wuiTable(
+				[]string{"Name", "Scope", "Target", "Period", "Quota", "Note", " "},
+				g.Group(g.Map(quotas, bandwidthQuotaToTD)),
+			),
+		),
+		wuiCard("Usage",
+			h.Div(
+				g.If(
+					!w.m.GetConfig().NetFlows.Enabled,
+					h.P(g.Text("Usage is computed from the netflows, which are disabled")),
+				),
+				quotaUsageTable(usage, w.m.GetConfig().Quotas.WarnPercent),
+			),
+		),
+		wuiCard("Add / Update Bandwidth Quota",
+			h.Div(
+				errAlert(err),
+				h.FormEl(
+					hx.Post(urlApiQuotas),
+					hx.Target("#quotascontent"),
+					hx.Swap("outerHTML"),
+					h.Div(
+						h.Class("form-control"),
+						wuiFormInput("Name",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiQuotaFormName),
+								h.Placeholder("backups"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Scope",
+							h.Select(
+								h.Name(wuiQuotaFormScope),
+								h.Class("select select-bordered w-full md:w-1/2"),
+								h.Option(h.Value(string(model.QuotaScopeDevice)), g.Text("Device")),
+								h.Option(h.Value(string(model.QuotaScopeTag)), g.Text("Tag")),
+							),
+						),
+						wuiFormInput("Target",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiQuotaFormTarget),
+								h.Placeholder("device addr or tag name"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Period",
+							h.Select(
+								h.Name(wuiQuotaFormPeriod),
+								h.Class("select select-bordered w-full md:w-1/2"),
+								h.Option(h.Value(string(model.QuotaDaily)), g.Text("Daily")),
+								h.Option(h.Value(string(model.QuotaMonthly)), g.Text("Monthly")),
+							),
+						),
+						wuiFormInput("Quota",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiQuotaFormBytes),
+								h.Placeholder("50GB"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Note",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiQuotaFormNote),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+					),
+					wuiFormButton("Save Quota"),
+				),
+			),
+		),
+	)
+}
+
+func bandwidthQuotaToTD(q model.BandwidthQuota) g.Node {
+	return h.Tr(
+		h.Td(g.Text(q.Name)),
+		h.Td(g.Text(string(q.Scope))),
+		h.Td(g.Text(q.Target)),
+		h.Td(g.Text(string(q.Period))),
+		h.Td(g.Text(q.Limit())),
+		h.Td(g.Text(q.Note)),
+		h.Td(
+			h.FormEl(
+				hx.Post(urlApiQuotas+"/delete"),
+				hx.Target("#quotascontent"),
+				hx.Swap("outerHTML"),
+				h.Input(h.Type("hidden"), h.Name(wuiQuotaFormName), h.Value(q.Name)),
+				h.Button(h.Class("btn btn-xs"), g.Text("Delete")),
+			),
+		),
+	)
+}
+
+// quotaUsageTable lists the usage of each device in the current period of its quotas
+func quotaUsageTable(usage []model.QuotaUsage, warnPercent int) g.Node {
+	return wuiTable(
+		[]string{"Device", "Quota", "Since", "Used", "Quota", " "},
+		g.Group(g.Map(usage, func(u model.QuotaUsage) g.Node {
+			return h.Tr(
+				h.Td(deviceLink(u.Addr)),
+				h.Td(g.Text(u.Quota.Name)),
+				h.Td(g.Text(u.Start.Format(time.DateOnly))),
+				h.Td(g.Text(humanize.Bytes(uint64(u.Used)))),
+				h.Td(g.Text(u.Quota.Limit())),
+				h.Td(quotaProgress(u, warnPercent)),
+			)
+		})),
+	)
+}
+
+func quotaProgress(u model.QuotaUsage, warnPercent int) g.Node {
+	class := "progress progress-success w-32"
+	switch u.Level(warnPercent) {
+	case model.QuotaWarning:
+		class = "progress progress-warning w-32"
+	case model.QuotaExceeded:
+		class = "progress progress-error w-32"
+	}
+	return h.Div(
+		h.Progress(
+			h.Class(class),
+			h.Value(fmt.Sprint(math.Min(u.Percent(), 100))),
+			h.Max("100"),
+		),
+		g.Textf(" %.0f%%", u.Percent()),
+	)
+}
+
+// quotaUsageGraph charts the traffic of the device accumulated over the period against the
+// quota, the usage is plotted at the end of each day
+func quotaUsageGraph(usage []model.QuotaUsage) g.Node {
+	if len(usage) == 0 {
+		return nil
+	}
+	now := time.Now()
+	line := timeLineGraph("traffic (GB)", "{value} GB")
+	for _, u := range usage {
+		used := []opts.LineData{{Value: EChartPoint{u.Start, 0}}}
+		var total int64
+		for _, day := range u.Days {
+			total += day.Total()
+			end := day.Day.AddDate(0, 0, 1)
+			if end.After(now) {
+				end = now
+			}
+			used = append(used, opts.LineData{Value: EChartPoint{end, gigabytes(total)}})
+		}
+		line.AddSeries(u.Quota.Name+" used", used)
+		line.AddSeries(u.Quota.Name+" quota", []opts.LineData{
+			{Value: EChartPoint{u.Start, gigabytes(u.Quota.Bytes)}},
+			{Value: EChartPoint{u.Quota.PeriodEnd(now), gigabytes(u.Quota.Bytes)}},
+		})
+	}
+	return renderLineGraph(line)
+}
+
+func gigabytes(b int64) float64 {
+	return math.Round(float64(b)/1e7) / 100
+}
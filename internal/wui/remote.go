@@ -0,0 +1,408 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/networkables/mason/internal/logging"
+	"github.com/networkables/mason/internal/model"
+	"github.com/networkables/mason/nettools"
+)
+
+// the remote api lets the cli work against a running server instead of opening its stores,
+// bodies and results are json and an error is returned as text
+const urlApiRemote = "/api/remote"
+
+var errRemoteBadRequest = errors.New("bad request")
+
+func (w WUI) addRemoteRoutes(mux *http.ServeMux) {
+	handle := func(pattern string, fn func(context.Context, *http.Request) (any, error)) {
+		mux.HandleFunc(pattern, remoteHandler(fn))
+	}
+	handle("GET "+urlApiRemote+"/devices", w.remoteListDevices)
+	handle("GET "+urlApiRemote+"/review", w.remoteReviewQueue)
+	handle("GET "+urlApiRemote+"/devices/{id}", w.remoteGetDevice)
+	handle("POST "+urlApiRemote+"/devices/{id}/enrich", w.remoteDeviceEnrich)
+	handle("POST "+urlApiRemote+"/devices/{id}/ping", w.remoteDevicePing)
+	handle("GET "+urlApiRemote+"/devices/{id}/history", w.remoteDeviceHistory)
+	handle("POST "+urlApiRemote+"/devices/{id}/policy", w.remoteDevicePolicy)
+	handle("POST "+urlApiRemote+"/devices/{id}/details", w.remoteDeviceDetails)
+	handle("POST "+urlApiRemote+"/devices/{id}/approval/{state}", w.remoteDeviceApproval)
+	handle("POST "+urlApiRemote+"/devices/{id}/delete", w.remoteDeviceDelete)
+	handle("POST "+urlApiRemote+"/devices/{id}/tags/{tag}", w.remoteDeviceTag)
+	handle("GET "+urlApiRemote+"/networks", w.remoteListNetworks)
+	handle("POST "+urlApiRemote+"/networks", w.remoteCreateNetwork)
+	handle("POST "+urlApiRemote+"/networks/{name}/scan", w.remoteScanNetwork)
+	handle("POST "+urlApiRemote+"/networks/{name}/delete", w.remoteNetworkDelete)
+	handle("POST "+urlApiRemote+"/networks/{name}/tags/{tag}", w.remoteNetworkTag)
+	handle("GET "+urlApiRemote+"/deleted", w.remoteListDeleted)
+	handle("POST "+urlApiRemote+"/deleted/{kind}/{key}/restore", w.remoteRestoreDeleted)
+	handle("POST "+urlApiRemote+"/deleted/purge", w.remotePurgeDeleted)
+	handle("GET "+urlApiRemote+"/maintenance", w.remoteListMaintenance)
+	handle("POST "+urlApiRemote+"/maintenance", w.remoteSaveMaintenance)
+	handle("POST "+urlApiRemote+"/maintenance/{name}/delete", w.remoteRemoveMaintenance)
+	handle("POST "+urlApiRemote+"/tags", w.remoteSaveTag)
+	handle("POST "+urlApiRemote+"/tags/{name}/delete", w.remoteRemoveTag)
+	handle("GET "+urlApiRemote+"/portprofiles", w.remoteListPortProfiles)
+	handle("POST "+urlApiRemote+"/portprofiles", w.remoteSavePortProfile)
+	handle("POST "+urlApiRemote+"/portprofiles/{name}/delete", w.remoteRemovePortProfile)
+	handle("GET "+urlApiRemote+"/events", w.remoteTailEvents)
+	handle("POST "+urlApiRemote+"/archive", w.remoteArchive)
+	handle("POST "+urlApiRemote+"/check", w.remoteCheck)
+	handle("POST "+urlApiRemote+"/import/cloud/{provider}", w.remoteImportCloud)
+	handle("POST "+urlApiRemote+"/import/netbox", w.remoteSyncNetbox)
+	handle("GET "+urlApiRemote+"/captures", w.remoteListCaptures)
+	handle("POST "+urlApiRemote+"/captures", w.remoteStartCapture)
+	handle("POST "+urlApiRemote+"/captures/{id}/stop", w.remoteStopCapture)
+	handle("POST "+urlApiRemote+"/captures/{id}/delete", w.remoteRemoveCapture)
+	handle("GET "+urlApiRemote+"/logging", w.remoteLogLevels)
+	handle("POST "+urlApiRemote+"/logging", w.remoteSetLogLevel)
+}
+
+// remoteHandler writes the result of fn as json, a nil result is answered with no content
+func remoteHandler(fn func(context.Context, *http.Request) (any, error)) http.HandlerFunc {
+	return func(wr http.ResponseWriter, r *http.Request) {
+		out, err := fn(r.Context(), r)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errRemoteBadRequest) {
+				status = http.StatusBadRequest
+			}
+			http.Error(wr, err.Error(), status)
+			return
+		}
+		if out == nil {
+			wr.WriteHeader(http.StatusNoContent)
+			return
+		}
+		wr.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(wr).Encode(out)
+		if err != nil {
+			logger.Error("remote encode", "path", r.URL.Path, "error", err)
+		}
+	}
+}
+
+func badRequest(err error) error {
+	return errors.Join(errRemoteBadRequest, err)
+}
+
+func (w WUI) remoteAddr(r *http.Request) (model.Addr, error) {
+	addr, err := w.m.StringToAddr(r.PathValue("id"))
+	if err != nil {
+		return addr, badRequest(err)
+	}
+	return addr, nil
+}
+
+func decodeBody(r *http.Request, v any) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		return badRequest(err)
+	}
+	return nil
+}
+
+func (w WUI) remoteListDevices(ctx context.Context, r *http.Request) (any, error) {
+	return w.m.ListDevices(ctx), nil
+}
+
+func (w WUI) remoteReviewQueue(ctx context.Context, r *http.Request) (any, error) {
+	return w.m.ReviewQueue(ctx), nil
+}
+
+func (w WUI) remoteGetDevice(ctx context.Context, r *http.Request) (any, error) {
+	addr, err := w.remoteAddr(r)
+	if err != nil {
+		return nil, err
+	}
+	return w.m.GetDeviceByAddr(ctx, addr)
+}
+
+// remoteDeviceEnrich waits for the enrichment and returns the updated device
+func (w WUI) remoteDeviceEnrich(ctx context.Context, r *http.Request) (any, error) {
+	addr, err := w.remoteAddr(r)
+	if err != nil {
+		return nil, err
+	}
+	return w.m.EnrichDeviceNow(ctx, addr)
+}
+
+// remoteDevicePing waits for the ping and returns the updated device
+func (w WUI) remoteDevicePing(ctx context.Context, r *http.Request) (any, error) {
+	addr, err := w.remoteAddr(r)
+	if err != nil {
+		return nil, err
+	}
+	return w.m.PingDeviceNow(ctx, addr)
+}
+
+func (w WUI) remoteDeviceHistory(ctx context.Context, r *http.Request) (any, error) {
+	addr, err := w.remoteAddr(r)
+	if err != nil {
+		return nil, err
+	}
+	return w.m.DeviceHistory(ctx, addr)
+}
+
+func (w WUI) remoteDevicePolicy(ctx context.Context, r *http.Request) (any, error) {
+	addr, err := w.remoteAddr(r)
+	if err != nil {
+		return nil, err
+	}
+	var policy model.MonitoringPolicy
+	err = decodeBody(r, &policy)
+	if err != nil {
+		return nil, err
+	}
+	return nil, w.m.SetDevicePolicy(ctx, addr, policy)
+}
+
+func (w WUI) remoteDeviceDetails(ctx context.Context, r *http.Request) (any, error) {
+	addr, err := w.remoteAddr(r)
+	if err != nil {
+		return nil, err
+	}
+	var details model.DeviceDetails
+	err = decodeBody(r, &details)
+	if err != nil {
+		return nil, err
+	}
+	err = w.m.SetDeviceDetails(ctx, addr, details)
+	if errors.Is(err, model.ErrInvalidDeviceName) ||
+		errors.Is(err, model.ErrInvalidSiteName) ||
+		errors.Is(err, model.ErrDeviceNotesTooLong) ||
+		errors.Is(err, model.ErrInvalidPurchaseDate) {
+		return nil, badRequest(err)
+	}
+	return nil, err
+}
+
+func (w WUI) remoteDeviceApproval(ctx context.Context, r *http.Request) (any, error) {
+	addr, err := w.remoteAddr(r)
+	if err != nil {
+		return nil, err
+	}
+	state, err := model.ParseApprovalState(r.PathValue("state"))
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	return nil, w.m.SetDeviceApproval(ctx, addr, state)
+}
+
+func (w WUI) remoteDeviceDelete(ctx context.Context, r *http.Request) (any, error) {
+	addr, err := w.remoteAddr(r)
+	if err != nil {
+		return nil, err
+	}
+	return nil, w.m.RemoveDevice(ctx, addr)
+}
+
+// remoteDeviceTag adds the tag, or removes it with ?remove=true
+func (w WUI) remoteDeviceTag(ctx context.Context, r *http.Request) (any, error) {
+	addr, err := w.remoteAddr(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.FormValue("remove") == "true" {
+		return nil, w.m.UntagDevice(ctx, addr, r.PathValue("tag"))
+	}
+	return nil, w.m.TagDevice(ctx, addr, r.PathValue("tag"))
+}
+
+func (w WUI) remoteListNetworks(ctx context.Context, r *http.Request) (any, error) {
+	return w.m.ListNetworks(ctx), nil
+}
+
+// remoteCreateNetwork adds the network of the query parameters prefix, name and scan
+func (w WUI) remoteCreateNetwork(ctx context.Context, r *http.Request) (any, error) {
+	prefix := r.FormValue("prefix")
+	_, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	n, err := w.m.CreateNetwork(ctx, r.FormValue("name"), prefix, r.FormValue("scan") == "true")
+	if errors.Is(err, model.ErrNetworkExists) {
+		return nil, badRequest(err)
+	}
+	return n, err
+}
+
+func (w WUI) remoteScanNetwork(ctx context.Context, r *http.Request) (any, error) {
+	return w.m.ScanNetwork(ctx, r.PathValue("name"))
+}
+
+func (w WUI) remoteNetworkDelete(ctx context.Context, r *http.Request) (any, error) {
+	return nil, w.m.RemoveNetwork(ctx, r.PathValue("name"))
+}
+
+// remoteNetworkTag adds the tag, or removes it with ?remove=true
+func (w WUI) remoteNetworkTag(ctx context.Context, r *http.Request) (any, error) {
+	if r.FormValue("remove") == "true" {
+		return nil, w.m.UntagNetwork(ctx, r.PathValue("name"), r.PathValue("tag"))
+	}
+	return nil, w.m.TagNetwork(ctx, r.PathValue("name"), r.PathValue("tag"))
+}
+
+func (w WUI) remoteListDeleted(ctx context.Context, r *http.Request) (any, error) {
+	return w.m.ListDeleted(ctx)
+}
+
+func (w WUI) remoteRestoreDeleted(ctx context.Context, r *http.Request) (any, error) {
+	kind, err := model.ParseTombstoneKind(r.PathValue("kind"))
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	return nil, w.m.RestoreDeleted(ctx, kind, r.PathValue("key"))
+}
+
+// remotePurgeDeleted returns the number of items purged
+func (w WUI) remotePurgeDeleted(ctx context.Context, r *http.Request) (any, error) {
+	return w.m.PurgeDeleted(ctx)
+}
+
+func (w WUI) remoteListMaintenance(ctx context.Context, r *http.Request) (any, error) {
+	return w.m.ListMaintenanceWindows(ctx)
+}
+
+func (w WUI) remoteSaveMaintenance(ctx context.Context, r *http.Request) (any, error) {
+	var window model.MaintenanceWindow
+	err := decodeBody(r, &window)
+	if err != nil {
+		return nil, err
+	}
+	return nil, w.m.SaveMaintenanceWindow(ctx, window)
+}
+
+func (w WUI) remoteRemoveMaintenance(ctx context.Context, r *http.Request) (any, error) {
+	return nil, w.m.RemoveMaintenanceWindow(ctx, r.PathValue("name"))
+}
+
+func (w WUI) remoteSaveTag(ctx context.Context, r *http.Request) (any, error) {
+	var def model.TagDefinition
+	err := decodeBody(r, &def)
+	if err != nil {
+		return nil, err
+	}
+	return nil, w.m.SaveTagDefinition(ctx, def)
+}
+
+func (w WUI) remoteRemoveTag(ctx context.Context, r *http.Request) (any, error) {
+	return nil, w.m.RemoveTagDefinition(ctx, r.PathValue("name"))
+}
+
+func (w WUI) remoteListPortProfiles(ctx context.Context, r *http.Request) (any, error) {
+	return w.m.ListPortProfiles(ctx)
+}
+
+func (w WUI) remoteSavePortProfile(ctx context.Context, r *http.Request) (any, error) {
+	var pp model.PortProfile
+	err := decodeBody(r, &pp)
+	if err != nil {
+		return nil, err
+	}
+	return nil, w.m.SavePortProfile(ctx, pp)
+}
+
+func (w WUI) remoteRemovePortProfile(ctx context.Context, r *http.Request) (any, error) {
+	return nil, w.m.RemovePortProfile(ctx, r.PathValue("name"))
+}
+
+// remoteTailEvents reads the stored events, the query parameters are kind, search, limit
+// and after (RFC 3339)
+func (w WUI) remoteTailEvents(ctx context.Context, r *http.Request) (any, error) {
+	q := model.EventQuery{
+		Kind:   model.EventRecordKind(r.FormValue("kind")),
+		Search: r.FormValue("search"),
+	}
+	var err error
+	if s := r.FormValue("limit"); s != "" {
+		q.Limit, err = strconv.Atoi(s)
+		if err != nil {
+			return nil, badRequest(err)
+		}
+	}
+	if s := r.FormValue("after"); s != "" {
+		q.After, err = time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, badRequest(err)
+		}
+	}
+	return w.m.TailEvents(ctx, q)
+}
+
+// remoteArchive returns the number of records archived
+func (w WUI) remoteArchive(ctx context.Context, r *http.Request) (any, error) {
+	return w.m.ArchiveTimeseries(ctx)
+}
+
+// remoteCheck returns the consistency issues found, fixed with ?repair=true
+func (w WUI) remoteCheck(ctx context.Context, r *http.Request) (any, error) {
+	return w.m.CheckConsistency(ctx, r.FormValue("repair") == "true")
+}
+
+func (w WUI) remoteImportCloud(ctx context.Context, r *http.Request) (any, error) {
+	return w.m.ImportCloud(ctx, r.PathValue("provider"))
+}
+
+func (w WUI) remoteSyncNetbox(ctx context.Context, r *http.Request) (any, error) {
+	return w.m.SyncNetbox(ctx)
+}
+
+func (w WUI) remoteListCaptures(ctx context.Context, r *http.Request) (any, error) {
+	return w.m.ListCaptures(ctx)
+}
+
+// remoteStartCapture returns the running capture, its file is downloaded from
+// /api/captures/{id}/file once it is finished
+func (w WUI) remoteStartCapture(ctx context.Context, r *http.Request) (any, error) {
+	var req model.PacketCaptureRequest
+	err := decodeBody(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	c, err := w.m.StartCapture(ctx, req)
+	if errors.Is(err, model.ErrCaptureInterfaceRequired) ||
+		errors.Is(err, model.ErrCaptureInterfaceNotExists) ||
+		errors.Is(err, model.ErrInvalidCaptureDuration) ||
+		errors.Is(err, model.ErrInvalidCaptureMaxBytes) ||
+		errors.Is(err, nettools.ErrInvalidCaptureFilter) {
+		return nil, badRequest(err)
+	}
+	return c, err
+}
+
+func (w WUI) remoteStopCapture(ctx context.Context, r *http.Request) (any, error) {
+	return nil, w.m.StopCapture(ctx, r.PathValue("id"))
+}
+
+func (w WUI) remoteRemoveCapture(ctx context.Context, r *http.Request) (any, error) {
+	return nil, w.m.RemoveCapture(ctx, r.PathValue("id"))
+}
+
+// the log levels belong to the process serving the api, not to mason
+func (w WUI) remoteLogLevels(ctx context.Context, r *http.Request) (any, error) {
+	return logging.Levels(), nil
+}
+
+func (w WUI) remoteSetLogLevel(ctx context.Context, r *http.Request) (any, error) {
+	var change logging.Change
+	err := decodeBody(r, &change)
+	if err != nil {
+		return nil, err
+	}
+	err = change.Apply()
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	return logging.Levels(), nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	wuiReviewFormAddr  = "addr"
+	wuiReviewFormState = "state"
+	wuiReviewFormTag   = "tag"
+)
+
+func (w WUI) wuiReviewPageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiReviewMain(ctx, nil),
+	)
+	w.basePage(ctx, "review", content, nil).Render(wr)
+}
+
+// wuiApiReviewHandler approves (optionally adding a tag) or blocks a device from the
+// review queue
+func (w WUI) wuiApiReviewHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	addr, err := w.m.StringToAddr(r.PostFormValue(wuiReviewFormAddr))
+	if err == nil {
+		if tag := r.PostFormValue(wuiReviewFormTag); tag != "" {
+			err = w.m.TagDevice(ctx, addr, tag)
+		}
+	}
+	if err == nil {
+		state := model.ApprovalState(r.PostFormValue(wuiReviewFormState))
+		err = w.m.SetDeviceApproval(ctx, addr, state)
+	}
+	w.wuiReviewMain(ctx, err).Render(wr)
+}
+
+// wuiApiDeviceApprovalHandler sets the approval state of a device and returns to the
+// device page
+func (w WUI) wuiApiDeviceApprovalHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	id := r.PathValue("id")
+	addr, err := w.m.StringToAddr(id)
+	if err == nil {
+		state := model.ApprovalState(r.PostFormValue(wuiReviewFormState))
+		err = w.m.SetDeviceApproval(ctx, addr, state)
+	}
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Redirect(wr, r, urlDevice+"/"+id, http.StatusSeeOther)
+}
+
+func (w WUI) wuiReviewMain(ctx context.Context, err error) g.Node {
+	devs := w.m.ReviewQueue(ctx)
+	exclusions, _ := w.m.ListExclusions(ctx)
+	return grid("reviewcontent",
+		wuiCard("Awaiting Review",
+			h.Div(
+				errAlert(err),
+				wuiTable(
+					[]string{"Name", "IP", "MAC", "Manufacturer", "Discovered", " "},
+					g.Group(g.Map(devs, func(d model.Device) g.Node {
+						return reviewToTD(d, model.Exclusions(exclusions).Matching(d.Addr))
+					})),
+				),
+			),
+		),
+	)
+}
+
+func reviewToTD(d model.Device, exclusions model.Exclusions) g.Node {
+	return h.Tr(
+		h.Td(
+			h.A(h.Href(urlDevice+"/"+d.Addr.String()), h.Class("link"), g.Text(d.Name)),
+			excludedBadge(exclusions),
+		),
+		h.Td(g.Text(d.Addr.String())),
+		h.Td(g.Text(d.MAC.String())),
+		h.Td(g.Text(d.Meta.Manufacturer)),
+		h.Td(g.Text(humanize.Time(d.DiscoveredAt)+" by "+d.DiscoveredBy.String())),
+		h.Td(
+			h.Div(
+				h.Class("flex gap-2"),
+				h.FormEl(
+					hx.Post(urlApiReview),
+					hx.Target("#reviewcontent"),
+					hx.Swap("outerHTML"),
+					h.Class("flex gap-2"),
+					h.Input(h.Type("hidden"), h.Name(wuiReviewFormAddr), h.Value(d.Addr.String())),
+					h.Input(
+						h.Type("hidden"),
+						h.Name(wuiReviewFormState),
+						h.Value(string(model.ApprovalApproved)),
+					),
+					h.Input(
+						h.Type("text"),
+						h.Name(wuiReviewFormTag),
+						h.Placeholder("tag (optional)"),
+						h.Class("input input-bordered input-xs"),
+					),
+					h.Button(h.Class("btn btn-success btn-xs"), g.Text("Approve")),
+				),
+				h.FormEl(
+					hx.Post(urlApiReview),
+					hx.Target("#reviewcontent"),
+					hx.Swap("outerHTML"),
+					h.Input(h.Type("hidden"), h.Name(wuiReviewFormAddr), h.Value(d.Addr.String())),
+					h.Input(
+						h.Type("hidden"),
+						h.Name(wuiReviewFormState),
+						h.Value(string(model.ApprovalBlocked)),
+					),
+					h.Button(h.Class("btn btn-error btn-xs"), g.Text("Block")),
+				),
+			),
+		),
+	)
+}
+
+// deviceApprovalForm shows the approval state of the device with buttons to change it
+func deviceApprovalForm(d model.Device) g.Node {
+	current := d.Approval()
+	return h.FormEl(
+		h.Action(urlApiDevice+"/"+d.Addr.String()+"/approval"),
+		h.Method("post"),
+		h.Class("flex items-center gap-2 py-4"),
+		h.Span(h.Class("badge badge-outline"), g.Text(string(current))),
+		g.Group(g.Map(model.ApprovalStates, func(s model.ApprovalState) g.Node {
+			return g.If(s != current, h.Button(
+				h.Name(wuiReviewFormState),
+				h.Value(string(s)),
+				h.Class("btn btn-sm"),
+				g.Text("Mark "+string(s)),
+			))
+		})),
+	)
+}
+
+func sideBarLinkReview(count int, selected string) g.Node {
+	name := "Review"
+	return baseSideBarLink(
+		name,
+		g.Group(
+			[]g.Node{
+				g.Text(name),
+				g.If(count > 0, h.Span(
+					h.Class("badge badge-warning badge-sm"),
+					g.Text(strconv.Itoa(count)),
+				)),
+			},
+		),
+		selected,
+		urlReview,
+		svgShieldExclamation,
+	)
+}
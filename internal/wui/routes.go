@@ -7,6 +7,7 @@ package wui
 import (
 	"net/http"
 
+	"github.com/networkables/mason/internal/agent"
 	"github.com/networkables/mason/internal/static"
 )
 
@@ -20,21 +21,70 @@ func (w WUI) addRoutes(mux *http.ServeMux) {
 
 const (
 	urlConfig          = "/config"
+	urlSettings        = "/settings"
 	urlInternals       = "/internals"
+	urlEventLog        = "/eventlog"
 	urlNetworks        = "/networks"
+	urlIpam            = "/ipam"
+	urlTags            = "/tags"
+	urlSites           = "/sites"
+	urlInternet        = "/internet"
+	urlCompare         = "/compare"
+	urlDeleted         = "/deleted"
+	urlMaintenance     = "/maintenance"
+	urlQuotas          = "/quotas"
+	urlExclusions      = "/exclusions"
+	urlDependencies    = "/dependencies"
+	urlPortProfiles    = "/portprofiles"
+	urlTopology        = "/topology"
+	urlHTTPChecks      = "/checks"
+	urlReview          = "/review"
 	urlDevices         = "/devices"
 	urlDevice          = "/device"
+	urlLabels          = "/labels"
 	urlRoot            = "/"
 	urlApiNetworks     = "/api/networks"
+	urlApiNetworkScans = "/api/networks/scans"
+	urlApiNetwork      = "/api/network"
+	urlApiScanProgress = "/api/scanprogress"
+	urlApiEvents       = "/api/events"
+	urlApiEventLog     = "/api/eventlog"
 	urlApiDevices      = "/api/devices"
+	urlApiDevicesBulk  = "/api/devices/bulk"
+	urlApiAgentReport  = agent.ReportPath
+	urlApiDevice       = "/api/device"
+	urlApiTags         = "/api/tags"
+	urlApiSites        = "/api/sites"
+	urlApiSite         = "/api/site"
+	urlApiDeleted      = "/api/deleted"
+	urlApiMaintenance  = "/api/maintenance"
+	urlApiQuotas       = "/api/quotas"
+	urlApiExclusions   = "/api/exclusions"
+	urlApiDependencies = "/api/dependencies"
+	urlApiPortProfiles = "/api/portprofiles"
+	urlApiHTTPChecks   = "/api/checks"
+	urlApiDHCPWatch    = "/api/checks/dhcp"
+	urlApiReview       = "/api/review"
 	urlApiPing         = "/api/ping"
 	urlApiTraceroute   = "/api/traceroute"
 	urlApiTLS          = "/api/tls"
+	urlApiCidr         = "/api/cidr"
+	urlApiCidrNetwork  = "/api/cidr/network"
 	urlApiInvestigator = "/api/investigator"
+	urlApiExport       = "/api/export"
+	urlApiAnnotations  = "/api/annotations"
+	urlApiIpam         = "/api/ipam"
+	urlApiReservations = "/api/ipam/reservations"
+	urlApiOpenAPI      = "/api/openapi.json"
+	urlApiCaptures     = "/api/captures"
 	urlInvestigator    = "/investigator"
 	urlPing            = "/ping"
 	urlTraceroute      = "/traceroute"
 	urlTLS             = "/tls"
+	urlCidr            = "/cidr"
+	urlCapture         = "/capture"
+	urlHealthz         = "/healthz"
+	urlReadyz          = "/readyz"
 )
 
 func (w WUI) addPageRoutes(mux *http.ServeMux) {
@@ -42,20 +92,98 @@ func (w WUI) addPageRoutes(mux *http.ServeMux) {
 	mux.HandleFunc(urlPing, w.wuiToolPingHandler)
 	mux.HandleFunc(urlTraceroute, w.wuiToolTracerouteHandler)
 	mux.HandleFunc(urlTLS, w.wuiToolTLSHandler)
+	mux.HandleFunc(urlCidr, w.wuiToolCidrHandler)
+	mux.HandleFunc(urlCapture, w.wuiToolCaptureHandler)
 
 	mux.HandleFunc(urlConfig, w.wuiConfigPageHandler)
+	mux.HandleFunc(urlSettings, w.wuiSettingsPageHandler)
 	mux.HandleFunc(urlInternals, w.wuiInternalsPageHandler)
+	mux.HandleFunc(urlEventLog, w.wuiEventLogPageHandler)
 	mux.HandleFunc(urlNetworks, w.wuiNetworksPageHandler)
+	mux.HandleFunc(urlNetworks+"/{prefix...}", w.wuiNetworkPageHandler)
+	mux.HandleFunc(urlIpam, w.wuiIpamPageHandler)
+	mux.HandleFunc(urlTags, w.wuiTagsPageHandler)
+	mux.HandleFunc(urlSites, w.wuiSitesPageHandler)
+	mux.HandleFunc(urlInternet, w.wuiInternetPageHandler)
+	mux.HandleFunc(urlCompare, w.wuiComparePageHandler)
+	mux.HandleFunc(urlDeleted, w.wuiDeletedPageHandler)
+	mux.HandleFunc(urlMaintenance, w.wuiMaintenancePageHandler)
+	mux.HandleFunc(urlQuotas, w.wuiQuotasPageHandler)
+	mux.HandleFunc(urlExclusions, w.wuiExclusionsPageHandler)
+	mux.HandleFunc(urlDependencies, w.wuiDependenciesPageHandler)
+	mux.HandleFunc(urlPortProfiles, w.wuiPortProfilesPageHandler)
+	mux.HandleFunc(urlTopology, w.wuiTopologyPageHandler)
+	mux.HandleFunc(urlHTTPChecks, w.wuiHTTPChecksPageHandler)
+	mux.HandleFunc(urlReview, w.wuiReviewPageHandler)
 	mux.HandleFunc(urlDevices, w.wuiDevicesPageHandler)
 	mux.HandleFunc(urlDevice+"/{id}", w.wuiDevicePageHandler)
+	mux.HandleFunc(urlLabels, w.wuiLabelsPageHandler)
 	mux.HandleFunc(urlRoot, w.wuiHomePageHandler)
 }
 
 func (w WUI) addApiRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET "+urlHealthz, w.wuiHealthzHandler)
+	mux.HandleFunc("GET "+urlReadyz, w.wuiReadyzHandler)
+	mux.HandleFunc("GET "+urlApiOpenAPI, w.wuiApiOpenAPIHandler)
 	mux.HandleFunc("POST "+urlApiNetworks, w.wuiNetworksApiCreate)
+	mux.HandleFunc("POST "+urlApiNetworks+"/delete", w.wuiNetworksApiDelete)
+	mux.HandleFunc("GET "+urlApiNetworkScans, w.wuiNetworkScansApiHandler)
+	mux.HandleFunc("GET "+urlApiScanProgress, w.wuiApiScanProgressHandler)
+	mux.HandleFunc("GET "+urlApiEvents, w.wuiApiEventsHandler)
+	mux.HandleFunc("GET "+urlApiEventLog, w.wuiEventLogApiHandler)
 	mux.HandleFunc(urlApiDevices, w.wuiDevicesApiHandler)
+	mux.HandleFunc("POST "+urlApiDevicesBulk, w.wuiApiDevicesBulkHandler)
+	mux.HandleFunc("POST "+urlApiAgentReport, w.wuiApiAgentReportHandler)
 	mux.HandleFunc(urlApiPing, w.wuiApiToolPingHandler)
 	mux.HandleFunc(urlApiTraceroute, w.wuiApiToolTracerouteHandler)
 	mux.HandleFunc(urlApiTLS, w.wuiApiToolTLSHandler)
+	mux.HandleFunc(urlApiCidr, w.wuiApiToolCidrHandler)
+	mux.HandleFunc(urlApiCidrNetwork, w.wuiApiToolCidrNetworkHandler)
 	mux.HandleFunc(urlApiInvestigator, w.wuiApiToolInvestigatorHandler)
+	mux.HandleFunc("GET "+urlApiExport, w.wuiApiExportHandler)
+	mux.HandleFunc("GET "+urlApiAnnotations+"/{id}", w.wuiApiAnnotationsHandler)
+	mux.HandleFunc("GET "+urlApiV1+"/devices/{id}/pings", w.wuiApiDevicePingsHandler)
+	mux.HandleFunc("GET "+urlApiIpam, w.wuiApiIpamHandler)
+	mux.HandleFunc("POST "+urlApiReservations, w.wuiApiReservationCreate)
+	mux.HandleFunc("POST "+urlApiReservations+"/delete", w.wuiApiReservationDelete)
+	mux.HandleFunc("GET "+urlApiTags, w.wuiApiTagsHandler)
+	mux.HandleFunc("POST "+urlApiTags, w.wuiApiTagCreate)
+	mux.HandleFunc("POST "+urlApiTags+"/delete", w.wuiApiTagDelete)
+	mux.HandleFunc("POST "+urlApiSites, w.wuiApiSiteCreate)
+	mux.HandleFunc("POST "+urlApiSites+"/delete", w.wuiApiSiteDelete)
+	mux.HandleFunc("GET "+urlApiSite, w.wuiApiSiteSelectorHandler)
+	mux.HandleFunc("POST "+urlApiSite, w.wuiApiSiteSelectHandler)
+	mux.HandleFunc("POST "+urlApiNetworks+"/site", w.wuiNetworksApiSite)
+	mux.HandleFunc("POST "+urlApiNetwork+"/scan", w.wuiApiNetworkScanHandler)
+	mux.HandleFunc("POST "+urlApiNetwork+"/details", w.wuiApiNetworkDetailsHandler)
+	mux.HandleFunc("POST "+urlApiNetwork+"/tags", w.wuiApiNetworkTagHandler)
+	mux.HandleFunc("POST "+urlApiNetwork+"/delete", w.wuiApiNetworkDeleteHandler)
+	mux.HandleFunc("POST "+urlApiDevice+"/{id}/tags", w.wuiApiDeviceTagHandler)
+	mux.HandleFunc("POST "+urlApiDevice+"/{id}/delete", w.wuiApiDeviceDeleteHandler)
+	mux.HandleFunc("POST "+urlApiDevice+"/{id}/policy", w.wuiApiDevicePolicyHandler)
+	mux.HandleFunc("POST "+urlApiDevice+"/{id}/approval", w.wuiApiDeviceApprovalHandler)
+	mux.HandleFunc("POST "+urlApiDevice+"/{id}/details", w.wuiApiDeviceDetailsHandler)
+	mux.HandleFunc("GET "+urlApiDevice+"/{id}/qr", w.wuiApiDeviceQRHandler)
+	mux.HandleFunc("POST "+urlApiReview, w.wuiApiReviewHandler)
+	mux.HandleFunc("POST "+urlApiDeleted+"/restore", w.wuiApiDeletedRestore)
+	mux.HandleFunc("POST "+urlApiMaintenance, w.wuiApiMaintenanceCreate)
+	mux.HandleFunc("POST "+urlApiMaintenance+"/delete", w.wuiApiMaintenanceDelete)
+	mux.HandleFunc("POST "+urlApiQuotas, w.wuiApiQuotaCreate)
+	mux.HandleFunc("POST "+urlApiQuotas+"/delete", w.wuiApiQuotaDelete)
+	mux.HandleFunc("POST "+urlApiExclusions, w.wuiApiExclusionCreate)
+	mux.HandleFunc("POST "+urlApiExclusions+"/delete", w.wuiApiExclusionDelete)
+	mux.HandleFunc("POST "+urlApiDependencies, w.wuiApiDependencyCreate)
+	mux.HandleFunc("POST "+urlApiDependencies+"/delete", w.wuiApiDependencyDelete)
+	mux.HandleFunc("POST "+urlApiPortProfiles, w.wuiApiPortProfileCreate)
+	mux.HandleFunc("POST "+urlApiPortProfiles+"/delete", w.wuiApiPortProfileDelete)
+	mux.HandleFunc("POST "+urlApiHTTPChecks, w.wuiApiHTTPCheckCreate)
+	mux.HandleFunc("POST "+urlApiHTTPChecks+"/delete", w.wuiApiHTTPCheckDelete)
+	mux.HandleFunc("POST "+urlApiDHCPWatch+"/trust", w.wuiApiDHCPSightingTrust)
+	mux.HandleFunc("POST "+urlApiDHCPWatch+"/forget", w.wuiApiDHCPSightingForget)
+	mux.HandleFunc("GET "+urlApiCaptures, w.wuiApiCapturesHandler)
+	mux.HandleFunc("POST "+urlApiCaptures, w.wuiApiCaptureStart)
+	mux.HandleFunc("POST "+urlApiCaptures+"/{id}/stop", w.wuiApiCaptureStop)
+	mux.HandleFunc("POST "+urlApiCaptures+"/{id}/delete", w.wuiApiCaptureDelete)
+	mux.HandleFunc("GET "+urlApiCaptures+"/{id}/file", w.wuiApiCaptureFile)
+	w.addRemoteRoutes(mux)
 }
@@ -0,0 +1,72 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"net/http"
+
+	g "github.com/maragudk/gomponents"
+	h "github.com/maragudk/gomponents/html"
+)
+
+type wuiTheme struct {
+	value string
+	label string
+}
+
+// wuiThemes are the daisyUI themes bundled with the WUI, the first entry follows the
+// operating system light or dark preference
+var wuiThemes = []wuiTheme{
+	{"", "System"},
+	{"light", "Light"},
+	{"dark", "Dark"},
+	{"dim", "Dim"},
+	{"night", "Night"},
+	{"nord", "Nord"},
+	{"corporate", "Corporate"},
+	{"business", "Business"},
+	{"winter", "Winter"},
+	{"forest", "Forest"},
+	{"dracula", "Dracula"},
+	{"retro", "Retro"},
+	{"synthwave", "Synthwave"},
+	{"cupcake", "Cupcake"},
+}
+
+func (w WUI) wuiSettingsPageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiSettingsMain(),
+	)
+	w.basePage(ctx, "settings", content, nil).Render(wr)
+}
+
+// wuiSettingsMain holds the preferences kept by the browser, theme-change.js saves the
+// selected theme to local storage and mason-theme.js applies it on every page
+func (w WUI) wuiSettingsMain() g.Node {
+	return grid("",
+		wuiCard("Theme",
+			h.Div(
+				wuiFormInput("Theme",
+					h.Select(
+						h.Class("select select-bordered"),
+						h.DataAttr("choose-theme", ""),
+						g.Group(g.Map(wuiThemes, func(t wuiTheme) g.Node {
+							return h.Option(h.Value(t.value), g.Text(t.label))
+						})),
+					),
+				),
+				h.P(
+					h.Class("text-sm opacity-70"),
+					g.Text("Saved in this browser. Charts switch to a dark palette with "+
+						"dark themes the next time they are drawn."),
+				),
+			),
+		),
+	)
+}
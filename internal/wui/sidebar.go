@@ -10,6 +10,7 @@ import (
 	"strconv"
 
 	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
 	h "github.com/maragudk/gomponents/html"
 )
 
@@ -102,27 +103,50 @@ func (w WUI) sideBarContent(ctx context.Context, selected string) g.Node {
 		h.Label(h.For("my-drawer"), h.Class("drawer-overlay")),
 		h.Nav(
 			// h.Class("flex min-h-screen w-72 flex-col gap-2 overflow-y-auto bg-base-100 px-6 py-10"),
-			h.Class("flex min-h-screen flex-col gap-2 overflow-y-auto bg-base-100"),
+			h.Class("flex min-h-screen w-72 flex-col gap-2 overflow-y-auto bg-base-100 lg:w-auto"),
 			h.Div(
 				h.Class("mx-4 my-4 flex items-center gap-2 font-black"),
 				g.Text("Mason"),
 			),
+			h.Div(
+				hx.Get(urlApiSite),
+				hx.Trigger("load"),
+				hx.Swap("outerHTML"),
+			),
 			h.Ul(
 				h.Class("menu"),
 				sideBarLink("Dashboard", selected, urlRoot, svgModernHome),
 				sideBarLinkDevices(len(w.m.ListDevices(ctx)), selected),
+				sideBarLinkReview(len(w.m.ReviewQueue(ctx)), selected),
 				sideBarLink("Networks", selected, urlNetworks, svgWifi),
+				sideBarLink("Sites", selected, urlSites, svgMapPin),
+				sideBarLink("Topology", selected, urlTopology, svgRectangleGroup),
+				sideBarLink("Internet", selected, urlInternet, svgBarChart),
+				sideBarLink("Compare", selected, urlCompare, svgArrowTrendingUp),
+				sideBarLink("Checks", selected, urlHTTPChecks, svgShieldExclamation),
+				sideBarLink("IPAM", selected, urlIpam, svgSquares),
+				sideBarLink("Tags", selected, urlTags, svgTag),
+				sideBarLink("Maintenance", selected, urlMaintenance, svgClock),
+				sideBarLink("Quotas", selected, urlQuotas, svgBarChart),
+				sideBarLink("Exclusions", selected, urlExclusions, svgShieldExclamation),
+				sideBarLink("Dependencies", selected, urlDependencies, svgShare),
+				sideBarLink("Port Profiles", selected, urlPortProfiles, svgQueueList),
 				sideBarSubsection(
 					"Tools", svgWrenchScrewdriver,
 					// sideBarLink("Investigator", selected, urlInvestigator, svgFingerPrint),
 					sideBarLink("Ping", selected, urlPing, svgCursorArrowRipple),
 					sideBarLink("Traceroute", selected, urlTraceroute, svgArrowTrendingUp),
 					sideBarLink("TLS", selected, urlTLS, svgLockClosed),
+					sideBarLink("CIDR", selected, urlCidr, svgSquares),
+					sideBarLink("Capture", selected, urlCapture, svgMagnifyGlass),
 				),
 				sideBarSubsection(
 					"System", svgAdjustmentVertical,
+					sideBarLink("Settings", selected, urlSettings, svgSwatch),
 					sideBarLink("Config", selected, urlConfig, svgCog),
 					sideBarLink("Internals", selected, urlInternals, svgEye),
+					sideBarLink("Event Log", selected, urlEventLog, svgQueueList),
+					sideBarLink("Deleted", selected, urlDeleted, svgTrash),
 				),
 			),
 		),
@@ -0,0 +1,224 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	wuiSitesFormName        = "name"
+	wuiSitesFormDescription = "description"
+
+	// wuiSiteParam limits a list page to a site for one request, the site selector keeps the
+	// choice in the wuiSiteCookie for every page
+	wuiSiteParam  = "site"
+	wuiSiteCookie = "mason-site"
+)
+
+// wuiSelectedSite returns the site the lists are limited to, blank for all sites
+func wuiSelectedSite(r *http.Request) string {
+	if site := r.URL.Query().Get(wuiSiteParam); site != "" {
+		return site
+	}
+	return wuiSiteFromCookie(r)
+}
+
+func wuiSiteFromCookie(r *http.Request) string {
+	c, err := r.Cookie(wuiSiteCookie)
+	if err != nil {
+		return ""
+	}
+	site, err := url.QueryUnescape(c.Value)
+	if err != nil {
+		return ""
+	}
+	return site
+}
+
+func (w WUI) wuiSitesPageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiSitesMain(ctx, nil),
+	)
+	w.basePage(ctx, "sites", content, nil).Render(wr)
+}
+
+func (w WUI) wuiApiSiteCreate(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	err := w.m.SaveSite(ctx, model.Site{
+		Name:        r.PostFormValue(wuiSitesFormName),
+		Description: r.PostFormValue(wuiSitesFormDescription),
+	})
+	w.wuiSitesMain(ctx, err).Render(wr)
+}
+
+func (w WUI) wuiApiSiteDelete(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	err := w.m.RemoveSite(ctx, r.PostFormValue(wuiSitesFormName))
+	w.wuiSitesMain(ctx, err).Render(wr)
+}
+
+// wuiApiSiteSelectorHandler renders the site selector for the sidebar
+func (w WUI) wuiApiSiteSelectorHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	w.siteSelector(ctx, wuiSiteFromCookie(r)).Render(wr)
+}
+
+// wuiApiSiteSelectHandler remembers the selected site in a cookie and has htmx reload the
+// page so every list on it follows the new site
+func (w WUI) wuiApiSiteSelectHandler(wr http.ResponseWriter, r *http.Request) {
+	c := &http.Cookie{
+		Name:     wuiSiteCookie,
+		Value:    url.QueryEscape(r.PostFormValue(wuiSiteParam)),
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if c.Value == "" {
+		c.MaxAge = -1
+	}
+	http.SetCookie(wr, c)
+	wr.Header().Set("HX-Refresh", "true")
+	wr.WriteHeader(http.StatusNoContent)
+}
+
+func (w WUI) siteSelector(ctx context.Context, selected string) g.Node {
+	sites, err := w.m.ListSites(ctx)
+	if err != nil {
+		return errAlert(err)
+	}
+	return h.FormEl(
+		h.Class("mx-4"),
+		hx.Post(urlApiSite),
+		hx.Trigger("change"),
+		h.Select(
+			h.Name(wuiSiteParam),
+			h.Class("select select-bordered select-sm w-full"),
+			siteOptions(sites, selected, "All sites"),
+		),
+	)
+}
+
+func (w WUI) wuiSitesMain(ctx context.Context, err error) g.Node {
+	summaries, lerr := w.m.SiteSummaries(ctx)
+	if err == nil {
+		err = lerr
+	}
+	local := w.m.LocalSite()
+	return grid("sitescontent",
+		wuiCard("Sites",
+			wuiTable(
+				[]string{
+					"Name", "Description", "Networks", "Devices", "Online",
+					"Avg Ping", "Flow Recv", "Flow Xmit", " ",
+				},
+				g.Group(g.Map(summaries, func(ss model.SiteSummary) g.Node {
+					return siteSummaryToTD(ss, ss.Site.Name == local)
+				})),
+			),
+		),
+		wuiCard("Add / Update Site",
+			h.Div(
+				errAlert(err),
+				h.FormEl(
+					hx.Post(urlApiSites),
+					hx.Target("#sitescontent"),
+					hx.Swap("outerHTML"),
+					h.Div(
+						h.Class("form-control"),
+						wuiFormInput("Name",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiSitesFormName),
+								h.Placeholder("branch-office"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Description",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiSitesFormDescription),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+					),
+					wuiFormButton("Save Site"),
+				),
+			),
+		),
+	)
+}
+
+func siteSummaryToTD(ss model.SiteSummary, local bool) g.Node {
+	name := ss.Site.Name
+	if local {
+		name += " (local)"
+	}
+	ping := ""
+	if ss.Online > 0 {
+		ping = fmtDur(ss.PingMean)
+	}
+	return h.Tr(
+		h.Td(g.Text(name)),
+		h.Td(g.Text(ss.Site.Description)),
+		h.Td(
+			h.A(
+				h.Href(urlNetworks+"?"+wuiSiteParam+"="+url.QueryEscape(ss.Site.Name)),
+				h.Class("link"),
+				g.Text(strconv.Itoa(ss.Networks)),
+			),
+		),
+		h.Td(
+			h.A(
+				h.Href(urlDevices+"?"+wuiSiteParam+"="+url.QueryEscape(ss.Site.Name)),
+				h.Class("link"),
+				g.Text(strconv.Itoa(ss.Devices)),
+			),
+		),
+		h.Td(g.Text(strconv.Itoa(ss.Online))),
+		h.Td(g.Text(ping)),
+		h.Td(g.Text(humanize.Bytes(uint64(ss.RecvBytes)))),
+		h.Td(g.Text(humanize.Bytes(uint64(ss.XmitBytes)))),
+		h.Td(
+			g.If(!local,
+				h.FormEl(
+					hx.Post(urlApiSites+"/delete"),
+					hx.Target("#sitescontent"),
+					hx.Swap("outerHTML"),
+					hx.Confirm("Delete the site "+ss.Site.Name+"?"),
+					h.Input(h.Type("hidden"), h.Name(wuiSitesFormName), h.Value(ss.Site.Name)),
+					h.Button(h.Class("btn btn-xs"), g.Text("Delete")),
+				),
+			),
+		),
+	)
+}
+
+// siteOptions lists the sites for a select, the first option is labelled with blank
+func siteOptions(sites []model.Site, selected string, blank string) g.Node {
+	return g.Group([]g.Node{
+		h.Option(h.Value(""), g.If(selected == "", h.Selected()), g.Text(blank)),
+		g.Group(g.Map(sites, func(s model.Site) g.Node {
+			return h.Option(
+				h.Value(s.Name),
+				g.If(s.Name == selected, h.Selected()),
+				g.Text(s.Name),
+			)
+		})),
+	})
+}
@@ -29,26 +29,35 @@ func (w WUI) wuiConfigPageHandler(wr http.ResponseWriter, r *http.Request) {
 
 func (w WUI) wuiConfigMain() g.Node {
 	return grid("",
-		wuiCard("Theme",
-			h.Div(
-				h.Button(
-					h.DataAttr("act-class", "shadow-outline"),
-					h.DataAttr("set-theme", ""),
-					h.Class("btn"),
-					g.Text("Default"),
+		wuiCard("Enrichments",
+			enrichmentStatusTable(w.m.GetEnrichmentStatus()),
+		),
+		wuiCard("Export",
+			h.Form(
+				h.Action(urlApiExport),
+				h.Method("get"),
+				h.Label(
+					h.Class("label cursor-pointer"),
+					h.Span(h.Class("label-text"), g.Text("Anonymize")),
+					h.Input(
+						h.Type("checkbox"),
+						h.Name("anonymize"),
+						h.Value("true"),
+						h.Class("checkbox"),
+					),
 				),
-				h.Button(
-					h.DataAttr("act-class", "shadow-outline"),
-					h.DataAttr("set-theme", "dark"),
-					h.Class("btn"),
-					g.Text("Dark"),
+				wuiFormInput("Key (optional, reuse to correlate exports)",
+					h.Input(h.Type("text"), h.Name("key"), h.Class("input input-bordered")),
 				),
-				h.Button(
-					h.DataAttr("act-class", "shadow-outline"),
-					h.DataAttr("set-theme", "light"),
-					h.Class("btn"),
-					g.Text("Light"),
+				wuiFormInput("Format",
+					h.Select(
+						h.Name("format"),
+						h.Class("select select-bordered"),
+						h.Option(h.Value("json"), g.Text("Inventory (json)")),
+						h.Option(h.Value("csv"), g.Text("Asset register (csv)")),
+					),
 				),
+				wuiFormButton("Download"),
 			),
 		),
 		wuiCard("Config",
@@ -57,6 +66,23 @@ func (w WUI) wuiConfigMain() g.Node {
 	)
 }
 
+func enrichmentStatusTable(stats []server.EnrichmentStatus) g.Node {
+	return wuiTable(
+		[]string{"Name", "Status", "Reason"},
+		g.Group(g.Map(stats, func(es server.EnrichmentStatus) g.Node {
+			status := h.Span(h.Class("badge badge-success"), g.Text("ok"))
+			if es.Degraded {
+				status = h.Span(h.Class("badge badge-warning"), g.Text("degraded"))
+			}
+			return h.Tr(
+				h.Td(g.Text(es.Name)),
+				h.Td(status),
+				h.Td(g.Text(es.Reason)),
+			)
+		})),
+	)
+}
+
 func configToTable(cfg *server.Config) g.Node {
 	val := reflect.ValueOf(cfg)
 	if val.Kind() == reflect.Pointer {
@@ -9,13 +9,17 @@ import (
 	"fmt"
 	"net/http"
 	"runtime/debug"
+	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/emicklei/tre"
 	g "github.com/maragudk/gomponents"
 	h "github.com/maragudk/gomponents/html"
 
+	"github.com/networkables/mason/internal/asn"
 	"github.com/networkables/mason/internal/bus"
+	"github.com/networkables/mason/internal/netflows"
+	"github.com/networkables/mason/internal/ratelimit"
 	"github.com/networkables/mason/internal/server"
 )
 
@@ -33,6 +37,18 @@ func (w WUI) wuiInternalsMain(ctx context.Context) g.Node {
 	internals := w.m.GetInternalsSnapshot(ctx)
 	return grid("",
 		wuiCard("Mason", masonInternalsToTable(internals)),
+		wuiCard("Network Scans", networkScansToTable(internals.NetworkScans)),
+		wuiCard("Event Bus", busStatsToTable(internals.Bus)),
+		wuiCard("Rate Limits", rateLimitsToTable(internals.RateLimits)),
+		g.If(
+			internals.FlowInserts != nil,
+			wuiCard("Flow Inserts", flowInsertsToTable(internals.FlowInserts)),
+		),
+		wuiCard("Agents", agentsToTable(internals.Agents)),
+		g.If(
+			w.m.GetConfig().Asn.Enabled,
+			wuiCard("ASN Data", asnStatusToTable(internals.Asn, w.m.GetConfig().Asn)),
+		),
 		wuiCard("Errors", wuiErrorsToTable(internals.Errors)),
 		wuiCard("Events", wuiEventsToTable(internals.Events)),
 		wuiCard("Go", goInternalsToTable(internals)),
@@ -61,8 +77,68 @@ func masonInternalsToTable(iv server.MasonInternalsView) g.Node {
 			fmt.Sprintf("%d / %d", iv.PerfPingActive, iv.PingerMaxWorkers),
 		),
 		toTD("PortScan MaxWorkers", fmt.Sprint(iv.PortScanMaxWorkers)),
-		toTD("Current Network Scan", fmt.Sprint(iv.CurrentNetworkScan)),
-		toTD("Bus Back Pressure", fmt.Sprint(iv.BusBackPressure)),
+		toTD(
+			"NetworkScan Workers",
+			fmt.Sprintf("%d / %d", iv.NetworkScanActive, iv.NetworkScanMaxWorkers),
+		),
+	)
+}
+
+func busStatsToTable(stats bus.Stats) g.Node {
+	queued := fmt.Sprintf("%d / %d", stats.Queued, stats.Capacity)
+	if stats.Queued >= stats.Capacity {
+		queued += " (full)"
+	}
+	return wuiTable([]string{"Name", "Value"},
+		toTD("Overflow Policy", string(stats.Policy)),
+		toTD("Queued", queued),
+		toTD("High Water", humanize.Comma(int64(stats.HighWater))),
+		g.If(
+			stats.Policy == bus.OverflowSpill,
+			toTD("Spilled", humanize.Comma(int64(stats.Spilled))),
+		),
+		toTD("Published", humanize.Comma(int64(stats.Published))),
+		toTD("Dispatched", humanize.Comma(int64(stats.Dispatched))),
+		toTD("Dropped", humanize.Comma(int64(stats.Dropped))),
+		g.If(
+			stats.Policy == bus.OverflowBlock,
+			toTD("Blocked Publishes", humanize.Comma(int64(stats.Blocked))),
+		),
+		g.If(stats.SpillErr != "", toTD("Spill Error", stats.SpillErr)),
+	)
+}
+
+func rateLimitsToTable(stats []ratelimit.Stats) g.Node {
+	return wuiTable([]string{"Limiter", "Rate", "Waiting", "Granted"},
+		g.Group(
+			g.Map(stats, func(s ratelimit.Stats) g.Node {
+				rate := "unlimited"
+				if s.Rate > 0 {
+					rate = fmt.Sprintf("%d/s", s.Rate)
+				}
+				return h.Tr(
+					h.Td(g.Text(s.Name)),
+					h.Td(g.Text(rate)),
+					h.Td(g.Text(fmt.Sprint(s.Waiting))),
+					h.Td(g.Text(humanize.Comma(int64(s.Granted)))),
+				)
+			}),
+		),
+	)
+}
+
+func flowInsertsToTable(stats *netflows.InsertStats) g.Node {
+	if stats == nil {
+		return nil
+	}
+	return wuiTable([]string{"Name", "Value"},
+		toTD("Queued", humanize.Comma(stats.Queued)),
+		toTD("Written", humanize.Comma(int64(stats.Written))),
+		toTD("Dropped", humanize.Comma(int64(stats.Dropped))),
+		toTD("Filtered", humanize.Comma(int64(stats.Filtered))),
+		toTD("Failed", humanize.Comma(int64(stats.Failed))),
+		toTD("Batches", humanize.Comma(int64(stats.Batches))),
+		toTD("Last Flush", stats.LastFlush.Round(time.Microsecond).String()),
 	)
 }
 
@@ -188,3 +264,35 @@ func wuiErrorsToTable(errors []bus.HistoricalError) g.Node {
 // 		// ),
 // 	)
 // }
+
+func asnStatusToTable(st asn.Status, cfg *asn.Config) g.Node {
+	state := "not loaded"
+	switch {
+	case st.Refreshing:
+		state = "refreshing"
+	case st.Available:
+		state = "loaded"
+	}
+	built, lastAttempt, lastError := "", "", ""
+	if !st.BuiltAt.IsZero() {
+		built = st.BuiltAt.Format(time.DateTime) + " (" + humanize.Time(st.BuiltAt) + ")"
+	}
+	if !st.LastAttempt.IsZero() {
+		lastAttempt = humanize.Time(st.LastAttempt)
+	}
+	if st.LastError != nil {
+		lastError = st.LastError.Error()
+	}
+	interval := "disabled"
+	if cfg.RefreshInterval > 0 {
+		interval = cfg.RefreshInterval.String()
+	}
+	return wuiTable([]string{"Name", "Value"},
+		toTD("State", state),
+		toTD("Entries", humanize.Comma(int64(st.Entries))),
+		toTD("Dataset Built", built),
+		toTD("Refresh Interval", interval),
+		toTD("Last Refresh", lastAttempt),
+		toTD("Last Refresh Error", lastError),
+	)
+}
@@ -0,0 +1,269 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	wuiTagsFormName             = "name"
+	wuiTagsFormDescription      = "description"
+	wuiTagsFormPingInterval     = "pinginterval"
+	wuiTagsFormPortScanInterval = "portscaninterval"
+)
+
+func (w WUI) wuiTagsPageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiTagsMain(ctx, nil),
+	)
+	w.basePage(ctx, "tags", content, nil).Render(wr)
+}
+
+// wuiApiTagsHandler returns the tag definitions as json
+func (w WUI) wuiApiTagsHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	defs, err := w.m.ListTagDefinitions(ctx)
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	wr.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(wr).Encode(defs)
+	if err != nil {
+		logger.Error("tags encode", "error", err)
+	}
+}
+
+func (w WUI) wuiApiTagCreate(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	def, err := tagDefinitionFromForm(r)
+	if err == nil {
+		err = w.m.SaveTagDefinition(ctx, def)
+	}
+	w.wuiTagsMain(ctx, err).Render(wr)
+}
+
+func (w WUI) wuiApiTagDelete(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	err := w.m.RemoveTagDefinition(ctx, r.PostFormValue(wuiTagsFormName))
+	w.wuiTagsMain(ctx, err).Render(wr)
+}
+
+// wuiApiDeviceTagHandler adds (or with remove=yes removes) a tag on a device and returns to
+// the device page
+func (w WUI) wuiApiDeviceTagHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	id := r.PathValue("id")
+	addr, err := w.m.StringToAddr(id)
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	name := r.PostFormValue(wuiTagsFormName)
+	if r.PostFormValue("remove") == "yes" {
+		err = w.m.UntagDevice(ctx, addr, name)
+	} else {
+		err = w.m.TagDevice(ctx, addr, name)
+	}
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Redirect(wr, r, urlDevice+"/"+id, http.StatusSeeOther)
+}
+
+func tagDefinitionFromForm(r *http.Request) (def model.TagDefinition, err error) {
+	def.Name = r.PostFormValue(wuiTagsFormName)
+	def.Description = r.PostFormValue(wuiTagsFormDescription)
+	def.Policy.PingInterval, err = formDuration(r, wuiTagsFormPingInterval)
+	if err != nil {
+		return def, err
+	}
+	def.Policy.PortScanInterval, err = formDuration(r, wuiTagsFormPortScanInterval)
+	return def, err
+}
+
+func formDuration(r *http.Request, name string) (time.Duration, error) {
+	s := r.PostFormValue(name)
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func (w WUI) wuiTagsMain(ctx context.Context, err error) g.Node {
+	defs, lerr := w.m.ListTagDefinitions(ctx)
+	if err == nil {
+		err = lerr
+	}
+	devs := w.m.ListDevices(ctx)
+	nets := w.m.ListNetworks(ctx)
+
+	rows := make([]g.Node, 0, len(defs))
+	for _, def := range defs {
+		devcount := len(filterDevices(devs, model.TagDeviceFilter(def.Name)))
+		netcount := 0
+		for _, n := range nets {
+			if n.Tags.Has(def.Name) {
+				netcount++
+			}
+		}
+		rows = append(rows, tagDefinitionToTD(def, devcount, netcount))
+	}
+
+	return grid("tagscontent",
+		wuiCard("Tags",
+			wuiTable(
+				[]string{"Name", "Description", "Ping", "Port Scan", "Devices", "Networks", " "},
+				rows...,
+			),
+		),
+		wuiCard("Add / Update Tag",
+			h.Div(
+				errAlert(err),
+				h.FormEl(
+					hx.Post(urlApiTags),
+					hx.Target("#tagscontent"),
+					hx.Swap("outerHTML"),
+					h.Div(
+						h.Class("form-control"),
+						wuiFormInput("Name",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiTagsFormName),
+								h.Placeholder("critical"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Description",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiTagsFormDescription),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Ping Interval",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiTagsFormPingInterval),
+								h.Placeholder("30s (blank for default)"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Port Scan Interval",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiTagsFormPortScanInterval),
+								h.Placeholder("6h (blank for default)"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+					),
+					wuiFormButton("Save Tag"),
+				),
+			),
+		),
+	)
+}
+
+func tagDefinitionToTD(def model.TagDefinition, devcount int, netcount int) g.Node {
+	return h.Tr(
+		h.Td(g.Text(def.Name)),
+		h.Td(g.Text(def.Description)),
+		h.Td(g.Text(policyIntervalString(def.Policy.PingInterval))),
+		h.Td(g.Text(policyIntervalString(def.Policy.PortScanInterval))),
+		h.Td(
+			h.A(
+				h.Href(urlDevices+"?tag="+url.QueryEscape(def.Name)),
+				h.Class("link"),
+				g.Text(strconv.Itoa(devcount)),
+			),
+		),
+		h.Td(
+			h.A(
+				h.Href(urlNetworks+"?tag="+url.QueryEscape(def.Name)),
+				h.Class("link"),
+				g.Text(strconv.Itoa(netcount)),
+			),
+		),
+		h.Td(
+			h.FormEl(
+				hx.Post(urlApiTags+"/delete"),
+				hx.Target("#tagscontent"),
+				hx.Swap("outerHTML"),
+				hx.Confirm("Remove the tag "+def.Name+" from all devices and networks?"),
+				h.Input(h.Type("hidden"), h.Name(wuiTagsFormName), h.Value(def.Name)),
+				h.Button(h.Class("btn btn-xs"), g.Text("Delete")),
+			),
+		),
+	)
+}
+
+func policyIntervalString(d time.Duration) string {
+	if d == 0 {
+		return "default"
+	}
+	return d.String()
+}
+
+// tagLinks renders the tags as badges linking to the list page filtered by the tag
+func tagLinks(base string, tags model.Tags) g.Node {
+	return g.Group(g.Map(tags, func(t model.Tag) g.Node {
+		return h.A(
+			h.Href(base+"?tag="+url.QueryEscape(t.Val)),
+			h.Class("badge badge-outline mr-1"),
+			g.Text(t.Val),
+		)
+	}))
+}
+
+// deviceTagsForm lists the tags of the device with remove buttons and a form to add a tag
+func deviceTagsForm(d model.Device) g.Node {
+	action := urlApiDevice + "/" + d.Addr.String() + "/tags"
+	return h.Div(
+		h.Div(
+			h.Class("flex flex-wrap gap-2"),
+			g.Group(g.Map(d.Meta.Tags, func(t model.Tag) g.Node {
+				return h.FormEl(
+					h.Action(action),
+					h.Method("post"),
+					h.Input(h.Type("hidden"), h.Name(wuiTagsFormName), h.Value(t.Val)),
+					h.Input(h.Type("hidden"), h.Name("remove"), h.Value("yes")),
+					h.Button(
+						h.Class("badge badge-outline gap-1"),
+						g.Text(t.Val+" ✕"),
+					),
+				)
+			})),
+		),
+		h.FormEl(
+			h.Action(action),
+			h.Method("post"),
+			h.Class("flex gap-4 py-4"),
+			h.Input(
+				h.Type("text"),
+				h.Name(wuiTagsFormName),
+				h.Placeholder("tag"),
+				h.Class("input input-bordered grow"),
+			),
+			h.Button(h.Class("btn btn-primary"), g.Text("Add Tag")),
+		),
+	)
+}
@@ -0,0 +1,218 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	wuiCaptureFormInterface = "interface"
+	wuiCaptureFormFilter    = "filter"
+	wuiCaptureFormDuration  = "duration"
+	wuiCaptureFormMaxSize   = "maxsize"
+)
+
+// captureURL links to the capture page with the filter filled in, used to start a capture of
+// the traffic of a device
+func captureURL(filter string) string {
+	return urlCapture + "?" + url.Values{wuiCaptureFormFilter: {filter}}.Encode()
+}
+
+func (w WUI) wuiToolCaptureHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiToolCapture(ctx, r.FormValue(wuiCaptureFormFilter), nil),
+	)
+	w.basePage(ctx, "capture", content, nil).Render(wr)
+}
+
+func (w WUI) wuiApiCaptureStart(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	req, err := captureRequestFromForm(r)
+	if err == nil {
+		_, err = w.m.StartCapture(ctx, req)
+	}
+	w.wuiToolCapture(ctx, req.Filter, err).Render(wr)
+}
+
+func (w WUI) wuiApiCapturesHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	w.wuiCaptures(ctx, nil).Render(wr)
+}
+
+func (w WUI) wuiApiCaptureStop(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	err := w.m.StopCapture(ctx, r.PathValue("id"))
+	w.wuiCaptures(ctx, err).Render(wr)
+}
+
+func (w WUI) wuiApiCaptureDelete(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	err := w.m.RemoveCapture(ctx, r.PathValue("id"))
+	w.wuiCaptures(ctx, err).Render(wr)
+}
+
+// wuiApiCaptureFile downloads the pcap file of a finished capture
+func (w WUI) wuiApiCaptureFile(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	c, f, err := w.m.OpenCapture(ctx, r.PathValue("id"))
+	switch {
+	case errors.Is(err, model.ErrCaptureDoesNotExist):
+		http.Error(wr, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, model.ErrCaptureStillRunning):
+		http.Error(wr, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(wr, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	wr.Header().Set("Content-Type", "application/vnd.tcpdump.pcap")
+	wr.Header().Set("Content-Disposition", "attachment; filename=\""+c.Filename()+"\"")
+	_, err = io.Copy(wr, f)
+	if err != nil {
+		logger.Error("capture download", "id", c.ID, "error", err)
+	}
+}
+
+func captureRequestFromForm(r *http.Request) (req model.PacketCaptureRequest, err error) {
+	req.Interface = r.PostFormValue(wuiCaptureFormInterface)
+	req.Filter = r.PostFormValue(wuiCaptureFormFilter)
+	req.Duration, err = formDuration(r, wuiCaptureFormDuration)
+	if err != nil {
+		return req, err
+	}
+	if s := r.PostFormValue(wuiCaptureFormMaxSize); s != "" {
+		req.MaxBytes, err = model.ParseQuotaBytes(s)
+	}
+	return req, err
+}
+
+func (w WUI) wuiToolCapture(ctx context.Context, filter string, err error) g.Node {
+	cfg := w.m.GetConfig().Capture
+	return grid("capturecontent",
+		wuiCard("Packet Capture",
+			h.Div(
+				errAlert(err),
+				g.If(!cfg.Enabled, h.P(g.Text("Packet capture is disabled, enable with --capture.enabled"))),
+				h.FormEl(
+					hx.Post(urlApiCaptures),
+					hx.Target("#capturecontent"),
+					hx.Swap("outerHTML"),
+					h.Div(
+						h.Class("form-control"),
+						wuiFormInput("Interface",
+							h.Select(
+								h.Name(wuiCaptureFormInterface),
+								h.Class("select select-bordered w-full md:w-1/2"),
+								g.Group(g.Map(w.m.CaptureInterfaces(ctx), func(name string) g.Node {
+									return h.Option(h.Value(name), g.Text(name))
+								})),
+							),
+						),
+						wuiFormInput("Filter",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiCaptureFormFilter),
+								h.Value(filter),
+								h.Placeholder("host 192.168.1.20 and not port 22"),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Duration",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiCaptureFormDuration),
+								h.Placeholder(cfg.MaxDuration.String()),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput("Max Size",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiCaptureFormMaxSize),
+								h.Placeholder(cfg.MaxSize),
+								h.Class("input input-bordered w-full md:w-1/2"),
+							),
+						),
+					),
+					wuiFormButton("Start Capture"),
+				),
+			),
+		),
+		wuiCard("Captures", w.wuiCaptures(ctx, nil)),
+	)
+}
+
+// wuiCaptures lists the captures, refreshing itself while the page is open
+func (w WUI) wuiCaptures(ctx context.Context, err error) g.Node {
+	captures, lerr := w.m.ListCaptures(ctx)
+	if err == nil {
+		err = lerr
+	}
+	return h.Div(
+		h.ID("captures"),
+		hx.Get(urlApiCaptures),
+		hx.Trigger("every 2s"),
+		hx.Swap("outerHTML"),
+		errAlert(err),
+		wuiTable(
+			[]string{"ID", "Interface", "Filter", "Started", "State", "Packets", "Size", " "},
+			g.Group(g.Map(captures, captureToTD)),
+		),
+	)
+}
+
+func captureToTD(c model.PacketCapture) g.Node {
+	action := func(name string, label string) g.Node {
+		return h.Button(
+			h.Class("btn btn-xs"),
+			hx.Post(urlApiCaptures+"/"+url.PathEscape(c.ID)+"/"+name),
+			hx.Target("#captures"),
+			hx.Swap("outerHTML"),
+			g.Text(label),
+		)
+	}
+	state := h.Span(h.Class("badge"), g.Text(string(c.State)))
+	if c.Err != "" {
+		state = h.Span(h.Class("badge badge-error"), h.Title(c.Err), g.Text(string(c.State)))
+	}
+	return h.Tr(
+		h.Td(g.Text(c.ID)),
+		h.Td(g.Text(c.Interface)),
+		h.Td(g.Text(c.Filter)),
+		h.Td(g.Text(c.Started.Local().Format(time.DateTime))),
+		h.Td(state),
+		h.Td(g.Text(strconv.Itoa(c.Packets))),
+		h.Td(g.Text(c.Size())),
+		h.Td(
+			g.If(c.State == model.CaptureRunning, action("stop", "Stop")),
+			g.If(c.State != model.CaptureRunning, g.Group([]g.Node{
+				h.A(
+					h.Class("btn btn-xs"),
+					h.Href(urlApiCaptures+"/"+url.PathEscape(c.ID)+"/file"),
+					g.Text("Download"),
+				),
+				action("delete", "Delete"),
+			})),
+		),
+	)
+}
@@ -0,0 +1,224 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+	g "github.com/maragudk/gomponents"
+	hx "github.com/maragudk/gomponents-htmx"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/nettools"
+)
+
+const (
+	wuiToolCidrSplit   = "split"
+	wuiToolCidrNetwork = "network"
+)
+
+func (w WUI) wuiToolCidrHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiToolCidr("", nil, nil, nil, nil),
+	)
+	w.basePage(ctx, "cidr", content, nil).Render(wr)
+}
+
+// wuiToolCidr shows the calculator form and, once a prefix is posted, its addresses, the
+// suggested splits and the subnets of the chosen split
+func (w WUI) wuiToolCidr(
+	target string,
+	info *nettools.CIDR,
+	subnets []netip.Prefix,
+	added g.Node,
+	err error,
+) g.Node {
+	return grid("cidrcontent",
+		wuiCard("CIDR Calculator",
+			h.Div(
+				errAlert(err),
+				added,
+				h.FormEl(
+					hx.Post(urlApiCidr),
+					hx.Target("#cidrcontent"),
+					hx.Swap("outerHTML"),
+					h.Div(
+						h.Class("form-control"),
+						wuiFormInput(
+							"Prefix",
+							h.Input(
+								h.Type("text"),
+								h.Name(wuiToolTarget),
+								h.Value(target),
+								h.Placeholder("192.168.1.0/24"),
+								h.Class("input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormInput(
+							"Split Into",
+							h.Input(
+								h.Type("number"),
+								h.Name(wuiToolCidrSplit),
+								h.Placeholder("prefix length, optional"),
+								h.Class("input-bordered w-full md:w-1/2"),
+							),
+						),
+						wuiFormButton("Calculate"),
+					),
+				),
+			),
+		),
+		wuiCidrResultTable(info),
+		wuiCidrSplitsTable(info),
+		wuiCidrSubnetsTable(info, subnets),
+	)
+}
+
+func (w WUI) wuiApiToolCidrHandler(wr http.ResponseWriter, r *http.Request) {
+	target := r.PostFormValue(wuiToolTarget)
+	info, subnets, err := cidrCalculate(target, r.PostFormValue(wuiToolCidrSplit))
+	if err != nil {
+		logger.Error("wuiApiToolCidrHandler", "error", err)
+	}
+	w.wuiToolCidr(target, info, subnets, nil, err).Render(wr)
+}
+
+// wuiApiToolCidrNetworkHandler adds the posted prefix as a network without scanning it, the
+// calculation it was picked from is shown again
+func (w WUI) wuiApiToolCidrNetworkHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	target := r.PostFormValue(wuiToolTarget)
+	info, subnets, err := cidrCalculate(target, r.PostFormValue(wuiToolCidrSplit))
+	if err != nil {
+		w.wuiToolCidr(target, info, subnets, nil, err).Render(wr)
+		return
+	}
+	n, err := w.m.CreateNetwork(ctx, "", r.PostFormValue(wuiToolCidrNetwork), false)
+	if err != nil {
+		logger.Error("wuiApiToolCidrNetworkHandler", "error", err)
+		w.wuiToolCidr(target, info, subnets, nil, err).Render(wr)
+		return
+	}
+	added := h.Div(
+		h.Class("py-2"),
+		successAlert("Added network "+n.Name),
+		h.A(h.Class("link"), h.Href(networkURL(n)), g.Text("View "+n.Prefix.String())),
+	)
+	w.wuiToolCidr(target, info, subnets, added, nil).Render(wr)
+}
+
+// cidrCalculate describes the target prefix and lists its subnets when a split prefix length
+// is given
+func cidrCalculate(target string, split string) (*nettools.CIDR, []netip.Prefix, error) {
+	info, err := nettools.ParseCIDR(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	if split == "" {
+		return &info, nil, nil
+	}
+	bits, err := strconv.Atoi(split)
+	if err != nil {
+		return &info, nil, err
+	}
+	subnets, err := nettools.SplitPrefix(info.Prefix, bits)
+	return &info, subnets, err
+}
+
+func wuiCidrResultTable(info *nettools.CIDR) g.Node {
+	if info == nil {
+		return nil
+	}
+	return wuiCard("Prefix "+info.Prefix.String(),
+		h.Div(
+			wuiTable([]string{" ", " "},
+				toTD("Network", info.Network.String()),
+				g.If(info.Broadcast.IsValid(), toTD("Broadcast", info.Broadcast.String())),
+				g.If(info.Netmask.IsValid(), toTD("Netmask", info.Netmask.String())),
+				g.If(info.Wildcard.IsValid(), toTD("Wildcard", info.Wildcard.String())),
+				toTD("First Usable", info.FirstUsable.String()),
+				toTD("Last Usable", info.LastUsable.String()),
+				toTD("Addresses", humanize.BigComma(info.Addresses)),
+				toTD("Usable", humanize.BigComma(info.Usable)),
+			),
+			h.Div(
+				h.Class("flex justify-end py-4"),
+				cidrAddNetworkButton(info.Prefix, info.Prefix, 0),
+			),
+		),
+	)
+}
+
+// wuiCidrSplitsTable lists the suggested splits, picking one lists its subnets
+func wuiCidrSplitsTable(info *nettools.CIDR) g.Node {
+	if info == nil || len(info.Splits) == 0 {
+		return nil
+	}
+	return wuiCard("Suggested Splits",
+		wuiTable([]string{"Prefix Length", "Subnets", "Usable Each", " "},
+			g.Group(g.Map(info.Splits, func(s nettools.CIDRSplit) g.Node {
+				return h.Tr(
+					h.Td(g.Text("/"+strconv.Itoa(s.Bits))),
+					h.Td(g.Text(humanize.BigComma(s.Subnets))),
+					h.Td(g.Text(humanize.BigComma(s.Usable))),
+					h.Td(
+						h.FormEl(
+							hx.Post(urlApiCidr),
+							hx.Target("#cidrcontent"),
+							hx.Swap("outerHTML"),
+							h.Input(h.Type("hidden"), h.Name(wuiToolTarget), h.Value(info.Prefix.String())),
+							h.Input(h.Type("hidden"), h.Name(wuiToolCidrSplit), h.Value(strconv.Itoa(s.Bits))),
+							h.Button(h.Class("btn btn-sm"), g.Text("Show Subnets")),
+						),
+					),
+				)
+			})),
+		),
+	)
+}
+
+func wuiCidrSubnetsTable(info *nettools.CIDR, subnets []netip.Prefix) g.Node {
+	if info == nil || len(subnets) == 0 {
+		return nil
+	}
+	bits := subnets[0].Bits()
+	return wuiCard("Subnets of "+info.Prefix.String()+" as /"+strconv.Itoa(bits),
+		wuiTable([]string{"Subnet", "First Usable", "Last Usable", " "},
+			g.Group(g.Map(subnets, func(p netip.Prefix) g.Node {
+				sub := nettools.DescribePrefix(p)
+				return h.Tr(
+					h.Td(g.Text(p.String())),
+					h.Td(g.Text(sub.FirstUsable.String())),
+					h.Td(g.Text(sub.LastUsable.String())),
+					h.Td(cidrAddNetworkButton(info.Prefix, p, bits)),
+				)
+			})),
+		),
+	)
+}
+
+// cidrAddNetworkButton adds the prefix as a network, the calculated prefix and split are
+// posted along so the results stay on the page
+func cidrAddNetworkButton(target netip.Prefix, p netip.Prefix, split int) g.Node {
+	return h.FormEl(
+		hx.Post(urlApiCidrNetwork),
+		hx.Target("#cidrcontent"),
+		hx.Swap("outerHTML"),
+		h.Input(h.Type("hidden"), h.Name(wuiToolTarget), h.Value(target.String())),
+		g.If(
+			split > 0,
+			h.Input(h.Type("hidden"), h.Name(wuiToolCidrSplit), h.Value(strconv.Itoa(split))),
+		),
+		h.Input(h.Type("hidden"), h.Name(wuiToolCidrNetwork), h.Value(p.String())),
+		h.Button(h.Class("btn btn-primary btn-sm"), g.Text("Add as Network")),
+	)
+}
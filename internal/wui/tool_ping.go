@@ -10,7 +10,6 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/charmbracelet/log"
 	g "github.com/maragudk/gomponents"
 	hx "github.com/maragudk/gomponents-htmx"
 	h "github.com/maragudk/gomponents/html"
@@ -110,7 +109,7 @@ func (w WUI) wuiToolPing(
 								h.Name(wuiToolTarget),
 								inValue,
 								h.Placeholder("192.168.1.1 or host.name"),
-								h.Class("input-bordered w-1/2"),
+								h.Class("input-bordered w-full md:w-1/2"),
 							),
 						),
 						wuiFormButton("Ping"),
@@ -130,7 +129,7 @@ func (w WUI) wuiApiToolPingHandler(wr http.ResponseWriter, r *http.Request) {
 	priviledged := false
 	stats, err := w.m.IcmpPing(ctx, target, count, timeout, priviledged)
 	if err != nil {
-		log.Error("wuiApiToolPingHandler", "error", err)
+		logger.Error("wuiApiToolPingHandler", "error", err)
 	}
 	mac, _ := w.m.ArpPing(ctx, target, timeout)
 	w.wuiToolPing(&stats, &mac, err).Render(wr)
@@ -9,7 +9,6 @@ import (
 	"fmt"
 	"net/http"
 
-	"github.com/charmbracelet/log"
 	g "github.com/maragudk/gomponents"
 	hx "github.com/maragudk/gomponents-htmx"
 	h "github.com/maragudk/gomponents/html"
@@ -44,7 +43,7 @@ func (w WUI) wuiToolTLS(info *nettools.TLS, err error) g.Node {
 								h.Type("text"),
 								h.Name(wuiToolTarget),
 								h.Placeholder("https://host.name"),
-								h.Class("input-bordered w-1/2"),
+								h.Class("input-bordered w-full md:w-1/2"),
 							),
 						),
 						wuiFormButton("Fetch TLS"),
@@ -61,7 +60,7 @@ func (w WUI) wuiApiToolTLSHandler(wr http.ResponseWriter, r *http.Request) {
 	target := r.PostFormValue(wuiToolTarget)
 	info, err := w.m.FetchTLSInfo(ctx, target)
 	if err != nil {
-		log.Error("wuiApiToolTLSHandler", "error", err)
+		logger.Error("wuiApiToolTLSHandler", "error", err)
 	}
 	w.wuiToolTLS(&info, err).Render(wr)
 }
@@ -9,7 +9,6 @@ import (
 	"fmt"
 	"net/http"
 
-	"github.com/charmbracelet/log"
 	g "github.com/maragudk/gomponents"
 	hx "github.com/maragudk/gomponents-htmx"
 	h "github.com/maragudk/gomponents/html"
@@ -44,7 +43,7 @@ func (w WUI) wuiToolTraceroute(tr []nettools.Icmp4EchoResponseStatistics, err er
 								h.Type("text"),
 								h.Name(wuiToolTarget),
 								h.Placeholder("192.168.1.1 or host.name"),
-								h.Class("input-bordered w-1/2"),
+								h.Class("input-bordered w-full md:w-1/2"),
 							),
 						),
 						wuiFormButton("Traceroute"),
@@ -61,7 +60,7 @@ func (w WUI) wuiApiToolTracerouteHandler(wr http.ResponseWriter, r *http.Request
 	target := r.PostFormValue(wuiToolTarget)
 	tr, err := w.m.Traceroute(ctx, target)
 	if err != nil {
-		log.Error("wuiApiToolTracerouteHandler", "error", err)
+		logger.Error("wuiApiToolTracerouteHandler", "error", err)
 	}
 	w.wuiToolTraceroute(tr, err).Render(wr)
 }
@@ -0,0 +1,170 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package wui
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+	g "github.com/maragudk/gomponents"
+	h "github.com/maragudk/gomponents/html"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+const (
+	// wuiTopologyNodeSpacing is the distance between the nodes of a layer
+	wuiTopologyNodeSpacing = 120
+	// wuiTopologyLayerSpacing is the distance between the layers
+	wuiTopologyLayerSpacing = 140
+)
+
+var wuiTopologyKinds = []model.TopologyNodeKind{
+	model.TopologyMason,
+	model.TopologyRouter,
+	model.TopologyNetwork,
+	model.TopologyDevice,
+	model.TopologyMore,
+}
+
+func (w WUI) wuiTopologyPageHandler(wr http.ResponseWriter, r *http.Request) {
+	ctx := context.TODO()
+	content := h.Main(
+		h.ID("maincontent"),
+		h.Class("drawer-content"),
+		w.wuiTopologyMain(ctx),
+	)
+	extra := h.Script(h.Src("/static/javascript/echarts.min.js"))
+	w.basePage(ctx, "topology", content, extra).Render(wr)
+}
+
+// wuiTopologyMain draws the networks layered under the routers the traces went through, the
+// traces are listed below so the path to each network can be read off
+func (w WUI) wuiTopologyMain(ctx context.Context) g.Node {
+	cfg := w.m.GetConfig()
+	topo := w.m.Topology(ctx)
+	var note g.Node
+	switch {
+	case !cfg.Topology.Enabled:
+		note = g.Text("Topology tracing is disabled, all networks are shown attached to mason.")
+	case !cfg.Discovery.Icmp.Privileged:
+		note = g.Text("Topology tracing requires privileged icmp, " +
+			"all networks are shown attached to mason.")
+	case len(topo.Traces) == 0:
+		note = g.Text("No network has been traced yet.")
+	}
+	return grid(
+		"",
+		g.If(note != nil, widecard("Topology", h.Div(h.Class("alert"), note))),
+		graphcard("Topology", topologyGraph(topo)),
+		g.If(len(topo.Traces) > 0, widecard("Traces", topologyTraceTable(topo.Traces))),
+	)
+}
+
+func topologyTraceTable(traces []model.TopologyTrace) g.Node {
+	return wuiTable(
+		[]string{"Network", "Traced Device", "Routers", "Traced"},
+		g.Group(g.Map(traces, func(t model.TopologyTrace) g.Node {
+			routers := make([]string, 0, len(t.Hops))
+			for _, r := range t.Routers() {
+				routers = append(routers, r.String())
+			}
+			return h.Tr(
+				h.Td(g.Text(t.Network.String())),
+				h.Td(deviceLink(t.Target)),
+				h.Td(g.Text(strings.Join(routers, " → "))),
+				h.Td(g.Text(humanize.Time(t.Traced))),
+			)
+		})),
+	)
+}
+
+// topologyGraph places each layer on its own row, centered above the widest one.  Node names
+// are made unique since the chart joins the edges by name.
+func topologyGraph(topo model.Topology) g.Node {
+	layers := topo.Layers()
+	widest := 1
+	for _, layer := range layers {
+		widest = max(widest, len(layer))
+	}
+
+	names := make(map[string]string, len(topo.Nodes))
+	taken := make(map[string]bool, len(topo.Nodes))
+	nodes := make([]opts.GraphNode, 0, len(topo.Nodes))
+	for depth, layer := range layers {
+		offset := float32(widest-len(layer)) * wuiTopologyNodeSpacing / 2
+		for idx, n := range layer {
+			name := n.Label
+			if name == "" || taken[name] {
+				name = n.Label + " [" + n.ID + "]"
+			}
+			taken[name] = true
+			names[n.ID] = name
+			nodes = append(nodes, opts.GraphNode{
+				Name:       name,
+				X:          offset + float32(idx)*wuiTopologyNodeSpacing,
+				Y:          float32(depth) * wuiTopologyLayerSpacing,
+				Fixed:      opts.Bool(true),
+				Category:   topologyCategory(n.Kind),
+				SymbolSize: topologySymbolSize(n.Kind),
+			})
+		}
+	}
+	links := make([]opts.GraphLink, 0, len(topo.Edges))
+	for _, e := range topo.Edges {
+		links = append(links, opts.GraphLink{Source: names[e.From], Target: names[e.To]})
+	}
+	categories := make([]*opts.GraphCategory, 0, len(wuiTopologyKinds))
+	for _, kind := range wuiTopologyKinds {
+		categories = append(categories, &opts.GraphCategory{Name: string(kind)})
+	}
+
+	graph := charts.NewGraph()
+	graph.Initialization.Width = "1000px"
+	graph.Initialization.Height = strconv.Itoa(max(400, len(layers)*wuiTopologyLayerSpacing)) + "px"
+	graph.SetGlobalOptions(
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true)}),
+		charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true)}),
+	)
+	graph.AddSeries("topology", nodes, links,
+		charts.WithGraphChartOpts(opts.GraphChart{
+			Layout:     "none",
+			Roam:       opts.Bool(true),
+			EdgeSymbol: []string{"none", "arrow"},
+			Categories: categories,
+		}),
+		charts.WithLabelOpts(opts.Label{
+			Show:     opts.Bool(true),
+			Position: "bottom",
+		}),
+	)
+	graph.Renderer = newSnippetRenderer(graph, graph.Validate)
+	return g.Raw(renderToString(graph))
+}
+
+func topologyCategory(kind model.TopologyNodeKind) int {
+	for idx, k := range wuiTopologyKinds {
+		if k == kind {
+			return idx
+		}
+	}
+	return 0
+}
+
+func topologySymbolSize(kind model.TopologyNodeKind) int {
+	switch kind {
+	case model.TopologyMason, model.TopologyRouter:
+		return 30
+	case model.TopologyNetwork:
+		return 24
+	default:
+		return 14
+	}
+}
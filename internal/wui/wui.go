@@ -11,15 +11,18 @@ import (
 	"net"
 	"net/http"
 	"net/netip"
+	"os"
 	"strings"
 	"time"
 
-	"github.com/charmbracelet/log"
 	"github.com/dustin/go-humanize"
 	g "github.com/maragudk/gomponents"
 	h "github.com/maragudk/gomponents/html"
 
+	"github.com/networkables/mason/internal/agent"
+	"github.com/networkables/mason/internal/discovery"
 	"github.com/networkables/mason/internal/enrichment"
+	"github.com/networkables/mason/internal/logging"
 	"github.com/networkables/mason/internal/model"
 	"github.com/networkables/mason/internal/pinger"
 	"github.com/networkables/mason/internal/server"
@@ -27,6 +30,8 @@ import (
 	"github.com/networkables/mason/nettools"
 )
 
+var logger = logging.For(logging.WUI)
+
 type MasonReaderWriter interface {
 	MasonReader
 	MasonWriter
@@ -35,8 +40,12 @@ type MasonReaderWriter interface {
 
 type MasonReader interface {
 	ListNetworks(context.Context) []model.Network
+	SubscribeEvents(context.Context) (<-chan server.LiveEvent, func())
+	NetworkScans(context.Context) []discovery.NetworkScanProgress
+	NetworkDetail(context.Context, string) (server.NetworkDetail, error)
 	CountNetworks(context.Context) int
 	ListDevices(context.Context) []model.Device
+	QueryDevices(context.Context, model.DeviceQuery) model.DevicePage
 	CountDevices(context.Context) int
 	GetDeviceByAddr(context.Context, model.Addr) (model.Device, error)
 	ReadPerformancePings(
@@ -55,11 +64,102 @@ type MasonReader interface {
 	FlowSummaryByName(context.Context, model.Addr) ([]model.FlowSummaryForAddrByName, error)
 	FlowSummaryByCountry(context.Context, model.Addr) ([]model.FlowSummaryForAddrByCountry, error)
 	LookupIP(model.Addr) string
+	GetEnrichmentStatus() []server.EnrichmentStatus
+	ExportInventory(context.Context, bool, string) server.InventoryExport
+	ReadAnnotations(context.Context, model.Addr, time.Duration) ([]model.Annotation, error)
+	DeviceHistory(context.Context, model.Addr) ([]model.DeviceChange, error)
+	GetAddressPlans(context.Context) ([]model.AddressPlan, error)
+	ListReservations(context.Context) ([]model.Reservation, error)
+	ListTagDefinitions(context.Context) ([]model.TagDefinition, error)
+	LocalSite() string
+	ListSites(context.Context) ([]model.Site, error)
+	SiteLookup(context.Context) func(model.Device) string
+	SiteSummaries(context.Context) ([]model.SiteSummary, error)
+	InternetHealth(context.Context) ([]model.HealthTargetStatus, error)
+	HealthProbes(context.Context, time.Duration) ([]model.HealthProbe, error)
+	SpeedTests(context.Context, time.Duration) ([]model.SpeedTest, error)
+	EventHistory(context.Context, model.EventQuery) ([]model.EventRecord, error)
+	ListDeleted(context.Context) ([]model.Tombstone, error)
+	EffectivePolicy(context.Context, model.Device) model.MonitoringPolicy
+	ListMaintenanceWindows(context.Context) ([]model.MaintenanceWindow, error)
+	DevicesInMaintenance(context.Context) []model.Device
+	ListHTTPChecks(context.Context) ([]model.HTTPCheck, error)
+	HTTPCheckStatus(context.Context) ([]model.HTTPCheckStatus, error)
+	ServiceCheckStatus(context.Context) ([]model.ServiceCheckStatus, error)
+	MACBindings(context.Context, model.Addr) ([]model.MACBinding, error)
+	DHCPSightings(context.Context) ([]model.DHCPSighting, error)
+	ListBandwidthQuotas(context.Context) ([]model.BandwidthQuota, error)
+	QuotaUsage(context.Context) ([]model.QuotaUsage, error)
+	DeviceQuotaUsage(context.Context, model.Device) ([]model.QuotaUsage, error)
+	ListExclusions(context.Context) ([]model.Exclusion, error)
+	DeviceExclusions(context.Context, model.Device) model.Exclusions
+	ListDependencies(context.Context) ([]model.Dependency, error)
+	ListPortProfiles(context.Context) ([]model.PortProfile, error)
+	DeviceFullPortScan(context.Context, model.Addr) (model.FullPortScan, bool)
+	DeviceVulnHints(context.Context, model.Device) ([]model.VulnHint, error)
+	DeviceParents(context.Context, model.Device) []model.Device
+	Topology(context.Context) model.Topology
+	RecentDomains(context.Context, model.Addr) ([]model.DomainSummary, error)
+	RecentSyslog(context.Context, model.Addr) []model.SyslogMessage
+	ListCaptures(context.Context) ([]model.PacketCapture, error)
+	OpenCapture(context.Context, string) (model.PacketCapture, *os.File, error)
+	CaptureInterfaces(context.Context) []string
+	ReviewQueue(context.Context) []model.Device
+	TailEvents(context.Context, model.EventQuery) ([]model.EventRecord, error)
+	Liveness(context.Context) server.HealthReport
+	Readiness(context.Context) server.HealthReport
 }
 
 type MasonWriter interface {
 	AddNetwork(context.Context, model.Network) error
 	AddNetworkByName(context.Context, string, string, bool) error
+	CreateNetwork(context.Context, string, string, bool) (model.Network, error)
+	ScanNetwork(context.Context, string) (model.Network, error)
+	RenameNetwork(context.Context, string, string) error
+	EstimateNetworkScan(string, string) (discovery.ScanEstimate, error)
+	ReserveAddress(context.Context, model.Reservation) error
+	ReleaseAddress(context.Context, model.Addr) error
+	SaveTagDefinition(context.Context, model.TagDefinition) error
+	RemoveTagDefinition(context.Context, string) error
+	SaveSite(context.Context, model.Site) error
+	RemoveSite(context.Context, string) error
+	SetNetworkSite(context.Context, string, string) error
+	TagDevice(context.Context, model.Addr, string) error
+	UntagDevice(context.Context, model.Addr, string) error
+	RemoveDevice(context.Context, model.Addr) error
+	SetDevicePolicy(context.Context, model.Addr, model.MonitoringPolicy) error
+	SetDeviceApproval(context.Context, model.Addr, model.ApprovalState) error
+	SetDeviceDetails(context.Context, model.Addr, model.DeviceDetails) error
+	BulkDevices(context.Context, server.BulkDeviceRequest) (int, error)
+	EnrichDeviceNow(context.Context, model.Addr) (model.Device, error)
+	PingDeviceNow(context.Context, model.Addr) (model.Device, error)
+	AgentReport(context.Context, string, string, agent.Report) (int, error)
+	RemoveNetwork(context.Context, string) error
+	RestoreDeleted(context.Context, model.TombstoneKind, string) error
+	SaveMaintenanceWindow(context.Context, model.MaintenanceWindow) error
+	RemoveMaintenanceWindow(context.Context, string) error
+	SaveHTTPCheck(context.Context, model.HTTPCheck) error
+	RemoveHTTPCheck(context.Context, string) error
+	TrustDHCPSighting(context.Context, model.DHCPSightingKind, model.Addr) error
+	ForgetDHCPSighting(context.Context, model.DHCPSightingKind, model.Addr) error
+	SaveBandwidthQuota(context.Context, model.BandwidthQuota) error
+	RemoveBandwidthQuota(context.Context, string) error
+	SaveExclusion(context.Context, model.Exclusion) error
+	RemoveExclusion(context.Context, string) error
+	SaveDependency(context.Context, model.Dependency) error
+	RemoveDependency(context.Context, string) error
+	SavePortProfile(context.Context, model.PortProfile) error
+	RemovePortProfile(context.Context, string) error
+	TagNetwork(context.Context, string, string) error
+	UntagNetwork(context.Context, string, string) error
+	PurgeDeleted(context.Context) (int, error)
+	ArchiveTimeseries(context.Context) (int, error)
+	CheckConsistency(context.Context, bool) ([]model.ConsistencyIssue, error)
+	ImportCloud(context.Context, string) (server.CloudImportResult, error)
+	SyncNetbox(context.Context) (server.NetboxSyncResult, error)
+	StartCapture(context.Context, model.PacketCaptureRequest) (model.PacketCapture, error)
+	StopCapture(context.Context, string) error
+	RemoveCapture(context.Context, string) error
 }
 
 type MasonNetworker interface {
@@ -81,10 +181,15 @@ type MasonNetworker interface {
 	ArpPing(context.Context, string, time.Duration) (model.MAC, error)
 	Portscan(context.Context, string, *enrichment.PortScanConfig) ([]int, error)
 	GetExternalAddr(ctx context.Context) (model.Addr, error)
-	Traceroute(context.Context, string) ([]nettools.Icmp4EchoResponseStatistics, error)
+	Traceroute(
+		context.Context,
+		string,
+		...nettools.Icmp4EchoOption,
+	) ([]nettools.Icmp4EchoResponseStatistics, error)
 	TracerouteAddr(
 		context.Context,
 		model.Addr,
+		...nettools.Icmp4EchoOption,
 	) ([]nettools.Icmp4EchoResponseStatistics, error)
 	FetchTLSInfo(context.Context, string) (nettools.TLS, error)
 	FetchSNMPInfo(context.Context, string) (nettools.SnmpInfo, error)
@@ -95,11 +200,15 @@ type MasonNetworker interface {
 type WUI struct {
 	m MasonReaderWriter
 	h *http.Server
+
+	// closed on shutdown to end the long lived event streams
+	stopping chan struct{}
 }
 
 func New(m MasonReaderWriter, listenaddress string) *WUI {
 	w := &WUI{
-		m: m,
+		m:        m,
+		stopping: make(chan struct{}),
 	}
 	handler := w.newHandler()
 	h := &http.Server{
@@ -112,7 +221,7 @@ func New(m MasonReaderWriter, listenaddress string) *WUI {
 }
 
 func (w *WUI) Start() error {
-	log.Info("starting http server", "addr", w.h.Addr)
+	logger.Info("starting http server", "addr", w.h.Addr)
 	err := w.h.ListenAndServe()
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
@@ -121,6 +230,7 @@ func (w *WUI) Start() error {
 }
 
 func (w *WUI) Shutdown(ctx context.Context) error {
+	close(w.stopping)
 	if w.h != nil {
 		if err := w.h.Shutdown(ctx); err != nil {
 			return err
@@ -150,7 +260,6 @@ func (w WUI) basePage(
 ) g.Node {
 	return h.Doctype(
 		h.HTML(
-			h.DataAttr("theme", "light"),
 			h.Lang("en"),
 			h.Head(
 				h.Meta(h.Charset("utf-8")),
@@ -165,7 +274,9 @@ func (w WUI) basePage(
 				),
 				h.Script(h.Src("/static/javascript/tailwindcss-3.4.3.js")),
 				h.Script(h.Src("/static/javascript/htmx.js")),
+				h.Script(h.Src("/static/javascript/mason-theme.js")),
 				h.Script(h.Src("/static/javascript/theme-change.js")),
+				h.Script(h.Src("/static/javascript/mason-events.js"), h.Defer()),
 				extrahead,
 			),
 			h.Body(
@@ -175,7 +286,11 @@ func (w WUI) basePage(
 					h.Type("checkbox"),
 					h.Class("drawer-toggle"),
 				),
-				content,
+				h.Div(
+					h.Class("drawer-content flex flex-col"),
+					mobileNavBar(),
+					content,
+				),
 				h.Aside(
 					h.ID("sidebar"),
 					h.Class("drawer-side z-10"),
@@ -186,6 +301,24 @@ func (w WUI) basePage(
 	)
 }
 
+// mobileNavBar opens the sidebar drawer below the large breakpoint, where the drawer is
+// closed by default
+func mobileNavBar() g.Node {
+	return h.Div(
+		h.Class("navbar sticky top-0 z-[5] bg-base-100 shadow-sm lg:hidden"),
+		h.Label(
+			h.For("my-drawer"),
+			h.Class("btn btn-square btn-ghost drawer-button"),
+			g.Attr("aria-label", "open menu"),
+			svgBars3(),
+		),
+		h.A(h.Href(urlRoot), h.Class("btn btn-ghost text-lg font-black"), g.Text("Mason")),
+	)
+}
+
+// wuiNarrowHidden hides the secondary columns of a table on phone sized screens
+const wuiNarrowHidden = "hidden md:table-cell"
+
 func isSelectd(name string, selected string) g.Node {
 	if strings.ToUpper(name) == strings.ToUpper(selected) {
 		return h.Class("active")
@@ -219,7 +352,7 @@ func netStatBox2(
 	return h.Section(
 		h.Class(
 			// "stats stats-horizontal col-span-12 w-full",
-			"stats stats-horizontal col-span-12 xl:col-span-6",
+			"stats stats-vertical col-span-12 sm:stats-horizontal xl:col-span-6",
 		),
 		h.Div(
 			h.Class("stat"),
@@ -263,6 +396,7 @@ func netStatBox2(
 func netStatBox(
 	netname string,
 	prefix model.Prefix,
+	vlan model.VLAN,
 	usedip uint64,
 	totalip float64,
 	avgping time.Duration,
@@ -283,7 +417,7 @@ func netStatBox(
 	return h.Section(
 		h.Class(
 			// "stats stats-horizontal col-span-12 w-full",
-			"stats stats-horizontal col-span-12 xl:col-span-6",
+			"stats stats-vertical col-span-12 sm:stats-horizontal xl:col-span-6",
 		),
 		h.Div(
 			h.Class("stat"),
@@ -298,6 +432,7 @@ func netStatBox(
 			h.Div(
 				h.Class("stat-desc text-secondary"),
 				g.Text(prefix.String()),
+				g.If(!vlan.IsEmpty(), g.Text(" vlan "+vlan.String())),
 			),
 		),
 		h.Div(
@@ -344,7 +479,7 @@ func wuiStatBox(title string, value string, desc string) g.Node {
 	return h.Section(
 		h.Class(
 			// "stats stats-vertical col-span-12 w-1/2 shadow-sm xl:stats-horizontal",
-			"stats col-span-3 xl:col-span-2",
+			"stats col-span-6 md:col-span-3 xl:col-span-2",
 		),
 		h.Div(
 			h.Class("stat"),
@@ -365,12 +500,12 @@ func widecard(title string, body g.Node) g.Node {
 	return h.Section(
 		h.Class("card bg-base-100 col-span-12 shadow-sm"),
 		h.Div(
-			h.Class("card-body"),
+			h.Class("card-body p-4 md:p-8"),
 			h.H2(
 				h.Class("card-title"),
 				g.Text(title),
 			),
-			body,
+			h.Div(h.Class("overflow-x-auto"), body),
 			// h.P(g.Text(body)),
 		),
 	)
@@ -386,7 +521,7 @@ func graphcard(title string, graphs ...g.Node) g.Node {
           `),
 		),
 		h.Div(
-			h.Class("card-body"),
+			h.Class("card-body p-4 md:p-8"),
 			h.H2(
 				h.Class("card-title"),
 				g.Text(title),
@@ -398,7 +533,7 @@ func graphcard(title string, graphs ...g.Node) g.Node {
 
 func card(title string, body string) g.Node {
 	return h.Section(
-		h.Class("card bg-base-100 col-span-3"),
+		h.Class("card bg-base-100 col-span-12 sm:col-span-6 md:col-span-3"),
 		h.Div(
 			h.Class("card-body"),
 			h.H2(
@@ -416,7 +551,7 @@ func grid(id string, cards ...g.Node) g.Node {
 		h.Div(
 			h.Class(
 				// "grid grid-cols-12 grid-rows-[min-content] gap-y-12 p-4 lg:gap-x-12 lg:p-10",
-				"grid grid-cols-12 grid-rows-[min-content] gap-4 p-4",
+				"grid grid-cols-12 grid-rows-[min-content] gap-2 p-2 md:gap-4 md:p-4",
 			),
 			g.Group(cards),
 		),
@@ -427,7 +562,7 @@ func wuiCard(title string, content g.Node) g.Node {
 	return h.Section(
 		h.Class("card col-span-12 overflow-hidden bg-base-100 shadow-sm xl:col-span-10"),
 		h.Div(
-			h.Class("card-body grow-0"),
+			h.Class("card-body grow-0 p-4 md:p-8"),
 			h.H2(
 				h.Class("card-title"),
 				h.A(
@@ -456,6 +591,26 @@ func errAlert(err error) g.Node {
 	)
 }
 
+func successAlert(msg string) g.Node {
+	return h.Div(
+		h.Class("alert alert-success"),
+		g.Raw(
+			`<svg xmlns="http://www.w3.org/2000/svg" class="stroke-current shrink-0 h-5 w-5" fill="none" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M9 12l2 2 4-4m6 2a9 9 0 11-18 0 9 9 0 0118 0z" /></svg>`,
+		),
+		h.Span(g.Text(msg)),
+	)
+}
+
+func warnAlert(msg string) g.Node {
+	return h.Div(
+		h.Class("alert alert-warning"),
+		g.Raw(
+			`<svg xmlns="http://www.w3.org/2000/svg" class="stroke-current shrink-0 h-5 w-5" fill="none" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M12 9v2m0 4h.01m-6.938 4h13.856c1.54 0 2.502-1.667 1.732-3L13.732 4c-.77-1.333-2.694-1.333-3.464 0L3.34 16c-.77 1.333.192 3 1.732 3z" /></svg>`,
+		),
+		h.Span(g.Text(msg)),
+	)
+}
+
 func wuiTable(names []string, rows ...g.Node) g.Node {
 	return h.Table(
 		h.Class("table table-zebra"),
@@ -476,7 +631,7 @@ func wuiTable(names []string, rows ...g.Node) g.Node {
 
 func wuiFormInput(name string, elem g.Node) g.Node {
 	return h.Label(
-		h.Class("label"),
+		h.Class("label flex-col items-start gap-2 md:flex-row md:items-center"),
 		h.Span(h.Class("label-text"), g.Text(name)),
 		elem,
 	)
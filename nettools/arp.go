@@ -10,7 +10,7 @@ import (
 	"net/netip"
 	"time"
 
-	"github.com/mdlayher/arp"
+	"github.com/networkables/mason/internal/tracing"
 )
 
 var _ Arper = (*pkg)(nil)
@@ -18,6 +18,7 @@ var _ Arper = (*pkg)(nil)
 type Arper interface {
 	FindHardwareAddrOf(context.Context, netip.Addr, ...arpRequestOptionFunc) (ArpEntry, error)
 	FindUsingIfNameHardwareAddrOf(context.Context, string, netip.Addr, ...arpRequestOptionFunc) (ArpEntry, error)
+	ReadHostArpTable(context.Context) ([]ArpEntry, error)
 }
 
 func FindHardwareAddrOf(ctx context.Context, target netip.Addr, options ...arpRequestOptionFunc) (entry ArpEntry, err error) {
@@ -39,6 +40,9 @@ func (p *pkg) FindHardwareAddrOf(ctx context.Context, target netip.Addr, options
 }
 
 func (p *pkg) FindUsingIfNameHardwareAddrOf(ctx context.Context, ifname string, target netip.Addr, options ...arpRequestOptionFunc) (entry ArpEntry, err error) {
+	ctx, span := tracing.Start(ctx, "arp.request", tracing.AddrKey.String(target.String()))
+	defer func() { tracing.End(span, err) }()
+
 	opts := applyArpRequestOptions(options...)
 	entry.Addr = target
 
@@ -49,34 +53,10 @@ func (p *pkg) FindUsingIfNameHardwareAddrOf(ctx context.Context, ifname string,
 		}
 	}
 
-	// Ensure valid network interface
-	ifi, err := net.InterfaceByName(ifname)
+	mac, err := p.resolveHardwareAddr(ctx, ifname, target, opts.responseTimeout)
 	if err != nil {
 		return entry, err
 	}
-
-	// Set up ARP client with socket
-	c, err := arp.Dial(ifi)
-	if err != nil {
-		return entry, err
-	}
-	defer c.Close()
-
-	// Set request deadline from flag
-	if err := c.SetDeadline(time.Now().Add(opts.responseTimeout)); err != nil {
-		return entry, err
-	}
-
-	// Request hardware address for IP address
-	mac, err := c.Resolve(target)
-	if err != nil {
-		if err, ok := err.(*net.OpError); ok {
-			if err.Timeout() {
-				return entry, ErrNoResponseFromRemote
-			}
-		}
-		return entry, err
-	}
 	entry.MAC = mac
 
 	// TODO: multi writer enabled
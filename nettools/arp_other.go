@@ -0,0 +1,52 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build !windows
+
+package nettools
+
+import (
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/mdlayher/arp"
+)
+
+// resolveHardwareAddr sends an arp request out of the interface
+func resolveHardwareAddr(
+	ifname string,
+	target netip.Addr,
+	timeout time.Duration,
+) (net.HardwareAddr, error) {
+	// Ensure valid network interface
+	ifi, err := net.InterfaceByName(ifname)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set up ARP client with socket
+	c, err := arp.Dial(ifi)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	// Set request deadline from flag
+	if err := c.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	// Request hardware address for IP address
+	mac, err := c.Resolve(target)
+	if err != nil {
+		if err, ok := err.(*net.OpError); ok {
+			if err.Timeout() {
+				return nil, ErrNoResponseFromRemote
+			}
+		}
+		return nil, err
+	}
+	return mac, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build windows
+
+package nettools
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procSendARP = iphlpapi.NewProc("SendARP")
+
+// resolveHardwareAddr sends an arp request with SendARP, windows picks the interface from its
+// routes and needs no packet capture driver.  SendARP has no timeout of its own so a request
+// still waiting after the timeout is left to finish in the background.
+func resolveHardwareAddr(
+	_ string,
+	target netip.Addr,
+	timeout time.Duration,
+) (net.HardwareAddr, error) {
+	if !target.Is4() {
+		return nil, ErrIPv6Unsupported
+	}
+	type result struct {
+		mac net.HardwareAddr
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		mac, err := sendARP(target)
+		done <- result{mac: mac, err: err}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.mac, r.err
+	case <-timer.C:
+		return nil, ErrNoResponseFromRemote
+	}
+}
+
+func sendARP(target netip.Addr) (net.HardwareAddr, error) {
+	buf := make([]byte, 8)
+	size := uint32(len(buf))
+	ret, _, _ := procSendARP.Call(
+		uintptr(binary.LittleEndian.Uint32(target.AsSlice())),
+		0,
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(unsafe.Pointer(&size)),
+	)
+	switch syscall.Errno(ret) {
+	case windows.ERROR_SUCCESS:
+	case windows.ERROR_BAD_NET_NAME, windows.ERROR_GEN_FAILURE:
+		return nil, ErrNoResponseFromRemote
+	default:
+		return nil, errors.Join(ErrNoResponseFromRemote, syscall.Errno(ret))
+	}
+	if size == 0 {
+		return nil, ErrNoResponseFromRemote
+	}
+	return net.HardwareAddr(buf[:size]), nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/networkables/mason/internal/tracing"
+)
+
+// ArpPing times arp requests to the target, a reachability and latency probe for hosts on a
+// local segment which drop icmp.  The arp cache is skipped so each request goes out, the read
+// timeout of the options bounds each request.  ErrNotOnLink is returned for a target outside
+// the networks of the interfaces and ErrNoResponseFromRemote when no request is answered.
+func ArpPing(
+	ctx context.Context,
+	target netip.Addr,
+	opts ...Icmp4EchoOption,
+) ([]Icmp4EchoResponse, error) {
+	return DefaultPkg.ArpPing(ctx, target, opts...)
+}
+
+func (p *pkg) ArpPing(
+	ctx context.Context,
+	target netip.Addr,
+	opts ...Icmp4EchoOption,
+) ([]Icmp4EchoResponse, error) {
+	ctx, span := tracing.Start(ctx, "arp.ping", tracing.AddrKey.String(target.String()))
+	response, err := p.arpPing(ctx, target, opts...)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (p *pkg) arpPing(
+	ctx context.Context,
+	target netip.Addr,
+	opts ...Icmp4EchoOption,
+) ([]Icmp4EchoResponse, error) {
+	if !target.Is4() {
+		return nil, ErrIPv6Unsupported
+	}
+	iface, ok := p.onLinkInterface(target)
+	if !ok {
+		return nil, ErrNotOnLink
+	}
+	opt := i4eApplyOptionsToDefault(opts...)
+	response := make([]Icmp4EchoResponse, 0, opt.Count)
+	answered := false
+	for i := 0; i < opt.Count; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(opt.BetweenDuration):
+			}
+		}
+		if ctx.Err() != nil {
+			return response, ctx.Err()
+		}
+		start := time.Now()
+		_, err := p.resolveHardwareAddr(ctx, iface.Name, target, opt.ReadTimeout)
+		r := Icmp4EchoResponse{
+			Peer:    target,
+			Start:   start,
+			Elapsed: time.Since(start),
+			Err:     err,
+		}
+		answered = answered || err == nil
+		response = append(response, r)
+	}
+	if !answered {
+		return response, newErrNoResponse(target, nil)
+	}
+	return response, nil
+}
+
+// onLinkInterface returns the interface with a network holding the target, arp only reaches
+// the local segment so the default route does not count
+func (p *pkg) onLinkInterface(target netip.Addr) (net.Interface, bool) {
+	for prefixstr, ifacep := range p.ifacesByNetPrefix {
+		prefix, err := netip.ParsePrefix(prefixstr)
+		if err != nil {
+			continue
+		}
+		if prefix.Contains(target) {
+			return *ifacep, true
+		}
+	}
+	return net.Interface{}, false
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func TestArpPing_Unreachable(t *testing.T) {
+	p := &pkg{
+		ifacesByNetPrefix: map[string]*net.Interface{
+			"192.168.1.0/24": {Name: "eth0"},
+		},
+	}
+	tests := map[string]struct {
+		target  netip.Addr
+		wantErr error
+	}{
+		"IPv6":      {target: netip.MustParseAddr("fe80::1"), wantErr: ErrIPv6Unsupported},
+		"NotOnLink": {target: netip.MustParseAddr("10.0.0.1"), wantErr: ErrNotOnLink},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rs, err := p.ArpPing(context.Background(), tc.target)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("want: %v, got: %v", tc.wantErr, err)
+			}
+			if len(rs) != 0 {
+				t.Errorf("want no responses, got: %d", len(rs))
+			}
+		})
+	}
+}
+
+func TestOnLinkInterface(t *testing.T) {
+	p := &pkg{
+		ifacesByNetPrefix: map[string]*net.Interface{
+			"192.168.1.0/24": {Name: "eth0"},
+			"10.0.0.0/16":    {Name: "eth1"},
+		},
+	}
+	iface, ok := p.onLinkInterface(netip.MustParseAddr("10.0.4.2"))
+	if !ok || iface.Name != "eth1" {
+		t.Errorf("want eth1, got: %q (%t)", iface.Name, ok)
+	}
+	if _, ok := p.onLinkInterface(netip.MustParseAddr("8.8.8.8")); ok {
+		t.Error("want 8.8.8.8 off link")
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// ReadHostArpTable returns the IPv4 neighbors the host has resolved on its own, reading the
+// table sends no packets.  Incomplete, broadcast and multicast entries are left out.
+func ReadHostArpTable(ctx context.Context) ([]ArpEntry, error) {
+	return DefaultPkg.ReadHostArpTable(ctx)
+}
+
+func (p *pkg) ReadHostArpTable(ctx context.Context) ([]ArpEntry, error) {
+	return hostArpTable(ctx)
+}
+
+// ReadHostNdpTable returns the IPv6 neighbors the host has resolved on its own, with the
+// zone of the link-local addresses dropped.  Reading the table sends no packets.
+func ReadHostNdpTable(ctx context.Context) ([]ArpEntry, error) {
+	return hostNdpTable(ctx)
+}
+
+// parseNdpOutput reads the output of ndp -an,
+// "fe80::1%en0    0:11:22:33:44:55    en0 23h59m58s S R"
+func parseNdpOutput(out []byte) []ArpEntry {
+	entries := make([]ArpEntry, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		addr, err := netip.ParseAddr(fields[0])
+		if err != nil || !addr.Is6() {
+			continue
+		}
+		mac, err := parseArpMAC(fields[1])
+		if err != nil || !isNeighborMAC(mac) {
+			continue
+		}
+		entries = append(entries, ArpEntry{Addr: addr.WithZone(""), MAC: mac})
+	}
+	return entries
+}
+
+// parseArpOutput reads the output of arp -a, both the bsd form
+// "? (192.168.1.1) at 0:11:22:33:44:55 on en0" and the windows form
+// "192.168.1.1    00-11-22-33-44-55    dynamic"
+func parseArpOutput(out []byte) []ArpEntry {
+	entries := make([]ArpEntry, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		var addrstr, macstr string
+		switch {
+		case len(fields) >= 4 && fields[2] == "at":
+			addrstr = strings.Trim(fields[1], "()")
+			macstr = fields[3]
+		case len(fields) >= 2:
+			addrstr = fields[0]
+			macstr = fields[1]
+		default:
+			continue
+		}
+		addr, err := netip.ParseAddr(addrstr)
+		if err != nil || !addr.Is4() {
+			continue
+		}
+		mac, err := parseArpMAC(macstr)
+		if err != nil || !isNeighborMAC(mac) {
+			continue
+		}
+		entries = append(entries, ArpEntry{Addr: addr, MAC: mac})
+	}
+	return entries
+}
+
+// parseArpMAC accepts the octets without leading zeros printed by the bsd arp
+func parseArpMAC(s string) (net.HardwareAddr, error) {
+	sep := ":"
+	if strings.Contains(s, "-") {
+		sep = "-"
+	}
+	octets := strings.Split(s, sep)
+	for idx, octet := range octets {
+		if len(octet) == 1 {
+			octets[idx] = "0" + octet
+		}
+	}
+	return net.ParseMAC(strings.Join(octets, ":"))
+}
+
+// isNeighborMAC reports if the mac belongs to a single device
+func isNeighborMAC(mac net.HardwareAddr) bool {
+	if len(mac) == 0 || mac[0]&0x01 == 0x01 {
+		return false
+	}
+	for _, b := range mac {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
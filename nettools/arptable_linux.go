@@ -0,0 +1,58 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package nettools
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/vishvananda/netlink"
+)
+
+// neighbors in these states have a resolved mac
+const resolvedNeighStates = netlink.NUD_REACHABLE | netlink.NUD_STALE | netlink.NUD_DELAY |
+	netlink.NUD_PROBE | netlink.NUD_PERMANENT
+
+// hostArpTable reads the neighbor table over netlink
+func hostArpTable(_ context.Context) ([]ArpEntry, error) {
+	neighs, err := netlink.NeighList(0, netlink.FAMILY_V4)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]ArpEntry, 0, len(neighs))
+	for _, neigh := range neighs {
+		if neigh.State&resolvedNeighStates == 0 || !isNeighborMAC(neigh.HardwareAddr) {
+			continue
+		}
+		addr, ok := netip.AddrFromSlice(neigh.IP.To4())
+		if !ok {
+			continue
+		}
+		entries = append(entries, ArpEntry{Addr: addr, MAC: neigh.HardwareAddr})
+	}
+	return entries, nil
+}
+
+// hostNdpTable reads the IPv6 neighbor table over netlink
+func hostNdpTable(_ context.Context) ([]ArpEntry, error) {
+	neighs, err := netlink.NeighList(0, netlink.FAMILY_V6)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]ArpEntry, 0, len(neighs))
+	for _, neigh := range neighs {
+		if neigh.State&resolvedNeighStates == 0 || !isNeighborMAC(neigh.HardwareAddr) {
+			continue
+		}
+		addr, ok := netip.AddrFromSlice(neigh.IP)
+		if !ok || !addr.Is6() || addr.IsMulticast() {
+			continue
+		}
+		entries = append(entries, ArpEntry{Addr: addr, MAC: neigh.HardwareAddr})
+	}
+	return entries, nil
+}
@@ -0,0 +1,31 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package nettools
+
+import (
+	"context"
+	"os/exec"
+)
+
+// hostArpTable parses the output of arp -a, which every other supported os ships
+func hostArpTable(ctx context.Context) ([]ArpEntry, error) {
+	out, err := exec.CommandContext(ctx, "arp", "-a").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseArpOutput(out), nil
+}
+
+// hostNdpTable parses the output of ndp -an, the bsds ship it and on windows the command is
+// missing so the error is returned
+func hostNdpTable(ctx context.Context) ([]ArpEntry, error) {
+	out, err := exec.CommandContext(ctx, "ndp", "-an").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseNdpOutput(out), nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestParseArpOutput(t *testing.T) {
+	mac := net.HardwareAddr{0x00, 0x11, 0x02, 0x33, 0x44, 0x55}
+	tests := map[string]struct {
+		input string
+		want  []ArpEntry
+	}{
+		"BSD": {
+			input: `? (192.168.1.1) at 0:11:2:33:44:55 on en0 ifscope [ethernet]
+? (192.168.1.7) at (incomplete) on en0 ifscope [ethernet]
+? (192.168.1.255) at ff:ff:ff:ff:ff:ff on en0 ifscope [ethernet]
+? (224.0.0.251) at 1:0:5e:0:0:fb on en0 ifscope permanent [ethernet]
+`,
+			want: []ArpEntry{{Addr: netip.MustParseAddr("192.168.1.1"), MAC: mac}},
+		},
+		"Windows": {
+			input: `
+Interface: 192.168.1.20 --- 0x4
+  Internet Address      Physical Address      Type
+  192.168.1.1           00-11-02-33-44-55     dynamic
+  192.168.1.255         ff-ff-ff-ff-ff-ff     static
+  224.0.0.22            01-00-5e-00-00-16     static
+`,
+			want: []ArpEntry{{Addr: netip.MustParseAddr("192.168.1.1"), MAC: mac}},
+		},
+		"Empty": {
+			input: "",
+			want:  []ArpEntry{},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := parseArpOutput([]byte(tc.input))
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateComparable(netip.Addr{})); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseNdpOutput(t *testing.T) {
+	mac := net.HardwareAddr{0x00, 0x11, 0x02, 0x33, 0x44, 0x55}
+	input := `Neighbor                        Linklayer Address  Netif Expire    S Flags
+fe80::1%en0                     0:11:2:33:44:55      en0 23h59m58s S R
+2001:db8::20                    0:11:2:33:44:55      en0 permanent R
+fe80::9%en0                     (incomplete)         en0 expired   N
+ff02::fb%en0                    33:33:0:0:0:fb       en0 permanent R
+`
+	want := []ArpEntry{
+		{Addr: netip.MustParseAddr("fe80::1"), MAC: mac},
+		{Addr: netip.MustParseAddr("2001:db8::20"), MAC: mac},
+	}
+	got := parseNdpOutput([]byte(input))
+	if diff := cmp.Diff(want, got, cmpopts.EquateComparable(netip.Addr{})); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
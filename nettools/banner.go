@@ -0,0 +1,109 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/networkables/mason/internal/tracing"
+)
+
+// maxBannerLength bounds the banner kept of a service
+const maxBannerLength = 128
+
+// bannerHttpPorts are asked for their server header right away, the others are given the
+// timeout to greet first
+var bannerHttpPorts = []uint16{80, 8000, 8008, 8080, 8888}
+
+var bannerHttpRequest = []byte("HEAD / HTTP/1.0\r\n\r\n")
+
+// GrabBanner connects to the target and returns the greeting of the service, for a web
+// server the value of its Server header.  A service which says nothing within the timeout
+// is sent a http request.  ErrEmptyResponse is returned when the service does not answer
+// either.
+func GrabBanner(ctx context.Context, target netip.AddrPort, timeout time.Duration) (string, error) {
+	return DefaultPkg.GrabBanner(ctx, target, timeout)
+}
+
+func (p *pkg) GrabBanner(
+	ctx context.Context,
+	target netip.AddrPort,
+	timeout time.Duration,
+) (string, error) {
+	ctx, span := tracing.Start(ctx, "banner.grab", tracing.AddrKey.String(target.String()))
+	banner, err := grabBanner(ctx, target, timeout)
+	tracing.End(span, err)
+	return banner, err
+}
+
+func grabBanner(ctx context.Context, target netip.AddrPort, timeout time.Duration) (string, error) {
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "tcp", target.String())
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	asked := slices.Contains(bannerHttpPorts, target.Port())
+	if asked {
+		if _, err = conn.Write(bannerHttpRequest); err != nil {
+			return "", err
+		}
+	}
+	reply := readBanner(conn, timeout)
+	if len(reply) == 0 && !asked {
+		if _, err = conn.Write(bannerHttpRequest); err != nil {
+			return "", err
+		}
+		reply = readBanner(conn, timeout)
+	}
+	banner := parseBanner(reply)
+	if banner == "" {
+		return "", ErrEmptyResponse
+	}
+	return banner, nil
+}
+
+func readBanner(conn net.Conn, timeout time.Duration) []byte {
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	n, _ := conn.Read(buf)
+	return buf[:n]
+}
+
+// parseBanner returns the Server header of a http reply and the first line of any other
+func parseBanner(reply []byte) string {
+	line := ""
+	if bytes.HasPrefix(reply, []byte("HTTP/")) {
+		sc := bufio.NewScanner(bytes.NewReader(reply))
+		for sc.Scan() {
+			name, value, ok := strings.Cut(sc.Text(), ":")
+			if ok && strings.EqualFold(strings.TrimSpace(name), "server") {
+				line = value
+				break
+			}
+		}
+	} else {
+		line, _, _ = strings.Cut(string(reply), "\n")
+	}
+	line = strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' {
+			return -1
+		}
+		return r
+	}, line)
+	line = strings.TrimSpace(line)
+	if len(line) > maxBannerLength {
+		line = line[:maxBannerLength]
+	}
+	return line
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestGrabBanner(t *testing.T) {
+	serve := func(handle func(net.Conn)) netip.AddrPort {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { ln.Close() })
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				handle(conn)
+				conn.Close()
+			}
+		}()
+		return netip.MustParseAddrPort(ln.Addr().String())
+	}
+
+	tests := map[string]struct {
+		target  netip.AddrPort
+		want    string
+		wantErr error
+	}{
+		"Greeting": {
+			target: serve(func(c net.Conn) { c.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n")) }),
+			want:   "SSH-2.0-OpenSSH_9.6",
+		},
+		"HttpAfterSilence": {
+			target: serve(func(c net.Conn) {
+				bufio.NewReader(c).ReadString('\n')
+				c.Write([]byte("HTTP/1.0 200 OK\r\nServer: nginx/1.24.0\r\n\r\n"))
+			}),
+			want: "nginx/1.24.0",
+		},
+		"Silent": {
+			target:  serve(func(c net.Conn) { time.Sleep(300 * time.Millisecond) }),
+			wantErr: ErrEmptyResponse,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := GrabBanner(context.Background(), tc.target, 100*time.Millisecond)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("want: %v, got: %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("want: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseBanner(t *testing.T) {
+	tests := map[string]struct {
+		reply string
+		want  string
+	}{
+		"Empty":       {reply: "", want: ""},
+		"FirstLine":   {reply: "220 (vsFTPd 3.0.5)\r\n230 more\r\n", want: "220 (vsFTPd 3.0.5)"},
+		"ServerField": {reply: "HTTP/1.1 404 Not Found\r\nserver:  Apache/2.4.57\r\n\r\n", want: "Apache/2.4.57"},
+		"NoServer":    {reply: "HTTP/1.1 200 OK\r\nDate: today\r\n\r\n", want: ""},
+		"Binary":      {reply: "\x00\x01abc\xff\n", want: "abc"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := parseBanner([]byte(tc.reply))
+			if got != tc.want {
+				t.Errorf("want: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,458 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCaptureUnavailable    = errors.New("packet capture unavailable")
+	ErrInvalidCaptureFilter  = errors.New("invalid capture filter")
+	ErrCaptureLimitsRequired = errors.New("capture needs a duration or size limit")
+)
+
+const (
+	// DefaultCaptureSnapLen is the number of bytes of each packet kept when no snap length is
+	// given, enough for the headers of most packets
+	DefaultCaptureSnapLen = 262144
+
+	pcapMagic        = 0xa1b2c3d4
+	pcapVersionMajor = 2
+	pcapVersionMinor = 4
+	pcapLinkEthernet = 1
+	pcapHeaderLen    = 24
+	pcapRecordLen    = 16
+)
+
+// CaptureOptions bounds a packet capture, it stops at the first limit reached
+type CaptureOptions struct {
+	Filter      CaptureFilter
+	Duration    time.Duration
+	MaxBytes    int64
+	MaxPackets  int
+	SnapLen     int
+	Promiscuous bool
+	// Progress, when set, is called after each frame written
+	Progress func(CaptureStats)
+}
+
+// CaptureStats counts what a capture wrote, Bytes includes the pcap headers
+type CaptureStats struct {
+	Packets int
+	Bytes   int64
+}
+
+// PcapWriter writes ethernet frames in the classic pcap file format read by tcpdump and
+// wireshark
+type PcapWriter struct {
+	w       io.Writer
+	snaplen int
+	written int64
+}
+
+// NewPcapWriter writes the file header, frames longer than the snap length are truncated
+func NewPcapWriter(w io.Writer, snaplen int) (*PcapWriter, error) {
+	if snaplen <= 0 {
+		snaplen = DefaultCaptureSnapLen
+	}
+	hdr := make([]byte, pcapHeaderLen)
+	binary.LittleEndian.PutUint32(hdr[0:], pcapMagic)
+	binary.LittleEndian.PutUint16(hdr[4:], pcapVersionMajor)
+	binary.LittleEndian.PutUint16(hdr[6:], pcapVersionMinor)
+	binary.LittleEndian.PutUint32(hdr[16:], uint32(snaplen))
+	binary.LittleEndian.PutUint32(hdr[20:], pcapLinkEthernet)
+	_, err := w.Write(hdr)
+	if err != nil {
+		return nil, err
+	}
+	return &PcapWriter{w: w, snaplen: snaplen, written: pcapHeaderLen}, nil
+}
+
+// WritePacket writes the frame seen at ts
+func (p *PcapWriter) WritePacket(ts time.Time, frame []byte) error {
+	data := frame
+	if len(data) > p.snaplen {
+		data = data[:p.snaplen]
+	}
+	rec := make([]byte, pcapRecordLen)
+	binary.LittleEndian.PutUint32(rec[0:], uint32(ts.Unix()))
+	binary.LittleEndian.PutUint32(rec[4:], uint32(ts.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(rec[8:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(rec[12:], uint32(len(frame)))
+	_, err := p.w.Write(rec)
+	if err != nil {
+		return err
+	}
+	_, err = p.w.Write(data)
+	if err != nil {
+		return err
+	}
+	p.written += int64(pcapRecordLen + len(data))
+	return nil
+}
+
+// Written returns the bytes written including the file header
+func (p *PcapWriter) Written() int64 {
+	return p.written
+}
+
+// RecordSize is the number of bytes the frame takes in the file
+func (p *PcapWriter) RecordSize(frame []byte) int64 {
+	return int64(pcapRecordLen + min(len(frame), p.snaplen))
+}
+
+// CaptureFilter selects the frames kept by a capture, the zero value keeps every frame.
+//
+// The filter is a subset of the tcpdump syntax: the protocols ip, ip6, arp, tcp, udp and icmp,
+// [src|dst] host ADDR, [src|dst] net PREFIX, [src|dst] port PORT and ether host MAC, combined
+// with and, or, not and parentheses.
+type CaptureFilter struct {
+	expr string
+	node filterNode
+}
+
+// ParseCaptureFilter parses the expression, an empty expression keeps every frame
+func ParseCaptureFilter(expr string) (CaptureFilter, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return CaptureFilter{}, nil
+	}
+	p := &filterParser{tokens: tokenizeFilter(expr)}
+	node, err := p.parseOr()
+	if err != nil {
+		return CaptureFilter{}, err
+	}
+	if p.pos != len(p.tokens) {
+		return CaptureFilter{}, fmt.Errorf(
+			"%w: unexpected %q",
+			ErrInvalidCaptureFilter,
+			p.tokens[p.pos],
+		)
+	}
+	return CaptureFilter{expr: expr, node: node}, nil
+}
+
+func (f CaptureFilter) String() string {
+	return f.expr
+}
+
+// Match reports whether the ethernet frame is kept
+func (f CaptureFilter) Match(frame []byte) bool {
+	if f.node == nil {
+		return true
+	}
+	pkt, ok := decodeFrame(frame)
+	if !ok {
+		return false
+	}
+	return f.node.match(pkt)
+}
+
+// decodedFrame holds the fields of a frame the filter can test
+type decodedFrame struct {
+	srcMAC, dstMAC net.HardwareAddr
+	etherType      uint16
+	src, dst       netip.Addr
+	proto          uint8
+	srcPort        uint16
+	dstPort        uint16
+	hasPorts       bool
+}
+
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeARP  = 0x0806
+	etherTypeVLAN = 0x8100
+	etherTypeIPv6 = 0x86dd
+
+	protocolICMP   = 1
+	protocolICMPv6 = 58
+)
+
+func decodeFrame(frame []byte) (decodedFrame, bool) {
+	var d decodedFrame
+	if len(frame) < 14 {
+		return d, false
+	}
+	d.dstMAC = net.HardwareAddr(frame[0:6])
+	d.srcMAC = net.HardwareAddr(frame[6:12])
+	d.etherType = binary.BigEndian.Uint16(frame[12:14])
+	payload := frame[14:]
+	if d.etherType == etherTypeVLAN {
+		if len(payload) < 4 {
+			return d, false
+		}
+		d.etherType = binary.BigEndian.Uint16(payload[2:4])
+		payload = payload[4:]
+	}
+
+	var transport []byte
+	switch d.etherType {
+	case etherTypeIPv4:
+		if len(payload) < 20 {
+			return d, true
+		}
+		ihl := int(payload[0]&0x0f) * 4
+		d.proto = payload[9]
+		d.src = netip.AddrFrom4([4]byte(payload[12:16]))
+		d.dst = netip.AddrFrom4([4]byte(payload[16:20]))
+		// only the first fragment carries the ports
+		if binary.BigEndian.Uint16(payload[6:8])&0x1fff == 0 && len(payload) >= ihl {
+			transport = payload[ihl:]
+		}
+	case etherTypeIPv6:
+		if len(payload) < 40 {
+			return d, true
+		}
+		d.proto = payload[6]
+		d.src = netip.AddrFrom16([16]byte(payload[8:24]))
+		d.dst = netip.AddrFrom16([16]byte(payload[24:40]))
+		transport = payload[40:]
+	case etherTypeARP:
+		// sender and target protocol addresses of ipv4 over ethernet
+		if len(payload) >= 28 && payload[4] == 6 && payload[5] == 4 {
+			d.src = netip.AddrFrom4([4]byte(payload[14:18]))
+			d.dst = netip.AddrFrom4([4]byte(payload[24:28]))
+		}
+	}
+	if (d.proto == protocolTCP || d.proto == protocolUDP) && len(transport) >= 4 {
+		d.srcPort = binary.BigEndian.Uint16(transport[0:2])
+		d.dstPort = binary.BigEndian.Uint16(transport[2:4])
+		d.hasPorts = true
+	}
+	return d, true
+}
+
+type filterNode interface {
+	match(decodedFrame) bool
+}
+
+type (
+	filterAnd struct{ left, right filterNode }
+	filterOr  struct{ left, right filterNode }
+	filterNot struct{ node filterNode }
+
+	filterProto struct{ name string }
+
+	// filterHost, filterNet and filterPort test the source, the destination or either
+	filterHost struct {
+		dir  string
+		addr netip.Addr
+	}
+	filterNet struct {
+		dir    string
+		prefix netip.Prefix
+	}
+	filterPort struct {
+		dir  string
+		port uint16
+	}
+	filterEther struct {
+		mac net.HardwareAddr
+	}
+)
+
+func (f filterAnd) match(d decodedFrame) bool { return f.left.match(d) && f.right.match(d) }
+func (f filterOr) match(d decodedFrame) bool  { return f.left.match(d) || f.right.match(d) }
+func (f filterNot) match(d decodedFrame) bool { return !f.node.match(d) }
+
+func (f filterProto) match(d decodedFrame) bool {
+	switch f.name {
+	case "ip":
+		return d.etherType == etherTypeIPv4
+	case "ip6":
+		return d.etherType == etherTypeIPv6
+	case "arp":
+		return d.etherType == etherTypeARP
+	case "tcp":
+		return d.proto == protocolTCP
+	case "udp":
+		return d.proto == protocolUDP
+	case "icmp":
+		return d.proto == protocolICMP && d.etherType == etherTypeIPv4 ||
+			d.proto == protocolICMPv6 && d.etherType == etherTypeIPv6
+	}
+	return false
+}
+
+func (f filterHost) match(d decodedFrame) bool {
+	return matchDir(f.dir, d.src == f.addr, d.dst == f.addr)
+}
+
+func (f filterNet) match(d decodedFrame) bool {
+	return matchDir(
+		f.dir,
+		d.src.IsValid() && f.prefix.Contains(d.src),
+		d.dst.IsValid() && f.prefix.Contains(d.dst),
+	)
+}
+
+func (f filterPort) match(d decodedFrame) bool {
+	return d.hasPorts && matchDir(f.dir, d.srcPort == f.port, d.dstPort == f.port)
+}
+
+func (f filterEther) match(d decodedFrame) bool {
+	return d.srcMAC.String() == f.mac.String() || d.dstMAC.String() == f.mac.String()
+}
+
+func matchDir(dir string, src bool, dst bool) bool {
+	switch dir {
+	case "src":
+		return src
+	case "dst":
+		return dst
+	}
+	return src || dst
+}
+
+func tokenizeFilter(expr string) []string {
+	expr = strings.ReplaceAll(expr, "(", " ( ")
+	expr = strings.ReplaceAll(expr, ")", " ) ")
+	return strings.Fields(strings.ToLower(expr))
+}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *filterParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", fmt.Errorf("%w: unexpected end", ErrInvalidCaptureFilter)
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "or" || p.peek() == "||" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = filterOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "and" || p.peek() == "&&" {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = filterAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case "not", "!":
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{node}, nil
+	case "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		closing, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if closing != ")" {
+			return nil, fmt.Errorf("%w: expected ) got %q", ErrInvalidCaptureFilter, closing)
+		}
+		return node, nil
+	case "ip", "ip6", "arp", "tcp", "udp", "icmp":
+		return filterProto{t}, nil
+	case "ether":
+		if kw, err := p.next(); err != nil || kw != "host" {
+			return nil, fmt.Errorf("%w: expected ether host", ErrInvalidCaptureFilter)
+		}
+		val, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		mac, err := net.ParseMAC(val)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidCaptureFilter, err)
+		}
+		return filterEther{mac}, nil
+	case "src", "dst":
+		kw, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		return p.parsePrimitive(t, kw)
+	}
+	return p.parsePrimitive("", t)
+}
+
+// parsePrimitive reads the value of the host, net or port keyword
+func (p *filterParser) parsePrimitive(dir string, kw string) (filterNode, error) {
+	switch kw {
+	case "host", "net", "port":
+	default:
+		return nil, fmt.Errorf("%w: unknown primitive %q", ErrInvalidCaptureFilter, kw)
+	}
+	val, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch kw {
+	case "host":
+		addr, err := netip.ParseAddr(val)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidCaptureFilter, err)
+		}
+		return filterHost{dir, addr.Unmap()}, nil
+	case "net":
+		prefix, err := netip.ParsePrefix(val)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidCaptureFilter, err)
+		}
+		return filterNet{dir, prefix.Masked()}, nil
+	}
+	port, err := strconv.ParseUint(val, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: port %q", ErrInvalidCaptureFilter, val)
+	}
+	return filterPort{dir, uint16(port)}, nil
+}
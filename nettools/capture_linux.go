@@ -0,0 +1,102 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package nettools
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/mdlayher/packet"
+	"golang.org/x/sys/unix"
+)
+
+// Capture writes the frames seen on the interface which match the filter to w as a pcap file,
+// until the duration passes, the file reaches the size limit or the context is done.  A
+// frame which would take the file past MaxBytes ends the capture without being written.
+// Requires net raw privileges.
+func Capture(
+	ctx context.Context,
+	ifname string,
+	opts CaptureOptions,
+	w io.Writer,
+) (CaptureStats, error) {
+	var stats CaptureStats
+	if opts.Duration <= 0 && opts.MaxBytes <= 0 && opts.MaxPackets <= 0 {
+		return stats, ErrCaptureLimitsRequired
+	}
+	ifi, err := net.InterfaceByName(ifname)
+	if err != nil {
+		return stats, err
+	}
+	conn, err := packet.Listen(ifi, packet.Raw, unix.ETH_P_ALL, nil)
+	if err != nil {
+		return stats, err
+	}
+	defer conn.Close()
+	if opts.Promiscuous {
+		err = conn.SetPromiscuous(true)
+		if err != nil {
+			return stats, err
+		}
+	}
+	pw, err := NewPcapWriter(w, opts.SnapLen)
+	if err != nil {
+		return stats, err
+	}
+	stats.Bytes = pw.Written()
+
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+	go func() {
+		<-ctx.Done()
+		_ = conn.SetReadDeadline(time.Now())
+	}()
+
+	buf := make([]byte, max(ifi.MTU, 1500)+64)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				// reaching the duration is the normal end of a capture
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return stats, nil
+				}
+				return stats, ctx.Err()
+			}
+			var nerr net.Error
+			if errors.As(err, &nerr) && nerr.Timeout() {
+				continue
+			}
+			return stats, err
+		}
+		frame := buf[:n]
+		if !opts.Filter.Match(frame) {
+			continue
+		}
+		if opts.MaxBytes > 0 && pw.Written()+pw.RecordSize(frame) > opts.MaxBytes {
+			return stats, nil
+		}
+		err = pw.WritePacket(time.Now(), frame)
+		if err != nil {
+			return stats, err
+		}
+		stats.Packets++
+		stats.Bytes = pw.Written()
+		if opts.Progress != nil {
+			opts.Progress(stats)
+		}
+		if opts.MaxPackets > 0 && stats.Packets >= opts.MaxPackets {
+			return stats, nil
+		}
+	}
+}
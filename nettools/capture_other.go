@@ -0,0 +1,22 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package nettools
+
+import (
+	"context"
+	"io"
+)
+
+// Capture is only supported on linux
+func Capture(
+	ctx context.Context,
+	ifname string,
+	opts CaptureOptions,
+	w io.Writer,
+) (CaptureStats, error) {
+	return CaptureStats{}, ErrCaptureUnavailable
+}
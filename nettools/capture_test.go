@@ -0,0 +1,127 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// ipv4Frame builds an ethernet frame of an ipv4 packet with the ports of a tcp or udp header
+func ipv4Frame(proto byte, src string, dst string, srcport int, dstport int) []byte {
+	b := make([]byte, 14+20+8)
+	copy(b[0:6], []byte{0x02, 0, 0, 0, 0, 0x02})
+	copy(b[6:12], []byte{0x02, 0, 0, 0, 0, 0x01})
+	binary.BigEndian.PutUint16(b[12:14], etherTypeIPv4)
+	ip := b[14:]
+	ip[0] = 0x45
+	ip[9] = proto
+	s := netip.MustParseAddr(src).As4()
+	d := netip.MustParseAddr(dst).As4()
+	copy(ip[12:16], s[:])
+	copy(ip[16:20], d[:])
+	binary.BigEndian.PutUint16(ip[20:22], uint16(srcport))
+	binary.BigEndian.PutUint16(ip[22:24], uint16(dstport))
+	return b
+}
+
+func TestCaptureFilter(t *testing.T) {
+	dns := ipv4Frame(protocolUDP, "192.168.1.20", "192.168.1.1", 40000, 53)
+	web := ipv4Frame(protocolTCP, "10.0.0.5", "192.168.1.20", 443, 50000)
+	arp := make([]byte, 14+28)
+	binary.BigEndian.PutUint16(arp[12:14], etherTypeARP)
+
+	tests := map[string]struct {
+		expr  string
+		frame []byte
+		want  bool
+	}{
+		"Empty":          {expr: "", frame: dns, want: true},
+		"Host":           {expr: "host 192.168.1.20", frame: web, want: true},
+		"SrcHost":        {expr: "src host 192.168.1.20", frame: web, want: false},
+		"DstPort":        {expr: "udp and dst port 53", frame: dns, want: true},
+		"Port":           {expr: "port 53", frame: web, want: false},
+		"Net":            {expr: "src net 10.0.0.0/8", frame: web, want: true},
+		"Not":            {expr: "not port 53", frame: dns, want: false},
+		"Or":             {expr: "tcp or port 53", frame: dns, want: true},
+		"Parens":         {expr: "host 192.168.1.20 and (port 22 or port 443)", frame: web, want: true},
+		"Proto":          {expr: "arp", frame: arp, want: true},
+		"ProtoMismatch":  {expr: "ip", frame: arp, want: false},
+		"EtherHost":      {expr: "ether host 02:00:00:00:00:01", frame: dns, want: true},
+		"Truncated":      {expr: "port 53", frame: dns[:10], want: false},
+		"UpperCaseWords": {expr: "UDP AND PORT 53", frame: dns, want: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, err := ParseCaptureFilter(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := f.Match(tc.frame)
+			if got != tc.want {
+				t.Errorf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseCaptureFilter_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"host",
+		"host nope",
+		"port 70000",
+		"tcp and",
+		"(tcp",
+		"tcp)",
+		"vlan 10",
+		"ether host zz",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := ParseCaptureFilter(expr)
+			if !errors.Is(err, ErrInvalidCaptureFilter) {
+				t.Errorf("want: %v, got: %v", ErrInvalidCaptureFilter, err)
+			}
+		})
+	}
+}
+
+func TestPcapWriter(t *testing.T) {
+	var buf bytes.Buffer
+	pw, err := NewPcapWriter(&buf, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := ipv4Frame(protocolUDP, "192.168.1.20", "192.168.1.1", 40000, 53)
+	ts := time.Unix(1700000000, 123456000)
+	err = pw.WritePacket(ts, frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.Bytes()
+	if got := int64(len(b)); got != pw.Written() || got != 24+16+20 {
+		t.Fatalf("written: %d, len: %d", pw.Written(), got)
+	}
+	if binary.LittleEndian.Uint32(b[0:4]) != pcapMagic ||
+		binary.LittleEndian.Uint32(b[16:20]) != 20 ||
+		binary.LittleEndian.Uint32(b[20:24]) != pcapLinkEthernet {
+		t.Errorf("bad file header: % x", b[:24])
+	}
+	rec := b[24:40]
+	want := []uint32{1700000000, 123456, 20, uint32(len(frame))}
+	for i, w := range want {
+		got := binary.LittleEndian.Uint32(rec[i*4:])
+		if got != w {
+			t.Errorf("record field %d: want: %d, got: %d", i, w, got)
+		}
+	}
+	if !bytes.Equal(b[40:], frame[:20]) {
+		t.Error("frame not truncated to the snap length")
+	}
+}
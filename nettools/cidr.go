@@ -0,0 +1,148 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"strings"
+
+	"go4.org/netipx"
+)
+
+const (
+	// cidrSplitLevels is the number of longer prefix lengths suggested as splits, and the
+	// most bits a prefix is split by
+	cidrSplitLevels = 8
+
+	// MaxSubnets is the most subnets SplitPrefix lists
+	MaxSubnets = 1 << cidrSplitLevels
+)
+
+// CIDR describes the addresses of a prefix.  An IPv4 prefix reserves its first and last
+// address for the network and broadcast, except a /31 (point to point links, rfc 3021) and a
+// /32.  IPv6 has no broadcast, so Broadcast, Netmask and Wildcard are left invalid and every
+// address is usable.
+type CIDR struct {
+	Prefix      netip.Prefix
+	Network     netip.Addr
+	Broadcast   netip.Addr
+	Netmask     netip.Addr
+	Wildcard    netip.Addr
+	FirstUsable netip.Addr
+	LastUsable  netip.Addr
+	Addresses   *big.Int
+	Usable      *big.Int
+	Splits      []CIDRSplit
+}
+
+// CIDRSplit is the division of a prefix into subnets of a longer prefix length
+type CIDRSplit struct {
+	Bits    int
+	Subnets *big.Int
+	Usable  *big.Int
+}
+
+// ParseCIDR reads a prefix, or an address as a single address prefix, and describes it.  The
+// host bits of the prefix are cleared.
+func ParseCIDR(s string) (CIDR, error) {
+	s = strings.TrimSpace(s)
+	var p netip.Prefix
+	if strings.Contains(s, "/") {
+		var err error
+		p, err = netip.ParsePrefix(s)
+		if err != nil {
+			return CIDR{}, err
+		}
+	} else {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return CIDR{}, err
+		}
+		p = netip.PrefixFrom(addr, addr.BitLen())
+	}
+	return DescribePrefix(p), nil
+}
+
+// DescribePrefix lists the network, broadcast, usable range and suggested splits of the
+// prefix
+func DescribePrefix(p netip.Prefix) CIDR {
+	p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()).Masked()
+	rng := netipx.RangeOfPrefix(p)
+	hostbits := p.Addr().BitLen() - p.Bits()
+	c := CIDR{
+		Prefix:      p,
+		Network:     rng.From(),
+		FirstUsable: rng.From(),
+		LastUsable:  rng.To(),
+		Addresses:   new(big.Int).Lsh(big.NewInt(1), uint(hostbits)),
+	}
+	c.Usable = usableAddresses(p.Addr().Is4(), hostbits)
+	if p.Addr().Is4() {
+		c.Broadcast = rng.To()
+		c.Netmask = maskAddr(p.Bits(), false)
+		c.Wildcard = maskAddr(p.Bits(), true)
+		if hostbits > 1 {
+			c.FirstUsable = c.FirstUsable.Next()
+			c.LastUsable = c.LastUsable.Prev()
+		}
+	}
+	for bits := p.Bits() + 1; bits <= p.Addr().BitLen() && bits <= p.Bits()+cidrSplitLevels; bits++ {
+		c.Splits = append(c.Splits, CIDRSplit{
+			Bits:    bits,
+			Subnets: new(big.Int).Lsh(big.NewInt(1), uint(bits-p.Bits())),
+			Usable:  usableAddresses(p.Addr().Is4(), p.Addr().BitLen()-bits),
+		})
+	}
+	return c
+}
+
+// usableAddresses is the number of host addresses of a prefix with the host bits
+func usableAddresses(is4 bool, hostbits int) *big.Int {
+	n := new(big.Int).Lsh(big.NewInt(1), uint(hostbits))
+	if is4 && hostbits > 1 {
+		n.Sub(n, big.NewInt(2))
+	}
+	return n
+}
+
+// maskAddr is the ipv4 netmask of the prefix length, or its inverse
+func maskAddr(bits int, inverse bool) netip.Addr {
+	mask := ^uint32(0) << (32 - bits)
+	if bits == 0 {
+		mask = 0
+	}
+	if inverse {
+		mask = ^mask
+	}
+	return netip.AddrFrom4([4]byte{byte(mask >> 24), byte(mask >> 16), byte(mask >> 8), byte(mask)})
+}
+
+// SplitPrefix divides the prefix into the subnets of the longer prefix length, at most
+// MaxSubnets are listed
+func SplitPrefix(p netip.Prefix, bits int) ([]netip.Prefix, error) {
+	p = p.Masked()
+	if bits <= p.Bits() || bits > p.Addr().BitLen() {
+		return nil, fmt.Errorf(
+			"%w: /%d does not split %s",
+			ErrInvalidSplit,
+			bits,
+			p,
+		)
+	}
+	if bits-p.Bits() > cidrSplitLevels {
+		return nil, fmt.Errorf("%w: %s into /%d", ErrTooManySubnets, p, bits)
+	}
+	count := 1 << (bits - p.Bits())
+	subnets := make([]netip.Prefix, 0, count)
+	next := netip.PrefixFrom(p.Addr(), bits)
+	for range count {
+		subnets = append(subnets, next)
+		last := netipx.PrefixLastIP(next)
+		next = netip.PrefixFrom(last.Next(), bits)
+	}
+	return subnets, nil
+}
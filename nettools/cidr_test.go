@@ -0,0 +1,209 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseCIDR(t *testing.T) {
+	type want struct {
+		prefix      string
+		network     string
+		broadcast   string
+		netmask     string
+		wildcard    string
+		firstUsable string
+		lastUsable  string
+		addresses   string
+		usable      string
+		splits      int
+	}
+	tests := map[string]struct {
+		input string
+		want  want
+	}{
+		"Slash24": {
+			input: "192.168.1.77/24",
+			want: want{
+				prefix:      "192.168.1.0/24",
+				network:     "192.168.1.0",
+				broadcast:   "192.168.1.255",
+				netmask:     "255.255.255.0",
+				wildcard:    "0.0.0.255",
+				firstUsable: "192.168.1.1",
+				lastUsable:  "192.168.1.254",
+				addresses:   "256",
+				usable:      "254",
+				splits:      8,
+			},
+		},
+		"Slash31": {
+			input: "10.0.0.1/31",
+			want: want{
+				prefix:      "10.0.0.0/31",
+				network:     "10.0.0.0",
+				broadcast:   "10.0.0.1",
+				netmask:     "255.255.255.254",
+				wildcard:    "0.0.0.1",
+				firstUsable: "10.0.0.0",
+				lastUsable:  "10.0.0.1",
+				addresses:   "2",
+				usable:      "2",
+				splits:      1,
+			},
+		},
+		"Address": {
+			input: " 10.0.0.9 ",
+			want: want{
+				prefix:      "10.0.0.9/32",
+				network:     "10.0.0.9",
+				broadcast:   "10.0.0.9",
+				netmask:     "255.255.255.255",
+				wildcard:    "0.0.0.0",
+				firstUsable: "10.0.0.9",
+				lastUsable:  "10.0.0.9",
+				addresses:   "1",
+				usable:      "1",
+			},
+		},
+		"Slash0": {
+			input: "0.0.0.0/0",
+			want: want{
+				prefix:      "0.0.0.0/0",
+				network:     "0.0.0.0",
+				broadcast:   "255.255.255.255",
+				netmask:     "0.0.0.0",
+				wildcard:    "255.255.255.255",
+				firstUsable: "0.0.0.1",
+				lastUsable:  "255.255.255.254",
+				addresses:   "4294967296",
+				usable:      "4294967294",
+				splits:      8,
+			},
+		},
+		"IPv6": {
+			input: "2001:db8::1/64",
+			want: want{
+				prefix:      "2001:db8::/64",
+				network:     "2001:db8::",
+				broadcast:   "invalid IP",
+				netmask:     "invalid IP",
+				wildcard:    "invalid IP",
+				firstUsable: "2001:db8::",
+				lastUsable:  "2001:db8::ffff:ffff:ffff:ffff",
+				addresses:   "18446744073709551616",
+				usable:      "18446744073709551616",
+				splits:      8,
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, err := ParseCIDR(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := want{
+				prefix:      c.Prefix.String(),
+				network:     c.Network.String(),
+				broadcast:   c.Broadcast.String(),
+				netmask:     c.Netmask.String(),
+				wildcard:    c.Wildcard.String(),
+				firstUsable: c.FirstUsable.String(),
+				lastUsable:  c.LastUsable.String(),
+				addresses:   c.Addresses.String(),
+				usable:      c.Usable.String(),
+				splits:      len(c.Splits),
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestParseCIDRInvalid(t *testing.T) {
+	for _, input := range []string{"", "192.168.1.0/33", "host.name"} {
+		if _, err := ParseCIDR(input); err == nil {
+			t.Errorf("%q: want error", input)
+		}
+	}
+}
+
+func TestCIDRSplits(t *testing.T) {
+	c := DescribePrefix(netip.MustParsePrefix("192.168.0.0/22"))
+	split := c.Splits[1]
+	if split.Bits != 24 || split.Subnets.Int64() != 4 || split.Usable.Int64() != 254 {
+		t.Fatalf("split mismatch: /%d %s subnets %s usable", split.Bits, split.Subnets, split.Usable)
+	}
+}
+
+func TestSplitPrefix(t *testing.T) {
+	tests := map[string]struct {
+		prefix string
+		bits   int
+		want   []string
+		err    error
+	}{
+		"Halves": {
+			prefix: "192.168.1.0/24",
+			bits:   25,
+			want:   []string{"192.168.1.0/25", "192.168.1.128/25"},
+		},
+		"Quarters": {
+			prefix: "10.0.0.0/22",
+			bits:   24,
+			want:   []string{"10.0.0.0/24", "10.0.1.0/24", "10.0.2.0/24", "10.0.3.0/24"},
+		},
+		"EndOfSpace": {
+			prefix: "255.255.255.252/30",
+			bits:   31,
+			want:   []string{"255.255.255.252/31", "255.255.255.254/31"},
+		},
+		"IPv6": {
+			prefix: "2001:db8::/63",
+			bits:   64,
+			want:   []string{"2001:db8::/64", "2001:db8:0:1::/64"},
+		},
+		"NotLonger": {
+			prefix: "192.168.1.0/24",
+			bits:   24,
+			err:    ErrInvalidSplit,
+		},
+		"PastEnd": {
+			prefix: "192.168.1.0/24",
+			bits:   33,
+			err:    ErrInvalidSplit,
+		},
+		"TooMany": {
+			prefix: "10.0.0.0/8",
+			bits:   24,
+			err:    ErrTooManySubnets,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			subnets, err := SplitPrefix(netip.MustParsePrefix(tc.prefix), tc.bits)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("error: want %v, got %v", tc.err, err)
+			}
+			got := make([]string, 0, len(subnets))
+			for _, s := range subnets {
+				got = append(got, s.String())
+			}
+			if tc.err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	dhcpHeaderLen   = 236
+	dhcpBootRequest = 1
+
+	dhcpOptionPad            = 0
+	dhcpOptionHostname       = 12
+	dhcpOptionRequestedAddr  = 50
+	dhcpOptionMessageType    = 53
+	dhcpOptionParameterList  = 55
+	dhcpOptionVendorClass    = 60
+	dhcpOptionEnd            = 255
+	dhcpListenerReadDeadline = time.Second
+)
+
+var (
+	dhcpMagicCookie = []byte{99, 130, 83, 99}
+
+	ErrNotDHCPRequest = errors.New("not a dhcp client request")
+)
+
+// DHCPRequest is what a client tells about itself when it asks for an address.  The
+// fingerprint is the parameter request list (option 55) as comma separated option codes, the
+// order of the list differs between operating systems and survives MAC randomization.
+type DHCPRequest struct {
+	MessageType int
+	MAC         net.HardwareAddr
+	Addr        netip.Addr
+	Hostname    string
+	VendorClass string
+	Fingerprint string
+}
+
+// ParseDHCPRequest reads a BOOTREQUEST sent by a DHCP client, the addr is the requested
+// address (option 50) or otherwise the address the client already has
+func ParseDHCPRequest(b []byte) (DHCPRequest, error) {
+	var req DHCPRequest
+	if len(b) < dhcpHeaderLen+len(dhcpMagicCookie) || b[0] != dhcpBootRequest {
+		return req, ErrNotDHCPRequest
+	}
+	if !bytes.Equal(b[dhcpHeaderLen:dhcpHeaderLen+len(dhcpMagicCookie)], dhcpMagicCookie) {
+		return req, ErrNotDHCPRequest
+	}
+	hlen := min(int(b[2]), 16)
+	req.MAC = net.HardwareAddr(bytes.Clone(b[28 : 28+hlen]))
+	if ciaddr := netip.AddrFrom4([4]byte(b[12:16])); !ciaddr.IsUnspecified() {
+		req.Addr = ciaddr
+	}
+
+	options := b[dhcpHeaderLen+len(dhcpMagicCookie):]
+	for len(options) > 0 {
+		code := options[0]
+		if code == dhcpOptionEnd {
+			break
+		}
+		if code == dhcpOptionPad {
+			options = options[1:]
+			continue
+		}
+		if len(options) < 2 || len(options) < 2+int(options[1]) {
+			return req, ErrNotDHCPRequest
+		}
+		value := options[2 : 2+int(options[1])]
+		options = options[2+int(options[1]):]
+		switch code {
+		case dhcpOptionHostname:
+			req.Hostname = string(value)
+		case dhcpOptionRequestedAddr:
+			if len(value) == 4 {
+				req.Addr = netip.AddrFrom4([4]byte(value))
+			}
+		case dhcpOptionMessageType:
+			if len(value) == 1 {
+				req.MessageType = int(value[0])
+			}
+		case dhcpOptionParameterList:
+			codes := make([]string, 0, len(value))
+			for _, c := range value {
+				codes = append(codes, strconv.Itoa(int(c)))
+			}
+			req.Fingerprint = strings.Join(codes, ",")
+		case dhcpOptionVendorClass:
+			req.VendorClass = string(value)
+		}
+	}
+	return req, nil
+}
+
+// ListenDHCP passes the DHCP client requests received on the address to fn until the context
+// is done.  The requests are broadcast so a listener on the dhcp server port sees them next to
+// the dhcp server of the network, binding the port needs the same privileges as the server.
+func ListenDHCP(ctx context.Context, address string, fn func(DHCPRequest)) error {
+	conn, err := net.ListenPacket("udp4", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 1500)
+	for ctx.Err() == nil {
+		err = conn.SetReadDeadline(time.Now().Add(dhcpListenerReadDeadline))
+		if err != nil {
+			return err
+		}
+		n, _, err := conn.ReadFrom(buf)
+		var neterr net.Error
+		if errors.As(err, &neterr) && neterr.Timeout() {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		req, err := ParseDHCPRequest(buf[:n])
+		if err != nil {
+			continue
+		}
+		fn(req)
+	}
+	return nil
+}
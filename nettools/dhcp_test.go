@@ -0,0 +1,90 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func dhcpPacket(op byte, ciaddr [4]byte, options ...byte) []byte {
+	b := make([]byte, dhcpHeaderLen)
+	b[0], b[1], b[2] = op, 1, 6
+	copy(b[12:16], ciaddr[:])
+	copy(b[28:34], []byte{0xda, 0x55, 0x01, 0x02, 0x03, 0x04})
+	b = append(b, dhcpMagicCookie...)
+	return append(b, options...)
+}
+
+func TestParseDHCPRequest(t *testing.T) {
+	mac := net.HardwareAddr{0xda, 0x55, 0x01, 0x02, 0x03, 0x04}
+	tests := map[string]struct {
+		input []byte
+		want  DHCPRequest
+		err   error
+	}{
+		"Request": {
+			input: dhcpPacket(dhcpBootRequest, [4]byte{},
+				dhcpOptionMessageType, 1, 3,
+				dhcpOptionPad,
+				dhcpOptionRequestedAddr, 4, 192, 168, 1, 20,
+				dhcpOptionHostname, 5, 'p', 'i', 'x', 'e', 'l',
+				dhcpOptionParameterList, 4, 1, 3, 6, 15,
+				dhcpOptionVendorClass, 4, 'a', 'n', 'd', 'r',
+				dhcpOptionEnd,
+			),
+			want: DHCPRequest{
+				MessageType: 3,
+				MAC:         mac,
+				Addr:        netip.MustParseAddr("192.168.1.20"),
+				Hostname:    "pixel",
+				VendorClass: "andr",
+				Fingerprint: "1,3,6,15",
+			},
+		},
+		"Renewal": {
+			input: dhcpPacket(dhcpBootRequest, [4]byte{192, 168, 1, 21},
+				dhcpOptionMessageType, 1, 3,
+				dhcpOptionEnd,
+			),
+			want: DHCPRequest{
+				MessageType: 3,
+				MAC:         mac,
+				Addr:        netip.MustParseAddr("192.168.1.21"),
+			},
+		},
+		"Reply": {
+			input: dhcpPacket(2, [4]byte{}, dhcpOptionEnd),
+			err:   ErrNotDHCPRequest,
+		},
+		"Truncated": {
+			input: dhcpPacket(dhcpBootRequest, [4]byte{}, dhcpOptionHostname, 5, 'p'),
+			err:   ErrNotDHCPRequest,
+		},
+		"Short": {
+			input: []byte{dhcpBootRequest, 1, 6},
+			err:   ErrNotDHCPRequest,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseDHCPRequest(tc.input)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("error want: %v, got: %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateComparable(netip.Addr{})); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
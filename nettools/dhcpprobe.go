@@ -0,0 +1,256 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"time"
+)
+
+const (
+	dhcpBootReply     = 2
+	dhcpFlagBroadcast = 0x8000
+
+	dhcpMessageDiscover = 1
+	dhcpMessageOffer    = 2
+
+	dhcpOptionSubnetMask = 1
+	dhcpOptionRouter     = 3
+	dhcpOptionDNS        = 6
+	dhcpOptionLeaseTime  = 51
+	dhcpOptionServerID   = 54
+	dhcpOptionClientID   = 61
+)
+
+var ErrNotDHCPOffer = errors.New("not a dhcp offer")
+
+// DHCPOffer is the answer of a DHCP server to a discover, the server is its identifier
+// (option 54) or otherwise the address the offer came from
+type DHCPOffer struct {
+	Server    netip.Addr
+	Addr      netip.Addr
+	Mask      net.IPMask
+	Routers   []netip.Addr
+	DNS       []netip.Addr
+	LeaseTime time.Duration
+}
+
+// DHCPDiscover broadcasts a DHCPDISCOVER and returns the offer of each server answering
+// before the timeout.  The discover asks for broadcast replies so they reach the probe
+// without an address, binding the dhcp client port needs the same privileges as the server.
+// No address is requested so the offers lapse on the servers.
+func DHCPDiscover(ctx context.Context, options ...dhcpProbeOptionFunc) ([]DHCPOffer, error) {
+	opts := applyDHCPProbeOptions(options...)
+	if len(opts.mac) == 0 {
+		opts.mac = randomLocalMAC()
+	}
+	var xidb [4]byte
+	_, err := rand.Read(xidb[:])
+	if err != nil {
+		return nil, err
+	}
+	xid := binary.BigEndian.Uint32(xidb[:])
+
+	conn, err := net.ListenPacket("udp4", opts.listenAddress)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	_, err = conn.WriteTo(
+		BuildDHCPDiscover(opts.mac, xid),
+		&net.UDPAddr{IP: net.IPv4bcast, Port: 67},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(opts.responseTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	err = conn.SetReadDeadline(deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	offers := make([]DHCPOffer, 0)
+	seen := make(map[netip.Addr]struct{})
+	buf := make([]byte, 1500)
+	for ctx.Err() == nil {
+		n, from, err := conn.ReadFrom(buf)
+		var neterr net.Error
+		if errors.As(err, &neterr) && neterr.Timeout() {
+			break
+		}
+		if err != nil {
+			return offers, err
+		}
+		offer, err := ParseDHCPOffer(buf[:n], xid)
+		if err != nil {
+			continue
+		}
+		if !offer.Server.IsValid() {
+			if udp, ok := from.(*net.UDPAddr); ok {
+				offer.Server, _ = netip.AddrFromSlice(udp.IP.To4())
+			}
+		}
+		if _, ok := seen[offer.Server]; ok {
+			continue
+		}
+		seen[offer.Server] = struct{}{}
+		offers = append(offers, offer)
+	}
+	return offers, ctx.Err()
+}
+
+// BuildDHCPDiscover returns a DHCPDISCOVER from the MAC asking for a broadcast reply
+func BuildDHCPDiscover(mac net.HardwareAddr, xid uint32) []byte {
+	b := make([]byte, dhcpHeaderLen, dhcpHeaderLen+32)
+	b[0], b[1], b[2] = dhcpBootRequest, 1, byte(len(mac))
+	binary.BigEndian.PutUint32(b[4:8], xid)
+	binary.BigEndian.PutUint16(b[10:12], dhcpFlagBroadcast)
+	copy(b[28:28+16], mac)
+	b = append(b, dhcpMagicCookie...)
+	b = append(b, dhcpOptionMessageType, 1, dhcpMessageDiscover)
+	b = append(b, dhcpOptionClientID, byte(len(mac)+1), 1)
+	b = append(b, mac...)
+	b = append(b,
+		dhcpOptionParameterList, 5,
+		dhcpOptionSubnetMask, dhcpOptionRouter, dhcpOptionDNS,
+		dhcpOptionLeaseTime, dhcpOptionServerID,
+	)
+	return append(b, dhcpOptionEnd)
+}
+
+// ParseDHCPOffer reads a DHCPOFFER answering the discover with the transaction id
+func ParseDHCPOffer(b []byte, xid uint32) (DHCPOffer, error) {
+	var offer DHCPOffer
+	if len(b) < dhcpHeaderLen+len(dhcpMagicCookie) || b[0] != dhcpBootReply {
+		return offer, ErrNotDHCPOffer
+	}
+	if binary.BigEndian.Uint32(b[4:8]) != xid {
+		return offer, ErrNotDHCPOffer
+	}
+	if !bytes.Equal(b[dhcpHeaderLen:dhcpHeaderLen+len(dhcpMagicCookie)], dhcpMagicCookie) {
+		return offer, ErrNotDHCPOffer
+	}
+	offer.Addr = netip.AddrFrom4([4]byte(b[16:20]))
+
+	messageType := 0
+	options := b[dhcpHeaderLen+len(dhcpMagicCookie):]
+	for len(options) > 0 {
+		code := options[0]
+		if code == dhcpOptionEnd {
+			break
+		}
+		if code == dhcpOptionPad {
+			options = options[1:]
+			continue
+		}
+		if len(options) < 2 || len(options) < 2+int(options[1]) {
+			return offer, ErrNotDHCPOffer
+		}
+		value := options[2 : 2+int(options[1])]
+		options = options[2+int(options[1]):]
+		switch code {
+		case dhcpOptionMessageType:
+			if len(value) == 1 {
+				messageType = int(value[0])
+			}
+		case dhcpOptionServerID:
+			if len(value) == 4 {
+				offer.Server = netip.AddrFrom4([4]byte(value))
+			}
+		case dhcpOptionSubnetMask:
+			if len(value) == 4 {
+				offer.Mask = net.IPMask(bytes.Clone(value))
+			}
+		case dhcpOptionRouter:
+			offer.Routers = dhcpAddrList(value)
+		case dhcpOptionDNS:
+			offer.DNS = dhcpAddrList(value)
+		case dhcpOptionLeaseTime:
+			if len(value) == 4 {
+				offer.LeaseTime = time.Duration(binary.BigEndian.Uint32(value)) * time.Second
+			}
+		}
+	}
+	if messageType != dhcpMessageOffer {
+		return offer, ErrNotDHCPOffer
+	}
+	return offer, nil
+}
+
+func dhcpAddrList(value []byte) []netip.Addr {
+	addrs := make([]netip.Addr, 0, len(value)/4)
+	for len(value) >= 4 {
+		addrs = append(addrs, netip.AddrFrom4([4]byte(value[:4])))
+		value = value[4:]
+	}
+	return addrs
+}
+
+// randomLocalMAC returns a locally administered unicast MAC, so the probe does not take over
+// the lease of the host
+func randomLocalMAC() net.HardwareAddr {
+	mac := make(net.HardwareAddr, 6)
+	_, _ = rand.Read(mac)
+	mac[0] = (mac[0] | 0x02) & 0xfe
+	return mac
+}
+
+//
+// Options available for DHCP probes
+//
+
+type dhcpProbeOptions struct {
+	listenAddress   string
+	responseTimeout time.Duration
+	mac             net.HardwareAddr
+}
+
+func defaultDHCPProbeOptions() *dhcpProbeOptions {
+	return &dhcpProbeOptions{
+		listenAddress:   ":68",
+		responseTimeout: 5 * time.Second,
+	}
+}
+
+func WithDHCPProbeReplyTimeout(duration time.Duration) dhcpProbeOptionFunc {
+	return func(o *dhcpProbeOptions) {
+		o.responseTimeout = duration
+	}
+}
+
+func WithDHCPProbeListenAddress(address string) dhcpProbeOptionFunc {
+	return func(o *dhcpProbeOptions) {
+		o.listenAddress = address
+	}
+}
+
+// WithDHCPProbeMAC sets the client MAC of the discover, a random locally administered MAC
+// is used otherwise
+func WithDHCPProbeMAC(mac net.HardwareAddr) dhcpProbeOptionFunc {
+	return func(o *dhcpProbeOptions) {
+		o.mac = mac
+	}
+}
+
+type dhcpProbeOptionFunc func(*dhcpProbeOptions)
+
+func applyDHCPProbeOptions(options ...dhcpProbeOptionFunc) *dhcpProbeOptions {
+	opts := defaultDHCPProbeOptions()
+	for _, f := range options {
+		f(opts)
+	}
+	return opts
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func dhcpOfferPacket(xid uint32, yiaddr [4]byte, options ...byte) []byte {
+	b := make([]byte, dhcpHeaderLen)
+	b[0], b[1], b[2] = dhcpBootReply, 1, 6
+	binary.BigEndian.PutUint32(b[4:8], xid)
+	copy(b[16:20], yiaddr[:])
+	b = append(b, dhcpMagicCookie...)
+	return append(b, options...)
+}
+
+func TestBuildDHCPDiscover(t *testing.T) {
+	mac := net.HardwareAddr{0x02, 0x11, 0x22, 0x33, 0x44, 0x55}
+	b := BuildDHCPDiscover(mac, 0xdeadbeef)
+	if b[0] != dhcpBootRequest {
+		t.Errorf("op want: %d, got: %d", dhcpBootRequest, b[0])
+	}
+	if got := binary.BigEndian.Uint32(b[4:8]); got != 0xdeadbeef {
+		t.Errorf("xid want: %x, got: %x", 0xdeadbeef, got)
+	}
+	if got := binary.BigEndian.Uint16(b[10:12]); got != dhcpFlagBroadcast {
+		t.Errorf("flags want: %x, got: %x", dhcpFlagBroadcast, got)
+	}
+	// the request parser reads the discover back
+	req, err := ParseDHCPRequest(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.MAC.String() != mac.String() {
+		t.Errorf("mac want: %s, got: %s", mac, req.MAC)
+	}
+}
+
+func TestParseDHCPOffer(t *testing.T) {
+	const xid = 0x01020304
+	tests := map[string]struct {
+		input []byte
+		want  DHCPOffer
+		err   error
+	}{
+		"Offer": {
+			input: dhcpOfferPacket(xid, [4]byte{192, 168, 1, 50},
+				dhcpOptionMessageType, 1, dhcpMessageOffer,
+				dhcpOptionServerID, 4, 192, 168, 1, 1,
+				dhcpOptionSubnetMask, 4, 255, 255, 255, 0,
+				dhcpOptionRouter, 4, 192, 168, 1, 1,
+				dhcpOptionDNS, 8, 1, 1, 1, 1, 8, 8, 8, 8,
+				dhcpOptionLeaseTime, 4, 0, 0, 0x0e, 0x10,
+				dhcpOptionEnd,
+			),
+			want: DHCPOffer{
+				Server:    netip.MustParseAddr("192.168.1.1"),
+				Addr:      netip.MustParseAddr("192.168.1.50"),
+				Mask:      net.IPv4Mask(255, 255, 255, 0),
+				Routers:   []netip.Addr{netip.MustParseAddr("192.168.1.1")},
+				DNS:       []netip.Addr{netip.MustParseAddr("1.1.1.1"), netip.MustParseAddr("8.8.8.8")},
+				LeaseTime: time.Hour,
+			},
+		},
+		"NoServerID": {
+			input: dhcpOfferPacket(xid, [4]byte{10, 0, 0, 9},
+				dhcpOptionMessageType, 1, dhcpMessageOffer,
+				dhcpOptionEnd,
+			),
+			want: DHCPOffer{Addr: netip.MustParseAddr("10.0.0.9")},
+		},
+		"OtherTransaction": {
+			input: dhcpOfferPacket(xid+1, [4]byte{},
+				dhcpOptionMessageType, 1, dhcpMessageOffer,
+				dhcpOptionEnd,
+			),
+			err: ErrNotDHCPOffer,
+		},
+		"Ack": {
+			input: dhcpOfferPacket(xid, [4]byte{}, dhcpOptionMessageType, 1, 5, dhcpOptionEnd),
+			err:   ErrNotDHCPOffer,
+		},
+		"Request": {
+			input: dhcpPacket(dhcpBootRequest, [4]byte{}, dhcpOptionEnd),
+			err:   ErrNotDHCPOffer,
+		},
+		"Truncated": {
+			input: dhcpOfferPacket(xid, [4]byte{}, dhcpOptionRouter, 8, 192, 168),
+			err:   ErrNotDHCPOffer,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseDHCPOffer(tc.input, xid)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("error want: %v, got: %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			diff := cmp.Diff(tc.want, got, cmpopts.EquateComparable(netip.Addr{}), cmpopts.EquateEmpty())
+			if diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net"
 	"net/netip"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/miekg/dns"
@@ -20,7 +21,9 @@ type Dnser interface {
 	FindFirstAddrOf(string) (netip.Addr, error)
 	FindAddrsOf(string) ([]netip.Addr, error)
 	FindHostnameOf(netip.Addr) (string, error)
+	FindMDNSNameOf(context.Context, netip.Addr, time.Duration) (string, error)
 	DNSCheckAllServers(context.Context, string) (map[string]map[string][]netip.Addr, error)
+	DNSCompareResolvers(context.Context, string) ([]DNSResolverResult, error)
 }
 
 // FindFirstAddrOf will return the netip.Addr of the first A record of the target.  If no A records are returned a ErrEmptyResponse error will be returned
@@ -0,0 +1,242 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"slices"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+type (
+	DNSTransport      string
+	DNSResolverStatus string
+
+	// DNSResolverResult is the answer of one resolver over one transport, Status reports if
+	// the resolver looks blocked or intercepted on the local network
+	DNSResolverResult struct {
+		Provider  string
+		Transport DNSTransport
+		Server    string
+		Addrs     []netip.Addr
+		Status    DNSResolverStatus
+		Err       error
+	}
+
+	dnsEndpoint struct {
+		provider   string
+		transport  DNSTransport
+		server     string
+		serverName string
+	}
+)
+
+const (
+	DNSTransportUDP DNSTransport = "udp"
+	DNSTransportDoT DNSTransport = "dot"
+	DNSTransportDoH DNSTransport = "doh"
+
+	DNSResolverOK          DNSResolverStatus = "ok"
+	DNSResolverBlocked     DNSResolverStatus = "blocked"
+	DNSResolverIntercepted DNSResolverStatus = "intercepted"
+
+	dnsMessageContentType = "application/dns-message"
+)
+
+var dnsEncryptedEndpoints = []dnsEndpoint{
+	{cloudflare, DNSTransportDoT, "1.1.1.1:853", "cloudflare-dns.com"},
+	{cloudflare, DNSTransportDoH, "https://cloudflare-dns.com/dns-query", ""},
+	{google, DNSTransportDoT, "8.8.8.8:853", "dns.google"},
+	{google, DNSTransportDoH, "https://dns.google/dns-query", ""},
+	{quad9, DNSTransportDoT, "9.9.9.9:853", "dns.quad9.net"},
+	{quad9, DNSTransportDoH, "https://dns.quad9.net/dns-query", ""},
+}
+
+// DNSCompareResolvers resolves the A records of target with the plain, DNS-over-TLS and
+// DNS-over-HTTPS endpoints of the public resolvers offering all three.  A resolver which
+// cannot be reached is reported as blocked, a plain answer which shares no address with the
+// encrypted answers of the same provider or a certificate which does not verify is reported
+// as intercepted.
+func DNSCompareResolvers(ctx context.Context, target string) ([]DNSResolverResult, error) {
+	return DefaultPkg.DNSCompareResolvers(ctx, target)
+}
+
+func (p pkg) DNSCompareResolvers(ctx context.Context, target string) ([]DNSResolverResult, error) {
+	// set the user agent before the queries share p
+	p.GetUserAgent()
+	endpoints := make([]dnsEndpoint, 0, len(dnsEncryptedEndpoints)*2)
+	for _, e := range dnsEncryptedEndpoints {
+		if e.transport == DNSTransportDoT {
+			endpoints = append(endpoints, dnsEndpoint{
+				provider:  e.provider,
+				transport: DNSTransportUDP,
+				server:    p.dnssvrs[e.provider]["A"],
+			})
+		}
+		endpoints = append(endpoints, e)
+	}
+
+	results := make([]DNSResolverResult, len(endpoints))
+	var wg sync.WaitGroup
+	for idx, e := range endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			addrs, err := p.exchangeDNS(ctx, e, target)
+			results[idx] = DNSResolverResult{
+				Provider:  e.provider,
+				Transport: e.transport,
+				Server:    e.server,
+				Addrs:     addrs,
+				Err:       err,
+			}
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return results, ctx.Err()
+	}
+	classifyDNSResults(results)
+	return results, nil
+}
+
+func (p pkg) exchangeDNS(ctx context.Context, e dnsEndpoint, target string) ([]netip.Addr, error) {
+	m := &dns.Msg{
+		MsgHdr: dns.MsgHdr{
+			RecursionDesired: true,
+		},
+	}
+	m.SetQuestion(dns.Fqdn(target), dns.TypeA)
+
+	var (
+		response *dns.Msg
+		err      error
+	)
+	switch e.transport {
+	case DNSTransportDoH:
+		response, err = p.exchangeDoH(ctx, m, e.server)
+	case DNSTransportDoT:
+		client := &dns.Client{
+			Net:       "tcp-tls",
+			Timeout:   p.dnsclient.Timeout,
+			TLSConfig: &tls.Config{ServerName: e.serverName},
+		}
+		response, _, err = client.ExchangeContext(ctx, m, e.server)
+	default:
+		response, _, err = p.dnsclient.ExchangeContext(ctx, m, e.server)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		return nil, ErrNoResponseFromRemote
+	}
+	addrs := make([]netip.Addr, 0, len(response.Answer))
+	for _, rec := range response.Answer {
+		arec, ok := rec.(*dns.A)
+		if !ok {
+			continue
+		}
+		ip, ok := netip.AddrFromSlice(arec.A)
+		if ok {
+			addrs = append(addrs, ip.Unmap())
+		}
+	}
+	return addrs, nil
+}
+
+// exchangeDoH sends the query as a RFC 8484 POST, certificates are verified so an
+// intercepting proxy shows up as an error
+func (p pkg) exchangeDoH(ctx context.Context, m *dns.Msg, url string) (*dns.Msg, error) {
+	packed, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(packed))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", dnsMessageContentType)
+	req.Header.Set("Accept", dnsMessageContentType)
+	req.Header.Set("User-Agent", p.userAgent)
+
+	resp, err := p.verifyclient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("doh %s: %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, err
+	}
+	response := &dns.Msg{}
+	err = response.Unpack(body)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// classifyDNSResults sets the status of each result, plain answers are checked against the
+// encrypted answers of the same provider
+func classifyDNSResults(results []DNSResolverResult) {
+	encrypted := make(map[string][]netip.Addr)
+	for _, r := range results {
+		if r.Transport != DNSTransportUDP && r.Err == nil {
+			encrypted[r.Provider] = append(encrypted[r.Provider], r.Addrs...)
+		}
+	}
+	for idx, r := range results {
+		answers, checked := encrypted[r.Provider]
+		switch {
+		case r.Err != nil && isCertificateError(r.Err):
+			results[idx].Status = DNSResolverIntercepted
+		case r.Err != nil:
+			results[idx].Status = DNSResolverBlocked
+		case r.Transport == DNSTransportUDP && checked && !sameAnswer(r.Addrs, answers):
+			results[idx].Status = DNSResolverIntercepted
+		default:
+			results[idx].Status = DNSResolverOK
+		}
+	}
+}
+
+// sameAnswer reports if the plain answer matches the encrypted answers, answers can differ
+// between requests so sharing a single address is enough
+func sameAnswer(plain []netip.Addr, encrypted []netip.Addr) bool {
+	if len(plain) == 0 || len(encrypted) == 0 {
+		return len(plain) == len(encrypted)
+	}
+	for _, addr := range plain {
+		if slices.Contains(encrypted, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCertificateError(err error) bool {
+	var (
+		verr *tls.CertificateVerificationError
+		herr x509.HostnameError
+		uerr x509.UnknownAuthorityError
+		cerr x509.CertificateInvalidError
+	)
+	return errors.As(err, &verr) || errors.As(err, &herr) || errors.As(err, &uerr) ||
+		errors.As(err, &cerr)
+}
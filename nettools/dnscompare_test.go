@@ -0,0 +1,78 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClassifyDNSResults(t *testing.T) {
+	answer := []netip.Addr{netip.MustParseAddr("93.184.216.34")}
+	other := []netip.Addr{netip.MustParseAddr("10.0.0.1")}
+	timeout := errors.New("i/o timeout")
+	certerr := fmt.Errorf("tls: %w", x509.UnknownAuthorityError{})
+
+	tests := map[string]struct {
+		results []DNSResolverResult
+		want    []DNSResolverStatus
+	}{
+		"AllAgree": {
+			results: []DNSResolverResult{
+				{Provider: google, Transport: DNSTransportUDP, Addrs: answer},
+				{Provider: google, Transport: DNSTransportDoT, Addrs: answer},
+				{Provider: google, Transport: DNSTransportDoH, Addrs: answer},
+			},
+			want: []DNSResolverStatus{DNSResolverOK, DNSResolverOK, DNSResolverOK},
+		},
+		"PlainRewritten": {
+			results: []DNSResolverResult{
+				{Provider: google, Transport: DNSTransportUDP, Addrs: other},
+				{Provider: google, Transport: DNSTransportDoT, Addrs: answer},
+				{Provider: google, Transport: DNSTransportDoH, Addrs: answer},
+			},
+			want: []DNSResolverStatus{DNSResolverIntercepted, DNSResolverOK, DNSResolverOK},
+		},
+		"PlainEmpty": {
+			results: []DNSResolverResult{
+				{Provider: google, Transport: DNSTransportUDP},
+				{Provider: google, Transport: DNSTransportDoH, Addrs: answer},
+			},
+			want: []DNSResolverStatus{DNSResolverIntercepted, DNSResolverOK},
+		},
+		"EncryptedBlocked": {
+			results: []DNSResolverResult{
+				{Provider: quad9, Transport: DNSTransportUDP, Addrs: other},
+				{Provider: quad9, Transport: DNSTransportDoT, Err: timeout},
+				{Provider: quad9, Transport: DNSTransportDoH, Err: certerr},
+			},
+			want: []DNSResolverStatus{DNSResolverOK, DNSResolverBlocked, DNSResolverIntercepted},
+		},
+		"ProvidersSeparate": {
+			results: []DNSResolverResult{
+				{Provider: google, Transport: DNSTransportUDP, Addrs: other},
+				{Provider: cloudflare, Transport: DNSTransportDoH, Addrs: answer},
+			},
+			want: []DNSResolverStatus{DNSResolverOK, DNSResolverOK},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			classifyDNSResults(tc.results)
+			got := make([]DNSResolverStatus, 0, len(tc.results))
+			for _, r := range tc.results {
+				got = append(got, r.Status)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
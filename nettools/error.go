@@ -41,7 +41,21 @@ var (
 
 	ErrNoDnsNames = errors.New("no dns names")
 
-	ErrInvalidPortListString = errors.New("invalid port list string")
+	ErrInvalidPortListString     = errors.New("invalid port list string")
+	ErrInvalidPortscanModeString = errors.New("invalid port scan mode string")
+	ErrSynScanUnavailable        = errors.New("syn scan unavailable")
+
+	ErrInvalidTraceProbeString = errors.New("invalid trace probe string")
+	ErrTraceProbeUnavailable   = errors.New("trace probe unavailable")
+	ErrDestinationUnreachable  = errors.New("destination unreachable")
+	ErrNotOnLink               = errors.New("target not on a local segment")
+
+	ErrInvalidPayloadSize = errors.New("invalid icmp payload size")
+	ErrInvalidDSCP        = errors.New("invalid dscp, must be 0 to 63")
+	ErrInvalidHelperArgs  = errors.New("invalid privileged helper request")
+
+	ErrInvalidSplit   = errors.New("invalid subnet split")
+	ErrTooManySubnets = errors.New("too many subnets")
 )
 
 type ErrNoResponseW struct {
@@ -0,0 +1,381 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+const (
+	helperServiceName = "Helper"
+
+	helperErrNoResponse = "noresponse"
+	helperErrTTL        = "ttlexceeded"
+
+	// maxHelperTimeout bounds how long a request holds a raw socket of the helper
+	maxHelperTimeout = 30 * time.Second
+)
+
+// UsePrivilegedHelper sends the privileged icmp echo requests and the arp requests to the
+// helper listening on the unix socket, the calling process then needs no raw socket access
+func UsePrivilegedHelper(socket string) {
+	DefaultPkg.helper = &helperClient{socket: socket}
+}
+
+// ServePrivilegedHelper answers the requests of processes using UsePrivilegedHelper until the
+// context is done.  Only sending icmp echo requests, traceroute probes and arp requests is
+// offered.
+func ServePrivilegedHelper(ctx context.Context, ln net.Listener) error {
+	server := rpc.NewServer()
+	err := server.RegisterName(helperServiceName, &PrivilegedHelper{})
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go server.ServeConn(conn)
+	}
+}
+
+// privilegedPingIcmp4 sends the icmp echo request over a raw socket, through the helper
+// when one is used
+func (p *pkg) privilegedPingIcmp4(
+	ctx context.Context,
+	target netip.Addr,
+	ttl int,
+	listenAddress netip.Addr,
+	readTimeout time.Duration,
+	icmpID int,
+	icmpSeq int,
+	payloadSize int,
+	dscp int,
+	allowAllErrors bool,
+) (Icmp4EchoResponse, error) {
+	if p.helper == nil {
+		return rawPingIcmp4(
+			ctx, target, ttl, listenAddress, readTimeout, icmpID, icmpSeq, payloadSize, dscp,
+			allowAllErrors,
+		)
+	}
+	return p.helper.pingIcmp4(ctx, HelperPingArgs{
+		Target:         target,
+		TTL:            ttl,
+		ListenAddress:  listenAddress,
+		ReadTimeout:    readTimeout,
+		IcmpID:         icmpID,
+		IcmpSeq:        icmpSeq,
+		PayloadSize:    payloadSize,
+		DSCP:           dscp,
+		AllowAllErrors: allowAllErrors,
+	})
+}
+
+// resolveHardwareAddr sends the arp request, through the helper when one is used
+func (p *pkg) resolveHardwareAddr(
+	ctx context.Context,
+	ifname string,
+	target netip.Addr,
+	timeout time.Duration,
+) (net.HardwareAddr, error) {
+	if p.helper == nil {
+		return resolveHardwareAddr(ifname, target, timeout)
+	}
+	return p.helper.resolveHardwareAddr(ctx, HelperArpArgs{
+		IfName:  ifname,
+		Target:  target,
+		Timeout: timeout,
+	})
+}
+
+// PrivilegedHelper is the rpc service of the helper
+type PrivilegedHelper struct{}
+
+type HelperPingArgs struct {
+	Target         netip.Addr
+	TTL            int
+	ListenAddress  netip.Addr
+	ReadTimeout    time.Duration
+	IcmpID         int
+	IcmpSeq        int
+	PayloadSize    int
+	DSCP           int
+	AllowAllErrors bool
+}
+
+type HelperPingReply struct {
+	Peer     netip.Addr
+	Start    time.Time
+	Elapsed  time.Duration
+	ReplyErr HelperError
+	Err      HelperError
+}
+
+type HelperProbeArgs struct {
+	Probe         TraceProbe
+	Target        netip.Addr
+	TTL           int
+	Port          int
+	ListenAddress netip.Addr
+	ReadTimeout   time.Duration
+}
+
+type HelperArpArgs struct {
+	IfName  string
+	Target  netip.Addr
+	Timeout time.Duration
+}
+
+type HelperArpReply struct {
+	MAC net.HardwareAddr
+	Err HelperError
+}
+
+// HelperError carries an error over the rpc, the errors callers test for keep their kind
+type HelperError struct {
+	Kind string
+	Msg  string
+}
+
+// Ping sends the icmp echo request, the args are checked before a raw socket is opened since
+// any process able to connect to the socket may call it
+func (h *PrivilegedHelper) Ping(args HelperPingArgs, reply *HelperPingReply) error {
+	err := args.validate()
+	if err != nil {
+		return err
+	}
+	args.ReadTimeout = clampHelperTimeout(args.ReadTimeout)
+	r, err := rawPingIcmp4(
+		context.Background(),
+		args.Target,
+		args.TTL,
+		args.ListenAddress,
+		args.ReadTimeout,
+		args.IcmpID,
+		args.IcmpSeq,
+		args.PayloadSize,
+		args.DSCP,
+		args.AllowAllErrors,
+	)
+	reply.Peer = r.Peer
+	reply.Start = r.Start
+	reply.Elapsed = r.Elapsed
+	reply.ReplyErr = toHelperError(r.Err)
+	reply.Err = toHelperError(err)
+	return nil
+}
+
+func (h *PrivilegedHelper) Probe(args HelperProbeArgs, reply *HelperPingReply) error {
+	err := args.validate()
+	if err != nil {
+		return err
+	}
+	args.ReadTimeout = clampHelperTimeout(args.ReadTimeout)
+	r, err := rawTraceProbe4(
+		context.Background(),
+		args.Probe,
+		args.Target,
+		args.TTL,
+		args.Port,
+		args.ListenAddress,
+		args.ReadTimeout,
+	)
+	reply.Peer = r.Peer
+	reply.Start = r.Start
+	reply.Elapsed = r.Elapsed
+	reply.ReplyErr = toHelperError(r.Err)
+	reply.Err = toHelperError(err)
+	return nil
+}
+
+func (h *PrivilegedHelper) Arp(args HelperArpArgs, reply *HelperArpReply) error {
+	err := args.validate()
+	if err != nil {
+		return err
+	}
+	args.Timeout = clampHelperTimeout(args.Timeout)
+	mac, err := resolveHardwareAddr(args.IfName, args.Target, args.Timeout)
+	reply.MAC = mac
+	reply.Err = toHelperError(err)
+	return nil
+}
+
+func (a HelperPingArgs) validate() error {
+	err := validateHelperTarget(a.Target, a.TTL)
+	if err != nil {
+		return err
+	}
+	opt := Icmp4EchoOptions{PayloadSize: a.PayloadSize, DSCP: a.DSCP}
+	return opt.validate()
+}
+
+func (a HelperProbeArgs) validate() error {
+	err := validateHelperTarget(a.Target, a.TTL)
+	if err != nil {
+		return err
+	}
+	switch a.Probe {
+	case IcmpTraceProbe, UdpTraceProbe, TcpTraceProbe:
+	default:
+		return fmt.Errorf("%w: probe %d", ErrInvalidHelperArgs, a.Probe)
+	}
+	if a.Port < 0 || a.Port > 65535 {
+		return fmt.Errorf("%w: port %d", ErrInvalidHelperArgs, a.Port)
+	}
+	return nil
+}
+
+func (a HelperArpArgs) validate() error {
+	if a.IfName == "" {
+		return fmt.Errorf("%w: no interface", ErrInvalidHelperArgs)
+	}
+	if !a.Target.IsValid() {
+		return fmt.Errorf("%w: no target", ErrInvalidHelperArgs)
+	}
+	return nil
+}
+
+func validateHelperTarget(target netip.Addr, ttl int) error {
+	if !target.Is4() {
+		return fmt.Errorf("%w: target %s is not ipv4", ErrInvalidHelperArgs, target)
+	}
+	if ttl < 1 || ttl > 255 {
+		return fmt.Errorf("%w: ttl %d", ErrInvalidHelperArgs, ttl)
+	}
+	return nil
+}
+
+// clampHelperTimeout keeps the timeout of a request within maxHelperTimeout, a request
+// without one is given the most
+func clampHelperTimeout(d time.Duration) time.Duration {
+	if d <= 0 || d > maxHelperTimeout {
+		return maxHelperTimeout
+	}
+	return d
+}
+
+func toHelperError(err error) HelperError {
+	switch {
+	case err == nil:
+		return HelperError{}
+	case errors.Is(err, ErrNoResponseFromRemote):
+		return HelperError{Kind: helperErrNoResponse, Msg: err.Error()}
+	case errors.Is(err, ErrTTLExceeded):
+		return HelperError{Kind: helperErrTTL, Msg: err.Error()}
+	}
+	return HelperError{Msg: err.Error()}
+}
+
+func (e HelperError) err() error {
+	switch {
+	case e.Kind == helperErrNoResponse:
+		return ErrNoResponseFromRemote
+	case e.Kind == helperErrTTL:
+		return ErrTTLExceeded
+	case e.Msg != "":
+		return errors.New(e.Msg)
+	}
+	return nil
+}
+
+// helperClient keeps one connection to the helper, it is dialed again once it breaks
+type helperClient struct {
+	socket string
+	mu     sync.Mutex
+	client *rpc.Client
+}
+
+func (c *helperClient) call(ctx context.Context, method string, args any, reply any) error {
+	c.mu.Lock()
+	if c.client == nil {
+		client, err := rpc.Dial("unix", c.socket)
+		if err != nil {
+			c.mu.Unlock()
+			return err
+		}
+		c.client = client
+	}
+	client := c.client
+	c.mu.Unlock()
+
+	var err error
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case call := <-client.Go(helperServiceName+"."+method, args, reply, nil).Done:
+		err = call.Error
+	}
+	if errors.Is(err, rpc.ErrShutdown) || errors.Is(err, io.ErrUnexpectedEOF) {
+		c.mu.Lock()
+		if c.client == client {
+			c.client = nil
+		}
+		c.mu.Unlock()
+	}
+	return err
+}
+
+func (c *helperClient) pingIcmp4(
+	ctx context.Context,
+	args HelperPingArgs,
+) (Icmp4EchoResponse, error) {
+	var reply HelperPingReply
+	err := c.call(ctx, "Ping", args, &reply)
+	if err != nil {
+		return Icmp4EchoResponse{}, err
+	}
+	return Icmp4EchoResponse{
+		Peer:    reply.Peer,
+		Start:   reply.Start,
+		Elapsed: reply.Elapsed,
+		Err:     reply.ReplyErr.err(),
+	}, reply.Err.err()
+}
+
+func (c *helperClient) traceProbe4(
+	ctx context.Context,
+	args HelperProbeArgs,
+) (Icmp4EchoResponse, error) {
+	var reply HelperPingReply
+	err := c.call(ctx, "Probe", args, &reply)
+	if err != nil {
+		return Icmp4EchoResponse{}, err
+	}
+	return Icmp4EchoResponse{
+		Peer:    reply.Peer,
+		Start:   reply.Start,
+		Elapsed: reply.Elapsed,
+		Err:     reply.ReplyErr.err(),
+	}, reply.Err.err()
+}
+
+func (c *helperClient) resolveHardwareAddr(
+	ctx context.Context,
+	args HelperArpArgs,
+) (net.HardwareAddr, error) {
+	var reply HelperArpReply
+	err := c.call(ctx, "Arp", args, &reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply.MAC, reply.Err.err()
+}
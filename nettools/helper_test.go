@@ -0,0 +1,152 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHelperError(t *testing.T) {
+	tests := map[string]struct {
+		input error
+		want  error
+	}{
+		"Nil":        {input: nil, want: nil},
+		"NoResponse": {input: newErrNoResponse(netip.Addr{}, nil), want: ErrNoResponseFromRemote},
+		"TTL":        {input: ErrTTLExceeded, want: ErrTTLExceeded},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := toHelperError(tc.input).err()
+			if !errors.Is(got, tc.want) {
+				t.Errorf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+	got := toHelperError(errors.New("boom")).err()
+	if got == nil || got.Error() != "boom" {
+		t.Errorf("want: boom, got: %v", got)
+	}
+}
+
+func TestPrivilegedHelper(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	socket := filepath.Join(t.TempDir(), "helper.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- ServePrivilegedHelper(ctx, ln) }()
+
+	client := &helperClient{socket: socket}
+	_, err = client.resolveHardwareAddr(ctx, HelperArpArgs{
+		IfName:  "nosuchinterface",
+		Target:  netip.MustParseAddr("192.0.2.1"),
+		Timeout: time.Millisecond,
+	})
+	if err == nil {
+		t.Fatal("want an error for a missing interface, got nil")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("serve want: nil, got: %v", err)
+	}
+}
+
+func TestPrivilegedHelper_InvalidArgs(t *testing.T) {
+	h := &PrivilegedHelper{}
+	target := netip.MustParseAddr("192.0.2.1")
+	tests := map[string]struct {
+		call func() error
+		want error
+	}{
+		"PingPayload": {
+			call: func() error {
+				return h.Ping(HelperPingArgs{Target: target, TTL: 64, PayloadSize: 1 << 30}, &HelperPingReply{})
+			},
+			want: ErrInvalidPayloadSize,
+		},
+		"PingNegativePayload": {
+			call: func() error {
+				return h.Ping(HelperPingArgs{Target: target, TTL: 64, PayloadSize: -1}, &HelperPingReply{})
+			},
+			want: ErrInvalidPayloadSize,
+		},
+		"PingDSCP": {
+			call: func() error {
+				return h.Ping(HelperPingArgs{Target: target, TTL: 64, DSCP: 64}, &HelperPingReply{})
+			},
+			want: ErrInvalidDSCP,
+		},
+		"PingTTL": {
+			call: func() error {
+				return h.Ping(HelperPingArgs{Target: target, TTL: 256}, &HelperPingReply{})
+			},
+			want: ErrInvalidHelperArgs,
+		},
+		"PingIPv6": {
+			call: func() error {
+				return h.Ping(HelperPingArgs{Target: netip.MustParseAddr("2001:db8::1"), TTL: 64}, &HelperPingReply{})
+			},
+			want: ErrInvalidHelperArgs,
+		},
+		"ProbeKind": {
+			call: func() error {
+				return h.Probe(HelperProbeArgs{Probe: InvalidTraceProbe, Target: target, TTL: 1}, &HelperPingReply{})
+			},
+			want: ErrInvalidHelperArgs,
+		},
+		"ProbePort": {
+			call: func() error {
+				return h.Probe(HelperProbeArgs{Probe: UdpTraceProbe, Target: target, TTL: 1, Port: 70000}, &HelperPingReply{})
+			},
+			want: ErrInvalidHelperArgs,
+		},
+		"ArpNoInterface": {
+			call: func() error {
+				return h.Arp(HelperArpArgs{Target: target}, &HelperArpReply{})
+			},
+			want: ErrInvalidHelperArgs,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tc.call()
+			if !errors.Is(err, tc.want) {
+				t.Errorf("want: %v, got: %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestClampHelperTimeout(t *testing.T) {
+	tests := map[string]struct {
+		input time.Duration
+		want  time.Duration
+	}{
+		"Zero":     {input: 0, want: maxHelperTimeout},
+		"Negative": {input: -time.Second, want: maxHelperTimeout},
+		"Within":   {input: time.Second, want: time.Second},
+		"TooLong":  {input: time.Hour, want: maxHelperTimeout},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := clampHelperTimeout(tc.input)
+			if got != tc.want {
+				t.Errorf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
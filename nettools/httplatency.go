@@ -0,0 +1,98 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var _ HTTPer = (*pkg)(nil)
+
+type HTTPer interface {
+	HTTPLatency(context.Context, string) (time.Duration, error)
+	HTTPCheck(context.Context, string, string, bool) (HTTPCheckResponse, error)
+}
+
+// httpCheckBodyLimit is the most of a response body read by HTTPCheck
+const httpCheckBodyLimit = 64 * 1024
+
+// HTTPCheckResponse is the status and the start of the body of a checked url, the latency is
+// the time until the response headers arrive
+type HTTPCheckResponse struct {
+	Status  int
+	Latency time.Duration
+	Body    []byte
+}
+
+// HTTPLatency returns the time from sending a GET of url until the response headers arrive,
+// the server certificate is verified and any status above 399 is an error
+func HTTPLatency(ctx context.Context, url string) (time.Duration, error) {
+	return DefaultPkg.HTTPLatency(ctx, url)
+}
+
+func (p *pkg) HTTPLatency(ctx context.Context, url string) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", p.GetUserAgent())
+
+	start := time.Now()
+	resp, err := p.verifyclient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	elapsed := time.Since(start)
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return elapsed, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return elapsed, nil
+}
+
+// HTTPCheck sends the request with the method to the url and returns the response status
+// and up to 64KiB of its body, any status is returned without error.  The server
+// certificate is only verified when insecure is false.
+func HTTPCheck(
+	ctx context.Context,
+	method string,
+	url string,
+	insecure bool,
+) (HTTPCheckResponse, error) {
+	return DefaultPkg.HTTPCheck(ctx, method, url, insecure)
+}
+
+func (p *pkg) HTTPCheck(
+	ctx context.Context,
+	method string,
+	url string,
+	insecure bool,
+) (r HTTPCheckResponse, err error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return r, err
+	}
+	req.Header.Set("User-Agent", p.GetUserAgent())
+
+	client := p.verifyclient
+	if insecure {
+		client = p.httpclient
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+	r.Latency = time.Since(start)
+	r.Status = resp.StatusCode
+	r.Body, err = io.ReadAll(io.LimitReader(resp.Body, httpCheckBodyLimit))
+	return r, err
+}
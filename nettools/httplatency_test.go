@@ -0,0 +1,58 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPLatency(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	elapsed, err := HTTPLatency(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed <= 0 {
+		t.Errorf("latency want > 0, got: %s", elapsed)
+	}
+
+	_, err = HTTPLatency(context.Background(), srv.URL+"/broken")
+	if err == nil {
+		t.Error("server error want error, got: nil")
+	}
+}
+
+func TestHTTPCheck(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			w.Write([]byte("status: ok"))
+		}
+	}))
+	defer srv.Close()
+
+	r, err := HTTPCheck(context.Background(), http.MethodGet, srv.URL, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != http.StatusOK || string(r.Body) != "status: ok" {
+		t.Errorf("want: 200 status: ok, got: %d %s", r.Status, r.Body)
+	}
+
+	_, err = HTTPCheck(context.Background(), http.MethodGet, srv.URL, false)
+	if err == nil {
+		t.Error("self signed certificate want error, got: nil")
+	}
+}
@@ -0,0 +1,352 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// icmpListenerBatchSize is the most echo requests written in one batch
+const icmpListenerBatchSize = 32
+
+// icmpListenerReadBuffer is the receive buffer of the shared socket, the os may cap it
+const icmpListenerReadBuffer = 4 << 20
+
+var (
+	ErrIcmpListenerClosed      = errors.New("icmp listener closed")
+	ErrIcmpListenerUnsupported = errors.New("shared icmp listener not supported")
+	ErrTooManyEchoes           = errors.New("too many echoes waiting for the target")
+)
+
+// IcmpListener shares a single icmp socket between all the echoes sent through it.  The
+// replies are demultiplexed to the waiting echo by peer and sequence number, and the queued
+// requests are written in batches paced to the rate, so thousands of devices can be pinged
+// without opening a socket per echo.
+type IcmpListener struct {
+	conn       *icmp.PacketConn
+	pc         *ipv4.PacketConn
+	privileged bool
+	id         int
+	rate       int
+	size       int
+	pending    *pendingEchoes
+	sends      chan *echoRequest
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+type echoRequest struct {
+	target netip.Addr
+	seq    int
+	sent   chan echoSent
+}
+
+type echoSent struct {
+	at  time.Time
+	err error
+}
+
+// NewIcmpListener opens the shared socket, a raw socket when privileged else an
+// unprivileged icmp socket, with the listen address, ttl, payload size and dscp of the
+// options.  The rate is the most echo requests sent per second, 0 sends them as fast as they
+// are queued.
+func NewIcmpListener(rate int, opts ...Icmp4EchoOption) (*IcmpListener, error) {
+	if runtime.GOOS == "windows" {
+		return nil, ErrIcmpListenerUnsupported
+	}
+	opt := i4eApplyOptionsToDefault(opts...)
+	err := opt.validate()
+	if err != nil {
+		return nil, err
+	}
+	network := "udp4"
+	if opt.Privileged {
+		network = "ip4:icmp"
+	}
+	conn, err := icmp.ListenPacket(network, opt.ListenAddress.String())
+	if err != nil {
+		return nil, err
+	}
+	l := &IcmpListener{
+		conn:       conn,
+		pc:         conn.IPv4PacketConn(),
+		privileged: opt.Privileged,
+		id:         opt.IcmpID,
+		rate:       rate,
+		size:       opt.PayloadSize,
+		pending:    newPendingEchoes(),
+		sends:      make(chan *echoRequest, icmpListenerBatchSize),
+		done:       make(chan struct{}),
+	}
+	err = l.pc.SetTTL(opt.TTL)
+	if err == nil {
+		err = setDSCP(l.pc, opt.DSCP)
+	}
+	if rb, ok := l.pc.PacketConn.(interface{ SetReadBuffer(int) error }); ok && err == nil {
+		// the replies to a whole batch arrive together
+		err = rb.SetReadBuffer(icmpListenerReadBuffer)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if l.privileged && runtime.GOOS == "linux" {
+		// a raw socket sees every icmp packet of the host, keep only the replies to ours
+		filter, err := buildIcmpFilterForID(uint32(l.id))
+		if err == nil {
+			err = l.pc.SetBPF(filter)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	go l.read()
+	go l.send()
+	return l, nil
+}
+
+// Close stops the listener, echoes waiting on it return ErrIcmpListenerClosed
+func (l *IcmpListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.done)
+		err = l.conn.Close()
+	})
+	return err
+}
+
+// Echo sends a single echo request to the target and waits up to the read timeout for its
+// reply
+func (l *IcmpListener) Echo(
+	ctx context.Context,
+	target netip.Addr,
+	readTimeout time.Duration,
+) (response Icmp4EchoResponse, err error) {
+	if ctx.Err() != nil {
+		return response, ctx.Err()
+	}
+	if !target.Is4() {
+		return response, ErrIPv6Unsupported
+	}
+	seq, reply, err := l.pending.register(target)
+	if err != nil {
+		return response, err
+	}
+	defer l.pending.cancel(target, seq, reply)
+
+	req := &echoRequest{target: target, seq: seq, sent: make(chan echoSent, 1)}
+	select {
+	case l.sends <- req:
+	case <-ctx.Done():
+		return response, ctx.Err()
+	case <-l.done:
+		return response, ErrIcmpListenerClosed
+	}
+	var sent echoSent
+	select {
+	case sent = <-req.sent:
+	case <-ctx.Done():
+		return response, ctx.Err()
+	case <-l.done:
+		return response, ErrIcmpListenerClosed
+	}
+	response.Start = sent.at
+	if sent.err != nil {
+		response.Err = sent.err
+		return response, sent.err
+	}
+
+	timer := time.NewTimer(readTimeout)
+	defer timer.Stop()
+	select {
+	case at := <-reply:
+		response.Peer = target
+		response.Elapsed = at.Sub(sent.at)
+		return response, nil
+	case <-timer.C:
+		response.Elapsed = time.Since(sent.at)
+		response.Err = ErrNoResponseFromRemote
+		return response, ErrNoResponseFromRemote
+	case <-ctx.Done():
+		return response, ctx.Err()
+	case <-l.done:
+		return response, ErrIcmpListenerClosed
+	}
+}
+
+// send writes the queued requests, gathering those already waiting into a batch
+func (l *IcmpListener) send() {
+	size := icmpListenerBatchSize
+	if l.rate > 0 && l.rate < size {
+		size = l.rate
+	}
+	batch := make([]*echoRequest, 0, size)
+	for {
+		select {
+		case <-l.done:
+			return
+		case req := <-l.sends:
+			batch = append(batch[:0], req)
+		}
+	gather:
+		for len(batch) < size {
+			select {
+			case req := <-l.sends:
+				batch = append(batch, req)
+			default:
+				break gather
+			}
+		}
+		l.writeBatch(batch)
+		if l.rate > 0 {
+			select {
+			case <-l.done:
+				return
+			case <-time.After(time.Duration(len(batch)) * time.Second / time.Duration(l.rate)):
+			}
+		}
+	}
+}
+
+func (l *IcmpListener) writeBatch(batch []*echoRequest) {
+	msgs := make([]ipv4.Message, len(batch))
+	for i, req := range batch {
+		msgs[i] = ipv4.Message{
+			Buffers: [][]byte{buildIcmpMessageBody(l.id, req.seq, l.size)},
+			Addr:    l.peerAddr(req.target),
+		}
+	}
+	for written := 0; written < len(msgs); {
+		at := time.Now()
+		n, err := l.pc.WriteBatch(msgs[written:], 0)
+		if err != nil {
+			for _, req := range batch[written:] {
+				req.sent <- echoSent{at: at, err: err}
+			}
+			return
+		}
+		for _, req := range batch[written : written+n] {
+			req.sent <- echoSent{at: at}
+		}
+		written += n
+	}
+}
+
+func (l *IcmpListener) peerAddr(target netip.Addr) net.Addr {
+	if l.privileged {
+		return &net.IPAddr{IP: target.AsSlice()}
+	}
+	return &net.UDPAddr{IP: target.AsSlice()}
+}
+
+// read hands each echo reply to the echo waiting for it
+func (l *IcmpListener) read() {
+	rb := make([]byte, replyBufferSize(l.size))
+	for {
+		n, peer, err := l.conn.ReadFrom(rb)
+		at := time.Now()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		rm, err := icmp.ParseMessage(ProtocolICMP, rb[:n])
+		if err != nil || rm.Type != ipv4.ICMPTypeEchoReply {
+			continue
+		}
+		pkt, ok := rm.Body.(*icmp.Echo)
+		if !ok {
+			continue
+		}
+		// the kernel sets the id of unprivileged echoes to the port of the socket, only the
+		// replies to this socket are read anyway
+		if l.privileged && pkt.ID != l.id {
+			continue
+		}
+		var addr netip.Addr
+		switch p := peer.(type) {
+		case *net.IPAddr:
+			addr, _ = netip.AddrFromSlice(p.IP)
+		case *net.UDPAddr:
+			addr, _ = netip.AddrFromSlice(p.IP)
+		}
+		l.pending.deliver(addr.Unmap(), pkt.Seq, at)
+	}
+}
+
+type echoKey struct {
+	peer netip.Addr
+	seq  int
+}
+
+// pendingEchoes are the echoes waiting for a reply, by target and sequence number
+type pendingEchoes struct {
+	mu      sync.Mutex
+	seq     int
+	waiting map[echoKey]chan time.Time
+}
+
+func newPendingEchoes() *pendingEchoes {
+	return &pendingEchoes{waiting: make(map[echoKey]chan time.Time)}
+}
+
+// register reserves the next sequence number not waiting on the target, the reply time is
+// sent on the returned channel
+func (p *pendingEchoes) register(target netip.Addr) (int, chan time.Time, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for range 0x10000 {
+		p.seq = (p.seq + 1) & 0xFFFF
+		key := echoKey{peer: target, seq: p.seq}
+		if _, ok := p.waiting[key]; !ok {
+			reply := make(chan time.Time, 1)
+			p.waiting[key] = reply
+			return p.seq, reply, nil
+		}
+	}
+	return 0, nil, ErrTooManyEchoes
+}
+
+// deliver passes the reply time to the echo waiting for it, reporting if there was one
+func (p *pendingEchoes) deliver(peer netip.Addr, seq int, at time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := echoKey{peer: peer, seq: seq}
+	reply, ok := p.waiting[key]
+	if !ok {
+		return false
+	}
+	delete(p.waiting, key)
+	reply <- at
+	return true
+}
+
+// cancel stops waiting, the sequence number may have been handed out again once the reply
+// was delivered so only the echo's own entry is removed
+func (p *pendingEchoes) cancel(target netip.Addr, seq int, reply chan time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := echoKey{peer: target, seq: seq}
+	if p.waiting[key] == reply {
+		delete(p.waiting, key)
+	}
+}
+
+func (p *pendingEchoes) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.waiting)
+}
@@ -0,0 +1,137 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPendingEchoes_Deliver(t *testing.T) {
+	a := netip.MustParseAddr("10.0.0.1")
+	b := netip.MustParseAddr("10.0.0.2")
+	at := time.Now()
+	tests := map[string]struct {
+		peer  netip.Addr
+		seq   func(seqA int, seqB int) int
+		want  bool
+		waitA bool
+		waitB bool
+	}{
+		"MatchA":    {peer: a, seq: func(sa, _ int) int { return sa }, want: true, waitA: true},
+		"MatchB":    {peer: b, seq: func(_, sb int) int { return sb }, want: true, waitB: true},
+		"WrongPeer": {peer: b, seq: func(sa, _ int) int { return sa }, want: false},
+		"WrongSeq":  {peer: a, seq: func(_, sb int) int { return sb + 100 }, want: false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := newPendingEchoes()
+			seqA, replyA, err := p.register(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			seqB, replyB, err := p.register(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := p.deliver(tc.peer, tc.seq(seqA, seqB), at)
+			if got != tc.want {
+				t.Fatalf("deliver want: %t, got: %t", tc.want, got)
+			}
+			if (len(replyA) == 1) != tc.waitA {
+				t.Errorf("reply a want: %t, got: %d", tc.waitA, len(replyA))
+			}
+			if (len(replyB) == 1) != tc.waitB {
+				t.Errorf("reply b want: %t, got: %d", tc.waitB, len(replyB))
+			}
+			if p.deliver(tc.peer, tc.seq(seqA, seqB), at) {
+				t.Error("reply delivered twice")
+			}
+		})
+	}
+}
+
+func TestPendingEchoes_RegisterSkipsWaiting(t *testing.T) {
+	target := netip.MustParseAddr("10.0.0.1")
+	p := newPendingEchoes()
+	first, reply, err := p.register(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// wrap the sequence around so the next one would be the waiting one
+	p.seq = first - 1
+	second, _, err := p.register(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == first {
+		t.Fatalf("sequence %d handed out while waiting", first)
+	}
+	p.cancel(target, first, reply)
+	if p.len() != 1 {
+		t.Errorf("pending want: 1, got: %d", p.len())
+	}
+}
+
+func TestPendingEchoes_CancelKeepsReissued(t *testing.T) {
+	target := netip.MustParseAddr("10.0.0.1")
+	p := newPendingEchoes()
+	seq, old, _ := p.register(target)
+	p.deliver(target, seq, time.Now())
+	p.seq = seq - 1
+	reseq, _, _ := p.register(target)
+	if reseq != seq {
+		t.Fatalf("sequence want: %d, got: %d", seq, reseq)
+	}
+	p.cancel(target, seq, old)
+	if p.len() != 1 {
+		t.Errorf("pending want: 1, got: %d", p.len())
+	}
+}
+
+func TestIcmpListener_Loopback(t *testing.T) {
+	var (
+		l   *IcmpListener
+		err error
+	)
+	for _, privileged := range []bool{false, true} {
+		l, err = NewIcmpListener(0, I4EWithPrivileged(privileged), I4EWithPayloadSize(1472))
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Skipf("no icmp socket: %v", err)
+	}
+	defer l.Close()
+
+	target := netip.MustParseAddr("127.0.0.1")
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := l.Echo(context.Background(), target, time.Second)
+			if err == nil && r.Peer != target {
+				t.Errorf("peer want: %s, got: %s", target, r.Peer)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if l.pending.len() != 0 {
+		t.Errorf("pending want: 0, got: %d", l.pending.len())
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const mdnsPort = 5353
+
+// FindMDNSNameOf asks the device for its .local hostname with a unicast mDNS reverse lookup
+// (RFC 6762 section 5.5), a device without an mDNS responder returns ErrNoDnsNames
+func FindMDNSNameOf(ctx context.Context, addr netip.Addr, timeout time.Duration) (string, error) {
+	return DefaultPkg.FindMDNSNameOf(ctx, addr, timeout)
+}
+
+func (p *pkg) FindMDNSNameOf(
+	ctx context.Context,
+	addr netip.Addr,
+	timeout time.Duration,
+) (string, error) {
+	rev, err := dns.ReverseAddr(addr.String())
+	if err != nil {
+		return "", err
+	}
+	m := &dns.Msg{}
+	m.SetQuestion(rev, dns.TypePTR)
+	client := &dns.Client{Timeout: timeout}
+	response, _, err := client.ExchangeContext(
+		ctx,
+		m,
+		netip.AddrPortFrom(addr, mdnsPort).String(),
+	)
+	var neterr net.Error
+	if errors.As(err, &neterr) && neterr.Timeout() {
+		return "", ErrNoDnsNames
+	}
+	if err != nil {
+		return "", err
+	}
+	for _, rec := range response.Answer {
+		if ptr, ok := rec.(*dns.PTR); ok {
+			return strings.TrimSuffix(ptr.Ptr, "."), nil
+		}
+	}
+	return "", ErrNoDnsNames
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/netip"
+	"time"
+)
+
+const defaultMtrMaxHops = 30
+
+// MtrHop holds the statistics of the probes sent to one hop of a route
+type MtrHop struct {
+	Peer     netip.Addr
+	Sent     int
+	Received int
+	Last     time.Duration
+	Best     time.Duration
+	Worst    time.Duration
+	Mean     time.Duration
+
+	// m2 is the sum of squared differences from the mean in nanoseconds (welford)
+	m2 float64
+}
+
+// Loss is the percentage of probes the hop did not answer
+func (h MtrHop) Loss() float64 {
+	if h.Sent == 0 {
+		return 0
+	}
+	return float64(h.Sent-h.Received) * 100 / float64(h.Sent)
+}
+
+// StdDev is the standard deviation of the answered probes
+func (h MtrHop) StdDev() time.Duration {
+	if h.Received == 0 {
+		return 0
+	}
+	return time.Duration(math.Sqrt(h.m2 / float64(h.Received)))
+}
+
+// add records a probe, answered is false for probes which timed out
+func (h *MtrHop) add(r Icmp4EchoResponse, answered bool) {
+	h.Sent++
+	if !answered {
+		return
+	}
+	if r.Peer.IsValid() {
+		h.Peer = r.Peer
+	}
+	h.Received++
+	h.Last = r.Elapsed
+	if h.Received == 1 || r.Elapsed < h.Best {
+		h.Best = r.Elapsed
+	}
+	if r.Elapsed > h.Worst {
+		h.Worst = r.Elapsed
+	}
+	delta := float64(r.Elapsed - h.Mean)
+	h.Mean += time.Duration(delta / float64(h.Received))
+	h.m2 += delta * float64(r.Elapsed-h.Mean)
+}
+
+// Mtr repeatedly traces the route to a target and keeps per hop loss and latency, the
+// probes are privileged (raw or through the helper) the same as Traceroute4
+type Mtr struct {
+	p       *pkg
+	target  netip.Addr
+	maxHops int
+	opts    *Icmp4EchoOptions
+	hops    []MtrHop
+}
+
+// NewMtr returns an Mtr of the target, a maxHops of 0 uses 30 hops
+func NewMtr(target netip.Addr, maxHops int, opts ...Icmp4EchoOption) *Mtr {
+	if maxHops <= 0 {
+		maxHops = defaultMtrMaxHops
+	}
+	return &Mtr{
+		p:       DefaultPkg,
+		target:  target,
+		maxHops: maxHops,
+		opts:    i4eApplyOptions(i4eApplyOptionsToDefault(opts...), I4EWithAllowAllErrors(true)),
+	}
+}
+
+// Round sends one probe to each hop until the target answers and returns a copy of the
+// statistics of every hop so far.  Hops past the target are dropped when the route gets
+// shorter.  The probes of a round are sent back to back, the caller paces the rounds.
+func (m *Mtr) Round(ctx context.Context) ([]MtrHop, error) {
+	for ttl := 1; ttl <= m.maxHops; ttl++ {
+		r, err := m.p.privilegedPingIcmp4(
+			ctx,
+			m.target,
+			ttl,
+			m.opts.ListenAddress,
+			m.opts.ReadTimeout,
+			m.opts.IcmpID,
+			m.opts.IcmpSeq,
+			m.opts.PayloadSize,
+			m.opts.DSCP,
+			m.opts.AllowAllErrors,
+		)
+		if ctx.Err() != nil {
+			return m.Hops(), ctx.Err()
+		}
+		// a raw socket to a local target can read back the request before the reply, any
+		// packet from the target still shows it is reached
+		reached := err == nil || r.Peer == m.target
+		answered := reached || errors.Is(err, ErrTTLExceeded)
+		if !answered && !errors.Is(err, ErrNoResponseFromRemote) {
+			return m.Hops(), err
+		}
+		if len(m.hops) < ttl {
+			m.hops = append(m.hops, MtrHop{})
+		}
+		m.hops[ttl-1].add(r, answered)
+		if reached {
+			m.hops = m.hops[:ttl]
+			break
+		}
+	}
+	return m.Hops(), nil
+}
+
+// Hops returns a copy of the statistics of every hop
+func (m *Mtr) Hops() []MtrHop {
+	hops := make([]MtrHop, len(m.hops))
+	copy(hops, m.hops)
+	return hops
+}
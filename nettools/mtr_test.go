@@ -0,0 +1,47 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestMtrHop(t *testing.T) {
+	peer := netip.MustParseAddr("10.0.0.1")
+	probe := func(ms int) Icmp4EchoResponse {
+		return Icmp4EchoResponse{Peer: peer, Elapsed: time.Duration(ms) * time.Millisecond}
+	}
+	var h MtrHop
+	h.add(probe(10), true)
+	h.add(Icmp4EchoResponse{}, false)
+	h.add(probe(30), true)
+	h.add(probe(20), true)
+
+	want := MtrHop{
+		Peer:     peer,
+		Sent:     4,
+		Received: 3,
+		Last:     20 * time.Millisecond,
+		Best:     10 * time.Millisecond,
+		Worst:    30 * time.Millisecond,
+		Mean:     20 * time.Millisecond,
+	}
+	opts := cmp.Options{cmpopts.EquateComparable(netip.Addr{}), cmpopts.IgnoreUnexported(MtrHop{})}
+	if diff := cmp.Diff(want, h, opts); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	if loss := h.Loss(); loss != 25 {
+		t.Errorf("loss want 25, got %v", loss)
+	}
+	// population standard deviation of 10, 30 and 20ms
+	if sd := h.StdDev().Round(time.Microsecond); sd != 8165*time.Microsecond {
+		t.Errorf("stddev want 8.165ms, got %s", sd)
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	ndpRouterSolicitation    = 133
+	ndpRouterAdvertisement   = 134
+	ndpNeighborSolicitation  = 135
+	ndpNeighborAdvertisement = 136
+
+	ndpOptionSourceLinkAddr = 1
+	ndpOptionTargetLinkAddr = 2
+	ndpOptionPrefixInfo     = 3
+
+	// ndpHopLimit is the only hop limit neighbor discovery messages are accepted with
+	ndpHopLimit             = 255
+	ndpListenerReadDeadline = time.Second
+)
+
+var (
+	ndpAllNodes   = netip.MustParseAddr("ff02::1")
+	ndpAllRouters = netip.MustParseAddr("ff02::2")
+
+	ErrNotNDPMessage = errors.New("not a neighbor discovery message")
+)
+
+// NDPNeighbor is a neighbor telling its link-layer address in a neighbor discovery message,
+// the addr of an advertisement is its target and of the other messages their source.  A
+// router advertisement also lists the prefixes hosts autoconfigure (SLAAC) addresses in.
+type NDPNeighbor struct {
+	Type     int
+	Addr     netip.Addr
+	MAC      net.HardwareAddr
+	Router   bool
+	Prefixes []netip.Prefix
+}
+
+// ParseNDP reads a router or neighbor solicitation or advertisement received from src.  The
+// messages of a duplicate address detection come from the unspecified address with no
+// link-layer address and are not neighbors.
+func ParseNDP(src netip.Addr, b []byte) (NDPNeighbor, error) {
+	var nb NDPNeighbor
+	if len(b) < 4 {
+		return nb, ErrNotNDPMessage
+	}
+	nb.Type = int(b[0])
+	nb.Addr = src.WithZone("")
+	var options []byte
+	switch nb.Type {
+	case ndpRouterSolicitation:
+		if len(b) < 8 {
+			return nb, ErrNotNDPMessage
+		}
+		options = b[8:]
+	case ndpRouterAdvertisement:
+		if len(b) < 16 {
+			return nb, ErrNotNDPMessage
+		}
+		nb.Router = true
+		options = b[16:]
+	case ndpNeighborSolicitation:
+		if len(b) < 24 {
+			return nb, ErrNotNDPMessage
+		}
+		options = b[24:]
+	case ndpNeighborAdvertisement:
+		if len(b) < 24 {
+			return nb, ErrNotNDPMessage
+		}
+		nb.Router = b[4]&0x80 != 0
+		nb.Addr = netip.AddrFrom16([16]byte(b[8:24]))
+		options = b[24:]
+	default:
+		return nb, ErrNotNDPMessage
+	}
+
+	for len(options) >= 2 {
+		size := int(options[1]) * 8
+		if size == 0 || len(options) < size {
+			return nb, ErrNotNDPMessage
+		}
+		value := options[2:size]
+		switch options[0] {
+		case ndpOptionSourceLinkAddr, ndpOptionTargetLinkAddr:
+			if len(value) >= 6 {
+				nb.MAC = net.HardwareAddr(bytes.Clone(value[:6]))
+			}
+		case ndpOptionPrefixInfo:
+			if prefix, ok := ndpAutonomousPrefix(value); ok {
+				nb.Prefixes = append(nb.Prefixes, prefix)
+			}
+		}
+		options = options[size:]
+	}
+	if !nb.Addr.IsValid() || nb.Addr.IsUnspecified() || !isNeighborMAC(nb.MAC) {
+		return nb, ErrNotNDPMessage
+	}
+	return nb, nil
+}
+
+// ndpAutonomousPrefix returns the prefix of a prefix information option when hosts may
+// autoconfigure addresses in it
+func ndpAutonomousPrefix(value []byte) (netip.Prefix, bool) {
+	if len(value) < 30 || value[1]&0x40 == 0 {
+		return netip.Prefix{}, false
+	}
+	if binary.BigEndian.Uint32(value[2:6]) == 0 {
+		// a valid lifetime of zero withdraws the prefix
+		return netip.Prefix{}, false
+	}
+	addr := netip.AddrFrom16([16]byte(value[14:30]))
+	prefix, err := addr.Prefix(int(value[0]))
+	if err != nil || addr.IsLinkLocalUnicast() {
+		return netip.Prefix{}, false
+	}
+	return prefix, true
+}
+
+// ListenNDP passes the neighbors of the neighbor discovery messages received to fn until the
+// context is done.  Every solicit interval a router solicitation and an echo to all nodes go
+// out of each interface, the routers answer with an advertisement and the nodes solicit the
+// link-layer address of mason before replying, both telling their own.  The raw icmpv6
+// socket needs the same privileges as a privileged ping.
+func ListenNDP(ctx context.Context, solicit time.Duration, fn func(NDPNeighbor)) error {
+	conn, err := icmp.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	pc := conn.IPv6PacketConn()
+	var filter ipv6.ICMPFilter
+	filter.SetAll(true)
+	for _, typ := range []ipv6.ICMPType{
+		ipv6.ICMPTypeRouterSolicitation,
+		ipv6.ICMPTypeRouterAdvertisement,
+		ipv6.ICMPTypeNeighborSolicitation,
+		ipv6.ICMPTypeNeighborAdvertisement,
+	} {
+		filter.Accept(typ)
+	}
+	// not every os filters, the other messages fail to parse instead
+	_ = pc.SetICMPFilter(&filter)
+	_ = pc.SetMulticastHopLimit(ndpHopLimit)
+
+	buf := make([]byte, 1500)
+	var solicited time.Time
+	for ctx.Err() == nil {
+		if solicit > 0 && time.Since(solicited) >= solicit {
+			solicitNeighbors(conn)
+			solicited = time.Now()
+		}
+		err = conn.SetReadDeadline(time.Now().Add(ndpListenerReadDeadline))
+		if err != nil {
+			return err
+		}
+		n, peer, err := conn.ReadFrom(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		ipaddr, ok := peer.(*net.IPAddr)
+		if !ok {
+			continue
+		}
+		src, ok := netip.AddrFromSlice(ipaddr.IP)
+		if !ok {
+			continue
+		}
+		nb, err := ParseNDP(src, buf[:n])
+		if err != nil {
+			continue
+		}
+		fn(nb)
+	}
+	return nil
+}
+
+// solicitNeighbors sends a router solicitation and an echo request to all nodes out of each
+// interface with an IPv6 link-local address, a failed send only skips the interface
+func solicitNeighbors(conn *icmp.PacketConn) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return
+	}
+	rs, _ := (&icmp.Message{
+		Type: ipv6.ICMPTypeRouterSolicitation,
+		Body: &icmp.RawBody{Data: make([]byte, 4)},
+	}).Marshal(nil)
+	echo, _ := (&icmp.Message{
+		Type: ipv6.ICMPTypeEchoRequest,
+		Body: &icmp.Echo{ID: os.Getpid() & 0xffff, Seq: 1, Data: []byte("mason")},
+	}).Marshal(nil)
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 ||
+			iface.Flags&net.FlagLoopback != 0 || !hasLinkLocal6(iface) {
+			continue
+		}
+		_, _ = conn.WriteTo(rs, &net.IPAddr{IP: ndpAllRouters.AsSlice(), Zone: iface.Name})
+		_, _ = conn.WriteTo(echo, &net.IPAddr{IP: ndpAllNodes.AsSlice(), Zone: iface.Name})
+	}
+}
+
+func hasLinkLocal6(iface net.Interface) bool {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		prefix, err := netip.ParsePrefix(a.String())
+		if err == nil && prefix.Addr().Is6() && prefix.Addr().IsLinkLocalUnicast() {
+			return true
+		}
+	}
+	return false
+}
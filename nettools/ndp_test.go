@@ -0,0 +1,107 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func ndpLinkAddrOption(kind byte, mac net.HardwareAddr) []byte {
+	return append([]byte{kind, 1}, mac...)
+}
+
+func ndpPrefixOption(prefix netip.Prefix, flags byte, valid uint32) []byte {
+	b := []byte{ndpOptionPrefixInfo, 4, byte(prefix.Bits()), flags}
+	b = append(b, byte(valid>>24), byte(valid>>16), byte(valid>>8), byte(valid))
+	b = append(b, 0, 0, 0x0e, 0x10, 0, 0, 0, 0)
+	return append(b, prefix.Addr().AsSlice()...)
+}
+
+func TestParseNDP(t *testing.T) {
+	mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	router := netip.MustParseAddr("fe80::1")
+	host := netip.MustParseAddr("fe80::211:22ff:fe33:4455")
+	target := netip.MustParseAddr("2001:db8:1::20")
+	slaac := netip.MustParsePrefix("2001:db8:1::/64")
+
+	ra := append([]byte{ndpRouterAdvertisement, 0, 0, 0, 64, 0, 0x07, 0x08}, make([]byte, 8)...)
+	ra = append(ra, ndpLinkAddrOption(ndpOptionSourceLinkAddr, mac)...)
+	ra = append(ra, ndpPrefixOption(slaac, 0xc0, 86400)...)
+	ra = append(ra, ndpPrefixOption(netip.MustParsePrefix("2001:db8:2::/64"), 0x80, 86400)...)
+	ra = append(ra, ndpPrefixOption(netip.MustParsePrefix("2001:db8:3::/64"), 0xc0, 0)...)
+
+	ns := append([]byte{ndpNeighborSolicitation, 0, 0, 0, 0, 0, 0, 0}, router.AsSlice()...)
+	ns = append(ns, ndpLinkAddrOption(ndpOptionSourceLinkAddr, mac)...)
+
+	na := append([]byte{ndpNeighborAdvertisement, 0, 0, 0, 0x60, 0, 0, 0}, target.AsSlice()...)
+	na = append(na, ndpLinkAddrOption(ndpOptionTargetLinkAddr, mac)...)
+
+	dad := append([]byte{ndpNeighborSolicitation, 0, 0, 0, 0, 0, 0, 0}, target.AsSlice()...)
+
+	tests := map[string]struct {
+		src     netip.Addr
+		input   []byte
+		want    NDPNeighbor
+		wantErr error
+	}{
+		"RouterAdvertisement": {
+			src:   router.WithZone("eth0"),
+			input: ra,
+			want: NDPNeighbor{
+				Type:     ndpRouterAdvertisement,
+				Addr:     router,
+				MAC:      mac,
+				Router:   true,
+				Prefixes: []netip.Prefix{slaac},
+			},
+		},
+		"NeighborSolicitation": {
+			src:   host,
+			input: ns,
+			want:  NDPNeighbor{Type: ndpNeighborSolicitation, Addr: host, MAC: mac},
+		},
+		"NeighborAdvertisement": {
+			src:   host,
+			input: na,
+			want:  NDPNeighbor{Type: ndpNeighborAdvertisement, Addr: target, MAC: mac},
+		},
+		"DuplicateAddressDetection": {
+			src:     netip.IPv6Unspecified(),
+			input:   dad,
+			wantErr: ErrNotNDPMessage,
+		},
+		"EchoRequest": {
+			src:     host,
+			input:   []byte{128, 0, 0, 0, 0, 1, 0, 1},
+			wantErr: ErrNotNDPMessage,
+		},
+		"BadOptionLength": {
+			src:     host,
+			input:   append(ns[:24:24], ndpOptionSourceLinkAddr, 0),
+			wantErr: ErrNotNDPMessage,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseNDP(tc.src, tc.input)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("want: %v, got: %v", tc.wantErr, err)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			opt := cmpopts.EquateComparable(netip.Addr{}, netip.Prefix{})
+			if diff := cmp.Diff(tc.want, got, opt); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
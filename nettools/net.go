@@ -23,10 +23,12 @@ var _ Nettooler = (*pkg)(nil)
 type Nettooler interface {
 	Arper
 	Dnser
+	HTTPer
 	Icmp4Echoer
 	Ipifyer
 	Portscanner
 	Snmper
+	SpeedTester
 	TLSer
 	Utiler
 }
@@ -79,8 +81,13 @@ type pkg struct {
 
 	arptable map[netip.Addr]net.HardwareAddr
 
+	// helper sends the raw packets when set, see UsePrivilegedHelper
+	helper *helperClient
+
 	dnsclient  *dns.Client
 	httpclient *http.Client
+	// verifyclient checks server certificates, unlike httpclient
+	verifyclient *http.Client
 
 	dnssvrs map[string]map[string]string
 
@@ -114,6 +121,9 @@ func newPkg() (*pkg, error) {
 				},
 			},
 		},
+		verifyclient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
 		dnssvrs: map[string]map[string]string{
 			google: {
 				"A": dnssvrGoogleA,
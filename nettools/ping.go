@@ -7,15 +7,19 @@ package nettools
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
 	"net"
 	"net/netip"
 	"runtime"
+	"slices"
 	"time"
 
 	"golang.org/x/net/bpf"
 	"golang.org/x/net/icmp"
 	"golang.org/x/net/ipv4"
+
+	"github.com/networkables/mason/internal/tracing"
 )
 
 var _ Icmp4Echoer = (*pkg)(nil)
@@ -29,24 +33,51 @@ func Icmp4Echo(ctx context.Context, target netip.Addr, opts ...Icmp4EchoOption)
 }
 
 func (p *pkg) Icmp4Echo(ctx context.Context, target netip.Addr, opts ...Icmp4EchoOption) ([]Icmp4EchoResponse, error) {
+	ctx, span := tracing.Start(ctx, "icmp.echo", tracing.AddrKey.String(target.String()))
+	response, err := p.icmp4Echo(ctx, target, opts...)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (p *pkg) icmp4Echo(ctx context.Context, target netip.Addr, opts ...Icmp4EchoOption) ([]Icmp4EchoResponse, error) {
 	opt := i4eApplyOptionsToDefault(opts...)
+	err := opt.validate()
+	if err != nil {
+		return nil, err
+	}
 	response := make([]Icmp4EchoResponse, 0, opt.Count)
+	var lost error
 	for seqNum := opt.IcmpSeq; seqNum < opt.Count+1; seqNum++ {
 		var (
 			err error
 			r   Icmp4EchoResponse
 		)
-		if opt.Privileged {
-			r, err = rawPingIcmp4(ctx, target, opt.TTL, opt.ListenAddress, opt.ReadTimeout, opt.IcmpID, seqNum, opt.AllowAllErrors)
-		} else {
-			r, err = rawPingUdp4(ctx, target, opt.TTL, opt.ListenAddress, opt.ReadTimeout, opt.IcmpID, seqNum, opt.AllowAllErrors)
+		switch {
+		case opt.Listener != nil:
+			r, err = opt.Listener.Echo(ctx, target, opt.ReadTimeout)
+		case opt.Privileged:
+			r, err = p.privilegedPingIcmp4(ctx, target, opt.TTL, opt.ListenAddress, opt.ReadTimeout, opt.IcmpID, seqNum, opt.PayloadSize, opt.DSCP, opt.AllowAllErrors)
+		default:
+			r, err = rawPingUdp4(ctx, target, opt.TTL, opt.ListenAddress, opt.ReadTimeout, opt.IcmpID, seqNum, opt.PayloadSize, opt.DSCP, opt.AllowAllErrors)
+		}
+		if err != nil && r.Err == nil {
+			r.Err = err
 		}
 		response = append(response, r)
-		if err != nil {
+		// an echo without a reply is counted as lost and the next one is sent
+		if err != nil && !errors.Is(err, ErrNoResponseFromRemote) {
 			return response, err
 		}
+		if err != nil {
+			lost = err
+		}
 		time.Sleep(opt.BetweenDuration)
 	}
+	if lost != nil && !slices.ContainsFunc(response, func(r Icmp4EchoResponse) bool {
+		return r.Err == nil
+	}) {
+		return response, lost
+	}
 	return response, nil
 }
 
@@ -60,6 +91,33 @@ type Icmp4EchoOptions struct {
 	Count           int
 	BetweenDuration time.Duration
 	AllowAllErrors  bool
+	// TraceProbes are tried in order at each hop of a traceroute, icmp only when empty
+	TraceProbes []TraceProbe
+	// TracePort is the destination port of udp and tcp probes, 0 uses 33434 plus the ttl
+	// for udp and 80 for tcp
+	TracePort int
+	// Listener sends the echoes through its shared socket instead of a socket per echo, the
+	// ttl, listen address, payload size and dscp are those of the listener
+	Listener *IcmpListener
+	// PayloadSize is the number of data bytes of each echo, 0 sends a short greeting.  1472
+	// fills a 1500 byte mtu.
+	PayloadSize int
+	// DSCP marks the echoes with a differentiated services class, such as 46 for expedited
+	// forwarding, so the latency of a qos class can be measured
+	DSCP int
+}
+
+// maxIcmp4PayloadSize is the largest echo data which fits an ipv4 packet
+const maxIcmp4PayloadSize = 65535 - ipv4.HeaderLen - 8
+
+func (o *Icmp4EchoOptions) validate() error {
+	if o.PayloadSize < 0 || o.PayloadSize > maxIcmp4PayloadSize {
+		return fmt.Errorf("%w: %d", ErrInvalidPayloadSize, o.PayloadSize)
+	}
+	if o.DSCP < 0 || o.DSCP > 63 {
+		return fmt.Errorf("%w: %d", ErrInvalidDSCP, o.DSCP)
+	}
+	return nil
 }
 
 type Icmp4EchoOption func(*Icmp4EchoOptions)
@@ -130,6 +188,36 @@ func I4EWithPrivileged(allow bool) Icmp4EchoOption {
 	}
 }
 
+func I4EWithTraceProbes(probes ...TraceProbe) Icmp4EchoOption {
+	return func(o *Icmp4EchoOptions) {
+		o.TraceProbes = probes
+	}
+}
+
+func I4EWithTracePort(port int) Icmp4EchoOption {
+	return func(o *Icmp4EchoOptions) {
+		o.TracePort = port
+	}
+}
+
+func I4EWithListener(l *IcmpListener) Icmp4EchoOption {
+	return func(o *Icmp4EchoOptions) {
+		o.Listener = l
+	}
+}
+
+func I4EWithPayloadSize(size int) Icmp4EchoOption {
+	return func(o *Icmp4EchoOptions) {
+		o.PayloadSize = size
+	}
+}
+
+func I4EWithDSCP(dscp int) Icmp4EchoOption {
+	return func(o *Icmp4EchoOptions) {
+		o.DSCP = dscp
+	}
+}
+
 func defaultIcmp4EchoOptions() *Icmp4EchoOptions {
 	listenAddress := netip.MustParseAddr("0.0.0.0")
 	icmpID := rander.Int() & 0xFFFF
@@ -150,6 +238,8 @@ type Icmp4EchoResponse struct {
 	Start   time.Time
 	Elapsed time.Duration
 	Err     error
+	// Probe is the probe a traceroute hop answered
+	Probe TraceProbe
 }
 
 func (r Icmp4EchoResponse) populate(addr net.Addr, start time.Time, stop time.Time, err error) Icmp4EchoResponse {
@@ -165,7 +255,7 @@ func (r Icmp4EchoResponse) populate(addr net.Addr, start time.Time, stop time.Ti
 	return r
 }
 
-func rawPingIcmp4(ctx context.Context, target netip.Addr, ttl int, listenAddress netip.Addr, readTimeout time.Duration, icmpID int, icmpSeq int, allowAllErrors bool) (response Icmp4EchoResponse, err error) {
+func rawPingIcmp4(ctx context.Context, target netip.Addr, ttl int, listenAddress netip.Addr, readTimeout time.Duration, icmpID int, icmpSeq int, payloadSize int, dscp int, allowAllErrors bool) (response Icmp4EchoResponse, err error) {
 	listenProto := "ip4:icmp"
 
 	if ctx.Err() != nil {
@@ -176,6 +266,8 @@ func rawPingIcmp4(ctx context.Context, target netip.Addr, ttl int, listenAddress
 	}
 	switch runtime.GOOS {
 	case "darwin", "ios":
+	case "windows":
+		return systemPingIcmp4(ctx, target, ttl, payloadSize, dscp, readTimeout)
 	case "linux":
 		// log.Print("you may need to adjust the net.ipv4.ping_group_range kernel state")
 	default:
@@ -197,6 +289,10 @@ func rawPingIcmp4(ctx context.Context, target netip.Addr, ttl int, listenAddress
 	if err != nil {
 		return response, err
 	}
+	err = setDSCP(pc, dscp)
+	if err != nil {
+		return response, err
+	}
 	err = pc.SetReadDeadline(time.Now().Add(readTimeout))
 	if err != nil {
 		return response, err
@@ -213,13 +309,13 @@ func rawPingIcmp4(ctx context.Context, target netip.Addr, ttl int, listenAddress
 		}
 	}
 
-	wb := buildIcmpMessageBody(icmpID, icmpSeq)
+	wb := buildIcmpMessageBody(icmpID, icmpSeq, payloadSize)
 
 	starttime := time.Now()
 	if _, err := pc.WriteTo(wb, noControlMessage, &net.IPAddr{IP: net.IP(target.AsSlice())}); err != nil {
 		return response, err
 	}
-	rb := make([]byte, 1500)
+	rb := make([]byte, replyBufferSize(payloadSize))
 	n, _, peer, err := pc.ReadFrom(rb)
 	endtime := time.Now()
 	response = response.populate(peer, starttime, endtime, err)
@@ -264,7 +360,7 @@ func rawPingIcmp4(ctx context.Context, target netip.Addr, ttl int, listenAddress
 	return response, newErrNoResponse(target, nil)
 }
 
-func rawPingUdp4(ctx context.Context, target netip.Addr, ttl int, listenAddress netip.Addr, readTimeout time.Duration, icmpID int, icmpSeq int, allowAllErrors bool) (response Icmp4EchoResponse, err error) {
+func rawPingUdp4(ctx context.Context, target netip.Addr, ttl int, listenAddress netip.Addr, readTimeout time.Duration, icmpID int, icmpSeq int, payloadSize int, dscp int, allowAllErrors bool) (response Icmp4EchoResponse, err error) {
 	listenProto := "udp4"
 
 	if ctx.Err() != nil {
@@ -273,6 +369,10 @@ func rawPingUdp4(ctx context.Context, target netip.Addr, ttl int, listenAddress
 	if target.Is6() {
 		return response, errors.New("ipv6 not supported")
 	}
+	if runtime.GOOS == "windows" {
+		// unprivileged icmp sockets are not available, the icmp helper api needs no privileges
+		return systemPingIcmp4(ctx, target, ttl, payloadSize, dscp, readTimeout)
+	}
 
 	ln, err := icmp.ListenPacket(listenProto, listenAddress.String())
 	defer ln.Close()
@@ -289,15 +389,19 @@ func rawPingUdp4(ctx context.Context, target netip.Addr, ttl int, listenAddress
 	if err != nil {
 		return response, err
 	}
+	err = setDSCP(pc, dscp)
+	if err != nil {
+		return response, err
+	}
 
-	wb := buildIcmpMessageBody(icmpID, icmpSeq)
+	wb := buildIcmpMessageBody(icmpID, icmpSeq, payloadSize)
 
 	starttime := time.Now()
 	if _, err := ln.WriteTo(wb, &net.UDPAddr{IP: target.AsSlice()}); err != nil {
 		return response, err
 	}
 
-	rb := make([]byte, 1500)
+	rb := make([]byte, replyBufferSize(payloadSize))
 	n, _, peer, err := pc.ReadFrom(rb)
 	endtime := time.Now()
 	response = response.populate(peer, starttime, endtime, err)
@@ -343,7 +447,7 @@ func rawPingUdp4(ctx context.Context, target netip.Addr, ttl int, listenAddress
 	return response, newErrNoResponse(target, nil)
 }
 
-func buildIcmpMessageBody(icmpID int, icmpSeq int) []byte {
+func buildIcmpMessageBody(icmpID int, icmpSeq int, payloadSize int) []byte {
 
 	wm := icmp.Message{
 		Type: ipv4.ICMPTypeEcho,
@@ -351,7 +455,7 @@ func buildIcmpMessageBody(icmpID int, icmpSeq int) []byte {
 		Body: &icmp.Echo{
 			ID:   icmpID,
 			Seq:  icmpSeq,
-			Data: []byte("HELLO-R-U-THERE"),
+			Data: echoPayload(payloadSize),
 		},
 	}
 	wb, err := wm.Marshal(nil)
@@ -361,6 +465,31 @@ func buildIcmpMessageBody(icmpID int, icmpSeq int) []byte {
 	return wb
 }
 
+// echoPayload is the data of an echo, the greeting repeated to the size
+func echoPayload(size int) []byte {
+	greeting := []byte("HELLO-R-U-THERE")
+	if size <= 0 {
+		return greeting
+	}
+	data := make([]byte, size)
+	for i := 0; i < size; i += copy(data[i:], greeting) {
+	}
+	return data
+}
+
+// replyBufferSize fits the echo reply of the payload size with its headers
+func replyBufferSize(payloadSize int) int {
+	return max(1500, ipv4.HeaderLen+8+payloadSize)
+}
+
+// setDSCP marks the packets of the conn with the dscp, the os default is kept for 0
+func setDSCP(pc *ipv4.PacketConn, dscp int) error {
+	if dscp == 0 {
+		return nil
+	}
+	return pc.SetTOS(dscp << 2)
+}
+
 func buildIcmpFilterForID(icmpid uint32) ([]bpf.RawInstruction, error) {
 	filter := []bpf.Instruction{
 		// Skip to the end of the IP header
@@ -392,8 +521,17 @@ type Icmp4EchoResponseStatistics struct {
 	StdDev       time.Duration
 	SuccessCount int
 	PacketLoss   float64
-	Asn          string
-	OrgName      string
+	// Jitter is the mean difference between the round trips of consecutive replies
+	Jitter time.Duration
+	// P95 and P99 are the round trip percentiles of the replies
+	P95 time.Duration
+	P99 time.Duration
+	// ConsecutiveFailures is the longest run of echoes without a reply
+	ConsecutiveFailures int
+	Asn                 string
+	OrgName             string
+	// Probe is the traceroute probe the hop answered
+	Probe TraceProbe
 }
 
 func CalculateIcmp4EchoResponseStatistics(rs []Icmp4EchoResponse) (ret Icmp4EchoResponseStatistics) {
@@ -406,10 +544,16 @@ func CalculateIcmp4EchoResponseStatistics(rs []Icmp4EchoResponse) (ret Icmp4Echo
 	ret.Start = rs[0].Start
 	ret.Minimum = math.MaxInt64
 	ret.Maximum = math.MinInt64
+	elapsed := make([]time.Duration, 0, count)
+	failures := 0
 	for _, x := range rs {
 		if x.Err != nil {
+			failures++
+			ret.ConsecutiveFailures = max(ret.ConsecutiveFailures, failures)
 			continue
 		}
+		failures = 0
+		elapsed = append(elapsed, x.Elapsed)
 		if ret.Start.After(ret.Start) {
 			ret.Start = x.Start
 		}
@@ -421,6 +565,7 @@ func CalculateIcmp4EchoResponseStatistics(rs []Icmp4EchoResponse) (ret Icmp4Echo
 		}
 		ret.SuccessCount++
 		ret.TotalElapsed += x.Elapsed
+		ret.Probe = x.Probe
 	}
 	if ret.SuccessCount > 0 {
 		ret.PacketLoss = float64(ret.TotalPackets-ret.SuccessCount) / float64(ret.SuccessCount)
@@ -435,9 +580,38 @@ func CalculateIcmp4EchoResponseStatistics(rs []Icmp4EchoResponse) (ret Icmp4Echo
 		}
 		variance /= float64(ret.SuccessCount)
 		ret.StdDev = time.Duration(math.Sqrt(variance))
+		ret.Jitter = jitter(elapsed)
+		slices.Sort(elapsed)
+		ret.P95 = percentile(elapsed, 95)
+		ret.P99 = percentile(elapsed, 99)
 	} else {
 		ret.Minimum = 0
 		ret.Maximum = 0
 	}
 	return ret
 }
+
+// jitter is the mean of the absolute differences between consecutive round trips
+func jitter(elapsed []time.Duration) time.Duration {
+	if len(elapsed) < 2 {
+		return 0
+	}
+	var total time.Duration
+	for i := 1; i < len(elapsed); i++ {
+		d := elapsed[i] - elapsed[i-1]
+		if d < 0 {
+			d = -d
+		}
+		total += d
+	}
+	return total / time.Duration(len(elapsed)-1)
+}
+
+// percentile returns the nearest rank percentile of the sorted round trips
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(float64(p) / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
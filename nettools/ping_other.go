@@ -0,0 +1,26 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build !windows
+
+package nettools
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"time"
+)
+
+// systemPingIcmp4 is only needed on windows, other systems ping over sockets
+func systemPingIcmp4(
+	ctx context.Context,
+	target netip.Addr,
+	ttl int,
+	payloadSize int,
+	dscp int,
+	readTimeout time.Duration,
+) (Icmp4EchoResponse, error) {
+	return Icmp4EchoResponse{}, errors.New("os not supported")
+}
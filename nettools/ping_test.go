@@ -4,6 +4,128 @@
 
 package nettools
 
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCalculateIcmp4EchoResponseStatistics(t *testing.T) {
+	ms := func(n int) Icmp4EchoResponse {
+		return Icmp4EchoResponse{Elapsed: time.Duration(n) * time.Millisecond}
+	}
+	lost := Icmp4EchoResponse{Err: ErrNoResponseFromRemote}
+	tests := map[string]struct {
+		responses []Icmp4EchoResponse
+		jitter    time.Duration
+		p95       time.Duration
+		p99       time.Duration
+		failures  int
+	}{
+		"Steady": {
+			responses: []Icmp4EchoResponse{ms(10), ms(10), ms(10)},
+			p95:       10 * time.Millisecond,
+			p99:       10 * time.Millisecond,
+		},
+		"Bufferbloat": {
+			responses: []Icmp4EchoResponse{ms(10), ms(50), ms(10), ms(90)},
+			jitter:    (40 + 40 + 80) * time.Millisecond / 3,
+			p95:       90 * time.Millisecond,
+			p99:       90 * time.Millisecond,
+		},
+		"Failures": {
+			responses: []Icmp4EchoResponse{lost, ms(20), lost, lost, lost, ms(30), lost},
+			jitter:    10 * time.Millisecond,
+			p95:       30 * time.Millisecond,
+			p99:       30 * time.Millisecond,
+			failures:  3,
+		},
+		"AllLost": {
+			responses: []Icmp4EchoResponse{lost, lost},
+			failures:  2,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := CalculateIcmp4EchoResponseStatistics(tc.responses)
+			if got.Jitter != tc.jitter {
+				t.Errorf("jitter want: %s, got: %s", tc.jitter, got.Jitter)
+			}
+			if got.P95 != tc.p95 {
+				t.Errorf("p95 want: %s, got: %s", tc.p95, got.P95)
+			}
+			if got.P99 != tc.p99 {
+				t.Errorf("p99 want: %s, got: %s", tc.p99, got.P99)
+			}
+			if got.ConsecutiveFailures != tc.failures {
+				t.Errorf("consecutive failures want: %d, got: %d", tc.failures, got.ConsecutiveFailures)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i + 1)
+	}
+	tests := map[string]struct {
+		p    int
+		want time.Duration
+	}{
+		"P50":  {p: 50, want: 50},
+		"P95":  {p: 95, want: 95},
+		"P99":  {p: 99, want: 99},
+		"P100": {p: 100, want: 100},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := percentile(sorted, tc.p); got != tc.want {
+				t.Errorf("percentile want: %d, got: %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestIcmp4EchoOptions_Validate(t *testing.T) {
+	tests := map[string]struct {
+		opts    []Icmp4EchoOption
+		wantErr error
+	}{
+		"Default":      {},
+		"MtuSized":     {opts: []Icmp4EchoOption{I4EWithPayloadSize(1472), I4EWithDSCP(46)}},
+		"NegativeSize": {opts: []Icmp4EchoOption{I4EWithPayloadSize(-1)}, wantErr: ErrInvalidPayloadSize},
+		"HugeSize":     {opts: []Icmp4EchoOption{I4EWithPayloadSize(65508)}, wantErr: ErrInvalidPayloadSize},
+		"DSCPTooBig":   {opts: []Icmp4EchoOption{I4EWithDSCP(64)}, wantErr: ErrInvalidDSCP},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := i4eApplyOptionsToDefault(tc.opts...).validate()
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("error want: %v, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestEchoPayload(t *testing.T) {
+	tests := map[string]struct {
+		size int
+		want string
+	}{
+		"Default": {size: 0, want: "HELLO-R-U-THERE"},
+		"Short":   {size: 5, want: "HELLO"},
+		"Long":    {size: 20, want: "HELLO-R-U-THEREHELLO"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := string(echoPayload(tc.size)); got != tc.want {
+				t.Errorf("payload want: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
 // func TestPing_rawPingIcmp4(t *testing.T) {
 // 	ctx := context.Background()
 // 	target := netip.MustParseAddr("127.0.0.1")
@@ -0,0 +1,126 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build windows
+
+package nettools
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// status codes of an icmp echo reply, see ipexport.h
+const (
+	ipSuccess            = 0
+	ipReqTimedOut        = 11010
+	ipTTLExpiredTransit  = 11013
+	ipTTLExpiredReassem  = 11014
+	icmpEchoReplyPadding = 8
+)
+
+var (
+	iphlpapi            = windows.NewLazySystemDLL("iphlpapi.dll")
+	procIcmpCreateFile  = iphlpapi.NewProc("IcmpCreateFile")
+	procIcmpSendEcho    = iphlpapi.NewProc("IcmpSendEcho")
+	procIcmpCloseHandle = iphlpapi.NewProc("IcmpCloseHandle")
+)
+
+// ipOptionInformation is IP_OPTION_INFORMATION
+type ipOptionInformation struct {
+	TTL         uint8
+	Tos         uint8
+	Flags       uint8
+	OptionsSize uint8
+	OptionsData uintptr
+}
+
+// icmpEchoReply is ICMP_ECHO_REPLY
+type icmpEchoReply struct {
+	Address       uint32
+	Status        uint32
+	RoundTripTime uint32
+	DataSize      uint16
+	Reserved      uint16
+	Data          uintptr
+	Options       ipOptionInformation
+}
+
+// systemPingIcmp4 sends the echo request with IcmpSendEcho, windows has no raw icmp sockets
+// for users but lets any user ping through the icmp helper api
+func systemPingIcmp4(
+	ctx context.Context,
+	target netip.Addr,
+	ttl int,
+	payloadSize int,
+	dscp int,
+	readTimeout time.Duration,
+) (response Icmp4EchoResponse, err error) {
+	if ctx.Err() != nil {
+		return response, ctx.Err()
+	}
+	if !target.Is4() {
+		return response, errors.New("ipv6 not supported")
+	}
+	handle, _, err := procIcmpCreateFile.Call()
+	if windows.Handle(handle) == windows.InvalidHandle {
+		return response, err
+	}
+	defer procIcmpCloseHandle.Call(handle)
+
+	request := echoPayload(payloadSize)
+	reply := make([]byte, unsafe.Sizeof(icmpEchoReply{})+uintptr(len(request))+icmpEchoReplyPadding)
+	options := ipOptionInformation{TTL: uint8(ttl), Tos: uint8(dscp << 2)}
+	timeout := max(readTimeout.Milliseconds(), 1)
+
+	start := time.Now()
+	count, _, callErr := procIcmpSendEcho.Call(
+		handle,
+		uintptr(binary.LittleEndian.Uint32(target.AsSlice())),
+		uintptr(unsafe.Pointer(&request[0])),
+		uintptr(len(request)),
+		uintptr(unsafe.Pointer(&options)),
+		uintptr(unsafe.Pointer(&reply[0])),
+		uintptr(len(reply)),
+		uintptr(timeout),
+	)
+	stop := time.Now()
+	response.Start = start
+	response.Elapsed = stop.Sub(start)
+
+	echo := (*icmpEchoReply)(unsafe.Pointer(&reply[0]))
+	status := echo.Status
+	if count == 0 {
+		// the status of a failed request is the last error
+		errno, ok := callErr.(syscall.Errno)
+		if !ok {
+			return response, callErr
+		}
+		status = uint32(errno)
+	}
+	var peer [4]byte
+	binary.LittleEndian.PutUint32(peer[:], echo.Address)
+	response.Peer = netip.AddrFrom4(peer)
+
+	switch status {
+	case ipSuccess:
+		if target.Compare(response.Peer) != 0 {
+			return response, newErrNoResponse(target, nil)
+		}
+		return response, nil
+	case ipReqTimedOut:
+		response.Err = ErrNoResponseFromRemote
+		return response, ErrNoResponseFromRemote
+	case ipTTLExpiredTransit, ipTTLExpiredReassem:
+		return response, ErrTTLExceeded
+	}
+	return response, newErrNoResponse(target, nil)
+}
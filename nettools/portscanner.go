@@ -6,7 +6,9 @@ package nettools
 
 import (
 	"context"
+	"errors"
 	"github.com/charmbracelet/log"
+	"github.com/networkables/mason/internal/tracing"
 	"github.com/networkables/mason/internal/workerpool"
 	"net"
 	"net/netip"
@@ -26,14 +28,30 @@ func ScanTcpPorts(ctx context.Context, target netip.Addr, options ...portscanReq
 	return DefaultPkg.ScanTcpPorts(ctx, target, options...)
 }
 
+// ScanTcpPorts returns the open tcp ports of the target.  A syn scan which cannot be run
+// (unprivileged, unsupported os or ipv6 target) falls back to a connect scan.
 func (p *pkg) ScanTcpPorts(ctx context.Context, target netip.Addr, options ...portscanRequestOptionFunc) (ports []int, err error) {
+	ctx, span := tracing.Start(ctx, "tcp.portscan", tracing.AddrKey.String(target.String()))
+	defer func() { tracing.End(span, err) }()
+
 	opts := applyPortscanRequestOptions(options...)
+	if opts.mode == SynPortscan {
+		ports, err = synScanTcpPorts(ctx, target, opts)
+		if !errors.Is(err, ErrSynScanUnavailable) {
+			return ports, err
+		}
+		log.Debug("syn scan unavailable, using connect scan", "target", target, "error", err)
+	}
+	return connectScanTcpPorts(ctx, target, opts)
+}
+
+func connectScanTcpPorts(ctx context.Context, target netip.Addr, opts *portscanRequestOptions) (ports []int, err error) {
 	portsToCheck := make(chan int)
 	var wg sync.WaitGroup
 	openports := make([]int, 0)
 
 	go func() {
-		for _, port := range getPortNumbers(opts.portlist) {
+		for _, port := range opts.portNumbers() {
 			portsToCheck <- port
 		}
 		close(portsToCheck)
@@ -41,7 +59,7 @@ func (p *pkg) ScanTcpPorts(ctx context.Context, target netip.Addr, options ...po
 	wp := workerpool.New(
 		"portscanner",
 		portsToCheck,
-		buildTcpPortChecker(target, opts.responseTimeout),
+		buildTcpPortChecker(target, opts.responseTimeout, opts.limiter),
 	)
 	wg.Add(2)
 	go func() {
@@ -64,8 +82,12 @@ func (p *pkg) ScanTcpPorts(ctx context.Context, target netip.Addr, options ...po
 	return openports, nil
 }
 
-func buildTcpPortChecker(addr netip.Addr, timeout time.Duration) func(context.Context, int) (int, error) {
+func buildTcpPortChecker(addr netip.Addr, timeout time.Duration, limiter RateLimiter) func(context.Context, int) (int, error) {
 	return func(ctx context.Context, port int) (int, error) {
+		if limiter != nil && limiter.Wait(ctx) != nil {
+			// scan cancelled while waiting
+			return 0, nil
+		}
 		isopen, err := isTcpPortOpen(addr, port, timeout)
 		if isopen {
 			return port, nil
@@ -103,6 +125,9 @@ type portscanRequestOptions struct {
 	responseTimeout time.Duration
 	maxWorkers      int
 	portlist        PortList
+	ports           []int
+	mode            PortscanMode
+	limiter         RateLimiter
 }
 
 func defaultPortscanRequestOptions() *portscanRequestOptions {
@@ -110,6 +135,7 @@ func defaultPortscanRequestOptions() *portscanRequestOptions {
 		responseTimeout: 100 * time.Millisecond,
 		maxWorkers:      1,
 		portlist:        GeneralPorts,
+		mode:            ConnectPortscan,
 	}
 }
 
@@ -131,6 +157,13 @@ func WithPortscanPortlist(list PortList) portscanRequestOptionFunc {
 	}
 }
 
+// WithPortscanPorts scans the ports instead of a preset port list
+func WithPortscanPorts(ports []int) portscanRequestOptionFunc {
+	return func(o *portscanRequestOptions) {
+		o.ports = ports
+	}
+}
+
 func WithPortscanPortlistName(name string) portscanRequestOptionFunc {
 	list, err := stringToPortList(name)
 	if err != nil {
@@ -141,6 +174,33 @@ func WithPortscanPortlistName(name string) portscanRequestOptionFunc {
 	}
 }
 
+func WithPortscanMode(mode PortscanMode) portscanRequestOptionFunc {
+	return func(o *portscanRequestOptions) {
+		o.mode = mode
+	}
+}
+
+func WithPortscanModeName(name string) portscanRequestOptionFunc {
+	mode, err := stringToPortscanMode(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return func(o *portscanRequestOptions) {
+		o.mode = mode
+	}
+}
+
+// RateLimiter paces the probes sent during a scan, Wait blocks until the next probe may be sent
+type RateLimiter interface {
+	Wait(context.Context) error
+}
+
+func WithPortscanRateLimiter(limiter RateLimiter) portscanRequestOptionFunc {
+	return func(o *portscanRequestOptions) {
+		o.limiter = limiter
+	}
+}
+
 type portscanRequestOptionFunc func(*portscanRequestOptions)
 
 func applyPortscanRequestOptions(options ...portscanRequestOptionFunc) *portscanRequestOptions {
@@ -151,6 +211,36 @@ func applyPortscanRequestOptions(options ...portscanRequestOptionFunc) *portscan
 	return opts
 }
 
+// PortscanMode selects how ports are probed.  A connect scan completes a full tcp handshake
+// per port, a syn scan sends a single raw syn packet per port and requires raw socket access.
+type PortscanMode int
+
+func (m PortscanMode) String() string {
+	switch m {
+	case ConnectPortscan:
+		return "Connect"
+	case SynPortscan:
+		return "Syn"
+	}
+	return "INVALID"
+}
+
+const (
+	InvalidPortscanMode PortscanMode = iota
+	ConnectPortscan
+	SynPortscan
+)
+
+func stringToPortscanMode(str string) (PortscanMode, error) {
+	switch strings.ToLower(str) {
+	case "", "connect":
+		return ConnectPortscan, nil
+	case "syn":
+		return SynPortscan, nil
+	}
+	return InvalidPortscanMode, ErrInvalidPortscanModeString
+}
+
 type PortList int
 
 func (p PortList) String() string {
@@ -175,6 +265,12 @@ const (
 	CommonPorts
 )
 
+// IsPortListName reports if the name is one of the preset port lists
+func IsPortListName(name string) bool {
+	_, err := stringToPortList(name)
+	return err == nil
+}
+
 func stringToPortList(str string) (PortList, error) {
 	switch strings.ToLower(str) {
 	case "all":
@@ -189,6 +285,14 @@ func stringToPortList(str string) (PortList, error) {
 	return InvalidPortList, ErrInvalidPortListString
 }
 
+// portNumbers are the ports given to scan, otherwise the ports of the preset list
+func (o *portscanRequestOptions) portNumbers() []int {
+	if len(o.ports) > 0 {
+		return o.ports
+	}
+	return getPortNumbers(o.portlist)
+}
+
 func getPortNumbers(list PortList) []int {
 	switch list {
 	case AllPorts:
@@ -0,0 +1,82 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+const (
+	tcpHeaderLen  = 20
+	tcpFlagSyn    = 0x02
+	tcpFlagRst    = 0x04
+	tcpFlagAck    = 0x10
+	tcpSynWindow  = 1024
+	protocolTCP   = 6
+	synPortOffset = 32768
+	synPortRange  = 28232
+	synReadBuffer = 4 << 20
+)
+
+// buildSynPacket returns a tcp header (no options) with the syn flag set and a valid
+// checksum for the src and dst addresses
+func buildSynPacket(src netip.Addr, dst netip.Addr, srcport int, dstport int, seq uint32) []byte {
+	b := make([]byte, tcpHeaderLen)
+	binary.BigEndian.PutUint16(b[0:2], uint16(srcport))
+	binary.BigEndian.PutUint16(b[2:4], uint16(dstport))
+	binary.BigEndian.PutUint32(b[4:8], seq)
+	b[12] = (tcpHeaderLen / 4) << 4
+	b[13] = tcpFlagSyn
+	binary.BigEndian.PutUint16(b[14:16], tcpSynWindow)
+	binary.BigEndian.PutUint16(b[16:18], tcpChecksum(src, dst, b))
+	return b
+}
+
+// tcpChecksum computes the tcp checksum over the ipv4 pseudo header and the segment
+func tcpChecksum(src netip.Addr, dst netip.Addr, segment []byte) uint16 {
+	s4, d4 := src.As4(), dst.As4()
+	var sum uint32
+	add := func(b []byte) {
+		for i := 0; i+1 < len(b); i += 2 {
+			sum += uint32(binary.BigEndian.Uint16(b[i : i+2]))
+		}
+		if len(b)%2 == 1 {
+			sum += uint32(b[len(b)-1]) << 8
+		}
+	}
+	add(s4[:])
+	add(d4[:])
+	sum += protocolTCP
+	sum += uint32(len(segment))
+	add(segment)
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
+
+// parseSynReply checks a received tcp segment is a reply to a syn sent from srcport with
+// the sequence number, returning the remote port and if it is open (syn+ack) or closed (rst)
+func parseSynReply(b []byte, srcport int, seq uint32) (port int, open bool, ok bool) {
+	if len(b) < tcpHeaderLen {
+		return 0, false, false
+	}
+	if int(binary.BigEndian.Uint16(b[2:4])) != srcport {
+		return 0, false, false
+	}
+	if binary.BigEndian.Uint32(b[8:12]) != seq+1 {
+		return 0, false, false
+	}
+	port = int(binary.BigEndian.Uint16(b[0:2]))
+	flags := b[13]
+	switch {
+	case flags&(tcpFlagSyn|tcpFlagAck) == tcpFlagSyn|tcpFlagAck:
+		return port, true, true
+	case flags&tcpFlagRst != 0:
+		return port, false, true
+	}
+	return 0, false, false
+}
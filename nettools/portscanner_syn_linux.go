@@ -0,0 +1,111 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package nettools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"time"
+)
+
+// synScanTcpPorts sends a syn to each port from a raw socket and collects the syn+ack
+// replies until the response timeout passes after the last syn.  The kernel answers the
+// syn+ack with a rst, so no connection is ever completed.
+func synScanTcpPorts(
+	ctx context.Context,
+	target netip.Addr,
+	opts *portscanRequestOptions,
+) ([]int, error) {
+	if !target.Is4() {
+		return nil, fmt.Errorf("%w: %w", ErrSynScanUnavailable, ErrIPv6Unsupported)
+	}
+	src, err := sourceAddrFor(target)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenPacket("ip4:tcp", src.String())
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return nil, fmt.Errorf("%w: %w", ErrSynScanUnavailable, err)
+		}
+		return nil, err
+	}
+	defer conn.Close()
+	// every tcp packet to the host is copied to the socket, a large buffer avoids dropping
+	// replies while the syns are being sent
+	err = conn.(*net.IPConn).SetReadBuffer(synReadBuffer)
+	if err != nil {
+		return nil, err
+	}
+
+	srcport := synPortOffset + rand.IntN(synPortRange)
+	seq := rand.Uint32()
+	dst := &net.IPAddr{IP: net.IP(target.AsSlice())}
+
+	openports := make([]int, 0)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 1500)
+		for {
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			ip, ok := peer.(*net.IPAddr)
+			if !ok || !ip.IP.Equal(dst.IP) {
+				continue
+			}
+			port, open, ok := parseSynReply(buf[:n], srcport, seq)
+			if ok && open && !slices.Contains(openports, port) {
+				openports = append(openports, port)
+			}
+		}
+	}()
+
+	for _, port := range opts.portNumbers() {
+		if ctx.Err() != nil {
+			break
+		}
+		if opts.limiter != nil && opts.limiter.Wait(ctx) != nil {
+			break
+		}
+		_, err = conn.WriteTo(buildSynPacket(src, target, srcport, port, seq), dst)
+		if err != nil {
+			conn.Close()
+			<-done
+			return nil, err
+		}
+	}
+	err = conn.SetReadDeadline(time.Now().Add(opts.responseTimeout))
+	if err != nil {
+		conn.Close()
+	}
+	<-done
+	slices.Sort(openports)
+	return openports, ctx.Err()
+}
+
+// sourceAddrFor returns the local address the kernel would use to reach the target
+func sourceAddrFor(target netip.Addr) (netip.Addr, error) {
+	c, err := net.Dial("udp4", net.JoinHostPort(target.String(), "9"))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer c.Close()
+	addr, ok := netip.AddrFromSlice(c.LocalAddr().(*net.UDPAddr).IP)
+	if !ok {
+		return netip.Addr{}, ErrInvalidAddr
+	}
+	return addr.Unmap(), nil
+}
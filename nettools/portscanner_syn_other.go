@@ -0,0 +1,22 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package nettools
+
+import (
+	"context"
+	"net/netip"
+)
+
+// synScanTcpPorts is only supported on linux, other systems do not pass tcp replies to
+// raw sockets
+func synScanTcpPorts(
+	ctx context.Context,
+	target netip.Addr,
+	opts *portscanRequestOptions,
+) ([]int, error) {
+	return nil, ErrSynScanUnavailable
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+)
+
+func TestPortscan_buildSynPacket(t *testing.T) {
+	src := netip.MustParseAddr("192.168.1.10")
+	dst := netip.MustParseAddr("192.168.1.1")
+	b := buildSynPacket(src, dst, 40000, 443, 1234)
+
+	if len(b) != tcpHeaderLen {
+		t.Fatalf("length want: %d, got: %d", tcpHeaderLen, len(b))
+	}
+	if got := binary.BigEndian.Uint16(b[2:4]); got != 443 {
+		t.Errorf("dstport want: 443, got: %d", got)
+	}
+	if b[13] != tcpFlagSyn {
+		t.Errorf("flags want: %x, got: %x", tcpFlagSyn, b[13])
+	}
+	// a segment including its checksum sums to zero
+	if got := tcpChecksum(src, dst, b); got != 0 {
+		t.Errorf("checksum verify want: 0, got: %x", got)
+	}
+}
+
+func TestPortscan_parseSynReply(t *testing.T) {
+	reply := func(srcport int, dstport int, ack uint32, flags byte) []byte {
+		b := make([]byte, tcpHeaderLen)
+		binary.BigEndian.PutUint16(b[0:2], uint16(srcport))
+		binary.BigEndian.PutUint16(b[2:4], uint16(dstport))
+		binary.BigEndian.PutUint32(b[8:12], ack)
+		b[13] = flags
+		return b
+	}
+	tests := map[string]struct {
+		b        []byte
+		wantPort int
+		wantOpen bool
+		wantOk   bool
+	}{
+		"Open": {
+			b:        reply(22, 40000, 101, tcpFlagSyn|tcpFlagAck),
+			wantPort: 22,
+			wantOpen: true,
+			wantOk:   true,
+		},
+		"Closed":    {b: reply(23, 40000, 101, tcpFlagRst|tcpFlagAck), wantPort: 23, wantOk: true},
+		"OtherPort": {b: reply(22, 40001, 101, tcpFlagSyn|tcpFlagAck)},
+		"OtherSeq":  {b: reply(22, 40000, 5, tcpFlagSyn|tcpFlagAck)},
+		"Short":     {b: []byte{0, 22}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			port, open, ok := parseSynReply(tc.b, 40000, 100)
+			if port != tc.wantPort || open != tc.wantOpen || ok != tc.wantOk {
+				t.Errorf(
+					"want: (%d, %t, %t), got: (%d, %t, %t)",
+					tc.wantPort, tc.wantOpen, tc.wantOk, port, open, ok,
+				)
+			}
+		})
+	}
+}
+
+func TestPortscan_stringToPortscanMode(t *testing.T) {
+	for str, want := range map[string]PortscanMode{
+		"":        ConnectPortscan,
+		"connect": ConnectPortscan,
+		"SYN":     SynPortscan,
+	} {
+		got, err := stringToPortscanMode(str)
+		if err != nil || got != want {
+			t.Errorf("%q want: %s, got: %s (%v)", str, want, got, err)
+		}
+	}
+	_, err := stringToPortscanMode("fin")
+	if err != ErrInvalidPortscanModeString {
+		t.Errorf("invalid want: %v, got: %v", ErrInvalidPortscanModeString, err)
+	}
+}
@@ -5,12 +5,16 @@
 package nettools
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"github.com/charmbracelet/log"
 	"github.com/gosnmp/gosnmp"
+	"github.com/networkables/mason/internal/tracing"
 	"net"
 	"net/netip"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -22,6 +26,12 @@ type Snmper interface {
 	SnmpGetSystemInfo(context.Context, netip.Addr, ...snmpRequestOptionFunc) (SnmpSystemInfo, error)
 	SnmpGetInterfaces(context.Context, netip.Addr, ...snmpRequestOptionFunc) ([]netip.Prefix, error)
 	SnmpGetArpTable(context.Context, netip.Addr, ...snmpRequestOptionFunc) ([]ArpEntry, error)
+	SnmpGetVlanTable(context.Context, netip.Addr, ...snmpRequestOptionFunc) ([]VlanEntry, error)
+	SnmpGetInterfaceCounters(
+		context.Context,
+		netip.Addr,
+		...snmpRequestOptionFunc,
+	) ([]InterfaceCounters, error)
 }
 
 type SnmpInfo struct {
@@ -55,6 +65,9 @@ func SnmpGetSystemInfo(ctx context.Context, target netip.Addr, options ...snmpRe
 }
 
 func (p pkg) SnmpGetSystemInfo(ctx context.Context, target netip.Addr, options ...snmpRequestOptionFunc) (ssi SnmpSystemInfo, err error) {
+	_, span := tracing.Start(ctx, "snmp.systeminfo", tracing.AddrKey.String(target.String()))
+	defer func() { tracing.End(span, err) }()
+
 	opts := applySnmpRequestOptions(options...)
 
 	ssi.Description, err = snmpGetSingleString(target, "1.3.6.1.2.1.1.1.0", opts.community, opts.port, opts.responseTimeout)
@@ -156,6 +169,167 @@ func (p pkg) SnmpGetArpTable(ctx context.Context, addr netip.Addr, options ...sn
 	return arps, nil
 }
 
+// VlanEntry is a MAC address learned by a bridge on the given 802.1Q vlan
+type VlanEntry struct {
+	MAC      net.HardwareAddr
+	VlanID   int
+	VlanName string
+}
+
+func SnmpGetVlanTable(ctx context.Context, addr netip.Addr, options ...snmpRequestOptionFunc) ([]VlanEntry, error) {
+	return DefaultPkg.SnmpGetVlanTable(ctx, addr, options...)
+}
+
+// SnmpGetVlanTable walks the Q-BRIDGE-MIB forwarding database (dot1qTpFdbPort) to map learned
+// MACs to vlans, names come from dot1qVlanStaticName.  The fdb id is treated as the vlan id,
+// which holds for switches using independent vlan learning.
+func (p pkg) SnmpGetVlanTable(ctx context.Context, addr netip.Addr, options ...snmpRequestOptionFunc) (entries []VlanEntry, err error) {
+	opts := applySnmpRequestOptions(options...)
+
+	nameoid := "1.3.6.1.2.1.17.7.1.4.3.1.1"
+	fdboid := "1.3.6.1.2.1.17.7.1.2.2.1.2"
+	entries = make([]VlanEntry, 0)
+	names := make(map[int]string)
+
+	client, err := snmpClient(addr, opts.community, opts.port, opts.responseTimeout)
+	if err != nil {
+		return entries, err
+	}
+	defer client.Conn.Close()
+	err = client.BulkWalk(nameoid, func(pdu gosnmp.SnmpPDU) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		idx := snmpOidSuffix(pdu.Name, nameoid)
+		if len(idx) != 1 {
+			return nil
+		}
+		if name, ok := pdu.Value.([]byte); ok {
+			names[idx[0]] = string(name)
+		}
+		return nil
+	})
+	err = snmpErrCheck(err)
+	if err != nil {
+		return entries, err
+	}
+
+	err = client.BulkWalk(fdboid, func(pdu gosnmp.SnmpPDU) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// index is fdbid.mac[0].mac[1]...mac[5]
+		idx := snmpOidSuffix(pdu.Name, fdboid)
+		if len(idx) != 7 {
+			return nil
+		}
+		mac := make(net.HardwareAddr, 6)
+		for i := range mac {
+			mac[i] = byte(idx[i+1])
+		}
+		entries = append(entries, VlanEntry{MAC: mac, VlanID: idx[0], VlanName: names[idx[0]]})
+		return nil
+	})
+	err = snmpErrCheck(err)
+	if err != nil {
+		return entries, err
+	}
+	return entries, nil
+}
+
+// InterfaceCounters are the 64 bit octet counters of an interface, the counters only grow (until
+// they wrap or the device restarts) so the rate is left to the consumer
+type InterfaceCounters struct {
+	Index     int
+	Name      string
+	InOctets  uint64
+	OutOctets uint64
+}
+
+func SnmpGetInterfaceCounters(ctx context.Context, addr netip.Addr, options ...snmpRequestOptionFunc) ([]InterfaceCounters, error) {
+	return DefaultPkg.SnmpGetInterfaceCounters(ctx, addr, options...)
+}
+
+// SnmpGetInterfaceCounters walks the IF-MIB ifXTable for the interface names (ifName) and octet
+// counters (ifHCInOctets, ifHCOutOctets), ordered by interface index
+func (p pkg) SnmpGetInterfaceCounters(ctx context.Context, addr netip.Addr, options ...snmpRequestOptionFunc) (counters []InterfaceCounters, err error) {
+	opts := applySnmpRequestOptions(options...)
+
+	nameoid := "1.3.6.1.2.1.31.1.1.1.1"
+	inoid := "1.3.6.1.2.1.31.1.1.1.6"
+	outoid := "1.3.6.1.2.1.31.1.1.1.10"
+	counters = make([]InterfaceCounters, 0)
+	byIndex := make(map[int]int)
+	entry := func(pdu gosnmp.SnmpPDU, rootoid string) *InterfaceCounters {
+		idx := snmpOidSuffix(pdu.Name, rootoid)
+		if len(idx) != 1 {
+			return nil
+		}
+		pos, ok := byIndex[idx[0]]
+		if !ok {
+			pos = len(counters)
+			byIndex[idx[0]] = pos
+			counters = append(counters, InterfaceCounters{Index: idx[0]})
+		}
+		return &counters[pos]
+	}
+
+	client, err := snmpClient(addr, opts.community, opts.port, opts.responseTimeout)
+	if err != nil {
+		return counters, err
+	}
+	defer client.Conn.Close()
+	walks := []struct {
+		oid string
+		set func(*InterfaceCounters, gosnmp.SnmpPDU)
+	}{
+		{nameoid, func(ic *InterfaceCounters, pdu gosnmp.SnmpPDU) {
+			if name, ok := pdu.Value.([]byte); ok {
+				ic.Name = string(name)
+			}
+		}},
+		{inoid, func(ic *InterfaceCounters, pdu gosnmp.SnmpPDU) {
+			ic.InOctets = gosnmp.ToBigInt(pdu.Value).Uint64()
+		}},
+		{outoid, func(ic *InterfaceCounters, pdu gosnmp.SnmpPDU) {
+			ic.OutOctets = gosnmp.ToBigInt(pdu.Value).Uint64()
+		}},
+	}
+	for _, walk := range walks {
+		err = client.BulkWalk(walk.oid, func(pdu gosnmp.SnmpPDU) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if ic := entry(pdu, walk.oid); ic != nil {
+				walk.set(ic, pdu)
+			}
+			return nil
+		})
+		err = snmpErrCheck(err)
+		if err != nil {
+			return counters, err
+		}
+	}
+	slices.SortFunc(counters, func(a, b InterfaceCounters) int {
+		return cmp.Compare(a.Index, b.Index)
+	})
+	return counters, nil
+}
+
+// snmpOidSuffix returns the numeric index parts of the oid after the root oid
+func snmpOidSuffix(oid, rootoid string) []int {
+	parts := strings.Split(strings.TrimPrefix(stripIPAddressFromSNMPOid(oid, rootoid), "."), ".")
+	ret := make([]int, 0, len(parts))
+	for _, part := range parts {
+		x, err := strconv.Atoi(part)
+		if err != nil {
+			return nil
+		}
+		ret = append(ret, x)
+	}
+	return ret
+}
+
 func stripIPAddressFromSNMPOid(oid, rootoid string) string {
 	return strings.Replace(oid, "."+rootoid+".", "", 1)
 }
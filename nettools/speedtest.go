@@ -0,0 +1,183 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+var _ SpeedTester = (*pkg)(nil)
+
+type SpeedTester interface {
+	HTTPSpeedTest(context.Context, string, string, int) (SpeedTestResult, error)
+	Iperf3SpeedTest(context.Context, string, int, time.Duration) (SpeedTestResult, error)
+}
+
+// SpeedTestResult is the throughput of one bandwidth test in bits per second, Latency is the
+// time until the first response of the download (zero for iperf3)
+type SpeedTestResult struct {
+	Start    time.Time
+	Latency  time.Duration
+	Download float64
+	Upload   float64
+}
+
+var ErrIperf3Unavailable = errors.New("iperf3 executable not found")
+
+// HTTPSpeedTest measures the download rate of a GET of downloadURL and the upload rate of a
+// POST of uploadSize bytes to uploadURL, a blank uploadURL skips the upload
+func HTTPSpeedTest(
+	ctx context.Context,
+	downloadURL string,
+	uploadURL string,
+	uploadSize int,
+) (SpeedTestResult, error) {
+	return DefaultPkg.HTTPSpeedTest(ctx, downloadURL, uploadURL, uploadSize)
+}
+
+func (p *pkg) HTTPSpeedTest(
+	ctx context.Context,
+	downloadURL string,
+	uploadURL string,
+	uploadSize int,
+) (result SpeedTestResult, err error) {
+	result.Start = time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("User-Agent", p.GetUserAgent())
+	resp, err := p.verifyclient.Do(req)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	result.Latency = time.Since(result.Start)
+	if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("%s: %s", downloadURL, resp.Status)
+	}
+	start := time.Now()
+	n, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return result, err
+	}
+	result.Download = bitsPerSecond(n, time.Since(start))
+
+	if uploadURL == "" {
+		return result, nil
+	}
+	req, err = http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		uploadURL,
+		bytes.NewReader(make([]byte, uploadSize)),
+	)
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("User-Agent", p.GetUserAgent())
+	req.Header.Set("Content-Type", "application/octet-stream")
+	start = time.Now()
+	resp, err = p.verifyclient.Do(req)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= http.StatusBadRequest {
+		return result, fmt.Errorf("%s: %s", uploadURL, resp.Status)
+	}
+	result.Upload = bitsPerSecond(int64(uploadSize), time.Since(start))
+	return result, nil
+}
+
+// Iperf3SpeedTest runs the iperf3 client against server twice, sending for the upload rate and
+// receiving (reverse mode) for the download rate
+func Iperf3SpeedTest(
+	ctx context.Context,
+	server string,
+	port int,
+	duration time.Duration,
+) (SpeedTestResult, error) {
+	return DefaultPkg.Iperf3SpeedTest(ctx, server, port, duration)
+}
+
+func (p *pkg) Iperf3SpeedTest(
+	ctx context.Context,
+	server string,
+	port int,
+	duration time.Duration,
+) (result SpeedTestResult, err error) {
+	result.Start = time.Now()
+	result.Upload, err = runIperf3(ctx, server, port, duration, false)
+	if err != nil {
+		return result, err
+	}
+	result.Download, err = runIperf3(ctx, server, port, duration, true)
+	return result, err
+}
+
+func runIperf3(
+	ctx context.Context,
+	server string,
+	port int,
+	duration time.Duration,
+	reverse bool,
+) (float64, error) {
+	args := []string{
+		"--client", server,
+		"--port", strconv.Itoa(port),
+		"--time", strconv.Itoa(max(1, int(duration/time.Second))),
+		"--json",
+	}
+	if reverse {
+		args = append(args, "--reverse")
+	}
+	out, err := exec.CommandContext(ctx, "iperf3", args...).Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return 0, ErrIperf3Unavailable
+	}
+	// iperf3 exits non zero on test errors, the reason is in the json output
+	if len(out) == 0 && err != nil {
+		return 0, err
+	}
+	return parseIperf3Output(out)
+}
+
+// parseIperf3Output returns the received rate from the json output of an iperf3 client
+func parseIperf3Output(out []byte) (float64, error) {
+	var report struct {
+		Error string `json:"error"`
+		End   struct {
+			SumReceived struct {
+				BitsPerSecond float64 `json:"bits_per_second"`
+			} `json:"sum_received"`
+		} `json:"end"`
+	}
+	err := json.Unmarshal(out, &report)
+	if err != nil {
+		return 0, err
+	}
+	if report.Error != "" {
+		return 0, errors.New("iperf3: " + report.Error)
+	}
+	return report.End.SumReceived.BitsPerSecond, nil
+}
+
+func bitsPerSecond(n int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(n*8) / elapsed.Seconds()
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPSpeedTest(t *testing.T) {
+	uploaded := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			n, _ := io.Copy(io.Discard, r.Body)
+			uploaded = int(n)
+			return
+		}
+		w.Write(make([]byte, 64*1024))
+	}))
+	defer srv.Close()
+
+	result, err := HTTPSpeedTest(context.Background(), srv.URL+"/down", srv.URL+"/up", 32*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Download <= 0 || result.Upload <= 0 || result.Latency <= 0 {
+		t.Errorf("want measured rates, got: %+v", result)
+	}
+	if uploaded != 32*1024 {
+		t.Errorf("uploaded want: %d, got: %d", 32*1024, uploaded)
+	}
+}
+
+func TestParseIperf3Output(t *testing.T) {
+	tests := map[string]struct {
+		out     string
+		want    float64
+		wantErr bool
+	}{
+		"Received": {
+			out:  `{"end":{"sum_sent":{"bits_per_second":2e8},"sum_received":{"bits_per_second":1.5e8}}}`,
+			want: 1.5e8,
+		},
+		"ServerBusy": {
+			out:     `{"end":{},"error":"the server is busy running a test. try again later"}`,
+			wantErr: true,
+		},
+		"Garbage": {
+			out:     `iperf3: error`,
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseIperf3Output([]byte(tc.out))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error want: %t, got: %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("want: %f, got: %f", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/networkables/mason/internal/tracing"
+)
+
+// TcpPing times tcp connects to the target, a latency probe for hosts which drop icmp but
+// run a service.  Each connect is closed once established.  The read timeout of the options
+// bounds each connect, the responses of unanswered connects carry the error, a refused
+// connect is unanswered as the port is closed.  ErrNoResponseFromRemote is returned when
+// no connect succeeds.
+func TcpPing(
+	ctx context.Context,
+	target netip.AddrPort,
+	opts ...Icmp4EchoOption,
+) ([]Icmp4EchoResponse, error) {
+	return DefaultPkg.TcpPing(ctx, target, opts...)
+}
+
+func (p *pkg) TcpPing(
+	ctx context.Context,
+	target netip.AddrPort,
+	opts ...Icmp4EchoOption,
+) ([]Icmp4EchoResponse, error) {
+	ctx, span := tracing.Start(ctx, "tcp.ping", tracing.AddrKey.String(target.String()))
+	response, err := p.tcpPing(ctx, target, opts...)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (p *pkg) tcpPing(
+	ctx context.Context,
+	target netip.AddrPort,
+	opts ...Icmp4EchoOption,
+) ([]Icmp4EchoResponse, error) {
+	opt := i4eApplyOptionsToDefault(opts...)
+	response := make([]Icmp4EchoResponse, 0, opt.Count)
+	answered := false
+	for i := 0; i < opt.Count; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(opt.BetweenDuration):
+			}
+		}
+		if ctx.Err() != nil {
+			return response, ctx.Err()
+		}
+		r := tcpConnect(ctx, target, opt.ReadTimeout)
+		answered = answered || r.Err == nil
+		response = append(response, r)
+	}
+	if !answered {
+		return response, newErrNoResponse(target.Addr(), nil)
+	}
+	return response, nil
+}
+
+func tcpConnect(ctx context.Context, target netip.AddrPort, timeout time.Duration) Icmp4EchoResponse {
+	d := net.Dialer{Timeout: timeout}
+	start := time.Now()
+	conn, err := d.DialContext(ctx, "tcp", target.String())
+	stop := time.Now()
+	r := Icmp4EchoResponse{
+		Peer:    target.Addr(),
+		Start:   start,
+		Elapsed: stop.Sub(start),
+		Err:     err,
+	}
+	if err != nil {
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			r.Err = ErrConnectionRefused
+		}
+		return r
+	}
+	conn.Close()
+	return r
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestTcpPing(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	open := netip.MustParseAddrPort(ln.Addr().String())
+
+	closedln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := netip.MustParseAddrPort(closedln.Addr().String())
+	closedln.Close()
+
+	tests := map[string]struct {
+		target  netip.AddrPort
+		wantErr error
+		wantR   error
+	}{
+		"Open":   {target: open, wantErr: nil, wantR: nil},
+		"Closed": {target: closed, wantErr: ErrNoResponseFromRemote, wantR: ErrConnectionRefused},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rs, err := TcpPing(
+				context.Background(),
+				tc.target,
+				I4EWithCount(2),
+				I4EWithReadTimeout(time.Second),
+				I4EWithBetweenDuration(0),
+			)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("want: %v, got: %v", tc.wantErr, err)
+			}
+			if len(rs) != 2 {
+				t.Fatalf("want 2 responses, got %d", len(rs))
+			}
+			for _, r := range rs {
+				if !errors.Is(r.Err, tc.wantR) {
+					t.Errorf("response want: %v, got: %v", tc.wantR, r.Err)
+				}
+				if r.Peer != tc.target.Addr() {
+					t.Errorf("peer want: %s, got: %s", tc.target.Addr(), r.Peer)
+				}
+			}
+		})
+	}
+}
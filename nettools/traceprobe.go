@@ -0,0 +1,246 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	protocolUDP = 17
+	// udpTracePortBase is the first destination port of udp probes, each ttl adds one the
+	// same as the classic traceroute
+	udpTracePortBase = 33434
+	tcpTracePort     = 80
+)
+
+// TraceProbe selects the packets a traceroute sends.  Routers answer each with an icmp time
+// exceeded, the target with an echo reply (icmp), a port unreachable (udp) or a syn+ack
+// or rst (tcp).  Networks dropping icmp echo requests often still pass udp or tcp.
+type TraceProbe int
+
+func (p TraceProbe) String() string {
+	switch p {
+	case IcmpTraceProbe:
+		return "ICMP"
+	case UdpTraceProbe:
+		return "UDP"
+	case TcpTraceProbe:
+		return "TCP"
+	}
+	return "INVALID"
+}
+
+const (
+	InvalidTraceProbe TraceProbe = iota
+	IcmpTraceProbe
+	UdpTraceProbe
+	TcpTraceProbe
+)
+
+func StringToTraceProbe(str string) (TraceProbe, error) {
+	switch strings.ToLower(str) {
+	case "", "icmp":
+		return IcmpTraceProbe, nil
+	case "udp":
+		return UdpTraceProbe, nil
+	case "tcp":
+		return TcpTraceProbe, nil
+	}
+	return InvalidTraceProbe, ErrInvalidTraceProbeString
+}
+
+// traceProbe sends each of the probes in turn until a hop or the target answers one.  The
+// probe answered is moved to the front, so the next hops try it first.
+func (p *pkg) traceProbe(
+	ctx context.Context,
+	target netip.Addr,
+	ttl int,
+	probes []TraceProbe,
+	opt *Icmp4EchoOptions,
+) (r Icmp4EchoResponse, err error) {
+	for i, probe := range probes {
+		r, err = p.privilegedProbe4(ctx, probe, target, ttl, opt)
+		r.Probe = probe
+		if err == nil || errors.Is(err, ErrTTLExceeded) {
+			copy(probes[1:i+1], probes[:i])
+			probes[0] = probe
+			return r, err
+		}
+		if ctx.Err() != nil {
+			return r, ctx.Err()
+		}
+	}
+	return r, err
+}
+
+// privilegedProbe4 sends one probe with the ttl, through the helper when one is used
+func (p *pkg) privilegedProbe4(
+	ctx context.Context,
+	probe TraceProbe,
+	target netip.Addr,
+	ttl int,
+	opt *Icmp4EchoOptions,
+) (Icmp4EchoResponse, error) {
+	if probe == IcmpTraceProbe {
+		return p.privilegedPingIcmp4(
+			ctx, target, ttl, opt.ListenAddress, opt.ReadTimeout, opt.IcmpID, opt.IcmpSeq,
+			opt.PayloadSize, opt.DSCP, opt.AllowAllErrors,
+		)
+	}
+	if p.helper == nil {
+		return rawTraceProbe4(ctx, probe, target, ttl, opt.TracePort, opt.ListenAddress, opt.ReadTimeout)
+	}
+	return p.helper.traceProbe4(ctx, HelperProbeArgs{
+		Probe:         probe,
+		Target:        target,
+		TTL:           ttl,
+		Port:          opt.TracePort,
+		ListenAddress: opt.ListenAddress,
+		ReadTimeout:   opt.ReadTimeout,
+	})
+}
+
+// rawTraceProbe4 sends a udp or tcp probe and waits for the icmp error quoting it, the
+// icmp errors are read from a raw socket
+func rawTraceProbe4(
+	ctx context.Context,
+	probe TraceProbe,
+	target netip.Addr,
+	ttl int,
+	port int,
+	listenAddress netip.Addr,
+	readTimeout time.Duration,
+) (response Icmp4EchoResponse, err error) {
+	if ctx.Err() != nil {
+		return response, ctx.Err()
+	}
+	if !target.Is4() {
+		return response, ErrIPv6Unsupported
+	}
+	ln, err := icmp.ListenPacket("ip4:icmp", listenAddress.String())
+	if err != nil {
+		return response, err
+	}
+	defer ln.Close()
+	deadline := time.Now().Add(readTimeout)
+	err = ln.SetReadDeadline(deadline)
+	if err != nil {
+		return response, err
+	}
+
+	switch probe {
+	case UdpTraceProbe:
+		if port == 0 {
+			port = udpTracePortBase + ttl - 1
+		}
+		return udpTraceProbe4(ln, target, ttl, port, listenAddress)
+	case TcpTraceProbe:
+		if port == 0 {
+			port = tcpTracePort
+		}
+		return tcpTraceProbe4(ln, target, ttl, port, deadline)
+	}
+	return response, ErrInvalidTraceProbeString
+}
+
+func udpTraceProbe4(
+	ln *icmp.PacketConn,
+	target netip.Addr,
+	ttl int,
+	port int,
+	listenAddress netip.Addr,
+) (response Icmp4EchoResponse, err error) {
+	c, err := net.ListenPacket("udp4", net.JoinHostPort(listenAddress.String(), "0"))
+	if err != nil {
+		return response, err
+	}
+	defer c.Close()
+	err = ipv4.NewPacketConn(c).SetTTL(ttl)
+	if err != nil {
+		return response, err
+	}
+	srcport := c.LocalAddr().(*net.UDPAddr).Port
+
+	start := time.Now()
+	_, err = c.WriteTo([]byte("HELLO-R-U-THERE"), &net.UDPAddr{IP: target.AsSlice(), Port: port})
+	if err != nil {
+		return response, err
+	}
+	return readTraceReply(ln, target, protocolUDP, srcport, port, start)
+}
+
+// readTraceReply reads icmp errors until one quotes the probe or the read deadline passes.
+// A time exceeded is from a hop, a port unreachable from the target means it is reached.
+func readTraceReply(
+	ln *icmp.PacketConn,
+	target netip.Addr,
+	proto int,
+	srcport int,
+	dstport int,
+	start time.Time,
+) (response Icmp4EchoResponse, err error) {
+	rb := make([]byte, 1500)
+	for {
+		n, peer, err := ln.ReadFrom(rb)
+		response = Icmp4EchoResponse{}.populate(peer, start, time.Now(), err)
+		if err != nil {
+			var operr *net.OpError
+			if errors.As(err, &operr) && operr.Timeout() {
+				return response, newErrNoResponse(target, operr)
+			}
+			return response, err
+		}
+		rm, err := icmp.ParseMessage(ProtocolICMP, rb[:n])
+		if err != nil {
+			continue
+		}
+		switch body := rm.Body.(type) {
+		case *icmp.TimeExceeded:
+			if quotesProbe(body.Data, proto, target, srcport, dstport) {
+				return response, ErrTTLExceeded
+			}
+		case *icmp.DstUnreach:
+			if !quotesProbe(body.Data, proto, target, srcport, dstport) {
+				continue
+			}
+			if response.Peer == target {
+				return response, nil
+			}
+			response.Err = ErrDestinationUnreachable
+			return response, ErrDestinationUnreachable
+		}
+	}
+}
+
+// quotesProbe checks the datagram quoted by an icmp error (its ip header and at least the
+// first 8 bytes of the payload) is the probe sent to the target from srcport to dstport
+func quotesProbe(quoted []byte, proto int, target netip.Addr, srcport int, dstport int) bool {
+	if len(quoted) < ipv4.HeaderLen {
+		return false
+	}
+	hdrlen := int(quoted[0]&0x0f) << 2
+	if hdrlen < ipv4.HeaderLen || len(quoted) < hdrlen+4 {
+		return false
+	}
+	if int(quoted[9]) != proto {
+		return false
+	}
+	if netip.AddrFrom4([4]byte(quoted[16:20])) != target {
+		return false
+	}
+	ports := quoted[hdrlen:]
+	return int(binary.BigEndian.Uint16(ports[0:2])) == srcport &&
+		int(binary.BigEndian.Uint16(ports[2:4])) == dstport
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package nettools
+
+import (
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// tcpTraceProbe4 sends a syn from a raw socket, a syn+ack or rst from the target means it
+// is reached.  The kernel answers the syn+ack with a rst, so no connection is completed.
+func tcpTraceProbe4(
+	ln *icmp.PacketConn,
+	target netip.Addr,
+	ttl int,
+	port int,
+	deadline time.Time,
+) (response Icmp4EchoResponse, err error) {
+	src, err := sourceAddrFor(target)
+	if err != nil {
+		return response, err
+	}
+	conn, err := net.ListenPacket("ip4:tcp", src.String())
+	if err != nil {
+		return response, err
+	}
+	defer conn.Close()
+	err = ipv4.NewPacketConn(conn).SetTTL(ttl)
+	if err != nil {
+		return response, err
+	}
+	err = conn.SetReadDeadline(deadline)
+	if err != nil {
+		return response, err
+	}
+
+	srcport := synPortOffset + rand.IntN(synPortRange)
+	seq := rand.Uint32()
+	dst := &net.IPAddr{IP: net.IP(target.AsSlice())}
+
+	// the reply of the target stops the wait for icmp errors
+	reached := make(chan time.Time, 1)
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			ip, ok := peer.(*net.IPAddr)
+			if !ok || !ip.IP.Equal(dst.IP) {
+				continue
+			}
+			if _, _, ok := parseSynReply(buf[:n], srcport, seq); ok {
+				reached <- time.Now()
+				_ = ln.SetReadDeadline(time.Now())
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	_, err = conn.WriteTo(buildSynPacket(src, target, srcport, port, seq), dst)
+	if err != nil {
+		return response, err
+	}
+	response, err = readTraceReply(ln, target, protocolTCP, srcport, port, start)
+	select {
+	case stop := <-reached:
+		return Icmp4EchoResponse{}.populate(dst, start, stop, nil), nil
+	default:
+	}
+	return response, err
+}
@@ -0,0 +1,26 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package nettools
+
+import (
+	"net/netip"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+// tcpTraceProbe4 is only supported on linux, other systems do not pass tcp replies to raw
+// sockets
+func tcpTraceProbe4(
+	ln *icmp.PacketConn,
+	target netip.Addr,
+	ttl int,
+	port int,
+	deadline time.Time,
+) (Icmp4EchoResponse, error) {
+	return Icmp4EchoResponse{}, ErrTraceProbeUnavailable
+}
@@ -0,0 +1,45 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+)
+
+func TestQuotesProbe(t *testing.T) {
+	target := netip.MustParseAddr("192.0.2.7")
+	quote := func(proto byte, dst netip.Addr, srcport int, dstport int) []byte {
+		b := make([]byte, 28)
+		b[0] = 0x45
+		b[9] = proto
+		d := dst.As4()
+		copy(b[16:20], d[:])
+		binary.BigEndian.PutUint16(b[20:22], uint16(srcport))
+		binary.BigEndian.PutUint16(b[22:24], uint16(dstport))
+		return b
+	}
+	tests := map[string]struct {
+		quoted []byte
+		want   bool
+	}{
+		"Match":     {quoted: quote(protocolUDP, target, 40000, 33434), want: true},
+		"SrcPort":   {quoted: quote(protocolUDP, target, 40001, 33434), want: false},
+		"DstPort":   {quoted: quote(protocolUDP, target, 40000, 33435), want: false},
+		"Protocol":  {quoted: quote(protocolTCP, target, 40000, 33434), want: false},
+		"Target":    {quoted: quote(protocolUDP, netip.MustParseAddr("192.0.2.8"), 40000, 33434), want: false},
+		"Truncated": {quoted: quote(protocolUDP, target, 40000, 33434)[:22], want: false},
+		"Empty":     {quoted: nil, want: false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := quotesProbe(tc.quoted, protocolUDP, target, 40000, 33434)
+			if got != tc.want {
+				t.Errorf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ package nettools
 import (
 	"context"
 	"net/netip"
+	"slices"
 	"time"
 )
 
@@ -17,13 +18,18 @@ func Traceroute4(ctx context.Context, target netip.Addr, opts ...Icmp4EchoOption
 func (p *pkg) Traceroute4(ctx context.Context, target netip.Addr, opts ...Icmp4EchoOption) ([][]Icmp4EchoResponse, error) {
 	traceopt := i4eApplyOptionsToDefault(opts...)
 	traceopt = i4eApplyOptions(traceopt, I4EWithAllowAllErrors(true), I4EWithCount(5))
+	// the order changes as hops answer, the caller's slice is left as is
+	probes := slices.Clone(traceopt.TraceProbes)
+	if len(probes) == 0 {
+		probes = []TraceProbe{IcmpTraceProbe}
+	}
 	hops := 20
 	response := make([][]Icmp4EchoResponse, 0, hops)
 	for i := 0; i < hops; i++ {
 		hopr := make([]Icmp4EchoResponse, 0, traceopt.Count)
 		for c := 0; c < traceopt.Count; c++ {
-			// Can only use privileged ping for traceroute
-			r, err := rawPingIcmp4(ctx, target, i+1, traceopt.ListenAddress, traceopt.ReadTimeout, traceopt.IcmpID, traceopt.IcmpSeq, traceopt.AllowAllErrors)
+			// Can only use privileged probes for traceroute
+			r, err := p.traceProbe(ctx, target, i+1, probes, traceopt)
 			hopr = append(hopr, r)
 			if err == nil {
 				i = hops
@@ -0,0 +1,320 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	GuestKindContainer = "container"
+	GuestKindVM        = "vm"
+
+	proxmoxServerHeader = "pve-api-daemon"
+)
+
+var (
+	// ErrNotVirtualHost is returned when the target does not answer as the platform asked for
+	ErrNotVirtualHost = errors.New("not a virtualization host")
+
+	// ErrVirtualCredentialsRequired is returned when the host lists its guests only to a
+	// client with credentials
+	ErrVirtualCredentialsRequired = errors.New("credentials required to list guests")
+
+	proxmoxNetRegex = regexp.MustCompile(`(?i)([0-9a-f]{2}:){5}[0-9a-f]{2}`)
+	proxmoxIPRegex  = regexp.MustCompile(`ip=([0-9.]+)`)
+)
+
+// VirtualGuest is a container or virtual machine reported by its host, the MAC and addr are
+// only set when the host knows them
+type VirtualGuest struct {
+	ID    string
+	Name  string
+	Kind  string
+	State string
+	MAC   net.HardwareAddr
+	Addr  netip.Addr
+}
+
+func DockerGuests(
+	ctx context.Context,
+	addr netip.Addr,
+	port int,
+	timeout time.Duration,
+) ([]VirtualGuest, error) {
+	return DefaultPkg.DockerGuests(ctx, addr, port, timeout)
+}
+
+// DockerGuests lists the containers of a docker engine exposing its api over plain tcp
+func (p *pkg) DockerGuests(
+	ctx context.Context,
+	addr netip.Addr,
+	port int,
+	timeout time.Duration,
+) ([]VirtualGuest, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	url := fmt.Sprintf("http://%s/containers/json?all=1", netip.AddrPortFrom(addr, uint16(port)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.httpclient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Api-Version") == "" || resp.StatusCode != http.StatusOK {
+		return nil, ErrNotVirtualHost
+	}
+
+	var containers []struct {
+		ID              string `json:"Id"`
+		Names           []string
+		State           string
+		NetworkSettings struct {
+			Networks map[string]struct {
+				IPAddress  string
+				MacAddress string
+			}
+		}
+	}
+	err = json.NewDecoder(resp.Body).Decode(&containers)
+	if err != nil {
+		return nil, err
+	}
+	guests := make([]VirtualGuest, 0, len(containers))
+	for _, c := range containers {
+		guest := VirtualGuest{
+			ID:    c.ID[:min(len(c.ID), 12)],
+			Kind:  GuestKindContainer,
+			State: c.State,
+		}
+		if len(c.Names) > 0 {
+			guest.Name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		for _, network := range c.NetworkSettings.Networks {
+			if mac, err := net.ParseMAC(network.MacAddress); err == nil {
+				guest.MAC = mac
+			}
+			if ip, err := netip.ParseAddr(network.IPAddress); err == nil {
+				guest.Addr = ip
+			}
+		}
+		guests = append(guests, guest)
+	}
+	return guests, nil
+}
+
+func ProxmoxGuests(
+	ctx context.Context,
+	addr netip.Addr,
+	port int,
+	token string,
+	timeout time.Duration,
+) ([]VirtualGuest, error) {
+	return DefaultPkg.ProxmoxGuests(ctx, addr, port, token, timeout)
+}
+
+// ProxmoxGuests lists the virtual machines and containers of a proxmox cluster through the api
+// of one of its nodes.  The token is an api token as USER@REALM!TOKENID=SECRET, without one a
+// proxmox host returns ErrVirtualCredentialsRequired.
+func (p *pkg) ProxmoxGuests(
+	ctx context.Context,
+	addr netip.Addr,
+	port int,
+	token string,
+	timeout time.Duration,
+) ([]VirtualGuest, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	base := fmt.Sprintf("https://%s/api2/json", netip.AddrPortFrom(addr, uint16(port)))
+	var resources []struct {
+		VMID   int `json:"vmid"`
+		Name   string
+		Type   string
+		Status string
+		Node   string
+	}
+	err := p.proxmoxGet(ctx, base+"/cluster/resources?type=vm", token, &resources)
+	if err != nil {
+		return nil, err
+	}
+	guests := make([]VirtualGuest, 0, len(resources))
+	for _, r := range resources {
+		guest := VirtualGuest{
+			ID:    strconv.Itoa(r.VMID),
+			Name:  r.Name,
+			Kind:  GuestKindVM,
+			State: r.Status,
+		}
+		if r.Type == "lxc" {
+			guest.Kind = GuestKindContainer
+		}
+		// the network devices of the config carry the mac, and for containers a static ip
+		var config map[string]any
+		url := fmt.Sprintf("%s/nodes/%s/%s/%d/config", base, r.Node, r.Type, r.VMID)
+		if p.proxmoxGet(ctx, url, token, &config) == nil {
+			guest.MAC, guest.Addr = proxmoxGuestNet(config)
+		}
+		guests = append(guests, guest)
+	}
+	return guests, nil
+}
+
+func (p *pkg) proxmoxGet(ctx context.Context, url string, token string, data any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "PVEAPIToken="+token)
+	}
+	resp, err := p.httpclient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if !strings.HasPrefix(resp.Header.Get("Server"), proxmoxServerHeader) {
+		return ErrNotVirtualHost
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrVirtualCredentialsRequired
+	default:
+		return fmt.Errorf("proxmox api %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(&struct{ Data any }{Data: data})
+}
+
+// proxmoxGuestNet reads the first network device of a guest config, net0=virtio=MAC,bridge=..
+// for virtual machines and net0=name=eth0,hwaddr=MAC,ip=ADDR/MASK,.. for containers
+func proxmoxGuestNet(config map[string]any) (net.HardwareAddr, netip.Addr) {
+	for idx := 0; idx < 32; idx++ {
+		value, ok := config["net"+strconv.Itoa(idx)].(string)
+		if !ok {
+			continue
+		}
+		mac, err := net.ParseMAC(proxmoxNetRegex.FindString(value))
+		if err != nil {
+			continue
+		}
+		var addr netip.Addr
+		if match := proxmoxIPRegex.FindStringSubmatch(value); match != nil {
+			addr, _ = netip.ParseAddr(match[1])
+		}
+		return mac, addr
+	}
+	return nil, netip.Addr{}
+}
+
+func SnmpGetVMwareGuests(
+	ctx context.Context,
+	addr netip.Addr,
+	options ...snmpRequestOptionFunc,
+) ([]VirtualGuest, error) {
+	return DefaultPkg.SnmpGetVMwareGuests(ctx, addr, options...)
+}
+
+// SnmpGetVMwareGuests walks the VMWARE-VMINFO-MIB of an esxi host for the virtual machines
+// (vmwVmTable) and their macs (vmwVmNetTable)
+func (p pkg) SnmpGetVMwareGuests(
+	ctx context.Context,
+	addr netip.Addr,
+	options ...snmpRequestOptionFunc,
+) ([]VirtualGuest, error) {
+	opts := applySnmpRequestOptions(options...)
+
+	nameoid := "1.3.6.1.4.1.6876.2.1.1.2"
+	stateoid := "1.3.6.1.4.1.6876.2.1.1.6"
+	netoid := "1.3.6.1.4.1.6876.2.4.1"
+	guests := make([]VirtualGuest, 0)
+	byIndex := make(map[int]int)
+
+	client, err := snmpClient(addr, opts.community, opts.port, opts.responseTimeout)
+	if err != nil {
+		return guests, err
+	}
+	defer client.Conn.Close()
+	err = client.BulkWalk(nameoid, func(pdu gosnmp.SnmpPDU) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		idx := snmpOidSuffix(pdu.Name, nameoid)
+		if name, ok := pdu.Value.([]byte); ok && len(idx) == 1 {
+			byIndex[idx[0]] = len(guests)
+			guests = append(guests, VirtualGuest{
+				ID:   strconv.Itoa(idx[0]),
+				Name: string(name),
+				Kind: GuestKindVM,
+			})
+		}
+		return nil
+	})
+	err = snmpErrCheck(err)
+	if err != nil {
+		return guests, err
+	}
+	if len(guests) == 0 {
+		return guests, ErrNotVirtualHost
+	}
+	err = client.BulkWalk(stateoid, func(pdu gosnmp.SnmpPDU) error {
+		idx := snmpOidSuffix(pdu.Name, stateoid)
+		state, ok := pdu.Value.([]byte)
+		if pos, found := byIndex[firstOr(idx, -1)]; ok && found {
+			guests[pos].State = string(state)
+		}
+		return nil
+	})
+	err = snmpErrCheck(err)
+	if err != nil {
+		return guests, err
+	}
+	// the mac column is found by its value, index is column.vmidx.netidx
+	err = client.BulkWalk(netoid, func(pdu gosnmp.SnmpPDU) error {
+		idx := snmpOidSuffix(pdu.Name, netoid)
+		value, ok := pdu.Value.([]byte)
+		if len(idx) != 3 || !ok {
+			return nil
+		}
+		pos, found := byIndex[idx[1]]
+		if !found || guests[pos].MAC != nil {
+			return nil
+		}
+		if len(value) == 6 {
+			guests[pos].MAC = net.HardwareAddr(value)
+		} else if mac, err := net.ParseMAC(string(value)); err == nil && len(mac) == 6 {
+			guests[pos].MAC = mac
+		}
+		return nil
+	})
+	err = snmpErrCheck(err)
+	if err != nil {
+		return guests, err
+	}
+	return guests, nil
+}
+
+func firstOr(idx []int, def int) int {
+	if len(idx) == 0 {
+		return def
+	}
+	return idx[0]
+}
@@ -0,0 +1,111 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package nettools
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestProxmoxGuestNet(t *testing.T) {
+	mac := net.HardwareAddr{0xbc, 0x24, 0x11, 0x2a, 0x3b, 0x4c}
+	tests := map[string]struct {
+		config   map[string]any
+		wantMAC  net.HardwareAddr
+		wantAddr netip.Addr
+	}{
+		"VM": {
+			config:  map[string]any{"net0": "virtio=BC:24:11:2A:3B:4C,bridge=vmbr0,firewall=1"},
+			wantMAC: mac,
+		},
+		"Container": {
+			config: map[string]any{
+				"net0": "name=eth0,bridge=vmbr0,hwaddr=BC:24:11:2A:3B:4C,ip=192.168.1.50/24,type=veth",
+			},
+			wantMAC:  mac,
+			wantAddr: netip.MustParseAddr("192.168.1.50"),
+		},
+		"SecondDevice": {
+			config: map[string]any{
+				"net1":   "virtio=BC:24:11:2A:3B:4C,bridge=vmbr1",
+				"memory": float64(2048),
+			},
+			wantMAC: mac,
+		},
+		"NoNetwork": {
+			config: map[string]any{"memory": float64(2048)},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotMAC, gotAddr := proxmoxGuestNet(tc.config)
+			if diff := cmp.Diff(tc.wantMAC, gotMAC); diff != "" {
+				t.Errorf("mac (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantAddr, gotAddr, cmpopts.EquateComparable(netip.Addr{})); diff != "" {
+				t.Errorf("addr (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDockerGuests(t *testing.T) {
+	tests := map[string]struct {
+		handler http.HandlerFunc
+		want    []VirtualGuest
+		wantErr error
+	}{
+		"Docker": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Api-Version", "1.45")
+				w.Write([]byte(`[{"Id":"8dfafdbc3a40f1b9","Names":["/web"],"State":"running",
+"NetworkSettings":{"Networks":{"bridge":{"IPAddress":"172.17.0.2","MacAddress":"02:42:ac:11:00:02"}}}}]`))
+			},
+			want: []VirtualGuest{{
+				ID:    "8dfafdbc3a40",
+				Name:  "web",
+				Kind:  GuestKindContainer,
+				State: "running",
+				MAC:   net.HardwareAddr{0x02, 0x42, 0xac, 0x11, 0x00, 0x02},
+				Addr:  netip.MustParseAddr("172.17.0.2"),
+			}},
+		},
+		"NotDocker": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`[]`))
+			},
+			wantErr: ErrNotVirtualHost,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(tc.handler)
+			defer srv.Close()
+			addrport := netip.MustParseAddrPort(srv.Listener.Addr().String())
+
+			got, err := DockerGuests(
+				context.Background(),
+				addrport.Addr(),
+				int(addrport.Port()),
+				time.Second,
+			)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("error want %v got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateComparable(netip.Addr{})); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,526 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package client reads the json endpoints of a running mason server, the endpoints are
+// described by the OpenAPI document the server serves at /api/openapi.json
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/networkables/mason/internal/discovery"
+	"github.com/networkables/mason/internal/logging"
+	"github.com/networkables/mason/internal/model"
+)
+
+const defaultTimeout = 30 * time.Second
+
+var ErrUnexpectedStatus = errors.New("unexpected status")
+
+type (
+	Addr                 = model.Addr
+	Device               = model.Device
+	DeviceChange         = model.DeviceChange
+	Network              = model.Network
+	TagDefinition        = model.TagDefinition
+	MonitoringPolicy     = model.MonitoringPolicy
+	DeviceDetails        = model.DeviceDetails
+	ApprovalState        = model.ApprovalState
+	AddressPlan          = model.AddressPlan
+	Annotation           = model.Annotation
+	Tombstone            = model.Tombstone
+	TombstoneKind        = model.TombstoneKind
+	MaintenanceWindow    = model.MaintenanceWindow
+	PortProfile          = model.PortProfile
+	EventQuery           = model.EventQuery
+	EventRecord          = model.EventRecord
+	ConsistencyIssue     = model.ConsistencyIssue
+	NetworkScanProgress  = discovery.NetworkScanProgress
+	PacketCapture        = model.PacketCapture
+	PacketCaptureRequest = model.PacketCaptureRequest
+	LogLevel             = logging.SubsystemLevel
+	LogLevelChange       = logging.Change
+
+	// Inventory is every network and device known to the server
+	Inventory struct {
+		GeneratedAt time.Time
+		Anonymized  bool
+		Networks    []Network
+		Devices     []Device
+	}
+
+	// CloudImportResult counts what an import of a cloud provider changed
+	CloudImportResult struct {
+		Provider        string
+		NetworksAdded   int
+		NetworksUpdated int
+		Devices         int
+	}
+
+	// NetboxSyncResult counts what a sync with netbox changed
+	NetboxSyncResult struct {
+		NetworksAdded   int
+		NetworksUpdated int
+		DevicesAdded    int
+		DevicesUpdated  int
+		DevicesPushed   int
+	}
+)
+
+type Client struct {
+	server *url.URL
+	client *http.Client
+}
+
+type Option func(*Client)
+
+// WithHTTPClient replaces the default client, which times out after 30 seconds
+func WithHTTPClient(c *http.Client) Option {
+	return func(cl *Client) {
+		cl.client = c
+	}
+}
+
+// New returns a client of the server at the url, ex: http://localhost:4322
+func New(server string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("server url %q needs a http or https scheme", server)
+	}
+	c := &Client{
+		server: u,
+		client: &http.Client{Timeout: defaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, data any) error {
+	return c.do(ctx, http.MethodGet, path, query, nil, data)
+}
+
+func (c *Client) post(ctx context.Context, path string, query url.Values, body any, data any) error {
+	return c.do(ctx, http.MethodPost, path, query, body, data)
+}
+
+// do sends the body as json and decodes the response into data, the path elements must be
+// escaped
+func (c *Client) do(
+	ctx context.Context,
+	method string,
+	path string,
+	query url.Values,
+	body any,
+	data any,
+) error {
+	u := c.server.JoinPath(path)
+	u.RawQuery = query.Encode()
+	var reqBody io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return statusError(path, resp)
+	}
+	if data == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(data)
+}
+
+// statusError returns the unexpected status with the start of the body, the error text of the
+// server
+func statusError(path string, resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf(
+		"%w: %s %s: %s",
+		ErrUnexpectedStatus,
+		path,
+		resp.Status,
+		strings.TrimSpace(string(msg)),
+	)
+}
+
+// Inventory returns all networks and devices
+func (c *Client) Inventory(ctx context.Context) (Inventory, error) {
+	var inv Inventory
+	err := c.get(ctx, "/api/export", nil, &inv)
+	return inv, err
+}
+
+// AnonymizedInventory returns all networks and devices with the macs, names and public ips
+// hashed with the key, exports made with the same key line up
+func (c *Client) AnonymizedInventory(ctx context.Context, key string) (Inventory, error) {
+	var inv Inventory
+	err := c.get(ctx, "/api/export", url.Values{"anonymize": {"true"}, "key": {key}}, &inv)
+	return inv, err
+}
+
+func (c *Client) Devices(ctx context.Context) ([]Device, error) {
+	var devices []Device
+	err := c.get(ctx, "/api/remote/devices", nil, &devices)
+	return devices, err
+}
+
+func (c *Client) Networks(ctx context.Context) ([]Network, error) {
+	var networks []Network
+	err := c.get(ctx, "/api/remote/networks", nil, &networks)
+	return networks, err
+}
+
+// CreateNetwork adds the network, with scan it is scanned once added.  An existing prefix is
+// an error.
+func (c *Client) CreateNetwork(
+	ctx context.Context,
+	name string,
+	prefix string,
+	scan bool,
+) (Network, error) {
+	query := url.Values{"name": {name}, "prefix": {prefix}}
+	if scan {
+		query.Set("scan", "true")
+	}
+	var n Network
+	err := c.post(ctx, "/api/remote/networks", query, nil, &n)
+	return n, err
+}
+
+// ScanNetwork requests a scan of the network with the name or prefix, the LastScan of the
+// returned network is the time of the request and NetworkScans reports the progress
+func (c *Client) ScanNetwork(ctx context.Context, network string) (Network, error) {
+	var n Network
+	err := c.post(ctx, networkPath(network, "scan"), nil, nil, &n)
+	return n, err
+}
+
+func (c *Client) Tags(ctx context.Context) ([]TagDefinition, error) {
+	var defs []TagDefinition
+	err := c.get(ctx, "/api/tags", nil, &defs)
+	return defs, err
+}
+
+// AddressPlans returns the address plan of every network
+func (c *Client) AddressPlans(ctx context.Context) ([]AddressPlan, error) {
+	var plans []AddressPlan
+	err := c.get(ctx, "/api/ipam", nil, &plans)
+	return plans, err
+}
+
+// NetworkScans returns the progress of the running and recent network scans
+func (c *Client) NetworkScans(ctx context.Context) ([]NetworkScanProgress, error) {
+	var scans []NetworkScanProgress
+	err := c.get(ctx, "/api/scanprogress", nil, &scans)
+	return scans, err
+}
+
+// Annotations returns the annotations of the device at the address over the duration, zero
+// uses the server default
+func (c *Client) Annotations(
+	ctx context.Context,
+	addr string,
+	duration time.Duration,
+) ([]Annotation, error) {
+	var query url.Values
+	if duration > 0 {
+		query = url.Values{"duration": {duration.String()}}
+	}
+	var annotations []Annotation
+	err := c.get(ctx, "/api/annotations/"+url.PathEscape(addr), query, &annotations)
+	return annotations, err
+}
+
+// ReviewQueue returns the devices waiting for review, oldest first
+func (c *Client) ReviewQueue(ctx context.Context) ([]Device, error) {
+	var devices []Device
+	err := c.get(ctx, "/api/remote/review", nil, &devices)
+	return devices, err
+}
+
+// Device returns the device at the address
+func (c *Client) Device(ctx context.Context, addr Addr) (Device, error) {
+	var d Device
+	err := c.get(ctx, devicePath(addr), nil, &d)
+	return d, err
+}
+
+// EnrichDevice looks the device up again (dns, mdns, manufacturer, port scan, snmp) and
+// returns the updated device once the enrichment is done
+func (c *Client) EnrichDevice(ctx context.Context, addr Addr) (Device, error) {
+	var d Device
+	err := c.post(ctx, devicePath(addr, "enrich"), nil, nil, &d)
+	return d, err
+}
+
+// PingDevice runs a performance ping of the device and returns the updated device
+func (c *Client) PingDevice(ctx context.Context, addr Addr) (Device, error) {
+	var d Device
+	err := c.post(ctx, devicePath(addr, "ping"), nil, nil, &d)
+	return d, err
+}
+
+// DeviceHistory returns the recorded changes of the device
+func (c *Client) DeviceHistory(ctx context.Context, addr Addr) ([]DeviceChange, error) {
+	var changes []DeviceChange
+	err := c.get(ctx, devicePath(addr, "history"), nil, &changes)
+	return changes, err
+}
+
+func (c *Client) SetDevicePolicy(ctx context.Context, addr Addr, policy MonitoringPolicy) error {
+	return c.post(ctx, devicePath(addr, "policy"), nil, policy, nil)
+}
+
+// SetDeviceDetails replaces the name, owner, notes, site and asset fields of the device
+func (c *Client) SetDeviceDetails(ctx context.Context, addr Addr, details DeviceDetails) error {
+	return c.post(ctx, devicePath(addr, "details"), nil, details, nil)
+}
+
+func (c *Client) SetDeviceApproval(ctx context.Context, addr Addr, state ApprovalState) error {
+	return c.post(ctx, devicePath(addr, "approval", string(state)), nil, nil, nil)
+}
+
+// RemoveDevice moves the device to the deleted items
+func (c *Client) RemoveDevice(ctx context.Context, addr Addr) error {
+	return c.post(ctx, devicePath(addr, "delete"), nil, nil, nil)
+}
+
+func (c *Client) TagDevice(ctx context.Context, addr Addr, tag string) error {
+	return c.post(ctx, devicePath(addr, "tags", tag), nil, nil, nil)
+}
+
+func (c *Client) UntagDevice(ctx context.Context, addr Addr, tag string) error {
+	return c.post(ctx, devicePath(addr, "tags", tag), removeQuery, nil, nil)
+}
+
+// RemoveNetwork moves the network to the deleted items
+func (c *Client) RemoveNetwork(ctx context.Context, name string) error {
+	return c.post(ctx, networkPath(name, "delete"), nil, nil, nil)
+}
+
+func (c *Client) TagNetwork(ctx context.Context, name string, tag string) error {
+	return c.post(ctx, networkPath(name, "tags", tag), nil, nil, nil)
+}
+
+func (c *Client) UntagNetwork(ctx context.Context, name string, tag string) error {
+	return c.post(ctx, networkPath(name, "tags", tag), removeQuery, nil, nil)
+}
+
+// ListDeleted returns the deleted devices and networks which can still be restored
+func (c *Client) ListDeleted(ctx context.Context) ([]Tombstone, error) {
+	var ts []Tombstone
+	err := c.get(ctx, "/api/remote/deleted", nil, &ts)
+	return ts, err
+}
+
+func (c *Client) RestoreDeleted(ctx context.Context, kind TombstoneKind, key string) error {
+	return c.post(ctx, remotePath("deleted", string(kind), key, "restore"), nil, nil, nil)
+}
+
+// PurgeDeleted removes the deleted items past their retention and returns how many
+func (c *Client) PurgeDeleted(ctx context.Context) (int, error) {
+	var count int
+	err := c.post(ctx, "/api/remote/deleted/purge", nil, nil, &count)
+	return count, err
+}
+
+func (c *Client) ListMaintenanceWindows(ctx context.Context) ([]MaintenanceWindow, error) {
+	var windows []MaintenanceWindow
+	err := c.get(ctx, "/api/remote/maintenance", nil, &windows)
+	return windows, err
+}
+
+func (c *Client) SaveMaintenanceWindow(ctx context.Context, w MaintenanceWindow) error {
+	return c.post(ctx, "/api/remote/maintenance", nil, w, nil)
+}
+
+func (c *Client) RemoveMaintenanceWindow(ctx context.Context, name string) error {
+	return c.post(ctx, remotePath("maintenance", name, "delete"), nil, nil, nil)
+}
+
+func (c *Client) SaveTagDefinition(ctx context.Context, def TagDefinition) error {
+	return c.post(ctx, "/api/remote/tags", nil, def, nil)
+}
+
+// RemoveTagDefinition deletes the definition and removes the tag from all devices and networks
+func (c *Client) RemoveTagDefinition(ctx context.Context, name string) error {
+	return c.post(ctx, remotePath("tags", name, "delete"), nil, nil, nil)
+}
+
+func (c *Client) ListPortProfiles(ctx context.Context) ([]PortProfile, error) {
+	var pps []PortProfile
+	err := c.get(ctx, "/api/remote/portprofiles", nil, &pps)
+	return pps, err
+}
+
+func (c *Client) SavePortProfile(ctx context.Context, pp PortProfile) error {
+	return c.post(ctx, "/api/remote/portprofiles", nil, pp, nil)
+}
+
+func (c *Client) RemovePortProfile(ctx context.Context, name string) error {
+	return c.post(ctx, remotePath("portprofiles", name, "delete"), nil, nil, nil)
+}
+
+// TailEvents returns the latest stored events matching the query, oldest first
+func (c *Client) TailEvents(ctx context.Context, q EventQuery) ([]EventRecord, error) {
+	query := url.Values{}
+	if q.Kind != "" {
+		query.Set("kind", string(q.Kind))
+	}
+	if q.Search != "" {
+		query.Set("search", q.Search)
+	}
+	if q.Limit > 0 {
+		query.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if !q.After.IsZero() {
+		query.Set("after", q.After.Format(time.RFC3339Nano))
+	}
+	var records []EventRecord
+	err := c.get(ctx, "/api/remote/events", query, &records)
+	return records, err
+}
+
+// ArchiveTimeseries moves old ping and flow data into archive files and returns the number
+// of records archived
+func (c *Client) ArchiveTimeseries(ctx context.Context) (int, error) {
+	var count int
+	err := c.post(ctx, "/api/remote/archive", nil, nil, &count)
+	return count, err
+}
+
+// CheckConsistency returns the records referencing missing data, with repair they are fixed
+// or quarantined
+func (c *Client) CheckConsistency(ctx context.Context, repair bool) ([]ConsistencyIssue, error) {
+	var query url.Values
+	if repair {
+		query = url.Values{"repair": {"true"}}
+	}
+	var issues []ConsistencyIssue
+	err := c.post(ctx, "/api/remote/check", query, nil, &issues)
+	return issues, err
+}
+
+// ImportCloud imports the vpc subnets and network interfaces of the provider
+func (c *Client) ImportCloud(ctx context.Context, provider string) (CloudImportResult, error) {
+	var result CloudImportResult
+	err := c.post(ctx, remotePath("import", "cloud", provider), nil, nil, &result)
+	return result, err
+}
+
+// SyncNetbox imports the prefixes and ip addresses of netbox and pushes back the devices it
+// does not know
+func (c *Client) SyncNetbox(ctx context.Context) (NetboxSyncResult, error) {
+	var result NetboxSyncResult
+	err := c.post(ctx, remotePath("import", "netbox"), nil, nil, &result)
+	return result, err
+}
+
+// LogLevels returns the global log level of the server followed by the level of each
+// subsystem and whether its probes are traced
+func (c *Client) LogLevels(ctx context.Context) ([]LogLevel, error) {
+	var levels []LogLevel
+	err := c.get(ctx, "/api/remote/logging", nil, &levels)
+	return levels, err
+}
+
+// SetLogLevel changes a log level or the probe traces of a subsystem while the server runs
+func (c *Client) SetLogLevel(ctx context.Context, change LogLevelChange) ([]LogLevel, error) {
+	var levels []LogLevel
+	err := c.post(ctx, "/api/remote/logging", nil, change, &levels)
+	return levels, err
+}
+
+// Captures returns the packet captures, the newest first
+func (c *Client) Captures(ctx context.Context) ([]PacketCapture, error) {
+	var captures []PacketCapture
+	err := c.get(ctx, "/api/remote/captures", nil, &captures)
+	return captures, err
+}
+
+// StartCapture begins a capture on an interface of the server and returns it running
+func (c *Client) StartCapture(ctx context.Context, req PacketCaptureRequest) (PacketCapture, error) {
+	var capture PacketCapture
+	err := c.post(ctx, "/api/remote/captures", nil, req, &capture)
+	return capture, err
+}
+
+// StopCapture ends a running capture, the packets captured so far are kept
+func (c *Client) StopCapture(ctx context.Context, id string) error {
+	return c.post(ctx, remotePath("captures", id, "stop"), nil, nil, nil)
+}
+
+// RemoveCapture deletes a finished capture and its file
+func (c *Client) RemoveCapture(ctx context.Context, id string) error {
+	return c.post(ctx, remotePath("captures", id, "delete"), nil, nil, nil)
+}
+
+// DownloadCapture writes the pcap file of a finished capture to w
+func (c *Client) DownloadCapture(ctx context.Context, id string, w io.Writer) error {
+	path := "/api/captures/" + url.PathEscape(id) + "/file"
+	u := c.server.JoinPath(path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError(path, resp)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+var removeQuery = url.Values{"remove": {"true"}}
+
+// remotePath escapes the elements and joins them under the remote api
+func remotePath(elems ...string) string {
+	escaped := make([]string, len(elems))
+	for i, e := range elems {
+		escaped[i] = url.PathEscape(e)
+	}
+	return "/api/remote/" + strings.Join(escaped, "/")
+}
+
+func devicePath(addr Addr, elems ...string) string {
+	return remotePath(append([]string{"devices", addr.String()}, elems...)...)
+}
+
+func networkPath(name string, elems ...string) string {
+	return remotePath(append([]string{"networks", name}, elems...)...)
+}
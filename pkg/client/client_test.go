@@ -0,0 +1,171 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/networkables/mason/internal/model"
+)
+
+func TestClient(t *testing.T) {
+	inv := Inventory{
+		Networks: []Network{model.NewNetworkFromPrefix(netip.MustParsePrefix("192.168.1.0/24"))},
+		Devices: []Device{{
+			Name: "printer",
+			Addr: model.MustParseAddr("192.168.1.20"),
+			Meta: model.Meta{Tags: model.Tags{{Val: "office"}}},
+		}},
+	}
+	var gotQuery string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/export", func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		json.NewEncoder(w).Encode(inv)
+	})
+	mux.HandleFunc("GET /api/remote/devices", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(inv.Devices)
+	})
+	mux.HandleFunc("POST /api/remote/devices/{id}/ping", func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.PathValue("id")
+		json.NewEncoder(w).Encode(inv.Devices[0])
+	})
+	mux.HandleFunc("POST /api/remote/networks/{name}/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.PathValue("name") + "|" + r.PathValue("tag") + "?" + r.URL.RawQuery
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /api/remote/maintenance", func(w http.ResponseWriter, r *http.Request) {
+		var window MaintenanceWindow
+		json.NewDecoder(r.Body).Decode(&window)
+		gotQuery = window.Name
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /api/annotations/{id}", func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.PathValue("id") + "?" + r.URL.RawQuery
+		w.Write([]byte("[]"))
+	})
+	mux.HandleFunc("GET /api/tags", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "store closed", http.StatusInternalServerError)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	opts := cmp.Options{
+		cmpopts.EquateComparable(netip.Addr{}, netip.Prefix{}),
+		cmpopts.IgnoreUnexported(model.Device{}),
+	}
+
+	devices, err := c.Devices(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(inv.Devices, devices, opts); diff != "" {
+		t.Errorf("devices (-want +got):\n%s", diff)
+	}
+
+	device, err := c.PingDevice(ctx, inv.Devices[0].Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(inv.Devices[0], device, opts); diff != "" {
+		t.Errorf("ping device (-want +got):\n%s", diff)
+	}
+	if gotQuery != "192.168.1.20" {
+		t.Errorf("ping device request: got %q", gotQuery)
+	}
+
+	_, err = c.AnonymizedInventory(ctx, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotQuery != "anonymize=true&key=secret" {
+		t.Errorf("anonymize query: got %q", gotQuery)
+	}
+
+	_, err = c.Annotations(ctx, "192.168.1.20", 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotQuery != "192.168.1.20?duration=24h0m0s" {
+		t.Errorf("annotations request: got %q", gotQuery)
+	}
+
+	err = c.UntagNetwork(ctx, "lab a/b", "office")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotQuery != "lab a/b|office?remove=true" {
+		t.Errorf("untag network request: got %q", gotQuery)
+	}
+
+	err = c.SaveMaintenanceWindow(ctx, MaintenanceWindow{Name: "patching"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotQuery != "patching" {
+		t.Errorf("maintenance body: got %q", gotQuery)
+	}
+
+	_, err = c.Tags(ctx)
+	if !errors.Is(err, ErrUnexpectedStatus) {
+		t.Errorf("want ErrUnexpectedStatus, got %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New("localhost:4322")
+	if err == nil {
+		t.Error("want an error for a url without scheme")
+	}
+}
+
+func TestClient_DownloadCapture(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/captures/{id}/file", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "eth0-20240101-120000" {
+			http.Error(w, "packet capture does not exist", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("pcap"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	err = c.DownloadCapture(ctx, "eth0-20240101-120000", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "pcap" {
+		t.Errorf("download: got %q", buf.String())
+	}
+
+	err = c.DownloadCapture(ctx, "missing", &buf)
+	if !errors.Is(err, ErrUnexpectedStatus) {
+		t.Errorf("want ErrUnexpectedStatus, got %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package mason runs the mason command line, for binaries which add their own providers:
+//
+//	package main
+//
+//	import (
+//		"github.com/networkables/mason/pkg/mason"
+//
+//		_ "example.com/mason-unifi"
+//	)
+//
+//	func main() {
+//		mason.Execute()
+//	}
+package mason
+
+import (
+	"github.com/networkables/mason/internal/commands"
+)
+
+// Execute runs the mason command line with the providers registered by the imported packages
+func Execute() {
+	commands.RootExecute()
+}
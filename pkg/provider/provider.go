@@ -0,0 +1,152 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package provider lets other modules add discovery sources and enrichers to mason.  A
+// provider registers a factory under its name from an init func, a binary importing the
+// provider package and running mason (see package github.com/networkables/mason/pkg/mason)
+// can then enable it by name with --providers.discovery or --providers.enrichment:
+//
+//	func init() {
+//		provider.RegisterDiscoverer("unifi", func(s provider.Settings) (provider.Discoverer, error) {
+//			return newController(s.Get("url", "https://unifi:8443"), s.Get("token", ""))
+//		})
+//	}
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+)
+
+var (
+	ErrUnknownProvider   = errors.New("unknown provider")
+	ErrProviderNameInUse = errors.New("provider name already registered")
+)
+
+type (
+	// Device is a device as found or described by a provider, the empty fields are left as
+	// mason knows them
+	Device struct {
+		Addr         netip.Addr
+		MAC          net.HardwareAddr
+		Name         string
+		Manufacturer string
+		Type         string
+		Tags         []string
+	}
+
+	// Settings are the options of a provider from the providers.settings.<name> section of
+	// the config file
+	Settings map[string]string
+
+	// Discoverer is a source of devices, e.g. the client list of a wireless controller.  It is
+	// called every providers.interval.
+	Discoverer interface {
+		Discover(ctx context.Context) ([]Device, error)
+	}
+
+	// Enricher adds what it knows of a device during its enrichment, it returns true when it
+	// changed the device
+	Enricher interface {
+		Enrich(ctx context.Context, d *Device) (bool, error)
+	}
+
+	DiscovererFactory func(Settings) (Discoverer, error)
+	EnricherFactory   func(Settings) (Enricher, error)
+)
+
+var registry = struct {
+	sync.Mutex
+	discoverers map[string]DiscovererFactory
+	enrichers   map[string]EnricherFactory
+}{
+	discoverers: make(map[string]DiscovererFactory),
+	enrichers:   make(map[string]EnricherFactory),
+}
+
+// Get returns the setting or the default when it is not set
+func (s Settings) Get(key string, def string) string {
+	if v, ok := s[key]; ok {
+		return v
+	}
+	return def
+}
+
+// RegisterDiscoverer makes the discoverer available under the name, it panics when the name
+// is taken or the factory is nil
+func RegisterDiscoverer(name string, f DiscovererFactory) {
+	registry.Lock()
+	defer registry.Unlock()
+	if f == nil {
+		panic("provider: nil discoverer factory for " + name)
+	}
+	if _, ok := registry.discoverers[name]; ok {
+		panic(fmt.Errorf("%w: discoverer %s", ErrProviderNameInUse, name))
+	}
+	registry.discoverers[name] = f
+}
+
+// RegisterEnricher makes the enricher available under the name, it panics when the name is
+// taken or the factory is nil
+func RegisterEnricher(name string, f EnricherFactory) {
+	registry.Lock()
+	defer registry.Unlock()
+	if f == nil {
+		panic("provider: nil enricher factory for " + name)
+	}
+	if _, ok := registry.enrichers[name]; ok {
+		panic(fmt.Errorf("%w: enricher %s", ErrProviderNameInUse, name))
+	}
+	registry.enrichers[name] = f
+}
+
+// Discoverers returns the sorted names of the registered discoverers
+func Discoverers() []string {
+	registry.Lock()
+	defer registry.Unlock()
+	return sortedKeys(registry.discoverers)
+}
+
+// Enrichers returns the sorted names of the registered enrichers
+func Enrichers() []string {
+	registry.Lock()
+	defer registry.Unlock()
+	return sortedKeys(registry.enrichers)
+}
+
+// NewDiscoverer builds the discoverer registered under the name
+func NewDiscoverer(name string, s Settings) (Discoverer, error) {
+	registry.Lock()
+	f, ok := registry.discoverers[name]
+	registry.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: discoverer %s", ErrUnknownProvider, name)
+	}
+	return f(s)
+}
+
+// NewEnricher builds the enricher registered under the name
+func NewEnricher(name string, s Settings) (Enricher, error) {
+	registry.Lock()
+	f, ok := registry.enrichers[name]
+	registry.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: enricher %s", ErrUnknownProvider, name)
+	}
+	return f(s)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
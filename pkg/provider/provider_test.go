@@ -0,0 +1,50 @@
+// Copyright 2024 David Hallum. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+type staticDiscoverer []Device
+
+func (sd staticDiscoverer) Discover(context.Context) ([]Device, error) {
+	return sd, nil
+}
+
+func TestRegistry(t *testing.T) {
+	RegisterDiscoverer("test-static", func(s Settings) (Discoverer, error) {
+		addr, err := netip.ParseAddr(s.Get("addr", "192.168.1.1"))
+		return staticDiscoverer{{Addr: addr}}, err
+	})
+	if !slices.Contains(Discoverers(), "test-static") {
+		t.Fatalf("want test-static registered, got %v", Discoverers())
+	}
+
+	d, err := NewDiscoverer("test-static", Settings{"addr": "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	devices, _ := d.Discover(context.Background())
+	if len(devices) != 1 || devices[0].Addr != netip.MustParseAddr("10.0.0.1") {
+		t.Errorf("want the address from the settings, got %v", devices)
+	}
+
+	_, err = NewEnricher("test-static", nil)
+	if !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("want %v, got %v", ErrUnknownProvider, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("want a panic registering a name twice")
+		}
+	}()
+	RegisterDiscoverer("test-static", func(Settings) (Discoverer, error) { return nil, nil })
+}